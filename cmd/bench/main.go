@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+var (
+	// PackageFlag is the package of the benchmarks
+	PackageFlag = cli.StringFlag{
+		Name:  "package",
+		Usage: "Package of the benchmarks",
+		Value: "github.com/SamuelMarks/dag1/src/poset",
+	}
+	// BenchFlag selects the benchmarks to run
+	BenchFlag = cli.StringFlag{
+		Name:  "bench",
+		Usage: "Regular expression of the benchmarks to run",
+		Value: ".",
+	}
+	// BenchTimeFlag is how long each benchmark runs
+	BenchTimeFlag = cli.StringFlag{
+		Name:  "benchtime",
+		Usage: "Run time of each benchmark, as a duration or Nx",
+		Value: "1s",
+	}
+	// CountFlag is how many times each benchmark runs
+	CountFlag = cli.IntFlag{
+		Name:  "count",
+		Usage: "Number of runs of each benchmark",
+		Value: 1,
+	}
+	// OutputFlag is the file of the JSON summary
+	OutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "File of the JSON summary, stdout if empty",
+	}
+)
+
+// Result is the outcome of one run of a benchmark
+type Result struct {
+	Name       string             `json:"name"`
+	Iterations int64              `json:"iterations"`
+	Metrics    map[string]float64 `json:"metrics"`
+}
+
+// Summary is the JSON summary of a run of the benchmarks
+type Summary struct {
+	Package   string    `json:"package"`
+	Time      time.Time `json:"time"`
+	GoVersion string    `json:"go_version"`
+	GOOS      string    `json:"goos"`
+	GOARCH    string    `json:"goarch"`
+	Results   []Result  `json:"results"`
+}
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "bench"
+	app.Usage = "Run the DAG1 benchmarks and summarise them in JSON"
+	app.Flags = []cli.Flag{
+		PackageFlag,
+		BenchFlag,
+		BenchTimeFlag,
+		CountFlag,
+		OutputFlag,
+	}
+	app.Action = run
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error in run: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(c *cli.Context) error {
+	pkg := c.String(PackageFlag.Name)
+	cmd := exec.Command("go", "test", "-run", "^$",
+		"-bench", c.String(BenchFlag.Name),
+		"-benchtime", c.String(BenchTimeFlag.Name),
+		"-count", strconv.Itoa(c.Int(CountFlag.Name)),
+		"-benchmem", pkg)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// the go test output goes on to stderr, for the summary to be alone
+	// on stdout
+	results, err := parse(io.TeeReader(stdout, os.Stderr))
+	if err != nil {
+		return err
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("go test: %v", err)
+	}
+
+	summary := Summary{
+		Package:   pkg,
+		Time:      time.Now().UTC(),
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		Results:   results,
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if output := c.String(OutputFlag.Name); output != "" {
+		return ioutil.WriteFile(output, data, 0644)
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// parse reads the results of the benchmark lines of go test, such as
+//
+//	BenchmarkDivideRounds-8   10   112233 ns/op   4096 B/op   12 allocs/op
+func parse(r io.Reader) ([]Result, error) {
+	var results []Result
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		iterations, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		result := Result{
+			Name:       trimProcs(fields[0]),
+			Iterations: iterations,
+			Metrics:    make(map[string]float64),
+		}
+		for i := 2; i < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: metric %s: %v", fields[0], fields[i+1], err)
+			}
+			result.Metrics[fields[i+1]] = value
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
+
+// trimProcs removes the GOMAXPROCS suffix of a benchmark name, which would
+// make the results of different machines look like different benchmarks
+func trimProcs(name string) string {
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return name
+	}
+	if _, err := strconv.Atoi(name[i+1:]); err != nil {
+		return name
+	}
+	return name[:i]
+}
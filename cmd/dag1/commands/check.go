@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SamuelMarks/dag1/src/dag1"
+)
+
+var (
+	checkDataDir      string
+	checkProbe        bool
+	checkProbeTimeout time.Duration
+)
+
+// NewCheckCmd produces a CheckCmd which validates the data directory of a
+// node before it starts
+func NewCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Validate peers.json, the keys and the store of a data directory",
+		Args:  cobra.NoArgs,
+		RunE:  runCheck,
+	}
+	AddCheckFlags(cmd)
+	return cmd
+}
+
+// AddCheckFlags adds flags to the check command
+func AddCheckFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&checkDataDir, "datadir", config.DAG1.DataDir, "Top-level directory for configuration and data")
+	cmd.Flags().BoolVar(&checkProbe, "probe", false, "Dial the net address of each peer")
+	cmd.Flags().DurationVar(&checkProbeTimeout, "probe-timeout", 2*time.Second, "Dial timeout of --probe")
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	findings := dag1.CheckDataDir(checkDataDir, dag1.CheckOptions{
+		Probe:        checkProbe,
+		ProbeTimeout: checkProbeTimeout,
+		PoSConfig:    &config.DAG1.PoSConfig,
+		CacheSize:    config.DAG1.NodeConfig.CacheSize,
+	})
+	writeFindings(os.Stdout, findings)
+	if dag1.HasErrors(findings) {
+		return fmt.Errorf("%s is not ready to start a node", checkDataDir)
+	}
+	return nil
+}
+
+func writeFindings(w io.Writer, findings []dag1.Finding) {
+	if len(findings) == 0 {
+		fmt.Fprintln(w, "no problem found")
+		return
+	}
+	for _, f := range findings {
+		fmt.Fprintln(w, f)
+	}
+}
@@ -1,9 +1,23 @@
 package commands
 
 import (
-	"github.com/SamuelMarks/dag1/src/dag1"
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/SamuelMarks/dag1/src/dag1"
+	dag1_log "github.com/SamuelMarks/dag1/src/log"
+	"github.com/SamuelMarks/dag1/src/node"
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/poset"
+	aproxy "github.com/SamuelMarks/dag1/src/proxy"
+	"github.com/SamuelMarks/dag1/src/service"
 )
 
 //CLIConfig contains configuration for the Run command
@@ -11,7 +25,13 @@ type CLIConfig struct {
 	DAG1   dag1.DAG1Config `mapstructure:",squash"`
 	ProxyAddr  string                  `mapstructure:"proxy-listen"`
 	ClientAddr string                  `mapstructure:"client-connect"`
+	// ProxyAllowlist is the file of the app clients allowed to submit
+	// transactions, all of them when empty, and ProxyCommits the clients the
+	// blocks go to
+	ProxyAllowlist string `mapstructure:"proxy-allowlist"`
+	ProxyCommits   string `mapstructure:"proxy-commits"`
 	Standalone bool                    `mapstructure:"standalone"`
+	EmbeddedApp string                 `mapstructure:"embedded-app"`
 	Log2file   bool                    `mapstructure:"log2file"`
 	Pidfile    string                  `mapstructure:"pidfile"`
 	Syslog     bool                    `mapstructure:"syslog"`
@@ -23,9 +43,224 @@ func NewDefaultCLIConfig() *CLIConfig {
 		DAG1:   *dag1.NewDefaultConfig(),
 		ProxyAddr:  "127.0.0.1:1338",
 		ClientAddr: "127.0.0.1:1339",
+		ProxyCommits: aproxy.CommitsOpen,
 		Standalone: false,
 		Log2file:   false,
 		Pidfile:    filepath.Join(os.TempDir(), "dag1.pid"),
 		Syslog:     false,
 	}
 }
+
+//Normalize lowercases the values compared case-insensitively
+func (c *CLIConfig) Normalize() {
+	c.DAG1.PeerSelector = strings.ToLower(c.DAG1.PeerSelector)
+	c.EmbeddedApp = strings.ToLower(c.EmbeddedApp)
+	c.ProxyCommits = strings.ToLower(c.ProxyCommits)
+	c.DAG1.TestDistribution = strings.ToLower(c.DAG1.TestDistribution)
+	c.DAG1.NodeConfig.TxPoolPolicy = strings.ToLower(c.DAG1.NodeConfig.TxPoolPolicy)
+	c.DAG1.NodeConfig.OtherParentSelector = strings.ToLower(c.DAG1.NodeConfig.OtherParentSelector)
+}
+
+//Validate checks the resolved configuration before the node starts, the
+//error lists every invalid field
+func (c *CLIConfig) Validate() error {
+	var result *multierror.Error
+	invalid := func(key string, format string, args ...interface{}) {
+		result = multierror.Append(result,
+			fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	addrs := []struct {
+		key      string
+		addr     string
+		optional bool
+	}{
+		{"listen", c.DAG1.BindAddr, false},
+		{"service-listen", c.DAG1.ServiceAddr, true},
+		{"proxy-listen", c.ProxyAddr, c.Standalone || c.EmbeddedApp != ""},
+		{"client-connect", c.ClientAddr, true},
+		{"join", c.DAG1.JoinAddr, true},
+	}
+	for _, a := range addrs {
+		if a.addr == "" && a.optional {
+			continue
+		}
+		if path, ok := service.SocketPath(a.addr); ok && a.key == "service-listen" {
+			if path == "" {
+				invalid(a.key, "no socket path in %q", a.addr)
+			}
+			continue
+		}
+		if err := checkAddr(a.addr); err != nil {
+			invalid(a.key, "%s", err)
+		}
+	}
+
+	durations := []struct {
+		key string
+		d   time.Duration
+	}{
+		{"heartbeat", c.DAG1.NodeConfig.HeartbeatTimeout},
+		{"timeout", c.DAG1.NodeConfig.TCPTimeout},
+		{"ready-round-window", c.DAG1.NodeConfig.ReadyRoundWindow},
+	}
+	for _, d := range durations {
+		if d.d <= 0 {
+			invalid(d.key, "%v is not a positive duration", d.d)
+		}
+	}
+	if c.DAG1.NodeConfig.HeartbeatMax < 0 {
+		invalid("heartbeat-max", "%v is negative", c.DAG1.NodeConfig.HeartbeatMax)
+	}
+	if err := poset.CheckCacheTriggers(c.DAG1.NodeConfig.CacheGrowBelow, c.DAG1.NodeConfig.CacheShrinkAbove); err != nil {
+		invalid("cache-grow-below", "%s", err)
+	}
+	if c.DAG1.NodeConfig.StallWarnTimeout < 0 {
+		invalid("stall-warn-timeout", "%v is negative", c.DAG1.NodeConfig.StallWarnTimeout)
+	}
+	if c.DAG1.NodeConfig.TickStallTimeout < 0 {
+		invalid("tick-stall-timeout", "%v is negative", c.DAG1.NodeConfig.TickStallTimeout)
+	}
+	if c.DAG1.NodeConfig.TickInterval < 0 {
+		invalid("tick-interval", "%v is negative", c.DAG1.NodeConfig.TickInterval)
+	}
+	if c.DAG1.NodeConfig.MaxTimestampDrift < 0 {
+		invalid("max-timestamp-drift", "%v is negative", c.DAG1.NodeConfig.MaxTimestampDrift)
+	}
+	if c.DAG1.NodeConfig.SyncTimeBudget < 0 {
+		invalid("sync-time-budget", "%v is negative", c.DAG1.NodeConfig.SyncTimeBudget)
+	}
+	if c.DAG1.FlightRecInterval < 0 {
+		invalid("flightrec-interval", "%v is negative", c.DAG1.FlightRecInterval)
+	}
+	if max := c.DAG1.NodeConfig.SyncMaxEvents; max > 0 && max < c.DAG1.NodeConfig.SyncMinEvents {
+		invalid("sync-max-events", "%d is less than sync-min-events %d", max, c.DAG1.NodeConfig.SyncMinEvents)
+	}
+
+	sizes := []struct {
+		key string
+		n   int64
+		min int64
+	}{
+		{"cache-size", int64(c.DAG1.NodeConfig.CacheSize), 1},
+		{"cache-budget", int64(c.DAG1.NodeConfig.CacheBudget), 0},
+		{"sync-limit", c.DAG1.NodeConfig.SyncLimit, 1},
+		{"sync-min-events", c.DAG1.NodeConfig.SyncMinEvents, 1},
+		{"sync-max-events", c.DAG1.NodeConfig.SyncMaxEvents, 0},
+		{"sync-max-bytes", c.DAG1.NodeConfig.SyncMaxBytes, 0},
+		{"frame-page-events", c.DAG1.NodeConfig.FramePageEvents, 1},
+		{"max-pool", int64(c.DAG1.MaxPool), 1},
+		{"min-protocol-version", int64(c.DAG1.MinProtocolVersion), 1},
+		{"max-message-size", c.DAG1.MaxMessageSize, 0},
+		{"pause-queue", int64(c.DAG1.NodeConfig.PauseQueueSize), 0},
+		{"ready-heartbeats", int64(c.DAG1.NodeConfig.ReadyHeartbeats), 1},
+		{"flow-control-skew", c.DAG1.NodeConfig.FlowControlSkew, 0},
+		{"flow-control-max-factor", c.DAG1.NodeConfig.FlowControlMaxFactor, 1},
+		{"undetermined-warn-age", c.DAG1.NodeConfig.UndeterminedWarnAge, 0},
+		{"undetermined-archive-age", c.DAG1.NodeConfig.UndeterminedArchiveAge, 0},
+		{"tx-pool-size", int64(c.DAG1.NodeConfig.TxPoolSize), 0},
+		{"tx-pool-bytes", int64(c.DAG1.NodeConfig.TxPoolBytes), 0},
+		{"max-events-per-frame", int64(c.DAG1.NodeConfig.MaxEventsPerFrame), 0},
+		{"max-block-transactions", int64(c.DAG1.NodeConfig.MaxBlockTransactions), 0},
+		{"max-block-bytes", int64(c.DAG1.NodeConfig.MaxBlockBytes), 0},
+		{"commit-batch", int64(c.DAG1.NodeConfig.CommitBatchSize), 1},
+		{"flightrec-max-size", c.DAG1.FlightRecMaxSize, 0},
+	}
+	for _, s := range sizes {
+		if s.n < s.min {
+			invalid(s.key, "%d is less than %d", s.n, s.min)
+		}
+	}
+
+	if c.DAG1.MinProtocolVersion > peer.ProtocolVersion {
+		invalid("min-protocol-version", "%d is above the protocol version %d",
+			c.DAG1.MinProtocolVersion, peer.ProtocolVersion)
+	}
+
+	if !contains(dag1.PeerSelectors, c.DAG1.PeerSelector) {
+		invalid("peer_selector", "unknown peer selector %q, available: %s",
+			c.DAG1.PeerSelector, strings.Join(dag1.PeerSelectors, ","))
+	}
+	if !contains(node.TxPoolPolicies, c.DAG1.NodeConfig.TxPoolPolicy) {
+		invalid("tx-pool-policy", "unknown policy %q, available: %s",
+			c.DAG1.NodeConfig.TxPoolPolicy, strings.Join(node.TxPoolPolicies, ","))
+	}
+	if !contains(node.OtherParentSelectors, c.DAG1.NodeConfig.OtherParentSelector) {
+		invalid("other-parent-selector", "unknown other-parent selector %q, available: %s",
+			c.DAG1.NodeConfig.OtherParentSelector, strings.Join(node.OtherParentSelectors, ","))
+	}
+	if c.DAG1.ServiceToken != "" && c.DAG1.ServiceTokenFile != "" {
+		invalid("service-token", "set either the token or service-token-file")
+	}
+	if c.DAG1.ServiceTimeout < 0 {
+		invalid("service-timeout", "%v is negative", c.DAG1.ServiceTimeout)
+	}
+	store := c.DAG1.StoreKind()
+	if !contains(dag1.StoreKinds, store) {
+		invalid("store", "unknown store %q, available: %s",
+			c.DAG1.Store, strings.Join(dag1.StoreKinds, ","))
+	}
+	if store == dag1.StoreSQLite && !poset.SQLiteAvailable {
+		invalid("store", "the sqlite store needs a build with the sqlite tag")
+	}
+	if c.DAG1.StorePath != "" && store == dag1.StoreInmem {
+		invalid("store-path", "the in-mem store has no path, set --store")
+	}
+	if c.DAG1.ArchiveDir != "" && store != dag1.StoreBadger {
+		invalid("archive-dir", "the archive needs the badger store, --store=badger")
+	}
+	if c.DAG1.TxIndex && store == dag1.StoreSQLite {
+		invalid("tx-index", "the sqlite store does not index the transactions")
+	}
+	if r := c.DAG1.ValueLogGCDiscardRatio; r <= 0 || r >= 1 {
+		invalid("value-log-gc-discard-ratio", "%v is not between 0 and 1", r)
+	}
+	if c.DAG1.ValueLogGCInterval < 0 {
+		invalid("value-log-gc-interval", "%v is negative", c.DAG1.ValueLogGCInterval)
+	}
+	if c.DAG1.InmemDumpOnExit != "" && store != dag1.StoreInmem {
+		invalid("inmem-dump-on-exit", "the dump is of the in-mem store, not of --store=%s", store)
+	}
+	if !contains(aproxy.CommitDeliveries, c.ProxyCommits) {
+		invalid("proxy-commits", "unknown delivery %q, available: %s",
+			c.ProxyCommits, strings.Join(aproxy.CommitDeliveries, ","))
+	}
+	if c.ProxyCommits == aproxy.CommitsRestricted && c.ProxyAllowlist == "" {
+		invalid("proxy-commits", "restricting the blocks needs --proxy-allowlist")
+	}
+	if c.EmbeddedApp != "" && c.EmbeddedApp != "kv" {
+		invalid("embedded-app", "unknown embedded app %q, available: kv", c.EmbeddedApp)
+	}
+	if c.DAG1.Test {
+		conf := testConfig(c)
+		if err := conf.Validate(); err != nil {
+			invalid("test", "%s", err)
+		}
+	}
+	if _, err := dag1_log.ParseLevels(c.DAG1.LogLevel); err != nil {
+		invalid("log", "%s", err)
+	}
+
+	return result.ErrorOrNil()
+}
+
+//checkAddr checks addr is a host:port with a valid port
+func checkAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port %q in %q", port, addr)
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+//NewConfigCmd returns the command that prints the effective configuration
+//of the run command
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Print the effective configuration, with the source of each value",
+		Long: `Print the configuration dag1 run would start with, given the same flags,
+environment and config file, as TOML. Each value is annotated with where it
+comes from: flag, env, config file or default, in that order of precedence.
+
+With --consensus-hash, print the hash of the consensus parameters instead,
+for operators to check they all use the same.`,
+		RunE: printConfig,
+	}
+	AddRunFlags(cmd)
+	cmd.Flags().Bool("consensus-hash", false, "Print the hash of the consensus parameters and the parameters")
+	return cmd
+}
+
+func printConfig(cmd *cobra.Command, args []string) error {
+	v := viper.New()
+	config, err := loadConfig(v, cmd)
+	if err != nil {
+		return err
+	}
+	if hash, _ := cmd.Flags().GetBool("consensus-hash"); hash {
+		return writeConsensusHash(cmd.OutOrStdout(), config)
+	}
+	writeConfig(cmd.OutOrStdout(), v, cmd.Flags())
+	return config.Validate()
+}
+
+//writeConfig writes every key of v as TOML, annotated with its source
+func writeConfig(w io.Writer, v *viper.Viper, flags *pflag.FlagSet) {
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "help" || key == "consensus-hash" {
+			continue
+		}
+		fmt.Fprintf(w, "%s = %s # %s\n",
+			key, tomlValue(v.Get(key), flags.Lookup(key)), configSource(v, flags, key))
+	}
+}
+
+//writeConsensusHash writes the hash of the consensus parameters, then the
+//parameters as JSON
+func writeConsensusHash(w io.Writer, config *CLIConfig) error {
+	params := config.DAG1.NodeConfig.Consensus()
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s\n%s\n", params.Hash().Hex(), data)
+	return nil
+}
+
+//configSource returns where the value of key comes from
+func configSource(v *viper.Viper, flags *pflag.FlagSet, key string) string {
+	if f := flags.Lookup(key); f != nil && f.Changed {
+		return "flag"
+	}
+	if env := envKey(key); os.Getenv(env) != "" {
+		return "env " + env
+	}
+	if v.InConfig(key) {
+		return "config " + v.ConfigFileUsed()
+	}
+	return "default"
+}
+
+//envKey returns the environment variable setting key
+func envKey(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.Replace(key, "-", "_", -1))
+}
+
+//tomlValue formats a value, bare for booleans and the numbers TOML can
+//represent, quoted otherwise (durations and uint64 parse back from strings)
+func tomlValue(value interface{}, flag *pflag.Flag) string {
+	s := fmt.Sprint(value)
+	switch value.(type) {
+	case bool, int, int8, int16, int32, int64, uint8, uint16, uint32, float32, float64:
+		return s
+	}
+	if flag != nil {
+		switch flag.Value.Type() {
+		case "bool":
+			if _, err := strconv.ParseBool(s); err == nil {
+				return s
+			}
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+			if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return s
+			}
+		}
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/SamuelMarks/dag1/src/dag1"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// resolve parses args as the flags of the run command and resolves its
+// configuration, with a dag1.toml made of file in a fresh data directory
+func resolve(t *testing.T, args []string, file string) (*CLIConfig, *viper.Viper, *cobra.Command) {
+	dir, err := ioutil.TempDir("", "dag1-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if file != "" {
+		if err := ioutil.WriteFile(filepath.Join(dir, "dag1.toml"), []byte(file), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := &cobra.Command{Use: "run"}
+	AddRunFlags(cmd)
+	if err := cmd.ParseFlags(append([]string{"--datadir", dir}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	v := viper.New()
+	config, err := loadConfig(v, cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config, v, cmd
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	defaultPool := NewDefaultCLIConfig().DAG1.MaxPool
+	for _, c := range []struct {
+		flag, env, file bool
+		expected        int
+		source          string
+	}{
+		{false, false, false, defaultPool, "default"},
+		{false, false, true, 30, "config"},
+		{false, true, false, 20, "env"},
+		{false, true, true, 20, "env"},
+		{true, false, false, 10, "flag"},
+		{true, false, true, 10, "flag"},
+		{true, true, false, 10, "flag"},
+		{true, true, true, 10, "flag"},
+	} {
+		name := fmt.Sprintf("flag=%v,env=%v,file=%v", c.flag, c.env, c.file)
+		t.Run(name, func(t *testing.T) {
+			var args []string
+			if c.flag {
+				args = []string{"--max-pool", "10"}
+			}
+			if c.env {
+				os.Setenv("DAG1_MAX_POOL", "20")
+				defer os.Unsetenv("DAG1_MAX_POOL")
+			}
+			var file string
+			if c.file {
+				file = "max-pool = 30\n"
+			}
+
+			config, v, cmd := resolve(t, args, file)
+			if config.DAG1.MaxPool != c.expected {
+				t.Fatalf("expected max-pool %d, got %d", c.expected, config.DAG1.MaxPool)
+			}
+			if source := configSource(v, cmd.Flags(), "max-pool"); !strings.HasPrefix(source, c.source) {
+				t.Fatalf("expected max-pool from %s, got %s", c.source, source)
+			}
+		})
+	}
+}
+
+func TestConfigDurationAndSelector(t *testing.T) {
+	os.Setenv("DAG1_PEER_SELECTOR", "Random")
+	defer os.Unsetenv("DAG1_PEER_SELECTOR")
+
+	config, v, cmd := resolve(t, nil, "heartbeat = \"250ms\"\n")
+	if config.DAG1.NodeConfig.HeartbeatTimeout != 250*time.Millisecond {
+		t.Fatalf("expected a 250ms heartbeat, got %v", config.DAG1.NodeConfig.HeartbeatTimeout)
+	}
+	if config.DAG1.PeerSelector != "random" {
+		t.Fatalf("expected the peer selector to be normalized, got %q", config.DAG1.PeerSelector)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	writeConfig(&out, v, cmd.Flags())
+	for _, line := range []string{
+		"heartbeat = \"250ms\" # config ",
+		"peer_selector = \"Random\" # env DAG1_PEER_SELECTOR",
+		"max-pool = 2 # default",
+		"store = \"inmem\" # default",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Fatalf("expected %q in:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	config, _, _ := resolve(t, []string{
+		"--listen", "127.0.0.1",
+		"--service-listen", "127.0.0.1:70000",
+		"--heartbeat", "-1s",
+		"--cache-size", "0",
+		"--peer_selector", "nearest",
+		"--embedded-app", "sql",
+		"--test", "--test_distribution", "all",
+	}, "")
+
+	err := config.Validate()
+	merr, ok := err.(*multierror.Error)
+	if !ok {
+		t.Fatalf("expected a multierror, got %v", err)
+	}
+	invalid := []string{"listen", "service-listen", "heartbeat", "cache-size", "peer_selector", "embedded-app", "test"}
+	if len(merr.Errors) != len(invalid) {
+		t.Fatalf("expected %d errors, got:\n%s", len(invalid), err)
+	}
+	for i, key := range invalid {
+		if !strings.HasPrefix(merr.Errors[i].Error(), key+": ") {
+			t.Fatalf("expected error %d about %s, got %v", i, key, merr.Errors[i])
+		}
+	}
+
+	if err := NewDefaultCLIConfig().Validate(); err != nil {
+		t.Fatalf("expected the default configuration to be valid, got %v", err)
+	}
+}
+
+func TestConfigStore(t *testing.T) {
+	cases := []struct {
+		args []string
+		file string
+		kind string
+	}{
+		{nil, "", dag1.StoreInmem},
+		// the former boolean flag and setting
+		{[]string{"--store"}, "", dag1.StoreBadger},
+		{[]string{"--store=false"}, "", dag1.StoreInmem},
+		{nil, "store = true\n", dag1.StoreBadger},
+		{nil, "store = false\n", dag1.StoreInmem},
+		{[]string{"--store=badger"}, "", dag1.StoreBadger},
+		{nil, "store = \"sqlite\"\n", dag1.StoreSQLite},
+	}
+	for _, c := range cases {
+		config, _, _ := resolve(t, c.args, c.file)
+		if kind := config.DAG1.StoreKind(); kind != c.kind {
+			t.Fatalf("%v %q: expected the %s store, got %s", c.args, c.file, c.kind, kind)
+		}
+		err := config.Validate()
+		if c.kind == dag1.StoreSQLite && !poset.SQLiteAvailable {
+			if err == nil {
+				t.Fatal("expected the sqlite store refused in a build without it")
+			}
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, _, _ := resolve(t, []string{"--store=mysql", "--store-path", "db"}, "")
+	merr, ok := config.Validate().(*multierror.Error)
+	if !ok || len(merr.Errors) != 1 || !strings.HasPrefix(merr.Errors[0].Error(), "store: ") {
+		t.Fatalf("expected an unknown store refused, got %v", merr)
+	}
+	if path := config.DAG1.StoreDBPath(); path != "db" {
+		t.Fatalf("expected the store path db, got %s", path)
+	}
+}
+
+func TestConfigValidateServiceSocket(t *testing.T) {
+	config, _, _ := resolve(t, []string{"--service-listen", "unix:///tmp/dag1.sock"}, "")
+	if err := config.Validate(); err != nil {
+		t.Fatalf("expected a socket path to be valid, got %v", err)
+	}
+
+	config, _, _ = resolve(t, []string{
+		"--service-listen", "unix://",
+		"--service-token", "t",
+		"--service-token-file", "token",
+	}, "")
+	err := config.Validate()
+	merr, ok := err.(*multierror.Error)
+	if !ok || len(merr.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %v", err)
+	}
+}
+
+func TestConfigConsensusHash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dag1-params")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "consensus.json")
+	if err := ioutil.WriteFile(file, []byte(`{"max_events_per_frame": 7}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file overrides the flag
+	config, _, _ := resolve(t, []string{
+		"--max-events-per-frame", "3",
+		"--consensus-params-file", file,
+	}, "")
+	if config.DAG1.NodeConfig.MaxEventsPerFrame != 7 {
+		t.Fatalf("expected 7 events per frame from the file, got %d", config.DAG1.NodeConfig.MaxEventsPerFrame)
+	}
+
+	var out bytes.Buffer
+	if err := writeConsensusHash(&out, config); err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprintf("%s\n{\"max_events_per_frame\":7}\n",
+		config.DAG1.NodeConfig.Consensus().Hash().Hex())
+	if out.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SamuelMarks/dag1/src/node"
+)
+
+var (
+	fastForwardService   string
+	fastForwardToken     string
+	fastForwardTokenFile string
+	fastForwardWait      bool
+)
+
+// NewFastForwardCmd produces a FastForwardCmd which makes a live node fast
+// forward from a chosen peer
+func NewFastForwardCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fastforward [peer host:port]",
+		Short: "Make a node fast forward from a peer, through its /admin/fastforward endpoint",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runFastForward,
+	}
+	AddFastForwardFlags(cmd)
+	return cmd
+}
+
+// AddFastForwardFlags adds flags to the fastforward command
+func AddFastForwardFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&fastForwardService, "service", "s", "127.0.0.1:8000", "IP:Port of the node HTTP service")
+	cmd.Flags().StringVar(&fastForwardToken, "token", "", "Bearer token of the node HTTP service")
+	cmd.Flags().StringVar(&fastForwardTokenFile, "token-file", "", "File holding the bearer token of the node HTTP service")
+	cmd.Flags().BoolVar(&fastForwardWait, "wait", true, "Follow the fast forward until it is done or failed")
+}
+
+func runFastForward(cmd *cobra.Command, args []string) error {
+	token := fastForwardToken
+	if fastForwardTokenFile != "" {
+		data, err := ioutil.ReadFile(fastForwardTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the service token: %s", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	body, err := json.Marshal(map[string]string{"peer": args[0]})
+	if err != nil {
+		return err
+	}
+	var job node.FastForwardJob
+	err = adminServiceJSON(http.MethodPost, fastForwardService, "/admin/fastforward", token, body, &job)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "fast forward %s from %s: %s\n", job.ID, job.Peer, job.Stage)
+
+	for fastForwardWait && job.Stage != node.FastForwardDone && job.Stage != node.FastForwardFailed {
+		time.Sleep(500 * time.Millisecond)
+		stage := job.Stage
+		err := adminServiceJSON(http.MethodGet, fastForwardService, "/admin/fastforward/"+job.ID, token, nil, &job)
+		if err != nil {
+			return err
+		}
+		if job.Stage != stage {
+			fmt.Fprintf(os.Stdout, "fast forward %s from %s: %s\n", job.ID, job.Peer, job.Stage)
+		}
+	}
+	if job.Stage == node.FastForwardFailed {
+		return fmt.Errorf("fast forward %s failed: %s", job.ID, job.Error)
+	}
+	if job.Stage == node.FastForwardDone {
+		fmt.Fprintf(os.Stdout, "anchor block: %d\n", job.Block)
+	}
+	return nil
+}
+
+// adminServiceJSON sends a request to the admin endpoints of the service,
+// with the token if any, and decodes the JSON answer in v
+func adminServiceJSON(method, addr, path, token string, body []byte, v interface{}) error {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	req, err := http.NewRequest(method, addr+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s%s: %s: %s", addr, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,265 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/node"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+var (
+	inspectService   string
+	inspectPeers     bool
+	inspectAccount   string
+	inspectTx        string
+	inspectFairness  bool
+	inspectStore     string
+	inspectFlightRec string
+	inspectCSV       bool
+)
+
+// NewInspectCmd produces an InspectCmd which queries the service of a live node
+func NewInspectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Show the state of a node from its HTTP service or its badger store",
+		Args:  cobra.NoArgs,
+		RunE:  runInspect,
+	}
+	AddInspectFlags(cmd)
+	return cmd
+}
+
+// AddInspectFlags adds flags to the inspect command
+func AddInspectFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&inspectService, "service", "s", "127.0.0.1:8000", "IP:Port of the node HTTP service")
+	cmd.Flags().BoolVar(&inspectPeers, "peers", false, "Show the height, in-degree, selections and last sync of each peer")
+	cmd.Flags().StringVar(&inspectAccount, "account", "", "Show the PoS balance of an address or peer public key")
+	cmd.Flags().StringVar(&inspectTx, "tx", "", "Show the event and block of a transaction by the hash of its content")
+	cmd.Flags().BoolVar(&inspectFairness, "fairness", false, "Show the events, clothos, Atropos and transactions in blocks of each creator, with their shares")
+	cmd.Flags().StringVar(&inspectStore, "store", "", "Badger directory of a stopped node to read instead of the service (--account, --tx and --fairness only)")
+	cmd.Flags().StringVar(&inspectFlightRec, "flightrec", "", "Show the metrics snapshots of the flightrec directory of a node")
+	cmd.Flags().BoolVar(&inspectCSV, "csv", false, "Write the snapshots of --flightrec as CSV")
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	if inspectFlightRec != "" {
+		records, err := node.ReadFlightRecords(inspectFlightRec)
+		if err != nil {
+			return err
+		}
+		if inspectCSV {
+			return writeFlightRecordsCSV(os.Stdout, records)
+		}
+		return writeFlightRecords(os.Stdout, records)
+	}
+	if inspectAccount != "" {
+		account, err := inspectAccountBalance(inspectAccount)
+		if err != nil {
+			return err
+		}
+		return writeAccount(os.Stdout, account)
+	}
+	if inspectTx != "" {
+		loc, err := inspectTxLocation(inspectTx)
+		if err != nil {
+			return err
+		}
+		return writeTxLocation(os.Stdout, loc)
+	}
+	if inspectFairness {
+		report, err := inspectFairnessReport()
+		if err != nil {
+			return err
+		}
+		return writeFairness(os.Stdout, report)
+	}
+	if !inspectPeers {
+		return fmt.Errorf("nothing to inspect, use --peers, --account, --tx, --fairness or --flightrec")
+	}
+
+	var snapshot []peers.PeerSnapshot
+	if err := getServiceJSON(inspectService, "/peers", &snapshot); err != nil {
+		return err
+	}
+	return writePeers(os.Stdout, snapshot, time.Now())
+}
+
+// inspectAccountBalance reads the account from the store when one is given,
+// from the service otherwise
+func inspectAccountBalance(addr string) (poset.Account, error) {
+	address, err := peers.ParseAddress(addr)
+	if err != nil {
+		return poset.Account{}, err
+	}
+	if inspectStore == "" {
+		var account poset.Account
+		err := getServiceJSON(inspectService, "/account/"+address.Hex(), &account)
+		return account, err
+	}
+
+	store, err := poset.LoadBadgerStore(config.DAG1.NodeConfig.CacheSize, inspectStore)
+	if err != nil {
+		return poset.Account{}, fmt.Errorf("loading %s: %s", inspectStore, err)
+	}
+	defer store.Close()
+	return poset.GetAccount(store, address)
+}
+
+// inspectTxLocation looks the transaction up in the store when one is given,
+// through the service otherwise
+func inspectTxLocation(hash string) (poset.TxLocation, error) {
+	var txHash common.Hash
+	if err := txHash.UnmarshalText([]byte(hash)); err != nil {
+		return poset.TxLocation{}, err
+	}
+	if inspectStore == "" {
+		var loc poset.TxLocation
+		err := getServiceJSON(inspectService, "/txlookup/"+txHash.Hex(), &loc)
+		return loc, err
+	}
+
+	store, err := poset.LoadBadgerStore(config.DAG1.NodeConfig.CacheSize, inspectStore)
+	if err != nil {
+		return poset.TxLocation{}, fmt.Errorf("loading %s: %s", inspectStore, err)
+	}
+	defer store.Close()
+	return store.LookupTx(txHash)
+}
+
+// inspectFairnessReport counts the contributions of the creators from the
+// events and blocks of the store when one is given, asks the service
+// otherwise
+func inspectFairnessReport() (poset.FairnessReport, error) {
+	if inspectStore == "" {
+		var report poset.FairnessReport
+		err := getServiceJSON(inspectService, "/report/fairness", &report)
+		return report, err
+	}
+
+	store, err := poset.LoadBadgerStore(config.DAG1.NodeConfig.CacheSize, inspectStore)
+	if err != nil {
+		return poset.FairnessReport{}, fmt.Errorf("loading %s: %s", inspectStore, err)
+	}
+	defer store.Close()
+	return poset.StoreFairness(store)
+}
+
+func getServiceJSON(addr, path string, v interface{}) error {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(addr + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s%s: %s", addr, path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// writeAccount prints the balance with the frame of the state it was read in
+func writeAccount(out io.Writer, account poset.Account) error {
+	frame := fmt.Sprint(account.Frame)
+	if account.Frame < 0 {
+		frame = "genesis"
+	}
+	_, err := fmt.Fprintf(out, "address:    %s\nbalance:    %d\nframe:      %s\nstate hash: %s\n",
+		account.Address.Hex(), account.Balance, frame, account.StateHash.Hex())
+	return err
+}
+
+// writeTxLocation prints the event of a transaction, and its block once
+// committed
+func writeTxLocation(out io.Writer, loc poset.TxLocation) error {
+	block := "not committed yet"
+	if loc.Block >= 0 {
+		block = fmt.Sprintf("%d, position %d", loc.Block, loc.Position)
+	}
+	_, err := fmt.Fprintf(out, "tx:    %s\nevent: %s\nblock: %s\n",
+		loc.Tx.Hex(), loc.Event.Hex(), block)
+	return err
+}
+
+// writeFairness prints one line per creator, each count with its share, and
+// the Gini coefficients of the counts
+func writeFairness(out io.Writer, report poset.FairnessReport) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CREATOR\tEVENTS\tCLOTHOS\tATROPOS\tTRANSACTIONS")
+	count := func(n uint64, share float64) string {
+		return fmt.Sprintf("%d (%.1f%%)", n, 100*share)
+	}
+	for _, c := range report.Creators {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Creator,
+			count(c.Counts.Events, c.Shares.Events),
+			count(c.Counts.Clothos, c.Shares.Clothos),
+			count(c.Counts.Atropos, c.Shares.Atropos),
+			count(c.Counts.Transactions, c.Shares.Transactions))
+	}
+	t := report.Totals
+	fmt.Fprintf(w, "total\t%d\t%d\t%d\t%d\n", t.Events, t.Clothos, t.Atropos, t.Transactions)
+	g := report.Gini
+	fmt.Fprintf(w, "gini\t%.3f\t%.3f\t%.3f\t%.3f\n", g.Events, g.Clothos, g.Atropos, g.Transactions)
+	return w.Flush()
+}
+
+// writePeers prints one line per peer, the last sync as an age
+func writePeers(out io.Writer, snapshot []peers.PeerSnapshot, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNET_ADDR\tHEIGHT\tIN_DEGREE\tUSED\tLAST_SYNC")
+	for _, p := range snapshot {
+		lastSync := "never"
+		if !p.LastSyncTime.IsZero() {
+			outcome := "ok"
+			if !p.LastSyncOK {
+				outcome = "failed"
+			}
+			lastSync = fmt.Sprintf("%s %s ago", outcome,
+				now.Sub(p.LastSyncTime).Round(time.Millisecond))
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%s\n",
+			p.ID, p.NetAddr, p.Height, p.InDegree, p.Used, lastSync)
+	}
+	return w.Flush()
+}
+
+// writeFlightRecords prints one line per snapshot of the flight recorder
+func writeFlightRecords(out io.Writer, records []node.FlightRecord) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(node.FlightRecordFields(), "\t")))
+	for _, r := range records {
+		fmt.Fprintln(w, strings.Join(r.Values(), "\t"))
+	}
+	return w.Flush()
+}
+
+// writeFlightRecordsCSV writes the snapshots of the flight recorder as CSV,
+// the header first
+func writeFlightRecordsCSV(out io.Writer, records []node.FlightRecord) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(node.FlightRecordFields()); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := w.Write(r.Values()); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
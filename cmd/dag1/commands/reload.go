@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/SamuelMarks/dag1/src/node"
+)
+
+// reloadOnHangup re-reads the config file of v on SIGHUP and applies to n
+// the settings which change at runtime, see reloadConfig. The consensus
+// parameters which changed in the file are left as they are, with a
+// warning. The returned func stops it.
+func reloadOnHangup(v *viper.Viper, n *node.Node, logger logrus.FieldLogger) func() {
+	started := consensusSettings(v)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+			}
+			changes, err := reloadConfig(v, n)
+			if err != nil {
+				logger.WithError(err).Error("Reloading the config file")
+				continue
+			}
+			for key, value := range consensusSettings(v) {
+				if value != started[key] {
+					logger.WithField("setting", key).Warn((&node.ConsensusSettingError{Key: key}).Error())
+				}
+			}
+			logger.WithField("changes", len(changes)).Info("Reloaded the config file")
+		}
+	}()
+	return func() { close(done) }
+}
+
+// reloadConfig re-reads the config file of v and applies to n its settings
+// of node.RuntimeSettings, all or none. The flags and the environment still
+// take precedence over the file, as when the node started.
+func reloadConfig(v *viper.Viper, n *node.Node) ([]node.RuntimeChange, error) {
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, err
+		}
+	}
+	settings := make(map[string]string)
+	for _, key := range node.RuntimeSettings {
+		if value := v.GetString(key); value != "" {
+			settings[key] = value
+		}
+	}
+	return n.Reconfigure(settings)
+}
+
+// consensusSettings returns the values of the consensus parameters in v
+func consensusSettings(v *viper.Viper) map[string]string {
+	res := make(map[string]string)
+	for _, key := range node.ConsensusSettings {
+		res[key] = v.GetString(key)
+	}
+	return res
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/SamuelMarks/dag1/src/node"
+)
+
+func TestReloadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dag1-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "dag1.toml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("heartbeat = \"250ms\"\nsync-limit = 10\n")
+
+	cmd := &cobra.Command{Use: "run"}
+	AddRunFlags(cmd)
+	if err := cmd.ParseFlags([]string{"--datadir", dir, "--sync-limit", "77"}); err != nil {
+		t.Fatal(err)
+	}
+	v := viper.New()
+	if _, err := loadConfig(v, cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.Level = logrus.FatalLevel
+	nodes, err := node.NewNodeList(1, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, n := range nodes {
+			n.Shutdown()
+		}
+	}()
+	n := nodes.Values()[0]
+
+	// the consensus parameters of the file are not among the settings
+	// reloaded, and the flags still take precedence
+	write("heartbeat = \"40ms\"\nlog = \"warn,node=debug\"\nsync-limit = 10\nmax-block-bytes = 1024\n")
+	if _, err := reloadConfig(v, n); err != nil {
+		t.Fatal(err)
+	}
+	c := n.GetRuntimeConfig()
+	if c.Heartbeat != 40*time.Millisecond || c.Log != "warning,node=debug" || c.SyncLimit != 77 {
+		t.Fatalf("expected the settings of the file under the flags, got %+v", c)
+	}
+	if consensusSettings(v)["max-block-bytes"] != "1024" {
+		t.Fatalf("expected the consensus parameters of the file read, got %v", consensusSettings(v))
+	}
+
+	write("heartbeat = \"often\"\nlog = \"error\"\n")
+	if _, err := reloadConfig(v, n); err == nil {
+		t.Fatal("expected a bad heartbeat refused")
+	}
+	if c := n.GetRuntimeConfig(); c.Heartbeat != 40*time.Millisecond || c.Log != "warning,node=debug" {
+		t.Fatalf("expected nothing applied, got %+v", c)
+	}
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/SamuelMarks/dag1/src/replay"
+)
+
+var (
+	replayOut     string
+	replayCompare string
+	replayExport  string
+)
+
+// NewReplayCmd produces a ReplayCmd which re-runs consensus from an event log
+func NewReplayCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay [badger dir | event stream]",
+		Short: "Re-run consensus from an event log and print the blocks",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runReplay,
+	}
+	AddReplayFlags(cmd)
+	return cmd
+}
+
+//AddReplayFlags adds flags to the replay command
+func AddReplayFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&replayOut, "out", "", "File where the blocks will be written, stdout if empty")
+	cmd.Flags().StringVar(&replayCompare, "compare", "", "Second input to replay and compare block by block")
+	cmd.Flags().StringVar(&replayExport, "export", "", "File where the input events will be written as an event stream")
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	logger := logrus.New()
+	logger.Level = logrus.WarnLevel
+	cacheSize := config.DAG1.NodeConfig.CacheSize
+
+	participants, events, err := replay.Load(args[0], cacheSize)
+	if err != nil {
+		return fmt.Errorf("loading %s: %s", args[0], err)
+	}
+
+	if replayExport != "" {
+		f, err := os.Create(replayExport)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := replay.WriteEvents(f, participants, events); err != nil {
+			return fmt.Errorf("exporting events: %s", err)
+		}
+	}
+
+	blocks, err := replay.Replay(participants, events, logger)
+	if err != nil {
+		return fmt.Errorf("replaying %s: %s", args[0], err)
+	}
+
+	var out io.Writer = os.Stdout
+	if replayOut != "" {
+		f, err := os.Create(replayOut)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if replayCompare == "" {
+		return replay.Write(out, blocks)
+	}
+
+	participants, events, err = replay.Load(replayCompare, cacheSize)
+	if err != nil {
+		return fmt.Errorf("loading %s: %s", replayCompare, err)
+	}
+	other, err := replay.Replay(participants, events, logger)
+	if err != nil {
+		return fmt.Errorf("replaying %s: %s", replayCompare, err)
+	}
+
+	diff := replay.Compare(blocks, other)
+	if diff == nil {
+		fmt.Fprintf(out, "%d blocks, no divergence\n", len(blocks))
+		return nil
+	}
+	if err := diff.Write(out); err != nil {
+		return err
+	}
+	return fmt.Errorf("replays diverge at block %d", diff.Position)
+}
@@ -1,15 +1,20 @@
 package commands
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/SamuelMarks/dag1/src/dag1"
 	"github.com/SamuelMarks/dag1/src/dummy"
 	dag1_log "github.com/SamuelMarks/dag1/src/log"
+	"github.com/SamuelMarks/dag1/src/node"
 	aproxy "github.com/SamuelMarks/dag1/src/proxy"
 	"github.com/SamuelMarks/dag1/tester"
 	"github.com/sirupsen/logrus"
@@ -29,7 +34,6 @@ func NewRunCmd() *cobra.Command {
 }
 
 func runSingleDAG1(config *CLIConfig) error {
-	config.DAG1.Logger.Level = dag1.LogLevel(config.DAG1.LogLevel)
 	config.DAG1.NodeConfig.Logger = config.DAG1.Logger
 	if config.Log2file {
 		f, err := os.OpenFile(fmt.Sprintf("dag1_%v.log", config.DAG1.BindAddr),
@@ -50,33 +54,80 @@ func runSingleDAG1(config *CLIConfig) error {
 		}
 	}
 
-	dag1_log.NewLocal(config.DAG1.Logger, config.DAG1.LogLevel)
+	if err := dag1.SetLogLevels(config.DAG1.Logger, config.DAG1.LogLevel); err != nil {
+		return err
+	}
+	dag1_log.ThresholdExit = os.Exit
+	dag1_log.NewLocal(config.DAG1.Logger, config.DAG1.Logger.Level.String())
 
 	config.DAG1.Logger.WithFields(logrus.Fields{
-		"proxy-listen":   config.ProxyAddr,
-		"client-connect": config.ClientAddr,
-		"standalone":     config.Standalone,
-		"service-only":   config.DAG1.ServiceOnly,
-
-		"dag1.datadir":        config.DAG1.DataDir,
-		"dag1.bindaddr":       config.DAG1.BindAddr,
-		"dag1.service-listen": config.DAG1.ServiceAddr,
-		"dag1.maxpool":        config.DAG1.MaxPool,
-		"dag1.store":          config.DAG1.Store,
-		"dag1.loadpeers":      config.DAG1.LoadPeers,
-		"dag1.log":            config.DAG1.LogLevel,
-
-		"dag1.node.heartbeat":  config.DAG1.NodeConfig.HeartbeatTimeout,
-		"dag1.node.tcptimeout": config.DAG1.NodeConfig.TCPTimeout,
-		"dag1.node.cachesize":  config.DAG1.NodeConfig.CacheSize,
-		"dag1.node.synclimit":  config.DAG1.NodeConfig.SyncLimit,
+		"proxy-listen":    config.ProxyAddr,
+		"client-connect":  config.ClientAddr,
+		"proxy-allowlist": config.ProxyAllowlist,
+		"proxy-commits":   config.ProxyCommits,
+		"standalone":      config.Standalone,
+		"embedded-app":    config.EmbeddedApp,
+		"service-only":    config.DAG1.ServiceOnly,
+
+		"dag1.datadir":           config.DAG1.DataDir,
+		"dag1.bindaddr":          config.DAG1.BindAddr,
+		"dag1.service-listen":    config.DAG1.ServiceAddr,
+		"dag1.admin":             config.DAG1.Admin,
+		"dag1.service-auth":      config.DAG1.ServiceToken != "" || config.DAG1.ServiceTokenFile != "",
+		"dag1.service-rpc-cors":  config.DAG1.ServiceRPCCors,
+		"dag1.service-timeout":   config.DAG1.ServiceTimeout,
+		"dag1.service-debug":     config.DAG1.ServiceDebug,
+		"dag1.maxpool":           config.DAG1.MaxPool,
+		"dag1.min-protocol-version": config.DAG1.MinProtocolVersion,
+		"dag1.max-message-size":     config.DAG1.MaxMessageSize,
+		"dag1.store":                      config.DAG1.StoreKind(),
+		"dag1.store-path":                 config.DAG1.StorePath,
+		"dag1.tx-index":          config.DAG1.TxIndex,
+		"dag1.value-log-gc-interval":      config.DAG1.ValueLogGCInterval,
+		"dag1.value-log-gc-discard-ratio": config.DAG1.ValueLogGCDiscardRatio,
+		"dag1.loadpeers":         config.DAG1.LoadPeers,
+		"dag1.join":              config.DAG1.JoinAddr,
+		"dag1.join-trust":        config.DAG1.JoinTrust,
+		"dag1.force-peer-change": config.DAG1.ForcePeerChange,
+		"dag1.log":               config.DAG1.LogLevel,
+		"dag1.audit-log":         config.DAG1.AuditLog,
+		"dag1.flightrec-interval": config.DAG1.FlightRecInterval,
+
+		"dag1.node.heartbeat":       config.DAG1.NodeConfig.HeartbeatTimeout,
+		"dag1.node.heartbeat-max":   config.DAG1.NodeConfig.HeartbeatMax,
+		"dag1.node.tcptimeout":      config.DAG1.NodeConfig.TCPTimeout,
+		"dag1.node.cachesize":       config.DAG1.NodeConfig.CacheSize,
+		"dag1.node.synclimit":       config.DAG1.NodeConfig.SyncLimit,
+		"dag1.node.metrics":         config.DAG1.NodeConfig.Metrics,
+		"dag1.node.synctimebudget":  config.DAG1.NodeConfig.SyncTimeBudget,
+		"dag1.node.syncmaxbytes":    config.DAG1.NodeConfig.SyncMaxBytes,
+		"dag1.node.framepageevents": config.DAG1.NodeConfig.FramePageEvents,
 	}).Debug("RUN")
 
-	if !config.Standalone {
+	switch {
+	case config.EmbeddedApp == "kv":
+		// the app runs in process, its state is served at /kv/ and written
+		// through /tx
+		p, state := dummy.NewInmemKVDummyApp(config.DAG1.Logger)
+		config.DAG1.Proxy = p
+		config.DAG1.KV = state
+	case config.EmbeddedApp != "":
+		return fmt.Errorf("unknown embedded app %q, available: kv", config.EmbeddedApp)
+	case !config.Standalone:
+		var opts []aproxy.GrpcAppProxyOption
+		if config.ProxyAllowlist != "" {
+			allowlist, err := aproxy.LoadAllowlist(config.ProxyAllowlist)
+			if err != nil {
+				config.DAG1.Logger.Error("Cannot load the submitter allowlist:", err)
+				return nil
+			}
+			opts = append(opts, aproxy.WithSubmitterAllowlist(allowlist, config.ProxyCommits))
+		}
 		p, err := aproxy.NewGrpcAppProxy(
 			config.ProxyAddr,
 			config.DAG1.NodeConfig.HeartbeatTimeout,
 			config.DAG1.Logger,
+			opts...,
 		)
 
 		if err != nil {
@@ -84,45 +135,99 @@ func runSingleDAG1(config *CLIConfig) error {
 			return nil
 		}
 		config.DAG1.Proxy = p
-	} else {
+	default:
 		p := dummy.NewInmemDummyApp(config.DAG1.Logger)
 		config.DAG1.Proxy = p
 	}
 
-	engine := dag1.NewDAG1(&config.DAG1)
-
-	if err := engine.Init(); err != nil {
+	engine, err := dag1.NewEngine(dag1.WithConfig(&config.DAG1))
+	if err != nil {
 		config.DAG1.Logger.Error("Cannot initialize engine:", err)
 		return nil
 	}
 
 	if config.DAG1.Test {
-		p := engine.Peers
+		p := engine.Peers()
 		go func() {
 			for {
 				time.Sleep(10 * time.Second)
-				ct := engine.Node.GetConsensusTransactionsCount()
-				pdl := engine.Node.GetPendingLoadedEvents()
-				// 3 - number of notes in test; 10 - number of transactions sent at once
-				if ct >= 3*10*config.DAG1.TestN && pdl < 1 {
-					//engine.Node.PrintStat() // this is for debug tag only
+				ct := engine.Node().GetConsensusTransactionsCount()
+				pdl := engine.Node().GetPendingLoadedEvents()
+				// 3 - number of nodes in test, each sending TestN transactions
+				if ct >= 3*config.DAG1.TestN && pdl < 1 {
+					//engine.Node().PrintStat() // this is for debug tag only
 					time.Sleep(10 * time.Second)
-					engine.Node.Shutdown()
+					printRoundSummary(config)
+					engine.Stop()
 					break
 				}
 			}
 		}()
-		go tester.PingNodesN(p.Sorted, p.ByPubKey, config.DAG1.TestN,
-			config.DAG1.TestDelay, config.DAG1.Logger,
-			config.ProxyAddr)
+		go func() {
+			res, err := tester.PingNodesN(p.Sorted, p.ByPubKey, testConfig(config), config.DAG1.Logger)
+			if err != nil {
+				config.DAG1.Logger.Error("Test failed: ", err)
+				return
+			}
+			data, err := json.Marshal(res)
+			if err != nil {
+				config.DAG1.Logger.Error(err)
+				return
+			}
+			fmt.Println(string(data))
+		}()
 	}
 
-	engine.Node.Register()
-	engine.Run()
+	engine.Node().Register()
+	stopReload := reloadOnHangup(viper.GetViper(), engine.Node(), config.DAG1.Logger)
+	defer stopReload()
+	if err := engine.Start(context.Background()); err != nil {
+		return err
+	}
+	<-engine.Done()
 
 	return nil
 }
 
+// printRoundSummary prints the round latencies and the throughput of the
+// round report of the node
+func printRoundSummary(config *CLIConfig) {
+	if config.DAG1.ServiceAddr == "" {
+		config.DAG1.Logger.Warn("No service to fetch the round report from")
+		return
+	}
+	token := config.DAG1.ServiceToken
+	if config.DAG1.ServiceTokenFile != "" {
+		data, err := ioutil.ReadFile(config.DAG1.ServiceTokenFile)
+		if err != nil {
+			config.DAG1.Logger.Error("Reading the service token: ", err)
+			return
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	rounds, err := tester.FetchRoundReport(config.DAG1.ServiceAddr, token)
+	if err != nil {
+		config.DAG1.Logger.Error("Fetching the round report: ", err)
+		return
+	}
+	summary := node.SummarizeRounds(rounds)
+	fmt.Printf("Rounds: %d, transactions: %d, round latency p50: %.3fs, p95: %.3fs, throughput: %.1f tx/s\n",
+		summary.Rounds, summary.Transactions, summary.LatencyP50, summary.LatencyP95, summary.Throughput)
+}
+
+//testConfig returns the load --test sends
+func testConfig(config *CLIConfig) tester.Config {
+	conf := tester.DefaultConfig()
+	conf.PayloadSize = config.DAG1.TestPayload
+	conf.TPS = config.DAG1.TestTPS
+	conf.Count = config.DAG1.TestN
+	conf.Duration = config.DAG1.TestDuration
+	conf.Distribution = config.DAG1.TestDistribution
+	conf.WaitFinality = config.DAG1.TestFinality
+	conf.Delay = time.Duration(config.DAG1.TestDelay) * time.Second
+	return *conf
+}
+
 //AddRunFlags adds flags to the Run command
 func AddRunFlags(cmd *cobra.Command) {
 
@@ -130,7 +235,7 @@ func AddRunFlags(cmd *cobra.Command) {
 	config := NewDefaultCLIConfig()
 
 	cmd.Flags().String("datadir", config.DAG1.DataDir, "Top-level directory for configuration and data")
-	cmd.Flags().String("log", config.DAG1.LogLevel, "debug, info, warn, error, fatal, panic")
+	cmd.Flags().String("log", config.DAG1.LogLevel, "debug, info, warn, error, fatal, panic; per module e.g. \"info,poset=warn,node=debug\"")
 	cmd.Flags().Bool("log2file", config.Log2file, "duplicate log output into file dag1_<BindAddr>.log")
 	switch runtime.GOOS {
 	default:
@@ -145,43 +250,123 @@ func AddRunFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("listen", "l", config.DAG1.BindAddr, "Listen IP:Port for dag1 node")
 	cmd.Flags().DurationP("timeout", "t", config.DAG1.NodeConfig.TCPTimeout, "TCP Timeout")
 	cmd.Flags().Int("max-pool", config.DAG1.MaxPool, "Connection pool size max")
+	cmd.Flags().Uint32("min-protocol-version", config.DAG1.MinProtocolVersion, "Lowest peer protocol version accepted")
+	cmd.Flags().Int64("max-message-size", config.DAG1.MaxMessageSize, "Size of the largest peer message taken, negotiated down with each peer, 0 for no limit")
+	cmd.Flags().String("join", config.DAG1.JoinAddr, "IP:Port of a running peer to bootstrap from, its participants checked against peers.json")
+	cmd.Flags().Bool("join-trust", config.DAG1.JoinTrust, "Trust the participants of the join peer when there is no peers.json to check its anchor block against")
+	cmd.Flags().String("network-id", config.DAG1.NodeConfig.NetworkName, "Name of the chain, which with the genesis makes the network ID the peers and events must carry")
+	cmd.Flags().Bool("network-id-compat", config.DAG1.NodeConfig.NetworkIDCompat, "Accept peers and events without a network ID, from old clients, while the network migrates")
 
 	// Proxy
 	cmd.Flags().Bool("standalone", config.Standalone, "Do not create a proxy")
+	cmd.Flags().String("embedded-app", config.EmbeddedApp, "Run an app in process instead of connecting to one; available: kv")
 	cmd.Flags().Bool("service-only", config.DAG1.ServiceOnly, "Only host the http service")
 	cmd.Flags().StringP("proxy-listen", "p", config.ProxyAddr, "Listen IP:Port for dag1 proxy")
 	cmd.Flags().StringP("client-connect", "c", config.ClientAddr, "IP:Port to connect to client")
+	cmd.Flags().String("proxy-allowlist", config.ProxyAllowlist, "File of the public keys and sha256:<hash> of the tokens of the clients allowed to submit transactions, reloaded on SIGHUP or change")
+	cmd.Flags().String("proxy-commits", config.ProxyCommits, "Clients the blocks go to with a proxy allowlist; available: open, restricted")
 
 	// Service
-	cmd.Flags().StringP("service-listen", "s", config.DAG1.ServiceAddr, "Listen IP:Port for HTTP service")
+	cmd.Flags().StringP("service-listen", "s", config.DAG1.ServiceAddr, "Listen IP:Port, or unix:///path of a socket, for HTTP service")
+	cmd.Flags().Bool("admin", config.DAG1.Admin, "Serve the /admin/pause and /admin/resume endpoints to local clients")
+	cmd.Flags().String("service-token", config.DAG1.ServiceToken, "Bearer token the HTTP service requires on the requests which change the node")
+	cmd.Flags().String("service-token-file", config.DAG1.ServiceTokenFile, "File holding the bearer token of the HTTP service")
+	cmd.Flags().Bool("service-auth-reads", config.DAG1.ServiceAuthReads, "Require the bearer token on the read-only requests too")
+	cmd.Flags().String("service-rpc-cors", config.DAG1.ServiceRPCCors, "Comma separated origins, or *, of the web pages allowed to call the /rpc JSON-RPC endpoint")
+	cmd.Flags().Duration("service-timeout", config.DAG1.ServiceTimeout, "Time the HTTP service takes at most to answer a request, 0 for no bound")
+	cmd.Flags().Bool("service-debug", config.DAG1.ServiceDebug, "Serve the /debug/ endpoints of the HTTP service")
 
 	// Store
-	cmd.Flags().Bool("store", config.DAG1.Store, "Use badgerDB instead of in-mem DB")
+	cmd.Flags().String("store", config.DAG1.Store, "Store the poset is kept in: inmem, badger or sqlite, the latter in builds with the sqlite tag; --store alone is badger")
+	cmd.Flags().Lookup("store").NoOptDefVal = dag1.StoreBadger
+	cmd.Flags().String("store-path", config.DAG1.StorePath, "Directory of badgerDB or file of the sqlite database, under datadir when empty")
+	cmd.Flags().String("archive-dir", config.DAG1.ArchiveDir, "Directory where the events of the final frames are archived, read back when gone from badgerDB")
+	cmd.Flags().String("inmem-dump-on-exit", config.DAG1.InmemDumpOnExit, "File the in-mem store is dumped to on a graceful shutdown, to reload it in a test")
+	cmd.Flags().String("audit-log", config.DAG1.AuditLog, "File the consensus decisions are appended to as JSON lines, for an audit trail")
+	cmd.Flags().Int64("audit-log-max-size", config.DAG1.AuditLogMaxSize, "Size in bytes the audit log is rotated at")
+	cmd.Flags().Duration("flightrec-interval", config.DAG1.FlightRecInterval, "Time between the snapshots of the metrics kept in the flightrec directory of datadir, 0 for none")
+	cmd.Flags().Int64("flightrec-max-size", config.DAG1.FlightRecMaxSize, "Size in bytes of the flightrec directory, the oldest snapshots are deleted past it")
+	cmd.Flags().Bool("metrics", config.DAG1.NodeConfig.Metrics, "Serve the metrics of the node at /metrics")
+	cmd.Flags().Bool("tx-index", config.DAG1.TxIndex, "Index the transactions by the hash of their content, for /txlookup")
+	cmd.Flags().Duration("value-log-gc-interval", config.DAG1.ValueLogGCInterval, "Time between the value log GCs of badgerDB, 0 for none")
+	cmd.Flags().Float64("value-log-gc-discard-ratio", config.DAG1.ValueLogGCDiscardRatio, "Share of discarded space above which the value log GC rewrites a file of badgerDB")
 	cmd.Flags().Int("cache-size", config.DAG1.NodeConfig.CacheSize, "Number of items in LRU caches")
+	cmd.Flags().Int("cache-budget", config.DAG1.NodeConfig.CacheBudget, "Total number of items the poset dominator, round and timestamp caches may grow to when their hit rates fall, 0 for fixed sizes")
+	cmd.Flags().Float64("cache-grow-below", config.DAG1.NodeConfig.CacheGrowBelow, "Hit rate under which a poset cache grows within the cache budget")
+	cmd.Flags().Float64("cache-shrink-above", config.DAG1.NodeConfig.CacheShrinkAbove, "Hit rate a grown poset cache must keep to shrink back")
+	cmd.Flags().Bool("force-peer-change", config.DAG1.ForcePeerChange, "Start as an observer when the store was created for other participants than peers.json")
 
 	// Node configuration
 	cmd.Flags().Duration("heartbeat", config.DAG1.NodeConfig.HeartbeatTimeout, "Time between gossips")
+	cmd.Flags().Duration("heartbeat-max", config.DAG1.NodeConfig.HeartbeatMax, "Time between gossips while there is nothing to gossip about")
 	cmd.Flags().Int64("sync-limit", config.DAG1.NodeConfig.SyncLimit, "Max number of events for sync")
+	cmd.Flags().Duration("sync-time-budget", config.DAG1.NodeConfig.SyncTimeBudget, "Transfer time of the events asked for in a sync, at the bandwidth measured with the peer, 0 to ask for all of them")
+	cmd.Flags().Int64("sync-min-events", config.DAG1.NodeConfig.SyncMinEvents, "Least number of events asked for in a sync")
+	cmd.Flags().Int64("sync-max-events", config.DAG1.NodeConfig.SyncMaxEvents, "Max number of events asked for in a sync")
+	cmd.Flags().Int64("sync-max-bytes", config.DAG1.NodeConfig.SyncMaxBytes, "Max total size of the events of a sync response, 0 for no limit")
+	cmd.Flags().Int64("frame-page-events", config.DAG1.NodeConfig.FramePageEvents, "Number of events asked for per page of the frame of a fast forward")
+	cmd.Flags().Int("pause-queue", config.DAG1.NodeConfig.PauseQueueSize, "Max number of transactions queued while paused")
+	cmd.Flags().Int("ready-heartbeats", config.DAG1.NodeConfig.ReadyHeartbeats, "Number of heartbeats a ready node may go without syncing")
+	cmd.Flags().Duration("ready-round-window", config.DAG1.NodeConfig.ReadyRoundWindow, "Time a ready node may go without the consensus round advancing")
+	cmd.Flags().Int64("push-threshold", config.DAG1.NodeConfig.PushThreshold, "Number of events a pulled peer has to lack to be pushed them right away, 0 to never push")
+	cmd.Flags().Int64("flow-control-skew", config.DAG1.NodeConfig.FlowControlSkew, "Number of rounds the head of the node may run ahead of the consensus round before slowing its heartbeat, 0 to never slow it")
+	cmd.Flags().Int64("flow-control-max-factor", config.DAG1.NodeConfig.FlowControlMaxFactor, "Max number of times the flow control may slow the heartbeat")
+	cmd.Flags().Int64("undetermined-warn-age", config.DAG1.NodeConfig.UndeterminedWarnAge, "Number of rounds an event may stay undetermined before warning about the round blocking it, 0 to never warn")
+	cmd.Flags().Int64("undetermined-archive-age", config.DAG1.NodeConfig.UndeterminedArchiveAge, "Number of rounds after which an undetermined event is moved from memory to the store, 0 to keep them in memory")
+	cmd.Flags().Duration("stall-warn-timeout", config.DAG1.NodeConfig.StallWarnTimeout, "Time the consensus round may go without advancing before logging the consensus pipeline status, 0 to never log it")
+	cmd.Flags().Duration("tick-stall-timeout", config.DAG1.NodeConfig.TickStallTimeout, "Time the consensus round may go without advancing, while events wait for consensus, before creating an empty event, 0 to never create one")
+	cmd.Flags().Duration("tick-interval", config.DAG1.NodeConfig.TickInterval, "Least time between two empty events created for a stalled consensus")
+	cmd.Flags().Int("tx-pool-size", config.DAG1.NodeConfig.TxPoolSize, "Max number of transactions waiting to be put in events, 0 for no limit")
+	cmd.Flags().Int("tx-pool-bytes", config.DAG1.NodeConfig.TxPoolBytes, "Max total size of the transactions waiting to be put in events, 0 for no limit")
+	cmd.Flags().String("tx-pool-policy", config.DAG1.NodeConfig.TxPoolPolicy, "What a full transaction pool does with a new transaction; available: reject-new,evict-oldest")
+	cmd.Flags().Int("max-events-per-frame", config.DAG1.NodeConfig.MaxEventsPerFrame, "Max number of events a participant may create per frame, the same on every node, 0 for no limit")
+	cmd.Flags().Int("max-block-transactions", config.DAG1.NodeConfig.MaxBlockTransactions, "Max number of transactions in a block, the rest carrying over into the next one, the same on every node, 0 for no limit")
+	cmd.Flags().Int("max-block-bytes", config.DAG1.NodeConfig.MaxBlockBytes, "Max total bytes of the transactions in a block, the rest carrying over into the next one, the same on every node, 0 for no limit")
+	cmd.Flags().Duration("max-timestamp-drift", config.DAG1.NodeConfig.MaxTimestampDrift, "Max time an event may claim to be made after its other-parent, the later claims counting as made then, and the blocks taking the median time of their events, the same on every node, 0 for no limit and blocks taking the local time")
+	cmd.Flags().Bool("dev-single-supermajority", config.DAG1.NodeConfig.DevSingleSuperMajority, "UNSAFE, for local development networks only: make any single participant a supermajority, so that a node decides alone, the same on every node")
+	cmd.Flags().String("consensus-params-file", config.DAG1.NodeConfig.ConsensusParamsFile, "JSON file of the consensus parameters, the same on every node, overriding the flags setting them")
+	cmd.Flags().String("other-parent-selector", config.DAG1.NodeConfig.OtherParentSelector, "Strategy choosing the other-parent of the events of the node; available: last-sync,most-starved,random-known")
+	cmd.Flags().Bool("require-quorum", config.DAG1.NodeConfig.RequireQuorum, "Create no events with transactions, and refuse those of the service, until synced with the peers making a supermajority")
+	cmd.Flags().Int("commit-batch", config.DAG1.NodeConfig.CommitBatchSize, "Max number of queued blocks committed to the app in a single round trip")
 
 	// Test
 	cmd.Flags().Bool("test", config.DAG1.Test, "Enable testing (sends transactions to random nodes in the network)")
 	cmd.Flags().Uint64("test_n", config.DAG1.TestN, "Number of transactions to send")
 	cmd.Flags().Uint64("test_delay", config.DAG1.TestDelay, "Number of second to delay before sending transactions")
+	cmd.Flags().Int("test_payload", config.DAG1.TestPayload, "Size of the test transactions in bytes")
+	cmd.Flags().Float64("test_tps", config.DAG1.TestTPS, "Target number of test transactions per second, 0 for as fast as possible")
+	cmd.Flags().Duration("test_duration", config.DAG1.TestDuration, "How long to send test transactions, 0 for no limit")
+	cmd.Flags().String("test_distribution", config.DAG1.TestDistribution, "How to spread the test transactions across the nodes; available: round-robin,random,single")
+	cmd.Flags().Bool("test_finality", config.DAG1.TestFinality, "Wait for the blocks of the test transactions and report their latency percentiles")
 	cmd.Flags().String("peer_selector", config.DAG1.PeerSelector, "Peer selector to user for the next peer; available: random,smart,fair,unfair,franky")
 }
 
-//Bind all flags and read the config into viper
-func bindFlagsLoadViper(cmd *cobra.Command, config *CLIConfig) error {
+//envPrefix prefixes the environment variables setting a flag, e.g.
+//DAG1_SERVICE_LISTEN for --service-listen
+const envPrefix = "DAG1"
+
+//Bind all flags and read the config into viper. A value is taken from, in
+//order of precedence: the flag, the DAG1_ environment variable, the
+//dag1.toml (or .yaml, .json) config file, the default.
+func bindFlagsLoadViper(v *viper.Viper, cmd *cobra.Command, config *CLIConfig) error {
 	// cmd.Flags() includes flags from this command and all persistent flags from the parent
-	if err := viper.BindPFlags(cmd.Flags()); err != nil {
+	if err := v.BindPFlags(cmd.Flags()); err != nil {
 		return err
 	}
-	viper.SetConfigName("dag1")              // name of config file (without extension)
-	viper.AddConfigPath(config.DAG1.DataDir) // search root directory
-	// viper.AddConfigPath(filepath.Join(config.DAG1.DataDir, "dag1")) // search root directory /config
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+
+	// the config file is searched for in the data directory, as set by the
+	// flag or the environment
+	if dataDir := v.GetString("datadir"); dataDir != "" {
+		config.DAG1.DataDir = dataDir
+	}
+	v.SetConfigName("dag1")              // name of config file (without extension)
+	v.AddConfigPath(config.DAG1.DataDir) // search root directory
 	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		config.DAG1.Logger.Debugf("Using config file: %s", viper.ConfigFileUsed())
+	if err := v.ReadInConfig(); err == nil {
+		config.DAG1.Logger.Debugf("Using config file: %s", v.ConfigFileUsed())
 	} else if _, ok := err.(viper.ConfigFileNotFoundError); ok {
 		config.DAG1.Logger.Debugf("No config file found in: %s", config.DAG1.DataDir)
 	} else {
@@ -189,3 +374,20 @@ func bindFlagsLoadViper(cmd *cobra.Command, config *CLIConfig) error {
 	}
 	return nil
 }
+
+//loadConfig resolves the configuration of the run command from its flags,
+//the environment and the config file. It is not validated.
+func loadConfig(v *viper.Viper, cmd *cobra.Command) (*CLIConfig, error) {
+	config := NewDefaultCLIConfig()
+	if err := bindFlagsLoadViper(v, cmd, config); err != nil {
+		return nil, err
+	}
+	if err := v.Unmarshal(config); err != nil {
+		return nil, err
+	}
+	config.Normalize()
+	if err := config.DAG1.NodeConfig.LoadConsensusParams(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
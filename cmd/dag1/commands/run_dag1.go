@@ -13,14 +13,11 @@ import (
 
 func runDAG1(cmd *cobra.Command, args []string) error {
 
-	config := NewDefaultCLIConfig()
-
-	if err := bindFlagsLoadViper(cmd, config); err != nil {
+	config, err := loadConfig(viper.GetViper(), cmd)
+	if err != nil {
 		return err
 	}
-
-	err := viper.Unmarshal(config)
-	if err != nil {
+	if err := config.Validate(); err != nil {
 		return err
 	}
 
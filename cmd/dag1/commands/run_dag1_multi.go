@@ -31,16 +31,14 @@ func runDAG1(cmd *cobra.Command, args []string) error {
 
 	for i := uint(0); i < n; i++ {
 
-		configs[i] = NewDefaultCLIConfig()
-
-		if err := bindFlagsLoadViper(cmd, configs[i]); err != nil {
+		config, err := loadConfig(viper.GetViper(), cmd)
+		if err != nil {
 			return err
 		}
-
-		err := viper.Unmarshal(configs[i])
-		if err != nil {
+		if err := config.Validate(); err != nil {
 			return err
 		}
+		configs[i] = config
 
 		configs[i].DAG1.BindAddr = fmt.Sprintf("127.0.0.1:%d", 12000+i+1)
 		configs[i].DAG1.ServiceAddr = fmt.Sprintf("127.0.0.1:%d", 8000+i+1)
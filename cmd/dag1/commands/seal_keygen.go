@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SamuelMarks/dag1/src/proxy/sealed"
+)
+
+var (
+	sealKeyDir        string
+	sealKeyRotate     bool
+	defaultSealKeyDir = filepath.Join(config.DAG1.DataDir, "seal")
+)
+
+// NewSealKeygenCmd produces a SealKeygenCmd which creates the key pair the
+// app transactions are sealed to
+func NewSealKeygenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "seal-keygen",
+		Short: "Create the key pair the app transactions are sealed to",
+		Long: `Create the key pair the app transactions are sealed to, so that the
+nodes order them without reading them. The apps consuming the blocks open
+them with the private keys of the dir, the submitting apps seal them to its
+public key.`,
+		RunE: sealKeygen,
+	}
+	AddSealKeygenFlags(cmd)
+	return cmd
+}
+
+// AddSealKeygenFlags adds flags to the seal-keygen command
+func AddSealKeygenFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&sealKeyDir, "dir", defaultSealKeyDir, "Dir where the keys will be written")
+	cmd.Flags().BoolVar(&sealKeyRotate, "rotate", false, "Replace the current key of the dir, keeping the old ones to open the transactions sealed to them")
+}
+
+func sealKeygen(cmd *cobra.Command, args []string) error {
+	keys, err := sealed.LoadKeyring(sealKeyDir)
+	if err != nil && err != sealed.ErrNoKeys {
+		return err
+	}
+	if err == nil && !sealKeyRotate {
+		return fmt.Errorf("a sealing key already lives under %s, --rotate to replace it", sealKeyDir)
+	}
+
+	key, err := sealed.NewKey(sealKeyDir)
+	if err != nil {
+		return fmt.Errorf("writing sealing key: %s", err)
+	}
+	fmt.Printf("Your sealing key %s has been saved to: %s\n", sealed.KeyIDOf(&key.PublicKey), sealKeyDir)
+	if keys != nil {
+		fmt.Printf("It replaces %s, kept with %d older keys\n", sealed.KeyIDOf(keys.Current()), keys.Len()-1)
+	}
+	fmt.Printf("Seal the transactions to the public key in: %s\n", filepath.Join(sealKeyDir, sealed.PublicKeyFile))
+	return nil
+}
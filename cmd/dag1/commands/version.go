@@ -2,16 +2,71 @@ package commands
 
 import (
 	"fmt"
+	"io"
 
-	"github.com/SamuelMarks/dag1/src/version"
 	"github.com/spf13/cobra"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/dag1"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/version"
 )
 
 // VersionCmd displays the version of dag1 being used
-var VersionCmd = &cobra.Command{
-	Use:   "version",
-	Short: "Show version info",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(version.Version)
-	},
+var VersionCmd = NewVersionCmd()
+
+// NewVersionCmd produces a VersionCmd which shows the build info, and the
+// network ID of a data directory when given the name of its chain
+func NewVersionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show version info",
+		Args:  cobra.NoArgs,
+		RunE:  runVersion,
+	}
+	AddVersionFlags(cmd)
+	return cmd
+}
+
+// AddVersionFlags adds flags to the version command
+func AddVersionFlags(cmd *cobra.Command) {
+	cmd.Flags().String("datadir", config.DAG1.DataDir, "Top-level directory for configuration and data")
+	cmd.Flags().String("network-id", config.DAG1.NodeConfig.NetworkName, "Name of the chain, to show the network ID derived from it and the genesis of peers.json")
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	w := cmd.OutOrStdout()
+	writeVersion(w, version.Get())
+
+	name, _ := cmd.Flags().GetString("network-id")
+	if name == "" {
+		return nil
+	}
+	datadir, _ := cmd.Flags().GetString("datadir")
+	participants, err := peers.NewJSONPeers(datadir).GetPeersFromMessages()
+	if err != nil {
+		return err
+	}
+	id, err := dag1.NetworkID(participants, &config.DAG1.PoSConfig, name)
+	if err != nil {
+		return err
+	}
+	writeNetworkID(w, name, id)
+	return nil
+}
+
+func writeVersion(w io.Writer, info version.Info) {
+	fmt.Fprintln(w, info.Version)
+	if info.GitCommit != "" {
+		fmt.Fprintln(w, "git commit:", info.GitCommit)
+	}
+	if info.BuildDate != "" {
+		fmt.Fprintln(w, "build date:", info.BuildDate)
+	}
+	fmt.Fprintln(w, "go version:", info.GoVersion)
+}
+
+func writeNetworkID(w io.Writer, name string, id common.Hash) {
+	fmt.Fprintln(w, "network:", name)
+	fmt.Fprintln(w, "network id:", id.Hex())
 }
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/dag1"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/version"
+)
+
+// runVersionCmd runs the version command with args and returns its output
+func runVersionCmd(t *testing.T, args ...string) string {
+	cmd := NewVersionCmd()
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestVersionNetworkID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dag1-version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	participants := peers.NewPeers()
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateECDSAKey()
+		participants.AddPeer(peers.NewPeer(
+			fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)),
+			fmt.Sprintf("127.0.0.1:%d", 12000+i)))
+	}
+	if err := peers.NewJSONPeers(dir).SetPeerMessages(participants.ToPeerSlice()); err != nil {
+		t.Fatal(err)
+	}
+
+	out := runVersionCmd(t, "--datadir", dir)
+	if !strings.HasPrefix(out, version.Get().Version+"\n") {
+		t.Fatalf("expected the version first, got %q", out)
+	}
+	if strings.Contains(out, "network") {
+		t.Fatalf("expected no network ID without a chain name, got %q", out)
+	}
+
+	expected, err := dag1.NetworkID(participants, &config.DAG1.PoSConfig, "testnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = runVersionCmd(t, "--datadir", dir, "--network-id", "testnet")
+	if !strings.Contains(out, "network: testnet\n") ||
+		!strings.Contains(out, "network id: "+expected.Hex()+"\n") {
+		t.Fatalf("expected the network ID %s of testnet, got %q", expected.Hex(), out)
+	}
+
+	other := runVersionCmd(t, "--datadir", dir, "--network-id", "devnet")
+	if strings.Contains(other, expected.Hex()) {
+		t.Fatalf("expected another network ID for devnet, got %q", other)
+	}
+}
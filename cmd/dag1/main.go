@@ -13,7 +13,13 @@ func main() {
 	rootCmd.AddCommand(
 		cmd.VersionCmd,
 		cmd.NewKeygenCmd(),
-		cmd.NewRunCmd())
+		cmd.NewSealKeygenCmd(),
+		cmd.NewRunCmd(),
+		cmd.NewConfigCmd(),
+		cmd.NewReplayCmd(),
+		cmd.NewInspectCmd(),
+		cmd.NewCheckCmd(),
+		cmd.NewFastForwardCmd())
 
 	//Do not print usage when error occurs
 	rootCmd.SilenceUsage = true
@@ -28,7 +28,8 @@ func main() {
 	rootCmd.AddCommand(
 		cmd.VersionCmd,
 		cmd.NewKeygenCmd(),
-		cmd.NewRunCmd())
+		cmd.NewRunCmd(),
+		cmd.NewReplayCmd())
 
 	//Do not print usage when error occurs
 	rootCmd.SilenceUsage = true
@@ -7,6 +7,7 @@ type CLIConfig struct {
 	ProxyAddr  string `mapstructure:"proxy-connect"`
 	Discard    bool   `mapstructure:"discard"`
 	LogLevel   string `mapstructure:"log"`
+	KVAddr     string `mapstructure:"kv-listen"`
 }
 
 //NewDefaultCLIConfig creates a CLIConfig with default values
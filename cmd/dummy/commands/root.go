@@ -3,10 +3,13 @@ package commands
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 
 	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/proxy"
 	"github.com/rifflock/lfshook"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -24,6 +27,7 @@ func init() {
 	RootCmd.Flags().String("proxy-connect", config.ProxyAddr, "IP:Port to connect to DAG1 proxy")
 	RootCmd.Flags().Bool("discard", config.Discard, "discard output to stderr and stdout")
 	RootCmd.Flags().String("log", config.LogLevel, "debug, info, warn, error, fatal, panic")
+	RootCmd.Flags().String("kv-listen", config.KVAddr, "IP:Port to serve the key/value state on, enables set:key=value and del:key transactions")
 }
 
 //RootCmd is the root command for Dummy
@@ -42,7 +46,13 @@ func runDummy(cmd *cobra.Command, args []string) error {
 	name := config.Name
 	address := config.ProxyAddr
 	//Create and run Dummy Socket Client
-	client, err := dummy.NewDummySocketClient(address, logger)
+	var handler proxy.ProxyHandler
+	if config.KVAddr != "" {
+		state := dummy.NewKVState(logger)
+		go serveKV(config.KVAddr, state)
+		handler = state
+	}
+	client, err := dummy.NewDummySocketClientWithHandler(address, handler, logger)
 	if err != nil {
 		return err
 	}
@@ -59,6 +69,24 @@ func runDummy(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// serveKV answers GET /kv/<key> with the value of key
+func serveKV(addr string, state *dummy.KVState) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/kv/", func(w http.ResponseWriter, r *http.Request) {
+		value, ok := state.Query(r.URL.Path[len("/kv/"):])
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if _, err := io.WriteString(w, value); err != nil {
+			logger.Debug(err)
+		}
+	})
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logger.WithField("error", err).Error("KV listener failed")
+	}
+}
+
 /*******************************************************************************
 * CONFIG
 *******************************************************************************/
@@ -80,6 +108,7 @@ func loadConfig(cmd *cobra.Command, args []string) error {
 		"proxy-connect": config.ProxyAddr,
 		"discard":       config.Discard,
 		"log":           config.LogLevel,
+		"kv-listen":     config.KVAddr,
 	}).Debug("RUN")
 	return nil
 }
@@ -1,6 +1,10 @@
 package commands
 
-import "github.com/SamuelMarks/dag1/src/dag1"
+import (
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/dag1"
+)
 
 //CLIConfig contains configuration for the Run command
 type CLIConfig struct {
@@ -9,6 +13,18 @@ type CLIConfig struct {
 	SendTxs  int                     `mapstructure:"send-txs"`
 	Stdin    bool                    `mapstructure:"stdin"`
 	Node     int                     `mapstructure:"node"`
+	Addr     string                  `mapstructure:"addr"`
+	File     string                  `mapstructure:"file"`
+	Rate     float64                 `mapstructure:"rate"`
+	Listen   bool                    `mapstructure:"listen"`
+	JSON     bool                    `mapstructure:"json"`
+	// Exec runs the nodes as dag1 processes instead of in process
+	Exec        bool          `mapstructure:"exec"`
+	Duration    time.Duration `mapstructure:"duration"`
+	UntilBlocks int64         `mapstructure:"until-blocks"`
+	// Scenario is a YAML or JSON file of the simulated network between the
+	// nodes run in process, see peer.Scenario
+	Scenario string `mapstructure:"scenario"`
 }
 
 //NewDefaultCLIConfig creates a CLIConfig with default values
@@ -0,0 +1,412 @@
+package commands
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/dag1"
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy"
+)
+
+// network is a set of DAG1 engines running in this process, each with its
+// own datadir, log file and dummy app. sim is the simulated network between
+// them, nil for none.
+type network struct {
+	dir     string
+	nodes   []*networkNode
+	sim     *peer.SimNetwork
+	stopSim func()
+}
+
+type networkNode struct {
+	engine *dag1.DAG1
+	proxy  *proxy.InmemAppProxy
+	state  *dummy.State
+	log    *os.File
+}
+
+// NodeReport is what a node of the network committed
+type NodeReport struct {
+	Node      int    `json:"node"`
+	ID        uint64 `json:"id"`
+	Blocks    int64  `json:"blocks"`
+	Txs       int    `json:"txs"`
+	LastBlock string `json:"last_block"`
+}
+
+// NetworkReport is the report of a run of the network. The blocks all the
+// nodes committed are compared: Divergence is the first index at which two
+// nodes committed different blocks, -1 when they agree. Sim counts the
+// messages of the simulated network, when one was run.
+type NetworkReport struct {
+	Nodes        []NodeReport   `json:"nodes"`
+	CommonBlocks int64          `json:"common_blocks"`
+	Divergence   int64          `json:"divergence"`
+	Sim          *peer.SimStats `json:"sim,omitempty"`
+}
+
+// Agree returns true when no two nodes committed different blocks
+func (r NetworkReport) Agree() bool {
+	return r.Divergence < 0
+}
+
+func (r NetworkReport) String() string {
+	s := ""
+	for _, n := range r.Nodes {
+		s += fmt.Sprintf("node %d (%d): %d blocks, %d txs, last block %s\n",
+			n.Node, n.ID, n.Blocks, n.Txs, n.LastBlock)
+	}
+	if r.Sim != nil {
+		s += fmt.Sprintf("simulated network: %d messages delivered, %d lost, %d cut off\n",
+			r.Sim.Delivered, r.Sim.Dropped, r.Sim.Partitioned)
+	}
+	switch {
+	case !r.Agree():
+		s += fmt.Sprintf("DIVERGED at block %d\n", r.Divergence)
+	case r.CommonBlocks == 0:
+		s += "no block committed by all the nodes to compare\n"
+	default:
+		s += fmt.Sprintf("agreed on %d blocks\n", r.CommonBlocks)
+	}
+	return s
+}
+
+// newNetwork creates the engines of n nodes under a new directory in parent,
+// with stores of the kind store, over the simulated network of the scenario
+// file when one is given
+func newNetwork(parent string, n int, store string, base *dag1.DAG1Config,
+	scenario string) (*network, error) {
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir(parent, "network")
+	if err != nil {
+		return nil, err
+	}
+	nw := &network{dir: dir}
+
+	adds, err := freeAddrs(n)
+	if err != nil {
+		nw.close()
+		return nil, err
+	}
+	if scenario != "" {
+		s, err := loadScenario(scenario, adds)
+		if err != nil {
+			nw.close()
+			return nil, err
+		}
+		nw.sim = peer.NewSimNetwork(s)
+	}
+	keys := make([]*ecdsa.PrivateKey, n)
+	participants := peers.NewPeers()
+	for i := range keys {
+		if keys[i], err = crypto.GenerateECDSAKey(); err != nil {
+			nw.close()
+			return nil, err
+		}
+		peer := peers.NewPeer(fmt.Sprintf("0x%X", crypto.FromECDSAPub(&keys[i].PublicKey)), adds[i])
+		participants.AddPeer(peer)
+		participants.SetPeerWeight(peer, 1)
+	}
+
+	for i := 0; i < n; i++ {
+		node, err := newNetworkNode(filepath.Join(dir, "node"+strconv.Itoa(i)),
+			adds[i], keys[i], participants, store, base, nw.sim)
+		if err != nil {
+			nw.close()
+			return nil, err
+		}
+		nw.nodes = append(nw.nodes, node)
+	}
+	return nw, nil
+}
+
+func newNetworkNode(dir, addr string, key *ecdsa.PrivateKey, participants *peers.Peers,
+	store string, base *dag1.DAG1Config, sim *peer.SimNetwork) (*networkNode, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(filepath.Join(dir, "dag1.log"))
+	if err != nil {
+		return nil, err
+	}
+	logger := logrus.New()
+	logger.Out = f
+	if err := dag1.SetLogLevels(logger, base.LogLevel); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	config := dag1.NewDefaultConfig()
+	config.DataDir = dir
+	config.BindAddr = addr
+	config.ServiceAddr = ""
+	config.Store = store
+	config.LogLevel = base.LogLevel
+	config.Logger = logger
+	config.NodeConfig.Logger = logger
+	config.NodeConfig.HeartbeatTimeout = base.NodeConfig.HeartbeatTimeout
+	config.NodeConfig.SyncLimit = base.NodeConfig.SyncLimit
+	config.LoadPeers = false
+	config.Key = key
+	if sim != nil {
+		config.WrapTransport = func(trans peer.SyncPeer) peer.SyncPeer {
+			return sim.Wrap(addr, trans)
+		}
+	}
+
+	state := dummy.NewState(logger)
+	p := proxy.NewInmemAppProxy(state, logger)
+	config.Proxy = p
+
+	engine := dag1.NewDAG1(config)
+	engine.Peers = participants
+	return &networkNode{engine: engine, proxy: p, state: state, log: f}, nil
+}
+
+// loadScenario reads the simulated network of the nodes from a YAML or JSON
+// file. Its links and partitions name the nodes by their index, which is
+// replaced by their address.
+func loadScenario(path string, adds []string) (peer.Scenario, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return peer.Scenario{}, err
+	}
+	var s peer.Scenario
+	if err := v.Unmarshal(&s); err != nil {
+		return peer.Scenario{}, err
+	}
+
+	var bad error
+	address := func(node string) string {
+		if node == "" {
+			return ""
+		}
+		i, err := strconv.Atoi(node)
+		if err != nil || i < 0 || i >= len(adds) {
+			bad = fmt.Errorf("scenario %s: no node %q among %d", path, node, len(adds))
+			return node
+		}
+		return adds[i]
+	}
+	for i := range s.Links {
+		s.Links[i].From = address(s.Links[i].From)
+		s.Links[i].To = address(s.Links[i].To)
+	}
+	for _, p := range s.Partitions {
+		for _, group := range p.Groups {
+			for i := range group {
+				group[i] = address(group[i])
+			}
+		}
+	}
+	return s, bad
+}
+
+// freeAddrs returns n loopback addresses nothing listens on
+func freeAddrs(n int) ([]string, error) {
+	adds := make([]string, n)
+	for i := range adds {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+		defer l.Close()
+		adds[i] = l.Addr().String()
+	}
+	return adds, nil
+}
+
+// start initialises and runs the engines
+func (n *network) start() error {
+	for i, node := range n.nodes {
+		if err := node.engine.Init(); err != nil {
+			return fmt.Errorf("node %d: %s", i, err)
+		}
+	}
+	for _, node := range n.nodes {
+		go node.engine.Run()
+	}
+	if n.sim != nil {
+		n.stopSim = n.sim.Start()
+	}
+	return nil
+}
+
+// sendTxs submits txs transactions to each node, one a second, until stop is
+// closed
+func (n *network) sendTxs(txs int, stop <-chan struct{}) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	for i, node := range n.nodes {
+		wg.Add(1)
+		go func(i int, p *proxy.InmemAppProxy) {
+			defer wg.Done()
+			ticker := time.NewTicker(1 * time.Second)
+			defer ticker.Stop()
+			for txNb := 0; txNb < txs; txNb++ {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+				p.SubmitTx([]byte(strconv.Itoa(i) + "_" + strconv.Itoa(txNb)))
+			}
+		}(i, node.proxy)
+	}
+	return wg
+}
+
+// wait returns once duration passed, or every node committed blocks blocks,
+// or stop is closed. A zero duration or blocks is no limit.
+func (n *network) wait(duration time.Duration, blocks int64, stop <-chan struct{}) {
+	var deadline <-chan time.Time
+	if duration > 0 {
+		deadline = time.After(duration)
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-deadline:
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+			if blocks > 0 && n.minBlocks() >= blocks {
+				return
+			}
+		}
+	}
+}
+
+func (n *network) minBlocks() int64 {
+	min := int64(-1)
+	for _, node := range n.nodes {
+		if blocks := node.engine.Node.GetLastBlockIndex() + 1; min < 0 || blocks < min {
+			min = blocks
+		}
+	}
+	return min
+}
+
+// report compares the blocks of the nodes. It reads the stores, so it is
+// made before the nodes shut down.
+func (n *network) report() (NetworkReport, error) {
+	r := NetworkReport{Divergence: -1, CommonBlocks: n.minBlocks()}
+	for i, node := range n.nodes {
+		nr := NodeReport{
+			Node:   i,
+			ID:     node.engine.Node.ID(),
+			Blocks: node.engine.Node.GetLastBlockIndex() + 1,
+			Txs:    len(node.state.GetCommittedTransactions()),
+		}
+		if nr.Blocks > 0 {
+			block, err := node.engine.Node.GetBlock(nr.Blocks - 1)
+			if err != nil {
+				return r, err
+			}
+			if nr.LastBlock, err = bodyHex(block); err != nil {
+				return r, err
+			}
+		}
+		r.Nodes = append(r.Nodes, nr)
+	}
+	if n.sim != nil {
+		stats := n.sim.Stats()
+		r.Sim = &stats
+	}
+	for idx := int64(0); idx < r.CommonBlocks && r.Agree(); idx++ {
+		var hash string
+		for i, node := range n.nodes {
+			block, err := node.engine.Node.GetBlock(idx)
+			if err != nil {
+				return r, err
+			}
+			h, err := bodyHex(block)
+			if err != nil {
+				return r, err
+			}
+			if i == 0 {
+				hash = h
+			} else if h != hash {
+				r.Divergence = idx
+				break
+			}
+		}
+	}
+	return r, nil
+}
+
+// bodyHex returns the hash of the body of a block, which every node commits
+// the same, unlike the signatures and creation time
+func bodyHex(block poset.Block) (string, error) {
+	hash, err := block.Body.Hash()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("0x%X", hash), nil
+}
+
+// shutdown stops the engines which were started
+func (n *network) shutdown() {
+	if n.stopSim != nil {
+		n.stopSim()
+	}
+	for _, node := range n.nodes {
+		if node.engine.Node != nil {
+			node.engine.Node.Shutdown()
+		}
+	}
+	n.close()
+}
+
+func (n *network) close() {
+	for _, node := range n.nodes {
+		node.log.Close()
+	}
+}
+
+// runNetwork runs the nodes of config in process and reports what they
+// committed
+func runNetwork(config *CLIConfig, out io.Writer, stop <-chan struct{}) (NetworkReport, error) {
+	nw, err := newNetwork(config.DAG1.DataDir, config.NbNodes, config.DAG1.StoreKind(), &config.DAG1,
+		config.Scenario)
+	if err != nil {
+		return NetworkReport{}, err
+	}
+	defer nw.shutdown()
+	fmt.Fprintln(out, "Running", config.NbNodes, "nodes in", nw.dir)
+	if err := nw.start(); err != nil {
+		return NetworkReport{}, err
+	}
+
+	done := make(chan struct{})
+	var txs *sync.WaitGroup
+	if config.SendTxs > 0 {
+		txs = nw.sendTxs(config.SendTxs, done)
+	}
+	nw.wait(config.Duration, config.UntilBlocks, stop)
+	close(done)
+	if txs != nil {
+		txs.Wait()
+	}
+	return nw.report()
+}
@@ -0,0 +1,46 @@
+// +build sqlite
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/dag1"
+)
+
+func TestRunNetworkSQLite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewDefaultCLIConfig()
+	conf.NbNodes = 3
+	conf.SendTxs = 2
+	conf.Duration = 3 * time.Second
+	conf.DAG1.DataDir = dir
+	conf.DAG1.Store = dag1.StoreSQLite
+	conf.DAG1.NodeConfig.HeartbeatTimeout = 10 * time.Millisecond
+
+	report, err := runNetwork(conf, ioutil.Discard, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(report)
+	if !report.Agree() {
+		t.Fatalf("expected the nodes to agree, diverged at block %d", report.Divergence)
+	}
+
+	dbs, err := filepath.Glob(filepath.Join(dir, "network*", "node*", "dag1.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dbs) != conf.NbNodes {
+		t.Fatalf("expected a sqlite database per node, got %v", dbs)
+	}
+}
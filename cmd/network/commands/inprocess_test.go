@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunNetwork(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewDefaultCLIConfig()
+	conf.NbNodes = 3
+	conf.SendTxs = 2
+	conf.Duration = 3 * time.Second
+	conf.DAG1.DataDir = dir
+	conf.DAG1.NodeConfig.HeartbeatTimeout = 10 * time.Millisecond
+
+	report, err := runNetwork(conf, ioutil.Discard, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(report)
+	if len(report.Nodes) != conf.NbNodes {
+		t.Fatalf("expected a report of %d nodes, got %d", conf.NbNodes, len(report.Nodes))
+	}
+	if !report.Agree() {
+		t.Fatalf("expected the nodes to agree, diverged at block %d", report.Divergence)
+	}
+
+	logs, err := filepath.Glob(filepath.Join(dir, "network*", "node*", "dag1.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != conf.NbNodes {
+		t.Fatalf("expected a log file per node, got %v", logs)
+	}
+}
+
+func TestRunNetworkScenario(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the last node is far from the others, and cut off for a while
+	scenario := filepath.Join(dir, "scenario.yaml")
+	if err := ioutil.WriteFile(scenario, []byte(`
+seed: 1
+links:
+  - from: 2
+    latency: 50ms
+    jitter: 10ms
+  - to: 2
+    latency: 50ms
+partitions:
+  - at: 1s
+    duration: 1s
+    groups: [[0, 1], [2]]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewDefaultCLIConfig()
+	conf.NbNodes = 3
+	conf.SendTxs = 2
+	conf.Duration = 4 * time.Second
+	conf.DAG1.DataDir = dir
+	conf.DAG1.NodeConfig.HeartbeatTimeout = 10 * time.Millisecond
+	conf.Scenario = scenario
+
+	report, err := runNetwork(conf, ioutil.Discard, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(report)
+	if !report.Agree() {
+		t.Fatalf("expected the nodes to agree, diverged at block %d", report.Divergence)
+	}
+	if report.Sim == nil || report.Sim.Delivered == 0 || report.Sim.Partitioned == 0 {
+		t.Fatalf("expected the messages over the simulated network counted, got %+v", report.Sim)
+	}
+
+	if _, err := loadScenario(scenario, []string{"a", "b"}); err == nil {
+		t.Fatal("expected a scenario of a missing node refused")
+	}
+}
@@ -2,21 +2,31 @@ package commands
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/dummy"
 	"github.com/SamuelMarks/dag1/src/poset"
 	"github.com/SamuelMarks/dag1/src/proxy"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+// maxTxSize is the longest line --stdin and --file submit as a transaction
+const maxTxSize = 1 << 20
+
 var tx string
 
-// NewProxyCmd displays the version of babble being used
+// NewProxyCmd connects to the app proxy of a node to submit transactions
+// and watch them commit
 func NewProxyCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "proxy",
@@ -32,15 +42,35 @@ func NewProxyCmd() *cobra.Command {
 // Handler struct
 type Handler struct {
 	stateHash []byte
+
+	mu     sync.Mutex
+	out    io.Writer
+	asJSON bool
+}
+
+// committedTx is a committed transaction as printed with --json
+type committedTx struct {
+	Block int64  `json:"block"`
+	Tx    string `json:"tx"`
 }
 
 // CommitHandler Called when a new block is coming
 // You must provide a method to compute the stateHash incrementally with incoming blocks
 func (h *Handler) CommitHandler(block poset.Block) (stateHash []byte, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	hash := h.stateHash
 
 	for _, tx := range block.Transactions() {
-		fmt.Println(string(tx))
+		if h.asJSON {
+			err = json.NewEncoder(h.out).Encode(committedTx{Block: block.Index(), Tx: string(tx)})
+		} else {
+			_, err = fmt.Fprintf(h.out, "block %d: %s\n", block.Index(), tx)
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	h.stateHash = crypto.Keccak256(append([][]byte{hash}, block.Transactions()...)...)
@@ -49,7 +79,7 @@ func (h *Handler) CommitHandler(block poset.Block) (stateHash []byte, err error)
 }
 
 // SnapshotHandler Called when syncing with the network
-func (h *Handler) SnapshotHandler(blockIndex int) (snapshot []byte, err error) {
+func (h *Handler) SnapshotHandler(blockIndex int64) (snapshot []byte, err error) {
 	return []byte{}, nil
 }
 
@@ -58,55 +88,126 @@ func (h *Handler) RestoreHandler(snapshot []byte) (stateHash []byte, err error)
 	return []byte{}, nil
 }
 
-// NewHandler constructor
-func NewHandler() *Handler {
-	return &Handler{}
+// NewHandler constructor, printing the committed transactions to out
+func NewHandler(out io.Writer, asJSON bool) *Handler {
+	return &Handler{
+		out:    out,
+		asJSON: asJSON,
+	}
 }
 
-func connectProxy(cmd *cobra.Command, args []string) error {
-	i := config.Node
+// proxyAddr returns the app proxy address of a node started by the run
+// command
+func proxyAddr(node int) string {
+	dag1Port := 1337
+	return "127.0.0.1:" + strconv.Itoa(dag1Port+(node*10)+1)
+}
+
+// txSubmitter is the part of a DAG1Proxy submitting transactions
+type txSubmitter interface {
+	SubmitTx(tx []byte) error
+}
+
+// submitLines submits every non-empty line of r as a transaction, at most
+// rate per second if rate is positive, until EOF. It returns the number of
+// transactions submitted.
+func submitLines(p txSubmitter, r io.Reader, rate float64) (int, error) {
+	var interval time.Duration
+	if rate > 0 {
+		interval = time.Duration(float64(time.Second) / rate)
+	}
 
-	babblePort := 1337
-	proxyServPortStr := strconv.Itoa(babblePort + (i * 10) + 1)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxTxSize)
+
+	submitted := 0
+	next := time.Now()
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if interval > 0 {
+			if now := time.Now(); now.Before(next) {
+				time.Sleep(next.Sub(now))
+			} else {
+				next = now
+			}
+			next = next.Add(interval)
+		}
+		if err := p.SubmitTx(append([]byte(nil), line...)); err != nil {
+			return submitted, err
+		}
+		submitted++
+	}
+	return submitted, scanner.Err()
+}
+
+func connectProxy(cmd *cobra.Command, args []string) error {
+	addr := config.Addr
+	if addr == "" {
+		addr = proxyAddr(config.Node)
+	}
 
 	logger := logrus.New()
 
 	logger.Level = logrus.InfoLevel
 
-	appProxy, err := proxy.NewGrpcDAG1Proxy("127.0.0.1:"+proxyServPortStr, logger)
+	appProxy, err := proxy.NewGrpcDAG1Proxy(addr, logger)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer appProxy.Close()
 
-	if len(tx) > 0 {
-		if err := appProxy.SubmitTx([]byte(tx)); err != nil {
-			panic(err)
+	if config.Listen {
+		if _, err := dummy.NewDummyClient(appProxy, NewHandler(os.Stdout, config.JSON), logger); err != nil {
+			return err
 		}
-
-		return nil
 	}
 
-	if config.Stdin {
-		scanner := bufio.NewScanner(os.Stdin)
-
-		for scanner.Scan() {
-			if err := appProxy.SubmitTx(scanner.Bytes()); err != nil {
-				panic(err)
-			}
+	submitting := true
+	switch {
+	case len(tx) > 0:
+		err = appProxy.SubmitTx([]byte(tx))
+	case config.Stdin:
+		_, err = submitLines(appProxy, os.Stdin, config.Rate)
+	case config.File != "":
+		var f *os.File
+		if f, err = os.Open(config.File); err != nil {
+			return err
 		}
+		var n int
+		n, err = submitLines(appProxy, f, config.Rate)
+		f.Close()
+		logger.Infof("Submitted %d transactions from %s", n, config.File)
+	default:
+		submitting = false
+	}
+	if err != nil {
+		return err
+	}
 
+	if !config.Listen {
+		if !submitting {
+			return fmt.Errorf("nothing to do, use --submit, --stdin, --file or --listen")
+		}
 		return nil
 	}
 
-	for {
-		time.Sleep(time.Second)
-	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	<-c
 	return nil
 }
 
 // AddProxyFlags adds flags to the Run command
 func AddProxyFlags(cmd *cobra.Command) {
 	cmd.Flags().IntVar(&config.Node, "node", config.Node, "Node index to connect to (starts from 0)")
-	cmd.Flags().BoolVar(&config.Stdin, "stdin", config.Stdin, "Send some transactions from stdin")
+	cmd.Flags().StringVar(&config.Addr, "addr", config.Addr, "Proxy address to connect to, instead of the one of --node")
+	cmd.Flags().BoolVar(&config.Stdin, "stdin", config.Stdin, "Submit each line of stdin as a transaction until EOF")
+	cmd.Flags().StringVar(&config.File, "file", config.File, "Submit each line of a file as a transaction")
+	cmd.Flags().Float64Var(&config.Rate, "rate", config.Rate, "Max transactions per second submitted from --stdin or --file, 0 for no limit")
+	cmd.Flags().BoolVar(&config.Listen, "listen", config.Listen, "Print the committed transactions with their block index until interrupted")
+	cmd.Flags().BoolVar(&config.JSON, "json", config.JSON, "Print the committed transactions as JSON lines")
 	cmd.Flags().StringVar(&tx, "submit", tx, "Tx to submit and quit")
 }
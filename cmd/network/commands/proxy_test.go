@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy"
+	"github.com/SamuelMarks/dag1/src/utils"
+)
+
+const timeout = time.Second
+
+func newTestProxies(t *testing.T) (*proxy.GrpcAppProxy, *proxy.GrpcDAG1Proxy) {
+	addr := utils.GetUnusedNetAddr(1, t)
+	logger := common.NewTestLogger(t)
+
+	s, err := proxy.NewGrpcAppProxy(addr[0], timeout, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := proxy.NewGrpcDAG1Proxy(addr[0], logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, c
+}
+
+func TestSubmitLinesRate(t *testing.T) {
+	s, c := newTestProxies(t)
+	defer s.Close()
+	defer c.Close()
+
+	const rate = 20
+	txs := []string{"a", "b", "", "c", "d", "e"}
+
+	received := make(chan []string)
+	go func() {
+		var got []string
+		for len(got) < 5 {
+			select {
+			case tx := <-s.SubmitCh():
+				got = append(got, string(tx))
+			case <-time.After(timeout):
+				received <- got
+				return
+			}
+		}
+		received <- got
+	}()
+
+	start := time.Now()
+	n, err := submitLines(c, strings.NewReader(strings.Join(txs, "\n")), rate)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Fatalf("expected 5 transactions submitted, got %d", n)
+	}
+	if min := 4 * time.Second / rate; elapsed < min {
+		t.Fatalf("expected 5 transactions at %d/s to take at least %v, took %v", rate, min, elapsed)
+	}
+
+	got := <-received
+	if strings.Join(got, ",") != "a,b,c,d,e" {
+		t.Fatalf("expected a,b,c,d,e to be submitted, got %v", got)
+	}
+}
+
+func TestCommitEcho(t *testing.T) {
+	s, c := newTestProxies(t)
+	defer s.Close()
+	defer c.Close()
+
+	var out bytes.Buffer
+	if _, err := dummy.NewDummyClient(c, NewHandler(&out, true), common.NewTestLogger(t)); err != nil {
+		t.Fatal(err)
+	}
+	// connect the stream the commits are pushed on
+	if err := c.SubmitTx([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	<-s.SubmitCh()
+
+	block := poset.NewBlock(3, 1, []byte("frame"), [][]byte{[]byte("tx1"), []byte("tx2")})
+	if _, err := s.CommitBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := json.NewDecoder(&out)
+	for _, want := range []string{"tx1", "tx2"} {
+		var got committedTx
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Block != 3 || got.Tx != want {
+			t.Fatalf("expected %s in block 3, got %+v", want, got)
+		}
+	}
+}
@@ -21,7 +21,7 @@ import (
 func NewRunCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "run",
-		Short:   "Run node",
+		Short:   "Run a network of nodes",
 		PreRunE: loadConfig,
 		RunE:    runDAG1,
 	}
@@ -106,6 +106,32 @@ func sendTxs(dag1Node *exec.Cmd, i int) {
 }
 
 func runDAG1(cmd *cobra.Command, args []string) error {
+	if config.Exec {
+		return runExec()
+	}
+
+	stop := make(chan struct{})
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+	go func() {
+		<-c
+		close(stop)
+	}()
+
+	report, err := runNetwork(config, os.Stdout, stop)
+	if err != nil {
+		return err
+	}
+	fmt.Print(report)
+	if !report.Agree() {
+		return fmt.Errorf("the nodes diverged at block %d", report.Divergence)
+	}
+	return nil
+}
+
+// runExec runs the nodes as dag1 processes
+func runExec() error {
 	if err := os.RemoveAll("/tmp/dag1_configs"); err != nil {
 		log.Fatal(err)
 	}
@@ -185,11 +211,18 @@ func runDAG1(cmd *cobra.Command, args []string) error {
 func AddRunFlags(cmd *cobra.Command) {
 	cmd.Flags().Int("nodes", config.NbNodes, "Amount of nodes to spawn")
 	cmd.Flags().String("datadir", config.DAG1.DataDir, "Top-level directory for configuration and data")
-	cmd.Flags().String("log", config.DAG1.LogLevel, "debug, info, warn, error, fatal, panic")
+	cmd.Flags().String("log", config.DAG1.LogLevel, "debug, info, warn, error, fatal, panic; per module e.g. \"info,poset=warn,node=debug\"")
 	cmd.Flags().Duration("heartbeat", config.DAG1.NodeConfig.HeartbeatTimeout, "Time between gossips")
 
 	cmd.Flags().Int64("sync-limit", config.DAG1.NodeConfig.SyncLimit, "Max number of events for sync")
 	cmd.Flags().Int("send-txs", config.SendTxs, "Send some random transactions")
+
+	cmd.Flags().Bool("exec", config.Exec, "Run the nodes as dag1 processes instead of in process")
+	cmd.Flags().String("store", config.DAG1.Store, "Store of the nodes: inmem, badger or sqlite; --store alone is badger")
+	cmd.Flags().Lookup("store").NoOptDefVal = dag1.StoreBadger
+	cmd.Flags().Duration("duration", config.Duration, "Stop the nodes after this time, 0 for no limit")
+	cmd.Flags().Int64("until-blocks", config.UntilBlocks, "Stop the nodes once each committed this many blocks, 0 for no limit")
+	cmd.Flags().String("scenario", config.Scenario, "YAML or JSON file of the latencies, losses and partitions between the nodes run in process")
 }
 
 func loadConfig(cmd *cobra.Command, args []string) error {
@@ -204,7 +237,9 @@ func loadConfig(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	config.DAG1.Logger.Level = dag1.LogLevel(config.DAG1.LogLevel)
+	if err := dag1.SetLogLevels(config.DAG1.Logger, config.DAG1.LogLevel); err != nil {
+		return err
+	}
 	config.DAG1.NodeConfig.Logger = config.DAG1.Logger
 
 	return nil
@@ -12,6 +12,14 @@ var VersionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version info",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(version.Version)
+		info := version.Get()
+		fmt.Println(info.Version)
+		if info.GitCommit != "" {
+			fmt.Println("git commit:", info.GitCommit)
+		}
+		if info.BuildDate != "" {
+			fmt.Println("build date:", info.BuildDate)
+		}
+		fmt.Println("go version:", info.GoVersion)
 	},
 }
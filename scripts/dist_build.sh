@@ -30,7 +30,7 @@ echo "==> Building..."
 		-os="${XC_OS}" \
 		-arch="${XC_ARCH}" \
 		-osarch="!darwin/arm !solaris/amd64 !freebsd/amd64" \
-		-ldflags "-X ${GIT_IMPORT}.GitCommit='${GIT_COMMIT}' -X ${GIT_IMPORT}.GitDescribe='${GIT_DESCRIBE}'" \
+		-ldflags "-X ${GIT_IMPORT}.GitCommit='${GIT_COMMIT}' -X ${GIT_IMPORT}.GitDescribe='${GIT_DESCRIBE}' -X ${GIT_IMPORT}.BuildDate='$(date -u +%Y-%m-%dT%H:%M:%SZ)'" \
 		-output "build/pkg/{{.OS}}_{{.Arch}}/dag1" \
 		-tags="${BUILD_TAGS}" \
 		github.com/SamuelMarks/dag1/cmd/dag1
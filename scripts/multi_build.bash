@@ -37,7 +37,7 @@ shift $((OPTIND-1))
 [ "${1:-}" = "--" ] && shift
 
 # Use -tags="netgo multi" in bgo build below to build multu dag1 version for testing
-declare args="-X github.com/SamuelMarks/dag1/src/version.GitCommit=$(git rev-parse HEAD)"
+declare args="-X github.com/SamuelMarks/dag1/src/version.GitCommit=$(git rev-parse HEAD) -X github.com/SamuelMarks/dag1/src/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
 if [ "$TARGET_OS" == "linux" ]; then
   args="$args -linkmode external -extldflags -static"
 fi
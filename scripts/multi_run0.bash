@@ -31,7 +31,7 @@ for ip in $(jq -rc '.[].NetAddr' "$PEERS_DIR/dag1_data_dir/peers.json"); do
 done
 
 # Run multi dag1
-#GOMAXPROCS=$(($logicalCpuCount - 1)) "$BUILD_DIR/dag1_$TARGET_OS" run --datadir "$DATAL_DIR/dag1_data_dir" --store --listen="$node_addr":12000 --log=warn --heartbeat=5s -p "$node_addr":9000 --test --test_n=10 --test_delay=10 --syslog
+#GOMAXPROCS=$(($logicalCpuCount - 1)) "$BUILD_DIR/dag1_$TARGET_OS" run --datadir "$DATAL_DIR/dag1_data_dir" --store --listen="$node_addr":12000 --log=warn --heartbeat=5s -p "$node_addr":9000 --test --test_n=100 --test_delay=10 --syslog
 
 declare -i debug=0
 while getopts "d" opt; do
@@ -0,0 +1,227 @@
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for it. The node, the transport, the proxies
+// and the tester take one so that the tests can drive the time with a
+// ManualClock instead of sleeping. The durations of the work done, measured
+// for the logs and the stats, are left to the time package.
+//
+// NewTimer is for the long timeouts which mostly do not fire, stopped so
+// that they do not hold memory until they would have.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+	Sleep(d time.Duration)
+}
+
+// Timer is a timer of a Clock, see time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Ticker is a ticker of a Clock, see time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// RealClock is the Clock of the time package
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+func (t realTimer) Stop() {
+	t.Timer.Stop()
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// manualTimer is a timer or a ticker of a ManualClock
+type manualTimer struct {
+	deadline time.Time
+	period   time.Duration // of a ticker, 0 for a timer
+	seq      uint64        // orders the timers of the same deadline
+	ch       chan time.Time
+	clock    *ManualClock
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *manualTimer) Stop() {
+	t.clock.remove(t)
+}
+
+// ManualClock is a Clock whose time only moves with Advance, for the tests.
+// The timers and tickers due fire in the order of their deadlines, the time
+// being that of each deadline as it fires. As with the time package, a ticker
+// drops the ticks its reader is not ready for.
+type ManualClock struct {
+	locker  sync.Mutex
+	now     time.Time
+	timers  []*manualTimer
+	seq     uint64
+	changed chan struct{} // closed when a timer is added
+}
+
+// NewManualClock constructor, the clock showing start
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{
+		now:     start,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the time of the clock
+func (c *ManualClock) Now() time.Time {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.now
+}
+
+// After returns a channel the time is sent on once the clock advanced by d
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer returns a timer which fires once the clock advanced by d
+func (c *ManualClock) NewTimer(d time.Duration) Timer {
+	t := &manualTimer{ch: make(chan time.Time, 1), clock: c}
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+	t.deadline = c.now.Add(d)
+	c.add(t)
+	return t
+}
+
+// NewTicker returns a ticker which ticks every time the clock advanced by d.
+// It panics when d is not positive, as time.NewTicker does.
+func (c *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	t := &manualTimer{deadline: c.now.Add(d), period: d, ch: make(chan time.Time, 1), clock: c}
+	c.add(t)
+	return t
+}
+
+// Sleep returns once the clock advanced by d
+func (c *ManualClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves the clock forward by d, firing the timers and the ticks due
+func (c *ManualClock) Advance(d time.Duration) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	end := c.now.Add(d)
+	for {
+		next := -1
+		for i, t := range c.timers {
+			if t.deadline.After(end) {
+				continue
+			}
+			if next < 0 || t.deadline.Before(c.timers[next].deadline) ||
+				(t.deadline.Equal(c.timers[next].deadline) && t.seq < c.timers[next].seq) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+		t := c.timers[next]
+		c.now = t.deadline
+		select {
+		case t.ch <- c.now:
+		default:
+		}
+		if t.period > 0 {
+			t.deadline = t.deadline.Add(t.period)
+		} else {
+			c.timers = append(c.timers[:next], c.timers[next+1:]...)
+		}
+	}
+	c.now = end
+}
+
+// Timers returns the number of timers and tickers waiting for the clock
+func (c *ManualClock) Timers() int {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntil returns once n timers or tickers wait for the clock, so that a
+// test advances it only after the goroutines it drives went to sleep
+func (c *ManualClock) BlockUntil(n int) {
+	for {
+		c.locker.Lock()
+		waiting, changed := len(c.timers), c.changed
+		c.locker.Unlock()
+		if waiting >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// add registers a timer, the clock locked
+func (c *ManualClock) add(t *manualTimer) {
+	c.seq++
+	t.seq = c.seq
+	c.timers = append(c.timers, t)
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// remove stops a timer
+func (c *ManualClock) remove(t *manualTimer) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+var clockStart = time.Unix(1500000000, 0)
+
+// fired returns the time sent on ch, or false when there is none
+func fired(ch <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-ch:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestManualClockTimers(t *testing.T) {
+	c := NewManualClock(clockStart)
+	if !c.Now().Equal(clockStart) {
+		t.Fatalf("expected %v, got %v", clockStart, c.Now())
+	}
+
+	timers := []<-chan time.Time{
+		c.After(3 * time.Second),
+		c.After(time.Second),
+		c.After(2 * time.Second),
+	}
+	deadlines := []time.Duration{3 * time.Second, time.Second, 2 * time.Second}
+	if c.Timers() != 3 {
+		t.Fatalf("expected 3 timers, got %d", c.Timers())
+	}
+
+	c.Advance(1500 * time.Millisecond)
+	if _, ok := fired(timers[0]); ok {
+		t.Fatal("expected the timer of 3s not fired")
+	}
+	if _, ok := fired(timers[2]); ok {
+		t.Fatal("expected the timer of 2s not fired")
+	}
+	if at, ok := fired(timers[1]); !ok || !at.Equal(clockStart.Add(time.Second)) {
+		t.Fatalf("expected the timer of 1s fired at its deadline, got %v %v", at, ok)
+	}
+	if now := c.Now(); !now.Equal(clockStart.Add(1500 * time.Millisecond)) {
+		t.Fatalf("expected the clock advanced, got %v", now)
+	}
+
+	c.Advance(time.Hour)
+	for i, ch := range timers {
+		if i == 1 {
+			continue
+		}
+		if at, ok := fired(ch); !ok || !at.Equal(clockStart.Add(deadlines[i])) {
+			t.Fatalf("timer %d: expected it fired at its deadline, got %v %v", i, at, ok)
+		}
+	}
+	if c.Timers() != 0 {
+		t.Fatalf("expected no timer left, got %d", c.Timers())
+	}
+
+	if _, ok := fired(c.After(0)); !ok {
+		t.Fatal("expected a timer of 0 fired at once")
+	}
+
+	stopped := c.NewTimer(time.Second)
+	stopped.Stop()
+	c.Advance(time.Minute)
+	if _, ok := fired(stopped.C()); ok {
+		t.Fatal("expected a stopped timer not fired")
+	}
+	if c.Timers() != 0 {
+		t.Fatalf("expected no timer left, got %d", c.Timers())
+	}
+}
+
+func TestManualClockOrder(t *testing.T) {
+	c := NewManualClock(clockStart)
+	order := make(chan int, 3)
+	// each goroutine wakes up in turn, the next timer firing only once
+	// the previous one was taken
+	for i, d := range []time.Duration{3, 1, 2} {
+		ch := c.After(d * time.Second)
+		go func(i int) {
+			<-ch
+			order <- i
+		}(i)
+	}
+	for _, expected := range []int{1, 2, 0} {
+		c.Advance(time.Second)
+		if i := <-order; i != expected {
+			t.Fatalf("expected timer %d, got %d", expected, i)
+		}
+	}
+}
+
+func TestManualClockTicker(t *testing.T) {
+	c := NewManualClock(clockStart)
+	ticker := c.NewTicker(time.Second)
+
+	c.Advance(500 * time.Millisecond)
+	if _, ok := fired(ticker.C()); ok {
+		t.Fatal("expected no tick before the period")
+	}
+	c.Advance(500 * time.Millisecond)
+	if at, ok := fired(ticker.C()); !ok || !at.Equal(clockStart.Add(time.Second)) {
+		t.Fatalf("expected a tick at 1s, got %v %v", at, ok)
+	}
+
+	// the ticks the reader is not ready for are dropped
+	c.Advance(3 * time.Second)
+	if at, ok := fired(ticker.C()); !ok || !at.Equal(clockStart.Add(2*time.Second)) {
+		t.Fatalf("expected the first tick kept, got %v %v", at, ok)
+	}
+	if _, ok := fired(ticker.C()); ok {
+		t.Fatal("expected the other ticks dropped")
+	}
+
+	// the ticks keep to the period
+	c.Advance(time.Second)
+	if at, ok := fired(ticker.C()); !ok || !at.Equal(clockStart.Add(5*time.Second)) {
+		t.Fatalf("expected a tick at 5s, got %v %v", at, ok)
+	}
+
+	ticker.Stop()
+	c.Advance(time.Minute)
+	if _, ok := fired(ticker.C()); ok {
+		t.Fatal("expected no tick once stopped")
+	}
+	if c.Timers() != 0 {
+		t.Fatalf("expected no timer left, got %d", c.Timers())
+	}
+}
+
+func TestManualClockSleep(t *testing.T) {
+	c := NewManualClock(clockStart)
+	done := make(chan time.Time)
+	go func() {
+		c.Sleep(time.Minute)
+		done <- c.Now()
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(30 * time.Second)
+	select {
+	case <-done:
+		t.Fatal("expected the sleep to go on")
+	case <-time.After(10 * time.Millisecond):
+	}
+	c.Advance(30 * time.Second)
+	if now := <-done; !now.Equal(clockStart.Add(time.Minute)) {
+		t.Fatalf("expected to wake up at %v, got %v", clockStart.Add(time.Minute), now)
+	}
+}
+
+func TestRealClock(t *testing.T) {
+	start := RealClock.Now()
+	<-RealClock.After(time.Millisecond)
+	timer := RealClock.NewTimer(time.Millisecond)
+	<-timer.C()
+	timer.Stop()
+	ticker := RealClock.NewTicker(time.Millisecond)
+	<-ticker.C()
+	ticker.Stop()
+	RealClock.Sleep(time.Millisecond)
+	if elapsed := time.Since(start); elapsed < 4*time.Millisecond {
+		t.Fatalf("expected at least 4ms elapsed, got %v", elapsed)
+	}
+}
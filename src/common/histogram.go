@@ -0,0 +1,75 @@
+package common
+
+import (
+	"sort"
+	"sync"
+)
+
+// Histogram counts observations in buckets with fixed upper bounds, the way
+// Prometheus histograms do
+type Histogram struct {
+	bounds []float64
+	counts []uint64 // one per bound, then one for the values above all bounds
+	sum    float64
+	count  uint64
+	locker sync.Mutex
+}
+
+// HistogramSnapshot is a copy of a Histogram. Counts are per bucket, not
+// cumulative, the last one being for the values above all bounds.
+type HistogramSnapshot struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  uint64    `json:"count"`
+}
+
+// NewHistogram constructor, bounds must be sorted in increasing order
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		bounds: append([]float64(nil), bounds...),
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// Observe adds a value to the bucket of the first bound it does not exceed
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.locker.Lock()
+	defer h.locker.Unlock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// Snapshot returns a copy of the histogram
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.locker.Lock()
+	defer h.locker.Unlock()
+	return HistogramSnapshot{
+		Bounds: h.bounds,
+		Counts: append([]uint64(nil), h.counts...),
+		Sum:    h.sum,
+		Count:  h.count,
+	}
+}
+
+// Mean returns the mean of the observed values, 0 without observations
+func (s HistogramSnapshot) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// Cumulative returns the number of observations up to each bound, then the
+// total, as Prometheus buckets are
+func (s HistogramSnapshot) Cumulative() []uint64 {
+	res := make([]uint64, len(s.Counts))
+	var total uint64
+	for i, c := range s.Counts {
+		total += c
+		res[i] = total
+	}
+	return res
+}
@@ -0,0 +1,33 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 2, 5})
+	for _, v := range []float64{0.5, 1, 1.5, 3, 10, 20} {
+		h.Observe(v)
+	}
+
+	s := h.Snapshot()
+	if !reflect.DeepEqual(s.Counts, []uint64{2, 1, 1, 2}) {
+		t.Fatalf("unexpected counts %v", s.Counts)
+	}
+	if !reflect.DeepEqual(s.Cumulative(), []uint64{2, 3, 4, 6}) {
+		t.Fatalf("unexpected cumulative counts %v", s.Cumulative())
+	}
+	if s.Count != 6 || s.Sum != 36 || s.Mean() != 6 {
+		t.Fatalf("unexpected count %d, sum %v and mean %v", s.Count, s.Sum, s.Mean())
+	}
+
+	// snapshots do not move with the histogram
+	h.Observe(0)
+	if s.Counts[0] != 2 {
+		t.Fatal("expected the snapshot to be a copy")
+	}
+	if (HistogramSnapshot{}).Mean() != 0 {
+		t.Fatal("expected a zero mean without observations")
+	}
+}
@@ -69,6 +69,17 @@ func (rim *RollingIndexMap) GetLast(key uint64) (interface{}, error) {
 	return cached[len(cached)-1], nil
 }
 
+// GetLastWindow returns the cached items of a key and the index of the last
+// one
+func (rim *RollingIndexMap) GetLastWindow(key uint64) ([]interface{}, int64, error) {
+	items, ok := rim.mapping[key]
+	if !ok {
+		return nil, -1, NewStoreErr(rim.name, KeyNotFound, fmt.Sprint(key))
+	}
+	window, last := items.GetLastWindow()
+	return window, last, nil
+}
+
 // Set sets a given key for the index
 func (rim *RollingIndexMap) Set(key uint64, item interface{}, index int64) error {
 	items, ok := rim.mapping[key]
@@ -0,0 +1,80 @@
+// Package verify checks the signatures of the events and blocks of a dag1
+// network from their marshalled bodies, for the clients which do not want
+// to import the poset package.
+package verify
+
+import (
+	"crypto/elliptic"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+// EventHash returns the hash the creator of an event signs, from its body
+// in deterministic protobuf encoding
+func EventHash(body []byte) []byte {
+	return crypto.Keccak256(body)
+}
+
+// BlockHash returns the hash the validators of a block sign, from its body
+// in deterministic protobuf encoding
+func BlockHash(body []byte) []byte {
+	return crypto.Keccak256(body)
+}
+
+// ParsePubKey decodes a public key in hex, with or without 0x, as listed in
+// peers.json, and checks it is a point of the curve
+func ParsePubKey(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	pubKey, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if x, _ := elliptic.Unmarshal(elliptic.P256(), pubKey); x == nil {
+		return nil, fmt.Errorf("%q is not a public key", s)
+	}
+	return pubKey, nil
+}
+
+// Address returns the address of a participant from its public key
+// TODO: hash of publickey
+func Address(pubKey []byte) (a common.Address) {
+	copy(a[:], pubKey)
+	return
+}
+
+// Signature returns true when sig, as encoded by crypto.EncodeSignature, is
+// the signature of hash by the owner of pubKey
+func Signature(pubKey, hash []byte, sig string) (bool, error) {
+	pub := crypto.ToECDSAPub(pubKey)
+	if pub == nil || pub.X == nil {
+		return false, fmt.Errorf("invalid public key %X", pubKey)
+	}
+	r, s, err := crypto.DecodeSignature(sig)
+	if err != nil {
+		return false, err
+	}
+	return crypto.Verify(pub, hash, r, s), nil
+}
+
+// Event returns true when sig is the signature of the event body by the
+// owner of pubKey, along with the hash signed. The body has to be the one
+// of an event created by pubKey for the event to be valid in the network.
+func Event(pubKey, body []byte, sig string) (bool, []byte, error) {
+	hash := EventHash(body)
+	ok, err := Signature(pubKey, hash, sig)
+	return ok, hash, err
+}
+
+// Block returns true when sig is the signature of the block body by the
+// owner of pubKey, along with the hash signed
+func Block(pubKey, body []byte, sig string) (bool, []byte, error) {
+	hash := BlockHash(body)
+	ok, err := Signature(pubKey, hash, sig)
+	return ok, hash, err
+}
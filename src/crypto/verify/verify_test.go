@@ -0,0 +1,91 @@
+package verify_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/crypto/verify"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+func TestVerifyEvent(t *testing.T) {
+	key, _ := crypto.GenerateECDSAKey()
+	pubKey := crypto.FromECDSAPub(&key.PublicKey)
+	event := poset.NewEvent([][]byte{[]byte("tx")}, nil, nil,
+		poset.EventHashes{}, pubKey, 0, nil, nil, 0, false)
+	if err := event.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	body, err := event.Message.Body.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := event.Message.Body.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, hash, err := verify.Event(pubKey, body, event.Message.Signature)
+	if err != nil || !ok {
+		t.Fatalf("expected the event signature valid, got %v %v", ok, err)
+	}
+	if !bytes.Equal(hash, expected.Bytes()) {
+		t.Fatalf("expected the hash %X, got %X", expected.Bytes(), hash)
+	}
+
+	// a tampered body, or another key, does not verify
+	tampered := append([]byte{}, body...)
+	tampered[len(tampered)-1] ^= 1
+	if ok, _, err := verify.Event(pubKey, tampered, event.Message.Signature); err != nil || ok {
+		t.Fatalf("expected a tampered event refused, got %v %v", ok, err)
+	}
+	other, _ := crypto.GenerateECDSAKey()
+	if ok, _, err := verify.Event(crypto.FromECDSAPub(&other.PublicKey), body, event.Message.Signature); err != nil || ok {
+		t.Fatalf("expected the signature refused for another key, got %v %v", ok, err)
+	}
+}
+
+func TestVerifyBlock(t *testing.T) {
+	key, _ := crypto.GenerateECDSAKey()
+	pubKey := crypto.FromECDSAPub(&key.PublicKey)
+	block := poset.NewBlock(1, 2, []byte("frame"), [][]byte{[]byte("tx")})
+	sig, err := block.Sign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := block.Body.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _, err := verify.Block(pubKey, body, sig.Signature); err != nil || !ok {
+		t.Fatalf("expected the block signature valid, got %v %v", ok, err)
+	}
+	tampered := append([]byte{}, body...)
+	tampered[len(tampered)-1] ^= 1
+	if ok, _, err := verify.Block(pubKey, tampered, sig.Signature); err != nil || ok {
+		t.Fatalf("expected a tampered block refused, got %v %v", ok, err)
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	key, _ := crypto.GenerateECDSAKey()
+	pubKey := crypto.FromECDSAPub(&key.PublicKey)
+	for _, sig := range []string{"", "1", "1|2|3", "zz!|1"} {
+		if _, _, err := verify.Event(pubKey, []byte("body"), sig); err == nil {
+			t.Errorf("expected the signature %q refused", sig)
+		}
+	}
+	if _, _, err := verify.Event([]byte{0x04, 0x01}, []byte("body"), "1|1"); err == nil {
+		t.Error("expected an invalid public key refused")
+	}
+
+	if _, err := verify.ParsePubKey("0x" + common.Bytes2Hex(pubKey)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verify.ParsePubKey("0x0401"); err == nil {
+		t.Fatal("expected a point out of the curve refused")
+	}
+}
@@ -0,0 +1,295 @@
+package dag1
+
+import (
+	"crypto/elliptic"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/pos"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// Severity is how bad a Finding is, only errors keep a node from starting
+type Severity string
+
+// The severities of the findings
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Finding is a problem found in the files of a data directory
+type Finding struct {
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.File, f.Message)
+}
+
+// HasErrors returns true when one of the findings is an error
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOptions are the options of CheckDataDir
+type CheckOptions struct {
+	// Probe dials the net address of each peer, ProbeTimeout long at most
+	Probe        bool
+	ProbeTimeout time.Duration
+
+	PoSConfig *pos.Config
+	CacheSize int
+}
+
+// checker gathers the findings of CheckDataDir
+type checker struct {
+	dir      string
+	opts     CheckOptions
+	findings []Finding
+}
+
+func (c *checker) add(severity Severity, file, format string, args ...interface{}) {
+	c.findings = append(c.findings, Finding{
+		Severity: severity,
+		File:     file,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// CheckDataDir validates the files a node reads from its data directory
+// before it starts: peers.json, the private key, the genesis allocation and
+// the badger store, when there is one.
+func CheckDataDir(dir string, opts CheckOptions) []Finding {
+	if opts.PoSConfig == nil {
+		opts.PoSConfig = pos.DefaultConfig()
+	}
+	if opts.ProbeTimeout <= 0 {
+		opts.ProbeTimeout = 2 * time.Second
+	}
+	c := &checker{dir: dir, opts: opts}
+
+	participants := c.checkPeers()
+	c.checkKey(participants)
+	if participants != nil {
+		c.checkGenesis(participants)
+		c.checkStore(participants)
+	}
+	return c.findings
+}
+
+// jsonPeer is an entry of peers.json in either format: a PeerMessage, as
+// written by SetPeerMessages, or a Peer with its Message
+type jsonPeer struct {
+	Message   *peers.PeerMessage
+	NetAddr   string
+	PubKeyHex string
+}
+
+// checkPeers validates peers.json and returns its participants, nil when
+// they are unusable
+func (c *checker) checkPeers() *peers.Peers {
+	const file = "peers.json"
+	buf, err := ioutil.ReadFile(filepath.Join(c.dir, file))
+	if err != nil {
+		c.add(SeverityError, file, "%v", err)
+		return nil
+	}
+	var entries []jsonPeer
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		c.add(SeverityError, file, "not a JSON list of peers: %v", err)
+		return nil
+	}
+	if len(entries) < 2 {
+		c.add(SeverityError, file, "%d peers, at least two are needed", len(entries))
+	}
+
+	participants := peers.NewPeers()
+	pubKeys := make(map[string]int)
+	addrs := make(map[string]int)
+	valid := true
+	for i, entry := range entries {
+		msg := peers.PeerMessage{NetAddr: entry.NetAddr, PubKeyHex: entry.PubKeyHex}
+		if entry.Message != nil {
+			msg = *entry.Message
+		}
+		pubKey, ok := c.checkPubKey(file, i, msg.PubKeyHex)
+		if ok {
+			key := string(pubKey)
+			if first, dup := pubKeys[key]; dup {
+				c.add(SeverityError, file, "peer %d: same public key as peer %d", i, first)
+				ok = false
+			} else {
+				pubKeys[key] = i
+			}
+		}
+		if err := checkNetAddr(msg.NetAddr); err != nil {
+			c.add(SeverityError, file, "peer %d: net address: %v", i, err)
+		} else if first, dup := addrs[msg.NetAddr]; dup {
+			c.add(SeverityWarning, file, "peer %d: same net address %s as peer %d", i, msg.NetAddr, first)
+		} else {
+			addrs[msg.NetAddr] = i
+			if c.opts.Probe {
+				c.probe(file, i, msg.NetAddr)
+			}
+		}
+		if !ok {
+			valid = false
+			continue
+		}
+		participants.AddPeer(peers.NewPeer(msg.PubKeyHex, msg.NetAddr))
+	}
+	if !valid || participants.Len() == 0 {
+		return nil
+	}
+	return participants
+}
+
+// checkPubKey returns the bytes of a public key of peers.json, ok is false
+// when it is not a point of the curve
+func (c *checker) checkPubKey(file string, i int, pubKeyHex string) (pubKey []byte, ok bool) {
+	if !strings.HasPrefix(pubKeyHex, "0x") {
+		c.add(SeverityError, file, "peer %d: public key %q does not start with 0x", i, pubKeyHex)
+		return nil, false
+	}
+	pubKey, err := hex.DecodeString(pubKeyHex[2:])
+	if err != nil {
+		c.add(SeverityError, file, "peer %d: public key is not hex: %v", i, err)
+		return nil, false
+	}
+	if x, _ := elliptic.Unmarshal(elliptic.P256(), pubKey); x == nil {
+		c.add(SeverityError, file, "peer %d: public key is not a P-256 point", i)
+		return nil, false
+	}
+	// the nodes look themselves up by their key in upper case
+	if canonical := fmt.Sprintf("0x%X", pubKey); canonical != pubKeyHex {
+		c.add(SeverityError, file, "peer %d: public key must be in upper case hex, %s", i, canonical)
+		return nil, false
+	}
+	return pubKey, true
+}
+
+// checkNetAddr checks addr is a host:port with a valid port
+func checkNetAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port %q in %q", port, addr)
+	}
+	return nil
+}
+
+// probe dials a peer, the peers of a network which did not start yet are
+// unreachable so a failure is only a warning
+func (c *checker) probe(file string, i int, addr string) {
+	conn, err := net.DialTimeout("tcp", addr, c.opts.ProbeTimeout)
+	if err != nil {
+		c.add(SeverityWarning, file, "peer %d: %s unreachable: %v", i, addr, err)
+		return
+	}
+	conn.Close()
+}
+
+// checkKey validates the private key, and the public key written along by
+// keygen, against the participants
+func (c *checker) checkKey(participants *peers.Peers) {
+	const file = "priv_key.pem"
+	buf, err := ioutil.ReadFile(filepath.Join(c.dir, file))
+	if os.IsNotExist(err) {
+		c.add(SeverityError, file, "no private key, the node would create one of no participant")
+		return
+	}
+	if err != nil {
+		c.add(SeverityError, file, "%v", err)
+		return
+	}
+	key, err := crypto.NewPemKey(c.dir).ReadKeyFromBuf(buf)
+	if err == nil && key == nil {
+		err = fmt.Errorf("empty file")
+	}
+	if err != nil {
+		c.add(SeverityError, file, "%v", err)
+		return
+	}
+	pubKeyHex := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey))
+
+	if pub, err := ioutil.ReadFile(filepath.Join(c.dir, "key.pub")); err == nil {
+		if got := strings.TrimSpace(string(pub)); !strings.EqualFold(got, pubKeyHex) {
+			c.add(SeverityError, "key.pub", "public key %s is not that of priv_key.pem, %s", got, pubKeyHex)
+		}
+	}
+	if participants == nil {
+		return
+	}
+	if _, ok := participants.ReadByPubKey(pubKeyHex); !ok {
+		c.add(SeverityError, file, "public key %s is in no peer of peers.json", pubKeyHex)
+	}
+}
+
+// checkGenesis validates the genesis allocation: the total supply of the
+// PoS configuration shared between the participants
+func (c *checker) checkGenesis(participants *peers.Peers) {
+	const file = "genesis"
+	n := uint64(participants.Len())
+	balance := c.opts.PoSConfig.TotalSupply / n
+	if balance == 0 {
+		c.add(SeverityError, file, "total supply %d does not give each of the %d participants a balance",
+			c.opts.PoSConfig.TotalSupply, n)
+		return
+	}
+	if rest := c.opts.PoSConfig.TotalSupply - balance*n; rest > 0 {
+		c.add(SeverityInfo, file, "%d of the total supply %d is allocated to no participant",
+			rest, c.opts.PoSConfig.TotalSupply)
+	}
+}
+
+// checkStore compares the badger store, when there is one, with the
+// participants the way the node does at startup
+func (c *checker) checkStore(participants *peers.Peers) {
+	const file = "badger_db"
+	path := filepath.Join(c.dir, file)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return
+	}
+	cacheSize := c.opts.CacheSize
+	if cacheSize <= 0 {
+		cacheSize = 100
+	}
+	store, err := poset.LoadBadgerStore(cacheSize, path)
+	if err != nil {
+		c.add(SeverityError, file, "cannot open the store, is the node running? %v", err)
+		return
+	}
+	defer store.Close()
+	diff, err := CheckPeerSet(participants, store, c.opts.PoSConfig)
+	if err != nil {
+		c.add(SeverityError, file, "%v", err)
+		return
+	}
+	if diff.Empty() {
+		return
+	}
+	for _, line := range strings.Split(diff.String(), "\n") {
+		c.add(SeverityError, file, "%s, the node starts only with --force-peer-change", line)
+	}
+}
@@ -0,0 +1,241 @@
+package dag1
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/pos"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// checkFixture is a data directory of the first of its participants
+type checkFixture struct {
+	dir   string
+	keys  []*ecdsa.PrivateKey
+	peers []*peers.PeerMessage
+}
+
+func newCheckFixture(t *testing.T, n int) *checkFixture {
+	dir, err := ioutil.TempDir("", "check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &checkFixture{dir: dir}
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateECDSAKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.keys = append(f.keys, key)
+		f.peers = append(f.peers, &peers.PeerMessage{
+			NetAddr:   fmt.Sprintf("127.0.0.1:%d", 12000+i),
+			PubKeyHex: fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)),
+		})
+	}
+	if err := crypto.NewPemKey(dir).WriteKey(f.keys[0]); err != nil {
+		t.Fatal(err)
+	}
+	f.write(t, "key.pub", f.peers[0].PubKeyHex)
+	f.writePeers(t, f.peers)
+	return f
+}
+
+func (f *checkFixture) write(t *testing.T, file, content string) {
+	if err := ioutil.WriteFile(filepath.Join(f.dir, file), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *checkFixture) writePeers(t *testing.T, list interface{}) {
+	buf, err := json.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.write(t, "peers.json", string(buf))
+}
+
+func (f *checkFixture) participants() *peers.Peers {
+	participants := peers.NewPeers()
+	for _, p := range f.peers {
+		participants.AddPeer(peers.NewPeer(p.PubKeyHex, p.NetAddr))
+	}
+	return participants
+}
+
+// expectFinding fails unless one of the findings is of severity and file
+// and holds text
+func expectFinding(t *testing.T, findings []Finding, severity Severity, file, text string) {
+	t.Helper()
+	for _, f := range findings {
+		if f.Severity == severity && f.File == file && strings.Contains(f.Message, text) {
+			return
+		}
+	}
+	t.Fatalf("expected a %s of %s about %q, got %v", severity, file, text, findings)
+}
+
+func TestCheckDataDir(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		if findings := CheckDataDir(f.dir, CheckOptions{}); len(findings) != 0 {
+			t.Fatalf("expected no finding, got %v", findings)
+		}
+	})
+
+	t.Run("Peer format", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		f.writePeers(t, f.participants().ToPeerSlice())
+		if findings := CheckDataDir(f.dir, CheckOptions{}); len(findings) != 0 {
+			t.Fatalf("expected no finding, got %v", findings)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		f.write(t, "peers.json", `[{"NetAddr": "127.0.0.1:12000",`)
+		expectFinding(t, CheckDataDir(f.dir, CheckOptions{}), SeverityError, "peers.json", "not a JSON list")
+	})
+
+	t.Run("Single peer", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		f.writePeers(t, f.peers[:1])
+		expectFinding(t, CheckDataDir(f.dir, CheckOptions{}), SeverityError, "peers.json", "at least two")
+	})
+
+	badKeys := []struct {
+		name, pubKey, text string
+	}{
+		{"No prefix", strings.TrimPrefix(newCheckPubKey(t), "0x"), "does not start with 0x"},
+		{"Not hex", "0xZZ", "not hex"},
+		{"Not a point", "0x04" + strings.Repeat("AB", 64), "not a P-256 point"},
+		{"Lower case", strings.ToLower(newCheckPubKey(t)), "upper case"},
+	}
+	for _, c := range badKeys {
+		t.Run(c.name, func(t *testing.T) {
+			f := newCheckFixture(t, 4)
+			defer os.RemoveAll(f.dir)
+			f.peers[1].PubKeyHex = c.pubKey
+			f.writePeers(t, f.peers)
+			expectFinding(t, CheckDataDir(f.dir, CheckOptions{}), SeverityError, "peers.json", c.text)
+		})
+	}
+
+	t.Run("Duplicate key", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		f.peers[2].PubKeyHex = f.peers[1].PubKeyHex
+		f.writePeers(t, f.peers)
+		expectFinding(t, CheckDataDir(f.dir, CheckOptions{}), SeverityError, "peers.json", "same public key as peer 1")
+	})
+
+	t.Run("Net address", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		f.peers[1].NetAddr = "127.0.0.1"
+		f.peers[2].NetAddr = "127.0.0.1:70000"
+		f.writePeers(t, f.peers)
+		findings := CheckDataDir(f.dir, CheckOptions{})
+		expectFinding(t, findings, SeverityError, "peers.json", "peer 1: net address")
+		expectFinding(t, findings, SeverityError, "peers.json", "invalid port")
+	})
+
+	t.Run("Probe", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		closed, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		closed.Close()
+		f.peers[0].NetAddr = listener.Addr().String()
+		f.peers[1].NetAddr = closed.Addr().String()
+		f.peers[2].NetAddr = listener.Addr().String()
+		f.writePeers(t, f.peers[:3])
+
+		findings := CheckDataDir(f.dir, CheckOptions{Probe: true, PoSConfig: pos.NewConfig(300)})
+		expectFinding(t, findings, SeverityWarning, "peers.json", "peer 1: "+closed.Addr().String()+" unreachable")
+		expectFinding(t, findings, SeverityWarning, "peers.json", "peer 2: same net address")
+		if HasErrors(findings) || len(findings) != 2 {
+			t.Fatalf("expected two warnings, got %v", findings)
+		}
+	})
+
+	t.Run("No key", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		os.Remove(filepath.Join(f.dir, "priv_key.pem"))
+		expectFinding(t, CheckDataDir(f.dir, CheckOptions{}), SeverityError, "priv_key.pem", "no private key")
+	})
+
+	t.Run("Key of no peer", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		f.writePeers(t, f.peers[1:])
+		expectFinding(t, CheckDataDir(f.dir, CheckOptions{}), SeverityError, "priv_key.pem", "in no peer")
+	})
+
+	t.Run("Public key mismatch", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		f.write(t, "key.pub", f.peers[1].PubKeyHex)
+		expectFinding(t, CheckDataDir(f.dir, CheckOptions{}), SeverityError, "key.pub", "not that of priv_key.pem")
+	})
+
+	t.Run("Genesis", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		expectFinding(t, CheckDataDir(f.dir, CheckOptions{PoSConfig: pos.NewConfig(2)}),
+			SeverityError, "genesis", "does not give each")
+		expectFinding(t, CheckDataDir(f.dir, CheckOptions{PoSConfig: pos.NewConfig(10)}),
+			SeverityInfo, "genesis", "2 of the total supply 10")
+	})
+
+	t.Run("Store", func(t *testing.T) {
+		f := newCheckFixture(t, 4)
+		defer os.RemoveAll(f.dir)
+		store, err := poset.LoadOrCreateBadgerStore(f.participants(), 100,
+			filepath.Join(f.dir, "badger_db"), pos.DefaultConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if findings := CheckDataDir(f.dir, CheckOptions{}); len(findings) != 0 {
+			t.Fatalf("expected the store to match, got %v", findings)
+		}
+
+		f.peers = append(f.peers, &peers.PeerMessage{
+			NetAddr:   "127.0.0.1:12010",
+			PubKeyHex: newCheckPubKey(t),
+		})
+		f.writePeers(t, f.peers)
+		expectFinding(t, CheckDataDir(f.dir, CheckOptions{}), SeverityError, "badger_db", "extra peer")
+	})
+}
+
+func newCheckPubKey(t *testing.T) string {
+	key, err := crypto.GenerateECDSAKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey))
+}
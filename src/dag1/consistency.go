@@ -0,0 +1,187 @@
+package dag1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/kvdb"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/pos"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/state"
+)
+
+// PubKeyChange is a net address whose participant came with another key
+type PubKeyChange struct {
+	NetAddr string
+	Stored  string
+	Peers   string
+}
+
+// PeerSetDiff is how the participants of peers.json differ from the ones the
+// store holds Roots for. Net addresses are not consensus data, so a participant
+// moving to another address is not a difference.
+type PeerSetDiff struct {
+	Extra          []string // in peers.json only
+	Missing        []string // in the store only
+	ChangedPubKeys []PubKeyChange
+
+	// StoredStateRoot is the zero hash when the store keeps no genesis state
+	StoredStateRoot  common.Hash
+	GenesisStateRoot common.Hash
+}
+
+// Empty returns true when the store matches peers.json
+func (d *PeerSetDiff) Empty() bool {
+	return len(d.Extra) == 0 && len(d.Missing) == 0 &&
+		len(d.ChangedPubKeys) == 0 && !d.StateRootMismatch()
+}
+
+// StateRootMismatch returns true when the stored genesis state root is not
+// the one peers.json leads to
+func (d *PeerSetDiff) StateRootMismatch() bool {
+	return d.StoredStateRoot != (common.Hash{}) &&
+		d.StoredStateRoot != d.GenesisStateRoot
+}
+
+func (d *PeerSetDiff) String() string {
+	var lines []string
+	for _, p := range d.Extra {
+		lines = append(lines, "extra peer "+p)
+	}
+	for _, p := range d.Missing {
+		lines = append(lines, "missing peer "+p)
+	}
+	for _, c := range d.ChangedPubKeys {
+		lines = append(lines, fmt.Sprintf("changed pubkey at %s: %s => %s",
+			c.NetAddr, c.Stored, c.Peers))
+	}
+	if d.StateRootMismatch() {
+		lines = append(lines, fmt.Sprintf("genesis state root mismatch: stored %s, peers.json %s",
+			d.StoredStateRoot.Hex(), d.GenesisStateRoot.Hex()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CheckPeerSet compares participants with the Roots and the genesis state
+// root of store. The genesis state is recomputed from participants the same
+// way the store created its own.
+func CheckPeerSet(participants *peers.Peers, store poset.Store, posConf *pos.Config) (*PeerSetDiff, error) {
+	genesis, err := pos.FakeGenesis(participants, posConf,
+		state.NewDatabase(kvdb.NewMemDatabase()))
+	if err != nil {
+		return nil, err
+	}
+	diff := &PeerSetDiff{
+		StoredStateRoot:  store.StateRoot(),
+		GenesisStateRoot: genesis,
+	}
+
+	roots := store.RootsByParticipant()
+	for _, p := range participants.ToPeerSlice() {
+		if _, ok := roots[p.Message.PubKeyHex]; !ok {
+			diff.Extra = append(diff.Extra, p.Message.PubKeyHex)
+		}
+	}
+	for pubKey := range roots {
+		if _, ok := participants.ReadByPubKey(pubKey); !ok {
+			diff.Missing = append(diff.Missing, pubKey)
+		}
+	}
+	sort.Strings(diff.Extra)
+	sort.Strings(diff.Missing)
+
+	// a peer missing with its address taken by an extra one had its key changed
+	stored, err := store.Participants()
+	if err != nil || stored == nil {
+		return diff, nil
+	}
+	extraByAddr := make(map[string]string)
+	for _, pubKey := range diff.Extra {
+		p, _ := participants.ReadByPubKey(pubKey)
+		extraByAddr[p.Message.NetAddr] = pubKey
+	}
+	var missing []string
+	for _, pubKey := range diff.Missing {
+		var newKey string
+		old, ok := stored.ReadByPubKey(pubKey)
+		if ok {
+			newKey, ok = extraByAddr[old.Message.NetAddr]
+		}
+		if !ok {
+			missing = append(missing, pubKey)
+			continue
+		}
+		diff.ChangedPubKeys = append(diff.ChangedPubKeys, PubKeyChange{
+			NetAddr: old.Message.NetAddr,
+			Stored:  pubKey,
+			Peers:   newKey,
+		})
+		delete(extraByAddr, old.Message.NetAddr)
+	}
+	var extra []string
+	for _, pubKey := range diff.Extra {
+		p, _ := participants.ReadByPubKey(pubKey)
+		if extraByAddr[p.Message.NetAddr] == pubKey {
+			extra = append(extra, pubKey)
+		}
+	}
+	diff.Extra, diff.Missing = extra, missing
+
+	return diff, nil
+}
+
+// checkConsensusConfig refuses a store whose events were accepted with
+// another consensus configuration, a store without one takes ours
+func (l *DAG1) checkConsensusConfig() error {
+	hash := l.Config.NodeConfig.Consensus().Hash()
+	l.Config.Logger.WithFields(logrus.Fields{
+		"hash":   hash.Hex(),
+		"config": fmt.Sprintf("%+v", l.Config.NodeConfig.Consensus()),
+	}).Info("Consensus configuration")
+
+	stored, err := l.Store.ConsensusConfigHash()
+	if err != nil {
+		return err
+	}
+	if stored == (common.Hash{}) {
+		return l.Store.SetConsensusConfigHash(hash)
+	}
+	if stored != hash {
+		return fmt.Errorf("the store was written with the consensus configuration %s, "+
+			"ours is %s: every participant must use the same, e.g. with --consensus-params-file",
+			stored.Hex(), hash.Hex())
+	}
+	return nil
+}
+
+// checkStore refuses a store made for other participants than peers.json,
+// unless ForcePeerChange is set, in which case the node only observes. The
+// consensus configuration must match in any case.
+func (l *DAG1) checkStore() error {
+	if err := l.checkConsensusConfig(); err != nil {
+		return err
+	}
+	diff, err := CheckPeerSet(l.Peers, l.Store, &l.Config.PoSConfig)
+	if err != nil {
+		return err
+	}
+	if diff.Empty() {
+		return nil
+	}
+
+	if !l.Config.ForcePeerChange {
+		return fmt.Errorf("the store does not match peers.json, "+
+			"run with --force-peer-change to start anyway as an observer:\n%s", diff)
+	}
+
+	l.Config.Logger.WithField("diff", diff.String()).Warn(
+		"THE STORE DOES NOT MATCH PEERS.JSON: --force-peer-change is set, " +
+			"running in observer mode, this node will not create events")
+	l.Config.NodeConfig.Observer = true
+	return nil
+}
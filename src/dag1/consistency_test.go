@@ -0,0 +1,169 @@
+package dag1
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peer/fakenet"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/pos"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// copyPeers returns the peers of ps, with the net address of some of them
+// changed by addr
+func copyPeers(ps *peers.Peers, addr func(p *peers.Peer) string) *peers.Peers {
+	res := peers.NewPeers()
+	for _, p := range ps.ToPeerSlice() {
+		res.AddPeer(peers.NewPeer(p.Message.PubKeyHex, addr(p)))
+	}
+	return res
+}
+
+func sameAddr(p *peers.Peer) string {
+	return p.Message.NetAddr
+}
+
+func TestCheckPeerSet(t *testing.T) {
+	network := fakenet.NewNetwork()
+	_, stored, _ := initPeers(4, network)
+	store := poset.NewInmemStore(stored, 100, pos.DefaultConfig())
+
+	newKey := func() string {
+		key, _ := crypto.GenerateECDSAKey()
+		return fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey))
+	}
+	check := func(participants *peers.Peers) *PeerSetDiff {
+		diff, err := CheckPeerSet(participants, store, pos.DefaultConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return diff
+	}
+
+	t.Run("Matched", func(t *testing.T) {
+		if diff := check(copyPeers(stored, sameAddr)); !diff.Empty() {
+			t.Fatalf("expected no difference, got:\n%s", diff)
+		}
+	})
+
+	t.Run("Added peer", func(t *testing.T) {
+		participants := copyPeers(stored, sameAddr)
+		added := newKey()
+		participants.AddPeer(peers.NewPeer(added, network.RandomAddress()))
+
+		diff := check(participants)
+		if len(diff.Extra) != 1 || diff.Extra[0] != added || len(diff.Missing) != 0 {
+			t.Fatalf("expected %s to be extra, got:\n%s", added, diff)
+		}
+		if !diff.StateRootMismatch() {
+			t.Fatal("expected the genesis state root to change with the participants")
+		}
+	})
+
+	t.Run("Removed peer", func(t *testing.T) {
+		removed := stored.ToPeerSlice()[1]
+		participants := peers.NewPeers()
+		for _, p := range stored.ToPeerSlice() {
+			if p != removed {
+				participants.AddPeer(peers.NewPeer(p.Message.PubKeyHex, p.Message.NetAddr))
+			}
+		}
+
+		diff := check(participants)
+		if len(diff.Missing) != 1 || diff.Missing[0] != removed.Message.PubKeyHex ||
+			len(diff.Extra) != 0 {
+			t.Fatalf("expected %s to be missing, got:\n%s", removed.Message.PubKeyHex, diff)
+		}
+	})
+
+	t.Run("Changed pubkey", func(t *testing.T) {
+		changed := stored.ToPeerSlice()[2]
+		key := newKey()
+		participants := peers.NewPeers()
+		for _, p := range stored.ToPeerSlice() {
+			pubKey := p.Message.PubKeyHex
+			if p == changed {
+				pubKey = key
+			}
+			participants.AddPeer(peers.NewPeer(pubKey, p.Message.NetAddr))
+		}
+
+		diff := check(participants)
+		if len(diff.ChangedPubKeys) != 1 || len(diff.Extra) != 0 || len(diff.Missing) != 0 {
+			t.Fatalf("expected one changed pubkey, got:\n%s", diff)
+		}
+		c := diff.ChangedPubKeys[0]
+		if c.NetAddr != changed.Message.NetAddr || c.Stored != changed.Message.PubKeyHex ||
+			c.Peers != key {
+			t.Fatalf("unexpected pubkey change %+v", c)
+		}
+	})
+
+	t.Run("Changed NetAddr", func(t *testing.T) {
+		moved := copyPeers(stored, func(p *peers.Peer) string {
+			return network.RandomAddress()
+		})
+		if diff := check(moved); !diff.Empty() {
+			t.Fatalf("expected a changed net address to be allowed, got:\n%s", diff)
+		}
+	})
+}
+
+func TestCheckStoreForcePeerChange(t *testing.T) {
+	network := fakenet.NewNetwork()
+	_, stored, _ := initPeers(3, network)
+	_, other, _ := initPeers(3, network)
+
+	engine := NewDAG1(NewDefaultConfig())
+	engine.Peers = other
+	engine.Store = poset.NewInmemStore(stored, 100, &engine.Config.PoSConfig)
+
+	err := engine.checkStore()
+	if err == nil || !strings.Contains(err.Error(), "missing peer") {
+		t.Fatalf("expected the store to be refused with a diff, got %v", err)
+	}
+	if engine.Config.NodeConfig.Observer {
+		t.Fatal("expected a refused store not to set observer mode")
+	}
+
+	engine.Config.ForcePeerChange = true
+	if err := engine.checkStore(); err != nil {
+		t.Fatal(err)
+	}
+	if !engine.Config.NodeConfig.Observer {
+		t.Fatal("expected --force-peer-change to start the node as an observer")
+	}
+}
+
+func TestCheckStoreConsensusConfig(t *testing.T) {
+	network := fakenet.NewNetwork()
+	_, participants, _ := initPeers(3, network)
+
+	engine := NewDAG1(NewDefaultConfig())
+	engine.Peers = participants
+	engine.Store = poset.NewInmemStore(participants, 100, &engine.Config.PoSConfig)
+
+	// a new store takes the configuration of the node
+	if err := engine.checkStore(); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := engine.Store.ConsensusConfigHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := engine.Config.NodeConfig.Consensus().Hash(); stored != want {
+		t.Fatalf("expected the hash %s to be stored, got %s", want.Hex(), stored.Hex())
+	}
+	if err := engine.checkStore(); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.Config.NodeConfig.MaxEventsPerFrame = 10
+	err = engine.checkStore()
+	if err == nil || !strings.Contains(err.Error(), "consensus configuration") {
+		t.Fatalf("expected the store to be refused for another consensus configuration, got %v", err)
+	}
+}
@@ -3,7 +3,10 @@ package dag1
 import (
 	"crypto/ecdsa"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -16,6 +19,7 @@ import (
 	"github.com/SamuelMarks/dag1/src/peers"
 	"github.com/SamuelMarks/dag1/src/poset"
 	"github.com/SamuelMarks/dag1/src/service"
+	"github.com/SamuelMarks/dag1/src/version"
 )
 
 // DAG1 struct
@@ -26,6 +30,10 @@ type DAG1 struct {
 	Store     poset.Store
 	Peers     *peers.Peers
 	Service   *service.Service
+
+	joinInfo  *peer.FastForwardResponse
+	auditLog  *poset.AuditLog
+	flightRec *node.FlightRecorder
 }
 
 // NewDAG1 constructor
@@ -38,6 +46,11 @@ func NewDAG1(config *DAG1Config) *DAG1 {
 }
 
 func (l *DAG1) initTransport() error {
+	if l.Transport != nil {
+		// already brought up by initJoin
+		return nil
+	}
+
 	createCliFu := func(target string,
 		timeout time.Duration) (peer.SyncClient, error) {
 
@@ -50,18 +63,33 @@ func (l *DAG1) initTransport() error {
 		return peer.NewClient(rpcCli)
 	}
 
+	logger := dag1_log.ForModule(l.Config.Logger, "peer")
 	producer := peer.NewProducer(
 		l.Config.MaxPool, l.Config.NodeConfig.TCPTimeout, createCliFu)
-	backend := peer.NewBackend(
-		peer.NewBackendConfig(), l.Config.Logger, net.Listen)
+	protocol := l.protocol()
+	backendConfig := peer.NewBackendConfig()
+	backendConfig.Protocol = protocol
+	if l.Config.NodeConfig.Clock != nil {
+		backendConfig.Clock = l.Config.NodeConfig.Clock
+	}
+	backend := peer.NewBackend(backendConfig, logger, net.Listen)
 	if err := backend.ListenAndServe(peer.TCP, l.Config.BindAddr); err != nil {
 		return err
 	}
-	l.Transport = peer.NewTransport(l.Config.Logger, producer, backend)
+	transport := peer.NewTransport(logger, producer, backend)
+	transport.SetProtocol(protocol)
+	l.Transport = transport
+	if l.Config.WrapTransport != nil {
+		l.Transport = l.Config.WrapTransport(transport)
+	}
 	return nil
 }
 
 func (l *DAG1) initPeers() error {
+	if l.Config.JoinAddr != "" {
+		return l.initJoin()
+	}
+
 	if !l.Config.LoadPeers {
 		if l.Peers == nil {
 			return fmt.Errorf("did not load peers but none was present")
@@ -89,17 +117,119 @@ func (l *DAG1) initPeers() error {
 	return nil
 }
 
+// initJoin fetches the participants, anchor block and frame from a running
+// peer instead of reading peers.json. The transport and key are needed to
+// talk to it, so both are initialised early.
+func (l *DAG1) initJoin() error {
+	if err := l.initKey(); err != nil {
+		return err
+	}
+	if err := l.initTransport(); err != nil {
+		return err
+	}
+
+	pub := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&l.Config.Key.PublicKey))
+	self := peers.NewPeer(pub, l.Config.BindAddr)
+
+	trusted, err := l.joinTrustedPeers()
+	if err != nil {
+		return err
+	}
+
+	resp, err := node.RequestJoinInfo(l.Transport, l.Config.JoinAddr, self.ID,
+		l.Config.NodeConfig.Consensus())
+	if err != nil {
+		return fmt.Errorf("cannot join %s: %s", l.Config.JoinAddr, err)
+	}
+	if trusted != nil {
+		if err := node.VerifyJoinInfo(resp, trusted); err != nil {
+			return fmt.Errorf("cannot join %s: %s", l.Config.JoinAddr, err)
+		}
+	} else {
+		l.Config.Logger.WithField("join", l.Config.JoinAddr).Warn(
+			"Trusting the participants of the join peer, there is no peers.json to check them against")
+	}
+
+	l.joinInfo = resp
+	l.Peers = node.NewPeersFromJoinInfo(resp)
+
+	l.Config.Logger.WithFields(logrus.Fields{
+		"join":         l.Config.JoinAddr,
+		"participants": l.Peers.Len(),
+		"block_index":  resp.Block.Index(),
+	}).Debug("fetched join info")
+
+	return nil
+}
+
+// joinTrustedPeers returns the participants of peers.json the join peer is
+// checked against, nil when there is no peers.json and JoinTrust takes the
+// join peer at its word
+func (l *DAG1) joinTrustedPeers() (*peers.Peers, error) {
+	path := filepath.Join(l.Config.DataDir, "peers.json")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if !l.Config.JoinTrust {
+			return nil, fmt.Errorf("no %s to check the participants of %s against, "+
+				"copy it from a participant or pass --join-trust to trust them",
+				path, l.Config.JoinAddr)
+		}
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	trusted, err := peers.NewJSONPeers(l.Config.DataDir).GetPeersFromMessages()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %s", path, err)
+	}
+	return trusted, nil
+}
+
 func (l *DAG1) initStore() (err error) {
-	if !l.Config.Store {
-		l.Store = poset.NewInmemStore(l.Peers, l.Config.NodeConfig.CacheSize, &l.Config.PoSConfig)
+	switch l.Config.StoreKind() {
+	case StoreInmem:
+		store := poset.NewInmemStore(l.Peers, l.Config.NodeConfig.CacheSize, &l.Config.PoSConfig)
+		if l.Config.TxIndex {
+			store.EnableTxIndex()
+		}
+		l.Store = store
 		l.Config.Logger.Debug("created new in-mem store")
-	} else {
-		dbDir := l.Config.BadgerDir()
+	case StoreBadger:
+		dbDir := l.Config.StoreDBPath()
 		l.Config.Logger.WithField("path", dbDir).Debug("Attempting to load or create database")
-		l.Store, err = poset.LoadOrCreateBadgerStore(l.Peers, l.Config.NodeConfig.CacheSize, dbDir, &l.Config.PoSConfig)
+		var store *poset.BadgerStore
+		store, err = poset.LoadOrCreateBadgerStore(l.Peers, l.Config.NodeConfig.CacheSize, dbDir, &l.Config.PoSConfig)
 		if err != nil {
 			return
 		}
+		if store.NeedBootstrap() {
+			stats := store.LoadStats()
+			l.Config.Logger.WithFields(logrus.Fields{
+				"duration": stats.Duration,
+				"roots":    stats.Roots,
+				"events":   stats.Events,
+			}).Info("Loaded the store")
+		}
+		if l.Config.ArchiveDir != "" {
+			var archive *poset.FileArchive
+			if archive, err = poset.NewFileArchive(l.Config.ArchiveDir); err != nil {
+				return
+			}
+			store.SetArchive(archive)
+		}
+		if l.Config.TxIndex {
+			store.EnableTxIndex()
+		}
+		store.StartValueLogGC(l.Config.ValueLogGCInterval, l.Config.ValueLogGCDiscardRatio, l.Config.Logger)
+		l.Store = store
+	case StoreSQLite:
+		dbPath := l.Config.StoreDBPath()
+		l.Config.Logger.WithField("path", dbPath).Debug("Attempting to load or create database")
+		l.Store, err = poset.OpenSQLiteStore(l.Peers, l.Config.NodeConfig.CacheSize, dbPath, &l.Config.PoSConfig)
+		if err != nil {
+			return
+		}
+	default:
+		return fmt.Errorf("unknown store %q, available: %s", l.Config.Store, strings.Join(StoreKinds, ","))
 	}
 
 	if l.Store.NeedBootstrap() {
@@ -145,12 +275,16 @@ func (l *DAG1) initNode() error {
 	nodePub := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey))
 	n, ok := l.Peers.ReadByPubKey(nodePub)
 
-	if !ok {
+	var nodeID uint64
+	if ok {
+		nodeID = n.ID
+	} else if l.joinInfo != nil {
+		// not a participant yet, the node runs as an observer
+		nodeID = peers.NewPeer(nodePub, l.Config.BindAddr).ID
+	} else {
 		return fmt.Errorf("cannot find self pubkey in peers.json")
 	}
 
-	nodeID := n.ID
-
 	l.Config.Logger.WithFields(logrus.Fields{
 		"participants": l.Peers,
 		"id":           nodeID,
@@ -186,10 +320,10 @@ func (l *DAG1) initNode() error {
 		}
 		selectorFn =  node.NewFrankyPeerSelectorWrapper
 	default:
-		panic(fmt.Errorf("Unknown peer selector %v", l.Config.PeerSelector))
+		return fmt.Errorf("unknown peer selector %v", l.Config.PeerSelector)
 	}
 
-	l.Node = node.NewNode(
+	nd, err := node.NewNode(
 		&l.Config.NodeConfig,
 		nodeID,
 		key,
@@ -201,17 +335,67 @@ func (l *DAG1) initNode() error {
 		selectorArgs,
 		l.Config.BindAddr,
 	)
+	if err != nil {
+		return fmt.Errorf("failed to create node: %s", err)
+	}
+	l.Node = nd
 
 	if err := l.Node.Init(); err != nil {
 		return fmt.Errorf("failed to initialize node: %s", err)
 	}
 
+	if l.Config.AuditLog != "" {
+		auditLog, err := poset.NewAuditLog(l.Config.AuditLog, nodeID, l.Config.AuditLogMaxSize)
+		if err != nil {
+			return fmt.Errorf("failed to open the audit log: %s", err)
+		}
+		l.auditLog = auditLog
+		l.Node.SetAuditSink(auditLog)
+	}
+
+	if l.Config.FlightRecInterval > 0 {
+		flightRec, err := node.NewFlightRecorder(
+			filepath.Join(l.Config.DataDir, "flightrec"), l.Config.FlightRecMaxSize)
+		if err != nil {
+			return fmt.Errorf("failed to open the flight recorder: %s", err)
+		}
+		l.flightRec = flightRec
+		l.Node.StartFlightRecorder(flightRec, l.Config.FlightRecInterval)
+	}
+
+	if l.joinInfo != nil {
+		if err := l.Node.Join(l.joinInfo); err != nil {
+			return fmt.Errorf("failed to join %s: %s", l.Config.JoinAddr, err)
+		}
+	}
+
 	return nil
 }
 
 func (l *DAG1) initService() error {
 	if l.Config.ServiceAddr != "" {
-		l.Service = service.NewService(l.Config.ServiceAddr, l.Node, l.Config.Logger)
+		opts := service.DefaultOptions()
+		opts.Admin = l.Config.Admin
+		opts.Debug = l.Config.ServiceDebug
+		opts.SetRequestTimeout(l.Config.ServiceTimeout)
+		l.Service = service.NewServiceWithOptions(l.Config.ServiceAddr, l.Node, opts, l.Config.Logger)
+		if l.Config.KV != nil {
+			l.Service.EnableKV(l.Config.KV)
+		}
+		token := l.Config.ServiceToken
+		if l.Config.ServiceTokenFile != "" {
+			data, err := ioutil.ReadFile(l.Config.ServiceTokenFile)
+			if err != nil {
+				return fmt.Errorf("failed to read the service token: %s", err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		if token != "" {
+			l.Service.EnableAuth(token, l.Config.ServiceAuthReads)
+		}
+		if l.Config.ServiceRPCCors != "" {
+			l.Service.EnableRPCCors(strings.Split(l.Config.ServiceRPCCors, ","))
+		}
 	}
 	return nil
 }
@@ -222,15 +406,30 @@ func (l *DAG1) Init() error {
 		l.Config.Logger = logrus.New()
 		dag1_log.NewLocal(l.Config.Logger, l.Config.LogLevel)
 	}
+	info := version.Get()
+	l.Config.Logger.WithFields(logrus.Fields{
+		"version":    info.Version,
+		"git_commit": info.GitCommit,
+		"build_date": info.BuildDate,
+		"go_version": info.GoVersion,
+	}).Info("DAG1 version")
 
 	if err := l.initPeers(); err != nil {
 		return err
 	}
 
+	if err := l.initNetworkID(); err != nil {
+		return err
+	}
+
 	if err := l.initStore(); err != nil {
 		return err
 	}
 
+	if err := l.checkStore(); err != nil {
+		return err
+	}
+
 	if err := l.initTransport(); err != nil {
 		return err
 	}
@@ -256,6 +455,50 @@ func (l *DAG1) Run() {
 		go l.Service.Serve()
 	}
 	l.Node.Run(true)
+	l.dumpStore()
+	l.savePeers()
+	if l.auditLog != nil {
+		if err := l.auditLog.Close(); err != nil {
+			l.Config.Logger.WithError(err).Error("Closing the audit log")
+		}
+	}
+	if l.flightRec != nil {
+		if err := l.flightRec.Close(); err != nil {
+			l.Config.Logger.WithError(err).Error("Closing the flight recorder")
+		}
+	}
+	if l.Service != nil {
+		if err := l.Service.Close(); err != nil {
+			l.Config.Logger.WithField("error", err).Error("Closing service")
+		}
+	}
+}
+
+// dumpStore dumps the in-mem store to InmemDumpOnExit, when set
+func (l *DAG1) dumpStore() {
+	store, ok := l.Store.(*poset.InmemStore)
+	if !ok || l.Config.InmemDumpOnExit == "" {
+		return
+	}
+	if err := store.DumpToFile(l.Config.InmemDumpOnExit); err != nil {
+		l.Config.Logger.WithError(err).Error("Dumping the in-mem store")
+		return
+	}
+	l.Config.Logger.WithField("path", l.Config.InmemDumpOnExit).Info("dumped the in-mem store")
+}
+
+// savePeers refreshes peers.json with the addresses the participants
+// announced, when it is where they were read from and some moved
+func (l *DAG1) savePeers() {
+	if !l.Config.LoadPeers || l.Config.JoinAddr != "" || !l.Node.AddressesMoved() {
+		return
+	}
+	peerStore := peers.NewJSONPeers(l.Config.DataDir)
+	if err := peerStore.SetPeerMessages(l.Peers.ToPeerSlice()); err != nil {
+		l.Config.Logger.WithError(err).Error("Saving the peer addresses")
+		return
+	}
+	l.Config.Logger.WithField("path", l.Config.DataDir).Info("saved the peer addresses to peers.json")
 }
 
 // Keygen generates a new key pair
@@ -7,38 +7,125 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/SamuelMarks/dag1/src/log"
 	"github.com/SamuelMarks/dag1/src/node"
 	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/poset"
 	"github.com/SamuelMarks/dag1/src/pos"
 	"github.com/SamuelMarks/dag1/src/proxy"
+	"github.com/SamuelMarks/dag1/src/service"
 )
 
+// PeerSelectors are the known values of PeerSelector
+var PeerSelectors = []string{"random", "smart", "fair", "unfair", "franky"}
+
+// The stores the poset is kept in, the values of Store
+const (
+	StoreInmem  = "inmem"
+	StoreBadger = "badger"
+	StoreSQLite = "sqlite"
+)
+
+// StoreKinds are the known values of Store
+var StoreKinds = []string{StoreInmem, StoreBadger, StoreSQLite}
+
 type DAG1Config struct {
 	DataDir     string `mapstructure:"datadir"`
 	BindAddr    string `mapstructure:"listen"`
 	ServiceAddr string `mapstructure:"service-listen"`
 	ServiceOnly bool   `mapstructure:"service-only"`
+	Admin       bool   `mapstructure:"admin"`
 	MaxPool     int    `mapstructure:"max-pool"`
-	Store       bool   `mapstructure:"store"`
+	// MinProtocolVersion is the lowest peer protocol version accepted
+	MinProtocolVersion uint32 `mapstructure:"min-protocol-version"`
+	// MaxMessageSize is the size of the largest peer message the node
+	// takes, 0 for no bound
+	MaxMessageSize int64 `mapstructure:"max-message-size"`
+	// Store is the kind of store the poset is kept in, one of StoreKinds.
+	// The former boolean values are still taken, see StoreKind.
+	Store string `mapstructure:"store"`
+	// StorePath is where the badger or sqlite store is, under DataDir when
+	// empty
+	StorePath string `mapstructure:"store-path"`
+	// ArchiveDir is where the badger store archives the final frames, none
+	// when empty
+	ArchiveDir  string `mapstructure:"archive-dir"`
+	// InmemDumpOnExit is where the in-mem store is dumped on a graceful
+	// shutdown, for poset.LoadOrCreateInmemStoreFromDump, none when empty
+	InmemDumpOnExit string `mapstructure:"inmem-dump-on-exit"`
+	// TxIndex makes the store index the transactions by the hash of their
+	// content
+	TxIndex     bool   `mapstructure:"tx-index"`
+	// ValueLogGCInterval is the time between the value log GCs of the
+	// badger store, none when 0, and ValueLogGCDiscardRatio the share of
+	// discarded space above which a value log file is rewritten
+	ValueLogGCInterval     time.Duration `mapstructure:"value-log-gc-interval"`
+	ValueLogGCDiscardRatio float64       `mapstructure:"value-log-gc-discard-ratio"`
+	// AuditLog is the file the consensus decisions are appended to, see
+	// poset.AuditLog, none when empty. It is rotated at AuditLogMaxSize
+	// bytes.
+	AuditLog        string `mapstructure:"audit-log"`
+	AuditLogMaxSize int64  `mapstructure:"audit-log-max-size"`
+	// FlightRecInterval is the time between the snapshots of the metrics
+	// of the node kept in the flightrec directory of DataDir, none when 0,
+	// see node.FlightRecorder. They take FlightRecMaxSize bytes at most.
+	FlightRecInterval time.Duration `mapstructure:"flightrec-interval"`
+	FlightRecMaxSize  int64         `mapstructure:"flightrec-max-size"`
 	LogLevel    string `mapstructure:"log"`
+	JoinAddr    string `mapstructure:"join"`
+	// JoinTrust takes the participants of the join peer at its word when
+	// there is no peers.json in DataDir to check its anchor block against
+	JoinTrust bool `mapstructure:"join-trust"`
+
+	// ServiceToken, or the content of ServiceTokenFile, is the bearer token
+	// the service requires on the requests which change the node, and on the
+	// reads too if ServiceAuthReads
+	ServiceToken     string `mapstructure:"service-token"`
+	ServiceTokenFile string `mapstructure:"service-token-file"`
+	ServiceAuthReads bool   `mapstructure:"service-auth-reads"`
+	// ServiceRPCCors are the comma separated origins, or "*", of the web
+	// pages allowed to call the JSON-RPC endpoint of the service
+	ServiceRPCCors   string `mapstructure:"service-rpc-cors"`
+	// ServiceTimeout bounds the time the service takes to answer a request,
+	// none when 0, and ServiceDebug mounts its /debug/ endpoints
+	ServiceTimeout time.Duration `mapstructure:"service-timeout"`
+	ServiceDebug   bool          `mapstructure:"service-debug"`
+
+	// ForcePeerChange starts a node whose store was made for other
+	// participants than peers.json, as an observer
+	ForcePeerChange bool `mapstructure:"force-peer-change"`
 
 	NodeConfig node.Config `mapstructure:",squash"`
 	PoSConfig  pos.Config  `mapstructure:",squash"`
 
 	LoadPeers bool
 	Proxy     proxy.AppProxy
+	// KV is the state of a key/value app embedded in the process, served by
+	// the service when set
+	KV        service.KVQuerier
 	Key       *ecdsa.PrivateKey
 	Logger    *logrus.Logger
 
 	ConnFunc peer.CreateNetConnFunc
+	// WrapTransport wraps the transport of the node when set, as the network
+	// command does to put a simulated network between its nodes
+	WrapTransport func(peer.SyncPeer) peer.SyncPeer
 
 	Test      bool   `mapstructure:"test"`
 	TestN     uint64 `mapstructure:"test_n"`
 	TestDelay uint64 `mapstructure:"test_delay"`
+	// TestPayload, TestTPS, TestDuration, TestDistribution and TestFinality
+	// shape the load the test sends, see tester.Config
+	TestPayload      int           `mapstructure:"test_payload"`
+	TestTPS          float64       `mapstructure:"test_tps"`
+	TestDuration     time.Duration `mapstructure:"test_duration"`
+	TestDistribution string        `mapstructure:"test_distribution"`
+	TestFinality     bool          `mapstructure:"test_finality"`
 	PeerSelector string `mapstructure:"peer_selector"`
 }
 
@@ -48,11 +135,20 @@ func NewDefaultConfig() *DAG1Config {
 		BindAddr:    ":1337",
 		ServiceAddr: ":8000",
 		ServiceOnly: false,
+		ServiceTimeout: service.DefaultOptions().RequestTimeout,
+		ServiceDebug:   service.DefaultOptions().Debug,
 		ConnFunc:    net.DialTimeout,
 		MaxPool:     2,
+		MinProtocolVersion: peer.MinProtocolVersion,
+		MaxMessageSize:     peer.DefaultMaxMessageSize,
 		NodeConfig:  *node.DefaultConfig(),
 		PoSConfig:   *pos.DefaultConfig(),
-		Store:       false,
+		Store:       StoreInmem,
+		ValueLogGCInterval:     10 * time.Minute,
+		ValueLogGCDiscardRatio: poset.DefaultValueLogGCDiscardRatio,
+		AuditLogMaxSize:        poset.DefaultAuditLogMaxSize,
+		FlightRecInterval:      10 * time.Second,
+		FlightRecMaxSize:       node.DefaultFlightRecMaxSize,
 		LogLevel:    "info",
 		Proxy:       nil,
 		Logger:      logrus.New(),
@@ -61,10 +157,12 @@ func NewDefaultConfig() *DAG1Config {
 		Test:        false,
 		TestN:       ^uint64(0),
 		TestDelay:   1,
+		TestPayload: 120,
+		TestDistribution: "random",
 		PeerSelector: "smart",
 	}
 
-	config.Logger.Level = LogLevel(config.LogLevel)
+	config.Logger.Level = logrus.InfoLevel
 	dag1_log.NewLocal(config.Logger, config.LogLevel)
 	//config.Proxy = sproxy.NewInmemAppProxy(config.Logger)
 	//config.Proxy, _ = sproxy.NewSocketAppProxy("127.0.0.1:1338", "127.0.0.1:1339", 1*time.Second, config.Logger)
@@ -86,6 +184,34 @@ func (c *DAG1Config) BadgerDir() string {
 	return filepath.Join(c.DataDir, "badger_db")
 }
 
+// StoreKind returns the kind of store of Store, where true, "1" and the
+// other booleans the --store flag took stand for the badger store and
+// false for the in-mem one
+func (c *DAG1Config) StoreKind() string {
+	if c.Store == "" {
+		return StoreInmem
+	}
+	if b, err := strconv.ParseBool(c.Store); err == nil {
+		if b {
+			return StoreBadger
+		}
+		return StoreInmem
+	}
+	return c.Store
+}
+
+// StoreDBPath returns where the database of the store is: StorePath, else
+// the badger_db directory or the dag1.sqlite file of the data dir
+func (c *DAG1Config) StoreDBPath() string {
+	if c.StorePath != "" {
+		return c.StorePath
+	}
+	if c.StoreKind() == StoreSQLite {
+		return filepath.Join(c.DataDir, "dag1.sqlite")
+	}
+	return c.BadgerDir()
+}
+
 func DefaultDataDir() string {
 	// Try to place the data folder in the user's home dir
 	home := HomeDir()
@@ -112,21 +238,14 @@ func HomeDir() string {
 	return ""
 }
 
-func LogLevel(l string) logrus.Level {
-	switch l {
-	case "debug":
-		return logrus.DebugLevel
-	case "info":
-		return logrus.InfoLevel
-	case "warn":
-		return logrus.WarnLevel
-	case "error":
-		return logrus.ErrorLevel
-	case "fatal":
-		return logrus.FatalLevel
-	case "panic":
-		return logrus.PanicLevel
-	default:
-		return logrus.DebugLevel
+// SetLogLevels applies a --log value such as "info" or
+// "poset=warn,node=debug" to logger. The module levels are picked up by the
+// poset, node, peer, proxy and service loggers derived from it.
+func SetLogLevels(logger *logrus.Logger, l string) error {
+	levels, err := dag1_log.ParseLevels(l)
+	if err != nil {
+		return err
 	}
+	dag1_log.SetLevels(logger, levels)
+	return nil
 }
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"sync"
@@ -43,7 +45,18 @@ func initPeers(
 		))
 	}
 
-	return keys, ps, adds
+	// the keys and addresses follow the order of the peers, sorted by ID
+	sortedKeys := make([]*ecdsa.PrivateKey, number)
+	sortedAdds := make([]string, number)
+	for i, peer := range ps.ToPeerSlice() {
+		for j, key := range keys {
+			if peer.Message.PubKeyHex == fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)) {
+				sortedKeys[i], sortedAdds[i] = key, adds[j]
+			}
+		}
+	}
+
+	return sortedKeys, ps, sortedAdds
 }
 
 func createNetwork() (*fakenet.Network, peer.CreateSyncClientFunc) {
@@ -83,20 +96,46 @@ func transportClose(t testing.TB, syncPeer peer.SyncPeer) {
 func runNode(t testing.TB, logger *logrus.Logger, config *node.Config,
 	id uint64, key *ecdsa.PrivateKey, participants *peers.Peers,
 	trans peer.SyncPeer, localAddr string, run bool) *node.Node {
+	node := initNode(t, logger, config, id, key, participants, trans, localAddr)
+	go node.Run(run)
+	return node
+}
+
+// initNode creates and initialises a node of the test without running it
+func initNode(t testing.TB, logger *logrus.Logger, config *node.Config,
+	id uint64, key *ecdsa.PrivateKey, participants *peers.Peers,
+	trans peer.SyncPeer, localAddr string) *node.Node {
+	participants = nodePeers(participants)
 	db := poset.NewInmemStore(participants, config.CacheSize, nil)
 	app := dummy.NewInmemDummyApp(logger)
 	selectorArgs := node.SmartPeerSelectorCreationFnArgs{
 		LocalAddr: localAddr,
 		GetFlagTable: nil,
 	}
-	node := node.NewNode(config, id, key, participants, db, trans, app, node.NewSmartPeerSelectorWrapper, selectorArgs, localAddr)
+	node, err := node.NewNode(config, id, key, participants, db, trans, app, node.NewSmartPeerSelectorWrapper, selectorArgs, localAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if err := node.Init(); err != nil {
 		t.Fatal(err)
 	}
-	go node.Run(run)
 	return node
 }
 
+// nodePeers returns a copy of a peer set for a node of the test, nodes
+// sharing a set would see the events of each other as known
+func nodePeers(ps *peers.Peers) *peers.Peers {
+	res := peers.NewPeers()
+	for _, p := range ps.ToPeerSlice() {
+		peer := peers.NewPeer(p.Message.PubKeyHex, p.Message.NetAddr)
+		res.AddPeer(peer)
+		if w := p.GetWeight(); w != 0 {
+			res.SetPeerWeight(peer, w)
+		}
+	}
+	return res
+}
+
 func TestGossip(t *testing.T) {
 
 	poolSize := 2
@@ -188,6 +227,7 @@ func TestMissingNodeGossip(t *testing.T) {
 	defer transportClose(t, trans4)
 
 	node1 := runNode(t, logger, config, ps[0].ID, keys[0], p, trans1, adds[0], true)
+	defer node1.Shutdown()
 
 	node2 := runNode(t, logger, config, ps[1].ID, keys[1], p, trans2, adds[1], true)
 
@@ -288,6 +328,13 @@ func TestCatchUp(t *testing.T) {
 	caught := false
 	logger := common.NewTestLogger(t)
 	config := node.TestConfig(t)
+	// the blocks come a few rounds after their frame, the rounds are quicker
+	// with a shorter heartbeat
+	config.HeartbeatTimeout = 200 * time.Millisecond
+	// node4 knows none of the events of the first blocks when it starts, far
+	// beyond the limit. Once caught up it lacks the events of the rounds the
+	// last block waits for, within it.
+	config.SyncLimit = 50
 
 	poolSize := 2
 	backConfig := peer.NewBackendConfig()
@@ -321,7 +368,7 @@ func TestCatchUp(t *testing.T) {
 
 	normalNodes := []*node.Node{node1, node2, node3}
 
-	target := int64(3)
+	target := int64(10)
 
 	err := gossip(normalNodes, target, false, 30*time.Second)
 	if err != nil {
@@ -333,8 +380,7 @@ func TestCatchUp(t *testing.T) {
 		poolSize, createFu, network.CreateListener)
 	defer transportClose(t, trans4)
 
-	node4 := runNode(t, logger, config, ps[3].ID, keys[3], p, trans4, adds[3], false)
-	node4.Shutdown()
+	node4 := initNode(t, logger, config, ps[3].ID, keys[3], p, trans4, adds[3])
 
 	// Run parallel routine to check node4 eventually reaches CatchingUp state.
 	timeout := time.After(30 * time.Second)
@@ -345,12 +391,12 @@ func TestCatchUp(t *testing.T) {
 			select {
 			case <-timeout:
 				t.Logf("Timeout waiting for node4 to enter CatchingUp state")
-				break
+				return
 			default:
 			}
 			if node4.GetState() == node.CatchingUp {
 				caught = true
-				break
+				return
 			}
 		}
 	}()
@@ -362,7 +408,7 @@ func TestCatchUp(t *testing.T) {
 	nodes := append(normalNodes, node4)
 	newTarget := target + 4
 
-	err = bombardAndWait(nodes, newTarget, 20*time.Second)
+	err = bombardAndWait(nodes, newTarget, 30*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -624,3 +670,29 @@ func BenchmarkGossip(b *testing.B) {
 	}
 }
 
+func TestJoinTrustedPeers(t *testing.T) {
+	f := newCheckFixture(t, 4)
+	defer os.RemoveAll(f.dir)
+
+	engine := NewDAG1(&DAG1Config{DataDir: f.dir, JoinAddr: "127.0.0.1:12000"})
+	trusted, err := engine.joinTrustedPeers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trusted.Len() != 4 {
+		t.Fatalf("expected the 4 participants of peers.json, got %d", trusted.Len())
+	}
+
+	if err := os.Remove(filepath.Join(f.dir, "peers.json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.joinTrustedPeers(); err == nil {
+		t.Fatal("expected an error with no peers.json and no --join-trust")
+	}
+
+	engine.Config.JoinTrust = true
+	trusted, err = engine.joinTrustedPeers()
+	if err != nil || trusted != nil {
+		t.Fatalf("expected to trust the join peer, got %v, %v", trusted, err)
+	}
+}
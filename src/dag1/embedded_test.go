@@ -0,0 +1,138 @@
+package dag1
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/utils"
+)
+
+// newEmbeddedKV starts a node running the key/value app in process, as
+// dag1 run --embedded-app=kv does, and returns it with the function which
+// shuts it down and waits for it to stop
+func newEmbeddedKV(t *testing.T, key *ecdsa.PrivateKey, keys []*ecdsa.PrivateKey,
+	adds []string, bindAddr, serviceAddr string) (*DAG1, func()) {
+	config := NewDefaultConfig()
+	config.Logger = common.NewTestLogger(t)
+	config.NodeConfig.Logger = config.Logger
+	config.NodeConfig.HeartbeatTimeout = 10 * time.Millisecond
+	config.BindAddr = bindAddr
+	config.ServiceAddr = serviceAddr
+	config.LoadPeers = false
+	config.Key = key
+	config.Proxy, config.KV = dummy.NewInmemKVDummyApp(config.Logger)
+
+	engine := NewDAG1(config)
+	engine.Peers = peers.NewPeers()
+	for i, k := range keys {
+		peer := peers.NewPeer(fmt.Sprintf("0x%X", crypto.FromECDSAPub(&k.PublicKey)), adds[i])
+		engine.Peers.AddPeer(peer)
+		engine.Peers.SetPeerWeight(peer, 1)
+	}
+	if err := engine.Init(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		engine.Run()
+	}()
+	stop := func() {
+		engine.Node.Shutdown()
+		<-done
+	}
+
+	// wait for the service to listen
+	for i := 0; ; i++ {
+		resp, err := http.Get("http://" + serviceAddr + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i == 50 {
+			stop()
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return engine, stop
+}
+
+func TestEmbeddedKV(t *testing.T) {
+	const n = 2
+	adds := utils.GetUnusedNetAddr(2*n, t)
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateECDSAKey()
+	}
+	var engines []*DAG1
+	for i := 0; i < n; i++ {
+		engine, stop := newEmbeddedKV(t, keys[i], keys, adds[:n], adds[i], adds[n+i])
+		defer stop()
+		engines = append(engines, engine)
+	}
+	// the transactions are refused until the first node synced with a quorum
+	timeout := time.After(30 * time.Second)
+	for !engines[0].Node.HasQuorum() {
+		select {
+		case <-timeout:
+			t.Fatal("the first node did not reach a quorum")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	expected := map[string]string{"a": "1", "b": "2", "c": "3"}
+	for k, v := range expected {
+		resp, err := http.Post("http://"+adds[n]+"/tx", "text/plain",
+			bytes.NewReader(dummy.SetTx(k, v)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d", http.StatusAccepted, resp.StatusCode)
+		}
+	}
+
+	// the keys written through the first node are read from the second
+	get := func(key string) (string, bool) {
+		resp, err := http.Get("http://" + adds[n+1] + "/kv/" + key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return "", false
+		}
+		var kv map[string]string
+		if err := json.NewDecoder(resp.Body).Decode(&kv); err != nil {
+			t.Fatal(err)
+		}
+		return kv["value"], true
+	}
+	for k, v := range expected {
+		for {
+			value, ok := get(k)
+			if ok {
+				if value != v {
+					t.Fatalf("expected %s to be %q, got %q", k, v, value)
+				}
+				break
+			}
+			select {
+			case <-timeout:
+				t.Fatalf("key %s did not reach the second node", k)
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package dag1
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/node"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/proxy"
+)
+
+// Engine is a dag1 node embedded in a Go program. It is configured with
+// Options rather than with the command line, config files and peers.json,
+// and never ends the process: its failures are returned as errors.
+type Engine struct {
+	config *DAG1Config
+	peers  *peers.Peers
+	dag1   *DAG1
+
+	runLock  sync.Mutex
+	started  bool
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Option configures an Engine made by NewEngine
+type Option func(*Engine) error
+
+// WithConfig starts from config instead of NewDefaultConfig. As it replaces
+// the whole config, it goes before the other options.
+func WithConfig(config *DAG1Config) Option {
+	return func(e *Engine) error {
+		if config == nil {
+			return fmt.Errorf("nil config")
+		}
+		e.config = config
+		return nil
+	}
+}
+
+// WithKey sets the private key of the node. Without a key, one is read from
+// the data dir, or generated and written there.
+func WithKey(key *ecdsa.PrivateKey) Option {
+	return func(e *Engine) error {
+		e.config.Key = key
+		return nil
+	}
+}
+
+// WithKeyPEM sets the private key of the node from its PEM encoding, as
+// dag1 keygen writes it
+func WithKeyPEM(pem []byte) Option {
+	return func(e *Engine) error {
+		key, err := (&crypto.PemKey{}).ReadKeyFromBuf(pem)
+		if err != nil {
+			return fmt.Errorf("failed to read private key: %s", err)
+		}
+		if key == nil {
+			return fmt.Errorf("failed to read private key: empty PEM")
+		}
+		e.config.Key = key
+		return nil
+	}
+}
+
+// WithPeers sets the participants instead of reading them from peers.json.
+// They are copied, so that the same slice may configure several engines.
+func WithPeers(participants []*peers.Peer) Option {
+	return func(e *Engine) error {
+		if len(participants) < 2 {
+			return fmt.Errorf("should define at least two peers")
+		}
+		copies := make([]*peers.Peer, len(participants))
+		for i, p := range participants {
+			copies[i] = peers.NewPeer(p.Message.PubKeyHex, p.Message.NetAddr)
+			copies[i].SetWeight(p.GetWeight())
+		}
+		e.peers = peers.NewPeersFromSlice(copies)
+		e.config.LoadPeers = false
+		return nil
+	}
+}
+
+// WithDataDir sets the directory of the key, peers.json and the badger
+// store
+func WithDataDir(dir string) Option {
+	return func(e *Engine) error {
+		e.config.DataDir = dir
+		return nil
+	}
+}
+
+// WithBadgerStore keeps the poset in a badger database under the data dir,
+// loading it when it exists
+func WithBadgerStore() Option {
+	return func(e *Engine) error {
+		e.config.Store = StoreBadger
+		return nil
+	}
+}
+
+// WithSQLiteStore keeps the poset in a sqlite database under the data dir,
+// loading it when it exists. The node fails to start in a build without
+// the sqlite tag.
+func WithSQLiteStore() Option {
+	return func(e *Engine) error {
+		e.config.Store = StoreSQLite
+		return nil
+	}
+}
+
+// WithInmemStore keeps the poset in memory, the default
+func WithInmemStore() Option {
+	return func(e *Engine) error {
+		e.config.Store = StoreInmem
+		return nil
+	}
+}
+
+// WithProxy sets the app the blocks are committed to and the transactions
+// come from
+func WithProxy(p proxy.AppProxy) Option {
+	return func(e *Engine) error {
+		e.config.Proxy = p
+		return nil
+	}
+}
+
+// WithListen sets the IP:Port the node gossips on
+func WithListen(addr string) Option {
+	return func(e *Engine) error {
+		e.config.BindAddr = addr
+		return nil
+	}
+}
+
+// WithService sets the IP:Port of the HTTP service, "" for none
+func WithService(addr string) Option {
+	return func(e *Engine) error {
+		e.config.ServiceAddr = addr
+		return nil
+	}
+}
+
+// WithLogger sets the logger of the engine and its node
+func WithLogger(logger *logrus.Logger) Option {
+	return func(e *Engine) error {
+		e.config.Logger = logger
+		e.config.NodeConfig.Logger = logger
+		return nil
+	}
+}
+
+// NewEngine makes an engine from NewDefaultConfig and the options, and
+// initializes its node: the peers, store, transport and key are set up and
+// the node listens, but it gossips only once started.
+func NewEngine(opts ...Option) (*Engine, error) {
+	e := &Engine{
+		config: NewDefaultConfig(),
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+
+	if e.config.Proxy == nil {
+		return nil, fmt.Errorf("no app proxy")
+	}
+	if e.config.NodeConfig.CacheSize <= 0 {
+		return nil, fmt.Errorf("cache size %d is not positive", e.config.NodeConfig.CacheSize)
+	}
+	if e.config.NodeConfig.Logger == nil {
+		e.config.NodeConfig.Logger = e.config.Logger
+	}
+
+	e.dag1 = NewDAG1(e.config)
+	if e.peers != nil {
+		e.dag1.Peers = e.peers
+	}
+	if err := e.dag1.Init(); err != nil {
+		if e.dag1.Transport != nil {
+			e.dag1.Transport.Close()
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// Start serves the service and runs the node until Stop is called or ctx
+// is done. It returns at once, Done tells when the node has stopped.
+func (e *Engine) Start(ctx context.Context) error {
+	e.runLock.Lock()
+	defer e.runLock.Unlock()
+	if e.started {
+		return fmt.Errorf("engine already started")
+	}
+	e.started = true
+
+	go func() {
+		defer close(e.done)
+		e.dag1.Run()
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			e.Stop()
+		case <-e.done:
+		}
+	}()
+	return nil
+}
+
+// Stop shuts the node down and, when it was started, waits for it to stop
+func (e *Engine) Stop() {
+	e.stopOnce.Do(e.dag1.Node.Shutdown)
+
+	e.runLock.Lock()
+	started := e.started
+	e.runLock.Unlock()
+	if started {
+		<-e.done
+	}
+}
+
+// Done is closed once a started engine has stopped
+func (e *Engine) Done() <-chan struct{} {
+	return e.done
+}
+
+// Node returns the node of the engine
+func (e *Engine) Node() *node.Node {
+	return e.dag1.Node
+}
+
+// Peers returns the participants of the engine
+func (e *Engine) Peers() *peers.Peers {
+	return e.dag1.Peers
+}
+
+// Config returns the configuration of the engine
+func (e *Engine) Config() *DAG1Config {
+	return e.config
+}
@@ -0,0 +1,97 @@
+package dag1_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/dag1"
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// freeAddr returns a local address nothing listens on
+func freeAddr() string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// ExampleNewEngine runs a network of two nodes in process, committing to
+// dummy apps, until a transaction reaches consensus on both.
+func ExampleNewEngine() {
+	logger := logrus.New()
+	logger.Level = logrus.ErrorLevel
+
+	const n = 2
+	keys := make([]*ecdsa.PrivateKey, n)
+	addrs := make([]string, n)
+	var participants []*peers.Peer
+	for i := range keys {
+		keys[i], _ = crypto.GenerateECDSAKey()
+		addrs[i] = freeAddr()
+		pub := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&keys[i].PublicKey))
+		participants = append(participants, peers.NewPeer(pub, addrs[i]))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var engines []*dag1.Engine
+	for i := range keys {
+		engine, err := dag1.NewEngine(
+			dag1.WithLogger(logger),
+			dag1.WithKey(keys[i]),
+			dag1.WithPeers(participants),
+			dag1.WithInmemStore(),
+			dag1.WithProxy(dummy.NewInmemDummyApp(logger)),
+			dag1.WithListen(addrs[i]),
+			dag1.WithService(""),
+		)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := engine.Start(ctx); err != nil {
+			fmt.Println(err)
+			return
+		}
+		engines = append(engines, engine)
+	}
+
+	// the transaction is refused until the node has synced with its peer
+	deadline := time.Now().Add(30 * time.Second)
+	for engines[0].Node().SubmitTxWithResult([]byte("hello")) != nil {
+		if time.Now().After(deadline) {
+			fmt.Println("transaction refused")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, engine := range engines {
+		for engine.Node().GetConsensusTransactionsCount() == 0 {
+			if time.Now().After(deadline) {
+				fmt.Println("no consensus")
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	fmt.Println("committed by", len(engines), "nodes")
+
+	cancel()
+	for _, engine := range engines {
+		<-engine.Done()
+	}
+	// Output:
+	// committed by 2 nodes
+}
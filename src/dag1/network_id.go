@@ -0,0 +1,70 @@
+package dag1
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/kvdb"
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/pos"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/state"
+)
+
+// protocol returns the peer protocol of the node, with its network ID once
+// known
+func (l *DAG1) protocol() peer.Protocol {
+	protocol := peer.DefaultProtocol()
+	if l.Config.MinProtocolVersion > 0 {
+		protocol.MinVersion = l.Config.MinProtocolVersion
+	}
+	protocol.MaxMessageSize = l.Config.MaxMessageSize
+	protocol.NetworkID = l.Config.NodeConfig.NetworkID
+	protocol.AcceptLegacyNetwork = l.Config.NodeConfig.NetworkIDCompat
+	return protocol
+}
+
+// protocolSetter is implemented by the transports negotiating a protocol,
+// peer.Peer and the simulation transport wrapping it
+type protocolSetter interface {
+	SetProtocol(peer.Protocol)
+}
+
+// initNetworkID derives the network ID from the genesis state root of the
+// participants and the name of the chain, when one is set. A node joining
+// brings its transport up before it knows the participants, so its inbound
+// handshakes do not check the ID, only its outbound ones and the events do.
+func (l *DAG1) initNetworkID() error {
+	conf := &l.Config.NodeConfig
+	if conf.NetworkName == "" {
+		return nil
+	}
+	id, err := NetworkID(l.Peers, &l.Config.PoSConfig, conf.NetworkName)
+	if err != nil {
+		return err
+	}
+	conf.NetworkID = id
+	if tr, ok := l.Transport.(protocolSetter); ok {
+		// already brought up by initJoin
+		tr.SetProtocol(l.protocol())
+	}
+
+	l.Config.Logger.WithFields(logrus.Fields{
+		"network":    conf.NetworkName,
+		"network_id": conf.NetworkID.Hex(),
+		"compat":     conf.NetworkIDCompat,
+	}).Info("network ID")
+	return nil
+}
+
+// NetworkID derives the network ID of the participants from their genesis
+// state root and the name of the chain
+func NetworkID(participants *peers.Peers, conf *pos.Config, name string) (common.Hash, error) {
+	genesis, err := pos.FakeGenesis(participants, conf,
+		state.NewDatabase(kvdb.NewMemDatabase()))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return poset.NetworkID(genesis, name), nil
+}
@@ -88,7 +88,7 @@ func compareRounds(diff *Diff) bool {
 
 	diff.RoundGap = n0.GetLastRound() - n1.GetLastRound()
 
-	var r0, r1 poset.RoundCreated
+	var r0, r1 poset.Round
 	var i int64
 	for i = 0; i <= diff.FirstRoundIndex; i++ {
 
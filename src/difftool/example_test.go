@@ -14,7 +14,10 @@ func Example() {
 	logger := logrus.New()
 	logger.Level = logrus.FatalLevel
 
-	nodes := node.NewNodeList(3, logger)
+	nodes, err := node.NewNodeList(3, logger)
+	if err != nil {
+		panic(err)
+	}
 
 	stop := nodes.StartRandTxStream()
 	nodes.WaitForBlock(5)
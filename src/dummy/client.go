@@ -20,14 +20,26 @@ func NewInmemDummyApp(logger *logrus.Logger) proxy.AppProxy {
 	return proxy.NewInmemAppProxy(state, logger)
 }
 
+// NewInmemKVDummyApp constructor of an app keeping a KVState
+func NewInmemKVDummyApp(logger *logrus.Logger) (proxy.AppProxy, *KVState) {
+	state := NewKVState(logger)
+	return proxy.NewInmemAppProxy(state, logger), state
+}
+
 // NewDummySocketClient constructor
 func NewDummySocketClient(addr string, logger *logrus.Logger) (*DummyClient, error) {
+	return NewDummySocketClientWithHandler(addr, nil, logger)
+}
+
+// NewDummySocketClientWithHandler constructor of a client applying the
+// committed blocks to handler
+func NewDummySocketClientWithHandler(addr string, handler proxy.ProxyHandler, logger *logrus.Logger) (*DummyClient, error) {
 	dag1Proxy, err := proxy.NewGrpcDAG1Proxy(addr, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewDummyClient(dag1Proxy, nil, logger)
+	return NewDummyClient(dag1Proxy, handler, logger)
 }
 
 // NewDummyClient instantiates an implementation of the dummy app
@@ -82,3 +94,8 @@ func NewDummyClient(dag1Proxy proxy.DAG1Proxy, handler proxy.ProxyHandler, logge
 func (c *DummyClient) SubmitTx(tx []byte) error {
 	return c.dag1Proxy.SubmitTx(tx)
 }
+
+// SubmitTxWithFlags sends a flagged transaction to node via proxy
+func (c *DummyClient) SubmitTxWithFlags(tx []byte, flags byte) error {
+	return c.dag1Proxy.SubmitTxWithFlags(tx, flags)
+}
@@ -0,0 +1,169 @@
+package dummy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+/*
+ * KVState is a dummy state with something to check: transactions
+ * "set:key=value" and "del:key" edit a key/value map, any other transaction is
+ * ignored. The state hash is the hash of the map encoded with sorted keys, so
+ * that nodes applying the same blocks end up with the same hash.
+ */
+
+const (
+	kvSetPrefix = "set:"
+	kvDelPrefix = "del:"
+	// kvSnapshots is how many snapshots of the latest blocks are kept
+	kvSnapshots = 64
+)
+
+// KVState implements ProxyHandler
+type KVState struct {
+	logger    *logrus.Logger
+	kv        map[string]string
+	stateHash []byte
+	snapshots map[int64][]byte
+	locker    sync.RWMutex
+}
+
+// NewKVState constructor
+func NewKVState(logger *logrus.Logger) *KVState {
+	state := &KVState{
+		logger:    logger,
+		kv:        make(map[string]string),
+		snapshots: make(map[int64][]byte),
+	}
+	state.stateHash = crypto.Keccak256(state.encode())
+	logger.Info("Init Dummy KV State")
+
+	return state
+}
+
+// SetTx returns the transaction setting key to value
+func SetTx(key, value string) []byte {
+	return []byte(kvSetPrefix + key + "=" + value)
+}
+
+// DelTx returns the transaction deleting key
+func DelTx(key string) []byte {
+	return []byte(kvDelPrefix + key)
+}
+
+/*
+ * inmem interface: ProxyHandler implementation
+ */
+
+// CommitHandler applies the block transactions to the map
+func (s *KVState) CommitHandler(block poset.Block) ([]byte, error) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.logger.WithField("block", block.Index()).Debug("CommitBlock")
+
+	for _, tx := range block.Transactions() {
+		if err := s.apply(tx); err != nil {
+			s.logger.WithError(err).Debug("Ignoring transaction")
+		}
+	}
+	snapshot := s.encode()
+	s.stateHash = crypto.Keccak256(snapshot)
+	s.snapshots[block.Index()] = snapshot
+	delete(s.snapshots, block.Index()-kvSnapshots)
+
+	s.logger.WithField("stateHash", s.stateHash).Debug("CommitBlock Answer")
+	return s.stateHash, nil
+}
+
+// SnapshotHandler returns the map as it was after the block, one of the
+// latest kvSnapshots blocks
+func (s *KVState) SnapshotHandler(blockIndex int64) ([]byte, error) {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	s.logger.WithField("block", blockIndex).Debug("GetSnapshot")
+
+	snapshot, ok := s.snapshots[blockIndex]
+	if !ok {
+		return nil, fmt.Errorf("snapshot %d not found", blockIndex)
+	}
+
+	return snapshot, nil
+}
+
+// RestoreHandler replaces the map with the one in the snapshot
+func (s *KVState) RestoreHandler(snapshot []byte) ([]byte, error) {
+	kv := make(map[string]string)
+	if err := json.Unmarshal(snapshot, &kv); err != nil {
+		return nil, err
+	}
+
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.kv = kv
+	s.stateHash = crypto.Keccak256(s.encode())
+	return s.stateHash, nil
+}
+
+/*
+ * staff:
+ */
+
+// Query returns the value of key
+func (s *KVState) Query(key string) (string, bool) {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	value, ok := s.kv[key]
+	return value, ok
+}
+
+// Len returns the number of keys
+func (s *KVState) Len() int {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	return len(s.kv)
+}
+
+// StateHash returns the hash of the current map
+func (s *KVState) StateHash() []byte {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	return s.stateHash
+}
+
+func (s *KVState) apply(tx []byte) error {
+	op := string(tx)
+	switch {
+	case strings.HasPrefix(op, kvSetPrefix):
+		pair := strings.SplitN(op[len(kvSetPrefix):], "=", 2)
+		if len(pair) != 2 || pair[0] == "" {
+			return fmt.Errorf("malformed set %q", op)
+		}
+		s.kv[pair[0]] = pair[1]
+	case strings.HasPrefix(op, kvDelPrefix):
+		key := op[len(kvDelPrefix):]
+		if key == "" {
+			return fmt.Errorf("malformed del %q", op)
+		}
+		delete(s.kv, key)
+	default:
+		return fmt.Errorf("unknown operation %q", op)
+	}
+	return nil
+}
+
+// encode serializes the map, encoding/json sorts the keys
+func (s *KVState) encode() []byte {
+	data, err := json.Marshal(s.kv)
+	if err != nil {
+		// a map of strings always encodes
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,122 @@
+package dummy
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy"
+)
+
+func TestKVStateProxyHandlerImplementation(t *testing.T) {
+	logger := common.NewTestLogger(t)
+
+	state := interface{}(
+		NewKVState(logger))
+
+	_, ok := state.(proxy.ProxyHandler)
+	if !ok {
+		t.Fatal("KVState does not implement ProxyHandler interface!")
+	}
+}
+
+func TestKVStateCommit(t *testing.T) {
+	logger := common.NewTestLogger(t)
+	state := NewKVState(logger)
+
+	blocks := []poset.Block{
+		poset.NewBlock(0, 1, []byte{}, [][]byte{
+			SetTx("a", "1"),
+			SetTx("b", "x=y"),
+			[]byte("not an operation"),
+			[]byte("set:novalue"),
+		}),
+		poset.NewBlock(1, 2, []byte{}, [][]byte{
+			SetTx("a", "2"),
+			DelTx("b"),
+			DelTx("missing"),
+			SetTx("c", "3"),
+		}),
+	}
+
+	hash0, err := state.CommitHandler(blocks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := state.Query("b"); v != "x=y" {
+		t.Fatalf("expected b to be x=y, got %q", v)
+	}
+	if state.Len() != 2 {
+		t.Fatalf("expected 2 keys, got %d", state.Len())
+	}
+
+	hash1, err := state.CommitHandler(blocks[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"a": "2", "c": "3"}
+	for k, v := range expected {
+		if got, ok := state.Query(k); !ok || got != v {
+			t.Fatalf("expected %s to be %s, got %q", k, v, got)
+		}
+	}
+	if _, ok := state.Query("b"); ok {
+		t.Fatal("expected b to be deleted")
+	}
+
+	// the same map reached in another order has the same hash
+	other := NewKVState(logger)
+	if _, err := other.CommitHandler(poset.NewBlock(0, 1, []byte{}, [][]byte{
+		SetTx("c", "3"),
+		SetTx("b", "gone"),
+		SetTx("a", "2"),
+		DelTx("b"),
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hash1, other.StateHash()) {
+		t.Fatal("expected equal maps to have equal state hashes")
+	}
+
+	// restoring the first snapshot brings back the first map
+	snapshot, err := state.SnapshotHandler(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := other.RestoreHandler(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hash0, restored) {
+		t.Fatal("expected the restored state hash to be the one of block 0")
+	}
+	if v, _ := other.Query("b"); v != "x=y" {
+		t.Fatalf("expected b to be restored to x=y, got %q", v)
+	}
+
+	if _, err := state.SnapshotHandler(5); err == nil {
+		t.Fatal("expected an error for an unknown block")
+	}
+}
+
+func TestKVStateSnapshotsBounded(t *testing.T) {
+	state := NewKVState(common.NewTestLogger(t))
+	for i := int64(0); i < 2*kvSnapshots; i++ {
+		if _, err := state.CommitHandler(poset.NewBlock(i, i+1, []byte{}, [][]byte{
+			SetTx("k", fmt.Sprint(i)),
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(state.snapshots) != kvSnapshots {
+		t.Fatalf("expected %d snapshots kept, got %d", kvSnapshots, len(state.snapshots))
+	}
+	if _, err := state.SnapshotHandler(kvSnapshots - 1); err == nil {
+		t.Fatal("expected the snapshot of an old block dropped")
+	}
+	if _, err := state.SnapshotHandler(2*kvSnapshots - 1); err != nil {
+		t.Fatal(err)
+	}
+}
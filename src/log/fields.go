@@ -0,0 +1,25 @@
+package dag1_log
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// DebugEnabled reports whether debug entries of e would be written. Hot
+// paths check it before building fields nobody will see.
+func DebugEnabled(e *logrus.Entry) bool {
+	return e.Logger.IsLevelEnabled(logrus.DebugLevel)
+}
+
+// Lazy logs msg at level with the fields returned by fields, which is only
+// called when the level is enabled.
+func Lazy(e *logrus.Entry, level logrus.Level, fields func() logrus.Fields, msg string) {
+	if !e.Logger.IsLevelEnabled(level) {
+		return
+	}
+	e.WithFields(fields()).Log(level, msg)
+}
+
+// LazyDebug is Lazy at debug level
+func LazyDebug(e *logrus.Entry, fields func() logrus.Fields, msg string) {
+	Lazy(e, logrus.DebugLevel, fields, msg)
+}
@@ -0,0 +1,39 @@
+package dag1_log
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func benchmarkEntry() *logrus.Entry {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	logger.Level = logrus.InfoLevel
+	return logrus.NewEntry(logger)
+}
+
+func BenchmarkEagerFieldsDisabled(b *testing.B) {
+	e := benchmarkEntry()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		e.WithFields(logrus.Fields{
+			"round": n,
+			"count": 3,
+		}).Debug("round2")
+	}
+}
+
+func BenchmarkLazyFieldsDisabled(b *testing.B) {
+	e := benchmarkEntry()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		LazyDebug(e, func() logrus.Fields {
+			return logrus.Fields{
+				"round": n,
+				"count": 3,
+			}
+		}, "round2")
+	}
+}
@@ -20,6 +20,17 @@ type Hook struct {
 	logshold  [6]int64
 }
 
+// ThresholdExit is called with the exit code when a level goes over its
+// logshold threshold. It is nil in the library, which never ends the
+// process; the dag1 command sets it to os.Exit.
+var ThresholdExit func(code int)
+
+func thresholdReached(code int) {
+	if ThresholdExit != nil {
+		ThresholdExit(code)
+	}
+}
+
 // NewLocal installs a test hook for a given local logger.
 func NewLocal(logger *logrus.Logger, logLevel string) {
 	levels := map[string]bool{"debug": true, "error": true, "fatal": true, "panic": true, "warn": true}
@@ -36,7 +47,8 @@ func NewLocal(logger *logrus.Logger, logLevel string) {
 				&h.logshold[logrus.ErrorLevel],
 				&h.logshold[logrus.FatalLevel],
 				&h.logshold[logrus.PanicLevel]); err != nil {
-				logger.Fatal(err)
+				logger.WithError(err).Error("Ignoring the malformed logshold")
+				h.logshold = [6]int64{-1, -1, -1, -1, -1, -1}
 			}
 		}
 		logger.Hooks.Add(h)
@@ -62,28 +74,28 @@ func (t *Hook) Fire(e *logrus.Entry) error {
 			t.stat[logrus.FatalLevel],
 			t.stat[logrus.PanicLevel])
 		if t.logshold[logrus.PanicLevel] >= 0 && t.stat[logrus.PanicLevel] > t.logshold[logrus.PanicLevel] {
-			fmt.Printf("PanicLevel logging threshold reached, exiting.\n")
-			os.Exit(127)
+			fmt.Printf("PanicLevel logging threshold reached.\n")
+			thresholdReached(127)
 		}
 		if t.logshold[logrus.FatalLevel] >= 0 && t.stat[logrus.FatalLevel] > t.logshold[logrus.FatalLevel] {
-			fmt.Printf("FatalLevel logging threshold reached, exiting.\n")
-			os.Exit(128)
+			fmt.Printf("FatalLevel logging threshold reached.\n")
+			thresholdReached(128)
 		}
 		if t.logshold[logrus.ErrorLevel] >= 0 && t.stat[logrus.ErrorLevel] > t.logshold[logrus.ErrorLevel] {
-			fmt.Printf("ErrorLevel logging threshold reached, exiting.\n")
-			os.Exit(129)
+			fmt.Printf("ErrorLevel logging threshold reached.\n")
+			thresholdReached(129)
 		}
 		if t.logshold[logrus.WarnLevel] >= 0 && t.stat[logrus.WarnLevel] > t.logshold[logrus.WarnLevel] {
-			fmt.Printf("WarnLevel logging threshold reached, exiting.\n")
-			os.Exit(130)
+			fmt.Printf("WarnLevel logging threshold reached.\n")
+			thresholdReached(130)
 		}
 		if t.logshold[logrus.InfoLevel] >= 0 && t.stat[logrus.InfoLevel] > t.logshold[logrus.InfoLevel] {
-			fmt.Printf("InfoLevel logging threshold reached, exiting.\n")
-			os.Exit(131)
+			fmt.Printf("InfoLevel logging threshold reached.\n")
+			thresholdReached(131)
 		}
 		if t.logshold[logrus.DebugLevel] >= 0 && t.stat[logrus.DebugLevel] > t.logshold[logrus.DebugLevel] {
-			fmt.Printf("DebugLevel logging threshold reached, exiting.\n")
-			os.Exit(132)
+			fmt.Printf("DebugLevel logging threshold reached.\n")
+			thresholdReached(132)
 		}
 		t.stat = [6]int64{} // 6 is current value of len(logrus.AllLevels)
 		// must be adjusted if changed in future in logrus
@@ -0,0 +1,172 @@
+package dag1_log
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Modules lists the module names accepted in a level string
+var Modules = []string{"poset", "node", "peer", "proxy", "service"}
+
+// Levels holds a default log level plus per-module overrides
+type Levels struct {
+	Default logrus.Level
+	Modules map[string]logrus.Level
+}
+
+// ParseLevels parses a level string such as "info", "poset=warn,node=debug"
+// or "warn,node=debug". A bare level sets the default, which is info when
+// only module levels are given.
+func ParseLevels(s string) (*Levels, error) {
+	levels := &Levels{
+		Default: logrus.InfoLevel,
+		Modules: make(map[string]logrus.Level),
+	}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 1 {
+			lvl, err := logrus.ParseLevel(kv[0])
+			if err != nil {
+				return nil, err
+			}
+			levels.Default = lvl
+			continue
+		}
+		module := strings.TrimSpace(kv[0])
+		if !isModule(module) {
+			return nil, fmt.Errorf("unknown log module %q", module)
+		}
+		lvl, err := logrus.ParseLevel(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, err
+		}
+		levels.Modules[module] = lvl
+	}
+	return levels, nil
+}
+
+// Level returns the level of a module, falling back to the default
+func (l *Levels) Level(module string) logrus.Level {
+	if lvl, ok := l.Modules[module]; ok {
+		return lvl
+	}
+	return l.Default
+}
+
+// String returns the level string ParseLevels parses back into l, the
+// modules in the order of Modules
+func (l *Levels) String() string {
+	parts := []string{l.Default.String()}
+	for _, m := range Modules {
+		if lvl, ok := l.Modules[m]; ok {
+			parts = append(parts, m+"="+lvl.String())
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func isModule(name string) bool {
+	for _, m := range Modules {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	modulesMu     sync.Mutex
+	moduleLevels  = make(map[*logrus.Logger]*Levels)
+	moduleLoggers = make(map[*logrus.Logger]map[string]*logrus.Logger)
+)
+
+// lockedWriter serializes the writes of a logger and of its module loggers,
+// each of which only holds its own mutex
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// SetLevels sets the default level of logger and registers the module
+// levels used by ForModule. Call it after the output, formatter and hooks
+// of logger are configured: module loggers copy them when first created,
+// the output wrapped so that the entries of all of them are written whole.
+// It may be called again while the modules run, their loggers taking the
+// new levels.
+func SetLevels(logger *logrus.Logger, levels *Levels) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	if _, ok := logger.Out.(*lockedWriter); !ok {
+		logger.SetOutput(&lockedWriter{w: logger.Out})
+	}
+	logger.SetLevel(levels.Default)
+	moduleLevels[logger] = levels
+	for module, l := range moduleLoggers[logger] {
+		l.SetLevel(levels.Level(module))
+	}
+}
+
+// LevelsOf returns the levels registered for logger with SetLevels, or its
+// level alone when there are none
+func LevelsOf(logger *logrus.Logger) *Levels {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	levels, ok := moduleLevels[logger]
+	if !ok {
+		return &Levels{Default: logger.GetLevel(), Modules: make(map[string]logrus.Level)}
+	}
+	res := &Levels{Default: levels.Default, Modules: make(map[string]logrus.Level)}
+	for m, lvl := range levels.Modules {
+		res.Modules[m] = lvl
+	}
+	return res
+}
+
+// ForModule returns the logger a module should use. When levels were
+// registered for base with SetLevels, it is a logger sharing the output,
+// formatter and hooks of base but with the module's own level, or the
+// default one, which SetLevels changes later. Its writes and those of base
+// hold the lock of the shared output. Otherwise it is base itself.
+func ForModule(base *logrus.Logger, module string) *logrus.Logger {
+	if base == nil {
+		return nil
+	}
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	levels, ok := moduleLevels[base]
+	if !ok {
+		return base
+	}
+	loggers, ok := moduleLoggers[base]
+	if !ok {
+		loggers = make(map[string]*logrus.Logger)
+		moduleLoggers[base] = loggers
+	}
+	if logger, ok := loggers[module]; ok {
+		return logger
+	}
+	logger := &logrus.Logger{
+		Out:          base.Out,
+		Hooks:        base.Hooks,
+		Formatter:    base.Formatter,
+		ReportCaller: base.ReportCaller,
+		Level:        levels.Level(module),
+		ExitFunc:     base.ExitFunc,
+	}
+	loggers[module] = logger
+	return logger
+}
@@ -0,0 +1,176 @@
+package dag1_log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseLevels(t *testing.T) {
+	cases := []struct {
+		in      string
+		def     logrus.Level
+		modules map[string]logrus.Level
+	}{
+		{"", logrus.InfoLevel, map[string]logrus.Level{}},
+		{"debug", logrus.DebugLevel, map[string]logrus.Level{}},
+		{"poset=warn,node=debug", logrus.InfoLevel, map[string]logrus.Level{
+			"poset": logrus.WarnLevel,
+			"node":  logrus.DebugLevel,
+		}},
+		{"error, peer=info ,proxy=panic", logrus.ErrorLevel, map[string]logrus.Level{
+			"peer":  logrus.InfoLevel,
+			"proxy": logrus.PanicLevel,
+		}},
+		{"service=debug,warn", logrus.WarnLevel, map[string]logrus.Level{
+			"service": logrus.DebugLevel,
+		}},
+	}
+
+	for _, c := range cases {
+		levels, err := ParseLevels(c.in)
+		if err != nil {
+			t.Fatalf("%q: %v", c.in, err)
+		}
+		if levels.Default != c.def {
+			t.Fatalf("%q: expected default %v, got %v", c.in, c.def, levels.Default)
+		}
+		if len(levels.Modules) != len(c.modules) {
+			t.Fatalf("%q: expected modules %v, got %v", c.in, c.modules, levels.Modules)
+		}
+		for m, lvl := range c.modules {
+			if levels.Level(m) != lvl {
+				t.Fatalf("%q: expected %s level %v, got %v", c.in, m, lvl, levels.Level(m))
+			}
+		}
+	}
+}
+
+func TestParseLevelsErrors(t *testing.T) {
+	for _, in := range []string{"loud", "poset=loud", "consensus=debug", "=debug"} {
+		if _, err := ParseLevels(in); err == nil {
+			t.Fatalf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestForModule(t *testing.T) {
+	base := logrus.New()
+
+	if ForModule(base, "poset") != base {
+		t.Fatal("expected the base logger when no levels are set")
+	}
+
+	levels, err := ParseLevels("warn,poset=debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetLevels(base, levels)
+
+	if base.Level != logrus.WarnLevel {
+		t.Fatalf("expected base level warn, got %v", base.Level)
+	}
+	node := ForModule(base, "node")
+	if node == base || node.Level != logrus.WarnLevel {
+		t.Fatalf("expected a warn logger for node, got %v", node.Level)
+	}
+	poset := ForModule(base, "poset")
+	if poset == base || poset.Level != logrus.DebugLevel {
+		t.Fatalf("expected a debug logger for poset, got %v", poset.Level)
+	}
+	if poset.Out != base.Out {
+		t.Fatal("expected the poset logger to share the base output")
+	}
+	if ForModule(base, "poset") != poset {
+		t.Fatal("expected the poset logger to be reused")
+	}
+}
+
+func TestSetLevelsAtRuntime(t *testing.T) {
+	base := logrus.New()
+	levels, err := ParseLevels("info,poset=debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetLevels(base, levels)
+	poset, node := ForModule(base, "poset"), ForModule(base, "node")
+
+	// the loggers handed out follow the new levels
+	levels, err = ParseLevels("warn,node=debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetLevels(base, levels)
+	if base.GetLevel() != logrus.WarnLevel || poset.GetLevel() != logrus.WarnLevel {
+		t.Fatalf("expected base and poset at warn, got %v and %v", base.GetLevel(), poset.GetLevel())
+	}
+	if node.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("expected node at debug, got %v", node.GetLevel())
+	}
+	if ForModule(base, "node") != node {
+		t.Fatal("expected the node logger to be reused")
+	}
+	if s := LevelsOf(base).String(); s != "warning,node=debug" {
+		t.Fatalf("expected the levels warning,node=debug, got %q", s)
+	}
+	if s := LevelsOf(logrus.New()).String(); s != "info" {
+		t.Fatalf("expected the level of a logger without levels, got %q", s)
+	}
+}
+
+// overlapWriter records the writes made while another one is in progress
+type overlapWriter struct {
+	inFlight int32
+	overlaps int32
+	buf      bytes.Buffer
+	mu       sync.Mutex
+}
+
+func (w *overlapWriter) Write(p []byte) (int, error) {
+	if atomic.AddInt32(&w.inFlight, 1) > 1 {
+		atomic.AddInt32(&w.overlaps, 1)
+	}
+	defer atomic.AddInt32(&w.inFlight, -1)
+	time.Sleep(100 * time.Microsecond)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestForModuleSharesOutputLock(t *testing.T) {
+	out := &overlapWriter{}
+	base := logrus.New()
+	base.Out = out
+	base.Formatter = &logrus.TextFormatter{DisableTimestamp: true}
+	levels, err := ParseLevels("info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetLevels(base, levels)
+
+	const n = 100
+	var wg sync.WaitGroup
+	for _, logger := range []*logrus.Logger{base, ForModule(base, "poset")} {
+		wg.Add(1)
+		go func(logger *logrus.Logger) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				logger.Info("entry")
+			}
+		}(logger)
+	}
+	wg.Wait()
+
+	if overlaps := atomic.LoadInt32(&out.overlaps); overlaps != 0 {
+		t.Fatalf("expected serialized writes, got %d overlapping", overlaps)
+	}
+	lines := strings.Split(strings.TrimSpace(out.buf.String()), "\n")
+	if len(lines) != 2*n {
+		t.Fatalf("expected %d lines, got %d", 2*n, len(lines))
+	}
+}
@@ -0,0 +1,102 @@
+package dag1_log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Limiter collapses repeated identical messages. The first message of a
+// format is written, repeats within the window are only counted and written
+// as a single "message repeated N times" entry once the window has passed,
+// with the next message logged through the Limiter or by Flush.
+type Limiter struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	repeats map[string]*repeat
+}
+
+type repeat struct {
+	since time.Time
+	count int
+	last  string
+	entry *logrus.Entry
+	level logrus.Level
+}
+
+// write logs the count of the repeats, if any
+func (r *repeat) write() {
+	if r.count > 0 {
+		r.entry.WithField("repeated", r.count).Log(r.level,
+			fmt.Sprintf("message repeated %d times: %s", r.count, r.last))
+	}
+}
+
+// NewLimiter creates a Limiter collapsing repeats within window
+func NewLimiter(window time.Duration) *Limiter {
+	return &Limiter{
+		window:  window,
+		now:     time.Now,
+		repeats: make(map[string]*repeat),
+	}
+}
+
+// Logf formats and logs a message at level unless the same format was
+// logged within the window. Messages are keyed by format, so they collapse
+// even when the arguments (hashes, errors) differ.
+func (l *Limiter) Logf(e *logrus.Entry, level logrus.Level, format string, args ...interface{}) {
+	if !e.Logger.IsLevelEnabled(level) {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	now := l.now()
+
+	l.mu.Lock()
+	r, ok := l.repeats[format]
+	if ok && now.Sub(r.since) < l.window {
+		r.count++
+		r.last = msg
+		l.mu.Unlock()
+		return
+	}
+	// the repeats of this format and of those whose window passed are written
+	var passed []*repeat
+	if ok {
+		passed = append(passed, r)
+	}
+	for f, other := range l.repeats {
+		if f != format && now.Sub(other.since) >= l.window {
+			passed = append(passed, other)
+			delete(l.repeats, f)
+		}
+	}
+	l.repeats[format] = &repeat{since: now, entry: e, level: level}
+	l.mu.Unlock()
+
+	for _, r := range passed {
+		r.write()
+	}
+	e.Log(level, msg)
+}
+
+// Warnf is Logf at warn level
+func (l *Limiter) Warnf(e *logrus.Entry, format string, args ...interface{}) {
+	l.Logf(e, logrus.WarnLevel, format, args...)
+}
+
+// Flush writes the counts of the repeats not written yet, as the owner of
+// the Limiter stops
+func (l *Limiter) Flush() {
+	l.mu.Lock()
+	repeats := l.repeats
+	l.repeats = make(map[string]*repeat)
+	l.mu.Unlock()
+
+	for _, r := range repeats {
+		r.write()
+	}
+}
@@ -0,0 +1,86 @@
+package dag1_log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLimiter(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &out
+	logger.Formatter = &logrus.TextFormatter{DisableTimestamp: true}
+	entry := logrus.NewEntry(logger)
+
+	now := time.Unix(0, 0)
+	l := NewLimiter(time.Second)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		l.Warnf(entry, "failed to get other parent: %d", i)
+	}
+	l.Warnf(entry, "something else")
+	now = now.Add(2 * time.Second)
+	l.Warnf(entry, "failed to get other parent: %d", 5)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	expected := []string{
+		"failed to get other parent: 0",
+		"something else",
+		"message repeated 4 times: failed to get other parent: 4",
+		"failed to get other parent: 5",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(expected), len(lines), out.String())
+	}
+	for i, e := range expected {
+		if !strings.Contains(lines[i], e) {
+			t.Fatalf("line %d: expected %q in %q", i, e, lines[i])
+		}
+	}
+}
+
+func TestLimiterFlush(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &out
+	logger.Formatter = &logrus.TextFormatter{DisableTimestamp: true}
+	entry := logrus.NewEntry(logger)
+
+	now := time.Unix(0, 0)
+	l := NewLimiter(time.Second)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		l.Warnf(entry, "stalled: %d", i)
+	}
+	// another message after the window writes the repeats of the first
+	now = now.Add(2 * time.Second)
+	l.Warnf(entry, "something else")
+	for i := 0; i < 2; i++ {
+		l.Warnf(entry, "something else")
+	}
+	// and the last repeats are written on Flush
+	l.Flush()
+	l.Flush()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	expected := []string{
+		"stalled: 0",
+		"message repeated 2 times: stalled: 2",
+		"something else",
+		"message repeated 2 times: something else",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(expected), len(lines), out.String())
+	}
+	for i, e := range expected {
+		if !strings.Contains(lines[i], e) {
+			t.Fatalf("line %d: expected %q in %q", i, e, lines[i])
+		}
+	}
+}
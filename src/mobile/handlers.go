@@ -16,3 +16,10 @@ type CommitHandler interface {
 type ExceptionHandler interface {
 	OnException(string)
 }
+
+// BlockListener is told of each block DAG1 commits, so the mobile app can
+// follow the chain without polling. OnBlock is always called from the same
+// goroutine, in the order the blocks are committed.
+type BlockListener interface {
+	OnBlock(index int64, createdTime int64, txCount int)
+}
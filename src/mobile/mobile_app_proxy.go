@@ -1,6 +1,8 @@
 package mobile
 
 import (
+	"sync"
+
 	"github.com/SamuelMarks/dag1/src/poset"
 	"github.com/SamuelMarks/dag1/src/proxy"
 	"github.com/sirupsen/logrus"
@@ -17,6 +19,13 @@ type mobileAppProxy struct {
 	commitHandler    CommitHandler
 	exceptionHandler ExceptionHandler
 	logger           *logrus.Logger
+
+	listenerLock sync.Mutex
+	listener     BlockListener
+	blocks       []poset.Block
+	blocksCh     chan struct{}
+	done         chan struct{}
+	closeOnce    sync.Once
 }
 
 // newMobileAppProxy create proxy
@@ -30,21 +39,18 @@ func newMobileAppProxy(
 		commitHandler:    commitHandler,
 		exceptionHandler: exceptionHandler,
 		logger:           logger,
+		blocksCh:         make(chan struct{}, 1),
+		done:             make(chan struct{}),
 	}
 
 	mobileApp.InmemAppProxy = proxy.NewInmemAppProxy(mobileApp, logger)
+	go mobileApp.dispatchBlocks()
 
 	return mobileApp
 }
 
 func (m *mobileAppProxy) CommitHandler(block poset.Block) ([]byte, error) {
-	blockBytes, err := block.ProtoMarshal()
-	if err != nil {
-		m.logger.Debug("mobileAppProxy error marhsalling Block")
-		return nil, err
-	}
-	stateHash := m.commitHandler.OnCommit(blockBytes)
-	return stateHash, nil
+	return m.commit(block)
 }
 func (m *mobileAppProxy) SnapshotHandler(blockIndex int64) ([]byte, error) {
 	return []byte{}, nil
@@ -58,15 +64,78 @@ func (m *mobileAppProxy) RestoreHandler(snapshot []byte) ([]byte, error) {
 // arrays of bytes; so we have to serialize the block.
 // Overrides  InappProxy::CommitBlock
 func (m *mobileAppProxy) CommitBlock(block poset.Block) ([]byte, error) {
+	return m.commit(block)
+}
+
+// commit passes a block to the commit handler of the mobile app and queues
+// it for the block listener
+func (m *mobileAppProxy) commit(block poset.Block) ([]byte, error) {
 	blockBytes, err := block.ProtoMarshal()
 	if err != nil {
 		m.logger.Debug("mobileAppProxy error marhsalling Block")
 		return nil, err
 	}
 	stateHash := m.commitHandler.OnCommit(blockBytes)
+	m.queueBlock(block)
 	return stateHash, nil
 }
 
+// setBlockListener sets the listener told of the committed blocks, nil for
+// none
+func (m *mobileAppProxy) setBlockListener(listener BlockListener) {
+	m.listenerLock.Lock()
+	defer m.listenerLock.Unlock()
+	m.listener = listener
+	if listener == nil {
+		m.blocks = nil
+	}
+}
+
+// queueBlock queues a committed block for the listener, so that committing
+// never waits on the mobile app
+func (m *mobileAppProxy) queueBlock(block poset.Block) {
+	m.listenerLock.Lock()
+	if m.listener == nil {
+		m.listenerLock.Unlock()
+		return
+	}
+	m.blocks = append(m.blocks, block)
+	m.listenerLock.Unlock()
+
+	select {
+	case m.blocksCh <- struct{}{}:
+	default:
+	}
+}
+
+// dispatchBlocks tells the listener of the queued blocks. It is the only
+// goroutine calling the listener, as the gomobile bindings require.
+func (m *mobileAppProxy) dispatchBlocks() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-m.blocksCh:
+		}
+
+		m.listenerLock.Lock()
+		blocks, listener := m.blocks, m.listener
+		m.blocks = nil
+		m.listenerLock.Unlock()
+
+		for _, block := range blocks {
+			listener.OnBlock(block.Index(), block.CreatedTime, len(block.Transactions()))
+		}
+	}
+}
+
+// close stops the dispatch of the blocks to the listener
+func (m *mobileAppProxy) close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+}
+
 //TODO - Implement these two functions
 func (m *mobileAppProxy) GetSnapshot(blockIndex int64) ([]byte, error) {
 	return []byte{}, nil
@@ -1,9 +1,10 @@
 package mobile
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 
-	"github.com/SamuelMarks/dag1/src/crypto"
 	"github.com/SamuelMarks/dag1/src/dag1"
 	"github.com/SamuelMarks/dag1/src/node"
 	"github.com/SamuelMarks/dag1/src/peers"
@@ -14,8 +15,10 @@ import (
 // Node struct
 type Node struct {
 	nodeID uint64
+	engine *dag1.Engine
 	node   *node.Node
 	proxy  proxy.AppProxy
+	app    *mobileAppProxy
 	logger *logrus.Logger
 }
 
@@ -27,67 +30,56 @@ func New(privKey string,
 	exceptionHandler ExceptionHandler,
 	config *MobileConfig) *Node {
 
-	dag1Config := dag1.NewDefaultConfig()
+	logger := dag1.NewDefaultConfig().Logger
 
-	dag1Config.Logger.WithFields(logrus.Fields{
+	logger.WithFields(logrus.Fields{
 		"nodeAddr": nodeAddr,
 		"peers":    participants,
 		"config":   fmt.Sprintf("%v", config),
 	}).Debug("New Mobile Node")
 
-	// Check private key
-	pemKey := &crypto.PemKey{}
-
-	key, err := pemKey.ReadKeyFromBuf([]byte(privKey))
+	app := newMobileAppProxy(commitHandler, exceptionHandler, logger)
 
+	engine, err := dag1.NewEngine(
+		dag1.WithLogger(logger),
+		dag1.WithKeyPEM([]byte(privKey)),
+		dag1.WithPeers(participants.ToPeerSlice()),
+		dag1.WithProxy(app),
+		dag1.WithListen(nodeAddr),
+		dag1.WithService(""),
+	)
 	if err != nil {
-		exceptionHandler.OnException(fmt.Sprintf("Failed to read private key: %s", err))
-
-		return nil
-	}
-
-	dag1Config.Key = key
-
-	// There should be at least two peers
-	if participants.Len() < 2 {
-		exceptionHandler.OnException(fmt.Sprintf("Should define at least two peers"))
-
-		return nil
-	}
-
-	dag1Config.Proxy = newMobileAppProxy(commitHandler, exceptionHandler, dag1Config.Logger)
-	dag1Config.LoadPeers = false
-
-	engine := dag1.NewDAG1(dag1Config)
-
-	engine.Peers = participants
-
-	if err := engine.Init(); err != nil {
 		exceptionHandler.OnException(fmt.Sprintf("Cannot initialize engine: %s", err))
+		app.close()
 
 		return nil
 	}
 
 	return &Node{
-		node:   engine.Node,
-		proxy:  dag1Config.Proxy,
-		nodeID: engine.Node.ID(),
-		logger: dag1Config.Logger,
+		engine: engine,
+		node:   engine.Node(),
+		proxy:  app,
+		app:    app,
+		nodeID: engine.Node().ID(),
+		logger: logger,
 	}
 }
 
 // Run the node (can be async)
 func (n *Node) Run(async bool) {
-	if async {
-		n.node.RunAsync(true)
-	} else {
-		n.node.Run(true)
+	if err := n.engine.Start(context.Background()); err != nil {
+		n.logger.WithError(err).Error("Starting the node")
+		return
+	}
+	if !async {
+		<-n.engine.Done()
 	}
 }
 
 // Shutdown the node
 func (n *Node) Shutdown() {
-	n.node.Shutdown()
+	n.engine.Stop()
+	n.app.close()
 }
 
 // SubmitTx submits the transaction
@@ -98,3 +90,37 @@ func (n *Node) SubmitTx(tx []byte) {
 	copy(t, tx)
 	n.proxy.SubmitCh() <- t
 }
+
+// GetStats returns the stats of the node as a JSON object of strings
+func (n *Node) GetStats() (string, error) {
+	data, err := json.Marshal(n.node.GetStats())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// GetBlock returns the committed block at an index as JSON
+func (n *Node) GetBlock(index int64) (string, error) {
+	block, err := n.node.GetBlock(index)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(block)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// GetLastBlockIndex returns the index of the last committed block, -1 before
+// the first one
+func (n *Node) GetLastBlockIndex() int64 {
+	return n.node.GetLastBlockIndex()
+}
+
+// SetBlockListener sets the listener told of each block committed from now
+// on, nil to stop
+func (n *Node) SetBlockListener(listener BlockListener) {
+	n.app.setBlockListener(listener)
+}
@@ -0,0 +1,206 @@
+package mobile
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// dummyCommitHandler commits the blocks to the state of the dummy app, as a
+// mobile app would to its own
+type dummyCommitHandler struct {
+	state *dummy.State
+	t     *testing.T
+}
+
+func (h *dummyCommitHandler) OnCommit(blockBytes []byte) []byte {
+	var block poset.Block
+	if err := block.ProtoUnmarshal(blockBytes); err != nil {
+		h.t.Error(err)
+		return nil
+	}
+	stateHash, err := h.state.CommitHandler(block)
+	if err != nil {
+		h.t.Error(err)
+	}
+	return stateHash
+}
+
+type testExceptionHandler struct {
+	t *testing.T
+}
+
+func (h *testExceptionHandler) OnException(msg string) {
+	h.t.Error(msg)
+}
+
+// testBlockListener records the blocks it is told of and whether it was ever
+// called from two goroutines at once
+type testBlockListener struct {
+	inside     int32
+	overlapped int32
+
+	lock    sync.Mutex
+	indexes []int64
+	txs     int
+}
+
+func (l *testBlockListener) OnBlock(index int64, createdTime int64, txCount int) {
+	if atomic.AddInt32(&l.inside, 1) > 1 {
+		atomic.StoreInt32(&l.overlapped, 1)
+	}
+	defer atomic.AddInt32(&l.inside, -1)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.indexes = append(l.indexes, index)
+	l.txs += txCount
+}
+
+func (l *testBlockListener) seen() ([]int64, int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]int64{}, l.indexes...), l.txs
+}
+
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// newTestNodes brings up n mobile nodes of one network, committing to dummy
+// apps
+func newTestNodes(n int, t *testing.T) []*Node {
+	var keys []*crypto.PemDump
+	var addrs []string
+	participants := peers.NewPeers()
+	for i := 0; i < n; i++ {
+		key, err := crypto.GeneratePemKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		addrs = append(addrs, freeAddr(t))
+		participants.AddPeer(peers.NewPeer(key.PublicKey, addrs[i]))
+	}
+
+	var nodes []*Node
+	for i := 0; i < n; i++ {
+		node := New(keys[i].PrivateKey, addrs[i], participants,
+			&dummyCommitHandler{state: dummy.NewState(common.NewTestLogger(t)), t: t},
+			&testExceptionHandler{t: t}, DefaultMobileConfig())
+		if node == nil {
+			t.Fatalf("failed to create node %d", i)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func TestMobileNodeStatus(t *testing.T) {
+	nodes := newTestNodes(2, t)
+	for _, node := range nodes {
+		defer node.Shutdown()
+	}
+
+	if last := nodes[0].GetLastBlockIndex(); last != -1 {
+		t.Fatalf("expected no block yet, got the last block %d", last)
+	}
+	if _, err := nodes[0].GetBlock(0); err == nil {
+		t.Fatal("expected no block 0 yet")
+	}
+
+	data, err := nodes[0].GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats map[string]string
+	if err := json.Unmarshal([]byte(data), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats["last_block_index"] != "-1" || stats["num_peers"] != "2" {
+		t.Fatalf("expected the stats of a node of 2 peers without a block, got %s", data)
+	}
+}
+
+func TestMobileBlockListener(t *testing.T) {
+	nodes := newTestNodes(2, t)
+	for _, node := range nodes {
+		defer node.Shutdown()
+	}
+	app := nodes[0].app
+
+	// blocks committed before a listener is set are not queued for it
+	if _, err := app.CommitBlock(poset.NewBlock(0, 1, []byte{}, [][]byte{[]byte("tx")})); err != nil {
+		t.Fatal(err)
+	}
+	listener := &testBlockListener{}
+	nodes[0].SetBlockListener(listener)
+
+	// the handler path and the overridden CommitBlock both reach the listener
+	blocks, txs := 20, 0
+	for i := 1; i <= blocks; i++ {
+		var blockTxs [][]byte
+		for j := 0; j < i%3; j++ {
+			blockTxs = append(blockTxs, []byte(fmt.Sprintf("tx %d %d", i, j)))
+		}
+		txs += len(blockTxs)
+		block := poset.NewBlock(int64(i), int64(i+1), []byte{}, blockTxs)
+		commit := app.CommitBlock
+		if i%2 == 0 {
+			commit = app.CommitHandler
+		}
+		if _, err := commit(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timeout := time.After(10 * time.Second)
+	for {
+		if indexes, _ := listener.seen(); len(indexes) >= blocks {
+			break
+		}
+		select {
+		case <-timeout:
+			indexes, _ := listener.seen()
+			t.Fatalf("expected the listener told of %d blocks, got %v", blocks, indexes)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	indexes, n := listener.seen()
+	for i, index := range indexes {
+		if index != int64(i+1) {
+			t.Fatalf("expected the blocks 1 to %d in commit order, got %v", blocks, indexes)
+		}
+	}
+	if n != txs {
+		t.Fatalf("expected %d transactions, got %d", txs, n)
+	}
+	if atomic.LoadInt32(&listener.overlapped) != 0 {
+		t.Fatal("expected the listener called from one goroutine at a time")
+	}
+
+	// no more calls once the listener is removed
+	nodes[0].SetBlockListener(nil)
+	if _, err := app.CommitBlock(poset.NewBlock(int64(blocks+1), 1, []byte{}, nil)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if after, _ := listener.seen(); len(after) != len(indexes) {
+		t.Fatalf("expected no block after removing the listener, got %v", after[len(indexes):])
+	}
+}
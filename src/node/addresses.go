@@ -0,0 +1,161 @@
+package node
+
+import (
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// addrBook keeps the latest signed network address of every participant,
+// which travel with the syncs, so that a participant moving to another
+// address is dialled there without anyone editing peers.json
+type addrBook struct {
+	sync.Mutex
+	own    *peers.AddrAnnouncement
+	latest map[string]peers.AddrAnnouncement // by public key
+	// moved is set once a participant is found at another address than the
+	// one it started with
+	moved bool
+}
+
+func newAddrBook() *addrBook {
+	return &addrBook{latest: make(map[string]peers.AddrAnnouncement)}
+}
+
+// newer returns true when the announcement is newer than the one known for
+// its participant
+func (b *addrBook) newer(a peers.AddrAnnouncement) bool {
+	b.Lock()
+	defer b.Unlock()
+	known, ok := b.latest[a.PubKeyHex]
+	return !ok || a.Nonce > known.Nonce
+}
+
+// announcements returns ours and the latest of the other participants,
+// sorted by public key
+func (b *addrBook) announcements() []peers.AddrAnnouncement {
+	b.Lock()
+	defer b.Unlock()
+	res := make([]peers.AddrAnnouncement, 0, len(b.latest)+1)
+	if b.own != nil {
+		res = append(res, *b.own)
+	}
+	for _, a := range b.latest {
+		res = append(res, a)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].PubKeyHex < res[j].PubKeyHex })
+	return res
+}
+
+// announceable returns true for an address others can dial, with a host
+// which is not the unspecified address of a listener bound to all
+// interfaces
+func announceable(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || !ip.IsUnspecified()
+}
+
+// initAddresses moves the participants to the addresses they were last
+// known at, and signs the announcement of the address of this node. A fresh
+// nonce per start makes it newer than the announcements of earlier runs.
+func (n *Node) initAddresses() error {
+	stored, err := n.core.poset.Store.PeerAddresses()
+	if err != nil {
+		return err
+	}
+	for _, a := range stored {
+		n.learnAddress(a, false)
+	}
+
+	if n.core.IsObserver() || !announceable(n.localAddr) {
+		return nil
+	}
+	own, err := peers.NewAddrAnnouncement(n.core.key, n.localAddr, n.clock.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+	n.addrs.Lock()
+	n.addrs.own = &own
+	n.addrs.Unlock()
+	if old, ok := n.core.participants.SetNetAddr(own.PubKeyHex, own.NetAddr); ok && old != own.NetAddr {
+		n.logger.WithFields(logrus.Fields{
+			"from": old,
+			"to":   own.NetAddr,
+		}).Info("Announcing a new address")
+		n.addrs.Lock()
+		n.addrs.moved = true
+		n.addrs.Unlock()
+	}
+	return nil
+}
+
+// learnAddresses takes the address announcements of a sync, those of
+// participants and newer than what we know
+func (n *Node) learnAddresses(announcements []peers.AddrAnnouncement) {
+	for _, a := range announcements {
+		n.learnAddress(a, true)
+	}
+}
+
+// learnAddress moves a participant to the address it announced, when the
+// announcement is newer than the last one and signed by the participant.
+// The connections to its former address are dropped, and the announcement
+// saved when persist is set.
+func (n *Node) learnAddress(a peers.AddrAnnouncement, persist bool) {
+	if a.PubKeyHex == n.core.HexID() || !n.addrs.newer(a) {
+		return
+	}
+	if _, ok := n.core.participants.ReadByPubKey(a.PubKeyHex); !ok {
+		return
+	}
+	if err := a.Verify(); err != nil {
+		n.logger.WithError(err).Debug("Dropping an address announcement")
+		return
+	}
+
+	n.addrs.Lock()
+	// a concurrent sync may have brought a newer one meanwhile
+	if known, ok := n.addrs.latest[a.PubKeyHex]; ok && a.Nonce <= known.Nonce {
+		n.addrs.Unlock()
+		return
+	}
+	n.addrs.latest[a.PubKeyHex] = a
+	old, _ := n.core.participants.SetNetAddr(a.PubKeyHex, a.NetAddr)
+	moved := old != a.NetAddr
+	if moved {
+		n.addrs.moved = true
+	}
+	n.addrs.Unlock()
+
+	if moved {
+		n.trans.Forget(old)
+		n.logger.WithFields(logrus.Fields{
+			"peer": a.PubKeyHex,
+			"from": old,
+			"to":   a.NetAddr,
+		}).Info("Peer moved to another address")
+	}
+	if persist {
+		err := n.core.poset.Store.SetPeerAddresses(map[string]peers.AddrAnnouncement{a.PubKeyHex: a})
+		if err != nil {
+			n.logger.WithError(err).Error("Saving the address of a peer")
+		}
+	}
+}
+
+// AddressesMoved returns true when a participant, this node included, was
+// found at another address than it started with, for peers.json to be
+// refreshed
+func (n *Node) AddressesMoved() bool {
+	n.addrs.Lock()
+	defer n.addrs.Unlock()
+	return n.addrs.moved
+}
@@ -0,0 +1,119 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+func TestAnnounceable(t *testing.T) {
+	for addr, expected := range map[string]bool{
+		"10.0.0.1:1337":   true,
+		"node1:1337":      true,
+		"0.0.0.0:1337":    false,
+		"[::]:1337":       false,
+		":1337":           false,
+		"10.0.0.1":        false,
+		"[::1]:1337":      true,
+		"127.0.0.1:12000": true,
+	} {
+		if announceable(addr) != expected {
+			t.Fatalf("%s: expected announceable %v", addr, expected)
+		}
+	}
+}
+
+// copyPeers returns participants of its own for a node, at the addresses of
+// peers.json
+func copyPeers(data *TestData) *peers.Peers {
+	ps := peers.NewPeers()
+	for _, p := range data.PeersSlice {
+		ps.AddPeer(peers.NewPeer(p.Message.PubKeyHex, p.Message.NetAddr))
+	}
+	return ps
+}
+
+// TestAddressMove restarts a node on another address with the same key, its
+// peers.json unchanged, and checks the other nodes find it there
+func TestAddressMove(t *testing.T) {
+	data := InitTestData(t, 3, 2)
+	// the nodes log as they stop, after the test
+	logger := logrus.New()
+	logger.Level = logrus.ErrorLevel
+	config := *data.Config
+	config.Logger = logger
+
+	// the keys and the addresses come in the same order, the peers by ID
+	ids := make([]uint64, 3)
+	nodes := make([]*Node, 3)
+	for i := range nodes {
+		p, _ := data.Peers.ReadByNetAddr(data.Adds[i])
+		ids[i] = p.ID
+		trans := createTransport(t, logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+		nodes[i] = createNode(t, logger, &config, ids[i],
+			data.Keys[i], copyPeers(data), trans, data.Adds[i], true)
+		defer nodes[i].Shutdown()
+	}
+
+	// the last node moves
+	nodes[2].Shutdown()
+	newAddr := data.Network.RandomAddress()
+	trans := createTransport(t, logger, data.BackConfig, newAddr,
+		data.PoolSize, data.CreateFu, data.Network.CreateListener)
+	defer transportClose(t, trans)
+	restart := time.Now()
+	moved := createNode(t, logger, &config, ids[2],
+		data.Keys[2], copyPeers(data), trans, newAddr, true)
+	defer moved.Shutdown()
+	if !moved.AddressesMoved() {
+		t.Fatal("expected the moved node to know it moved")
+	}
+
+	timeout := time.After(20 * time.Second)
+	for _, n := range nodes[:2] {
+		for {
+			var synced bool
+			for _, p := range n.GetPeersSnapshot() {
+				if p.ID == ids[2] && p.NetAddr == newAddr {
+					synced = p.LastSyncOK && p.LastSyncTime.After(restart)
+				}
+			}
+			if synced {
+				break
+			}
+			select {
+			case <-timeout:
+				t.Fatalf("node %d did not sync with the moved node", n.ID())
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+		if !n.AddressesMoved() {
+			t.Fatalf("node %d: expected peers.json to be refreshed", n.ID())
+		}
+
+		// the address survives a restart
+		addrs, err := n.core.poset.Store.PeerAddresses()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a := addrs[moved.core.HexID()]; a.NetAddr != newAddr {
+			t.Fatalf("node %d: expected the address %s saved, got %+v", n.ID(), newAddr, a)
+		}
+	}
+
+	// an older announcement, replayed, does not move it back
+	n := nodes[0]
+	stale, err := peers.NewAddrAnnouncement(data.Keys[2], data.Adds[2], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.learnAddresses([]peers.AddrAnnouncement{stale})
+	if p, ok := n.core.participants.ByID[ids[2]]; !ok || p.Message.NetAddr != newAddr {
+		t.Fatalf("expected a stale announcement dropped, the node moved to %s", p.Message.NetAddr)
+	}
+}
@@ -0,0 +1,110 @@
+package node
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := InitTestData(t, 4, 2)
+	var (
+		nodes []*Node
+		logs  []*poset.AuditLog
+	)
+	for i := 0; i < 4; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		id := data.Peers.ByNetAddr[data.Adds[i]].ID
+		node := createNode(t, data.Logger, data.Config, id,
+			data.Keys[i], data.Peers, trans, data.Adds[i], false)
+		defer node.Shutdown()
+		audit, err := poset.NewAuditLog(filepath.Join(dir, fmt.Sprintf("node%d.log", i)), id, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		node.SetAuditSink(audit)
+		nodes = append(nodes, node)
+		logs = append(logs, audit)
+	}
+
+	if err := gossip(nodes, 3, true, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, n := range nodes {
+		if err := logs[i].Close(); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("node%d.log", i))
+		entries, err := poset.VerifyAuditLog(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the block entries are the blocks of the store, in order
+		kinds := map[poset.AuditKind]int{}
+		var blocks []poset.AuditEntry
+		for _, entry := range entries {
+			kinds[entry.Kind]++
+			if entry.Node != n.ID() {
+				t.Fatalf("node %d: expected the entries of node %d, got %+v", i, n.ID(), entry)
+			}
+			if entry.Kind == poset.AuditBlock {
+				blocks = append(blocks, entry)
+			}
+		}
+		last := n.GetLastBlockIndex()
+		if int64(len(blocks)) != last+1 {
+			t.Fatalf("node %d: expected %d block entries, got %d", i, last+1, len(blocks))
+		}
+		for index, entry := range blocks {
+			block, err := n.GetBlock(int64(index))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *entry.Block != block.Index() ||
+				entry.FrameHash != fmt.Sprintf("%X", block.GetFrameHash()) ||
+				entry.Transactions != len(block.Transactions()) {
+				t.Fatalf("node %d: block %d: entry %+v does not match", i, index, entry)
+			}
+		}
+		for _, kind := range []poset.AuditKind{poset.AuditEventAccepted, poset.AuditClotho, poset.AuditAtropos} {
+			if kinds[kind] == 0 {
+				t.Fatalf("node %d: expected %s entries, got %v", i, kind, kinds)
+			}
+		}
+
+		// the verifier detects a deleted line
+		if i > 0 {
+			continue
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.SplitAfter(string(content), "\n")
+		tampered := lines[:len(lines)/2]
+		tampered = append(tampered, lines[len(lines)/2+1:]...)
+		if err := ioutil.WriteFile(path, []byte(strings.Join(tampered, "")), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := poset.VerifyAuditLog(path); !poset.IsAuditGap(err) {
+			t.Fatalf("expected a gap in the audit log, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+const (
+	// gossipedCheckpoints is how many of the latest checkpoints a node
+	// piggy-backs its signatures for on sync responses
+	gossipedCheckpoints = 5
+	// maxPendingCheckpoints bounds the frames we keep signatures for before
+	// having checkpointed them ourselves
+	maxPendingCheckpoints = 64
+)
+
+// Checkpoint signs the checkpoint of a frame made final by block, stores it
+// and adds the signatures already received for it
+func (c *Core) Checkpoint(block poset.Block, stateHash []byte) (poset.Checkpoint, error) {
+	if len(block.FrameHash) == 0 {
+		return poset.Checkpoint{}, fmt.Errorf("block %d has no frame hash", block.Index())
+	}
+	checkpoint := poset.NewCheckpoint(block.RoundReceived(), block.FrameHash, stateHash)
+	sig, err := checkpoint.Sign(c.key)
+	if err != nil {
+		return poset.Checkpoint{}, err
+	}
+	checkpoint.SetSignature(sig)
+
+	c.checkpointLocker.Lock()
+	defer c.checkpointLocker.Unlock()
+	for _, sig := range c.pendingCheckpointSigs[checkpoint.Frame] {
+		c.addCheckpointSignature(&checkpoint, sig)
+	}
+	delete(c.pendingCheckpointSigs, checkpoint.Frame)
+
+	if err := c.poset.Store.SetCheckpoint(checkpoint); err != nil {
+		return poset.Checkpoint{}, err
+	}
+	c.checkpointFrames = append(c.checkpointFrames, checkpoint.Frame)
+	if len(c.checkpointFrames) > gossipedCheckpoints {
+		c.checkpointFrames = c.checkpointFrames[1:]
+	}
+	return checkpoint, nil
+}
+
+// AddCheckpointSignatures adds the signatures of participants to our
+// checkpoints. Signatures for frames we did not checkpoint yet are kept until
+// we do.
+func (c *Core) AddCheckpointSignatures(sigs []poset.BlockSignature) {
+	if len(sigs) == 0 {
+		return
+	}
+	c.checkpointLocker.Lock()
+	defer c.checkpointLocker.Unlock()
+
+	updated := make(map[int64]*poset.Checkpoint)
+	for _, sig := range sigs {
+		if _, ok := c.participants.ReadByPubKey(sig.ValidatorHex()); !ok {
+			c.logger.WithField("validator", sig.ValidatorHex()).Debug("Checkpoint signature from a non participant")
+			continue
+		}
+
+		checkpoint, ok := updated[sig.Index]
+		if !ok {
+			cp, err := c.poset.Store.GetCheckpoint(sig.Index)
+			if common.Is(err, common.KeyNotFound) {
+				c.addPendingCheckpointSignature(sig)
+				continue
+			}
+			if err != nil {
+				c.logger.WithError(err).Error("GetCheckpoint")
+				continue
+			}
+			checkpoint = &cp
+			updated[sig.Index] = checkpoint
+		}
+		c.addCheckpointSignature(checkpoint, sig)
+	}
+
+	for _, checkpoint := range updated {
+		if err := c.poset.Store.SetCheckpoint(*checkpoint); err != nil {
+			c.logger.WithError(err).Error("SetCheckpoint")
+		}
+	}
+}
+
+// addCheckpointSignature sets sig on checkpoint when it verifies
+func (c *Core) addCheckpointSignature(checkpoint *poset.Checkpoint, sig poset.BlockSignature) {
+	if _, ok := checkpoint.Signatures[sig.ValidatorHex()]; ok {
+		return
+	}
+	if ok, err := checkpoint.Verify(sig); !ok || err != nil {
+		c.logger.WithField("validator", sig.ValidatorHex()).WithError(err).
+			Debug("Invalid checkpoint signature")
+		return
+	}
+	checkpoint.SetSignature(sig)
+}
+
+func (c *Core) addPendingCheckpointSignature(sig poset.BlockSignature) {
+	if last := len(c.checkpointFrames); last > 0 && sig.Index <= c.checkpointFrames[last-1] {
+		// a frame we skipped, such as one before a fast-forward
+		return
+	}
+	if _, ok := c.pendingCheckpointSigs[sig.Index]; !ok &&
+		len(c.pendingCheckpointSigs) >= maxPendingCheckpoints {
+		return
+	}
+	c.pendingCheckpointSigs[sig.Index] = append(c.pendingCheckpointSigs[sig.Index], sig)
+}
+
+// CheckpointSignatures returns the signatures we know for our latest
+// checkpoints, to gossip them on
+func (c *Core) CheckpointSignatures() []poset.BlockSignature {
+	c.checkpointLocker.Lock()
+	defer c.checkpointLocker.Unlock()
+
+	var sigs []poset.BlockSignature
+	for _, frame := range c.checkpointFrames {
+		checkpoint, err := c.poset.Store.GetCheckpoint(frame)
+		if err != nil {
+			continue
+		}
+		sigs = append(sigs, checkpoint.GetSignatures()...)
+	}
+	return sigs
+}
+
+// GetCheckpoint returns the checkpoint of a frame and whether its signers hold
+// more than the trust count, which makes it a proof of finality
+func (c *Core) GetCheckpoint(frame int64) (poset.Checkpoint, bool, error) {
+	c.checkpointLocker.Lock()
+	defer c.checkpointLocker.Unlock()
+	checkpoint, err := c.poset.Store.GetCheckpoint(frame)
+	if err != nil {
+		return poset.Checkpoint{}, false, err
+	}
+
+	// the stored signatures keep growing, hand out a copy
+	res := poset.NewCheckpoint(checkpoint.Frame, checkpoint.FrameHash, checkpoint.StateHash)
+	for val, sig := range checkpoint.Signatures {
+		res.Signatures[val] = sig
+	}
+	signed, trustCount := c.checkpointStake(res)
+	return res, signed > trustCount, nil
+}
+
+// VerifyCheckpoint returns whether the participants with a valid signature on
+// a checkpoint received from a peer hold more than the trust count
+func (c *Core) VerifyCheckpoint(checkpoint poset.Checkpoint) bool {
+	res := poset.NewCheckpoint(checkpoint.Frame, checkpoint.FrameHash, checkpoint.StateHash)
+	for _, sig := range checkpoint.GetSignatures() {
+		c.addCheckpointSignature(&res, sig)
+	}
+	signed, trustCount := c.checkpointStake(res)
+	return signed > trustCount
+}
+
+// checkpointStake returns the stake of the signers and the trust count.
+// Participants count one each unless stakes were given.
+func (c *Core) checkpointStake(checkpoint poset.Checkpoint) (signed, trustCount uint64) {
+	var stake, signedCount uint64
+	for _, p := range c.participants.ToPeerSlice() {
+		_, ok := checkpoint.Signatures[p.Message.PubKeyHex]
+		stake += p.GetWeight()
+		if ok {
+			signed += p.GetWeight()
+			signedCount++
+		}
+	}
+	if stake == 0 {
+		signed = signedCount
+	}
+	return signed, c.participants.GetTrustCount()
+}
@@ -0,0 +1,66 @@
+package node
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy"
+)
+
+// The commit journal of the store keeps the blocks handed to the app until
+// the app acknowledges them. A node restarted in between delivers them again
+// before any new block, and never delivers a block the app acknowledged,
+// such as those the Bootstrap emits again.
+
+// watchAcks has the app proxy report the blocks the app acknowledged when it
+// can, otherwise a block is acknowledged when CommitBlock returns no error
+func (n *Node) watchAcks() {
+	if a, ok := n.proxy.(proxy.Acknowledger); ok {
+		a.SetAckHandler(n.acknowledged)
+		n.proxyAcks = true
+	}
+}
+
+// acknowledged records that the app acknowledged a block
+func (n *Node) acknowledged(index int64, stateHash []byte) {
+	if err := n.core.poset.Store.AcknowledgeBlock(index); err != nil {
+		n.logger.WithError(err).WithField("block", index).Error("Acknowledging block")
+		return
+	}
+	n.logger.WithFields(logrus.Fields{
+		"block":      index,
+		"state_hash": stateHash,
+	}).Debug("Block acknowledged")
+}
+
+// journalBlocks journals the blocks about to be emitted to the app, leaving
+// out those it acknowledged already
+func (n *Node) journalBlocks(blocks []poset.Block) ([]poset.Block, error) {
+	last, err := n.core.poset.Store.LastAcknowledgedBlock()
+	if err != nil {
+		return nil, err
+	}
+	var res []poset.Block
+	for _, block := range blocks {
+		if block.Index() <= last {
+			n.logger.WithField("block", block.Index()).Debug("Skipping acknowledged block")
+			continue
+		}
+		if err := n.core.poset.Store.JournalBlock(block); err != nil {
+			return nil, err
+		}
+		res = append(res, block)
+	}
+	return res, nil
+}
+
+// redeliverJournalled commits again, in order, the blocks a previous run
+// emitted without the app acknowledging them
+func (n *Node) redeliverJournalled() error {
+	blocks, err := n.core.poset.Store.JournalledBlocks()
+	if err != nil || len(blocks) == 0 {
+		return err
+	}
+	n.logger.WithField("blocks", len(blocks)).Info("Delivering the blocks not acknowledged again")
+	return n.commitBlocks(blocks)
+}
@@ -0,0 +1,122 @@
+package node
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy"
+)
+
+var errAppKilled = errors.New("app killed")
+
+// killableApp is a dummy KV app recording the blocks it applied. Killed, it
+// loses the blocks it receives before answering for them.
+type killableApp struct {
+	*dummy.KVState
+	killed    bool
+	delivered map[int64]int
+}
+
+func (a *killableApp) CommitHandler(block poset.Block) ([]byte, error) {
+	if a.killed {
+		return nil, errAppKilled
+	}
+	a.delivered[block.Index()]++
+	return a.KVState.CommitHandler(block)
+}
+
+func TestCommitJournalRedelivery(t *testing.T) {
+	data := InitTestData(t, 1, 2)
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "badger")
+
+	newNode := func(store poset.Store, app *killableApp) *Node {
+		network, createFu := createNetwork()
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[0],
+			data.PoolSize, createFu, network.CreateListener)
+		selectorArgs := SmartPeerSelectorCreationFnArgs{LocalAddr: data.Adds[0]}
+		node, err := NewNode(data.Config, data.PeersSlice[0].ID, data.Keys[0], data.Peers,
+			store, trans, proxy.NewInmemAppProxy(app, data.Logger),
+			NewSmartPeerSelectorWrapper, selectorArgs, data.Adds[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := node.Init(); err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+
+	var blocks []poset.Block
+	for i, tx := range [][]byte{dummy.SetTx("a", "1"), dummy.SetTx("b", "2"), dummy.SetTx("a", "3")} {
+		blocks = append(blocks, poset.NewBlock(int64(i), int64(i+1), []byte("framehash"), [][]byte{tx}))
+	}
+
+	store, err := poset.NewBadgerStore(data.Peers, data.Config.CacheSize, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &killableApp{KVState: dummy.NewKVState(data.Logger), delivered: make(map[int64]int)}
+	node := newNode(store, app)
+
+	if err := node.commitBlocks(blocks[:1]); err != nil {
+		t.Fatal(err)
+	}
+	// the app is killed once the block is emitted, before it acknowledges
+	// it, and the node stops
+	app.killed = true
+	if err := node.commitBlocks(blocks[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	node.Shutdown()
+
+	// the app comes back with what it applied, and so does the node
+	app.killed = false
+	loaded, err := poset.LoadBadgerStore(data.Config.CacheSize, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node = newNode(loaded, app)
+	defer node.Shutdown()
+
+	if err := node.redeliverJournalled(); err != nil {
+		t.Fatal(err)
+	}
+	// the consensus emits every block again, the new one included
+	if err := node.commitBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, block := range blocks {
+		if n := app.delivered[block.Index()]; n != 1 {
+			t.Fatalf("expected block %d delivered once, got %d times", block.Index(), n)
+		}
+	}
+	journalled, err := loaded.JournalledBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journalled) != 0 {
+		t.Fatalf("expected the journal empty, got %d blocks", len(journalled))
+	}
+
+	expected := dummy.NewKVState(data.Logger)
+	for _, block := range blocks {
+		if _, err := expected.CommitHandler(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(app.StateHash(), expected.StateHash()) {
+		t.Fatalf("expected state hash %X, got %X", expected.StateHash(), app.StateHash())
+	}
+}
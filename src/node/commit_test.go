@@ -0,0 +1,90 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy"
+)
+
+// batchApp is an app committing blocks in batches, which it records
+type batchApp struct {
+	proxy.AppProxy
+	batches []int
+}
+
+func (a *batchApp) CommitBlocks(blocks []poset.Block) ([][]byte, error) {
+	a.batches = append(a.batches, len(blocks))
+	var stateHashes [][]byte
+	for _, block := range blocks {
+		stateHash, err := a.CommitBlock(block)
+		if err != nil {
+			return stateHashes, err
+		}
+		stateHashes = append(stateHashes, stateHash)
+	}
+	return stateHashes, nil
+}
+
+func TestQueuedBlocks(t *testing.T) {
+	n := &Node{
+		conf:     &Config{CommitBatchSize: 3},
+		commitCh: make(chan poset.Block, 10),
+	}
+	for i := int64(1); i < 5; i++ {
+		n.commitCh <- poset.NewBlock(i, i, nil, nil)
+	}
+
+	blocks := n.queuedBlocks(poset.NewBlock(0, 0, nil, nil))
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(blocks))
+	}
+	for i, block := range blocks {
+		if block.Index() != int64(i) {
+			t.Fatalf("expected block %d at %d, got %d", i, i, block.Index())
+		}
+	}
+	if len(n.commitCh) != 2 {
+		t.Fatalf("expected 2 blocks left queued, got %d", len(n.commitCh))
+	}
+}
+
+func TestCommitBlocks(t *testing.T) {
+	data := InitTestData(t, 1, 2)
+	trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[0],
+		data.PoolSize, data.CreateFu, data.Network.CreateListener)
+	defer transportClose(t, trans)
+
+	kv, state := dummy.NewInmemKVDummyApp(data.Logger)
+	app := &batchApp{AppProxy: kv}
+	node := createNodeWithApp(t, data.Config, data.PeersSlice[0].ID,
+		data.Keys[0], data.Peers, trans, app, data.Adds[0], false)
+	defer node.Shutdown()
+
+	var blocks []poset.Block
+	for i, tx := range [][]byte{dummy.SetTx("a", "1"), dummy.SetTx("b", "2"), dummy.DelTx("a")} {
+		blocks = append(blocks, poset.NewBlock(int64(i), int64(i+1), []byte("framehash"), [][]byte{tx}))
+	}
+
+	// a single block goes the usual way
+	if err := node.commitBlocks(blocks[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if len(app.batches) != 0 {
+		t.Fatalf("expected no batch for a single block, got %v", app.batches)
+	}
+
+	if err := node.commitBlocks(blocks[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if len(app.batches) != 1 || app.batches[0] != 2 {
+		t.Fatalf("expected one batch of 2 blocks, got %v", app.batches)
+	}
+	if v, ok := state.Query("b"); !ok || v != "2" {
+		t.Fatalf("expected b set to 2, got %q", v)
+	}
+	if _, ok := state.Query("a"); ok {
+		t.Fatal("expected the blocks committed in order")
+	}
+}
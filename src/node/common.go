@@ -21,8 +21,9 @@ const delay = 100 * time.Millisecond
 // NodeList is a list of connected nodes for tests purposes
 type NodeList map[*ecdsa.PrivateKey]*Node
 
-// NewNodeList makes, fills and runs NodeList instance
-func NewNodeList(count int, logger *logrus.Logger) NodeList {
+// NewNodeList makes, fills and runs NodeList instance. The nodes already
+// running are shut down when one cannot be made.
+func NewNodeList(count int, logger *logrus.Logger) (NodeList, error) {
 	config := DefaultConfig()
 	syncBackConfig := peer.NewBackendConfig()
 
@@ -58,7 +59,8 @@ func NewNodeList(count int, logger *logrus.Logger) NodeList {
 		backend := peer.NewBackend(
 			syncBackConfig, logger, network.CreateListener)
 		if err := backend.ListenAndServe(peer.TCP, peer2.Message.NetAddr); err != nil {
-			logger.Panic(err)
+			nodes.shutdown()
+			return nil, err
 		}
 		transport := peer.NewTransport(logger, producer, backend)
 
@@ -67,26 +69,41 @@ func NewNodeList(count int, logger *logrus.Logger) NodeList {
 			GetFlagTable: nil,
 		}
 
-		n := NewNode(
+		store := poset.NewInmemStore(participants, config.CacheSize, nil)
+		store.EnableTxIndex()
+
+		n, err := NewNode(
 			config,
 			peer2.ID,
 			key,
 			participants,
-			poset.NewInmemStore(participants, config.CacheSize, nil),
+			store,
 			transport,
 			dummy.NewInmemDummyApp(logger),
 			NewSmartPeerSelectorWrapper,
 			selectorArgs,
 			peer2.Message.NetAddr,
 			)
-		if err := n.Init(); err != nil {
-			logger.Fatal(err)
+		if err == nil {
+			err = n.Init()
+		}
+		if err != nil {
+			backend.Close()
+			nodes.shutdown()
+			return nil, err
 		}
 		n.RunAsync(true)
 		nodes[key] = n
 	}
 
-	return nodes
+	return nodes, nil
+}
+
+// shutdown stops the nodes of the list
+func (n NodeList) shutdown() {
+	for _, node := range n {
+		node.Shutdown()
+	}
 }
 
 // Keys returns the all PrivateKeys slice
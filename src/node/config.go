@@ -6,9 +6,53 @@ import (
 
 	"github.com/SamuelMarks/dag1/src/common"
 	"github.com/SamuelMarks/dag1/src/log"
+	"github.com/SamuelMarks/dag1/src/poset"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// defaultPauseQueueSize is the number of transactions accepted while paused
+	defaultPauseQueueSize = 10000
+	// defaultReadyHeartbeats is the number of heartbeats a ready node may go
+	// without syncing
+	defaultReadyHeartbeats = 10
+	// defaultReadyRoundWindow is how long a ready node may go without the
+	// last consensus round advancing
+	defaultReadyRoundWindow = time.Minute
+	// defaultPushThreshold is the number of events a peer has to lack for the
+	// node to push them right after pulling from it
+	defaultPushThreshold = 1
+	// defaultStallWarnTimeout is how long the last consensus round may go
+	// without advancing before the pipeline status is logged
+	defaultStallWarnTimeout = time.Minute
+	// defaultTickStallTimeout is how long the last consensus round may go
+	// without advancing before the node creates an empty event
+	defaultTickStallTimeout = 30 * time.Second
+	// defaultTickInterval is the least time between two empty events created
+	// for a stalled consensus
+	defaultTickInterval = 10 * time.Second
+	// defaultCommitBatchSize is the max number of queued blocks committed to
+	// the app together
+	defaultCommitBatchSize = 100
+	// defaultSyncTimeBudget is the transfer time the events asked for in a
+	// sync should take, at the bandwidth measured with the peer
+	defaultSyncTimeBudget = 500 * time.Millisecond
+	// defaultSyncMinEvents and defaultSyncMaxEvents bound the events asked
+	// for in a sync
+	defaultSyncMinEvents = 10
+	defaultSyncMaxEvents = 10000
+	// defaultSyncMaxBytes bounds the size of the events of a sync response
+	defaultSyncMaxBytes = 32 << 20
+	// defaultFramePageEvents is the number of events of the pages of a fast
+	// forward frame
+	defaultFramePageEvents = 1000
+	// defaultFlowControlSkew is the number of rounds the head of the node
+	// may run ahead of the last consensus round before its heartbeat slows
+	defaultFlowControlSkew = 10
+	// defaultFlowControlMaxFactor bounds the slowing of the heartbeat
+	defaultFlowControlMaxFactor = 16
+)
+
 // Config for node configuration settings
 type Config struct {
 	HeartbeatTimeout time.Duration `mapstructure:"heartbeat"`
@@ -16,7 +60,155 @@ type Config struct {
 	CacheSize        int           `mapstructure:"cache-size"`
 	SyncLimit        int64         `mapstructure:"sync-limit"`
 	Logger           *logrus.Logger
-	TestDelay        uint64 `mapstructure:"test_delay"`
+	TestDelay        uint64        `mapstructure:"test_delay"`
+	PauseQueueSize   int           `mapstructure:"pause-queue"`
+	ReadyHeartbeats  int           `mapstructure:"ready-heartbeats"`
+	ReadyRoundWindow time.Duration `mapstructure:"ready-round-window"`
+	// HeartbeatMax is the heartbeat of the node while it has nothing to
+	// gossip about, never below HeartbeatTimeout
+	HeartbeatMax time.Duration `mapstructure:"heartbeat-max"`
+	// PushThreshold is the number of our events the known map of a sync
+	// response has to lack for the node to push them with a ForceSync,
+	// instead of waiting for the peer to pull them. 0 never pushes.
+	PushThreshold int64 `mapstructure:"push-threshold"`
+	// SyncTimeBudget is how long the transfer of the events of a sync should
+	// take. The events asked for are those the bandwidth measured with the
+	// peer carries in that time, between SyncMinEvents and SyncMaxEvents.
+	// 0 asks for all the events, up to SyncLimit.
+	SyncTimeBudget time.Duration `mapstructure:"sync-time-budget"`
+	SyncMinEvents  int64         `mapstructure:"sync-min-events"`
+	SyncMaxEvents  int64         `mapstructure:"sync-max-events"`
+	// SyncMaxBytes bounds the size of the events the node asks for and
+	// those it answers with, 0 for no bound
+	SyncMaxBytes int64 `mapstructure:"sync-max-bytes"`
+	// FramePageEvents is the number of events asked for per page of the
+	// frame of a fast forward, within SyncMaxBytes and the max message size
+	// of the connection
+	FramePageEvents int64 `mapstructure:"frame-page-events"`
+	// FlowControlSkew is the number of rounds the head of the node may run
+	// ahead of the last consensus round before the node slows its heartbeat,
+	// doubling it each heartbeat up to FlowControlMaxFactor times until the
+	// skew shrinks, see flowControl. 0 never slows it, nor does a network of
+	// two participants.
+	FlowControlSkew      int64 `mapstructure:"flow-control-skew"`
+	FlowControlMaxFactor int64 `mapstructure:"flow-control-max-factor"`
+	// UndeterminedWarnAge is the number of rounds an event may stay
+	// undetermined before a warning names the round blocking it
+	UndeterminedWarnAge int64 `mapstructure:"undetermined-warn-age"`
+	// UndeterminedArchiveAge is the number of rounds after which an
+	// undetermined event is moved out of memory to the store, where it is
+	// still decided but less often. 0 keeps them all in memory.
+	UndeterminedArchiveAge int64 `mapstructure:"undetermined-archive-age"`
+	// StallWarnTimeout is how long the last consensus round may go without
+	// advancing before a summary of the pipeline status is logged, and then
+	// logged again every as long. 0 never logs it.
+	StallWarnTimeout time.Duration `mapstructure:"stall-warn-timeout"`
+	// TickStallTimeout is how long the last consensus round may go without
+	// advancing, while events wait for consensus, before the node creates an
+	// empty event for them to gather support, see Node.CreateEmptyEvent.
+	// 0 never creates one.
+	TickStallTimeout time.Duration `mapstructure:"tick-stall-timeout"`
+	// TickInterval is the least time between two such empty events, which
+	// bounds them while the network is partitioned
+	TickInterval time.Duration `mapstructure:"tick-interval"`
+	// TxPoolSize and TxPoolBytes bound the number and the total size of the
+	// transactions waiting to be put in events, 0 for no bound
+	TxPoolSize  int `mapstructure:"tx-pool-size"`
+	TxPoolBytes int `mapstructure:"tx-pool-bytes"`
+	// TxPoolPolicy is what a full pool does with a new transaction,
+	// TxPoolRejectNew or TxPoolEvictOldest
+	TxPoolPolicy string `mapstructure:"tx-pool-policy"`
+	// MaxEventsPerFrame caps the events a creator may make per frame, 0 for
+	// no cap. It is part of the consensus configuration.
+	MaxEventsPerFrame int `mapstructure:"max-events-per-frame"`
+	// MaxBlockTransactions and MaxBlockBytes bound the transactions of a
+	// block, 0 for no bound, the rest carrying over into the next block.
+	// They are part of the consensus configuration.
+	MaxBlockTransactions int `mapstructure:"max-block-transactions"`
+	MaxBlockBytes        int `mapstructure:"max-block-bytes"`
+	// MaxTimestampDrift bounds how far in the future of its other-parent an
+	// event may claim to be made for the block times, 0 for no bound, see
+	// poset.ConsensusParams. It is part of the consensus configuration.
+	MaxTimestampDrift time.Duration `mapstructure:"max-timestamp-drift"`
+	// DevSingleSuperMajority makes any single participant a supermajority,
+	// for local development networks. It is UNSAFE, and part of the
+	// consensus configuration so that no peer without it accepts the node.
+	DevSingleSuperMajority bool `mapstructure:"dev-single-supermajority"`
+	// ConsensusParamsFile is a JSON file of poset.ConsensusParams, the one
+	// operators distribute to agree on them
+	ConsensusParamsFile string `mapstructure:"consensus-params-file"`
+	// CommitBatchSize is the max number of queued blocks committed to the
+	// app in a single round trip, when its proxy supports it
+	CommitBatchSize int `mapstructure:"commit-batch"`
+	// CacheBudget is the total number of items the dominator, round and
+	// timestamp caches of the poset may grow to when their hit rates fall,
+	// 0 for fixed sizes
+	CacheBudget int `mapstructure:"cache-budget"`
+	// CacheGrowBelow is the hit rate under which such a cache grows, and
+	// CacheShrinkAbove the one a grown cache must keep to shrink back, the
+	// poset defaults when 0
+	CacheGrowBelow   float64 `mapstructure:"cache-grow-below"`
+	CacheShrinkAbove float64 `mapstructure:"cache-shrink-above"`
+	// Metrics serves the metrics of the node at the /metrics endpoint of
+	// the service
+	Metrics bool `mapstructure:"metrics"`
+	// NetworkName is the name of the chain, which together with the genesis
+	// makes the network ID
+	NetworkName string `mapstructure:"network-id"`
+	// NetworkIDCompat accepts peers and events without a network ID, from
+	// old clients, while a network migrates
+	NetworkIDCompat bool `mapstructure:"network-id-compat"`
+	// NetworkID is derived from the genesis and NetworkName before the node
+	// is made. The zero ID checks nothing.
+	NetworkID common.Hash `mapstructure:"-"`
+	// RequireQuorum keeps the node from putting transactions in its events,
+	// and from taking transactions from the service, until it has synced
+	// with the peers which with it make a supermajority of the participants,
+	// within ReadyHeartbeats heartbeats. The events carrying only block
+	// signatures or internal transactions are still created. A single
+	// participant needs none.
+	RequireQuorum bool `mapstructure:"require-quorum"`
+	// OtherParentSelector is the strategy choosing the other-parent of the
+	// events of the node, one of OtherParentSelectors
+	OtherParentSelector string `mapstructure:"other-parent-selector"`
+
+	// Observer makes the node follow consensus without creating events even
+	// when its key is among the participants
+	Observer bool
+
+	// Clock is the time of the node, its heartbeat, watchdogs and the times
+	// of its events and blocks. The tests set a common.ManualClock.
+	Clock common.Clock `mapstructure:"-"`
+}
+
+// Consensus returns the part of the configuration deciding which events are
+// accepted, which every participant must share
+func (c *Config) Consensus() poset.ConsensusParams {
+	return poset.ConsensusParams{
+		MaxEventsPerFrame:      c.MaxEventsPerFrame,
+		MaxBlockTransactions:   c.MaxBlockTransactions,
+		MaxBlockBytes:          c.MaxBlockBytes,
+		DevSingleSuperMajority: c.DevSingleSuperMajority,
+		MaxTimestampDrift:      c.MaxTimestampDrift,
+	}
+}
+
+// LoadConsensusParams sets the consensus parameters from
+// ConsensusParamsFile, when there is one. They override those set otherwise.
+func (c *Config) LoadConsensusParams() error {
+	if c.ConsensusParamsFile == "" {
+		return nil
+	}
+	params, err := poset.ReadConsensusParams(c.ConsensusParamsFile)
+	if err != nil {
+		return err
+	}
+	c.MaxEventsPerFrame = params.MaxEventsPerFrame
+	c.MaxBlockTransactions = params.MaxBlockTransactions
+	c.MaxBlockBytes = params.MaxBlockBytes
+	c.DevSingleSuperMajority = params.DevSingleSuperMajority
+	c.MaxTimestampDrift = params.MaxTimestampDrift
+	return nil
 }
 
 // NewConfig creates a new node config
@@ -32,6 +224,34 @@ func NewConfig(heartbeat time.Duration,
 		CacheSize:        cacheSize,
 		SyncLimit:        syncLimit,
 		Logger:           logger,
+		PauseQueueSize:   defaultPauseQueueSize,
+		ReadyHeartbeats:  defaultReadyHeartbeats,
+		ReadyRoundWindow: defaultReadyRoundWindow,
+		HeartbeatMax:     idleHeartbeat,
+		PushThreshold:    defaultPushThreshold,
+		SyncTimeBudget:   defaultSyncTimeBudget,
+		SyncMinEvents:    defaultSyncMinEvents,
+		SyncMaxEvents:    defaultSyncMaxEvents,
+		SyncMaxBytes:     defaultSyncMaxBytes,
+		FramePageEvents:  defaultFramePageEvents,
+
+		FlowControlSkew:      defaultFlowControlSkew,
+		FlowControlMaxFactor: defaultFlowControlMaxFactor,
+
+		UndeterminedWarnAge: poset.DefaultUndeterminedWarnAge,
+		StallWarnTimeout:    defaultStallWarnTimeout,
+		TickStallTimeout:    defaultTickStallTimeout,
+		TickInterval:        defaultTickInterval,
+		TxPoolSize:          defaultTxPoolSize,
+		TxPoolBytes:         defaultTxPoolBytes,
+		TxPoolPolicy:        TxPoolRejectNew,
+		CommitBatchSize:     defaultCommitBatchSize,
+		CacheGrowBelow:      poset.DefaultCacheGrowBelow,
+		CacheShrinkAbove:    poset.DefaultCacheShrinkAbove,
+		RequireQuorum:       true,
+		OtherParentSelector: OtherParentLastSync,
+		Metrics:             true,
+		Clock:               common.RealClock,
 	}
 }
 
@@ -48,6 +268,34 @@ func DefaultConfig() *Config {
 		SyncLimit:        100000,
 		Logger:           logger,
 		TestDelay:        1,
+		PauseQueueSize:   defaultPauseQueueSize,
+		ReadyHeartbeats:  defaultReadyHeartbeats,
+		ReadyRoundWindow: defaultReadyRoundWindow,
+		HeartbeatMax:     idleHeartbeat,
+		PushThreshold:    defaultPushThreshold,
+		SyncTimeBudget:   defaultSyncTimeBudget,
+		SyncMinEvents:    defaultSyncMinEvents,
+		SyncMaxEvents:    defaultSyncMaxEvents,
+		SyncMaxBytes:     defaultSyncMaxBytes,
+		FramePageEvents:  defaultFramePageEvents,
+
+		FlowControlSkew:      defaultFlowControlSkew,
+		FlowControlMaxFactor: defaultFlowControlMaxFactor,
+
+		UndeterminedWarnAge: poset.DefaultUndeterminedWarnAge,
+		StallWarnTimeout:    defaultStallWarnTimeout,
+		TickStallTimeout:    defaultTickStallTimeout,
+		TickInterval:        defaultTickInterval,
+		TxPoolSize:          defaultTxPoolSize,
+		TxPoolBytes:         defaultTxPoolBytes,
+		TxPoolPolicy:        TxPoolRejectNew,
+		CommitBatchSize:     defaultCommitBatchSize,
+		CacheGrowBelow:      poset.DefaultCacheGrowBelow,
+		CacheShrinkAbove:    poset.DefaultCacheShrinkAbove,
+		RequireQuorum:       true,
+		OtherParentSelector: OtherParentLastSync,
+		Metrics:             true,
+		Clock:               common.RealClock,
 	}
 }
 
@@ -55,6 +303,7 @@ func DefaultConfig() *Config {
 func TestConfig(t testing.TB) *Config {
 	config := DefaultConfig()
 	config.HeartbeatTimeout = time.Second * 1
+	config.RequireQuorum = false
 
 	config.Logger = common.NewTestLogger(t)
 
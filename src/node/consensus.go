@@ -0,0 +1,76 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// ConsensusMismatchError is returned when syncing with a peer whose consensus
+// parameters differ from ours. Diff is empty when the peer did not send its
+// parameters.
+type ConsensusMismatchError struct {
+	PeerID uint64
+	Ours   common.Hash
+	Theirs common.Hash
+	Diff   []string
+}
+
+func (e *ConsensusMismatchError) Error() string {
+	msg := fmt.Sprintf("peer %d has the consensus parameters %s, ours are %s",
+		e.PeerID, e.Theirs.Hex(), e.Ours.Hex())
+	if len(e.Diff) > 0 {
+		msg += " (" + strings.Join(e.Diff, ", ") + ")"
+	}
+	return msg
+}
+
+// IsConsensusMismatch returns true for a ConsensusMismatchError
+func IsConsensusMismatch(err error) bool {
+	_, ok := err.(*ConsensusMismatchError)
+	return ok
+}
+
+// checkConsensus refuses a peer with other consensus parameters than ours,
+// a peer sending no hash included. What differs is logged when the peer sent
+// its parameters.
+func (n *Node) checkConsensus(peerID uint64, hash common.Hash, params *poset.ConsensusParams) error {
+	if hash == n.consensusHash {
+		return nil
+	}
+	err := &ConsensusMismatchError{
+		PeerID: peerID,
+		Ours:   n.consensusHash,
+		Theirs: hash,
+	}
+	if params != nil {
+		err.Diff = n.consensus.Diff(*params)
+	}
+	n.logger.WithFields(logrus.Fields{
+		"peer_id": peerID,
+		"ours":    err.Ours.Hex(),
+		"theirs":  err.Theirs.Hex(),
+		"diff":    strings.Join(err.Diff, ", "),
+	}).Error("Consensus parameters differ, refusing to sync")
+	return err
+}
+
+// checkNetwork refuses a sync request from a peer of another network, or
+// without a network ID unless NetworkIDCompat is set
+func (n *Node) checkNetwork(peerID uint64, id common.Hash) error {
+	ours := n.conf.NetworkID
+	if ours == (common.Hash{}) || id == ours || id == (common.Hash{}) && n.conf.NetworkIDCompat {
+		return nil
+	}
+	n.logger.WithFields(logrus.Fields{
+		"peer_id": peerID,
+		"ours":    ours.Hex(),
+		"theirs":  id.Hex(),
+	}).Error("Peer of another network, refusing to sync")
+	return &peer.NetworkError{Ours: ours, Theirs: id}
+}
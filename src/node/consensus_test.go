@@ -0,0 +1,53 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConsensusMismatch(t *testing.T) {
+	data := InitTestData(t, 3, 2)
+
+	var nodes []*Node
+	for i := 0; i < 3; i++ {
+		conf := *data.Config
+		if i == 2 {
+			conf.MaxEventsPerFrame = 5
+		}
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+		node := createNode(t, data.Logger, &conf, data.Peers.ByNetAddr[data.Adds[i]].ID, data.Keys[i],
+			data.Peers, trans, data.Adds[i], false)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+
+	// the same parameters sync
+	if _, _, err := nodes[0].pull(data.Peers.ByNetAddr[data.Adds[1]]); err != nil {
+		t.Fatal(err)
+	}
+
+	// other ones are refused both ways, the requester tells what differs
+	for _, c := range []struct {
+		from, to int
+		diff     []string
+	}{
+		{0, 2, []string{"max_events_per_frame: 0 != 5"}},
+		{2, 0, []string{"max_events_per_frame: 5 != 0"}},
+	} {
+		_, _, err := nodes[c.from].pull(data.Peers.ByNetAddr[data.Adds[c.to]])
+		mismatch, ok := err.(*ConsensusMismatchError)
+		if !ok {
+			t.Fatalf("%d from %d: expected a consensus mismatch, got %v", c.from, c.to, err)
+		}
+		if !reflect.DeepEqual(mismatch.Diff, c.diff) {
+			t.Fatalf("%d from %d: expected the diff %v, got %v", c.from, c.to, c.diff, mismatch.Diff)
+		}
+	}
+
+	// both other nodes have other parameters than the third one
+	if err := nodes[2].fastForward(); !IsConsensusMismatch(err) {
+		t.Fatalf("expected a consensus mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package node
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// consensusQueueSize bounds the hints waiting for the consensus worker. A
+// hint sent to a full queue is dropped, the pass it asks for is due anyway.
+const consensusQueueSize = 16
+
+// consensusHint tells the consensus worker why a pass is due
+type consensusHint int
+
+const (
+	// hintEvents is sent when new events were inserted
+	hintEvents consensusHint = iota
+	// hintRound is sent when the inserted events reached a new round
+	hintRound
+)
+
+// hintConsensus asks the consensus worker for a pass, without waiting
+func (n *Node) hintConsensus(hint consensusHint) {
+	select {
+	case n.consensusCh <- hint:
+	default:
+	}
+}
+
+// runConsensus is the consensus worker: it runs a consensus pass for each
+// batch of hints until the node shuts down. The hints queued while a pass
+// runs are coalesced into the next one. Passes hold batchLock, not
+// coreLock, so the node keeps answering its peers while they run.
+func (n *Node) runConsensus() {
+	for {
+		select {
+		case hint := <-n.consensusCh:
+			hints, round := 1, hint == hintRound
+		coalesce:
+			for {
+				select {
+				case hint := <-n.consensusCh:
+					hints++
+					round = round || hint == hintRound
+				default:
+					break coalesce
+				}
+			}
+			if !n.consensusPassLocked(hints, round) {
+				return
+			}
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// consensusPassLocked runs a consensus pass between two batches of inserted
+// events, and returns false once the node shut down
+func (n *Node) consensusPassLocked(hints int, round bool) bool {
+	n.batchLock.Lock()
+	defer n.batchLock.Unlock()
+	select {
+	case <-n.shutdownCh:
+		return false
+	default:
+	}
+
+	if err := n.consensusPass(); err != nil {
+		n.logger.WithError(err).Error("Consensus pass")
+	}
+	n.logger.WithFields(logrus.Fields{
+		"hints": hints,
+		"round": round,
+	}).Debug("Consensus pass")
+	n.health.observeRound(n.core.GetLastConsensusRound())
+	return true
+}
+
+// waitConsensusPass waits for the consensus pass in progress, if any
+func (n *Node) waitConsensusPass() {
+	n.batchLock.Lock()
+	n.batchLock.Unlock()
+}
@@ -0,0 +1,159 @@
+package node
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// createNodeWithPass is createNode with the consensus pass replaced, before
+// the node runs
+func createNodeWithPass(t *testing.T, data *TestData, config *Config, i int,
+	pass func(n *Node) error, gossip bool) *Node {
+	trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+		data.PoolSize, data.CreateFu, data.Network.CreateListener)
+	participants := nodePeers(data.Peers)
+	db := poset.NewInmemStore(participants, config.CacheSize, nil)
+	selectorArgs := SmartPeerSelectorCreationFnArgs{LocalAddr: data.Adds[i]}
+	node, err := NewNode(config, data.Peers.ByNetAddr[data.Adds[i]].ID, data.Keys[i], participants,
+		db, trans, dummy.NewInmemDummyApp(data.Logger), NewSmartPeerSelectorWrapper,
+		selectorArgs, data.Adds[i])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Init(); err != nil {
+		t.Fatal(err)
+	}
+	runConsensus := node.consensusPass
+	node.consensusPass = func() error {
+		if err := pass(node); err != nil {
+			return err
+		}
+		return runConsensus()
+	}
+	go node.Run(gossip)
+	return node
+}
+
+func TestConsensusWorkerCoalescesHints(t *testing.T) {
+	data := InitTestData(t, 1, 2)
+	started := make(chan struct{}, 1)
+	var passes int32
+	node := createNodeWithPass(t, data, data.Config, 0, func(*Node) error {
+		if atomic.AddInt32(&passes, 1) == 1 {
+			started <- struct{}{}
+		}
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}, false)
+	defer node.Shutdown()
+
+	node.hintConsensus(hintEvents)
+	<-started
+	// hints sent while a pass runs, even more than the queue holds, make
+	// a single pass
+	for i := 0; i < 2*consensusQueueSize; i++ {
+		node.hintConsensus(hintEvents)
+	}
+	node.hintConsensus(hintRound)
+
+	time.Sleep(500 * time.Millisecond)
+	if p := atomic.LoadInt32(&passes); p != 2 {
+		t.Fatalf("expected 2 passes, got %d", p)
+	}
+}
+
+// TestConsensusWorkerSlowPass checks that a node with slow consensus passes
+// goes on answering the sync requests of its peers without waiting for them
+func TestConsensusWorkerSlowPass(t *testing.T) {
+	const passDelay = 300 * time.Millisecond
+
+	data := InitTestData(t, 2, 2)
+	config := *data.Config
+	config.HeartbeatTimeout = 5 * time.Millisecond
+
+	var passes int32
+	slow := createNodeWithPass(t, data, &config, 0, func(*Node) error {
+		atomic.AddInt32(&passes, 1)
+		time.Sleep(passDelay)
+		return nil
+	}, true)
+	defer slow.Shutdown()
+	other := createNodeWithPass(t, data, &config, 1, func(*Node) error { return nil }, true)
+	defer other.Shutdown()
+
+	// the first request opens the connection
+	if _, err := other.requestSync(data.Adds[0], nil); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	var syncs int
+	var slowest time.Duration
+	for time.Now().Before(deadline) {
+		// keep both nodes gossiping
+		slow.submitCh <- []byte(fmt.Sprintf("slow %d", syncs))
+		other.submitCh <- []byte(fmt.Sprintf("other %d", syncs))
+
+		other.coreLock.Lock()
+		known := other.core.KnownEvents()
+		other.coreLock.Unlock()
+
+		start := time.Now()
+		if _, err := other.requestSync(data.Adds[0], known); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed > slowest {
+			slowest = elapsed
+		}
+		syncs++
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if atomic.LoadInt32(&passes) < 2 {
+		t.Fatalf("expected the slow node to run consensus passes, got %d", passes)
+	}
+	if slowest >= passDelay/2 {
+		t.Fatalf("expected the sync requests to be answered during the passes, "+
+			"the slowest of %d took %v", syncs, slowest)
+	}
+}
+
+// TestCoreSyncQueuesRoots checks that the syncs leave the root events to
+// the consensus pass, which decides them
+func TestCoreSyncQueuesRoots(t *testing.T) {
+	cores, _, _ := initCores(3, t)
+	for _, c := range cores {
+		c.poset.SetRootQueue(true)
+	}
+	for i := 0; i < 30; i++ {
+		from, to := i%3, (i+1)%3
+		if err := synchronizeCores(cores, from, to, [][]byte{[]byte(fmt.Sprint(i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queued := 0
+	for _, c := range cores {
+		queued += c.poset.QueuedRoots()
+		if c.GetPendingLoadedEvents() < int64(c.poset.QueuedRoots()) {
+			t.Fatalf("expected the queued roots pending, got %d", c.GetPendingLoadedEvents())
+		}
+	}
+	if queued == 0 {
+		t.Fatal("expected the syncs to leave roots queued")
+	}
+
+	for i, c := range cores {
+		if err := c.RunConsensus(); err != nil {
+			t.Fatal(err)
+		}
+		if n := c.poset.QueuedRoots(); n != 0 {
+			t.Fatalf("core %d: expected the roots decided, %d left", i, n)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"math/rand"
 	"sync"
 	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
 )
 
 type timerFactory func(time.Duration) <-chan time.Time
@@ -30,15 +32,16 @@ func NewControlTimer(timerFactory timerFactory) *ControlTimer {
 	}
 }
 
-// NewRandomControlTimer creates a random time controller with no defaults set
-func NewRandomControlTimer() *ControlTimer {
+// NewRandomControlTimer creates a random time controller with no defaults
+// set, its timers those of clock
+func NewRandomControlTimer(clock common.Clock) *ControlTimer {
 
 	randomTimeout := func(min time.Duration) <-chan time.Time {
 		if min == 0 {
 			return nil
 		}
 		extra := time.Duration(rand.Int63()) % min
-		return time.After(min + extra)
+		return clock.After(min + extra)
 	}
 	return NewControlTimer(randomTimeout)
 }
@@ -55,7 +58,12 @@ func (c *ControlTimer) Run(init time.Duration) {
 	for {
 		select {
 		case <-timer:
-			c.tickCh <- struct{}{}
+			select {
+			case c.tickCh <- struct{}{}:
+			case <-c.shutdownCh:
+				c.SetSet(false)
+				return
+			}
 			c.SetSet(false)
 		case t := <-c.resetCh:
 			timer = setTimer(t)
@@ -69,6 +77,14 @@ func (c *ControlTimer) Run(init time.Duration) {
 	}
 }
 
+// reset sets the heartbeatTimer again, unless the control timer is shut down
+func (c *ControlTimer) reset(t time.Duration) {
+	select {
+	case c.resetCh <- t:
+	case <-c.shutdownCh:
+	}
+}
+
 // Shutdown the control timer
 func (c *ControlTimer) Shutdown() {
 	close(c.shutdownCh)
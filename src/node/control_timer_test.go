@@ -0,0 +1,89 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+// heartbeat returns true when the control timer ticks shortly
+func heartbeat(c *ControlTimer) bool {
+	select {
+	case <-c.tickCh:
+		return true
+	case <-time.After(100 * time.Millisecond):
+		return false
+	}
+}
+
+func TestControlTimerHeartbeat(t *testing.T) {
+	const timeout = time.Second
+	clock := common.NewManualClock(time.Unix(1500000000, 0))
+	c := NewRandomControlTimer(clock)
+	go c.Run(timeout)
+	defer c.Shutdown()
+
+	// the heartbeat fires between the timeout and twice the timeout
+	clock.BlockUntil(1)
+	clock.Advance(timeout - time.Millisecond)
+	if heartbeat(c) {
+		t.Fatal("expected no heartbeat before the timeout")
+	}
+	clock.Advance(timeout)
+	if !heartbeat(c) {
+		t.Fatal("expected a heartbeat within twice the timeout")
+	}
+
+	// a reset starts a new heartbeat
+	c.resetCh <- timeout
+	clock.BlockUntil(1)
+	if !c.GetSet() {
+		t.Fatal("expected the timer set once reset")
+	}
+	clock.Advance(2 * timeout)
+	if !heartbeat(c) {
+		t.Fatal("expected a heartbeat after the reset")
+	}
+
+	// a stopped timer does not fire
+	c.resetCh <- timeout
+	c.stopCh <- struct{}{}
+	clock.Advance(2 * timeout)
+	if heartbeat(c) {
+		t.Fatal("expected no heartbeat once stopped")
+	}
+}
+
+func TestControlTimerShutdownWithPendingTick(t *testing.T) {
+	const timeout = time.Second
+	clock := common.NewManualClock(time.Unix(1500000000, 0))
+	c := NewRandomControlTimer(clock)
+	done := make(chan struct{})
+	go func() {
+		c.Run(timeout)
+		close(done)
+	}()
+
+	// the heartbeat fires and nobody takes the tick
+	clock.BlockUntil(1)
+	clock.Advance(2 * timeout)
+	c.Shutdown()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return on Shutdown with a tick pending")
+	}
+
+	// nothing resets a timer shut down
+	reset := make(chan struct{})
+	go func() {
+		c.reset(timeout)
+		close(reset)
+	}()
+	select {
+	case <-reset:
+	case <-time.After(time.Second):
+		t.Fatal("expected a reset to return once shut down")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"math/rand"
 	"github.com/sirupsen/logrus"
 
+	"github.com/SamuelMarks/dag1/src/common"
 	"github.com/SamuelMarks/dag1/src/crypto"
 	"github.com/SamuelMarks/dag1/src/log"
 	"github.com/SamuelMarks/dag1/src/peers"
@@ -39,23 +40,50 @@ type Core struct {
 	participants *peers.Peers // [PubKey] => id
 	head         poset.EventHash
 
+	// observer is set when this core's key is not among the participants.
+	// Such a core follows consensus but never creates events.
+	observer bool
+	// quorum tells whether the node synced with enough peers to create
+	// events with transactions, nil for always
+	quorum func() bool
+	// eventVersion returns the version of the body of the events to
+	// create, nil for the highest supported one
+	eventVersion func() uint32
+	// inserted is told about each event inserted, nil for none
+	inserted func(poset.Event)
+
+	// otherParents chooses the other-parent of the self events made on
+	// sync, otherParentRecord records its choices
+	otherParents      OtherParentSelector
+	otherParentRecord *otherParentRecord
+
 	eventCreationRate float64
 
-	transactionPool         [][]byte
+	transactionPool         *txPool
 	internalTransactionPool []poset.InternalTransaction
 	blockSignaturePool      []poset.BlockSignature
 
+	checkpointFrames      []int64 // oldest first
+	pendingCheckpointSigs map[int64][]poset.BlockSignature
+
+	// pending are the synced events waiting for their other-parent
+	pending *pendingEvents
+	// duplicates counts the synced events known already
+	duplicates count64
+
 	logger *logrus.Entry
+	// clock dates the events of the core
+	clock common.Clock
 
 	addSelfEventBlockLocker       sync.Mutex
-	transactionPoolLocker         sync.RWMutex
 	internalTransactionPoolLocker sync.RWMutex
 	blockSignaturePoolLocker      sync.RWMutex
+	checkpointLocker              sync.Mutex
 }
 
 // NewCore creates a new core struct
 func NewCore(id uint64, key *ecdsa.PrivateKey, participants *peers.Peers,
-	store poset.Store, commitCh chan poset.Block, logger *logrus.Logger) *Core {
+	store poset.Store, commitCh chan poset.Block, logger *logrus.Logger) (*Core, error) {
 
 	if logger == nil {
 		logger = logrus.New()
@@ -63,12 +91,13 @@ func NewCore(id uint64, key *ecdsa.PrivateKey, participants *peers.Peers,
 		dag1_log.NewLocal(logger, logger.Level.String())
 	}
 	n, ok := participants.ReadByID(id)
-	var logEntry *logrus.Entry
+	fields := logrus.Fields{"id": id}
 	if ok {
-		logEntry = logger.WithField("id", id).WithField("addr", n.Message.NetAddr)
+		fields["addr"] = n.Message.NetAddr
 	} else {
-		logEntry = logger.WithField("id", id)
+		fields["observer"] = true
 	}
+	logEntry := dag1_log.ForModule(logger, "node").WithFields(fields)
 
 	// add some creation rates for node simulation
 	evCreationRate := 1.0
@@ -78,64 +107,38 @@ func NewCore(id uint64, key *ecdsa.PrivateKey, participants *peers.Peers,
 	}
 	logEntry.WithField("rate", evCreationRate).Debug("Event Creation ratio")
 
-	p2 := poset.NewPoset(participants, store, commitCh, logEntry)
+	p2, err := poset.NewPoset(participants, store, commitCh,
+		dag1_log.ForModule(logger, "poset").WithFields(fields))
+	if err != nil {
+		return nil, err
+	}
 	core := &Core{
 		id:                      id,
 		key:                     key,
 		poset:                   p2,
 		participants:            participants,
 		eventCreationRate:       evCreationRate,
-		transactionPool:         [][]byte{},
+		transactionPool:         newTxPool(defaultTxPoolSize, defaultTxPoolBytes, TxPoolRejectNew),
 		internalTransactionPool: []poset.InternalTransaction{},
 		blockSignaturePool:      []poset.BlockSignature{},
+		pendingCheckpointSigs:   make(map[int64][]poset.BlockSignature),
+		pending:                 newPendingEvents(),
 		logger:                  logEntry,
 		head:                    poset.EventHash{},
+		observer:                !ok,
+		otherParents:            NewOtherParentSelector(OtherParentLastSync),
+		otherParentRecord:       newOtherParentRecord(),
+		clock:                   common.RealClock,
 	}
 
 	p2.SetCore(core)
 
 	// Set Leaf Events for each participant; tag: leaf
-	for _, peer := range participants.ToPeerSlice() {
-		creator, err := peer.PubKeyBytes()
-		if err != nil {
-			panic(err)
-		}
-		body := poset.EventBody{
-			Creator: creator,
-			Index:   0,
-			Parents: poset.EventHashes{poset.EventHash{}, poset.EventHash{}}.Bytes(),
-		}
-		hash, err := body.Hash()
-		if err != nil {
-			panic(err)
-		}
-		ft := poset.NewFlagTable()
-		ft[hash] = 0
-		event := poset.Event{
-			Message: &poset.EventMessage{
-				Hash:             hash.Bytes(),
-				CreatorID:        peer.ID,
-				TopologicalIndex: p2.NextTopologicalIndex(),
-				Body:             &body,
-			},
-			FlagTableBytes:   ft.Marshal(),
-			RootTableBytes:   ft.Marshal(),
-			LamportTimestamp: int64(creator[15]),
-			AtroposTimestamp: int64(creator[15]),
-			Frame:            0,
-			Atropos:          true,
-			Clotho:           true,
-			Root:             true,
-		}
-		event.AtTimes = append(event.AtTimes, event.LamportTimestamp)
-		if err := p2.Store.SetEvent(event); err != nil {
-			panic(err)
-		}
-		//p2.topologicalIndex++
-		peer.SetHeight(0)
+	if err := p2.SetLeafEvents(participants.ToPeerSlice()); err != nil {
+		return nil, err
 	}
 
-	return core
+	return core, nil
 }
 
 // ID returns the ID of this core
@@ -160,6 +163,11 @@ func (c *Core) HexID() string {
 	return c.hexID
 }
 
+// IsObserver returns true if this core is not a participant
+func (c *Core) IsObserver() bool {
+	return c.observer
+}
+
 // Head returns the current chain head for this core
 func (c *Core) Head() poset.EventHash {
 	return c.head
@@ -194,31 +202,16 @@ func (c *Core) InDegrees() map[string]int64 {
 
 // SetHeadAndHeight calculates and sets the current head and height for the chain
 func (c *Core) SetHeadAndHeight() error {
+	if c.observer {
+		// no events of our own to track yet
+		return nil
+	}
 
-	var head poset.EventHash
-	var height int64
-
-	last, isRoot, err := c.poset.Store.LastEventFrom(c.HexID())
+	head, height, isRoot, err := c.lastEventFrom(c.HexID())
 	if err != nil {
 		return err
 	}
 
-	if isRoot {
-		root, err := c.poset.Store.GetRoot(c.HexID())
-		if err != nil {
-			return err
-		}
-		head.Set(root.SelfParent.Hash)
-		height = root.SelfParent.Index
-	} else {
-		lastEvent, err := c.GetEventBlock(last)
-		if err != nil {
-			return err
-		}
-		head = last
-		height = lastEvent.Index()
-	}
-
 	c.head = head
 	c.participants.SetHeightByPubKeyHex(c.HexID(), height)
 
@@ -231,15 +224,69 @@ func (c *Core) SetHeadAndHeight() error {
 	return nil
 }
 
+// lastEventFrom returns the hash and index of the last event of a
+// participant, which is the self-parent of its root when it has none
+func (c *Core) lastEventFrom(pubKey string) (poset.EventHash, int64, bool, error) {
+	last, isRoot, err := c.poset.Store.LastEventFrom(pubKey)
+	if err != nil {
+		return poset.EventHash{}, 0, false, err
+	}
+
+	if isRoot {
+		root, err := c.poset.Store.GetRoot(pubKey)
+		if err != nil {
+			return poset.EventHash{}, 0, false, err
+		}
+		var head poset.EventHash
+		if err := head.Set(root.SelfParent.Hash); err != nil {
+			return poset.EventHash{}, 0, false, err
+		}
+		return head, root.SelfParent.Index, true, nil
+	}
+
+	lastEvent, err := c.GetEventBlock(last)
+	if err != nil {
+		return poset.EventHash{}, 0, false, err
+	}
+	return last, lastEvent.Index(), false, nil
+}
+
 // Bootstrap the poset with default values
 func (c *Core) Bootstrap() error {
 	if err := c.poset.Bootstrap(); err != nil {
 		return err
 	}
-	c.bootstrapInDegrees()
+	c.refreshPeerStats()
 	return nil
 }
 
+// refreshPeerStats recomputes the height and in-degree of the other
+// participants from the store, after it was loaded or reset
+func (c *Core) refreshPeerStats() {
+	for _, pubKey := range c.participants.ToPubKeySlice() {
+		if pubKey == c.HexID() {
+			// ours follows our head, see SetHeadAndHeight
+			continue
+		}
+		if _, height, _, err := c.lastEventFrom(pubKey); err == nil {
+			c.participants.SetHeightByPubKeyHex(pubKey, height)
+		}
+	}
+	c.bootstrapInDegrees()
+}
+
+// recordPeerEvent updates the height of the creator of an inserted event and
+// the in-degrees it changes, the peer selectors rely on both. Our own events
+// come back from the peers too after a restart, the next ones follow them.
+func (c *Core) recordPeerEvent(event poset.Event) {
+	c.participants.SetHeightByPubKeyHex(event.GetCreator(), event.Index())
+	c.participants.SetInDegreeByPubKeyHex(event.GetCreator(), 0)
+
+	if otherEvent, err := c.poset.Store.GetEventBlock(event.OtherParent()); err == nil {
+		c.participants.IncInDegreeByPubKeyHex(otherEvent.GetCreator())
+	}
+}
+
 func (c *Core) bootstrapInDegrees() {
 	for _, pubKey := range c.participants.ToPubKeySlice() {
 		c.participants.SetInDegreeByPubKeyHex(pubKey, 0)
@@ -274,6 +321,7 @@ func (c *Core) bootstrapInDegrees() {
 
 // SignAndInsertSelfEvent signs and inserts a self generated event block
 func (c *Core) SignAndInsertSelfEvent(event poset.Event) error {
+	event.SetVersion(c.newEventVersion())
 	if err := c.poset.SetWireInfoAndSign(&event, c.key); err != nil {
 		return err
 	}
@@ -281,6 +329,14 @@ func (c *Core) SignAndInsertSelfEvent(event poset.Event) error {
 	return c.InsertEvent(event, true)
 }
 
+// newEventVersion returns the version of the body of the events to create
+func (c *Core) newEventVersion() uint32 {
+	if c.eventVersion == nil {
+		return poset.SupportedEventVersions.Max
+	}
+	return c.eventVersion()
+}
+
 // InsertEvent inserts an unknown event block
 func (c *Core) InsertEvent(event poset.Event, setWireInfo bool) error {
 
@@ -295,23 +351,42 @@ func (c *Core) InsertEvent(event poset.Event, setWireInfo bool) error {
 	if err := c.poset.InsertEvent(event, setWireInfo); err != nil {
 		return err
 	}
+	c.eventInserted(event)
+	return nil
+}
+
+// insertPreverifiedEvent is InsertEvent for an event whose signature was
+// verified with the rest of its sync batch
+func (c *Core) insertPreverifiedEvent(event poset.Event) error {
+	if err := c.poset.InsertPreverifiedEvent(event, false); err != nil {
+		return err
+	}
+	c.eventInserted(event)
+	return nil
+}
 
+func (c *Core) eventInserted(event poset.Event) {
 	if event.GetCreator() == c.HexID() {
 		c.head = event.Hash()
-	} else {
-		c.participants.SetHeightByPubKeyHex(event.GetCreator(), event.Index())
 	}
-	c.participants.SetInDegreeByPubKeyHex(event.GetCreator(), 0)
-
-	if otherEvent, err := c.poset.Store.GetEventBlock(event.OtherParent()); err == nil {
-		c.participants.IncInDegreeByPubKeyHex(otherEvent.GetCreator())
+	c.recordPeerEvent(event)
+	if c.inserted != nil {
+		c.inserted(event)
 	}
-	return nil
 }
 
-// KnownEvents returns map of last known event blocks per participant.ID
+// GetDuplicateEventsCount returns the number of synced events which were
+// known already, sent by a peer unaware of it
+func (c *Core) GetDuplicateEventsCount() int64 {
+	return c.duplicates.get()
+}
+
+// KnownEvents returns map of last known event blocks per participant.ID,
+// before the first missing event of those with gaps
 func (c *Core) KnownEvents() map[uint64]int64 {
-	return c.HeightsByID()
+	known := c.HeightsByID()
+	c.belowGaps(known)
+	return known
 }
 
 // ++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -332,6 +407,11 @@ func (c *Core) SignBlock(block poset.Block) (poset.BlockSignature, error) {
 
 // OverSyncLimit checks if the unknown events is over the sync limit and if the node should catch up
 func (c *Core) OverSyncLimit(knownEvents map[uint64]int64, syncLimit int64) bool {
+	return c.UnknownCount(knownEvents) > syncLimit
+}
+
+// UnknownCount returns the number of events we know of and knownEvents does not
+func (c *Core) UnknownCount(knownEvents map[uint64]int64) int64 {
 	totUnknown := int64(0)
 	myKnownEvents := c.KnownEvents()
 	for i, li := range myKnownEvents {
@@ -339,7 +419,7 @@ func (c *Core) OverSyncLimit(knownEvents map[uint64]int64, syncLimit int64) bool
 			totUnknown += li - knownEvents[i]
 		}
 	}
-	return totUnknown > syncLimit
+	return totUnknown
 }
 
 // GetAnchorBlockWithFrame returns the current anchor block and their frame
@@ -349,7 +429,15 @@ func (c *Core) GetAnchorBlockWithFrame() (poset.Block, poset.Frame, error) {
 
 // EventDiff returns events that c knows about and are not in 'known'
 func (c *Core) EventDiff(known map[uint64]int64) (events []poset.Event, err error) {
-	var unknown []poset.Event
+	// the archived events the peer misses are read frame by frame
+	unknown, err := c.archivedEventDiff(known)
+	if err != nil {
+		return []poset.Event{}, err
+	}
+	archived := make(map[poset.EventHash]bool, len(unknown))
+	for i := range unknown {
+		archived[unknown[i].Hash()] = true
+	}
 	// known represents the index of the last event known for every participant
 	// compare this to our view of events and fill unknown with events that we know of
 	// and the other doesn't
@@ -366,6 +454,9 @@ func (c *Core) EventDiff(known map[uint64]int64) (events []poset.Event, err erro
 			return []poset.Event{}, err
 		}
 		for _, e := range participantEvents {
+			if archived[e] {
+				continue
+			}
 			ev, err := c.poset.Store.GetEventBlock(e)
 			if err != nil {
 				return []poset.Event{}, err
@@ -387,6 +478,46 @@ func (c *Core) EventDiff(known map[uint64]int64) (events []poset.Event, err erro
 	return unknown, nil
 }
 
+// archivedEventDiff returns the events of the archived frames that are not
+// in known, the frames being read once from the first one the peer misses
+func (c *Core) archivedEventDiff(known map[uint64]int64) ([]poset.Event, error) {
+	archive, ok := c.poset.Store.(poset.FrameArchive)
+	if !ok {
+		return nil, nil
+	}
+	first, last, err := archive.ArchivedFrames()
+	if err != nil || last < first {
+		return nil, err
+	}
+	from := last + 1
+	knownByPubKey := make(map[string]int64, len(known))
+	for id, ct := range known {
+		peer, ok := c.participants.ReadByID(id)
+		if !ok {
+			continue
+		}
+		knownByPubKey[peer.Message.PubKeyHex] = ct
+		frame, err := archive.ArchivedEventFrame(peer.Message.PubKeyHex, ct+1)
+		if common.Is(err, common.KeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if frame < from {
+			from = frame
+		}
+	}
+	var unknown []poset.Event
+	err = archive.ForEachFrameEvent(from, last, func(ev poset.Event) bool {
+		if ct, ok := knownByPubKey[ev.GetCreator()]; ok && ev.Index() > ct {
+			unknown = append(unknown, ev)
+		}
+		return true
+	})
+	return unknown, err
+}
+
 // Sync unknown events into our poset
 func (c *Core) Sync(peer *peers.Peer, unknownEvents []poset.WireEvent) error {
 
@@ -399,38 +530,72 @@ func (c *Core) Sync(peer *peers.Peer, unknownEvents []poset.WireEvent) error {
 	}).Debug("Sync(unknownEventBlocks []poset.EventBlock)")
 
 	myKnownEvents := c.KnownEvents()
-	otherHead, _, err := c.poset.Store.LastEventFrom(peer.Message.PubKeyHex)
-	if err != nil {
-		c.logger.WithField("peer", peer).Errorf("c.poset.Store.LastEventFrom(peer.PubKeyHex)")
-		return err
-	}
-	// add unknown events
-	for _/*k*/, we := range unknownEvents {
+	// the signatures of the batch are verified concurrently up front, an
+	// invalid one only rejects its event and the descendants in the batch
+	verified, rejected := c.preverify(unknownEvents)
+	// events beyond the per-frame cap are rejected with the later events of
+	// their creator, the first rejection is returned once the rest is in
+	var capped error
+	cappedCreators := make(map[uint64]bool)
+	// add unknown events, those whose other-parent is not known yet wait for
+	// it in the pending queue
+	for _, we := range unknownEvents {
 		c.logger.WithFields(logrus.Fields{
 			"unknown_events": fmt.Sprintf("%#v", we),
 		}).Debug("unknownEvents")
-		ev, err := c.poset.ReadWireInfo(we)
+		if rejected.rejects(we) {
+			c.logger.WithFields(logrus.Fields{
+				"peer":       peer.ID,
+				"creator_id": we.Body.CreatorID,
+				"index":      we.Body.Index,
+			}).Warn("SYNC: event rejected, invalid signature in its ancestry")
+			continue
+		}
+		if cappedCreators[we.Body.CreatorID] {
+			continue
+		}
+		err := c.syncEvent(we, myKnownEvents, verified)
+		if poset.IsEventCap(err) {
+			c.logger.WithFields(logrus.Fields{
+				"peer":       peer.ID,
+				"creator_id": we.Body.CreatorID,
+				"index":      we.Body.Index,
+				"error":      err,
+			}).Warn("SYNC: event rejected, beyond the per-frame cap")
+			cappedCreators[we.Body.CreatorID] = true
+			if capped == nil {
+				capped = err
+			}
+			continue
+		}
 		if err != nil {
-			c.logger.WithField("EventBlock", we).WithField("err", err).Errorf("c.poset.ReadWireInfo(we)")
+			c.logger.WithField("EventBlock", we).WithField("err", err).Error("SYNC: INSERT ERR")
 			return err
-
 		}
-		if ev.Index() > myKnownEvents[ev.CreatorID()] {
-			ev.SetLamportTimestamp(poset.LamportTimestampNIL)
-//			ev.SetFrame(poset.FrameNIL)  // do we really need it here? It should set in poset.ReadWireInfo()
-//			ev.SetRound(poset.RoundNIL)
-//			ev.SetRoundReceived(poset.RoundNIL)
-			if err := c.InsertEvent(*ev, false); err != nil {
-				c.logger.Error("SYNC: INSERT ERR:", err)
+	}
+	c.retryPendingEvents(myKnownEvents)
+
+	// the other-head is the last event of the peer actually inserted
+	otherHead, _, err := c.poset.Store.LastEventFrom(peer.Message.PubKeyHex)
+	if err != nil {
+		c.logger.WithField("peer", peer).Errorf("c.poset.Store.LastEventFrom(peer.PubKeyHex)")
+		return err
+	}
+
+	if c.observer {
+		return capped
+	}
+	if c.quorum != nil && !c.quorum() {
+		// the transactions wait for the quorum, the signatures go on
+		if c.GetInternalTransactionPoolCount() > 0 ||
+			c.GetBlockSignaturePoolCount() > 0 {
+			if err := c.addSelectedSelfEventBlock(peer.ID, otherHead, false); err != nil {
 				return err
 			}
+			return capped
 		}
-
-		// assume last event corresponds to other-head
-		//if k == len(unknownEvents)-1 {
-		if ev.GetCreator() == peer.Message.PubKeyHex {
-			otherHead = ev.Hash()
-		}
+		c.logger.Debug("Skipping AddSelfEventBlock() without a quorum")
+		return capped
 	}
 
 	// create new event with self head and other head only if there are pending
@@ -439,13 +604,16 @@ func (c *Core) Sync(peer *peers.Peer, unknownEvents []poset.WireEvent) error {
 		c.GetTransactionPoolCount() > 0 ||
 		c.GetInternalTransactionPoolCount() > 0 ||
 		c.GetBlockSignaturePoolCount() > 0 {
-		return c.AddSelfEventBlock(otherHead)
+		if err := c.addSelectedSelfEventBlock(peer.ID, otherHead, true); err != nil {
+			return err
+		}
 	}
-	return nil
+	return capped
 }
 
-// FastForward catch up to another peer if too far behind
-func (c *Core) FastForward(peer string, block poset.Block, frame poset.Frame) error {
+// FastForward catch up to another peer if too far behind, going on with the
+// transactions it carried over past the block
+func (c *Core) FastForward(peer string, block poset.Block, frame poset.Frame, carry [][]byte) error {
 
 	// Check Block Signatures
 	err := c.poset.CheckBlock(block)
@@ -462,15 +630,41 @@ func (c *Core) FastForward(peer string, block poset.Block, frame poset.Frame) er
 		return fmt.Errorf("invalid Frame Hash")
 	}
 
+	if err := c.poset.SetBlockCarry(block, frame, carry); err != nil {
+		return err
+	}
+
 	err = c.poset.Reset(block, frame)
 	if err != nil {
 		return err
 	}
 
+	// the participants without events yet have the base root of the frame,
+	// their next events still build on their leaf events. Those of the first
+	// frame are set by the Reset already.
+	var leafOnly []*peers.Peer
+	for _, peer := range c.participants.ToPeerSlice() {
+		root, err := c.poset.Store.GetRoot(peer.Message.PubKeyHex)
+		if err != nil {
+			return err
+		}
+		_, noEvent, err := c.poset.Store.LastEventFrom(peer.Message.PubKeyHex)
+		if err != nil {
+			return err
+		}
+		if root.SelfParent.Index < 0 && noEvent {
+			leafOnly = append(leafOnly, peer)
+		}
+	}
+	if err := c.poset.SetLeafEvents(leafOnly); err != nil {
+		return err
+	}
+
 	err = c.SetHeadAndHeight()
 	if err != nil {
 		return err
 	}
+	c.refreshPeerStats()
 
 	err = c.RunConsensus()
 	if err != nil {
@@ -489,6 +683,12 @@ func min(a, b int) int {
 
 // AddSelfEventBlock adds an event block created by this node
 func (c *Core) AddSelfEventBlock(otherHead poset.EventHash) error {
+	return c.addRatedSelfEventBlock(otherHead, true)
+}
+
+// addRatedSelfEventBlock adds an event block created by this node, with the
+// pooled transactions or without, at the event creation rate
+func (c *Core) addRatedSelfEventBlock(otherHead poset.EventHash, withTransactions bool) error {
 
 	if c.eventCreationRate < 1.0 && rand.Float64() > c.eventCreationRate {
 		c.logger.WithFields(logrus.Fields{
@@ -500,6 +700,65 @@ func (c *Core) AddSelfEventBlock(otherHead poset.EventHash) error {
 		return nil;
 	}
 
+	err := c.addSelfEventBlock(otherHead, withTransactions)
+	if poset.IsEventCap(err) {
+		// the transactions wait for the next frame
+		c.logger.WithField("error", err).Debug("Skipping AddSelfEventBlock()")
+		return nil
+	}
+	return err
+}
+
+// AddEmptyEventBlock adds an event block created by this node without
+// transactions, whatever the event creation rate, on the newest event of the
+// other participants. It returns the new head, or an EventCapError when this
+// node made all the events it may in the frame.
+func (c *Core) AddEmptyEventBlock() (poset.EventHash, error) {
+	otherHead, err := c.newestOtherHead()
+	if err != nil {
+		return poset.EventHash{}, err
+	}
+	if err := c.addSelfEventBlock(otherHead, false); err != nil {
+		return poset.EventHash{}, err
+	}
+	return c.head, nil
+}
+
+// newestOtherHead returns the last event of the other participant that made
+// one last, or the root of another participant when they made none yet, as
+// Sync would
+func (c *Core) newestOtherHead() (poset.EventHash, error) {
+	var newest, root poset.EventHash
+	var newestTime time.Time
+	for _, peer := range c.participants.ToPeerSlice() {
+		if peer.Message.PubKeyHex == c.HexID() {
+			continue
+		}
+		last, isRoot, err := c.poset.Store.LastEventFrom(peer.Message.PubKeyHex)
+		if err != nil {
+			return poset.EventHash{}, err
+		}
+		if isRoot {
+			root = last
+			continue
+		}
+		event, err := c.GetEventBlock(last)
+		if err != nil {
+			return poset.EventHash{}, err
+		}
+		if newest.Zero() || event.Timestamp().After(newestTime) {
+			newest, newestTime = last, event.Timestamp()
+		}
+	}
+	if newest.Zero() {
+		return root, nil
+	}
+	return newest, nil
+}
+
+// addSelfEventBlock creates, signs and inserts an event of this node, with
+// the pooled transactions when withTransactions is set
+func (c *Core) addSelfEventBlock(otherHead poset.EventHash, withTransactions bool) error {
 	c.addSelfEventBlockLocker.Lock()
 	defer c.addSelfEventBlockLocker.Unlock()
 
@@ -514,19 +773,17 @@ func (c *Core) AddSelfEventBlock(otherHead poset.EventHash) error {
 //	}
 
 	// get transactions batch for new Event
-	c.transactionPoolLocker.Lock()
-	var payloadSize, nTxs int
-	for nTxs = 0; nTxs < len(c.transactionPool); nTxs++ {
-		// NOTE: if len(tx)>MaxEventsPayloadSize it will be payloadSize>MaxEventsPayloadSize
-		txSize := len(c.transactionPool[nTxs])
-		if nTxs > 0 && payloadSize >= (MaxEventsPayloadSize-txSize) {
-			break
-		}
-		payloadSize += txSize
+	// NOTE: if len(tx)>MaxEventsPayloadSize it will be payloadSize>MaxEventsPayloadSize
+	var pooled []pooledTx
+	if withTransactions {
+		pooled = c.transactionPool.take(MaxEventsPayloadSize)
+	}
+	nTxs := len(pooled)
+	batch := make([][]byte, nTxs)
+	batchFlags := make([]byte, nTxs)
+	for i, t := range pooled {
+		batch[i], batchFlags[i] = t.tx, t.flags
 	}
-	batch := c.transactionPool[0:nTxs]
-	c.transactionPool = c.transactionPool[nTxs:]
-	c.transactionPoolLocker.Unlock()
 
 	// create new event with self head and empty other parent
 	newHead := poset.NewEvent(batch,
@@ -534,12 +791,15 @@ func (c *Core) AddSelfEventBlock(otherHead poset.EventHash) error {
 		c.blockSignaturePool,
 		poset.EventHashes{c.head, otherHead}, c.PubKey(), c.participants.NextHeightByPubKeyHex(c.HexID()),
 		poset.NewFlagTable(), poset.NewFlagTable() /*rootTable*/, poset.FrameNIL, false /*Root*/)
+	newHead.SetTransactionFlags(batchFlags)
+	newHead.SetTimestamp(c.clock.Now())
 
 	if err := c.SignAndInsertSelfEvent(newHead); err != nil {
 		// put batch back to transactionPool
-		c.transactionPoolLocker.Lock()
-		c.transactionPool = append(batch, c.transactionPool...)
-		c.transactionPoolLocker.Unlock()
+		c.transactionPool.putBack(pooled)
+		if poset.IsEventCap(err) {
+			return err
+		}
 		return fmt.Errorf("newHead := poset.NewEventBlock: %s", err)
 	}
 	c.logger.WithFields(logrus.Fields{
@@ -585,7 +845,8 @@ func (c *Core) ToWire(events []poset.Event) ([]poset.WireEvent, error) {
 	return wireEvents, nil
 }
 
-// RunConsensus is the core consensus mechanism, this checks rounds/frames and creates blocks.
+// RunConsensus is the core consensus mechanism, this checks rounds/frames and
+// creates blocks until there are no more to create.
 func (c *Core) RunConsensus() error {
 //	start := time.Now()
 //	err := c.poset.DivideRounds()
@@ -612,20 +873,22 @@ func (c *Core) RunConsensus() error {
 //	}
 
 	start := time.Now()
-	err := c.poset.ProcessDecidedRounds()
-	c.logger.WithField("Duration", time.Since(start).Nanoseconds()).Debug("c.poset.ProcessDecidedRounds()")
+	err := c.poset.RunToQuiescence()
+	c.logger.WithField("Duration", time.Since(start).Nanoseconds()).Debug("c.poset.RunToQuiescence()")
 	if err != nil {
-		c.logger.WithField("Error", err).Error("c.poset.ProcessDecidedRounds()")
+		c.logger.WithField("Error", err).Error("c.poset.RunToQuiescence()")
 		return err
 	}
 
-//	start := time.Now()
-//	err = c.poset.ProcessSigPool()
-//	c.logger.WithField("Duration", time.Since(start).Nanoseconds()).Debug("c.poset.ProcessSigPool()")
-//	if err != nil {
-//		c.logger.WithField("Error", err).Error("c.poset.ProcessSigPool()")
-//		return err
-//	}
+	// the signatures of the blocks make the anchor block fast forwards
+	// start from
+	start = time.Now()
+	err = c.poset.ProcessSigPool()
+	c.logger.WithField("Duration", time.Since(start).Nanoseconds()).Debug("c.poset.ProcessSigPool()")
+	if err != nil {
+		c.logger.WithField("Error", err).Error("c.poset.ProcessSigPool()")
+		return err
+	}
 
 	c.logger.WithFields(logrus.Fields{
 		"transaction_pool":            c.GetTransactionPoolCount(),
@@ -638,14 +901,31 @@ func (c *Core) RunConsensus() error {
 
 // AddTransactions add transactions to the pending pool
 func (c *Core) AddTransactions(txs [][]byte) error {
+	return c.AddTransactionsWithFlags(txs, make([]byte, len(txs)))
+}
+
+// AddTransactionsWithFlags add transactions with their flags bytes to the
+// pending pool
+func (c *Core) AddTransactionsWithFlags(txs [][]byte, flags []byte) error {
+	return c.addTransactionsFrom(sourceApp, txs, flags)
+}
+
+// addTransactionsFrom adds the transactions of a source to the pending pool.
+// It stops at the first transaction the pool rejects.
+func (c *Core) addTransactionsFrom(source string, txs [][]byte, flags []byte) error {
+	if len(flags) != len(txs) {
+		return fmt.Errorf("got %d flags for %d transactions", len(flags), len(txs))
+	}
 	for _, tx := range txs {
 		if len(tx) > MaxEventsPayloadSize {
 			return ErrTooBigTx
 		}
 	}
-	c.transactionPoolLocker.Lock()
-	defer c.transactionPoolLocker.Unlock()
-	c.transactionPool = append(c.transactionPool, txs...)
+	for i, tx := range txs {
+		if err := c.transactionPool.add(source, tx, flags[i]); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -656,6 +936,42 @@ func (c *Core) AddInternalTransactions(txs []poset.InternalTransaction) {
 	c.internalTransactionPool = append(c.internalTransactionPool, txs...)
 }
 
+// AddWireCreator accepts the peer a syncing node claims is behind creator
+// ID id, provided its public key hashes to id and it is either a participant
+// or the subject of a pending PEER_ADD internal transaction
+func (c *Core) AddWireCreator(id uint64, pm *peers.PeerMessage) error {
+	if len(pm.PubKeyHex) < 2 {
+		return fmt.Errorf("bad public key %q for creator ID %d", pm.PubKeyHex, id)
+	}
+	pubKey, err := pm.PubKeyBytes()
+	if err != nil {
+		return fmt.Errorf("bad public key %q for creator ID %d: %v", pm.PubKeyHex, id, err)
+	}
+	if common.Hash64(pubKey) != id {
+		return fmt.Errorf("public key %s does not match creator ID %d", pm.PubKeyHex, id)
+	}
+	if _, ok := c.participants.ReadByPubKey(pm.PubKeyHex); !ok &&
+		!c.pendingPeerAdd(pm.PubKeyHex) && !c.poset.PendingPeerAdd(pm.PubKeyHex) {
+		return fmt.Errorf("creator %s is neither a participant nor pending addition", pm.PubKeyHex)
+	}
+	c.poset.SetWireCreator(id, pm)
+	return nil
+}
+
+// pendingPeerAdd returns true when the internal transaction pool holds a
+// PEER_ADD for pubKey
+func (c *Core) pendingPeerAdd(pubKey string) bool {
+	c.internalTransactionPoolLocker.RLock()
+	defer c.internalTransactionPoolLocker.RUnlock()
+	for _, tx := range c.internalTransactionPool {
+		if tx.Type == poset.TransactionType_PEER_ADD && tx.Peer != nil &&
+			tx.Peer.PubKeyHex == pubKey {
+			return true
+		}
+	}
+	return false
+}
+
 // AddBlockSignature add block signatures to the pending pool
 func (c *Core) AddBlockSignature(bs poset.BlockSignature) {
 	c.blockSignaturePoolLocker.Lock()
@@ -739,9 +1055,12 @@ func (c *Core) GetLastBlockIndex() int64 {
 
 // GetTransactionPoolCount returns the count of all pending transactions
 func (c *Core) GetTransactionPoolCount() int64 {
-	c.transactionPoolLocker.RLock()
-	defer c.transactionPoolLocker.RUnlock()
-	return int64(len(c.transactionPool))
+	return int64(c.transactionPool.len())
+}
+
+// GetTransactionPoolStats returns the occupancy of the transaction pool
+func (c *Core) GetTransactionPoolStats() TxPoolStats {
+	return c.transactionPool.stats()
 }
 
 // GetInternalTransactionPoolCount returns the count of all pending internal transactions
@@ -14,6 +14,22 @@ import (
 )
 
 func initCores(n int, t *testing.T) ([]*Core,
+	map[uint64]*ecdsa.PrivateKey, map[string]poset.EventHash) {
+	return initCoresWith(n, nil, t)
+}
+
+// initCommittingCores is initCores with the blocks of each core sent on
+// its commit channel
+func initCommittingCores(n int, t *testing.T) ([]*Core, []chan poset.Block) {
+	commitChs := make([]chan poset.Block, n)
+	for i := range commitChs {
+		commitChs[i] = make(chan poset.Block, 100)
+	}
+	cores, _, _ := initCoresWith(n, commitChs, t)
+	return cores, commitChs
+}
+
+func initCoresWith(n int, commitChs []chan poset.Block, t *testing.T) ([]*Core,
 	map[uint64]*ecdsa.PrivateKey, map[string]poset.EventHash) {
 	cacheSize := 1000
 
@@ -32,25 +48,33 @@ func initCores(n int, t *testing.T) ([]*Core,
 	}
 
 	for i, peer := range participants.ToPeerSlice() {
-		core := NewCore(uint64(i),
+		// each core keeps its own view of the heights of the participants
+		ps := nodePeers(participants)
+		var commitCh chan poset.Block
+		if commitChs != nil {
+			commitCh = commitChs[i]
+		}
+		core, err := NewCore(peer.ID,
 			participantKeys[peer.ID],
-			participants,
-			poset.NewInmemStore(participants, cacheSize, nil),
-			nil,
+			ps,
+			poset.NewInmemStore(ps, cacheSize, nil),
+			commitCh,
 			common.NewTestLogger(t))
-
-		selfParent := poset.GenRootSelfParent(peer.ID)
-
-		flagTable := make(poset.FlagTable)
-		flagTable[selfParent] = 1
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the first event follows the leaf event NewCore made
+		if err := core.SetHeadAndHeight(); err != nil {
+			t.Fatal(err)
+		}
 
 		// Create and save the first Event
 		initialEvent := poset.NewEvent([][]byte(nil),
 			[]poset.InternalTransaction{},
 			nil,
-			poset.EventHashes{selfParent, poset.EventHash{}}, core.PubKey(), 0, flagTable)
-		err := core.SignAndInsertSelfEvent(initialEvent)
-		if err != nil {
+			poset.EventHashes{core.Head(), poset.EventHash{}}, core.PubKey(),
+			ps.NextHeightByPubKeyHex(core.HexID()), poset.NewFlagTable(), nil, 0, false)
+		if err := core.SignAndInsertSelfEvent(initialEvent); err != nil {
 			t.Fatal(err)
 		}
 
@@ -104,13 +128,13 @@ func initPoset(t *testing.T, cores []*Core, keys map[uint64]*ecdsa.PrivateKey,
 	}
 
 	event1ft, _ := event1.GetFlagTable()
-	event01ft, _ := event0.MergeFlagTable(event1ft)
+	event01ft, _ := event0.MergeFlagTable(event1ft, 0)
 
 	event01 := poset.NewEvent([][]byte{},
 		[]poset.InternalTransaction{},
 		nil,
 		poset.EventHashes{index["e0"], index["e1"]}, // e0 and e1
-		cores[0].PubKey(), 1, event01ft)
+		cores[0].PubKey(), event0.Index()+1, event01ft, nil, 0, false)
 	if err := insertEvent(cores, keys, index, event01, "e01", participant,
 		common.Hash64(cores[0].pubKey)); err != nil {
 		t.Fatalf("error inserting e01: %s\n", err)
@@ -122,25 +146,25 @@ func initPoset(t *testing.T, cores []*Core, keys map[uint64]*ecdsa.PrivateKey,
 		t.Fatalf("failed to get parent: %s", err)
 	}
 
-	event20ft, _ := event2.MergeFlagTable(event01ft)
+	event20ft, _ := event2.MergeFlagTable(event01ft, 0)
 
 	event20 := poset.NewEvent([][]byte{},
 		[]poset.InternalTransaction{},
 		nil,
 		poset.EventHashes{index["e2"], index["e01"]}, // e2 and e01
-		cores[2].PubKey(), 1, event20ft)
+		cores[2].PubKey(), event2.Index()+1, event20ft, nil, 0, false)
 	if err := insertEvent(cores, keys, index, event20, "e20", participant,
 		common.Hash64(cores[2].pubKey)); err != nil {
 		fmt.Printf("error inserting e20: %s\n", err)
 	}
 
-	event12ft, _ := event1.MergeFlagTable(event20ft)
+	event12ft, _ := event1.MergeFlagTable(event20ft, 0)
 
 	event12 := poset.NewEvent([][]byte{},
 		[]poset.InternalTransaction{},
 		nil,
 		poset.EventHashes{index["e1"], index["e20"]}, // e1 and e20
-		cores[1].PubKey(), 1, event12ft)
+		cores[1].PubKey(), event1.Index()+1, event12ft, nil, 0, false)
 	if err := insertEvent(cores, keys, index, event12, "e12", participant,
 		common.Hash64(cores[1].pubKey)); err != nil {
 		fmt.Printf("error inserting e12: %s\n", err)
@@ -170,7 +194,7 @@ func insertEvent(cores []*Core, keys map[uint64]*ecdsa.PrivateKey,
 }
 
 func checkHeights(
-	cores []*Core, expectedHeights []map[string]uint64, t *testing.T) {
+	cores []*Core, expectedHeights []map[string]int64, t *testing.T) {
 	for i, core := range cores {
 		heights := core.Heights()
 		if !reflect.DeepEqual(heights, expectedHeights[i]) {
@@ -230,18 +254,18 @@ func TestSync(t *testing.T) {
 	   0   1   2        0   1   2       0   1   2
 	*/
 
-	expectedHeights := make([]map[string]uint64, 3)
-	expectedHeights[0] = map[string]uint64{
+	expectedHeights := make([]map[string]int64, 3)
+	expectedHeights[0] = map[string]int64{
 		cores[0].hexID: 1,
 		cores[1].hexID: 0,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[1] = map[string]uint64{
+	expectedHeights[1] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[2] = map[string]uint64{
+	expectedHeights[2] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 0,
 		cores[2].hexID: 1,
@@ -249,7 +273,7 @@ func TestSync(t *testing.T) {
 	checkHeights(cores, expectedHeights, t)
 
 	// core 1 is going to tell core 0 everything it knows
-	if err := synchronizeCores(cores, 1, 0, [][]byte{}); err != nil {
+	if err := synchronizeCores(cores, 1, 0, [][]byte{[]byte("e01")}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -262,17 +286,17 @@ func TestSync(t *testing.T) {
 	   0   1   2        0   1   2       0   1   2
 	*/
 
-	expectedHeights[0] = map[string]uint64{
+	expectedHeights[0] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[1] = map[string]uint64{
+	expectedHeights[1] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[2] = map[string]uint64{
+	expectedHeights[2] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 0,
 		cores[2].hexID: 1,
@@ -280,14 +304,14 @@ func TestSync(t *testing.T) {
 	checkHeights(cores, expectedHeights, t)
 
 	knownBy0 := cores[0].KnownEvents()
-	if k := knownBy0[common.Hash64(cores[0].pubKey)]; k != 1 {
-		t.Fatalf("core 0 should have last-index 1 for core 0, not %d", k)
+	if k := knownBy0[common.Hash64(cores[0].pubKey)]; k != 2 {
+		t.Fatalf("core 0 should have last-index 2 for core 0, not %d", k)
 	}
-	if k := knownBy0[common.Hash64(cores[1].pubKey)]; k != 0 {
-		t.Fatalf("core 0 should have last-index 0 for core 1, not %d", k)
+	if k := knownBy0[common.Hash64(cores[1].pubKey)]; k != 1 {
+		t.Fatalf("core 0 should have last-index 1 for core 1, not %d", k)
 	}
-	if k := knownBy0[common.Hash64(cores[2].pubKey)]; k != -1 {
-		t.Fatalf("core 0 should have last-index -1 for core 2, not %d", k)
+	if k := knownBy0[common.Hash64(cores[2].pubKey)]; k != 0 {
+		t.Fatalf("core 0 should have last-index 0 for core 2, not %d", k)
 	}
 	core0Head, _ := cores[0].GetHead()
 	if core0Head.SelfParent() != index["e0"] {
@@ -296,13 +320,13 @@ func TestSync(t *testing.T) {
 	if core0Head.OtherParent() != index["e1"] {
 		t.Fatalf("core 0 head other-parent should be e1")
 	}
-	if len(core0Head.Message.FlagTable) == 0 {
+	if len(core0Head.FlagTableBytes) == 0 {
 		t.Fatal("flag table is null")
 	}
 	index["e01"] = core0Head.Hash()
 
 	// core 0 is going to tell core 2 everything it knows
-	if err := synchronizeCores(cores, 0, 2, [][]byte{}); err != nil {
+	if err := synchronizeCores(cores, 0, 2, [][]byte{[]byte("e20")}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -320,17 +344,17 @@ func TestSync(t *testing.T) {
 		   0   1   2        0   1   2       0   1   2
 	*/
 
-	expectedHeights[0] = map[string]uint64{
+	expectedHeights[0] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[1] = map[string]uint64{
+	expectedHeights[1] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[2] = map[string]uint64{
+	expectedHeights[2] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 1,
 		cores[2].hexID: 2,
@@ -338,14 +362,14 @@ func TestSync(t *testing.T) {
 	checkHeights(cores, expectedHeights, t)
 
 	knownBy2 := cores[2].KnownEvents()
-	if k := knownBy2[common.Hash64(cores[0].pubKey)]; k != 1 {
-		t.Fatalf("core 2 should have last-index 1 for core 0, not %d", k)
+	if k := knownBy2[common.Hash64(cores[0].pubKey)]; k != 2 {
+		t.Fatalf("core 2 should have last-index 2 for core 0, not %d", k)
 	}
-	if k := knownBy2[common.Hash64(cores[1].pubKey)]; k != 0 {
-		t.Fatalf("core 2 should have last-index 0 core 1, not %d", k)
+	if k := knownBy2[common.Hash64(cores[1].pubKey)]; k != 1 {
+		t.Fatalf("core 2 should have last-index 1 core 1, not %d", k)
 	}
-	if k := knownBy2[common.Hash64(cores[2].pubKey)]; k != 1 {
-		t.Fatalf("core 2 should have last-index 1 for core 2, not %d", k)
+	if k := knownBy2[common.Hash64(cores[2].pubKey)]; k != 2 {
+		t.Fatalf("core 2 should have last-index 2 for core 2, not %d", k)
 	}
 	core2Head, _ := cores[2].GetHead()
 	if core2Head.SelfParent() != index["e2"] {
@@ -357,7 +381,7 @@ func TestSync(t *testing.T) {
 	index["e20"] = core2Head.Hash()
 
 	// core 2 is going to tell core 1 everything it knows
-	if err := synchronizeCores(cores, 2, 1, [][]byte{}); err != nil {
+	if err := synchronizeCores(cores, 2, 1, [][]byte{[]byte("e12")}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -376,17 +400,17 @@ func TestSync(t *testing.T) {
 		   0   1   2        0   1   2       0   1   2
 	*/
 
-	expectedHeights[0] = map[string]uint64{
+	expectedHeights[0] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[1] = map[string]uint64{
+	expectedHeights[1] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 2,
 		cores[2].hexID: 2,
 	}
-	expectedHeights[2] = map[string]uint64{
+	expectedHeights[2] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 1,
 		cores[2].hexID: 2,
@@ -394,14 +418,14 @@ func TestSync(t *testing.T) {
 	checkHeights(cores, expectedHeights, t)
 
 	knownBy1 := cores[1].KnownEvents()
-	if k := knownBy1[common.Hash64(cores[0].pubKey)]; k != 1 {
-		t.Fatalf("core 1 should have last-index 1 for core 0, not %d", k)
+	if k := knownBy1[common.Hash64(cores[0].pubKey)]; k != 2 {
+		t.Fatalf("core 1 should have last-index 2 for core 0, not %d", k)
 	}
-	if k := knownBy1[common.Hash64(cores[1].pubKey)]; k != 1 {
-		t.Fatalf("core 1 should have last-index 1 for core 1, not %d", k)
+	if k := knownBy1[common.Hash64(cores[1].pubKey)]; k != 2 {
+		t.Fatalf("core 1 should have last-index 2 for core 1, not %d", k)
 	}
-	if k := knownBy1[common.Hash64(cores[2].pubKey)]; k != 1 {
-		t.Fatalf("core 1 should have last-index 1 for core 2, not %d", k)
+	if k := knownBy1[common.Hash64(cores[2].pubKey)]; k != 2 {
+		t.Fatalf("core 1 should have last-index 2 for core 2, not %d", k)
 	}
 	core1Head, _ := cores[1].GetHead()
 	if core1Head.SelfParent() != index["e1"] {
@@ -415,7 +439,7 @@ func TestSync(t *testing.T) {
 }
 
 func checkInDegree(
-	cores []*Core, expectedInDegree []map[string]uint64, t *testing.T) {
+	cores []*Core, expectedInDegree []map[string]int64, t *testing.T) {
 	for i, core := range cores {
 		inDegrees := core.InDegrees()
 		if !reflect.DeepEqual(inDegrees, expectedInDegree[i]) {
@@ -436,7 +460,7 @@ func TestInDegrees(t *testing.T) {
 	*/
 
 	// core 1 is going to tell core 0 everything it knows
-	if err := synchronizeCores(cores, 1, 0, [][]byte{}); err != nil {
+	if err := synchronizeCores(cores, 1, 0, [][]byte{[]byte("e01")}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -449,36 +473,36 @@ func TestInDegrees(t *testing.T) {
 	   0   1   2        0   1   2       0   1   2
 	*/
 
-	expectedHeights := make([]map[string]uint64, 3)
-	expectedHeights[0] = map[string]uint64{
+	expectedHeights := make([]map[string]int64, 3)
+	expectedHeights[0] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[1] = map[string]uint64{
+	expectedHeights[1] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[2] = map[string]uint64{
+	expectedHeights[2] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 0,
 		cores[2].hexID: 1,
 	}
 	checkHeights(cores, expectedHeights, t)
 
-	expectedInDegree := make([]map[string]uint64, 3)
-	expectedInDegree[0] = map[string]uint64{
+	expectedInDegree := make([]map[string]int64, 3)
+	expectedInDegree[0] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedInDegree[1] = map[string]uint64{
+	expectedInDegree[1] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 0,
 		cores[2].hexID: 0,
 	}
-	expectedInDegree[2] = map[string]uint64{
+	expectedInDegree[2] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 0,
 		cores[2].hexID: 0,
@@ -486,7 +510,7 @@ func TestInDegrees(t *testing.T) {
 	checkInDegree(cores, expectedInDegree, t)
 
 	// core 1 is going to tell core 2 everything it knows
-	if err := synchronizeCores(cores, 1, 2, [][]byte{}); err != nil {
+	if err := synchronizeCores(cores, 1, 2, [][]byte{[]byte("e21")}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -500,34 +524,34 @@ func TestInDegrees(t *testing.T) {
 	   0   1   2        0   1   2       0   1   2
 	*/
 
-	expectedHeights[0] = map[string]uint64{
+	expectedHeights[0] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[1] = map[string]uint64{
+	expectedHeights[1] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[2] = map[string]uint64{
+	expectedHeights[2] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 2,
 	}
 	checkHeights(cores, expectedHeights, t)
 
-	expectedInDegree[0] = map[string]uint64{
+	expectedInDegree[0] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedInDegree[1] = map[string]uint64{
+	expectedInDegree[1] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 0,
 		cores[2].hexID: 0,
 	}
-	expectedInDegree[2] = map[string]uint64{
+	expectedInDegree[2] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
@@ -535,7 +559,7 @@ func TestInDegrees(t *testing.T) {
 	checkInDegree(cores, expectedInDegree, t)
 
 	// core 0 is going to tell core 2 everything it knows
-	if err := synchronizeCores(cores, 0, 2, [][]byte{}); err != nil {
+	if err := synchronizeCores(cores, 0, 2, [][]byte{[]byte("e20")}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -553,34 +577,34 @@ func TestInDegrees(t *testing.T) {
 		   0   1   2        0   1   2       0   1   2
 	*/
 
-	expectedHeights[0] = map[string]uint64{
+	expectedHeights[0] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[1] = map[string]uint64{
+	expectedHeights[1] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[2] = map[string]uint64{
+	expectedHeights[2] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 1,
 		cores[2].hexID: 3,
 	}
 	checkHeights(cores, expectedHeights, t)
 
-	expectedInDegree[0] = map[string]uint64{
+	expectedInDegree[0] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedInDegree[1] = map[string]uint64{
+	expectedInDegree[1] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 0,
 		cores[2].hexID: 0,
 	}
-	expectedInDegree[2] = map[string]uint64{
+	expectedInDegree[2] = map[string]int64{
 		cores[0].hexID: 1,
 		cores[1].hexID: 2,
 		cores[2].hexID: 0,
@@ -588,7 +612,7 @@ func TestInDegrees(t *testing.T) {
 	checkInDegree(cores, expectedInDegree, t)
 
 	// core 2 is going to tell core 1 everything it knows
-	if err := synchronizeCores(cores, 2, 1, [][]byte{}); err != nil {
+	if err := synchronizeCores(cores, 2, 1, [][]byte{[]byte("e12")}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -608,34 +632,34 @@ func TestInDegrees(t *testing.T) {
 		   0   1   2        0   1   2       0   1   2
 	*/
 
-	expectedHeights[0] = map[string]uint64{
+	expectedHeights[0] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedHeights[1] = map[string]uint64{
+	expectedHeights[1] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 2,
 		cores[2].hexID: 3,
 	}
-	expectedHeights[2] = map[string]uint64{
+	expectedHeights[2] = map[string]int64{
 		cores[0].hexID: 2,
 		cores[1].hexID: 1,
 		cores[2].hexID: 3,
 	}
 	checkHeights(cores, expectedHeights, t)
 
-	expectedInDegree[0] = map[string]uint64{
+	expectedInDegree[0] = map[string]int64{
 		cores[0].hexID: 0,
 		cores[1].hexID: 1,
 		cores[2].hexID: 0,
 	}
-	expectedInDegree[1] = map[string]uint64{
+	expectedInDegree[1] = map[string]int64{
 		cores[0].hexID: 1,
 		cores[1].hexID: 0,
 		cores[2].hexID: 1,
 	}
-	expectedInDegree[2] = map[string]uint64{
+	expectedInDegree[2] = map[string]int64{
 		cores[0].hexID: 1,
 		cores[1].hexID: 2,
 		cores[2].hexID: 0,
@@ -644,6 +668,8 @@ func TestInDegrees(t *testing.T) {
 }
 
 /*
+|   i1  |
+| / |   |
 h0  |   h2
 | \ | / |
 |   h1  |
@@ -712,6 +738,7 @@ func initConsensusPoset(t *testing.T) []*Core {
 		{from: 0, to: 1, payload: [][]byte{[]byte("h1")}},
 		{from: 1, to: 0, payload: [][]byte{[]byte("h0")}},
 		{from: 1, to: 2, payload: [][]byte{[]byte("h2")}},
+		{from: 0, to: 1, payload: [][]byte{[]byte("i1")}},
 	}
 
 	for _, play := range playbook {
@@ -726,13 +753,18 @@ func initConsensusPoset(t *testing.T) []*Core {
 func TestConsensus(t *testing.T) {
 	cores := initConsensusPoset(t)
 
-	if l := len(cores[0].GetConsensusEvents()); l != 4 {
-		t.Fatalf("length of consensus should be 4 not %d", l)
+	if l := len(cores[0].GetConsensusEvents()); l != 7 {
+		t.Fatalf("length of consensus should be 7 not %d", l)
 	}
 
 	core0Consensus := cores[0].GetConsensusEvents()
 	core1Consensus := cores[1].GetConsensusEvents()
 	core2Consensus := cores[2].GetConsensusEvents()
+	if len(core1Consensus) != len(core0Consensus) ||
+		len(core2Consensus) != len(core0Consensus) {
+		t.Fatalf("cores 1 and 2 should have %d consensus events, not %d and %d",
+			len(core0Consensus), len(core1Consensus), len(core2Consensus))
+	}
 
 	for i, e := range core0Consensus {
 		if core1Consensus[i] != e {
@@ -773,9 +805,9 @@ func TestOverSyncLimit(t *testing.T) {
 
 	// edge
 	known = map[uint64]int64{
-		common.Hash64(cores[0].pubKey): 2,
+		common.Hash64(cores[0].pubKey): 3,
 		common.Hash64(cores[1].pubKey): 3,
-		common.Hash64(cores[2].pubKey): 3,
+		common.Hash64(cores[2].pubKey): 4,
 	}
 	if cores[0].OverSyncLimit(known, syncLimit) {
 		t.Fatalf("OverSyncLimit(%v, %v) should return false", known, syncLimit)
@@ -784,6 +816,10 @@ func TestOverSyncLimit(t *testing.T) {
 }
 
 /*
+    .   .   .   .
+    .   .   .   .  h21 to w61: the cycle of g21 to w31
+    .   .   .   .  three more times, until the frames
+    .   .   .   .  decide their Atropos
     |   |   |   |-----------------
 	|   w31 |   | R3
 	|	| \ |   |
@@ -836,6 +872,18 @@ func initFFPoset(cores []*Core, t *testing.T) {
 		{from: 2, to: 3, payload: [][]byte{[]byte("w33")}},
 		{from: 3, to: 2, payload: [][]byte{[]byte("w32")}},
 		{from: 2, to: 1, payload: [][]byte{[]byte("w31")}},
+		{from: 1, to: 2, payload: [][]byte{[]byte("h21")}},
+		{from: 2, to: 3, payload: [][]byte{[]byte("w43")}},
+		{from: 3, to: 2, payload: [][]byte{[]byte("w42")}},
+		{from: 2, to: 1, payload: [][]byte{[]byte("w41")}},
+		{from: 1, to: 2, payload: [][]byte{[]byte("i21")}},
+		{from: 2, to: 3, payload: [][]byte{[]byte("w53")}},
+		{from: 3, to: 2, payload: [][]byte{[]byte("w52")}},
+		{from: 2, to: 1, payload: [][]byte{[]byte("w51")}},
+		{from: 1, to: 2, payload: [][]byte{[]byte("j21")}},
+		{from: 2, to: 3, payload: [][]byte{[]byte("w63")}},
+		{from: 3, to: 2, payload: [][]byte{[]byte("w62")}},
+		{from: 2, to: 1, payload: [][]byte{[]byte("w61")}},
 	}
 
 	for k, play := range playbook {
@@ -857,8 +905,8 @@ func TestConsensusFF(t *testing.T) {
 		t.Fatalf("Cores[1] last consensus Round should be 2, not %s", disp)
 	}
 
-	if l := len(cores[1].GetConsensusEvents()); l != 7 {
-		t.Fatalf("Node 1 should have 7 consensus events, not %d", l)
+	if l := len(cores[1].GetConsensusEvents()); l != 10 {
+		t.Fatalf("Node 1 should have 10 consensus events, not %d", l)
 	}
 
 	core1Consensus := cores[1].GetConsensusEvents()
@@ -876,7 +924,7 @@ func TestConsensusFF(t *testing.T) {
 }
 
 func TestCoreFastForward(t *testing.T) {
-	cores, _, _ := initCores(4, t)
+	cores, commitChs := initCommittingCores(4, t)
 	initFFPoset(cores, t)
 
 	t.Run("Test no Anchor", func(t *testing.T) {
@@ -888,17 +936,15 @@ func TestCoreFastForward(t *testing.T) {
 		}
 	})
 
-	block0, err := cores[1].poset.Store.GetBlock(0)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// collect signatures
+	// collect signatures, the cores sign the first block they commit as
+	// a node does
 	signatures := make([]poset.BlockSignature, 3)
 	for k, c := range cores[1:] {
-		b, err := c.poset.Store.GetBlock(0)
-		if err != nil {
-			t.Fatal(err)
+		var b poset.Block
+		select {
+		case b = <-commitChs[k+1]:
+		default:
+			t.Fatalf("core %d should have committed a block", k+1)
 		}
 		sig, err := c.SignBlock(b)
 		if err != nil {
@@ -907,6 +953,11 @@ func TestCoreFastForward(t *testing.T) {
 		signatures[k] = sig
 	}
 
+	block0, err := cores[1].poset.Store.GetBlock(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	t.Run("Test not enough signatures", func(t *testing.T) {
 		// Append only 1 signatures
 		if err := block0.SetSignature(signatures[0]); err != nil {
@@ -927,7 +978,7 @@ func TestCoreFastForward(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		err = cores[0].FastForward(cores[1].hexID, block, frame)
+		err = cores[0].FastForward(cores[1].hexID, block, frame, nil)
 		// We should get an error because AnchorBlock doesnt contain enough
 		// signatures
 		if err == nil {
@@ -954,7 +1005,7 @@ func TestCoreFastForward(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		err = cores[0].FastForward(cores[1].hexID, block, frame)
+		err = cores[0].FastForward(cores[1].hexID, block, frame, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -965,10 +1016,10 @@ func TestCoreFastForward(t *testing.T) {
 		}
 
 		expectedKnown := map[uint64]int64{
-			common.Hash64(cores[0].pubKey): -1,
-			common.Hash64(cores[1].pubKey): 0,
-			common.Hash64(cores[2].pubKey): 1,
-			common.Hash64(cores[3].pubKey): 0,
+			common.Hash64(cores[0].pubKey): 0,
+			common.Hash64(cores[1].pubKey): 1,
+			common.Hash64(cores[2].pubKey): 2,
+			common.Hash64(cores[3].pubKey): 1,
 		}
 
 		if !reflect.DeepEqual(knownBy0, expectedKnown) {
@@ -976,12 +1027,14 @@ func TestCoreFastForward(t *testing.T) {
 				expectedKnown, knownBy0)
 		}
 
-		if r := cores[0].GetLastConsensusRound(); r < 0 || r != 1 {
+		// the last consensus frame is the one of the block
+		if r := cores[0].GetLastConsensusRound(); r < 0 || r != block.RoundReceived() {
 			disp := "nil"
 			if r >= 0 {
 				disp = strconv.FormatInt(r, 10)
 			}
-			t.Fatalf("Cores[0] last consensus Round should be 1, not %s", disp)
+			t.Fatalf("Cores[0] last consensus Round should be %d, not %s",
+				block.RoundReceived(), disp)
 		}
 
 		if lbi := cores[0].poset.Store.LastBlockIndex(); lbi != 0 {
@@ -1005,14 +1058,63 @@ func TestCoreFastForward(t *testing.T) {
 			t.Fatalf("Head should be %s, not %s", lastEventFrom0, c0h)
 		}
 
-		if c0s := cores[0].participants.GetHeightByPubKeyHex(cores[0].HexID()); c0s != -1 {
-			t.Fatalf("core 0 height should be %d, not %d", -1, c0s)
+		// core 0 made no event on its leaf event yet
+		if c0s := cores[0].participants.GetHeightByPubKeyHex(cores[0].HexID()); c0s != 0 {
+			t.Fatalf("core 0 height should be %d, not %d", 0, c0s)
 		}
 
 	})
 
 }
 
+func TestCoreAddWireCreator(t *testing.T) {
+	cores, _, _ := initCores(3, t)
+	core := cores[0]
+
+	key, _ := crypto.GenerateECDSAKey()
+	pubKey := crypto.FromECDSAPub(&key.PublicKey)
+	outsider := peers.NewPeer(fmt.Sprintf("0x%X", pubKey), "")
+
+	// first event of the outsider, as it would arrive from a sync
+	ev := poset.NewEvent([][]byte{[]byte("tx")}, nil, nil,
+		poset.EventHashes{poset.GenRootSelfParent(outsider.ID), poset.EventHash{}},
+		pubKey, 0, poset.NewFlagTable(), poset.NewFlagTable(), poset.FrameNIL, false)
+	ev.SetWireInfo(-1, 0, -1, outsider.ID)
+	if err := ev.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	wev := ev.ToWire()
+
+	if _, err := core.poset.ReadWireInfo(wev); err == nil {
+		t.Fatal("expected an error reading an event of an unknown creator")
+	} else if _, ok := err.(*poset.UnknownCreatorError); !ok {
+		t.Fatalf("expected an UnknownCreatorError, got %v", err)
+	}
+
+	if err := core.AddWireCreator(outsider.ID+1, outsider.Message); err == nil {
+		t.Fatal("expected an error for a public key not matching the ID")
+	}
+	if err := core.AddWireCreator(outsider.ID, outsider.Message); err == nil {
+		t.Fatal("expected an error for a creator not pending addition")
+	}
+
+	core.AddInternalTransactions([]poset.InternalTransaction{
+		poset.NewInternalTransaction(poset.TransactionType_PEER_ADD, *outsider),
+	})
+	if err := core.AddWireCreator(outsider.ID, outsider.Message); err != nil {
+		t.Fatal(err)
+	}
+
+	evFromWire, err := core.poset.ReadWireInfo(wev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evFromWire.GetCreator() != outsider.Message.PubKeyHex {
+		t.Fatalf("expected creator %s, got %s",
+			outsider.Message.PubKeyHex, evFromWire.GetCreator())
+	}
+}
+
 func synchronizeCores(cores []*Core, from int, to int, payload [][]byte) error {
 	knownByTo := cores[to].KnownEvents()
 	unknownByTo, err := cores[from].EventDiff(knownByTo)
@@ -21,7 +21,7 @@ import (
 // InfosLite small subset of debug info for node
 type InfosLite struct {
 	ParticipantEvents map[string]map[string]EventLite
-	Rounds            []poset.RoundCreated
+	Rounds            []poset.Round
 	Blocks            []poset.Block
 }
 
@@ -71,21 +71,10 @@ func (g *Graph) GetParticipantEventsLite() map[string]map[string]EventLite {
 	store := g.Node.core.poset.Store
 	peers := g.Node.core.poset.Participants
 
-	// evs, err := store.ParticipantEvents(p.PubKeyHex, root.SelfParent.Index)
-	evs, err := store.TopologicalEvents()
-
-	if err != nil {
-		panic(err)
-	}
-
 	res[g.Node.localAddr /*p.PubKeyHex*/] = make(map[string]EventLite)
 
-	for _, event := range evs {
-
-		if err != nil {
-			panic(err)
-		}
-
+	// evs, err := store.ParticipantEvents(p.PubKeyHex, root.SelfParent.Index)
+	err := store.ForEachEvent(func(event poset.Event) bool {
 		peer, ok := peers.ReadByPubKey(event.GetCreator())
 		if !ok {
 			panic(fmt.Sprintf("Creator %v not found", event.GetCreator()))
@@ -126,6 +115,10 @@ func (g *Graph) GetParticipantEventsLite() map[string]map[string]EventLite {
 		}
 
 		res[g.Node.localAddr /*p.PubKeyHex*/][hash.String()] = liteEvent
+		return true
+	})
+	if err != nil {
+		panic(err)
 	}
 
 	return res
@@ -302,8 +295,9 @@ func (n *Node) PrintStat() {
 	for _/*localAddr*/, events := range res.ParticipantEvents {
 		for _/*hash*/, le := range events {
 			var parent, otherParent poset.EventHash
-			parent.Set(le.Message.Body.Parents[0])
-			otherParent.Set(le.Message.Body.Parents[1])
+			// the events of the graph were inserted, so were their parents
+			_ = parent.Set(le.Message.Body.Parents[0])
+			_ = otherParent.Set(le.Message.Body.Parents[1])
 			if !parent.Zero() {
 				fmt.Fprintf(file, "v%v -> v%v;\n", le.Message.Hash, parent.String())
 			}
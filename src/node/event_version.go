@@ -0,0 +1,97 @@
+package node
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// eventVersions keeps the highest event body version each peer advertised
+// in its syncs
+type eventVersions struct {
+	mtx        sync.Mutex
+	advertised map[uint64]uint32
+}
+
+func newEventVersions() *eventVersions {
+	return &eventVersions{advertised: make(map[uint64]uint32)}
+}
+
+// advertise records the version a peer advertised, and returns true when it
+// is not the one recorded before
+func (v *eventVersions) advertise(id uint64, version uint32) bool {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	old, ok := v.advertised[id]
+	v.advertised[id] = version
+	return !ok || old != version
+}
+
+// get returns a copy of the advertised versions
+func (v *eventVersions) get() map[uint64]uint32 {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	res := make(map[uint64]uint32, len(v.advertised))
+	for id, version := range v.advertised {
+		res[id] = version
+	}
+	return res
+}
+
+// selectEventVersion returns the version to create events at, the highest
+// supported one which all the other participants read. A participant not
+// heard from yet is assumed to read the lowest supported version only, so
+// that no participant ever receives an event it has to refuse.
+func selectEventVersion(supported poset.EventVersionRange, self uint64,
+	participants []uint64, advertised map[uint64]uint32) uint32 {
+	version := supported.Max
+	for _, id := range participants {
+		if id == self {
+			continue
+		}
+		theirs, ok := advertised[id]
+		if !ok {
+			theirs = supported.Min
+		}
+		if theirs < version {
+			version = theirs
+		}
+	}
+	if version < supported.Min {
+		// a participant older than the oldest version we read cannot read
+		// our events either way
+		version = supported.Min
+	}
+	return version
+}
+
+// EventVersion returns the version of the body of the events the node
+// creates
+func (n *Node) EventVersion() uint32 {
+	var ids []uint64
+	for _, p := range n.core.participants.ToPeerSlice() {
+		ids = append(ids, p.ID)
+	}
+	return selectEventVersion(poset.SupportedEventVersions, n.id, ids, n.eventVersions.get())
+}
+
+// learnEventVersion records the event version a peer advertised, and warns
+// when the peer reads newer events than this node
+func (n *Node) learnEventVersion(peerID uint64, version uint32) {
+	if !n.eventVersions.advertise(peerID, version) {
+		return
+	}
+	fields := logrus.Fields{
+		"peer_id":       peerID,
+		"their_version": version,
+		"our_version":   poset.SupportedEventVersions.Max,
+	}
+	if version > poset.SupportedEventVersions.Max {
+		n.logger.WithFields(fields).Warn("Peer is newer, upgrade this node")
+		return
+	}
+	n.logger.WithFields(fields).WithField("event_version", n.EventVersion()).
+		Debug("Peer event version")
+}
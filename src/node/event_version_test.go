@@ -0,0 +1,66 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+func TestSelectEventVersion(t *testing.T) {
+	supported := poset.EventVersionRange{Min: 1, Max: 3}
+	participants := []uint64{1, 2, 3}
+
+	for _, c := range []struct {
+		name       string
+		advertised map[uint64]uint32
+		expected   uint32
+	}{
+		{"no peer heard from", map[uint64]uint32{}, 1},
+		{"one peer heard from", map[uint64]uint32{2: 3}, 1},
+		{"all peers current", map[uint64]uint32{2: 3, 3: 3}, 3},
+		{"all peers newer", map[uint64]uint32{2: 5, 3: 4}, 3},
+		{"an older peer", map[uint64]uint32{2: 3, 3: 2}, 2},
+		{"a peer older than supported", map[uint64]uint32{2: 3, 3: 0}, 1},
+		{"ourselves ignored", map[uint64]uint32{1: 0, 2: 3, 3: 3}, 3},
+	} {
+		if v := selectEventVersion(supported, 1, participants, c.advertised); v != c.expected {
+			t.Errorf("%s: expected version %d, got %d", c.name, c.expected, v)
+		}
+	}
+}
+
+func TestCoreCreatesEventsAtSelectedVersion(t *testing.T) {
+	cores, _, _ := initCores(2, t)
+
+	// while a peer advertises the legacy version only, the events are legacy
+	version := poset.EventVersionLegacy
+	cores[0].eventVersion = func() uint32 { return version }
+	hash, err := cores[0].AddEmptyEventBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, err := cores[0].poset.Store.GetEventBlock(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Version() != poset.EventVersionLegacy {
+		t.Fatalf("expected the legacy version, got %d", ev.Version())
+	}
+
+	// once it advertises the highest one, so are the events
+	version = poset.SupportedEventVersions.Max
+	if hash, err = cores[0].AddEmptyEventBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if ev, err = cores[0].poset.Store.GetEventBlock(hash); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Version() != poset.SupportedEventVersions.Max {
+		t.Fatalf("expected version %d, got %d", poset.SupportedEventVersions.Max, ev.Version())
+	}
+
+	// and the peer reads them all
+	if err := synchronizeCores(cores, 0, 1, [][]byte{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,109 @@
+package node
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// fairnessRun gossips 4 nodes choosing their peers with a selector until
+// they commit a few blocks, and returns the fairness report of each
+func fairnessRun(t *testing.T, selectorFn SelectorCreationFn,
+	selectorArgs func(addr string) SelectorCreationFnArgs) []poset.FairnessReport {
+	data := InitTestData(t, 4, 2)
+	var nodes []*Node
+	for i, addr := range data.Adds {
+		trans := createTransport(t, data.Logger, data.BackConfig, addr,
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		participants := nodePeers(data.Peers)
+		db := poset.NewInmemStore(participants, data.Config.CacheSize, nil)
+		node, err := NewNode(data.Config, data.Peers.ByNetAddr[addr].ID, data.Keys[i], participants,
+			db, trans, dummy.NewInmemDummyApp(data.Logger), selectorFn, selectorArgs(addr), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := node.Init(); err != nil {
+			t.Fatal(err)
+		}
+		nodes = append(nodes, node)
+	}
+
+	if err := gossip(nodes, 5, true, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []poset.FairnessReport
+	for i, n := range nodes {
+		report := n.GetFairnessReport()
+		if len(report.Creators) != len(nodes) {
+			t.Fatalf("node %d: expected a row per participant, got %d", i, len(report.Creators))
+		}
+		var events uint64
+		var clothoShares float64
+		for _, c := range report.Creators {
+			events += c.Counts.Events
+			clothoShares += c.Shares.Clothos
+			// every node gossips, and decides rounds
+			if c.Counts.Clothos == 0 || c.Counts.Atropos > c.Counts.Clothos {
+				t.Fatalf("node %d: expected clothos of %s, Atropos among them, got %+v",
+					i, c.Creator, c.Counts)
+			}
+		}
+		if events != report.Totals.Events || math.Abs(clothoShares-1) > 1e-9 {
+			t.Fatalf("node %d: expected the rows to add up to the totals, got %+v", i, report)
+		}
+		var txs uint64
+		for b := int64(0); b <= n.GetLastBlockIndex(); b++ {
+			block, err := n.GetBlock(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txs += uint64(len(block.Transactions()))
+		}
+		if report.Totals.Transactions != txs {
+			t.Fatalf("node %d: expected the %d transactions of the blocks, got %d",
+				i, txs, report.Totals.Transactions)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// TestFairnessSelectors compares the clothos concentration of the fair and
+// random selectors. The fair one picks the peers with the least in-degree
+// for their height, which keeps every creator deciding rounds. The random
+// one does too, in expectation, so neither may concentrate the clothos on
+// a few creators, the Gini coefficient staying below that of a creator
+// missing from the rounds.
+func TestFairnessSelectors(t *testing.T) {
+	runs := []struct {
+		name string
+		fn   SelectorCreationFn
+		args func(addr string) SelectorCreationFnArgs
+	}{
+		{"fair", NewFairPeerSelectorWrapper, func(addr string) SelectorCreationFnArgs {
+			return FairPeerSelectorCreationFnArgs{LocalAddr: addr}
+		}},
+		{"random", NewRandomPeerSelectorWrapper, func(addr string) SelectorCreationFnArgs {
+			return RandomPeerSelectorCreationFnArgs{LocalAddr: addr}
+		}},
+	}
+	// the Gini coefficient of 4 creators of which 3 contribute evenly
+	const missingCreator = 0.25
+	for _, run := range runs {
+		reports := fairnessRun(t, run.fn, run.args)
+		for i, report := range reports {
+			t.Logf("%s selector, node %d: clothos Gini %.3f, Atropos Gini %.3f",
+				run.name, i, report.Gini.Clothos, report.Gini.Atropos)
+			if report.Gini.Clothos >= missingCreator {
+				t.Fatalf("%s selector, node %d: expected the clothos spread over the creators, got a Gini of %.3f",
+					run.name, i, report.Gini.Clothos)
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package node
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// FastForwardStage is the progress of a fast forward started by
+// FastForwardFrom
+type FastForwardStage string
+
+// The stages of a fast forward, in order. It ends done or failed.
+const (
+	FastForwardRequested    FastForwardStage = "requested"
+	FastForwardTransferring FastForwardStage = "transferring"
+	FastForwardResetting    FastForwardStage = "resetting"
+	FastForwardRestoring    FastForwardStage = "restoring"
+	FastForwardDone         FastForwardStage = "done"
+	FastForwardFailed       FastForwardStage = "failed"
+)
+
+// maxFastForwardJobs is the number of fast forward jobs kept for GET, the
+// oldest are forgotten
+const maxFastForwardJobs = 16
+
+// ErrResetInProgress is returned by FastForwardFrom while another fast
+// forward is resetting the node
+var ErrResetInProgress = fmt.Errorf("a reset is already in progress")
+
+// FastForwardJob is a fast forward started by FastForwardFrom
+type FastForwardJob struct {
+	ID       string           `json:"id"`
+	Peer     string           `json:"peer"`
+	Stage    FastForwardStage `json:"stage"`
+	Error    string           `json:"error,omitempty"`
+	Block    int64            `json:"block"` // anchor block, -1 until transferred
+	Started  time.Time        `json:"started"`
+	Finished time.Time        `json:"finished"` // zero until done or failed
+}
+
+// fastForwardJobs are the jobs of FastForwardFrom. resetting is set while
+// one of them, or the fast forward of the CatchingUp state, runs.
+type fastForwardJobs struct {
+	sync.Mutex
+	resetting bool
+	next      uint64
+	jobs      map[string]*FastForwardJob
+	order     []string // job IDs, oldest first
+}
+
+// begin marks a reset in progress, false if one already is
+func (f *fastForwardJobs) begin() bool {
+	f.Lock()
+	defer f.Unlock()
+	if f.resetting {
+		return false
+	}
+	f.resetting = true
+	return true
+}
+
+// end marks the reset done
+func (f *fastForwardJobs) end() {
+	f.Lock()
+	f.resetting = false
+	f.Unlock()
+}
+
+// add adds a job started at started and returns it
+func (f *fastForwardJobs) add(peer string, started time.Time) *FastForwardJob {
+	f.Lock()
+	defer f.Unlock()
+	if f.jobs == nil {
+		f.jobs = make(map[string]*FastForwardJob)
+	}
+	f.next++
+	job := &FastForwardJob{
+		ID:      strconv.FormatUint(f.next, 10),
+		Peer:    peer,
+		Stage:   FastForwardRequested,
+		Block:   -1,
+		Started: started,
+	}
+	f.jobs[job.ID] = job
+	f.order = append(f.order, job.ID)
+	if len(f.order) > maxFastForwardJobs {
+		delete(f.jobs, f.order[0])
+		f.order = f.order[1:]
+	}
+	return job
+}
+
+// update changes a job under the lock
+func (f *fastForwardJobs) update(job *FastForwardJob, change func(*FastForwardJob)) {
+	f.Lock()
+	change(job)
+	f.Unlock()
+}
+
+// get returns a copy of a job
+func (f *fastForwardJobs) get(id string) (FastForwardJob, bool) {
+	f.Lock()
+	defer f.Unlock()
+	job, ok := f.jobs[id]
+	if !ok {
+		return FastForwardJob{}, false
+	}
+	return *job, true
+}
+
+// FastForwardFrom starts fast forwarding the node from the peer at an
+// address, rather than from the peer the CatchingUp state would pick, and
+// returns the job reporting its progress. The node is paused meanwhile. The
+// anchor block and the app snapshot are both transferred before the node is
+// reset, so that a peer failing mid-transfer leaves the node as it was. It
+// fails with ErrResetInProgress while another fast forward runs.
+func (n *Node) FastForwardFrom(addr string) (FastForwardJob, error) {
+	from, ok := n.core.participants.ReadByNetAddr(addr)
+	if !ok {
+		return FastForwardJob{}, fmt.Errorf("no participant at %s", addr)
+	}
+	if !n.fastForwards.begin() {
+		return FastForwardJob{}, ErrResetInProgress
+	}
+	job := n.fastForwards.add(addr, n.clock.Now())
+	started := *job
+
+	go func() {
+		defer n.fastForwards.end()
+		err := n.runFastForwardJob(&from, job)
+		n.fastForwards.update(job, func(job *FastForwardJob) {
+			job.Finished = n.clock.Now()
+			if err != nil {
+				job.Stage, job.Error = FastForwardFailed, err.Error()
+				return
+			}
+			job.Stage = FastForwardDone
+		})
+		if err != nil {
+			n.logger.WithError(err).WithField("peer", addr).Error("Fast forward failed")
+			return
+		}
+		n.logger.WithField("peer", addr).Info("Fast forwarded")
+	}()
+	return started, nil
+}
+
+// GetFastForward returns a job of FastForwardFrom
+func (n *Node) GetFastForward(id string) (FastForwardJob, bool) {
+	return n.fastForwards.get(id)
+}
+
+func (n *Node) runFastForwardJob(from *peers.Peer, job *FastForwardJob) error {
+	// a node the operator paused is left for them to resume
+	paused := n.IsPaused()
+	if err := n.Pause(); err != nil {
+		return err
+	}
+	if !paused {
+		defer n.Resume()
+	}
+
+	stage := func(stage FastForwardStage) {
+		n.fastForwards.update(job, func(job *FastForwardJob) {
+			job.Stage = stage
+		})
+	}
+	stage(FastForwardTransferring)
+	resp, err := n.requestFastForward(from.Message.NetAddr)
+	if err != nil {
+		return err
+	}
+	if err := n.checkConsensus(resp.FromID, resp.ConsensusHash, resp.ConsensusParams); err != nil {
+		return err
+	}
+	n.fastForwards.update(job, func(job *FastForwardJob) {
+		job.Block = resp.Block.Index()
+	})
+	return n.applyFastForward(from, resp, stage)
+}
@@ -0,0 +1,165 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitFastForward polls a job until it is done or failed and returns it,
+// with the stages seen on the way
+func waitFastForward(n *Node, id string, t *testing.T) (FastForwardJob, []FastForwardStage) {
+	var stages []FastForwardStage
+	timeout := time.After(60 * time.Second)
+	for {
+		job, ok := n.GetFastForward(id)
+		if !ok {
+			t.Fatalf("fast forward %s not found", id)
+		}
+		if len(stages) == 0 || stages[len(stages)-1] != job.Stage {
+			stages = append(stages, job.Stage)
+		}
+		if job.Stage == FastForwardDone || job.Stage == FastForwardFailed {
+			return job, stages
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("fast forward %s stuck at %s", id, job.Stage)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestFastForwardFrom(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+
+	var nodes []*Node
+	for i := range data.Adds {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+		node := createNode(t, data.Logger, data.Config, data.PeersSlice[i].ID, data.Keys[i],
+			data.Peers, trans, data.Adds[i], false)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+
+	// the first node does not gossip and falls many rounds behind
+	if err := gossip(nodes[1:], 3, false, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nodes[0].FastForwardFrom("unknown:1234"); err == nil {
+		t.Fatal("expected a fast forward from an unknown peer to be refused")
+	}
+
+	started, err := nodes[0].FastForwardFrom(data.Adds[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.Stage != FastForwardRequested || started.Peer != data.Adds[1] {
+		t.Fatalf("expected a requested job from %s, got %+v", data.Adds[1], started)
+	}
+	job, stages := waitFastForward(nodes[0], started.ID, t)
+	if job.Stage != FastForwardDone {
+		t.Fatalf("expected the fast forward done, got %+v", job)
+	}
+	if last := stages[len(stages)-1]; last != FastForwardDone {
+		t.Fatalf("expected the stages to end done, got %v", stages)
+	}
+
+	block, err := nodes[0].GetBlock(job.Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := nodes[1].GetBlock(job.Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(block.Body, expected.Body) {
+		t.Fatalf("expected the anchor block %d of %s", job.Block, data.Adds[1])
+	}
+	if nodes[0].IsPaused() {
+		t.Fatal("expected the node resumed after the fast forward")
+	}
+}
+
+// dyingPeer listens at an address, reads the first request sent to it and
+// dies once released, without an answer
+func dyingPeer(data *TestData, addr string, t *testing.T) (entered, release chan struct{}) {
+	ln, err := data.Network.CreateListener("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entered, release = make(chan struct{}), make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn.Read(make([]byte, 1))
+		close(entered)
+		<-release
+		conn.Close()
+		ln.Close()
+	}()
+	return entered, release
+}
+
+func TestFastForwardFromPeerDies(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+	trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[0],
+		data.PoolSize, data.CreateFu, data.Network.CreateListener)
+	defer transportClose(t, trans)
+	node := createNode(t, data.Logger, data.Config, data.PeersSlice[0].ID, data.Keys[0],
+		data.Peers, trans, data.Adds[0], false)
+	defer node.Shutdown()
+	lastBlock, lastRound := node.GetLastBlockIndex(), node.GetLastRound()
+
+	// the peer dies in the middle of the transfer
+	entered, release := dyingPeer(data, data.Adds[1], t)
+	started, err := node.FastForwardFrom(data.Adds[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-entered:
+	case <-time.After(30 * time.Second):
+		close(release)
+		t.Fatal("expected the fast forward to be asked for")
+	}
+	if _, err := node.FastForwardFrom(data.Adds[2]); err != ErrResetInProgress {
+		close(release)
+		t.Fatalf("expected a second fast forward to be refused, got %v", err)
+	}
+	if job, _ := node.GetFastForward(started.ID); job.Stage != FastForwardTransferring {
+		close(release)
+		t.Fatalf("expected the job transferring, got %+v", job)
+	}
+	close(release)
+
+	job, stages := waitFastForward(node, started.ID, t)
+	if job.Stage != FastForwardFailed || job.Error == "" || job.Block != -1 {
+		t.Fatalf("expected the fast forward failed with an error, got %+v", job)
+	}
+	for _, stage := range stages {
+		if stage == FastForwardResetting || stage == FastForwardRestoring {
+			t.Fatalf("expected no reset, got the stages %v", stages)
+		}
+	}
+	if b, r := node.GetLastBlockIndex(), node.GetLastRound(); b != lastBlock || r != lastRound {
+		t.Fatalf("expected the node left at block %d round %d, got block %d round %d",
+			lastBlock, lastRound, b, r)
+	}
+	if node.IsPaused() {
+		t.Fatal("expected the node resumed after the failed fast forward")
+	}
+
+	// the reset is no longer in progress
+	_, release = dyingPeer(data, data.Adds[2], t)
+	close(release)
+	retry, err := node.FastForwardFrom(data.Adds[2])
+	if err != nil {
+		t.Fatalf("expected a new fast forward once the first failed, got %v", err)
+	}
+	waitFastForward(node, retry.ID, t)
+}
@@ -0,0 +1,77 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/proxy/proto"
+)
+
+// txPriority returns the priority a transaction of the test starts with, the
+// unflagged ones of bombardAndWait having none
+func txPriority(tx []byte) byte {
+	if len(tx) > 0 && tx[0] >= '0' && tx[0] <= '9' {
+		return tx[0] - '0'
+	}
+	return 0
+}
+
+func TestFlaggedTransactionOrder(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+
+	var nodes []*Node
+	for i := range data.Adds {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+		node := createNode(t, data.Logger, data.Config, data.PeersSlice[i].ID, data.Keys[i],
+			data.Peers, trans, data.Adds[i], false)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+
+	// every node is given transactions of each priority before the
+	// unflagged ones
+	const perNode = 6
+	for i, n := range nodes {
+		for j := 0; j < perNode; j++ {
+			flags := byte(j % 3)
+			n.proxy.SubmitFlaggedCh() <- proto.FlaggedTx{
+				Tx:    []byte(fmt.Sprintf("%d node%d tx%d", flags, i, j)),
+				Flags: flags,
+			}
+		}
+	}
+	if err := gossip(nodes, 3, true, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// the nodes built the same blocks from the events of each other
+	checkGossip(nodes, 0, t)
+
+	for i, n := range nodes {
+		flagged := 0
+		for index := int64(0); index <= n.GetLastBlockIndex(); index++ {
+			block, err := n.GetBlock(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := byte(255)
+			for _, tx := range block.Transactions() {
+				priority := txPriority(tx)
+				if priority > last {
+					t.Fatalf("node %d: block %d: transaction %q of priority %d after one of priority %d",
+						i, index, tx, priority, last)
+				}
+				last = priority
+				if priority > 0 {
+					flagged++
+				}
+			}
+		}
+		if flagged == 0 {
+			t.Fatalf("node %d: expected flagged transactions in the blocks", i)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package node
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultFlightRecMaxSize is the total size of the files of a flight
+	// recorder
+	DefaultFlightRecMaxSize = 16 << 20
+	// flightRecSegments is the number of files the records are spread over,
+	// the oldest is deleted as a new one is started
+	flightRecSegments = 8
+	// flightRecExt is the extension of the files of a flight recorder
+	flightRecExt = ".jsonl"
+)
+
+// FlightRecord is a snapshot of the gauges and counters of a node, kept by
+// the flight recorder for when nothing else watched the node
+type FlightRecord struct {
+	Time  time.Time `json:"time"`
+	State string    `json:"state"`
+
+	Round                 int64  `json:"round"`
+	Block                 int64  `json:"block"`
+	ConsensusEvents       int64  `json:"consensus_events"`
+	ConsensusTransactions uint64 `json:"consensus_transactions"`
+	Undetermined          int    `json:"undetermined"`
+
+	// Peers are the peers of the node, Synced those it synced with lately
+	Peers         int     `json:"peers"`
+	Synced        int     `json:"synced"`
+	PeerPenalties int     `json:"peer_penalties"`
+	SyncRate      float64 `json:"sync_rate"`
+
+	CacheHitRate float64 `json:"cache_hit_rate"`
+	TxPool       int     `json:"tx_pool"`
+	TxPoolBytes  int     `json:"tx_pool_bytes"`
+	TxPoolMax    int     `json:"tx_pool_max"`
+}
+
+// flightRecordFields are the columns of the FlightRecord table
+var flightRecordFields = []string{
+	"time", "state", "round", "block", "consensus_events", "consensus_transactions",
+	"undetermined", "peers", "synced", "peer_penalties", "sync_rate",
+	"cache_hit_rate", "tx_pool", "tx_pool_bytes", "tx_pool_max",
+}
+
+// FlightRecordFields returns the names of the columns of Values
+func FlightRecordFields() []string {
+	return append([]string(nil), flightRecordFields...)
+}
+
+// Values returns the fields of the record as strings, in the order of
+// FlightRecordFields
+func (r FlightRecord) Values() []string {
+	return []string{
+		r.Time.UTC().Format(time.RFC3339),
+		r.State,
+		strconv.FormatInt(r.Round, 10),
+		strconv.FormatInt(r.Block, 10),
+		strconv.FormatInt(r.ConsensusEvents, 10),
+		strconv.FormatUint(r.ConsensusTransactions, 10),
+		strconv.Itoa(r.Undetermined),
+		strconv.Itoa(r.Peers),
+		strconv.Itoa(r.Synced),
+		strconv.Itoa(r.PeerPenalties),
+		strconv.FormatFloat(r.SyncRate, 'f', 2, 64),
+		strconv.FormatFloat(r.CacheHitRate, 'f', 3, 64),
+		strconv.Itoa(r.TxPool),
+		strconv.Itoa(r.TxPoolBytes),
+		strconv.Itoa(r.TxPoolMax),
+	}
+}
+
+// FlightRecord returns the current snapshot of the gauges and counters of
+// the node
+func (n *Node) FlightRecord() FlightRecord {
+	txPool := n.core.GetTransactionPoolStats()
+	var cacheHits, cacheLookups uint64
+	for _, c := range n.GetCacheStats() {
+		cacheHits += c.Hits
+		cacheLookups += c.Hits + c.Misses
+	}
+	var cacheHitRate float64
+	if cacheLookups > 0 {
+		cacheHitRate = float64(cacheHits) / float64(cacheLookups)
+	}
+	return FlightRecord{
+		Time:                  n.clock.Now(),
+		State:                 n.getState().String(),
+		Round:                 n.core.GetLastConsensusRound(),
+		Block:                 n.core.GetLastBlockIndex(),
+		ConsensusEvents:       n.core.GetConsensusEventsCount(),
+		ConsensusTransactions: n.core.GetConsensusTransactionsCount(),
+		Undetermined:          n.core.poset.GetUndeterminedStats().Events,
+		Peers:                 n.peerSelector.Peers().Len(),
+		Synced:                n.health.syncedSince(n.clock.Now().Add(-n.readySyncWindow())),
+		PeerPenalties:         n.health.totalPenalties(),
+		SyncRate:              n.SyncRate(),
+		CacheHitRate:          cacheHitRate,
+		TxPool:                txPool.Count,
+		TxPoolBytes:           txPool.Bytes,
+		TxPoolMax:             txPool.MaxCount,
+	}
+}
+
+// FlightRecorder appends FlightRecords to a ring of files in a directory,
+// one JSON line each, so that a record cut short by a crash spoils no other.
+// The oldest file is deleted as the files outgrow the max size.
+type FlightRecorder struct {
+	sync.Mutex
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	file *os.File
+	size int64
+	seq  uint64
+
+	// busy is set while a record is written, dropped the records coming
+	// meanwhile
+	busy    int32
+	dropped uint64
+}
+
+// NewFlightRecorder opens the flight recorder of a directory, of maxSize
+// bytes in all, DefaultFlightRecMaxSize when 0. The records go to a new file
+// after those already there.
+func NewFlightRecorder(dir string, maxSize int64) (*FlightRecorder, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultFlightRecMaxSize
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	r := &FlightRecorder{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: maxSize / flightRecSegments,
+	}
+	files, err := flightRecFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > 0 {
+		r.seq = files[len(files)-1].seq
+	}
+	if err := r.next(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// flightRecFile is a file of a flight recorder
+type flightRecFile struct {
+	seq  uint64
+	path string
+	size int64
+}
+
+// flightRecFiles returns the files of a flight recorder, the oldest first
+func flightRecFiles(dir string) ([]flightRecFile, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []flightRecFile
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, flightRecExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, flightRecExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		files = append(files, flightRecFile{seq: seq, path: filepath.Join(dir, name), size: info.Size()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].seq < files[j].seq })
+	return files, nil
+}
+
+// next starts a new file and deletes the oldest ones over the max size
+func (r *FlightRecorder) next() error {
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			return err
+		}
+	}
+	r.seq++
+	path := filepath.Join(r.dir, fmt.Sprintf("%08d%s", r.seq, flightRecExt))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	r.file, r.size = f, 0
+
+	files, err := flightRecFiles(r.dir)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	// the file just started is the last one
+	for _, file := range files[:len(files)-1] {
+		if total <= r.maxSize-r.segmentSize {
+			break
+		}
+		if err := os.Remove(file.path); err != nil {
+			return err
+		}
+		total -= file.size
+	}
+	return nil
+}
+
+// Write appends a record, unless another is being written, in which case it
+// is dropped: recording must never hold the node up
+func (r *FlightRecorder) Write(rec FlightRecord) error {
+	if !atomic.CompareAndSwapInt32(&r.busy, 0, 1) {
+		atomic.AddUint64(&r.dropped, 1)
+		return nil
+	}
+	defer atomic.StoreInt32(&r.busy, 0)
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	r.Lock()
+	defer r.Unlock()
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(line)) > r.segmentSize {
+		if err := r.next(); err != nil {
+			return err
+		}
+	}
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	return err
+}
+
+// Dropped returns the number of records dropped as another was written
+func (r *FlightRecorder) Dropped() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
+
+// Close closes the current file of the recorder
+func (r *FlightRecorder) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// ReadFlightRecords returns the records of the flight recorder of a
+// directory, the oldest first. The lines cut short by a crash are skipped.
+func ReadFlightRecords(dir string) ([]FlightRecord, error) {
+	files, err := flightRecFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var records []FlightRecord
+	for _, file := range files {
+		f, err := os.Open(file.path)
+		if os.IsNotExist(err) {
+			// deleted by the recorder meanwhile
+			continue
+		}
+		if err != nil {
+			return records, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var rec FlightRecord
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+				continue
+			}
+			records = append(records, rec)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return records, err
+		}
+	}
+	return records, nil
+}
+
+// StartFlightRecorder writes a FlightRecord of the node to rec every
+// interval, until the node shuts down. The records are written apart from
+// the consensus, a record is dropped while the previous one is written.
+func (n *Node) StartFlightRecorder(rec *FlightRecorder, interval time.Duration) {
+	if rec == nil || interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := n.clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-n.shutdownCh:
+				return
+			case <-ticker.C():
+			}
+			// a slow disk drops the records rather than delaying the next
+			record := n.FlightRecord()
+			go func() {
+				if err := rec.Write(record); err != nil {
+					n.logger.WithError(err).Warn("Writing the flight recorder")
+				}
+			}()
+		}
+	}()
+}
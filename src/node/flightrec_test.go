@@ -0,0 +1,151 @@
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// dirSize returns the total size of the files of a directory
+func dirSize(t *testing.T, dir string) int64 {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var size int64
+	for _, info := range infos {
+		size += info.Size()
+	}
+	return size
+}
+
+func TestFlightRecorderRotation(t *testing.T) {
+	const maxSize = 4096
+	dir, err := ioutil.TempDir("", "flightrec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rec, err := NewFlightRecorder(dir, maxSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1500000000, 0)
+	const n = 500
+	for i := 0; i < n; i++ {
+		if err := rec.Write(FlightRecord{Time: start.Add(time.Duration(i) * time.Second), Round: int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+		if size := dirSize(t, dir); size > maxSize {
+			t.Fatalf("record %d: expected at most %d bytes, got %d", i, maxSize, size)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "00000001.jsonl")); !os.IsNotExist(err) {
+		t.Fatalf("expected the oldest file deleted, got %v", err)
+	}
+	records, err := ReadFlightRecords(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) == 0 || len(records) == n {
+		t.Fatalf("expected some of the %d records kept, got %d", n, len(records))
+	}
+	// the newest records are kept, in order
+	first := records[0].Round
+	for i, r := range records {
+		if r.Round != first+int64(i) {
+			t.Fatalf("expected round %d at %d, got %d", first+int64(i), i, r.Round)
+		}
+	}
+	if last := records[len(records)-1].Round; last != n-1 {
+		t.Fatalf("expected the last record of round %d, got %d", n-1, last)
+	}
+}
+
+func TestReadFlightRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flightrec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Unix(1500000000, 0).UTC()
+	var written []FlightRecord
+	// a restart continues in a new file after the others
+	for run := 0; run < 2; run++ {
+		rec, err := NewFlightRecorder(dir, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			r := FlightRecord{
+				Time:         start.Add(time.Duration(len(written)) * time.Second),
+				State:        "Babbling",
+				Round:        int64(len(written)),
+				Block:        int64(len(written) / 2),
+				Peers:        4,
+				Synced:       3,
+				SyncRate:     0.75,
+				CacheHitRate: 0.5,
+				TxPool:       len(written),
+			}
+			if err := rec.Write(r); err != nil {
+				t.Fatal(err)
+			}
+			written = append(written, r)
+		}
+		if err := rec.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a crash cut the last record short
+	files, err := flightRecFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	f, err := os.OpenFile(files[1].path, os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"time":"2017-07-14T02:40:06Z","state":"Bab`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	records, err := ReadFlightRecords(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(written) {
+		t.Fatalf("expected %d records, got %d", len(written), len(records))
+	}
+	for i, r := range records {
+		if !r.Time.Equal(written[i].Time) {
+			t.Fatalf("record %d: expected time %v, got %v", i, written[i].Time, r.Time)
+		}
+		r.Time = written[i].Time
+		if r != written[i] {
+			t.Fatalf("record %d: expected %+v, got %+v", i, written[i], r)
+		}
+	}
+
+	fields := FlightRecordFields()
+	values := records[0].Values()
+	if len(values) != len(fields) {
+		t.Fatalf("expected %d values, got %d", len(fields), len(values))
+	}
+	if values[0] != "2017-07-14T02:40:00Z" || values[1] != "Babbling" {
+		t.Fatalf("unexpected values %v", values)
+	}
+}
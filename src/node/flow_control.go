@@ -0,0 +1,94 @@
+package node
+
+import (
+	"sync"
+	"time"
+)
+
+// flowControl slows the creation of the events of the node while the round
+// of its head runs ahead of the last consensus round, the skew, by more than
+// a threshold. The heartbeat is doubled at each heartbeat the skew exceeds
+// the threshold, up to maxFactor times, and halved back at each heartbeat
+// it does not. The bounded factor keeps the node creating events however
+// large the skew gets, and the transactions queue in the pool meanwhile.
+type flowControl struct {
+	sync.Mutex
+	threshold int64
+	maxFactor int64
+	skew      int64
+	factor    int64
+}
+
+func newFlowControl(threshold, maxFactor int64) *flowControl {
+	if maxFactor < 1 {
+		maxFactor = 1
+	}
+	return &flowControl{threshold: threshold, maxFactor: maxFactor, factor: 1}
+}
+
+// update records the skew of a heartbeat and returns the factor of the next
+// heartbeat. In a network of two participants each round waits for both
+// nodes, the skew is structural and the heartbeat left alone.
+func (f *flowControl) update(skew int64, participants int) int64 {
+	f.Lock()
+	defer f.Unlock()
+	f.skew = skew
+	switch {
+	case f.threshold <= 0 || participants <= 2:
+		f.factor = 1
+	case skew > f.threshold:
+		f.factor *= 2
+		if f.factor > f.maxFactor {
+			f.factor = f.maxFactor
+		}
+	case f.factor > 1:
+		f.factor /= 2
+	}
+	return f.factor
+}
+
+// state returns the last skew recorded and the factor of the heartbeat
+func (f *flowControl) state() (skew, factor int64) {
+	f.Lock()
+	defer f.Unlock()
+	return f.skew, f.factor
+}
+
+// headSkew returns the number of rounds the head of the node is ahead of
+// the last consensus round, 0 before the node made an event
+func (n *Node) headSkew() int64 {
+	store := n.core.poset.Store
+	hash, _, err := store.LastEventFrom(n.core.HexID())
+	if err != nil {
+		return 0
+	}
+	head, err := store.GetEventBlock(hash)
+	if err != nil {
+		return 0
+	}
+	last := n.core.GetLastConsensusRound()
+	if last < 0 {
+		// no round decided yet, the first one is 0
+		last = -1
+	}
+	if skew := head.Frame - last; skew > 0 {
+		return skew
+	}
+	return 0
+}
+
+// checkFlow feeds the skew of the head to the flow control, once per
+// heartbeat
+func (n *Node) checkFlow() {
+	if n.core.IsObserver() {
+		return
+	}
+	n.flow.update(n.headSkew(), n.core.participants.Len())
+}
+
+// heartbeat returns the heartbeat of the node, slowed by the flow control
+func (n *Node) heartbeat() time.Duration {
+	_, factor := n.flow.state()
+	base, _ := n.heartbeats()
+	return base * time.Duration(factor)
+}
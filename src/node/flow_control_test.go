@@ -0,0 +1,110 @@
+package node
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlowControl(t *testing.T) {
+	f := newFlowControl(10, 8)
+	steps := []struct {
+		skew         int64
+		participants int
+		factor       int64
+	}{
+		{5, 4, 1},
+		{11, 4, 2},
+		{12, 4, 4},
+		{15, 4, 8},
+		// bounded, so that the node keeps creating events
+		{30, 4, 8},
+		{10, 4, 4},
+		{3, 4, 2},
+		{11, 4, 4},
+		// the skew of two participants is structural
+		{30, 2, 1},
+		{30, 4, 2},
+		{0, 4, 1},
+		{0, 4, 1},
+	}
+	for i, s := range steps {
+		if factor := f.update(s.skew, s.participants); factor != s.factor {
+			t.Fatalf("step %d: expected factor %d, got %d", i, s.factor, factor)
+		}
+	}
+	if skew, factor := f.state(); skew != 0 || factor != 1 {
+		t.Fatalf("expected skew 0 and factor 1, got %d and %d", skew, factor)
+	}
+
+	if factor := newFlowControl(0, 8).update(100, 4); factor != 1 {
+		t.Fatalf("expected a zero threshold never to throttle, got factor %d", factor)
+	}
+}
+
+// playFastNode plays, in virtual time, a node creating an event at each of
+// its heartbeats and reaching a new round every roundEvents of them, faster
+// than the network decides rounds. It returns the skew of the node after
+// each of its events.
+func playFastNode(flow *flowControl, participants int, d time.Duration) []int64 {
+	const (
+		heartbeat   = 10 * time.Millisecond
+		roundEvents = 4
+		decideEvery = 100 * time.Millisecond
+	)
+	var (
+		now    time.Duration
+		events int64
+		skew   int64
+		skews  []int64
+	)
+	for now < d {
+		now += heartbeat * time.Duration(flow.update(skew, participants))
+		events++
+		head := events / roundEvents
+		decided := int64(now / decideEvery)
+		if decided > head {
+			// no round is decided before it is created
+			decided = head
+		}
+		skew = head - decided
+		skews = append(skews, skew)
+	}
+	return skews
+}
+
+func TestFlowControlFastNode(t *testing.T) {
+	const (
+		threshold = 10
+		maxFactor = 16
+		// the skew overshoots the threshold by the events of a heartbeat
+		bound = threshold + 2
+		d     = time.Minute
+	)
+
+	throttled := playFastNode(newFlowControl(threshold, maxFactor), 4, d)
+	// it never stops creating events, the slowest at the max factor
+	if min := int(d / (maxFactor * 10 * time.Millisecond)); len(throttled) < min {
+		t.Fatalf("expected at least %d events, got %d", min, len(throttled))
+	}
+	for i, skew := range throttled {
+		if skew > bound {
+			t.Fatalf("event %d: expected the skew below %d, got %d", i, bound, skew)
+		}
+	}
+
+	// without the throttle, or with two participants, the skew grows
+	for _, flow := range []*flowControl{
+		newFlowControl(0, maxFactor),
+		newFlowControl(threshold, maxFactor),
+	} {
+		participants := 4
+		if flow.threshold > 0 {
+			participants = 2
+		}
+		free := playFastNode(flow, participants, d)
+		half, end := free[len(free)/2], free[len(free)-1]
+		if end <= bound || end <= half {
+			t.Fatalf("expected the skew of an unthrottled node to grow, from %d to %d", half, end)
+		}
+	}
+}
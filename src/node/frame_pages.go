@@ -0,0 +1,186 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// FrameHashError refuses the frame of a paged fast forward whose assembled
+// events do not hash to the frame hash the peer announced
+type FrameHashError struct {
+	Round    int64
+	Expected []byte
+	Got      []byte
+}
+
+func (e *FrameHashError) Error() string {
+	return fmt.Sprintf("frame %d hashes to %X, the peer announced %X", e.Round, e.Got, e.Expected)
+}
+
+// IsFrameHashError returns true for a FrameHashError
+func IsFrameHashError(err error) bool {
+	_, ok := err.(*FrameHashError)
+	return ok
+}
+
+// servedFrame is the last frame whose events were paged out, so that the
+// pages of a fast forward do not each make the frame again
+type servedFrame struct {
+	sync.Mutex
+	hash  []byte
+	frame poset.Frame
+}
+
+// set remembers the frame of a paged fast forward
+func (s *servedFrame) set(hash []byte, frame poset.Frame) {
+	s.Lock()
+	defer s.Unlock()
+	s.hash, s.frame = hash, frame
+}
+
+// get returns the frame of a hash, false when it is not the last one served
+func (s *servedFrame) get(hash []byte) (poset.Frame, bool) {
+	s.Lock()
+	defer s.Unlock()
+	if s.hash == nil || !bytes.Equal(s.hash, hash) {
+		return poset.Frame{}, false
+	}
+	return s.frame, true
+}
+
+// pageFrame leaves the events out of the frame of a paged fast forward
+// response, announcing their number and the frame hash instead
+func (n *Node) pageFrame(resp *peer.FastForwardResponse) error {
+	hash, err := resp.Frame.Hash()
+	if err != nil {
+		return err
+	}
+	n.frames.set(hash, resp.Frame)
+	resp.FrameEvents = int64(len(resp.Frame.Events))
+	resp.FrameHash = hash
+	resp.Frame.Events = nil
+	return nil
+}
+
+func (n *Node) processFrameEventsRequest(rpc *peer.RPC, cmd *peer.FrameEventsRequest) {
+	n.logger.WithFields(logrus.Fields{
+		"from_id": cmd.FromID,
+		"round":   cmd.Round,
+		"offset":  cmd.Offset,
+	}).Debug("processFrameEventsRequest(rpc net.RPC, cmd *net.FrameEventsRequest)")
+
+	resp := &peer.FrameEventsResponse{
+		FromID: n.id,
+	}
+	frame, err := n.frameOfHash(cmd.Round, cmd.FrameHash)
+	if err == nil {
+		resp.Events, resp.More = framePage(frame.Events, cmd.Offset, cmd.Limit, cmd.MaxBytes)
+	} else {
+		n.logger.WithField("error", err).Error("n.frameOfHash(cmd.Round, cmd.FrameHash)")
+	}
+
+	// TODO: context.Background
+	rpc.SendResult(context.Background(), n.logger, resp, err)
+}
+
+// frameOfHash returns the frame of a round, which must have the hash a paged
+// fast forward announced
+func (n *Node) frameOfHash(round int64, hash []byte) (poset.Frame, error) {
+	if frame, ok := n.frames.get(hash); ok && frame.Round == round {
+		return frame, nil
+	}
+	n.coreLock.Lock()
+	frame, err := n.core.poset.GetFrame(round)
+	n.coreLock.Unlock()
+	if err != nil {
+		return poset.Frame{}, err
+	}
+	frameHash, err := frame.Hash()
+	if err != nil {
+		return poset.Frame{}, err
+	}
+	if !bytes.Equal(frameHash, hash) {
+		return poset.Frame{}, fmt.Errorf("frame %d no longer hashes to %X", round, hash)
+	}
+	n.frames.set(frameHash, frame)
+	return frame, nil
+}
+
+// framePage returns the events of a page, from offset on, bounded in count
+// by limit and in size by maxBytes as the events of a sync response, and
+// whether events are left after it
+func framePage(events []*poset.EventMessage, offset, limit, maxBytes int64) ([]*poset.EventMessage, bool) {
+	if offset < 0 || offset >= int64(len(events)) {
+		return nil, false
+	}
+	events = events[offset:]
+	var size int64
+	for i := range events {
+		if limit > 0 && int64(i) >= limit {
+			return events[:i], true
+		}
+		eventSize := int64(proto.Size(events[i]))
+		if maxBytes > 0 && i > 0 && size+eventSize > maxBytes {
+			return events[:i], true
+		}
+		size += eventSize
+	}
+	return events, false
+}
+
+// fetchFrameEvents fetches in pages the events left out of the frame of a
+// paged fast forward response, and checks the assembled frame against the
+// frame hash the peer announced. A whole frame is left as it is.
+func fetchFrameEvents(trans peer.SyncPeer, target string, fromID uint64,
+	resp *peer.FastForwardResponse, limit, maxBytes int64) error {
+	if resp.FrameHash == nil {
+		return nil
+	}
+	events := make([]*poset.EventMessage, 0, resp.FrameEvents)
+	for int64(len(events)) < resp.FrameEvents {
+		args := &peer.FrameEventsRequest{
+			FromID:    fromID,
+			Round:     resp.Frame.Round,
+			FrameHash: resp.FrameHash,
+			Offset:    int64(len(events)),
+			Limit:     limit,
+			MaxBytes:  maxBytes,
+		}
+		out := &peer.FrameEventsResponse{}
+		if err := trans.FrameEvents(context.Background(), target, args, out); err != nil {
+			return err
+		}
+		if len(out.Events) == 0 {
+			return fmt.Errorf("peer %s sent no events of frame %d from %d, of %d",
+				target, resp.Frame.Round, len(events), resp.FrameEvents)
+		}
+		events = append(events, out.Events...)
+		if !out.More {
+			break
+		}
+	}
+	if int64(len(events)) != resp.FrameEvents {
+		return fmt.Errorf("peer %s sent %d events of frame %d, of %d",
+			target, len(events), resp.Frame.Round, resp.FrameEvents)
+	}
+
+	frame := resp.Frame
+	frame.Events = events
+	hash, err := frame.Hash()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash, resp.FrameHash) {
+		return &FrameHashError{Round: frame.Round, Expected: resp.FrameHash, Got: hash}
+	}
+	resp.Frame = frame
+	return nil
+}
@@ -0,0 +1,206 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// pagingTransport counts the frame pages fetched through it, and corrupts
+// the events of the page corrupt when it is positive
+type pagingTransport struct {
+	peer.SyncPeer
+	pages   count64
+	corrupt int64
+}
+
+func (tr *pagingTransport) FrameEvents(ctx context.Context, target string,
+	req *peer.FrameEventsRequest, resp *peer.FrameEventsResponse) error {
+	if err := tr.SyncPeer.FrameEvents(ctx, target, req, resp); err != nil {
+		return err
+	}
+	if page := tr.pages.increment(); page == tr.corrupt && len(resp.Events) > 0 {
+		ev := *resp.Events[0]
+		body := *ev.Body
+		body.Transactions = [][]byte{[]byte("corrupted")}
+		ev.Body = &body
+		resp.Events[0] = &ev
+	}
+	return nil
+}
+
+func TestFramePage(t *testing.T) {
+	var events []*poset.EventMessage
+	for i := 0; i < 10; i++ {
+		events = append(events, &poset.EventMessage{Body: &poset.EventBody{Index: int64(i)}})
+	}
+	size := int64(proto.Size(events[1]))
+
+	checks := []struct {
+		offset, limit, maxBytes int64
+		count                   int
+		more                    bool
+	}{
+		{0, 0, 0, 10, false},
+		{0, 4, 0, 4, true},
+		{8, 4, 0, 2, false},
+		{2, 0, 3 * size, 3, true},
+		// an event larger than the budget still goes alone
+		{2, 0, 1, 1, true},
+		{10, 4, 0, 0, false},
+	}
+	for _, c := range checks {
+		page, more := framePage(events, c.offset, c.limit, c.maxBytes)
+		if len(page) != c.count || more != c.more {
+			t.Fatalf("%+v: expected %d events and more %v, got %d and %v", c, c.count, c.more, len(page), more)
+		}
+		if c.count > 0 && page[0].Body.Index != c.offset {
+			t.Fatalf("%+v: expected the page from %d, got %d", c, c.offset, page[0].Body.Index)
+		}
+	}
+}
+
+func TestFastForwardFramePages(t *testing.T) {
+	const frameEvents = 10000
+	data := InitTestData(t, 2, 2)
+
+	trans1 := createTransport(t, data.Logger, data.BackConfig, data.Adds[0],
+		data.PoolSize, data.CreateFu, data.Network.CreateListener)
+	defer transportClose(t, trans1)
+	trans2 := createTransport(t, data.Logger, data.BackConfig, data.Adds[1],
+		data.PoolSize, data.CreateFu, data.Network.CreateListener)
+	defer transportClose(t, trans2)
+
+	node1 := createNode(t, data.Logger, data.Config, data.PeersSlice[0].ID, data.Keys[0], data.Peers, trans1, data.Adds[0], false)
+	defer node1.Shutdown()
+	node2 := createNode(t, data.Logger, data.Config, data.PeersSlice[1].ID, data.Keys[1], data.Peers, trans2, data.Adds[1], false)
+	defer node2.Shutdown()
+
+	// node2 has a frame of many small events
+	frame := poset.Frame{Round: 1}
+	for i := 0; i < frameEvents; i++ {
+		frame.Events = append(frame.Events, &poset.EventMessage{
+			Body: &poset.EventBody{
+				Index:        int64(i),
+				Transactions: [][]byte{[]byte(fmt.Sprintf("tx%d", i))},
+			},
+		})
+	}
+	if err := node2.core.poset.Store.SetFrame(frame); err != nil {
+		t.Fatal(err)
+	}
+	frameHash, err := frame.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node2.commitCh <- poset.NewBlock(0, 1, frameHash, nil)
+	// wait for the commit
+	time.Sleep(3 * time.Second)
+	node2.core.poset.AnchorBlock = new(int64)
+
+	// the events are left out of the fast forward response
+	args := &peer.FastForwardRequest{FromID: node1.id, ConsensusHash: node1.consensusHash, Paged: true}
+	resp := &peer.FastForwardResponse{}
+	if err := trans1.FastForward(context.Background(), data.Adds[1], args, resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Frame.Events) != 0 || resp.FrameEvents != frameEvents {
+		t.Fatalf("expected the %d events left out, got %d events and a count of %d",
+			frameEvents, len(resp.Frame.Events), resp.FrameEvents)
+	}
+
+	// and fetched in small pages
+	paged := *resp
+	tr := &pagingTransport{SyncPeer: trans1}
+	if err := fetchFrameEvents(tr, data.Adds[1], node1.id, &paged, 100, 0); err != nil {
+		t.Fatal(err)
+	}
+	if pages := tr.pages.get(); pages != frameEvents/100 {
+		t.Fatalf("expected %d pages, got %d", frameEvents/100, pages)
+	}
+	if !paged.Frame.Equals(&frame) {
+		t.Fatal("expected the frame of the peer assembled")
+	}
+	if hash, err := paged.Frame.Hash(); err != nil || !bytes.Equal(hash, frameHash) {
+		t.Fatalf("expected the frame hash %X, got %X (%v)", frameHash, hash, err)
+	}
+
+	// a corrupted page does not make it past the frame hash
+	corrupted := *resp
+	tr = &pagingTransport{SyncPeer: trans1, corrupt: 3}
+	err = fetchFrameEvents(tr, data.Adds[1], node1.id, &corrupted, 100, 0)
+	if !IsFrameHashError(err) {
+		t.Fatalf("expected a frame hash error, got %v", err)
+	}
+	if len(corrupted.Frame.Events) != 0 {
+		t.Fatal("expected the corrupted frame not kept")
+	}
+
+	// the requests of the node page the frame as configured
+	node1.conf.FramePageEvents = 1000
+	result, err := node1.requestFastForward(data.Adds[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Frame.Equals(&frame) {
+		t.Fatal("expected the frame of the peer assembled")
+	}
+}
+
+func TestFastForwardFromPaged(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+	// a page per event
+	data.Config.FramePageEvents = 1
+
+	var nodes []*Node
+	var tr *pagingTransport
+	for i := range data.Adds {
+		var trans peer.SyncPeer = createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+		if i == 0 {
+			tr = &pagingTransport{SyncPeer: trans}
+			trans = tr
+		}
+		node := createNode(t, data.Logger, data.Config, data.PeersSlice[i].ID, data.Keys[i],
+			data.Peers, trans, data.Adds[i], false)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+
+	// the first node does not gossip and falls many rounds behind
+	if err := gossip(nodes[1:], 3, false, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	started, err := nodes[0].FastForwardFrom(data.Adds[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, _ := waitFastForward(nodes[0], started.ID, t)
+	if job.Stage != FastForwardDone {
+		t.Fatalf("expected the fast forward done, got %+v", job)
+	}
+	if tr.pages.get() == 0 {
+		t.Fatal("expected the frame fetched in pages")
+	}
+
+	// the reset took the assembled frame
+	block, err := nodes[0].GetBlock(job.Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := nodes[1].GetBlock(job.Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(block.GetFrameHash(), expected.GetFrameHash()) {
+		t.Fatalf("expected the frame hash %X, got %X", expected.GetFrameHash(), block.GetFrameHash())
+	}
+}
@@ -0,0 +1,34 @@
+package node
+
+// GetParticipantGaps returns the indexes missing before the events known to
+// exist of each participant, by public key
+func (n *Node) GetParticipantGaps() map[string][]int64 {
+	return n.core.poset.Store.ParticipantGaps()
+}
+
+// missingEvents returns the number of indexes missing in the gaps
+func (n *Node) missingEvents() int {
+	res := 0
+	for _, indexes := range n.GetParticipantGaps() {
+		res += len(indexes)
+	}
+	return res
+}
+
+// belowGaps lowers the known index of the creators with missing events to
+// the one before the first missing, so that the sync requests ask the peers
+// for them again and the sync responses do not skip them
+func (c *Core) belowGaps(known map[uint64]int64) {
+	for pubKey, indexes := range c.poset.Store.ParticipantGaps() {
+		if len(indexes) == 0 {
+			continue
+		}
+		peer, ok := c.participants.ReadByPubKey(pubKey)
+		if !ok {
+			continue
+		}
+		if index, ok := known[peer.ID]; ok && index >= indexes[0] {
+			known[peer.ID] = indexes[0] - 1
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+func TestCoreFillsGaps(t *testing.T) {
+	cores, _, _ := initCores(2, t)
+	creator, behind := cores[0], cores[1]
+
+	// the events 2 and 3 of the creator, its event 0 is the leaf event every
+	// core has
+	for i := 0; i < 2; i++ {
+		if _, err := creator.AddEmptyEventBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	peer, ok := behind.participants.ReadByPubKey(creator.HexID())
+	if !ok {
+		t.Fatal("creator not found")
+	}
+	events, err := creator.EventDiff(map[uint64]int64{peer.ID: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(events); l != 3 {
+		t.Fatalf("expected 3 events of the creator, got %d", l)
+	}
+	wire, err := creator.ToWire(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a sync missing the event 2
+	if err := behind.Sync(&peer, []poset.WireEvent{wire[0], wire[2]}); err == nil {
+		t.Fatal("expected an error inserting the event after the missing one")
+	}
+	expected := map[string][]int64{creator.HexID(): {2}}
+	if gaps := behind.poset.Store.ParticipantGaps(); !reflect.DeepEqual(gaps, expected) {
+		t.Fatalf("expected the gaps %v, got %v", expected, gaps)
+	}
+
+	// the height of the creator ran ahead of the store, the known events
+	// still ask for the missing one
+	behind.participants.SetHeightByPubKeyHex(creator.HexID(), 3)
+	if known := behind.KnownEvents()[peer.ID]; known != 1 {
+		t.Fatalf("expected the known index of the creator to be 1, got %d", known)
+	}
+
+	if err := synchronizeCores(cores, 0, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if gaps := behind.poset.Store.ParticipantGaps(); len(gaps) != 0 {
+		t.Fatalf("expected the gaps to be filled, got %v", gaps)
+	}
+	last, _, err := behind.poset.Store.LastEventFrom(creator.HexID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != creator.head {
+		t.Fatalf("expected the last event of the creator to be %s, got %s",
+			creator.head, last)
+	}
+}
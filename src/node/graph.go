@@ -7,7 +7,7 @@ import (
 // Infos struct for graph data (visualizer)
 type Infos struct {
 	ParticipantEvents map[string]map[poset.EventHash]poset.Event
-	Rounds            []poset.RoundCreated
+	Rounds            []poset.Round
 	Blocks            []poset.Block
 }
 
@@ -99,9 +99,9 @@ func (g *Graph) GetParticipantEvents() map[string]map[poset.EventHash]poset.Even
 	return res
 }
 
-// GetRounds returns the created rounds for the DAG
-func (g *Graph) GetRounds() []poset.RoundCreated {
-	var res []poset.RoundCreated
+// GetRounds returns the rounds for the DAG
+func (g *Graph) GetRounds() []poset.Round {
+	var res []poset.Round
 
 	store := g.Node.core.poset.Store
 
@@ -112,7 +112,7 @@ func (g *Graph) GetRounds() []poset.RoundCreated {
 	}
 
 	for round <= store.LastRound() {
-		r, err := store.GetRoundCreated(round)
+		r, err := store.GetRound(round)
 
 		if err != nil {
 			break
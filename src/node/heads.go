@@ -0,0 +1,168 @@
+package node
+
+import (
+	"sync"
+
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// Head is the last event of a participant known to the node, with the last
+// event the participant advertised in its syncs with the node
+type Head struct {
+	ID     uint64 `json:"id"`
+	PubKey string `json:"pub_key"`
+	Self   bool   `json:"self,omitempty"`
+	// Known is the last event of the participant in the store
+	Known *HeadEvent `json:"known,omitempty"`
+	// Advertised is the last head the participant advertised, nil when it
+	// never did
+	Advertised *HeadEvent `json:"advertised,omitempty"`
+}
+
+// HeadEvent is the last event of a participant. A head only known by its
+// index, from a sync request, has no hash and the round -1.
+type HeadEvent struct {
+	Hash  string `json:"hash,omitempty"`
+	Index int64  `json:"index"`
+	Round int64  `json:"round"` // the frame of the event
+}
+
+func newHeadEvent(head peer.Head) *HeadEvent {
+	ev := &HeadEvent{Index: head.Index, Round: head.Round}
+	if !head.Hash.Zero() {
+		ev.Hash = head.Hash.String()
+	}
+	return ev
+}
+
+// lastHead returns the last event of a participant in the store, or the
+// event the root stands for. It reads what the syncs stored and runs no
+// consensus, so that it can be called at any time.
+func lastHead(store poset.Store, pubKey string) (peer.Head, error) {
+	hash, isRoot, err := store.LastEventFrom(pubKey)
+	if err != nil {
+		return peer.Head{}, err
+	}
+	if isRoot {
+		root, err := store.GetRoot(pubKey)
+		if err != nil {
+			return peer.Head{}, err
+		}
+		return peer.Head{Hash: hash, Index: root.SelfParent.Index, Round: root.SelfParent.Round}, nil
+	}
+	ev, err := store.GetEventBlock(hash)
+	if err != nil {
+		return peer.Head{}, err
+	}
+	return peer.Head{Hash: hash, Index: ev.Index(), Round: ev.Frame}, nil
+}
+
+// headBook keeps the heads the peers advertised, in their sync responses,
+// or by the known events of their sync requests which tell the index of
+// their head
+type headBook struct {
+	sync.RWMutex
+	heads map[uint64]peer.Head
+}
+
+func newHeadBook() *headBook {
+	return &headBook{heads: make(map[uint64]peer.Head)}
+}
+
+// observe records the head a peer advertised, unless a later one is known
+// already, the syncs arriving in any order
+func (b *headBook) observe(id uint64, head *peer.Head) {
+	if head == nil {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	if last, ok := b.heads[id]; ok && (last.Index > head.Index ||
+		last.Index == head.Index && head.Hash.Zero()) {
+		return
+	}
+	b.heads[id] = *head
+}
+
+// observeIndex records the index of the head a peer told in its known
+// events
+func (b *headBook) observeIndex(id uint64, known map[uint64]int64) {
+	if index, ok := known[id]; ok {
+		b.observe(id, &peer.Head{Index: index, Round: -1})
+	}
+}
+
+// get returns the head a peer advertised last
+func (b *headBook) get(id uint64) (peer.Head, bool) {
+	b.RLock()
+	defer b.RUnlock()
+	head, ok := b.heads[id]
+	return head, ok
+}
+
+// caughtUp maps the public keys of the peers which advertised a head to
+// whether the node already has it, given the last index it knows of each
+// participant. The caught up peers most likely have nothing new for the node.
+func (b *headBook) caughtUp(known map[uint64]int64, pubKeys map[uint64]string) map[string]bool {
+	b.RLock()
+	defer b.RUnlock()
+	res := make(map[string]bool)
+	for id, head := range b.heads {
+		if pubKey, ok := pubKeys[id]; ok {
+			index, ok := known[id]
+			res[pubKey] = ok && index >= head.Index
+		}
+	}
+	return res
+}
+
+// caughtUpWith returns the peers the core is caught up with, see caughtUp
+func (b *headBook) caughtUpWith(core *Core) map[string]bool {
+	pubKeys := make(map[uint64]string)
+	for _, p := range core.participants.ToPeerSlice() {
+		pubKeys[p.ID] = p.Message.PubKeyHex
+	}
+	return b.caughtUp(core.KnownEvents(), pubKeys)
+}
+
+// CaughtUpPeers maps the public keys of the peers which advertised a head to
+// whether the node already has it. The smart and fair selectors pick the
+// peers it lacks the head of first.
+func (n *Node) CaughtUpPeers() map[string]bool {
+	return n.heads.caughtUpWith(n.core)
+}
+
+// Heads returns the head of the node, then the last known event of each
+// other participant with the head it advertised last
+func (n *Node) Heads() []Head {
+	store := n.core.poset.Store
+	self := n.core.HexID()
+	var heads []Head
+	for _, p := range n.core.participants.ToPeerSlice() {
+		pubKey := p.Message.PubKeyHex
+		head := Head{ID: p.ID, PubKey: pubKey, Self: pubKey == self}
+		if known, err := lastHead(store, pubKey); err == nil {
+			head.Known = newHeadEvent(known)
+		}
+		if advertised, ok := n.heads.get(p.ID); ok {
+			head.Advertised = newHeadEvent(advertised)
+		}
+		if head.Self {
+			heads = append([]Head{head}, heads...)
+		} else {
+			heads = append(heads, head)
+		}
+	}
+	return heads
+}
+
+// ownHead returns the head the node advertises in its sync responses, nil
+// when it is no participant
+func (n *Node) ownHead() *peer.Head {
+	head, err := lastHead(n.core.poset.Store, n.core.HexID())
+	if err != nil {
+		return nil
+	}
+	return &head
+}
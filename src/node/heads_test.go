@@ -0,0 +1,184 @@
+package node
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// headOf returns the head of a participant in the heads of a node
+func headOf(t *testing.T, n *Node, id uint64) Head {
+	for _, head := range n.Heads() {
+		if head.ID == id {
+			return head
+		}
+	}
+	t.Fatalf("no head of %d", id)
+	return Head{}
+}
+
+func TestHeadsAfterSync(t *testing.T) {
+	data := InitTestData(t, 2, 2)
+
+	trans1 := createTransport(t, data.Logger, data.BackConfig, data.Adds[0],
+		data.PoolSize, data.CreateFu, data.Network.CreateListener)
+	defer transportClose(t, trans1)
+	trans2 := createTransport(t, data.Logger, data.BackConfig, data.Adds[1],
+		data.PoolSize, data.CreateFu, data.Network.CreateListener)
+	defer transportClose(t, trans2)
+
+	node1 := createNode(t, data.Logger, data.Config, data.PeersSlice[0].ID, data.Keys[0], data.Peers, trans1, data.Adds[0], false)
+	defer node1.Shutdown()
+	node2 := createNode(t, data.Logger, data.Config, data.PeersSlice[1].ID, data.Keys[1], data.Peers, trans2, data.Adds[1], false)
+	defer node2.Shutdown()
+
+	addEvent := func(n, other *Node) {
+		n.coreLock.Lock()
+		defer n.coreLock.Unlock()
+		otherHead, _, err := n.core.poset.Store.LastEventFrom(other.core.HexID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.core.AddSelfEventBlock(otherHead); err != nil {
+			t.Fatal(err)
+		}
+	}
+	peer2, _ := node1.core.participants.ReadByID(node2.ID())
+
+	if heads := node1.Heads(); len(heads) != 2 || heads[0].ID != node1.ID() || !heads[0].Self {
+		t.Fatalf("expected the head of the node first, got %+v", heads)
+	}
+	if head := headOf(t, node1, node2.ID()); head.Advertised != nil {
+		t.Fatalf("expected no advertised head before a sync, got %+v", head.Advertised)
+	}
+
+	addEvent(node2, node1)
+	if _, _, err := node1.pull(&peer2); err != nil {
+		t.Fatal(err)
+	}
+	head := headOf(t, node1, node2.ID())
+	// the event 0 of a node is its leaf event
+	if head.Advertised == nil || head.Advertised.Index != 1 {
+		t.Fatalf("expected the advertised head 1, got %+v", head.Advertised)
+	}
+	if head.Known == nil || head.Known.Hash != head.Advertised.Hash || head.Known.Index != 1 {
+		t.Fatalf("expected the advertised head known, got %+v", head.Known)
+	}
+	if !node1.CaughtUpPeers()[peer2.Message.PubKeyHex] {
+		t.Fatal("expected node1 caught up with node2")
+	}
+	// node2 learnt the head of node1 from its request
+	if head := headOf(t, node2, node1.ID()); head.Advertised == nil {
+		t.Fatal("expected node2 to know the head of node1")
+	}
+
+	// a sync without events still tells the head
+	resp, err := node1.requestSync(data.Adds[1], node1.core.KnownEvents())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Events) != 0 || resp.Head == nil || resp.Head.Index != 1 {
+		t.Fatalf("expected no events and the head 1, got %d events and %+v", len(resp.Events), resp.Head)
+	}
+
+	addEvent(node2, node1)
+	resp, err = node1.requestSync(data.Adds[1], node1.core.KnownEvents())
+	if err != nil {
+		t.Fatal(err)
+	}
+	node1.heads.observe(node2.ID(), resp.Head)
+	if head := headOf(t, node1, node2.ID()); head.Advertised.Index != 2 || head.Known.Index != 1 {
+		t.Fatalf("expected the head 2 advertised and 1 known, got %+v %+v", head.Advertised, head.Known)
+	}
+	if node1.CaughtUpPeers()[peer2.Message.PubKeyHex] {
+		t.Fatal("expected node2 to have news for node1")
+	}
+}
+
+// emptySyncs simulates 5 nodes gossiping, each pull making an event, and
+// returns how many of the pulls of the first node, picking its peers with the
+// smart selector, brought no events
+func emptySyncs(preferNews bool) int {
+	const n, steps = 5, 1000
+	participants := peers.NewPeers()
+	var nodes []*peers.Peer
+	pubKeys := make(map[uint64]string)
+	for i := 0; i < n; i++ {
+		key, _ := crypto.GenerateECDSAKey()
+		p := peers.NewPeer(fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)), fakeAddr(i))
+		participants.AddPeer(p)
+		nodes = append(nodes, p)
+		pubKeys[p.ID] = p.Message.PubKeyHex
+	}
+	// known[i] are the last indexes node i knows of each node
+	known := make([]map[uint64]int64, n)
+	for i := range known {
+		known[i] = make(map[uint64]int64)
+		for _, p := range nodes {
+			known[i][p.ID] = -1
+		}
+	}
+	heads := newHeadBook()
+	// pull makes node i learn what node j knows and make an event, the
+	// first node learning the head of i from its request
+	pull := func(i, j int) (empty bool) {
+		if j == 0 {
+			heads.observeIndex(nodes[i].ID, known[i])
+		}
+		empty = true
+		for id, index := range known[j] {
+			if index > known[i][id] {
+				known[i][id] = index
+				empty = false
+			}
+		}
+		known[i][nodes[i].ID]++
+		return empty
+	}
+
+	args := SmartPeerSelectorCreationFnArgs{
+		LocalAddr: nodes[0].Message.NetAddr,
+		GetFlagTable: func() (map[string]int64, error) {
+			return nil, nil
+		},
+	}
+	if preferNews {
+		args.GetCaughtUp = func() map[string]bool {
+			return heads.caughtUp(known[0], pubKeys)
+		}
+	}
+	selector := NewSmartPeerSelector(participants, args)
+
+	r := rand.New(rand.NewSource(1))
+	empty := 0
+	for step := 0; step < steps; step++ {
+		for k := 1; k < n; k++ {
+			i := 1 + r.Intn(n-1)
+			pull(i, (i+1+r.Intn(n-1))%n)
+		}
+		next := selector.Next()
+		j := 0
+		for nodes[j].ID != next.ID {
+			j++
+		}
+		heads.observe(next.ID, &peer.Head{Index: known[j][next.ID]})
+		if pull(0, j) {
+			empty++
+		}
+		selector.UpdateLast(next.Message.NetAddr)
+	}
+	return empty
+}
+
+func TestSelectorPrefersNews(t *testing.T) {
+	without := emptySyncs(false)
+	with := emptySyncs(true)
+	t.Logf("empty syncs: %d picking any peer, %d preferring those with news", without, with)
+	if with >= without {
+		t.Fatalf("expected fewer than %d empty syncs preferring the peers with news, got %d", without, with)
+	}
+}
@@ -0,0 +1,237 @@
+package node
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy"
+)
+
+// idleHeartbeat is the default HeartbeatMax, the gossip period when there is
+// nothing to gossip about
+const idleHeartbeat = time.Second
+
+// peerHealth tracks when each peer last answered or called us, when we last
+// synced and when the last consensus round advanced. The outcome of the last
+// sync with each peer is also kept on the participants for their snapshot.
+// The failures, penalties and bans of the peers are saved in the store, see
+// reputation.go.
+type peerHealth struct {
+	sync.Mutex
+	clock        common.Clock
+	participants *peers.Peers
+	lastSeen     map[uint64]time.Time
+	lastSync     time.Time
+	syncedAt     map[uint64]time.Time // last sync with each peer
+	round        int64
+	roundSince   time.Time
+	penalties    map[uint64]int
+	malformed    map[poset.StructureViolation]int // malformed events received
+	failures     map[uint64]int
+	bannedUntil  map[uint64]time.Time
+	seenBefore   map[uint64]time.Time     // last seen in an earlier run
+	transfers    map[uint64]transferStats // bandwidth of the syncs, see sync_limit.go
+	dirty        bool                     // changed since saved
+	saved        time.Time
+}
+
+func newPeerHealth(participants *peers.Peers, clock common.Clock) *peerHealth {
+	return &peerHealth{
+		clock:        clock,
+		participants: participants,
+		lastSeen:     make(map[uint64]time.Time),
+		syncedAt:     make(map[uint64]time.Time),
+		penalties:    make(map[uint64]int),
+		malformed:    make(map[poset.StructureViolation]int),
+		failures:     make(map[uint64]int),
+		bannedUntil:  make(map[uint64]time.Time),
+		seenBefore:   make(map[uint64]time.Time),
+		transfers:    make(map[uint64]transferStats),
+		round:        -2,
+		roundSince:   clock.Now(),
+		saved:        clock.Now(),
+	}
+}
+
+// seen records a successful exchange with a peer
+func (h *peerHealth) seen(id uint64) {
+	h.Lock()
+	defer h.Unlock()
+	h.lastSeen[id] = h.clock.Now()
+	h.dirty = true
+}
+
+// synced records events pulled from a peer
+func (h *peerHealth) synced(id uint64) {
+	h.Lock()
+	defer h.Unlock()
+	now := h.clock.Now()
+	h.lastSeen[id] = now
+	h.lastSync = now
+	h.syncedAt[id] = now
+	h.dirty = true
+	h.participants.SetLastSyncByID(id, true, now)
+}
+
+// syncFailed records a failed attempt to pull events from a peer
+func (h *peerHealth) syncFailed(id uint64) {
+	h.Lock()
+	defer h.Unlock()
+	h.failures[id]++
+	h.dirty = true
+	h.participants.SetLastSyncByID(id, false, h.clock.Now())
+}
+
+// penalize records a peer sending us events the consensus rules reject
+func (h *peerHealth) penalize(id uint64) {
+	h.Lock()
+	defer h.Unlock()
+	h.penalties[id]++
+	h.dirty = true
+}
+
+// rejectMalformed penalizes a peer sending us an event of a malformed
+// structure, and counts the event by the rule it breaks
+func (h *peerHealth) rejectMalformed(id uint64, v poset.StructureViolation) {
+	h.Lock()
+	defer h.Unlock()
+	h.penalties[id]++
+	h.malformed[v]++
+	h.dirty = true
+}
+
+// malformedEvents returns the number of malformed events received, by the
+// rule they break
+func (h *peerHealth) malformedEvents() map[poset.StructureViolation]int {
+	h.Lock()
+	defer h.Unlock()
+	res := make(map[poset.StructureViolation]int, len(h.malformed))
+	for v, n := range h.malformed {
+		res[v] = n
+	}
+	return res
+}
+
+// totalPenalties returns the number of times peers were penalized
+func (h *peerHealth) totalPenalties() int {
+	h.Lock()
+	defer h.Unlock()
+	res := 0
+	for _, n := range h.penalties {
+		res += n
+	}
+	return res
+}
+
+// observeRound notes the time the last consensus round changed
+func (h *peerHealth) observeRound(round int64) {
+	h.Lock()
+	defer h.Unlock()
+	if round != h.round {
+		h.round = round
+		h.roundSince = h.clock.Now()
+	}
+}
+
+func (h *peerHealth) seenSince(id uint64, since time.Time) bool {
+	h.Lock()
+	defer h.Unlock()
+	return h.lastSeen[id].After(since)
+}
+
+// syncedSince returns the number of peers synced with after since
+func (h *peerHealth) syncedSince(since time.Time) int {
+	h.Lock()
+	defer h.Unlock()
+	res := 0
+	for _, t := range h.syncedAt {
+		if t.After(since) {
+			res++
+		}
+	}
+	return res
+}
+
+// syncedWeightSince returns the weight of the peers synced with after since
+func (h *peerHealth) syncedWeightSince(since time.Time, weight func(uint64) uint64) uint64 {
+	h.Lock()
+	defer h.Unlock()
+	var res uint64
+	for id, t := range h.syncedAt {
+		if t.After(since) {
+			res += weight(id)
+		}
+	}
+	return res
+}
+
+// readySyncWindow is how recent the last sync and the reachable peers must be
+// for the node to be ready
+func (n *Node) readySyncWindow() time.Duration {
+	_, heartbeat := n.heartbeats()
+	return time.Duration(n.conf.ReadyHeartbeats) * heartbeat
+}
+
+// Ready returns nil when the node is gossiping, has synced recently, reaches
+// a supermajority of the participants, has a quorum to create events and sees
+// consensus progress. Otherwise the error says why it is not ready.
+func (n *Node) Ready() error {
+	if state := n.getState(); state != Gossiping {
+		return fmt.Errorf("node is %s", state)
+	}
+
+	n.health.observeRound(n.core.GetLastConsensusRound())
+	now := n.clock.Now()
+	since := now.Add(-n.readySyncWindow())
+
+	n.health.Lock()
+	lastSync, roundSince := n.health.lastSync, n.health.roundSince
+	n.health.Unlock()
+
+	if !lastSync.After(since) {
+		if lastSync.IsZero() {
+			return fmt.Errorf("never synced")
+		}
+		return fmt.Errorf("last synced %s ago", now.Sub(lastSync))
+	}
+
+	// participants count one each unless stakes were given
+	var stake, reachable, count, reachableCount uint64
+	for _, p := range n.core.participants.ToPeerSlice() {
+		up := p.ID == n.id || n.health.seenSince(p.ID, since)
+		stake += p.GetWeight()
+		count++
+		if up {
+			reachable += p.GetWeight()
+			reachableCount++
+		}
+	}
+	if stake == 0 {
+		stake, reachable = count, reachableCount
+	}
+	if superMajority := peers.SuperMajorityOf(stake); reachable < superMajority {
+		return fmt.Errorf("reachable stake %d is below the supermajority %d",
+			reachable, superMajority)
+	}
+
+	if err := n.quorumErr(); err != nil {
+		return err
+	}
+
+	if stalled := now.Sub(roundSince); stalled > n.conf.ReadyRoundWindow {
+		return fmt.Errorf("last consensus round has not advanced for %s", stalled)
+	}
+
+	return nil
+}
+
+// reportReady passes the readiness on to an app proxy exposing it
+func (n *Node) reportReady() {
+	if r, ok := n.proxy.(proxy.ReadyReporter); ok {
+		r.SetReady(n.Ready() == nil)
+	}
+}
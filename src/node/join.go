@@ -0,0 +1,119 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// RequestJoinInfo asks a running peer for its participants, anchor block and
+// frame, the events of the frame fetched in pages of the default size. It
+// only needs a transport, so it can run before the Node exists. The peer must
+// share our consensus parameters.
+func RequestJoinInfo(trans peer.SyncPeer, target string, fromID uint64,
+	consensus poset.ConsensusParams) (*peer.FastForwardResponse, error) {
+	args := &peer.FastForwardRequest{FromID: fromID, ConsensusHash: consensus.Hash(), Paged: true}
+	out := &peer.FastForwardResponse{}
+	if err := trans.FastForward(context.Background(), target, args, out); err != nil {
+		return nil, err
+	}
+	if out.ConsensusHash != args.ConsensusHash {
+		err := &ConsensusMismatchError{
+			PeerID: out.FromID,
+			Ours:   args.ConsensusHash,
+			Theirs: out.ConsensusHash,
+		}
+		if out.ConsensusParams != nil {
+			err.Diff = consensus.Diff(*out.ConsensusParams)
+		}
+		return nil, err
+	}
+	if len(out.Participants) == 0 {
+		return nil, fmt.Errorf("peer %s returned no participants", target)
+	}
+	if err := fetchFrameEvents(trans, target, fromID, out, defaultFramePageEvents, defaultSyncMaxBytes); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VerifyJoinInfo checks the response of RequestJoinInfo against participants
+// trusted beforehand, such as those of peers.json. The participants the join
+// peer returned are no proof of their own, it could make them up together
+// with the keys signing its anchor block. More than the trust count of the
+// trusted participants must have signed the block, and the frame the block
+// commits to must have a root for each returned participant and events of
+// returned participants only.
+func VerifyJoinInfo(resp *peer.FastForwardResponse, trusted *peers.Peers) error {
+	if signed := resp.Block.SignedStake(trusted); signed <= trusted.GetTrustCount() {
+		return fmt.Errorf("anchor block %d is signed by %d of the trusted participants, need %d",
+			resp.Block.Index(), signed, trusted.GetTrustCount()+1)
+	}
+	frameHash, err := resp.Frame.Hash()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(resp.Block.GetFrameHash(), frameHash) {
+		return fmt.Errorf("frame of anchor block %d does not match its frame hash",
+			resp.Block.Index())
+	}
+	participants := NewPeersFromJoinInfo(resp)
+	if len(resp.Frame.Roots) != participants.Len() {
+		return fmt.Errorf("frame of anchor block %d has %d roots for %d participants",
+			resp.Block.Index(), len(resp.Frame.Roots), participants.Len())
+	}
+	for _, msg := range resp.Frame.Events {
+		ev := msg.ToEvent()
+		if _, ok := participants.ReadByPubKey(ev.GetCreator()); !ok {
+			return fmt.Errorf("frame of anchor block %d has events of %s, not a participant",
+				resp.Block.Index(), ev.GetCreator())
+		}
+	}
+	return nil
+}
+
+// Join bootstraps a brand-new node from the response of RequestJoinInfo,
+// checked by VerifyJoinInfo.
+// The anchor block signatures are checked against TrustCount before the
+// poset is reset, and the app is restored from the snapshot of the anchor
+// block, asked for to the same peer.
+func (n *Node) Join(resp *peer.FastForwardResponse) error {
+	from, ok := n.core.participants.ReadByID(resp.FromID)
+	if !ok {
+		return fmt.Errorf("join peer %d is not a participant", resp.FromID)
+	}
+
+	n.logger.WithFields(logrus.Fields{
+		"from_id":              resp.FromID,
+		"block_index":          resp.Block.Index(),
+		"block_round_received": resp.Block.RoundReceived(),
+		"frame_events":         len(resp.Frame.Events),
+	}).Debug("Join")
+
+	if err := n.applyFastForward(&from, resp, nil); err != nil {
+		return err
+	}
+
+	if n.core.IsObserver() {
+		n.logger.WithFields(logrus.Fields{
+			"pub_key":  n.core.HexID(),
+			"net_addr": n.localAddr,
+		}).Warn("Running in observer mode: this key is not a participant. " +
+			"Ask an existing participant to submit a PEER_ADD internal " +
+			"transaction with this pub_key and net_addr, then restart " +
+			"this node with --join once the peer has been added")
+	}
+
+	return nil
+}
+
+// NewPeersFromJoinInfo builds the participant set returned by a join peer
+func NewPeersFromJoinInfo(resp *peer.FastForwardResponse) *peers.Peers {
+	return peers.NewPeersFromMessageSlice(resp.Participants)
+}
@@ -0,0 +1,65 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// joinInfo makes the join info of an anchor block of the participants, signed
+// by the keys
+func joinInfo(t *testing.T, participants *peers.Peers,
+	keys []*ecdsa.PrivateKey) *peer.FastForwardResponse {
+	resp := &peer.FastForwardResponse{}
+	for _, p := range participants.ToPeerSlice() {
+		root := poset.NewBaseRoot(p.ID)
+		resp.Frame.Roots = append(resp.Frame.Roots, &root)
+		resp.Participants = append(resp.Participants, p.Message)
+	}
+	resp.Frame.Round = 1
+
+	block, err := poset.NewBlockFromFrame(1, resp.Frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		sig, err := block.Sign(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := block.SetSignature(sig); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp.Block = block
+	return resp
+}
+
+func TestVerifyJoinInfo(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+
+	if err := VerifyJoinInfo(joinInfo(t, data.Peers, data.Keys[:2]), data.Peers); err != nil {
+		t.Fatalf("expected the join info to be verified, got %v", err)
+	}
+
+	// a join peer making up the participants and signing for them all
+	other := InitTestData(t, 4, 2)
+	if err := VerifyJoinInfo(joinInfo(t, other.Peers, other.Keys), data.Peers); err == nil {
+		t.Fatal("expected the participants made up by the join peer to be refused")
+	}
+
+	// not more than the trust count of the trusted participants
+	if err := VerifyJoinInfo(joinInfo(t, data.Peers, data.Keys[:1]), data.Peers); err == nil {
+		t.Fatal("expected an anchor block signed by one participant to be refused")
+	}
+
+	// a participant added by the join peer, who has no root in the frame
+	resp := joinInfo(t, data.Peers, data.Keys)
+	resp.Participants = append(resp.Participants, other.PeersSlice[0].Message)
+	if err := VerifyJoinInfo(resp, data.Peers); err == nil {
+		t.Fatal("expected a participant with no root to be refused")
+	}
+}
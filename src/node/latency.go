@@ -0,0 +1,108 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+var (
+	// finalityBuckets are the bounds, in seconds, of the histograms of the
+	// time from event creation to consensus
+	finalityBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+	// roundsBuckets are the bounds of the histograms of the frames between
+	// the creation of an event and its consensus
+	roundsBuckets = []float64{0, 1, 2, 3, 4, 5, 8, 13, 21}
+)
+
+// CreatorLatency is how long the events of a creator took to reach
+// consensus. Finality is measured with the creator's clock, so it is skewed
+// by the difference between the clocks; RoundsToFinality is not.
+type CreatorLatency struct {
+	Finality         common.HistogramSnapshot `json:"finality_seconds"`
+	RoundsToFinality common.HistogramSnapshot `json:"rounds_to_finality"`
+}
+
+type creatorHistograms struct {
+	finality *common.Histogram
+	rounds   *common.Histogram
+}
+
+// latencyStats aggregates per creator how long events take to reach consensus
+type latencyStats struct {
+	sync.Mutex
+	creators map[string]*creatorHistograms
+	now      func() time.Time
+}
+
+func newLatencyStats() *latencyStats {
+	return &latencyStats{
+		creators: make(map[string]*creatorHistograms),
+		now:      time.Now,
+	}
+}
+
+// observe is the consensus listener of the poset
+func (l *latencyStats) observe(ev poset.Event) {
+	h := l.histograms(ev.GetCreator())
+	if rounds := ev.FrameReceived - ev.Frame; rounds >= 0 {
+		h.rounds.Observe(float64(rounds))
+	}
+	if created := ev.Timestamp(); !created.IsZero() {
+		latency := l.now().Sub(created).Seconds()
+		if latency < 0 {
+			// the creator's clock is ahead of ours
+			latency = 0
+		}
+		h.finality.Observe(latency)
+	}
+}
+
+func (l *latencyStats) histograms(creator string) *creatorHistograms {
+	l.Lock()
+	defer l.Unlock()
+	h, ok := l.creators[creator]
+	if !ok {
+		h = &creatorHistograms{
+			finality: common.NewHistogram(finalityBuckets),
+			rounds:   common.NewHistogram(roundsBuckets),
+		}
+		l.creators[creator] = h
+	}
+	return h
+}
+
+// snapshot returns the histograms by creator public key
+func (l *latencyStats) snapshot() map[string]CreatorLatency {
+	l.Lock()
+	defer l.Unlock()
+	res := make(map[string]CreatorLatency, len(l.creators))
+	for creator, h := range l.creators {
+		res[creator] = CreatorLatency{
+			Finality:         h.finality.Snapshot(),
+			RoundsToFinality: h.rounds.Snapshot(),
+		}
+	}
+	return res
+}
+
+// means returns the mean finality and rounds to finality of all creators
+func (l *latencyStats) means() (finality, rounds float64) {
+	var finalitySum, roundsSum float64
+	var finalityCount, roundsCount uint64
+	for _, c := range l.snapshot() {
+		finalitySum += c.Finality.Sum
+		finalityCount += c.Finality.Count
+		roundsSum += c.RoundsToFinality.Sum
+		roundsCount += c.RoundsToFinality.Count
+	}
+	if finalityCount > 0 {
+		finality = finalitySum / float64(finalityCount)
+	}
+	if roundsCount > 0 {
+		rounds = roundsSum / float64(roundsCount)
+	}
+	return finality, rounds
+}
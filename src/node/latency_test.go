@@ -0,0 +1,72 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// consensusEvent is an event of creator made in frame and received in
+// frame+rounds, created at created
+func consensusEvent(creator string, frame, rounds int64, created time.Time) poset.Event {
+	ev := poset.NewEvent(nil, nil, nil, make(poset.EventHashes, 2), []byte(creator), 0,
+		poset.NewFlagTable(), poset.NewFlagTable(), frame, false)
+	ev.FrameReceived = frame + rounds
+	if !created.IsZero() {
+		ev.SetTimestamp(created)
+	}
+	return ev
+}
+
+func TestLatencyStats(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	l := newLatencyStats()
+	l.now = func() time.Time { return now }
+
+	// the throttled creator takes 3 frames and 4 seconds to reach consensus,
+	// the others 1 frame and 1 second
+	creators := []string{"fast0", "fast1", "throttled"}
+	for frame := int64(1); frame <= 10; frame++ {
+		for _, c := range creators {
+			rounds, latency := int64(1), time.Second
+			if c == "throttled" {
+				rounds, latency = 3, 4*time.Second
+			}
+			l.observe(consensusEvent(c, frame, rounds, now.Add(-latency)))
+		}
+	}
+	// old clients do not stamp their events
+	l.observe(consensusEvent("fast0", 11, 1, time.Time{}))
+
+	stats := l.snapshot()
+	if len(stats) != len(creators) {
+		t.Fatalf("expected %d creators, got %d", len(creators), len(stats))
+	}
+	creatorKey := func(c string) string {
+		return fmt.Sprintf("0x%X", []byte(c))
+	}
+	throttled := stats[creatorKey("throttled")]
+	for _, c := range creators[:2] {
+		fast := stats[creatorKey(c)]
+		if throttled.RoundsToFinality.Mean() <= fast.RoundsToFinality.Mean() {
+			t.Fatalf("expected the throttled creator to take more rounds than %s: %v <= %v",
+				c, throttled.RoundsToFinality.Mean(), fast.RoundsToFinality.Mean())
+		}
+		if throttled.Finality.Mean() <= fast.Finality.Mean() {
+			t.Fatalf("expected the throttled creator to be slower than %s: %v <= %v",
+				c, throttled.Finality.Mean(), fast.Finality.Mean())
+		}
+	}
+	if fast0 := stats[creatorKey("fast0")]; fast0.RoundsToFinality.Count != 11 || fast0.Finality.Count != 10 {
+		t.Fatalf("expected 11 rounds and 10 finality observations, got %d and %d",
+			fast0.RoundsToFinality.Count, fast0.Finality.Count)
+	}
+
+	// a creator clock ahead of ours does not make negative latencies
+	l.observe(consensusEvent("ahead", 1, 1, now.Add(time.Minute)))
+	if ahead := l.snapshot()[creatorKey("ahead")]; ahead.Finality.Sum != 0 {
+		t.Fatalf("expected a zero latency, got %v", ahead.Finality.Sum)
+	}
+}
@@ -7,19 +7,28 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/log"
 	"github.com/SamuelMarks/dag1/src/peer"
 	"github.com/SamuelMarks/dag1/src/peers"
 	"github.com/SamuelMarks/dag1/src/poset"
 	"github.com/SamuelMarks/dag1/src/proxy"
+	"github.com/SamuelMarks/dag1/src/proxy/proto"
 )
 
+// maxCreatorLookups bounds the number of unknown creators looked up for a
+// single batch of synced events
+const maxCreatorLookups = 4
+
 // Node struct that keeps all high level node functions
 type Node struct {
 	*nodeState2
@@ -37,22 +46,72 @@ type Node struct {
 
 	trans peer.SyncPeer
 	proxy proxy.AppProxy
+	// proxyAcks is true when the proxy reports the blocks the app
+	// acknowledged
+	proxyAcks bool
+
+	// consensus are the consensus parameters, peers must send their hash
+	consensus     poset.ConsensusParams
+	consensusHash common.Hash
 
 	submitCh         chan []byte
+	submitResultCh   chan txSubmission
+	submitFlaggedCh  chan proto.FlaggedTx
 	submitInternalCh chan poset.InternalTransaction
 	commitCh         chan poset.Block
 	shutdownCh       chan struct{}
 	signalTERMch     chan os.Signal
 
 	controlTimer *ControlTimer
+	clock        common.Clock
+	// runtime are the settings Reconfigure changes, see RuntimeConfig
+	runtime runtimeSettings
 
 	start        time.Time
 	syncRequests int
 	syncErrors   int
 
 	needBoostrap bool
+
+	// consensusCh queues the hints of the consensus worker, which runs
+	// consensusPass, see runConsensus
+	consensusCh   chan consensusHint
+	consensusPass func() error
+	// batchLock is held while a batch of events is inserted and while a
+	// consensus pass runs, so that a pass never sees a half inserted batch.
+	// It is taken before coreLock.
+	batchLock sync.Mutex
+	// background starts the workers of Run once, however many times it is
+	// called
+	background   sync.Once
 	gossipJobs   count64
 	rpcJobs      count64
+
+	health  *peerHealth
+	latency *latencyStats
+	rounds  *roundReport
+	stall   *stallWatchdog
+	tick    *tickWatchdog
+	flow    *flowControl
+	addrs   *addrBook
+	// eventVersions are the event versions the peers advertised
+	eventVersions *eventVersions
+	// heads are the heads the peers advertised
+	heads *headBook
+	// sent are the events sent to each peer lately
+	sent *sentEvents
+	// frames is the last frame whose events were paged out to a peer
+	frames *servedFrame
+	// emptyEvent creates the empty events of the tick watchdog, see
+	// checkTick
+	emptyEvent func() (poset.EventHash, error)
+
+	pauseLock sync.Mutex
+	pauseCh   chan struct{} // closed when Pause is called
+	resumeCh  chan struct{} // non-nil while paused, closed by Resume
+	drainedCh chan struct{} // closed once in-flight work is drained
+
+	fastForwards fastForwardJobs
 }
 
 // NewNode create a new node struct
@@ -65,41 +124,117 @@ func NewNode(conf *Config,
 	proxy proxy.AppProxy,
 	selectorInitFunc SelectorCreationFn,
 	selectorInitArgs SelectorCreationFnArgs,
-	localAddr string) *Node {
+	localAddr string) (*Node, error) {
+
+	clock := conf.Clock
+	if clock == nil {
+		clock = common.RealClock
+	}
 
 	commitCh := make(chan poset.Block, 400)
-	core := NewCore(id, key, participants, store, commitCh, conf.Logger)
+	core, err := NewCore(id, key, participants, store, commitCh, conf.Logger)
+	if err != nil {
+		return nil, err
+	}
+	core.clock = clock
+	core.poset.SetClock(clock)
+	if conf.Observer {
+		core.observer = true
+	}
+	core.transactionPool = newTxPool(conf.TxPoolSize, conf.TxPoolBytes, conf.TxPoolPolicy)
+	core.otherParents = NewOtherParentSelector(conf.OtherParentSelector)
 
 	pubKey := core.HexID()
 
-	if args, ok := selectorInitArgs.(SmartPeerSelectorCreationFnArgs); ok {
+	heads := newHeadBook()
+	caughtUp := func() map[string]bool {
+		return heads.caughtUpWith(core)
+	}
+	switch args := selectorInitArgs.(type) {
+	case SmartPeerSelectorCreationFnArgs:
 		args.GetFlagTable = core.poset.GetPeerFlagTableOfRandomUndeterminedEvent
+		args.GetCaughtUp = caughtUp
 		args.LocalAddr = localAddr
 		selectorInitArgs = args
+	case FairPeerSelectorCreationFnArgs:
+		args.GetCaughtUp = caughtUp
+		selectorInitArgs = args
 	}
 
-	peerSelector := selectorInitFunc(participants, selectorInitArgs)
+	health := newPeerHealth(participants, clock)
+	peerSelector := newBanFilter(
+		selectorInitFunc(participants, selectorInitArgs), health, localAddr)
 
 	node := Node{
 		id:               id,
 		conf:             conf,
 		core:             core,
-		logger:           conf.Logger.WithField("this_id", id),
+		logger:           dag1_log.ForModule(conf.Logger, "node").WithField("this_id", id),
 		peerSelector:     peerSelector,
 		trans:            trans,
 		proxy:            proxy,
 		submitCh:         proxy.SubmitCh(),
+		submitFlaggedCh:  proxy.SubmitFlaggedCh(),
 		submitInternalCh: proxy.SubmitInternalCh(),
+		submitResultCh:   make(chan txSubmission),
 		commitCh:         commitCh,
+		consensusCh:      make(chan consensusHint, consensusQueueSize),
+		consensusPass:    core.RunConsensus,
 		shutdownCh:       make(chan struct{}),
-		controlTimer:     NewRandomControlTimer(),
-		start:            time.Now(),
+		pauseCh:          make(chan struct{}),
+		controlTimer:     NewRandomControlTimer(clock),
+		clock:            clock,
+		start:            clock.Now(),
 		gossipJobs:       0,
 		rpcJobs:          0,
+		health:           health,
+		latency:          newLatencyStats(),
+		rounds:           newRoundReport(),
+		frames:           &servedFrame{},
+		stall:            newStallWatchdog(conf.StallWarnTimeout),
+		tick:             newTickWatchdog(conf.TickStallTimeout, conf.TickInterval),
+		flow:             newFlowControl(conf.FlowControlSkew, conf.FlowControlMaxFactor),
+		addrs:            newAddrBook(),
+		eventVersions:    newEventVersions(),
+		heads:            heads,
+		sent:             newSentEvents(),
 		nodeState2:       newNodeState2(),
 		signalTERMch:     make(chan os.Signal, 1),
 		localAddr:        localAddr,
+		consensus:        conf.Consensus(),
+		consensusHash:    conf.Consensus().Hash(),
+	}
+	node.runtime.heartbeat = conf.HeartbeatTimeout
+	node.runtime.heartbeatMax = conf.HeartbeatMax
+	node.runtime.syncLimit = conf.SyncLimit
+	node.runtime.metrics = conf.Metrics
+
+	node.emptyEvent = node.CreateEmptyEvent
+	if tr, ok := trans.(*peer.Peer); ok {
+		tr.SetVersionHandler(node.learnPeerVersion)
+	}
+	core.quorum = node.HasQuorum
+	core.eventVersion = node.EventVersion
+	core.inserted = node.rounds.insert
+	node.watchAcks()
+
+	core.poset.SetConsensusListener(func(ev poset.Event) {
+		node.latency.observe(ev)
+		node.rounds.consensus(ev)
+	})
+	core.poset.SetDecisionListener(node.rounds.decided)
+	core.poset.SetUndeterminedAges(conf.UndeterminedWarnAge, conf.UndeterminedArchiveAge)
+	core.poset.SetConsensusParams(node.consensus)
+	if node.consensus.DevSingleSuperMajority {
+		node.logger.Warn("UNSAFE: any single participant is a supermajority, for development networks only")
+	}
+	core.poset.SetCacheBudget(conf.CacheBudget)
+	if err := core.poset.SetCacheTriggers(conf.CacheGrowBelow, conf.CacheShrinkAbove); err != nil {
+		node.logger.WithError(err).Warn("Keeping the default cache triggers")
 	}
+	core.poset.SetNetworkID(conf.NetworkID, conf.NetworkIDCompat)
+	// the roots are decided by the consensus worker, not by the syncs
+	core.poset.SetRootQueue(true)
 
 	signal.Notify(node.signalTERMch, syscall.SIGTERM, os.Kill)
 
@@ -111,7 +246,7 @@ func NewNode(conf *Config,
 	// Initialize
 	node.setState(Gossiping)
 
-	return &node
+	return &node, nil
 }
 
 // Init initializes all the node processes
@@ -127,9 +262,19 @@ func (n *Node) Init() error {
 		if err := n.core.Bootstrap(); err != nil {
 			return err
 		}
+		if _, err := n.VerifyCaches(); err != nil {
+			return err
+		}
 	}
 	n.Register()
 
+	if err := n.loadReputations(); err != nil {
+		return err
+	}
+	if err := n.initAddresses(); err != nil {
+		return err
+	}
+
 	return n.core.SetHeadAndHeight()
 }
 
@@ -141,22 +286,33 @@ func (n *Node) RunAsync(gossip bool) {
 
 // Run core run loop, takes care of all processes
 func (n *Node) Run(gossip bool) {
-	// The ControlTimer allows the background routines to control the
-	// heartbeat timer when the node is in the Gossiping state. The timer should
-	// only be running when there are uncommitted transactions in the system.
-	go n.controlTimer.Run(n.conf.HeartbeatTimeout)
-
-	// Execute some background work regardless of the state of the node.
-	// Process SubmitTx and CommitBlock requests
-	go n.doBackgroundWork()
+	n.background.Do(func() {
+		// The ControlTimer allows the background routines to control the
+		// heartbeat timer when the node is in the Gossiping state. The timer
+		// should only be running when there are uncommitted transactions in
+		// the system.
+		go n.controlTimer.Run(n.heartbeat())
+
+		// Execute some background work regardless of the state of the node.
+		// Process SubmitTx and CommitBlock requests. A second worker would
+		// commit the blocks out of order.
+		go n.doBackgroundWork()
+
+		// Run the consensus passes asked for by the inserts
+		go n.runConsensus()
+	})
 
 	// pause before gossiping test transactions to allow all nodes come up
-	time.Sleep(time.Duration(n.conf.TestDelay) * time.Second)
+	n.clock.Sleep(time.Duration(n.conf.TestDelay) * time.Second)
 
 	// Execute Node State Machine
 	for {
 		// Run different routines depending on node state
 		state := n.getState()
+		if state != Shutdown && n.IsPaused() {
+			// an in-flight gossip may have switched to CatchingUp
+			state = Paused
+		}
 		n.logger.WithField("state", state.String()).Debug("Run(gossip bool)")
 
 		switch state {
@@ -168,6 +324,8 @@ func (n *Node) Run(gossip bool) {
 			}
 		case Stop:
 			// do nothing in Stop state
+		case Paused:
+			n.paused()
 		case Shutdown:
 			return
 		}
@@ -176,38 +334,63 @@ func (n *Node) Run(gossip bool) {
 
 func (n *Node) resetTimer() {
 	if !n.controlTimer.GetSet() {
-		ts := n.conf.HeartbeatTimeout
+		ts := n.heartbeat()
 		// Slow gossip if nothing interesting to say
 		if n.core.poset.GetPendingLoadedEvents() == 0 &&
 			n.core.GetTransactionPoolCount() == 0 &&
 			n.core.GetBlockSignaturePoolCount() == 0 {
-			ts = time.Duration(time.Second)
+			_, ts = n.heartbeats()
 		}
-		n.controlTimer.resetCh <- ts
+		n.controlTimer.reset(ts)
 	}
 }
 
 func (n *Node) doBackgroundWork() {
+	// the blocks of the last run come before the new ones
+	if err := n.redeliverJournalled(); err != nil {
+		n.logger.WithError(err).Error("Delivering the journalled blocks")
+	}
+
 	for {
+		// while paused, transactions queue in the pool up to PauseQueueSize,
+		// beyond that submitters block until Resume
+		submitCh, submitFlaggedCh, submitResultCh := n.submitCh, n.submitFlaggedCh, n.submitResultCh
+		resumeCh := n.pausedResumeCh()
+		if resumeCh != nil && n.core.GetTransactionPoolCount() >= int64(n.conf.PauseQueueSize) {
+			submitCh, submitFlaggedCh, submitResultCh = nil, nil, nil
+		}
 		select {
-		case t := <-n.submitCh:
+		case <-resumeCh:
+		case s := <-submitResultCh:
+			s.result <- n.core.addTransactionsFrom(sourceService, [][]byte{s.tx}, []byte{0})
+			n.resetTimer()
+		case t := <-submitCh:
 			n.logger.Debug("Adding Transactions to Transaction Pool")
 			err := n.addTransaction(t)
 			if err != nil {
 				n.logger.Errorf("Adding Transactions to Transaction Pool: %s", err)
 			}
 			n.resetTimer()
+		case t := <-submitFlaggedCh:
+			n.logger.Debug("Adding Flagged Transaction to Transaction Pool")
+			err := n.addTransactionWithFlags(t.Tx, t.Flags)
+			if err != nil {
+				n.logger.Errorf("Adding Flagged Transaction to Transaction Pool: %s", err)
+			}
+			n.resetTimer()
 		case t := <-n.submitInternalCh:
 			n.logger.Debug("Adding Internal Transaction")
 			n.addInternalTransaction(t)
 			n.resetTimer()
 		case block := <-n.commitCh:
+			blocks := n.queuedBlocks(block)
 			n.logger.WithFields(logrus.Fields{
 				"index":          block.Index(),
+				"blocks":         len(blocks),
 				"round_received": block.RoundReceived(),
 				"transactions":   len(block.Transactions()),
 			}).Debug("Adding EventBlock")
-			if err := n.commit(block); err != nil {
+			if err := n.commitBlocks(blocks); err != nil {
 				n.logger.WithField("error", err).Error("Adding EventBlock")
 			}
 		case <-n.shutdownCh:
@@ -224,6 +407,9 @@ func (n *Node) doBackgroundWork() {
 // is something to gossip about, or waits.
 func (n *Node) dag1(gossip bool) {
 	returnCh := make(chan struct{}, 100)
+	n.pauseLock.Lock()
+	pauseCh := n.pauseCh
+	n.pauseLock.Unlock()
 	for {
 		select {
 		case rpc, ok := <-n.trans.ReceiverChannel():
@@ -239,6 +425,11 @@ func (n *Node) dag1(gossip bool) {
 			})
 		case <-n.controlTimer.tickCh:
 			n.logStats()
+			n.reportReady()
+			n.checkStall()
+			n.checkTick()
+			n.checkFlow()
+			n.saveReputations(false)
 			if gossip && n.gossipJobs.get() < 1 {
 				n.goFunc(func() {
 					n.gossipJobs.increment()
@@ -252,6 +443,8 @@ func (n *Node) dag1(gossip bool) {
 			n.resetTimer()
 		case <-returnCh:
 			return
+		case <-pauseCh:
+			return
 		case <-n.shutdownCh:
 			return
 		}
@@ -261,6 +454,12 @@ func (n *Node) dag1(gossip bool) {
 func (n *Node) processRPC(rpc *peer.RPC) {
 	logger := n.logger.WithFields(logrus.Fields{"method": "processRPC",
 		"cmd": rpc.Command})
+	if id, ok := requesterID(rpc.Command); ok && n.health.banned(id) {
+		logger.WithField("from_id", id).Debug("Refused the request of a banned peer")
+		// TODO: context.Background
+		rpc.SendResult(context.Background(), n.logger, nil, peer.ErrRateLimited)
+		return
+	}
 	switch cmd := rpc.Command.(type) {
 	case *peer.SyncRequest:
 		n.processSyncRequest(rpc, cmd)
@@ -268,6 +467,12 @@ func (n *Node) processRPC(rpc *peer.RPC) {
 		n.processEagerSyncRequest(rpc, cmd)
 	case *peer.FastForwardRequest:
 		n.processFastForwardRequest(rpc, cmd)
+	case *peer.PeerLookupRequest:
+		n.processPeerLookupRequest(rpc, cmd)
+	case *peer.SnapshotRequest:
+		n.processSnapshotRequest(rpc, cmd)
+	case *peer.FrameEventsRequest:
+		n.processFrameEventsRequest(rpc, cmd)
 	default:
 		logger.Warn("unexpected RPC command")
 		// TODO: context.Background
@@ -281,15 +486,35 @@ func (n *Node) processSyncRequest(rpc *peer.RPC, cmd *peer.SyncRequest) {
 		"from_id": cmd.FromID,
 		"known":   cmd.Known,
 	}).Debug("processSyncRequest(rpc net.RPC, cmd *net.SyncRequest)")
-
+	if err := n.checkNetwork(cmd.FromID, cmd.NetworkID); err != nil {
+		// TODO: context.Background
+		rpc.SendResult(context.Background(), n.logger, nil, err)
+		return
+	}
 	resp := &peer.SyncResponse{
-		FromID: n.id,
+		FromID:        n.id,
+		ConsensusHash: n.consensusHash,
+		EventVersion:  poset.SupportedEventVersions.Max,
+		Head:          n.ownHead(),
+	}
+	if err := n.checkConsensus(cmd.FromID, cmd.ConsensusHash, nil); err != nil {
+		// the requester tells what differs
+		resp.ConsensusParams = &n.consensus
+		// TODO: context.Background
+		rpc.SendResult(context.Background(), n.logger, resp, nil)
+		return
 	}
+	n.health.seen(cmd.FromID)
+	n.heads.observeIndex(cmd.FromID, cmd.Known)
+	n.learnEventVersion(cmd.FromID, cmd.EventVersion)
+	n.core.AddCheckpointSignatures(cmd.Checkpoints)
+	n.learnAddresses(cmd.Addresses)
+
 	var respErr error
 
 	// Check sync limit
 	n.coreLock.Lock()
-	overSyncLimit := n.core.OverSyncLimit(cmd.Known, n.conf.SyncLimit)
+	overSyncLimit := n.core.OverSyncLimit(cmd.Known, n.syncLimit())
 	n.coreLock.Unlock()
 	if overSyncLimit {
 		n.logger.Debug("n.core.OverSyncLimit(cmd.Known, n.conf.SyncLimit)")
@@ -307,6 +532,15 @@ func (n *Node) processSyncRequest(rpc *peer.RPC, cmd *peer.SyncRequest) {
 			respErr = err
 		}
 
+		// the events the peer has, or was just sent, are not sent again and
+		// the rest of the diff goes in the next syncs
+		eventDiff = n.sent.take(cmd.FromID, cmd.Known, eventDiff, n.clock.Now(),
+			func(events []poset.Event) []poset.Event {
+				events, resp.Bytes = budgetEvents(events, cmd.Limit,
+					n.responseMaxBytes(cmd.MaxBytes), n.core.poset.WireSize)
+				return events
+			})
+
 		// Convert to WireEvents
 		wireEvents, err := n.core.ToWire(eventDiff)
 		if err != nil {
@@ -322,6 +556,8 @@ func (n *Node) processSyncRequest(rpc *peer.RPC, cmd *peer.SyncRequest) {
 	knownEvents := n.core.KnownEvents()
 	n.coreLock.Unlock()
 	resp.Known = knownEvents
+	resp.Checkpoints = n.core.CheckpointSignatures()
+	resp.Addresses = n.addrs.announcements()
 
 	n.logger.WithFields(logrus.Fields{
 		"events":     len(resp.Events),
@@ -351,6 +587,7 @@ func (n *Node) processEagerSyncRequest(rpc *peer.RPC, cmd *peer.ForceSyncRequest
 		"from_id": cmd.FromID,
 		"events":  len(cmd.Events),
 	}).Debug("processEagerSyncRequest(rpc net.RPC, cmd *net.ForceSyncRequest)")
+	n.health.seen(cmd.FromID)
 
 
 	resp := &peer.ForceSyncResponse{
@@ -360,9 +597,11 @@ func (n *Node) processEagerSyncRequest(rpc *peer.RPC, cmd *peer.ForceSyncRequest
 	// TODO: context.Background
 	rpc.SendResult(context.Background(), n.logger, resp, nil)
 
+	n.batchLock.Lock()
 	n.coreLock.Lock()
 	err = n.sync(&p, cmd.Events)
 	n.coreLock.Unlock()
+	n.batchLock.Unlock()
 
 	if err != nil {
 		n.logger.WithField("error", err).Error("n.sync(cmd.Events)")
@@ -376,13 +615,27 @@ func (n *Node) processFastForwardRequest(rpc *peer.RPC, cmd *peer.FastForwardReq
 	}).Debug("processFastForwardRequest(rpc net.RPC, cmd *net.FastForwardRequest)")
 
 	resp := &peer.FastForwardResponse{
-		FromID: n.id,
+		FromID:        n.id,
+		ConsensusHash: n.consensusHash,
+	}
+	if err := n.checkConsensus(cmd.FromID, cmd.ConsensusHash, nil); err != nil {
+		resp.ConsensusParams = &n.consensus
+		// TODO: context.Background
+		rpc.SendResult(context.Background(), n.logger, resp, nil)
+		return
+	}
+	for _, p := range n.core.participants.ToPeerSlice() {
+		resp.Participants = append(resp.Participants, p.Message)
 	}
 	var respErr error
 
 	// Get latest Frame
 	n.coreLock.Lock()
 	block, frame, err := n.core.GetAnchorBlockWithFrame()
+	var carry [][]byte
+	if err == nil {
+		carry, err = n.core.poset.Store.BlockCarry(block.RoundReceived())
+	}
 	n.coreLock.Unlock()
 	if err != nil {
 		n.logger.WithField("error", err).Error("n.core.GetAnchorBlockWithFrame()")
@@ -390,19 +643,19 @@ func (n *Node) processFastForwardRequest(rpc *peer.RPC, cmd *peer.FastForwardReq
 	} else {
 		resp.Block = block
 		resp.Frame = frame
-
-		// Get snapshot
-		snapshot, err := n.proxy.GetSnapshot(block.Index())
-		if err != nil {
-			n.logger.WithField("error", err).Error("n.proxy.GetSnapshot(block.Index())")
-			respErr = err
+		resp.BlockCarry = carry
+		if cmd.Paged {
+			if err := n.pageFrame(resp); err != nil {
+				n.logger.WithField("error", err).Error("n.pageFrame(resp)")
+				respErr = err
+			}
 		}
-		resp.Snapshot = snapshot
 	}
 
 	n.logger.WithFields(logrus.Fields{
-		"Events": len(resp.Frame.Events),
-		"Error":  respErr,
+		"Events":      len(resp.Frame.Events),
+		"FrameEvents": resp.FrameEvents,
+		"Error":       respErr,
 	}).Debug("FastForwardRequest Received")
 	// TODO: context.Background
 	rpc.SendResult(context.Background(), n.logger, resp, respErr)
@@ -432,10 +685,12 @@ func (n *Node) gossip(parentReturnCh chan struct{}) error {
 		return nil
 	}
 
-	// push
-	err = n.push(peer.Message.NetAddr, otherKnownEvents)
-	if err != nil {
-		return err
+	// push; observers have nothing of their own to offer
+	if !n.core.IsObserver() {
+		err = n.push(peer.Message.NetAddr, otherKnownEvents)
+		if err != nil {
+			return err
+		}
 	}
 
 	// update peer selector
@@ -461,6 +716,7 @@ func (n *Node) pull(peer *peers.Peer) (syncLimit bool, otherKnownEvents map[uint
 	// 	}
 	if err != nil {
 		n.logger.WithField("Error", err).Error("n.requestSync(peer.NetAddr, knownEvents)")
+		n.health.syncFailed(peer.ID)
 		return resp.SyncLimit, nil, err
 	}
 	n.logger.WithFields(logrus.Fields{
@@ -471,29 +727,53 @@ func (n *Node) pull(peer *peers.Peer) (syncLimit bool, otherKnownEvents map[uint
 		"knownEvents": knownEvents,
 	}).Debug("SyncResponse")
 
+	if err := n.checkConsensus(resp.FromID, resp.ConsensusHash, resp.ConsensusParams); err != nil {
+		n.health.syncFailed(peer.ID)
+		return false, nil, err
+	}
+	n.health.seen(peer.ID)
+	n.learnEventVersion(peer.ID, resp.EventVersion)
+	n.core.AddCheckpointSignatures(resp.Checkpoints)
+	n.learnAddresses(resp.Addresses)
+	n.heads.observe(peer.ID, resp.Head)
+
 	if resp.SyncLimit {
 		return true, nil, nil
 	}
 
 	// Add Events to poset and create new Head if necessary
+	n.batchLock.Lock()
 	n.coreLock.Lock()
 	err = n.sync(peer, resp.Events)
 	n.coreLock.Unlock()
+	n.batchLock.Unlock()
 	if err != nil {
 		n.logger.WithField("error", err).Error("n.sync(peer, resp.Events)")
+		n.health.syncFailed(peer.ID)
 		return false, nil, err
 	}
+	n.health.synced(peer.ID)
+	n.health.transferred(peer.ID, len(resp.Events), resp.Bytes, elapsed)
 
 	return false, resp.Known, nil
 }
 
+// push sends the peer the events its sync response says it lacks, when they
+// are at least PushThreshold, saving it the round trip of pulling them
 func (n *Node) push(peerAddr string, knownEvents map[uint64]int64) error {
+	// older peers do not tell what they know
+	if knownEvents == nil || n.conf.PushThreshold <= 0 {
+		return nil
+	}
 
 	// Check SyncLimit
 	n.coreLock.Lock()
-	overSyncLimit := n.core.OverSyncLimit(knownEvents, n.conf.SyncLimit)
+	unknown := n.core.UnknownCount(knownEvents)
 	n.coreLock.Unlock()
-	if overSyncLimit {
+	if unknown < n.conf.PushThreshold {
+		return nil
+	}
+	if unknown > n.syncLimit() {
 		n.logger.Debug("n.core.OverSyncLimit(knownEvents, n.conf.SyncLimit)")
 		return nil
 	}
@@ -539,12 +819,19 @@ func (n *Node) push(peerAddr string, knownEvents map[uint64]int64) error {
 
 func (n *Node) fastForward() error {
 	n.logger.Debug("fastForward()")
+	if !n.fastForwards.begin() {
+		return ErrResetInProgress
+	}
+	defer n.fastForwards.end()
 
 	// wait until sync routines finish
 	n.waitRoutines()
 
 	// fastForwardRequest
 	peer := n.peerSelector.Next()
+	if peer == nil {
+		return fmt.Errorf("can't select next peer")
+	}
 	start := time.Now()
 	resp, err := n.requestFastForward(peer.Message.NetAddr)
 	elapsed := time.Since(start)
@@ -553,38 +840,96 @@ func (n *Node) fastForward() error {
 		n.logger.WithField("Error", err).Error("n.requestFastForward(peer.NetAddr)")
 		return err
 	}
+	if err := n.checkConsensus(resp.FromID, resp.ConsensusHash, resp.ConsensusParams); err != nil {
+		return err
+	}
 	n.logger.WithFields(logrus.Fields{
 		"from_id":              resp.FromID,
 		"block_index":          resp.Block.Index(),
 		"block_round_received": resp.Block.RoundReceived(),
 		"frame_events":         len(resp.Frame.Events),
 		"frame_roots":          resp.Frame.Roots,
-		"snapshot":             resp.Snapshot,
 	}).Debug("FastForwardResponse")
 
+	if err := n.applyFastForward(peer, resp, nil); err != nil {
+		return err
+	}
+
+	n.setState(Gossiping)
+
+	return nil
+}
+
+// applyFastForward resets the core from the anchor block and frame of a
+// FastForwardResponse and restores the app from the snapshot of the anchor
+// block, asked for to the same peer. The fast forward fails when the app
+// rejects the snapshot. stage, if not nil, is told the stages it goes
+// through.
+func (n *Node) applyFastForward(from *peers.Peer, resp *peer.FastForwardResponse,
+	stage func(FastForwardStage)) error {
+	if stage == nil {
+		stage = func(FastForwardStage) {}
+	}
+	snapshot, stateHash, err := n.fetchSnapshot(from, resp.Block)
+	if err != nil {
+		n.logger.WithField("Error", err).Error("n.fetchSnapshot(from, resp.Block)")
+		return err
+	}
+	stage(FastForwardResetting)
+
 	// prepare core. ie: fresh poset
+	n.batchLock.Lock()
 	n.coreLock.Lock()
-	err = n.core.FastForward(peer.Message.PubKeyHex, resp.Block, resp.Frame)
+	err = n.core.FastForward(from.Message.PubKeyHex, resp.Block, resp.Frame, resp.BlockCarry)
 	n.coreLock.Unlock()
+	n.batchLock.Unlock()
 	if err != nil {
 		n.logger.WithField("Error", err).Error("n.core.FastForward(peer.PubKeyHex, resp.Block, resp.Frame)")
 		return err
 	}
 
 	// update app from snapshot
-	err = n.proxy.Restore(resp.Snapshot)
-	if err != nil {
-		n.logger.WithField("Error", err).Error("n.proxy.Restore(resp.Snapshot)")
+	stage(FastForwardRestoring)
+	if err := n.restoreSnapshot(snapshot, stateHash); err != nil {
+		n.logger.WithField("Error", err).Error("n.restoreSnapshot(snapshot, stateHash)")
 		return err
 	}
 
-	n.setState(Gossiping)
-
 	return nil
 }
 
+func (n *Node) processPeerLookupRequest(rpc *peer.RPC, cmd *peer.PeerLookupRequest) {
+	n.logger.WithFields(logrus.Fields{
+		"from_id": cmd.FromID,
+		"id":      cmd.ID,
+	}).Debug("processPeerLookupRequest(rpc net.RPC, cmd *net.PeerLookupRequest)")
+
+	resp := &peer.PeerLookupResponse{
+		FromID: n.id,
+	}
+	// the requester may hold its coreLock while waiting for this answer,
+	// so do not take ours
+	if p, ok := n.core.participants.ReadByID(cmd.ID); ok {
+		resp.Peer = p.Message
+	}
+
+	// TODO: context.Background
+	rpc.SendResult(context.Background(), n.logger, resp, nil)
+}
+
 func (n *Node) requestSync(target string, known map[uint64]int64) (*peer.SyncResponse, error) {
-	args := &peer.SyncRequest{FromID: n.id, Known: known}
+	args := &peer.SyncRequest{
+		FromID:        n.id,
+		Known:         known,
+		Checkpoints:   n.core.CheckpointSignatures(),
+		Addresses:     n.addrs.announcements(),
+		ConsensusHash: n.consensusHash,
+		NetworkID:     n.conf.NetworkID,
+		EventVersion:  poset.SupportedEventVersions.Max,
+	}
+	if p, ok := n.core.participants.ReadByNetAddr(target); ok {
+		args.Limit, args.MaxBytes = n.syncLimits(p.ID)
+	}
 	out := &peer.SyncResponse{}
 	err := n.trans.Sync(context.Background(), target, args, out)
 
@@ -599,28 +944,145 @@ func (n *Node) requestEagerSync(target string, events []poset.WireEvent) (*peer.
 	return out, err
 }
 
+// requestFastForward asks a peer for its anchor block and frame, the events
+// of the frame fetched in pages
 func (n *Node) requestFastForward(target string) (*peer.FastForwardResponse, error) {
-	args := &peer.FastForwardRequest{FromID: n.id}
+	args := &peer.FastForwardRequest{FromID: n.id, ConsensusHash: n.consensusHash, Paged: true}
 	out := &peer.FastForwardResponse{}
-	err := n.trans.FastForward(context.Background(), target, args, out)
+	if err := n.trans.FastForward(context.Background(), target, args, out); err != nil {
+		return out, err
+	}
+	err := fetchFrameEvents(n.trans, target, n.id, out, n.conf.FramePageEvents, n.conf.SyncMaxBytes)
+
+	return out, err
+}
+
+func (n *Node) requestPeerLookup(target string, id uint64) (*peer.PeerLookupResponse, error) {
+	args := &peer.PeerLookupRequest{FromID: n.id, ID: id}
+	out := &peer.PeerLookupResponse{}
+	err := n.trans.PeerLookup(context.Background(), target, args, out)
 
 	return out, err
 }
 
+// lookupCreator asks the syncing peer who is behind a creator ID we do not
+// know and, if the answer checks out, lets the poset read its events
+func (n *Node) lookupCreator(target string, id uint64) error {
+	resp, err := n.requestPeerLookup(target, id)
+	if err != nil {
+		return err
+	}
+	if resp.Peer == nil {
+		return fmt.Errorf("peer %s does not know creator ID %d", target, id)
+	}
+	return n.core.AddWireCreator(id, resp.Peer)
+}
+
+// sync inserts a batch of events from a peer, and asks the consensus worker
+// for a pass. batchLock and coreLock must be held.
 func (n *Node) sync(peer *peers.Peer, events []poset.WireEvent) error {
 	// Insert Events in Poset and create new Head if necessary
+	lastRound := n.core.poset.Store.LastRound()
 	start := time.Now()
 	err := n.core.Sync(peer, events)
+	// the events may refer to creators we have not heard of yet, look them
+	// up and read the batch again, events already inserted are skipped
+	for i := 0; i < maxCreatorLookups; i++ {
+		unknown, ok := err.(*poset.UnknownCreatorError)
+		if !ok {
+			break
+		}
+		if lookupErr := n.lookupCreator(peer.Message.NetAddr, unknown.ID); lookupErr != nil {
+			n.logger.WithField("error", lookupErr).Warn("n.lookupCreator(peer.NetAddr, id)")
+			break
+		}
+		err = n.core.Sync(peer, events)
+	}
 	elapsed := time.Since(start)
 	n.logger.WithField("Duration", elapsed.Nanoseconds()).Debug("n.core.Sync(events)")
+	// the events inserted before an error are in too
+	hint := hintEvents
+	if n.core.poset.Store.LastRound() > lastRound {
+		hint = hintRound
+	}
+	n.hintConsensus(hint)
+	// the rest of the batch is in, only the peer is to blame
+	if poset.IsEventCap(err) {
+		n.health.penalize(peer.ID)
+		err = nil
+	}
+	// the rest of the batch is not to be trusted either
+	if malformed, ok := err.(*poset.EventStructureError); ok {
+		n.health.rejectMalformed(peer.ID, malformed.Violation)
+		n.logger.WithFields(logrus.Fields{
+			"peer_id":   peer.ID,
+			"violation": malformed.Violation.String(),
+			"error":     err,
+		}).Warn("Peer sent a malformed event")
+	}
+	if unsupported, ok := err.(*poset.ErrUnsupportedEventVersion); ok && unsupported.Newer() {
+		n.logger.WithFields(logrus.Fields{
+			"peer_id": peer.ID,
+			"error":   err,
+		}).Warn("Peer is newer, upgrade this node")
+	}
 	if err != nil {
 		return fmt.Errorf("n.core.Sync(peer, events): %v", err)
 	}
 
-	if err := n.core.RunConsensus(); err != nil {
-		return err
+	return nil
+}
+
+// queuedBlocks returns a block with the blocks queued after it, up to
+// CommitBatchSize of them, without waiting for more
+func (n *Node) queuedBlocks(block poset.Block) []poset.Block {
+	blocks := []poset.Block{block}
+	for len(blocks) < n.conf.CommitBatchSize {
+		select {
+		case next := <-n.commitCh:
+			blocks = append(blocks, next)
+		default:
+			return blocks
+		}
 	}
+	return blocks
+}
 
+// commitBlocks commits blocks to the app in a single round trip when the
+// proxy can, one by one otherwise
+func (n *Node) commitBlocks(blocks []poset.Block) error {
+	committer, ok := n.proxy.(proxy.BatchCommitter)
+	if !ok || len(blocks) < 2 {
+		for _, block := range blocks {
+			if err := n.commit(block); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	n.coreLock.Lock()
+	defer n.coreLock.Unlock()
+
+	blocks, err := n.journalBlocks(blocks)
+	if err != nil || len(blocks) == 0 {
+		return err
+	}
+	appStateHashes, commitErr := committer.CommitBlocks(blocks)
+	if commitErr != nil {
+		n.logger.WithError(commitErr).Debug("commitBlocks(blocks []poset.Block)")
+	}
+	for i, block := range blocks {
+		// the blocks without a state hash are those the error is about
+		var appStateHash []byte
+		err := commitErr
+		if i < len(appStateHashes) {
+			appStateHash, err = appStateHashes[i], nil
+		}
+		if err := n.committed(block, appStateHash, err); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -629,11 +1091,25 @@ func (n *Node) commit(block poset.Block) error {
 	n.coreLock.Lock()
 	defer n.coreLock.Unlock()
 
-	stateHash := []byte{0, 1, 2}
-	_, err := n.proxy.CommitBlock(block)
-	if err != nil {
-		n.logger.WithError(err).Debug("commit(block poset.Block)")
+	blocks, err := n.journalBlocks([]poset.Block{block})
+	if err != nil || len(blocks) == 0 {
+		return err
+	}
+	appStateHash, commitErr := n.proxy.CommitBlock(block)
+	if commitErr != nil {
+		n.logger.WithError(commitErr).Debug("commit(block poset.Block)")
 	}
+	return n.committed(block, appStateHash, commitErr)
+}
+
+// committed signs a block the app committed, and checkpoints it with the
+// state hash of the app. coreLock must be held.
+func (n *Node) committed(block poset.Block, appStateHash []byte, commitErr error) error {
+	if commitErr == nil && !n.proxyAcks {
+		n.acknowledged(block.Index(), appStateHash)
+	}
+	n.rounds.committed(block)
+	stateHash := []byte{0, 1, 2}
 
 	n.logger.WithFields(logrus.Fields{
 		"block":      block.Index(),
@@ -651,26 +1127,42 @@ func (n *Node) commit(block poset.Block) error {
 
 	// There is no point in using the stateHash if we know it is wrong
 	// if err == nil {
-	if true {
-		// inmem statehash would be different than proxy statehash
-		// inmem is simply the hash of transactions
-		// this requires a 1:1 relationship with nodes and clients
-		// multiple nodes can't read from the same client
-
+	// Observers are not participants, so their signatures would not count,
+	// they only keep the block
+	if n.core.IsObserver() {
 		block.StateHash = stateHash
-		sig, err := n.core.SignBlock(block)
-		if err != nil {
-			return err
-		}
-		n.core.AddBlockSignature(sig)
+		return n.core.poset.Store.SetBlock(block)
 	}
 
+	// inmem statehash would be different than proxy statehash
+	// inmem is simply the hash of transactions
+	// this requires a 1:1 relationship with nodes and clients
+	// multiple nodes can't read from the same client
+
+	block.StateHash = stateHash
+	sig, err := n.core.SignBlock(block)
+	if err != nil {
+		return err
+	}
+	n.core.AddBlockSignature(sig)
+
+	// checkpoints carry the real state hash for light clients, so none
+	// is signed when the app did not give one
+	if commitErr == nil && appStateHash != nil {
+		if _, err := n.core.Checkpoint(block, appStateHash); err != nil {
+			n.logger.WithError(err).Error("n.core.Checkpoint(block, appStateHash)")
+		}
+	}
 	return nil
 }
 
 func (n *Node) addTransaction(tx []byte) error {
-	// we do not need coreLock here as n.core.AddTransactions has TransactionPoolLocker
-	return n.core.AddTransactions([][]byte{tx})
+	// we do not need coreLock here as the transaction pool has its own lock
+	return n.core.addTransactionsFrom(sourceApp, [][]byte{tx}, []byte{0})
+}
+
+func (n *Node) addTransactionWithFlags(tx []byte, flags byte) error {
+	return n.core.addTransactionsFrom(sourceFlagged, [][]byte{tx}, []byte{flags})
 }
 
 func (n *Node) addInternalTransaction(tx poset.InternalTransaction) {
@@ -690,7 +1182,8 @@ func (n *Node) Shutdown() {
 
 		// Stop and wait for concurrent operations
 		close(n.shutdownCh)
-		n.waitRoutines()
+		n.closeRoutines()
+		n.waitConsensusPass()
 
 		// For some reason this needs to be called after closing the shutdownCh
 		// Not entirely sure why...
@@ -699,6 +1192,8 @@ func (n *Node) Shutdown() {
 		// transport and store should only be closed once all concurrent operations
 		// are finished otherwise they will panic trying to use close objects
 		n.trans.Close()
+		n.saveReputations(true)
+		n.core.poset.FlushWarnings()
 		if err := n.core.poset.Store.Close(); err != nil {
 			n.logger.WithError(err).Debug("node::Shutdown::n.core.poset.Store.Close()")
 		}
@@ -714,40 +1209,98 @@ func (n *Node) GetStats() map[string]string {
 		return strconv.FormatInt(i, 10)
 	}
 
-	timeElapsed := time.Since(n.start)
+	timeElapsed := n.clock.Now().Sub(n.start)
 
 	consensusEvents := n.core.GetConsensusEventsCount()
 	consensusEventsPerSecond := float64(consensusEvents) / timeElapsed.Seconds()
 	consensusTransactions := n.core.GetConsensusTransactionsCount()
 	transactionsPerSecond := float64(consensusTransactions) / timeElapsed.Seconds()
 
+	finality, roundsToFinality := n.latency.means()
+	undetermined := n.core.poset.GetUndeterminedStats()
+	txPool := n.core.GetTransactionPoolStats()
+	otherParents := n.core.GetOtherParentStats()
+	var cacheHits, cacheLookups uint64
+	for _, c := range n.GetCacheStats() {
+		cacheHits += c.Hits
+		cacheLookups += c.Hits + c.Misses
+	}
+	var cacheHitRate float64
+	if cacheLookups > 0 {
+		cacheHitRate = float64(cacheHits) / float64(cacheLookups)
+	}
+
 	lastConsensusRound := n.core.GetLastConsensusRound()
 	var consensusRoundsPerSecond float64
-//	if lastConsensusRound > poset.RoundNIL {
+	if lastConsensusRound != poset.LastConsensusRoundNIL {
 		consensusRoundsPerSecond = float64(lastConsensusRound+1) / timeElapsed.Seconds()
-//	}
+	}
+
+	skew, throttle := n.flow.state()
+	heartbeat, _ := n.heartbeats()
 
 	s := map[string]string{
 		"last_consensus_round":    toString(lastConsensusRound),
 		"time_elapsed":            strconv.FormatFloat(timeElapsed.Seconds(), 'f', 2, 64),
-		"heartbeat":               strconv.FormatFloat(n.conf.HeartbeatTimeout.Seconds(), 'f', 2, 64),
-		"node_current":            strconv.FormatInt(time.Now().Unix(), 10),
+		"heartbeat":               strconv.FormatFloat(heartbeat.Seconds(), 'f', 2, 64),
+		"round_skew":              strconv.FormatInt(skew, 10),
+		"heartbeat_throttle":      strconv.FormatInt(throttle, 10),
+		"node_current":            strconv.FormatInt(n.clock.Now().Unix(), 10),
 		"node_start":              strconv.FormatInt(n.start.Unix(), 10),
 		"last_block_index":        strconv.FormatInt(n.core.GetLastBlockIndex(), 10),
 		"consensus_events":        strconv.FormatInt(consensusEvents, 10),
-		"sync_limit":              strconv.FormatInt(n.conf.SyncLimit, 10),
+		"sync_limit":              strconv.FormatInt(n.syncLimit(), 10),
 		"consensus_transactions":  strconv.FormatUint(consensusTransactions, 10),
-//		"undetermined_events":     strconv.Itoa(len(n.core.GetUndeterminedEvents())),
-		"transaction_pool":        strconv.FormatInt(n.core.GetTransactionPoolCount(), 10),
+		"undetermined_events":     strconv.Itoa(undetermined.Events),
+		"undetermined_archived":   strconv.Itoa(undetermined.Archived),
+		"undetermined_oldest_age": strconv.FormatInt(undetermined.OldestAge, 10),
+		"transaction_pool":        strconv.Itoa(txPool.Count),
+		"transaction_pool_bytes":  strconv.Itoa(txPool.Bytes),
+		"transaction_pool_max":    strconv.Itoa(txPool.MaxCount),
+		"transaction_evictions":   strconv.FormatUint(txPool.Evicted, 10),
+		"transaction_rejections":  strconv.FormatUint(txPool.Rejected, 10),
+		"peer_penalties":          strconv.Itoa(n.health.totalPenalties()),
 		"num_peers":               strconv.Itoa(n.peerSelector.Peers().Len()),
 		"sync_rate":               strconv.FormatFloat(n.SyncRate(), 'f', 2, 64),
 		"transactions_per_second": strconv.FormatFloat(transactionsPerSecond, 'f', 2, 64),
 		"events_per_second":       strconv.FormatFloat(consensusEventsPerSecond, 'f', 2, 64),
 		"rounds_per_second":       strconv.FormatFloat(consensusRoundsPerSecond, 'f', 2, 64),
+		"finality_seconds":        strconv.FormatFloat(finality, 'f', 3, 64),
+		"rounds_to_finality":      strconv.FormatFloat(roundsToFinality, 'f', 2, 64),
+		"cache_hit_rate":          strconv.FormatFloat(cacheHitRate, 'f', 3, 64),
+		"network_id":              n.conf.NetworkID.Hex(),
+		"other_parent_selector":   otherParents.Selector,
+		"other_parent_events":     strconv.FormatUint(otherParents.Events, 10),
+		"quorum":                  strconv.FormatBool(n.HasQuorum()),
+		"quorum_peers":            strconv.Itoa(n.quorumSynced()),
+		"quorum_required":         strconv.Itoa(n.quorumPeers()),
+		"missing_events":          strconv.Itoa(n.missingEvents()),
+		"duplicate_events":        strconv.FormatInt(n.core.GetDuplicateEventsCount(), 10),
+		"suppressed_events":       strconv.FormatInt(n.sent.suppressedCount(), 10),
+		"event_version":           strconv.FormatUint(uint64(n.EventVersion()), 10),
+		"queued_roots":            strconv.Itoa(n.core.poset.QueuedRoots()),
 //		"round_events":            strconv.Itoa(n.core.GetLastCommittedRoundEventsCount()),
 		"id":                      fmt.Sprint(n.id),
 		"state":                   n.getState().String(),
 	}
+	var malformedEvents int
+	malformed := n.health.malformedEvents()
+	for _, v := range poset.StructureViolations {
+		s["malformed_events_"+v.String()] = strconv.Itoa(malformed[v])
+		malformedEvents += malformed[v]
+	}
+	s["malformed_events"] = strconv.Itoa(malformedEvents)
+	var skewedEvents uint64
+	skewed := n.core.poset.SkewedCreators()
+	skewedCreators := make([]string, 0, len(skewed))
+	for creator, events := range skewed {
+		skewedCreators = append(skewedCreators, creator)
+		skewedEvents += events
+	}
+	sort.Strings(skewedCreators)
+	s["skewed_events"] = strconv.FormatUint(skewedEvents, 10)
+	s["skewed_creators"] = strings.Join(skewedCreators, ",")
+	s["clock_offset"] = strconv.FormatFloat(n.core.poset.ClockOffset().Seconds(), 'f', 3, 64)
 	// n.mqtt.FireEvent(s, "/mq/dag1/stats")
 	return s
 }
@@ -766,6 +1319,7 @@ func (n *Node) logStats() {
 		"events/s":               stats["events_per_second"],
 		"t/s":                    stats["transactions_per_second"],
 		"rounds/s":               stats["rounds_per_second"],
+		"round_skew":             stats["round_skew"],
 		"round_events":           stats["round_events"],
 		"state":                  stats["state"],
 		"z_gossipJobs":           n.gossipJobs.get(),
@@ -796,6 +1350,36 @@ func (n *Node) GetParticipants() (*peers.Peers, error) {
 	return n.core.poset.Store.Participants()
 }
 
+// GetPeersSnapshot returns the height, in-degree, selection count and last
+// sync of every participant
+func (n *Node) GetPeersSnapshot() []peers.PeerSnapshot {
+	return n.core.participants.Snapshot()
+}
+
+// GetUndeterminedStats returns the ages of the events whose consensus order
+// is not determined yet
+func (n *Node) GetUndeterminedStats() poset.UndeterminedStats {
+	return n.core.poset.GetUndeterminedStats()
+}
+
+// GetFairnessReport returns the contributions of the participants to the
+// consensus, see poset.FairnessReport
+func (n *Node) GetFairnessReport() poset.FairnessReport {
+	return n.core.poset.FairnessReport()
+}
+
+// GetCacheStats returns the hits, misses and sizes of the caches of the
+// poset
+func (n *Node) GetCacheStats() []poset.CacheStats {
+	return n.core.poset.CacheStats()
+}
+
+// GetLatencyStats returns how long the events of each creator took to reach
+// consensus, by creator public key
+func (n *Node) GetLatencyStats() map[string]CreatorLatency {
+	return n.latency.snapshot()
+}
+
 // GetEventBlock returns a specific event block for the given hash
 func (n *Node) GetEventBlock(event poset.EventHash) (poset.Event, error) {
 	return n.core.poset.Store.GetEventBlock(event)
@@ -832,9 +1416,9 @@ func (n *Node) GetPendingLoadedEvents() int64 {
 	return n.core.GetPendingLoadedEvents()
 }
 
-// GetRound returns the created round info for a given index
-func (n *Node) GetRound(roundIndex int64) (poset.RoundCreated, error) {
-	return n.core.poset.Store.GetRoundCreated(roundIndex)
+// GetRound returns the round info for a given index
+func (n *Node) GetRound(roundIndex int64) (poset.Round, error) {
+	return n.core.poset.Store.GetRound(roundIndex)
 }
 
 // GetLastRound returns the last round
@@ -862,11 +1446,98 @@ func (n *Node) GetBlock(blockIndex int64) (poset.Block, error) {
 	return n.core.poset.Store.GetBlock(blockIndex)
 }
 
+// GetFrameOrdering returns the events of a final frame in their final order
+func (n *Node) GetFrameOrdering(frame int64) ([]poset.OrderedEventRef, error) {
+	return n.core.poset.GetFrameOrdering(frame)
+}
+
+// GetCheckpoint returns the checkpoint of a frame and whether it proves the
+// frame final
+func (n *Node) GetCheckpoint(frame int64) (poset.Checkpoint, bool, error) {
+	return n.core.GetCheckpoint(frame)
+}
+
+// GetAccount returns the PoS balance of an address in the state of the last
+// block
+func (n *Node) GetAccount(address common.Address) (poset.Account, error) {
+	return poset.GetAccount(n.core.poset.Store, address)
+}
+
+// GetAccountProof returns the PoS balance of an address in the state of a
+// final frame, with the proof of it for light clients
+func (n *Node) GetAccountProof(address common.Address, frame int64) (poset.AccountProof, error) {
+	return poset.GetAccountProof(n.core.poset.Store, address, frame)
+}
+
+// LookupTx returns the event and block of a transaction by the hash of its
+// content, see poset.TxHash
+func (n *Node) LookupTx(txHash common.Hash) (poset.TxLocation, error) {
+	return n.core.poset.Store.LookupTx(txHash)
+}
+
+// VerifyCaches checks the rounds and lamport timestamps of the undetermined
+// events against the store, repairs those found wrong, and returns them
+func (n *Node) VerifyCaches() ([]poset.Inconsistency, error) {
+	n.coreLock.Lock()
+	defer n.coreLock.Unlock()
+	found, err := n.core.poset.VerifyCaches(0)
+	if err != nil {
+		n.logger.WithError(err).Error("Verifying caches")
+		return found, err
+	}
+	n.logger.WithField("inconsistencies", len(found)).Debug("Verified caches")
+	return found, nil
+}
+
+// txSubmission is a transaction submitted with SubmitTxWithResult
+type txSubmission struct {
+	tx     []byte
+	result chan error
+}
+
+// SubmitTxWithResult adds a transaction to the pool, like the app does
+// through its proxy, and returns whether the pool took it: ErrTxPoolFull if
+// it rejected it, ErrNoQuorum if the node cannot create events yet. It fails
+// if the node shuts down before taking it.
+func (n *Node) SubmitTxWithResult(tx []byte) error {
+	if !n.HasQuorum() {
+		return ErrNoQuorum
+	}
+	s := txSubmission{
+		tx:     make([]byte, len(tx)),
+		result: make(chan error, 1),
+	}
+	copy(s.tx, tx)
+	select {
+	case n.submitResultCh <- s:
+		return <-s.result
+	case <-n.shutdownCh:
+		return fmt.Errorf("node is shutting down")
+	}
+}
+
+// GetOtherParentStats returns the choices of the other-parent selector of
+// the node, by creator of the other-parents
+func (n *Node) GetOtherParentStats() OtherParentStats {
+	return n.core.GetOtherParentStats()
+}
+
+// GetTransactionPoolStats returns the occupancy of the transaction pool
+func (n *Node) GetTransactionPoolStats() TxPoolStats {
+	return n.core.GetTransactionPoolStats()
+}
+
 // ID shows the ID of the node
 func (n *Node) ID() uint64 {
 	return n.id
 }
 
+// SetAuditSink makes the poset of the node write its consensus decisions to
+// sink, see poset.AuditSink. It must be set before the node runs.
+func (n *Node) SetAuditSink(sink poset.AuditSink) {
+	n.core.poset.SetAuditSink(sink)
+}
+
 // Stop stops the node from gossiping
 func (n *Node) Stop() {
 	n.setState(Stop)
@@ -7,6 +7,7 @@ import (
 	"math/rand"
 	"os"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -21,6 +22,8 @@ import (
 	"github.com/SamuelMarks/dag1/src/peer/fakenet"
 	"github.com/SamuelMarks/dag1/src/peers"
 	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy"
+	"github.com/SamuelMarks/dag1/src/version"
 )
 
 type TestData struct {
@@ -74,7 +77,19 @@ func initPeers(
 		))
 	}
 
-	return keys, ps, adds
+	// the keys and addresses in the order of the peers, ToPeerSlice sorts
+	// them by ID
+	sortedKeys := make([]*ecdsa.PrivateKey, number)
+	sortedAdds := make([]string, number)
+	for i, peer := range ps.ToPeerSlice() {
+		for j, addr := range adds {
+			if peer.Message.NetAddr == addr {
+				sortedKeys[i], sortedAdds[i] = keys[j], addr
+			}
+		}
+	}
+
+	return sortedKeys, ps, sortedAdds
 }
 
 func createNetwork() (*fakenet.Network, peer.CreateSyncClientFunc) {
@@ -120,15 +135,36 @@ func createNode(t *testing.T, logger *logrus.Logger, config *Config,
 	id uint64, key *ecdsa.PrivateKey, participants *peers.Peers,
 	trans peer.SyncPeer, localAddr string, run bool) *Node {
 
-	db := poset.NewInmemStore(participants, config.CacheSize, nil)
 	app := dummy.NewInmemDummyApp(logger)
+	return createNodeWithApp(t, config, id, key, participants, trans, app,
+		localAddr, run)
+}
+
+func createNodeWithApp(t *testing.T, config *Config,
+	id uint64, key *ecdsa.PrivateKey, participants *peers.Peers,
+	trans peer.SyncPeer, app proxy.AppProxy, localAddr string, run bool) *Node {
+
+	participants = nodePeers(participants)
+	db := poset.NewInmemStore(participants, config.CacheSize, nil)
+	return createNodeWithStore(t, config, id, key, participants, db, trans, app,
+		localAddr, run)
+}
+
+// createNodeWithStore is createNodeWithApp on a store of the test, made for
+// the peers of the node
+func createNodeWithStore(t *testing.T, config *Config,
+	id uint64, key *ecdsa.PrivateKey, participants *peers.Peers, db poset.Store,
+	trans peer.SyncPeer, app proxy.AppProxy, localAddr string, run bool) *Node {
 
 	selectorArgs := SmartPeerSelectorCreationFnArgs{
 		LocalAddr: localAddr,
 		GetFlagTable: nil,
 	}
 
-	node := NewNode(config, id, key, participants, db, trans, app, NewSmartPeerSelectorWrapper, selectorArgs, localAddr)
+	node, err := NewNode(config, id, key, participants, db, trans, app, NewSmartPeerSelectorWrapper, selectorArgs, localAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if err := node.Init(); err != nil {
 		t.Fatal(err)
 	}
@@ -138,6 +174,21 @@ func createNode(t *testing.T, logger *logrus.Logger, config *Config,
 	return node
 }
 
+// nodePeers returns a copy of a peer set for a node of the test. A node
+// keeps its view of the heights and in-degrees of the participants in its
+// peers, nodes sharing a set would see the events of each other as known.
+func nodePeers(ps *peers.Peers) *peers.Peers {
+	res := peers.NewPeers()
+	for _, p := range ps.ToPeerSlice() {
+		peer := peers.NewPeer(p.Message.PubKeyHex, p.Message.NetAddr)
+		res.AddPeer(peer)
+		if w := p.GetWeight(); w != 0 {
+			res.SetPeerWeight(peer, w)
+		}
+	}
+	return res
+}
+
 func gossip(
 	nodes []*Node, target int64, shutdown bool, timeout time.Duration) error {
 	for _, n := range nodes {
@@ -221,17 +272,20 @@ func submitTransaction(n *Node, tx []byte) error {
 	return nil
 }
 
-func recycleNodes(
-	oldNodes []*Node, logger *logrus.Logger, t *testing.T) []*Node {
+func recycleNodes(oldNodes []*Node, network *fakenet.Network,
+	createFu peer.CreateSyncClientFunc, logger *logrus.Logger, t *testing.T) []*Node {
 	var newNodes []*Node
 	for _, oldNode := range oldNodes {
-		newNode := recycleNode(oldNode, logger, t)
+		newNode := recycleNode(oldNode, network, createFu, logger, t)
 		newNodes = append(newNodes, newNode)
 	}
 	return newNodes
 }
 
-func recycleNode(oldNode *Node, logger *logrus.Logger, t *testing.T) *Node {
+// recycleNode creates a node again from a shut down one, listening on its
+// address of the network. The node closes the transport when shut down.
+func recycleNode(oldNode *Node, network *fakenet.Network,
+	createFu peer.CreateSyncClientFunc, logger *logrus.Logger, t *testing.T) *Node {
 	conf := oldNode.conf
 	id := oldNode.id
 	key := oldNode.core.key
@@ -250,23 +304,24 @@ func recycleNode(oldNode *Node, logger *logrus.Logger, t *testing.T) *Node {
 	}
 
 	backConfig := peer.NewBackendConfig()
-	network, createFu := createNetwork()
-	p := ps.ToPeerSlice()
+	addr := oldNode.localAddr
 
 	// Create transport
-	trans := createTransport(t, logger, backConfig, p[0].NetAddr,
+	trans := createTransport(t, logger, backConfig, addr,
 		2, createFu, network.CreateListener)
-	defer transportClose(t, trans)
 
 	prox := dummy.NewInmemDummyApp(logger)
 
 	selectorArgs := SmartPeerSelectorCreationFnArgs{
-		LocalAddr: p[0].NetAddr,
+		LocalAddr: addr,
 		GetFlagTable: nil,
 	}
 
 	// Create & Init node
-	newNode := NewNode(conf, id, key, ps, store, trans, prox, NewSmartPeerSelectorWrapper, selectorArgs, p[0].NetAddr)
+	newNode, err := NewNode(conf, id, key, ps, store, trans, prox, NewSmartPeerSelectorWrapper, selectorArgs, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if err := newNode.Init(); err != nil {
 		t.Fatal(err)
 	}
@@ -537,7 +592,7 @@ func TestSyncAndRequestSync(t *testing.T) {
 	}
 
 	// Check pool
-	if l := len(node1.core.transactionPool); l > 0 {
+	if l := node1.core.GetTransactionPoolCount(); l > 0 {
 		t.Fatalf("expected %d, got %d", 0, l)
 	}
 
@@ -701,23 +756,16 @@ func TestRequestFastForward(t *testing.T) {
 	// Because of we have the value in src/node/commit func with explanation.
 	block.StateHash = []byte{0, 1, 2}
 
-	// Get snapshot
-	snapshot, err := node2.proxy.GetSnapshot(block.Index())
-	if err != nil {
-		t.Fatal(err)
-	}
-
 	// Create expected object
 	expected := peer.FastForwardResponse{
-		FromID:   node2.id,
-		Block:    block,
-		Frame:    frame,
-		Snapshot: snapshot,
+		FromID: node2.id,
+		Block:  block,
+		Frame:  frame,
 	}
 
 	// Check actual result
 	if !result.Block.Equals(&expected.Block) || !result.Frame.Equals(&expected.Frame) ||
-		result.FromID != expected.FromID || !bytes.Equal(result.Snapshot, expected.Snapshot) {
+		result.FromID != expected.FromID {
 		t.Fatalf("bad response, expected: %+v, got: %+v", expected, result)
 	}
 
@@ -810,6 +858,8 @@ func TestFastSync(t *testing.T) {
 	
 	poolSize := 2
 	config := TestConfig(t)
+	// node4 knows none of the events once it is back, far beyond the limit
+	config.SyncLimit = 50
 	backConfig := peer.NewBackendConfig()
 
 	network, createFu := createNetwork()
@@ -865,7 +915,7 @@ func TestFastSync(t *testing.T) {
 	checkGossip(nodes[0:3], 0, t)
 
 	// Can't re-run it; have to reinstantiate a new node.
-	node4 = recycleNode(node4, logger, t)
+	node4 = recycleNode(node4, network, createFu, logger, t)
 
 	// Run parallel routine to check node4 eventually reaches CatchingUp state.
 	timeout := time.After(30 * time.Second)
@@ -906,7 +956,6 @@ func TestFastSync(t *testing.T) {
 	}
 }
 
-// TODO: Failed
 func TestBootstrapAllNodes(t *testing.T) {
 	logger := common.NewTestLogger(t)
 
@@ -944,47 +993,78 @@ func TestBootstrapAllNodes(t *testing.T) {
 		poolSize, createFu, network.CreateListener)
 	defer transportClose(t, trans4)
 
-	node1 := createNode(t, logger, config, ps[0].ID, keys[0], p, trans1, adds[0], true)
+	// the nodes keep their events in the databases of test_data
+	badgerNode := func(i int, trans peer.SyncPeer) *Node {
+		participants := nodePeers(p)
+		store, err := poset.NewBadgerStore(participants, config.CacheSize,
+			fmt.Sprintf("test_data/node%d", i), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return createNodeWithStore(t, config, ps[i].ID, keys[i], participants,
+			store, trans, dummy.NewInmemDummyApp(logger), adds[i], false)
+	}
+
+	node1 := badgerNode(0, trans1)
 	defer node1.Shutdown()
 
-	node2 := createNode(t, logger, config, ps[1].ID, keys[1], p, trans2, adds[1], true)
+	node2 := badgerNode(1, trans2)
 	defer node2.Shutdown()
 
-	node3 := createNode(t, logger, config, ps[2].ID, keys[2], p, trans3, adds[2], true)
+	node3 := badgerNode(2, trans3)
 	defer node3.Shutdown()
 
-	node4 := createNode(t, logger, config, ps[3].ID, keys[3], p, trans4, adds[3], true)
+	node4 := badgerNode(3, trans4)
 	defer node4.Shutdown()
 
 	nodes := []*Node{node1, node2, node3, node4}
 
-	err := gossip(nodes, 10, false, 3*time.Second)
+	var target int64 = 10
+
+	err := gossip(nodes, target, false, 60*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
 	checkGossip(nodes, 0, t)
+
+	// the blocks of the first network, the databases are closed with it
+	var blocks []poset.Block
+	for i := int64(0); i <= target; i++ {
+		block, err := node1.GetBlock(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+	}
 	for _, n := range nodes {
 		n.Shutdown()
 	}
 
 	// Now try to recreate a network from the databases created
 	// in the first step and advance it to 20 consensus rounds
-	newNodes := recycleNodes(nodes, logger, t)
-	for _, n := range nodes {
-		n.RunAsync(true)
+	newNodes := recycleNodes(nodes, network, createFu, logger, t)
+	for _, n := range newNodes {
+		defer n.Shutdown()
 	}
-	err = gossip(newNodes, 20, false, 3*time.Second)
+	err = gossip(newNodes, 2*target, false, 60*time.Second)
 	if err != nil {
 		t.Fatal(err)
 	}
 	checkGossip(newNodes, 0, t)
-	for _, n := range nodes {
-		n.Shutdown()
-	}
 
 	// Check that both networks did not have
 	// completely different consensus events
-	checkGossip([]*Node{nodes[0], newNodes[0]}, 0, t)
+	for _, block := range blocks {
+		nBlock, err := newNodes[0].GetBlock(block.Index())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nBlock.RoundReceived() != block.RoundReceived() ||
+			!reflect.DeepEqual(nBlock.Transactions(), block.Transactions()) {
+			t.Fatalf("block %d should be the same after the bootstrap, %v not %v",
+				block.Index(), block.Body, nBlock.Body)
+		}
+	}
 }
 
 func TestShutdown(t *testing.T) {
@@ -1044,4 +1124,784 @@ func TestShutdown(t *testing.T) {
 	}
 
 	nodes[1].Shutdown()
-}
\ No newline at end of file
+}
+
+// joinNetwork runs the first n nodes of data, of the participants, until
+// they produce 20 rounds for another node to join. stop shuts them down.
+func joinNetwork(t *testing.T, data *TestData, config *Config,
+	participants *peers.Peers, n int) (nodes []*Node, stop func()) {
+	var transports []peer.SyncPeer
+	stop = func() {
+		for _, node := range nodes {
+			node.Shutdown()
+		}
+		for _, trans := range transports {
+			transportClose(t, trans)
+		}
+	}
+	for i := 0; i < n; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		transports = append(transports, trans)
+
+		node := createNode(t, data.Logger, config, data.PeersSlice[i].ID,
+			data.Keys[i], participants, trans, data.Adds[i], false)
+		nodes = append(nodes, node)
+	}
+
+	if err := gossip(nodes, 1, false, 60*time.Second); err != nil {
+		stop()
+		t.Fatal(err)
+	}
+	stopper := time.After(60 * time.Second)
+	for nodes[0].GetLastRound() < 20 {
+		select {
+		case <-stopper:
+			stop()
+			t.Fatalf("timeout waiting for 20 rounds, got %d",
+				nodes[0].GetLastRound())
+		default:
+		}
+		if err := submitTransaction(nodes[0], []byte("round filler")); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nodes, stop
+}
+
+// joinNode brings up node i of data, which only knows the address of the
+// first node, and joins it to the network, the join info checked against the
+// trusted participants. stop shuts it down.
+func joinNode(t *testing.T, data *TestData, config *Config, trusted *peers.Peers,
+	i int) (node *Node, resp *peer.FastForwardResponse, stop func()) {
+	trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+		data.PoolSize, data.CreateFu, data.Network.CreateListener)
+
+	resp, err := RequestJoinInfo(trans, data.Adds[0], data.PeersSlice[i].ID,
+		config.Consensus())
+	if err != nil {
+		transportClose(t, trans)
+		t.Fatal(err)
+	}
+	if err := VerifyJoinInfo(resp, trusted); err != nil {
+		transportClose(t, trans)
+		t.Fatal(err)
+	}
+	participants := NewPeersFromJoinInfo(resp)
+	if participants.Len() != trusted.Len() {
+		transportClose(t, trans)
+		t.Fatalf("expected %d participants, got %d",
+			trusted.Len(), participants.Len())
+	}
+
+	db := poset.NewInmemStore(participants, config.CacheSize, nil)
+	app := dummy.NewInmemDummyApp(data.Logger)
+	selectorArgs := SmartPeerSelectorCreationFnArgs{
+		LocalAddr: data.Adds[i],
+	}
+	node, err = NewNode(config, data.PeersSlice[i].ID, data.Keys[i],
+		participants, db, trans, app, NewSmartPeerSelectorWrapper,
+		selectorArgs, data.Adds[i])
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop = func() {
+		node.Shutdown()
+		transportClose(t, trans)
+	}
+	if err := node.Init(); err != nil {
+		stop()
+		t.Fatal(err)
+	}
+
+	if err := node.Join(resp); err != nil {
+		stop()
+		t.Fatalf("Error joining: %s", err)
+	}
+	if node.GetLastBlockIndex() != resp.Block.Index() {
+		stop()
+		t.Fatalf("expected last block %d after join, got %d",
+			resp.Block.Index(), node.GetLastBlockIndex())
+	}
+	return node, resp, stop
+}
+
+func TestJoin(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+	// the 20 rounds take minutes at the heartbeat of the test config
+	config := *data.Config
+	config.HeartbeatTimeout = 10 * time.Millisecond
+
+	// produce 20 rounds with the first three nodes only
+	nodes, stop := joinNetwork(t, data, &config, data.Peers, 3)
+	defer stop()
+
+	// bring up the 4th participant, which did not run so far
+	node4, resp, stop4 := joinNode(t, data, &config, data.Peers, 3)
+	defer stop4()
+	if node4.core.IsObserver() {
+		t.Fatal("expected the 4th participant not to be an observer")
+	}
+
+	nodes = append(nodes, node4)
+	target := nodes[0].GetLastBlockIndex() + 3
+	if err := gossip(nodes[3:], target, false, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// the joined node orders the frame after its anchor block as the others
+	frame := resp.Block.RoundReceived() + 1
+	ordering, err := node4.GetFrameOrdering(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := nodes[0].GetFrameOrdering(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ordering) == 0 || !reflect.DeepEqual(ordering, expected) {
+		t.Fatalf("frame %d ordering mismatch: joined %v, expected %v",
+			frame, ordering, expected)
+	}
+
+	// and all the nodes, the joined one too, come to the same last block
+	checkLastBlock(t, nodes, 30*time.Second)
+}
+
+// checkLastBlock waits for the running nodes to have the same last block,
+// with the same hash of its body. The blocks of the same index must be the
+// same, the nodes only have to be in between two blocks together.
+func checkLastBlock(t *testing.T, nodes []*Node, timeout time.Duration) {
+	stopper := time.After(timeout)
+	for {
+		index := nodes[0].GetLastBlockIndex()
+		same := true
+		for _, n := range nodes[1:] {
+			if n.GetLastBlockIndex() != index {
+				same = false
+				break
+			}
+		}
+		if same {
+			var hashes [][]byte
+			for _, n := range nodes {
+				block, err := n.GetBlock(index)
+				if err != nil {
+					t.Fatal(err)
+				}
+				hash, err := block.Body.Hash()
+				if err != nil {
+					t.Fatal(err)
+				}
+				hashes = append(hashes, hash)
+			}
+			for i, hash := range hashes[1:] {
+				if !bytes.Equal(hash, hashes[0]) {
+					t.Fatalf("block %d hash mismatch: node %d %X, node 0 %X",
+						index, i+1, hash, hashes[0])
+				}
+			}
+			return
+		}
+		select {
+		case <-stopper:
+			var indexes []int64
+			for _, n := range nodes {
+				indexes = append(indexes, n.GetLastBlockIndex())
+			}
+			t.Fatalf("timeout waiting for the same last block, got %v", indexes)
+		default:
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestJoinObserver joins a node with a brand-new key, which is no
+// participant, and which follows the network as an observer
+func TestJoinObserver(t *testing.T) {
+	data := InitTestData(t, 5, 2)
+	config := *data.Config
+	config.HeartbeatTimeout = 10 * time.Millisecond
+	// the events of the first rounds of the four nodes outgrow the cache
+	config.CacheSize = 10000
+
+	participants := peers.NewPeers()
+	for _, p := range data.PeersSlice[:4] {
+		participants.AddPeer(peers.NewPeer(p.Message.PubKeyHex, p.Message.NetAddr))
+	}
+	nodes, stop := joinNetwork(t, data, &config, participants, 4)
+	defer stop()
+
+	observer, _, stop5 := joinNode(t, data, &config, participants, 4)
+	defer stop5()
+	if !observer.core.IsObserver() {
+		t.Fatal("expected the node with a new key to be an observer")
+	}
+	if _, err := observer.CreateEmptyEvent(); err != ErrObserverEvent {
+		t.Fatalf("expected %v, got %v", ErrObserverEvent, err)
+	}
+
+	// the observer gets the blocks the participants make
+	go observer.Run(true)
+	target := nodes[0].GetLastBlockIndex() + 3
+	if err := bombardAndWait(append(nodes, observer), target, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if known := nodes[0].GetKnownEvents()[observer.ID()]; known > 0 {
+		t.Fatalf("expected no events of the observer, got up to index %d", known)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+
+	var nodes []*Node
+	for i := 0; i < 4; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		node := createNode(t, data.Logger, data.Config, data.PeersSlice[i].ID,
+			data.Keys[i], data.Peers, trans, data.Adds[i], false)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+
+	if err := gossip(nodes, 1, false, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nodes[0].Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if !nodes[0].IsPaused() || nodes[0].getState() != Paused {
+		t.Fatalf("expected a paused node, got state %s", nodes[0].getState())
+	}
+	pausedBlock := nodes[0].GetLastBlockIndex()
+	pausedKnown := nodes[0].GetKnownEvents()
+
+	queued := [][]byte{[]byte("queued 0"), []byte("queued 1"), []byte("queued 2")}
+	for _, tx := range queued {
+		if err := submitTransaction(nodes[0], tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the other three still make a quorum
+	target := nodes[1].GetLastBlockIndex() + 3
+	if err := bombardAndWait(nodes[1:], target, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if lbi := nodes[0].GetLastBlockIndex(); lbi != pausedBlock {
+		t.Fatalf("paused node committed blocks, %d -> %d", pausedBlock, lbi)
+	}
+	if !reflect.DeepEqual(nodes[0].GetKnownEvents(), pausedKnown) {
+		t.Fatal("paused node inserted events")
+	}
+
+	// the paused node still answers syncs, but refuses pushed events. The
+	// request misses the last event of the paused node.
+	known := nodes[1].GetKnownEvents()
+	known[nodes[0].ID()]--
+	resp, err := nodes[1].requestSync(data.Adds[0], known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Events) == 0 {
+		t.Fatal("expected events from the paused node")
+	}
+	if _, err := nodes[1].requestEagerSync(data.Adds[0], resp.Events); err == nil {
+		t.Fatal("expected a paused node to refuse pushed events")
+	}
+
+	nodes[0].Resume()
+	if nodes[0].IsPaused() {
+		t.Fatal("expected a resumed node")
+	}
+
+	pending := make(map[string]bool)
+	for _, tx := range queued {
+		pending[string(tx)] = true
+	}
+	stopper := time.After(60 * time.Second)
+	next := pausedBlock + 1
+	for len(pending) > 0 {
+		select {
+		case <-stopper:
+			t.Fatalf("timeout waiting for the queued transactions, %d left",
+				len(pending))
+		default:
+		}
+		if err := submitTransaction(nodes[1], []byte("filler")); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		for ; next <= nodes[0].GetLastBlockIndex(); next++ {
+			block, err := nodes[0].GetBlock(next)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, tx := range block.Transactions() {
+				delete(pending, string(tx))
+			}
+		}
+	}
+}
+
+func TestPauseQueue(t *testing.T) {
+	data := InitTestData(t, 2, 2)
+	data.Config.PauseQueueSize = 2
+
+	var nodes []*Node
+	for i := 0; i < 2; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		node := createNode(t, data.Logger, data.Config, data.PeersSlice[i].ID,
+			data.Keys[i], data.Peers, trans, data.Adds[i], false)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+
+	if err := nodes[0].Pause(); err != nil {
+		t.Fatal(err)
+	}
+	// pausing twice is harmless
+	if err := nodes[0].Pause(); err != nil {
+		t.Fatal(err)
+	}
+
+	submit := func(tx string) chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			nodes[0].proxy.SubmitCh() <- []byte(tx)
+			close(done)
+		}()
+		return done
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-submit(fmt.Sprintf("queued %d", i)):
+		case <-time.After(5 * time.Second):
+			t.Fatalf("transaction %d was not queued", i)
+		}
+	}
+	// wait for the second one to reach the pool
+	for nodes[0].core.GetTransactionPoolCount() < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	blocked := submit("blocked")
+	select {
+	case <-blocked:
+		t.Fatal("expected the queue of a paused node to be bounded")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	if _, err := nodes[1].requestSync(data.Adds[0], map[uint64]int64{}); err != nil {
+		t.Fatalf("expected a paused node to answer syncs: %v", err)
+	}
+	if _, err := nodes[1].requestEagerSync(data.Adds[0], nil); err == nil {
+		t.Fatal("expected a paused node to refuse pushed events")
+	}
+
+	nodes[0].Resume()
+	select {
+	case <-blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the queue to accept transactions after Resume")
+	}
+	if nodes[0].IsPaused() {
+		t.Fatal("expected a resumed node")
+	}
+}
+
+func TestReady(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+	data.Config.ReadyHeartbeats = 2
+
+	// waitReady waits for the readiness of the node, or for the reason it is
+	// not ready: a late sync may come before the loss of the supermajority
+	waitReady := func(n *Node, ready bool, reason string) error {
+		timeout := time.After(20 * time.Second)
+		for {
+			err := n.Ready()
+			if (err == nil) == ready &&
+				(err == nil || strings.Contains(err.Error(), reason)) {
+				return err
+			}
+			select {
+			case <-timeout:
+				return fmt.Errorf("expected ready to be %v, last error: %v", ready, err)
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}
+
+	var nodes []*Node
+	for i := 0; i < 4; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		node := createNode(t, data.Logger, data.Config, data.PeersSlice[i].ID,
+			data.Keys[i], data.Peers, trans, data.Adds[i], true)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+
+		if i == 0 {
+			// alone in the network
+			if err := nodes[0].Ready(); err == nil {
+				t.Fatal("expected a node without peers not to be ready")
+			}
+		}
+	}
+
+	if err := waitReady(nodes[0], true, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// a third of the stake is not enough
+	nodes[2].Shutdown()
+	nodes[3].Shutdown()
+	err := waitReady(nodes[0], false, "supermajority")
+	if err == nil || !strings.Contains(err.Error(), "supermajority") {
+		t.Fatalf("expected the node to lose its supermajority, got %v", err)
+	}
+}
+
+func TestKVConvergence(t *testing.T) {
+	const ops = 200
+	data := InitTestData(t, 4, 2)
+
+	var (
+		nodes  []*Node
+		states []*dummy.KVState
+	)
+	for i := 0; i < 4; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		app, state := dummy.NewInmemKVDummyApp(data.Logger)
+		node := createNodeWithApp(t, data.Config, data.PeersSlice[i].ID,
+			data.Keys[i], data.Peers, trans, app, data.Adds[i], true)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+		states = append(states, state)
+	}
+
+	// random operations on a few keys, submitted to random nodes
+	for i := 0; i < ops; i++ {
+		key := fmt.Sprintf("key%d", rand.Intn(10))
+		tx := dummy.SetTx(key, fmt.Sprintf("value%d", i))
+		if rand.Intn(4) == 0 {
+			tx = dummy.DelTx(key)
+		}
+		if err := submitTransaction(nodes[rand.Intn(len(nodes))], tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timeout := time.After(60 * time.Second)
+	for {
+		converged := true
+		for i, n := range nodes {
+			if n.GetConsensusTransactionsCount() < ops ||
+				!bytes.Equal(states[i].StateHash(), states[0].StateHash()) {
+				converged = false
+				break
+			}
+		}
+		if converged {
+			break
+		}
+		select {
+		case <-timeout:
+			t.Fatal("the key/value states did not converge")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	for k := 0; k < 10; k++ {
+		key := fmt.Sprintf("key%d", k)
+		expected, expectedOk := states[0].Query(key)
+		for i, state := range states[1:] {
+			if v, ok := state.Query(key); ok != expectedOk || v != expected {
+				t.Fatalf("node %d: expected %s to be %q, got %q", i+1, key, expected, v)
+			}
+		}
+	}
+}
+
+func TestTwoNodeNetwork(t *testing.T) {
+	data := InitTestData(t, 2, 2)
+	if sm, tc := data.Peers.GetSuperMajority(), data.Peers.GetTrustCount(); sm != 2 || tc != 0 {
+		t.Fatalf("expected supermajority 2 and trust count 0, got %d and %d", sm, tc)
+	}
+
+	var nodes []*Node
+	for i := 0; i < 2; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		node := createNode(t, data.Logger, data.Config, data.Peers.ByNetAddr[data.Adds[i]].ID,
+			data.Keys[i], data.Peers, trans, data.Adds[i], false)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+
+	if err := gossip(nodes, 5, false, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	checkGossip(nodes, 0, t)
+}
+
+func TestCheckpointSignatures(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+
+	var nodes []*Node
+	for i := 0; i < 4; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		node := createNode(t, data.Logger, data.Config, data.PeersSlice[i].ID,
+			data.Keys[i], data.Peers, trans, data.Adds[i], true)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+
+	// every node commits the same block, then the signatures spread with
+	// the sync responses
+	block := poset.NewBlock(0, 3, []byte("framehash"), [][]byte{[]byte("tx")})
+	stateHash := []byte("statehash")
+	frame := block.RoundReceived()
+	for _, n := range nodes {
+		if _, err := n.core.Checkpoint(block, stateHash); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timeout := time.After(20 * time.Second)
+	for _, n := range nodes {
+		for {
+			checkpoint, provable, err := n.GetCheckpoint(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if provable && len(checkpoint.Signatures) == len(nodes) {
+				break
+			}
+			select {
+			case <-timeout:
+				t.Fatalf("node %d: got %d checkpoint signatures, provable %v",
+					n.ID(), len(checkpoint.Signatures), provable)
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}
+
+	checkpoint, _, err := nodes[0].GetCheckpoint(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(checkpoint.StateHash, stateHash) {
+		t.Fatalf("expected state hash %s, got %s", stateHash, checkpoint.StateHash)
+	}
+	for _, sig := range checkpoint.GetSignatures() {
+		if _, ok := data.Peers.ReadByPubKey(sig.ValidatorHex()); !ok {
+			t.Fatalf("signature from %s who is not a participant", sig.ValidatorHex())
+		}
+		if ok, err := checkpoint.Verify(sig); err != nil || !ok {
+			t.Fatalf("signature from %s does not verify: %v", sig.ValidatorHex(), err)
+		}
+	}
+
+	// signatures of outsiders or of another state are dropped
+	outsider, _ := crypto.GenerateECDSAKey()
+	outsiderSig, err := checkpoint.Sign(outsider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := poset.NewCheckpoint(frame, checkpoint.FrameHash, []byte("other"))
+	forgedSig, err := forged.Sign(data.Keys[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes[0].core.AddCheckpointSignatures([]poset.BlockSignature{outsiderSig, forgedSig})
+	after, _, err := nodes[0].GetCheckpoint(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(after.Signatures, checkpoint.Signatures) {
+		t.Fatal("expected invalid checkpoint signatures to be dropped")
+	}
+}
+
+func TestPeersSnapshot(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+
+	var nodes []*Node
+	for i := 0; i < 4; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		// each node tracks the heights of its own participants
+		participants := peers.NewPeers()
+		for _, p := range data.PeersSlice {
+			participants.AddPeer(peers.NewPeer(p.Message.PubKeyHex, p.Message.NetAddr))
+		}
+		node := createNode(t, data.Logger, data.Config, data.PeersSlice[i].ID,
+			data.Keys[i], participants, trans, data.Adds[i], true)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+	// wait for nodes[0] to have synced with the peers its selector picks, a
+	// supermajority of them
+	timeout := time.After(20 * time.Second)
+	for {
+		synced := 0
+		for _, p := range nodes[0].GetPeersSnapshot() {
+			if p.ID != nodes[0].ID() && p.LastSyncOK && !p.LastSyncTime.IsZero() {
+				synced++
+			}
+		}
+		if synced >= 2*(len(nodes)-1)/3 {
+			break
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("node 0 synced with %d peers", synced)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// the peers it synced with told their release in the handshake
+	for _, p := range nodes[0].GetPeersSnapshot() {
+		if p.LastSyncOK && p.Version != version.Version {
+			t.Fatalf("peer %d: expected the release %q, got %q", p.ID, version.Version, p.Version)
+		}
+	}
+
+	// heights match the store once nodes[0] stops inserting events
+	if err := nodes[0].Pause(); err != nil {
+		t.Fatal(err)
+	}
+	defer nodes[0].Resume()
+	snapshot := nodes[0].GetPeersSnapshot()
+	if len(snapshot) != len(nodes) {
+		t.Fatalf("expected %d peers, got %d", len(nodes), len(snapshot))
+	}
+	for _, p := range snapshot {
+		peer, _ := data.Peers.ReadByID(p.ID)
+		_, height, _, err := nodes[0].core.lastEventFrom(peer.Message.PubKeyHex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Height != height {
+			t.Fatalf("peer %d: expected height %d, got %d", p.ID, height, p.Height)
+		}
+	}
+}
+
+// gossipRoundsToConvergence seeds 4 idle nodes with 2 events each and counts
+// the rounds of gossip, each node pulling from the next, until they all know
+// the same events
+func gossipRoundsToConvergence(t *testing.T, pushThreshold int64) int {
+	data := InitTestData(t, 4, 2)
+	data.Config.PushThreshold = pushThreshold
+
+	// data.Keys, data.Adds and data.PeersSlice are all in ID order
+	var nodes []*Node
+	for i, key := range data.Keys {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		// each node tracks the heights of its own participants, of unit
+		// weight so that the seeded events are not roots
+		participants := peers.NewPeers()
+		for _, p := range data.PeersSlice {
+			peer := peers.NewPeer(p.Message.PubKeyHex, p.Message.NetAddr)
+			participants.AddPeer(peer)
+			participants.SetPeerWeight(peer, 1)
+		}
+		self, _ := participants.ReadByPubKey(
+			fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)))
+		node := createNode(t, data.Logger, data.Config, self.ID,
+			key, participants, trans, data.Adds[i], false)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+
+	for i, n := range nodes {
+		next := nodes[(i+1)%len(nodes)]
+		n.coreLock.Lock()
+		for j := 0; j < 2; j++ {
+			otherHead, _, err := n.core.poset.Store.LastEventFrom(next.core.HexID())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := n.core.AddSelfEventBlock(otherHead); err != nil {
+				t.Fatal(err)
+			}
+		}
+		n.coreLock.Unlock()
+	}
+
+	known := func(n *Node) map[uint64]int64 {
+		n.coreLock.Lock()
+		defer n.coreLock.Unlock()
+		return n.core.KnownEvents()
+	}
+	for round := 1; round <= 10; round++ {
+		for i, n := range nodes {
+			next := nodes[(i+1)%len(nodes)]
+			peer, _ := n.core.participants.ReadByID(next.ID())
+			_, otherKnown, err := n.pull(&peer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := n.push(peer.Message.NetAddr, otherKnown); err != nil {
+				t.Fatal(err)
+			}
+			// the peer applies a push after answering it
+			for timeout := time.After(5 * time.Second); pushThreshold > 0; {
+				n.coreLock.Lock()
+				unknown := n.core.UnknownCount(known(next))
+				n.coreLock.Unlock()
+				if unknown == 0 {
+					break
+				}
+				select {
+				case <-timeout:
+					t.Fatalf("node %d: push not applied", next.ID())
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+		}
+
+		converged := true
+		for _, n := range nodes[1:] {
+			converged = converged && reflect.DeepEqual(known(n), known(nodes[0]))
+		}
+		if converged {
+			return round
+		}
+	}
+	t.Fatal("the nodes did not converge in 10 rounds")
+	return 0
+}
+
+func TestPushKnownDiff(t *testing.T) {
+	pull := gossipRoundsToConvergence(t, 0)
+	pushPull := gossipRoundsToConvergence(t, 1)
+	t.Logf("rounds to convergence: %d pulling only, %d pushing too", pull, pushPull)
+	if pushPull >= pull {
+		t.Fatalf("expected pushing to converge in fewer than %d rounds, took %d", pull, pushPull)
+	}
+}
@@ -1,5 +1,3 @@
-// +build test
-
 package node
 
 // This funcs use only for test purposes. Don't use it for debug or product build.
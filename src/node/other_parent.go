@@ -0,0 +1,248 @@
+package node
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// Other-parent selectors, the strategies choosing the other-parent of the
+// self events of a node
+const (
+	// OtherParentLastSync chooses the last event of the peer synced with
+	OtherParentLastSync = "last-sync"
+	// OtherParentMostStarved chooses the last event of the participant
+	// referenced least recently, to even out the in-degree of the creators
+	OtherParentMostStarved = "most-starved"
+	// OtherParentRandomKnown chooses the last event of a random participant
+	OtherParentRandomKnown = "random-known"
+
+	// otherParentChoices is the number of events the strategy which chose
+	// their other-parent is remembered for
+	otherParentChoices = 10000
+)
+
+// OtherParentSelectors are the known other-parent selectors
+var OtherParentSelectors = []string{OtherParentLastSync, OtherParentMostStarved, OtherParentRandomKnown}
+
+// OtherHead is the last event the node has of another participant, a
+// candidate other-parent
+type OtherHead struct {
+	Creator uint64
+	Hash    poset.EventHash
+}
+
+// OtherParentSelector provides an interface for the core to choose the
+// other-parent of its next self event, as PeerSelector does for the peer to
+// gossip with
+type OtherParentSelector interface {
+	// Name returns the strategy, one of OtherParentSelectors
+	Name() string
+	// Select returns the other-parent among the events the node has: the
+	// last one of the peer synced with, and of the other participants
+	Select(synced OtherHead, others []OtherHead) OtherHead
+	// Referenced records the creator of the other-parent of a new event
+	Referenced(creator uint64)
+}
+
+// NewOtherParentSelector creates the other-parent selector of a name, the
+// last-sync one for an unknown name
+func NewOtherParentSelector(name string) OtherParentSelector {
+	switch name {
+	case OtherParentMostStarved:
+		return &MostStarvedOtherParentSelector{referenced: make(map[uint64]uint64)}
+	case OtherParentRandomKnown:
+		return &RandomKnownOtherParentSelector{}
+	default:
+		return &LastSyncOtherParentSelector{}
+	}
+}
+
+// LastSyncOtherParentSelector chooses the last event of the peer synced with
+type LastSyncOtherParentSelector struct{}
+
+// Name implements OtherParentSelector interface method
+func (s *LastSyncOtherParentSelector) Name() string {
+	return OtherParentLastSync
+}
+
+// Select implements OtherParentSelector interface method
+func (s *LastSyncOtherParentSelector) Select(synced OtherHead, others []OtherHead) OtherHead {
+	return synced
+}
+
+// Referenced implements OtherParentSelector interface method
+func (s *LastSyncOtherParentSelector) Referenced(creator uint64) {}
+
+// MostStarvedOtherParentSelector chooses the last event of the participant
+// whose events the node referenced least recently, never first, the lowest
+// ID first among those never referenced
+type MostStarvedOtherParentSelector struct {
+	sync.Mutex
+	seq        uint64
+	referenced map[uint64]uint64 // [creator] => seq of the last reference
+}
+
+// Name implements OtherParentSelector interface method
+func (s *MostStarvedOtherParentSelector) Name() string {
+	return OtherParentMostStarved
+}
+
+// Select implements OtherParentSelector interface method
+func (s *MostStarvedOtherParentSelector) Select(synced OtherHead, others []OtherHead) OtherHead {
+	s.Lock()
+	defer s.Unlock()
+	res := synced
+	for _, h := range others {
+		last, best := s.referenced[h.Creator], s.referenced[res.Creator]
+		if last < best || (last == best && h.Creator < res.Creator) {
+			res = h
+		}
+	}
+	return res
+}
+
+// Referenced implements OtherParentSelector interface method
+func (s *MostStarvedOtherParentSelector) Referenced(creator uint64) {
+	s.Lock()
+	defer s.Unlock()
+	s.seq++
+	s.referenced[creator] = s.seq
+}
+
+// RandomKnownOtherParentSelector chooses the last event of a random
+// participant among those the node has events of
+type RandomKnownOtherParentSelector struct{}
+
+// Name implements OtherParentSelector interface method
+func (s *RandomKnownOtherParentSelector) Name() string {
+	return OtherParentRandomKnown
+}
+
+// Select implements OtherParentSelector interface method
+func (s *RandomKnownOtherParentSelector) Select(synced OtherHead, others []OtherHead) OtherHead {
+	i := rand.Intn(len(others) + 1)
+	if i == len(others) {
+		return synced
+	}
+	return others[i]
+}
+
+// Referenced implements OtherParentSelector interface method
+func (s *RandomKnownOtherParentSelector) Referenced(creator uint64) {}
+
+// OtherParentStats are the choices of the other-parent selector of a node,
+// which are local, not part of the events
+type OtherParentStats struct {
+	Selector string
+	// Events is the number of self events made on a selected other-parent
+	Events uint64
+	// References is their number by creator of the other-parent
+	References map[uint64]uint64
+}
+
+// otherParentRecord remembers the strategy which chose the other-parent of
+// the last otherParentChoices self events, and counts the choices
+type otherParentRecord struct {
+	sync.Mutex
+	choices    map[poset.EventHash]string
+	order      []poset.EventHash // oldest first
+	events     uint64
+	references map[uint64]uint64
+}
+
+func newOtherParentRecord() *otherParentRecord {
+	return &otherParentRecord{
+		choices:    make(map[poset.EventHash]string),
+		references: make(map[uint64]uint64),
+	}
+}
+
+// add records the other-parent of a self event chosen by a strategy
+func (r *otherParentRecord) add(event poset.EventHash, strategy string, creator uint64) {
+	r.Lock()
+	defer r.Unlock()
+	r.choices[event] = strategy
+	r.order = append(r.order, event)
+	if len(r.order) > otherParentChoices {
+		delete(r.choices, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.events++
+	r.references[creator]++
+}
+
+// strategy returns the strategy which chose the other-parent of a self
+// event, when it is among the last ones
+func (r *otherParentRecord) strategy(event poset.EventHash) (string, bool) {
+	r.Lock()
+	defer r.Unlock()
+	s, ok := r.choices[event]
+	return s, ok
+}
+
+func (r *otherParentRecord) stats(selector string) OtherParentStats {
+	r.Lock()
+	defer r.Unlock()
+	res := OtherParentStats{
+		Selector:   selector,
+		Events:     r.events,
+		References: make(map[uint64]uint64, len(r.references)),
+	}
+	for creator, n := range r.references {
+		res.References[creator] = n
+	}
+	return res
+}
+
+// selectOtherParent returns the other-parent of the next self event, chosen
+// by the selector of the core among the last events of the participants,
+// that of the peer synced with being the given one. Only those of the others
+// which are events the core has are candidates, roots are not.
+func (c *Core) selectOtherParent(peerID uint64, peerHead poset.EventHash) (OtherHead, error) {
+	synced := OtherHead{Creator: peerID, Hash: peerHead}
+	var others []OtherHead
+	for _, p := range c.participants.ToPeerSlice() {
+		if p.Message.PubKeyHex == c.HexID() || p.ID == peerID {
+			continue
+		}
+		last, isRoot, err := c.poset.Store.LastEventFrom(p.Message.PubKeyHex)
+		if err != nil {
+			return OtherHead{}, err
+		}
+		if !isRoot {
+			others = append(others, OtherHead{Creator: p.ID, Hash: last})
+		}
+	}
+	return c.otherParents.Select(synced, others), nil
+}
+
+// addSelectedSelfEventBlock adds a self event on the other-parent chosen by
+// selectOtherParent, and records the choice when the event is made
+func (c *Core) addSelectedSelfEventBlock(peerID uint64, peerHead poset.EventHash, withTransactions bool) error {
+	head, err := c.selectOtherParent(peerID, peerHead)
+	if err != nil {
+		return err
+	}
+	before := c.head
+	if err := c.addRatedSelfEventBlock(head.Hash, withTransactions); err != nil {
+		return err
+	}
+	if c.head != before {
+		c.otherParents.Referenced(head.Creator)
+		c.otherParentRecord.add(c.head, c.otherParents.Name(), head.Creator)
+	}
+	return nil
+}
+
+// OtherParentStrategy returns the strategy which chose the other-parent of
+// one of the last self events of the core
+func (c *Core) OtherParentStrategy(event poset.EventHash) (string, bool) {
+	return c.otherParentRecord.strategy(event)
+}
+
+// GetOtherParentStats returns the choices of the other-parent selector
+func (c *Core) GetOtherParentStats() OtherParentStats {
+	return c.otherParentRecord.stats(c.otherParents.Name())
+}
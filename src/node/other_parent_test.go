@@ -0,0 +1,116 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMostStarvedOtherParentSelector(t *testing.T) {
+	s := NewOtherParentSelector(OtherParentMostStarved)
+	synced := OtherHead{Creator: 1}
+	others := []OtherHead{{Creator: 2}, {Creator: 3}}
+
+	// never referenced ones come first, then the least recently referenced
+	for i, expected := range []uint64{1, 2, 3, 1, 2, 3} {
+		head := s.Select(synced, others)
+		if head.Creator != expected {
+			t.Fatalf("choice %d: expected creator %d, got %d", i, expected, head.Creator)
+		}
+		s.Referenced(head.Creator)
+	}
+	s.Referenced(2)
+	s.Referenced(1)
+	if head := s.Select(synced, others); head.Creator != 3 {
+		t.Fatalf("expected creator 3, got %d", head.Creator)
+	}
+}
+
+// otherParentInDegrees makes the first of four cores sync only with the
+// second, which has the events of the others, and returns the number of
+// events of the first core on the events of each creator
+func otherParentInDegrees(t *testing.T, strategy string, rounds int) map[uint64]int {
+	cores, _, _ := initCores(4, t)
+	cores[0].otherParents = NewOtherParentSelector(strategy)
+
+	for r := 0; r < rounds; r++ {
+		payload := [][]byte{[]byte(fmt.Sprintf("round %d", r))}
+		for _, s := range [][2]int{{1, 2}, {1, 3}, {2, 1}, {3, 1}, {1, 0}} {
+			if err := synchronizeCores(cores, s[0], s[1], payload); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	store := cores[0].poset.Store
+	hashes, err := store.ParticipantEvents(cores[0].HexID(), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inDegrees := make(map[uint64]int)
+	for _, hash := range hashes {
+		ev, err := store.GetEventBlock(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if otherParent := ev.OtherParent(); otherParent.Zero() {
+			continue
+		}
+		if s, ok := cores[0].OtherParentStrategy(hash); !ok || s != strategy {
+			t.Fatalf("%s: expected the event recorded with its strategy, got %q", strategy, s)
+		}
+		// the chosen other-parent is an event the core has
+		other, err := store.GetEventBlock(ev.OtherParent())
+		if err != nil {
+			t.Fatalf("%s: other-parent: %v", strategy, err)
+		}
+		creator, ok := cores[0].participants.ReadByPubKey(other.GetCreator())
+		if !ok || creator.Message.PubKeyHex == cores[0].HexID() {
+			t.Fatalf("%s: unexpected other-parent creator %s", strategy, other.GetCreator())
+		}
+		inDegrees[creator.ID]++
+	}
+
+	stats := cores[0].GetOtherParentStats()
+	if stats.Selector != strategy || stats.Events != uint64(rounds) {
+		t.Fatalf("%s: expected %d events in the stats, got %d of %s",
+			strategy, rounds, stats.Events, stats.Selector)
+	}
+	return inDegrees
+}
+
+// spread returns the difference between the most and the least referenced
+// of the three other creators
+func spread(inDegrees map[uint64]int) int {
+	min, max := -1, 0
+	for _, n := range inDegrees {
+		if n > max {
+			max = n
+		}
+	}
+	if len(inDegrees) == 3 {
+		for _, n := range inDegrees {
+			if min < 0 || n < min {
+				min = n
+			}
+		}
+	} else {
+		min = 0
+	}
+	return max - min
+}
+
+func TestOtherParentSelectors(t *testing.T) {
+	const rounds = 12
+
+	lastSync := otherParentInDegrees(t, OtherParentLastSync, rounds)
+	if len(lastSync) != 1 {
+		t.Fatalf("expected every event on the peer synced with, got %v", lastSync)
+	}
+	otherParentInDegrees(t, OtherParentRandomKnown, rounds)
+	mostStarved := otherParentInDegrees(t, OtherParentMostStarved, rounds)
+
+	if s := spread(mostStarved); s > 1 || s >= spread(lastSync) {
+		t.Fatalf("expected most-starved to even out the in-degrees, got %v against %v",
+			mostStarved, lastSync)
+	}
+}
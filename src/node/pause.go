@@ -0,0 +1,135 @@
+package node
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SamuelMarks/dag1/src/peer"
+)
+
+// ErrNodePaused is returned to peers pushing events to a paused node
+var ErrNodePaused = fmt.Errorf("node is paused")
+
+// Pause stops the node from gossiping and creating events, while it keeps
+// answering sync requests from its current store. It returns once in-flight
+// gossip and event insertion have finished, so that the store can be
+// snapshotted. Submitted transactions queue until Resume.
+func (n *Node) Pause() error {
+	n.pauseLock.Lock()
+	if n.resumeCh != nil {
+		drainedCh := n.drainedCh
+		n.pauseLock.Unlock()
+		return n.waitDrained(drainedCh)
+	}
+	if state := n.getState(); state != Gossiping && state != CatchingUp {
+		n.pauseLock.Unlock()
+		return fmt.Errorf("cannot pause a node in the %s state", state)
+	}
+	n.resumeCh = make(chan struct{})
+	n.drainedCh = make(chan struct{})
+	drainedCh := n.drainedCh
+	close(n.pauseCh)
+	n.pauseLock.Unlock()
+
+	n.logger.Info("Pausing")
+	return n.waitDrained(drainedCh)
+}
+
+// Resume undoes Pause, the node gossips again and the transactions queued
+// while paused are included in its next events.
+func (n *Node) Resume() {
+	n.pauseLock.Lock()
+	if n.resumeCh == nil {
+		n.pauseLock.Unlock()
+		return
+	}
+	n.logger.Info("Resuming")
+	close(n.resumeCh)
+	n.resumeCh = nil
+	n.drainedCh = nil
+	n.pauseCh = make(chan struct{})
+	n.pauseLock.Unlock()
+
+	n.resetTimer()
+}
+
+// IsPaused returns true between Pause and Resume
+func (n *Node) IsPaused() bool {
+	return n.pausedResumeCh() != nil
+}
+
+// pausedResumeCh returns the channel closed by Resume, or nil when the node
+// is not paused
+func (n *Node) pausedResumeCh() chan struct{} {
+	n.pauseLock.Lock()
+	defer n.pauseLock.Unlock()
+	return n.resumeCh
+}
+
+func (n *Node) waitDrained(drainedCh chan struct{}) error {
+	select {
+	case <-drainedCh:
+		return nil
+	case <-n.shutdownCh:
+		return fmt.Errorf("node shut down while pausing")
+	}
+}
+
+// paused waits for the routines started while gossiping, then answers RPCs
+// without inserting events until the node is resumed or shut down.
+func (n *Node) paused() {
+	n.pauseLock.Lock()
+	resumeCh, drainedCh := n.resumeCh, n.drainedCh
+	n.pauseLock.Unlock()
+	if resumeCh == nil {
+		return
+	}
+
+	n.waitRoutines()
+	n.waitConsensusPass()
+	n.setState(Paused)
+	select {
+	case <-drainedCh:
+		// paused again after the transport closed
+	default:
+		close(drainedCh)
+	}
+
+	defer func() {
+		if n.getState() == Paused {
+			n.setState(Gossiping)
+		}
+	}()
+	for {
+		select {
+		case rpc, ok := <-n.trans.ReceiverChannel():
+			if !ok {
+				return
+			}
+			n.goFunc(func() {
+				n.rpcJobs.increment()
+				n.processPausedRPC(rpc)
+				n.rpcJobs.decrement()
+			})
+		case <-n.controlTimer.tickCh:
+			// keep the timer going so that resetTimer never blocks
+			n.logStats()
+			n.reportReady()
+		case <-resumeCh:
+			return
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// processPausedRPC answers the requests served from the store as usual and
+// refuses pushed events
+func (n *Node) processPausedRPC(rpc *peer.RPC) {
+	if _, ok := rpc.Command.(*peer.ForceSyncRequest); ok {
+		// TODO: context.Background
+		rpc.SendResult(context.Background(), n.logger, nil, ErrNodePaused)
+		return
+	}
+	n.processRPC(rpc)
+}
@@ -71,3 +71,68 @@ func (ps *RandomPeerSelector) Next() *peers.Peer {
 
 	return peer
 }
+
+// withNews returns the peers whose advertised head the node lacks, unless
+// none remains besides the local one and the last one. A sync with a peer
+// whose advertised head the node has most likely brings no events, and one
+// which advertised nothing may well be down.
+func withNews(src []*peers.Peer, localAddr, last string, caughtUp map[string]bool) []*peers.Peer {
+	if len(caughtUp) == 0 {
+		return src
+	}
+	var res []*peers.Peer
+	news := false
+	for _, p := range src {
+		if done, ok := caughtUp[p.Message.PubKeyHex]; !ok || done {
+			continue
+		}
+		res = append(res, p)
+		if p.Message.NetAddr != localAddr && p.Message.NetAddr != last &&
+			p.Message.PubKeyHex != last {
+			news = true
+		}
+	}
+	if !news {
+		return src
+	}
+	return res
+}
+
+// banFilter wraps the peer selector of a node, whatever its kind, to skip
+// the banned peers
+type banFilter struct {
+	PeerSelector
+	health    *peerHealth
+	localAddr string
+}
+
+func newBanFilter(selector PeerSelector, health *peerHealth, localAddr string) *banFilter {
+	return &banFilter{
+		PeerSelector: selector,
+		health:       health,
+		localAddr:    localAddr,
+	}
+}
+
+// Next returns the peer the selector picks unless it is banned. When the
+// selector keeps picking banned peers another peer is drawn at random. It
+// returns nil when every peer is banned.
+func (f *banFilter) Next() *peers.Peer {
+	for i := 0; i <= f.Peers().Len(); i++ {
+		peer := f.PeerSelector.Next()
+		if peer == nil || !f.health.banned(peer.ID) {
+			return peer
+		}
+	}
+
+	var allowed []*peers.Peer
+	for _, peer := range peers.ExcludePeers(f.Peers().ToPeerSlice(), f.localAddr, "") {
+		if !f.health.banned(peer.ID) {
+			allowed = append(allowed, peer)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	return allowed[rand.Intn(len(allowed))]
+}
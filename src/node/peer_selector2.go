@@ -10,17 +10,23 @@ import (
 // GetFlagTableFn declares flag table function signature
 type GetFlagTableFn func() (map[string]int64, error)
 
+// GetCaughtUpFn declares the function mapping the public keys of the peers
+// which advertised a head to whether it is known already
+type GetCaughtUpFn func() map[string]bool
+
 // SmartPeerSelector provides selection based on FlagTable of a randomly chosen undermined event
 type SmartPeerSelector struct {
 	peers        *peers.Peers
 	localAddr    string
 	last         string
 	GetFlagTable GetFlagTableFn
+	GetCaughtUp  GetCaughtUpFn
 }
 
 // SmartPeerSelectorCreationFnArgs specifies which additional arguments are required to create a SmartPeerSelector
 type SmartPeerSelectorCreationFnArgs struct {
 	GetFlagTable GetFlagTableFn
+	GetCaughtUp  GetCaughtUpFn
 	LocalAddr    string
 }
 
@@ -31,6 +37,7 @@ func NewSmartPeerSelector(participants *peers.Peers, args SmartPeerSelectorCreat
 		localAddr:    args.LocalAddr,
 		peers:        participants,
 		GetFlagTable: args.GetFlagTable,
+		GetCaughtUp:  args.GetCaughtUp,
 	}
 }
 
@@ -61,11 +68,15 @@ func (ps *SmartPeerSelector) Next() *peers.Peer {
 	if err != nil {
 		flagTable = nil
 	}
+	var caughtUp map[string]bool
+	if ps.GetCaughtUp != nil {
+		caughtUp = ps.GetCaughtUp()
+	}
 
 	ps.peers.Lock()
 	defer ps.peers.Unlock()
 
-	sortedSrc := ps.peers.ToPeerByUsedSlice()
+	sortedSrc := withNews(ps.peers.ToPeerByUsedSlice(), ps.localAddr, ps.last, caughtUp)
 	n := int(2*len(sortedSrc)/3 + 1)
 	if n < len(sortedSrc) {
 		sortedSrc = sortedSrc[0:n]
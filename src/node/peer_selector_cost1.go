@@ -10,22 +10,25 @@ import (
 // FairPeerSelector provides selection to prevent lazy node creation
 type FairPeerSelector struct {
 	// kPeerSize uint64
-	last      string
-	localAddr string
-	peers     *peers.Peers
+	last        string
+	localAddr   string
+	peers       *peers.Peers
+	GetCaughtUp GetCaughtUpFn
 }
 
 // FairPeerSelectorCreationFnArgs specifies which additional arguments are require to create a FairPeerSelector
 type FairPeerSelectorCreationFnArgs struct {
-	KPeerSize uint64
-	LocalAddr string
+	KPeerSize   uint64
+	LocalAddr   string
+	GetCaughtUp GetCaughtUpFn
 }
 
 // NewFairPeerSelector creates a new fair peer selection struct
 func NewFairPeerSelector(participants *peers.Peers, args FairPeerSelectorCreationFnArgs) *FairPeerSelector {
 	return &FairPeerSelector{
-		localAddr: args.LocalAddr,
-		peers:     participants,
+		localAddr:   args.LocalAddr,
+		peers:       participants,
+		GetCaughtUp: args.GetCaughtUp,
 		// kPeerSize: args.KPeerSize,
 	}
 }
@@ -65,11 +68,15 @@ func (ps *FairPeerSelector) Next() *peers.Peer {
 	// if maxPeers == 0 {
 	// 	maxPeers = 1
 	// }
+	var caughtUp map[string]bool
+	if ps.GetCaughtUp != nil {
+		caughtUp = ps.GetCaughtUp()
+	}
 
 	ps.peers.Lock()
 	defer ps.peers.Unlock()
 
-	sortedSrc := ps.peers.ToPeerByUsedSlice()
+	sortedSrc := withNews(ps.peers.ToPeerByUsedSlice(), ps.localAddr, ps.last, caughtUp)
 	var lastUsed []*peers.Peer
 
 	minCost := math.Inf(1)
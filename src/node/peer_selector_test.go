@@ -24,11 +24,10 @@ func fakePeers(n int) *peers.Peers {
 	participants := peers.NewPeers()
 	for i := 0; i < n; i++ {
 		key, _ := crypto.GenerateECDSAKey()
-		peer := peers.Peer{
-			NetAddr:   fakeAddr(i),
-			PubKeyHex: fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)),
-		}
-		participants.AddPeer(&peer)
+		peer := peers.NewPeer(
+			fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)),
+			fakeAddr(i))
+		participants.AddPeer(peer)
 	}
 	return participants
 }
@@ -0,0 +1,27 @@
+package node
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/version"
+)
+
+// learnPeerVersion records the release the participant at a network address
+// told in its hello, and warns when it is another minor release than ours.
+// The protocol negotiation refuses the incompatible ones.
+func (n *Node) learnPeerVersion(netAddr, release string) {
+	old, ok := n.core.participants.SetVersionByNetAddr(netAddr, release)
+	if !ok || old == release {
+		return
+	}
+	fields := logrus.Fields{
+		"peer_addr":     netAddr,
+		"their_release": release,
+		"our_release":   version.Version,
+	}
+	if !version.SameMinor(release) {
+		n.logger.WithFields(fields).Warn("Peer runs another minor release")
+		return
+	}
+	n.logger.WithFields(fields).Debug("Peer release")
+}
@@ -0,0 +1,151 @@
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+const (
+	// maxPendingEvents bounds the events waiting for their other-parent
+	maxPendingEvents = 1000
+	// pendingEventTTL is how long an event waits for its other-parent before
+	// it is dropped. Dropped events are not known, so a later sync requests
+	// them again.
+	pendingEventTTL = 30 * time.Second
+)
+
+// errPendingEventsFull is returned by Sync when an event has to wait for its
+// other-parent but maxPendingEvents already do
+var errPendingEventsFull = fmt.Errorf("pending events queue full")
+
+type pendingEvent struct {
+	wire  poset.WireEvent
+	added time.Time
+}
+
+// pendingEvents are the synced events whose other-parent is not known yet,
+// in arrival order so that self-parents come before their children. They are
+// only accessed by Sync, under the node's core lock.
+type pendingEvents struct {
+	events []pendingEvent
+	now    func() time.Time
+}
+
+func newPendingEvents() *pendingEvents {
+	return &pendingEvents{now: time.Now}
+}
+
+// Len returns the number of pending events
+func (q *pendingEvents) Len() int {
+	return len(q.events)
+}
+
+// has returns true if the event of the creator with the index is pending
+func (q *pendingEvents) has(creatorID uint64, index int64) bool {
+	for _, pe := range q.events {
+		if pe.wire.Body.CreatorID == creatorID && pe.wire.Body.Index == index {
+			return true
+		}
+	}
+	return false
+}
+
+// add queues an event unless it is already pending, it returns false when
+// the queue is full
+func (q *pendingEvents) add(we poset.WireEvent) bool {
+	if q.has(we.Body.CreatorID, we.Body.Index) {
+		return true
+	}
+	if len(q.events) >= maxPendingEvents {
+		return false
+	}
+	q.events = append(q.events, pendingEvent{wire: we, added: q.now()})
+	return true
+}
+
+// syncEvent inserts an event received by Sync, or queues it when its
+// other-parent, or the self-parent it waits for, is not known yet
+func (c *Core) syncEvent(we poset.WireEvent, known map[uint64]int64,
+	verified verifiedEvents) error {
+	if !c.pending.has(we.Body.CreatorID, we.Body.SelfParentIndex) {
+		err := c.insertWireEvent(we, known, verified)
+		if !poset.IsMissingParent(err) {
+			return err
+		}
+	}
+	if !c.pending.add(we) {
+		return errPendingEventsFull
+	}
+	c.logger.WithFields(logrus.Fields{
+		"creator_id": we.Body.CreatorID,
+		"index":      we.Body.Index,
+		"pending":    c.pending.Len(),
+	}).Debug("event waits for its other-parent")
+	return nil
+}
+
+// insertWireEvent inserts a wire event unless it is already known. Its
+// signature is only verified if it is not among the verified events.
+func (c *Core) insertWireEvent(we poset.WireEvent, known map[uint64]int64,
+	verified verifiedEvents) error {
+	ev, err := c.poset.ReadWireInfo(we)
+	if err != nil {
+		return err
+	}
+	if ev.Index() <= known[ev.CreatorID()] {
+		c.duplicates.increment()
+		return nil
+	}
+	ev.SetLamportTimestamp(poset.LamportTimestampNIL)
+	if verified.has(ev) {
+		err = c.insertPreverifiedEvent(*ev)
+	} else {
+		err = c.InsertEvent(*ev, false)
+	}
+	if err == poset.ErrAlreadyKnown {
+		// stored already, above a gap the known events stop at
+		c.duplicates.increment()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	known[ev.CreatorID()] = ev.Index()
+	return nil
+}
+
+// retryPendingEvents inserts the pending events whose parents are now known,
+// until no more can be. Expired events, and those which fail for another
+// reason than a missing other-parent, are dropped.
+func (c *Core) retryPendingEvents(known map[uint64]int64) {
+	for progress := true; progress; {
+		progress = false
+		events := c.pending.events
+		c.pending.events = nil
+		for _, pe := range events {
+			body := pe.wire.Body
+			fields := logrus.Fields{"creator_id": body.CreatorID, "index": body.Index}
+			if c.pending.now().Sub(pe.added) > pendingEventTTL {
+				c.logger.WithFields(fields).Warn("pending event expired")
+				continue
+			}
+			if c.pending.has(body.CreatorID, body.SelfParentIndex) {
+				c.pending.events = append(c.pending.events, pe)
+				continue
+			}
+			err := c.insertWireEvent(pe.wire, known, nil)
+			switch {
+			case err == nil:
+				progress = true
+			case poset.IsMissingParent(err):
+				c.pending.events = append(c.pending.events, pe)
+			default:
+				c.logger.WithFields(fields).WithField("error", err).Warn("pending event dropped")
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// initPendingCores returns n cores of unit weight participants, each with its
+// own copy of the participants since cores track the participants' heights
+func initPendingCores(n int, t *testing.T) []*Core {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateECDSAKey()
+	}
+	cores := make([]*Core, n)
+	for i := range cores {
+		participants := peers.NewPeers()
+		for _, key := range keys {
+			pubHex := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey))
+			peer := peers.NewPeer(pubHex, "")
+			participants.AddPeer(peer)
+			participants.SetPeerWeight(peer, 1)
+		}
+		pubHex := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&keys[i].PublicKey))
+		self, _ := participants.ReadByPubKey(pubHex)
+		var err error
+		cores[i], err = NewCore(self.ID, keys[i], participants,
+			poset.NewInmemStore(participants, 1000, nil), nil,
+			common.NewTestLogger(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cores[i].SetHeadAndHeight(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cores
+}
+
+// diff returns the events of from unknown to to, in wire format
+func diff(from, to *Core, t *testing.T) []poset.WireEvent {
+	events, err := from.EventDiff(to.KnownEvents())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wireEvents, err := from.ToWire(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wireEvents
+}
+
+// addEvent makes c create an event with the last event of other it knows
+// as other-parent
+func addEvent(c, other *Core, t *testing.T) {
+	otherHead, _, err := c.poset.Store.LastEventFrom(other.HexID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddSelfEventBlock(otherHead); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// peerOf returns the peer of core c as known by core of
+func peerOf(of, c *Core) *peers.Peer {
+	p, _ := of.participants.ReadByID(c.ID())
+	return &p
+}
+
+func TestSyncPendingOtherParent(t *testing.T) {
+	cores := initPendingCores(5, t)
+	a, b, c := cores[0], cores[1], cores[2]
+
+	// b1 <- c1 <- c2: c1 has b1 as other-parent
+	addEvent(b, a, t)
+	if err := c.Sync(peerOf(c, b), diff(b, c, t)); err != nil {
+		t.Fatal(err)
+	}
+	addEvent(c, b, t)
+	addEvent(c, a, t)
+
+	// a hears of c's events before b's
+	fromC := diff(c, a, t)
+	var cOnly []poset.WireEvent
+	for _, we := range fromC {
+		if we.Body.CreatorID == c.ID() {
+			cOnly = append(cOnly, we)
+		}
+	}
+	if len(cOnly) != 2 {
+		t.Fatalf("expected 2 events of c, got %d", len(cOnly))
+	}
+	if err := a.Sync(peerOf(a, c), cOnly); err != nil {
+		t.Fatal(err)
+	}
+	if l := a.pending.Len(); l != 2 {
+		t.Fatalf("expected 2 pending events, got %d", l)
+	}
+	if h := a.KnownEvents()[c.ID()]; h != 0 {
+		t.Fatalf("expected no event of c to be inserted, got height %d", h)
+	}
+
+	// the next sync brings the other-parent in
+	if err := a.Sync(peerOf(a, b), diff(b, a, t)); err != nil {
+		t.Fatal(err)
+	}
+	if l := a.pending.Len(); l != 0 {
+		t.Fatalf("expected no pending events, got %d", l)
+	}
+	if !reflect.DeepEqual(a.KnownEvents(), c.KnownEvents()) {
+		t.Fatalf("expected a to know %v, got %v", c.KnownEvents(), a.KnownEvents())
+	}
+}
+
+func TestSyncPendingExpiry(t *testing.T) {
+	cores := initPendingCores(5, t)
+	a, b, c := cores[0], cores[1], cores[2]
+
+	addEvent(b, a, t)
+	if err := c.Sync(peerOf(c, b), diff(b, c, t)); err != nil {
+		t.Fatal(err)
+	}
+	addEvent(c, b, t)
+
+	now := time.Now()
+	a.pending.now = func() time.Time { return now }
+	var cOnly []poset.WireEvent
+	for _, we := range diff(c, a, t) {
+		if we.Body.CreatorID == c.ID() {
+			cOnly = append(cOnly, we)
+		}
+	}
+	if err := a.Sync(peerOf(a, c), cOnly); err != nil {
+		t.Fatal(err)
+	}
+	if l := a.pending.Len(); l != 1 {
+		t.Fatalf("expected 1 pending event, got %d", l)
+	}
+
+	// the other-parent never comes: the event is dropped, and a full sync
+	// requests it again
+	now = now.Add(pendingEventTTL + time.Second)
+	if err := a.Sync(peerOf(a, c), nil); err != nil {
+		t.Fatal(err)
+	}
+	if l := a.pending.Len(); l != 0 {
+		t.Fatalf("expected the pending event to expire, got %d", l)
+	}
+	if err := a.Sync(peerOf(a, c), diff(c, a, t)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a.KnownEvents(), c.KnownEvents()) {
+		t.Fatalf("expected a to know %v, got %v", c.KnownEvents(), a.KnownEvents())
+	}
+}
@@ -0,0 +1,54 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoQuorum is returned for a transaction submitted while the node has not
+// synced with enough peers to create events, see Config.RequireQuorum
+var ErrNoQuorum = errors.New("no quorum")
+
+// quorumPeers returns the weight of the other participants the node must
+// have synced with to create events, that which with its own makes the
+// supermajority of them. Participants count one each unless stakes were
+// given. It is 0 when no quorum is required, as for a single participant
+// or a development network of single supermajorities.
+func (n *Node) quorumPeers() int {
+	if !n.conf.RequireQuorum {
+		return 0
+	}
+	participants := n.core.participants
+	superMajority, own := participants.GetSuperMajority(), participants.WeightOf(n.id)
+	if own >= superMajority {
+		return 0
+	}
+	return int(superMajority - own)
+}
+
+// quorumSynced returns the weight of the distinct peers the node synced with
+// in the last readySyncWindow
+func (n *Node) quorumSynced() int {
+	since := n.clock.Now().Add(-n.readySyncWindow())
+	return int(n.health.syncedWeightSince(since, n.core.participants.WeightOf))
+}
+
+// HasQuorum returns true when the node may create events other than the
+// empty ones: it synced with peers of weight quorumPeers recently, or needs
+// no quorum. Without one the events it created alone would conflict with
+// the view of the network once it reaches it.
+func (n *Node) HasQuorum() bool {
+	return n.quorumErr() == nil
+}
+
+// quorumErr says why the node has no quorum, nil when it has one
+func (n *Node) quorumErr() error {
+	required := n.quorumPeers()
+	if required == 0 {
+		return nil
+	}
+	if synced := n.quorumSynced(); synced < required {
+		return fmt.Errorf("synced with peers of weight %d of the %d of a quorum", synced, required)
+	}
+	return nil
+}
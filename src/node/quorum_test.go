@@ -0,0 +1,119 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+func TestQuorumPeersStake(t *testing.T) {
+	participants := peers.NewPeers()
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateECDSAKey()
+		participants.AddPeer(peers.NewPeer(
+			fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)), ""))
+	}
+	slice := participants.ToPeerSlice()
+	n := &Node{
+		conf: &Config{RequireQuorum: true},
+		core: &Core{participants: participants},
+		id:   slice[0].ID,
+	}
+
+	// 3 of 4 without stakes, 2 of them others
+	if required := n.quorumPeers(); required != 2 {
+		t.Fatalf("expected a quorum of 2 without stakes, got %d", required)
+	}
+	// a supermajority of 7 of the stake of 10, 5 of it others
+	for i, w := range []uint64{2, 1, 3, 4} {
+		participants.SetPeerWeight(slice[i], w)
+	}
+	if required := n.quorumPeers(); required != 5 {
+		t.Fatalf("expected a quorum of 5 with stakes, got %d", required)
+	}
+	// the node alone is a supermajority
+	participants.SetPeerWeight(slice[0], 30)
+	if required := n.quorumPeers(); required != 0 {
+		t.Fatalf("expected no quorum for a supermajority alone, got %d", required)
+	}
+	participants.SetPeerWeight(slice[0], 2)
+	participants.SetDevSingleSuperMajority(true)
+	if required := n.quorumPeers(); required != 0 {
+		t.Fatalf("expected no quorum with a dev single supermajority, got %d", required)
+	}
+}
+
+func TestQuorumGuard(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+	config := *data.Config
+	config.RequireQuorum = true
+
+	start := func(i int) *Node {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		node := createNode(t, data.Logger, &config, data.PeersSlice[i].ID,
+			data.Keys[i], data.Peers, trans, data.Adds[i], true)
+		return node
+	}
+	// noEvents fails when the node created an event of its own, beyond the
+	// leaf event it starts from
+	noEvents := func(n *Node) {
+		if head, err := lastHead(n.core.poset.Store, n.core.HexID()); err != nil {
+			t.Fatal(err)
+		} else if head.Index > 0 {
+			t.Fatal("expected no event created without a quorum")
+		}
+	}
+	waitFor := func(cond func() bool, what string) {
+		timeout := time.After(20 * time.Second)
+		for !cond() {
+			select {
+			case <-timeout:
+				t.Fatalf("timeout waiting for %s", what)
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}
+
+	// the first node is alone, then with a peer of the two it needs
+	nodes := []*Node{start(0)}
+	defer func() {
+		for _, n := range nodes {
+			n.Shutdown()
+		}
+	}()
+	if required := nodes[0].quorumPeers(); required != 2 {
+		t.Fatalf("expected a quorum of 2 peers, got %d", required)
+	}
+	for i := 0; i < 10; i++ {
+		if err := submitTransaction(nodes[0], []byte(fmt.Sprintf("alone %d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := nodes[0].SubmitTxWithResult([]byte("refused")); err != ErrNoQuorum {
+		t.Fatalf("expected %v, got %v", ErrNoQuorum, err)
+	}
+	nodes = append(nodes, start(1))
+	waitFor(func() bool { return nodes[0].quorumSynced() >= 1 }, "a sync with the peer")
+	noEvents(nodes[0])
+	if stats := nodes[0].GetStats(); stats["quorum"] != "false" {
+		t.Fatalf("expected no quorum in the stats, got %s", stats["quorum"])
+	}
+	if err := nodes[0].Ready(); err == nil {
+		t.Fatal("expected a node without a quorum not to be ready")
+	}
+
+	// the rest of the peers come up
+	nodes = append(nodes, start(2), start(3))
+	waitFor(nodes[0].HasQuorum, "the quorum")
+	if err := nodes[0].SubmitTxWithResult([]byte("accepted")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bombardAndWait(nodes, 1, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	checkGossip(nodes, 0, t)
+}
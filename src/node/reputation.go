@@ -0,0 +1,179 @@
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// reputationSaveInterval is how often the reputation of the peers is saved
+// to the store when it changed
+const reputationSaveInterval = 30 * time.Second
+
+// PeerReputation is the reputation of a peer, as saved in the store, with
+// the peer it is about
+type PeerReputation struct {
+	ID      uint64 `json:"id"`
+	PubKey  string `json:"pub_key"`
+	NetAddr string `json:"net_addr"`
+	Banned  bool   `json:"banned"`
+	poset.PeerReputation
+}
+
+// ban keeps the peer out of gossip until the given time
+func (h *peerHealth) ban(id uint64, until time.Time) {
+	h.Lock()
+	defer h.Unlock()
+	h.bannedUntil[id] = until
+	h.dirty = true
+}
+
+// unban lifts a ban of the peer
+func (h *peerHealth) unban(id uint64) {
+	h.Lock()
+	defer h.Unlock()
+	delete(h.bannedUntil, id)
+	h.dirty = true
+}
+
+// banned returns true while a ban of the peer lasts
+func (h *peerHealth) banned(id uint64) bool {
+	h.Lock()
+	defer h.Unlock()
+	return h.clock.Now().Before(h.bannedUntil[id])
+}
+
+// reputations returns the reputation of the participants, by public key
+func (h *peerHealth) reputations() map[string]poset.PeerReputation {
+	h.Lock()
+	defer h.Unlock()
+	res := make(map[string]poset.PeerReputation)
+	for _, p := range h.participants.ToPeerSlice() {
+		seen, ok := h.lastSeen[p.ID]
+		if !ok {
+			seen = h.seenBefore[p.ID]
+		}
+		res[p.Message.PubKeyHex] = poset.PeerReputation{
+			Failures:    h.failures[p.ID],
+			Penalties:   h.penalties[p.ID],
+			LastSeen:    seen,
+			BannedUntil: h.bannedUntil[p.ID],
+		}
+	}
+	return res
+}
+
+// load restores the reputation of the participants saved by an earlier run.
+// When the peers were last seen only tells about that run, it does not make
+// them reachable now.
+func (h *peerHealth) load(reps map[string]poset.PeerReputation) {
+	h.Lock()
+	defer h.Unlock()
+	for pubKey, rep := range reps {
+		p, ok := h.participants.ReadByPubKey(pubKey)
+		if !ok {
+			continue
+		}
+		h.failures[p.ID] = rep.Failures
+		h.penalties[p.ID] = rep.Penalties
+		h.seenBefore[p.ID] = rep.LastSeen
+		if !rep.BannedUntil.IsZero() {
+			h.bannedUntil[p.ID] = rep.BannedUntil
+		}
+	}
+}
+
+// loadReputations restores the reputation of the peers from the store
+func (n *Node) loadReputations() error {
+	reps, err := n.core.poset.Store.PeerReputations()
+	if err != nil {
+		return err
+	}
+	n.health.load(reps)
+	return nil
+}
+
+// saveReputations saves the reputation of the peers to the store when it
+// changed, at most every reputationSaveInterval unless forced
+func (n *Node) saveReputations(force bool) {
+	n.health.Lock()
+	due := n.health.dirty &&
+		(force || n.clock.Now().Sub(n.health.saved) >= reputationSaveInterval)
+	if due {
+		n.health.dirty = false
+		n.health.saved = n.clock.Now()
+	}
+	n.health.Unlock()
+	if !due {
+		return
+	}
+	if err := n.core.poset.Store.SetPeerReputations(n.health.reputations()); err != nil {
+		n.logger.WithError(err).Error("Saving the reputation of the peers")
+		n.health.Lock()
+		n.health.dirty = true
+		n.health.Unlock()
+	}
+}
+
+// BanPeer keeps a participant out of gossip for the given duration, and
+// refuses its requests meanwhile
+func (n *Node) BanPeer(id uint64, duration time.Duration) error {
+	if _, ok := n.core.participants.ReadByID(id); !ok {
+		return fmt.Errorf("unknown peer %d", id)
+	}
+	if duration <= 0 {
+		return fmt.Errorf("invalid ban duration %s", duration)
+	}
+	n.health.ban(id, n.clock.Now().Add(duration))
+	n.saveReputations(true)
+	return nil
+}
+
+// UnbanPeer lifts a ban of a participant
+func (n *Node) UnbanPeer(id uint64) error {
+	if _, ok := n.core.participants.ReadByID(id); !ok {
+		return fmt.Errorf("unknown peer %d", id)
+	}
+	n.health.unban(id)
+	n.saveReputations(true)
+	return nil
+}
+
+// GetPeerReputations returns the reputation of the participants, sorted by ID
+func (n *Node) GetPeerReputations() []PeerReputation {
+	reps := n.health.reputations()
+	now := n.clock.Now()
+	res := make([]PeerReputation, 0, len(reps))
+	for _, p := range n.core.participants.ToPeerSlice() {
+		rep := reps[p.Message.PubKeyHex]
+		res = append(res, PeerReputation{
+			ID:             p.ID,
+			PubKey:         p.Message.PubKeyHex,
+			NetAddr:        p.Message.NetAddr,
+			Banned:         rep.Banned(now),
+			PeerReputation: rep,
+		})
+	}
+	return res
+}
+
+// requesterID returns the ID of the peer sending a request
+func requesterID(cmd interface{}) (uint64, bool) {
+	switch cmd := cmd.(type) {
+	case *peer.SyncRequest:
+		return cmd.FromID, true
+	case *peer.ForceSyncRequest:
+		return cmd.FromID, true
+	case *peer.FastForwardRequest:
+		return cmd.FromID, true
+	case *peer.PeerLookupRequest:
+		return cmd.FromID, true
+	case *peer.SnapshotRequest:
+		return cmd.FromID, true
+	case *peer.FrameEventsRequest:
+		return cmd.FromID, true
+	}
+	return 0, false
+}
@@ -0,0 +1,93 @@
+package node
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+func TestPeerBans(t *testing.T) {
+	data := InitTestData(t, 3, 2)
+	// the node shut down for the restart may still log, not to the test
+	data.Logger = logrus.New()
+	data.Logger.Level = logrus.WarnLevel
+	data.Config.Logger = data.Logger
+	store := poset.NewInmemStore(data.Peers, data.Config.CacheSize, nil)
+	self := data.Peers.ByNetAddr[data.Adds[0]]
+	banned := data.Peers.ByNetAddr[data.Adds[1]]
+
+	// the node restarts on the same store
+	start := func(addr string) *Node {
+		trans := createTransport(t, data.Logger, data.BackConfig, addr,
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		n, err := NewNode(data.Config, self.ID, data.Keys[0], data.Peers, store, trans,
+			dummy.NewInmemDummyApp(data.Logger), NewRandomPeerSelectorWrapper,
+			RandomPeerSelectorCreationFnArgs{LocalAddr: addr}, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Init(); err != nil {
+			t.Fatal(err)
+		}
+		go n.Run(false)
+		return n
+	}
+	selects := func(n *Node, id uint64) bool {
+		for i := 0; i < 100; i++ {
+			if p := n.peerSelector.Next(); p != nil && p.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	node := start(data.Adds[0])
+	if err := node.BanPeer(banned.ID, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if selects(node, banned.ID) {
+		t.Fatal("expected the banned peer not to be selected")
+	}
+
+	// the requests of the banned peer are refused
+	trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[1],
+		data.PoolSize, data.CreateFu, data.Network.CreateListener)
+	other := createNode(t, data.Logger, data.Config, banned.ID, data.Keys[1],
+		data.Peers, trans, data.Adds[1], false)
+	defer other.Shutdown()
+	if _, _, err := other.pull(self); err == nil ||
+		!strings.Contains(err.Error(), peer.ErrRateLimited.Error()) {
+		t.Fatalf("expected the request to be rate limited, got %v", err)
+	}
+
+	node.Shutdown()
+	node = start(data.Network.RandomAddress())
+	defer node.Shutdown()
+	if !node.health.banned(banned.ID) {
+		t.Fatal("expected the ban to survive the restart")
+	}
+	for _, rep := range node.GetPeerReputations() {
+		if rep.Banned != (rep.ID == banned.ID) {
+			t.Fatalf("peer %d: expected banned to be %v", rep.ID, rep.ID == banned.ID)
+		}
+	}
+	if selects(node, banned.ID) {
+		t.Fatal("expected the banned peer not to be selected after the restart")
+	}
+
+	if err := node.UnbanPeer(banned.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !selects(node, banned.ID) {
+		t.Fatal("expected the peer to be selected once unbanned")
+	}
+	if err := node.BanPeer(12345, time.Hour); err == nil {
+		t.Fatal("expected an unknown peer not to be banned")
+	}
+}
@@ -0,0 +1,209 @@
+package node
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+const (
+	// maxReportRounds is the number of committed rounds the report keeps
+	maxReportRounds = 10000
+	// maxReportInserts is the number of events awaiting consensus whose
+	// insertion time is kept, the events inserted beyond it are not timed
+	maxReportInserts = 1 << 16
+)
+
+// RoundReport is the row of a committed round in the round report. Latency
+// is the time from the first of its events inserted to its block committed.
+type RoundReport struct {
+	Round        int64     `json:"round"`
+	Started      time.Time `json:"started"`
+	Committed    time.Time `json:"committed"`
+	Latency      float64   `json:"latency_seconds"`
+	Events       int       `json:"events"`
+	Transactions int       `json:"transactions"`
+	// LastDecider is the creator of the clotho of the round decided last
+	LastDecider string `json:"last_decider"`
+}
+
+// RoundSummary sums up a round report
+type RoundSummary struct {
+	Rounds       int     `json:"rounds"`
+	Transactions int     `json:"transactions"`
+	LatencyP50   float64 `json:"latency_p50_seconds"`
+	LatencyP95   float64 `json:"latency_p95_seconds"`
+	// Throughput is the transactions per second from the first round
+	// started to the last one committed
+	Throughput float64 `json:"throughput_tps"`
+}
+
+// roundReport times the consensus rounds from the insertion of their events
+// to the commit of their blocks. It is metadata of the node, not consensus
+// data, and is lost on restart.
+type roundReport struct {
+	sync.Mutex
+	inserted map[poset.EventHash]time.Time // events awaiting consensus
+	pending  map[int64]*RoundReport        // rounds not committed yet
+	deciders map[int64]string              // round => creator of the clotho decided last
+	rounds   []RoundReport                 // committed rounds, in order
+	now      func() time.Time
+}
+
+func newRoundReport() *roundReport {
+	return &roundReport{
+		inserted: make(map[poset.EventHash]time.Time),
+		pending:  make(map[int64]*RoundReport),
+		deciders: make(map[int64]string),
+		now:      time.Now,
+	}
+}
+
+// insert records the insertion time of an event
+func (r *roundReport) insert(ev poset.Event) {
+	r.Lock()
+	defer r.Unlock()
+	if len(r.inserted) < maxReportInserts {
+		r.inserted[ev.Hash()] = r.now()
+	}
+}
+
+// consensus is the consensus listener of the poset, it counts the event in
+// the round it is received in
+func (r *roundReport) consensus(ev poset.Event) {
+	r.Lock()
+	defer r.Unlock()
+	round := r.pendingRound(ev.FrameReceived)
+	if round == nil {
+		return
+	}
+	round.Events++
+	hash := ev.Hash()
+	if at, ok := r.inserted[hash]; ok {
+		if round.Started.IsZero() || at.Before(round.Started) {
+			round.Started = at
+		}
+		delete(r.inserted, hash)
+	}
+}
+
+// decided is the decision listener of the poset
+func (r *roundReport) decided(round int64, creator string) {
+	r.Lock()
+	defer r.Unlock()
+	if n := len(r.rounds); n > 0 && round <= r.rounds[n-1].Round {
+		return
+	}
+	r.deciders[round] = creator
+}
+
+// committed completes the row of the round of a block. The blocks a round
+// is split in add up in its row.
+func (r *roundReport) committed(block poset.Block) {
+	r.Lock()
+	defer r.Unlock()
+	index := block.RoundReceived()
+	txs := len(block.Transactions())
+	if n := len(r.rounds); n > 0 && r.rounds[n-1].Round >= index {
+		if r.rounds[n-1].Round == index {
+			r.rounds[n-1].Transactions += txs
+		}
+		return
+	}
+	row := RoundReport{Round: index}
+	if round, ok := r.pending[index]; ok {
+		row = *round
+	}
+	row.Committed = r.now()
+	row.Transactions = txs
+	row.LastDecider = r.deciders[index]
+	if !row.Started.IsZero() {
+		row.Latency = row.Committed.Sub(row.Started).Seconds()
+	}
+	r.rounds = append(r.rounds, row)
+	if len(r.rounds) > maxReportRounds {
+		r.rounds = append(r.rounds[:0:0], r.rounds[len(r.rounds)-maxReportRounds:]...)
+	}
+	for round := range r.pending {
+		if round <= index {
+			delete(r.pending, round)
+		}
+	}
+	for round := range r.deciders {
+		if round <= index {
+			delete(r.deciders, round)
+		}
+	}
+}
+
+// pendingRound returns the row of a round not committed yet, nil for a
+// committed one
+func (r *roundReport) pendingRound(index int64) *RoundReport {
+	if n := len(r.rounds); n > 0 && index <= r.rounds[n-1].Round {
+		return nil
+	}
+	round, ok := r.pending[index]
+	if !ok {
+		round = &RoundReport{Round: index}
+		r.pending[index] = round
+	}
+	return round
+}
+
+// report returns the rows of the committed rounds, in order
+func (r *roundReport) report() []RoundReport {
+	r.Lock()
+	defer r.Unlock()
+	return append([]RoundReport{}, r.rounds...)
+}
+
+// SummarizeRounds returns the percentiles of the latency of the rounds of a
+// report and the throughput of their transactions
+func SummarizeRounds(rounds []RoundReport) RoundSummary {
+	summary := RoundSummary{Rounds: len(rounds)}
+	var latencies []float64
+	var first, last time.Time
+	for _, round := range rounds {
+		summary.Transactions += round.Transactions
+		if round.Started.IsZero() {
+			continue
+		}
+		latencies = append(latencies, round.Latency)
+		if first.IsZero() || round.Started.Before(first) {
+			first = round.Started
+		}
+		if round.Committed.After(last) {
+			last = round.Committed
+		}
+	}
+	if len(latencies) == 0 {
+		return summary
+	}
+	sort.Float64s(latencies)
+	summary.LatencyP50 = quantile(latencies, 0.5)
+	summary.LatencyP95 = quantile(latencies, 0.95)
+	if span := last.Sub(first).Seconds(); span > 0 {
+		summary.Throughput = float64(summary.Transactions) / span
+	}
+	return summary
+}
+
+// quantile returns the q-th quantile of sorted values, nearest rank
+func quantile(sorted []float64, q float64) float64 {
+	i := int(q*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// GetRoundReport returns the committed consensus rounds, in order, with the
+// time they took and their events and transactions
+func (n *Node) GetRoundReport() []RoundReport {
+	return n.rounds.report()
+}
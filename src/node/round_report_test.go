@@ -0,0 +1,124 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+func TestRoundReport(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	r := newRoundReport()
+	r.now = func() time.Time { return now }
+
+	// the events of round 1 are inserted a second apart, the last one is
+	// received in round 3
+	var events []poset.Event
+	for i := int64(0); i < 3; i++ {
+		ev := poset.NewEvent(nil, nil, nil, make(poset.EventHashes, 2), []byte("creator"), i,
+			poset.NewFlagTable(), poset.NewFlagTable(), 0, false)
+		ev.FrameReceived = 1 + 2*(i/2)
+		events = append(events, ev)
+		r.insert(ev)
+		now = now.Add(time.Second)
+	}
+	r.consensus(events[0])
+	r.consensus(events[1])
+	r.decided(1, "first")
+	r.decided(1, "last")
+	now = now.Add(time.Second)
+	r.committed(poset.NewBlock(0, 1, nil, [][]byte{[]byte("tx1"), []byte("tx2")}))
+	// a round split in two blocks
+	r.committed(poset.NewBlock(1, 1, nil, [][]byte{[]byte("tx3")}))
+	// late news of a committed round are ignored
+	r.decided(1, "late")
+
+	r.consensus(events[2])
+	now = now.Add(time.Second)
+	r.committed(poset.NewBlock(2, 3, nil, nil))
+
+	rounds := r.report()
+	if len(rounds) != 2 {
+		t.Fatalf("expected 2 rounds, got %d", len(rounds))
+	}
+	first := rounds[0]
+	if first.Round != 1 || first.Events != 2 || first.Transactions != 3 || first.LastDecider != "last" {
+		t.Fatalf("unexpected first round %+v", first)
+	}
+	if first.Latency != 4 {
+		t.Fatalf("expected the first round to take 4s, got %v", first.Latency)
+	}
+	if second := rounds[1]; second.Round != 3 || second.Events != 1 || second.Latency != 3 {
+		t.Fatalf("unexpected second round %+v", second)
+	}
+	if len(r.inserted) != 0 || len(r.pending) != 0 || len(r.deciders) != 0 {
+		t.Fatalf("expected nothing left of the committed rounds, got %d, %d and %d",
+			len(r.inserted), len(r.pending), len(r.deciders))
+	}
+
+	summary := SummarizeRounds(rounds)
+	if summary.Rounds != 2 || summary.Transactions != 3 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if summary.LatencyP50 != 3 || summary.LatencyP95 != 4 {
+		t.Fatalf("expected the latency p50 3s and p95 4s, got %v and %v", summary.LatencyP50, summary.LatencyP95)
+	}
+	// 3 transactions from the first event inserted to the last round
+	// committed, 5 seconds later
+	if summary.Throughput != 0.6 {
+		t.Fatalf("expected a throughput of 0.6, got %v", summary.Throughput)
+	}
+}
+
+func TestRoundReportGossip(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+	var nodes []*Node
+	for i := range data.Adds {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+		n := createNode(t, data.Logger, data.Config, data.PeersSlice[i].ID, data.Keys[i], data.Peers, trans, data.Adds[i], false)
+		defer n.Shutdown()
+		nodes = append(nodes, n)
+	}
+	const timeout = 60 * time.Second
+	if err := gossip(nodes, 3, true, timeout); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, n := range nodes {
+		rounds := n.GetRoundReport()
+		if len(rounds) == 0 {
+			t.Fatalf("node %d: expected committed rounds", i)
+		}
+		// one row per round of the committed blocks
+		blockRounds := make(map[int64]bool)
+		for b := int64(0); b <= 3; b++ {
+			if block, err := n.GetBlock(b); err == nil {
+				blockRounds[block.RoundReceived()] = true
+			}
+		}
+		reported := make(map[int64]bool)
+		for j, round := range rounds {
+			if j > 0 && round.Round <= rounds[j-1].Round {
+				t.Fatalf("node %d: expected increasing rounds, got %d after %d", i, round.Round, rounds[j-1].Round)
+			}
+			reported[round.Round] = true
+			if round.Started.IsZero() {
+				continue
+			}
+			if round.Latency < 0 || round.Latency > timeout.Seconds() {
+				t.Fatalf("node %d: round %d: unexpected latency %v", i, round.Round, round.Latency)
+			}
+			if round.Events == 0 {
+				t.Fatalf("node %d: round %d: expected its events counted", i, round.Round)
+			}
+		}
+		for round := range blockRounds {
+			if !reported[round] {
+				t.Fatalf("node %d: expected the committed round %d reported", i, round)
+			}
+		}
+	}
+}
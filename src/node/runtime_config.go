@@ -0,0 +1,318 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/log"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// The settings Reconfigure changes while the node runs, keyed as in the
+// config file and the flags of the run command
+const (
+	SettingLog              = "log"
+	SettingHeartbeat        = "heartbeat"
+	SettingHeartbeatMax     = "heartbeat-max"
+	SettingSyncLimit        = "sync-limit"
+	SettingCacheBudget      = "cache-budget"
+	SettingCacheGrowBelow   = "cache-grow-below"
+	SettingCacheShrinkAbove = "cache-shrink-above"
+	SettingMetrics          = "metrics"
+)
+
+// RuntimeSettings are the keys of the settings Reconfigure takes
+var RuntimeSettings = []string{
+	SettingLog,
+	SettingHeartbeat,
+	SettingHeartbeatMax,
+	SettingSyncLimit,
+	SettingCacheBudget,
+	SettingCacheGrowBelow,
+	SettingCacheShrinkAbove,
+	SettingMetrics,
+}
+
+// ConsensusSettings are the keys of the consensus parameters, see
+// Config.Consensus
+var ConsensusSettings = []string{
+	"max-events-per-frame",
+	"max-block-transactions",
+	"max-block-bytes",
+	"max-timestamp-drift",
+	"dev-single-supermajority",
+	"consensus-params-file",
+}
+
+// ConsensusSettingError is returned by Reconfigure for a consensus
+// parameter, which the node may not change alone
+type ConsensusSettingError struct {
+	Key string
+}
+
+func (e *ConsensusSettingError) Error() string {
+	return fmt.Sprintf("%s is a consensus parameter, which every participant must share: "+
+		"change it in the consensus-params-file distributed to all the nodes and restart them", e.Key)
+}
+
+// IsConsensusSetting returns true for a ConsensusSettingError
+func IsConsensusSetting(err error) bool {
+	_, ok := err.(*ConsensusSettingError)
+	return ok
+}
+
+// RuntimeSettingError is returned by Reconfigure for a setting it does not
+// take, or a value it refuses
+type RuntimeSettingError struct {
+	Key    string
+	Reason string
+}
+
+func (e *RuntimeSettingError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Reason)
+}
+
+// IsRuntimeSetting returns true for a RuntimeSettingError
+func IsRuntimeSetting(err error) bool {
+	_, ok := err.(*RuntimeSettingError)
+	return ok
+}
+
+// RuntimeConfig are the settings of a running node which Reconfigure
+// changes. Log is a level string of dag1_log.ParseLevels.
+type RuntimeConfig struct {
+	Log              string
+	Heartbeat        time.Duration
+	HeartbeatMax     time.Duration
+	SyncLimit        int64
+	CacheBudget      int
+	CacheGrowBelow   float64
+	CacheShrinkAbove float64
+	Metrics          bool
+}
+
+// Settings returns the config keyed as RuntimeSettings, in the format
+// Reconfigure takes
+func (c RuntimeConfig) Settings() map[string]string {
+	return map[string]string{
+		SettingLog:              c.Log,
+		SettingHeartbeat:        c.Heartbeat.String(),
+		SettingHeartbeatMax:     c.HeartbeatMax.String(),
+		SettingSyncLimit:        strconv.FormatInt(c.SyncLimit, 10),
+		SettingCacheBudget:      strconv.Itoa(c.CacheBudget),
+		SettingCacheGrowBelow:   strconv.FormatFloat(c.CacheGrowBelow, 'f', -1, 64),
+		SettingCacheShrinkAbove: strconv.FormatFloat(c.CacheShrinkAbove, 'f', -1, 64),
+		SettingMetrics:          strconv.FormatBool(c.Metrics),
+	}
+}
+
+// with returns c with the settings parsed over it. The consensus parameters
+// are refused with a ConsensusSettingError, the other keys but
+// RuntimeSettings and the values which do not parse with a
+// RuntimeSettingError.
+func (c RuntimeConfig) with(settings map[string]string) (RuntimeConfig, error) {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	// the same key is reported whatever the order of the map
+	sort.Strings(keys)
+
+	var err error
+	for _, key := range keys {
+		value := settings[key]
+		switch key {
+		case SettingLog:
+			c.Log = value
+		case SettingHeartbeat:
+			c.Heartbeat, err = time.ParseDuration(value)
+		case SettingHeartbeatMax:
+			c.HeartbeatMax, err = time.ParseDuration(value)
+		case SettingSyncLimit:
+			c.SyncLimit, err = strconv.ParseInt(value, 10, 64)
+		case SettingCacheBudget:
+			c.CacheBudget, err = strconv.Atoi(value)
+		case SettingCacheGrowBelow:
+			c.CacheGrowBelow, err = strconv.ParseFloat(value, 64)
+		case SettingCacheShrinkAbove:
+			c.CacheShrinkAbove, err = strconv.ParseFloat(value, 64)
+		case SettingMetrics:
+			c.Metrics, err = strconv.ParseBool(value)
+		default:
+			for _, k := range ConsensusSettings {
+				if k == key {
+					return c, &ConsensusSettingError{Key: key}
+				}
+			}
+			return c, &RuntimeSettingError{Key: key, Reason: "not changeable at runtime"}
+		}
+		if err != nil {
+			return c, &RuntimeSettingError{Key: key, Reason: err.Error()}
+		}
+	}
+	return c, nil
+}
+
+// Validate returns a RuntimeSettingError for the first setting out of its
+// bounds
+func (c RuntimeConfig) Validate() error {
+	if _, err := dag1_log.ParseLevels(c.Log); err != nil {
+		return &RuntimeSettingError{Key: SettingLog, Reason: err.Error()}
+	}
+	if c.Heartbeat <= 0 {
+		return &RuntimeSettingError{Key: SettingHeartbeat,
+			Reason: fmt.Sprintf("%v is not a positive duration", c.Heartbeat)}
+	}
+	if c.HeartbeatMax < 0 {
+		return &RuntimeSettingError{Key: SettingHeartbeatMax,
+			Reason: fmt.Sprintf("%v is negative", c.HeartbeatMax)}
+	}
+	if c.SyncLimit < 1 {
+		return &RuntimeSettingError{Key: SettingSyncLimit,
+			Reason: fmt.Sprintf("%d is less than 1", c.SyncLimit)}
+	}
+	if c.CacheBudget < 0 {
+		return &RuntimeSettingError{Key: SettingCacheBudget,
+			Reason: fmt.Sprintf("%d is negative", c.CacheBudget)}
+	}
+	if err := poset.CheckCacheTriggers(c.CacheGrowBelow, c.CacheShrinkAbove); err != nil {
+		return &RuntimeSettingError{Key: SettingCacheGrowBelow, Reason: err.Error()}
+	}
+	return nil
+}
+
+// runtimeSettings are the settings of RuntimeConfig the node keeps, the
+// others being kept by the loggers and the poset
+type runtimeSettings struct {
+	sync.RWMutex
+	heartbeat    time.Duration
+	heartbeatMax time.Duration
+	syncLimit    int64
+	metrics      bool
+}
+
+// RuntimeChange is a setting changed by Reconfigure
+type RuntimeChange struct {
+	Key    string `json:"key"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// GetRuntimeConfig returns the settings of the node which Reconfigure
+// changes
+func (n *Node) GetRuntimeConfig() RuntimeConfig {
+	n.runtime.RLock()
+	defer n.runtime.RUnlock()
+	return n.runtimeConfig()
+}
+
+// runtimeConfig returns the RuntimeConfig, with the lock of the settings
+// held
+func (n *Node) runtimeConfig() RuntimeConfig {
+	grow, shrink := n.core.poset.CacheTriggers()
+	return RuntimeConfig{
+		Log:              dag1_log.LevelsOf(n.conf.Logger).String(),
+		Heartbeat:        n.runtime.heartbeat,
+		HeartbeatMax:     n.runtime.heartbeatMax,
+		SyncLimit:        n.runtime.syncLimit,
+		CacheBudget:      n.core.poset.CacheBudget(),
+		CacheGrowBelow:   grow,
+		CacheShrinkAbove: shrink,
+		Metrics:          n.runtime.metrics,
+	}
+}
+
+// Reconfigure changes the settings of the running node, keyed as
+// RuntimeSettings. They are validated together and applied all or none,
+// a consensus parameter refused with a ConsensusSettingError, see
+// Config.ConsensusParamsFile. It returns the settings which changed, each
+// logged with its values before and after.
+func (n *Node) Reconfigure(settings map[string]string) ([]RuntimeChange, error) {
+	n.runtime.Lock()
+	before := n.runtimeConfig()
+	after, err := before.with(settings)
+	if err == nil {
+		err = after.Validate()
+	}
+	if err != nil {
+		n.runtime.Unlock()
+		return nil, err
+	}
+
+	if after.Log != before.Log {
+		levels, _ := dag1_log.ParseLevels(after.Log)
+		dag1_log.SetLevels(n.conf.Logger, levels)
+	}
+	n.runtime.heartbeat = after.Heartbeat
+	n.runtime.heartbeatMax = after.HeartbeatMax
+	n.runtime.syncLimit = after.SyncLimit
+	n.runtime.metrics = after.Metrics
+	n.core.poset.SetCacheBudget(after.CacheBudget)
+	// validated above
+	_ = n.core.poset.SetCacheTriggers(after.CacheGrowBelow, after.CacheShrinkAbove)
+	old, cur := before.Settings(), n.runtimeConfig().Settings()
+	n.runtime.Unlock()
+
+	var changes []RuntimeChange
+	for _, key := range RuntimeSettings {
+		if old[key] == cur[key] {
+			continue
+		}
+		changes = append(changes, RuntimeChange{Key: key, Before: old[key], After: cur[key]})
+		n.logger.WithFields(logrus.Fields{
+			"setting": key,
+			"before":  old[key],
+			"after":   cur[key],
+		}).Info("Setting changed at runtime")
+	}
+
+	if after.Heartbeat != before.Heartbeat || after.HeartbeatMax != before.HeartbeatMax {
+		n.restartTimer()
+	}
+	return changes, nil
+}
+
+// restartTimer starts the heartbeat over with the current settings, when
+// the timer is running and waiting, so that a long heartbeat is not waited
+// for once shortened. Otherwise the next heartbeat takes them.
+func (n *Node) restartTimer() {
+	if !n.controlTimer.GetSet() {
+		return
+	}
+	select {
+	case n.controlTimer.resetCh <- n.heartbeat():
+	default:
+	}
+}
+
+// heartbeats returns the heartbeat of the node, before the flow control,
+// and its heartbeat while it has nothing to gossip about, never below the
+// first
+func (n *Node) heartbeats() (base, idle time.Duration) {
+	n.runtime.RLock()
+	defer n.runtime.RUnlock()
+	base, idle = n.runtime.heartbeat, n.runtime.heartbeatMax
+	if idle < base {
+		idle = base
+	}
+	return base, idle
+}
+
+// syncLimit returns the max number of events of a sync
+func (n *Node) syncLimit() int64 {
+	n.runtime.RLock()
+	defer n.runtime.RUnlock()
+	return n.runtime.syncLimit
+}
+
+// MetricsEnabled returns true when the metrics of the node are served
+func (n *Node) MetricsEnabled() bool {
+	n.runtime.RLock()
+	defer n.runtime.RUnlock()
+	return n.runtime.metrics
+}
@@ -0,0 +1,178 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/log"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+func TestRuntimeConfigWith(t *testing.T) {
+	base := RuntimeConfig{
+		Log:              "info",
+		Heartbeat:        time.Second,
+		HeartbeatMax:     time.Second,
+		SyncLimit:        1000,
+		CacheGrowBelow:   poset.DefaultCacheGrowBelow,
+		CacheShrinkAbove: poset.DefaultCacheShrinkAbove,
+		Metrics:          true,
+	}
+	c, err := base.with(map[string]string{
+		SettingLog:         "warn,node=debug",
+		SettingHeartbeat:   "200ms",
+		SettingSyncLimit:   "50",
+		SettingCacheBudget: "3000",
+		SettingMetrics:     "false",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := base
+	expected.Log, expected.Heartbeat, expected.SyncLimit = "warn,node=debug", 200*time.Millisecond, 50
+	expected.CacheBudget, expected.Metrics = 3000, false
+	if c != expected {
+		t.Fatalf("expected %+v, got %+v", expected, c)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// the settings read back as they are set
+	if again, err := c.with(c.Settings()); err != nil || again != c {
+		t.Fatalf("expected the settings to read back, got %+v, %v", again, err)
+	}
+
+	for _, key := range ConsensusSettings {
+		if _, err := base.with(map[string]string{SettingHeartbeat: "1s", key: "1"}); !IsConsensusSetting(err) {
+			t.Fatalf("%s: expected a consensus setting error, got %v", key, err)
+		}
+	}
+	for _, settings := range []map[string]string{
+		{"datadir": "/tmp"},
+		{SettingHeartbeat: "often"},
+		{SettingMetrics: "maybe"},
+	} {
+		if _, err := base.with(settings); !IsRuntimeSetting(err) {
+			t.Fatalf("%v: expected a runtime setting error, got %v", settings, err)
+		}
+	}
+	for _, settings := range []map[string]string{
+		{SettingLog: "loud"},
+		{SettingHeartbeat: "0s"},
+		{SettingHeartbeatMax: "-1s"},
+		{SettingSyncLimit: "0"},
+		{SettingCacheBudget: "-1"},
+		{SettingCacheGrowBelow: "0.99"},
+	} {
+		c, err := base.with(settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Validate(); !IsRuntimeSetting(err) {
+			t.Fatalf("%v: expected a runtime setting error, got %v", settings, err)
+		}
+	}
+}
+
+// TestReconfigureAtRuntime starts nodes with a heartbeat too long for them
+// to gossip, then shortens it and raises the log level of the node module
+// while they run
+func TestReconfigureAtRuntime(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+	data.Config.HeartbeatTimeout = time.Hour
+	data.Config.HeartbeatMax = time.Hour
+	dag1_log.SetLevels(data.Config.Logger, &dag1_log.Levels{Default: logrus.InfoLevel})
+
+	var nodes []*Node
+	for i, addr := range data.Adds {
+		trans := createTransport(t, data.Logger, data.BackConfig, addr,
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		participants := nodePeers(data.Peers)
+		db := poset.NewInmemStore(participants, data.Config.CacheSize, nil)
+		selectorArgs := FairPeerSelectorCreationFnArgs{LocalAddr: addr}
+		node, err := NewNode(data.Config, data.Peers.ByNetAddr[addr].ID, data.Keys[i], participants,
+			db, trans, dummy.NewInmemDummyApp(data.Logger), NewFairPeerSelectorWrapper, selectorArgs, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := node.Init(); err != nil {
+			t.Fatal(err)
+		}
+		nodes = append(nodes, node)
+		go node.Run(true)
+	}
+	defer func() {
+		for _, n := range nodes {
+			n.Shutdown()
+		}
+	}()
+
+	for i, n := range nodes {
+		if err := submitTransaction(n, []byte(fmt.Sprintf("node%d transaction", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// past the TestDelay, the first heartbeat is still an hour away
+	time.Sleep(2 * time.Second)
+	for i, n := range nodes {
+		n.health.Lock()
+		lastSync := n.health.lastSync
+		n.health.Unlock()
+		if !lastSync.IsZero() {
+			t.Fatalf("node %d: expected no gossip with an hour heartbeat, synced at %s", i, lastSync)
+		}
+	}
+	if nodes[0].logger.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		t.Fatal("expected the node module at info")
+	}
+
+	// the settings are refused all together
+	for _, settings := range []map[string]string{
+		{SettingHeartbeat: "10ms", "max-block-bytes": "1024"},
+		{SettingHeartbeat: "10ms", SettingSyncLimit: "0"},
+		{SettingHeartbeat: "10ms", "datadir": "/tmp"},
+	} {
+		if _, err := nodes[0].Reconfigure(settings); err == nil {
+			t.Fatalf("%v: expected an error", settings)
+		}
+		if hb := nodes[0].GetRuntimeConfig().Heartbeat; hb != time.Hour {
+			t.Fatalf("%v: expected the heartbeat unchanged, got %s", settings, hb)
+		}
+	}
+
+	for i, n := range nodes {
+		changes, err := n.Reconfigure(map[string]string{
+			SettingLog:          "info,node=debug",
+			SettingHeartbeat:    "10ms",
+			SettingHeartbeatMax: "100ms",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the log levels are those of the logger all the nodes share
+		if i == 0 && (len(changes) != 3 || changes[0].Key != SettingLog ||
+			changes[1] != (RuntimeChange{SettingHeartbeat, "1h0m0s", "10ms"})) {
+			t.Fatalf("expected the log level and the heartbeats changed, got %+v", changes)
+		}
+	}
+	if !nodes[0].logger.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		t.Fatal("expected the node module at debug")
+	}
+	if dag1_log.ForModule(data.Config.Logger, "poset").IsLevelEnabled(logrus.DebugLevel) {
+		t.Fatal("expected the poset module still at info")
+	}
+	if c := nodes[0].GetRuntimeConfig(); c.Log != "info,node=debug" || c.Heartbeat != 10*time.Millisecond {
+		t.Fatalf("expected the new settings reflected, got %+v", c)
+	}
+
+	// the nodes gossip without a restart
+	if err := bombardAndWait(nodes, 3, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,102 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+const (
+	// recentlySentTTL is how long an event sent to a peer is not sent to it
+	// again, the time its sync response takes to be inserted. A response
+	// lost meanwhile is sent again once it expires.
+	recentlySentTTL = 2 * time.Second
+	// recentlySentSize is the number of events remembered per peer
+	recentlySentSize = 4096
+)
+
+// sentEvents remembers, for each peer, the events sent to it in its last
+// sync responses, so that back-to-back syncs racing with each other do not
+// send them twice
+type sentEvents struct {
+	mtx        sync.Mutex
+	peers      map[uint64]*lru.Cache // hash => time sent
+	suppressed count64
+}
+
+func newSentEvents() *sentEvents {
+	return &sentEvents{peers: make(map[uint64]*lru.Cache)}
+}
+
+// take returns the events of a sync response to a peer, see filter, within
+// the budget, and remembers them sent. Concurrent syncs of the peer take
+// their events in turn, no event goes in both responses.
+func (s *sentEvents) take(id uint64, known map[uint64]int64, events []poset.Event,
+	now time.Time, budget func([]poset.Event) []poset.Event) []poset.Event {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	events = budget(s.filter(id, known, events, now))
+	s.record(id, events, now)
+	return events
+}
+
+// filter returns the events the peer does not know, by its known events, and
+// which were not sent to it lately. The descendants of an event sent lately
+// are left out too, the peer could not insert them before it. The events are
+// in topological order.
+func (s *sentEvents) filter(id uint64, known map[uint64]int64, events []poset.Event, now time.Time) []poset.Event {
+	sent := s.peers[id]
+	left := make(map[poset.EventHash]bool)
+	res := make([]poset.Event, 0, len(events))
+	for _, ev := range events {
+		hash := ev.Hash()
+		if index, ok := known[ev.CreatorID()]; ok && ev.Index() <= index {
+			// checked against the known events as they are sent, not only
+			// where the events of each creator start
+			s.suppressed.increment()
+			continue
+		}
+		if left[ev.SelfParent()] || left[ev.OtherParent()] {
+			left[hash] = true
+		} else if sent != nil && recentlySent(sent, hash, now) {
+			left[hash] = true
+		} else {
+			res = append(res, ev)
+			continue
+		}
+		s.suppressed.increment()
+	}
+	return res
+}
+
+// recentlySent returns true for an event sent less than recentlySentTTL ago
+func recentlySent(sent *lru.Cache, hash poset.EventHash, now time.Time) bool {
+	at, ok := sent.Get(hash)
+	return ok && now.Sub(at.(time.Time)) < recentlySentTTL
+}
+
+// record remembers the events sent to a peer
+func (s *sentEvents) record(id uint64, events []poset.Event, now time.Time) {
+	if len(events) == 0 {
+		return
+	}
+	sent, ok := s.peers[id]
+	if !ok {
+		var err error
+		if sent, err = lru.New(recentlySentSize); err != nil {
+			return
+		}
+		s.peers[id] = sent
+	}
+	for _, ev := range events {
+		sent.Add(ev.Hash(), now)
+	}
+}
+
+// suppressedCount returns the number of events left out of the responses
+func (s *sentEvents) suppressedCount() int64 {
+	return s.suppressed.get()
+}
@@ -0,0 +1,218 @@
+package node
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+func TestSentEventsFilter(t *testing.T) {
+	key, _ := crypto.GenerateECDSAKey()
+	pubKey := crypto.FromECDSAPub(&key.PublicKey)
+	creator := peers.NewPeer(fmt.Sprintf("0x%X", pubKey), "")
+	// a chain of events of the creator
+	var events []poset.Event
+	self := poset.GenRootSelfParent(creator.ID)
+	for i := int64(0); i < 4; i++ {
+		ev := poset.NewEvent([][]byte{[]byte(fmt.Sprint(i))}, nil, nil,
+			poset.EventHashes{self, poset.EventHash{}},
+			pubKey, i, poset.NewFlagTable(), poset.NewFlagTable(), poset.FrameNIL, false)
+		ev.SetWireInfo(i-1, 0, -1, creator.ID)
+		events = append(events, ev)
+		self = ev.Hash()
+	}
+	all := func(events []poset.Event) []poset.Event { return events }
+	first := func(events []poset.Event) []poset.Event {
+		if len(events) > 1 {
+			return events[:1]
+		}
+		return events
+	}
+
+	const id = 7
+	now := time.Unix(1500000000, 0)
+	s := newSentEvents()
+	known := map[uint64]int64{creator.ID: -1}
+
+	if res := s.take(id, known, events[:2], now, all); len(res) != 2 {
+		t.Fatalf("expected the 2 events sent, got %d", len(res))
+	}
+	// sent lately, and their descendants, are left out
+	if res := s.take(id, known, events, now.Add(time.Second), first); len(res) != 0 {
+		t.Fatalf("expected no event sent again, got %d", len(res))
+	}
+	if n := s.suppressedCount(); n != 4 {
+		t.Fatalf("expected 4 events suppressed, got %d", n)
+	}
+	// another peer gets them
+	if res := s.take(id+1, known, events, now, all); len(res) != 4 {
+		t.Fatalf("expected the 4 events sent to another peer, got %d", len(res))
+	}
+	// the known events are left out as they are sent
+	known[creator.ID] = 2
+	if res := s.take(id+1, known, events, now.Add(recentlySentTTL), all); len(res) != 1 || res[0].Index() != 3 {
+		t.Fatalf("expected the last event only, got %d", len(res))
+	}
+	// once expired they are sent again, within the budget
+	known[creator.ID] = -1
+	res := s.take(id, known, events, now.Add(recentlySentTTL), first)
+	if len(res) != 1 || res[0].Index() != 0 {
+		t.Fatalf("expected the first event sent again, got %d", len(res))
+	}
+	// the events left out by the budget were not sent
+	known[creator.ID] = 0
+	res = s.take(id, known, events, now.Add(recentlySentTTL), all)
+	if len(res) != 3 || res[0].Index() != 1 {
+		t.Fatalf("expected the 3 events left out by the budget sent, got %d", len(res))
+	}
+}
+
+func TestSyncDuplicatesSuppressed(t *testing.T) {
+	data := InitTestData(t, 3, 2)
+
+	var nodes []*Node
+	for i := range data.Adds {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+		n := createNode(t, data.Logger, data.Config, data.PeersSlice[i].ID, data.Keys[i], data.Peers, trans, data.Adds[i], false)
+		defer n.Shutdown()
+		nodes = append(nodes, n)
+	}
+	node1, node2, node3 := nodes[0], nodes[1], nodes[2]
+	peerOf := func(n, other *Node) *peers.Peer {
+		p, _ := n.core.participants.ReadByID(other.ID())
+		return &p
+	}
+	known := func(n *Node) map[uint64]int64 {
+		n.coreLock.Lock()
+		defer n.coreLock.Unlock()
+		return n.core.KnownEvents()
+	}
+	addEvent := func(n, other *Node) {
+		n.coreLock.Lock()
+		defer n.coreLock.Unlock()
+		otherHead, _, err := n.core.poset.Store.LastEventFrom(other.core.HexID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.core.AddSelfEventBlock(otherHead); err != nil {
+			t.Fatal(err)
+		}
+	}
+	syncEvents := func(n, other *Node, events []poset.WireEvent) {
+		n.batchLock.Lock()
+		defer n.batchLock.Unlock()
+		n.coreLock.Lock()
+		defer n.coreLock.Unlock()
+		if err := n.sync(peerOf(n, other), events); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// node2 and node3 make some events, which node1 lacks
+	for i := 0; i < 3; i++ {
+		addEvent(node2, node3)
+		if _, _, err := node3.pull(peerOf(node3, node2)); err != nil {
+			t.Fatal(err)
+		}
+		addEvent(node3, node2)
+		if _, _, err := node2.pull(peerOf(node2, node3)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale := known(node1)
+	node3.coreLock.Lock()
+	missing := node3.core.UnknownCount(stale)
+	node3.coreLock.Unlock()
+	if missing == 0 {
+		t.Fatal("expected node1 to lack events")
+	}
+
+	// node1 syncs with node3 twice at once, before inserting either response
+	var resps [2]*peer.SyncResponse
+	var wg sync.WaitGroup
+	for i := range resps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := node1.requestSync(data.Adds[2], stale)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resps[i] = resp
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+	// each event went in one response only, not in both as it would have
+	// without suppression
+	sent := make(map[string]bool)
+	for _, resp := range resps {
+		for _, we := range resp.Events {
+			key := fmt.Sprintf("%d/%d", we.Body.CreatorID, we.Body.Index)
+			if sent[key] {
+				t.Fatalf("expected the event %s sent once", key)
+			}
+			sent[key] = true
+		}
+	}
+	if len(sent) == 0 || int64(len(sent)) > missing {
+		t.Fatalf("expected up to the %d missing events sent, got %d", missing, len(sent))
+	}
+	if node3.sent.suppressedCount() == 0 {
+		t.Fatal("expected events suppressed")
+	}
+	for _, resp := range resps {
+		syncEvents(node1, node3, resp.Events)
+	}
+
+	// node2 answers the stale known events of node1 with events node1
+	// received from node3 meanwhile, they are no error
+	resp, err := node1.requestSync(data.Adds[1], stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Events) == 0 {
+		t.Fatal("expected node2 to send the events node1 lacked")
+	}
+	syncEvents(node1, node2, resp.Events)
+	if n := node1.core.GetDuplicateEventsCount(); n == 0 {
+		t.Fatal("expected the events received twice counted")
+	}
+	if stats := node1.GetStats(); stats["duplicate_events"] == "0" {
+		t.Fatalf("expected the duplicate events in the stats, got %s", stats["duplicate_events"])
+	}
+
+	// the nodes converge all the same
+	for _, other := range []*Node{node2, node3} {
+		if _, _, err := node1.pull(peerOf(node1, other)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, other := range []*Node{node2, node3} {
+		if _, _, err := other.pull(peerOf(other, node1)); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := node1.pull(peerOf(node1, other)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	k1 := known(node1)
+	for _, other := range []*Node{node2, node3} {
+		for id, index := range known(other) {
+			if index > k1[id] {
+				t.Fatalf("expected node1 to know the event %d of %d, knows %d", index, id, k1[id])
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package node
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/peer"
+)
+
+// simNodes creates the nodes of data, their transports over the simulated
+// network sim, and returns a function shutting them down
+func simNodes(t *testing.T, data *TestData, sim *peer.SimNetwork) ([]*Node, func()) {
+	var nodes []*Node
+	var transports []peer.SyncPeer
+	for i, addr := range data.Adds {
+		trans := createTransport(t, data.Logger, data.BackConfig, addr,
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		transports = append(transports, trans)
+		node := createNode(t, data.Logger, data.Config, data.Peers.ByNetAddr[addr].ID,
+			data.Keys[i], data.Peers, sim.Wrap(addr, trans), addr, false)
+		nodes = append(nodes, node)
+	}
+	return nodes, func() {
+		for _, n := range nodes {
+			n.Shutdown()
+		}
+		for _, trans := range transports {
+			transportClose(t, trans)
+		}
+	}
+}
+
+// lastBlocks returns the last block index of each node
+func lastBlocks(nodes []*Node) []int64 {
+	res := make([]int64, len(nodes))
+	for i, n := range nodes {
+		res[i] = n.GetLastBlockIndex()
+	}
+	return res
+}
+
+// checkNoForks checks the nodes committed the same blocks, up to the last
+// block all of them have
+func checkNoForks(nodes []*Node, t *testing.T) {
+	last := nodes[0].GetLastBlockIndex()
+	for _, n := range nodes[1:] {
+		if l := n.GetLastBlockIndex(); l < last {
+			last = l
+		}
+	}
+	for i := int64(0); i <= last; i++ {
+		expected, err := nodes[0].GetBlock(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, n := range nodes[1:] {
+			block, err := n.GetBlock(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(block.Body, expected.Body) {
+				t.Fatalf("fork at block %d: node 0: %v, node %d: %v",
+					i, expected.Body, k+1, block.Body)
+			}
+		}
+	}
+}
+
+func TestSimHighLatencyNode(t *testing.T) {
+	data := InitTestData(t, 5, 2)
+	data.Config.HeartbeatTimeout = 10 * time.Millisecond
+
+	// the last node is far from the others
+	far := peer.LinkConditions{Latency: 150 * time.Millisecond, Jitter: 50 * time.Millisecond}
+	sim := peer.NewSimNetwork(peer.Scenario{Seed: 1, Links: []peer.LinkRule{
+		{From: data.Adds[4], LinkConditions: far},
+		{To: data.Adds[4], LinkConditions: far},
+	}})
+	nodes, shutdown := simNodes(t, data, sim)
+	defer shutdown()
+
+	if err := gossip(nodes, 5, false, 120*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	checkNoForks(nodes, t)
+}
+
+func TestSimPartitionHeals(t *testing.T) {
+	data := InitTestData(t, 5, 2)
+	data.Config.HeartbeatTimeout = 10 * time.Millisecond
+
+	sim := peer.NewSimNetwork(peer.Scenario{Seed: 1})
+	nodes, shutdown := simNodes(t, data, sim)
+	defer shutdown()
+
+	if err := gossip(nodes, 2, false, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// neither side of a 2/3 split holds a supermajority of the 5 nodes
+	sim.Partition(data.Adds[:2], data.Adds[2:])
+	// the events which crossed before the split may still decide blocks
+	time.Sleep(2 * time.Second)
+	halted := lastBlocks(nodes)
+	for i := 0; i < 300; i++ {
+		if err := submitTransaction(nodes[i%len(nodes)],
+			[]byte(fmt.Sprintf("partitioned transaction %d", i))); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if blocks := lastBlocks(nodes); !reflect.DeepEqual(blocks, halted) {
+		t.Fatalf("expected no block committed while partitioned, from %v to %v", halted, blocks)
+	}
+	if sim.Stats().Partitioned == 0 {
+		t.Fatal("expected syncs cut off by the partition")
+	}
+
+	sim.Heal()
+	target := halted[0]
+	for _, last := range halted {
+		if last > target {
+			target = last
+		}
+	}
+	if err := bombardAndWait(nodes, target+3, 120*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	checkNoForks(nodes, t)
+}
@@ -0,0 +1,96 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy"
+)
+
+func (n *Node) processSnapshotRequest(rpc *peer.RPC, cmd *peer.SnapshotRequest) {
+	n.logger.WithFields(logrus.Fields{
+		"from_id":     cmd.FromID,
+		"block_index": cmd.BlockIndex,
+	}).Debug("processSnapshotRequest(rpc net.RPC, cmd *net.SnapshotRequest)")
+
+	resp := &peer.SnapshotResponse{
+		FromID:     n.id,
+		BlockIndex: cmd.BlockIndex,
+	}
+	snapshot, err := n.proxy.GetSnapshot(cmd.BlockIndex)
+	if err != nil {
+		n.logger.WithField("error", err).Error("n.proxy.GetSnapshot(cmd.BlockIndex)")
+	}
+	resp.Snapshot = snapshot
+	if checkpoint, _, cpErr := n.core.GetCheckpoint(cmd.Frame); cpErr == nil {
+		resp.Checkpoint = &checkpoint
+	}
+
+	// TODO: context.Background
+	rpc.SendResult(context.Background(), n.logger, resp, err)
+}
+
+func (n *Node) requestSnapshot(target string, block poset.Block) (*peer.SnapshotResponse, error) {
+	args := &peer.SnapshotRequest{
+		FromID:     n.id,
+		BlockIndex: block.Index(),
+		Frame:      block.RoundReceived(),
+	}
+	out := &peer.SnapshotResponse{}
+	err := n.trans.Snapshot(context.Background(), target, args, out)
+
+	return out, err
+}
+
+// fetchSnapshot asks a peer for the app snapshot of the anchor block of a
+// fast forward. The state hash the snapshot must lead to is returned along,
+// nil when the peer has no checkpoint of the block frame signed by more than
+// the trust count: the anchor block itself does not carry the app state hash.
+func (n *Node) fetchSnapshot(from *peers.Peer, block poset.Block) ([]byte, []byte, error) {
+	resp, err := n.requestSnapshot(from.Message.NetAddr, block)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.BlockIndex != block.Index() {
+		return nil, nil, fmt.Errorf("peer %s sent the snapshot of block %d, not %d",
+			from.Message.NetAddr, resp.BlockIndex, block.Index())
+	}
+	if resp.Checkpoint == nil {
+		return resp.Snapshot, nil, nil
+	}
+
+	checkpoint := *resp.Checkpoint
+	if checkpoint.Frame != block.RoundReceived() || !bytes.Equal(checkpoint.FrameHash, block.FrameHash) {
+		return nil, nil, fmt.Errorf("peer %s sent a checkpoint of frame %d not matching block %d",
+			from.Message.NetAddr, checkpoint.Frame, block.Index())
+	}
+	if !n.core.VerifyCheckpoint(checkpoint) {
+		n.logger.WithField("frame", checkpoint.Frame).Debug("Snapshot checkpoint is not a proof of finality")
+		return resp.Snapshot, nil, nil
+	}
+	return resp.Snapshot, checkpoint.StateHash, nil
+}
+
+// restoreSnapshot delivers a snapshot to the app. When stateHash is given and
+// the app tells the state hash it restored, they must match.
+func (n *Node) restoreSnapshot(snapshot, stateHash []byte) error {
+	restorer, ok := n.proxy.(proxy.StateRestorer)
+	if !ok || stateHash == nil {
+		return n.proxy.Restore(snapshot)
+	}
+	restored, err := restorer.RestoreState(snapshot)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(restored, stateHash) {
+		return fmt.Errorf("restored state hash %X does not match the checkpoint state hash %X",
+			restored, stateHash)
+	}
+	return nil
+}
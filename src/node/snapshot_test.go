@@ -0,0 +1,99 @@
+package node
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+func TestSnapshotSync(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+
+	var (
+		nodes  []*Node
+		states []*dummy.KVState
+	)
+	for i := 0; i < 2; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		app, state := dummy.NewInmemKVDummyApp(data.Logger)
+		node := createNodeWithApp(t, data.Config, data.Peers.ByNetAddr[data.Adds[i]].ID,
+			data.Keys[i], data.Peers, trans, app, data.Adds[i], false)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+		states = append(states, state)
+	}
+
+	// the first node committed two blocks, the second one none
+	var blocks []poset.Block
+	for i, txs := range [][][]byte{
+		{dummy.SetTx("a", "1"), dummy.SetTx("b", "2")},
+		{dummy.SetTx("c", "3"), dummy.DelTx("a")},
+	} {
+		block := poset.NewBlock(int64(i), int64(i+1), []byte("framehash"), txs)
+		stateHash, err := states[0].CommitHandler(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkpoint, err := nodes[0].core.Checkpoint(block, stateHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+
+		// only the last checkpoint gets signed by more than the trust count
+		if i == 0 {
+			continue
+		}
+		var sigs []poset.BlockSignature
+		for _, key := range data.Keys[1:3] {
+			sig, err := checkpoint.Sign(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sigs = append(sigs, sig)
+		}
+		nodes[0].core.AddCheckpointSignatures(sigs)
+	}
+
+	// without a proof of finality there is nothing to check the snapshot with
+	_, stateHash, err := nodes[1].fetchSnapshot(data.Peers.ByNetAddr[data.Adds[0]], blocks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stateHash != nil {
+		t.Fatalf("expected no state hash without a proof of finality, got %X", stateHash)
+	}
+
+	snapshot, stateHash, err := nodes[1].fetchSnapshot(data.Peers.ByNetAddr[data.Adds[0]], blocks[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stateHash, states[0].StateHash()) {
+		t.Fatalf("expected the state hash %X, got %X", states[0].StateHash(), stateHash)
+	}
+
+	// the app rejects a corrupt snapshot, and the state hash must match
+	if err := nodes[1].restoreSnapshot([]byte("corrupt"), nil); err == nil {
+		t.Fatal("expected a corrupt snapshot to be rejected")
+	}
+	if err := nodes[1].restoreSnapshot(snapshot, []byte("other")); err == nil {
+		t.Fatal("expected a snapshot not leading to the state hash to be rejected")
+	}
+
+	if err := nodes[1].restoreSnapshot(snapshot, stateHash); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(states[1].StateHash(), states[0].StateHash()) || states[1].Len() != 2 {
+		t.Fatalf("expected the restored map to match, got %d keys", states[1].Len())
+	}
+	for key, value := range map[string]string{"b": "2", "c": "3"} {
+		if got, ok := states[1].Query(key); !ok || got != value {
+			t.Fatalf("expected %s=%s, got %q", key, value, got)
+		}
+	}
+}
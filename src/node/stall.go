@@ -0,0 +1,68 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/sirupsen/logrus"
+)
+
+// stallWatchdog decides when to warn about the last consensus round not
+// advancing, once per timeout for as long as it does not
+type stallWatchdog struct {
+	sync.Mutex
+	timeout time.Duration
+	warned  time.Time
+}
+
+func newStallWatchdog(timeout time.Duration) *stallWatchdog {
+	return &stallWatchdog{timeout: timeout}
+}
+
+// due returns true when the round did not advance since roundSince for the
+// timeout, and the last warning is a timeout old
+func (w *stallWatchdog) due(now, roundSince time.Time) bool {
+	w.Lock()
+	defer w.Unlock()
+	if w.timeout <= 0 || now.Sub(roundSince) < w.timeout {
+		return false
+	}
+	if w.warned.After(roundSince) && now.Sub(w.warned) < w.timeout {
+		return false
+	}
+	w.warned = now
+	return true
+}
+
+// checkStall logs the pipeline status when the last consensus round has not
+// advanced for StallWarnTimeout
+func (n *Node) checkStall() {
+	n.health.observeRound(n.core.GetLastConsensusRound())
+	n.health.Lock()
+	roundSince := n.health.roundSince
+	n.health.Unlock()
+
+	now := n.clock.Now()
+	if !n.stall.due(now, roundSince) {
+		return
+	}
+	status, err := n.GetPipelineStatus()
+	if err != nil {
+		n.logger.WithError(err).Error("GetPipelineStatus()")
+		return
+	}
+	fields := logrus.Fields{
+		"stalled":  now.Sub(roundSince).String(),
+		"pipeline": status.Summary(),
+	}
+	if len(status.Frames) > 0 {
+		fields["stage"] = status.Frames[0].Stage
+	}
+	n.logger.WithFields(fields).Warn("Consensus stalled")
+}
+
+// GetPipelineStatus returns how far the frames waiting for consensus got
+func (n *Node) GetPipelineStatus() (poset.PipelineStatus, error) {
+	return n.core.poset.PipelineStatus()
+}
@@ -0,0 +1,110 @@
+package node
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// stallHook records the stall warnings logged
+type stallHook struct {
+	sync.Mutex
+	entries []*logrus.Entry
+}
+
+func (h *stallHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.WarnLevel}
+}
+
+func (h *stallHook) Fire(e *logrus.Entry) error {
+	if e.Message == "Consensus stalled" {
+		h.Lock()
+		h.entries = append(h.entries, e)
+		h.Unlock()
+	}
+	return nil
+}
+
+func (h *stallHook) first() *logrus.Entry {
+	h.Lock()
+	defer h.Unlock()
+	if len(h.entries) == 0 {
+		return nil
+	}
+	return h.entries[0]
+}
+
+func TestStallWatchdog(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	w := newStallWatchdog(time.Minute)
+
+	checks := []struct {
+		now, roundSince time.Duration
+		due             bool
+	}{
+		{30 * time.Second, 0, false},
+		{time.Minute, 0, true},
+		// not again before another timeout
+		{90 * time.Second, 0, false},
+		{2 * time.Minute, 0, true},
+		// the round advanced
+		{150 * time.Second, 140 * time.Second, false},
+		{200 * time.Second, 140 * time.Second, true},
+	}
+	for i, c := range checks {
+		if due := w.due(start.Add(c.now), start.Add(c.roundSince)); due != c.due {
+			t.Fatalf("check %d: expected due %v, got %v", i, c.due, due)
+		}
+	}
+
+	if newStallWatchdog(0).due(start.Add(time.Hour), start) {
+		t.Fatal("expected a zero timeout never to be due")
+	}
+}
+
+func TestStallWarning(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+	data.Config.StallWarnTimeout = 2 * time.Second
+	hook := &stallHook{}
+	data.Config.Logger.AddHook(hook)
+
+	// half of the participants are below the supermajority
+	var nodes []*Node
+	for i := 0; i < 2; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		node := createNode(t, data.Logger, data.Config, data.PeersSlice[i].ID,
+			data.Keys[i], data.Peers, trans, data.Adds[i], true)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+
+	timeout := time.After(20 * time.Second)
+	for hook.first() == nil {
+		select {
+		case <-timeout:
+			t.Fatal("expected the stall to be logged")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	status, err := nodes[0].GetPipelineStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Frames) == 0 {
+		t.Fatalf("expected the next final frame, got %+v", status)
+	}
+	stuck := status.Frames[0]
+	if stuck.Frame != status.NextFinalFrame || stuck.Stage == poset.StageFinal {
+		t.Fatalf("expected the next final frame stuck, got %+v", status)
+	}
+	if stage := hook.first().Data["stage"]; stage != stuck.Stage {
+		t.Fatalf("expected the stall logged at the stage %q, got %v", stuck.Stage, stage)
+	}
+}
@@ -13,6 +13,8 @@ const (
 	Shutdown
 	// Stop is the stop communicating state
 	Stop
+	// Paused answers syncs but neither gossips nor creates events
+	Paused
 )
 
 type state int
@@ -21,6 +23,8 @@ type nodeState2 struct {
 	cond *sync.Cond
 	lock sync.RWMutex
 	wip  int
+	// closed once the node shuts down, no routine starts after it
+	closed bool
 
 	state        state
 	getStateChan chan state
@@ -47,6 +51,8 @@ func (s state) String() string {
 		return "Shutdown"
 	case Stop:
 		return "Stop"
+	case Paused:
+		return "Paused"
 	default:
 		return "Unknown"
 	}
@@ -62,11 +68,16 @@ func (s *nodeState2) mtx() {
 }
 
 func (s *nodeState2) goFunc(fu func()) {
-	go func() {
-		s.lock.Lock()
-		s.wip++
+	// counted before it starts, for waitRoutines not to miss it
+	s.lock.Lock()
+	if s.closed {
 		s.lock.Unlock()
+		return
+	}
+	s.wip++
+	s.lock.Unlock()
 
+	go func() {
 		fu()
 
 		s.lock.Lock()
@@ -96,6 +107,16 @@ func (s *nodeState2) waitRoutines() {
 	}
 }
 
+// closeRoutines waits for the routines to finish and keeps goFunc from
+// starting new ones
+func (s *nodeState2) closeRoutines() {
+	s.lock.Lock()
+	s.closed = true
+	s.lock.Unlock()
+
+	s.waitRoutines()
+}
+
 func (s *nodeState2) getState() state {
 	return <-s.getStateChan
 }
@@ -74,3 +74,41 @@ func TestConcurrentGoFuncs(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestWaitRoutinesOfStartingGoFunc(t *testing.T) {
+	ns := newNodeState2()
+
+	release := make(chan struct{})
+	ns.goFunc(func() { <-release })
+
+	done := make(chan struct{})
+	go func() {
+		ns.waitRoutines()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("expected waitRoutines to wait for the routine")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected waitRoutines to return once the routine finished")
+	}
+}
+
+func TestGoFuncAfterCloseRoutines(t *testing.T) {
+	ns := newNodeState2()
+	ns.closeRoutines()
+
+	started := make(chan struct{}, 1)
+	ns.goFunc(func() { started <- struct{}{} })
+	select {
+	case <-started:
+		t.Fatal("expected no routine to start once closed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ns.waitRoutines()
+}
@@ -0,0 +1,39 @@
+package node
+
+import (
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// valueLogGCStore is implemented by the stores with a value log to GC, as
+// poset.BadgerStore
+type valueLogGCStore interface {
+	RunValueLogGC() (poset.ValueLogGCResult, error)
+	ValueLogGCStats() poset.ValueLogGCStats
+}
+
+// RunStoreGC runs the value log GC of the store, it returns
+// poset.ErrValueLogGCUnsupported for the stores without one
+func (n *Node) RunStoreGC() (poset.ValueLogGCResult, error) {
+	store, ok := n.core.poset.Store.(valueLogGCStore)
+	if !ok {
+		return poset.ValueLogGCResult{}, poset.ErrValueLogGCUnsupported
+	}
+	res, err := store.RunValueLogGC()
+	if err != nil {
+		return res, err
+	}
+	n.logger.WithField("rewritten", res.Rewritten).
+		WithField("reclaimed", res.Reclaimed).
+		WithField("duration", res.Duration).Info("Store GC")
+	return res, nil
+}
+
+// GetStoreGCStats returns the totals of the value log GCs of the store, ok
+// is false for the stores without one
+func (n *Node) GetStoreGCStats() (stats poset.ValueLogGCStats, ok bool) {
+	store, ok := n.core.poset.Store.(valueLogGCStore)
+	if !ok {
+		return stats, false
+	}
+	return store.ValueLogGCStats(), true
+}
@@ -0,0 +1,21 @@
+package node
+
+import (
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// loadStatsStore is implemented by the stores loaded from a database, as
+// poset.BadgerStore
+type loadStatsStore interface {
+	LoadStats() poset.StoreLoadStats
+}
+
+// GetStoreLoadStats returns what loading the store from its database took,
+// ok is false for the stores without a database
+func (n *Node) GetStoreLoadStats() (stats poset.StoreLoadStats, ok bool) {
+	store, ok := n.core.poset.Store.(loadStatsStore)
+	if !ok {
+		return stats, false
+	}
+	return store.LoadStats(), true
+}
@@ -0,0 +1,115 @@
+package node
+
+import (
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// bandwidthWeight is the weight of the last sync in the smoothed bandwidth
+// and event size of a peer
+const bandwidthWeight = 0.3
+
+// transferStats are the smoothed bandwidth of the syncs with a peer and the
+// size of the events they carried
+type transferStats struct {
+	bandwidth float64 // bytes per second
+	eventSize float64 // bytes
+}
+
+// transferred records the events of a sync response pulled from a peer, of
+// the given size in bytes, in d. Responses without events say nothing of the
+// bandwidth.
+func (h *peerHealth) transferred(id uint64, events int, bytes int64, d time.Duration) {
+	if events == 0 || bytes <= 0 || d <= 0 {
+		return
+	}
+	bandwidth := float64(bytes) / d.Seconds()
+	eventSize := float64(bytes) / float64(events)
+
+	h.Lock()
+	defer h.Unlock()
+	stats, ok := h.transfers[id]
+	if !ok {
+		h.transfers[id] = transferStats{bandwidth: bandwidth, eventSize: eventSize}
+		return
+	}
+	stats.bandwidth += bandwidthWeight * (bandwidth - stats.bandwidth)
+	stats.eventSize += bandwidthWeight * (eventSize - stats.eventSize)
+	h.transfers[id] = stats
+}
+
+// transferStats returns the smoothed transfer stats of a peer, ok is false
+// before the first sync carrying events
+func (h *peerHealth) transferStats(id uint64) (stats transferStats, ok bool) {
+	h.Lock()
+	defer h.Unlock()
+	stats, ok = h.transfers[id]
+	return
+}
+
+// syncLimits returns the number and the size of the events to ask a peer
+// for: what the bandwidth measured with it carries in SyncTimeBudget,
+// bounded by SyncMinEvents, SyncMaxEvents and SyncMaxBytes. The first sync
+// asks for SyncMinEvents. Without a time budget only SyncMaxBytes bounds
+// the response.
+func (n *Node) syncLimits(id uint64) (limit, maxBytes int64) {
+	maxBytes = n.conf.SyncMaxBytes
+	if n.conf.SyncTimeBudget <= 0 {
+		return 0, maxBytes
+	}
+	stats, ok := n.health.transferStats(id)
+	if !ok {
+		return n.conf.SyncMinEvents, maxBytes
+	}
+
+	budget := int64(stats.bandwidth * n.conf.SyncTimeBudget.Seconds())
+	if maxBytes <= 0 || budget < maxBytes {
+		maxBytes = budget
+	}
+	limit = int64(float64(budget) / stats.eventSize)
+	if limit < n.conf.SyncMinEvents {
+		limit = n.conf.SyncMinEvents
+	}
+	if n.conf.SyncMaxEvents > 0 && limit > n.conf.SyncMaxEvents {
+		limit = n.conf.SyncMaxEvents
+	}
+	// the min events are asked for whatever their size
+	if min := int64(stats.eventSize) * n.conf.SyncMinEvents; maxBytes < min {
+		maxBytes = min
+	}
+	if n.conf.SyncMaxBytes > 0 && maxBytes > n.conf.SyncMaxBytes {
+		maxBytes = n.conf.SyncMaxBytes
+	}
+	return limit, maxBytes
+}
+
+// budgetEvents returns the first events, in the topological order of the
+// diff, within limit events and maxBytes bytes of their wire sizes, and their
+// size. A bound of 0 is none. The first event is returned whatever its size,
+// for the requester to make progress. Any prefix of the diff holds the
+// parents the requester lacks of its events.
+func budgetEvents(events []poset.Event, limit, maxBytes int64,
+	wireSize func(*poset.Event) int) ([]poset.Event, int64) {
+	var size int64
+	for i := range events {
+		if limit > 0 && int64(i) >= limit {
+			return events[:i], size
+		}
+		eventSize := int64(wireSize(&events[i]))
+		if maxBytes > 0 && i > 0 && size+eventSize > maxBytes {
+			return events[:i], size
+		}
+		size += eventSize
+	}
+	return events, size
+}
+
+// responseMaxBytes returns the byte budget of a sync response, the least of
+// that of the request and ours
+func (n *Node) responseMaxBytes(requested int64) int64 {
+	if requested <= 0 || (n.conf.SyncMaxBytes > 0 && n.conf.SyncMaxBytes < requested) {
+		return n.conf.SyncMaxBytes
+	}
+	return requested
+}
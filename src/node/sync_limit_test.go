@@ -0,0 +1,132 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+func syncLimitNode() *Node {
+	conf := DefaultConfig()
+	conf.SyncTimeBudget = 500 * time.Millisecond
+	conf.SyncMinEvents = 10
+	conf.SyncMaxEvents = 1000
+	conf.SyncMaxBytes = 1 << 20
+	return &Node{conf: conf, health: newPeerHealth(nil, common.RealClock)}
+}
+
+func TestSyncLimitsAdapt(t *testing.T) {
+	n := syncLimitNode()
+	const fast, slow = 1, 2
+
+	// the first sync asks for the min events
+	if limit, maxBytes := n.syncLimits(fast); limit != n.conf.SyncMinEvents || maxBytes != n.conf.SyncMaxBytes {
+		t.Fatalf("expected the first sync to ask for %d events, got %d in %d bytes",
+			n.conf.SyncMinEvents, limit, maxBytes)
+	}
+
+	// 1KB events: the fast peer carries 100MB/s, the slow one 20KB/s
+	for i := 0; i < 5; i++ {
+		n.health.transferred(fast, 1000, 1000*1000, 10*time.Millisecond)
+		n.health.transferred(slow, 20, 20*1000, time.Second)
+	}
+	fastLimit, fastBytes := n.syncLimits(fast)
+	slowLimit, slowBytes := n.syncLimits(slow)
+	if fastLimit != n.conf.SyncMaxEvents || fastBytes != n.conf.SyncMaxBytes {
+		t.Fatalf("expected the fast peer limits capped at %d events and %d bytes, got %d and %d",
+			n.conf.SyncMaxEvents, n.conf.SyncMaxBytes, fastLimit, fastBytes)
+	}
+	// 10KB in the half second budget
+	if slowLimit != n.conf.SyncMinEvents || slowBytes != 10*1000 {
+		t.Fatalf("expected the slow peer limited to %d events and 10000 bytes, got %d and %d",
+			n.conf.SyncMinEvents, slowLimit, slowBytes)
+	}
+
+	// the slow peer speeds up
+	for i := 0; i < 20; i++ {
+		n.health.transferred(slow, 100, 100*1000, 500*time.Millisecond)
+	}
+	if limit, _ := n.syncLimits(slow); limit <= slowLimit || limit >= fastLimit {
+		t.Fatalf("expected the limit of the slow peer between %d and %d, got %d",
+			slowLimit, fastLimit, limit)
+	}
+
+	// no time budget, no event limit
+	n.conf.SyncTimeBudget = 0
+	if limit, maxBytes := n.syncLimits(slow); limit != 0 || maxBytes != n.conf.SyncMaxBytes {
+		t.Fatalf("expected no event limit, got %d in %d bytes", limit, maxBytes)
+	}
+}
+
+func TestBudgetEvents(t *testing.T) {
+	var events []poset.Event
+	for i := 0; i < 10; i++ {
+		events = append(events, poset.NewEvent([][]byte{make([]byte, 100)}, nil, nil,
+			make(poset.EventHashes, 2), []byte("creator"), int64(i+1), nil, nil, 0, false))
+	}
+	// sizes[i] is the size of the first i events
+	sizes := []int64{0}
+	for i := range events {
+		sizes = append(sizes, sizes[i]+int64(events[i].WireSize()))
+	}
+
+	cases := []struct {
+		limit, maxBytes int64
+		expected        int
+	}{
+		{0, 0, 10},
+		{4, 0, 4},
+		{0, sizes[3], 3},
+		{0, sizes[4] - 1, 3},
+		{2, sizes[3], 2},
+		{20, sizes[10], 10},
+		// the first event is sent whatever its size
+		{0, 1, 1},
+	}
+	for _, c := range cases {
+		got, bytes := budgetEvents(events, c.limit, c.maxBytes, (*poset.Event).WireSize)
+		if len(got) != c.expected || bytes != sizes[c.expected] {
+			t.Fatalf("limit %d, max bytes %d: expected %d events of %d bytes, got %d of %d",
+				c.limit, c.maxBytes, c.expected, sizes[c.expected], len(got), bytes)
+		}
+	}
+}
+
+func TestSyncLimitConvergence(t *testing.T) {
+	data := InitTestData(t, 4, 2)
+	// a few events per sync, the nodes catch up over many
+	data.Config.SyncMinEvents = 2
+	data.Config.SyncMaxEvents = 4
+	data.Config.SyncMaxBytes = 4096
+
+	var nodes []*Node
+	for i := 0; i < 4; i++ {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+
+		id := data.Peers.ByNetAddr[data.Adds[i]].ID
+		node := createNode(t, data.Logger, data.Config, id,
+			data.Keys[i], data.Peers, trans, data.Adds[i], false)
+		defer node.Shutdown()
+		nodes = append(nodes, node)
+	}
+
+	if err := gossip(nodes, 3, true, 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	checkGossip(nodes, 0, t)
+
+	for _, n := range nodes {
+		for _, p := range data.PeersSlice {
+			if p.ID == n.ID() {
+				continue
+			}
+			if limit, _ := n.syncLimits(p.ID); limit < 2 || limit > 4 {
+				t.Fatalf("expected a limit between 2 and 4 events, got %d", limit)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package node
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// ErrObserverEvent is returned when an observer is asked to create an event
+var ErrObserverEvent = fmt.Errorf("observers create no events")
+
+// tickWatchdog decides when to create an empty event for the last consensus
+// round not advancing, at most once per interval for as long as it does not
+type tickWatchdog struct {
+	sync.Mutex
+	timeout  time.Duration
+	interval time.Duration
+	ticked   time.Time
+}
+
+func newTickWatchdog(timeout, interval time.Duration) *tickWatchdog {
+	return &tickWatchdog{timeout: timeout, interval: interval}
+}
+
+// due returns true when the round did not advance since roundSince for the
+// timeout, and the last tick is an interval old
+func (w *tickWatchdog) due(now, roundSince time.Time) bool {
+	w.Lock()
+	defer w.Unlock()
+	if w.timeout <= 0 || now.Sub(roundSince) < w.timeout {
+		return false
+	}
+	if !w.ticked.IsZero() && now.Sub(w.ticked) < w.interval {
+		return false
+	}
+	w.ticked = now
+	return true
+}
+
+// CreateEmptyEvent creates an event of this node without transactions, on
+// the newest event of the other participants, so that the pending clothos
+// gather support when no transactions flow. The event is inserted as the
+// other events of the node are, and refused with a poset.EventCapError when
+// the node made all the events it may in the frame. It returns the event.
+func (n *Node) CreateEmptyEvent() (poset.EventHash, error) {
+	if n.core.IsObserver() {
+		return poset.EventHash{}, ErrObserverEvent
+	}
+	if n.IsPaused() {
+		return poset.EventHash{}, ErrNodePaused
+	}
+
+	n.batchLock.Lock()
+	n.coreLock.Lock()
+	hash, err := n.core.AddEmptyEventBlock()
+	n.coreLock.Unlock()
+	n.batchLock.Unlock()
+	if err != nil {
+		return poset.EventHash{}, err
+	}
+	n.hintConsensus(hintEvents)
+	return hash, nil
+}
+
+// checkTick creates an empty event when the last consensus round has not
+// advanced for TickStallTimeout while events wait for consensus, at most
+// once per TickInterval. checkStall keeps the round observed.
+func (n *Node) checkTick() {
+	if n.core.IsObserver() || len(n.core.GetUndeterminedEvents()) == 0 {
+		return
+	}
+	n.health.Lock()
+	roundSince := n.health.roundSince
+	n.health.Unlock()
+	if !n.tick.due(n.clock.Now(), roundSince) {
+		return
+	}
+
+	n.goFunc(func() {
+		hash, err := n.emptyEvent()
+		switch {
+		case poset.IsEventCap(err) || err == ErrNodePaused:
+			n.logger.WithError(err).Debug("Skipping the empty event")
+		case err != nil:
+			n.logger.WithError(err).Error("CreateEmptyEvent()")
+		default:
+			n.logger.WithField("event", hash.String()).Info("Created an empty event for the stalled consensus")
+		}
+	})
+}
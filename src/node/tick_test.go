@@ -0,0 +1,173 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+func TestTickWatchdog(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	w := newTickWatchdog(time.Minute, 10*time.Second)
+
+	checks := []struct {
+		now, roundSince time.Duration
+		due             bool
+	}{
+		{30 * time.Second, 0, false},
+		{time.Minute, 0, true},
+		// not again before the interval
+		{65 * time.Second, 0, false},
+		{70 * time.Second, 0, true},
+		// the round advanced
+		{100 * time.Second, 90 * time.Second, false},
+		{150 * time.Second, 90 * time.Second, true},
+	}
+	for i, c := range checks {
+		if due := w.due(start.Add(c.now), start.Add(c.roundSince)); due != c.due {
+			t.Fatalf("check %d: expected due %v, got %v", i, c.due, due)
+		}
+	}
+
+	if newTickWatchdog(0, 0).due(start.Add(time.Hour), start) {
+		t.Fatal("expected a zero timeout never to be due")
+	}
+}
+
+func TestCreateEmptyEventRefused(t *testing.T) {
+	data := InitTestData(t, 2, 2)
+	observerConfig := *data.Config
+	observerConfig.Observer = true
+	for i, config := range []*Config{&observerConfig, data.Config} {
+		trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[i],
+			data.PoolSize, data.CreateFu, data.Network.CreateListener)
+		defer transportClose(t, trans)
+		node := createNode(t, data.Logger, config, data.PeersSlice[i].ID,
+			data.Keys[i], data.Peers, trans, data.Adds[i], false)
+		defer node.Shutdown()
+
+		expected := ErrObserverEvent
+		if !config.Observer {
+			if err := node.Pause(); err != nil {
+				t.Fatal(err)
+			}
+			expected = ErrNodePaused
+		}
+		if _, err := node.CreateEmptyEvent(); err != expected {
+			t.Fatalf("expected %v, got %v", expected, err)
+		}
+	}
+}
+
+// newTickNode makes the first of four participants without running it, on a
+// manual clock, its empty events counted on the returned channel instead of
+// created
+func newTickNode(t *testing.T, timeout, interval time.Duration) (*Node, *common.ManualClock, chan struct{}) {
+	data := InitTestData(t, 4, 2)
+	clock := common.NewManualClock(time.Unix(1500000000, 0))
+	config := *data.Config
+	config.TickStallTimeout = timeout
+	config.TickInterval = interval
+	config.Clock = clock
+	trans := createTransport(t, data.Logger, data.BackConfig, data.Adds[0],
+		data.PoolSize, data.CreateFu, data.Network.CreateListener)
+	db := poset.NewInmemStore(data.Peers, config.CacheSize, nil)
+	selectorArgs := SmartPeerSelectorCreationFnArgs{LocalAddr: data.Adds[0]}
+	node, err := NewNode(&config, data.PeersSlice[0].ID, data.Keys[0], data.Peers,
+		db, trans, dummy.NewInmemDummyApp(data.Logger), NewSmartPeerSelectorWrapper,
+		selectorArgs, data.Adds[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	ticks := make(chan struct{}, 100)
+	node.emptyEvent = func() (poset.EventHash, error) {
+		ticks <- struct{}{}
+		return poset.EventHash{}, nil
+	}
+	return node, clock, ticks
+}
+
+// stallFor makes the last consensus round of the node look stalled for d
+func stallFor(n *Node, d time.Duration) {
+	n.health.Lock()
+	n.health.roundSince = n.clock.Now().Add(-d)
+	n.health.Unlock()
+}
+
+// ticked returns true when the watchdog of the node creates an empty event
+// shortly
+func ticked(ticks chan struct{}) bool {
+	select {
+	case <-ticks:
+		return true
+	case <-time.After(200 * time.Millisecond):
+		return false
+	}
+}
+
+func TestTickStalledConsensus(t *testing.T) {
+	node, _, ticks := newTickNode(t, time.Minute, time.Second)
+	defer node.Shutdown()
+
+	// the transactions stopped and the round did not advance since, but no
+	// event waits for consensus
+	stallFor(node, 2*time.Minute)
+	node.checkTick()
+	if ticked(ticks) {
+		t.Fatal("expected no empty event with no undetermined event")
+	}
+
+	// the last events wait for consensus
+	node.core.poset.UndeterminedEvents = poset.EventHashes{poset.CalcEventHash([]byte("last"))}
+	stallFor(node, 30*time.Second)
+	node.checkTick()
+	if ticked(ticks) {
+		t.Fatal("expected no empty event before the stall timeout")
+	}
+	stallFor(node, 2*time.Minute)
+	node.checkTick()
+	if !ticked(ticks) {
+		t.Fatal("expected an empty event for the stalled consensus")
+	}
+
+	// the empty events unstuck the round
+	node.health.observeRound(node.health.round + 1)
+	node.checkTick()
+	if ticked(ticks) {
+		t.Fatal("expected no empty event once the round advanced")
+	}
+}
+
+// TestTickPartition checks the watchdog of a node cut off from the
+// supermajority, which can never decide its events, creates no more empty
+// events than its rate allows
+func TestTickPartition(t *testing.T) {
+	const interval = 300 * time.Millisecond
+	node, clock, ticks := newTickNode(t, 100*time.Millisecond, interval)
+	defer node.Shutdown()
+
+	node.core.poset.UndeterminedEvents = poset.EventHashes{poset.CalcEventHash([]byte("partitioned"))}
+	stallFor(node, time.Second)
+	start := clock.Now()
+	for clock.Now().Sub(start) < 1500*time.Millisecond {
+		node.checkTick()
+		clock.Advance(10 * time.Millisecond)
+	}
+
+	// a tick at once, then one every interval
+	for i := 0; i < 5; i++ {
+		if !ticked(ticks) {
+			t.Fatalf("expected 5 empty events, got %d", i)
+		}
+	}
+	if ticked(ticks) {
+		t.Fatal("expected no more than 5 empty events")
+	}
+}
@@ -0,0 +1,232 @@
+package node
+
+import (
+	"errors"
+	"sync"
+)
+
+const (
+	// TxPoolRejectNew rejects the transactions submitted while the pool is
+	// full, with ErrTxPoolFull
+	TxPoolRejectNew = "reject-new"
+	// TxPoolEvictOldest makes room for the transactions submitted while the
+	// pool is full by dropping the oldest ones
+	TxPoolEvictOldest = "evict-oldest"
+
+	// defaultTxPoolSize is the number of transactions the pool holds
+	defaultTxPoolSize = 100000
+	// defaultTxPoolBytes is the total size of the transactions the pool holds
+	defaultTxPoolBytes = 64 << 20
+)
+
+// TxPoolPolicies are the overflow policies of the transaction pool
+var TxPoolPolicies = []string{TxPoolRejectNew, TxPoolEvictOldest}
+
+// ErrTxPoolFull is returned for a transaction rejected by a full pool
+var ErrTxPoolFull = errors.New("transaction pool is full")
+
+// Transaction sources, the submission paths of the node
+const (
+	sourceApp     = "app"
+	sourceFlagged = "flagged"
+	sourceService = "service"
+)
+
+// pooledTx is a transaction waiting in the pool for an event
+type pooledTx struct {
+	source string
+	tx     []byte
+	flags  byte
+}
+
+// txSource queues the transactions of one source, oldest first
+type txSource struct {
+	name string
+	txs  []pooledTx
+}
+
+// TxPoolStats is the occupancy of the transaction pool
+type TxPoolStats struct {
+	Count    int
+	Bytes    int
+	MaxCount int
+	MaxBytes int
+	Evicted  uint64
+	Rejected uint64
+}
+
+// txPool holds the transactions submitted to the node until they are put in
+// events. It is bounded in count and bytes, 0 for no bound. When it is full
+// the source queueing the most transactions makes room: its oldest one is
+// evicted, unless it is the submitter and the policy is TxPoolRejectNew. So
+// a source flooding the pool only ever pushes out its own transactions.
+// Events take the transactions of the sources in turn.
+type txPool struct {
+	maxCount int
+	maxBytes int
+	policy   string
+
+	mu       sync.Mutex
+	sources  []*txSource // non-empty ones, in turn order
+	bySource map[string]*txSource
+	next     int // the source the next batch starts from
+	count    int
+	bytes    int
+	evicted  uint64
+	rejected uint64
+}
+
+func newTxPool(maxCount, maxBytes int, policy string) *txPool {
+	return &txPool{
+		maxCount: maxCount,
+		maxBytes: maxBytes,
+		policy:   policy,
+		bySource: make(map[string]*txSource),
+	}
+}
+
+func (p *txPool) full(size int) bool {
+	return (p.maxCount > 0 && p.count+1 > p.maxCount) ||
+		(p.maxBytes > 0 && p.bytes+size > p.maxBytes)
+}
+
+// add queues a transaction of a source, making room if the pool is full
+func (p *txPool) add(source string, tx []byte, flags byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.maxBytes > 0 && len(tx) > p.maxBytes {
+		p.rejected++
+		return ErrTxPoolFull
+	}
+	for p.full(len(tx)) {
+		victim := p.largest(source)
+		if victim.name == source && p.policy != TxPoolEvictOldest {
+			p.rejected++
+			return ErrTxPoolFull
+		}
+		p.evictOldest(victim)
+	}
+
+	s, ok := p.bySource[source]
+	if !ok {
+		s = &txSource{name: source}
+		p.bySource[source] = s
+		p.sources = append(p.sources, s)
+	}
+	s.txs = append(s.txs, pooledTx{source, tx, flags})
+	p.count++
+	p.bytes += len(tx)
+	return nil
+}
+
+// largest returns the source queueing the most transactions, preferring
+// source on ties
+func (p *txPool) largest(source string) *txSource {
+	res, ok := p.bySource[source]
+	for _, s := range p.sources {
+		if !ok || len(s.txs) > len(res.txs) {
+			res, ok = s, true
+		}
+	}
+	return res
+}
+
+func (p *txPool) evictOldest(s *txSource) {
+	p.count--
+	p.bytes -= len(s.txs[0].tx)
+	p.evicted++
+	s.txs = s.txs[1:]
+	if len(s.txs) == 0 {
+		for i := range p.sources {
+			if p.sources[i] == s {
+				p.remove(i)
+				break
+			}
+		}
+	}
+}
+
+// remove drops an emptied source from the turn order
+func (p *txPool) remove(i int) {
+	delete(p.bySource, p.sources[i].name)
+	p.sources = append(p.sources[:i], p.sources[i+1:]...)
+	if p.next > i {
+		p.next--
+	}
+}
+
+// take removes a batch of transactions for an event, one of each source in
+// turn, while their payload fits maxPayload. The first transaction is taken
+// whatever its size.
+func (p *txPool) take(maxPayload int) []pooledTx {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var batch []pooledTx
+	payload := 0
+	i := p.next
+	for len(p.sources) > 0 {
+		if i >= len(p.sources) {
+			i = 0
+		}
+		s := p.sources[i]
+		t := s.txs[0]
+		if len(batch) > 0 && payload >= maxPayload-len(t.tx) {
+			break
+		}
+		batch = append(batch, t)
+		payload += len(t.tx)
+		p.count--
+		p.bytes -= len(t.tx)
+		s.txs = s.txs[1:]
+		if len(s.txs) == 0 {
+			p.next = i
+			p.remove(i)
+			i = p.next
+		} else {
+			i++
+		}
+	}
+	p.next = i
+	return batch
+}
+
+// putBack returns a batch which could not be put in an event ahead of the
+// transactions of its sources. The pool may exceed its bounds meanwhile.
+func (p *txPool) putBack(batch []pooledTx) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := len(batch) - 1; i >= 0; i-- {
+		t := batch[i]
+		s, ok := p.bySource[t.source]
+		if !ok {
+			s = &txSource{name: t.source}
+			p.bySource[t.source] = s
+			p.sources = append(p.sources, s)
+		}
+		s.txs = append([]pooledTx{t}, s.txs...)
+		p.count++
+		p.bytes += len(t.tx)
+	}
+}
+
+func (p *txPool) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.count
+}
+
+func (p *txPool) stats() TxPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return TxPoolStats{
+		Count:    p.count,
+		Bytes:    p.bytes,
+		MaxCount: p.maxCount,
+		MaxBytes: p.maxBytes,
+		Evicted:  p.evicted,
+		Rejected: p.rejected,
+	}
+}
@@ -0,0 +1,140 @@
+package node
+
+import (
+	"fmt"
+	"testing"
+)
+
+func poolTx(source string, i int) []byte {
+	return []byte(fmt.Sprintf("%s%d", source, i))
+}
+
+func takeAll(p *txPool) []string {
+	var res []string
+	for _, t := range p.take(MaxEventsPayloadSize) {
+		res = append(res, string(t.tx))
+	}
+	return res
+}
+
+func TestTxPoolFill(t *testing.T) {
+	p := newTxPool(3, 0, TxPoolRejectNew)
+	for i := 0; i < 3; i++ {
+		if err := p.add("a", poolTx("a", i), byte(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := p.stats(); s.Count != 3 || s.Bytes != 6 {
+		t.Fatalf("expected 3 transactions of 6 bytes, got %+v", s)
+	}
+
+	batch := p.take(MaxEventsPayloadSize)
+	if len(batch) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(batch))
+	}
+	for i, tx := range batch {
+		if string(tx.tx) != string(poolTx("a", i)) || tx.flags != byte(i) {
+			t.Fatalf("expected a%d with flags %d, got %s with flags %d", i, i, tx.tx, tx.flags)
+		}
+	}
+	if s := p.stats(); s.Count != 0 || s.Bytes != 0 {
+		t.Fatalf("expected an empty pool, got %+v", s)
+	}
+
+	// a batch which could not be put in an event is taken again first
+	if err := p.add("a", poolTx("a", 3), 0); err != nil {
+		t.Fatal(err)
+	}
+	p.putBack(batch)
+	if got := fmt.Sprint(takeAll(p)); got != "[a0 a1 a2 a3]" {
+		t.Fatalf("expected [a0 a1 a2 a3], got %s", got)
+	}
+}
+
+func TestTxPoolTakePayload(t *testing.T) {
+	p := newTxPool(0, 0, TxPoolRejectNew)
+	for i := 0; i < 5; i++ {
+		if err := p.add("a", make([]byte, 10), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if batch := p.take(35); len(batch) != 3 {
+		t.Fatalf("expected 3 transactions of 10 bytes to fit 35 bytes, got %d", len(batch))
+	}
+	// the first transaction is taken whatever its size
+	if batch := p.take(5); len(batch) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(batch))
+	}
+}
+
+func TestTxPoolRejectNew(t *testing.T) {
+	p := newTxPool(0, 6, TxPoolRejectNew)
+	for i := 0; i < 3; i++ {
+		if err := p.add("a", poolTx("a", i), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.add("a", poolTx("a", 3), 0); err != ErrTxPoolFull {
+		t.Fatalf("expected ErrTxPoolFull, got %v", err)
+	}
+	if err := p.add("a", make([]byte, 7), 0); err != ErrTxPoolFull {
+		t.Fatalf("expected ErrTxPoolFull for a transaction bigger than the pool, got %v", err)
+	}
+	if s := p.stats(); s.Count != 3 || s.Rejected != 2 || s.Evicted != 0 {
+		t.Fatalf("expected 3 transactions and 2 rejected, got %+v", s)
+	}
+	if got := fmt.Sprint(takeAll(p)); got != "[a0 a1 a2]" {
+		t.Fatalf("expected the first transactions to be kept, got %s", got)
+	}
+}
+
+func TestTxPoolEvictOldest(t *testing.T) {
+	p := newTxPool(3, 0, TxPoolEvictOldest)
+	for i := 0; i < 5; i++ {
+		if err := p.add("a", poolTx("a", i), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := p.stats(); s.Count != 3 || s.Evicted != 2 || s.Rejected != 0 {
+		t.Fatalf("expected 3 transactions and 2 evicted, got %+v", s)
+	}
+	if got := fmt.Sprint(takeAll(p)); got != "[a2 a3 a4]" {
+		t.Fatalf("expected the last transactions to be kept, got %s", got)
+	}
+}
+
+func TestTxPoolFairness(t *testing.T) {
+	for _, policy := range TxPoolPolicies {
+		t.Run(policy, func(t *testing.T) {
+			p := newTxPool(4, 0, policy)
+
+			// a floods the pool before b submits
+			for i := 0; i < 10; i++ {
+				err := p.add("a", poolTx("a", i), 0)
+				if err != nil && (policy == TxPoolEvictOldest || i < 4) {
+					t.Fatal(err)
+				}
+			}
+			for i := 0; i < 2; i++ {
+				if err := p.add("b", poolTx("b", i), 0); err != nil {
+					t.Fatalf("expected b to make room, got %v", err)
+				}
+			}
+			// b does not push a below its own share
+			if err := p.add("b", poolTx("b", 2), 0); err == nil && policy == TxPoolRejectNew {
+				t.Fatal("expected b to be rejected once it queues as many as a")
+			}
+
+			// events take a and b in turn
+			batch := takeAll(p)
+			if len(batch) != 4 {
+				t.Fatalf("expected 4 transactions, got %v", batch)
+			}
+			for i, tx := range batch {
+				if want := "ab"[i%2]; tx[0] != want {
+					t.Fatalf("expected the sources to alternate, got %v", batch)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package node
+
+import (
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// verifiedEvents are the signatures of the events of a sync batch verified
+// up front, by event hash
+type verifiedEvents map[poset.EventHash]string
+
+// has returns true if the signature of the event was verified
+func (v verifiedEvents) has(ev *poset.Event) bool {
+	sig, ok := v[ev.Hash()]
+	return ok && sig == ev.Message.Signature
+}
+
+type wireEventID struct {
+	creatorID uint64
+	index     int64
+}
+
+// rejectedEvents are the events of a sync batch with an invalid signature,
+// and their descendants in the batch
+type rejectedEvents map[wireEventID]bool
+
+// rejects returns true if the event, or one of its parents, is rejected. As
+// the batch is in topological order, the event is then marked rejected for
+// its own descendants.
+func (r rejectedEvents) rejects(we poset.WireEvent) bool {
+	id := wireEventID{we.Body.CreatorID, we.Body.Index}
+	if r[id] {
+		return true
+	}
+	if r[wireEventID{we.Body.CreatorID, we.Body.SelfParentIndex}] ||
+		r[wireEventID{we.Body.OtherParentCreatorID, we.Body.OtherParentIndex}] {
+		r[id] = true
+		return true
+	}
+	return false
+}
+
+// preverify verifies the signatures of a sync batch concurrently. The events
+// whose parents are neither in the batch nor in the store are left to be
+// verified on insertion.
+func (c *Core) preverify(wevents []poset.WireEvent) (verifiedEvents, rejectedEvents) {
+	events, _ := c.poset.ReadWireBatch(wevents)
+	errs := poset.VerifyEvents(events)
+
+	verified := make(verifiedEvents, len(events))
+	rejected := make(rejectedEvents)
+	for i, ev := range events {
+		switch {
+		case ev == nil:
+		case errs[i] != nil:
+			rejected[wireEventID{wevents[i].Body.CreatorID, wevents[i].Body.Index}] = true
+		default:
+			verified[ev.Hash()] = ev.Message.Signature
+		}
+	}
+	return verified, rejected
+}
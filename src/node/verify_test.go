@@ -0,0 +1,40 @@
+package node
+
+import (
+	"testing"
+)
+
+func TestSyncRejectsInvalidSignature(t *testing.T) {
+	cores := initPendingCores(5, t)
+	a, b, c := cores[0], cores[1], cores[2]
+
+	addEvent(c, a, t)
+	addEvent(b, a, t)
+	addEvent(b, a, t)
+
+	// b1 carries the signature of b2, b2 descends from it
+	batch := append(diff(b, a, t), diff(c, a, t)...)
+	if len(batch) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(batch))
+	}
+	batch[0].Signature = batch[1].Signature
+
+	if err := a.Sync(peerOf(a, b), batch); err != nil {
+		t.Fatal(err)
+	}
+	known := a.KnownEvents()
+	if known[b.ID()] != 0 {
+		t.Fatalf("expected the events of b to be rejected, got height %d", known[b.ID()])
+	}
+	if known[c.ID()] != 1 {
+		t.Fatalf("expected the event of c to be inserted, got height %d", known[c.ID()])
+	}
+
+	// the valid events are accepted from a later sync
+	if err := a.Sync(peerOf(a, b), diff(b, a, t)); err != nil {
+		t.Fatal(err)
+	}
+	if known := a.KnownEvents(); known[b.ID()] != 2 {
+		t.Fatalf("expected the events of b to be inserted, got height %d", known[b.ID()])
+	}
+}
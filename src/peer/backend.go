@@ -11,6 +11,8 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/hashicorp/go-multierror"
+
+	"github.com/SamuelMarks/dag1/src/common"
 )
 
 const (
@@ -34,6 +36,11 @@ type BackendConfig struct {
 	ReceiveTimeout time.Duration
 	ProcessTimeout time.Duration
 	IdleTimeout    time.Duration
+	// Protocol is what the server negotiates with its peers, the default
+	// protocol when zero
+	Protocol Protocol
+	// Clock times the receive and process timeouts, the real clock when nil
+	Clock common.Clock
 }
 
 // Backend is sync server.
@@ -44,7 +51,8 @@ type Backend struct {
 	listenerFunc CreateListenerFunc
 	logger       logrus.FieldLogger
 	receiver     chan *RPC
-	server       *rpc.Server
+	handler      *DAG1
+	protocol     Protocol
 
 	mtx      sync.RWMutex
 	shutdown bool
@@ -63,6 +71,8 @@ func NewBackendConfig() *BackendConfig {
 		ReceiveTimeout: time.Minute * 60,
 		ProcessTimeout: time.Minute * 60,
 		IdleTimeout:    time.Minute * 10,
+		Protocol:       DefaultProtocol(),
+		Clock:          common.RealClock,
 	}
 }
 
@@ -72,10 +82,9 @@ func NewBackend(conf *BackendConfig,
 	conns := make(map[net.Conn]bool)
 	receiver := make(chan *RPC)
 	done := make(chan struct{})
-	rpcServer := rpc.NewServer()
-	if err := rpcServer.RegisterName(dag1, NewDAG1(
-		done, receiver, conf.ReceiveTimeout, conf.ProcessTimeout)); err != nil {
-		logger.Panic(err)
+	handler := NewDAG1(done, receiver, conf.ReceiveTimeout, conf.ProcessTimeout)
+	if conf.Clock != nil {
+		handler.clock = conf.Clock
 	}
 
 	return &Backend{
@@ -85,7 +94,8 @@ func NewBackend(conf *BackendConfig,
 		listenerFunc: listenerFunc,
 		logger:       logger,
 		receiver:     receiver,
-		server:       rpcServer,
+		handler:      handler,
+		protocol:     conf.Protocol.orDefault(),
 		wg:           &sync.WaitGroup{},
 	}
 }
@@ -126,20 +136,20 @@ func (srv *Backend) ListenAndServe(network, address string) error {
 				return
 			}
 
+			// the connection is counted before Close can wait for them
 			srv.mtx.RLock()
-			shutdown := srv.shutdown
-			srv.mtx.RUnlock()
-
-			if shutdown {
+			if srv.shutdown {
+				srv.mtx.RUnlock()
+				conn.Close()
 				return
 			}
-
 			srv.wg.Add(1)
-			go func() {
-				srv.connsLock.Lock()
-				srv.conns[conn] = true
-				srv.connsLock.Unlock()
+			srv.connsLock.Lock()
+			srv.conns[conn] = true
+			srv.connsLock.Unlock()
+			srv.mtx.RUnlock()
 
+			go func() {
 				defer func() {
 					srv.connsLock.Lock()
 					delete(srv.conns, conn)
@@ -154,11 +164,19 @@ func (srv *Backend) ListenAndServe(network, address string) error {
 	return <-errChan
 }
 
-//
+// serveConn serves the requests of a connection with its own handler, which
+// keeps the protocol negotiated with the peer
 func (srv *Backend) serveConn(conn net.Conn) {
 	logger := srv.logger.WithFields(logrus.Fields{"method": "serveConn",
 		"remoteAddr": conn.RemoteAddr().String()})
 
+	server := rpc.NewServer()
+	if err := server.RegisterName(dag1,
+		srv.handler.forConn(srv.protocol, logger)); err != nil {
+		logger.Error(err)
+		return
+	}
+
 	buf := bufio.NewWriter(conn)
 	codec := &serverCodec{
 		rwc:         conn,
@@ -174,12 +192,13 @@ func (srv *Backend) serveConn(conn net.Conn) {
 		return
 	}
 
-	if err := srv.serveCodec(codec); err != io.EOF {
+	if err := srv.serveCodec(server, codec); err != io.EOF {
 		logger.Warn(err.GoString())
 	}
 }
 
-func (srv *Backend) serveCodec(codec rpc.ServerCodec) *multierror.Error {
+func (srv *Backend) serveCodec(server *rpc.Server,
+	codec rpc.ServerCodec) *multierror.Error {
 	var result *multierror.Error
 	defer func() {
 		if err := codec.Close(); err != nil {
@@ -189,7 +208,7 @@ func (srv *Backend) serveCodec(codec rpc.ServerCodec) *multierror.Error {
 	}()
 
 	for {
-		if err := srv.server.ServeRequest(codec); err != nil {
+		if err := server.ServeRequest(codec); err != nil {
 			result = multierror.Append(result, err)
 			println(err.Error())
 			return result
@@ -225,9 +244,6 @@ func (srv *Backend) Close() error {
 			er = err
 		}
 	}
-	if er != nil {
-		return er
-	}
 	srv.connsLock.Unlock()
 
 	// Stop handler.
@@ -235,7 +251,7 @@ func (srv *Backend) Close() error {
 
 	// Wait for all connections to complete.
 	srv.wg.Wait()
-	return nil
+	return er
 }
 
 type serverCodec struct {
@@ -2,8 +2,10 @@ package peer_test
 
 import (
 	"context"
+	"errors"
 	"net"
 	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -102,3 +104,92 @@ func TestBackendClose(t *testing.T) {
 		}
 	}
 }
+
+var errConnClose = errors.New("close failed")
+
+// failingCloseListener accepts connections which fail to close, and which
+// wait for release to close a second time, at the end of their serving
+type failingCloseListener struct {
+	net.Listener
+	release chan struct{}
+}
+
+func (l failingCloseListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &failingCloseConn{Conn: conn, release: l.release}, nil
+}
+
+type failingCloseConn struct {
+	net.Conn
+	release chan struct{}
+	closes  int32
+}
+
+func (c *failingCloseConn) Close() error {
+	closes := atomic.AddInt32(&c.closes, 1)
+	c.Conn.Close()
+	if closes == 2 {
+		<-c.release
+	}
+	return errConnClose
+}
+
+func TestBackendCloseError(t *testing.T) {
+	srvTimeout := time.Second * 30
+	conf := &peer.BackendConfig{
+		ReceiveTimeout: srvTimeout,
+		ProcessTimeout: srvTimeout,
+		IdleTimeout:    srvTimeout,
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	release := make(chan struct{})
+	listen := func(network, address string) (net.Listener, error) {
+		listener, err := net.Listen(network, address)
+		if err != nil {
+			return nil, err
+		}
+		return failingCloseListener{listener, release}, nil
+	}
+	address := newAddress()
+	backend := newBackend(t, conf, logger, address, done,
+		expSyncResponse, 0, listen)
+
+	rpcCli, err := peer.NewRPCClient(
+		peer.TCP, address, time.Second, net.DialTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := peer.NewClient(rpcCli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the connection is served
+	if err := cli.Sync(context.Background(), &peer.SyncRequest{}, &peer.SyncResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- backend.Close()
+	}()
+	select {
+	case err := <-result:
+		t.Fatalf("expected Close to wait for the connection served to its end, got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case err := <-result:
+		if err != errConnClose {
+			t.Fatalf("expected the error of the connection, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Close to return once the connection is served")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"net/rpc"
+	"sync"
 	"time"
 )
 
@@ -30,12 +31,24 @@ type SyncClient interface {
 		req *ForceSyncRequest, resp *ForceSyncResponse) error
 	FastForward(ctx context.Context,
 		req *FastForwardRequest, resp *FastForwardResponse) error
+	PeerLookup(ctx context.Context,
+		req *PeerLookupRequest, resp *PeerLookupResponse) error
+	Snapshot(ctx context.Context,
+		req *SnapshotRequest, resp *SnapshotResponse) error
+	FrameEvents(ctx context.Context,
+		req *FrameEventsRequest, resp *FrameEventsResponse) error
 	Close() error
 }
 
-// Client is a sync client.
+// Client is a sync client. Once negotiated, the capabilities of its
+// connection gate the optional fields and methods.
 type Client struct {
 	connect RPCClient
+
+	capsLock sync.Mutex
+	caps     *Capabilities
+	// peerVersion is the release the server told in its hello
+	peerVersion string
 }
 
 // NewRPCClient creates new RPC client.
@@ -55,24 +68,165 @@ func NewClient(rpcClient RPCClient) (*Client, error) {
 	return &Client{connect: rpcClient}, nil
 }
 
-// Sync sends a sync request.
+// Negotiate says hello to the server, once per connection, and returns the
+// capabilities of the connection. A server without the Hello method speaks
+// the protocol from before the handshake.
+func (c *Client) Negotiate(ctx context.Context, p Protocol) (Capabilities, error) {
+	c.capsLock.Lock()
+	defer c.capsLock.Unlock()
+	if c.caps != nil {
+		return *c.caps, nil
+	}
+
+	p = p.orDefault()
+	req := &HelloRequest{
+		Version:        p.Version,
+		MinVersion:     p.MinVersion,
+		Features:       p.Features,
+		NetworkID:      p.NetworkID,
+		EventVersion:   p.EventVersion,
+		NodeVersion:    p.NodeVersion,
+		MaxMessageSize: p.MaxMessageSize,
+	}
+	var resp HelloResponse
+	var caps Capabilities
+	err := c.call(ctx, MethodHello, req, &resp, nil)
+	switch {
+	case isUnknownMethod(err):
+		caps, err = p.acceptLegacy()
+	case err == nil:
+		caps, err = p.accept(&resp)
+	}
+	if err != nil {
+		return Capabilities{}, err
+	}
+	c.caps = &caps
+	c.peerVersion = resp.NodeVersion
+	return caps, nil
+}
+
+// PeerVersion returns the release the server told in its hello, empty before
+// the negotiation or for a server which does not tell
+func (c *Client) PeerVersion() string {
+	c.capsLock.Lock()
+	defer c.capsLock.Unlock()
+	return c.peerVersion
+}
+
+// Capabilities returns the capabilities of the connection, false before the
+// negotiation.
+func (c *Client) Capabilities() (Capabilities, bool) {
+	c.capsLock.Lock()
+	defer c.capsLock.Unlock()
+	if c.caps == nil {
+		return Capabilities{}, false
+	}
+	return *c.caps, true
+}
+
+// lacks returns true when the connection was negotiated without f
+func (c *Client) lacks(f Features) bool {
+	caps, ok := c.Capabilities()
+	return ok && !caps.Has(f)
+}
+
+// has returns true when the connection was negotiated with f
+func (c *Client) has(f Features) bool {
+	caps, ok := c.Capabilities()
+	return ok && caps.Has(f)
+}
+
+// Sync sends a sync request. The events of the response may come in the
+// compact encoding.
 func (c *Client) Sync(ctx context.Context,
 	req *SyncRequest, resp *SyncResponse) error {
-	return c.call(ctx, MethodSync, req, resp, nil)
+	noCheckpoints, noAddresses := c.lacks(FeatureCheckpoints), c.lacks(FeatureAddresses)
+	if !noCheckpoints && !noAddresses {
+		if err := c.call(ctx, MethodSync, req, resp, nil); err != nil {
+			return err
+		}
+		return resp.expand()
+	}
+
+	r := *req
+	if noCheckpoints {
+		r.Checkpoints = nil
+	}
+	if noAddresses {
+		r.Addresses = nil
+	}
+	if err := c.call(ctx, MethodSync, &r, resp, nil); err != nil {
+		return err
+	}
+	if noCheckpoints {
+		resp.Checkpoints = nil
+	}
+	if noAddresses {
+		resp.Addresses = nil
+	}
+	return resp.expand()
 }
 
-// ForceSync sends a force sync request.
+// ForceSync sends a force sync request, its events in the compact encoding
+// when the connection has FeatureCompactEvents.
 func (c *Client) ForceSync(ctx context.Context,
 	req *ForceSyncRequest, resp *ForceSyncResponse) error {
+	if c.has(FeatureCompactEvents) {
+		r := *req
+		r.compact()
+		return c.call(ctx, MethodForceSync, &r, resp, nil)
+	}
 	return c.call(ctx, MethodForceSync, req, resp, nil)
 }
 
-// FastForward sends a fast forward request.
+// FastForward sends a fast forward request. A server without the paged
+// frames sends the frame whole.
 func (c *Client) FastForward(ctx context.Context,
 	req *FastForwardRequest, resp *FastForwardResponse) error {
+	if req.Paged && c.lacks(FeatureFramePages) {
+		r := *req
+		r.Paged = false
+		return c.call(ctx, MethodFastForward, &r, resp, nil)
+	}
 	return c.call(ctx, MethodFastForward, req, resp, nil)
 }
 
+// PeerLookup sends a participant lookup request.
+func (c *Client) PeerLookup(ctx context.Context,
+	req *PeerLookupRequest, resp *PeerLookupResponse) error {
+	if c.lacks(FeaturePeerLookup) {
+		return ErrUnsupported
+	}
+	return c.call(ctx, MethodPeerLookup, req, resp, nil)
+}
+
+// Snapshot sends an app snapshot request.
+func (c *Client) Snapshot(ctx context.Context,
+	req *SnapshotRequest, resp *SnapshotResponse) error {
+	if c.lacks(FeatureSnapshot) {
+		return ErrUnsupported
+	}
+	return c.call(ctx, MethodSnapshot, req, resp, nil)
+}
+
+// FrameEvents asks for a page of the events of a fast forward frame, within
+// the max message size of the connection.
+func (c *Client) FrameEvents(ctx context.Context,
+	req *FrameEventsRequest, resp *FrameEventsResponse) error {
+	if c.lacks(FeatureFramePages) {
+		return ErrUnsupported
+	}
+	if caps, ok := c.Capabilities(); ok {
+		r := *req
+		r.MaxBytes = pageMaxBytes(r.MaxBytes, caps.MaxMessageSize)
+		req = &r
+	}
+	if err := c.call(ctx, MethodFrameEvents, req, resp, nil); err != nil {
+		return err
+	}
+	return resp.expand()
+}
+
 // Close closes a sync client.
 func (c *Client) Close() error {
 	return c.connect.Close()
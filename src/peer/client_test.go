@@ -15,6 +15,7 @@ import (
 
 	"github.com/SamuelMarks/dag1/src/peer"
 	"github.com/SamuelMarks/dag1/src/peer/fakenet"
+	"github.com/SamuelMarks/dag1/src/peers"
 	"github.com/SamuelMarks/dag1/src/poset"
 )
 
@@ -35,7 +36,18 @@ var (
 	}
 	expEagerSyncResponse  = &peer.ForceSyncResponse{FromID: 1, Success: true}
 	expFastForwardRequest = &peer.FastForwardRequest{FromID: 0}
-	expSyncRequest        = &peer.SyncRequest{
+	expPeerLookupRequest  = &peer.PeerLookupRequest{FromID: 0, ID: 9}
+	expPeerLookupResponse = &peer.PeerLookupResponse{
+		FromID: 1,
+		Peer:   &peers.PeerMessage{NetAddr: "127.0.0.1:1337", PubKeyHex: "0x0409"},
+	}
+	expSnapshotRequest  = &peer.SnapshotRequest{FromID: 0, BlockIndex: 3, Frame: 5}
+	expSnapshotResponse = &peer.SnapshotResponse{
+		FromID:     1,
+		BlockIndex: 3,
+		Snapshot:   []byte("snapshot"),
+	}
+	expSyncRequest = &peer.SyncRequest{
 		FromID: 0,
 		Known:  map[uint64]int64{0: 1, 1: 2, 2: 3},
 	}
@@ -182,6 +194,66 @@ func TestClientFastForward(t *testing.T) {
 	checkFastForwardResponse(t, expResponse, resp)
 }
 
+func TestClientPeerLookup(t *testing.T) {
+	ctx := context.Background()
+	m := newRPCClient(t, testError, expPeerLookupResponse)
+	cli := newClient(t, m)
+	defer func() {
+		if err := cli.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	resp := &peer.PeerLookupResponse{}
+	if err := cli.PeerLookup(
+		ctx, expPeerLookupRequest, resp); err != testError {
+		t.Fatalf("expected error: %s, got: %s", testError, err)
+	}
+
+	m.err = nil
+
+	resp = &peer.PeerLookupResponse{}
+	if err := cli.PeerLookup(
+		ctx, expPeerLookupRequest, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(resp, expPeerLookupResponse) {
+		t.Fatalf("failed to get response, expected: %+v, got: %+v",
+			expPeerLookupResponse, resp)
+	}
+}
+
+func TestClientSnapshot(t *testing.T) {
+	ctx := context.Background()
+	m := newRPCClient(t, testError, expSnapshotResponse)
+	cli := newClient(t, m)
+	defer func() {
+		if err := cli.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	resp := &peer.SnapshotResponse{}
+	if err := cli.Snapshot(
+		ctx, expSnapshotRequest, resp); err != testError {
+		t.Fatalf("expected error: %s, got: %s", testError, err)
+	}
+
+	m.err = nil
+
+	resp = &peer.SnapshotResponse{}
+	if err := cli.Snapshot(
+		ctx, expSnapshotRequest, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(resp, expSnapshotResponse) {
+		t.Fatalf("failed to get response, expected: %+v, got: %+v",
+			expSnapshotResponse, resp)
+	}
+}
+
 func TestNewClient(t *testing.T) {
 	timeout := time.Second
 	conf := &peer.BackendConfig{
@@ -277,16 +349,15 @@ func newFastForwardResponse(t *testing.T) *peer.FastForwardResponse {
 	}
 
 	return &peer.FastForwardResponse{
-		FromID:   1,
-		Block:    block,
-		Frame:    frame,
-		Snapshot: []byte("snapshot"),
+		FromID: 1,
+		Block:  block,
+		Frame:  frame,
 	}
 }
 
 func checkFastForwardResponse(t *testing.T, exp, got *peer.FastForwardResponse) {
 	if !got.Block.Equals(&exp.Block) || !got.Frame.Equals(&exp.Frame) ||
-		got.FromID != exp.FromID || !bytes.Equal(got.Snapshot, exp.Snapshot) {
+		got.FromID != exp.FromID {
 		t.Fatalf("bad response, expected: %+v, got: %+v", exp, got)
 	}
 
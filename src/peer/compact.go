@@ -0,0 +1,81 @@
+package peer
+
+import (
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// The events of the syncs and of the frame pages go in the compact encoding
+// of the poset package when both nodes have FeatureCompactEvents. The sender
+// compacts them and the receiver expands them back, the nodes without the
+// feature keep the gob encoding of the Events fields.
+
+// compact moves the events of the response to their compact encoding
+func (r *SyncResponse) compact() {
+	if len(r.Events) == 0 {
+		return
+	}
+	r.CompactEvents = poset.MarshalWireEvents(r.Events)
+	r.Events = nil
+}
+
+// expand decodes the compact events of the response
+func (r *SyncResponse) expand() error {
+	if r.CompactEvents == nil {
+		return nil
+	}
+	events, err := poset.UnmarshalWireEvents(r.CompactEvents)
+	if err != nil {
+		return err
+	}
+	r.Events, r.CompactEvents = events, nil
+	return nil
+}
+
+// compact moves the events of the request to their compact encoding
+func (r *ForceSyncRequest) compact() {
+	if len(r.Events) == 0 {
+		return
+	}
+	r.CompactEvents = poset.MarshalWireEvents(r.Events)
+	r.Events = nil
+}
+
+// expand decodes the compact events of the request
+func (r *ForceSyncRequest) expand() error {
+	if r.CompactEvents == nil {
+		return nil
+	}
+	events, err := poset.UnmarshalWireEvents(r.CompactEvents)
+	if err != nil {
+		return err
+	}
+	r.Events, r.CompactEvents = events, nil
+	return nil
+}
+
+// compact moves the events of the response to their compact encoding, it
+// keeps the Events of a page holding a nil event
+func (r *FrameEventsResponse) compact() {
+	if len(r.Events) == 0 {
+		return
+	}
+	data, err := poset.MarshalEventMessages(r.Events)
+	if err != nil {
+		return
+	}
+	r.CompactEvents = data
+	r.Events = nil
+}
+
+// expand decodes the compact events of the response
+func (r *FrameEventsResponse) expand() error {
+	if r.CompactEvents == nil {
+		return nil
+	}
+	events, err := poset.UnmarshalEventMessages(r.CompactEvents)
+	if err != nil {
+		return err
+	}
+	r.Events, r.CompactEvents = events, nil
+	return nil
+}
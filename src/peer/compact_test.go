@@ -0,0 +1,156 @@
+package peer_test
+
+import (
+	"context"
+	"net"
+	"net/rpc"
+	"reflect"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+var (
+	compactWireEvents = []poset.WireEvent{
+		{
+			Body: poset.WireBody{
+				Transactions:    [][]byte{[]byte("tx")},
+				SelfParentIndex: 1,
+				CreatorID:       9,
+				Index:           2,
+				BlockSignatures: []poset.WireBlockSignature{{Index: 1, Signature: "block"}},
+			},
+			Signature: "sig",
+		},
+	}
+	compactEventMessages = []*poset.EventMessage{
+		{
+			Body:      &poset.EventBody{Index: 2, Transactions: [][]byte{[]byte("tx")}},
+			Signature: "sig",
+			CreatorID: 9,
+		},
+	}
+)
+
+// newCompactBackend starts a server replying with events to the syncs and
+// frame pages. The events of the force syncs it receives are sent to
+// received.
+func newCompactBackend(t *testing.T) (string, chan []poset.WireEvent, func()) {
+	backend := peer.NewBackend(peer.NewBackendConfig(), logger, net.Listen)
+	received := make(chan []poset.WireEvent, 10)
+	done := make(chan struct{})
+	go func() {
+		receiver := backend.ReceiverChannel()
+		for {
+			select {
+			case <-done:
+				return
+			case req := <-receiver:
+				var resp interface{}
+				switch r := req.Command.(type) {
+				case *peer.SyncRequest:
+					resp = &peer.SyncResponse{FromID: 1, Events: compactWireEvents}
+				case *peer.ForceSyncRequest:
+					received <- r.Events
+					resp = &peer.ForceSyncResponse{FromID: 1, Success: true}
+				case *peer.FrameEventsRequest:
+					resp = &peer.FrameEventsResponse{FromID: 1, Events: compactEventMessages}
+				}
+				req.RespChan <- &peer.RPCResponse{Response: resp}
+			}
+		}
+	}()
+
+	address := newAddress()
+	if err := backend.ListenAndServe(peer.TCP, address); err != nil {
+		t.Fatal(err)
+	}
+	return address, received, func() {
+		close(done)
+		backend.Close()
+	}
+}
+
+func TestCompactEvents(t *testing.T) {
+	address, received, stop := newCompactBackend(t)
+	defer stop()
+
+	legacy := peer.DefaultProtocol()
+	legacy.Features &^= peer.FeatureCompactEvents
+	for _, protocol := range []peer.Protocol{peer.DefaultProtocol(), legacy} {
+		ctx := context.Background()
+		cli := newVersionClient(t, address)
+		if _, err := cli.Negotiate(ctx, protocol); err != nil {
+			t.Fatal(err)
+		}
+
+		resp := &peer.SyncResponse{}
+		if err := cli.Sync(ctx, &peer.SyncRequest{}, resp); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(resp.Events, compactWireEvents) || resp.CompactEvents != nil {
+			t.Fatalf("expected the events of the sync, got %+v", resp)
+		}
+
+		force := &peer.ForceSyncRequest{Events: compactWireEvents}
+		if err := cli.ForceSync(ctx, force, &peer.ForceSyncResponse{}); err != nil {
+			t.Fatal(err)
+		}
+		if events := <-received; !reflect.DeepEqual(events, compactWireEvents) {
+			t.Fatalf("expected the events of the force sync, got %+v", events)
+		}
+		if force.CompactEvents != nil {
+			t.Fatal("expected the request of the caller untouched")
+		}
+
+		page := &peer.FrameEventsResponse{}
+		if err := cli.FrameEvents(ctx, &peer.FrameEventsRequest{}, page); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(page.Events, compactEventMessages) || page.CompactEvents != nil {
+			t.Fatalf("expected the events of the page, got %+v", page)
+		}
+		cli.Close()
+	}
+}
+
+// TestCompactEventsOnTheWire checks the events go compact only over the
+// connections which negotiated it
+func TestCompactEventsOnTheWire(t *testing.T) {
+	address, _, stop := newCompactBackend(t)
+	defer stop()
+
+	for _, features := range []peer.Features{
+		peer.SupportedFeatures,
+		peer.SupportedFeatures &^ peer.FeatureCompactEvents,
+	} {
+		conn, err := rpc.Dial(peer.TCP, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hello := &peer.HelloRequest{
+			Version:    peer.ProtocolVersion,
+			MinVersion: peer.MinProtocolVersion,
+			Features:   features,
+		}
+		if err := conn.Call(peer.MethodHello, hello, &peer.HelloResponse{}); err != nil {
+			t.Fatal(err)
+		}
+		resp := &peer.SyncResponse{}
+		if err := conn.Call(peer.MethodSync, &peer.SyncRequest{}, resp); err != nil {
+			t.Fatal(err)
+		}
+		compact := features&peer.FeatureCompactEvents != 0
+		if compact != (resp.CompactEvents != nil) || compact != (resp.Events == nil) {
+			t.Fatalf("features %b: unexpected response %+v", features, resp)
+		}
+		if compact {
+			events, err := poset.UnmarshalWireEvents(resp.CompactEvents)
+			if err != nil || !reflect.DeepEqual(events, compactWireEvents) {
+				t.Fatalf("expected the compact events decoded, got %+v (%v)", events, err)
+			}
+		}
+		conn.Close()
+	}
+}
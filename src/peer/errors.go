@@ -10,4 +10,6 @@ var (
 	ErrProcessingTimeout     = errors.New("processing timeout")
 	ErrBadResult             = errors.New("bad result")
 	ErrServerAlreadyRunning  = errors.New("server already running")
+	ErrRateLimited           = errors.New("rate limited")
+	ErrUnsupported           = errors.New("not supported by the peer")
 )
@@ -2,28 +2,69 @@ package peer
 
 import (
 	"context"
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/peers"
 	"github.com/SamuelMarks/dag1/src/poset"
 	"github.com/sirupsen/logrus"
 )
 
-// SyncRequest initiates a synchronization request.
+// SyncRequest initiates a synchronization request. ConsensusHash is the
+// hash of the consensus parameters of the requesting node, a node with other
+// ones is refused. EventVersion is the highest event body version it reads,
+// zero from old clients.
 type SyncRequest struct {
-	FromID uint64
-	Known  map[uint64]int64
+	FromID        uint64
+	Known         map[uint64]int64
+	Checkpoints   []poset.BlockSignature   // optional, latest checkpoint signatures
+	Addresses     []peers.AddrAnnouncement // optional, signed participant addresses
+	ConsensusHash common.Hash
+	NetworkID     common.Hash
+	EventVersion  uint32
+	// Limit and MaxBytes bound the events of the response, in count and in
+	// size, 0 for no bound. Old clients send neither.
+	Limit    int64
+	MaxBytes int64
 }
 
-// SyncResponse is a response to a SyncRequest request.
+// SyncResponse is a response to a SyncRequest request. A node refusing the
+// consensus parameters of the request sends no events, and its own parameters
+// for the requester to tell what differs.
 type SyncResponse struct {
-	FromID    uint64
-	SyncLimit bool
-	Events    []poset.WireEvent
-	Known     map[uint64]int64
+	FromID          uint64
+	SyncLimit       bool
+	Events          []poset.WireEvent
+	Known           map[uint64]int64
+	Checkpoints     []poset.BlockSignature   // optional, latest checkpoint signatures
+	Addresses       []peers.AddrAnnouncement // optional, signed participant addresses
+	ConsensusHash   common.Hash
+	ConsensusParams *poset.ConsensusParams // only when refused
+	EventVersion    uint32
+	// Bytes is the size of the events, see poset.Event.WireSize, 0 from
+	// old clients
+	Bytes int64
+	// Head is the last event of the responder, sent even without events so
+	// the requester knows whether the responder has news for it. Old clients
+	// send none.
+	Head *Head
+	// CompactEvents are the Events in the compact encoding, in their place
+	// when both nodes have FeatureCompactEvents
+	CompactEvents []byte
+}
+
+// Head is the last event a participant made
+type Head struct {
+	Hash  poset.EventHash
+	Index int64
+	Round int64 // the frame of the event
 }
 
 // ForceSyncRequest after an initial sync to quickly catch up.
 type ForceSyncRequest struct {
 	FromID uint64
 	Events []poset.WireEvent
+	// CompactEvents are the Events in the compact encoding, in their place
+	// when both nodes have FeatureCompactEvents
+	CompactEvents []byte
 }
 
 // ForceSyncResponse response to an ForceSyncRequest.
@@ -32,18 +73,88 @@ type ForceSyncResponse struct {
 	Success bool
 }
 
-// FastForwardRequest request to start a fast forward catch up.
+// FastForwardRequest request to start a fast forward catch up. Paged asks
+// for the frame without its events, which are fetched with FrameEvents
+// requests. Old clients do not ask.
 type FastForwardRequest struct {
-	FromID uint64
+	FromID        uint64
+	ConsensusHash common.Hash
+	Paged         bool
 }
 
-// FastForwardResponse response with the snapshot data for fast forward
-// request.
+// FastForwardResponse response with the anchor block and frame for fast
+// forward request, the app snapshot of the block is asked for with a
+// SnapshotRequest. Participants lets a joining node learn the peer set
+// without a local peers.json.
 type FastForwardResponse struct {
-	FromID   uint64
-	Block    poset.Block
-	Frame    poset.Frame
-	Snapshot []byte
+	FromID          uint64
+	Block           poset.Block
+	Frame           poset.Frame
+	Participants    []*peers.PeerMessage
+	ConsensusHash   common.Hash
+	ConsensusParams *poset.ConsensusParams // only when refused
+	// FrameEvents and FrameHash are the number of events and the hash of
+	// the frame when its events are left out, as asked by Paged. FrameHash
+	// is nil when the frame is whole.
+	FrameEvents int64
+	FrameHash   []byte
+	// BlockCarry is the transactions carried over past Block into the next
+	// block
+	BlockCarry [][]byte
+}
+
+// FrameEventsRequest asks for the events of the frame of a paged fast
+// forward, from Offset on. Limit and MaxBytes bound the events of the
+// response as those of a SyncRequest.
+type FrameEventsRequest struct {
+	FromID    uint64
+	Round     int64
+	FrameHash []byte
+	Offset    int64
+	Limit     int64
+	MaxBytes  int64
+}
+
+// FrameEventsResponse carries a page of the events of a frame. More is set
+// while events of the frame are left after the page.
+type FrameEventsResponse struct {
+	FromID uint64
+	Events []*poset.EventMessage
+	More   bool
+	// CompactEvents are the Events in the compact encoding, in their place
+	// when both nodes have FeatureCompactEvents
+	CompactEvents []byte
+}
+
+// SnapshotRequest asks for the app snapshot taken after a block, the anchor
+// block of a fast forward.
+type SnapshotRequest struct {
+	FromID     uint64
+	BlockIndex int64
+	Frame      int64
+}
+
+// SnapshotResponse carries the app snapshot of the requested block and, when
+// the responding node has one, its checkpoint of the frame of the block.
+type SnapshotResponse struct {
+	FromID     uint64
+	BlockIndex int64
+	Snapshot   []byte
+	Checkpoint *poset.Checkpoint
+}
+
+// PeerLookupRequest asks for the participant with a given ID, used when a
+// wire event refers to a creator ID missing from the local peer set.
+type PeerLookupRequest struct {
+	FromID uint64
+	ID     uint64
+}
+
+// PeerLookupResponse carries the requested participant, Peer is nil when
+// the responding node does not know the ID either.
+type PeerLookupResponse struct {
+	FromID uint64
+	Peer   *peers.PeerMessage
 }
 
 // RPCResponse captures both a response and a potential error.
@@ -18,7 +18,16 @@ type SyncPeer interface {
 		req *ForceSyncRequest, resp *ForceSyncResponse) error
 	FastForward(ctx context.Context, target string,
 		req *FastForwardRequest, resp *FastForwardResponse) error
+	PeerLookup(ctx context.Context, target string,
+		req *PeerLookupRequest, resp *PeerLookupResponse) error
+	Snapshot(ctx context.Context, target string,
+		req *SnapshotRequest, resp *SnapshotResponse) error
+	FrameEvents(ctx context.Context, target string,
+		req *FrameEventsRequest, resp *FrameEventsResponse) error
 	ReceiverChannel() <-chan *RPC
+	// Forget drops the connections to a target, a peer which moved to
+	// another address
+	Forget(target string)
 	Close() error
 }
 
@@ -28,6 +37,10 @@ type Peer struct {
 	logger         logrus.FieldLogger
 	server         SyncServer
 
+	protocolLock sync.RWMutex
+	protocol     Protocol
+	onVersion    func(target, version string)
+
 	mtx      sync.RWMutex
 	shutdown bool
 
@@ -42,10 +55,48 @@ func NewTransport(logger logrus.FieldLogger,
 		clientProducer: clientProducer,
 		logger:         logger,
 		server:         server,
+		protocol:       DefaultProtocol(),
 		wg:             &sync.WaitGroup{},
 	}
 }
 
+// SetProtocol sets the protocol the transport negotiates on its connections.
+func (tr *Peer) SetProtocol(p Protocol) {
+	tr.protocolLock.Lock()
+	defer tr.protocolLock.Unlock()
+	tr.protocol = p.orDefault()
+}
+
+// SetVersionHandler sets the function told the release of the target of a
+// connection once it is negotiated, on every request to it.
+func (tr *Peer) SetVersionHandler(f func(target, version string)) {
+	tr.protocolLock.Lock()
+	defer tr.protocolLock.Unlock()
+	tr.onVersion = f
+}
+
+// negotiate negotiates the protocol of the connection of a client which
+// supports it, and closes a connection to a peer it refuses.
+func (tr *Peer) negotiate(ctx context.Context, target string, cli SyncClient) error {
+	n, ok := cli.(negotiator)
+	if !ok {
+		return nil
+	}
+	tr.protocolLock.RLock()
+	protocol := tr.protocol
+	onVersion := tr.onVersion
+	tr.protocolLock.RUnlock()
+
+	if _, err := n.Negotiate(ctx, protocol); err != nil {
+		cli.Close()
+		return err
+	}
+	if v, ok := cli.(versionTeller); ok && onVersion != nil {
+		onVersion(target, v.PeerVersion())
+	}
+	return nil
+}
+
 // Sync creates a sync request to a specific node.
 func (tr *Peer) Sync(ctx context.Context, target string,
 	req *SyncRequest, resp *SyncResponse) error {
@@ -70,6 +121,11 @@ func (tr *Peer) sync(ctx context.Context, target string,
 		return err
 	}
 
+	if err := tr.negotiate(ctx, target, cli); err != nil {
+		logger.Error(err)
+		return err
+	}
+
 	if err := cli.Sync(ctx, req, resp); err != nil {
 		logger.Error(err)
 		return err
@@ -103,6 +159,11 @@ func (tr *Peer) forceSync(ctx context.Context, target string,
 		return err
 	}
 
+	if err := tr.negotiate(ctx, target, cli); err != nil {
+		logger.Error(err)
+		return err
+	}
+
 	if err := cli.ForceSync(ctx, req, resp); err != nil {
 		logger.Error(err)
 		return err
@@ -137,6 +198,11 @@ func (tr *Peer) fastForward(ctx context.Context, target string,
 		return err
 	}
 
+	if err := tr.negotiate(ctx, target, cli); err != nil {
+		logger.Error(err)
+		return err
+	}
+
 	if err := cli.FastForward(ctx, req, resp); err != nil {
 		logger.Error(err)
 		return err
@@ -146,6 +212,124 @@ func (tr *Peer) fastForward(ctx context.Context, target string,
 	return nil
 }
 
+// PeerLookup asks a specific node for the participant with a given ID.
+func (tr *Peer) PeerLookup(ctx context.Context, target string,
+	req *PeerLookupRequest, resp *PeerLookupResponse) error {
+
+	if tr.isShutdown() {
+		return ErrTransportStopped
+	}
+
+	tr.wg.Add(1)
+	defer tr.wg.Done()
+
+	return tr.peerLookup(ctx, target, req, resp)
+}
+
+func (tr *Peer) peerLookup(ctx context.Context, target string,
+	req *PeerLookupRequest, resp *PeerLookupResponse) error {
+	logger := tr.logger.WithFields(logrus.Fields{"method": "peerLookup",
+		"target": target})
+
+	cli, err := tr.clientProducer.Pop(target)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	if err := tr.negotiate(ctx, target, cli); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	if err := cli.PeerLookup(ctx, req, resp); err != nil {
+		logger.Error(err)
+		return err
+	}
+	tr.clientProducer.Push(target, cli)
+
+	return nil
+}
+
+// Snapshot asks a specific node for the app snapshot of a block.
+func (tr *Peer) Snapshot(ctx context.Context, target string,
+	req *SnapshotRequest, resp *SnapshotResponse) error {
+
+	if tr.isShutdown() {
+		return ErrTransportStopped
+	}
+
+	tr.wg.Add(1)
+	defer tr.wg.Done()
+
+	return tr.snapshot(ctx, target, req, resp)
+}
+
+func (tr *Peer) snapshot(ctx context.Context, target string,
+	req *SnapshotRequest, resp *SnapshotResponse) error {
+	logger := tr.logger.WithFields(logrus.Fields{"method": "snapshot",
+		"target": target})
+
+	cli, err := tr.clientProducer.Pop(target)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	if err := tr.negotiate(ctx, target, cli); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	if err := cli.Snapshot(ctx, req, resp); err != nil {
+		logger.Error(err)
+		return err
+	}
+	tr.clientProducer.Push(target, cli)
+
+	return nil
+}
+
+// FrameEvents asks a specific node for a page of the events of the frame of
+// a paged fast forward.
+func (tr *Peer) FrameEvents(ctx context.Context, target string,
+	req *FrameEventsRequest, resp *FrameEventsResponse) error {
+
+	if tr.isShutdown() {
+		return ErrTransportStopped
+	}
+
+	tr.wg.Add(1)
+	defer tr.wg.Done()
+
+	return tr.frameEvents(ctx, target, req, resp)
+}
+
+func (tr *Peer) frameEvents(ctx context.Context, target string,
+	req *FrameEventsRequest, resp *FrameEventsResponse) error {
+	logger := tr.logger.WithFields(logrus.Fields{"method": "frameEvents",
+		"target": target})
+
+	cli, err := tr.clientProducer.Pop(target)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	if err := tr.negotiate(ctx, target, cli); err != nil {
+		logger.Error(err)
+		return err
+	}
+
+	if err := cli.FrameEvents(ctx, req, resp); err != nil {
+		logger.Error(err)
+		return err
+	}
+	tr.clientProducer.Push(target, cli)
+
+	return nil
+}
+
 // ReceiverChannel returns a sync server receiver channel.
 func (tr *Peer) ReceiverChannel() <-chan *RPC {
 	tr.mtx.Lock()
@@ -154,6 +338,11 @@ func (tr *Peer) ReceiverChannel() <-chan *RPC {
 	return tr.server.ReceiverChannel()
 }
 
+// Forget drops the pooled connections to a target.
+func (tr *Peer) Forget(target string) {
+	tr.clientProducer.Drop(target)
+}
+
 // Close closes the transport.
 func (tr *Peer) Close() error {
 	logger := tr.logger.WithField("method", "Close")
@@ -10,6 +10,7 @@ import (
 type ClientProducer interface {
 	Pop(target string) (SyncClient, error)
 	Push(target string, client SyncClient)
+	Drop(target string)
 	Close()
 }
 
@@ -73,6 +74,22 @@ func (p *Producer) Push(target string, client SyncClient) {
 	p.pool[target] = append(p.pool[target], client)
 }
 
+// Drop closes the connections to a target in the pool.
+func (p *Producer) Drop(target string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.shutdown {
+		return
+	}
+	for _, client := range p.pool[target] {
+		if err := client.Close(); err != nil {
+			panic(err)
+		}
+	}
+	delete(p.pool, target)
+}
+
 // Close closes a producer.
 func (p *Producer) Close() {
 	p.mtx.Lock()
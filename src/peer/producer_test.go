@@ -74,6 +74,13 @@ func TestProducer(t *testing.T) {
 		t.Fatalf("expected %d, got %d", limit, producer.ConnLen(target))
 	}
 
+	// Test drop the connections to a moved target.
+	producer.Drop(target)
+
+	if producer.ConnLen(target) != 0 {
+		t.Fatalf("expected %d, got %d", 0, producer.ConnLen(target))
+	}
+
 	// Test close producer.
 	producer.Close()
 
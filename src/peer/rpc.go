@@ -1,7 +1,13 @@
 package peer
 
 import (
+	"sync"
 	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/version"
 )
 
 // RPC Methods.
@@ -9,14 +15,29 @@ const (
 	MethodSync        = "DAG1.Sync"
 	MethodForceSync   = "DAG1.ForceSync"
 	MethodFastForward = "DAG1.FastForward"
+	MethodPeerLookup  = "DAG1.PeerLookup"
+	MethodSnapshot    = "DAG1.Snapshot"
+	MethodFrameEvents = "DAG1.FrameEvents"
 )
 
-// DAG1 implements DAG1 synchronization methods.
+// DAG1 implements DAG1 synchronization methods. The handler of a connection
+// keeps the capabilities negotiated by the Hello of the peer.
 type DAG1 struct {
 	done           chan struct{}
 	receiver       chan *RPC
 	processTimeout time.Duration
 	receiveTimeout time.Duration
+	clock          common.Clock
+
+	protocol Protocol
+	logger   logrus.FieldLogger
+	conn     *connection
+}
+
+// connection is the state of the connection of a handler
+type connection struct {
+	mtx  sync.Mutex
+	caps *Capabilities
 }
 
 // NewDAG1 creates new DAG1 RPC handler.
@@ -27,12 +48,95 @@ func NewDAG1(done chan struct{}, receiver chan *RPC,
 		receiver:       receiver,
 		processTimeout: processTimeout,
 		receiveTimeout: receiveTimeout,
+		clock:          common.RealClock,
+		protocol:       DefaultProtocol(),
+		logger:         logrus.New(),
+		conn:           &connection{},
+	}
+}
+
+// forConn returns a handler for a new connection, with protocol and logger
+func (r *DAG1) forConn(protocol Protocol, logger logrus.FieldLogger) *DAG1 {
+	h := *r
+	h.protocol = protocol.orDefault()
+	h.logger = logger
+	h.conn = &connection{}
+	return &h
+}
+
+// Hello negotiates the protocol of the connection: the highest version both
+// nodes support and the features both have. A peer below the minimum
+// version of either node is refused.
+func (r *DAG1) Hello(req *HelloRequest, resp *HelloResponse) error {
+	caps, err := r.protocol.negotiate(req)
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"our_version":   r.protocol.Version,
+			"their_version": req.Version,
+			"min_version":   r.protocol.MinVersion,
+			"their_min":     req.MinVersion,
+		}).Warn("refused the protocol of the peer")
+		return err
+	}
+
+	r.conn.mtx.Lock()
+	r.conn.caps = &caps
+	r.conn.mtx.Unlock()
+
+	if !version.SameMinor(req.NodeVersion) {
+		r.logger.WithFields(logrus.Fields{
+			"our_release":   r.protocol.NodeVersion,
+			"their_release": req.NodeVersion,
+		}).Warn("peer runs another minor release")
+	}
+
+	*resp = HelloResponse{
+		Version:        caps.Version,
+		Features:       caps.Features,
+		NetworkID:      r.protocol.NetworkID,
+		EventVersion:   r.protocol.EventVersion,
+		NodeVersion:    r.protocol.NodeVersion,
+		MaxMessageSize: caps.MaxMessageSize,
+	}
+	return nil
+}
+
+// capabilities returns the capabilities of the connection, those of a peer
+// from before the handshake when it did not say hello. Such a peer is
+// refused below the minimum version.
+func (r *DAG1) capabilities() (Capabilities, error) {
+	r.conn.mtx.Lock()
+	defer r.conn.mtx.Unlock()
+	if r.conn.caps != nil {
+		return *r.conn.caps, nil
 	}
+	caps, err := r.protocol.acceptLegacy()
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"our_version":   r.protocol.Version,
+			"their_version": legacyProtocolVersion,
+			"min_version":   r.protocol.MinVersion,
+		}).Warn("refused a peer which did not negotiate the protocol")
+		return caps, err
+	}
+	r.conn.caps = &caps
+	return caps, nil
 }
 
 // Sync handles sync requests.
 func (r *DAG1) Sync(
 	req *SyncRequest, resp *SyncResponse) error {
+	caps, err := r.capabilities()
+	if err != nil {
+		return err
+	}
+	if !caps.Has(FeatureCheckpoints) {
+		req.Checkpoints = nil
+	}
+	if !caps.Has(FeatureAddresses) {
+		req.Addresses = nil
+	}
+
 	result, err := r.process(req)
 	if err != nil {
 		return err
@@ -43,12 +147,25 @@ func (r *DAG1) Sync(
 		return ErrBadResult
 	}
 	*resp = *item
+	if !caps.Has(FeatureCheckpoints) {
+		resp.Checkpoints = nil
+	}
+	if !caps.Has(FeatureAddresses) {
+		resp.Addresses = nil
+	}
+	if caps.Has(FeatureCompactEvents) {
+		resp.compact()
+	}
 	return nil
 }
 
-// ForceSync handles force sync requests.
+// ForceSync handles force sync requests, whose events may come in the
+// compact encoding.
 func (r *DAG1) ForceSync(
 	req *ForceSyncRequest, resp *ForceSyncResponse) error {
+	if err := req.expand(); err != nil {
+		return err
+	}
 	result, err := r.process(req)
 	if err != nil {
 		return err
@@ -65,6 +182,14 @@ func (r *DAG1) ForceSync(
 // FastForward handles fast forward requests.
 func (r *DAG1) FastForward(
 	req *FastForwardRequest, resp *FastForwardResponse) error {
+	caps, err := r.capabilities()
+	if err != nil {
+		return err
+	}
+	if !caps.Has(FeatureFramePages) {
+		req.Paged = false
+	}
+
 	result, err := r.process(req)
 	if err != nil {
 		return err
@@ -78,6 +203,67 @@ func (r *DAG1) FastForward(
 	return nil
 }
 
+// PeerLookup handles participant lookup requests.
+func (r *DAG1) PeerLookup(
+	req *PeerLookupRequest, resp *PeerLookupResponse) error {
+	result, err := r.process(req)
+	if err != nil {
+		return err
+	}
+
+	item, ok := result.(*PeerLookupResponse)
+	if !ok {
+		return ErrBadResult
+	}
+	*resp = *item
+	return nil
+}
+
+// Snapshot handles app snapshot requests.
+func (r *DAG1) Snapshot(
+	req *SnapshotRequest, resp *SnapshotResponse) error {
+	result, err := r.process(req)
+	if err != nil {
+		return err
+	}
+
+	item, ok := result.(*SnapshotResponse)
+	if !ok {
+		return ErrBadResult
+	}
+	*resp = *item
+	return nil
+}
+
+// FrameEvents handles the requests of the events of a paged fast forward
+// frame. The page fits in the max message size of the connection.
+func (r *DAG1) FrameEvents(
+	req *FrameEventsRequest, resp *FrameEventsResponse) error {
+	caps, err := r.capabilities()
+	if err != nil {
+		return err
+	}
+	if !caps.Has(FeatureFramePages) {
+		return ErrUnsupported
+	}
+	req.MaxBytes = pageMaxBytes(req.MaxBytes, caps.MaxMessageSize)
+
+	result, err := r.process(req)
+	if err != nil {
+		return err
+	}
+
+	item, ok := result.(*FrameEventsResponse)
+	if !ok {
+		return ErrBadResult
+	}
+	*resp = *item
+	if caps.Has(FeatureCompactEvents) {
+		resp.compact()
+	}
+	return nil
+}
+
 func (r *DAG1) send(req interface{}) *RPCResponse {
 	reply := make(chan *RPCResponse, 1) // Buffered.
 	ticket := &RPC{
@@ -85,23 +271,25 @@ func (r *DAG1) send(req interface{}) *RPCResponse {
 		RespChan: reply,
 	}
 
-	timer := time.NewTimer(r.receiveTimeout)
-
+	timer := r.clock.NewTimer(r.receiveTimeout)
 	select {
 	case r.receiver <- ticket:
-	case <-timer.C:
+		timer.Stop()
+	case <-timer.C():
 		return &RPCResponse{Error: ErrReceiverIsBusy}
 	case <-r.done:
+		timer.Stop()
 		return &RPCResponse{Error: ErrTransportStopped}
 	}
 
 	var result *RPCResponse
 
-	timer.Reset(r.processTimeout)
+	timer = r.clock.NewTimer(r.processTimeout)
+	defer timer.Stop()
 
 	select {
 	case result = <-reply:
-	case <-timer.C:
+	case <-timer.C():
 		result = &RPCResponse{Error: ErrProcessingTimeout}
 	case <-r.done:
 		return &RPCResponse{Error: ErrTransportStopped}
@@ -111,6 +299,9 @@ func (r *DAG1) send(req interface{}) *RPCResponse {
 }
 
 func (r *DAG1) process(req interface{}) (resp interface{}, err error) {
+	if _, err := r.capabilities(); err != nil {
+		return nil, err
+	}
 	result := r.send(req)
 	if result.Error != nil {
 		return nil, result.Error
@@ -166,6 +166,62 @@ func TestDAG1FastForward(t *testing.T) {
 	}
 }
 
+func TestDAG1PeerLookup(t *testing.T) {
+	receiver := make(chan *peer.RPC)
+	env := newEnv(expPeerLookupRequest, expPeerLookupResponse,
+		testError, 0, time.Second, receiver)
+	defer env.close(t)
+
+	resp := &peer.PeerLookupResponse{}
+	if err := env.handler.PeerLookup(expPeerLookupRequest, resp); err == nil {
+		t.Fatalf("expected error %s, got: error is null", testError)
+	}
+	env.close(t)
+
+	receiver = make(chan *peer.RPC)
+	env = newEnv(expPeerLookupRequest, expPeerLookupResponse,
+		nil, 0, time.Second, receiver)
+	defer env.close(t)
+
+	resp = &peer.PeerLookupResponse{}
+	if err := env.handler.PeerLookup(expPeerLookupRequest, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(resp, expPeerLookupResponse) {
+		t.Fatalf("failed to get response, expected: %+v, got: %+v",
+			expPeerLookupResponse, resp)
+	}
+}
+
+func TestDAG1Snapshot(t *testing.T) {
+	receiver := make(chan *peer.RPC)
+	env := newEnv(expSnapshotRequest, expSnapshotResponse,
+		testError, 0, time.Second, receiver)
+	defer env.close(t)
+
+	resp := &peer.SnapshotResponse{}
+	if err := env.handler.Snapshot(expSnapshotRequest, resp); err == nil {
+		t.Fatalf("expected error %s, got: error is null", testError)
+	}
+	env.close(t)
+
+	receiver = make(chan *peer.RPC)
+	env = newEnv(expSnapshotRequest, expSnapshotResponse,
+		nil, 0, time.Second, receiver)
+	defer env.close(t)
+
+	resp = &peer.SnapshotResponse{}
+	if err := env.handler.Snapshot(expSnapshotRequest, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(resp, expSnapshotResponse) {
+		t.Fatalf("failed to get response, expected: %+v, got: %+v",
+			expSnapshotResponse, resp)
+	}
+}
+
 func TestTimeout(t *testing.T) {
 	delay := time.Second
 
@@ -0,0 +1,284 @@
+package peer
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The simulation transport puts the conditions of a wide area network
+// between the nodes of a process: latency and jitter, lost messages and
+// partitions. It wraps the transports of the nodes, which the node and
+// poset code use unchanged. A request goes over the link from the node to
+// its target, its response over the link back, each delayed and possibly
+// lost by the conditions of its link.
+
+// Errors of the simulated network
+var (
+	ErrSimDropped     = errors.New("message lost by the simulated network")
+	ErrSimPartitioned = errors.New("peer cut off by a simulated partition")
+)
+
+// LinkConditions are those of the messages over a link: a latency drawn in
+// [Latency-Jitter, Latency+Jitter] each, and the probability Loss of one
+// being lost.
+type LinkConditions struct {
+	Latency time.Duration `mapstructure:"latency"`
+	Jitter  time.Duration `mapstructure:"jitter"`
+	Loss    float64       `mapstructure:"loss"`
+}
+
+// LinkRule sets the conditions of the links from From to To, an empty
+// address standing for any node. The last rule matching a link wins.
+type LinkRule struct {
+	From           string `mapstructure:"from"`
+	To             string `mapstructure:"to"`
+	LinkConditions `mapstructure:",squash"`
+}
+
+// PartitionSchedule cuts the network into Groups At a time after the start
+// of the scenario, and heals it after Duration, never for 0. A node in no
+// group is cut off from all the others.
+type PartitionSchedule struct {
+	At       time.Duration `mapstructure:"at"`
+	Duration time.Duration `mapstructure:"duration"`
+	Groups   [][]string    `mapstructure:"groups"`
+}
+
+// Scenario is the network a simulation puts between its nodes. Seed seeds
+// the draws of latencies and losses.
+type Scenario struct {
+	Seed       int64               `mapstructure:"seed"`
+	Links      []LinkRule          `mapstructure:"links"`
+	Partitions []PartitionSchedule `mapstructure:"partitions"`
+}
+
+// SimStats count the messages of a simulated network
+type SimStats struct {
+	Delivered   uint64
+	Dropped     uint64
+	Partitioned uint64
+}
+
+// SimNetwork is the simulated network shared by the transports of the
+// nodes, see Wrap. The links and partitions can be changed while it runs.
+type SimNetwork struct {
+	mtx      sync.Mutex
+	rng      *rand.Rand
+	links    []LinkRule
+	schedule []PartitionSchedule
+	// groups is the group of each node while partitioned, nil when healed
+	groups map[string]int
+	stats  SimStats
+}
+
+// NewSimNetwork creates a simulated network of the links of scenario, its
+// partitions are played by Start
+func NewSimNetwork(scenario Scenario) *SimNetwork {
+	return &SimNetwork{
+		rng:      rand.New(rand.NewSource(scenario.Seed)),
+		links:    append([]LinkRule(nil), scenario.Links...),
+		schedule: append([]PartitionSchedule(nil), scenario.Partitions...),
+	}
+}
+
+// SetLink sets the conditions of the links from from to to, an empty
+// address standing for any node
+func (n *SimNetwork) SetLink(from, to string, conditions LinkConditions) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.links = append(n.links, LinkRule{From: from, To: to, LinkConditions: conditions})
+}
+
+// Partition cuts the network into groups, the nodes of a group only reach
+// each other
+func (n *SimNetwork) Partition(groups ...[]string) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.groups = make(map[string]int)
+	for i, group := range groups {
+		for _, addr := range group {
+			n.groups[addr] = i
+		}
+	}
+}
+
+// Heal ends the partition of the network
+func (n *SimNetwork) Heal() {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.groups = nil
+}
+
+// Stats returns the counts of the messages of the network
+func (n *SimNetwork) Stats() SimStats {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return n.stats
+}
+
+// Start plays the partitions of the scenario, and returns a function
+// stopping them
+func (n *SimNetwork) Start() func() {
+	stop := make(chan struct{})
+	start := time.Now()
+	for _, p := range n.schedule {
+		go func(p PartitionSchedule) {
+			if !sleepUntil(stop, start.Add(p.At)) {
+				return
+			}
+			n.Partition(p.Groups...)
+			if p.Duration > 0 && sleepUntil(stop, start.Add(p.At+p.Duration)) {
+				n.Heal()
+			}
+		}(p)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stop) })
+	}
+}
+
+// sleepUntil returns true at t, false when stop is closed before
+func sleepUntil(stop <-chan struct{}, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// Wrap returns the transport of the node at local over the network
+func (n *SimNetwork) Wrap(local string, trans SyncPeer) *SimTransport {
+	return &SimTransport{SyncPeer: trans, local: local, network: n}
+}
+
+// send returns the delay of a message from from to to, or the error of a
+// message lost or cut off
+func (n *SimNetwork) send(from, to string) (time.Duration, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if n.groups != nil {
+		g1, ok1 := n.groups[from]
+		g2, ok2 := n.groups[to]
+		if !ok1 || !ok2 || g1 != g2 {
+			n.stats.Partitioned++
+			return 0, ErrSimPartitioned
+		}
+	}
+
+	var c LinkConditions
+	for _, rule := range n.links {
+		if (rule.From == "" || rule.From == from) && (rule.To == "" || rule.To == to) {
+			c = rule.LinkConditions
+		}
+	}
+	if c.Loss > 0 && n.rng.Float64() < c.Loss {
+		n.stats.Dropped++
+		return c.Latency, ErrSimDropped
+	}
+	delay := c.Latency
+	if c.Jitter > 0 {
+		delay += time.Duration(n.rng.Int63n(int64(2*c.Jitter)+1)) - c.Jitter
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	n.stats.Delivered++
+	return delay, nil
+}
+
+// SimTransport is the transport of a node over a simulated network
+type SimTransport struct {
+	SyncPeer
+	local   string
+	network *SimNetwork
+}
+
+// SetProtocol sets the protocol of the wrapped transport, see Peer
+func (t *SimTransport) SetProtocol(p Protocol) {
+	if tr, ok := t.SyncPeer.(*Peer); ok {
+		tr.SetProtocol(p)
+	}
+}
+
+// call makes a request to target over the network: the request is delayed
+// over the link to the target, the response over the link back
+func (t *SimTransport) call(ctx context.Context, target string, request func() error) error {
+	if err := t.hop(ctx, t.local, target); err != nil {
+		return err
+	}
+	if err := request(); err != nil {
+		return err
+	}
+	return t.hop(ctx, target, t.local)
+}
+
+// hop waits for a message to go from from to to
+func (t *SimTransport) hop(ctx context.Context, from, to string) error {
+	delay, err := t.network.send(from, to)
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// Sync sends a sync request over the network.
+func (t *SimTransport) Sync(ctx context.Context, target string,
+	req *SyncRequest, resp *SyncResponse) error {
+	return t.call(ctx, target, func() error {
+		return t.SyncPeer.Sync(ctx, target, req, resp)
+	})
+}
+
+// ForceSync sends a force sync request over the network.
+func (t *SimTransport) ForceSync(ctx context.Context, target string,
+	req *ForceSyncRequest, resp *ForceSyncResponse) error {
+	return t.call(ctx, target, func() error {
+		return t.SyncPeer.ForceSync(ctx, target, req, resp)
+	})
+}
+
+// FastForward sends a fast forward request over the network.
+func (t *SimTransport) FastForward(ctx context.Context, target string,
+	req *FastForwardRequest, resp *FastForwardResponse) error {
+	return t.call(ctx, target, func() error {
+		return t.SyncPeer.FastForward(ctx, target, req, resp)
+	})
+}
+
+// PeerLookup sends a participant lookup request over the network.
+func (t *SimTransport) PeerLookup(ctx context.Context, target string,
+	req *PeerLookupRequest, resp *PeerLookupResponse) error {
+	return t.call(ctx, target, func() error {
+		return t.SyncPeer.PeerLookup(ctx, target, req, resp)
+	})
+}
+
+// Snapshot sends an app snapshot request over the network.
+func (t *SimTransport) Snapshot(ctx context.Context, target string,
+	req *SnapshotRequest, resp *SnapshotResponse) error {
+	return t.call(ctx, target, func() error {
+		return t.SyncPeer.Snapshot(ctx, target, req, resp)
+	})
+}
+
+// FrameEvents sends a frame page request over the network.
+func (t *SimTransport) FrameEvents(ctx context.Context, target string,
+	req *FrameEventsRequest, resp *FrameEventsResponse) error {
+	return t.call(ctx, target, func() error {
+		return t.SyncPeer.FrameEvents(ctx, target, req, resp)
+	})
+}
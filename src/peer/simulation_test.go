@@ -0,0 +1,95 @@
+package peer_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/peer"
+)
+
+// countingPeer counts the syncs reaching it
+type countingPeer struct {
+	peer.SyncPeer
+	syncs int32
+}
+
+func (p *countingPeer) Sync(ctx context.Context, target string,
+	req *peer.SyncRequest, resp *peer.SyncResponse) error {
+	atomic.AddInt32(&p.syncs, 1)
+	return nil
+}
+
+// timedSync returns how long a sync to target took and its error
+func timedSync(tr peer.SyncPeer, target string) (time.Duration, error) {
+	start := time.Now()
+	err := tr.Sync(context.Background(), target, &peer.SyncRequest{}, &peer.SyncResponse{})
+	return time.Since(start), err
+}
+
+func TestSimNetworkLinks(t *testing.T) {
+	sim := peer.NewSimNetwork(peer.Scenario{Seed: 1, Links: []peer.LinkRule{
+		{To: "b", LinkConditions: peer.LinkConditions{Latency: 40 * time.Millisecond}},
+	}})
+	sim.SetLink("b", "a", peer.LinkConditions{Latency: 20 * time.Millisecond, Jitter: 10 * time.Millisecond})
+	inner := &countingPeer{}
+	a := sim.Wrap("a", inner)
+
+	// the request over a to b and the response over b to a
+	if took, err := timedSync(a, "b"); err != nil || took < 50*time.Millisecond {
+		t.Fatalf("expected a sync of at least 50ms, took %v (%v)", took, err)
+	}
+	if took, err := timedSync(a, "c"); err != nil || took > 30*time.Millisecond {
+		t.Fatalf("expected a sync without latency, took %v (%v)", took, err)
+	}
+
+	sim.SetLink("a", "", peer.LinkConditions{Loss: 1})
+	if _, err := timedSync(a, "b"); err != peer.ErrSimDropped {
+		t.Fatalf("expected %v, got %v", peer.ErrSimDropped, err)
+	}
+	if syncs := atomic.LoadInt32(&inner.syncs); syncs != 2 {
+		t.Fatalf("expected the lost sync not to reach the peer, got %d syncs", syncs)
+	}
+	if stats := sim.Stats(); stats.Dropped != 1 || stats.Delivered != 4 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSimNetworkPartitions(t *testing.T) {
+	sim := peer.NewSimNetwork(peer.Scenario{})
+	a := sim.Wrap("a", &countingPeer{})
+
+	sim.Partition([]string{"a", "b"}, []string{"c"})
+	if _, err := timedSync(a, "b"); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"c", "d"} {
+		if _, err := timedSync(a, target); err != peer.ErrSimPartitioned {
+			t.Fatalf("expected %s cut off, got %v", target, err)
+		}
+	}
+	sim.Heal()
+	if _, err := timedSync(a, "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a scheduled partition heals by itself
+	sim = peer.NewSimNetwork(peer.Scenario{Partitions: []peer.PartitionSchedule{
+		{At: 10 * time.Millisecond, Duration: 100 * time.Millisecond, Groups: [][]string{{"a"}, {"b"}}},
+	}})
+	a = sim.Wrap("a", &countingPeer{})
+	stop := sim.Start()
+	defer stop()
+	if _, err := timedSync(a, "b"); err != nil {
+		t.Fatalf("expected no partition before its time, got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := timedSync(a, "b"); err != peer.ErrSimPartitioned {
+		t.Fatalf("expected the scheduled partition, got %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := timedSync(a, "b"); err != nil {
+		t.Fatalf("expected the partition healed, got %v", err)
+	}
+}
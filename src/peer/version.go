@@ -0,0 +1,267 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/version"
+)
+
+// MethodHello is the RPC method negotiating the protocol of a connection
+const MethodHello = "DAG1.Hello"
+
+// Protocol versions. Version 1 is the protocol of the nodes from before the
+// Hello handshake, which are assumed to support no optional feature.
+const (
+	ProtocolVersion    uint32 = 2
+	MinProtocolVersion uint32 = 1
+
+	legacyProtocolVersion uint32 = 1
+)
+
+// Features is a bit-set of the optional parts of the protocol
+type Features uint64
+
+// Optional parts of the protocol
+const (
+	// FeatureCheckpoints is the checkpoint signatures in syncs
+	FeatureCheckpoints Features = 1 << iota
+	// FeaturePeerLookup is the PeerLookup method
+	FeaturePeerLookup
+	// FeatureSnapshot is the Snapshot method
+	FeatureSnapshot
+	// FeatureAddresses is the address announcements in syncs
+	FeatureAddresses
+	// FeatureFramePages is the FrameEvents method, the events of the frame
+	// of a fast forward fetched in pages
+	FeatureFramePages
+	// FeatureCompactEvents is the compact encoding of the events of syncs
+	// and frame pages, see poset.MarshalWireEvents
+	FeatureCompactEvents
+
+	// SupportedFeatures are the features of this node
+	SupportedFeatures = FeatureCheckpoints | FeaturePeerLookup | FeatureSnapshot |
+		FeatureAddresses | FeatureFramePages | FeatureCompactEvents
+)
+
+// DefaultMaxMessageSize is the size of the largest message a node takes by
+// default
+const DefaultMaxMessageSize int64 = 16 << 20
+
+// Protocol is the range of versions and the features a node speaks, the
+// highest event body version it reads, and the network it belongs to. A
+// peer of another network is refused, and so is a peer without a network ID
+// unless AcceptLegacyNetwork is set. The zero NetworkID checks nothing.
+// NodeVersion is the release the node tells its peers, for their operators.
+// MaxMessageSize is the size of the largest message the node takes, 0 for
+// no bound.
+type Protocol struct {
+	Version        uint32
+	MinVersion     uint32
+	Features       Features
+	EventVersion   uint32
+	NodeVersion    string
+	MaxMessageSize int64
+
+	NetworkID           common.Hash
+	AcceptLegacyNetwork bool
+}
+
+// DefaultProtocol returns the protocol of this node, accepting every
+// version down to MinProtocolVersion
+func DefaultProtocol() Protocol {
+	return Protocol{
+		Version:        ProtocolVersion,
+		MinVersion:     MinProtocolVersion,
+		Features:       SupportedFeatures,
+		EventVersion:   poset.SupportedEventVersions.Max,
+		NodeVersion:    version.Version,
+		MaxMessageSize: DefaultMaxMessageSize,
+	}
+}
+
+// orDefault returns the default protocol for a zero one
+func (p Protocol) orDefault() Protocol {
+	if p.Version == 0 {
+		return DefaultProtocol()
+	}
+	return p
+}
+
+// Capabilities are what two nodes negotiated for a connection, and the
+// highest event body version the peer reads, the legacy one for a peer
+// which does not tell. MaxMessageSize is the least of the max message sizes
+// of the nodes, 0 when neither tells one.
+type Capabilities struct {
+	Version        uint32
+	Features       Features
+	EventVersion   uint32
+	MaxMessageSize int64
+}
+
+// legacyCapabilities are those of a peer which did not say hello
+var legacyCapabilities = Capabilities{Version: legacyProtocolVersion}
+
+// Has returns true when the feature was negotiated
+func (c Capabilities) Has(f Features) bool {
+	return c.Features&f == f
+}
+
+// HelloRequest advertises the protocol, the event version, the network, the
+// release and the max message size of the connecting node
+type HelloRequest struct {
+	Version        uint32
+	MinVersion     uint32
+	Features       Features
+	NetworkID      common.Hash
+	EventVersion   uint32
+	NodeVersion    string
+	MaxMessageSize int64
+}
+
+// HelloResponse carries the version selected by the responder, the highest
+// both nodes support, the features both have, its event version, its
+// network, its release and the max message size of the connection
+type HelloResponse struct {
+	Version        uint32
+	Features       Features
+	NetworkID      common.Hash
+	EventVersion   uint32
+	NodeVersion    string
+	MaxMessageSize int64
+}
+
+// VersionError refuses a peer whose protocol version is below the minimum
+type VersionError struct {
+	Ours   uint32
+	Theirs uint32
+	Min    uint32
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("peer protocol version %d is below the minimum %d (ours is %d)",
+		e.Theirs, e.Min, e.Ours)
+}
+
+// NetworkError refuses a peer of another network, or without a network ID
+type NetworkError struct {
+	Ours   common.Hash
+	Theirs common.Hash
+}
+
+func (e *NetworkError) Error() string {
+	if e.Theirs == (common.Hash{}) {
+		return fmt.Sprintf("peer has no network ID, ours is %s", e.Ours.Hex())
+	}
+	return fmt.Sprintf("peer belongs to the network %s, ours is %s", e.Theirs.Hex(), e.Ours.Hex())
+}
+
+// checkNetwork refuses the network ID of a peer when it is not ours
+func (p Protocol) checkNetwork(theirs common.Hash) error {
+	if p.NetworkID == (common.Hash{}) || theirs == p.NetworkID ||
+		theirs == (common.Hash{}) && p.AcceptLegacyNetwork {
+		return nil
+	}
+	return &NetworkError{Ours: p.NetworkID, Theirs: theirs}
+}
+
+// negotiate selects the capabilities of a connection from the protocol of
+// the responder and the hello of the connecting node
+func (p Protocol) negotiate(req *HelloRequest) (Capabilities, error) {
+	if err := p.checkNetwork(req.NetworkID); err != nil {
+		return Capabilities{}, err
+	}
+	version := p.Version
+	if req.Version < version {
+		version = req.Version
+	}
+	if version < p.MinVersion {
+		return Capabilities{}, &VersionError{Ours: p.Version, Theirs: req.Version, Min: p.MinVersion}
+	}
+	if version < req.MinVersion {
+		return Capabilities{}, &VersionError{Ours: req.Version, Theirs: p.Version, Min: req.MinVersion}
+	}
+	return Capabilities{Version: version, Features: p.Features & req.Features,
+		EventVersion:   req.EventVersion,
+		MaxMessageSize: leastMessageSize(p.MaxMessageSize, req.MaxMessageSize)}, nil
+}
+
+// accept checks the capabilities a responder selected
+func (p Protocol) accept(resp *HelloResponse) (Capabilities, error) {
+	if resp.Version == 0 {
+		// a responder which does not know the handshake
+		return p.acceptLegacy()
+	}
+	if err := p.checkNetwork(resp.NetworkID); err != nil {
+		return Capabilities{}, err
+	}
+	if resp.Version > p.Version {
+		return Capabilities{}, fmt.Errorf("peer selected the protocol version %d, ours is %d",
+			resp.Version, p.Version)
+	}
+	if resp.Version < p.MinVersion {
+		return Capabilities{}, &VersionError{Ours: p.Version, Theirs: resp.Version, Min: p.MinVersion}
+	}
+	return Capabilities{Version: resp.Version, Features: p.Features & resp.Features,
+		EventVersion:   resp.EventVersion,
+		MaxMessageSize: leastMessageSize(p.MaxMessageSize, resp.MaxMessageSize)}, nil
+}
+
+// leastMessageSize returns the least of two max message sizes, 0 standing
+// for no bound
+func leastMessageSize(a, b int64) int64 {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// acceptLegacy checks a peer from before the handshake, which has no
+// network ID
+func (p Protocol) acceptLegacy() (Capabilities, error) {
+	if err := p.checkNetwork(common.Hash{}); err != nil {
+		return Capabilities{}, err
+	}
+	if legacyProtocolVersion < p.MinVersion {
+		return Capabilities{}, &VersionError{Ours: p.Version, Theirs: legacyProtocolVersion, Min: p.MinVersion}
+	}
+	return legacyCapabilities, nil
+}
+
+// pageOverhead is the room kept in a message for what goes along the
+// events of a page
+const pageOverhead = 4 << 10
+
+// pageMaxBytes returns the byte budget of the events of a page, the least of
+// the one asked for and the one fitting in the max message size, 0 for no
+// bound
+func pageMaxBytes(requested, maxMessageSize int64) int64 {
+	if maxMessageSize <= 0 {
+		return requested
+	}
+	fits := maxMessageSize - pageOverhead
+	if fits < 1 {
+		fits = 1
+	}
+	return leastMessageSize(requested, fits)
+}
+
+// isUnknownMethod returns true for the error of a server without a method
+func isUnknownMethod(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "can't find method")
+}
+
+// negotiator is implemented by the sync clients negotiating the protocol of
+// their connection
+type negotiator interface {
+	Negotiate(ctx context.Context, p Protocol) (Capabilities, error)
+}
+
+// versionTeller is implemented by the sync clients keeping the release their
+// server told
+type versionTeller interface {
+	PeerVersion() string
+}
@@ -0,0 +1,376 @@
+package peer_test
+
+import (
+	"context"
+	"net"
+	"net/rpc"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/peer"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+var checkpoints = []poset.BlockSignature{{Validator: []byte("v"), Index: 1, Signature: "s"}}
+
+// newVersionBackend starts a server with protocol, replying to the syncs
+// with checkpoints. The checkpoints of the requests it receives are sent to
+// received.
+func newVersionBackend(t *testing.T, protocol peer.Protocol) (string, chan int, func()) {
+	conf := peer.NewBackendConfig()
+	conf.Protocol = protocol
+	backend := peer.NewBackend(conf, logger, net.Listen)
+	received := make(chan int, 10)
+	done := make(chan struct{})
+	go func() {
+		receiver := backend.ReceiverChannel()
+		for {
+			select {
+			case <-done:
+				return
+			case req := <-receiver:
+				var resp interface{}
+				switch r := req.Command.(type) {
+				case *peer.SyncRequest:
+					received <- len(r.Checkpoints)
+					resp = &peer.SyncResponse{FromID: 1, Checkpoints: checkpoints}
+				case *peer.PeerLookupRequest:
+					resp = &peer.PeerLookupResponse{}
+				}
+				req.RespChan <- &peer.RPCResponse{Response: resp}
+			}
+		}
+	}()
+
+	address := newAddress()
+	if err := backend.ListenAndServe(peer.TCP, address); err != nil {
+		t.Fatal(err)
+	}
+	return address, received, func() {
+		close(done)
+		backend.Close()
+	}
+}
+
+// legacyDAG1 is a server from before the Hello handshake
+type legacyDAG1 struct{}
+
+func (legacyDAG1) Sync(req *peer.SyncRequest, resp *peer.SyncResponse) error {
+	*resp = peer.SyncResponse{FromID: 1}
+	return nil
+}
+
+func newLegacyServer(t *testing.T) (string, func()) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("DAG1", legacyDAG1{}); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen(peer.TCP, newAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Accept(l)
+	return l.Addr().String(), func() { l.Close() }
+}
+
+func newVersionClient(t *testing.T, address string) *peer.Client {
+	rpcCli, err := peer.NewRPCClient(peer.TCP, address, time.Second, net.DialTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := peer.NewClient(rpcCli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cli
+}
+
+// syncCheckpoints syncs with checkpoints and returns how many the server
+// received and how many came back
+func syncCheckpoints(t *testing.T, cli *peer.Client, received chan int) (int, int) {
+	resp := &peer.SyncResponse{}
+	err := cli.Sync(context.Background(), &peer.SyncRequest{Checkpoints: checkpoints}, resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FromID != 1 {
+		t.Fatalf("expected the response of the server, got %+v", resp)
+	}
+	return <-received, len(resp.Checkpoints)
+}
+
+func TestHelloNegotiation(t *testing.T) {
+	address, received, stop := newVersionBackend(t, peer.DefaultProtocol())
+	defer stop()
+	cli := newVersionClient(t, address)
+	defer cli.Close()
+
+	if _, ok := cli.Capabilities(); ok {
+		t.Fatal("expected no capabilities before the negotiation")
+	}
+	caps, err := cli.Negotiate(context.Background(), peer.DefaultProtocol())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := peer.Capabilities{Version: peer.ProtocolVersion, Features: peer.SupportedFeatures,
+		EventVersion: peer.DefaultProtocol().EventVersion, MaxMessageSize: peer.DefaultMaxMessageSize}
+	if caps != expected {
+		t.Fatalf("expected %+v, got %+v", expected, caps)
+	}
+	if kept, _ := cli.Capabilities(); kept != expected {
+		t.Fatalf("expected the client to keep %+v, got %+v", expected, kept)
+	}
+
+	if sent, got := syncCheckpoints(t, cli, received); sent != 1 || got != 1 {
+		t.Fatalf("expected the checkpoints both ways, sent %d, got %d", sent, got)
+	}
+}
+
+func TestHelloMaxMessageSize(t *testing.T) {
+	protocol := peer.DefaultProtocol()
+	protocol.MaxMessageSize = 1 << 20
+	address, _, stop := newVersionBackend(t, protocol)
+	defer stop()
+
+	// the least of the sizes of the nodes bounds the connection
+	cli := newVersionClient(t, address)
+	defer cli.Close()
+	caps, err := cli.Negotiate(context.Background(), peer.DefaultProtocol())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.MaxMessageSize != protocol.MaxMessageSize {
+		t.Fatalf("expected the max message size %d, got %d", protocol.MaxMessageSize, caps.MaxMessageSize)
+	}
+
+	// a node without a bound takes that of the other
+	unbounded := newVersionClient(t, address)
+	defer unbounded.Close()
+	ours := peer.DefaultProtocol()
+	ours.MaxMessageSize = 0
+	if caps, err = unbounded.Negotiate(context.Background(), ours); err != nil {
+		t.Fatal(err)
+	}
+	if caps.MaxMessageSize != protocol.MaxMessageSize {
+		t.Fatalf("expected the max message size %d, got %d", protocol.MaxMessageSize, caps.MaxMessageSize)
+	}
+}
+
+func TestHelloDowngradedClient(t *testing.T) {
+	address, received, stop := newVersionBackend(t, peer.DefaultProtocol())
+	defer stop()
+	cli := newVersionClient(t, address)
+	defer cli.Close()
+
+	caps, err := cli.Negotiate(context.Background(), peer.Protocol{Version: 1, MinVersion: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps != (peer.Capabilities{Version: 1, EventVersion: peer.DefaultProtocol().EventVersion,
+		MaxMessageSize: peer.DefaultMaxMessageSize}) {
+		t.Fatalf("expected version 1 without features, got %+v", caps)
+	}
+
+	if sent, got := syncCheckpoints(t, cli, received); sent != 0 || got != 0 {
+		t.Fatalf("expected no checkpoints, sent %d, got %d", sent, got)
+	}
+	err = cli.PeerLookup(context.Background(), &peer.PeerLookupRequest{}, &peer.PeerLookupResponse{})
+	if err != peer.ErrUnsupported {
+		t.Fatalf("expected %v, got %v", peer.ErrUnsupported, err)
+	}
+}
+
+func TestHelloDowngradedServer(t *testing.T) {
+	address, received, stop := newVersionBackend(t, peer.Protocol{Version: 1, MinVersion: 1})
+	defer stop()
+
+	cli := newVersionClient(t, address)
+	defer cli.Close()
+	caps, err := cli.Negotiate(context.Background(), peer.DefaultProtocol())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps != (peer.Capabilities{Version: 1, MaxMessageSize: peer.DefaultMaxMessageSize}) {
+		t.Fatalf("expected version 1 without features, got %+v", caps)
+	}
+	if sent, got := syncCheckpoints(t, cli, received); sent != 0 || got != 0 {
+		t.Fatalf("expected no checkpoints, sent %d, got %d", sent, got)
+	}
+
+	strict := newVersionClient(t, address)
+	defer strict.Close()
+	protocol := peer.DefaultProtocol()
+	protocol.MinVersion = 2
+	_, err = strict.Negotiate(context.Background(), protocol)
+	if err == nil || !strings.Contains(err.Error(), "version 1 is below the minimum 2") {
+		t.Fatalf("expected the server to be refused, got %v", err)
+	}
+}
+
+func TestHelloLegacyServer(t *testing.T) {
+	address, stop := newLegacyServer(t)
+	defer stop()
+
+	cli := newVersionClient(t, address)
+	defer cli.Close()
+	caps, err := cli.Negotiate(context.Background(), peer.DefaultProtocol())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps != (peer.Capabilities{Version: 1}) {
+		t.Fatalf("expected version 1 without features, got %+v", caps)
+	}
+	resp := &peer.SyncResponse{}
+	if err := cli.Sync(context.Background(), &peer.SyncRequest{Checkpoints: checkpoints}, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	strict := newVersionClient(t, address)
+	defer strict.Close()
+	protocol := peer.DefaultProtocol()
+	protocol.MinVersion = 2
+	_, err = strict.Negotiate(context.Background(), protocol)
+	verr, ok := err.(*peer.VersionError)
+	if !ok || verr.Theirs != 1 || verr.Min != 2 {
+		t.Fatalf("expected a version error, got %v", err)
+	}
+}
+
+func TestHelloRefusesOldClients(t *testing.T) {
+	protocol := peer.DefaultProtocol()
+	protocol.MinVersion = 2
+	address, _, stop := newVersionBackend(t, protocol)
+	defer stop()
+
+	old := newVersionClient(t, address)
+	defer old.Close()
+	_, err := old.Negotiate(context.Background(), peer.Protocol{Version: 1, MinVersion: 1})
+	if err == nil || !strings.Contains(err.Error(), "version 1 is below the minimum 2") {
+		t.Fatalf("expected the client to be refused, got %v", err)
+	}
+
+	// a client from before the handshake syncs without saying hello
+	legacy := newVersionClient(t, address)
+	defer legacy.Close()
+	err = legacy.Sync(context.Background(), &peer.SyncRequest{}, &peer.SyncResponse{})
+	if err == nil || !strings.Contains(err.Error(), "version 1 is below the minimum 2") {
+		t.Fatalf("expected the client to be refused, got %v", err)
+	}
+}
+
+func TestTransportNegotiates(t *testing.T) {
+	address, stop := newLegacyServer(t)
+	defer stop()
+
+	producer := peer.NewProducer(2, time.Second, func(target string,
+		timeout time.Duration) (peer.SyncClient, error) {
+		return newVersionClient(t, target), nil
+	})
+	transport := peer.NewTransport(logger, producer, nil)
+	defer transport.Close()
+
+	resp := &peer.SyncResponse{}
+	if err := transport.Sync(context.Background(), address, &peer.SyncRequest{}, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	protocol := peer.DefaultProtocol()
+	protocol.MinVersion = 2
+	transport.SetProtocol(protocol)
+	err := transport.Sync(context.Background(), address, &peer.SyncRequest{}, resp)
+	if _, ok := err.(*peer.VersionError); ok {
+		t.Fatalf("expected the pooled connection to keep its capabilities, got %v", err)
+	}
+
+	strict := peer.NewTransport(logger, peer.NewProducer(2, time.Second, func(target string,
+		timeout time.Duration) (peer.SyncClient, error) {
+		return newVersionClient(t, target), nil
+	}), nil)
+	defer strict.Close()
+	strict.SetProtocol(protocol)
+	err = strict.Sync(context.Background(), address, &peer.SyncRequest{}, resp)
+	if _, ok := err.(*peer.VersionError); !ok {
+		t.Fatalf("expected a version error, got %v", err)
+	}
+}
+
+func TestHelloRefusesOtherNetworks(t *testing.T) {
+	main, test := peer.DefaultProtocol(), peer.DefaultProtocol()
+	main.NetworkID = common.BytesToHash([]byte("main"))
+	test.NetworkID = common.BytesToHash([]byte("test"))
+
+	// each network refuses the hello of the other one
+	for _, c := range []struct{ server, client peer.Protocol }{{main, test}, {test, main}} {
+		address, _, stop := newVersionBackend(t, c.server)
+		cli := newVersionClient(t, address)
+		_, err := cli.Negotiate(context.Background(), c.client)
+		if err == nil || !strings.Contains(err.Error(), "belongs to the network") {
+			t.Fatalf("expected the client to be refused, got %v", err)
+		}
+		cli.Close()
+		stop()
+	}
+
+	// a peer without a network ID passes only in compatibility mode
+	address, _, stop := newVersionBackend(t, main)
+	defer stop()
+	legacy := newVersionClient(t, address)
+	defer legacy.Close()
+	_, err := legacy.Negotiate(context.Background(), peer.DefaultProtocol())
+	if err == nil || !strings.Contains(err.Error(), "no network ID") {
+		t.Fatalf("expected a client without network ID to be refused, got %v", err)
+	}
+
+	compat := main
+	compat.AcceptLegacyNetwork = true
+	address, received, stopCompat := newVersionBackend(t, compat)
+	defer stopCompat()
+	cli := newVersionClient(t, address)
+	defer cli.Close()
+	if _, err := cli.Negotiate(context.Background(), peer.DefaultProtocol()); err != nil {
+		t.Fatalf("expected a client without network ID accepted in compatibility mode, got %v", err)
+	}
+	syncCheckpoints(t, cli, received)
+
+	// the client checks the network of the server too
+	server, _, stopServer := newVersionBackend(t, peer.DefaultProtocol())
+	defer stopServer()
+	strict := newVersionClient(t, server)
+	defer strict.Close()
+	if _, err := strict.Negotiate(context.Background(), main); err == nil {
+		t.Fatal("expected the client to refuse a server without network ID")
+	}
+}
+
+func TestHelloNodeVersion(t *testing.T) {
+	protocol := peer.DefaultProtocol()
+	protocol.NodeVersion = "0.9.1-0123abcd"
+	address, _, stop := newVersionBackend(t, protocol)
+	defer stop()
+	cli := newVersionClient(t, address)
+	defer cli.Close()
+
+	if v := cli.PeerVersion(); v != "" {
+		t.Fatalf("expected no release before the negotiation, got %q", v)
+	}
+	if _, err := cli.Negotiate(context.Background(), peer.DefaultProtocol()); err != nil {
+		t.Fatal(err)
+	}
+	if v := cli.PeerVersion(); v != protocol.NodeVersion {
+		t.Fatalf("expected the release %q, got %q", protocol.NodeVersion, v)
+	}
+
+	// a server from before the handshake tells none
+	legacy, stopLegacy := newLegacyServer(t)
+	defer stopLegacy()
+	cli = newVersionClient(t, legacy)
+	defer cli.Close()
+	if _, err := cli.Negotiate(context.Background(), peer.DefaultProtocol()); err != nil {
+		t.Fatal(err)
+	}
+	if v := cli.PeerVersion(); v != "" {
+		t.Fatalf("expected no release from a legacy server, got %q", v)
+	}
+}
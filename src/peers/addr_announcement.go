@@ -0,0 +1,69 @@
+package peers
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+// AddrAnnouncement is the network address a participant says it listens
+// on, signed with its key. The network address is no consensus data, so a
+// participant moving to another address announces it to its peers instead
+// of having every operator edit peers.json. Of two announcements of a
+// participant, the one with the higher nonce is the newer.
+type AddrAnnouncement struct {
+	PubKeyHex string `json:"pub_key_hex"`
+	NetAddr   string `json:"net_addr"`
+	Nonce     int64  `json:"nonce"`
+	Signature string `json:"signature"`
+}
+
+// NewAddrAnnouncement signs the announcement of the network address of the
+// owner of the key
+func NewAddrAnnouncement(key *ecdsa.PrivateKey, netAddr string, nonce int64) (AddrAnnouncement, error) {
+	a := AddrAnnouncement{
+		PubKeyHex: fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)),
+		NetAddr:   netAddr,
+		Nonce:     nonce,
+	}
+	r, s, err := crypto.Sign(key, a.Hash())
+	if err != nil {
+		return AddrAnnouncement{}, err
+	}
+	a.Signature = crypto.EncodeSignature(r, s)
+	return a, nil
+}
+
+// Hash returns what the participant signs: the Keccak256 hash of the big
+// endian nonce followed by the network address
+func (a *AddrAnnouncement) Hash() []byte {
+	nonce := make([]byte, 8)
+	binary.BigEndian.PutUint64(nonce, uint64(a.Nonce))
+	return crypto.Keccak256(nonce, []byte(a.NetAddr))
+}
+
+// Verify checks the announcement is signed by the key of PubKeyHex
+func (a *AddrAnnouncement) Verify() error {
+	pm := PeerMessage{PubKeyHex: a.PubKeyHex}
+	if len(a.PubKeyHex) < 2 {
+		return fmt.Errorf("invalid public key %q", a.PubKeyHex)
+	}
+	pubBytes, err := pm.PubKeyBytes()
+	if err != nil {
+		return err
+	}
+	pub := crypto.ToECDSAPub(pubBytes)
+	if pub == nil || pub.X == nil {
+		return fmt.Errorf("invalid public key %s", a.PubKeyHex)
+	}
+	r, s, err := crypto.DecodeSignature(a.Signature)
+	if err != nil {
+		return err
+	}
+	if r == nil || s == nil || !crypto.Verify(pub, a.Hash(), r, s) {
+		return fmt.Errorf("bad address signature of %s", a.PubKeyHex)
+	}
+	return nil
+}
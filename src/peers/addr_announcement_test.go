@@ -0,0 +1,105 @@
+package peers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	scrypto "github.com/SamuelMarks/dag1/src/crypto"
+)
+
+func TestAddrAnnouncement(t *testing.T) {
+	key, _ := scrypto.GenerateECDSAKey()
+	other, _ := scrypto.GenerateECDSAKey()
+
+	a, err := NewAddrAnnouncement(key, "10.0.0.1:1337", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := fmt.Sprintf("0x%X", scrypto.FromECDSAPub(&key.PublicKey)); a.PubKeyHex != expected {
+		t.Fatalf("expected the announcement of %s, got %s", expected, a.PubKeyHex)
+	}
+	if err := a.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the address and the nonce are signed, and so is the participant
+	forged, err := NewAddrAnnouncement(other, "10.0.0.2:1337", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, change := range map[string]func(a *AddrAnnouncement){
+		"address":    func(a *AddrAnnouncement) { a.NetAddr = "10.0.0.2:1337" },
+		"nonce":      func(a *AddrAnnouncement) { a.Nonce++ },
+		"signer":     func(a *AddrAnnouncement) { a.Signature = forged.Signature },
+		"public key": func(a *AddrAnnouncement) { a.PubKeyHex = "0x1234" },
+		"signature":  func(a *AddrAnnouncement) { a.Signature = "" },
+	} {
+		b := a
+		change(&b)
+		if err := b.Verify(); err == nil {
+			t.Fatalf("expected a changed %s to fail to verify", name)
+		}
+	}
+}
+
+func TestSetNetAddr(t *testing.T) {
+	key, _ := scrypto.GenerateECDSAKey()
+	pubKey := fmt.Sprintf("0x%X", scrypto.FromECDSAPub(&key.PublicKey))
+	ps := NewPeers()
+	ps.AddPeer(NewPeer(pubKey, "10.0.0.1:1337"))
+	// copies of the peer see the move
+	before, _ := ps.ReadByPubKey(pubKey)
+
+	old, ok := ps.SetNetAddr(pubKey, "10.0.0.2:1337")
+	if !ok || old != "10.0.0.1:1337" {
+		t.Fatalf("expected the peer to move from 10.0.0.1:1337, got %q, %v", old, ok)
+	}
+	if before.Message.NetAddr != "10.0.0.2:1337" {
+		t.Fatalf("expected the peer at 10.0.0.2:1337, got %s", before.Message.NetAddr)
+	}
+	if _, ok := ps.ReadByNetAddr("10.0.0.1:1337"); ok {
+		t.Fatal("expected no peer at the former address")
+	}
+	if p, ok := ps.ReadByNetAddr("10.0.0.2:1337"); !ok || p.Message.PubKeyHex != pubKey {
+		t.Fatal("expected the peer at its new address")
+	}
+	if _, ok := ps.SetNetAddr("0x1234", "10.0.0.3:1337"); ok {
+		t.Fatal("expected an unknown peer not to move")
+	}
+}
+
+func TestJSONPeersSetPeerMessages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dag1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ps := NewPeers()
+	for i := 0; i < 3; i++ {
+		key, _ := scrypto.GenerateECDSAKey()
+		ps.AddPeer(NewPeer(fmt.Sprintf("0x%X", scrypto.FromECDSAPub(&key.PublicKey)),
+			fmt.Sprintf("10.0.0.%d:1337", i)))
+	}
+	store := NewJSONPeers(dir)
+	if err := store.SetPeerMessages(ps.ToPeerSlice()); err != nil {
+		t.Fatal(err)
+	}
+
+	// it is the format peers.json is read in
+	loaded, err := store.GetPeersFromMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != ps.Len() {
+		t.Fatalf("expected %d peers, got %d", ps.Len(), loaded.Len())
+	}
+	for _, p := range ps.ToPeerSlice() {
+		l, ok := loaded.ReadByID(p.ID)
+		if !ok || !l.Message.Equals(p.Message) {
+			t.Fatalf("expected peer %s at %s", p.Message.PubKeyHex, p.Message.NetAddr)
+		}
+	}
+}
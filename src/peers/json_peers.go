@@ -114,3 +114,29 @@ func (j *JSONPeers) SetPeers(peers []*Peer) error {
 	// Write out as JSON
 	return ioutil.WriteFile(j.path, buf.Bytes(), 0755)
 }
+
+// SetPeerMessages writes the peers in the format GetPeersFromMessages reads,
+// their public keys and network addresses.
+func (j *JSONPeers) SetPeerMessages(peers []*Peer) error {
+	j.l.Lock()
+	defer j.l.Unlock()
+
+	messages := make([]*PeerMessage, len(peers))
+	for i, peer := range peers {
+		messages[i] = &PeerMessage{
+			NetAddr:   peer.Message.NetAddr,
+			PubKeyHex: peer.Message.PubKeyHex,
+		}
+	}
+	buf, err := json.MarshalIndent(messages, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	// replace the file at once, operators may be reading it
+	tmp := j.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(buf, '\n'), 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
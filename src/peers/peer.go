@@ -2,9 +2,13 @@ package peers
 
 import (
 	"encoding/hex"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto/verify"
 )
 
 const (
@@ -29,14 +33,28 @@ func (pm *PeerMessage) PubKeyBytes() ([]byte, error) {
 }
 
 // Address returns the address for a peerMessage
-// TODO: hash of publickey
 func (pm *PeerMessage) Address() (a common.Address) {
 	bytes, err := pm.PubKeyBytes()
 	if err != nil {
 		panic(err)
 	}
-	copy(a[:], bytes)
-	return
+	return verify.Address(bytes)
+}
+
+// ParseAddress parses an address in hex, or derives it from a public key in
+// hex the way the address of a peer is
+func ParseAddress(s string) (common.Address, error) {
+	if common.IsHexAddress(s) {
+		return common.HexToAddress(s), nil
+	}
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		s = "0x" + s
+	}
+	pm := PeerMessage{PubKeyHex: s}
+	if bytes, err := pm.PubKeyBytes(); err != nil || len(bytes) < common.AddressLength {
+		return common.Address{}, fmt.Errorf("%q is neither an address nor a public key", s)
+	}
+	return pm.Address(), nil
 }
 
 /* Peer type */
@@ -49,6 +67,11 @@ type Peer struct {
 	height    int64
 	inDegree  int64
 	weight    uint64
+
+	lastSyncOK   bool
+	lastSyncTime time.Time
+	// version is the release the peer told in its hello
+	version string
 }
 
 // NewPeer creates a new peer based on public key and network address
@@ -157,6 +180,39 @@ func (p *Peer) SetWeight(w uint64) {
 	p.weight = w
 }
 
+// SetLastSync records the outcome of the last sync with the peer
+func (p *Peer) SetLastSync(ok bool, at time.Time) {
+	p.Lock()
+	defer p.Unlock()
+	p.lastSyncOK = ok
+	p.lastSyncTime = at
+}
+
+// GetLastSync returns the outcome and time of the last sync with the peer,
+// the time is zero when there was none
+func (p *Peer) GetLastSync() (bool, time.Time) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.lastSyncOK, p.lastSyncTime
+}
+
+// SetVersion records the release the peer told, and returns the one it told
+// before
+func (p *Peer) SetVersion(version string) string {
+	p.Lock()
+	defer p.Unlock()
+	old := p.version
+	p.version = version
+	return old
+}
+
+// GetVersion returns the release the peer told, empty until it did
+func (p *Peer) GetVersion() string {
+	p.RLock()
+	defer p.RUnlock()
+	return p.version
+}
+
 // PeerStore provides an interface for persistent storage and
 // retrieval of peers.
 type PeerStore interface {
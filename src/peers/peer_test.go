@@ -88,3 +88,55 @@ func TestJSONPeers(t *testing.T) {
 		}
 	}
 }
+
+func TestParseAddress(t *testing.T) {
+	key, _ := scrypto.GenerateECDSAKey()
+	peer := NewPeer(fmt.Sprintf("0x%X", scrypto.FromECDSAPub(&key.PublicKey)), "")
+	expected := peer.Address()
+
+	for _, s := range []string{
+		peer.Message.PubKeyHex,
+		peer.Message.PubKeyHex[2:],
+		expected.Hex(),
+		expected.Hex()[2:],
+	} {
+		a, err := ParseAddress(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if a != expected {
+			t.Fatalf("%s: expected %s, got %s", s, expected.Hex(), a.Hex())
+		}
+	}
+
+	for _, s := range []string{"", "0x", "0x1234", "not hex"} {
+		if _, err := ParseAddress(s); err == nil {
+			t.Fatalf("expected %q to be refused", s)
+		}
+	}
+}
+
+func TestSetVersionByNetAddrWhileReading(t *testing.T) {
+	key, _ := scrypto.GenerateECDSAKey()
+	peer := NewPeer(fmt.Sprintf("0x%X", scrypto.FromECDSAPub(&key.PublicKey)), "127.0.0.1:1337")
+	participants := NewPeers()
+	participants.AddPeer(peer)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			participants.SetVersionByNetAddr(peer.Message.NetAddr, fmt.Sprintf("0.%d.0", i))
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if _, ok := participants.ReadByPubKey(peer.Message.PubKeyHex); !ok {
+			t.Fatalf("peer %s not found", peer.Message.PubKeyHex)
+		}
+	}
+	<-done
+
+	if v := peer.GetVersion(); v != "0.999.0" {
+		t.Fatalf("expected version 0.999.0, got %s", v)
+	}
+}
@@ -1,9 +1,9 @@
 package peers
 
 import (
-	"math"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/SamuelMarks/dag1/src/common"
 )
@@ -34,6 +34,8 @@ type Peers struct {
 	Stake     uint64
 	SuperMajority uint64
 	TrustCount    uint64
+
+	devSingleSuperMajority bool
 }
 
 /* Constructors */
@@ -88,13 +90,12 @@ func (p *Peers) addPeerRaw(peer *Peer) {
 		}
 	}
 	p.Stake = p.Stake + peer.GetWeight()
-	p.SuperMajority = uint64(2 * p.Stake / 3 + 1)
-	p.TrustCount = uint64(math.Ceil(float64(p.Stake) / float64(3)))
 
 	p.ByPubKey[peer.Message.PubKeyHex] = peer
 	p.ByID[peer.ID] = peer
 	p.ByAddress[peer.Address()] = peer
 	p.ByNetAddr[peer.Message.NetAddr] = peer
+	p.updateThresholds()
 }
 
 // AddPeer adds a peer to the peers struct
@@ -125,7 +126,8 @@ func (p *Peers) RemovePeer(peer *Peer) {
 	p.Lock()
 	defer p.Unlock()
 
-	if _, ok := p.ByPubKey[peer.Message.PubKeyHex]; !ok {
+	stored, ok := p.ByPubKey[peer.Message.PubKeyHex]
+	if !ok {
 		return
 	}
 
@@ -133,6 +135,8 @@ func (p *Peers) RemovePeer(peer *Peer) {
 	delete(p.ByID, peer.ID)
 	delete(p.ByAddress, peer.Address())
 	delete(p.ByNetAddr, peer.Message.NetAddr)
+	p.Stake = p.Stake - stored.GetWeight()
+	p.updateThresholds()
 
 	p.internalSort()
 }
@@ -222,6 +226,9 @@ func (p *Peers) ReadByPubKey(key string) (Peer, bool) {
 	p.RLock()
 	defer p.RUnlock()
 	peer, ok := p.ByPubKey[key]
+	if !ok {
+		return Peer{}, false
+	}
 	return *peer, ok
 }
 
@@ -229,6 +236,9 @@ func (p *Peers) ReadByID(key uint64) (Peer, bool) {
 	p.RLock()
 	defer p.RUnlock()
 	peer, ok := p.ByID[key]
+	if !ok {
+		return Peer{}, false
+	}
 	return *peer, ok
 }
 
@@ -236,6 +246,9 @@ func (p *Peers) ReadByAddress(key common.Address) (Peer, bool) {
 	p.RLock()
 	defer p.RUnlock()
 	peer, ok := p.ByAddress[key]
+	if !ok {
+		return Peer{}, false
+	}
 	return *peer, ok
 }
 
@@ -243,9 +256,45 @@ func (p *Peers) ReadByNetAddr(key string) (Peer, bool) {
 	p.RLock()
 	defer p.RUnlock()
 	peer, ok := p.ByNetAddr[key]
+	if !ok {
+		return Peer{}, false
+	}
 	return *peer, ok
 }
 
+// SetNetAddr moves the participant with a public key to another network
+// address and returns its previous one, false when it is unknown
+func (p *Peers) SetNetAddr(pubKey, netAddr string) (string, bool) {
+	p.Lock()
+	defer p.Unlock()
+	peer, ok := p.ByPubKey[pubKey]
+	if !ok {
+		return "", false
+	}
+	peer.Lock()
+	old := peer.Message.NetAddr
+	peer.Message.NetAddr = netAddr
+	peer.Unlock()
+	if p.ByNetAddr[old] == peer {
+		delete(p.ByNetAddr, old)
+	}
+	p.ByNetAddr[netAddr] = peer
+	return old, true
+}
+
+// SetVersionByNetAddr records the release told by the participant at a
+// network address, and returns the one it told before, false when the
+// address is unknown
+func (p *Peers) SetVersionByNetAddr(netAddr, version string) (string, bool) {
+	p.Lock()
+	defer p.Unlock()
+	peer, ok := p.ByNetAddr[netAddr]
+	if !ok {
+		return "", false
+	}
+	return peer.SetVersion(version), true
+}
+
 func (p *Peers) SetHeightByPubKeyHex(key string, height int64) {
 	p.Lock()
 	defer p.Unlock()
@@ -276,6 +325,16 @@ func (p *Peers) IncInDegreeByPubKeyHex(key string) {
 	(p.ByPubKey[key]).IncInDegree()
 }
 
+// SetLastSyncByID records the outcome of the last sync with a peer, unknown
+// peers are ignored
+func (p *Peers) SetLastSyncByID(id uint64, ok bool, at time.Time) {
+	p.Lock()
+	defer p.Unlock()
+	if peer, exists := p.ByID[id]; exists {
+		peer.SetLastSync(ok, at)
+	}
+}
+
 // Set new weight to a peer and recalculate PoS values
 func (p *Peers) SetPeerWeight(peer *Peer, w uint64) {
 	p.Lock()
@@ -284,8 +343,7 @@ func (p *Peers) SetPeerWeight(peer *Peer, w uint64) {
 	p.Stake = p.Stake - oldW
 	peer.SetWeight(w)
 	p.Stake = p.Stake + w
-	p.SuperMajority = uint64(2 * p.Stake / 3 + 1)
-	p.TrustCount = uint64(math.Ceil(float64(p.Stake) / float64(3)))
+	p.updateThresholds()
 }
 
 // Set new weight to a peer by their public Key
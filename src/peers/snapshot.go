@@ -0,0 +1,47 @@
+package peers
+
+import (
+	"time"
+)
+
+// PeerSnapshot is what a node knows about a peer at one point in time
+type PeerSnapshot struct {
+	// ID of the peer, derived from its public key
+	ID uint64 `json:"id"`
+	// NetAddr the peer is reached at
+	NetAddr string `json:"net_addr"`
+	// Height is the index of the last event we have from the peer
+	Height int64 `json:"height"`
+	// InDegree counts the events of other peers on top of its last event
+	InDegree int64 `json:"in_degree"`
+	// Used is how many times the peer selector picked the peer
+	Used int64 `json:"used"`
+	// LastSyncOK tells whether the last sync with the peer succeeded
+	LastSyncOK bool `json:"last_sync_ok"`
+	// LastSyncTime is when we last synced with the peer, zero if never
+	LastSyncTime time.Time `json:"last_sync_time"`
+	// Version is the release the peer told, empty until it did
+	Version string `json:"version,omitempty"`
+}
+
+// Snapshot returns the state of every peer, sorted by ID
+func (p *Peers) Snapshot() []PeerSnapshot {
+	p.RLock()
+	defer p.RUnlock()
+
+	res := make([]PeerSnapshot, 0, len(p.Sorted))
+	for _, peer := range p.Sorted {
+		ok, at := peer.GetLastSync()
+		res = append(res, PeerSnapshot{
+			ID:           peer.ID,
+			NetAddr:      peer.Message.NetAddr,
+			Height:       peer.GetHeight(),
+			InDegree:     peer.GetInDegree(),
+			Used:         peer.Used,
+			LastSyncOK:   ok,
+			LastSyncTime: at,
+			Version:      peer.GetVersion(),
+		})
+	}
+	return res
+}
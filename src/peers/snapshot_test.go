@@ -0,0 +1,104 @@
+package peers
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	scrypto "github.com/SamuelMarks/dag1/src/crypto"
+)
+
+func TestPeersSnapshot(t *testing.T) {
+	participants := NewPeers()
+	for i := 0; i < 3; i++ {
+		key, _ := scrypto.GenerateECDSAKey()
+		participants.AddPeer(NewPeer(
+			fmt.Sprintf("0x%X", scrypto.FromECDSAPub(&key.PublicKey)),
+			fmt.Sprintf("127.0.0.1:%d", 1337+i)))
+	}
+	sorted := participants.ToPeerSlice()
+
+	at := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	participants.SetHeightByPubKeyHex(sorted[0].Message.PubKeyHex, 7)
+	participants.IncInDegreeByPubKeyHex(sorted[1].Message.PubKeyHex)
+	participants.SetLastSyncByID(sorted[1].ID, true, at)
+	participants.SetLastSyncByID(sorted[2].ID, false, at)
+	sorted[2].Used = 4
+
+	snapshot := participants.Snapshot()
+	if len(snapshot) != 3 {
+		t.Fatalf("expected 3 peers, got %d", len(snapshot))
+	}
+	for i, p := range snapshot {
+		if p.ID != sorted[i].ID || p.NetAddr != sorted[i].Message.NetAddr {
+			t.Fatalf("peer %d: expected %d at %s, got %d at %s", i,
+				sorted[i].ID, sorted[i].Message.NetAddr, p.ID, p.NetAddr)
+		}
+	}
+	if snapshot[0].Height != 7 || !snapshot[0].LastSyncTime.IsZero() {
+		t.Fatalf("unexpected first peer %+v", snapshot[0])
+	}
+	if snapshot[1].InDegree != 1 || !snapshot[1].LastSyncOK || !snapshot[1].LastSyncTime.Equal(at) {
+		t.Fatalf("unexpected second peer %+v", snapshot[1])
+	}
+	if snapshot[2].Used != 4 || snapshot[2].LastSyncOK || !snapshot[2].LastSyncTime.Equal(at) {
+		t.Fatalf("unexpected third peer %+v", snapshot[2])
+	}
+}
+
+func TestPeerSnapshotJSON(t *testing.T) {
+	snapshot := PeerSnapshot{
+		ID:           42,
+		NetAddr:      "127.0.0.1:1337",
+		Height:       7,
+		InDegree:     2,
+		Used:         3,
+		LastSyncOK:   true,
+		LastSyncTime: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	expected := `{"id":42,"net_addr":"127.0.0.1:1337","height":7,"in_degree":2,` +
+		`"used":3,"last_sync_ok":true,"last_sync_time":"2019-01-02T03:04:05Z"}`
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Fatalf("expected %s, got %s", expected, data)
+	}
+
+	var decoded PeerSnapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != snapshot {
+		t.Fatalf("expected %+v to survive a round trip, got %+v", snapshot, decoded)
+	}
+}
+
+func TestSetLastSyncByIDWhileReading(t *testing.T) {
+	key, _ := scrypto.GenerateECDSAKey()
+	peer := NewPeer(fmt.Sprintf("0x%X", scrypto.FromECDSAPub(&key.PublicKey)), "127.0.0.1:1337")
+	participants := NewPeers()
+	participants.AddPeer(peer)
+
+	at := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			participants.SetLastSyncByID(peer.ID, i%2 == 0, at.Add(time.Duration(i)))
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		if _, ok := participants.ReadByID(peer.ID); !ok {
+			t.Fatalf("peer %d not found", peer.ID)
+		}
+	}
+	<-done
+
+	if ok, last := peer.GetLastSync(); ok || !last.Equal(at.Add(999)) {
+		t.Fatalf("expected a failed sync at %v, got %v at %v", at.Add(999), ok, last)
+	}
+}
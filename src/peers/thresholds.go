@@ -0,0 +1,66 @@
+package peers
+
+// SuperMajorityOf returns the least weight more than 2/3 of total, which a
+// decision needs. It is total itself for up to 3 participants of unit
+// weight: 2 of 2 and 3 of 3.
+func SuperMajorityOf(total uint64) uint64 {
+	return 2*total/3 + 1
+}
+
+// TrustCountOf returns the greatest weight not more than 1/3 of total. A set
+// of signers is trusted when its weight is above it: 1 of 2, 2 of 3 or 4.
+func TrustCountOf(total uint64) uint64 {
+	return total / 3
+}
+
+// updateThresholds recomputes SuperMajority and TrustCount after the stake
+// or the participants changed. Participants count one each unless stakes
+// were given. The caller holds the lock.
+func (p *Peers) updateThresholds() {
+	total := p.Stake
+	if total == 0 {
+		total = uint64(len(p.ByPubKey))
+	}
+	p.SuperMajority = SuperMajorityOf(total)
+	p.TrustCount = TrustCountOf(total)
+	if p.devSingleSuperMajority {
+		p.SuperMajority, p.TrustCount = 1, 0
+	}
+}
+
+// WeightOf returns the weight the participant of an ID counts for in the
+// thresholds: one unless stakes were given. It is 0 for an unknown ID.
+func (p *Peers) WeightOf(id uint64) uint64 {
+	p.RLock()
+	defer p.RUnlock()
+	peer, ok := p.ByID[id]
+	if !ok {
+		return 0
+	}
+	if p.Stake == 0 {
+		return 1
+	}
+	return peer.GetWeight()
+}
+
+// SetDevSingleSuperMajority makes any single participant a supermajority,
+// and any signature trusted, for local development networks which must make
+// progress with one of their nodes down.
+//
+// It is UNSAFE: every participant decides alone, so a single faulty or
+// partitioned one forks the network. It must never be set on a network
+// with participants others run.
+func (p *Peers) SetDevSingleSuperMajority(enabled bool) {
+	p.Lock()
+	defer p.Unlock()
+	p.devSingleSuperMajority = enabled
+	p.updateThresholds()
+}
+
+// DevSingleSuperMajority returns true when the thresholds were relaxed by
+// SetDevSingleSuperMajority
+func (p *Peers) DevSingleSuperMajority() bool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.devSingleSuperMajority
+}
@@ -0,0 +1,121 @@
+package peers
+
+import (
+	"fmt"
+	"testing"
+
+	scrypto "github.com/SamuelMarks/dag1/src/crypto"
+)
+
+func newTestPeers(n int) *Peers {
+	participants := NewPeers()
+	for i := 0; i < n; i++ {
+		key, _ := scrypto.GenerateECDSAKey()
+		participants.AddPeer(NewPeer(
+			fmt.Sprintf("0x%X", scrypto.FromECDSAPub(&key.PublicKey)),
+			fmt.Sprintf("127.0.0.1:%d", 1337+i)))
+	}
+	return participants
+}
+
+func TestThresholdsSmallN(t *testing.T) {
+	cases := []struct {
+		n                         int
+		superMajority, trustCount uint64
+	}{
+		{1, 1, 0},
+		{2, 2, 0},
+		{3, 3, 1},
+		{4, 3, 1},
+		{5, 4, 1},
+		{6, 5, 2},
+		{7, 5, 2},
+	}
+	for _, c := range cases {
+		// participants count one each without stakes, and the same with
+		// unit stakes
+		unweighted := newTestPeers(c.n)
+		weighted := newTestPeers(c.n)
+		for _, p := range weighted.ToPeerSlice() {
+			weighted.SetPeerWeight(p, 1)
+		}
+		for name, participants := range map[string]*Peers{
+			"unweighted": unweighted, "weighted": weighted} {
+			sm, tc := participants.GetSuperMajority(), participants.GetTrustCount()
+			if sm != c.superMajority || tc != c.trustCount {
+				t.Fatalf("%s N=%d: expected supermajority %d and trust count %d, got %d and %d",
+					name, c.n, c.superMajority, c.trustCount, sm, tc)
+			}
+		}
+
+		// a supermajority is more than 2N/3, and one less is not
+		if 3*c.superMajority <= 2*uint64(c.n) || 3*(c.superMajority-1) > 2*uint64(c.n) {
+			t.Fatalf("N=%d: %d is not the least supermajority", c.n, c.superMajority)
+		}
+		// more than the trust count is more than N/3, and it is not
+		if 3*(c.trustCount+1) <= uint64(c.n) || 3*c.trustCount > uint64(c.n) {
+			t.Fatalf("N=%d: %d is not the greatest untrusted count", c.n, c.trustCount)
+		}
+	}
+}
+
+func TestThresholdsFollowStake(t *testing.T) {
+	participants := newTestPeers(3)
+	sorted := participants.ToPeerSlice()
+	participants.SetPeerWeight(sorted[0], 4)
+	participants.SetPeerWeight(sorted[1], 1)
+	participants.SetPeerWeight(sorted[2], 1)
+	if sm, tc := participants.GetSuperMajority(), participants.GetTrustCount(); sm != 5 || tc != 2 {
+		t.Fatalf("expected supermajority 5 and trust count 2 of stake 6, got %d and %d", sm, tc)
+	}
+
+	participants.RemovePeer(sorted[0])
+	if participants.Stake != 2 {
+		t.Fatalf("expected the stake of the removed peer gone, got %d", participants.Stake)
+	}
+	if sm, tc := participants.GetSuperMajority(), participants.GetTrustCount(); sm != 2 || tc != 0 {
+		t.Fatalf("expected supermajority 2 and trust count 0 of stake 2, got %d and %d", sm, tc)
+	}
+}
+
+func TestDevSingleSuperMajority(t *testing.T) {
+	participants := newTestPeers(2)
+	participants.SetDevSingleSuperMajority(true)
+	if !participants.DevSingleSuperMajority() {
+		t.Fatal("expected the relaxed thresholds")
+	}
+	if sm, tc := participants.GetSuperMajority(), participants.GetTrustCount(); sm != 1 || tc != 0 {
+		t.Fatalf("expected supermajority 1 and trust count 0, got %d and %d", sm, tc)
+	}
+
+	// they stay relaxed as participants come
+	participants.AddPeer(newTestPeers(1).ToPeerSlice()[0])
+	if sm := participants.GetSuperMajority(); sm != 1 {
+		t.Fatalf("expected supermajority 1, got %d", sm)
+	}
+
+	participants.SetDevSingleSuperMajority(false)
+	if sm, tc := participants.GetSuperMajority(), participants.GetTrustCount(); sm != 3 || tc != 1 {
+		t.Fatalf("expected supermajority 3 and trust count 1, got %d and %d", sm, tc)
+	}
+}
+
+func TestWeightOf(t *testing.T) {
+	participants := newTestPeers(3)
+	slice := participants.ToPeerSlice()
+	for _, p := range slice {
+		if w := participants.WeightOf(p.ID); w != 1 {
+			t.Fatalf("expected a weight of 1 without stakes, got %d", w)
+		}
+	}
+	participants.SetPeerWeight(slice[1], 5)
+	if w := participants.WeightOf(slice[1].ID); w != 5 {
+		t.Fatalf("expected the stake of 5, got %d", w)
+	}
+	if w := participants.WeightOf(slice[0].ID); w != 0 {
+		t.Fatalf("expected no weight without a stake once stakes are given, got %d", w)
+	}
+	if w := participants.WeightOf(slice[2].ID + 1000); w != 0 {
+		t.Fatalf("expected no weight for an unknown ID, got %d", w)
+	}
+}
@@ -0,0 +1,107 @@
+package poset
+
+import (
+	"strconv"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/state"
+)
+
+// Account is the PoS balance of an address in the state of a frame
+type Account struct {
+	Address   common.Address `json:"address"`
+	Balance   uint64         `json:"balance"`
+	Frame     int64          `json:"frame"`
+	StateHash common.Hash    `json:"state_hash"`
+}
+
+// GetAccount returns the balance of an address in the state of the frame of
+// the last block, or in the genesis state, as frame -1, before the first one
+func GetAccount(store Store, address common.Address) (Account, error) {
+	res := Account{
+		Address:   address,
+		Frame:     FrameNIL,
+		StateHash: store.StateRoot(),
+	}
+	if last := store.LastBlockIndex(); last >= 0 {
+		block, err := store.GetBlock(last)
+		if err != nil {
+			return Account{}, err
+		}
+		frame, err := store.GetFrame(block.RoundReceived())
+		if err != nil {
+			return Account{}, err
+		}
+		res.Frame = frame.Round
+		res.StateHash = common.BytesToHash(frame.StateHash)
+	}
+
+	statedb, err := state.New(res.StateHash, store.StateDB())
+	if err != nil {
+		return Account{}, err
+	}
+	res.Balance = statedb.GetBalance(address)
+	return res, nil
+}
+
+// AccountProof is the balance of an address in the state of a final frame,
+// with what a light client needs to check it: the trie nodes proving it
+// against the state hash of the frame, the frame, whose hash is the frame
+// hash of the block, and the block made of the frame with its signatures.
+// The signatures cover the block body, not its frame hash.
+type AccountProof struct {
+	Account
+	Proof state.ProofNodes
+	Frame Frame
+	Block Block
+}
+
+// GetAccountProof returns the balance of an address in the state of a
+// frame, with the proof of it
+func GetAccountProof(store Store, address common.Address, frame int64) (AccountProof, error) {
+	f, err := store.GetFrame(frame)
+	if err != nil {
+		return AccountProof{}, err
+	}
+	block, err := frameBlock(store, frame)
+	if err != nil {
+		return AccountProof{}, err
+	}
+
+	res := AccountProof{
+		Account: Account{
+			Address:   address,
+			Frame:     frame,
+			StateHash: common.BytesToHash(f.StateHash),
+		},
+		Frame: f,
+		Block: block,
+	}
+	statedb, err := state.New(res.StateHash, store.StateDB())
+	if err != nil {
+		return AccountProof{}, err
+	}
+	res.Balance = statedb.GetBalance(address)
+	if res.Proof, err = state.GetProof(store.StateDB(), res.StateHash, address); err != nil {
+		return AccountProof{}, err
+	}
+	return res, nil
+}
+
+// frameBlock returns the block made of a frame, looking back from the last
+// block as the blocks follow the frames
+func frameBlock(store Store, frame int64) (Block, error) {
+	for i := store.LastBlockIndex(); i >= 0; i-- {
+		block, err := store.GetBlock(i)
+		if err != nil {
+			return Block{}, err
+		}
+		if block.RoundReceived() == frame {
+			return block, nil
+		}
+		if block.RoundReceived() < frame {
+			break
+		}
+	}
+	return Block{}, common.NewStoreErr("FrameBlock", common.KeyNotFound, strconv.FormatInt(frame, 10))
+}
@@ -0,0 +1,149 @@
+package poset
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/pos"
+	"github.com/SamuelMarks/dag1/src/state"
+)
+
+// transferStore returns a store whose frame 1, made into block 0, has
+// ps[0] send 100 to ps[1] which sends 30 to ps[2], with the genesis balance
+func transferStore(t *testing.T) (Store, []*peers.Peer, common.Hash, uint64) {
+	participants := peers.NewPeers()
+	keys := make(map[uint64]*ecdsa.PrivateKey)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateECDSAKey()
+		peer := peers.NewPeer(fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)), "")
+		participants.AddPeer(peer)
+		keys[peer.ID] = key
+	}
+	store := NewInmemStore(participants, cacheSize, nil)
+	p, err := NewPoset(participants, store, nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := participants.ToPeerSlice()
+	genesis := pos.DefaultConfig().TotalSupply / uint64(len(ps))
+
+	transfer := func(from, to *peers.Peer, amount uint64) Event {
+		tx := NewInternalTransaction(TransactionType_POS_TRANSFER, *to)
+		tx.Amount = amount
+		ev := NewEvent(nil, []InternalTransaction{tx}, nil,
+			EventHashes{GenRootSelfParent(from.ID), EventHash{}},
+			crypto.FromECDSAPub(&keys[from.ID].PublicKey), 0,
+			NewFlagTable(), NewFlagTable(), 0, false)
+		ev.Message.CreatorID = from.ID
+		return ev
+	}
+	events := []Event{transfer(ps[0], ps[1], 100), transfer(ps[1], ps[2], 30)}
+	stateHash, err := p.ApplyInternalTransactions(1, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetFrame(Frame{Round: 1, StateHash: stateHash.Bytes()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetBlock(NewBlock(0, 1, nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	return store, ps, stateHash, genesis
+}
+
+func TestGetAccount(t *testing.T) {
+	store, ps, stateHash, genesis := transferStore(t)
+
+	participants := peers.NewPeers()
+	for _, peer := range ps {
+		participants.AddPeer(peers.NewPeer(peer.Message.PubKeyHex, ""))
+	}
+	account, err := GetAccount(NewInmemStore(participants, cacheSize, nil), ps[1].Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Frame != -1 || account.Balance != genesis {
+		t.Fatalf("expected the genesis balance %d, got %d at frame %d",
+			genesis, account.Balance, account.Frame)
+	}
+
+	for i, expected := range []uint64{genesis - 100, genesis + 70, genesis + 30} {
+		account, err := GetAccount(store, ps[i].Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if account.Frame != 1 || account.StateHash != stateHash {
+			t.Fatalf("expected the state of frame 1, got frame %d", account.Frame)
+		}
+		if account.Balance != expected {
+			t.Fatalf("participant %d: expected a balance of %d, got %d",
+				i, expected, account.Balance)
+		}
+	}
+}
+
+func TestGetAccountProof(t *testing.T) {
+	store, ps, stateHash, genesis := transferStore(t)
+
+	for i, expected := range []uint64{genesis - 100, genesis + 70, genesis + 30} {
+		proof, err := GetAccountProof(store, ps[i].Address(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proof.Balance != expected || proof.StateHash != stateHash {
+			t.Fatalf("participant %d: expected a balance of %d, got %d", i, expected, proof.Balance)
+		}
+		if proof.Block.Index() != 0 || proof.Block.RoundReceived() != 1 {
+			t.Fatalf("participant %d: expected block 0 of frame 1, got block %d of frame %d",
+				i, proof.Block.Index(), proof.Block.RoundReceived())
+		}
+
+		// offline, with nothing but the proof
+		if err := state.VerifyProof(stateHash, ps[i].Address(), expected, proof.Proof); err != nil {
+			t.Fatalf("participant %d: %v", i, err)
+		}
+		if err := state.VerifyProof(stateHash, ps[i].Address(), expected+1, proof.Proof); err == nil {
+			t.Fatalf("participant %d: expected another balance to fail", i)
+		}
+		if err := state.VerifyProof(stateHash, ps[(i+1)%3].Address(), expected, proof.Proof); err == nil {
+			t.Fatalf("participant %d: expected another address to fail", i)
+		}
+		if err := state.VerifyProof(common.Hash{}, ps[i].Address(), expected, proof.Proof); err == nil {
+			t.Fatalf("participant %d: expected another root to fail", i)
+		}
+
+		// every node of the proof is needed, and tampered with it fails
+		for j := range proof.Proof {
+			tampered := make(state.ProofNodes, len(proof.Proof))
+			for k := range proof.Proof {
+				tampered[k] = append([]byte{}, proof.Proof[k]...)
+			}
+			tampered[j][len(tampered[j])-1] ^= 0xFF
+			if err := state.VerifyProof(stateHash, ps[i].Address(), expected, tampered); err == nil {
+				t.Fatalf("participant %d: expected a tampered node %d to fail", i, j)
+			}
+			dropped := append(append(state.ProofNodes{}, proof.Proof[:j]...), proof.Proof[j+1:]...)
+			if err := state.VerifyProof(stateHash, ps[i].Address(), expected, dropped); err == nil {
+				t.Fatalf("participant %d: expected a proof without node %d to fail", i, j)
+			}
+		}
+	}
+
+	// an address without an account has no balance
+	var stranger common.Address
+	proof, err := GetAccountProof(store, stranger, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.VerifyProof(stateHash, stranger, 0, proof.Proof); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GetAccountProof(store, ps[0].Address(), 2); err == nil {
+		t.Fatal("expected no proof for a frame not made")
+	}
+}
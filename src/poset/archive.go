@@ -0,0 +1,203 @@
+package poset
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ArchiveSink keeps the events of the final frames out of the hot store, to
+// serve peers syncing from far behind
+type ArchiveSink interface {
+	// WriteEvents archives the events received in a final frame
+	WriteEvents(frame int64, events []Event) error
+	// ReadEvents returns the events archived for a frame, an error
+	// satisfying os.IsNotExist when the frame is not archived
+	ReadEvents(frame int64) ([]Event, error)
+}
+
+// archiveManifest opens each archive file and lists the hashes of its
+// events, in order, which must match the events read back
+type archiveManifest struct {
+	Frame  int64    `json:"frame"`
+	Hashes []string `json:"hashes"`
+}
+
+// FileArchive is an ArchiveSink writing one gzip file per frame in a
+// directory. A file holds the manifest then the events, each as a length
+// prefixed record.
+type FileArchive struct {
+	dir string
+}
+
+// NewFileArchive creates an archive in dir, which is created if needed
+func NewFileArchive(dir string) (*FileArchive, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileArchive{dir: dir}, nil
+}
+
+func (a *FileArchive) path(frame int64) string {
+	return filepath.Join(a.dir, fmt.Sprintf("frame-%d.gz", frame))
+}
+
+// WriteEvents implements ArchiveSink. The file is written aside and renamed,
+// so a frame is either archived whole or not at all.
+func (a *FileArchive) WriteEvents(frame int64, events []Event) error {
+	manifest := archiveManifest{Frame: frame}
+	records := make([][]byte, len(events))
+	for i := range events {
+		hash := events[i].Hash()
+		manifest.Hashes = append(manifest.Hashes, hash.String())
+		data, err := events[i].StoreMarshal()
+		if err != nil {
+			return err
+		}
+		records[i] = data
+	}
+	header, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(a.dir, "frame-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	zw := gzip.NewWriter(tmp)
+	w := bufio.NewWriter(zw)
+	for _, record := range append([][]byte{header}, records...) {
+		if err := writeArchiveRecord(w, record); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), a.path(frame))
+}
+
+// ReadEvents implements ArchiveSink. The events are checked against the
+// manifest of the file.
+func (a *FileArchive) ReadEvents(frame int64) ([]Event, error) {
+	f, err := os.Open(a.path(frame))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("archive of frame %d: %v", frame, err)
+	}
+	r := bufio.NewReader(zr)
+
+	header, err := readArchiveRecord(r)
+	if err != nil {
+		return nil, fmt.Errorf("archive of frame %d: manifest: %v", frame, err)
+	}
+	var manifest archiveManifest
+	if err := json.Unmarshal(header, &manifest); err != nil {
+		return nil, fmt.Errorf("archive of frame %d: manifest: %v", frame, err)
+	}
+	if manifest.Frame != frame {
+		return nil, fmt.Errorf("archive of frame %d holds frame %d", frame, manifest.Frame)
+	}
+
+	events := make([]Event, 0, len(manifest.Hashes))
+	for _, want := range manifest.Hashes {
+		data, err := readArchiveRecord(r)
+		if err != nil {
+			return nil, fmt.Errorf("archive of frame %d: event %s: %v", frame, want, err)
+		}
+		var event Event
+		if err := event.StoreUnmarshal(data); err != nil {
+			return nil, fmt.Errorf("archive of frame %d: event %s: %v", frame, want, err)
+		}
+		// the hash is computed from the body, not taken from the record
+		event.Message.Hash = nil
+		if hash := event.Hash(); hash.String() != want {
+			return nil, fmt.Errorf("archive of frame %d: expected event %s, got %s",
+				frame, want, hash.String())
+		}
+		events = append(events, event)
+	}
+	if _, err := readArchiveRecord(r); err != io.EOF {
+		return nil, fmt.Errorf("archive of frame %d: more events than its manifest", frame)
+	}
+	return events, nil
+}
+
+// frameArchiver is implemented by the stores archiving the final frames
+type frameArchiver interface {
+	ArchiveFrame(frame int64) error
+}
+
+// FrameArchive is implemented by the stores whose archived events are gone
+// from the database, to read them frame by frame when serving a peer from
+// far behind
+type FrameArchive interface {
+	// ArchivedFrames returns the first and last frames archived, last is
+	// below first when none is
+	ArchivedFrames() (first, last int64, err error)
+	// ArchivedEventFrame returns the frame an event of a participant was
+	// archived with, a KeyNotFound StoreErr when it is not archived
+	ArchivedEventFrame(participant string, index int64) (int64, error)
+	// ForEachFrameEvent calls fn with the events received in the frames
+	// from fromFrame to toFrame included, frame by frame, until fn returns
+	// false
+	ForEachFrameEvent(fromFrame, toFrame int64, fn func(Event) bool) error
+}
+
+// archiveFrame archives a final frame when the store can. Consensus goes on
+// when it fails, the events stay in the store.
+func (p *Poset) archiveFrame(frame int64) {
+	archiver, ok := p.Store.(frameArchiver)
+	if !ok {
+		return
+	}
+	if err := archiver.ArchiveFrame(frame); err != nil {
+		p.logger.WithError(err).WithField("frame", frame).Warn("Archiving frame")
+	}
+}
+
+func writeArchiveRecord(w io.Writer, data []byte) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(data)))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func readArchiveRecord(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
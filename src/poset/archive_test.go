@@ -0,0 +1,261 @@
+package poset
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+func TestArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dbDir, archiveDir := filepath.Join(dir, "badger"), filepath.Join(dir, "archive")
+
+	participants, keys := iteratorParticipants()
+	store, err := NewBadgerStore(participants, cacheSize, dbDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := NewFileArchive(archiveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetArchive(archive)
+
+	// two events of each of the frames 1 to 3, received in their frame
+	var events []Event
+	for i := 0; i < 6; i++ {
+		var self *Event
+		if i > 0 {
+			self = &events[i-1]
+		}
+		ev := capEvent(participants, keys[0], self, EventHash{}, fmt.Sprintf("a%d", i))
+		ev.Frame = int64(1 + i/2)
+		ev.FrameReceived = ev.Frame
+		if err := store.SetEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, ev)
+	}
+	for frame := int64(1); frame <= 3; frame++ {
+		if err := store.ArchiveFrame(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the events are gone from badger, and are removed from the cache
+	for _, ev := range events {
+		hash := ev.Hash()
+		if _, err := store.dbGetEventBlock(hash); !isDBKeyNotFound(err) {
+			t.Fatalf("expected archived event %s to be deleted, got %v", hash.String(), err)
+		}
+		store.inmemStore.eventCache.Remove(hash)
+	}
+	if first, last, err := store.ArchivedFrames(); err != nil || first != 1 || last != 3 {
+		t.Fatalf("expected frames 1 to 3 archived, got %d to %d (%v)", first, last, err)
+	}
+	defer store.Close()
+	store.SetArchive(nil)
+	hash := events[0].Hash()
+	if _, err := store.GetEventBlock(hash); err == nil {
+		t.Fatal("expected the event to be gone without the archive")
+	}
+	store.SetArchive(archive)
+
+	for i, ev := range events {
+		hash := ev.Hash()
+		got, err := store.GetEventBlock(hash)
+		if err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+		if gotHash := got.Hash(); gotHash != hash || got.FrameReceived != ev.FrameReceived {
+			t.Fatalf("event %d: expected %s received in %d, got %s received in %d",
+				i, hash.String(), ev.FrameReceived, gotHash.String(), got.FrameReceived)
+		}
+	}
+
+	var exported []EventHash
+	err = store.ForEachFrameEvent(1, 3, func(ev Event) bool {
+		exported = append(exported, ev.Hash())
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != len(events) {
+		t.Fatalf("expected %d events exported, got %d", len(events), len(exported))
+	}
+	for i := range exported {
+		// the events of a frame come in the order of their hashes
+		if frame := int64(1 + i/2); !containsHash(events[2*(frame-1):2*frame], exported[i]) {
+			t.Fatalf("expected event %d from frame %d", i, frame)
+		}
+	}
+}
+
+func TestArchiveIndexes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	participants, keys := iteratorParticipants()
+	store, err := NewBadgerStore(participants, cacheSize, filepath.Join(dir, "badger"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	archive, err := NewFileArchive(filepath.Join(dir, "archive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetArchive(archive)
+
+	// four events of a, the first two received in the archived frame 1
+	var events []Event
+	for i := 0; i < 4; i++ {
+		var self *Event
+		if i > 0 {
+			self = &events[i-1]
+		}
+		ev := capEvent(participants, keys[0], self, EventHash{}, fmt.Sprintf("a%d", i))
+		ev.Message.TopologicalIndex = int64(i)
+		ev.LamportTimestamp = int64(i)
+		ev.Frame = int64(1 + i/2)
+		ev.FrameReceived = ev.Frame
+		if err := store.SetEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, ev)
+	}
+	if err := store.ArchiveFrame(1); err != nil {
+		t.Fatal(err)
+	}
+	creator := events[0].GetCreator()
+
+	// the creator and sort indexes of the database cover the archived events
+	byCreator, err := store.dbParticipantEvents(creator, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byRound, err := store.EventsByRoundRange(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inOrder []EventHash
+	err = store.ForEachEvent(func(ev Event) bool {
+		inOrder = append(inOrder, ev.Hash())
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, hashes := range map[string][]EventHash{
+		"creator index": byCreator, "sort index": byRound, "topological order": inOrder} {
+		if len(hashes) != len(events) {
+			t.Fatalf("%s: expected %d events, got %d", name, len(events), len(hashes))
+		}
+		for i := range hashes {
+			if expected := events[i].Hash(); hashes[i] != expected {
+				t.Fatalf("%s: expected event %d to be %s, got %s",
+					name, i, expected.String(), hashes[i].String())
+			}
+		}
+	}
+	hash, err := store.dbParticipantEvent(creator, 1)
+	if err != nil || hash != events[1].Hash() {
+		t.Fatalf("expected archived event 1 of the creator, got %s (%v)", hash.String(), err)
+	}
+	if frame, err := store.ArchivedEventFrame(creator, 1); err != nil || frame != 1 {
+		t.Fatalf("expected event 1 to be archived with frame 1, got %d (%v)", frame, err)
+	}
+	if _, err := store.ArchivedEventFrame(creator, 2); !common.Is(err, common.KeyNotFound) {
+		t.Fatalf("expected event 2 not to be archived, got %v", err)
+	}
+
+	// an archived event set again, as the store bootstraps, is deleted again
+	if err := store.SetEvent(events[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ArchiveFrame(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.dbGetEventBlock(events[0].Hash()); !isDBKeyNotFound(err) {
+		t.Fatalf("expected the archived event to be deleted again, got %v", err)
+	}
+}
+
+func containsHash(events []Event, hash EventHash) bool {
+	for _, ev := range events {
+		if ev.Hash() == hash {
+			return true
+		}
+	}
+	return false
+}
+
+func TestArchiveManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive, err := NewFileArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	participants, keys := iteratorParticipants()
+	a := capEvent(participants, keys[0], nil, EventHash{}, "a")
+	b := capEvent(participants, keys[1], nil, EventHash{}, "b")
+	if err := archive.WriteEvents(1, []Event{a}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := archive.ReadEvents(2); !os.IsNotExist(err) {
+		t.Fatalf("expected a frame not archived not to exist, got %v", err)
+	}
+
+	// a file whose events do not match its manifest is refused
+	f, err := os.Create(archive.path(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	w := bufio.NewWriter(zw)
+	hash := a.Hash()
+	manifest, _ := json.Marshal(archiveManifest{Frame: 2, Hashes: []string{hash.String()}})
+	data, err := b.StoreMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range [][]byte{manifest, data} {
+		if err := writeArchiveRecord(w, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Flush()
+	zw.Close()
+	f.Close()
+	if _, err := archive.ReadEvents(2); err == nil {
+		t.Fatal("expected an event not matching the manifest to be refused")
+	}
+
+	// and so is a file of another frame
+	if err := os.Rename(archive.path(1), archive.path(3)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := archive.ReadEvents(3); err == nil {
+		t.Fatal("expected the archive of another frame to be refused")
+	}
+}
@@ -0,0 +1,285 @@
+package poset
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// decide sets the events of the DAG in creation order in a fresh
+// Poset, dividing them into rounds as they come, and calls decide after
+// each one. A round is one above the rounds of the parents when the event
+// strictly dominates a supermajority of the clothos of that round. The
+// events are left undetermined for DecideRoundReceived.
+func (d *randomDAG) decide(decide func(*Poset) error) (*Poset, error) {
+	participants := peers.NewPeers()
+	for _, peer := range d.participants.ToPeerSlice() {
+		peer := peers.NewPeer(peer.Message.PubKeyHex, peer.Message.NetAddr)
+		participants.AddPeer(peer)
+		participants.SetPeerWeight(peer, 1)
+	}
+	store := NewInmemStore(participants, len(d.events)+1000, nil)
+	logger := logrus.New()
+	logger.Level = logrus.WarnLevel
+	p, err := NewPoset(participants, store, nil, logrus.NewEntry(logger))
+	if err != nil {
+		return nil, err
+	}
+
+	rounds := make(map[EventHash]int64)
+	for i := range d.events {
+		ev := d.events[i]
+		ev.Message.CreatorID = participants.ByPubKey[ev.GetCreator()].ID
+		ev.Message.TopologicalIndex = int64(i)
+		if err := store.SetEvent(ev); err != nil {
+			return nil, err
+		}
+		hash := ev.Hash()
+
+		parentRound := int64(0)
+		spRound, known := rounds[ev.SelfParent()]
+		if known {
+			parentRound = spRound
+		}
+		if r, ok := rounds[ev.OtherParent()]; ok && r > parentRound {
+			parentRound = r
+		}
+		seen := uint64(0)
+		for _, w := range store.RoundClothos(parentRound) {
+			ss, err := p.strictlyDominated(hash, w)
+			if err != nil {
+				return nil, err
+			}
+			if ss {
+				seen++
+			}
+		}
+		round := parentRound
+		if seen >= p.GetSuperMajority() {
+			round++
+		}
+		rounds[hash] = round
+		p.roundCache.Add(hash, round)
+		p.UndeterminedEvents = append(p.UndeterminedEvents, hash)
+
+		roundInfo, err := store.GetRound(round)
+		if err != nil {
+			roundInfo = *NewRound()
+		}
+		if !roundInfo.Message.Queued {
+			p.PendingRounds = append(p.PendingRounds, &pendingRound{round, false})
+			roundInfo.Message.Queued = true
+		}
+		roundInfo.AddEvent(hash, !known || round > spRound)
+		if err := store.SetRound(round, roundInfo); err != nil {
+			return nil, err
+		}
+
+		if err := decide(p); err != nil {
+			return nil, fmt.Errorf("event %d: %v", i, err)
+		}
+	}
+	return p, nil
+}
+
+// decideAtroposUnmemoized counts all the votes again on each call, as
+// DecideAtropos did before it kept them
+func (p *Poset) decideAtroposUnmemoized() error {
+
+	// Initialize the vote map
+	votes := make(map[EventHash]map[EventHash]bool) // [x][y]=>vote(x,y)
+	setVote := func(votes map[EventHash]map[EventHash]bool, x, y EventHash, vote bool) {
+		if votes[x] == nil {
+			votes[x] = make(map[EventHash]bool)
+		}
+		votes[x][y] = vote
+	}
+
+	decidedRounds := map[int64]int64{} // [round number] => index in p.PendingRounds
+	c := 11
+
+	for pos, r := range p.PendingRounds {
+		roundIndex := r.Index
+		roundInfo, err := p.Store.GetRound(roundIndex)
+		if err != nil {
+			return err
+		}
+		for _, x := range roundInfo.Clotho() {
+			if roundInfo.IsDecided(x) {
+				continue
+			}
+		VoteLoop:
+			for j := roundIndex + 1; j <= p.Store.LastRound(); j++ {
+				for _, y := range p.Store.RoundClothos(j) {
+					diff := j - roundIndex
+					if diff == 1 {
+						ycx, err := p.dominated(y, x)
+						if err != nil {
+							return err
+						}
+						setVote(votes, y, x, ycx)
+					} else {
+						// count votes
+						var ssClotho []EventHash
+						for _, w := range p.Store.RoundClothos(j - 1) {
+							ss, err := p.strictlyDominated(y, w)
+							if err != nil {
+								return err
+							}
+							if ss {
+								ssClotho = append(ssClotho, w)
+							}
+						}
+						yays := uint64(0)
+						nays := uint64(0)
+						for _, w := range ssClotho {
+							if votes[w][x] {
+								yays++
+							} else {
+								nays++
+							}
+						}
+						v := false
+						t := nays
+						if yays >= nays {
+							v = true
+							t = yays
+						}
+
+						// normal round
+						if math.Mod(float64(diff), float64(c)) > 0 {
+							if t >= p.GetSuperMajority() {
+								roundInfo.SetAtropos(x, v)
+								setVote(votes, y, x, v)
+								break VoteLoop // break out of j loop
+							} else {
+								setVote(votes, y, x, v)
+							}
+						} else { // coin round
+							if t >= p.GetSuperMajority() {
+								setVote(votes, y, x, v)
+							} else {
+								setVote(votes, y, x, CoinFlip(j, x, y))
+							}
+						}
+					}
+				}
+			}
+		}
+
+		err = p.Store.SetRound(roundIndex, roundInfo)
+		if err != nil {
+			return err
+		}
+
+		if roundInfo.ClothoDecided() {
+			decidedRounds[roundIndex] = int64(pos)
+		}
+	}
+
+	p.updatePendingRounds(decidedRounds)
+	return nil
+}
+
+func TestAtroposVotes(t *testing.T) {
+	for _, conf := range []struct {
+		participants, events int
+		seed                 int64
+	}{
+		{4, 300, 1},
+		{5, 300, 2},
+		{7, 400, 3},
+	} {
+		d := newGossipDAG(conf.participants, conf.events, conf.seed, t)
+
+		memoized, err := d.decide(func(p *Poset) error {
+			if err := p.DecideAtropos(); err != nil {
+				return err
+			}
+			// the votes are only kept for the pending rounds
+			for r := range p.atroposVotes {
+				if !p.isPendingRound(r) {
+					return fmt.Errorf("votes kept for round %d, which is not pending", r)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%+v: %v", conf, err)
+		}
+		reference, err := d.decide((*Poset).decideAtroposUnmemoized)
+		if err != nil {
+			t.Fatalf("%+v: %v", conf, err)
+		}
+
+		if !reflect.DeepEqual(memoized.PendingRounds, reference.PendingRounds) {
+			t.Fatalf("%+v: expected the pending rounds %v, got %v",
+				conf, reference.PendingRounds, memoized.PendingRounds)
+		}
+		decided := 0
+		for r := int64(0); r <= reference.Store.LastRound(); r++ {
+			x, err := memoized.Store.GetRound(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			y, err := reference.Store.GetRound(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(x.Message.Events, y.Message.Events) {
+				t.Fatalf("%+v: round %d: expected the events %v, got %v",
+					conf, r, y.Message.Events, x.Message.Events)
+			}
+			if y.ClothoDecided() && len(y.Clotho()) > 0 {
+				decided++
+			}
+		}
+		if decided == 0 {
+			t.Fatalf("%+v: expected decided rounds", conf)
+		}
+	}
+}
+
+func (p *Poset) isPendingRound(r int64) bool {
+	for _, pr := range p.PendingRounds {
+		if pr.Index == r {
+			return true
+		}
+	}
+	return false
+}
+
+// BenchmarkDecideAtropos times the calls to DecideAtropos after each event,
+// across which the last rounds stay undecided. The strictly dominated cache
+// is purged before each call, as if evicted, so each vote counted again
+// costs its strictlyDominated walks.
+func BenchmarkDecideAtropos(b *testing.B) {
+	d := newGossipDAG(7, 300, 1, b)
+	for _, memoized := range []bool{true, false} {
+		name := "unmemoized"
+		decide := (*Poset).decideAtroposUnmemoized
+		if memoized {
+			name = "memoized"
+			decide = (*Poset).DecideAtropos
+		}
+		b.Run(name, func(b *testing.B) {
+			b.StopTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := d.decide(func(p *Poset) error {
+					p.strictlyDominatedCache.Purge()
+					b.StartTimer()
+					defer b.StopTimer()
+					return decide(p)
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package poset
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditKind is the consensus milestone an AuditEntry records
+type AuditKind string
+
+// The milestones of the audit trail
+const (
+	// AuditEventAccepted is an event inserted in the DAG
+	AuditEventAccepted AuditKind = "event_accepted"
+	// AuditClotho is a root designated clotho
+	AuditClotho AuditKind = "clotho"
+	// AuditAtropos is a clotho decided atropos, with its timestamp
+	AuditAtropos AuditKind = "atropos"
+	// AuditBlock is a block emitted for the app
+	AuditBlock AuditKind = "block"
+	// AuditBlockSignature is a valid signature added to a block
+	AuditBlockSignature AuditKind = "block_signature"
+)
+
+// AuditEntry is a line of the audit trail. The sink sets Seq and Node.
+type AuditEntry struct {
+	// Seq increases by one from entry to entry of a node, from 1
+	Seq  uint64    `json:"seq"`
+	Node uint64    `json:"node"`
+	Time time.Time `json:"time"`
+	Kind AuditKind `json:"kind"`
+
+	// Event, Creator and Round are those of the event entries, Round is the
+	// round received of the block entries
+	Event            string `json:"event,omitempty"`
+	Creator          string `json:"creator,omitempty"`
+	Round            int64  `json:"round"`
+	AtroposTimestamp int64  `json:"atropos_timestamp,omitempty"`
+
+	// Block, FrameHash and Transactions are those of the block entries,
+	// Signatures the number gathered by the block so far
+	Block        *int64 `json:"block,omitempty"`
+	FrameHash    string `json:"frame_hash,omitempty"`
+	Transactions int    `json:"transactions,omitempty"`
+	Signatures   int    `json:"signatures,omitempty"`
+	Validator    string `json:"validator,omitempty"`
+}
+
+// AuditSink keeps the audit trail of the consensus decisions of a node,
+// apart from the debug logs. Write is called in the order of the decisions.
+type AuditSink interface {
+	Write(entry AuditEntry) error
+}
+
+// nopAuditSink is the sink of the posets without audit trail
+type nopAuditSink struct{}
+
+func (nopAuditSink) Write(AuditEntry) error { return nil }
+
+// SetAuditSink makes the poset write its consensus decisions to sink, none
+// when nil. It must be set before events are inserted.
+func (p *Poset) SetAuditSink(sink AuditSink) {
+	if sink == nil {
+		sink = nopAuditSink{}
+	}
+	p.auditSink = sink
+}
+
+// audit writes an entry to the audit sink. A failed write is logged, the
+// consensus goes on.
+func (p *Poset) audit(entry AuditEntry) {
+	if p.auditSink == nil {
+		return
+	}
+	entry.Time = time.Now().UTC()
+	if err := p.auditSink.Write(entry); err != nil {
+		p.warnLimiter.Warnf(p.logger, "Writing the audit log: %v", err)
+	}
+}
+
+// auditEvent returns the entry of a milestone of an event
+func auditEvent(kind AuditKind, ev *Event) AuditEntry {
+	hash := ev.Hash()
+	entry := AuditEntry{
+		Kind:    kind,
+		Event:   hash.String(),
+		Creator: ev.GetCreator(),
+		Round:   ev.Frame,
+	}
+	if kind == AuditAtropos {
+		entry.AtroposTimestamp = ev.AtroposTimestamp
+	}
+	return entry
+}
+
+// auditBlock returns the entry of a block emitted
+func auditBlock(block Block) AuditEntry {
+	index := block.Index()
+	return AuditEntry{
+		Kind:         AuditBlock,
+		Round:        block.RoundReceived(),
+		Block:        &index,
+		FrameHash:    fmt.Sprintf("%X", block.GetFrameHash()),
+		Transactions: len(block.Transactions()),
+		Signatures:   len(block.Signatures),
+	}
+}
+
+// DefaultAuditLogMaxSize is the size an AuditLog file is rotated at
+const DefaultAuditLogMaxSize = 64 << 20
+
+// AuditLog is an AuditSink appending the entries to a file as JSON lines.
+// The file is renamed path.1, path.2 and so on as it reaches its max size,
+// and a new one is started. The sequence continues from the last entry of
+// the file when the log is reopened.
+type AuditLog struct {
+	sync.Mutex
+	path    string
+	node    uint64
+	maxSize int64
+
+	file *os.File
+	size int64
+	seq  uint64
+}
+
+// NewAuditLog opens the audit log of a node at path, rotated at maxSize
+// bytes, DefaultAuditLogMaxSize when 0
+func NewAuditLog(path string, node uint64, maxSize int64) (*AuditLog, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultAuditLogMaxSize
+	}
+	l := &AuditLog{path: path, node: node, maxSize: maxSize}
+	seq, err := lastAuditSeq(path)
+	if err != nil {
+		return nil, err
+	}
+	l.seq = seq
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// lastAuditSeq returns the sequence number of the last entry of a file, 0
+// when there is none
+func lastAuditSeq(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var last uint64
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return 0, fmt.Errorf("%s: %v", path, err)
+		}
+		last = entry.Seq
+	}
+	return last, scanner.Err()
+}
+
+func (l *AuditLog) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// rotate renames the file to the first free path.N and starts a new one
+func (l *AuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	for n := 1; ; n++ {
+		rotated := fmt.Sprintf("%s.%d", l.path, n)
+		if _, err := os.Stat(rotated); os.IsNotExist(err) {
+			if err := os.Rename(l.path, rotated); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return l.open()
+}
+
+// Write implements AuditSink. Each entry is synced to the disk.
+func (l *AuditLog) Write(entry AuditEntry) error {
+	l.Lock()
+	defer l.Unlock()
+	if l.file == nil {
+		return os.ErrClosed
+	}
+	entry.Seq = l.seq + 1
+	entry.Node = l.node
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := l.file.Write(line); err != nil {
+		return err
+	}
+	l.size += int64(len(line))
+	l.seq = entry.Seq
+	return l.file.Sync()
+}
+
+// Close closes the file of the log
+func (l *AuditLog) Close() error {
+	l.Lock()
+	defer l.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// AuditGapError is returned by VerifyAuditLog for an entry which does not
+// follow the previous one
+type AuditGapError struct {
+	Path     string
+	Line     int
+	Expected uint64
+	Seq      uint64
+}
+
+func (e *AuditGapError) Error() string {
+	return fmt.Sprintf("%s:%d: expected audit entry %d, got %d",
+		e.Path, e.Line, e.Expected, e.Seq)
+}
+
+// IsAuditGap returns true for an AuditGapError
+func IsAuditGap(err error) bool {
+	_, ok := err.(*AuditGapError)
+	return ok
+}
+
+// VerifyAuditLog checks that the entries of the files of an audit log, the
+// rotated ones first, have consecutive sequence numbers and the same node.
+// It returns the entries.
+func VerifyAuditLog(paths ...string) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return entries, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1<<20)
+		for line := 1; scanner.Scan(); line++ {
+			var entry AuditEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				f.Close()
+				return entries, fmt.Errorf("%s:%d: %v", path, line, err)
+			}
+			if n := len(entries); n > 0 {
+				prev := entries[n-1]
+				if entry.Seq != prev.Seq+1 {
+					f.Close()
+					return entries, &AuditGapError{Path: path, Line: line, Expected: prev.Seq + 1, Seq: entry.Seq}
+				}
+				if entry.Node != prev.Node {
+					f.Close()
+					return entries, fmt.Errorf("%s:%d: entry of node %d in the log of node %d",
+						path, line, entry.Node, prev.Node)
+				}
+			}
+			entries = append(entries, entry)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return entries, err
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,83 @@
+package poset
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLogRotateAndVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	// small files, rotated every few entries
+	l, err := NewAuditLog(path, 7, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := l.Write(AuditEntry{Kind: AuditEventAccepted, Event: "0x01", Round: int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the sequence goes on when the log is reopened
+	l, err = NewAuditLog(path, 7, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Write(AuditEntry{Kind: AuditClotho, Event: "0x01"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) < 2 {
+		t.Fatalf("expected the log rotated, got %v", rotated)
+	}
+	paths := make([]string, 0, len(rotated)+1)
+	for i := range rotated {
+		paths = append(paths, fmt.Sprintf("%s.%d", path, i+1))
+	}
+	paths = append(paths, path)
+	entries, err := VerifyAuditLog(paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 11 || entries[0].Seq != 1 || entries[10].Seq != 11 {
+		t.Fatalf("expected entries 1 to 11, got %d entries", len(entries))
+	}
+	for _, entry := range entries {
+		if entry.Node != 7 {
+			t.Fatalf("expected the entries of node 7, got %+v", entry)
+		}
+	}
+
+	// a deleted line breaks the sequence
+	data, err := ioutil.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if err := ioutil.WriteFile(paths[0], []byte(lines[0]+strings.Join(lines[2:], "")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyAuditLog(paths...); !IsAuditGap(err) {
+		t.Fatalf("expected a gap in the audit log, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package poset
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/1lann/cete"
+)
+
+const (
+	schemaVersionKey = "schema_version"
+	timeTablePrefix  = "tt/"
+)
+
+// migration upgrades the layout of a badger store by one version
+type migration struct {
+	name string
+	run  func(db *cete.DB) error
+}
+
+// migrations are run in order on open, the one at index i brings a store
+// from version i to version i+1. Stores made before versioning are at
+// version 0. Append only: a released migration must never change.
+var migrations = []migration{
+	{"time tables under tt/ with frame scoping", migrateTimeTables},
+	{"created and received rounds in one record", migrateRounds},
+}
+
+// schemaVersion is the version of the layout written by this code
+var schemaVersion = len(migrations)
+
+// getSchemaVersion returns the schema version of a store, 0 for stores made
+// before versioning
+func getSchemaVersion(db *cete.DB) (int, error) {
+	if !hasTable(db, META_TBL) {
+		return 0, nil
+	}
+	var version int
+	if _, err := db.Table(META_TBL).Get(schemaVersionKey, &version); err != nil {
+		if isDBKeyNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return version, nil
+}
+
+func setSchemaVersion(db *cete.DB, version int) error {
+	if !hasTable(db, META_TBL) {
+		if err := db.NewTable(META_TBL); err != nil {
+			return err
+		}
+	}
+	return db.Table(META_TBL).Set(schemaVersionKey, version)
+}
+
+// migrate brings a store to schemaVersion, bumping the stored version after
+// each migration so that an interrupted upgrade resumes where it stopped.
+// Stores from a newer version are refused rather than misread.
+func migrate(db *cete.DB) error {
+	version, err := getSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("store schema version %d is newer than %d, "+
+			"the store was written by a newer version", version, schemaVersion)
+	}
+	for ; version < schemaVersion; version++ {
+		m := migrations[version]
+		if err := m.run(db); err != nil {
+			return fmt.Errorf("migration to schema version %d (%s): %v",
+				version+1, m.name, err)
+		}
+		if err := setSchemaVersion(db, version+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasTable(db *cete.DB, name string) bool {
+	for _, t := range db.Tables() {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateTimeTables moves the time tables from "<frame>_<hash>" keys, which
+// shared the key space with any future key of the table, to
+// "tt/<frame>/<hash>"
+func migrateTimeTables(db *cete.DB) error {
+	if !hasTable(db, TIMETABLE_TBL) {
+		return nil
+	}
+	tbl := db.Table(TIMETABLE_TBL)
+
+	moved := make(map[string]FlagTable)
+	r := tbl.All()
+	for r.Next() {
+		if strings.HasPrefix(r.Key(), timeTablePrefix) {
+			continue
+		}
+		ft := NewFlagTable()
+		if err := r.Decode(&ft); err != nil {
+			r.Close()
+			return err
+		}
+		moved[r.Key()] = ft
+	}
+	err := r.Error()
+	r.Close()
+	if err != nil && err != cete.ErrEndOfRange {
+		return err
+	}
+
+	for key, ft := range moved {
+		parts := strings.SplitN(key, "_", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("malformed time table key %q", key)
+		}
+		if err := tbl.Set(timeTablePrefix+parts[0]+"/"+parts[1], ft); err != nil {
+			return err
+		}
+		if err := tbl.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateRounds creates the table of the Round records, which replace the
+// RoundCreated and RoundReceived records of a round. Those were never
+// written since the store moved to cete tables, so there is no record to
+// carry over.
+func migrateRounds(db *cete.DB) error {
+	if hasTable(db, ROUNDS_TBL) {
+		return nil
+	}
+	return db.NewTable(ROUNDS_TBL)
+}
@@ -0,0 +1,117 @@
+package poset
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMigrateTimeTables(t *testing.T) {
+	if err := os.RemoveAll("test_data"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("test_data", os.ModeDir|0777); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := "test_data/badger"
+
+	// a store in the layout from before versioning: no schema version and
+	// time tables keyed "<frame>_<hash>"
+	store := createTestDB(dbPath, t)
+	defer func() {
+		if err := os.RemoveAll(store.path); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if err := store.db.Table(META_TBL).Delete(schemaVersionKey); err != nil {
+		t.Fatal(err)
+	}
+	hashes := []EventHash{fakeEventHash("a"), fakeEventHash("b")}
+	tables := map[int64]FlagTable{
+		3: {hashes[0]: 1, hashes[1]: 2},
+		5: {hashes[1]: 7},
+	}
+	for frame, ft := range tables {
+		key := fmt.Sprintf("%09d_%s", frame, hashes[0].String())
+		if err := store.db.Table(TIMETABLE_TBL).Set(key, ft); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// what LoadBadgerStore runs on open
+	if err := migrate(store.db); err != nil {
+		t.Fatal(err)
+	}
+	version, err := getSchemaVersion(store.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != schemaVersion {
+		t.Fatalf("expected schema version %d, got %d", schemaVersion, version)
+	}
+	for frame, ft := range tables {
+		res, err := store.GetTimeTable(frame, hashes[0])
+		if err != nil {
+			t.Fatalf("frame %d: %v", frame, err)
+		}
+		if !reflect.DeepEqual(ft, res) {
+			t.Fatalf("frame %d: expected time table %v, got %v", frame, ft, res)
+		}
+		var legacy FlagTable
+		key := fmt.Sprintf("%09d_%s", frame, hashes[0].String())
+		if _, err := store.db.Table(TIMETABLE_TBL).Get(key, &legacy); !isDBKeyNotFound(err) {
+			t.Fatalf("frame %d: expected the legacy key to be gone, got %v", frame, err)
+		}
+	}
+
+	// migrated keys are frame scoped
+	if err := store.DropTimeTables(4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.dbGetTimeTable(3, hashes[0]); err == nil {
+		t.Fatal("expected the time table of frame 3 to be dropped")
+	}
+	if _, err := store.dbGetTimeTable(5, hashes[0]); err != nil {
+		t.Fatalf("expected the time table of frame 5 to be kept: %v", err)
+	}
+
+	// a second open has nothing to migrate
+	if err := migrate(store.db); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewerSchemaVersion(t *testing.T) {
+	if err := os.RemoveAll("test_data"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("test_data", os.ModeDir|0777); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := "test_data/badger"
+
+	store := createTestDB(dbPath, t)
+	defer func() {
+		if err := os.RemoveAll(store.path); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if version, err := getSchemaVersion(store.db); err != nil || version != schemaVersion {
+		t.Fatalf("expected a new store at schema version %d, got %d (%v)",
+			schemaVersion, version, err)
+	}
+	if err := setSchemaVersion(store.db, schemaVersion+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadBadgerStore(cacheSize, dbPath); err == nil {
+		t.Fatal("expected a store from a newer version to be refused")
+	}
+}
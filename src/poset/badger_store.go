@@ -2,7 +2,11 @@ package poset
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/dgraph-io/badger"
 	"github.com/1lann/cete"
@@ -18,8 +22,6 @@ import (
 const (
 	participantPrefix   = "participant"
 	rootSuffix          = "root"
-	roundCreatedPrefix  = "roundCreated"
-	roundReceivedPrefix = "roundReceived"
 	topoPrefix          = "topo"
 	blockPrefix         = "block"
 	framePrefix         = "frame"
@@ -33,7 +35,17 @@ const (
 	CLOTHOCHK_TBL       = "clotho_chk"
 	CLOTHOCREATORCHK_TBL= "clotho_creator_chk"
 	TIMETABLE_TBL       = "time_table"
+	CHECKPOINT_TBL      = "checkpoint"
+	ROUNDS_TBL          = "rounds"
+	ARCHIVE_TBL         = "archive"
 	PEERS_TBL           = "peers"
+	META_TBL            = "meta"
+	REPUTATION_TBL      = "reputation"
+	ADDRESS_TBL         = "address"
+	JOURNAL_TBL         = "journal"
+	BLOCKS_TBL          = "blocks"
+	FRAMES_TBL          = "frames"
+	ROOTS_TBL           = "roots"
 )
 
 // BadgerStore struct for badger config data
@@ -46,15 +58,47 @@ type BadgerStore struct {
 
 	states    state.Database
 	stateRoot common.Hash
+
+	// archive, when set, keeps the final frames out of the database
+	archive      ArchiveSink
+	archiveCache *archiveCache
+
+	// finalityLog, when set, is the file the final events are logged to
+	finalityLog string
+
+	// txIndex is true when the transactions are indexed
+	txIndex bool
+
+	// maintenance is held by the value log GC and by the operations deleting
+	// many keys, pruning counts the latter
+	maintenance sync.Mutex
+	pruning     int32
+	gc          valueLogGC
+
+	// loadStats tell what loading the store from its database took
+	loadStats StoreLoadStats
+}
+
+// badgerValueLogFileSize is the size of the value log files of the stores,
+// the default of badger when zero
+var badgerValueLogFileSize int64
+
+// badgerOptions returns the options the stores open badger with
+func badgerOptions() badger.Options {
+	opts := badger.DefaultOptions
+	opts.SyncWrites = false
+	if badgerValueLogFileSize != 0 {
+		opts.ValueLogFileSize = badgerValueLogFileSize
+	}
+	return opts
 }
 
 // NewBadgerStore creates a brand new Store with a new database
 func NewBadgerStore(participants *peers.Peers, cacheSize int, path string, posConf *pos.Config) (*BadgerStore, error) {
 	inmemStore := NewInmemStore(participants, cacheSize, posConf)
-	opts := badger.DefaultOptions
+	opts := badgerOptions()
 //	opts.Dir = path
 //	opts.ValueDir = path
-	opts.SyncWrites = false
 	handle, err := cete.Open(path, opts)
 	if err != nil {
 		return nil, err
@@ -100,10 +144,22 @@ func NewBadgerStore(participants *peers.Peers, cacheSize int, path string, posCo
 		return nil, err
 	}
 
+	if err := store.db.NewTable(CHECKPOINT_TBL); err != nil {
+		return nil, err
+	}
+
+	if err := store.db.NewTable(ROUNDS_TBL); err != nil {
+		return nil, err
+	}
+
 	if err := store.db.NewTable(PEERS_TBL); err != nil {
 		return nil, err
 	}
 
+	if err := setSchemaVersion(store.db, schemaVersion); err != nil {
+		return nil, err
+	}
+
 	if err := store.dbSetParticipants(participants); err != nil {
 		return nil, err
 	}
@@ -120,17 +176,19 @@ func NewBadgerStore(participants *peers.Peers, cacheSize int, path string, posCo
 	return store, nil
 }
 
-// LoadBadgerStore creates a Store from an existing database
+// LoadBadgerStore creates a Store from an existing database. It loads the
+// roots and the last cacheSize events of each participant, the older events
+// are read from the database when asked for.
 func LoadBadgerStore(cacheSize int, path string) (*BadgerStore, error) {
 
 	if _, err := os.Stat(path); err != nil {
 		return nil, err
 	}
+	start := time.Now()
 
-	opts := badger.DefaultOptions
+	opts := badgerOptions()
 //	opts.Dir = path
 //	opts.ValueDir = path
-	opts.SyncWrites = false
 	handle, err := cete.Open(path, opts)
 	if err != nil {
 		return nil, err
@@ -145,6 +203,10 @@ func LoadBadgerStore(cacheSize int, path string) (*BadgerStore, error) {
 //					handle), statePrefix)),
 	}
 
+	if err := migrate(store.db); err != nil {
+		return nil, err
+	}
+
 	participants, err := store.dbGetParticipants()
 	if err != nil {
 		return nil, err
@@ -154,8 +216,12 @@ func LoadBadgerStore(cacheSize int, path string) (*BadgerStore, error) {
 
 	// read roots from db and put them in InmemStore
 	roots := make(map[string]Root)
-	for p := range participants.ByPubKey {
+	for p, peer := range participants.ByPubKey {
 		root, err := store.dbGetRoot(p)
+		if isDBKeyNotFound(err) {
+			// a root not stored is the base root of the participant
+			root, err = NewBaseRoot(peer.ID), nil
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -169,6 +235,16 @@ func LoadBadgerStore(cacheSize int, path string) (*BadgerStore, error) {
 	store.participants = participants
 	store.inmemStore = inmemStore
 
+	if err := store.dbReplayBatch(); err != nil {
+		return nil, fmt.Errorf("failed to replay the pending batch: %v", err)
+	}
+
+	if err := store.dbLoadRecent(cacheSize); err != nil {
+		return nil, fmt.Errorf("failed to load the last events: %v", err)
+	}
+	store.loadStats.Roots = len(roots)
+	store.loadStats.Duration = time.Since(start)
+
 	return store, nil
 }
 
@@ -207,17 +283,18 @@ func participantRootKey(participant string) []byte {
 	return []byte(fmt.Sprintf("%s_%s", participant, rootSuffix))
 }
 
-func roundCreatedKey(index int64) []byte {
-	return []byte(fmt.Sprintf("%s_%09d", roundCreatedPrefix, index))
+func roundKey(index int64) string {
+	return fmt.Sprintf("%09d", index)
 }
 
-func roundReceivedKey(index int64) []byte {
-	return []byte(fmt.Sprintf("%s_%09d", roundReceivedPrefix, index))
-}
 func blockKey(index int64) []byte {
 	return []byte(fmt.Sprintf("%s_%09d", blockPrefix, index))
 }
 
+func checkpointKey(frame int64) string {
+	return fmt.Sprintf("%09d", frame)
+}
+
 func frameKey(index int64) []byte {
 	return []byte(fmt.Sprintf("%s_%09d", framePrefix, index))
 }
@@ -230,8 +307,12 @@ func checkClothoCreatorKey(frame int64, creatorID uint64) string {
 	return string(fmt.Sprintf("%09d_%d", frame, creatorID))
 }
 
-func timeTableKey(hash EventHash) string {
-	return string(fmt.Sprintf("timeTable_%s", hash.String()))
+func timeTableKey(frame int64, hash EventHash) string {
+	return fmt.Sprintf("%s/%s", timeTableFrameKey(frame), hash.String())
+}
+
+func timeTableFrameKey(frame int64) string {
+	return fmt.Sprintf("%s%09d", timeTablePrefix, frame)
 }
 
 /*
@@ -241,15 +322,113 @@ func timeTableKey(hash EventHash) string {
 // TopologicalEvents returns event in topological order.
 func (s *BadgerStore) TopologicalEvents() ([]Event, error) {
 	var res []Event
+	err := s.ForEachEvent(func(event Event) bool {
+		res = append(res, event)
+		return true
+	})
+	return res, err
+}
 
+// ForEachEvent calls fn on the events in topological order until it returns
+// false, decoding one event at a time. The events of the archived frames come
+// first, frame by frame.
+func (s *BadgerStore) ForEachEvent(fn func(Event) bool) error {
+	first, last, err := s.ArchivedFrames()
+	if err != nil {
+		return err
+	}
+	if more, err := s.forEachArchivedEventFrom(first, last, fn); !more || err != nil {
+		return err
+	}
 	r := s.db.Table(EVENTS_TBL).Index(TOPO_IDX).Between(cete.MinValue, cete.MaxValue)
+	defer r.Close()
 	for r.Next() {
 		var result Event
 		r.Decode(&result)
-		res = append(res, result)
+		// an archived event set again is not visited twice
+		if first <= last && result.FrameReceived >= first &&
+			result.FrameReceived <= last && s.dbIsArchived(result.Hash()) {
+			continue
+		}
+		if !fn(result) {
+			return nil
+		}
 	}
 	if r.Error() != cete.ErrEndOfRange {
-		return res, fmt.Errorf("%v", r.Error())
+		return fmt.Errorf("%v", r.Error())
+	}
+	return nil
+}
+
+// EventsByCreator returns the events of a creator with an index from
+// fromIndex to toIndex included, from the creator index
+func (s *BadgerStore) EventsByCreator(creator string, fromIndex, toIndex int64) (EventHashes, error) {
+	if _, ok := s.participants.ReadByPubKey(creator); !ok {
+		return EventHashes{}, common.NewStoreErr("ParticipantEvents", common.UnknownParticipant, creator)
+	}
+	if toIndex < fromIndex {
+		return EventHashes{}, nil
+	}
+	pubKey, err := hexutil.Decode(creator)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := s.dbArchivedCreatorEvents(pubKey, fromIndex, toIndex, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(archived) == 0 {
+		return s.dbEventHashes(s.db.Table(EVENTS_TBL).Index(CREATOR_IDX).Between(
+			[]interface{}{pubKey, fromIndex}, []interface{}{pubKey, toIndex}))
+	}
+	events, err := s.dbEvents(s.db.Table(EVENTS_TBL).Index(CREATOR_IDX).Between(
+		[]interface{}{pubKey, fromIndex}, []interface{}{pubKey, toIndex}))
+	if err != nil {
+		return nil, err
+	}
+	return mergeByIndex(events, archived)
+}
+
+// EventsByRoundRange returns the events created in a round from fromRound to
+// toRound included, ordered by round, from the sort index
+func (s *BadgerStore) EventsByRoundRange(fromRound, toRound int64) (EventHashes, error) {
+	if toRound < fromRound {
+		return EventHashes{}, nil
+	}
+	archived, err := s.dbArchivedRoundEvents(fromRound, toRound)
+	if err != nil {
+		return nil, err
+	}
+	r := s.db.Table(EVENTS_TBL).Index(SORT_IDX).Between(
+		[]interface{}{fromRound, cete.MinValue, cete.MinValue, cete.MinValue},
+		[]interface{}{toRound, cete.MaxValue, cete.MaxValue, cete.MaxValue})
+	if len(archived) == 0 {
+		return s.dbEventHashes(r)
+	}
+	events, err := s.dbEvents(r)
+	if err != nil {
+		return nil, err
+	}
+	// the events of the database and of the archive are merged as SORT_IDX
+	// orders them
+	entries := make([]archiveEntry, 0, len(events)+len(archived))
+	seen := make(map[string]bool, len(events))
+	for _, event := range events {
+		entry := newArchiveEntry(event)
+		seen[string(entry.Hash)] = true
+		entries = append(entries, entry)
+	}
+	for _, entry := range archived {
+		if !seen[string(entry.Hash)] {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].sortsBefore(entries[j]) })
+	res := make(EventHashes, len(entries))
+	for i, entry := range entries {
+		if err := res[i].Set(entry.Hash); err != nil {
+			return nil, err
+		}
 	}
 	return res, nil
 }
@@ -282,6 +461,10 @@ func (s *BadgerStore) GetEventBlock(hash EventHash) (event Event, err error) {
 	if err != nil {
 		event, err = s.dbGetEventBlock(hash)
 	}
+	// events of the archived frames may be gone from the db
+	if isDBKeyNotFound(err) && s.archive != nil {
+		event, err = s.archivedEvent(hash)
+	}
 	return event, mapError(err, "Event", hash.String())
 }
 
@@ -292,22 +475,27 @@ func (s *BadgerStore) SetEvent(event Event) error {
 		return err
 	}
 	// try to add it to the db
-	return s.dbSetEvents([]Event{event})
+	if err := s.dbSetEvents([]Event{event}); err != nil {
+		return err
+	}
+	return s.dbIndexEventTxs(&event)
 }
 
-// ParticipantEvents return all participant events
+// ParticipantEvents return all participant events, those older than the
+// cache read from the database
 func (s *BadgerStore) ParticipantEvents(participant string, skip int64) (EventHashes, error) {
 	res, err := s.inmemStore.ParticipantEvents(participant, skip)
-	if err != nil {
+	if common.Is(err, common.TooLate) {
 		res, err = s.dbParticipantEvents(participant, skip)
 	}
 	return res, err
 }
 
-// ParticipantEvent get specific participant event
+// ParticipantEvent get specific participant event, from the database when
+// it is not in the cache. An unknown participant has none.
 func (s *BadgerStore) ParticipantEvent(participant string, index int64) (EventHash, error) {
 	result, err := s.inmemStore.ParticipantEvent(participant, index)
-	if err != nil {
+	if common.Is(err, common.TooLate) || common.Is(err, common.KeyNotFound) {
 		result, err = s.dbParticipantEvent(participant, index)
 	}
 	return result, mapError(err, "ParticipantEvent", string(participantEventKey(participant, index)))
@@ -338,38 +526,49 @@ func (s *BadgerStore) AddConsensusEvent(event Event) error {
 	return s.inmemStore.AddConsensusEvent(event)
 }
 
+// GetRound gets the round info for a given index
+func (s *BadgerStore) GetRound(r int64) (Round, error) {
+	res, err := s.inmemStore.GetRound(r)
+	if common.Is(err, common.KeyNotFound) {
+		res, err = s.dbGetRound(r)
+	}
+	return res, err
+}
+
+// SetRound sets the round info for a given index
+func (s *BadgerStore) SetRound(r int64, round Round) error {
+	if err := s.inmemStore.SetRound(r, round); err != nil {
+		return err
+	}
+	return s.dbSetRound(r, round)
+}
+
 // GetRoundCreated gets the created round info for a given index
+//
+// Deprecated: use GetRound
 func (s *BadgerStore) GetRoundCreated(r int64) (RoundCreated, error) {
-	res, err := s.inmemStore.GetRoundCreated(r)
-	if err != nil {
-		res, err = s.dbGetRoundCreated(r)
-	}
-	return res, mapError(err, "RoundCreated", string(roundCreatedKey(r)))
+	return getRoundCreated(s, r)
 }
 
 // SetRoundCreated sets the created round info for a given index
+//
+// Deprecated: use SetRound
 func (s *BadgerStore) SetRoundCreated(r int64, round RoundCreated) error {
-	if err := s.inmemStore.SetRoundCreated(r, round); err != nil {
-		return err
-	}
-	return s.dbSetRoundCreated(r, round)
+	return setRoundCreated(s, r, round)
 }
 
 // GetRoundReceived gets the received round for a given index
+//
+// Deprecated: use GetRound
 func (s *BadgerStore) GetRoundReceived(r int64) (RoundReceived, error) {
-	res, err := s.inmemStore.GetRoundReceived(r)
-	if err != nil {
-		res, err = s.dbGetRoundReceived(r)
-	}
-	return res, mapError(err, "RoundReceived", string(roundReceivedKey(r)))
+	return getRoundReceived(s, r)
 }
 
 // SetRoundReceived sets the received round info for a given index
+//
+// Deprecated: use SetRound
 func (s *BadgerStore) SetRoundReceived(r int64, round RoundReceived) error {
-	if err := s.inmemStore.SetRoundReceived(r, round); err != nil {
-		return err
-	}
-	return s.dbSetRoundReceived(r, round)
+	return setRoundReceived(s, r, round)
 }
 
 // LastRound returns the last round for the store
@@ -379,7 +578,7 @@ func (s *BadgerStore) LastRound() int64 {
 
 // RoundClothos returns all clothos for a round
 func (s *BadgerStore) RoundClothos(r int64) EventHashes {
-	round, err := s.GetRoundCreated(r)
+	round, err := s.GetRound(r)
 	if err != nil {
 		return EventHashes{}
 	}
@@ -388,7 +587,7 @@ func (s *BadgerStore) RoundClothos(r int64) EventHashes {
 
 // RoundEvents returns all events for a round
 func (s *BadgerStore) RoundEvents(r int64) int {
-	round, err := s.GetRoundCreated(r)
+	round, err := s.GetRound(r)
 	if err != nil {
 		return 0
 	}
@@ -418,7 +617,285 @@ func (s *BadgerStore) SetBlock(block Block) error {
 	if err := s.inmemStore.SetBlock(block); err != nil {
 		return err
 	}
-	return s.dbSetBlock(block)
+	if err := s.dbSetBlock(block); err != nil {
+		return err
+	}
+	return s.dbIndexBlockTxs(&block)
+}
+
+// GetCheckpoint returns the checkpoint of a frame
+func (s *BadgerStore) GetCheckpoint(frame int64) (Checkpoint, error) {
+	res, err := s.inmemStore.GetCheckpoint(frame)
+	if common.Is(err, common.KeyNotFound) {
+		res = Checkpoint{}
+		key := checkpointKey(frame)
+		if _, err = s.db.Table(CHECKPOINT_TBL).Get(key, &res); err != nil {
+			return Checkpoint{}, mapError(err, "Checkpoint", key)
+		}
+	}
+	return res, err
+}
+
+// SetCheckpoint adds or updates the checkpoint of a frame
+func (s *BadgerStore) SetCheckpoint(checkpoint Checkpoint) error {
+	if err := s.inmemStore.SetCheckpoint(checkpoint); err != nil {
+		return err
+	}
+	return s.db.Table(CHECKPOINT_TBL).Set(checkpointKey(checkpoint.Frame), checkpoint)
+}
+
+// archivedEventsKey is the key of the archived undetermined events
+const archivedEventsKey = "undetermined"
+
+// ArchivedEvents returns the undetermined events archived by the poset. They
+// are only kept in the database, out of memory.
+func (s *BadgerStore) ArchivedEvents() (EventHashes, error) {
+	if !hasTable(s.db, ARCHIVE_TBL) {
+		return nil, nil
+	}
+	var res EventHashes
+	if _, err := s.db.Table(ARCHIVE_TBL).Get(archivedEventsKey, &res); err != nil {
+		if isDBKeyNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// SetArchivedEvents replaces the undetermined events archived by the poset
+func (s *BadgerStore) SetArchivedEvents(hashes EventHashes) error {
+	if !hasTable(s.db, ARCHIVE_TBL) {
+		if err := s.db.NewTable(ARCHIVE_TBL); err != nil {
+			return err
+		}
+	}
+	return s.db.Table(ARCHIVE_TBL).Set(archivedEventsKey, hashes)
+}
+
+// consensusConfigKey is the key of the consensus configuration hash
+const consensusConfigKey = "consensus_config"
+
+// ConsensusConfigHash returns the hash of the consensus configuration the
+// events were accepted with, the zero hash if none was set
+func (s *BadgerStore) ConsensusConfigHash() (common.Hash, error) {
+	if !hasTable(s.db, META_TBL) {
+		return common.Hash{}, nil
+	}
+	var res common.Hash
+	if _, err := s.db.Table(META_TBL).Get(consensusConfigKey, &res); err != nil {
+		if isDBKeyNotFound(err) {
+			return common.Hash{}, nil
+		}
+		return common.Hash{}, err
+	}
+	return res, nil
+}
+
+// SetConsensusConfigHash sets the hash of the consensus configuration
+func (s *BadgerStore) SetConsensusConfigHash(hash common.Hash) error {
+	if !hasTable(s.db, META_TBL) {
+		if err := s.db.NewTable(META_TBL); err != nil {
+			return err
+		}
+	}
+	return s.db.Table(META_TBL).Set(consensusConfigKey, hash)
+}
+
+// blockCarryKey is the key of the transactions carried over past the block
+// of a frame
+func blockCarryKey(frame int64) string {
+	return fmt.Sprintf("block_carry_%09d", frame)
+}
+
+// BlockCarry returns the transactions carried over past the block of a
+// frame into the next block
+func (s *BadgerStore) BlockCarry(frame int64) ([][]byte, error) {
+	if !hasTable(s.db, META_TBL) {
+		return nil, nil
+	}
+	var res [][]byte
+	if _, err := s.db.Table(META_TBL).Get(blockCarryKey(frame), &res); err != nil {
+		if isDBKeyNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+// SetBlockCarry sets the transactions carried over past the block of a
+// frame into the next block
+func (s *BadgerStore) SetBlockCarry(frame int64, txs [][]byte) error {
+	if !hasTable(s.db, META_TBL) {
+		if err := s.db.NewTable(META_TBL); err != nil {
+			return err
+		}
+	}
+	return s.db.Table(META_TBL).Set(blockCarryKey(frame), txs)
+}
+
+// PeerReputations returns what the node learnt about its peers
+func (s *BadgerStore) PeerReputations() (map[string]PeerReputation, error) {
+	res := make(map[string]PeerReputation)
+	if !hasTable(s.db, REPUTATION_TBL) {
+		return res, nil
+	}
+	r := s.db.Table(REPUTATION_TBL).All()
+	for r.Next() {
+		var rec peerReputationRecord
+		if err := r.Decode(&rec); err != nil {
+			return nil, err
+		}
+		res[r.Key()] = rec.reputation()
+	}
+	if r.Error() != cete.ErrEndOfRange {
+		return nil, fmt.Errorf("%v", r.Error())
+	}
+	return res, nil
+}
+
+// SetPeerReputations adds or updates the reputation of peers
+func (s *BadgerStore) SetPeerReputations(reps map[string]PeerReputation) error {
+	if !hasTable(s.db, REPUTATION_TBL) {
+		if err := s.db.NewTable(REPUTATION_TBL); err != nil {
+			return err
+		}
+	}
+	for pubKey, rep := range reps {
+		if err := s.db.Table(REPUTATION_TBL).Set(pubKey, newPeerReputationRecord(rep)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PeerAddresses returns the latest signed network addresses of the
+// participants
+func (s *BadgerStore) PeerAddresses() (map[string]peers.AddrAnnouncement, error) {
+	res := make(map[string]peers.AddrAnnouncement)
+	if !hasTable(s.db, ADDRESS_TBL) {
+		return res, nil
+	}
+	r := s.db.Table(ADDRESS_TBL).All()
+	for r.Next() {
+		var addr peers.AddrAnnouncement
+		if err := r.Decode(&addr); err != nil {
+			return nil, err
+		}
+		res[r.Key()] = addr
+	}
+	if r.Error() != cete.ErrEndOfRange {
+		return nil, fmt.Errorf("%v", r.Error())
+	}
+	return res, nil
+}
+
+// SetPeerAddresses adds or updates the network addresses of participants
+func (s *BadgerStore) SetPeerAddresses(addrs map[string]peers.AddrAnnouncement) error {
+	if !hasTable(s.db, ADDRESS_TBL) {
+		if err := s.db.NewTable(ADDRESS_TBL); err != nil {
+			return err
+		}
+	}
+	for pubKey, addr := range addrs {
+		if err := s.db.Table(ADDRESS_TBL).Set(pubKey, addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lastAcknowledgedKey is the key of the index of the last block the app
+// acknowledged
+const lastAcknowledgedKey = "last_acknowledged_block"
+
+// JournalBlock records a block emitted to the app, until it is acknowledged
+func (s *BadgerStore) JournalBlock(block Block) error {
+	if !hasTable(s.db, JOURNAL_TBL) {
+		if err := s.db.NewTable(JOURNAL_TBL); err != nil {
+			return err
+		}
+	}
+	data, err := block.ProtoMarshal()
+	if err != nil {
+		return err
+	}
+	return s.db.Table(JOURNAL_TBL).Set(checkpointKey(block.Index()), data)
+}
+
+// AcknowledgeBlock records that the app acknowledged the blocks up to index,
+// which leave the journal
+func (s *BadgerStore) AcknowledgeBlock(index int64) error {
+	last, err := s.LastAcknowledgedBlock()
+	if err != nil {
+		return err
+	}
+	if index > last {
+		if !hasTable(s.db, META_TBL) {
+			if err := s.db.NewTable(META_TBL); err != nil {
+				return err
+			}
+		}
+		if err := s.db.Table(META_TBL).Set(lastAcknowledgedKey, index); err != nil {
+			return err
+		}
+	}
+
+	blocks, err := s.JournalledBlocks()
+	if err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		if block.Index() > index {
+			break
+		}
+		if err := s.db.Table(JOURNAL_TBL).Delete(checkpointKey(block.Index())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JournalledBlocks returns the blocks emitted to the app and not
+// acknowledged, in index order
+func (s *BadgerStore) JournalledBlocks() ([]Block, error) {
+	var res []Block
+	if !hasTable(s.db, JOURNAL_TBL) {
+		return res, nil
+	}
+	r := s.db.Table(JOURNAL_TBL).All()
+	for r.Next() {
+		var data []byte
+		if err := r.Decode(&data); err != nil {
+			return nil, err
+		}
+		var block Block
+		if err := block.ProtoUnmarshal(data); err != nil {
+			return nil, err
+		}
+		res = append(res, block)
+	}
+	if r.Error() != cete.ErrEndOfRange {
+		return nil, fmt.Errorf("%v", r.Error())
+	}
+	return res, nil
+}
+
+// LastAcknowledgedBlock returns the index of the last block the app
+// acknowledged, -1 for none
+func (s *BadgerStore) LastAcknowledgedBlock() (int64, error) {
+	if !hasTable(s.db, META_TBL) {
+		return -1, nil
+	}
+	var res int64
+	if _, err := s.db.Table(META_TBL).Get(lastAcknowledgedKey, &res); err != nil {
+		if isDBKeyNotFound(err) {
+			return -1, nil
+		}
+		return -1, err
+	}
+	return res, nil
 }
 
 // LastBlockIndex returns the last block index (height)
@@ -443,13 +920,26 @@ func (s *BadgerStore) SetFrame(frame Frame) error {
 	return s.dbSetFrame(frame)
 }
 
-// Reset all roots
+// Reset all roots. The events, rounds, clotho checks and time tables are
+// dropped from the database as they are from memory, and the roots stored
+// for the store to load with them.
 func (s *BadgerStore) Reset(roots map[string]Root) error {
-	return s.inmemStore.Reset(roots)
+	s.beginMaintenance()
+	defer s.endMaintenance()
+	if err := s.inmemStore.Reset(roots); err != nil {
+		return err
+	}
+	for _, table := range []string{EVENTS_TBL, ROUNDS_TBL, CLOTHOCHK_TBL, CLOTHOCREATORCHK_TBL, TIMETABLE_TBL} {
+		if err := s.dbDropTable(table); err != nil {
+			return err
+		}
+	}
+	return s.dbSetRoots(roots)
 }
 
 // Close badger
 func (s *BadgerStore) Close() error {
+	s.stopValueLogGC()
 	if err := s.inmemStore.Close(); err != nil {
 		return err
 	}
@@ -467,8 +957,12 @@ func (s *BadgerStore) StorePath() string {
 	return s.path
 }
 
-// StateDB returns state database
+// StateDB returns state database, the in-memory one of the cache while the
+// state is not persisted
 func (s *BadgerStore) StateDB() state.Database {
+	if s.states == nil {
+		return s.inmemStore.StateDB()
+	}
 	return s.states
 }
 
@@ -501,6 +995,8 @@ func (s *BadgerStore) dbSetEvents(events []Event) error {
 	return nil
 }
 
+// dbParticipantEvents returns the events of a participant with an index
+// above skip, the ones older than the cache are paged from the database
 func (s *BadgerStore) dbParticipantEvents(participant string, skip int64) (res EventHashes, err error) {
 
 	creator, err := hexutil.Decode(participant)
@@ -508,13 +1004,45 @@ func (s *BadgerStore) dbParticipantEvents(participant string, skip int64) (res E
 		return nil, err
 	}
 
-	r := s.db.Table(EVENTS_TBL).Index(CREATOR_IDX).Between(
-		[]interface{}{creator, cete.MinValue}, []interface{}{creator, cete.MaxValue})
+	events, err := s.dbEvents(s.db.Table(EVENTS_TBL).Index(CREATOR_IDX).Between(
+		[]interface{}{creator, skip + 1}, []interface{}{creator, cete.MaxValue}))
+	if err != nil {
+		return nil, err
+	}
+	archived, err := s.dbArchivedCreatorEvents(creator, skip+1, cete.MaxValue, false)
+	if err != nil {
+		return nil, err
+	}
+	return mergeByIndex(events, archived)
+}
 
+// dbEvents returns the events of an events table range
+func (s *BadgerStore) dbEvents(r *cete.Range) ([]Event, error) {
+	defer r.Close()
+	var res []Event
 	for r.Next() {
-		var result Event
-		r.Decode(&result)
-		res = append(res, result.Hash())
+		var event Event
+		if err := r.Decode(&event); err != nil {
+			return nil, err
+		}
+		res = append(res, event)
+	}
+	if r.Error() != cete.ErrEndOfRange {
+		return nil, fmt.Errorf("%v", r.Error())
+	}
+	return res, nil
+}
+
+// dbEventHashes returns the hashes of the events of an events table range
+func (s *BadgerStore) dbEventHashes(r *cete.Range) (EventHashes, error) {
+	defer r.Close()
+	res := EventHashes{}
+	for r.Next() {
+		var hash EventHash
+		if err := hash.Parse(r.Key()); err != nil {
+			return res, err
+		}
+		res = append(res, hash)
 	}
 	if r.Error() != cete.ErrEndOfRange {
 		return res, fmt.Errorf("%v", r.Error())
@@ -535,149 +1063,105 @@ func (s *BadgerStore) dbParticipantEvent(participant string, index int64) (hash
 
 	if err == nil {
 		hash = result.Hash()
+		return
+	}
+	if isDBKeyNotFound(err) {
+		var archived []archiveEntry
+		archived, err = s.dbArchivedCreatorEvents(creator, index, index, false)
+		if err != nil {
+			return
+		}
+		if len(archived) == 0 {
+			return hash, cete.ErrNotFound
+		}
+		err = hash.Set(archived[0].Hash)
 	}
 	return
 }
 
 func (s *BadgerStore) dbSetRoots(roots map[string]Root) error {
-//	tx := s.db.NewTransaction(true)
-//	defer tx.Discard()
-//	for participant, root := range roots {
-//		val, err := root.ProtoMarshal()
-//		if err != nil {
-//			return err
-//		}
-//		key := participantRootKey(participant)
-//		// fmt.Println("Setting root", participant, "->", key)
-//		// insert [participant_root] => [root bytes]
-//		if err := tx.Set(key, val); err != nil {
-//			return err
-//		}
-//	}
-//	return tx.Commit(nil)
+	if !hasTable(s.db, ROOTS_TBL) {
+		if err := s.db.NewTable(ROOTS_TBL); err != nil {
+			return err
+		}
+	}
+	for participant, root := range roots {
+		val, err := root.ProtoMarshal()
+		if err != nil {
+			return err
+		}
+		// insert [participant_root] => [root bytes]
+		if err := s.db.Table(ROOTS_TBL).Set(string(participantRootKey(participant)), val); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// dbGetRoot returns the stored root of a participant, cete.ErrNotFound for
+// none
 func (s *BadgerStore) dbGetRoot(participant string) (Root, error) {
-//	var rootBytes []byte
-//	key := participantRootKey(participant)
-//	err := s.db.View(func(txn *badger.Txn) error {
-//		item, err := txn.Get(key)
-//		if err != nil {
-//			return err
-//		}
-//		err = item.Value(func(val []byte) error {
-//			rootBytes = val
-//			return nil
-//		})
-//		return err
-//	})
-
-//	if err != nil {
-//		return Root{}, err
-//	}
-
+	if !hasTable(s.db, ROOTS_TBL) {
+		return Root{}, cete.ErrNotFound
+	}
+	var rootBytes []byte
+	if _, err := s.db.Table(ROOTS_TBL).Get(string(participantRootKey(participant)), &rootBytes); err != nil {
+		return Root{}, err
+	}
 	root := new(Root)
-//	if err := root.ProtoUnmarshal(rootBytes); err != nil {
-//		return Root{}, err
-//	}
-
+	if err := root.ProtoUnmarshal(rootBytes); err != nil {
+		return Root{}, err
+	}
 	return *root, nil
 }
 
-func (s *BadgerStore) dbGetRoundCreated(index int64) (RoundCreated, error) {
-//	var roundBytes []byte
-//	key := roundCreatedKey(index)
-//	err := s.db.View(func(txn *badger.Txn) error {
-//		item, err := txn.Get(key)
-//		if err != nil {
-//			return err
-//		}
-//		err = item.Value(func(val []byte) error {
-//			roundBytes = val
-//			return nil
-//		})
-//		return err
-//	})
-
-//	if err != nil {
-//		return *NewRoundCreated(), err
-//	}
-
-	roundInfo := new(RoundCreated)
-//	if err := roundInfo.ProtoUnmarshal(roundBytes); err != nil {
-//		return *NewRoundCreated(), err
-//	}
+func (s *BadgerStore) dbGetRound(index int64) (Round, error) {
+	var data []byte
+	key := roundKey(index)
+	if _, err := s.db.Table(ROUNDS_TBL).Get(key, &data); err != nil {
+		return *NewRound(), mapError(err, "Round", key)
+	}
+
+	round := NewRound()
+	if err := round.ProtoUnmarshal(data); err != nil {
+		return *NewRound(), err
+	}
 	// In the current design, Queued field must be re-calculated every time for
 	// each round. When retrieving a round info from a database, this field
 	// should be ignored.
-	roundInfo.Message.Queued = false
+	round.Message.Queued = false
 
-	return *roundInfo, nil
-}
-
-func (s *BadgerStore) dbSetRoundCreated(index int64, round RoundCreated) error {
-//	tx := s.db.NewTransaction(true)
-//	defer tx.Discard()
-//
-//	key := roundCreatedKey(index)
-//	val, err := round.ProtoMarshal()
-//	if err != nil {
-//		return err
-//	}
-
-	// insert [round_index] => [round bytes]
-//	if err := tx.Set(key, val); err != nil {
-//		return err
-//	}
-
-//	return tx.Commit(nil)
-	return nil
+	return *round, nil
 }
 
-func (s *BadgerStore) dbGetRoundReceived(index int64) (RoundReceived, error) {
-//	var roundBytes []byte
-//	key := roundReceivedKey(index)
-//	err := s.db.View(func(txn *badger.Txn) error {
-//		item, err := txn.Get(key)
-//		if err != nil {
-//			return err
-//		}
-//		err = item.Value(func(val []byte) error {
-//			roundBytes = val
-//			return nil
-//		})
-//		return err
-//	})
-//
-//	if err != nil {
-//		return *NewRoundReceived(), err
-//	}
-
-	roundInfo := new(RoundReceived)
-//	if err := roundInfo.ProtoUnmarshal(roundBytes); err != nil {
-//		return *NewRoundReceived(), err
-//	}
-
-	return *roundInfo, nil
+func (s *BadgerStore) dbSetRound(index int64, round Round) error {
+	data, err := round.ProtoMarshal()
+	if err != nil {
+		return err
+	}
+	return s.db.Table(ROUNDS_TBL).Set(roundKey(index), data)
 }
 
-func (s *BadgerStore) dbSetRoundReceived(index int64, round RoundReceived) error {
-//	tx := s.db.NewTransaction(true)
-//	defer tx.Discard()
-//
-//	key := roundReceivedKey(index)
-//	val, err := round.ProtoMarshal()
-//	if err != nil {
-//		return err
-//	}
-
-	// insert [round_index] => [round bytes]
-//	if err := tx.Set(key, val); err != nil {
-//		return err
-//	}
-
-//	return tx.Commit(nil)
+// dbDropTable deletes all the records of a table
+func (s *BadgerStore) dbDropTable(table string) error {
+	if !hasTable(s.db, table) {
+		return nil
+	}
+	var keys []string
+	r := s.db.Table(table).All()
+	for r.Next() {
+		keys = append(keys, r.Key())
+	}
+	err := r.Error()
+	r.Close()
+	if err != nil && err != cete.ErrEndOfRange {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.db.Table(table).Delete(key); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -708,96 +1192,64 @@ func (s *BadgerStore) dbSetParticipants(participants *peers.Peers) error {
 	return nil
 }
 
+// dbGetBlock returns the stored block of an index, cete.ErrNotFound for none
 func (s *BadgerStore) dbGetBlock(index int64) (Block, error) {
-//	var blockBytes []byte
-//	key := blockKey(index)
-//	err := s.db.View(func(txn *badger.Txn) error {
-//		item, err := txn.Get(key)
-//		if err != nil {
-//			return err
-//		}
-//		err = item.Value(func(val []byte) error {
-//			blockBytes = val
-//			return nil
-//		})
-//		return err
-//	})
-
-//	if err != nil {
-//		return Block{}, err
-//	}
-
+	if !hasTable(s.db, BLOCKS_TBL) {
+		return Block{}, cete.ErrNotFound
+	}
+	var blockBytes []byte
+	if _, err := s.db.Table(BLOCKS_TBL).Get(string(blockKey(index)), &blockBytes); err != nil {
+		return Block{}, err
+	}
 	block := new(Block)
-//	if err := block.ProtoUnmarshal(blockBytes); err != nil {
-//		return Block{}, err
-//	}
-
+	if err := block.ProtoUnmarshal(blockBytes); err != nil {
+		return Block{}, err
+	}
 	return *block, nil
 }
 
 func (s *BadgerStore) dbSetBlock(block Block) error {
-//	tx := s.db.NewTransaction(true)
-//	defer tx.Discard()
-//
-//	key := blockKey(block.Index())
-//	val, err := block.ProtoMarshal()
-//	if err != nil {
-//		return err
-//	}
-//
+	if !hasTable(s.db, BLOCKS_TBL) {
+		if err := s.db.NewTable(BLOCKS_TBL); err != nil {
+			return err
+		}
+	}
+	val, err := block.ProtoMarshal()
+	if err != nil {
+		return err
+	}
 	// insert [index] => [block bytes]
-//	if err := tx.Set(key, val); err != nil {
-//		return err
-//	}
-
-//	return tx.Commit(nil)
-	return nil
+	return s.db.Table(BLOCKS_TBL).Set(string(blockKey(block.Index())), val)
 }
 
+// dbGetFrame returns the stored frame of a round, cete.ErrNotFound for none
 func (s *BadgerStore) dbGetFrame(index int64) (Frame, error) {
-//	var frameBytes []byte
-//	key := frameKey(index)
-//	err := s.db.View(func(txn *badger.Txn) error {
-//		item, err := txn.Get(key)
-//		if err != nil {
-//			return err
-//		}
-//		err = item.Value(func(val []byte) error {
-//			frameBytes = val
-//			return nil
-//		})
-//		return err
-//	})
-
-//	if err != nil {
-//		return Frame{}, err
-//	}
-
+	if !hasTable(s.db, FRAMES_TBL) {
+		return Frame{}, cete.ErrNotFound
+	}
+	var frameBytes []byte
+	if _, err := s.db.Table(FRAMES_TBL).Get(string(frameKey(index)), &frameBytes); err != nil {
+		return Frame{}, err
+	}
 	frame := new(Frame)
-//	if err := frame.ProtoUnmarshal(frameBytes); err != nil {
-//		return Frame{}, err
-//	}
-
+	if err := frame.ProtoUnmarshal(frameBytes); err != nil {
+		return Frame{}, err
+	}
 	return *frame, nil
 }
 
 func (s *BadgerStore) dbSetFrame(frame Frame) error {
-//	tx := s.db.NewTransaction(true)
-//	defer tx.Discard()
-//
-//	key := frameKey(frame.Round)
-//	val, err := frame.ProtoMarshal()
-//	if err != nil {
-//		return err
-//	}
-
-	// insert [index] => [block bytes]
-//	if err := tx.Set(key, val); err != nil {
-//		return err
-//	}
-
-//	return tx.Commit(nil)
-	return nil
+	if !hasTable(s.db, FRAMES_TBL) {
+		if err := s.db.NewTable(FRAMES_TBL); err != nil {
+			return err
+		}
+	}
+	val, err := frame.ProtoMarshal()
+	if err != nil {
+		return err
+	}
+	// insert [round] => [frame bytes]
+	return s.db.Table(FRAMES_TBL).Set(string(frameKey(frame.Round)), val)
 }
 
 // ++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
@@ -822,7 +1274,7 @@ func (s *BadgerStore) GetClothoCheck(frame int64, hash EventHash) (EventHash, er
 	if err != nil {
 		res, err = s.dbGetClothoCheck(frame, hash)
 	}
-	return res, err // mapError(err, "badger_store GetClothoCheck", string(checkClothoKey(frame, hash)))
+	return res, mapError(err, "ClothoCheck", checkClothoKey(frame, hash))
 }
 
 // GetClothoCreatorCheck retrieves EventHash by frame + creator
@@ -831,7 +1283,7 @@ func (s *BadgerStore) GetClothoCreatorCheck(frame int64, creatorID uint64) (Even
 	if err != nil {
 		res, err = s.dbGetClothoCreatorCheck(frame, creatorID)
 	}
-	return res, err // mapError(err, "badger_store GetClothoCreatorCheck", string(checkClothoCreatorKey(frame, hash)))
+	return res, mapError(err, "ClothoCreatorCheck", checkClothoCreatorKey(frame, creatorID))
 }
 
 // AddClothoCheck to store
@@ -881,46 +1333,72 @@ func (s *BadgerStore) dbAddClothoCheck(frame int64, creatorID uint64, hash Event
 	return nil
 }
 
+// NewTimeTable creates the empty time table of a root
+func (s *BadgerStore) NewTimeTable(frame int64, hash EventHash) error {
+	if err := s.inmemStore.NewTimeTable(frame, hash); err != nil {
+		return err
+	}
+	return s.dbSetTimeTable(frame, hash)
+}
+
 // AddTimeTable adds lamport timestamp for pair of events for voting in atropos time selection
-func (s *BadgerStore) AddTimeTable(hashTo EventHash, hashFrom EventHash, lamportTime int64) error {
-	if err := s.inmemStore.AddTimeTable(hashTo, hashFrom, lamportTime); err != nil {
+func (s *BadgerStore) AddTimeTable(frame int64, hashTo EventHash, hashFrom EventHash, lamportTime int64) error {
+	if err := s.inmemStore.AddTimeTable(frame, hashTo, hashFrom, lamportTime); err != nil {
 		return err
 	}
-	return s.dbAddTimeTable(hashTo, hashFrom, lamportTime)
+	return s.dbSetTimeTable(frame, hashTo)
 }
 
 // GetTimeTable retrieve FlagTable with lamport time votes in atropos time selection for specified EventHash
-func (s *BadgerStore) GetTimeTable(hash EventHash) (FlagTable, error) {
-	res, err := s.inmemStore.GetTimeTable(hash)
-	if err != nil {
-		res, err = s.dbGetTimeTable(hash)
+func (s *BadgerStore) GetTimeTable(frame int64, hash EventHash) (FlagTable, error) {
+	res, err := s.inmemStore.GetTimeTable(frame, hash)
+	if common.Is(err, common.KeyNotFound) {
+		// not loaded since the store was opened
+		res, err = s.dbGetTimeTable(frame, hash)
 	}
-	return res, err // mapError(err, "badger_store GetTimeTable", string(timeTableKey(hash)))
+	return res, err
 }
 
-func (s *BadgerStore) dbAddTimeTable(hashTo EventHash, hashFrom EventHash, lamportTime int64) error {
-	ft := NewFlagTable()
-	key := timeTableKey(hashTo)
-	_, err := s.db.Table(TIMETABLE_TBL).Get(key, &ft)
-	if err != cete.ErrNotFound {
+// DropTimeTables removes the time tables of the frames before beforeFrame
+func (s *BadgerStore) DropTimeTables(beforeFrame int64) error {
+	s.beginMaintenance()
+	defer s.endMaintenance()
+	if err := s.inmemStore.DropTimeTables(beforeFrame); err != nil {
 		return err
 	}
 
-	ft[hashFrom] = lamportTime
-
-	if err := s.db.Table(TIMETABLE_TBL).Set(key, ft); err != nil {
+	var keys []string
+	r := s.db.Table(TIMETABLE_TBL).Between(cete.MinValue, timeTableFrameKey(beforeFrame))
+	for r.Next() {
+		keys = append(keys, r.Key())
+	}
+	err := r.Error()
+	r.Close()
+	if err != nil && err != cete.ErrEndOfRange {
 		return err
 	}
-
+	for _, key := range keys {
+		if err := s.db.Table(TIMETABLE_TBL).Delete(key); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (s *BadgerStore) dbGetTimeTable(hash EventHash) (FlagTable, error) {
+// dbSetTimeTable writes the in-memory time table of a root
+func (s *BadgerStore) dbSetTimeTable(frame int64, hash EventHash) error {
+	ft, err := s.inmemStore.GetTimeTable(frame, hash)
+	if err != nil {
+		return err
+	}
+	return s.db.Table(TIMETABLE_TBL).Set(timeTableKey(frame, hash), ft)
+}
+
+func (s *BadgerStore) dbGetTimeTable(frame int64, hash EventHash) (FlagTable, error) {
 	ft := NewFlagTable()
-	key := timeTableKey(hash)
-	_, err := s.db.Table(TIMETABLE_TBL).Get(key, &ft)
-	if err != cete.ErrNotFound {
-		return nil, err
+	key := timeTableKey(frame, hash)
+	if _, err := s.db.Table(TIMETABLE_TBL).Get(key, &ft); err != nil {
+		return nil, mapError(err, "TimeTable", key)
 	}
 	return ft, nil
 }
@@ -935,31 +1413,49 @@ func (s *BadgerStore) CheckFrameFinality(frame int64) bool {
 	return false
 }
 
-func (s *BadgerStore) ProcessOutFrame(frame int64, address string) ([][]byte, error) {
-	file, err := os.OpenFile(fmt.Sprintf("Node_%v.finality", address), os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0644)
-	if err != nil {
-		fmt.Printf("*** Open  err: %v", err)
-		return nil, err
-	}
-	defer file.Close()
+// SetFinalityLog sets the file ProcessOutFrame logs the final events to, an
+// empty path logging nothing
+func (s *BadgerStore) SetFinalityLog(path string) {
+	s.finalityLog = path
+}
 
-	var transactions [][]byte
+// ProcessOutFrame returns the transactions of the loaded events received in a
+// final frame, logging the events when a finality log is set
+func (s *BadgerStore) ProcessOutFrame(frame int64) ([][]byte, error) {
+	file := ioutil.Discard
+	if s.finalityLog != "" {
+		f, err := os.OpenFile(s.finalityLog, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		file = f
+	}
 
+	var events []Event
 	r := s.db.Table(EVENTS_TBL).Index(SORT_IDX).Between(
 		[]interface{}{frame, cete.MinValue, cete.MinValue, cete.MinValue},
 		[]interface{}{frame, cete.MaxValue, cete.MaxValue, cete.MaxValue})
 	for r.Next() {
 		var ev Event
 		r.Decode(&ev)
+		events = append(events, ev)
+	}
+	if r.Error() != cete.ErrEndOfRange {
+		return nil, fmt.Errorf("%v", r.Error())
+	}
+	// the index already is in this order, the comparator is what
+	// GetFrameOrdering reports
+	sort.Sort(ByFinalOrder(events))
+
+	var bodies []*EventBody
+	for _, ev := range events {
 		if ev.IsLoaded() {
 			hash := ev.Hash()
 			fmt.Fprintf(file, "%v:%v:%v:%v:%v\n",
 				hash.String(), ev.Frame, ev.FrameReceived, ev.LamportTimestamp, ev.AtroposTimestamp)
-			transactions = append(transactions, ev.Message.Body.Transactions...)
+			bodies = append(bodies, ev.Message.Body)
 		}
 	}
-	if r.Error() != cete.ErrEndOfRange {
-		return nil, fmt.Errorf("%v", r.Error())
-	}
-	return transactions, nil
+	return orderedTransactions(bodies), nil
 }
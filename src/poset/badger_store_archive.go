@@ -0,0 +1,403 @@
+package poset
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/1lann/cete"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/common/hexutil"
+)
+
+const (
+	// ARCHIVEINDEX_TBL indexes the archived events, keyed by hash, once
+	// they are deleted from EVENTS_TBL
+	ARCHIVEINDEX_TBL = "archive_index"
+	// ARCHIVECREATOR_IDX and ARCHIVESORT_IDX stand for CREATOR_IDX and
+	// SORT_IDX for the archived events
+	ARCHIVECREATOR_IDX = "Creator,Index"
+	ARCHIVESORT_IDX    = "Frame,LamportTimestamp,AtroposTimestamp,Hash"
+)
+
+// archivedFramesKey is the key of the range of the archived frames
+const archivedFramesKey = "archived_frames"
+
+// archivedFrames is the range of the archived frames, the events received in
+// them are read from the archive only
+type archivedFrames struct {
+	First int64
+	Last  int64
+}
+
+// archiveEntry is the record of ARCHIVEINDEX_TBL of an archived event, with
+// what the event indexes of EVENTS_TBL are made of
+type archiveEntry struct {
+	FrameReceived    int64
+	Creator          []byte
+	Index            int64
+	Frame            int64
+	LamportTimestamp int64
+	AtroposTimestamp int64
+	Hash             []byte
+}
+
+func newArchiveEntry(event Event) archiveEntry {
+	hash := event.Hash()
+	return archiveEntry{
+		FrameReceived:    event.FrameReceived,
+		Creator:          event.Message.Body.Creator,
+		Index:            event.Message.Body.Index,
+		Frame:            event.Frame,
+		LamportTimestamp: event.LamportTimestamp,
+		AtroposTimestamp: event.AtroposTimestamp,
+		Hash:             hash.Bytes(),
+	}
+}
+
+// sortsBefore orders the entries as SORT_IDX orders the events
+func (e archiveEntry) sortsBefore(o archiveEntry) bool {
+	if e.Frame != o.Frame {
+		return e.Frame < o.Frame
+	}
+	if e.LamportTimestamp != o.LamportTimestamp {
+		return e.LamportTimestamp < o.LamportTimestamp
+	}
+	if e.AtroposTimestamp != o.AtroposTimestamp {
+		return e.AtroposTimestamp < o.AtroposTimestamp
+	}
+	return bytes.Compare(e.Hash, o.Hash) < 0
+}
+
+// archiveCache keeps the events of the frame read last from the archive, a
+// peer syncing from far behind asks for many events of the same frame
+type archiveCache struct {
+	sync.Mutex
+	frame  int64
+	events []Event
+}
+
+// SetArchive makes the store archive the final frames to sink, and read the
+// events missing from the database back from it
+func (s *BadgerStore) SetArchive(sink ArchiveSink) {
+	s.archive = sink
+	s.archiveCache = &archiveCache{frame: -1}
+}
+
+// ArchivedFrames implements FrameArchive
+func (s *BadgerStore) ArchivedFrames() (first, last int64, err error) {
+	if s.archive == nil || !hasTable(s.db, META_TBL) {
+		return 0, -1, nil
+	}
+	var r archivedFrames
+	if _, err := s.db.Table(META_TBL).Get(archivedFramesKey, &r); err != nil {
+		if isDBKeyNotFound(err) {
+			return 0, -1, nil
+		}
+		return 0, -1, err
+	}
+	return r.First, r.Last, nil
+}
+
+// ArchiveFrame writes the events received in a final frame to the archive,
+// when the store has one, and deletes them from the database once the file
+// and its manifest are written. The frames after the last one archived whose
+// archiving failed are archived first: the archived events must all be older
+// than the ones of the database.
+func (s *BadgerStore) ArchiveFrame(frame int64) error {
+	if s.archive == nil {
+		return nil
+	}
+	first, last, err := s.ArchivedFrames()
+	if err != nil {
+		return err
+	}
+	if first <= last && frame <= last {
+		// the events of an archived frame were set again, as the store
+		// bootstraps
+		return s.dbDeleteArchivedEvents(frame)
+	}
+	from := frame
+	if first <= last {
+		from = last + 1
+	} else {
+		first = frame
+	}
+	for f := from; f <= frame; f++ {
+		if err := s.archiveFrame(f); err != nil {
+			return err
+		}
+		if !hasTable(s.db, META_TBL) {
+			if err := s.db.NewTable(META_TBL); err != nil {
+				return err
+			}
+		}
+		if err := s.db.Table(META_TBL).Set(archivedFramesKey, archivedFrames{First: first, Last: f}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveFrame archives the events of the database received in a frame, a
+// frame without events gets no file
+func (s *BadgerStore) archiveFrame(frame int64) error {
+	hashes, err := s.dbEventHashes(s.db.Table(EVENTS_TBL).Index(FRAMERECEIVED_IDX).Between(frame, frame))
+	if err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	events := make([]Event, 0, len(hashes))
+	for _, hash := range hashes {
+		event, err := s.dbGetEventBlock(hash)
+		if err != nil {
+			return mapError(err, "Event", hash.String())
+		}
+		events = append(events, event)
+	}
+	if err := s.archive.WriteEvents(frame, events); err != nil {
+		return fmt.Errorf("archiving frame %d: %v", frame, err)
+	}
+
+	if !hasTable(s.db, ARCHIVEINDEX_TBL) {
+		if err := s.db.NewTable(ARCHIVEINDEX_TBL); err != nil {
+			return err
+		}
+		for _, idx := range []string{ARCHIVECREATOR_IDX, ARCHIVESORT_IDX} {
+			if err := s.db.Table(ARCHIVEINDEX_TBL).NewIndex(idx); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range events {
+		if err := s.db.Table(ARCHIVEINDEX_TBL).Set(hashes[i].String(), newArchiveEntry(events[i])); err != nil {
+			return err
+		}
+	}
+	for _, hash := range hashes {
+		if err := s.db.Table(EVENTS_TBL).Delete(hash.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbDeleteArchivedEvents deletes from the database the archived events
+// received in a frame
+func (s *BadgerStore) dbDeleteArchivedEvents(frame int64) error {
+	hashes, err := s.dbEventHashes(s.db.Table(EVENTS_TBL).Index(FRAMERECEIVED_IDX).Between(frame, frame))
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if !s.dbIsArchived(hash) {
+			continue
+		}
+		if err := s.db.Table(EVENTS_TBL).Delete(hash.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbIsArchived returns true for an event of the archive index
+func (s *BadgerStore) dbIsArchived(hash EventHash) bool {
+	if !hasTable(s.db, ARCHIVEINDEX_TBL) {
+		return false
+	}
+	var entry archiveEntry
+	_, err := s.db.Table(ARCHIVEINDEX_TBL).Get(hash.String(), &entry)
+	return err == nil
+}
+
+// archivedEvent reads an event from the archive
+func (s *BadgerStore) archivedEvent(hash EventHash) (Event, error) {
+	if s.archive == nil || !hasTable(s.db, ARCHIVEINDEX_TBL) {
+		return Event{}, cete.ErrNotFound
+	}
+	var entry archiveEntry
+	if _, err := s.db.Table(ARCHIVEINDEX_TBL).Get(hash.String(), &entry); err != nil {
+		return Event{}, err
+	}
+	events, err := s.archivedFrame(entry.FrameReceived)
+	if err != nil {
+		return Event{}, err
+	}
+	for _, event := range events {
+		if event.Hash() == hash {
+			return event, nil
+		}
+	}
+	return Event{}, cete.ErrNotFound
+}
+
+// archivedFrame reads the events of a frame from the archive, the last frame
+// read is cached
+func (s *BadgerStore) archivedFrame(frame int64) ([]Event, error) {
+	c := s.archiveCache
+	c.Lock()
+	defer c.Unlock()
+	if c.frame == frame {
+		return c.events, nil
+	}
+	events, err := s.archive.ReadEvents(frame)
+	if err != nil {
+		return nil, err
+	}
+	c.frame, c.events = frame, events
+	return events, nil
+}
+
+// ForEachFrameEvent implements FrameArchive. The archived frames are read
+// from the archive, their events are gone from the database.
+func (s *BadgerStore) ForEachFrameEvent(fromFrame, toFrame int64, fn func(Event) bool) error {
+	for frame := fromFrame; frame <= toFrame; frame++ {
+		var events []Event
+		var err error
+		if s.archive != nil {
+			events, err = s.archivedFrame(frame)
+		}
+		if s.archive == nil || os.IsNotExist(err) {
+			events, err = s.frameReceivedEvents(frame)
+		}
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if !fn(event) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// ArchivedEventFrame implements FrameArchive
+func (s *BadgerStore) ArchivedEventFrame(participant string, index int64) (int64, error) {
+	creator, err := hexutil.Decode(participant)
+	if err != nil {
+		return 0, err
+	}
+	archived, err := s.dbArchivedCreatorEvents(creator, index, index, false)
+	if err != nil {
+		return 0, err
+	}
+	if len(archived) == 0 {
+		return 0, common.NewStoreErr("ArchivedEvent", common.KeyNotFound,
+			string(participantEventKey(participant, index)))
+	}
+	return archived[0].FrameReceived, nil
+}
+
+// forEachArchivedEventFrom calls fn with the events of the archived frames,
+// frame by frame and in topological order within a frame, until fn returns
+// false. It returns false when fn did.
+func (s *BadgerStore) forEachArchivedEventFrom(first, last int64, fn func(Event) bool) (bool, error) {
+	for frame := first; frame <= last; frame++ {
+		archived, err := s.archivedFrame(frame)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		events := make([]Event, len(archived))
+		copy(events, archived)
+		sort.Sort(ByTopologicalOrder(events))
+		for _, event := range events {
+			if !fn(event) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// dbArchivedCreatorEvents returns the archived events of a creator with an
+// index from fromIndex to toIndex included, by index
+func (s *BadgerStore) dbArchivedCreatorEvents(creator []byte, fromIndex, toIndex interface{},
+	reverse bool) ([]archiveEntry, error) {
+	if !hasTable(s.db, ARCHIVEINDEX_TBL) {
+		return nil, nil
+	}
+	r := s.db.Table(ARCHIVEINDEX_TBL).Index(ARCHIVECREATOR_IDX).Between(
+		[]interface{}{creator, fromIndex}, []interface{}{creator, toIndex}, reverse)
+	return s.dbArchiveEntries(r)
+}
+
+// dbArchivedRoundEvents returns the archived events created in a round from
+// fromRound to toRound included, ordered as SORT_IDX orders them
+func (s *BadgerStore) dbArchivedRoundEvents(fromRound, toRound int64) ([]archiveEntry, error) {
+	if !hasTable(s.db, ARCHIVEINDEX_TBL) {
+		return nil, nil
+	}
+	r := s.db.Table(ARCHIVEINDEX_TBL).Index(ARCHIVESORT_IDX).Between(
+		[]interface{}{fromRound, cete.MinValue, cete.MinValue, cete.MinValue},
+		[]interface{}{toRound, cete.MaxValue, cete.MaxValue, cete.MaxValue})
+	return s.dbArchiveEntries(r)
+}
+
+func (s *BadgerStore) dbArchiveEntries(r *cete.Range) ([]archiveEntry, error) {
+	defer r.Close()
+	var res []archiveEntry
+	for r.Next() {
+		var entry archiveEntry
+		if err := r.Decode(&entry); err != nil {
+			return nil, err
+		}
+		res = append(res, entry)
+	}
+	if r.Error() != cete.ErrEndOfRange {
+		return nil, fmt.Errorf("%v", r.Error())
+	}
+	return res, nil
+}
+
+// mergeByIndex merges the hashes of events of a creator of the database and
+// of the archive, by index. An event found in both is kept once.
+func mergeByIndex(db []Event, archived []archiveEntry) (EventHashes, error) {
+	byIndex := make(map[int64]EventHash, len(db)+len(archived))
+	for _, entry := range archived {
+		var hash EventHash
+		if err := hash.Set(entry.Hash); err != nil {
+			return nil, err
+		}
+		byIndex[entry.Index] = hash
+	}
+	for _, event := range db {
+		byIndex[event.Index()] = event.Hash()
+	}
+	indexes := make([]int64, 0, len(byIndex))
+	for index := range byIndex {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	res := make(EventHashes, len(indexes))
+	for i, index := range indexes {
+		res[i] = byIndex[index]
+	}
+	return res, nil
+}
+
+// frameReceivedEvents returns the events of the database received in a frame
+func (s *BadgerStore) frameReceivedEvents(frame int64) ([]Event, error) {
+	r := s.db.Table(EVENTS_TBL).Index(FRAMERECEIVED_IDX).Between(frame, frame)
+	defer r.Close()
+	var events []Event
+	for r.Next() {
+		var event Event
+		if err := r.Decode(&event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if r.Error() != cete.ErrEndOfRange {
+		return nil, fmt.Errorf("%v", r.Error())
+	}
+	return events, nil
+}
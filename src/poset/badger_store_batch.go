@@ -0,0 +1,60 @@
+package poset
+
+// pendingBatchKey is the key of the batch being committed, in the meta table
+const pendingBatchKey = "pending_batch"
+
+// CommitBatch journals the writes of a batch in a single database write
+// before applying them, so that a batch cut short by a crash is applied
+// again, whole, when the store is loaded
+func (s *BadgerStore) CommitBatch(batch *StoreBatch) error {
+	if err := s.db.Table(META_TBL).Set(pendingBatchKey, batch.ops); err != nil {
+		return err
+	}
+	if err := applyBatchOps(s, batch.ops); err != nil {
+		return err
+	}
+	return s.db.Table(META_TBL).Delete(pendingBatchKey)
+}
+
+// dbReplayBatch applies to the database the batch journalled by a
+// CommitBatch cut short, if any. The consensus events are left out: they
+// are not persisted, the Bootstrap finds them again.
+func (s *BadgerStore) dbReplayBatch() error {
+	var ops []batchOp
+	if _, err := s.db.Table(META_TBL).Get(pendingBatchKey, &ops); err != nil {
+		if isDBKeyNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, op := range ops {
+		var err error
+		switch op.Kind {
+		case batchSetEvent:
+			if err = s.dbSetEvents([]Event{op.Event}); err == nil {
+				err = s.dbIndexEventTxs(&op.Event)
+			}
+		case batchSetRound:
+			var round Round
+			if err = round.ProtoUnmarshal(op.Round); err == nil {
+				err = s.dbSetRound(op.Frame, round)
+			}
+		case batchAddClothoCheck:
+			err = s.dbAddClothoCheck(op.Frame, op.CreatorID, op.Hash)
+		case batchNewTimeTable, batchAddTimeTable:
+			ft, getErr := s.dbGetTimeTable(op.Frame, op.Hash)
+			if getErr != nil {
+				ft = NewFlagTable()
+			}
+			if op.Kind == batchAddTimeTable {
+				ft[op.From] = op.Lamport
+			}
+			err = s.db.Table(TIMETABLE_TBL).Set(timeTableKey(op.Frame, op.Hash), ft)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return s.db.Table(META_TBL).Delete(pendingBatchKey)
+}
@@ -0,0 +1,235 @@
+package poset
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/1lann/cete"
+	"github.com/dgraph-io/badger"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrValueLogGCUnsupported is returned by RunValueLogGC when no badger
+// database is found under the database handle
+var ErrValueLogGCUnsupported = errors.New("value log GC unsupported by the database")
+
+// DefaultValueLogGCDiscardRatio is the share of discarded space of a value
+// log file above which the GC rewrites it
+const DefaultValueLogGCDiscardRatio = 0.5
+
+// ValueLogGCResult is the outcome of a value log GC
+type ValueLogGCResult struct {
+	// Rewritten is the number of value log files rewritten
+	Rewritten int `json:"rewritten"`
+	// Reclaimed is the shrinking of the database on disk, in bytes
+	Reclaimed int64         `json:"reclaimed"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// ValueLogGCStats are the totals of the value log GCs of a store
+type ValueLogGCStats struct {
+	Runs      uint64        `json:"runs"`
+	Skipped   uint64        `json:"skipped"`
+	Rewritten uint64        `json:"rewritten"`
+	Reclaimed int64         `json:"reclaimed"`
+	Duration  time.Duration `json:"duration"`
+	Last      time.Time     `json:"last"`
+}
+
+// valueLogGC is the state of the value log GC of a store
+type valueLogGC struct {
+	sync.Mutex
+	discardRatio float64
+	stats        ValueLogGCStats
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// StartValueLogGC runs the value log GC every interval in the background,
+// until Close. A turn coming while Reset or DropTimeTables run is skipped.
+// The discard ratio is also that of RunValueLogGC.
+func (s *BadgerStore) StartValueLogGC(interval time.Duration, discardRatio float64, logger logrus.FieldLogger) {
+	s.gc.Lock()
+	s.gc.discardRatio = discardRatio
+	if s.gc.stop != nil || interval <= 0 {
+		s.gc.Unlock()
+		return
+	}
+	s.gc.stop = make(chan struct{})
+	s.gc.done = make(chan struct{})
+	stop, done := s.gc.stop, s.gc.done
+	s.gc.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			if atomic.LoadInt32(&s.pruning) != 0 {
+				s.gc.Lock()
+				s.gc.stats.Skipped++
+				s.gc.Unlock()
+				continue
+			}
+			res, err := s.RunValueLogGC()
+			if err != nil {
+				logger.WithError(err).Warn("Value log GC")
+				continue
+			}
+			logger.WithFields(logrus.Fields{
+				"rewritten": res.Rewritten,
+				"reclaimed": res.Reclaimed,
+				"duration":  res.Duration,
+			}).Debug("Value log GC")
+		}
+	}()
+}
+
+// stopValueLogGC stops the background value log GC and waits for its turn
+// in progress
+func (s *BadgerStore) stopValueLogGC() {
+	s.gc.Lock()
+	stop, done := s.gc.stop, s.gc.done
+	s.gc.stop, s.gc.done = nil, nil
+	s.gc.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// RunValueLogGC rewrites the value log files with more discarded space than
+// the discard ratio, until none is left, and returns how much the database
+// shrank on disk. It waits for Reset or DropTimeTables to end.
+func (s *BadgerStore) RunValueLogGC() (ValueLogGCResult, error) {
+	s.gc.Lock()
+	discardRatio := s.gc.discardRatio
+	s.gc.Unlock()
+	if discardRatio <= 0 || discardRatio >= 1 {
+		discardRatio = DefaultValueLogGCDiscardRatio
+	}
+
+	s.maintenance.Lock()
+	defer s.maintenance.Unlock()
+	handles := badgerHandles(s.db)
+	if len(handles) == 0 {
+		return ValueLogGCResult{}, ErrValueLogGCUnsupported
+	}
+
+	start := time.Now()
+	before := dirSize(s.path)
+	var res ValueLogGCResult
+	for _, handle := range handles {
+		for {
+			err := handle.RunValueLogGC(discardRatio)
+			if err == badger.ErrNoRewrite {
+				break
+			}
+			if err != nil {
+				return res, err
+			}
+			res.Rewritten++
+		}
+	}
+	res.Duration = time.Since(start)
+	if after := dirSize(s.path); after < before {
+		res.Reclaimed = before - after
+	}
+
+	s.gc.Lock()
+	s.gc.stats.Runs++
+	s.gc.stats.Rewritten += uint64(res.Rewritten)
+	s.gc.stats.Reclaimed += res.Reclaimed
+	s.gc.stats.Duration += res.Duration
+	s.gc.stats.Last = start
+	s.gc.Unlock()
+	return res, nil
+}
+
+// ValueLogGCStats returns the totals of the value log GCs of the store
+func (s *BadgerStore) ValueLogGCStats() ValueLogGCStats {
+	s.gc.Lock()
+	defer s.gc.Unlock()
+	return s.gc.stats
+}
+
+// beginMaintenance keeps the value log GC out of the operations deleting
+// many keys, the background one skips its turn meanwhile
+func (s *BadgerStore) beginMaintenance() {
+	atomic.AddInt32(&s.pruning, 1)
+	s.maintenance.Lock()
+}
+
+// endMaintenance ends what beginMaintenance began
+func (s *BadgerStore) endMaintenance() {
+	s.maintenance.Unlock()
+	atomic.AddInt32(&s.pruning, -1)
+}
+
+// badgerDBType is the type of the badger databases badgerHandles looks for
+var badgerDBType = reflect.TypeOf((*badger.DB)(nil))
+
+// badgerHandles returns the badger databases under a cete handle. cete opens
+// one for the data of each table and one for each index, and keeps them
+// unexported, so they are found by walking its fields.
+func badgerHandles(db *cete.DB) []*badger.DB {
+	var handles []*badger.DB
+	seen := make(map[uintptr]bool)
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Ptr:
+			if v.IsNil() || seen[v.Pointer()] {
+				return
+			}
+			seen[v.Pointer()] = true
+			if v.Type() == badgerDBType {
+				handles = append(handles, (*badger.DB)(unsafe.Pointer(v.Pointer())))
+				return
+			}
+			walk(v.Elem())
+		case reflect.Interface:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				walk(v.Field(i))
+			}
+		case reflect.Map:
+			for _, key := range v.MapKeys() {
+				walk(v.MapIndex(key))
+			}
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(db))
+	return handles
+}
+
+// dirSize returns the size of the files under a directory
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
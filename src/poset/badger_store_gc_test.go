@@ -0,0 +1,82 @@
+package poset
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBadgerStoreValueLogGC(t *testing.T) {
+	// small value log files, for the deleted values to fill whole ones
+	badgerValueLogFileSize = 1 << 20
+	defer func() { badgerValueLogFileSize = 0 }()
+	store, participants := initBadgerStore(cacheSize, t)
+	defer removeBadgerStore(store, t)
+
+	rnd := rand.New(rand.NewSource(1))
+	var events []Event
+	for i := 0; i < 600; i++ {
+		tx := make([]byte, 16*1024)
+		rnd.Read(tx)
+		p := participants[i%len(participants)]
+		events = append(events, NewEvent([][]byte{tx}, nil, nil,
+			EventHashes{}, p.pubKey, int64(i/len(participants)), nil, nil, 0, false))
+	}
+	if err := store.dbSetEvents(events); err != nil {
+		t.Fatal(err)
+	}
+	// all but the last events are deleted
+	kept := events[len(events)-10:]
+	for _, event := range events[:len(events)-len(kept)] {
+		hash := event.Hash()
+		if err := store.db.Table(EVENTS_TBL).Delete(hash.String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := dirSize(store.path)
+	res, err := store.RunValueLogGC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rewritten == 0 || res.Reclaimed <= 0 {
+		t.Fatalf("expected value log files rewritten, got %+v", res)
+	}
+	if after := dirSize(store.path); after >= before {
+		t.Fatalf("expected the store to shrink from %d bytes, got %d", before, after)
+	}
+	if stats := store.ValueLogGCStats(); stats.Runs != 1 || stats.Reclaimed != res.Reclaimed {
+		t.Fatalf("expected the stats of the run, got %+v", stats)
+	}
+
+	for _, event := range kept {
+		got, err := store.dbGetEventBlock(event.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Message.Body.Equals(event.Message.Body) {
+			t.Fatalf("expected the event %v, got %v", event.Message.Body, got.Message.Body)
+		}
+	}
+}
+
+func TestBadgerStoreValueLogGCWaitsForMaintenance(t *testing.T) {
+	store, _ := initBadgerStore(cacheSize, t)
+	defer removeBadgerStore(store, t)
+
+	store.beginMaintenance()
+	done := make(chan error)
+	go func() {
+		_, err := store.RunValueLogGC()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("expected the GC to wait for the maintenance, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	store.endMaintenance()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
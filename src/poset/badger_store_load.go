@@ -0,0 +1,159 @@
+package poset
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/1lann/cete"
+
+	"github.com/SamuelMarks/dag1/src/common/hexutil"
+)
+
+// StoreLoadStats tell what loading a store from its database took. Only the
+// roots and the last events of each participant are loaded, the older ones
+// are read from the database when asked for.
+type StoreLoadStats struct {
+	Duration time.Duration `json:"duration"`
+	Roots    int           `json:"roots"`
+	// Events are the events of the participants indexed in memory, at most
+	// the cache size for each participant
+	Events    int   `json:"events"`
+	LastRound int64 `json:"last_round"`
+	LastBlock int64 `json:"last_block"`
+}
+
+// LoadStats returns what loading the store took, zero for a new store
+func (s *BadgerStore) LoadStats() StoreLoadStats {
+	return s.loadStats
+}
+
+// dbLoadRecent indexes in memory the last window events of each
+// participant, and sets the last round and block from the database
+func (s *BadgerStore) dbLoadRecent(window int) error {
+	for p := range s.participants.ByPubKey {
+		n, err := s.dbLoadParticipantEvents(p, window)
+		if err != nil {
+			return err
+		}
+		s.loadStats.Events += n
+	}
+
+	lastRound, err := s.dbLastRound()
+	if err != nil {
+		return err
+	}
+	lastBlock, err := s.dbLastBlock()
+	if err != nil {
+		return err
+	}
+	s.inmemStore.setLast(lastRound, lastBlock)
+	s.loadStats.LastRound = lastRound
+	s.loadStats.LastBlock = lastBlock
+	return nil
+}
+
+// dbLoadParticipantEvents indexes the last window events of a participant,
+// reading them from the last one backwards, and returns how many it indexed
+func (s *BadgerStore) dbLoadParticipantEvents(participant string, window int) (int, error) {
+	creator, err := hexutil.Decode(participant)
+	if err != nil {
+		return 0, err
+	}
+	r := s.db.Table(EVENTS_TBL).Index(CREATOR_IDX).Between(
+		[]interface{}{creator, cete.MinValue}, []interface{}{creator, cete.MaxValue}, true)
+	defer r.Close()
+	var events []Event
+	for len(events) < window && r.Next() {
+		var event Event
+		if err := r.Decode(&event); err != nil {
+			return 0, err
+		}
+		events = append(events, event)
+	}
+	if err := r.Error(); err != nil && err != cete.ErrEndOfRange {
+		return 0, err
+	}
+	hashes := make([]EventHash, len(events))
+	indexes := make([]int64, len(events))
+	for i := range events {
+		hashes[i], indexes[i] = events[i].Hash(), events[i].Index()
+	}
+
+	// the events of an idle participant may all be archived
+	if len(events) < window {
+		to := interface{}(cete.MaxValue)
+		if len(events) > 0 {
+			to = indexes[len(indexes)-1] - 1
+		}
+		archived, err := s.dbArchivedCreatorEvents(creator, cete.MinValue, to, true)
+		if err != nil {
+			return 0, err
+		}
+		for i := 0; i < len(archived) && len(hashes) < window; i++ {
+			var hash EventHash
+			if err := hash.Set(archived[i].Hash); err != nil {
+				return 0, err
+			}
+			hashes, indexes = append(hashes, hash), append(indexes, archived[i].Index)
+		}
+	}
+
+	for i := len(hashes) - 1; i >= 0; i-- {
+		err := s.inmemStore.participantEventsCache.Set(participant, hashes[i], indexes[i])
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(hashes), nil
+}
+
+// dbLastRound returns the last round in the database, -1 for none
+func (s *BadgerStore) dbLastRound() (int64, error) {
+	r := s.db.Table(ROUNDS_TBL).All(true)
+	defer r.Close()
+	if !r.Next() {
+		if err := r.Error(); err != nil && err != cete.ErrEndOfRange {
+			return -1, err
+		}
+		return -1, nil
+	}
+	return strconv.ParseInt(r.Key(), 10, 64)
+}
+
+// dbLastBlock returns the last block emitted to the app, the last one
+// journalled or else the last one acknowledged, -1 for none
+func (s *BadgerStore) dbLastBlock() (int64, error) {
+	blocks, err := s.JournalledBlocks()
+	if err != nil {
+		return -1, err
+	}
+	if len(blocks) > 0 {
+		return blocks[len(blocks)-1].Index(), nil
+	}
+	return s.LastAcknowledgedBlock()
+}
+
+// dropPreloaded forgets the events of the participants indexed when the
+// store was loaded, which Bootstrap indexes again as it inserts all the
+// events. The leaf events the node set are kept, Bootstrap inserts none.
+func (s *BadgerStore) dropPreloaded() error {
+	leaves := make(map[string]EventHash)
+	for _, pubKey := range s.participants.ToPubKeySlice() {
+		hash, err := s.inmemStore.participantEventsCache.GetItem(pubKey, 0)
+		if err != nil {
+			continue
+		}
+		if ev, err := s.GetEventBlock(hash); err == nil && isLeafEvent(ev) {
+			leaves[pubKey] = hash
+		}
+	}
+	if err := s.inmemStore.participantEventsCache.Reset(); err != nil {
+		return err
+	}
+	for pubKey, hash := range leaves {
+		if err := s.inmemStore.participantEventsCache.Set(pubKey, hash, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package poset
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLoadBadgerStoreRecent(t *testing.T) {
+	const window = 100
+	// 3 participants make 50001 events
+	testSize := int64(16667)
+	store, participants := initBadgerStore(window, t)
+	path := store.path
+
+	events := make(map[string][]EventHash)
+	topologicalIndex := int64(0)
+	for _, p := range participants {
+		for k := int64(0); k < testSize; k++ {
+			event := NewEvent(
+				[][]byte{[]byte(fmt.Sprintf("%s_%d", p.hex[:5], k))},
+				[]InternalTransaction{},
+				nil,
+				make(EventHashes, 2),
+				p.pubKey,
+				k, NewFlagTable(), NewFlagTable(), FrameNIL, false)
+			event.Message.TopologicalIndex = topologicalIndex
+			topologicalIndex++
+			if err := store.dbSetEvents([]Event{event}); err != nil {
+				t.Fatal(err)
+			}
+			events[p.hex] = append(events[p.hex], event.Hash())
+		}
+	}
+	for r := int64(0); r < 10; r++ {
+		if err := store.dbSetRound(r, *NewRound()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadBadgerStore(window, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeBadgerStore(loaded, t)
+
+	// only the last events of each participant are loaded
+	stats := loaded.LoadStats()
+	if stats.Events != len(participants)*window {
+		t.Fatalf("expected %d events loaded, got %d", len(participants)*window, stats.Events)
+	}
+	if stats.Roots != len(participants) {
+		t.Fatalf("expected %d roots loaded, got %d", len(participants), stats.Roots)
+	}
+	if stats.LastRound != 9 || loaded.LastRound() != 9 {
+		t.Fatalf("expected the last round 9, got %d and %d", stats.LastRound, loaded.LastRound())
+	}
+	if stats.LastBlock != -1 || loaded.LastBlockIndex() != -1 {
+		t.Fatalf("expected no last block, got %d and %d", stats.LastBlock, loaded.LastBlockIndex())
+	}
+
+	// and the older ones are read from the database
+	for _, p := range participants {
+		hashes := events[p.hex]
+		last, isRoot, err := loaded.LastEventFrom(p.hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isRoot || last != hashes[testSize-1] {
+			t.Fatalf("%s: expected the last event %s, got %s (root %v)", p.hex, hashes[testSize-1], last, isRoot)
+		}
+		for _, k := range []int64{0, testSize / 2, testSize - window - 1, testSize - window, testSize - 1} {
+			hash, err := loaded.ParticipantEvent(p.hex, k)
+			if err != nil {
+				t.Fatalf("%s: event %d: %v", p.hex, k, err)
+			}
+			if hash != hashes[k] {
+				t.Fatalf("%s: expected the event %d %s, got %s", p.hex, k, hashes[k], hash)
+			}
+			if _, err := loaded.GetEventBlock(hash); err != nil {
+				t.Fatalf("%s: event %d: %v", p.hex, k, err)
+			}
+		}
+		for _, skip := range []int64{-1, testSize / 2, testSize - window - 1, testSize - 11, testSize - 1} {
+			res, err := loaded.ParticipantEvents(p.hex, skip)
+			if err != nil {
+				t.Fatalf("%s: skipping %d: %v", p.hex, skip, err)
+			}
+			expected := hashes[skip+1:]
+			if len(res) != len(expected) {
+				t.Fatalf("%s: skipping %d, expected %d events, got %d", p.hex, skip, len(expected), len(res))
+			}
+			for i := range res {
+				if res[i] != expected[i] {
+					t.Fatalf("%s: skipping %d, expected %s at %d, got %s", p.hex, skip, expected[i], i, res[i])
+				}
+			}
+		}
+	}
+
+	// all the events are streamed in topological order
+	count := int64(0)
+	err = loaded.ForEachEvent(func(event Event) bool {
+		if event.Message.TopologicalIndex != count {
+			t.Fatalf("expected the topological index %d, got %d", count, event.Message.TopologicalIndex)
+		}
+		count++
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != topologicalIndex {
+		t.Fatalf("expected %d events, got %d", topologicalIndex, count)
+	}
+	if !loaded.NeedBootstrap() {
+		t.Fatal("expected a loaded store to need a bootstrap")
+	}
+}
@@ -21,7 +21,7 @@ func initBadgerStore(cacheSize int, t *testing.T) (*BadgerStore, []pub) {
 		peer := peers.NewPeer(fmt.Sprintf("0x%X", pubKey), "")
 		participants.AddPeer(peer)
 		participantPubs = append(participantPubs,
-			pub{peer.ID, key, pubKey, peer.PubKeyHex})
+			pub{peer.ID, key, pubKey, peer.Message.PubKeyHex})
 	}
 
 	if err := os.RemoveAll("test_data"); err != nil {
@@ -190,7 +190,7 @@ func TestDBEventMethods(t *testing.T) {
 				[]BlockSignature{{Validator: []byte("validator"), Index: 0, Signature: "r|s"}},
 				make(EventHashes, 2),
 				p.pubKey,
-				k, nil)
+				k, nil, nil, 0, false)
 			if err := event.Sign(p.privKey); err != nil {
 				t.Fatal(err)
 			}
@@ -285,7 +285,7 @@ func TestDBRoundMethods(t *testing.T) {
 	store, participants := initBadgerStore(cacheSize, t)
 	defer removeBadgerStore(store, t)
 
-	round := NewRoundCreated()
+	round := NewRound()
 	events := make(map[string]Event)
 	for _, p := range participants {
 		event := NewEvent([][]byte{},
@@ -293,16 +293,16 @@ func TestDBRoundMethods(t *testing.T) {
 			[]BlockSignature{},
 			make(EventHashes, 2),
 			p.pubKey,
-			0, nil)
+			0, nil, nil, 0, false)
 		events[p.hex] = event
 		round.AddEvent(event.Hash(), true)
 	}
 
-	if err := store.dbSetRoundCreated(0, *round); err != nil {
+	if err := store.dbSetRound(0, *round); err != nil {
 		t.Fatal(err)
 	}
 
-	storedRound, err := store.dbGetRoundCreated(0)
+	storedRound, err := store.dbGetRound(0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -438,7 +438,7 @@ func TestDBFrameMethods(t *testing.T) {
 			[]BlockSignature{{Validator: []byte("validator"), Index: 0, Signature: "r|s"}},
 			make(EventHashes, 2),
 			p.pubKey,
-			0, nil)
+			0, nil, nil, 0, false)
 		if err := event.Sign(p.privKey); err != nil {
 			t.Fatal(err)
 		}
@@ -491,7 +491,7 @@ func TestBadgerEvents(t *testing.T) {
 				[]BlockSignature{{Validator: []byte("validator"), Index: 0, Signature: "r|s"}},
 				make(EventHashes, 2),
 				p.pubKey,
-				k, nil)
+				k, nil, nil, 0, false)
 			items = append(items, event)
 			err := store.SetEvent(event)
 			if err != nil {
@@ -575,7 +575,7 @@ func TestBadgerRounds(t *testing.T) {
 			[]BlockSignature{},
 			make(EventHashes, 2),
 			p.pubKey,
-			0, nil)
+			0, nil, nil, 0, false)
 		events[p.hex] = event
 		round.AddEvent(event.Hash(), true)
 	}
@@ -696,7 +696,7 @@ func TestBadgerFrames(t *testing.T) {
 			[]BlockSignature{{Validator: []byte("validator"), Index: 0, Signature: "r|s"}},
 			make(EventHashes, 2),
 			p.pubKey,
-			0, nil)
+			0, nil, nil, 0, false)
 		if err := event.Sign(p.privKey); err != nil {
 			t.Fatal(err)
 		}
@@ -0,0 +1,92 @@
+package poset
+
+import (
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+// TXINDEX_TBL maps the hashes of the transactions to their TxLocation
+const TXINDEX_TBL = "tx_index"
+
+// EnableTxIndex makes the store index the transactions of the events and
+// blocks set from now on. The database prunes no event, nor the entries.
+func (s *BadgerStore) EnableTxIndex() {
+	s.txIndex = true
+}
+
+// dbIndexEventTxs indexes the transactions of an event, when the index is on
+func (s *BadgerStore) dbIndexEventTxs(event *Event) error {
+	if !s.txIndex || len(event.Transactions()) == 0 {
+		return nil
+	}
+	hash := event.Hash()
+	for _, tx := range event.Transactions() {
+		txHash := TxHash(tx)
+		prev, err := s.dbTxLocation(txHash)
+		if err != nil {
+			return err
+		}
+		if err := s.dbSetTxLocation(eventTxLocation(txHash, hash, prev)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbIndexBlockTxs indexes the transactions of a block, when the index is on
+func (s *BadgerStore) dbIndexBlockTxs(block *Block) error {
+	if !s.txIndex {
+		return nil
+	}
+	for i, tx := range block.Transactions() {
+		txHash := TxHash(tx)
+		prev, err := s.dbTxLocation(txHash)
+		if err != nil {
+			return err
+		}
+		if err := s.dbSetTxLocation(blockTxLocation(txHash, block.Index(), i, prev)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbTxLocation returns the indexed location of a transaction, nil if none
+func (s *BadgerStore) dbTxLocation(txHash common.Hash) (*TxLocation, error) {
+	if !hasTable(s.db, TXINDEX_TBL) {
+		return nil, nil
+	}
+	var loc TxLocation
+	if _, err := s.db.Table(TXINDEX_TBL).Get(txHash.Hex(), &loc); err != nil {
+		if isDBKeyNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &loc, nil
+}
+
+func (s *BadgerStore) dbSetTxLocation(loc TxLocation) error {
+	if !hasTable(s.db, TXINDEX_TBL) {
+		if err := s.db.NewTable(TXINDEX_TBL); err != nil {
+			return err
+		}
+	}
+	return s.db.Table(TXINDEX_TBL).Set(loc.Tx.Hex(), loc)
+}
+
+// LookupTx returns the location of a transaction by the hash of its content.
+// A database indexed before it was loaded is searched even if the index is
+// not on.
+func (s *BadgerStore) LookupTx(txHash common.Hash) (TxLocation, error) {
+	if !s.txIndex && !hasTable(s.db, TXINDEX_TBL) {
+		return TxLocation{}, ErrNoTxIndex
+	}
+	loc, err := s.dbTxLocation(txHash)
+	if err != nil {
+		return TxLocation{}, err
+	}
+	if loc == nil {
+		return TxLocation{}, common.NewStoreErr("TxIndex", common.KeyNotFound, txHash.Hex())
+	}
+	return *loc, nil
+}
@@ -0,0 +1,278 @@
+package poset
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// The benchmarks run on fixtures, the same DAGs from one run to the next,
+// cmd/bench runs them and summarises the results in JSON.
+
+// benchShapes are the DAGs of the dominator benchmarks, a deep one of few
+// long chains and a wide one of many short ones
+var benchShapes = []struct {
+	name string
+	conf fixtureConfig
+}{
+	{"deep", fixtureConfig{Participants: 3, Events: 3000, Seed: 1}},
+	{"wide", fixtureConfig{Participants: 32, Events: 3000, Seed: 1}},
+}
+
+// perEvent reports the time per event of a benchmark of which each
+// operation handles n events
+func perEvent(b *testing.B, n int) {
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/event")
+}
+
+func BenchmarkInsertEvent(b *testing.B) {
+	for _, participants := range []int{4, 16} {
+		f := newFixture(b, fixtureConfig{Participants: participants, Events: 1000, Seed: 1, Txs: 4, TxSize: 64})
+		b.Run(fmt.Sprintf("participants=%d", participants), func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				events := f.copyEvents()
+				p := f.emptyPoset(b)
+				b.StartTimer()
+
+				for i, ev := range events {
+					if err := p.InsertEvent(ev, false); err != nil {
+						b.Fatalf("inserting event %d: %v", i, err)
+					}
+				}
+			}
+			perEvent(b, len(f.events))
+		})
+	}
+}
+
+// benchPairs returns pairs of events of the DAG to compare, the second
+// made before the first
+func benchPairs(f *fixture, n int) [][2]EventHash {
+	rng := rand.New(rand.NewSource(f.config.Seed))
+	res := make([][2]EventHash, n)
+	for i := range res {
+		x := len(f.events)/2 + rng.Intn(len(f.events)/2)
+		y := rng.Intn(x)
+		res[i] = [2]EventHash{f.events[x].Hash(), f.events[y].Hash()}
+	}
+	return res
+}
+
+// purgeDominatorCaches empties the caches of the dominator relations, so
+// that the benchmarks measure them computed
+func (p *Poset) purgeDominatorCaches() {
+	p.dominatorCache.Purge()
+	p.selfDominatorCache.Purge()
+	p.strictlyDominatedCache.Purge()
+	p.timestampCache.Purge()
+}
+
+func BenchmarkDominator(b *testing.B) {
+	for _, shape := range benchShapes {
+		f := newFixture(b, shape.conf)
+		p := f.newPoset(b, false)
+		pairs := benchPairs(f, 100)
+		b.Run(shape.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				p.purgeDominatorCaches()
+				b.StartTimer()
+				for _, pair := range pairs {
+					if _, err := p.dominator(pair[0], pair[1]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkStrictlyDominated(b *testing.B) {
+	for _, shape := range benchShapes {
+		f := newFixture(b, shape.conf)
+		p := f.newPoset(b, false)
+		pairs := benchPairs(f, 100)
+		b.Run(shape.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				p.purgeDominatorCaches()
+				b.StartTimer()
+				for _, pair := range pairs {
+					if _, err := p.strictlyDominated(pair[0], pair[1]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDivideRounds(b *testing.B) {
+	f := newFixture(b, fixtureConfig{Participants: 8, Events: 5000, Seed: 1})
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		p := f.newPoset(b, false)
+		b.StartTimer()
+
+		if err := p.DivideRounds(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	perEvent(b, len(f.events))
+}
+
+// BenchmarkDivideRoundsUndetermined times DivideRounds after each batch of
+// events inserted on top of a number of undetermined ones. The queued
+// division costs the same whatever the number, the division of all the
+// undetermined events grows with it.
+func BenchmarkDivideRoundsUndetermined(b *testing.B) {
+	const batch = 10
+	for _, undetermined := range []int{500, 4000} {
+		f := newFixture(b, fixtureConfig{Participants: 8, Events: undetermined + 1000, Seed: 1})
+		for _, all := range []bool{false, true} {
+			name := fmt.Sprintf("undetermined=%d/queued", undetermined)
+			divide := (*Poset).DivideRounds
+			if all {
+				name = fmt.Sprintf("undetermined=%d/all", undetermined)
+				divide = (*Poset).divideRoundsAll
+			}
+			b.Run(name, func(b *testing.B) {
+				b.ReportAllocs()
+				b.StopTimer()
+				var p *Poset
+				var events []Event
+				next := len(f.events)
+				for n := 0; n < b.N; n++ {
+					if next+batch > len(f.events) {
+						p, events = f.emptyPoset(b), f.copyEvents()
+						for i, ev := range events[:undetermined] {
+							if err := p.InsertEvent(ev, false); err != nil {
+								b.Fatalf("inserting event %d: %v", i, err)
+							}
+						}
+						if err := p.DivideRounds(); err != nil {
+							b.Fatal(err)
+						}
+						next = undetermined
+					}
+					for i, ev := range events[next : next+batch] {
+						if err := p.InsertEvent(ev, false); err != nil {
+							b.Fatalf("inserting event %d: %v", next+i, err)
+						}
+					}
+					next += batch
+
+					b.StartTimer()
+					if err := divide(p); err != nil {
+						b.Fatal(err)
+					}
+					b.StopTimer()
+				}
+				perEvent(b, batch)
+			})
+		}
+	}
+}
+
+func BenchmarkMakeFrame(b *testing.B) {
+	for _, participants := range []int{4, 16, 32} {
+		f := newFixture(b, fixtureConfig{Participants: participants, Events: 60 * participants, Seed: 1, Txs: 4, TxSize: 64})
+		p := f.newPoset(b, true)
+
+		// the largest frame made
+		var round int64 = -1
+		var events int
+		for r := int64(0); r <= p.Store.LastRound(); r++ {
+			frame, err := p.Store.GetFrame(r)
+			if err == nil && len(frame.Events) > events {
+				round, events = r, len(frame.Events)
+			}
+		}
+		if round < 0 {
+			b.Fatalf("%d participants: no frame made", participants)
+		}
+
+		b.Run(fmt.Sprintf("participants=%d", participants), func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if _, err := p.MakeFrame(round); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(events), "events/frame")
+		})
+	}
+}
+
+// BenchmarkBadgerStore measures the event writes and the reads missing the
+// cache, with transactions of the size of a token transfer
+func BenchmarkBadgerStore(b *testing.B) {
+	f := newFixture(b, fixtureConfig{Participants: 4, Events: 500, Seed: 1, Txs: 16, TxSize: 256})
+
+	newStore := func(b *testing.B) (*BadgerStore, func()) {
+		dir, err := ioutil.TempDir("", "dag1-bench")
+		if err != nil {
+			b.Fatal(err)
+		}
+		// a small cache, for the reads to go to the database
+		store, err := NewBadgerStore(f.newParticipants(), 10, dir, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return store, func() {
+			if err := store.Close(); err != nil {
+				b.Fatal(err)
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("SetEvent", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			b.StopTimer()
+			store, cleanup := newStore(b)
+			events := f.copyEvents()
+			b.StartTimer()
+
+			for _, ev := range events {
+				if err := store.SetEvent(ev); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.StopTimer()
+			cleanup()
+			b.StartTimer()
+		}
+		perEvent(b, len(f.events))
+	})
+
+	b.Run("GetEventBlock", func(b *testing.B) {
+		store, cleanup := newStore(b)
+		defer cleanup()
+		for _, ev := range f.copyEvents() {
+			if err := store.SetEvent(ev); err != nil {
+				b.Fatal(err)
+			}
+		}
+		rng := rand.New(rand.NewSource(f.config.Seed))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			hash := f.events[rng.Intn(len(f.events))].Hash()
+			if _, err := store.GetEventBlock(hash); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -6,9 +6,11 @@ import (
 	"encoding/hex"
 	"fmt"
 	"reflect"
+	"sort"
 	"time"
 
 	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
 	"github.com/golang/protobuf/proto"
 )
 
@@ -63,6 +65,31 @@ func (bs *BlockSignature) ProtoUnmarshal(data []byte) error {
 	return proto.Unmarshal(data, bs)
 }
 
+// signHash signs hash, index tells what the signature is for
+func signHash(privKey *ecdsa.PrivateKey, index int64, hash []byte) (BlockSignature, error) {
+	R, S, err := crypto.Sign(privKey, hash)
+	if err != nil {
+		return BlockSignature{}, err
+	}
+	return BlockSignature{
+		Validator: crypto.FromECDSAPub(&privKey.PublicKey),
+		Index:     index,
+		Signature: crypto.EncodeSignature(R, S),
+	}, nil
+}
+
+// verifyHash checks that the validator signed hash
+func (bs *BlockSignature) verifyHash(hash []byte) (bool, error) {
+	pubKey := crypto.ToECDSAPub(bs.Validator)
+
+	r, s, err := crypto.DecodeSignature(bs.Signature)
+	if err != nil {
+		return false, err
+	}
+
+	return crypto.Verify(pubKey, hash, r, s), nil
+}
+
 // ToWire converts block signatures to wire (transport)
 func (bs *BlockSignature) ToWire() WireBlockSignature {
 	return WireBlockSignature{
@@ -80,19 +107,60 @@ func (bs *BlockSignature) Equals(that *BlockSignature) bool {
 
 // ------------------------------------------------------------------------------
 
-// NewBlockFromFrame creates a new block from the given frame
+// NewBlockFromFrame creates a new block from the given frame. Transactions
+// are ordered by their flags byte (highest priority first), ties keep the
+// frame order, so every node builds the same block.
 func NewBlockFromFrame(blockIndex int64, frame Frame) (Block, error) {
 	frameHash, err := frame.Hash()
 	if err != nil {
 		return Block{}, err
 	}
-	var transactions [][]byte
-	for _, e := range frame.Events {
-		transactions = append(transactions, e.Body.Transactions...)
+	return NewBlock(blockIndex, frame.Round, frameHash, frameTransactions(frame)), nil
+}
+
+// frameTransactions returns the transactions of a frame in the order of its
+// blocks, by descending priority
+func frameTransactions(frame Frame) [][]byte {
+	bodies := make([]*EventBody, len(frame.Events))
+	for i, e := range frame.Events {
+		bodies[i] = e.Body
+	}
+	return orderedTransactions(bodies)
+}
+
+// orderedTransactions returns the transactions of event bodies by descending
+// priority, ties keeping the order of the bodies
+func orderedTransactions(bodies []*EventBody) [][]byte {
+	var transactions []flaggedTransaction
+	for _, body := range bodies {
+		for i, tx := range body.Transactions {
+			transactions = append(transactions, flaggedTransaction{
+				tx:       tx,
+				priority: body.TransactionFlag(i),
+			})
+		}
+	}
+	sort.Stable(byPriority(transactions))
+
+	var txs [][]byte
+	for _, t := range transactions {
+		txs = append(txs, t.tx)
 	}
-	return NewBlock(blockIndex, frame.Round, frameHash, transactions), nil
+	return txs
+}
+
+type flaggedTransaction struct {
+	tx       []byte
+	priority byte
 }
 
+// byPriority sorts transactions by descending priority
+type byPriority []flaggedTransaction
+
+func (a byPriority) Len() int           { return len(a) }
+func (a byPriority) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byPriority) Less(i, j int) bool { return a[i].priority > a[j].priority }
+
 // NewBlock creates a new empty block with current time
 func NewBlock(blockIndex, roundReceived int64, frameHash []byte, txs [][]byte) Block {
 	body := BlockBody{
@@ -195,21 +263,15 @@ func (b *Block) Sign(privKey *ecdsa.PrivateKey) (bs BlockSignature, err error) {
 	if err != nil {
 		return bs, err
 	}
-	R, S, err := crypto.Sign(privKey, signBytes)
-	if err != nil {
-		return bs, err
-	}
-	signature := BlockSignature{
-		Validator: crypto.FromECDSAPub(&privKey.PublicKey),
-		Index:     b.Index(),
-		Signature: crypto.EncodeSignature(R, S),
-	}
-
-	return signature, nil
+	return signHash(privKey, b.Index(), signBytes)
 }
 
 // SetSignature sets the known blocksignatures for the block
 func (b *Block) SetSignature(bs BlockSignature) error {
+	// a decoded block without signatures has no map
+	if b.Signatures == nil {
+		b.Signatures = make(map[string]string)
+	}
 	b.Signatures[bs.ValidatorHex()] = bs.Signature
 	return nil
 }
@@ -220,15 +282,29 @@ func (b *Block) Verify(sig BlockSignature) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	return sig.verifyHash(signBytes)
+}
 
-	pubKey := crypto.ToECDSAPub(sig.Validator)
-
-	r, s, err := crypto.DecodeSignature(sig.Signature)
-	if err != nil {
-		return false, err
+// SignedStake returns the stake of the participants with a valid signature on
+// the block, the signatures of other keys not counted. Participants count one
+// each unless stakes were given.
+func (b *Block) SignedStake(participants *peers.Peers) uint64 {
+	var stake, signed, signedCount uint64
+	for _, p := range participants.ToPeerSlice() {
+		stake += p.GetWeight()
+		sig, err := b.GetSignature(p.Message.PubKeyHex)
+		if err != nil {
+			continue
+		}
+		if ok, _ := b.Verify(sig); ok {
+			signed += p.GetWeight()
+			signedCount++
+		}
 	}
-
-	return crypto.Verify(pubKey, signBytes, r, s), nil
+	if stake == 0 {
+		return signedCount
+	}
+	return signed
 }
 
 // ListBytesEquals compares the equality of two lists
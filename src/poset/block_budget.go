@@ -0,0 +1,83 @@
+package poset
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// blockBudget bounds the transactions of a block, in count and in total
+// bytes, 0 for no bound. It is part of the consensus parameters: the blocks
+// are cut from the consensus order of the transactions only, so every node
+// cuts them at the same places.
+type blockBudget struct {
+	maxTxs   int
+	maxBytes int
+}
+
+// SetBlockBudget bounds the transactions of a block, in count and in total
+// bytes, 0 for no bound. The transactions of a frame beyond the budget carry
+// over, in the same order, into the next block. Every participant must use
+// the same budget.
+func (p *Poset) SetBlockBudget(maxTxs, maxBytes int) {
+	p.blockBudget = blockBudget{maxTxs: maxTxs, maxBytes: maxBytes}
+}
+
+// split cuts txs, in their order, into the blocks the budget allows. A
+// transaction bigger than maxBytes makes a block on its own, the size
+// limit of the events keeps it bounded.
+func (b blockBudget) split(txs [][]byte) [][][]byte {
+	if len(txs) == 0 {
+		return nil
+	}
+	var blocks [][][]byte
+	start, size := 0, 0
+	for i, tx := range txs {
+		full := b.maxTxs > 0 && i-start == b.maxTxs ||
+			b.maxBytes > 0 && i > start && size+len(tx) > b.maxBytes
+		if full {
+			blocks = append(blocks, txs[start:i:i])
+			start, size = i, 0
+		}
+		size += len(tx)
+	}
+	return append(blocks, txs[start:])
+}
+
+// take returns the transactions of the next block from the carried ones
+// followed by txs, and the ones left to carry over into the block after
+func (b blockBudget) take(carry, txs [][]byte) (block, rest [][]byte) {
+	all := append(carry[:len(carry):len(carry)], txs...)
+	blocks := b.split(all)
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	return blocks[0], all[len(blocks[0]):]
+}
+
+// setBlockCarry keeps the transactions left past the block of a frame for
+// the next block. The store keeps them too, for a restarted poset, or one
+// reset from that block, to go on with them.
+func (p *Poset) setBlockCarry(frame int64, carry [][]byte) error {
+	p.blockCarry = carry
+	return p.Store.SetBlockCarry(frame, carry)
+}
+
+// SetBlockCarry sets the transactions a peer carried over past a block, for
+// a Reset from that block to go on with them. The block and the carry must
+// be exactly the transactions of the frame of the block, in order, so a
+// block holding transactions carried from earlier frames is refused.
+func (p *Poset) SetBlockCarry(block Block, frame Frame, carry [][]byte) error {
+	txs := block.Transactions()
+	all := append(txs[:len(txs):len(txs)], carry...)
+	frameTxs := frameTransactions(frame)
+	if len(all) != len(frameTxs) {
+		return fmt.Errorf("block %d and its carry hold %d transactions, its frame %d",
+			block.Index(), len(all), len(frameTxs))
+	}
+	for i, tx := range all {
+		if !bytes.Equal(tx, frameTxs[i]) {
+			return fmt.Errorf("block %d and its carry are not the transactions of its frame", block.Index())
+		}
+	}
+	return p.Store.SetBlockCarry(block.RoundReceived(), carry)
+}
@@ -0,0 +1,273 @@
+package poset
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBlockBudgetSplit(t *testing.T) {
+	txs := [][]byte{[]byte("aa"), []byte("bbbb"), []byte("c"), []byte("dddddd"), []byte("e")}
+	checks := []struct {
+		budget   blockBudget
+		expected []int // transactions per block
+	}{
+		{blockBudget{}, []int{5}},
+		{blockBudget{maxTxs: 2}, []int{2, 2, 1}},
+		{blockBudget{maxBytes: 7}, []int{3, 2}},
+		// a transaction bigger than the budget makes a block on its own
+		{blockBudget{maxBytes: 4}, []int{1, 1, 1, 1, 1}},
+		{blockBudget{maxTxs: 2, maxBytes: 6}, []int{2, 1, 1, 1}},
+	}
+	for _, c := range checks {
+		var sizes []int
+		var all [][]byte
+		for _, block := range c.budget.split(txs) {
+			sizes = append(sizes, len(block))
+			all = append(all, block...)
+		}
+		if !reflect.DeepEqual(sizes, c.expected) {
+			t.Fatalf("%+v: expected blocks of %v, got %v", c.budget, c.expected, sizes)
+		}
+		if !reflect.DeepEqual(all, txs) {
+			t.Fatalf("%+v: expected the transactions in order, got %q", c.budget, all)
+		}
+	}
+
+	if blocks := (blockBudget{maxTxs: 2}).split(nil); len(blocks) != 0 {
+		t.Fatalf("expected no block, got %v", blocks)
+	}
+}
+
+func TestBlockBudgetTake(t *testing.T) {
+	budget := blockBudget{maxTxs: 3}
+	carry := [][]byte{[]byte("a"), []byte("b")}
+	block, rest := budget.take(carry, [][]byte{[]byte("c"), []byte("d")})
+	if len(block) != 3 || string(block[0]) != "a" || len(rest) != 1 || string(rest[0]) != "d" {
+		t.Fatalf("expected a, b and c with d left, got %q and %q", block, rest)
+	}
+	// the carried transactions are not overwritten
+	if string(carry[0]) != "a" || len(carry) != 2 {
+		t.Fatalf("expected the carried transactions untouched, got %q", carry)
+	}
+}
+
+// budgetFrames returns frames of events, each with a burst of transactions
+func budgetFrames() []Frame {
+	participants, keys := iteratorParticipants()
+	var frames []Frame
+	heads := make([]*Event, len(keys))
+	for round := 0; round < 4; round++ {
+		frame := Frame{Round: int64(round)}
+		for i, key := range keys {
+			ev := capEvent(participants, key, heads[i], EventHash{}, "")
+			var txs [][]byte
+			for j := 0; j < 3+round*i; j++ {
+				txs = append(txs, []byte(fmt.Sprintf("tx %d %d %d", round, i, j)))
+			}
+			ev.Message.Body.Transactions = txs
+			heads[i] = &ev
+			frame.Events = append(frame.Events, ev.Message)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// budgetBlocks processes the frames, batch by batch, with a fresh Poset
+// and returns its blocks and the transactions it carries over
+func budgetBlocks(t *testing.T, frames []Frame, batches [][]int, params ConsensusParams) ([]Block, [][]byte) {
+	participants, _ := iteratorParticipants()
+	store := NewInmemStore(participants, cacheSize, nil)
+	logger := logrus.New()
+	logger.Level = logrus.FatalLevel
+	commitCh := make(chan Block, 100)
+	p, err := NewPoset(participants, store, commitCh, logrus.NewEntry(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetConsensusParams(params)
+
+	for _, batch := range batches {
+		for _, i := range batch {
+			if err := store.SetFrame(frames[i]); err != nil {
+				t.Fatal(err)
+			}
+			p.PendingRoundReceived = append(p.PendingRoundReceived, frames[i].Round)
+		}
+		if err := p.ProcessDecidedRounds(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var blocks []Block
+	for i := int64(0); i <= store.LastBlockIndex(); i++ {
+		block, err := store.GetBlock(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+	}
+	if len(commitCh) != len(blocks) {
+		t.Fatalf("expected %d blocks committed, got %d", len(blocks), len(commitCh))
+	}
+	return blocks, p.blockCarry
+}
+
+func TestBlockBudgetBurst(t *testing.T) {
+	const maxTxs, maxBytes = 4, 40
+	frames := budgetFrames()
+	params := ConsensusParams{MaxBlockTransactions: maxTxs, MaxBlockBytes: maxBytes}
+
+	// one poset gets all the frames at once, the other one by one
+	all, carry := budgetBlocks(t, frames, [][]int{{0, 1, 2, 3}}, params)
+	single, singleCarry := budgetBlocks(t, frames, [][]int{{0}, {1}, {2}, {3}}, params)
+	if len(all) != len(single) || !reflect.DeepEqual(carry, singleCarry) {
+		t.Fatalf("expected the same blocks and carry, got %d and %d blocks", len(all), len(single))
+	}
+
+	var committed [][]byte
+	for i := range all {
+		if !reflect.DeepEqual(all[i].Body, single[i].Body) {
+			t.Fatalf("block %d: expected the same body, got %+v and %+v", i, all[i].Body, single[i].Body)
+		}
+		txs := all[i].Transactions()
+		size := 0
+		for _, tx := range txs {
+			size += len(tx)
+		}
+		if len(txs) > maxTxs || size > maxBytes {
+			t.Fatalf("block %d: expected at most %d transactions of %d bytes, got %d of %d",
+				i, maxTxs, maxBytes, len(txs), size)
+		}
+		committed = append(committed, txs...)
+	}
+
+	// no transaction is lost or duplicated, and they keep their order, the
+	// ones beyond the budget of the last block carried over into the next
+	committed = append(committed, carry...)
+	var expected [][]byte
+	for _, frame := range frames {
+		expected = append(expected, frameTransactions(frame)...)
+	}
+	if !reflect.DeepEqual(committed, expected) {
+		t.Fatalf("expected the %d transactions of the frames in order, got %d", len(expected), len(committed))
+	}
+
+	unbounded, unboundedCarry := budgetBlocks(t, frames, [][]int{{0, 1, 2, 3}}, ConsensusParams{})
+	if len(unbounded) != len(frames) || len(all) != len(frames) {
+		t.Fatalf("expected a block per frame with and without budget, got %d and %d",
+			len(unbounded), len(all))
+	}
+	if len(unboundedCarry) != 0 || len(carry) == 0 {
+		t.Fatalf("expected a carry with a budget only, got %d and %d transactions",
+			len(unboundedCarry), len(carry))
+	}
+}
+
+// TestBlockCarryResetAndRestart checks the store keeps the transactions
+// carried over past a block, for a Poset reset from the block and a
+// restarted one to go on with them
+func TestBlockCarryResetAndRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "block_carry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "store")
+
+	params := ConsensusParams{MaxBlockTransactions: 3}
+	f := newFixture(t, fixtureConfig{Participants: 4, Events: 200, Seed: 1, Txs: 2, TxSize: 8})
+	participants := f.newParticipants()
+	store, err := NewBadgerStore(participants, cacheSize, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitCh := make(chan Block, 2*len(f.events)+10)
+	p, err := NewPoset(participants, store, commitCh, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetConsensusParams(params)
+	for i, ev := range f.copyEvents() {
+		if err := p.InsertEvent(ev, false); err != nil {
+			t.Fatalf("inserting event %d: %v", i, err)
+		}
+	}
+	if err := p.RunToQuiescence(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first block with transactions carried over past it
+	var (
+		block Block
+		carry [][]byte
+	)
+	for _, b := range committedBlocks(commitCh) {
+		if carry, err = store.BlockCarry(b.RoundReceived()); err != nil {
+			t.Fatal(err)
+		}
+		if len(carry) > 0 {
+			block = b
+			break
+		}
+	}
+	if len(carry) == 0 {
+		t.Fatal("expected a block with transactions carried over")
+	}
+	frame, err := p.GetFrame(block.RoundReceived())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a Poset reset from the block goes on with the carry of the peer
+	participants2 := f.newParticipants()
+	p2, err := NewPoset(participants2, NewInmemStore(participants2, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2.SetConsensusParams(params)
+	if err := p2.SetBlockCarry(block, frame, carry[:len(carry)-1]); err == nil {
+		t.Fatal("expected a carry missing a transaction of the frame refused")
+	}
+	extra := append(carry[:len(carry):len(carry)], []byte("extra"))
+	if err := p2.SetBlockCarry(block, frame, extra); err == nil {
+		t.Fatal("expected a carry with a transaction out of the frame refused")
+	}
+	if err := p2.SetBlockCarry(block, frame, carry); err != nil {
+		t.Fatal(err)
+	}
+	if err := p2.Reset(block, frame); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p2.blockCarry, carry) {
+		t.Fatalf("expected the reset Poset to carry %q, got %q", carry, p2.blockCarry)
+	}
+
+	// a restarted Poset makes the blocks again on to the same carry
+	last := p.blockCarry
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadBadgerStore(cacheSize, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+	np, err := NewPoset(loaded.participants, loaded, make(chan Block, 2*len(f.events)+10), testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	np.SetConsensusParams(params)
+	if err := np.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(np.blockCarry, last) {
+		t.Fatalf("expected the restarted Poset to carry %q, got %q", last, np.blockCarry)
+	}
+}
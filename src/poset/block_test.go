@@ -2,6 +2,9 @@ package poset
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/SamuelMarks/dag1/src/crypto"
@@ -68,3 +71,134 @@ func TestAppendSignature(t *testing.T) {
 	}
 
 }
+
+func TestNewBlockFromFrameTransactionPriority(t *testing.T) {
+	frame := Frame{
+		Round: 1,
+		Events: []*EventMessage{
+			{Body: &EventBody{
+				Transactions: [][]byte{[]byte("a0"), []byte("a1"), []byte("a2")},
+				// old event without flags, all zero
+			}},
+			{Body: &EventBody{
+				Transactions:     [][]byte{[]byte("b0"), []byte("b1"), []byte("b2")},
+				TransactionFlags: []byte{0, 2, 1},
+			}},
+			{Body: &EventBody{
+				Transactions:     [][]byte{[]byte("c0"), []byte("c1")},
+				TransactionFlags: []byte{2, 0},
+			}},
+		},
+	}
+
+	block, err := NewBlockFromFrame(0, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"b1", "c0", "b2", "a0", "a1", "a2", "b0", "c1"}
+	txs := block.Transactions()
+	if len(txs) != len(expected) {
+		t.Fatalf("expected %d transactions, got %d", len(expected), len(txs))
+	}
+	for i, tx := range txs {
+		if string(tx) != expected[i] {
+			t.Fatalf("transaction %d: expected %s, got %s", i, expected[i], tx)
+		}
+	}
+
+	// another node decoding the same frame must build the same block
+	data, err := frame.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other Frame
+	if err := other.ProtoUnmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	otherBlock, err := NewBlockFromFrame(0, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !otherBlock.Body.Equals(block.Body) {
+		t.Fatalf("blocks differ: %v, %v", otherBlock.Body, block.Body)
+	}
+}
+
+func TestCommitJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	participants, _ := iteratorParticipants()
+	path := filepath.Join(dir, "badger")
+	badgerStore, err := NewBadgerStore(participants, cacheSize, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkJournal := func(name string, store Store, lastAcknowledged int64, indexes ...int64) {
+		last, err := store.LastAcknowledgedBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last != lastAcknowledged {
+			t.Fatalf("%s: expected block %d acknowledged last, got %d", name, lastAcknowledged, last)
+		}
+		blocks, err := store.JournalledBlocks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(blocks) != len(indexes) {
+			t.Fatalf("%s: expected %d journalled blocks, got %d", name, len(indexes), len(blocks))
+		}
+		for i, block := range blocks {
+			if block.Index() != indexes[i] {
+				t.Fatalf("%s: expected block %d journalled, got %d", name, indexes[i], block.Index())
+			}
+			if string(block.Transactions()[0]) != fmt.Sprintf("tx%d", indexes[i]) {
+				t.Fatalf("%s: block %d: unexpected transactions %q", name, indexes[i], block.Transactions())
+			}
+		}
+	}
+
+	for name, store := range map[string]Store{
+		"inmem":  NewInmemStore(participants, cacheSize, nil),
+		"badger": badgerStore,
+	} {
+		checkJournal(name, store, -1)
+
+		// out of order, as blocks are emitted again after a restart
+		for _, i := range []int64{2, 0, 1, 3} {
+			block := NewBlock(i, i+1, []byte("framehash"), [][]byte{[]byte(fmt.Sprintf("tx%d", i))})
+			if err := store.JournalBlock(block); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkJournal(name, store, -1, 0, 1, 2, 3)
+
+		if err := store.AcknowledgeBlock(1); err != nil {
+			t.Fatal(err)
+		}
+		checkJournal(name, store, 1, 2, 3)
+
+		// an older acknowledgement changes nothing
+		if err := store.AcknowledgeBlock(0); err != nil {
+			t.Fatal(err)
+		}
+		checkJournal(name, store, 1, 2, 3)
+	}
+
+	// the journal outlives a restart
+	if err := badgerStore.Close(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadBadgerStore(cacheSize, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+	checkJournal("loaded", loaded, 1, 2, 3)
+}
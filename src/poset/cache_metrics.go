@@ -0,0 +1,291 @@
+package poset
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/golang-lru"
+)
+
+const (
+	// cacheWindow is the number of lookups over which the hit rate of a
+	// cache is measured before it is resized
+	cacheWindow = 1024
+	// DefaultCacheGrowBelow is the hit rate under which a cache grows
+	DefaultCacheGrowBelow = 0.5
+	// DefaultCacheShrinkAbove is the hit rate a grown cache must keep for
+	// cacheShrinkWindows windows in a row to shrink back
+	DefaultCacheShrinkAbove = 0.95
+	cacheShrinkWindows      = 4
+)
+
+// CacheStats are the counters of a cache of the Poset
+type CacheStats struct {
+	Name      string `json:"name"`
+	Size      int    `json:"size"`
+	Len       int    `json:"len"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Resizes   uint64 `json:"resizes"`
+}
+
+// HitRate returns the share of the lookups which were hits, 0 without any
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// meteredCache is an LRU cache counting its hits, misses and evictions,
+// which its tuner resizes to follow its hit rate
+type meteredCache struct {
+	name  string
+	base  int // size it starts with and shrinks back to
+	tuner *cacheTuner
+
+	lock  sync.RWMutex // guards cache, swapped on resize
+	cache *lru.Cache
+	size  int64
+
+	hits, misses, evictions, resizes uint64
+
+	// the counters at the start of the window, and the windows in a row
+	// with a hit rate above the shrink trigger, guarded by the tuner
+	windowHits, windowMisses uint64
+	goodWindows              int
+}
+
+func newMeteredCache(name string, size int, tuner *cacheTuner) (*meteredCache, error) {
+	cache, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	c := &meteredCache{name: name, base: size, tuner: tuner, cache: cache, size: int64(size)}
+	tuner.caches = append(tuner.caches, c)
+	return c, nil
+}
+
+// Get looks up a key, counting a hit or a miss
+func (c *meteredCache) Get(key interface{}) (interface{}, bool) {
+	c.lock.RLock()
+	value, ok := c.cache.Get(key)
+	c.lock.RUnlock()
+
+	var lookups uint64
+	if ok {
+		lookups = atomic.AddUint64(&c.hits, 1) + atomic.LoadUint64(&c.misses)
+	} else {
+		lookups = atomic.AddUint64(&c.misses, 1) + atomic.LoadUint64(&c.hits)
+	}
+	if lookups%cacheWindow == 0 {
+		c.tuner.tune(c)
+	}
+	return value, ok
+}
+
+// Peek looks up a key without counting it, nor making it recent
+func (c *meteredCache) Peek(key interface{}) (interface{}, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.cache.Peek(key)
+}
+
+// Add adds a value, counting the eviction it makes room with
+func (c *meteredCache) Add(key, value interface{}) {
+	c.lock.RLock()
+	evicted := c.cache.Add(key, value)
+	c.lock.RUnlock()
+	if evicted {
+		atomic.AddUint64(&c.evictions, 1)
+	}
+}
+
+// Remove removes a key
+func (c *meteredCache) Remove(key interface{}) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	c.cache.Remove(key)
+}
+
+// Purge empties the cache, which keeps its size and counters
+func (c *meteredCache) Purge() {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	c.cache.Purge()
+}
+
+// Len returns the number of entries
+func (c *meteredCache) Len() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.cache.Len()
+}
+
+// resize moves the entries to a cache of the given size, the least
+// recently used ones left out when it is smaller
+func (c *meteredCache) resize(size int) error {
+	cache, err := lru.New(size)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// the keys come oldest first, so the recency order is kept
+	for _, key := range c.cache.Keys() {
+		if value, ok := c.cache.Peek(key); ok {
+			if cache.Add(key, value) {
+				atomic.AddUint64(&c.evictions, 1)
+			}
+		}
+	}
+	c.cache = cache
+	atomic.StoreInt64(&c.size, int64(size))
+	atomic.AddUint64(&c.resizes, 1)
+	return nil
+}
+
+func (c *meteredCache) stats() CacheStats {
+	return CacheStats{
+		Name:      c.name,
+		Size:      int(atomic.LoadInt64(&c.size)),
+		Len:       c.Len(),
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+		Resizes:   atomic.LoadUint64(&c.resizes),
+	}
+}
+
+// cacheTuner resizes the caches sharing a budget of entries. A cache whose
+// hit rate over a window falls below growBelow doubles, as far as the
+// budget allows, and a grown cache whose hit rate recovers halves.
+type cacheTuner struct {
+	lock   sync.Mutex
+	budget int // total entries of the caches, 0 for no resizing
+	caches []*meteredCache
+	// the hit rates resizing the caches, the defaults when 0
+	growBelow, shrinkAbove float64
+}
+
+// triggers returns the hit rates resizing the caches, see SetCacheTriggers
+func (t *cacheTuner) triggers() (growBelow, shrinkAbove float64) {
+	growBelow, shrinkAbove = t.growBelow, t.shrinkAbove
+	if growBelow == 0 {
+		growBelow = DefaultCacheGrowBelow
+	}
+	if shrinkAbove == 0 {
+		shrinkAbove = DefaultCacheShrinkAbove
+	}
+	return growBelow, shrinkAbove
+}
+
+// tune resizes a cache from its hit rate over the window just ended
+func (t *cacheTuner) tune(c *meteredCache) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	hits, misses := atomic.LoadUint64(&c.hits), atomic.LoadUint64(&c.misses)
+	windowHits, windowMisses := hits-c.windowHits, misses-c.windowMisses
+	c.windowHits, c.windowMisses = hits, misses
+	if t.budget <= 0 || windowHits+windowMisses == 0 {
+		return
+	}
+	rate := float64(windowHits) / float64(windowHits+windowMisses)
+	growBelow, shrinkAbove := t.triggers()
+
+	size := int(atomic.LoadInt64(&c.size))
+	switch {
+	case rate < growBelow:
+		c.goodWindows = 0
+		total := 0
+		for _, cache := range t.caches {
+			total += int(atomic.LoadInt64(&cache.size))
+		}
+		grow := size
+		if total+grow > t.budget {
+			grow = t.budget - total
+		}
+		if grow > 0 {
+			_ = c.resize(size + grow)
+		}
+	case rate >= shrinkAbove && size > c.base:
+		c.goodWindows++
+		if c.goodWindows < cacheShrinkWindows {
+			return
+		}
+		c.goodWindows = 0
+		shrunk := size / 2
+		if shrunk < c.base {
+			shrunk = c.base
+		}
+		_ = c.resize(shrunk)
+	default:
+		c.goodWindows = 0
+	}
+}
+
+// SetCacheBudget sets the total entries the dominator, round and timestamp
+// caches may grow to when their hit rates fall, 0 for fixed sizes. Each
+// cache starts with, and shrinks back to, the cache size of the store.
+func (p *Poset) SetCacheBudget(entries int) {
+	p.cacheTuner.lock.Lock()
+	defer p.cacheTuner.lock.Unlock()
+	p.cacheTuner.budget = entries
+}
+
+// CacheBudget returns the budget of SetCacheBudget
+func (p *Poset) CacheBudget() int {
+	p.cacheTuner.lock.Lock()
+	defer p.cacheTuner.lock.Unlock()
+	return p.cacheTuner.budget
+}
+
+// CheckCacheTriggers returns an error unless a cache may grow below the hit
+// rate growBelow and shrink back above shrinkAbove, as SetCacheTriggers
+// takes them
+func CheckCacheTriggers(growBelow, shrinkAbove float64) error {
+	if growBelow <= 0 || shrinkAbove > 1 || growBelow >= shrinkAbove {
+		return fmt.Errorf("the hit rates must be 0 < %v < %v <= 1", growBelow, shrinkAbove)
+	}
+	return nil
+}
+
+// SetCacheTriggers sets the hit rate under which a cache grows within the
+// budget, and the one a grown cache must keep to shrink back. 0 keeps
+// DefaultCacheGrowBelow or DefaultCacheShrinkAbove.
+func (p *Poset) SetCacheTriggers(growBelow, shrinkAbove float64) error {
+	t := p.cacheTuner
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if growBelow == 0 {
+		growBelow = DefaultCacheGrowBelow
+	}
+	if shrinkAbove == 0 {
+		shrinkAbove = DefaultCacheShrinkAbove
+	}
+	if err := CheckCacheTriggers(growBelow, shrinkAbove); err != nil {
+		return err
+	}
+	t.growBelow, t.shrinkAbove = growBelow, shrinkAbove
+	return nil
+}
+
+// CacheTriggers returns the hit rates of SetCacheTriggers
+func (p *Poset) CacheTriggers() (growBelow, shrinkAbove float64) {
+	p.cacheTuner.lock.Lock()
+	defer p.cacheTuner.lock.Unlock()
+	return p.cacheTuner.triggers()
+}
+
+// CacheStats returns the counters of the dominator, round and timestamp
+// caches
+func (p *Poset) CacheStats() []CacheStats {
+	var stats []CacheStats
+	for _, c := range p.cacheTuner.caches {
+		stats = append(stats, c.stats())
+	}
+	return stats
+}
@@ -0,0 +1,158 @@
+package poset
+
+import (
+	"testing"
+)
+
+// cycleLookups looks up keys 0 to keys-1 in turn, adding the missing ones,
+// n times, and returns the hit rate over these lookups
+func cycleLookups(c *meteredCache, keys, n int) float64 {
+	before := c.stats()
+	for i := 0; i < n; i++ {
+		key := i % keys
+		if _, ok := c.Get(key); !ok {
+			c.Add(key, key)
+		}
+	}
+	after := c.stats()
+	return CacheStats{
+		Hits:   after.Hits - before.Hits,
+		Misses: after.Misses - before.Misses,
+	}.HitRate()
+}
+
+func TestMeteredCacheCounters(t *testing.T) {
+	c, err := newMeteredCache("test", 2, &cacheTuner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Add(1, 1)
+	c.Add(2, 2)
+	c.Add(3, 3) // evicts 1
+	c.Get(1)
+	c.Get(3)
+	c.Peek(2) // not counted
+
+	stats := c.stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Evictions != 1 || stats.Len != 2 {
+		t.Fatalf("expected 1 hit, 1 miss and 1 eviction, got %+v", stats)
+	}
+	if stats.HitRate() != 0.5 {
+		t.Fatalf("expected a hit rate of 0.5, got %v", stats.HitRate())
+	}
+}
+
+func TestMeteredCacheResize(t *testing.T) {
+	const size, keys = 50, 200
+	tuner := &cacheTuner{}
+	c, err := newMeteredCache("undersized", size, tuner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newMeteredCache("other", size, tuner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a cycle over more keys than the cache holds never hits
+	if rate := cycleLookups(c, keys, 4*cacheWindow); rate != 0 {
+		t.Fatalf("expected no hit without resizing, got %v", rate)
+	}
+	if stats := c.stats(); stats.Resizes != 0 || stats.Size != size {
+		t.Fatalf("expected no resize without a budget, got %+v", stats)
+	}
+
+	// the budget is shared with the other cache: 300 entries leave 250 to
+	// the undersized one
+	tuner.budget = 300
+	cycleLookups(c, keys, 4*cacheWindow)
+	stats := c.stats()
+	if stats.Resizes == 0 || stats.Size < keys {
+		t.Fatalf("expected the cache to grow to %d, got %+v", keys, stats)
+	}
+	if total := stats.Size + other.stats().Size; total > tuner.budget {
+		t.Fatalf("expected the caches within the budget of %d, got %d", tuner.budget, total)
+	}
+	if rate := cycleLookups(c, keys, 2*cacheWindow); rate < 0.9 {
+		t.Fatalf("expected the hit rate to improve once grown, got %v", rate)
+	}
+
+	// once the hit rate recovers on a smaller working set, it shrinks back
+	cycleLookups(c, size/2, (cacheShrinkWindows+2)*cacheWindow)
+	if stats := c.stats(); stats.Size != size {
+		t.Fatalf("expected the cache to shrink back to %d, got %+v", size, stats)
+	}
+	if rate := cycleLookups(c, size/2, cacheWindow); rate != 1 {
+		t.Fatalf("expected the working set to stay cached, got %v", rate)
+	}
+}
+
+func TestPosetCacheStats(t *testing.T) {
+	participants, _ := iteratorParticipants()
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetCacheBudget(10 * cacheSize)
+
+	var names []string
+	for _, c := range p.CacheStats() {
+		names = append(names, c.Name)
+		if c.Size != cacheSize {
+			t.Fatalf("expected %s to start with %d items, got %d", c.Name, cacheSize, c.Size)
+		}
+	}
+	if len(names) != 6 {
+		t.Fatalf("expected the 6 dominator, round, timestamp and table caches, got %v", names)
+	}
+}
+
+func TestPosetCacheTriggers(t *testing.T) {
+	participants, _ := iteratorParticipants()
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grow, shrink := p.CacheTriggers(); grow != DefaultCacheGrowBelow || shrink != DefaultCacheShrinkAbove {
+		t.Fatalf("expected the default triggers, got %v and %v", grow, shrink)
+	}
+	if err := p.SetCacheTriggers(0.3, 0.9); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range [][2]float64{{0.9, 0.3}, {0.5, 1.5}, {-0.1, 0.9}} {
+		if err := p.SetCacheTriggers(c[0], c[1]); err == nil {
+			t.Fatalf("expected %v refused", c)
+		}
+	}
+	if grow, shrink := p.CacheTriggers(); grow != 0.3 || shrink != 0.9 {
+		t.Fatalf("expected the triggers 0.3 and 0.9 kept, got %v and %v", grow, shrink)
+	}
+
+	// a third of the lookups hit a hot set, the rest miss: the default
+	// triggers grow the cache, one growing below 0.3 leaves it alone
+	for _, c := range []struct {
+		tuner  *cacheTuner
+		resize bool
+	}{
+		{&cacheTuner{budget: 1000}, true},
+		{&cacheTuner{budget: 1000, growBelow: 0.3, shrinkAbove: 0.9}, false},
+	} {
+		cache, err := newMeteredCache("third", 50, c.tuner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 4*cacheWindow; i++ {
+			key := 1000 + i
+			if i%3 == 0 {
+				key = i % 10
+			}
+			if _, ok := cache.Get(key); !ok {
+				cache.Add(key, key)
+			}
+		}
+		if resized := cache.stats().Resizes > 0; resized != c.resize {
+			t.Fatalf("growing below %v: expected a resize %v, got %+v",
+				c.tuner.growBelow, c.resize, cache.stats())
+		}
+	}
+}
@@ -79,7 +79,9 @@ func (pec *ParticipantEventsCache) Get(participant string, skipIndex int64) (Eve
 
 	res := make(EventHashes, len(pe))
 	for k := 0; k < len(pe); k++ {
-		res[k].Set(pe[k].([]byte))
+		if err := res[k].Set(pe[k].([]byte)); err != nil {
+			return EventHashes{}, err
+		}
 	}
 	return res, nil
 }
@@ -96,7 +98,7 @@ func (pec *ParticipantEventsCache) GetItem(participant string, index int64) (has
 		return
 	}
 
-	hash.Set(item.([]byte))
+	err = hash.Set(item.([]byte))
 	return
 }
 
@@ -112,10 +114,22 @@ func (pec *ParticipantEventsCache) GetLast(participant string) (hash EventHash,
 		return
 	}
 
-	hash.Set(last.([]byte))
+	err = hash.Set(last.([]byte))
 	return
 }
 
+// LastIndex returns the index of the last event of the participant, -1 for
+// none
+func (pec *ParticipantEventsCache) LastIndex(participant string) (int64, error) {
+	id, err := pec.participantID(participant)
+	if err != nil {
+		return -1, err
+	}
+
+	_, last, err := pec.rim.GetLastWindow(id)
+	return last, err
+}
+
 // Set the event for the participant
 func (pec *ParticipantEventsCache) Set(participant string, hash EventHash, index int64) error {
 	id, err := pec.participantID(participant)
@@ -0,0 +1,87 @@
+package poset
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+// Checkpoint states that a frame is final, along with the hash of the frame
+// and the application state hash it led to. The
+// participants sign it so that light clients can check the finality of a
+// frame against peers.json without downloading its events.
+type Checkpoint struct {
+	Frame      int64
+	FrameHash  []byte
+	StateHash  []byte
+	Signatures map[string]string // [validator hex] => signature
+}
+
+// NewCheckpoint creates an unsigned checkpoint
+func NewCheckpoint(frame int64, frameHash, stateHash []byte) Checkpoint {
+	return Checkpoint{
+		Frame:      frame,
+		FrameHash:  frameHash,
+		StateHash:  stateHash,
+		Signatures: make(map[string]string),
+	}
+}
+
+// Hash returns what the participants sign: the Keccak256 hash of the big
+// endian frame number followed by the frame hash and the state hash
+func (c *Checkpoint) Hash() []byte {
+	frame := make([]byte, 8)
+	binary.BigEndian.PutUint64(frame, uint64(c.Frame))
+	return crypto.Keccak256(frame, c.FrameHash, c.StateHash)
+}
+
+// Sign returns the signature of the checkpoint, its Index is the frame
+func (c *Checkpoint) Sign(privKey *ecdsa.PrivateKey) (BlockSignature, error) {
+	return signHash(privKey, c.Frame, c.Hash())
+}
+
+// Verify checks a signature of the checkpoint
+func (c *Checkpoint) Verify(sig BlockSignature) (bool, error) {
+	if sig.Index != c.Frame {
+		return false, fmt.Errorf("signature for frame %d, not %d", sig.Index, c.Frame)
+	}
+	return sig.verifyHash(c.Hash())
+}
+
+// SetSignature adds a verified signature
+func (c *Checkpoint) SetSignature(sig BlockSignature) {
+	c.Signatures[sig.ValidatorHex()] = sig.Signature
+}
+
+// GetSignatures returns the signatures sorted by validator. Validators which
+// are not 0x prefixed hex, such as ones of a checkpoint received from a peer,
+// are skipped.
+func (c *Checkpoint) GetSignatures() []BlockSignature {
+	validators := make([]string, 0, len(c.Signatures))
+	for val := range c.Signatures {
+		validators = append(validators, val)
+	}
+	sort.Strings(validators)
+
+	res := make([]BlockSignature, 0, len(validators))
+	for _, val := range validators {
+		if !strings.HasPrefix(val, "0x") {
+			continue
+		}
+		validatorBytes, err := hex.DecodeString(val[2:])
+		if err != nil {
+			continue
+		}
+		res = append(res, BlockSignature{
+			Validator: validatorBytes,
+			Index:     c.Frame,
+			Signature: c.Signatures[val],
+		})
+	}
+	return res
+}
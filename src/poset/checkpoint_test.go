@@ -0,0 +1,62 @@
+package poset
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+func TestSignCheckpoint(t *testing.T) {
+	checkpoint := NewCheckpoint(7, []byte("framehash"), []byte("statehash"))
+
+	var keys []string
+	for i := 0; i < 3; i++ {
+		privateKey, _ := crypto.GenerateECDSAKey()
+		keys = append(keys, fmt.Sprintf("0x%X", crypto.FromECDSAPub(&privateKey.PublicKey)))
+
+		sig, err := checkpoint.Sign(privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig.Index != checkpoint.Frame {
+			t.Fatalf("expected the signature index to be frame %d, got %d",
+				checkpoint.Frame, sig.Index)
+		}
+		res, err := checkpoint.Verify(sig)
+		if err != nil {
+			t.Fatalf("Error verifying signature: %v", err)
+		}
+		if !res {
+			t.Fatal("Verify returned false")
+		}
+		checkpoint.SetSignature(sig)
+	}
+
+	sigs := checkpoint.GetSignatures()
+	if len(sigs) != len(keys) {
+		t.Fatalf("expected %d signatures, got %d", len(keys), len(sigs))
+	}
+	for i, sig := range sigs {
+		if i > 0 && sigs[i-1].ValidatorHex() >= sig.ValidatorHex() {
+			t.Fatal("expected signatures sorted by validator")
+		}
+		if res, err := checkpoint.Verify(sig); err != nil || !res {
+			t.Fatalf("signature %s does not verify: %v", sig.ValidatorHex(), err)
+		}
+	}
+
+	t.Run("Other state hash", func(t *testing.T) {
+		other := NewCheckpoint(7, []byte("framehash"), []byte("other statehash"))
+		if res, _ := other.Verify(sigs[0]); res {
+			t.Fatal("expected a signature of another state hash not to verify")
+		}
+	})
+
+	t.Run("Other frame", func(t *testing.T) {
+		other := NewCheckpoint(8, []byte("framehash"), []byte("statehash"))
+		if _, err := other.Verify(sigs[0]); err == nil {
+			t.Fatal("expected a signature of another frame to be an error")
+		}
+	})
+}
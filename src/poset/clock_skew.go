@@ -0,0 +1,183 @@
+package poset
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// clockSamples is the number of recent events of other creators the offset
+// of the local clock is estimated from
+const clockSamples = 64
+
+// clockMinSamples is the number of events of other creators needed before
+// any is told from the future
+const clockMinSamples = 8
+
+// clockSkew estimates the offset of the local clock to the clocks of the
+// peers, as the median of the differences between the creation times the
+// recent events of other creators claim and the local time they are
+// inserted at, and counts the events claiming a time beyond the drift from
+// the local clock so adjusted. Events arrive after they are made, the more
+// so as a node catches up, so only a positive offset, that of a local clock
+// late on the peers, adjusts it.
+type clockSkew struct {
+	sync.RWMutex
+	offsets []time.Duration // the last clockSamples offsets, a ring
+	next    int
+	skewed  map[string]uint64 // creator => events from its future
+}
+
+// offset returns the median of the offsets, 0 without any
+func (c *clockSkew) offset() time.Duration {
+	if len(c.offsets) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(c.offsets))
+	copy(sorted, c.offsets)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+// observe accounts for an event of creator claiming to be made at claim,
+// inserted at now. It returns true when the claim is beyond drift from now
+// adjusted by the offset of the previous events, never with a zero drift or
+// before clockMinSamples events.
+func (c *clockSkew) observe(creator string, claim, now time.Time, drift time.Duration) bool {
+	c.Lock()
+	defer c.Unlock()
+	adjust := c.offset()
+	if adjust < 0 {
+		adjust = 0
+	}
+	skewed := drift > 0 && len(c.offsets) >= clockMinSamples &&
+		claim.After(now.Add(adjust+drift))
+	if skewed {
+		if c.skewed == nil {
+			c.skewed = make(map[string]uint64)
+		}
+		c.skewed[creator]++
+	}
+	if len(c.offsets) < clockSamples {
+		c.offsets = append(c.offsets, claim.Sub(now))
+	} else {
+		c.offsets[c.next] = claim.Sub(now)
+		c.next = (c.next + 1) % clockSamples
+	}
+	return skewed
+}
+
+// SetMaxTimestampDrift sets how far in the future of its parents an event
+// may claim to be made, 0 for no bound. The bound decides the times of the
+// blocks, so every participant must use the same.
+func (p *Poset) SetMaxTimestampDrift(d time.Duration) {
+	p.maxTimestampDrift = d
+	p.eventTimeCache.Purge()
+}
+
+// ClockOffset returns the offset of the clocks of the peers to the local
+// one, as estimated from the creation times of their recent events
+func (p *Poset) ClockOffset() time.Duration {
+	p.clockSkew.RLock()
+	defer p.clockSkew.RUnlock()
+	return p.clockSkew.offset()
+}
+
+// SkewedCreators returns the number of events each creator made claiming a
+// time beyond the max timestamp drift from the local clock, adjusted by
+// ClockOffset. It is empty without a max timestamp drift.
+func (p *Poset) SkewedCreators() map[string]uint64 {
+	p.clockSkew.RLock()
+	defer p.clockSkew.RUnlock()
+	skewed := make(map[string]uint64, len(p.clockSkew.skewed))
+	for creator, n := range p.clockSkew.skewed {
+		skewed[creator] = n
+	}
+	return skewed
+}
+
+// checkClockSkew accounts for the creation time claimed by an inserted
+// event of another creator, warning about the ones from the future. The
+// outcome depends on the local clock, so it only goes to the stats; the
+// consensus uses eventTime, computed here for the events to come.
+func (p *Poset) checkClockSkew(event *Event) {
+	p.eventTime(event)
+	if event.Message.Body.Timestamp == 0 ||
+		(p.core != nil && event.GetCreator() == p.core.HexID()) {
+		return
+	}
+	now := p.clock.Now()
+	if p.clockSkew.observe(event.GetCreator(), event.Timestamp(), now, p.maxTimestampDrift) {
+		p.warnLimiter.Warnf(p.logger, "event of %s claims to be made %v after the local clock",
+			event.GetCreator(), event.Timestamp().Sub(now))
+	}
+}
+
+// eventTime returns the creation time of an event counted by the consensus,
+// in Unix nanoseconds, 0 for an event without one. With a max timestamp
+// drift, an event claiming a time beyond it after the time of its
+// other-parent, or of its self-parent for a root without one, counts as
+// made at that bound. The parents are the same on every poset, and so is
+// the time.
+func (p *Poset) eventTime(ev *Event) int64 {
+	claim := ev.Message.Body.Timestamp
+	if p.maxTimestampDrift <= 0 || claim == 0 {
+		return claim
+	}
+	hash := ev.Hash()
+	if t, ok := p.eventTimeCache.Get(hash); ok {
+		return t.(int64)
+	}
+	var ref int64
+	if parent, err := p.Store.GetEventBlock(ev.OtherParent()); err == nil {
+		ref = p.eventTime(&parent)
+	} else if parent, err := p.Store.GetEventBlock(ev.SelfParent()); err == nil {
+		ref = p.eventTime(&parent)
+	}
+	t := claim
+	if bound := ref + int64(p.maxTimestampDrift); ref != 0 && claim > bound {
+		t = bound
+	}
+	p.eventTimeCache.Add(hash, t)
+	return t
+}
+
+// blockTime returns the time of the block of the events, in Unix seconds.
+// With a max timestamp drift it is the median of the times of the events,
+// the same on every poset; otherwise, or for events without times, it is
+// the local time.
+func (p *Poset) blockTime(events []Event) int64 {
+	if p.maxTimestampDrift <= 0 {
+		return p.clock.Now().Unix()
+	}
+	var times []int64
+	for i := range events {
+		if t := p.eventTime(&events[i]); t != 0 {
+			times = append(times, t)
+		}
+	}
+	if len(times) == 0 {
+		return p.clock.Now().Unix()
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return time.Unix(0, times[len(times)/2]).Unix()
+}
+
+// finalFrameTime returns the time of the block of a final frame, see
+// blockTime. The events are only read with a max timestamp drift.
+func (p *Poset) finalFrameTime(frame int64) (int64, error) {
+	if p.maxTimestampDrift <= 0 {
+		return p.clock.Now().Unix(), nil
+	}
+	hashes, err := p.Store.EventsByRoundRange(frame, frame)
+	if err != nil {
+		return 0, err
+	}
+	events := make([]Event, len(hashes))
+	for i, hash := range hashes {
+		if events[i], err = p.Store.GetEventBlock(hash); err != nil {
+			return 0, err
+		}
+	}
+	return p.blockTime(events), nil
+}
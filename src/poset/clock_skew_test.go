@@ -0,0 +1,136 @@
+package poset
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+func TestClockSkewObserve(t *testing.T) {
+	const drift = time.Minute
+	now := time.Unix(1500000000, 0)
+
+	// the local clock is 5 minutes late on its peers
+	var late clockSkew
+	for i := 0; i < clockMinSamples; i++ {
+		if late.observe("a", now.Add(5*time.Minute), now, drift) {
+			t.Fatalf("event %d: expected none from the future before %d events", i, clockMinSamples)
+		}
+	}
+	if offset := late.offset(); offset != 5*time.Minute {
+		t.Fatalf("expected an offset of 5m, got %v", offset)
+	}
+	if late.observe("b", now.Add(5*time.Minute+drift/2), now, drift) {
+		t.Fatal("expected an event within the drift of the adjusted clock")
+	}
+	if !late.observe("c", now.Add(15*time.Minute), now, drift) {
+		t.Fatal("expected an event 10 minutes ahead of the adjusted clock from the future")
+	}
+	if late.observe("c", now.Add(time.Hour), now, 0) {
+		t.Fatal("expected no event from the future without drift")
+	}
+	if expected := map[string]uint64{"c": 1}; !reflect.DeepEqual(late.skewed, expected) {
+		t.Fatalf("expected %v, got %v", expected, late.skewed)
+	}
+
+	// the events arrive an hour after they are made, or the local clock is
+	// an hour ahead, which does not adjust it
+	var ahead clockSkew
+	for i := 0; i < clockMinSamples; i++ {
+		ahead.observe("a", now.Add(-time.Hour), now, drift)
+	}
+	if ahead.observe("b", now.Add(drift/2), now, drift) {
+		t.Fatal("expected an event within the drift of the local clock")
+	}
+	if !ahead.observe("c", now.Add(2*drift), now, drift) {
+		t.Fatal("expected an event beyond the drift of the local clock from the future")
+	}
+}
+
+func TestClockSkewBlockTimes(t *testing.T) {
+	const drift = time.Minute
+	start := time.Unix(1500000000, 0)
+	conf := fixtureConfig{
+		Participants: 4, Events: 200, Seed: 5, Txs: 1, TxSize: 8,
+		Start: start, Step: time.Second,
+	}
+
+	// the first events have no other-parent to bound their times, the
+	// skewed creator is another one. The skew leaves the keys and the
+	// structure of the DAG as they are.
+	plain := newFixture(t, conf)
+	first := plain.events[0].GetCreator()
+	skewed := 0
+	pub := func(i int) string {
+		return fmt.Sprintf("0x%X", crypto.FromECDSAPub(&plain.keys[i].PublicKey))
+	}
+	for pub(skewed) == first {
+		skewed++
+	}
+	conf.Skew = map[int]time.Duration{skewed: 10 * time.Minute}
+	f := newFixture(t, conf)
+	skewedCreator := pub(skewed)
+
+	// the nodes insert the events as they are made, by clocks 2 minutes
+	// late, right and 2 minutes ahead
+	var blockTimes [][]int64
+	for _, offset := range []time.Duration{-2 * time.Minute, 0, 2 * time.Minute} {
+		p, commitCh := f.committingPoset(t)
+		p.SetConsensusParams(ConsensusParams{MaxTimestampDrift: drift})
+		clock := common.NewManualClock(start.Add(offset))
+		p.SetClock(clock)
+		for i, ev := range f.copyEvents() {
+			if err := p.InsertEvent(ev, false); err != nil {
+				t.Fatalf("inserting event %d: %v", i, err)
+			}
+			clock.Advance(conf.Step)
+		}
+		if err := p.RunToQuiescence(); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := range f.events {
+			ev := &f.events[i]
+			claim, made := ev.Message.Body.Timestamp, f.eventTime(i)
+			switch eventTime := p.eventTime(ev); {
+			case ev.GetCreator() != skewedCreator && eventTime != claim:
+				t.Fatalf("event %d: expected the time claimed, %d, got %d", i, claim, eventTime)
+			case ev.GetCreator() == skewedCreator && eventTime > made.Add(drift).UnixNano():
+				t.Fatalf("event %d: expected a time within %v of %v, got %v",
+					i, drift, made, time.Unix(0, eventTime))
+			}
+		}
+
+		var times []int64
+		for _, block := range committedBlocks(commitCh) {
+			times = append(times, block.CreatedTime)
+		}
+		if len(times) == 0 {
+			t.Fatal("expected blocks")
+		}
+		blockTimes = append(blockTimes, times)
+
+		flagged := p.SkewedCreators()
+		if len(flagged) != 1 || flagged[skewedCreator] == 0 {
+			t.Fatalf("clock offset %v: expected %s flagged, got %v", offset, skewedCreator, flagged)
+		}
+	}
+
+	// the block times are those of the events, the same on every node, and
+	// the skewed creator does not push them into the future
+	for i := 1; i < len(blockTimes); i++ {
+		if !reflect.DeepEqual(blockTimes[0], blockTimes[i]) {
+			t.Fatalf("expected the same block times, got %v and %v", blockTimes[0], blockTimes[i])
+		}
+	}
+	last := f.eventTime(len(f.events) - 1).Add(drift).Unix()
+	for i, created := range blockTimes[0] {
+		if created < start.Unix() || created > last {
+			t.Fatalf("block %d: expected a time from %d to %d, got %d", i, start.Unix(), last, created)
+		}
+	}
+}
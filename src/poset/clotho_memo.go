@@ -0,0 +1,87 @@
+package poset
+
+import (
+	"fmt"
+
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+// isClothoCheckMissing returns true for the error of a Clotho check which
+// is not in the store, which the InmemStore and the database report apart
+func isClothoCheckMissing(err error) bool {
+	return common.Is(err, common.KeyNotFound) || isDBKeyNotFound(err)
+}
+
+// clothoCounts are counts of supporting roots, per frame and root hash
+type clothoCounts map[int64]map[EventHash]int64
+
+// clothoSupport counts, for a root, the roots known to the roots of its root
+// table. The roots of both tables are ancestors of the root, added to the
+// Clotho checks before it, so the counts are computed once and reused by
+// every later root of the frame seeing it. They must not be modified.
+func (p *Poset) clothoSupport(root EventHash) (clothoCounts, error) {
+	if c, ok := p.clothoSupportCache.Get(root); ok {
+		return c.(clothoCounts), nil
+	}
+	rootTable, err := p.rootTable(root)
+	if err != nil {
+		return nil, fmt.Errorf("ClothoChecking() prevRootEvent.GetRootTable(): %v", err)
+	}
+
+	res := make(clothoCounts)
+	for rkey, rval := range rootTable {
+		prevRoot, err := p.Store.GetClothoCheck(rval, rkey)
+		if isClothoCheckMissing(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ClothoChecking(): GetClothoCheck(rval, rkey): %v", err)
+		}
+		prevRootTable, err := p.rootTable(prevRoot)
+		if err != nil {
+			return nil, fmt.Errorf("ClothoChecking() prevPrevRootEvent.GetRootTable(): %v", err)
+		}
+
+		for rrkey, rrval := range prevRootTable {
+			_, err := p.Store.GetClothoCheck(rrval, rrkey)
+			if isClothoCheckMissing(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("ClothoChecking(): GetClothoCheck(rrval, rrkey): %v", err)
+			}
+			incCcTemp(res, rrval, rrkey)
+		}
+	}
+	p.clothoSupportCache.Add(root, res)
+	return res, nil
+}
+
+// clothoCounts returns, per frame and root hash, the greatest count of
+// supporting roots over the roots of the root table of a new root
+func (p *Poset) clothoCounts(e *Event) (clothoCounts, error) {
+	ccList := make(clothoCounts)
+	rootTable, err := p.rootTableOf(e)
+	if err != nil {
+		return nil, fmt.Errorf("ClothoChecking() e.GetRootTable(): %v", err)
+	}
+	for key, val := range rootTable {
+		prevRoot, err := p.Store.GetClothoCheck(val, key)
+		if isClothoCheckMissing(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ClothoChecking(): GetClothoCheck(): %v", err)
+		}
+		ccTemp, err := p.clothoSupport(prevRoot)
+		if err != nil {
+			return nil, err
+		}
+		for frame, eventHashMap := range ccTemp {
+			for hash, val := range eventHashMap {
+				updateCcList(ccList, frame, hash, val)
+			}
+		}
+	}
+	return ccList, nil
+}
@@ -0,0 +1,197 @@
+package poset
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// checkRoots checks the roots of the DAG in frame order with a fresh Poset.
+// Unless memoized, ClothoChecking starts from scratch for each root. check
+// is called with each root before it is checked.
+func (d *randomDAG) checkRoots(memoized bool, check func(*Poset, *Event) error) (*Poset, error) {
+	participants := d.copyParticipants()
+	store := NewInmemStore(participants, len(d.roots)+1000, nil)
+	logger := logrus.New()
+	logger.Level = logrus.WarnLevel
+	p, err := NewPoset(participants, store, nil, logrus.NewEntry(logger))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range d.roots {
+		ev := d.roots[i]
+		if !memoized {
+			p.tableCache.Purge()
+			p.clothoSupportCache.Purge()
+		}
+		if err := store.SetEvent(ev); err != nil {
+			return nil, err
+		}
+		if err := store.AddClothoCheck(ev.Frame, ev.CreatorID(), ev.Hash()); err != nil {
+			return nil, err
+		}
+		if err := store.NewTimeTable(ev.Frame, ev.Hash()); err != nil {
+			return nil, err
+		}
+		if check != nil {
+			if err := check(p, &ev); err != nil {
+				return nil, fmt.Errorf("root %d: %v", i, err)
+			}
+		}
+		if err := p.ClothoChecking(&ev); err != nil {
+			return nil, fmt.Errorf("root %d: %v", i, err)
+		}
+	}
+	return p, nil
+}
+
+// clothoCountsUnmemoized walks the root tables from the store as
+// ClothoChecking did before they were memoized
+func (p *Poset) clothoCountsUnmemoized(e *Event) (clothoCounts, error) {
+	ccList := make(clothoCounts)
+	rootTable, err := e.GetRootTable()
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range rootTable {
+		prevRoot, err := p.Store.GetClothoCheck(val, key)
+		if isDBKeyNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		prevRootEvent, err := p.Store.GetEventBlock(prevRoot)
+		if err != nil {
+			return nil, err
+		}
+		prevRootTable, err := prevRootEvent.GetRootTable()
+		if err != nil {
+			return nil, err
+		}
+		ccTemp := make(map[int64]map[EventHash]int64)
+		for rkey, rval := range prevRootTable {
+			prevPrevRoot, err := p.Store.GetClothoCheck(rval, rkey)
+			if isDBKeyNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			prevPrevRootEvent, err := p.Store.GetEventBlock(prevPrevRoot)
+			if err != nil {
+				return nil, err
+			}
+			prevPrevRootTable, err := prevPrevRootEvent.GetRootTable()
+			if err != nil {
+				return nil, err
+			}
+			for rrkey, rrval := range prevPrevRootTable {
+				_, err := p.Store.GetClothoCheck(rrval, rrkey)
+				if isDBKeyNotFound(err) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				incCcTemp(ccTemp, rrval, rrkey)
+			}
+		}
+		for frame, eventHashMap := range ccTemp {
+			for hash, val := range eventHashMap {
+				updateCcList(ccList, frame, hash, val)
+			}
+		}
+	}
+	return ccList, nil
+}
+
+func TestClothoMemoization(t *testing.T) {
+	for _, conf := range []struct {
+		participants, frames int
+		seed                 int64
+	}{
+		{4, 20, 1},
+		{7, 15, 2},
+		{10, 10, 3},
+		{20, 6, 4},
+	} {
+		d := newRootDAG(conf.participants, conf.frames, conf.seed, t)
+
+		memoized, err := d.checkRoots(true, func(p *Poset, e *Event) error {
+			got, err := p.clothoCounts(e)
+			if err != nil {
+				return err
+			}
+			want, err := p.clothoCountsUnmemoized(e)
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(got, want) {
+				return fmt.Errorf("expected the counts %v, got %v", want, got)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%+v: %v", conf, err)
+		}
+		reference, err := d.checkRoots(false, nil)
+		if err != nil {
+			t.Fatalf("%+v: %v", conf, err)
+		}
+
+		clothos := 0
+		for i := range d.roots {
+			hash := d.roots[i].Hash()
+			frame := d.roots[i].Frame
+			x, err := memoized.Store.GetEventBlock(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			y, err := reference.Store.GetEventBlock(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if x.Clotho != y.Clotho {
+				t.Fatalf("%+v: root %d: expected Clotho %v, got %v", conf, i, y.Clotho, x.Clotho)
+			}
+			if x.Clotho {
+				clothos++
+			}
+			xTable, err := memoized.Store.GetTimeTable(frame, hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			yTable, err := reference.Store.GetTimeTable(frame, hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(xTable, yTable) {
+				t.Fatalf("%+v: root %d: expected the time table %v, got %v", conf, i, yTable, xTable)
+			}
+		}
+		if clothos == 0 {
+			t.Fatalf("%+v: expected Clothos", conf)
+		}
+	}
+}
+
+func BenchmarkClothoChecking(b *testing.B) {
+	d := newRootDAG(20, 10, 1, b)
+	for _, memoized := range []bool{true, false} {
+		name := "unmemoized"
+		if memoized {
+			name = "memoized"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := d.checkRoots(memoized, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package poset
+
+import (
+	"encoding/binary"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+// CoinFlip is the vote of the clotho y on the fame of the clotho x in a
+// coin round, when y sees no super-majority either way. Every node must
+// flip the same coin for the same (round, x, y), or they decide different
+// atropos, so the flip is defined byte for byte: it is the lowest bit of
+//
+//	Keccak256(round as 8 bytes big-endian || x || y)
+//
+// Mixing in the round and x makes y flip independently in every coin round
+// and for every clotho it votes on, instead of repeating the flip taken
+// from its own hash. Zero hashes are not special, they are hashed like any
+// other.
+func CoinFlip(round int64, x, y EventHash) bool {
+	var r [8]byte
+	binary.BigEndian.PutUint64(r[:], uint64(round))
+	h := crypto.Keccak256(r[:], x.Bytes(), y.Bytes())
+	return h[len(h)-1]&1 == 1
+}
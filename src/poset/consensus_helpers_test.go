@@ -0,0 +1,93 @@
+package poset
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+func randomEventHash(rng *rand.Rand) EventHash {
+	var hash EventHash
+	rng.Read(hash[:])
+	return hash
+}
+
+func TestCoinFlipDefinition(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	x, y := randomEventHash(rng), randomEventHash(rng)
+	for _, c := range []struct {
+		round int64
+		x, y  EventHash
+	}{
+		{0, EventHash{}, EventHash{}},
+		{12, EventHash{}, y},
+		{-1, x, EventHash{}},
+		{1 << 40, x, y},
+	} {
+		// the round goes first, in 8 bytes big-endian
+		data := make([]byte, 8, 8+2*len(c.x))
+		for i := 0; i < 8; i++ {
+			data[i] = byte(uint64(c.round) >> uint(56-8*i))
+		}
+		data = append(data, c.x[:]...)
+		data = append(data, c.y[:]...)
+		h := crypto.Keccak256(data)
+		if expected := h[len(h)-1]&1 == 1; CoinFlip(c.round, c.x, c.y) != expected {
+			t.Fatalf("round %d: expected the flip %v", c.round, expected)
+		}
+	}
+}
+
+func TestCoinFlipDeterministic(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 100; i++ {
+		round, x, y := rng.Int63(), randomEventHash(rng), randomEventHash(rng)
+		flip := CoinFlip(round, x, y)
+		for j := 0; j < 3; j++ {
+			if CoinFlip(round, x, y) != flip {
+				t.Fatalf("round %d: the flip changed between calls", round)
+			}
+		}
+	}
+}
+
+func TestCoinFlipBalanced(t *testing.T) {
+	const flips = 10000
+	rng := rand.New(rand.NewSource(3))
+	heads := 0
+	for i := 0; i < flips; i++ {
+		if CoinFlip(rng.Int63n(1000), randomEventHash(rng), randomEventHash(rng)) {
+			heads++
+		}
+	}
+	// more than 10 standard deviations from flips/2
+	if heads < flips*45/100 || heads > flips*55/100 {
+		t.Fatalf("expected about %d heads out of %d flips, got %d", flips/2, flips, heads)
+	}
+}
+
+func TestCoinFlipChangesWithRound(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < 100; i++ {
+		x, y := randomEventHash(rng), randomEventHash(rng)
+		first := CoinFlip(0, x, y)
+		changed := false
+		for round := int64(1); round < 64 && !changed; round++ {
+			changed = CoinFlip(round, x, y) != first
+		}
+		if !changed {
+			t.Fatalf("expected the flip of %s for %s to change within 64 rounds", y.String(), x.String())
+		}
+	}
+
+	// the clotho voted on changes the flip as well
+	y := randomEventHash(rng)
+	flips := make(map[bool]int)
+	for i := 0; i < 64; i++ {
+		flips[CoinFlip(11, randomEventHash(rng), y)]++
+	}
+	if len(flips) != 2 {
+		t.Fatalf("expected the flip of %s to depend on the clotho, got %v", y.String(), flips)
+	}
+}
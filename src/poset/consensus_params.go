@@ -0,0 +1,88 @@
+package poset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+// ConsensusParams are the settings deciding which events and blocks are
+// valid. Nodes with different ones diverge, so every participant must use
+// the same, which peers check with their hash when they sync.
+type ConsensusParams struct {
+	// MaxEventsPerFrame caps the events a creator may make per frame, 0 for
+	// no cap
+	MaxEventsPerFrame int `json:"max_events_per_frame"`
+	// MaxBlockTransactions and MaxBlockBytes bound the transactions of a
+	// block, in count and in total bytes, 0 for no bound. They are left out
+	// of the hash when not set.
+	MaxBlockTransactions int `json:"max_block_transactions,omitempty"`
+	MaxBlockBytes        int `json:"max_block_bytes,omitempty"`
+	// DevSingleSuperMajority makes any single participant a supermajority,
+	// see peers.Peers.SetDevSingleSuperMajority. It is UNSAFE, for local
+	// development networks only, and left out of the hash when not set.
+	DevSingleSuperMajority bool `json:"dev_single_supermajority,omitempty"`
+	// MaxTimestampDrift bounds how far in the future of its other-parent an
+	// event may claim to be made, the claims beyond counting as made at the
+	// bound, and makes the time of a block the median of the times of its
+	// events. 0 for no bound, the blocks then taking the local time, and
+	// left out of the hash when not set.
+	MaxTimestampDrift time.Duration `json:"max_timestamp_drift,omitempty"`
+}
+
+// Hash returns the hash identifying the parameters. They are hashed in
+// their JSON encoding, where the fields keep their declaration order.
+func (c ConsensusParams) Hash() common.Hash {
+	data, _ := json.Marshal(c)
+	return crypto.Keccak256Hash(data)
+}
+
+// Diff returns the parameters differing from other, one per line as
+// "name: ours != theirs"
+func (c ConsensusParams) Diff(other ConsensusParams) []string {
+	var diff []string
+	ours, theirs := reflect.ValueOf(c), reflect.ValueOf(other)
+	for i := 0; i < ours.NumField(); i++ {
+		a, b := ours.Field(i).Interface(), theirs.Field(i).Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		name := strings.Split(ours.Type().Field(i).Tag.Get("json"), ",")[0]
+		diff = append(diff, fmt.Sprintf("%s: %v != %v", name, a, b))
+	}
+	return diff
+}
+
+// ReadConsensusParams reads the parameters from a JSON file, the one
+// operators distribute to agree on them. Unknown fields are refused, they
+// would be parameters this version does not apply.
+func ReadConsensusParams(path string) (ConsensusParams, error) {
+	var params ConsensusParams
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return params, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&params); err != nil {
+		return params, fmt.Errorf("%s: %v", path, err)
+	}
+	return params, nil
+}
+
+// SetConsensusParams applies the consensus parameters
+func (p *Poset) SetConsensusParams(params ConsensusParams) {
+	p.SetMaxEventsPerFrame(params.MaxEventsPerFrame)
+	p.SetBlockBudget(params.MaxBlockTransactions, params.MaxBlockBytes)
+	p.SetMaxTimestampDrift(params.MaxTimestampDrift)
+	if params.DevSingleSuperMajority != p.Participants.DevSingleSuperMajority() {
+		p.Participants.SetDevSingleSuperMajority(params.DevSingleSuperMajority)
+	}
+}
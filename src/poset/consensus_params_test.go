@@ -0,0 +1,64 @@
+package poset
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConsensusParamsHash(t *testing.T) {
+	a := ConsensusParams{MaxEventsPerFrame: 3}
+	b := ConsensusParams{MaxEventsPerFrame: 3}
+	c := ConsensusParams{MaxEventsPerFrame: 5}
+
+	if a.Hash() != b.Hash() {
+		t.Fatal("expected the same parameters to have the same hash")
+	}
+	if a.Hash() == c.Hash() {
+		t.Fatal("expected other parameters to have another hash")
+	}
+
+	if diff := a.Diff(b); len(diff) != 0 {
+		t.Fatalf("expected no diff, got %v", diff)
+	}
+	expected := []string{"max_events_per_frame: 3 != 5"}
+	if diff := a.Diff(c); !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("expected %v, got %v", expected, diff)
+	}
+
+	// the development thresholds cannot be mixed with the others
+	dev := ConsensusParams{MaxEventsPerFrame: 3, DevSingleSuperMajority: true}
+	if a.Hash() == dev.Hash() {
+		t.Fatal("expected the development thresholds to change the hash")
+	}
+}
+
+func TestReadConsensusParams(t *testing.T) {
+	dir, err := ioutil.TempDir("", "params")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "consensus.json")
+
+	if err := ioutil.WriteFile(path, []byte(`{"max_events_per_frame": 4}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	params, err := ReadConsensusParams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.MaxEventsPerFrame != 4 {
+		t.Fatalf("expected 4 events per frame, got %d", params.MaxEventsPerFrame)
+	}
+
+	// a parameter this version does not know is refused
+	if err := ioutil.WriteFile(path, []byte(`{"coin_round_period": 4}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadConsensusParams(path); err == nil {
+		t.Fatal("expected an unknown parameter to be refused")
+	}
+}
@@ -0,0 +1,149 @@
+package poset
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+// Inconsistency is a round or lamport timestamp of an event, cached by the
+// Poset or stored, which differs from the one recomputed from its parents
+type Inconsistency struct {
+	Event    string `json:"event"`
+	Field    string `json:"field"`  // "round" or "lamport"
+	Source   string `json:"source"` // "cache" or "store"
+	Value    int64  `json:"value"`
+	Expected int64  `json:"expected"`
+}
+
+// VerifyCaches checks the rounds and lamport timestamps of the last n
+// undetermined events, all of them if n <= 0, against a recomputation from
+// the store. The entries found wrong are invalidated, and DivideRounds is run
+// again on the checked events to assign them their recomputed values.
+func (p *Poset) VerifyCaches(n int) ([]Inconsistency, error) {
+	undetermined, epoch := p.undeterminedSnapshot()
+	if n > 0 && n < len(undetermined) {
+		undetermined = undetermined[len(undetermined)-n:]
+	}
+
+	var found []Inconsistency
+	// in insertion order the parents are checked before their children, so
+	// the recomputation of an event does not build on a wrong entry
+	for _, hash := range undetermined {
+		ev, err := p.Store.GetEventBlock(hash)
+		if err != nil {
+			return found, err
+		}
+
+		cached, err := p.recompute(p.roundCache, "round", hash, p.round)
+		if err != nil {
+			return found, err
+		}
+		found = append(found, cached...)
+		for _, inc := range cached {
+			removed, err := p.removeFromRound(hash, inc.Value)
+			if err != nil {
+				return found, err
+			}
+			if removed {
+				found = append(found, Inconsistency{hash.String(), "round", "store", inc.Value, inc.Expected})
+			}
+		}
+
+		cached, err = p.recompute(p.timestampCache, "lamport", hash, p.lamportTimestamp)
+		if err != nil {
+			return found, err
+		}
+		found = append(found, cached...)
+		if ev.HasLamport() {
+			lamport := ev.GetLamportTimestamp()
+			expected, err := p.lamportTimestamp(hash)
+			if err != nil {
+				return found, err
+			}
+			if lamport != expected {
+				found = append(found, Inconsistency{hash.String(), "lamport", "store", lamport, expected})
+				ev.SetLamportTimestamp(LamportTimestampNIL)
+				if err := p.Store.SetEvent(ev); err != nil {
+					return found, err
+				}
+			}
+		}
+	}
+
+	for _, inc := range found {
+		p.logger.WithFields(logrus.Fields{
+			"event":    inc.Event,
+			"field":    inc.Field,
+			"source":   inc.Source,
+			"value":    inc.Value,
+			"expected": inc.Expected,
+		}).Warn("inconsistent event")
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	p.requeueUndivided(undetermined, epoch)
+	return found, p.DivideRounds()
+}
+
+// recompute drops the cached value of an event and computes it again. A
+// cached value which differs is returned as a cache Inconsistency; it is put
+// back if the recomputation fails.
+func (p *Poset) recompute(cache *meteredCache, field string, hash EventHash,
+	compute func(EventHash) (int64, error)) ([]Inconsistency, error) {
+	c, ok := cache.Peek(hash)
+	cache.Remove(hash)
+	value, err := compute(hash)
+	if err != nil {
+		if ok {
+			cache.Add(hash, c)
+		}
+		return nil, err
+	}
+	if !ok || c.(int64) == value {
+		return nil, nil
+	}
+	return []Inconsistency{{hash.String(), field, "cache", c.(int64), value}}, nil
+}
+
+// removeFromRound removes an event from the events created in a round, and
+// returns whether it was there. A round left empty leaves the PendingRounds.
+func (p *Poset) removeFromRound(hash EventHash, round int64) (bool, error) {
+	roundInfo, err := p.Store.GetRound(round)
+	if err != nil {
+		if common.Is(err, common.KeyNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, ok := roundInfo.Message.Events[hash.String()]; !ok {
+		return false, nil
+	}
+	delete(roundInfo.Message.Events, hash.String())
+	if len(roundInfo.Message.Events) == 0 && roundInfo.Message.Queued {
+		roundInfo.Message.Queued = false
+		pending := p.PendingRounds[:0]
+		for _, r := range p.PendingRounds {
+			if r.Index != round {
+				pending = append(pending, r)
+			}
+		}
+		p.PendingRounds = pending
+	}
+	return true, p.Store.SetRound(round, roundInfo)
+}
+
+// purgeCaches empties the caches of the Poset
+func (p *Poset) purgeCaches() {
+	p.dominatorCache.Purge()
+	p.selfDominatorCache.Purge()
+	p.strictlyDominatedCache.Purge()
+	p.roundCache.Purge()
+	p.timestampCache.Purge()
+	p.tableCache.Purge()
+	p.clothoSupportCache.Purge()
+	p.eventTimeCache.Purge()
+	p.wireSizeCache.Purge()
+	p.atroposVotes = make(map[int64]map[EventHash]map[EventHash]bool)
+}
@@ -0,0 +1,189 @@
+package poset
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// consistencyEvents returns events in rounds: each creator makes an event
+// per round, on the head of the next creator
+func consistencyEvents() (*peers.Peers, []Event) {
+	participants, keys := iteratorParticipants()
+	heads := make([]*Event, len(keys))
+	var events []Event
+	for round := 0; round < 4; round++ {
+		for i, key := range keys {
+			other := EventHash{}
+			if round > 0 {
+				other = heads[(i+1)%len(keys)].Hash()
+			}
+			ev := capEvent(participants, key, heads[i], other, fmt.Sprintf("%d %d", round, i))
+			ev.Message.CreatorID = participants.ByPubKey[ev.GetCreator()].ID
+			ev.Message.TopologicalIndex = int64(len(events))
+			heads[i] = &ev
+			events = append(events, ev)
+		}
+	}
+	return participants, events
+}
+
+// consistencyPoset returns a fresh Poset with the events set and their
+// rounds divided. If corrupt is set, it is called before the rounds are
+// divided.
+func consistencyPoset(t *testing.T, ps *peers.Peers, events []Event,
+	corrupt func(*Poset, []EventHash)) *Poset {
+	participants := peers.NewPeers()
+	for _, peer := range ps.ToPeerSlice() {
+		peer := peers.NewPeer(peer.Message.PubKeyHex, peer.Message.NetAddr)
+		participants.AddPeer(peer)
+		participants.SetPeerWeight(peer, 1)
+	}
+	store := NewInmemStore(participants, cacheSize, nil)
+	logger := logrus.New()
+	logger.Level = logrus.FatalLevel
+	p, err := NewPoset(participants, store, nil, logrus.NewEntry(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hashes []EventHash
+	for _, ev := range events {
+		if err := store.SetEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, ev.Hash())
+	}
+	p.UndeterminedEvents = hashes
+	p.undivided = hashes
+
+	if corrupt != nil {
+		corrupt(p, hashes)
+	}
+	if err := p.DivideRounds(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// checkConsistent checks the rounds and lamport timestamps of p are those
+// of expected
+func checkConsistent(t *testing.T, p, expected *Poset) {
+	for _, hash := range expected.UndeterminedEvents {
+		for _, pos := range []*Poset{p, expected} {
+			if _, err := pos.round(hash); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r, _ := p.round(hash)
+		er, _ := expected.round(hash)
+		if r != er {
+			t.Fatalf("%v: expected round %d, got %d", hash, er, r)
+		}
+		rc, err := p.Store.GetRound(er)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := rc.Message.Events[hash.String()]; !ok {
+			t.Fatalf("%v: expected in round %d", hash, er)
+		}
+
+		ev, err := p.Store.GetEventBlock(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eev, err := expected.Store.GetEventBlock(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.GetLamportTimestamp() != eev.GetLamportTimestamp() {
+			t.Fatalf("%v: expected lamport timestamp %d, got %d", hash,
+				eev.GetLamportTimestamp(), ev.GetLamportTimestamp())
+		}
+	}
+}
+
+func TestVerifyCachesConsistent(t *testing.T) {
+	participants, events := consistencyEvents()
+	p := consistencyPoset(t, participants, events, nil)
+	found, err := p.VerifyCaches(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Fatalf("expected no inconsistency, got %+v", found)
+	}
+}
+
+func TestVerifyCachesRepairs(t *testing.T) {
+	participants, events := consistencyEvents()
+	expected := consistencyPoset(t, participants, events, nil)
+
+	var corrupted EventHash
+	p := consistencyPoset(t, participants, events, func(p *Poset, hashes []EventHash) {
+		corrupted = hashes[len(hashes)/2]
+		p.roundCache.Add(corrupted, int64(1))
+		p.timestampCache.Add(corrupted, int64(42))
+	})
+
+	found, err := p.VerifyCaches(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]bool)
+	for _, inc := range found {
+		kinds[inc.Field+" "+inc.Source] = true
+		if inc.Event == corrupted.String() && inc.Field == "round" && inc.Value != 1 {
+			t.Fatalf("expected the corrupted round 1, got %+v", inc)
+		}
+	}
+	for _, kind := range []string{"round cache", "round store", "lamport cache", "lamport store"} {
+		if !kinds[kind] {
+			t.Fatalf("expected a %s inconsistency, got %+v", kind, found)
+		}
+	}
+
+	// the event left the round it was wrongly put in
+	rc, err := p.Store.GetRound(1)
+	if err != nil && !common.Is(err, common.KeyNotFound) {
+		t.Fatal(err)
+	}
+	if _, ok := rc.Message.Events[corrupted.String()]; ok {
+		t.Fatalf("expected %v to be removed from round 1", corrupted)
+	}
+	checkConsistent(t, p, expected)
+
+	// the repaired poset is consistent, and goes on with consensus
+	if found, err := p.VerifyCaches(0); err != nil || len(found) != 0 {
+		t.Fatalf("expected no inconsistency left, got %+v, %v", found, err)
+	}
+	if err := p.DecideAtropos(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.DecideRoundReceived(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyCachesLast(t *testing.T) {
+	participants, events := consistencyEvents()
+	var corrupted EventHash
+	p := consistencyPoset(t, participants, events, func(p *Poset, hashes []EventHash) {
+		corrupted = hashes[0]
+		p.timestampCache.Add(corrupted, int64(42))
+	})
+	// the first event is not among the last ones
+	found, err := p.VerifyCaches(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inc := range found {
+		if inc.Event == corrupted.String() {
+			t.Fatalf("expected %v not to be verified, got %+v", corrupted, inc)
+		}
+	}
+}
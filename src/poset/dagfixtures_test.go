@@ -0,0 +1,225 @@
+package poset
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// fixtureConfig describes a DAG of signed events. The same config always
+// gives the same DAG, keys and event hashes included, so that benchmark
+// results can be compared from one run to the next.
+type fixtureConfig struct {
+	Participants int
+	Events       int
+	Seed         int64
+	// Txs is the number of transactions of each event, of TxSize bytes
+	Txs    int
+	TxSize int
+	// Start, when set, is the creation time of the first event, each next
+	// one made Step later. Skew shifts the times the creators claim, by
+	// creator index.
+	Start time.Time
+	Step  time.Duration
+	Skew  map[int]time.Duration
+}
+
+// fixture is a generated DAG
+type fixture struct {
+	config fixtureConfig
+	keys   []*ecdsa.PrivateKey
+	// events are in creation order, which is topological
+	events []Event
+}
+
+// fixtureKey derives a P256 key from the generator, crypto.GenerateECDSAKey
+// reads crypto/rand
+func fixtureKey(rng *rand.Rand) *ecdsa.PrivateKey {
+	curve := elliptic.P256()
+	b := make([]byte, 32)
+	rng.Read(b)
+	d := new(big.Int).SetBytes(b)
+	d.Mod(d, new(big.Int).Sub(curve.Params().N, big.NewInt(1)))
+	d.Add(d, big.NewInt(1))
+
+	key := &ecdsa.PrivateKey{D: d}
+	key.PublicKey.Curve = curve
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d.Bytes())
+	return key
+}
+
+// newFixture generates a DAG. Each event has the head of its creator as
+// self-parent and the head of another participant as other-parent, except
+// for the first events made before anybody else created one. Few
+// participants give a deep DAG, many a wide one.
+func newFixture(tb testing.TB, conf fixtureConfig) *fixture {
+	if conf.Participants < 2 {
+		tb.Fatalf("at least 2 participants are needed, got %d", conf.Participants)
+	}
+	rng := rand.New(rand.NewSource(conf.Seed))
+
+	f := &fixture{config: conf}
+	for i := 0; i < conf.Participants; i++ {
+		f.keys = append(f.keys, fixtureKey(rng))
+	}
+	participants := f.newParticipants()
+
+	ids := make([]uint64, conf.Participants)
+	heads := make([]EventHash, conf.Participants)
+	counts := make([]int64, conf.Participants)
+	for i, key := range f.keys {
+		pub := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey))
+		ids[i] = participants.ByPubKey[pub].ID
+		heads[i] = GenRootSelfParent(ids[i])
+	}
+	for i := 0; i < conf.Events; i++ {
+		creator := rng.Intn(conf.Participants)
+		var others []int
+		for j := range heads {
+			if j != creator && counts[j] > 0 {
+				others = append(others, j)
+			}
+		}
+		var otherHead EventHash
+		var otherID uint64
+		otherIndex := int64(-1)
+		if len(others) > 0 {
+			other := others[rng.Intn(len(others))]
+			otherHead, otherID, otherIndex = heads[other], ids[other], counts[other]-1
+		}
+
+		txs := make([][]byte, conf.Txs)
+		for j := range txs {
+			txs[j] = make([]byte, conf.TxSize)
+			rng.Read(txs[j])
+		}
+		key := f.keys[creator]
+		event := NewEvent(txs, nil, nil,
+			EventHashes{heads[creator], otherHead},
+			crypto.FromECDSAPub(&key.PublicKey), counts[creator],
+			NewFlagTable(), NewFlagTable(), FrameNIL, false)
+		// as the Posets inserting the events do not set it
+		event.SetWireInfo(counts[creator]-1, otherID, otherIndex, ids[creator])
+		if !conf.Start.IsZero() {
+			event.SetTimestamp(f.eventTime(i).Add(conf.Skew[creator]))
+		}
+		if err := event.Sign(key); err != nil {
+			tb.Fatal(err)
+		}
+
+		f.events = append(f.events, event)
+		heads[creator] = event.Hash()
+		counts[creator]++
+	}
+	return f
+}
+
+// eventTime returns the time the i-th event is made at, without skew
+func (f *fixture) eventTime(i int) time.Time {
+	return f.config.Start.Add(time.Duration(i) * f.config.Step)
+}
+
+// newParticipants returns a new peer set of the DAG participants, each
+// Poset needs its own
+func (f *fixture) newParticipants() *peers.Peers {
+	participants := peers.NewPeers()
+	for i, key := range f.keys {
+		participants.AddPeer(peers.NewPeer(
+			fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)),
+			fmt.Sprintf("127.0.0.1:%d", 1337+i)))
+	}
+	return participants
+}
+
+// copyEvents returns copies of the events, which a Poset can insert without
+// changing the fixture
+func (f *fixture) copyEvents() []Event {
+	res := make([]Event, len(f.events))
+	for i := range f.events {
+		res[i] = f.events[i].Message.ToEvent()
+	}
+	return res
+}
+
+// emptyPoset returns a Poset of the DAG participants backed by an
+// InmemStore large enough for the whole DAG
+func (f *fixture) emptyPoset(tb testing.TB) *Poset {
+	p, _ := f.committingPosetTo(tb, nil)
+	return p
+}
+
+// committingPoset returns an emptyPoset committing its blocks to a channel
+// large enough for the whole DAG
+func (f *fixture) committingPoset(tb testing.TB) (*Poset, chan Block) {
+	return f.committingPosetTo(tb, make(chan Block, 2*len(f.events)+10))
+}
+
+// committingPosetTo returns an emptyPoset committing its blocks to
+// commitCh, nil for none
+func (f *fixture) committingPosetTo(tb testing.TB, commitCh chan Block) (*Poset, chan Block) {
+	logger := logrus.New()
+	logger.Level = logrus.WarnLevel
+	participants := f.newParticipants()
+	store := NewInmemStore(participants, len(f.events)+1000, nil)
+	p, err := NewPoset(participants, store, commitCh, logrus.NewEntry(logger))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return p, commitCh
+}
+
+// committedBlocks closes the channel of a committingPoset and returns the
+// blocks it committed
+func committedBlocks(commitCh chan Block) []Block {
+	close(commitCh)
+	var blocks []Block
+	for block := range commitCh {
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// newPoset returns an emptyPoset with the events inserted and, when
+// consensus is set, their consensus run
+func (f *fixture) newPoset(tb testing.TB, consensus bool) *Poset {
+	p := f.emptyPoset(tb)
+	for i, ev := range f.copyEvents() {
+		if err := p.InsertEvent(ev, false); err != nil {
+			tb.Fatalf("inserting event %d: %v", i, err)
+		}
+	}
+	if consensus {
+		if err := p.RunToQuiescence(); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return p
+}
+
+func TestFixtureDeterministic(t *testing.T) {
+	conf := fixtureConfig{Participants: 4, Events: 50, Seed: 3, Txs: 2, TxSize: 16}
+	a, b := newFixture(t, conf), newFixture(t, conf)
+	for i := range a.events {
+		if x, y := a.events[i].Hash(), b.events[i].Hash(); x != y {
+			t.Fatalf("event %d: %s, %s", i, x.String(), y.String())
+		}
+	}
+
+	conf.Seed++
+	c := newFixture(t, conf)
+	if x, y := a.events[0].Hash(), c.events[0].Hash(); x == y {
+		t.Fatal("expected another seed to give another DAG")
+	}
+
+	// the events are valid, in a topological order
+	a.newPoset(t, false)
+}
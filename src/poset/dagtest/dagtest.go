@@ -0,0 +1,247 @@
+// Package dagtest generates random valid DAGs of signed events and checks
+// that Posets fed with the same DAG in different orders reach the same
+// consensus.
+package dagtest
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/rand"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// Config describes the DAG to generate
+type Config struct {
+	Participants int
+	Events       int
+	// Seed drives the topology, the delivery orders and the knobs below.
+	// Keys are random, so event hashes differ from one run to the next.
+	Seed int64
+
+	// ForkRate is the probability for an event to get a fork: an event of
+	// the same creator with the same parents and index, which the Posets
+	// must reject
+	ForkRate float64
+	// MissingParentRate is the probability, at each delivery, to first
+	// deliver an event before one of its parents, which the Posets must
+	// reject then and accept once its parents are in
+	MissingParentRate float64
+	// PeerAdds is the number of events carrying a PEER_ADD internal
+	// transaction for a new peer
+	PeerAdds int
+}
+
+// Fork is an event forking the creator of Events[Of]
+type Fork struct {
+	Of    int
+	Event poset.Event
+}
+
+// DAG is a generated set of events
+type DAG struct {
+	Config Config
+	// Events are the valid events in creation order, which is topological
+	Events []poset.Event
+	Forks  []Fork
+
+	pubKeys  []string
+	netAddrs []string
+	// parents are the indexes in Events of the parents of each event
+	parents [][]int
+}
+
+// Generate creates a random DAG. Each event has the head of its creator as
+// self-parent and the head of another participant as other-parent, except
+// for the first events made before anybody else created one.
+func Generate(conf Config) (*DAG, error) {
+	if conf.Participants < 2 {
+		return nil, fmt.Errorf("at least 2 participants are needed, got %d", conf.Participants)
+	}
+	rng := rand.New(rand.NewSource(conf.Seed))
+
+	d := &DAG{Config: conf}
+	keys := make([]*ecdsa.PrivateKey, conf.Participants)
+	for i := range keys {
+		key, err := crypto.GenerateECDSAKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+		d.pubKeys = append(d.pubKeys, fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)))
+		d.netAddrs = append(d.netAddrs, fmt.Sprintf("127.0.0.1:%d", 1337+i))
+	}
+	participants := d.NewParticipants()
+
+	peerAdds := make(map[int]bool)
+	if conf.PeerAdds > conf.Events {
+		conf.PeerAdds = conf.Events
+	}
+	for _, i := range rng.Perm(conf.Events)[:conf.PeerAdds] {
+		peerAdds[i] = true
+	}
+
+	heads := make([]int, conf.Participants) // index of the head in Events, -1 for none
+	for i := range heads {
+		heads[i] = -1
+	}
+	counts := make([]int64, conf.Participants)
+	for i := 0; i < conf.Events; i++ {
+		creator := rng.Intn(conf.Participants)
+		var others []int
+		for j, head := range heads {
+			if j != creator && head >= 0 {
+				others = append(others, j)
+			}
+		}
+
+		var parents []int
+		selfHead := poset.GenRootSelfParent(participants.ByPubKey[d.pubKeys[creator]].ID)
+		if heads[creator] >= 0 {
+			selfHead = d.Events[heads[creator]].Hash()
+			parents = append(parents, heads[creator])
+		}
+		var otherHead poset.EventHash
+		if len(others) > 0 {
+			other := heads[others[rng.Intn(len(others))]]
+			otherHead = d.Events[other].Hash()
+			parents = append(parents, other)
+		}
+
+		var internalTxs []poset.InternalTransaction
+		if peerAdds[i] {
+			key, err := crypto.GenerateECDSAKey()
+			if err != nil {
+				return nil, err
+			}
+			peer := peers.NewPeer(fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)),
+				fmt.Sprintf("127.0.0.1:%d", 2337+i))
+			internalTxs = append(internalTxs, poset.InternalTransaction{
+				Type: poset.TransactionType_PEER_ADD,
+				Peer: peer.Message,
+			})
+		}
+
+		tx := []byte(fmt.Sprintf("seed %d event %d", conf.Seed, i))
+		event, err := newEvent(keys[creator], tx, internalTxs, selfHead, otherHead, counts[creator])
+		if err != nil {
+			return nil, err
+		}
+		if rng.Float64() < conf.ForkRate {
+			fork, err := newEvent(keys[creator], []byte(string(tx)+" fork"), nil,
+				selfHead, otherHead, counts[creator])
+			if err != nil {
+				return nil, err
+			}
+			d.Forks = append(d.Forks, Fork{Of: i, Event: fork})
+		}
+
+		d.Events = append(d.Events, event)
+		d.parents = append(d.parents, parents)
+		heads[creator] = i
+		counts[creator]++
+	}
+	return d, nil
+}
+
+func newEvent(key *ecdsa.PrivateKey, tx []byte, internalTxs []poset.InternalTransaction,
+	selfHead, otherHead poset.EventHash, index int64) (poset.Event, error) {
+	event := poset.NewEvent([][]byte{tx}, internalTxs, nil,
+		poset.EventHashes{selfHead, otherHead},
+		crypto.FromECDSAPub(&key.PublicKey), index,
+		poset.NewFlagTable(), poset.NewFlagTable(), poset.FrameNIL, false)
+	if err := event.Sign(key); err != nil {
+		return poset.Event{}, err
+	}
+	return event, nil
+}
+
+// NewParticipants returns a new peer set of the DAG participants, each Poset
+// needs its own
+func (d *DAG) NewParticipants() *peers.Peers {
+	participants := peers.NewPeers()
+	for i, pubKey := range d.pubKeys {
+		participants.AddPeer(peers.NewPeer(pubKey, d.netAddrs[i]))
+	}
+	return participants
+}
+
+// Delivery is an event handed to a Poset
+type Delivery struct {
+	Event poset.Event
+	// Reject is set when the Poset must refuse the event: a fork, or an
+	// event delivered before its parents
+	Reject bool
+}
+
+// Order returns a random delivery order of the DAG. Valid events come in a
+// random topological order, forks come at random after the event they fork
+// and, with MissingParentRate, events are tried before their parents.
+func (d *DAG) Order(rng *rand.Rand) []Delivery {
+	children := make([][]int, len(d.Events))
+	missing := make([]int, len(d.Events))
+	var ready []int
+	for i, parents := range d.parents {
+		missing[i] = len(parents)
+		for _, p := range parents {
+			children[p] = append(children[p], i)
+		}
+		if missing[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	forks := make(map[int][]poset.Event)
+	for _, f := range d.Forks {
+		forks[f.Of] = append(forks[f.Of], f.Event)
+	}
+
+	var (
+		res      []Delivery
+		released []poset.Event
+	)
+	for len(ready) > 0 {
+		if rng.Float64() < d.Config.MissingParentRate {
+			var early []int
+			for i, m := range missing {
+				if m > 0 {
+					early = append(early, i)
+				}
+			}
+			if len(early) > 0 {
+				res = append(res, Delivery{Event: d.Events[early[rng.Intn(len(early))]], Reject: true})
+			}
+		}
+		if len(released) > 0 && rng.Intn(2) == 0 {
+			j := rng.Intn(len(released))
+			res = append(res, Delivery{Event: released[j], Reject: true})
+			released = append(released[:j], released[j+1:]...)
+		}
+
+		j := rng.Intn(len(ready))
+		next := ready[j]
+		ready = append(ready[:j], ready[j+1:]...)
+		res = append(res, Delivery{Event: d.Events[next]})
+		released = append(released, forks[next]...)
+		for _, c := range children[next] {
+			missing[c]--
+			if missing[c] == 0 {
+				ready = append(ready, c)
+			}
+		}
+	}
+	for _, fork := range released {
+		res = append(res, Delivery{Event: fork, Reject: true})
+	}
+	return res
+}
+
+// CreationOrder returns the valid events in the order they were created
+func (d *DAG) CreationOrder() []Delivery {
+	res := make([]Delivery, len(d.Events))
+	for i, e := range d.Events {
+		res[i] = Delivery{Event: e}
+	}
+	return res
+}
@@ -0,0 +1,153 @@
+package dagtest
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// scale multiplies the size of the generated DAGs and the number of orders,
+// set DAGTEST_SCALE for longer nightly runs
+func scale(t *testing.T) int {
+	s := os.Getenv("DAGTEST_SCALE")
+	if s == "" {
+		return 1
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		t.Fatalf("invalid DAGTEST_SCALE %q", s)
+	}
+	return n
+}
+
+func TestGenerate(t *testing.T) {
+	d, err := Generate(Config{Participants: 4, Events: 40, Seed: 1, ForkRate: 0.2, PeerAdds: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Events) != 40 {
+		t.Fatalf("expected 40 events, got %d", len(d.Events))
+	}
+	if len(d.Forks) == 0 {
+		t.Fatal("expected forks")
+	}
+	peerAdds := 0
+	for _, e := range d.Events {
+		peerAdds += len(e.InternalTransactions())
+	}
+	if peerAdds != 3 {
+		t.Fatalf("expected 3 PEER_ADD transactions, got %d", peerAdds)
+	}
+
+	// every order delivers each valid event once, after its parents
+	valid := make(map[string]bool)
+	for _, e := range d.Events {
+		hash := e.Hash()
+		valid[hash.String()] = true
+	}
+	order := d.Order(rand.New(rand.NewSource(2)))
+	delivered := make(map[string]bool)
+	for i, delivery := range order {
+		hash := delivery.Event.Hash()
+		if delivery.Reject {
+			continue
+		}
+		if delivered[hash.String()] {
+			t.Fatalf("delivery %d: event delivered twice", i)
+		}
+		for _, parent := range []poset.EventHash{delivery.Event.SelfParent(),
+			delivery.Event.OtherParent()} {
+			if valid[parent.String()] && !delivered[parent.String()] {
+				t.Fatalf("delivery %d: event delivered before its parent", i)
+			}
+		}
+		delivered[hash.String()] = true
+	}
+	if len(delivered) != len(d.Events) {
+		t.Fatalf("expected %d delivered events, got %d", len(d.Events), len(delivered))
+	}
+
+	if _, err := Generate(Config{Participants: 1, Events: 1}); err == nil {
+		t.Fatal("expected an error for a single participant")
+	}
+}
+
+func TestDeliveryOrders(t *testing.T) {
+	n := scale(t)
+	for _, conf := range []Config{
+		{Participants: 3, Events: 100 * n, Seed: 1},
+		{Participants: 4, Events: 100 * n, Seed: 2, ForkRate: 0.1},
+		{Participants: 4, Events: 100 * n, Seed: 3, MissingParentRate: 0.3},
+		{Participants: 5, Events: 150 * n, Seed: 4, ForkRate: 0.05,
+			MissingParentRate: 0.1, PeerAdds: 5},
+	} {
+		d, err := Generate(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the orders are only compared on something if blocks are made
+		res, err := d.Deliver(d.CreationOrder(), common.NewTestLogger(t))
+		if err != nil {
+			t.Fatalf("%+v: %v", conf, err)
+		}
+		if len(res.Blocks) == 0 {
+			t.Fatalf("%+v: expected blocks", conf)
+		}
+		if err := d.Check(3*n, common.NewTestLogger(t)); err != nil {
+			t.Fatalf("%+v: %v", conf, err)
+		}
+	}
+}
+
+// TestStandalone runs the consensus pipeline with no core and no commit
+// channel, which must not need either
+func TestStandalone(t *testing.T) {
+	d, err := Generate(Config{Participants: 4, Events: 80, Seed: 5, ForkRate: 0.05})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "standalone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	participants := d.NewParticipants()
+	badgerStore, err := poset.NewBadgerStore(participants, len(d.Events)+cacheMargin,
+		filepath.Join(dir, "badger"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer badgerStore.Close()
+	stores := map[string]poset.Store{
+		"inmem":  poset.NewInmemStore(participants, len(d.Events)+cacheMargin, nil),
+		"badger": badgerStore,
+	}
+	for name, store := range stores {
+		p, err := poset.NewStandalonePoset(participants, store,
+			logrus.NewEntry(common.NewTestLogger(t)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr := p.Address(); addr != poset.UnknownAddress {
+			t.Fatalf("%s: expected the address %q, got %q", name, poset.UnknownAddress, addr)
+		}
+		if err := run(p, d.CreationOrder()); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for i := range d.Events {
+			hash := d.Events[i].Hash()
+			if _, err := p.Store.GetEventBlock(hash); err != nil {
+				t.Fatalf("%s: event %s: %v", name, hash.String(), err)
+			}
+		}
+	}
+}
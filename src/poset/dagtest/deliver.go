@@ -0,0 +1,192 @@
+package dagtest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// cacheMargin is added to the number of events to size the store caches,
+// which must not evict anything during a run
+const cacheMargin = 1000
+
+// Block is what must not depend on the delivery order of a committed block
+type Block struct {
+	Index         int64
+	RoundReceived int64
+	FrameHash     string
+	TxHashes      []string
+}
+
+// EventInfo is what a Poset computed for an event
+type EventInfo struct {
+	Frame         int64
+	Root          bool
+	Clotho        bool
+	Atropos       bool
+	FrameReceived int64
+}
+
+// Result is the outcome of one delivery order
+type Result struct {
+	Blocks          []Block
+	ConsensusEvents []string
+	// Events are keyed by event hash
+	Events map[string]EventInfo
+}
+
+// Deliver inserts the deliveries into a fresh Poset backed by an InmemStore,
+// running it to quiescence after each accepted event. It fails when an event
+// is accepted or refused against its Delivery.Reject.
+func (d *DAG) Deliver(deliveries []Delivery, logger *logrus.Logger) (*Result, error) {
+	if logger == nil {
+		logger = logrus.New()
+		logger.Level = logrus.WarnLevel
+	}
+
+	participants := d.NewParticipants()
+	store := poset.NewInmemStore(participants, len(d.Events)+cacheMargin, nil)
+	commitCh := make(chan poset.Block, 400)
+	p, err := poset.NewPoset(participants, store, commitCh, logrus.NewEntry(logger))
+	if err != nil {
+		return nil, err
+	}
+
+	// consume the commit channel here, there is no node to do it
+	var blocks []poset.Block
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for block := range commitCh {
+			blocks = append(blocks, block)
+		}
+	}()
+
+	err = run(p, deliveries)
+	close(commitCh)
+	<-done
+	if err != nil {
+		return nil, err
+	}
+	return d.result(p, blocks)
+}
+
+func run(p *poset.Poset, deliveries []Delivery) error {
+	for i, delivery := range deliveries {
+		ev := delivery.Event.Message.ToEvent()
+		hash := ev.Hash()
+		err := p.InsertEvent(ev, true)
+		if delivery.Reject {
+			if err == nil {
+				return fmt.Errorf("delivery %d: event %s accepted, expected a rejection",
+					i, hash.String())
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("delivery %d: inserting event %s: %v", i, hash.String(), err)
+		}
+		if err := p.RunToQuiescence(); err != nil {
+			return fmt.Errorf("delivery %d: running consensus: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func (d *DAG) result(p *poset.Poset, blocks []poset.Block) (*Result, error) {
+	res := &Result{Events: make(map[string]EventInfo, len(d.Events))}
+	for _, block := range blocks {
+		b := Block{
+			Index:         block.Index(),
+			RoundReceived: block.RoundReceived(),
+		}
+		if frame, err := p.Store.GetFrame(block.RoundReceived()); err == nil {
+			hash, err := frame.Hash()
+			if err != nil {
+				return nil, err
+			}
+			b.FrameHash = fmt.Sprintf("%X", hash)
+		}
+		for _, tx := range block.Transactions() {
+			b.TxHashes = append(b.TxHashes, fmt.Sprintf("%X", crypto.Keccak256(tx)))
+		}
+		res.Blocks = append(res.Blocks, b)
+	}
+	for _, hash := range p.Store.ConsensusEvents() {
+		res.ConsensusEvents = append(res.ConsensusEvents, hash.String())
+	}
+	for i := range d.Events {
+		hash := d.Events[i].Hash()
+		ev, err := p.Store.GetEventBlock(hash)
+		if err != nil {
+			return nil, fmt.Errorf("event %s: %v", hash.String(), err)
+		}
+		res.Events[hash.String()] = EventInfo{
+			Frame:         ev.Frame,
+			Root:          ev.Root,
+			Clotho:        ev.Clotho,
+			Atropos:       ev.Atropos,
+			FrameReceived: ev.FrameReceived,
+		}
+	}
+	return res, nil
+}
+
+// Compare returns an error describing the first difference between two
+// results
+func Compare(a, b *Result) error {
+	if len(a.Blocks) != len(b.Blocks) {
+		return fmt.Errorf("%d blocks, %d blocks", len(a.Blocks), len(b.Blocks))
+	}
+	for i := range a.Blocks {
+		x, y := a.Blocks[i], b.Blocks[i]
+		if x.Index != y.Index || x.RoundReceived != y.RoundReceived ||
+			x.FrameHash != y.FrameHash ||
+			strings.Join(x.TxHashes, ",") != strings.Join(y.TxHashes, ",") {
+			return fmt.Errorf("block %d differs: %+v, %+v", i, x, y)
+		}
+	}
+
+	if len(a.ConsensusEvents) != len(b.ConsensusEvents) {
+		return fmt.Errorf("%d consensus events, %d consensus events",
+			len(a.ConsensusEvents), len(b.ConsensusEvents))
+	}
+	for i := range a.ConsensusEvents {
+		if a.ConsensusEvents[i] != b.ConsensusEvents[i] {
+			return fmt.Errorf("consensus event %d differs: %s, %s",
+				i, a.ConsensusEvents[i], b.ConsensusEvents[i])
+		}
+	}
+
+	for hash, x := range a.Events {
+		if y, ok := b.Events[hash]; !ok || x != y {
+			return fmt.Errorf("event %s differs: %+v, %+v", hash, x, y)
+		}
+	}
+	return nil
+}
+
+// Check delivers the DAG in its creation order, then in as many random
+// orders as given, drawn from the DAG seed, and compares the results
+func (d *DAG) Check(orders int, logger *logrus.Logger) error {
+	expected, err := d.Deliver(d.CreationOrder(), logger)
+	if err != nil {
+		return fmt.Errorf("creation order: %v", err)
+	}
+	rng := rand.New(rand.NewSource(d.Config.Seed))
+	for i := 0; i < orders; i++ {
+		res, err := d.Deliver(d.Order(rng), logger)
+		if err != nil {
+			return fmt.Errorf("order %d: %v", i, err)
+		}
+		if err := Compare(expected, res); err != nil {
+			return fmt.Errorf("order %d against the creation order: %v", i, err)
+		}
+	}
+	return nil
+}
@@ -28,3 +28,6 @@ func (p *Poset) PrintStat(logger *logrus.Entry) {
 //func (s *InmemStore) TopologicalEvents() ([]Event, error) {
 //	return []Event{}, nil
 //}
+
+// strictEventHash makes EventHash.Set panic on bytes of the wrong length
+const strictEventHash = true
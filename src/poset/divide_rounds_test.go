@@ -0,0 +1,111 @@
+package poset
+
+import (
+	"reflect"
+	"testing"
+)
+
+// divideRoundsAll divides all the undetermined events again, as
+// DivideRounds did before it kept the queue of the events to divide
+func (p *Poset) divideRoundsAll() error {
+	undetermined, epoch := p.undeterminedSnapshot()
+	p.requeueUndivided(undetermined, epoch)
+	return p.DivideRounds()
+}
+
+// divideFixture inserts the events of the fixture in a fresh Poset, and
+// calls divide after every batch of them
+func divideFixture(t *testing.T, f *fixture, batch int, divide func(*Poset) error) *Poset {
+	p := f.emptyPoset(t)
+	for i, ev := range f.copyEvents() {
+		if err := p.InsertEvent(ev, false); err != nil {
+			t.Fatalf("inserting event %d: %v", i, err)
+		}
+		if (i+1)%batch == 0 {
+			if err := divide(p); err != nil {
+				t.Fatalf("dividing after event %d: %v", i, err)
+			}
+		}
+	}
+	if err := divide(p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDivideRoundsQueue(t *testing.T) {
+	for _, conf := range []fixtureConfig{
+		{Participants: 4, Events: 400, Seed: 1},
+		{Participants: 7, Events: 500, Seed: 2},
+		{Participants: 16, Events: 600, Seed: 3},
+	} {
+		f := newFixture(t, conf)
+		for _, batch := range []int{1, 7, 50} {
+			queued := divideFixture(t, f, batch, (*Poset).DivideRounds)
+			reference := divideFixture(t, f, batch, (*Poset).divideRoundsAll)
+
+			if n := len(queued.undivided); n != 0 {
+				t.Fatalf("%+v batch %d: %d events left to divide", conf, batch, n)
+			}
+			if !reflect.DeepEqual(queued.PendingRounds, reference.PendingRounds) {
+				t.Fatalf("%+v batch %d: expected the pending rounds %v, got %v",
+					conf, batch, reference.PendingRounds, queued.PendingRounds)
+			}
+			for r := int64(0); r <= reference.Store.LastRound(); r++ {
+				x, err := queued.Store.GetRound(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				y, err := reference.Store.GetRound(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(x.Message.Events, y.Message.Events) {
+					t.Fatalf("%+v batch %d: round %d: expected the events %v, got %v",
+						conf, batch, r, y.Message.Events, x.Message.Events)
+				}
+			}
+			for _, ev := range f.events {
+				x, err := queued.Store.GetEventBlock(ev.Hash())
+				if err != nil {
+					t.Fatal(err)
+				}
+				y, err := reference.Store.GetEventBlock(ev.Hash())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if x.GetLamportTimestamp() != y.GetLamportTimestamp() {
+					t.Fatalf("%+v batch %d: expected the lamport timestamp %d, got %d",
+						conf, batch, y.GetLamportTimestamp(), x.GetLamportTimestamp())
+				}
+			}
+		}
+	}
+}
+
+func TestDivideRoundsRequeuesOnError(t *testing.T) {
+	f := newFixture(t, fixtureConfig{Participants: 4, Events: 50, Seed: 1})
+	p := f.newPoset(t, false)
+
+	// an event missing from the store fails the division, and waits for the
+	// next one with the events after it
+	missing := CalcEventHash([]byte("missing"))
+	p.undeterminedEventsLocker.Lock()
+	p.undivided = append(EventHashes{p.undivided[0], missing}, p.undivided[1:]...)
+	p.undeterminedEventsLocker.Unlock()
+	if err := p.DivideRounds(); err == nil {
+		t.Fatal("expected an error for an event missing from the store")
+	}
+	if n := len(p.undivided); n != len(f.events) {
+		t.Fatalf("expected %d events left to divide, got %d", len(f.events), n)
+	}
+	if p.undivided[0] != missing {
+		t.Fatalf("expected the failed event first, got %v", p.undivided[0])
+	}
+
+	// the events taken before a Reset are not put back
+	p.requeueUndivided(EventHashes{missing}, p.undeterminedEventsEpoch-1)
+	if n := len(p.undivided); n != len(f.events) {
+		t.Fatalf("expected the events of an old epoch dropped, got %d", n)
+	}
+}
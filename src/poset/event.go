@@ -5,7 +5,9 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"reflect"
+	"time"
 
+	"github.com/SamuelMarks/dag1/src/common"
 	"github.com/SamuelMarks/dag1/src/crypto"
 	"github.com/SamuelMarks/dag1/src/peers"
 	"github.com/golang/protobuf/proto"
@@ -81,7 +83,20 @@ func (e *EventBody) Equals(that *EventBody) bool {
 		reflect.DeepEqual(e.Parents, that.Parents) &&
 		reflect.DeepEqual(e.Creator, that.Creator) &&
 		e.Index == that.Index &&
-		BlockSignatureListEquals(e.BlockSignatures, that.BlockSignatures)
+		BlockSignatureListEquals(e.BlockSignatures, that.BlockSignatures) &&
+		reflect.DeepEqual(e.TransactionFlags, that.TransactionFlags) &&
+		e.Timestamp == that.Timestamp &&
+		reflect.DeepEqual(e.NetworkID, that.NetworkID) &&
+		e.Version == that.Version
+}
+
+// TransactionFlag returns the flags byte of the i-th transaction. Bodies
+// without flags, e.g. from old clients, report zero for every transaction.
+func (e *EventBody) TransactionFlag(i int) byte {
+	if i < len(e.TransactionFlags) {
+		return e.TransactionFlags[i]
+	}
+	return 0
 }
 
 // ProtoMarshal marshal event body to protobuff
@@ -112,18 +127,13 @@ func (e *EventBody) Hash() (hash EventHash, err error) {
 Event
 *******************************************************************************/
 
-// LamportTimestampNIL nil value for lamport
-const LamportTimestampNIL int64 = -1
-
-// FrameNIL nil value for event frame number
-const FrameNIL int64 = -1
-
 // ToEvent converts message to event
 func (m *EventMessage) ToEvent() Event {
 	ft := NewFlagTable()
 	return Event{
 		Message:          m,
 		LamportTimestamp: LamportTimestampNIL,
+		AtroposTimestamp: AtroposTimestampNIL,
 		Frame:            FrameNIL,
 		FlagTableBytes:   ft.Marshal(),
 		RootTableBytes:   ft.Marshal(),
@@ -174,6 +184,7 @@ func NewEvent(
 //			FlagTable: ft.Marshal(),
 		},
 		LamportTimestamp: LamportTimestampNIL,
+		AtroposTimestamp: AtroposTimestampNIL,
 		FlagTableBytes:   ft.Marshal(),
 		RootTableBytes:   rt.Marshal(),
 		Frame:            Frame,
@@ -196,13 +207,13 @@ func (e *Event) GetRound() int64 {
 //	if e.round < 0 {
 //		return RoundNIL
 //	}
-	return FrameNIL //e.round
+	return RoundNIL //e.round
 }
 
 // GetRoundReceived Round returns round in which the event is received.
 func (e *Event) GetRoundReceived() int64 {
 //	if e.roundReceived < 0 {
-		return FrameNIL
+		return RoundNIL
 //	}
 //	return e.round
 }
@@ -220,15 +231,17 @@ func (e *Event) GetCreator() string {
 	return fmt.Sprintf("0x%X", e.Message.Body.Creator)
 }
 
-// SelfParent returns the previous event block hash in this creator DAG
+// SelfParent returns the previous event block hash in this creator DAG. The
+// parents are checked by InsertEvent, a malformed one is the zero hash.
 func (e *Event) SelfParent() (hash EventHash) {
-	hash.Set(e.Message.Body.Parents[0])
+	_ = hash.Set(e.Message.Body.Parents[0])
 	return
 }
 
-// OtherParent returns the other (not creators) parent(s) hash(es)
+// OtherParent returns the other (not creators) parent(s) hash(es), as
+// SelfParent
 func (e *Event) OtherParent() (hash EventHash) {
-	hash.Set(e.Message.Body.Parents[1])
+	_ = hash.Set(e.Message.Body.Parents[1])
 	return
 }
 
@@ -247,6 +260,54 @@ func (e *Event) Index() int64 {
 	return e.Message.Body.Index
 }
 
+// SetTransactionFlags sets the per-transaction flags. They are only kept
+// when at least one is non-zero so unflagged events hash as before.
+func (e *Event) SetTransactionFlags(flags []byte) {
+	for _, f := range flags {
+		if f != 0 {
+			e.Message.Body.TransactionFlags = append([]byte(nil), flags...)
+			return
+		}
+	}
+	e.Message.Body.TransactionFlags = nil
+}
+
+// SetTimestamp sets the creation wall clock of the event, before signing
+func (e *Event) SetTimestamp(t time.Time) {
+	e.Message.Body.Timestamp = t.UnixNano()
+}
+
+// Timestamp returns the creation wall clock of the event, the zero time for
+// events from old clients
+func (e *Event) Timestamp() time.Time {
+	if e.Message.Body.Timestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, e.Message.Body.Timestamp)
+}
+
+// SetNetworkID sets the network the event is made for, before signing
+func (e *Event) SetNetworkID(id common.Hash) {
+	e.Message.Body.NetworkID = id.Bytes()
+}
+
+// NetworkID returns the network the event was made for, the zero hash for
+// events from old clients
+func (e *Event) NetworkID() common.Hash {
+	return common.BytesToHash(e.Message.Body.NetworkID)
+}
+
+// SetVersion sets the version of the body, before signing
+func (e *Event) SetVersion(version uint32) {
+	e.Message.Body.Version = version
+}
+
+// Version returns the version of the body, EventVersionLegacy for events
+// from old clients
+func (e *Event) Version() uint32 {
+	return e.Message.Body.Version
+}
+
 // BlockSignatures returns all block signatures for this event
 func (e *Event) BlockSignatures() []*BlockSignature {
 	return e.Message.Body.BlockSignatures
@@ -342,6 +403,13 @@ func (e *Event) Hash() (hash EventHash) {
 	return
 }
 
+// WireSize returns the size of the event marshalled, which the sync
+// responses are budgeted in. Event and EventMessage are generated, so the
+// size is computed on each call, Poset.WireSize caches it.
+func (e *Event) WireSize() int {
+	return proto.Size(e.Message)
+}
+
 // SetRound for event
 func (e *Event) SetRound(r int64) {
 //	e.round = r
@@ -395,6 +463,10 @@ func (e *Event) ToWire() WireEvent {
 			CreatorID:            e.Message.CreatorID,
 			Index:                e.Message.Body.Index,
 			BlockSignatures:      e.WireBlockSignatures(),
+			TransactionFlags:     e.Message.Body.TransactionFlags,
+			Timestamp:            e.Message.Body.Timestamp,
+			NetworkID:            e.Message.Body.NetworkID,
+			Version:              e.Message.Body.Version,
 		},
 		Signature:   e.Message.Signature,
 //		FlagTable:   e.Message.FlagTable,
@@ -428,28 +500,15 @@ func (e *Event) GetRootTable() (FlagTable, error) {
 	return res, err
 }
 
-// MergeFlagTable returns merged flag table object.
+// MergeFlagTable returns the flag table of the event merged with dst at
+// frame, as MergeFlagTables does.
 func (e *Event) MergeFlagTable(dst FlagTable, Frame int64) (FlagTable, error) {
-	res := NewFlagTable()
 	src := NewFlagTable()
 	err := src.Unmarshal(e.FlagTableBytes)
 	if err != nil {
 		return nil, err
 	}
-
-	for id, frame := range src {
-		if frame == Frame {
-			res[id] = frame
-		}
-	}
-
-	for id, frame := range dst {
-		_, exists := res[id]
-		if !exists && frame == Frame {
-			res[id] = frame
-		}
-	}
-	return res, nil
+	return MergeFlagTables(src, dst, Frame), nil
 }
 
 // CreatorID returns the creator ID for an event
@@ -512,6 +571,11 @@ type WireBody struct {
 	CreatorID            uint64
 
 	Index int64
+
+	TransactionFlags []byte
+	Timestamp        int64
+	NetworkID        []byte
+	Version          uint32
 }
 
 // WireEvent struct
@@ -108,6 +108,10 @@ type EventBody struct {
 	Creator              []byte                 `protobuf:"bytes,4,opt,name=Creator,json=creator,proto3" json:"Creator,omitempty"`
 	Index                int64                  `protobuf:"varint,5,opt,name=Index,json=index" json:"Index,omitempty"`
 	BlockSignatures      []*BlockSignature      `protobuf:"bytes,6,rep,name=BlockSignatures,json=blockSignatures" json:"BlockSignatures,omitempty"`
+	TransactionFlags     []byte                 `protobuf:"bytes,7,opt,name=TransactionFlags,json=transactionFlags,proto3" json:"TransactionFlags,omitempty"`
+	Timestamp            int64                  `protobuf:"varint,8,opt,name=Timestamp,json=timestamp" json:"Timestamp,omitempty"`
+	NetworkID            []byte                 `protobuf:"bytes,9,opt,name=NetworkID,json=networkID,proto3" json:"NetworkID,omitempty"`
+	Version              uint32                 `protobuf:"varint,10,opt,name=Version,json=version,proto3" json:"Version,omitempty"`
 }
 
 func (m *EventBody) Reset()                    { *m = EventBody{} }
@@ -157,6 +161,34 @@ func (m *EventBody) GetBlockSignatures() []*BlockSignature {
 	return nil
 }
 
+func (m *EventBody) GetTransactionFlags() []byte {
+	if m != nil {
+		return m.TransactionFlags
+	}
+	return nil
+}
+
+func (m *EventBody) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *EventBody) GetNetworkID() []byte {
+	if m != nil {
+		return m.NetworkID
+	}
+	return nil
+}
+
+func (m *EventBody) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 type EventMessage struct {
 	Body                 *EventBody `protobuf:"bytes,1,opt,name=Body,json=body" json:"Body,omitempty"`
 	Signature            string     `protobuf:"bytes,2,opt,name=Signature,json=signature" json:"Signature,omitempty"`
@@ -353,50 +385,54 @@ func init() {
 func init() { proto.RegisterFile("event.proto", fileDescriptor1) }
 
 var fileDescriptor1 = []byte{
-	// 720 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x94, 0xdf, 0x6b, 0xfa, 0x48,
-	0x14, 0xc5, 0x57, 0x13, 0x7f, 0xe4, 0x9a, 0x6a, 0x98, 0xba, 0x25, 0x94, 0x7d, 0x10, 0x29, 0x45,
-	0x84, 0x2a, 0xb8, 0xcf, 0xcb, 0xa2, 0xad, 0xb2, 0x85, 0x6d, 0x2b, 0xa3, 0xf4, 0xb5, 0x8c, 0x71,
-	0xd4, 0xb0, 0x49, 0x26, 0xcc, 0x8c, 0x65, 0x7d, 0xdc, 0xd7, 0xfd, 0x83, 0xf6, 0x61, 0xff, 0xba,
-	0x2f, 0x73, 0x13, 0xdb, 0x28, 0xbe, 0x08, 0xf7, 0xdc, 0x3b, 0x67, 0xe6, 0x7c, 0x66, 0x0c, 0x34,
-	0xf8, 0x27, 0x4f, 0xf4, 0x20, 0x95, 0x42, 0x0b, 0x52, 0x49, 0x85, 0xe2, 0xfa, 0xf6, 0xb7, 0x6d,
-	0xa8, 0x77, 0xfb, 0xd5, 0x20, 0x10, 0xf1, 0x70, 0xc6, 0x12, 0x2d, 0xe2, 0x87, 0x8d, 0xd8, 0x27,
-	0x6b, 0xa6, 0x43, 0x91, 0x0c, 0xb7, 0xe2, 0x21, 0x62, 0xc1, 0x8e, 0xab, 0x50, 0x0d, 0x95, 0x0c,
-	0x86, 0x29, 0xe7, 0x52, 0xe1, 0x6f, 0xe6, 0xd2, 0xfd, 0xa7, 0x04, 0xd7, 0xcf, 0x89, 0xe6, 0x32,
-	0x61, 0xd1, 0x52, 0xb2, 0x44, 0xb1, 0xc0, 0x2c, 0x24, 0x7d, 0xb0, 0x97, 0x87, 0x94, 0xfb, 0xa5,
-	0x4e, 0xa9, 0xd7, 0x1c, 0xdd, 0x0c, 0x70, 0xb3, 0x41, 0x61, 0xc2, 0x74, 0xa9, 0xad, 0x0f, 0x29,
-	0x27, 0xf7, 0x60, 0x1b, 0x47, 0xbf, 0xdc, 0x29, 0xf5, 0x1a, 0x23, 0x32, 0xc0, 0x4d, 0x06, 0x73,
-	0xce, 0xe5, 0x0b, 0x57, 0x8a, 0x6d, 0x39, 0xc5, 0x3e, 0xb9, 0x81, 0xea, 0x38, 0x16, 0xfb, 0x44,
-	0xfb, 0x56, 0xa7, 0xd4, 0xb3, 0x69, 0x95, 0x61, 0xd5, 0x5d, 0x41, 0x73, 0x12, 0x89, 0xe0, 0xaf,
-	0x45, 0xb8, 0x4d, 0x98, 0xde, 0x4b, 0x4e, 0x7e, 0x01, 0xe7, 0x9d, 0x45, 0xe1, 0x9a, 0x69, 0x21,
-	0xf1, 0x08, 0x2e, 0x75, 0x3e, 0x8f, 0x02, 0x69, 0x43, 0xe5, 0x39, 0x59, 0xf3, 0xbf, 0x71, 0x43,
-	0x8b, 0x56, 0x42, 0x53, 0x98, 0x35, 0x5f, 0x06, 0xb8, 0x81, 0x43, 0x1d, 0x75, 0x14, 0xba, 0xff,
-	0x96, 0xc1, 0x99, 0x1a, 0x7a, 0x13, 0xb1, 0x3e, 0x90, 0x2e, 0xb8, 0x85, 0x28, 0xca, 0x2f, 0x75,
-	0xac, 0x9e, 0x4b, 0x5d, 0x5d, 0xd0, 0xc8, 0x2b, 0xb4, 0x2f, 0x80, 0x51, 0x7e, 0xb9, 0x63, 0xf5,
-	0x1a, 0xa3, 0xdb, 0x9c, 0xc8, 0x85, 0x11, 0xda, 0x0e, 0x2f, 0xac, 0x23, 0x3e, 0xd4, 0xe6, 0x4c,
-	0xf2, 0x44, 0x2b, 0xdf, 0xc2, 0xed, 0x6a, 0x69, 0x56, 0x9a, 0xce, 0xa3, 0xe4, 0x98, 0xd5, 0xc6,
-	0xac, 0xb5, 0x20, 0x2b, 0xbf, 0x93, 0x56, 0x8a, 0x49, 0x7f, 0x87, 0xd6, 0x29, 0x2f, 0xe5, 0x57,
-	0xf1, 0x50, 0x3f, 0xe7, 0x87, 0x3a, 0xed, 0xd2, 0xd6, 0xea, 0x74, 0xba, 0xfb, 0x7f, 0x19, 0x5c,
-	0x84, 0x91, 0xdf, 0x0f, 0xb9, 0x03, 0xdb, 0x70, 0x41, 0xd4, 0x8d, 0x91, 0x97, 0xdb, 0x7c, 0xf1,
-	0xa2, 0xf6, 0xca, 0x50, 0x3b, 0x21, 0x5c, 0x3e, 0x23, 0x4c, 0x7a, 0xd0, 0x5a, 0xf0, 0x68, 0x93,
-	0x65, 0xcc, 0x4e, 0x6d, 0xe1, 0xa9, 0x5b, 0xea, 0x54, 0x26, 0x23, 0x68, 0xbf, 0xe9, 0x1d, 0x97,
-	0x99, 0x96, 0x47, 0x7f, 0x7e, 0xc2, 0xf0, 0x36, 0x6d, 0x8b, 0x0b, 0x3d, 0xd2, 0x07, 0xaf, 0xb0,
-	0xa6, 0x08, 0xc5, 0x13, 0x67, 0xba, 0x39, 0xe7, 0xb7, 0x69, 0x15, 0x4d, 0x9d, 0xa0, 0xe8, 0xb4,
-	0x14, 0xa9, 0x88, 0xc4, 0x36, 0x0c, 0x58, 0x94, 0x39, 0xd5, 0x32, 0x27, 0x7d, 0xa6, 0x13, 0x02,
-	0xf6, 0x1f, 0x4c, 0xed, 0xfc, 0x3a, 0x5e, 0x8b, 0xbd, 0x63, 0x6a, 0xd7, 0xfd, 0xcf, 0x82, 0x0a,
-	0x92, 0x21, 0x0f, 0x50, 0xcb, 0x01, 0xe6, 0xe0, 0xae, 0x8b, 0xe0, 0x8e, 0x6f, 0xbf, 0x16, 0xe7,
-	0x90, 0xfb, 0xe0, 0xfd, 0xc9, 0xe2, 0x54, 0x48, 0xbd, 0x0c, 0x63, 0xae, 0x34, 0x8b, 0xd3, 0xfc,
-	0x05, 0x7b, 0xd1, 0x99, 0x6e, 0x2e, 0x7e, 0x26, 0x59, 0xcc, 0x73, 0x84, 0x95, 0x8d, 0x29, 0xc8,
-	0x3d, 0x34, 0x67, 0x11, 0xdb, 0x2e, 0xd9, 0x2a, 0xe2, 0x93, 0x83, 0xe6, 0x2a, 0x7f, 0x2f, 0xcd,
-	0xcd, 0x89, 0x6a, 0xe6, 0xa8, 0x10, 0xba, 0x30, 0x57, 0xc9, 0xe6, 0xe4, 0x89, 0x6a, 0xe2, 0x99,
-	0x39, 0x64, 0x54, 0xa7, 0xb6, 0xe9, 0x9a, 0x3f, 0xe9, 0x63, 0x24, 0xf4, 0x4e, 0x20, 0x94, 0x3a,
-	0xad, 0x06, 0x58, 0x99, 0x47, 0x3a, 0xd6, 0x52, 0xa4, 0x42, 0x21, 0x8d, 0x3a, 0xad, 0xb1, 0xac,
-	0x34, 0xb9, 0xf2, 0xce, 0x77, 0x2e, 0x27, 0xcb, 0xc5, 0xce, 0xf4, 0xcc, 0x05, 0x4b, 0x1f, 0x3a,
-	0x56, 0xcf, 0x32, 0x2e, 0x58, 0x9a, 0x4b, 0x1b, 0xeb, 0xf7, 0x50, 0x85, 0x9a, 0xaf, 0xfd, 0x06,
-	0x2e, 0x77, 0xd8, 0x51, 0x20, 0x77, 0x70, 0x85, 0x3c, 0x28, 0x0f, 0x78, 0xf8, 0xc9, 0xd7, 0xbe,
-	0x8b, 0x13, 0x57, 0x9b, 0xa2, 0x68, 0x3c, 0x28, 0x0f, 0x70, 0x50, 0xf9, 0x57, 0xe8, 0xef, 0xc8,
-	0xa3, 0xd0, 0x9f, 0x40, 0xeb, 0xec, 0xfb, 0x45, 0x5c, 0xa8, 0xcf, 0xa7, 0x53, 0xfa, 0x31, 0x7e,
-	0x7a, 0xf2, 0x7e, 0x22, 0x2d, 0x68, 0x60, 0x45, 0xa7, 0x2f, 0x6f, 0xef, 0x53, 0xaf, 0x44, 0x3c,
-	0x70, 0xe7, 0x6f, 0x8b, 0x8f, 0x25, 0x1d, 0xbf, 0x2e, 0x66, 0x53, 0xea, 0x95, 0x57, 0x55, 0xfc,
-	0x6a, 0xfe, 0xfa, 0x23, 0x00, 0x00, 0xff, 0xff, 0xe4, 0xe2, 0x5b, 0x4b, 0x8a, 0x05, 0x00, 0x00,
+	// 775 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x54, 0x51, 0x6f, 0xe2, 0x46,
+	0x10, 0xae, 0xb1, 0xc1, 0x78, 0x71, 0x82, 0xb5, 0x47, 0x4f, 0xab, 0x53, 0x1f, 0x10, 0x3a, 0x9d,
+	0xac, 0x48, 0x21, 0x12, 0x7d, 0xae, 0x2a, 0x72, 0x21, 0x6a, 0xa4, 0x5e, 0x12, 0x2d, 0x88, 0xd7,
+	0xd3, 0x62, 0x16, 0xb0, 0xce, 0xf6, 0x5a, 0xbb, 0x0b, 0x2d, 0x8f, 0x7d, 0xef, 0xef, 0xe9, 0x43,
+	0x7f, 0xdd, 0x69, 0x67, 0xcd, 0xc5, 0x46, 0xbc, 0x44, 0x99, 0x6f, 0x66, 0xbf, 0x99, 0xf9, 0xbe,
+	0xc1, 0xa8, 0xc7, 0x0f, 0xbc, 0xd0, 0xe3, 0x52, 0x0a, 0x2d, 0x70, 0xbb, 0x14, 0x8a, 0xeb, 0x0f,
+	0xbf, 0x6d, 0x53, 0xbd, 0xdb, 0xaf, 0xc6, 0x89, 0xc8, 0xef, 0x1e, 0x59, 0xa1, 0x45, 0x7e, 0xbb,
+	0x11, 0xfb, 0x62, 0xcd, 0x74, 0x2a, 0x8a, 0xbb, 0xad, 0xb8, 0xcd, 0x58, 0xb2, 0xe3, 0x2a, 0x55,
+	0x77, 0x4a, 0x26, 0x77, 0x25, 0xe7, 0x52, 0xc1, 0x5f, 0xcb, 0x32, 0xfa, 0xc7, 0x41, 0xef, 0x9e,
+	0x0a, 0xcd, 0x65, 0xc1, 0xb2, 0x85, 0x64, 0x85, 0x62, 0x89, 0x79, 0x88, 0x6f, 0x90, 0xb7, 0x38,
+	0x96, 0x9c, 0x38, 0x43, 0x27, 0xbe, 0x9e, 0xbc, 0x1f, 0x43, 0xb3, 0x71, 0xad, 0xc2, 0x64, 0xa9,
+	0xa7, 0x8f, 0x25, 0xc7, 0x9f, 0x90, 0x67, 0x18, 0x49, 0x6b, 0xe8, 0xc4, 0xbd, 0x09, 0x1e, 0x43,
+	0x93, 0xf1, 0x2b, 0xe7, 0xf2, 0x0b, 0x57, 0x8a, 0x6d, 0x39, 0x85, 0x3c, 0x7e, 0x8f, 0x3a, 0xd3,
+	0x5c, 0xec, 0x0b, 0x4d, 0xdc, 0xa1, 0x13, 0x7b, 0xb4, 0xc3, 0x20, 0x1a, 0xad, 0xd0, 0xf5, 0x7d,
+	0x26, 0x92, 0x6f, 0xf3, 0x74, 0x5b, 0x30, 0xbd, 0x97, 0x1c, 0xff, 0x82, 0x82, 0x25, 0xcb, 0xd2,
+	0x35, 0xd3, 0x42, 0xc2, 0x08, 0x21, 0x0d, 0x0e, 0x27, 0x00, 0x0f, 0x50, 0xfb, 0xa9, 0x58, 0xf3,
+	0xbf, 0xa1, 0xa1, 0x4b, 0xdb, 0xa9, 0x09, 0xcc, 0x9b, 0x1f, 0x04, 0xd0, 0x20, 0xa0, 0x81, 0x3a,
+	0x01, 0xa3, 0x7f, 0x5d, 0x14, 0xcc, 0x8c, 0x7a, 0xf7, 0x62, 0x7d, 0xc4, 0x23, 0x14, 0xd6, 0x56,
+	0x51, 0xc4, 0x19, 0xba, 0x71, 0x48, 0x43, 0x5d, 0xc3, 0xf0, 0x33, 0x1a, 0x5c, 0x10, 0x46, 0x91,
+	0xd6, 0xd0, 0x8d, 0x7b, 0x93, 0x0f, 0x95, 0x22, 0x17, 0x4a, 0xe8, 0x20, 0xbd, 0xf0, 0x0e, 0x13,
+	0xe4, 0xbf, 0x32, 0xc9, 0x0b, 0xad, 0x88, 0x0b, 0xed, 0xfc, 0xd2, 0x86, 0x26, 0xf3, 0x59, 0x72,
+	0xd8, 0xd5, 0x83, 0x5d, 0xfd, 0x44, 0xf2, 0xe6, 0xa6, 0xed, 0xfa, 0xa6, 0xbf, 0xa3, 0x7e, 0x53,
+	0x2f, 0x45, 0x3a, 0x30, 0xd4, 0xcf, 0xd5, 0x50, 0xcd, 0x2c, 0xed, 0xaf, 0x9a, 0xd5, 0xf8, 0x06,
+	0x45, 0xb5, 0xd1, 0x1e, 0x33, 0xb6, 0x55, 0xc4, 0x87, 0xce, 0x91, 0x3e, 0xc3, 0x8d, 0xac, 0x8b,
+	0x34, 0xe7, 0x4a, 0xb3, 0xbc, 0x24, 0x5d, 0x18, 0x23, 0xd0, 0x27, 0xc0, 0x64, 0x9f, 0xb9, 0xfe,
+	0x4b, 0xc8, 0x6f, 0x4f, 0x0f, 0x24, 0xb0, 0x46, 0x15, 0x27, 0xc0, 0x2c, 0xb6, 0xe4, 0x52, 0xa5,
+	0xa2, 0x20, 0x68, 0xe8, 0xc4, 0x57, 0xd4, 0x3f, 0xd8, 0x70, 0xf4, 0x7f, 0x0b, 0x85, 0x60, 0x47,
+	0x75, 0x21, 0xf8, 0x23, 0xf2, 0x8c, 0x33, 0x60, 0x76, 0x6f, 0x12, 0x55, 0x8b, 0xfc, 0x70, 0x8c,
+	0x7a, 0x2b, 0xe3, 0x5b, 0xc3, 0xe3, 0xd6, 0x99, 0xc7, 0x38, 0x46, 0xfd, 0x39, 0xcf, 0x36, 0x56,
+	0x65, 0xab, 0x9b, 0x0b, 0x03, 0xf7, 0x55, 0x13, 0xc6, 0x13, 0x34, 0x78, 0xd1, 0x3b, 0x2e, 0x2d,
+	0x56, 0x89, 0xff, 0xf4, 0x00, 0xf2, 0x7b, 0x74, 0x20, 0x2e, 0xe4, 0x8c, 0x68, 0xb5, 0x37, 0x75,
+	0x5b, 0x22, 0x71, 0x86, 0x9b, 0x39, 0xdf, 0x48, 0x3b, 0x40, 0x1a, 0x24, 0x75, 0xa6, 0x85, 0x28,
+	0x45, 0x26, 0xb6, 0x69, 0xc2, 0x32, 0xcb, 0xe4, 0x5b, 0x26, 0x7d, 0x86, 0x63, 0x8c, 0xbc, 0x3f,
+	0x98, 0xda, 0x81, 0xf2, 0x21, 0xf5, 0x76, 0x4c, 0xed, 0x46, 0xff, 0xb9, 0xa8, 0x0d, 0xca, 0xe0,
+	0x5b, 0xe4, 0x57, 0x02, 0x56, 0xc2, 0xbd, 0xab, 0x0b, 0x57, 0xa5, 0xa8, 0x9f, 0xdb, 0x7f, 0x4c,
+	0xe3, 0x3f, 0x59, 0x5e, 0x0a, 0xa9, 0xdf, 0x2c, 0xb5, 0xbf, 0xa1, 0x28, 0x3b, 0xc3, 0xcd, 0xe9,
+	0x3d, 0x4a, 0x96, 0xf3, 0x4a, 0xc2, 0xf6, 0xc6, 0x04, 0xf8, 0x13, 0xba, 0x36, 0x67, 0xb1, 0x60,
+	0xab, 0x8c, 0xdf, 0x1f, 0x35, 0x57, 0xd5, 0xc5, 0x5e, 0x6f, 0x1a, 0xa8, 0xa9, 0xa3, 0x42, 0xe8,
+	0x5a, 0x5d, 0xdb, 0xd6, 0xc9, 0x06, 0x6a, 0xd6, 0x33, 0x75, 0xa0, 0x51, 0x97, 0x7a, 0x26, 0x6b,
+	0x3e, 0x13, 0x9f, 0x33, 0xa1, 0x77, 0x02, 0x44, 0xe9, 0xd2, 0x4e, 0x02, 0x91, 0xb9, 0xa6, 0xa9,
+	0x96, 0xa2, 0x14, 0x0a, 0xd4, 0xe8, 0x52, 0x9f, 0xd9, 0xd0, 0xec, 0x55, 0x65, 0xde, 0xf6, 0x0a,
+	0xec, 0x5e, 0xec, 0x0c, 0xb7, 0x2c, 0x10, 0x12, 0x34, 0x74, 0x63, 0xd7, 0xb0, 0x40, 0x68, 0x4c,
+	0x9b, 0xea, 0x65, 0xaa, 0x52, 0xcd, 0xd7, 0xa4, 0x67, 0x2f, 0x9d, 0x9d, 0x00, 0xfc, 0x11, 0x5d,
+	0x81, 0x1e, 0x94, 0x27, 0x3c, 0x3d, 0xf0, 0x35, 0x09, 0xa1, 0xe2, 0x6a, 0x53, 0x07, 0x0d, 0x07,
+	0xe5, 0x09, 0x14, 0x2a, 0x72, 0x05, 0xfc, 0x81, 0x3c, 0x01, 0x37, 0xf7, 0xa8, 0x7f, 0xf6, 0x05,
+	0xc5, 0x21, 0xea, 0xbe, 0xce, 0x66, 0xf4, 0xeb, 0xf4, 0xe1, 0x21, 0xfa, 0x09, 0xf7, 0x51, 0x0f,
+	0x22, 0x3a, 0xfb, 0xf2, 0xb2, 0x9c, 0x45, 0x0e, 0x8e, 0x50, 0xf8, 0xfa, 0x32, 0xff, 0xba, 0xa0,
+	0xd3, 0xe7, 0xf9, 0xe3, 0x8c, 0x46, 0xad, 0x55, 0x07, 0xbe, 0xdb, 0xbf, 0x7e, 0x1f, 0x00, 0x3a,
+	0x58, 0xf8, 0x8f, 0x0c, 0x06, 0x00, 0x00,
 }
@@ -0,0 +1,59 @@
+package poset
+
+import (
+	"fmt"
+)
+
+// EventCapError is returned by InsertEvent for an event its creator made
+// beyond the events it may make per frame
+type EventCapError struct {
+	Creator string
+	Frame   int64
+	Cap     int
+}
+
+func (e *EventCapError) Error() string {
+	return fmt.Sprintf("creator %s made more than %d events in frame %d",
+		e.Creator, e.Cap, e.Frame)
+}
+
+// IsEventCap returns true for an EventCapError
+func IsEventCap(err error) bool {
+	_, ok := err.(*EventCapError)
+	return ok
+}
+
+// SetMaxEventsPerFrame caps the events a creator may make per frame, 0 for
+// no cap. The cap decides which events are accepted, so every participant
+// must use the same.
+func (p *Poset) SetMaxEventsPerFrame(n int) {
+	p.maxEventsPerFrame = n
+}
+
+// checkEventCap refuses an event of the given frame when its creator already
+// made the capped number of events in that frame. They are counted along the
+// self-parents, which makes the outcome the same on every poset whatever
+// the order the events came in.
+func (p *Poset) checkEventCap(event Event, selfParent *Event, frame int64) error {
+	if p.maxEventsPerFrame <= 0 {
+		return nil
+	}
+	count := 1
+	for ev := selfParent; ev != nil && ev.Frame == frame; {
+		count++
+		if count > p.maxEventsPerFrame {
+			return &EventCapError{
+				Creator: event.GetCreator(),
+				Frame:   frame,
+				Cap:     p.maxEventsPerFrame,
+			}
+		}
+		parent, err := p.Store.GetEventBlock(ev.SelfParent())
+		if err != nil {
+			// the root, or the reset frame, is reached
+			break
+		}
+		ev = &parent
+	}
+	return nil
+}
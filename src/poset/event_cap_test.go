@@ -0,0 +1,95 @@
+package poset
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// capEvent is a signed event of a creator, on its self-parent and an
+// other-parent, zero for none
+func capEvent(participants *peers.Peers, key *ecdsa.PrivateKey, selfParent *Event,
+	otherParent EventHash, name string) Event {
+	pubKey := crypto.FromECDSAPub(&key.PublicKey)
+	self := GenRootSelfParent(participants.ByPubKey[fmt.Sprintf("0x%X", pubKey)].ID)
+	index := int64(0)
+	if selfParent != nil {
+		self = selfParent.Hash()
+		index = selfParent.Index() + 1
+	}
+	ev := NewEvent([][]byte{[]byte(name)}, nil, nil, EventHashes{self, otherParent},
+		pubKey, index, NewFlagTable(), NewFlagTable(), FrameNIL, false)
+	if err := ev.Sign(key); err != nil {
+		panic(err)
+	}
+	return ev
+}
+
+func TestEventCap(t *testing.T) {
+	const maxEvents = 3
+	participants, keys := iteratorParticipants()
+
+	// a makes 5 events in a row, b makes one on the second one and c one on
+	// the fourth one
+	var a []Event
+	for i := 0; i < 5; i++ {
+		var self *Event
+		if i > 0 {
+			self = &a[i-1]
+		}
+		a = append(a, capEvent(participants, keys[0], self, EventHash{}, fmt.Sprintf("a%d", i)))
+	}
+	b := capEvent(participants, keys[1], nil, a[1].Hash(), "b0")
+	c := capEvent(participants, keys[2], nil, a[3].Hash(), "c0")
+
+	orders := [][]Event{
+		{a[0], a[1], a[2], a[3], a[4], b, c},
+		{a[0], a[1], b, a[2], c, a[3], a[4]},
+	}
+	for i, order := range orders {
+		p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.SetMaxEventsPerFrame(maxEvents)
+
+		accepted := make(map[string]bool)
+		for _, ev := range order {
+			name := string(ev.Transactions()[0])
+			err := p.InsertEvent(ev, false)
+			if name == "a3" && !IsEventCap(err) {
+				t.Fatalf("order %d: expected a3 to be beyond the cap, got %v", i, err)
+			}
+			accepted[name] = err == nil
+		}
+
+		for name, want := range map[string]bool{
+			"a0": true, "a1": true, "a2": true, "a3": false, "a4": false,
+			"b0": true, "c0": false,
+		} {
+			if accepted[name] != want {
+				t.Fatalf("order %d: expected %s accepted %v, got %v", i, name, want, accepted[name])
+			}
+		}
+	}
+}
+
+func TestEventCapDisabled(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var self *Event
+	for i := 0; i < 10; i++ {
+		ev := capEvent(participants, keys[0], self, EventHash{}, fmt.Sprintf("a%d", i))
+		if err := p.InsertEvent(ev, false); err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+		self = &ev
+	}
+}
@@ -0,0 +1,52 @@
+package poset
+
+// frameParents are the frames and the flag tables of the parents of an
+// event. otherRootTable returns the root table of the root of the creator of
+// the other-parent in its frame, it is only needed when the other-parent is
+// ahead.
+type frameParents struct {
+	selfFrame      int64
+	selfTable      FlagTable
+	otherFrame     int64
+	otherTable     FlagTable
+	otherRootTable func() (FlagTable, error)
+	// first is set for the first event of a creator, whose root stands for
+	// the self-parent
+	first bool
+}
+
+// eventFrame returns the frame of an event, whether it is a root, and its
+// flag and root tables, but for the event itself which the caller adds to
+// the flag table of a root. The tables of the parents are not modified.
+//
+// With parents of the same frame, the event is a root of the next frame when
+// the merged flag tables of its parents see a supermajority of the roots of
+// their frame. Its root table is then the merged one, and its flag table is
+// empty. Otherwise it is in the frame of its parents, with the merged table,
+// and it is a root of that frame when it is the first event of its creator.
+//
+// With the self-parent ahead, the event is in its frame with its flag table.
+//
+// With the other-parent ahead, the event is a root of its frame, with its
+// flag table. The root table is the flag table of the self-parent merged with
+// the root table of the root of the other-parent, at the frame before.
+func eventFrame(parents frameParents, superMajority uint64) (frame int64, root bool, flagTable, rootTable FlagTable, err error) {
+	switch {
+	case parents.selfFrame == parents.otherFrame:
+		flagTable = MergeFlagTables(parents.selfTable, parents.otherTable, parents.selfFrame)
+		if uint64(len(flagTable)) >= superMajority {
+			return parents.selfFrame + 1, true, NewFlagTable(), flagTable, nil
+		}
+		return parents.selfFrame, parents.first, flagTable, nil, nil
+	case parents.selfFrame > parents.otherFrame:
+		return parents.selfFrame, false, parents.selfTable.Copy(), nil, nil
+	default:
+		otherRootTable, err := parents.otherRootTable()
+		if err != nil {
+			return 0, false, nil, nil, err
+		}
+		frame = parents.otherFrame
+		rootTable = MergeFlagTables(parents.selfTable, otherRootTable, frame-1)
+		return frame, true, parents.otherTable.Copy(), rootTable, nil
+	}
+}
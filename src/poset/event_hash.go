@@ -1,6 +1,10 @@
 package poset
 
 import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
 	"github.com/SamuelMarks/dag1/src/common"
 	"github.com/SamuelMarks/dag1/src/common/hexutil"
 	"github.com/SamuelMarks/dag1/src/crypto"
@@ -14,14 +18,67 @@ type (
 	EventHashes []EventHash
 )
 
+// EventHashLength is the length of an EventHash in bytes
+const EventHashLength = common.HashLength
+
+// InvalidEventHashError is returned for bytes of the wrong length for an
+// EventHash, which would otherwise become another hash
+type InvalidEventHashError struct {
+	Length int
+}
+
+func (e *InvalidEventHashError) Error() string {
+	return fmt.Sprintf("invalid event hash of %d bytes, expected %d", e.Length, EventHashLength)
+}
+
+// IsInvalidEventHash returns true for an InvalidEventHashError
+func IsInvalidEventHash(err error) bool {
+	_, ok := err.(*InvalidEventHashError)
+	return ok
+}
+
+// IsValidEventHash returns true when raw has the length of an EventHash. The
+// hashes decoded from the wire are checked with it before they are used.
+func IsValidEventHash(raw []byte) bool {
+	return len(raw) == EventHashLength
+}
+
 // CalcEventHash calculates hash of data.
 func CalcEventHash(data []byte) EventHash {
 	return EventHash(crypto.Keccak256Hash(data))
 }
 
-// Set sets value to bytes.
-func (hash *EventHash) Set(raw []byte) {
-	(*common.Hash)(hash).SetBytes(raw)
+// ParseEventHash parses an EventHash in hex, with or without 0x
+func ParseEventHash(s string) (hash EventHash, err error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		s = "0x" + s
+	}
+	err = hash.Parse(s)
+	return
+}
+
+// MustParseEventHash is ParseEventHash which panics on a malformed hash
+func MustParseEventHash(s string) EventHash {
+	hash, err := ParseEventHash(s)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+}
+
+// Set sets value to bytes, which must be EventHashLength long. The value is
+// unchanged on error. Debug builds panic instead, as the hashes from outside
+// are checked before they get here.
+func (hash *EventHash) Set(raw []byte) error {
+	if !IsValidEventHash(raw) {
+		err := &InvalidEventHashError{Length: len(raw)}
+		if strictEventHash {
+			panic(err)
+		}
+		return err
+	}
+	copy(hash[:], raw)
+	return nil
 }
 
 // Parse sets value to bytes parsed from hex string.
@@ -30,20 +87,27 @@ func (hash *EventHash) Parse(raw string) error {
 	if err != nil {
 		return err
 	}
-	hash.Set(b)
-	return nil
+	if !IsValidEventHash(b) {
+		return &InvalidEventHashError{Length: len(b)}
+	}
+	return hash.Set(b)
 }
 
 // Equal compares value with bytes.
 func (hash *EventHash) Equal(raw []byte) bool {
-	if len(hash) != len(raw) {
+	var other EventHash
+	if !IsValidEventHash(raw) || other.Set(raw) != nil {
 		return false
 	}
-	var other EventHash
-	other.Set(raw)
 	return *hash == other
 }
 
+// EqualConstantTime compares value with another hash in a time which does
+// not depend on where they differ, for the hashes of signed data.
+func (hash *EventHash) EqualConstantTime(other EventHash) bool {
+	return subtle.ConstantTimeCompare(hash[:], other[:]) == 1
+}
+
 // Bytes returns value as bytes.
 func (hash *EventHash) Bytes() []byte {
 	return (*common.Hash)(hash).Bytes()
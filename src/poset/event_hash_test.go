@@ -10,19 +10,123 @@ func TestEventHash(t *testing.T) {
 	assertO := assert.New(t)
 	var h EventHash
 
-	arrLongTrim := []byte{1, 2, 3, 4}
-	arrLongFull := []byte{1, 2, 3, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0} // len = sha256.Size
-	arrShortTrim := []byte{3, 2, 1}
-	arrShortFull := []byte{3, 2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0} // len = sha256.Size
+	arrFull := []byte{1, 2, 3, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0} // len = sha256.Size
 
-	h.Set(arrLongTrim)
-	b1 := h.Bytes()
+	assertO.NoError(h.Set(arrFull))
+	assertO.Equal(arrFull, h.Bytes())
 
-	h.Set(arrShortTrim)
-	b2 := h.Bytes()
+	// bytes of another length are refused, the value is unchanged
+	for _, arr := range [][]byte{nil, {3, 2, 1}, arrFull[1:], append(arrFull, 0)} {
+		err := h.Set(arr)
+		assertO.True(IsInvalidEventHash(err), "length %d: %v", len(arr), err)
+		assertO.Equal(arrFull, h.Bytes())
+		assertO.False(h.Equal(arr))
+	}
+	assertO.True(h.Equal(arrFull))
+}
+
+func TestParseEventHash(t *testing.T) {
+	assertO := assert.New(t)
+	hash := CalcEventHash([]byte("parse"))
+
+	// round trip, with or without 0x
+	for _, s := range []string{hash.String(), hash.String()[2:]} {
+		parsed, err := ParseEventHash(s)
+		assertO.NoError(err)
+		assertO.Equal(hash, parsed)
+	}
+	assertO.Equal(hash, MustParseEventHash(hash.String()))
+
+	for _, s := range []string{"", "0x", "0x0102", hash.String() + "00", hash.String()[:len(hash.String())-2]} {
+		_, err := ParseEventHash(s)
+		assertO.True(IsInvalidEventHash(err), "%q: %v", s, err)
+	}
+	_, err := ParseEventHash("0xzz")
+	assertO.Error(err)
+	assertO.False(IsInvalidEventHash(err))
+	assertO.Panics(func() { MustParseEventHash("0x0102") })
+}
+
+func TestEventHashEqualConstantTime(t *testing.T) {
+	assertO := assert.New(t)
+	a, b := CalcEventHash([]byte("a")), CalcEventHash([]byte("b"))
+	assertO.True(a.EqualConstantTime(a))
+	assertO.False(a.EqualConstantTime(b))
+	c := a
+	c[EventHashLength-1] ^= 1
+	assertO.False(a.EqualConstantTime(c))
+}
+
+func TestRootHashesChecked(t *testing.T) {
+	assertO := assert.New(t)
+	root := NewBaseRoot(1)
+	data, err := root.ProtoMarshal()
+	assertO.NoError(err)
+	var decoded Root
+	assertO.NoError(decoded.ProtoUnmarshal(data))
+
+	root.Others["x"] = &RootEvent{Hash: []byte{1, 2, 3}, CreatorID: 2}
+	data, err = root.ProtoMarshal()
+	assertO.NoError(err)
+	err = decoded.ProtoUnmarshal(data)
+	assertO.True(IsInvalidEventHash(err), "%v", err)
+}
 
-	assertO.Equal(arrLongFull, b1)
-	assertO.Equal(arrShortFull, b2)
+func TestReadWireInfoShortHash(t *testing.T) {
+	participants, _ := iteratorParticipants()
+	peerSlice := participants.ToPeerSlice()
+	creator, other := peerSlice[0], peerSlice[1]
+
+	// a root whose other-parent hash was truncated
+	roots := make(map[string]Root)
+	for _, peer := range peerSlice {
+		roots[peer.Message.PubKeyHex] = NewBaseRoot(peer.ID)
+	}
+	roots[creator.Message.PubKeyHex].Others["x"] = &RootEvent{
+		Hash:      []byte{1, 2, 3},
+		CreatorID: other.ID,
+		Index:     5,
+	}
+	store := NewInmemStore(participants, cacheSize, nil)
+	if err := store.Reset(roots); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPoset(participants, store, nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = p.ReadWireInfo(WireEvent{Body: WireBody{
+		CreatorID:            creator.ID,
+		SelfParentIndex:      -1,
+		OtherParentCreatorID: other.ID,
+		OtherParentIndex:     5,
+	}})
+	if !IsInvalidEventHash(err) {
+		t.Fatalf("expected the short hash refused, got %v", err)
+	}
+
+	// and a Reset to a frame with such a root
+	if err := p.Reset(Block{}, Frame{Roots: []*Root{{SelfParent: &RootEvent{Hash: []byte{1}}}}}); !IsInvalidEventHash(err) {
+		t.Fatalf("expected the frame refused, got %v", err)
+	}
+}
+
+func TestInsertEventShortParent(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev := capEvent(participants, keys[0], nil, EventHash{}, "tx")
+	ev.Message.Body.Parents[1] = []byte{1, 2, 3}
+	if err := ev.Sign(keys[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.InsertEvent(ev, false); !IsInvalidEventHash(err) {
+		t.Fatalf("expected the short parent refused, got %v", err)
+	}
 }
 
 func TestEventHashes(t *testing.T) {
@@ -0,0 +1,175 @@
+package poset
+
+import (
+	"crypto/elliptic"
+	"fmt"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+// StructureViolation is the rule of the shape of an event an
+// EventStructureError reports
+type StructureViolation int
+
+const (
+	// ViolationDuplicateParents is an other-parent equal to the self-parent
+	ViolationDuplicateParents StructureViolation = iota + 1
+	// ViolationSelfReference is a parent equal to the event itself
+	ViolationSelfReference
+	// ViolationSelfParentCreator is a self-parent made by another creator
+	ViolationSelfParentCreator
+	// ViolationNegativeIndex is an event of a negative index
+	ViolationNegativeIndex
+	// ViolationSelfParentIndex is a self-parent of another index than the
+	// index of the event minus one
+	ViolationSelfParentIndex
+	// ViolationMalformedSignature is a signature which is no ECDSA signature
+	ViolationMalformedSignature
+	// ViolationTransactionFlags is an event with flags, but not one flags
+	// byte per transaction
+	ViolationTransactionFlags
+)
+
+// StructureViolations are all the violations, in order
+var StructureViolations = []StructureViolation{
+	ViolationDuplicateParents,
+	ViolationSelfReference,
+	ViolationSelfParentCreator,
+	ViolationNegativeIndex,
+	ViolationSelfParentIndex,
+	ViolationMalformedSignature,
+	ViolationTransactionFlags,
+}
+
+func (v StructureViolation) String() string {
+	switch v {
+	case ViolationDuplicateParents:
+		return "duplicate_parents"
+	case ViolationSelfReference:
+		return "self_reference"
+	case ViolationSelfParentCreator:
+		return "self_parent_creator"
+	case ViolationNegativeIndex:
+		return "negative_index"
+	case ViolationSelfParentIndex:
+		return "self_parent_index"
+	case ViolationMalformedSignature:
+		return "malformed_signature"
+	case ViolationTransactionFlags:
+		return "transaction_flags"
+	}
+	return fmt.Sprintf("violation_%d", int(v))
+}
+
+// EventStructureError is returned by ReadWireInfo and InsertEvent for an
+// event whose shape no honest creator makes, e.g. of the same event as both
+// parents. Such an event is refused before its signature is verified, the
+// peer delivering it is to blame.
+type EventStructureError struct {
+	Violation StructureViolation
+	Index     int64
+	Detail    string
+}
+
+func (e *EventStructureError) Error() string {
+	return fmt.Sprintf("malformed event %d, %s: %s", e.Index, e.Violation, e.Detail)
+}
+
+// IsEventStructure returns true for an EventStructureError
+func IsEventStructure(err error) bool {
+	_, ok := err.(*EventStructureError)
+	return ok
+}
+
+// structureError returns an EventStructureError
+func structureError(v StructureViolation, index int64, format string, args ...interface{}) error {
+	return &EventStructureError{
+		Violation: v,
+		Index:     index,
+		Detail:    fmt.Sprintf(format, args...),
+	}
+}
+
+// checkWireStructure checks the indexes and the flags of a wire event, before
+// its parents are looked up by them
+func checkWireStructure(body WireBody) error {
+	if body.Index < 0 {
+		return structureError(ViolationNegativeIndex, body.Index, "negative index")
+	}
+	// a negative self-parent index stands for the root, whose index the
+	// event does not tell
+	if body.SelfParentIndex >= 0 && body.SelfParentIndex != body.Index-1 {
+		return structureError(ViolationSelfParentIndex, body.Index,
+			"self-parent index %d", body.SelfParentIndex)
+	}
+	if body.OtherParentCreatorID == body.CreatorID && body.OtherParentIndex >= 0 &&
+		body.OtherParentIndex == body.SelfParentIndex {
+		return structureError(ViolationDuplicateParents, body.Index,
+			"other-parent is the self-parent %d", body.SelfParentIndex)
+	}
+	return checkTransactionFlags(body.Index, body.Transactions, body.TransactionFlags)
+}
+
+// checkEventStructure checks what the event tells of its own shape: its
+// index, its parents, its flags and its signature. The parents must be valid hashes,
+// see checkParentHashes.
+func checkEventStructure(event Event) error {
+	index := event.Index()
+	if index < 0 {
+		return structureError(ViolationNegativeIndex, index, "negative index")
+	}
+	hash := event.Hash()
+	selfParent, otherParent := event.SelfParent(), event.OtherParent()
+	if selfParent == hash || otherParent == hash {
+		return structureError(ViolationSelfReference, index,
+			"parent of its own hash %s", hash.String())
+	}
+	if !otherParent.Zero() && otherParent == selfParent {
+		return structureError(ViolationDuplicateParents, index,
+			"other-parent is the self-parent %s", selfParent.String())
+	}
+	if err := checkTransactionFlags(index, event.Transactions(), event.Message.Body.TransactionFlags); err != nil {
+		return err
+	}
+	return checkSignatureFormat(index, event.Message.Signature)
+}
+
+// checkTransactionFlags checks an event has either no flags or one flags
+// byte per transaction
+func checkTransactionFlags(index int64, txs [][]byte, flags []byte) error {
+	if len(flags) != 0 && len(flags) != len(txs) {
+		return structureError(ViolationTransactionFlags, index,
+			"%d flags for %d transactions", len(flags), len(txs))
+	}
+	return nil
+}
+
+// checkSignatureFormat checks a signature decodes to the two values of an
+// ECDSA signature on the curve of the keys, which Verify takes for granted
+func checkSignatureFormat(index int64, signature string) error {
+	r, s, err := crypto.DecodeSignature(signature)
+	if err != nil {
+		return structureError(ViolationMalformedSignature, index, "%v", err)
+	}
+	n := elliptic.P256().Params().N
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(n) >= 0 || s.Cmp(n) >= 0 {
+		return structureError(ViolationMalformedSignature, index,
+			"signature values out of range")
+	}
+	return nil
+}
+
+// checkSelfParentStructure checks the self-parent of an event was made by
+// the same creator just before the event
+func checkSelfParentStructure(event, selfParent Event) error {
+	index := event.Index()
+	if selfParent.GetCreator() != event.GetCreator() {
+		return structureError(ViolationSelfParentCreator, index,
+			"self-parent made by %s", selfParent.GetCreator())
+	}
+	if selfParent.Index() != index-1 {
+		return structureError(ViolationSelfParentIndex, index,
+			"self-parent index %d", selfParent.Index())
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package poset
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+// expectViolation fails unless err is an EventStructureError of v
+func expectViolation(t *testing.T, name string, err error, v StructureViolation) {
+	t.Helper()
+	e, ok := err.(*EventStructureError)
+	if !ok {
+		t.Fatalf("%s: expected an EventStructureError, got %v", name, err)
+	}
+	if e.Violation != v {
+		t.Fatalf("%s: expected a violation %s, got %s", name, v, e.Violation)
+	}
+}
+
+func TestInsertEventStructure(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	a0 := capEvent(participants, keys[0], nil, EventHash{}, "a0")
+	b0 := capEvent(participants, keys[1], nil, EventHash{}, "b0")
+	outOfRange := crypto.EncodeSignature(elliptic.P256().Params().N, big.NewInt(1))
+
+	// resign signs the event again once changed
+	resign := func(ev Event, key int) Event {
+		ev.Message.Hash = nil
+		if err := ev.Sign(keys[key]); err != nil {
+			t.Fatal(err)
+		}
+		return ev
+	}
+
+	cases := []struct {
+		name      string
+		event     func() Event
+		violation StructureViolation
+	}{
+		{"duplicate parents", func() Event {
+			return capEvent(participants, keys[0], &a0, a0.Hash(), "a1")
+		}, ViolationDuplicateParents},
+		{"self reference", func() Event {
+			ev := capEvent(participants, keys[0], &a0, b0.Hash(), "a1")
+			// a crafted event claiming the hash of its other-parent
+			hash := b0.Hash()
+			ev.Message.Hash = hash.Bytes()
+			return ev
+		}, ViolationSelfReference},
+		{"self-parent of another creator", func() Event {
+			return capEvent(participants, keys[1], &a0, EventHash{}, "b1")
+		}, ViolationSelfParentCreator},
+		{"negative index", func() Event {
+			ev := capEvent(participants, keys[0], nil, EventHash{}, "a")
+			ev.Message.Body.Index = -1
+			return resign(ev, 0)
+		}, ViolationNegativeIndex},
+		{"self-parent index", func() Event {
+			ev := capEvent(participants, keys[0], &a0, EventHash{}, "a2")
+			ev.Message.Body.Index = 2
+			return resign(ev, 0)
+		}, ViolationSelfParentIndex},
+		{"undecodable signature", func() Event {
+			ev := capEvent(participants, keys[0], &a0, EventHash{}, "a1")
+			ev.Message.Signature = "not a signature"
+			return ev
+		}, ViolationMalformedSignature},
+		{"zero signature", func() Event {
+			ev := capEvent(participants, keys[0], &a0, EventHash{}, "a1")
+			ev.Message.Signature = "0|0"
+			return ev
+		}, ViolationMalformedSignature},
+		{"signature out of range", func() Event {
+			ev := capEvent(participants, keys[0], &a0, EventHash{}, "a1")
+			ev.Message.Signature = outOfRange
+			return ev
+		}, ViolationMalformedSignature},
+		{"flags of other transactions", func() Event {
+			ev := capEvent(participants, keys[0], &a0, EventHash{}, "a1")
+			ev.Message.Body.TransactionFlags = []byte{1, 2}
+			return resign(ev, 0)
+		}, ViolationTransactionFlags},
+	}
+
+	for _, c := range cases {
+		p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.InsertEvent(a0, true); err != nil {
+			t.Fatal(err)
+		}
+		ev := c.event()
+		expectViolation(t, c.name, p.InsertEvent(ev, false), c.violation)
+		// refused before the signature, even if the caller verified it
+		expectViolation(t, c.name, p.InsertPreverifiedEvent(ev, false), c.violation)
+		if _, err := p.Store.GetEventBlock(ev.Hash()); err == nil {
+			t.Fatalf("%s: expected the event not to be stored", c.name)
+		}
+	}
+}
+
+func TestReadWireInfoStructure(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a0 := capEvent(participants, keys[0], nil, EventHash{}, "a0")
+	b0 := capEvent(participants, keys[1], nil, EventHash{}, "b0")
+	for _, ev := range []Event{a0, b0} {
+		if err := p.InsertEvent(ev, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a1 := capEvent(participants, keys[0], &a0, b0.Hash(), "a1")
+	if err := p.InsertEvent(a1, true); err != nil {
+		t.Fatal(err)
+	}
+	valid := a1.ToWire()
+
+	cases := []struct {
+		name      string
+		change    func(*WireEvent)
+		violation StructureViolation
+	}{
+		{"negative index", func(we *WireEvent) {
+			we.Body.Index = -2
+			we.Body.SelfParentIndex = -3
+		}, ViolationNegativeIndex},
+		{"self-parent index", func(we *WireEvent) {
+			we.Body.Index = 3
+		}, ViolationSelfParentIndex},
+		{"duplicate parents", func(we *WireEvent) {
+			we.Body.OtherParentCreatorID = we.Body.CreatorID
+			we.Body.OtherParentIndex = we.Body.SelfParentIndex
+		}, ViolationDuplicateParents},
+		{"malformed signature", func(we *WireEvent) {
+			we.Signature = "|"
+		}, ViolationMalformedSignature},
+		{"flags of other transactions", func(we *WireEvent) {
+			we.Body.TransactionFlags = []byte{1, 0, 1}
+		}, ViolationTransactionFlags},
+	}
+
+	for _, c := range cases {
+		we := valid
+		c.change(&we)
+		_, err := p.ReadWireInfo(we)
+		expectViolation(t, c.name, err, c.violation)
+	}
+}
+
+func TestEventStructureValid(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// roots, a zero other-parent, and events on both parents pass
+	a0 := capEvent(participants, keys[0], nil, EventHash{}, "a0")
+	b0 := capEvent(participants, keys[1], nil, EventHash{}, "b0")
+	a1 := capEvent(participants, keys[0], &a0, b0.Hash(), "a1")
+	b1 := capEvent(participants, keys[1], &b0, a1.Hash(), "b1")
+	a2 := capEvent(participants, keys[0], &a1, EventHash{}, "a2")
+	for _, ev := range []Event{a0, b0, a1, b1, a2} {
+		if err := p.InsertEvent(ev, true); err != nil {
+			t.Fatalf("event %d of %s: %v", ev.Index(), ev.GetCreator(), err)
+		}
+	}
+
+	// and read back from the wire
+	for _, ev := range []Event{a1, b1, a2} {
+		stored, err := p.Store.GetEventBlock(ev.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fromWire, err := p.ReadWireInfo(stored.ToWire())
+		if err != nil {
+			t.Fatalf("event %d of %s: %v", ev.Index(), ev.GetCreator(), err)
+		}
+		if hash := fromWire.Hash(); hash != ev.Hash() {
+			t.Fatalf("event %d of %s: another hash %s from the wire", ev.Index(), ev.GetCreator(), hash.String())
+		}
+	}
+}
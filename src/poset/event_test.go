@@ -3,7 +3,11 @@ package poset
 import (
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
+
+	"github.com/SamuelMarks/dag1/src/common"
 	"github.com/SamuelMarks/dag1/src/crypto"
 )
 
@@ -102,6 +106,57 @@ func TestMarshallEvent(t *testing.T) {
 	}
 }
 
+func TestEventWireSize(t *testing.T) {
+	privateKey, _ := crypto.GenerateECDSAKey()
+	body := createDummyEventBody()
+	body.Creator = crypto.FromECDSAPub(&privateKey.PublicKey)
+	event := Event{Message: &EventMessage{Body: &body}}
+	if err := event.Sign(privateKey); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := event.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := event.WireSize(); size != len(raw) {
+		t.Fatalf("expected a wire size of %d, got %d", len(raw), size)
+	}
+	// the message still unmarshals once sized
+	newEvent := new(Event)
+	if err := newEvent.ProtoUnmarshal(raw); err != nil {
+		t.Fatal(err)
+	}
+	if newEvent.WireSize() != len(raw) {
+		t.Fatalf("expected the unmarshalled event to have a wire size of %d, got %d",
+			len(raw), newEvent.WireSize())
+	}
+}
+
+func TestPosetWireSize(t *testing.T) {
+	f := newFixture(t, fixtureConfig{Participants: 3, Events: 30, Seed: 1, Txs: 2, TxSize: 16})
+	p := f.newPoset(t, false)
+	for _, e := range f.events {
+		hash := e.Hash()
+		ev, err := p.Store.GetEventBlock(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := p.WireSize(&ev)
+		if size != proto.Size(ev.Message) {
+			t.Fatalf("event %s: expected a wire size of %d, got %d",
+				hash, proto.Size(ev.Message), size)
+		}
+		cached, ok := p.wireSizeCache.Get(hash)
+		if !ok || cached.(int) != size {
+			t.Fatalf("event %s: expected the wire size %d cached, got %v", hash, size, cached)
+		}
+		if again := p.WireSize(&ev); again != size {
+			t.Fatalf("event %s: expected the cached wire size %d, got %d", hash, size, again)
+		}
+	}
+}
+
 func TestWireEvent(t *testing.T) {
 	privateKey, _ := crypto.GenerateECDSAKey()
 	publicKeyBytes := crypto.FromECDSAPub(&privateKey.PublicKey)
@@ -141,10 +196,41 @@ func TestWireEvent(t *testing.T) {
 	}
 }
 
+func TestEventTimestamp(t *testing.T) {
+	privateKey, _ := crypto.GenerateECDSAKey()
+	body := createDummyEventBody()
+	body.Creator = crypto.FromECDSAPub(&privateKey.PublicKey)
+	event := Event{Message: &EventMessage{Body: &body}}
+
+	if !event.Timestamp().IsZero() {
+		t.Fatalf("expected no timestamp, got %v", event.Timestamp())
+	}
+	unstamped, err := body.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Unix(1500000000, 123)
+	event.SetTimestamp(created)
+	if !event.Timestamp().Equal(created) {
+		t.Fatalf("expected timestamp %v, got %v", created, event.Timestamp())
+	}
+	if stamped, err := body.Hash(); err != nil || stamped == unstamped {
+		t.Fatalf("expected the timestamp to be part of the hash (%v)", err)
+	}
+	if err := event.Sign(privateKey); err != nil {
+		t.Fatal(err)
+	}
+	if wire := event.ToWire(); wire.Body.Timestamp != created.UnixNano() {
+		t.Fatalf("expected the wire timestamp to be %d, got %d",
+			created.UnixNano(), wire.Body.Timestamp)
+	}
+}
+
 func TestIsLoaded(t *testing.T) {
 	//nil payload
 
-	event := NewEvent(nil, nil, nil, make(EventHashes, 2), []byte("creator"), 1, nil)
+	event := NewEvent(nil, nil, nil, make(EventHashes, 2), []byte("creator"), 1, nil, nil, 0, false)
 	if event.IsLoaded() {
 		t.Fatalf("IsLoaded() should return false for nil Body.Transactions and Body.BlockSignatures")
 	}
@@ -189,12 +275,12 @@ func TestEventFlagTable(t *testing.T) {
 		fakeEventHash("z"): 2,
 	}
 
-	event := NewEvent(nil, nil, nil, make(EventHashes, 2), []byte("creator"), 1, exp)
+	event := NewEvent(nil, nil, nil, make(EventHashes, 2), []byte("creator"), 1, exp, nil, 0, false)
 	if event.IsLoaded() {
 		t.Fatalf("IsLoaded() should return false for nil Body.Transactions and Body.BlockSignatures")
 	}
 
-	if len(event.Message.FlagTable) == 0 {
+	if len(event.FlagTableBytes) == 0 {
 		t.Fatal("FlagTable is nil")
 	}
 
@@ -234,19 +320,18 @@ func TestMergeFlagTable(t *testing.T) {
 		fakeEventHash("z"): 0,
 	}
 
-	ft := start.Marshal()
-	event := Event{Message: &EventMessage{FlagTable: ft}}
+	event := Event{FlagTableBytes: start.Marshal()}
 
 	for _, v := range syncData {
-		flagTable, err := event.MergeFlagTable(v)
+		flagTable, err := event.MergeFlagTable(v, 1)
 		if err != nil {
 			t.Fatal(err)
 		}
-		event.Message.FlagTable = flagTable.Marshal()
+		event.FlagTableBytes = flagTable.Marshal()
 	}
 
 	res := FlagTable{}
-	err := res.Unmarshal(event.Message.FlagTable)
+	err := res.Unmarshal(event.FlagTableBytes)
 	if err != nil {
 		t.Error(err)
 	}
@@ -260,7 +345,6 @@ func TestMergeFlagTable(t *testing.T) {
  * stuff
  */
 
-func fakeEventHash(s string) (hash EventHash) {
-	hash.Set([]byte(s))
-	return
+func fakeEventHash(s string) EventHash {
+	return EventHash(common.BytesToHash([]byte(s)))
 }
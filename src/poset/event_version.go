@@ -0,0 +1,62 @@
+package poset
+
+import (
+	"fmt"
+)
+
+// Versions of the event body. The version is part of the signed body, and
+// as proto3 leaves out a zero field, a body of the legacy version marshals
+// as it did before the field existed.
+const (
+	// EventVersionLegacy is the version of the bodies from before the field
+	EventVersionLegacy uint32 = 0
+	// EventVersion1 is the first versioned body
+	EventVersion1 uint32 = 1
+)
+
+// EventVersionRange is a range of event body versions, both included
+type EventVersionRange struct {
+	Min uint32
+	Max uint32
+}
+
+// Contains returns true when version is in the range
+func (r EventVersionRange) Contains(version uint32) bool {
+	return version >= r.Min && version <= r.Max
+}
+
+// SupportedEventVersions are the versions of the event bodies this node
+// reads. The events of any other version are refused, as their fields may
+// mean what this node does not know.
+var SupportedEventVersions = EventVersionRange{Min: EventVersionLegacy, Max: EventVersion1}
+
+// ErrUnsupportedEventVersion is returned by ReadWireInfo and InsertEvent for
+// an event whose body version is not supported
+type ErrUnsupportedEventVersion struct {
+	Version   uint32
+	Supported EventVersionRange
+}
+
+func (e *ErrUnsupportedEventVersion) Error() string {
+	return fmt.Sprintf("event version %d is not supported, only %d to %d are",
+		e.Version, e.Supported.Min, e.Supported.Max)
+}
+
+// Newer returns true when the event is from a newer client than this one
+func (e *ErrUnsupportedEventVersion) Newer() bool {
+	return e.Version > e.Supported.Max
+}
+
+// IsUnsupportedEventVersion returns true for an ErrUnsupportedEventVersion
+func IsUnsupportedEventVersion(err error) bool {
+	_, ok := err.(*ErrUnsupportedEventVersion)
+	return ok
+}
+
+// CheckEventVersion refuses a body version out of SupportedEventVersions
+func CheckEventVersion(version uint32) error {
+	if SupportedEventVersions.Contains(version) {
+		return nil
+	}
+	return &ErrUnsupportedEventVersion{Version: version, Supported: SupportedEventVersions}
+}
@@ -0,0 +1,100 @@
+package poset
+
+import (
+	"bytes"
+	"testing"
+)
+
+// eventVersionFixtures are the marshalled bodies of each supported version:
+// Creator 0x01 and Index 1, then the version. The legacy body has no version
+// field, as marshalled by the clients from before it.
+var eventVersionFixtures = map[uint32][]byte{
+	EventVersionLegacy: {0x22, 0x01, 0x01, 0x28, 0x01},
+	EventVersion1:      {0x22, 0x01, 0x01, 0x28, 0x01, 0x50, 0x01},
+}
+
+func TestEventVersionDecoding(t *testing.T) {
+	for version := SupportedEventVersions.Min; version <= SupportedEventVersions.Max; version++ {
+		fixture, ok := eventVersionFixtures[version]
+		if !ok {
+			t.Fatalf("no fixture for the supported event version %d", version)
+		}
+		var body EventBody
+		if err := body.ProtoUnmarshal(fixture); err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if body.Version != version || body.Index != 1 || !bytes.Equal(body.Creator, []byte{0x01}) {
+			t.Fatalf("version %d: decoded %+v", version, body)
+		}
+		if err := CheckEventVersion(body.Version); err != nil {
+			t.Fatalf("version %d: expected supported, got %v", version, err)
+		}
+		// the signed bytes of the body are those it was decoded from
+		data, err := body.ProtoMarshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, fixture) {
+			t.Fatalf("version %d: marshalled %x, expected %x", version, data, fixture)
+		}
+	}
+}
+
+func TestEventVersionRejectsNewer(t *testing.T) {
+	newer := SupportedEventVersions.Max + 1
+
+	// a body of a newer version decodes, but is refused
+	var body EventBody
+	if err := body.ProtoUnmarshal([]byte{0x22, 0x01, 0x01, 0x28, 0x01, 0x50, byte(newer)}); err != nil {
+		t.Fatal(err)
+	}
+	err := CheckEventVersion(body.Version)
+	if !IsUnsupportedEventVersion(err) || !err.(*ErrUnsupportedEventVersion).Newer() {
+		t.Fatalf("expected a newer event version refused, got %v", err)
+	}
+
+	participants, keys := iteratorParticipants()
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ReadWireInfo(WireEvent{Body: WireBody{Version: newer}}); !IsUnsupportedEventVersion(err) {
+		t.Fatalf("expected a wire event of version %d refused, got %v", newer, err)
+	}
+
+	ev := capEvent(participants, keys[0], nil, EventHash{}, "tx")
+	ev.SetVersion(newer)
+	if err := ev.Sign(keys[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.InsertEvent(ev, false); !IsUnsupportedEventVersion(err) {
+		t.Fatalf("expected an event of version %d refused, got %v", newer, err)
+	}
+
+	// every supported version is inserted
+	for i, version := range []uint32{SupportedEventVersions.Min, SupportedEventVersions.Max} {
+		ev := capEvent(participants, keys[i+1], nil, EventHash{}, "tx")
+		ev.SetVersion(version)
+		if err := ev.Sign(keys[i+1]); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.InsertEvent(ev, false); err != nil {
+			t.Fatalf("expected an event of version %d accepted, got %v", version, err)
+		}
+	}
+}
+
+func TestEventVersionSigned(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an event whose version is changed after signing no longer verifies
+	ev := capEvent(participants, keys[0], nil, EventHash{}, "tx")
+	ev.SetVersion(EventVersion1)
+	if err := p.InsertEvent(ev, false); err == nil || IsUnsupportedEventVersion(err) {
+		t.Fatalf("expected the signature to cover the version, got %v", err)
+	}
+}
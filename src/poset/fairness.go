@@ -0,0 +1,240 @@
+package poset
+
+import (
+	"sort"
+	"sync"
+)
+
+// FairnessCounts are the contributions of a creator to the consensus: the
+// events it created, those of them which became clotho and Atropos, and the
+// transactions of its events which went in blocks
+type FairnessCounts struct {
+	Events       uint64 `json:"events"`
+	Clothos      uint64 `json:"clothos"`
+	Atropos      uint64 `json:"atropos"`
+	Transactions uint64 `json:"transactions"`
+}
+
+// FairnessShares are FairnessCounts normalized, each in [0, 1]
+type FairnessShares struct {
+	Events       float64 `json:"events"`
+	Clothos      float64 `json:"clothos"`
+	Atropos      float64 `json:"atropos"`
+	Transactions float64 `json:"transactions"`
+}
+
+// CreatorFairness is the row of a creator in the fairness report. Shares
+// are its counts over the totals of all the creators, 0 when a total is.
+type CreatorFairness struct {
+	Creator string         `json:"creator"`
+	Counts  FairnessCounts `json:"counts"`
+	Shares  FairnessShares `json:"shares"`
+}
+
+// FairnessReport tells how evenly the creators decide the rounds. Gini are
+// the Gini coefficients of each count across the creators, from 0 when all
+// contribute the same to 1 when a single one does it all.
+type FairnessReport struct {
+	Creators []CreatorFairness `json:"creators"`
+	Totals   FairnessCounts    `json:"totals"`
+	Gini     FairnessShares    `json:"gini"`
+}
+
+// NewFairnessReport makes the report of the counts by creator, sorted by
+// creator. The creators which contributed nothing must be in counts, at
+// zero, to weigh in the Gini coefficients.
+func NewFairnessReport(counts map[string]FairnessCounts) FairnessReport {
+	report := FairnessReport{Creators: make([]CreatorFairness, 0, len(counts))}
+	for creator, c := range counts {
+		report.Creators = append(report.Creators, CreatorFairness{Creator: creator, Counts: c})
+		report.Totals.Events += c.Events
+		report.Totals.Clothos += c.Clothos
+		report.Totals.Atropos += c.Atropos
+		report.Totals.Transactions += c.Transactions
+	}
+	sort.Slice(report.Creators, func(i, j int) bool {
+		return report.Creators[i].Creator < report.Creators[j].Creator
+	})
+
+	share := func(n, total uint64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(n) / float64(total)
+	}
+	values := make([][4]uint64, len(report.Creators))
+	for i := range report.Creators {
+		c, t := report.Creators[i].Counts, report.Totals
+		report.Creators[i].Shares = FairnessShares{
+			Events:       share(c.Events, t.Events),
+			Clothos:      share(c.Clothos, t.Clothos),
+			Atropos:      share(c.Atropos, t.Atropos),
+			Transactions: share(c.Transactions, t.Transactions),
+		}
+		values[i] = [4]uint64{c.Events, c.Clothos, c.Atropos, c.Transactions}
+	}
+	column := func(k int) float64 {
+		xs := make([]uint64, len(values))
+		for i := range values {
+			xs[i] = values[i][k]
+		}
+		return gini(xs)
+	}
+	report.Gini = FairnessShares{
+		Events:       column(0),
+		Clothos:      column(1),
+		Atropos:      column(2),
+		Transactions: column(3),
+	}
+	return report
+}
+
+// gini returns the Gini coefficient of xs, the mean absolute difference of
+// its pairs over twice its mean, 0 when empty or all zeros
+func gini(xs []uint64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += float64(x)
+	}
+	if sum == 0 {
+		return 0
+	}
+	var diffs float64
+	for _, x := range xs {
+		for _, y := range xs {
+			if x > y {
+				diffs += float64(x - y)
+			} else {
+				diffs += float64(y - x)
+			}
+		}
+	}
+	// sum of the |x - y| over 2 n^2 mean, the mean being sum / n
+	return diffs / (2 * float64(len(xs)) * sum)
+}
+
+// fairness counts the contributions of the creators as the consensus
+// decides on their events
+type fairness struct {
+	sync.Mutex
+	counts map[string]*FairnessCounts
+}
+
+// add adds to the counts of creator
+func (f *fairness) add(creator string, delta FairnessCounts) {
+	f.Lock()
+	defer f.Unlock()
+	if f.counts == nil {
+		f.counts = make(map[string]*FairnessCounts)
+	}
+	c, ok := f.counts[creator]
+	if !ok {
+		c = &FairnessCounts{}
+		f.counts[creator] = c
+	}
+	c.Events += delta.Events
+	c.Clothos += delta.Clothos
+	c.Atropos += delta.Atropos
+	c.Transactions += delta.Transactions
+}
+
+// countDecision counts the decision of an audit entry of an insertion, the
+// audit trail having an entry at each of them
+func (f *fairness) countDecision(entry AuditEntry) {
+	switch entry.Kind {
+	case AuditEventAccepted:
+		f.add(entry.Creator, FairnessCounts{Events: 1})
+	case AuditClotho:
+		f.add(entry.Creator, FairnessCounts{Clothos: 1})
+	case AuditAtropos:
+		f.add(entry.Creator, FairnessCounts{Atropos: 1})
+	}
+}
+
+// FairnessReport returns the contributions of the participants to the
+// consensus since the poset was made or bootstrapped, see FairnessReport
+func (p *Poset) FairnessReport() FairnessReport {
+	counts := make(map[string]FairnessCounts)
+	for _, creator := range p.Participants.ToPubKeySlice() {
+		counts[creator] = FairnessCounts{}
+	}
+	p.fairness.Lock()
+	for creator, c := range p.fairness.counts {
+		counts[creator] = *c
+	}
+	p.fairness.Unlock()
+	return NewFairnessReport(counts)
+}
+
+// StoreFairness returns the contributions of the participants to the
+// consensus from the events of a store, with their clotho and Atropos
+// flags, and from the frames of its blocks, as the poset counts them
+func StoreFairness(store Store) (FairnessReport, error) {
+	participants, err := store.Participants()
+	if err != nil {
+		return FairnessReport{}, err
+	}
+	counts := make(map[string]FairnessCounts)
+	for _, creator := range participants.ToPubKeySlice() {
+		counts[creator] = FairnessCounts{}
+	}
+	err = store.ForEachEvent(func(ev Event) bool {
+		c := counts[ev.GetCreator()]
+		c.Events++
+		if ev.Clotho {
+			c.Clothos++
+		}
+		if ev.Atropos {
+			c.Atropos++
+		}
+		counts[ev.GetCreator()] = c
+		return true
+	})
+	if err != nil {
+		return FairnessReport{}, err
+	}
+
+	// the transactions of a frame may be split over several blocks
+	framed := make(map[int64]bool)
+	for i := int64(0); i <= store.LastBlockIndex(); i++ {
+		block, err := store.GetBlock(i)
+		if err != nil {
+			return FairnessReport{}, err
+		}
+		frame := block.RoundReceived()
+		if framed[frame] {
+			continue
+		}
+		framed[frame] = true
+		txs, err := creatorTransactions(store, frame)
+		if err != nil {
+			return FairnessReport{}, err
+		}
+		for creator, n := range txs {
+			c := counts[creator]
+			c.Transactions += n
+			counts[creator] = c
+		}
+	}
+	return NewFairnessReport(counts), nil
+}
+
+// creatorTransactions returns the number of transactions of the events of a
+// frame by creator, those its block is made of
+func creatorTransactions(store Store, frame int64) (map[string]uint64, error) {
+	hashes, err := store.EventsByRoundRange(frame, frame)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]uint64)
+	for _, hash := range hashes {
+		ev, err := store.GetEventBlock(hash)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(ev.Transactions()); n > 0 {
+			res[ev.GetCreator()] += uint64(n)
+		}
+	}
+	return res, nil
+}
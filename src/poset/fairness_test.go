@@ -0,0 +1,103 @@
+package poset
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNewFairnessReport(t *testing.T) {
+	report := NewFairnessReport(map[string]FairnessCounts{
+		"b": {Events: 6, Clothos: 3, Atropos: 1, Transactions: 30},
+		"a": {Events: 2, Clothos: 1, Atropos: 1, Transactions: 10},
+		"c": {},
+	})
+	if len(report.Creators) != 3 || report.Creators[0].Creator != "a" || report.Creators[2].Creator != "c" {
+		t.Fatalf("expected the creators sorted, got %+v", report.Creators)
+	}
+	if expected := (FairnessCounts{8, 4, 2, 40}); report.Totals != expected {
+		t.Fatalf("expected the totals %+v, got %+v", expected, report.Totals)
+	}
+	if expected := (FairnessShares{0.75, 0.75, 0.5, 0.75}); report.Creators[1].Shares != expected {
+		t.Fatalf("expected the shares of b %+v, got %+v", expected, report.Creators[1].Shares)
+	}
+	if report.Creators[2].Shares != (FairnessShares{}) {
+		t.Fatalf("expected no share for c, got %+v", report.Creators[2].Shares)
+	}
+	// the pairs of 2, 6, 0 differ by 4, 2 and 6 in both orders
+	if g := report.Gini.Events; math.Abs(g-24.0/(2*3*8)) > 1e-9 {
+		t.Fatalf("expected a Gini of 0.5 for the events, got %v", g)
+	}
+
+	even := NewFairnessReport(map[string]FairnessCounts{
+		"a": {Events: 5, Clothos: 2},
+		"b": {Events: 5, Clothos: 2},
+	})
+	if even.Gini != (FairnessShares{}) {
+		t.Fatalf("expected even counts to have no Gini, got %+v", even.Gini)
+	}
+	single := NewFairnessReport(map[string]FairnessCounts{
+		"a": {Clothos: 4},
+		"b": {},
+		"c": {},
+		"d": {},
+	})
+	// a single creator of n has a Gini of 1 - 1/n
+	if g := single.Gini.Clothos; math.Abs(g-0.75) > 1e-9 {
+		t.Fatalf("expected a Gini of 0.75 for the clothos, got %v", g)
+	}
+}
+
+func TestPosetFairness(t *testing.T) {
+	f := newFixture(t, fixtureConfig{Participants: 4, Events: 200, Seed: 3, Txs: 2, TxSize: 8})
+	p, commitCh := f.committingPoset(t)
+	for i, ev := range f.copyEvents() {
+		if err := p.InsertEvent(ev, false); err != nil {
+			t.Fatalf("inserting event %d: %v", i, err)
+		}
+	}
+	if err := p.RunToQuiescence(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(map[string]uint64)
+	for _, ev := range f.events {
+		events[ev.GetCreator()]++
+	}
+	// the blocks are stored as a node does
+	var blockTxs uint64
+	for _, block := range committedBlocks(commitCh) {
+		blockTxs += uint64(len(block.Transactions()))
+		if err := p.Store.SetBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if blockTxs == 0 {
+		t.Fatal("expected blocks")
+	}
+
+	report := p.FairnessReport()
+	if len(report.Creators) != len(f.keys) {
+		t.Fatalf("expected a row per participant, got %d", len(report.Creators))
+	}
+	for _, c := range report.Creators {
+		if c.Counts.Events != events[c.Creator] {
+			t.Fatalf("%s: expected %d events, got %d", c.Creator, events[c.Creator], c.Counts.Events)
+		}
+		if c.Counts.Clothos == 0 || c.Counts.Atropos > c.Counts.Clothos {
+			t.Fatalf("%s: expected clothos, Atropos among them, got %+v", c.Creator, c.Counts)
+		}
+	}
+	if report.Totals.Transactions != blockTxs {
+		t.Fatalf("expected the %d transactions of the blocks, got %d", blockTxs, report.Totals.Transactions)
+	}
+
+	// the store tells the same once the poset is gone
+	fromStore, err := StoreFairness(p.Store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromStore, report) {
+		t.Fatalf("expected the store to give %+v, got %+v", report, fromStore)
+	}
+}
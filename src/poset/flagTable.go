@@ -1,6 +1,10 @@
 package poset
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/golang/protobuf/proto"
 )
 
@@ -54,3 +58,75 @@ func (ft FlagTable) Copy() FlagTable {
 	}
 	return res
 }
+
+// MergeFlagTables returns the roots of frame the two tables hold, the flag
+// table of an event whose parents have the tables. An entry of a frame below
+// or above frame is dropped, whichever table holds it: the roots of other
+// frames do not count for the frame. An entry at frame in either table is
+// kept, at frame, even when the other table holds the root at another frame.
+// The result is a new table, the same whatever the order of the arguments.
+func MergeFlagTables(a, b FlagTable, frame int64) FlagTable {
+	res := NewFlagTable()
+	for _, table := range []FlagTable{a, b} {
+		for id, f := range table {
+			if f == frame {
+				res[id] = frame
+			}
+		}
+	}
+	return res
+}
+
+// FlagTableDiff is how two flag tables differ
+type FlagTableDiff struct {
+	// Added are the entries of the other table only
+	Added FlagTable
+	// Removed are the entries of the table only
+	Removed FlagTable
+	// Changed are the frames of the other table of the entries both hold at
+	// different frames
+	Changed FlagTable
+}
+
+// Empty returns true when the tables are the same
+func (d FlagTableDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func (d FlagTableDiff) String() string {
+	var lines []string
+	for _, part := range []struct {
+		sign  string
+		table FlagTable
+	}{{"+", d.Added}, {"-", d.Removed}, {"~", d.Changed}} {
+		for id, frame := range part.table {
+			lines = append(lines, fmt.Sprintf("%s%s:%d", part.sign, id.String(), frame))
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// Diff returns how other differs from the table, to debug the flag tables
+// of events which should be the same on every node
+func (ft FlagTable) Diff(other FlagTable) FlagTableDiff {
+	diff := FlagTableDiff{
+		Added:   NewFlagTable(),
+		Removed: NewFlagTable(),
+		Changed: NewFlagTable(),
+	}
+	for id, frame := range ft {
+		otherFrame, ok := other[id]
+		if !ok {
+			diff.Removed[id] = frame
+		} else if otherFrame != frame {
+			diff.Changed[id] = otherFrame
+		}
+	}
+	for id, frame := range other {
+		if _, ok := ft[id]; !ok {
+			diff.Added[id] = frame
+		}
+	}
+	return diff
+}
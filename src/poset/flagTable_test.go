@@ -0,0 +1,215 @@
+package poset
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// rootsTable returns the table of the roots named after their creator, at
+// their frame
+func rootsTable(roots map[string]int64) FlagTable {
+	ft := NewFlagTable()
+	for name, frame := range roots {
+		ft[fakeEventHash(name)] = frame
+	}
+	return ft
+}
+
+func TestMergeFlagTables(t *testing.T) {
+	cases := []struct {
+		name     string
+		a, b     map[string]int64
+		frame    int64
+		expected map[string]int64
+	}{
+		{"empty", nil, nil, 1, nil},
+		{"below frame", map[string]int64{"a": 0}, map[string]int64{"b": 0}, 1, nil},
+		{"above frame", map[string]int64{"a": 2}, map[string]int64{"b": 3}, 1, nil},
+		{"at frame", map[string]int64{"a": 1}, map[string]int64{"b": 1}, 1,
+			map[string]int64{"a": 1, "b": 1}},
+		{"same root", map[string]int64{"a": 1}, map[string]int64{"a": 1}, 1,
+			map[string]int64{"a": 1}},
+		{"root at another frame in the other table", map[string]int64{"a": 1}, map[string]int64{"a": 0}, 1,
+			map[string]int64{"a": 1}},
+		{"missing from one table", map[string]int64{"a": 1, "b": 1}, map[string]int64{"c": 0}, 1,
+			map[string]int64{"a": 1, "b": 1}},
+		{"mixed", map[string]int64{"a": 0, "b": 1, "c": 2}, map[string]int64{"a": 1, "c": 1, "d": 0}, 1,
+			map[string]int64{"a": 1, "b": 1, "c": 1}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, b := rootsTable(c.a), rootsTable(c.b)
+			expected := rootsTable(c.expected)
+			for _, args := range [][2]FlagTable{{a, b}, {b, a}} {
+				res := MergeFlagTables(args[0], args[1], c.frame)
+				if diff := expected.Diff(res); !diff.Empty() {
+					t.Fatalf("expected %v, got the differences:\n%s", expected, diff)
+				}
+			}
+			if !reflect.DeepEqual(a, rootsTable(c.a)) || !reflect.DeepEqual(b, rootsTable(c.b)) {
+				t.Fatal("expected the merged tables unchanged")
+			}
+		})
+	}
+}
+
+func TestFlagTableDiff(t *testing.T) {
+	a := rootsTable(map[string]int64{"a": 1, "b": 1, "c": 1})
+	b := rootsTable(map[string]int64{"a": 1, "b": 2, "d": 1})
+	diff := a.Diff(b)
+	if !reflect.DeepEqual(diff.Added, rootsTable(map[string]int64{"d": 1})) ||
+		!reflect.DeepEqual(diff.Removed, rootsTable(map[string]int64{"c": 1})) ||
+		!reflect.DeepEqual(diff.Changed, rootsTable(map[string]int64{"b": 2})) {
+		t.Fatalf("unexpected diff:\n%s", diff)
+	}
+	added, removed, changed := fakeEventHash("d"), fakeEventHash("c"), fakeEventHash("b")
+	expected := fmt.Sprintf("+%s:1\n-%s:1\n~%s:2", added.String(), removed.String(), changed.String())
+	if diff.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, diff)
+	}
+	if !a.Diff(a.Copy()).Empty() {
+		t.Fatal("expected no difference with a copy")
+	}
+}
+
+// frameModel is the reference model of the frame of an event: the roots
+// each parent sees, by frame
+type frameModel struct {
+	selfFrame, otherFrame int64
+	// self and other are the roots the parents see in their frame
+	self, other []string
+	// stale are roots of the frame before that of each table
+	stale []string
+	// otherRoot are the roots the root of the other-parent saw in the
+	// frame before its own
+	otherRoot []string
+	// first is set for the first event of the creator
+	first bool
+}
+
+// expect returns the frame, root and tables the model gives with a
+// supermajority of superMajority
+func (m frameModel) expect(superMajority int) (frame int64, root bool, flagTable, rootTable map[string]int64) {
+	seen := func(frame int64, sets ...[]string) map[string]int64 {
+		res := map[string]int64{}
+		for _, set := range sets {
+			for _, name := range set {
+				res[name] = frame
+			}
+		}
+		return res
+	}
+	if m.selfFrame == m.otherFrame {
+		roots := seen(m.selfFrame, m.self, m.other)
+		if len(roots) >= superMajority {
+			return m.selfFrame + 1, true, nil, roots
+		}
+		return m.selfFrame, m.first, roots, nil
+	}
+	// a parent ahead passes its flag table on as is
+	passed := func(frame int64, roots []string) map[string]int64 {
+		res := seen(frame, roots)
+		for name, f := range seen(frame-1, m.stale) {
+			res[name] = f
+		}
+		return res
+	}
+	if m.selfFrame > m.otherFrame {
+		return m.selfFrame, false, passed(m.selfFrame, m.self), nil
+	}
+	// the roots the self-parent saw in the frame before that of the other
+	var selfBefore []string
+	if m.selfFrame == m.otherFrame-1 {
+		selfBefore = m.self
+	}
+	return m.otherFrame, true, passed(m.otherFrame, m.other), seen(m.otherFrame-1, selfBefore, m.otherRoot)
+}
+
+// parents returns the inputs of eventFrame for the model
+func (m frameModel) parents() frameParents {
+	table := func(frame int64, roots []string) FlagTable {
+		ft := NewFlagTable()
+		for _, name := range roots {
+			ft[fakeEventHash(name)] = frame
+		}
+		for _, name := range m.stale {
+			ft[fakeEventHash(name)] = frame - 1
+		}
+		return ft
+	}
+	return frameParents{
+		selfFrame:  m.selfFrame,
+		selfTable:  table(m.selfFrame, m.self),
+		otherFrame: m.otherFrame,
+		otherTable: table(m.otherFrame, m.other),
+		otherRootTable: func() (FlagTable, error) {
+			return table(m.otherFrame-1, m.otherRoot), nil
+		},
+		first: m.first,
+	}
+}
+
+func TestEventFrame(t *testing.T) {
+	const superMajority = 3
+	cases := []struct {
+		name  string
+		model frameModel
+	}{
+		{"equal frames, no supermajority", frameModel{
+			selfFrame: 1, otherFrame: 1, self: []string{"a"}, other: []string{"b"}}},
+		{"equal frames, same roots", frameModel{
+			selfFrame: 1, otherFrame: 1, self: []string{"a", "b"}, other: []string{"a", "b"}}},
+		{"equal frames, supermajority", frameModel{
+			selfFrame: 1, otherFrame: 1, self: []string{"a", "b"}, other: []string{"b", "c"}}},
+		{"equal frames, stale roots do not count", frameModel{
+			selfFrame: 2, otherFrame: 2, self: []string{"a"}, other: []string{"b"},
+			stale: []string{"c", "d"}}},
+		{"equal frames, first event", frameModel{
+			selfFrame: 1, otherFrame: 1, self: []string{"a"}, other: []string{"b"}, first: true}},
+		{"equal frames, first event with a supermajority", frameModel{
+			selfFrame: 1, otherFrame: 1, self: []string{"a", "b"}, other: []string{"c"}, first: true}},
+		{"equal frames, all roots", frameModel{
+			selfFrame: 0, otherFrame: 0, self: []string{"a", "b", "c", "d"}, other: []string{"d"}}},
+		{"parent ahead", frameModel{
+			selfFrame: 2, otherFrame: 1, self: []string{"a"}, other: []string{"a", "b", "c"}}},
+		{"parent ahead, stale roots", frameModel{
+			selfFrame: 3, otherFrame: 1, self: []string{"a", "b"}, other: []string{"c"},
+			stale: []string{"d"}}},
+		{"other-parent ahead", frameModel{
+			selfFrame: 1, otherFrame: 2, self: []string{"a"}, other: []string{"b"},
+			otherRoot: []string{"b", "c", "d"}}},
+		{"other-parent ahead, same roots", frameModel{
+			selfFrame: 1, otherFrame: 2, self: []string{"b", "c"}, other: []string{"b"},
+			otherRoot: []string{"b", "c", "d"}}},
+		{"other-parent ahead by frames", frameModel{
+			selfFrame: 1, otherFrame: 3, self: []string{"a"}, other: []string{"b"},
+			otherRoot: []string{"b", "c", "d"}, stale: []string{"e"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			parents := c.model.parents()
+			selfTable, otherTable := parents.selfTable.Copy(), parents.otherTable.Copy()
+
+			frame, root, flagTable, rootTable, err := eventFrame(parents, superMajority)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expFrame, expRoot, expFlagTable, expRootTable := c.model.expect(superMajority)
+			if frame != expFrame || root != expRoot {
+				t.Fatalf("expected frame %d, root %v, got frame %d, root %v",
+					expFrame, expRoot, frame, root)
+			}
+			if diff := rootsTable(expFlagTable).Diff(flagTable); !diff.Empty() {
+				t.Fatalf("flag table differences:\n%s", diff)
+			}
+			if diff := rootsTable(expRootTable).Diff(rootTable); !diff.Empty() {
+				t.Fatalf("root table differences:\n%s", diff)
+			}
+			if !reflect.DeepEqual(selfTable, parents.selfTable) ||
+				!reflect.DeepEqual(otherTable, parents.otherTable) {
+				t.Fatal("expected the tables of the parents unchanged")
+			}
+		})
+	}
+}
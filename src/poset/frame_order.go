@@ -0,0 +1,100 @@
+package poset
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// ByFinalOrder implements sort.Interface for the []Event of a final frame in
+// the order their transactions go into its block: by frame, lamport
+// timestamp, atropos timestamp, then hash. It is the order of SORT_IDX.
+type ByFinalOrder []Event
+
+func (a ByFinalOrder) Len() int      { return len(a) }
+func (a ByFinalOrder) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByFinalOrder) Less(i, j int) bool {
+	if a[i].Frame != a[j].Frame {
+		return a[i].Frame < a[j].Frame
+	}
+	if a[i].LamportTimestamp != a[j].LamportTimestamp {
+		return a[i].LamportTimestamp < a[j].LamportTimestamp
+	}
+	if a[i].AtroposTimestamp != a[j].AtroposTimestamp {
+		return a[i].AtroposTimestamp < a[j].AtroposTimestamp
+	}
+	hi, hj := a[i].Hash(), a[j].Hash()
+	return bytes.Compare(hi.Bytes(), hj.Bytes()) < 0
+}
+
+// OrderedEventRef is an event of a final frame with its position in the
+// frame, the order its transactions are in the block of the frame
+type OrderedEventRef struct {
+	Hash             string `json:"hash"`
+	Creator          string `json:"creator"`
+	CreatorID        uint64 `json:"creator_id"`
+	Index            int64  `json:"index"`
+	LamportTimestamp int64  `json:"lamport_timestamp"`
+	AtroposTimestamp int64  `json:"atropos_timestamp"`
+	Position         int    `json:"position"`
+	Transactions     int    `json:"transactions"`
+}
+
+// FrameNotFinalError is returned by GetFrameOrdering for a frame whose order
+// is not decided yet, with how far it got
+type FrameNotFinalError struct {
+	Status FrameStatus
+}
+
+func (e *FrameNotFinalError) Error() string {
+	return fmt.Sprintf("frame %d is not final: %s", e.Status.Frame, e.Status.Stage)
+}
+
+// IsFrameNotFinal returns true for a FrameNotFinalError
+func IsFrameNotFinal(err error) bool {
+	_, ok := err.(*FrameNotFinalError)
+	return ok
+}
+
+// GetFrameOrdering returns the events of a final frame in their final order,
+// the one of the transactions of its block
+func (p *Poset) GetFrameOrdering(frame int64) ([]OrderedEventRef, error) {
+	p.DecidedLocker.Lock()
+	final := frame < p.nextFinalFrame
+	p.DecidedLocker.Unlock()
+	if !final {
+		status, err := p.frameStatus(frame)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &FrameNotFinalError{Status: status}
+	}
+
+	hashes, err := p.Store.EventsByRoundRange(frame, frame)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, len(hashes))
+	for i, hash := range hashes {
+		if events[i], err = p.Store.GetEventBlock(hash); err != nil {
+			return nil, err
+		}
+	}
+	sort.Sort(ByFinalOrder(events))
+
+	refs := make([]OrderedEventRef, len(events))
+	for i, ev := range events {
+		hash := ev.Hash()
+		refs[i] = OrderedEventRef{
+			Hash:             hash.String(),
+			Creator:          ev.GetCreator(),
+			CreatorID:        ev.CreatorID(),
+			Index:            ev.Index(),
+			LamportTimestamp: ev.LamportTimestamp,
+			AtroposTimestamp: ev.AtroposTimestamp,
+			Position:         i,
+			Transactions:     len(ev.Transactions()),
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,108 @@
+package poset
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TestFrameOrdering checks the order GetFrameOrdering reports for a final
+// frame is the order of the transactions of its block
+func TestFrameOrdering(t *testing.T) {
+	dir, err := ioutil.TempDir("", "frame_order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	participants, keys := iteratorParticipants()
+	store, err := NewBadgerStore(participants, cacheSize, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// the events of the frames 1 and 2 are received, with lamport timestamps
+	// in a tie and atropos timestamps out of the order of the creators. An
+	// event of the frame 3 waits for consensus.
+	heads := make([]*Event, len(keys))
+	for frame := int64(1); frame <= 3; frame++ {
+		for i, key := range keys {
+			if frame == 3 && i > 0 {
+				break
+			}
+			ev := capEvent(participants, key, heads[i], EventHash{}, fmt.Sprintf("tx %d %d", frame, i))
+			ev.Frame = frame
+			ev.LamportTimestamp = frame*10 + int64(i/2)
+			ev.AtroposTimestamp = frame*10 + int64(len(keys)-i)
+			if frame < 3 {
+				ev.FrameReceived = frame + 1
+			}
+			if err := store.SetEvent(ev); err != nil {
+				t.Fatal(err)
+			}
+			heads[i] = &ev
+		}
+	}
+
+	logger := logrus.New()
+	logger.Level = logrus.FatalLevel
+	commitCh := make(chan Block, 10)
+	p, err := NewPoset(participants, store, commitCh, logrus.NewEntry(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// as an Atropos of the frame 3 decided
+	p.setDecidedFrame(3)
+	if err := p.ProcessDecidedRounds(); err != nil {
+		t.Fatal(err)
+	}
+	// the frame 0 has no event
+	if len(commitCh) != 3 {
+		t.Fatalf("expected the blocks of 3 frames, got %d", len(commitCh))
+	}
+	<-commitCh
+
+	for frame := int64(1); frame <= 2; frame++ {
+		block := <-commitCh
+		refs, err := p.GetFrameOrdering(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(refs) != len(keys) {
+			t.Fatalf("frame %d: expected %d events, got %d", frame, len(keys), len(refs))
+		}
+
+		var txs [][]byte
+		for i, ref := range refs {
+			if ref.Position != i {
+				t.Fatalf("frame %d: expected position %d, got %d", frame, i, ref.Position)
+			}
+			var hash EventHash
+			if err := hash.Parse(ref.Hash); err != nil {
+				t.Fatal(err)
+			}
+			ev, err := store.GetEventBlock(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txs = append(txs, ev.Transactions()...)
+		}
+		if !reflect.DeepEqual(txs, block.Transactions()) {
+			t.Fatalf("frame %d: expected the transactions of the block %q, got %q",
+				frame, block.Transactions(), txs)
+		}
+	}
+
+	_, err = p.GetFrameOrdering(3)
+	if !IsFrameNotFinal(err) {
+		t.Fatalf("expected the frame 3 not final, got %v", err)
+	}
+	if status := err.(*FrameNotFinalError).Status; status.Frame != 3 || status.Events != 1 {
+		t.Fatalf("expected the status of the frame 3 with its event, got %+v", status)
+	}
+}
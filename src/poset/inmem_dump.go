@@ -0,0 +1,533 @@
+package poset
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/golang-lru"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/pos"
+)
+
+// inmemDumpVersion is the version of the dump format, a dump of another
+// version is refused
+const inmemDumpVersion = 3
+
+// indexDump is a rolling index: the hashes it holds, oldest first, and the
+// index of the last one
+type indexDump struct {
+	Last   int64    `json:"last"`
+	Hashes []string `json:"hashes"`
+}
+
+// clothoCheckDump is an entry of a clotho check cache
+type clothoCheckDump struct {
+	Key  string `json:"key"`
+	Hash string `json:"hash"`
+}
+
+// timeTableDump is the time table of a root
+type timeTableDump struct {
+	Frame int64  `json:"frame"`
+	Root  string `json:"root"`
+	Table []byte `json:"table"`
+}
+
+// inmemDumpHeader opens a dump and holds everything but the events, rounds,
+// blocks, frames, checkpoints and roots, which follow it as records in this
+// order. The caches are listed from the least recently used entry.
+type inmemDumpHeader struct {
+	Version      int         `json:"version"`
+	CacheSize    int         `json:"cache_size"`
+	Participants []string    `json:"participants"`
+	StateRoot    common.Hash `json:"state_root"`
+
+	LastRound           int64              `json:"last_round"`
+	LastBlock           int64              `json:"last_block"`
+	TotConsensusEvents  int64              `json:"tot_consensus_events"`
+	TimeTablesFrom      int64              `json:"time_tables_from"`
+	ConsensusConfigHash common.Hash        `json:"consensus_config_hash"`
+	BlockCarries        map[int64][][]byte `json:"block_carries"`
+
+	// the number, or the keys, of the records of each kind
+	Events      int      `json:"events"`
+	Rounds      []int64  `json:"rounds"`
+	Blocks      int      `json:"blocks"`
+	Frames      int      `json:"frames"`
+	Checkpoints int      `json:"checkpoints"`
+	Roots       []string `json:"roots"`
+
+	Topological         indexDump                         `json:"topological"`
+	Consensus           indexDump                         `json:"consensus"`
+	ParticipantEvents   map[uint64]indexDump              `json:"participant_events"`
+	LastConsensusEvents map[string]string                 `json:"last_consensus_events"`
+	ArchivedEvents      []string                          `json:"archived_events"`
+	ClothoChecks        []clothoCheckDump                 `json:"clotho_checks"`
+	ClothoCreatorChecks []clothoCheckDump                 `json:"clotho_creator_checks"`
+	TimeTables          []timeTableDump                   `json:"time_tables"`
+	PeerReputations     map[string]PeerReputation         `json:"peer_reputations"`
+	PeerAddresses       map[string]peers.AddrAnnouncement `json:"peer_addresses"`
+	Journal             []Block                           `json:"journal"`
+	LastAcknowledged    int64                             `json:"last_acknowledged"`
+	TxIndex             bool                              `json:"tx_index"`
+	TxLocations         []TxLocation                      `json:"tx_locations"`
+}
+
+// DumpToFile writes the whole content of the store to path, to load it back
+// with LoadFromFile, in a gzip file holding a JSON header followed by length
+// prefixed records. The store must not be written to meanwhile.
+func (s *InmemStore) DumpToFile(path string) error {
+	header, records, err := s.dump()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	// written aside and renamed, so a dump is whole or missing
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	zw := gzip.NewWriter(tmp)
+	w := bufio.NewWriter(zw)
+	for _, record := range append([][]byte{data}, records...) {
+		if err := writeArchiveRecord(w, record); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *InmemStore) dump() (*inmemDumpHeader, [][]byte, error) {
+	h := &inmemDumpHeader{
+		Version:             inmemDumpVersion,
+		CacheSize:           s.cacheSize,
+		StateRoot:           s.stateRoot,
+		LastRound:           s.LastRound(),
+		LastBlock:           s.LastBlockIndex(),
+		TotConsensusEvents:  s.ConsensusEventsCount(),
+		ParticipantEvents:   make(map[uint64]indexDump),
+		LastConsensusEvents: make(map[string]string),
+	}
+	h.ConsensusConfigHash, _ = s.ConsensusConfigHash()
+	s.blockCarryLocker.RLock()
+	h.BlockCarries = s.blockCarries
+	s.blockCarryLocker.RUnlock()
+	h.PeerReputations, _ = s.PeerReputations()
+	h.PeerAddresses, _ = s.PeerAddresses()
+	h.Journal, _ = s.JournalledBlocks()
+	h.LastAcknowledged, _ = s.LastAcknowledgedBlock()
+	for _, p := range s.participants.ToPeerSlice() {
+		h.Participants = append(h.Participants, p.Message.PubKeyHex)
+		window, last, err := s.participantEventsCache.rim.GetLastWindow(p.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		index := indexDump{Last: last}
+		for _, item := range window {
+			var hash EventHash
+			hash.Set(item.([]byte))
+			index.Hashes = append(index.Hashes, hash.String())
+		}
+		h.ParticipantEvents[p.ID] = index
+	}
+
+	var records [][]byte
+	add := func(data []byte, err error) error {
+		if err == nil {
+			records = append(records, data)
+		}
+		return err
+	}
+
+	for _, key := range s.eventCache.Keys() {
+		if value, ok := s.eventCache.Peek(key); ok {
+			event := value.(Event)
+			if err := add(event.StoreMarshal()); err != nil {
+				return nil, nil, err
+			}
+			h.Events++
+		}
+	}
+	for _, key := range s.roundCache.Keys() {
+		if value, ok := s.roundCache.Peek(key); ok {
+			round := value.(Round)
+			if err := add(round.ProtoMarshal()); err != nil {
+				return nil, nil, err
+			}
+			h.Rounds = append(h.Rounds, key.(int64))
+		}
+	}
+	for _, key := range s.blockCache.Keys() {
+		if value, ok := s.blockCache.Peek(key); ok {
+			block := value.(Block)
+			if err := add(block.ProtoMarshal()); err != nil {
+				return nil, nil, err
+			}
+			h.Blocks++
+		}
+	}
+	for _, key := range s.frameCache.Keys() {
+		if value, ok := s.frameCache.Peek(key); ok {
+			frame := value.(Frame)
+			if err := add(frame.ProtoMarshal()); err != nil {
+				return nil, nil, err
+			}
+			h.Frames++
+		}
+	}
+	for _, checkpoint := range s.checkpoints {
+		if err := add(json.Marshal(checkpoint)); err != nil {
+			return nil, nil, err
+		}
+		h.Checkpoints++
+	}
+	for participant, root := range s.rootsByParticipant {
+		if err := add(root.ProtoMarshal()); err != nil {
+			return nil, nil, err
+		}
+		h.Roots = append(h.Roots, participant)
+	}
+
+	s.topologicalIndexLocker.Lock()
+	h.Topological = dumpRollingIndex(s.topologicalIndex)
+	s.topologicalIndexLocker.Unlock()
+	s.totConsensusEventsLocker.RLock()
+	h.Consensus = dumpRollingIndex(s.consensusCache)
+	for participant, hash := range s.lastConsensusEvents {
+		h.LastConsensusEvents[participant] = hash.String()
+	}
+	s.totConsensusEventsLocker.RUnlock()
+
+	archived, _ := s.ArchivedEvents()
+	for _, hash := range archived {
+		h.ArchivedEvents = append(h.ArchivedEvents, hash.String())
+	}
+
+	s.clothoCheckLocker.Lock()
+	h.ClothoChecks = dumpClothoChecks(s.clothoCheckCache)
+	h.ClothoCreatorChecks = dumpClothoChecks(s.clothoCheckCreatorCache)
+	s.clothoCheckLocker.Unlock()
+
+	s.timeTableLocker.RLock()
+	h.TimeTablesFrom = s.timeTablesFrom
+	for frame, tables := range s.timeTables {
+		for root, table := range tables {
+			h.TimeTables = append(h.TimeTables, timeTableDump{
+				Frame: frame,
+				Root:  root.String(),
+				Table: table.Marshal(),
+			})
+		}
+	}
+	s.timeTableLocker.RUnlock()
+
+	if s.txIndex != nil {
+		h.TxIndex = true
+		for _, key := range s.txIndex.Keys() {
+			if value, ok := s.txIndex.Peek(key); ok {
+				h.TxLocations = append(h.TxLocations, value.(TxLocation))
+			}
+		}
+	}
+
+	return h, records, nil
+}
+
+func dumpRollingIndex(index *common.RollingIndex) indexDump {
+	window, last := index.GetLastWindow()
+	res := indexDump{Last: last}
+	for _, item := range window {
+		hash := item.(EventHash)
+		res.Hashes = append(res.Hashes, hash.String())
+	}
+	return res
+}
+
+func dumpClothoChecks(cache *lru.Cache) []clothoCheckDump {
+	var res []clothoCheckDump
+	for _, key := range cache.Keys() {
+		if value, ok := cache.Peek(key); ok {
+			var hash EventHash
+			hash.Set(value.([]byte))
+			res = append(res, clothoCheckDump{Key: key.(string), Hash: hash.String()})
+		}
+	}
+	return res
+}
+
+// LoadFromFile loads a dump written by DumpToFile into a new store, made
+// for the same participants, cache size and PoS configuration as the dumped
+// one
+func (s *InmemStore) LoadFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("dump %s: %v", path, err)
+	}
+	if err := s.load(bufio.NewReader(zr)); err != nil {
+		return fmt.Errorf("dump %s: %v", path, err)
+	}
+	return nil
+}
+
+func (s *InmemStore) load(r *bufio.Reader) error {
+	data, err := readArchiveRecord(r)
+	if err != nil {
+		return fmt.Errorf("header: %v", err)
+	}
+	var h inmemDumpHeader
+	if err := json.Unmarshal(data, &h); err != nil {
+		return fmt.Errorf("header: %v", err)
+	}
+	if h.Version != inmemDumpVersion {
+		return fmt.Errorf("version %d, expected %d", h.Version, inmemDumpVersion)
+	}
+	if h.CacheSize != s.cacheSize {
+		return fmt.Errorf("cache size %d, the store has %d", h.CacheSize, s.cacheSize)
+	}
+	if h.StateRoot != s.stateRoot {
+		return fmt.Errorf("genesis state root %s, the store has %s",
+			h.StateRoot.Hex(), s.stateRoot.Hex())
+	}
+	for _, participant := range h.Participants {
+		if _, ok := s.participants.ReadByPubKey(participant); !ok {
+			return fmt.Errorf("unknown participant %s", participant)
+		}
+	}
+
+	next := func(what string) ([]byte, error) {
+		data, err := readArchiveRecord(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", what, err)
+		}
+		return data, nil
+	}
+
+	for i := 0; i < h.Events; i++ {
+		data, err := next("event")
+		if err != nil {
+			return err
+		}
+		var event Event
+		if err := event.StoreUnmarshal(data); err != nil {
+			return fmt.Errorf("event: %v", err)
+		}
+		hash := event.Hash()
+		s.eventCache.Add(hash, event)
+		s.addFrameEvent(event.Frame, hash)
+	}
+	for _, r := range h.Rounds {
+		data, err := next("round")
+		if err != nil {
+			return err
+		}
+		round := NewRound()
+		if err := round.ProtoUnmarshal(data); err != nil {
+			return fmt.Errorf("round %d: %v", r, err)
+		}
+		s.roundCache.Add(r, *round)
+	}
+	for i := 0; i < h.Blocks; i++ {
+		data, err := next("block")
+		if err != nil {
+			return err
+		}
+		var block Block
+		if err := block.ProtoUnmarshal(data); err != nil {
+			return fmt.Errorf("block: %v", err)
+		}
+		s.blockCache.Add(block.Index(), block)
+	}
+	for i := 0; i < h.Frames; i++ {
+		data, err := next("frame")
+		if err != nil {
+			return err
+		}
+		var frame Frame
+		if err := frame.ProtoUnmarshal(data); err != nil {
+			return fmt.Errorf("frame: %v", err)
+		}
+		s.frameCache.Add(frame.Round, frame)
+	}
+	for i := 0; i < h.Checkpoints; i++ {
+		data, err := next("checkpoint")
+		if err != nil {
+			return err
+		}
+		var checkpoint Checkpoint
+		if err := json.Unmarshal(data, &checkpoint); err != nil {
+			return fmt.Errorf("checkpoint: %v", err)
+		}
+		s.checkpoints[checkpoint.Frame] = checkpoint
+	}
+	roots := make(map[string]Root, len(h.Roots))
+	for _, participant := range h.Roots {
+		data, err := next("root")
+		if err != nil {
+			return err
+		}
+		var root Root
+		if err := root.ProtoUnmarshal(data); err != nil {
+			return fmt.Errorf("root of %s: %v", participant, err)
+		}
+		roots[participant] = root
+	}
+	if _, err := readArchiveRecord(r); err != io.EOF {
+		return fmt.Errorf("more records than its header")
+	}
+	s.rootsByParticipant = roots
+	s.rootsBySelfParent = nil
+	_ = s.RootsBySelfParent()
+
+	if s.topologicalIndex, err = loadRollingIndex("TopologicalIndex", s.cacheSize, h.Topological); err != nil {
+		return err
+	}
+	if s.consensusCache, err = loadRollingIndex("ConsensusCache", s.cacheSize, h.Consensus); err != nil {
+		return err
+	}
+	s.participantEventsCache = NewParticipantEventsCache(s.cacheSize, s.participants)
+	for id, index := range h.ParticipantEvents {
+		for i, hex := range index.Hashes {
+			var hash EventHash
+			if err := hash.Parse(hex); err != nil {
+				return err
+			}
+			at := index.Last - int64(len(index.Hashes)) + 1 + int64(i)
+			if err := s.participantEventsCache.rim.Set(id, hash.Bytes(), at); err != nil {
+				return err
+			}
+		}
+	}
+	s.lastConsensusEvents = make(map[string]EventHash, len(h.LastConsensusEvents))
+	for participant, hex := range h.LastConsensusEvents {
+		var hash EventHash
+		if err := hash.Parse(hex); err != nil {
+			return err
+		}
+		s.lastConsensusEvents[participant] = hash
+	}
+	archived := make(EventHashes, len(h.ArchivedEvents))
+	for i, hex := range h.ArchivedEvents {
+		if err := archived[i].Parse(hex); err != nil {
+			return err
+		}
+	}
+	if len(archived) > 0 {
+		s.archivedEvents = archived
+	}
+	if err := loadClothoChecks(s.clothoCheckCache, h.ClothoChecks); err != nil {
+		return err
+	}
+	if err := loadClothoChecks(s.clothoCheckCreatorCache, h.ClothoCreatorChecks); err != nil {
+		return err
+	}
+	s.timeTables = make(map[int64]map[EventHash]FlagTable)
+	s.timeTablesFrom = h.TimeTablesFrom
+	for _, t := range h.TimeTables {
+		var root EventHash
+		if err := root.Parse(t.Root); err != nil {
+			return err
+		}
+		table := NewFlagTable()
+		if err := table.Unmarshal(t.Table); err != nil {
+			return fmt.Errorf("time table of %s: %v", t.Root, err)
+		}
+		if s.timeTables[t.Frame] == nil {
+			s.timeTables[t.Frame] = make(map[EventHash]FlagTable)
+		}
+		s.timeTables[t.Frame][root] = table
+	}
+
+	if h.TxIndex {
+		if s.txIndex == nil {
+			s.EnableTxIndex()
+		}
+		for _, loc := range h.TxLocations {
+			s.txIndex.Add(loc.Tx, loc)
+		}
+	}
+
+	s.lastRound = h.LastRound
+	s.lastBlock = h.LastBlock
+	s.totConsensusEvents = h.TotConsensusEvents
+	s.consensusConfigHash = h.ConsensusConfigHash
+	s.blockCarries = h.BlockCarries
+	if len(h.PeerReputations) > 0 {
+		s.peerReputations = h.PeerReputations
+	}
+	if len(h.PeerAddresses) > 0 {
+		s.peerAddresses = h.PeerAddresses
+	}
+	for _, block := range h.Journal {
+		s.journal[block.Index()] = block
+	}
+	s.lastAcknowledged = h.LastAcknowledged
+	return nil
+}
+
+func loadRollingIndex(name string, size int, dump indexDump) (*common.RollingIndex, error) {
+	index := common.NewRollingIndex(name, size)
+	for i, hex := range dump.Hashes {
+		var hash EventHash
+		if err := hash.Parse(hex); err != nil {
+			return nil, err
+		}
+		if err := index.Set(hash, dump.Last-int64(len(dump.Hashes))+1+int64(i)); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+func loadClothoChecks(cache *lru.Cache, checks []clothoCheckDump) error {
+	for _, c := range checks {
+		var hash EventHash
+		if err := hash.Parse(c.Hash); err != nil {
+			return err
+		}
+		cache.Add(c.Key, hash.Bytes())
+	}
+	return nil
+}
+
+// LoadOrCreateInmemStoreFromDump creates an InmemStore and loads the dump at
+// path into it, when there is one
+func LoadOrCreateInmemStoreFromDump(path string, participants *peers.Peers, cacheSize int, posConf *pos.Config) (*InmemStore, error) {
+	store := NewInmemStore(participants, cacheSize, posConf)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return store, nil
+	}
+	if err := store.LoadFromFile(path); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
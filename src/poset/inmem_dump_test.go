@@ -0,0 +1,292 @@
+package poset
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+// dumpCacheSize is small enough for the caches of the dump fixture to roll
+// and evict
+const dumpCacheSize = 5
+
+// newDumpStore returns an in-mem store with every part filled, and the
+// hashes of the events set in it
+func newDumpStore(t *testing.T) (*InmemStore, EventHashes) {
+	participants, keys := iteratorParticipants()
+	store := NewInmemStore(participants, dumpCacheSize, nil)
+	store.EnableTxIndex()
+
+	var hashes EventHashes
+	for i := 0; i < iteratorIndexes; i++ {
+		for _, key := range keys {
+			ev := NewEvent([][]byte{[]byte(fmt.Sprintf("tx%d %d", i, len(hashes)))}, nil, nil,
+				make(EventHashes, 2), crypto.FromECDSAPub(&key.PublicKey), int64(i),
+				NewFlagTable(), NewFlagTable(), int64(i/3), false)
+			ev.Message.TopologicalIndex = int64(len(hashes))
+			hash := ev.Hash()
+			if err := store.SetEvent(ev); err != nil {
+				t.Fatal(err)
+			}
+			if i%2 == 0 {
+				if err := store.AddConsensusEvent(ev); err != nil {
+					t.Fatal(err)
+				}
+			}
+			hashes = append(hashes, hash)
+		}
+	}
+
+	for r := int64(0); r < 4; r++ {
+		round := NewRound()
+		for i, hash := range hashes[r*3 : r*3+3] {
+			round.AddEvent(hash, i == 0)
+			round.AddReceived(hash)
+			if err := store.AddClothoCheck(r, uint64(i), hash); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.AddTimeTable(r, hash, hashes[i], r*10+int64(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := store.SetRound(r, *round); err != nil {
+			t.Fatal(err)
+		}
+		event, _ := store.GetEventBlock(hashes[len(hashes)-1])
+		frame := Frame{Round: r, Events: []*EventMessage{event.Message}}
+		if err := store.SetFrame(frame); err != nil {
+			t.Fatal(err)
+		}
+		block := NewBlock(r, r, []byte("frame"), [][]byte{[]byte(fmt.Sprintf("block tx %d", r))})
+		if err := store.SetBlock(block); err != nil {
+			t.Fatal(err)
+		}
+		checkpoint := NewCheckpoint(r, []byte("frame"), []byte("state"))
+		checkpoint.Signatures["validator"] = "signature"
+		if err := store.SetCheckpoint(checkpoint); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.DropTimeTables(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetArchivedEvents(hashes[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetConsensusConfigHash(common.BytesToHash([]byte("params"))); err != nil {
+		t.Fatal(err)
+	}
+	pub := participants.ToPeerSlice()[0].Message.PubKeyHex
+	err := store.SetPeerReputations(map[string]PeerReputation{
+		pub: {Failures: 2, Penalties: 1, LastSeen: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, hashes
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// protoValue returns the protobuf encoding of a value which has one, for
+// comparisons not to depend on the internals of the generated types
+func protoValue(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Map {
+		res := make(map[interface{}]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			res[key.Interface()] = protoValue(v.MapIndex(key).Interface())
+		}
+		return res
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct {
+		res := make([]interface{}, v.Len())
+		for i := range res {
+			res[i] = protoValue(v.Index(i).Interface())
+		}
+		return res
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	m, ok := ptr.Interface().(interface{ ProtoMarshal() ([]byte, error) })
+	if !ok {
+		return value
+	}
+	data, err := m.ProtoMarshal()
+	if err != nil {
+		return err.Error()
+	}
+	return data
+}
+
+// inmemGetters returns the output of every getter of the store, by call
+func inmemGetters(s *InmemStore, hashes EventHashes) map[string]interface{} {
+	res := make(map[string]interface{})
+	call := func(name string, value interface{}, err error) {
+		if err != nil {
+			// the zero value
+			res[name] = errString(err)
+			return
+		}
+		res[name] = protoValue(value)
+	}
+
+	topological, err := s.TopologicalEvents()
+	call("TopologicalEvents", topological, err)
+	for _, hash := range hashes {
+		event, err := s.GetEventBlock(hash)
+		call("GetEventBlock "+hash.String(), event, err)
+	}
+
+	participants, _ := s.Participants()
+	for _, p := range participants.ToPeerSlice() {
+		pub := p.Message.PubKeyHex
+		for skip := int64(-1); skip <= iteratorIndexes; skip++ {
+			events, err := s.ParticipantEvents(pub, skip)
+			call(fmt.Sprintf("ParticipantEvents %s %d", pub, skip), events, err)
+			event, err := s.ParticipantEvent(pub, skip)
+			call(fmt.Sprintf("ParticipantEvent %s %d", pub, skip), event, err)
+			byCreator, err := s.EventsByCreator(pub, skip, skip+3)
+			call(fmt.Sprintf("EventsByCreator %s %d", pub, skip), byCreator, err)
+		}
+		last, isRoot, err := s.LastEventFrom(pub)
+		call("LastEventFrom "+pub, []interface{}{last, isRoot}, err)
+		last, isRoot, err = s.LastConsensusEventFrom(pub)
+		call("LastConsensusEventFrom "+pub, []interface{}{last, isRoot}, err)
+		root, err := s.GetRoot(pub)
+		call("GetRoot "+pub, root, err)
+		for frame := int64(0); frame < 4; frame++ {
+			check, err := s.GetClothoCreatorCheck(frame, p.ID)
+			call(fmt.Sprintf("GetClothoCreatorCheck %d %d", frame, p.ID), check, err)
+		}
+	}
+	call("RootsBySelfParent", s.RootsBySelfParent(), nil)
+	call("ConsensusEvents", s.ConsensusEvents(), nil)
+	call("ConsensusEventsCount", s.ConsensusEventsCount(), nil)
+	call("LastRound", s.LastRound(), nil)
+	call("LastBlockIndex", s.LastBlockIndex(), nil)
+	call("StateRoot", s.StateRoot(), nil)
+
+	for r := int64(-1); r < 6; r++ {
+		round, err := s.GetRound(r)
+		call(fmt.Sprintf("GetRound %d", r), round, err)
+		call(fmt.Sprintf("RoundClothos %d", r), s.RoundClothos(r), nil)
+		call(fmt.Sprintf("RoundEvents %d", r), s.RoundEvents(r), nil)
+		byRound, err := s.EventsByRoundRange(r, r+1)
+		call(fmt.Sprintf("EventsByRoundRange %d", r), byRound, err)
+		block, err := s.GetBlock(r)
+		call(fmt.Sprintf("GetBlock %d", r), block, err)
+		frame, err := s.GetFrame(r)
+		call(fmt.Sprintf("GetFrame %d", r), frame, err)
+		checkpoint, err := s.GetCheckpoint(r)
+		call(fmt.Sprintf("GetCheckpoint %d", r), checkpoint, err)
+		for _, hash := range hashes {
+			check, err := s.GetClothoCheck(r, hash)
+			call(fmt.Sprintf("GetClothoCheck %d %s", r, hash.String()), check, err)
+			table, err := s.GetTimeTable(r, hash)
+			call(fmt.Sprintf("GetTimeTable %d %s", r, hash.String()), table, err)
+		}
+	}
+
+	archived, err := s.ArchivedEvents()
+	call("ArchivedEvents", archived, err)
+	configHash, err := s.ConsensusConfigHash()
+	call("ConsensusConfigHash", configHash, err)
+	reps, err := s.PeerReputations()
+	lastSeen := make(map[string]int64)
+	for pub, rep := range reps {
+		// the monotonic clock reading does not survive a dump
+		lastSeen[pub] = rep.LastSeen.UnixNano()
+		rep.LastSeen, rep.BannedUntil = time.Time{}, time.Time{}
+		reps[pub] = rep
+	}
+	call("PeerReputations", []interface{}{reps, lastSeen}, err)
+	for _, hash := range hashes {
+		event, err := s.GetEventBlock(hash)
+		if err != nil {
+			continue
+		}
+		for _, tx := range event.Transactions() {
+			location, err := s.LookupTx(TxHash(tx))
+			call("LookupTx "+string(tx), location, err)
+		}
+	}
+	return res
+}
+
+func TestInmemDumpRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inmem-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "store.dump")
+
+	store, hashes := newDumpStore(t)
+	if err := store.DumpToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	participants, _ := store.Participants()
+	loaded, err := LoadOrCreateInmemStoreFromDump(path, participants, dumpCacheSize, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, got := inmemGetters(store, hashes), inmemGetters(loaded, hashes)
+	for call, want := range expected {
+		if !reflect.DeepEqual(want, got[call]) {
+			t.Fatalf("%s: expected %+v, got %+v", call, want, got[call])
+		}
+	}
+
+	// the reloaded store goes on where the dumped one stopped
+	if last := loaded.LastBlockIndex(); last != 3 {
+		t.Fatalf("expected the last block 3, got %d", last)
+	}
+	if known := loaded.participantEventsCache.Known(); !reflect.DeepEqual(known, store.participantEventsCache.Known()) {
+		t.Fatalf("expected the known events %v, got %v", store.participantEventsCache.Known(), known)
+	}
+}
+
+func TestInmemDumpRefused(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inmem-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "store.dump")
+
+	// no dump makes an empty store
+	participants, _ := iteratorParticipants()
+	store, err := LoadOrCreateInmemStoreFromDump(path, participants, dumpCacheSize, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.LastRound() != -1 {
+		t.Fatalf("expected an empty store, got the last round %d", store.LastRound())
+	}
+
+	dumped, _ := newDumpStore(t)
+	if err := dumped.DumpToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	dumpedParticipants, _ := dumped.Participants()
+	if _, err := LoadOrCreateInmemStoreFromDump(path, dumpedParticipants, 2*dumpCacheSize, nil); err == nil {
+		t.Fatal("expected a dump of another cache size refused")
+	}
+	if _, err := LoadOrCreateInmemStoreFromDump(path, participants, dumpCacheSize, nil); err == nil {
+		t.Fatal("expected a dump of other participants refused")
+	}
+}
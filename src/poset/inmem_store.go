@@ -2,7 +2,7 @@ package poset
 
 import (
 	"fmt"
-	"os"
+	"sort"
 	"strconv"
 	"sync"
 
@@ -20,33 +20,56 @@ type InmemStore struct {
 	cacheSize              int
 	participants           *peers.Peers
 	eventCache             *lru.Cache           // hash => Event
-	roundCreatedCache      *lru.Cache           // round number => RoundCreated
-	roundReceivedCache     *lru.Cache           // round received number => RoundReceived
+	roundCache             *lru.Cache           // round number => Round
 	blockCache             *lru.Cache           // index => Block
 	frameCache             *lru.Cache           // round received => Frame
+	frameEventsCache       *lru.Cache           // frame => EventHashes of its events
+	checkpoints            map[int64]Checkpoint // frame => Checkpoint, never evicted
 	clothoCheckCache       *lru.Cache           // frame + hash => hash
 	clothoCheckCreatorCache *lru.Cache          // frame + creator => hash
-	timeTableCache         *lru.Cache          // 
+	timeTables             map[int64]map[EventHash]FlagTable // frame => root hash => lamport time votes
+	timeTablesFrom         int64                             // time tables of earlier frames were dropped
 	consensusCache         *common.RollingIndex // consensus index => hash
 	totConsensusEvents     int64
 	participantEventsCache *ParticipantEventsCache // pubkey => Events
+	gaps                   *participantGaps        // pubkey => missing indexes
+	topologicalIndex       *common.RollingIndex    // insertion order => hash
 	rootsByParticipant     map[string]Root         // [participant] => Root
 	rootsBySelfParent      map[EventHash]Root      // [Root.SelfParent.Hash] => Root
 	lastRound              int64
 	lastConsensusEvents    map[string]EventHash // [participant] => hex() of last consensus event
 	lastBlock              int64
+	archivedEvents         EventHashes
+	consensusConfigHash    common.Hash
+	blockCarries           map[int64][][]byte
+	peerReputations        map[string]PeerReputation
+	peerAddresses          map[string]peers.AddrAnnouncement
+	journal                map[int64]Block // index => block emitted, not acknowledged
+	lastAcknowledged       int64
+	txIndex                *lru.Cache // tx hash => TxLocation, nil when not indexed
 
 	lastRoundLocker          sync.RWMutex
 	lastBlockLocker          sync.RWMutex
 	totConsensusEventsLocker sync.RWMutex
 	clothoCheckLocker        sync.RWMutex
 	timeTableLocker          sync.RWMutex
+	archivedEventsLocker     sync.RWMutex
+	consensusConfigLocker    sync.RWMutex
+	blockCarryLocker         sync.RWMutex
+	peerReputationsLocker    sync.RWMutex
+	peerAddressesLocker      sync.RWMutex
+	journalLocker            sync.RWMutex
+	checkpointsLocker        sync.RWMutex
+	topologicalIndexLocker   sync.Mutex
+	batchLocker              sync.Mutex
+	frameEventsLocker        sync.Mutex
 
 	states    state.Database
 	stateRoot common.Hash
 }
 
-// NewInmemStore constructor
+// NewInmemStore constructor. It panics when the caches cannot be made, for
+// a cacheSize below 1, or the genesis state cannot be written.
 func NewInmemStore(participants *peers.Peers, cacheSize int, posConf *pos.Config) *InmemStore {
 	rootsByParticipant := make(map[string]Root)
 
@@ -59,62 +82,54 @@ func NewInmemStore(participants *peers.Peers, cacheSize int, posConf *pos.Config
 
 	eventCache, err := lru.New(cacheSize)
 	if err != nil {
-		fmt.Println("Unable to init InmemStore.eventCache:", err)
-		os.Exit(31)
+		panic(fmt.Errorf("unable to init InmemStore.eventCache: %s", err))
 	}
-	roundCreatedCache, err := lru.New(cacheSize)
+	roundCache, err := lru.New(cacheSize)
 	if err != nil {
-		fmt.Println("Unable to init InmemStore.roundCreatedCache:", err)
-		os.Exit(32)
-	}
-	roundReceivedCache, err := lru.New(cacheSize)
-	if err != nil {
-		fmt.Println("Unable to init InmemStore.roundReceivedCache:", err)
-		os.Exit(35)
+		panic(fmt.Errorf("unable to init InmemStore.roundCache: %s", err))
 	}
 	blockCache, err := lru.New(cacheSize)
 	if err != nil {
-		fmt.Println("Unable to init InmemStore.blockCache:", err)
-		os.Exit(33)
+		panic(fmt.Errorf("unable to init InmemStore.blockCache: %s", err))
 	}
 	frameCache, err := lru.New(cacheSize)
 	if err != nil {
-		fmt.Println("Unable to init InmemStore.frameCache:", err)
-		os.Exit(34)
+		panic(fmt.Errorf("unable to init InmemStore.frameCache: %s", err))
 	}
-	clothoCheckCache, err := lru.New(cacheSize)
+	frameEventsCache, err := lru.New(cacheSize)
 	if err != nil {
-		fmt.Println("Unable to init InmemStore.checkClothoCache:", err)
-		os.Exit(35)
+		panic(fmt.Errorf("unable to init InmemStore.frameEventsCache: %s", err))
 	}
-	clothoCheckCreatorCache, err := lru.New(cacheSize)
+	clothoCheckCache, err := lru.New(cacheSize)
 	if err != nil {
-		fmt.Println("Unable to init InmemStore.checkClothoCreatorCache:", err)
-		os.Exit(36)
+		panic(fmt.Errorf("unable to init InmemStore.checkClothoCache: %s", err))
 	}
-	timeTableCache, err := lru.New(cacheSize)
+	clothoCheckCreatorCache, err := lru.New(cacheSize)
 	if err != nil {
-		fmt.Println("Unable to init InmemStore.timeTableCache:", err)
-		os.Exit(36)
+		panic(fmt.Errorf("unable to init InmemStore.checkClothoCreatorCache: %s", err))
 	}
-
 	store := &InmemStore{
 		cacheSize:              cacheSize,
 		participants:           participants,
 		eventCache:             eventCache,
-		roundCreatedCache:      roundCreatedCache,
-		roundReceivedCache:     roundReceivedCache,
+		roundCache:             roundCache,
 		blockCache:             blockCache,
 		frameCache:             frameCache,
+		frameEventsCache:       frameEventsCache,
+		checkpoints:            make(map[int64]Checkpoint),
 		clothoCheckCache:       clothoCheckCache,
 		clothoCheckCreatorCache:clothoCheckCreatorCache,
-		timeTableCache:         timeTableCache,
+		timeTables:             make(map[int64]map[EventHash]FlagTable),
 		consensusCache:         common.NewRollingIndex("ConsensusCache", cacheSize),
 		participantEventsCache: NewParticipantEventsCache(cacheSize, participants),
+		gaps:                   newParticipantGaps(),
+		topologicalIndex:       common.NewRollingIndex("TopologicalIndex", cacheSize),
 		rootsByParticipant:     rootsByParticipant,
 		lastRound:              -1,
 		lastBlock:              -1,
 		lastConsensusEvents:    map[string]EventHash{},
+		journal:                make(map[int64]Block),
+		lastAcknowledged:       -1,
 		states: state.NewDatabase(
 			kvdb.NewTable(
 				kvdb.NewMemDatabase(), statePrefix)),
@@ -133,8 +148,7 @@ func NewInmemStore(participants *peers.Peers, cacheSize int, posConf *pos.Config
 	// TODO: replace with real genesis
 	store.stateRoot, err = pos.FakeGenesis(participants, posConf, store.states)
 	if err != nil {
-		fmt.Println("Unable to init genesis state:", err)
-		os.Exit(36)
+		panic(fmt.Errorf("unable to init genesis state: %s", err))
 	}
 
 	return store
@@ -146,8 +160,63 @@ func NewInmemStore(participants *peers.Peers, cacheSize int, posConf *pos.Config
 
 // TopologicalEvents returns event in topological order.
 func (s *InmemStore) TopologicalEvents() ([]Event, error) {
-	// NOTE: it's used for bootstrap only, so is not implemented
-	return nil, nil
+	var res []Event
+	err := s.ForEachEvent(func(event Event) bool {
+		res = append(res, event)
+		return true
+	})
+	return res, err
+}
+
+// ForEachEvent calls fn on the events in topological order until it returns
+// false. Only the last cached events are iterated.
+func (s *InmemStore) ForEachEvent(fn func(Event) bool) error {
+	s.topologicalIndexLocker.Lock()
+	items, _ := s.topologicalIndex.GetLastWindow()
+	s.topologicalIndexLocker.Unlock()
+	for _, item := range items {
+		event, ok := s.eventCache.Get(item.(EventHash))
+		if !ok {
+			// evicted from the cache already
+			continue
+		}
+		if !fn(event.(Event)) {
+			break
+		}
+	}
+	return nil
+}
+
+// EventsByCreator returns the events of a creator with an index from
+// fromIndex to toIndex included
+func (s *InmemStore) EventsByCreator(creator string, fromIndex, toIndex int64) (EventHashes, error) {
+	if fromIndex < 0 {
+		fromIndex = 0
+	}
+	if toIndex < fromIndex {
+		return EventHashes{}, nil
+	}
+	res, err := s.participantEventsCache.Get(creator, fromIndex-1)
+	if err != nil {
+		return res, err
+	}
+	if fromIndex+int64(len(res))-1 > toIndex {
+		res = res[:toIndex-fromIndex+1]
+	}
+	return res, nil
+}
+
+// EventsByRoundRange returns the events created in a round from fromRound to
+// toRound included, in topological order
+func (s *InmemStore) EventsByRoundRange(fromRound, toRound int64) (EventHashes, error) {
+	res := EventHashes{}
+	err := s.ForEachEvent(func(event Event) bool {
+		if event.Frame >= fromRound && event.Frame <= toRound {
+			res = append(res, event.Hash())
+		}
+		return true
+	})
+	return res, err
 }
 
 // CacheSize size of cache
@@ -166,7 +235,10 @@ func (s *InmemStore) RootsBySelfParent() map[EventHash]Root {
 		s.rootsBySelfParent = make(map[EventHash]Root)
 		for _, root := range s.rootsByParticipant {
 			var hash EventHash
-			hash.Set(root.SelfParent.Hash)
+			if err := hash.Set(root.SelfParent.Hash); err != nil {
+				// no event has it as self-parent
+				continue
+			}
 			s.rootsBySelfParent[hash] = root
 		}
 	}
@@ -188,8 +260,12 @@ func (s *InmemStore) GetEventBlock(hash EventHash) (Event, error) {
 	return res.(Event), nil
 }
 
-// SetEvent set event for event block
+// SetEvent set event for event block, refusing an event claiming consensus
+// fields it cannot have yet
 func (s *InmemStore) SetEvent(event Event) error {
+	if err := checkConsensusFields(&event); err != nil {
+		return err
+	}
 	eventHash := event.Hash()
 	_, err := s.GetEventBlock(eventHash)
 	if err != nil && !common.Is(err, common.KeyNotFound) {
@@ -199,16 +275,68 @@ func (s *InmemStore) SetEvent(event Event) error {
 		if err := s.addParticipantEvent(event.GetCreator(), eventHash, event.Index()); err != nil {
 			return err
 		}
+		s.topologicalIndexLocker.Lock()
+		_, last := s.topologicalIndex.GetLastWindow()
+		err := s.topologicalIndex.Set(eventHash, last+1)
+		s.topologicalIndexLocker.Unlock()
+		if err != nil {
+			return err
+		}
+		s.addFrameEvent(event.Frame, eventHash)
 	}
 
 	// fmt.Println("Adding event to cache", event.Hex())
 	s.eventCache.Add(eventHash, event)
+	s.indexEventTxs(&event)
 
 	return nil
 }
 
+// CommitBatch applies the writes of a batch under one lock, so that no other
+// batch is interleaved with them
+func (s *InmemStore) CommitBatch(batch *StoreBatch) error {
+	s.batchLocker.Lock()
+	defer s.batchLocker.Unlock()
+	return applyBatchOps(s, batch.ops)
+}
+
+// addParticipantEvent indexes an event of a participant. An index skipping
+// some is refused and the skipped ones are recorded as gaps, which setting
+// them fills.
 func (s *InmemStore) addParticipantEvent(participant string, hash EventHash, index int64) error {
-	return s.participantEventsCache.Set(participant, hash, index)
+	err := s.participantEventsCache.Set(participant, hash, index)
+	if common.Is(err, common.SkippedIndex) {
+		if last, lerr := s.participantEventsCache.LastIndex(participant); lerr == nil {
+			s.gaps.add(participant, last+1, index-1)
+		}
+		return err
+	}
+	if err == nil {
+		s.gaps.fill(participant, index)
+	}
+	return err
+}
+
+// addFrameEvent indexes an event by its frame, see ProcessOutFrame
+func (s *InmemStore) addFrameEvent(frame int64, hash EventHash) {
+	s.frameEventsLocker.Lock()
+	defer s.frameEventsLocker.Unlock()
+	var hashes EventHashes
+	if res, ok := s.frameEventsCache.Get(frame); ok {
+		hashes = res.(EventHashes)
+	}
+	s.frameEventsCache.Add(frame, append(hashes, hash))
+}
+
+// frameEvents returns the hashes of the events of a frame
+func (s *InmemStore) frameEvents(frame int64) EventHashes {
+	s.frameEventsLocker.Lock()
+	defer s.frameEventsLocker.Unlock()
+	res, ok := s.frameEventsCache.Get(frame)
+	if !ok {
+		return nil
+	}
+	return res.(EventHashes)
 }
 
 // ParticipantEvents events for the participant
@@ -230,8 +358,7 @@ func (s *InmemStore) ParticipantEvent(participant string, index int64) (hash Eve
 	}
 
 	if root.SelfParent.Index == index {
-		hash.Set(root.SelfParent.Hash)
-		err = nil
+		err = hash.Set(root.SelfParent.Hash)
 	}
 	return
 }
@@ -245,9 +372,8 @@ func (s *InmemStore) LastEventFrom(participant string) (last EventHash, isRoot b
 	}
 	// if there is none, grab the root
 	if root, ok := s.rootsByParticipant[participant]; ok {
-		last.Set(root.SelfParent.Hash)
+		err = last.Set(root.SelfParent.Hash)
 		isRoot = true
-		err = nil
 	} else {
 		err = common.NewStoreErr("InmemStore.Roots", common.NoRoot, participant)
 	}
@@ -264,7 +390,7 @@ func (s *InmemStore) LastConsensusEventFrom(participant string) (last EventHash,
 	// if there is none, grab the root
 	root, ok := s.rootsByParticipant[participant]
 	if ok {
-		last.Set(root.SelfParent.Hash)
+		err = last.Set(root.SelfParent.Hash)
 		isRoot = true
 	} else {
 		err = common.NewStoreErr("InmemStore.Roots", common.NoRoot, participant)
@@ -303,44 +429,52 @@ func (s *InmemStore) AddConsensusEvent(event Event) error {
 	return nil
 }
 
-// GetRoundCreated retrieves created round by ID
-func (s *InmemStore) GetRoundCreated(r int64) (RoundCreated, error) {
-	res, ok := s.roundCreatedCache.Get(r)
+// GetRound retrieves round by ID
+func (s *InmemStore) GetRound(r int64) (Round, error) {
+	res, ok := s.roundCache.Get(r)
 	if !ok {
-		return *NewRoundCreated(), common.NewStoreErr("RoundCreatedCache", common.KeyNotFound, strconv.FormatInt(r, 10))
+		return *NewRound(), common.NewStoreErr("RoundCache", common.KeyNotFound, strconv.FormatInt(r, 10))
 	}
-	return res.(RoundCreated), nil
+	return res.(Round), nil
 }
 
-// SetRoundCreated stores created round by ID
-func (s *InmemStore) SetRoundCreated(r int64, round RoundCreated) error {
+// SetRound stores round by ID
+func (s *InmemStore) SetRound(r int64, round Round) error {
 	s.lastRoundLocker.Lock()
 	defer s.lastRoundLocker.Unlock()
-	s.roundCreatedCache.Add(r, round)
+	s.roundCache.Add(r, round)
 	if r > s.lastRound {
 		s.lastRound = r
 	}
 	return nil
 }
 
+// GetRoundCreated retrieves created round by ID
+//
+// Deprecated: use GetRound
+func (s *InmemStore) GetRoundCreated(r int64) (RoundCreated, error) {
+	return getRoundCreated(s, r)
+}
+
+// SetRoundCreated stores created round by ID
+//
+// Deprecated: use SetRound
+func (s *InmemStore) SetRoundCreated(r int64, round RoundCreated) error {
+	return setRoundCreated(s, r, round)
+}
+
 // GetRoundReceived gets received round by ID
+//
+// Deprecated: use GetRound
 func (s *InmemStore) GetRoundReceived(r int64) (RoundReceived, error) {
-	res, ok := s.roundReceivedCache.Get(r)
-	if !ok {
-		return *NewRoundReceived(), common.NewStoreErr("RoundReceivedCache", common.KeyNotFound, strconv.FormatInt(r, 10))
-	}
-	return res.(RoundReceived), nil
+	return getRoundReceived(s, r)
 }
 
 // SetRoundReceived stores received round by ID
+//
+// Deprecated: use SetRound
 func (s *InmemStore) SetRoundReceived(r int64, round RoundReceived) error {
-	s.lastRoundLocker.Lock()
-	defer s.lastRoundLocker.Unlock()
-	s.roundReceivedCache.Add(r, round)
-	if r > s.lastRound {
-		s.lastRound = r
-	}
-	return nil
+	return setRoundReceived(s, r, round)
 }
 
 // LastRound getter
@@ -352,7 +486,7 @@ func (s *InmemStore) LastRound() int64 {
 
 // RoundClothos all clothos for the specified round
 func (s *InmemStore) RoundClothos(r int64) EventHashes {
-	round, err := s.GetRoundCreated(r)
+	round, err := s.GetRound(r)
 	if err != nil {
 		return EventHashes{}
 	}
@@ -361,7 +495,7 @@ func (s *InmemStore) RoundClothos(r int64) EventHashes {
 
 // RoundEvents returns events for the round
 func (s *InmemStore) RoundEvents(r int64) int {
-	round, err := s.GetRoundCreated(r)
+	round, err := s.GetRound(r)
 	if err != nil {
 		return 0
 	}
@@ -396,6 +530,7 @@ func (s *InmemStore) SetBlock(block Block) error {
 		return err
 	}
 	s.blockCache.Add(index, block)
+	s.indexBlockTxs(&block)
 	if index > s.lastBlock {
 		s.lastBlock = index
 	}
@@ -409,6 +544,17 @@ func (s *InmemStore) LastBlockIndex() int64 {
 	return s.lastBlock
 }
 
+// setLast sets the last round and block, as read from a database, without
+// caching them
+func (s *InmemStore) setLast(round, block int64) {
+	s.lastRoundLocker.Lock()
+	s.lastRound = round
+	s.lastRoundLocker.Unlock()
+	s.lastBlockLocker.Lock()
+	s.lastBlock = block
+	s.lastBlockLocker.Unlock()
+}
+
 // GetFrame by index
 func (s *InmemStore) GetFrame(index int64) (Frame, error) {
 	res, ok := s.frameCache.Get(index)
@@ -429,37 +575,197 @@ func (s *InmemStore) SetFrame(frame Frame) error {
 	return nil
 }
 
+// GetCheckpoint by frame
+func (s *InmemStore) GetCheckpoint(frame int64) (Checkpoint, error) {
+	s.checkpointsLocker.RLock()
+	defer s.checkpointsLocker.RUnlock()
+	res, ok := s.checkpoints[frame]
+	if !ok {
+		return Checkpoint{}, common.NewStoreErr("Checkpoints", common.KeyNotFound, strconv.FormatInt(frame, 10))
+	}
+	return res, nil
+}
+
+// SetCheckpoint in the store
+func (s *InmemStore) SetCheckpoint(checkpoint Checkpoint) error {
+	s.checkpointsLocker.Lock()
+	defer s.checkpointsLocker.Unlock()
+	s.checkpoints[checkpoint.Frame] = checkpoint
+	return nil
+}
+
+// ArchivedEvents returns the undetermined events archived by the poset
+func (s *InmemStore) ArchivedEvents() (EventHashes, error) {
+	s.archivedEventsLocker.RLock()
+	defer s.archivedEventsLocker.RUnlock()
+	return s.archivedEvents, nil
+}
+
+// SetArchivedEvents replaces the undetermined events archived by the poset
+func (s *InmemStore) SetArchivedEvents(hashes EventHashes) error {
+	s.archivedEventsLocker.Lock()
+	defer s.archivedEventsLocker.Unlock()
+	s.archivedEvents = hashes
+	return nil
+}
+
+// ConsensusConfigHash returns the hash of the consensus configuration the
+// events were accepted with, the zero hash if none was set
+func (s *InmemStore) ConsensusConfigHash() (common.Hash, error) {
+	s.consensusConfigLocker.RLock()
+	defer s.consensusConfigLocker.RUnlock()
+	return s.consensusConfigHash, nil
+}
+
+// SetConsensusConfigHash sets the hash of the consensus configuration
+func (s *InmemStore) SetConsensusConfigHash(hash common.Hash) error {
+	s.consensusConfigLocker.Lock()
+	defer s.consensusConfigLocker.Unlock()
+	s.consensusConfigHash = hash
+	return nil
+}
+
+// BlockCarry returns the transactions carried over past the block of a
+// frame into the next block
+func (s *InmemStore) BlockCarry(frame int64) ([][]byte, error) {
+	s.blockCarryLocker.RLock()
+	defer s.blockCarryLocker.RUnlock()
+	return s.blockCarries[frame], nil
+}
+
+// SetBlockCarry sets the transactions carried over past the block of a
+// frame into the next block. Only those of frames with a carry are kept.
+func (s *InmemStore) SetBlockCarry(frame int64, txs [][]byte) error {
+	s.blockCarryLocker.Lock()
+	defer s.blockCarryLocker.Unlock()
+	if len(txs) == 0 {
+		delete(s.blockCarries, frame)
+		return nil
+	}
+	if s.blockCarries == nil {
+		s.blockCarries = make(map[int64][][]byte)
+	}
+	s.blockCarries[frame] = txs
+	return nil
+}
+
+// PeerReputations returns what the node learnt about its peers
+func (s *InmemStore) PeerReputations() (map[string]PeerReputation, error) {
+	s.peerReputationsLocker.RLock()
+	defer s.peerReputationsLocker.RUnlock()
+	res := make(map[string]PeerReputation, len(s.peerReputations))
+	for pubKey, rep := range s.peerReputations {
+		res[pubKey] = rep
+	}
+	return res, nil
+}
+
+// SetPeerReputations adds or updates the reputation of peers
+func (s *InmemStore) SetPeerReputations(reps map[string]PeerReputation) error {
+	s.peerReputationsLocker.Lock()
+	defer s.peerReputationsLocker.Unlock()
+	if s.peerReputations == nil {
+		s.peerReputations = make(map[string]PeerReputation, len(reps))
+	}
+	for pubKey, rep := range reps {
+		s.peerReputations[pubKey] = rep
+	}
+	return nil
+}
+
+// PeerAddresses returns the latest signed network addresses of the
+// participants
+func (s *InmemStore) PeerAddresses() (map[string]peers.AddrAnnouncement, error) {
+	s.peerAddressesLocker.RLock()
+	defer s.peerAddressesLocker.RUnlock()
+	res := make(map[string]peers.AddrAnnouncement, len(s.peerAddresses))
+	for pubKey, addr := range s.peerAddresses {
+		res[pubKey] = addr
+	}
+	return res, nil
+}
+
+// SetPeerAddresses adds or updates the network addresses of participants
+func (s *InmemStore) SetPeerAddresses(addrs map[string]peers.AddrAnnouncement) error {
+	s.peerAddressesLocker.Lock()
+	defer s.peerAddressesLocker.Unlock()
+	if s.peerAddresses == nil {
+		s.peerAddresses = make(map[string]peers.AddrAnnouncement, len(addrs))
+	}
+	for pubKey, addr := range addrs {
+		s.peerAddresses[pubKey] = addr
+	}
+	return nil
+}
+
+// JournalBlock records a block emitted to the app, until it is acknowledged
+func (s *InmemStore) JournalBlock(block Block) error {
+	s.journalLocker.Lock()
+	defer s.journalLocker.Unlock()
+	s.journal[block.Index()] = block
+	return nil
+}
+
+// AcknowledgeBlock records that the app acknowledged the blocks up to index,
+// which leave the journal
+func (s *InmemStore) AcknowledgeBlock(index int64) error {
+	s.journalLocker.Lock()
+	defer s.journalLocker.Unlock()
+	for i := range s.journal {
+		if i <= index {
+			delete(s.journal, i)
+		}
+	}
+	if index > s.lastAcknowledged {
+		s.lastAcknowledged = index
+	}
+	return nil
+}
+
+// JournalledBlocks returns the blocks emitted to the app and not
+// acknowledged, in index order
+func (s *InmemStore) JournalledBlocks() ([]Block, error) {
+	s.journalLocker.RLock()
+	defer s.journalLocker.RUnlock()
+	res := make([]Block, 0, len(s.journal))
+	for _, block := range s.journal {
+		res = append(res, block)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Index() < res[j].Index()
+	})
+	return res, nil
+}
+
+// LastAcknowledgedBlock returns the index of the last block the app
+// acknowledged, -1 for none
+func (s *InmemStore) LastAcknowledgedBlock() (int64, error) {
+	s.journalLocker.RLock()
+	defer s.journalLocker.RUnlock()
+	return s.lastAcknowledged, nil
+}
+
 // Reset resets the store
 func (s *InmemStore) Reset(roots map[string]Root) error {
 	eventCache, errr := lru.New(s.cacheSize)
 	if errr != nil {
-		fmt.Println("Unable to reset InmemStore.eventCache:", errr)
-		os.Exit(41)
+		return fmt.Errorf("unable to reset InmemStore.eventCache: %s", errr)
 	}
 	roundCache, errr := lru.New(s.cacheSize)
 	if errr != nil {
-		fmt.Println("Unable to reset InmemStore.roundCreatedCache:", errr)
-		os.Exit(42)
-	}
-	roundReceivedCache, errr := lru.New(s.cacheSize)
-	if errr != nil {
-		fmt.Println("Unable to reset InmemStore.roundReceivedCache:", errr)
-		os.Exit(45)
+		return fmt.Errorf("unable to reset InmemStore.roundCache: %s", errr)
 	}
 	clothoCheckCache, errr := lru.New(s.cacheSize)
 	if errr != nil {
-		fmt.Println("Unable to reset InmemStore.clothoCheckCache:", errr)
-		os.Exit(46)
+		return fmt.Errorf("unable to reset InmemStore.clothoCheckCache: %s", errr)
 	}
 	clothoCheckCreatorCache, errr := lru.New(s.cacheSize)
 	if errr != nil {
-		fmt.Println("Unable to reset InmemStore.clothoCheckCreatorCache:", errr)
-		os.Exit(47)
+		return fmt.Errorf("unable to reset InmemStore.clothoCheckCreatorCache: %s", errr)
 	}
-	timeTableCache, errr := lru.New(s.cacheSize)
+	frameEventsCache, errr := lru.New(s.cacheSize)
 	if errr != nil {
-		fmt.Println("Unable to reset InmemStore.timeTableCache:", errr)
-		os.Exit(48)
+		return fmt.Errorf("unable to reset InmemStore.frameEventsCache: %s", errr)
 	}
 	// FIXIT: Should we recreate blockCache, frameCache and participantEventsCache here as well
 	//        and reset lastConsensusEvents ?
@@ -467,13 +773,22 @@ func (s *InmemStore) Reset(roots map[string]Root) error {
 	s.rootsBySelfParent = nil
 	_ = s.RootsBySelfParent()
 	s.eventCache = eventCache
-	s.roundCreatedCache = roundCache
-	s.roundReceivedCache = roundReceivedCache
+	s.roundCache = roundCache
 	s.clothoCheckCache = clothoCheckCache
 	s.clothoCheckCreatorCache = clothoCheckCreatorCache
-	s.timeTableCache = timeTableCache
+	s.timeTableLocker.Lock()
+	s.timeTables = make(map[int64]map[EventHash]FlagTable)
+	s.timeTablesFrom = 0
+	s.timeTableLocker.Unlock()
+	s.frameEventsLocker.Lock()
+	s.frameEventsCache = frameEventsCache
+	s.frameEventsLocker.Unlock()
 	s.consensusCache = common.NewRollingIndex("ConsensusCache", s.cacheSize)
+	s.topologicalIndexLocker.Lock()
+	s.topologicalIndex = common.NewRollingIndex("TopologicalIndex", s.cacheSize)
+	s.topologicalIndexLocker.Unlock()
 	err := s.participantEventsCache.Reset()
+	s.gaps.reset()
 	s.lastRoundLocker.Lock()
 	s.lastRound = -1
 	s.lastRoundLocker.Unlock()
@@ -537,7 +852,9 @@ func (s *InmemStore) GetClothoCheck(frame int64, phash EventHash) (hash EventHas
 	if !ok {
 		return EventHash{}, common.NewStoreErr("ClothoCheckCache", common.KeyNotFound, string(key))
 	}
-	hash.Set(res.([]byte))
+	if err := hash.Set(res.([]byte)); err != nil {
+		return EventHash{}, err
+	}
 	return hash, nil
 }
 
@@ -550,50 +867,111 @@ func (s *InmemStore) GetClothoCreatorCheck(frame int64, creatorID uint64) (hash
 	if !ok {
 		return EventHash{}, common.NewStoreErr("ClothoCheckCreatorCache", common.KeyNotFound, string(key))
 	}
-	hash.Set(res.([]byte))
+	if err := hash.Set(res.([]byte)); err != nil {
+		return EventHash{}, err
+	}
 	return hash, nil
 }
 
-func timeTableKeyStr(hash EventHash) string {
-	return fmt.Sprintf("timeTable_%s", hash.String())
+func timeTableKeyStr(frame int64, hash EventHash) string {
+	return fmt.Sprintf("timeTable_%d_%s", frame, hash.String())
+}
+
+// timeTable returns the time table of a root, creating it if asked to.
+// Time tables are never evicted, a frame that was dropped is an error.
+func (s *InmemStore) timeTable(frame int64, hash EventHash, create bool) (FlagTable, error) {
+	if frame < s.timeTablesFrom {
+		return nil, common.NewStoreErr("TimeTable", common.TooLate, timeTableKeyStr(frame, hash))
+	}
+	tables, ok := s.timeTables[frame]
+	if !ok {
+		if !create {
+			return nil, common.NewStoreErr("TimeTable", common.KeyNotFound, timeTableKeyStr(frame, hash))
+		}
+		tables = make(map[EventHash]FlagTable)
+		s.timeTables[frame] = tables
+	}
+	ft, ok := tables[hash]
+	if !ok {
+		if !create {
+			return nil, common.NewStoreErr("TimeTable", common.KeyNotFound, timeTableKeyStr(frame, hash))
+		}
+		ft = NewFlagTable()
+		tables[hash] = ft
+	}
+	return ft, nil
+}
+
+// NewTimeTable creates the empty time table of a root
+func (s *InmemStore) NewTimeTable(frame int64, hash EventHash) error {
+	s.timeTableLocker.Lock()
+	defer s.timeTableLocker.Unlock()
+	_, err := s.timeTable(frame, hash, true)
+	return err
 }
 
 // AddTimeTable adds lamport timestamp for pair of events for voting in atropos time selection
-func (s *InmemStore) AddTimeTable(hashTo EventHash, hashFrom EventHash, lamportTime int64) error {
-	ft := NewFlagTable()
-	key := timeTableKeyStr(hashTo)
+func (s *InmemStore) AddTimeTable(frame int64, hashTo EventHash, hashFrom EventHash, lamportTime int64) error {
 	s.timeTableLocker.Lock()
 	defer s.timeTableLocker.Unlock()
-	res, ok := s.timeTableCache.Get(key)
-	if ok {
-		ft.Unmarshal(res.([]byte))
+	ft, err := s.timeTable(frame, hashTo, true)
+	if err != nil {
+		return err
 	}
 	ft[hashFrom] = lamportTime
-	res = ft.Marshal()
-	s.timeTableCache.Add(key, res)
 	return nil
 }
 
 // GetTimeTable retrieve FlagTable with lamport time votes in atropos time selection for specified EventHash
-func (s *InmemStore) GetTimeTable(hash EventHash) (FlagTable, error) {
-	ft := NewFlagTable()
-	key := timeTableKeyStr(hash)
+func (s *InmemStore) GetTimeTable(frame int64, hash EventHash) (FlagTable, error) {
 	s.timeTableLocker.RLock()
 	defer s.timeTableLocker.RUnlock()
-	res, ok := s.timeTableCache.Get(key)
-	if !ok {
-		return nil, common.NewStoreErr("GetTimeTableCache", common.KeyNotFound, string(key))
+	ft, err := s.timeTable(frame, hash, false)
+	if err != nil {
+		return nil, err
 	}
-	ft.Unmarshal(res.([]byte))
-	return ft, nil
+	return ft.Copy(), nil
+}
+
+// DropTimeTables removes the time tables of the frames before beforeFrame
+func (s *InmemStore) DropTimeTables(beforeFrame int64) error {
+	s.timeTableLocker.Lock()
+	defer s.timeTableLocker.Unlock()
+	for frame := range s.timeTables {
+		if frame < beforeFrame {
+			delete(s.timeTables, frame)
+		}
+	}
+	if beforeFrame > s.timeTablesFrom {
+		s.timeTablesFrom = beforeFrame
+	}
+	return nil
 }
 
-// This is just a stub, yet to bee implemented if needed
+// CheckFrameFinality keeps no index of the events not received yet: the
+// frames the Poset asks about, those before a decided Atropos, are final
 func (s *InmemStore) CheckFrameFinality(frame int64) bool {
 	return true
 }
 
-// This is just a stub, yet to bee implemented if needed
-func (s *InmemStore) ProcessOutFrame(frame int64, address string) ([][]byte, error) {
-	return nil, nil
+// ProcessOutFrame returns the transactions of the loaded events of a final
+// frame, in the order of ByFinalOrder as the badger store does
+func (s *InmemStore) ProcessOutFrame(frame int64) ([][]byte, error) {
+	var events []Event
+	for _, hash := range s.frameEvents(frame) {
+		event, err := s.GetEventBlock(hash)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	sort.Sort(ByFinalOrder(events))
+
+	var bodies []*EventBody
+	for _, ev := range events {
+		if ev.IsLoaded() {
+			bodies = append(bodies, ev.Message.Body)
+		}
+	}
+	return orderedTransactions(bodies), nil
 }
@@ -4,8 +4,10 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/SamuelMarks/dag1/src/common"
 	"github.com/SamuelMarks/dag1/src/crypto"
 	"github.com/SamuelMarks/dag1/src/peers"
 )
@@ -26,7 +28,7 @@ func initInmemStore(cacheSize int) (*InmemStore, []pub) {
 		pubKey := crypto.FromECDSAPub(&key.PublicKey)
 		peer := peers.NewPeer(fmt.Sprintf("0x%X", pubKey), "")
 		participantPubs = append(participantPubs,
-			pub{i, key, pubKey, peer.PubKeyHex})
+			pub{i, key, pubKey, peer.Message.PubKeyHex})
 		participants.AddPeer(peer)
 		participantPubs[len(participantPubs)-1].id = peer.ID
 	}
@@ -51,7 +53,7 @@ func TestInmemEvents(t *testing.T) {
 					[]BlockSignature{{Validator: []byte("validator"), Index: 0, Signature: "r|s"}},
 					make(EventHashes, 2),
 					p.pubKey,
-					k, nil)
+					k, nil, nil, 0, false)
 				_ = event.Hash() // just to set private variables
 				items = append(items, event)
 				err := store.SetEvent(event)
@@ -120,7 +122,7 @@ func TestInmemRounds(t *testing.T) {
 			[]BlockSignature{},
 			make(EventHashes, 2),
 			p.pubKey,
-			0, nil)
+			0, nil, nil, 0, false)
 		events[p.hex] = event
 		round.AddEvent(event.Hash(), true)
 	}
@@ -229,3 +231,161 @@ func TestInmemBlocks(t *testing.T) {
 		}
 	})
 }
+
+func TestInmemCheckpoints(t *testing.T) {
+	store, participants := initInmemStore(10)
+
+	checkpoint := NewCheckpoint(4, []byte("framehash"), []byte("statehash"))
+	sig, err := checkpoint.Sign(participants[0].privKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoint.SetSignature(sig)
+
+	if err := store.SetCheckpoint(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := store.GetCheckpoint(checkpoint.Frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stored, checkpoint) {
+		t.Fatalf("Checkpoint and stored Checkpoint do not match")
+	}
+
+	if _, err := store.GetCheckpoint(checkpoint.Frame + 1); !common.Is(err, common.KeyNotFound) {
+		t.Fatalf("expected a missing checkpoint to be KeyNotFound, got %v", err)
+	}
+}
+
+func TestInmemTimeTables(t *testing.T) {
+	// a cache far smaller than the number of time tables
+	store, _ := initInmemStore(2)
+	frames := int64(50)
+	rootsPerFrame := 3
+
+	root := func(frame int64, i int) EventHash {
+		return CalcEventHash([]byte(fmt.Sprintf("root %d %d", frame, i)))
+	}
+	for frame := int64(0); frame < frames; frame++ {
+		for i := 0; i < rootsPerFrame; i++ {
+			if err := store.NewTimeTable(frame, root(frame, i)); err != nil {
+				t.Fatal(err)
+			}
+			if frame == 0 {
+				continue
+			}
+			// vote for the roots of the previous frame
+			for j := 0; j < rootsPerFrame; j++ {
+				if err := store.AddTimeTable(frame, root(frame, i),
+					root(frame-1, j), frame*10+int64(j)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	checkVotes := func(frame int64) {
+		for i := 0; i < rootsPerFrame; i++ {
+			ft, err := store.GetTimeTable(frame, root(frame, i))
+			if err != nil {
+				t.Fatalf("frame %d root %d: %v", frame, i, err)
+			}
+			expected := 0
+			if frame > 0 {
+				expected = rootsPerFrame
+			}
+			if len(ft) != expected {
+				t.Fatalf("frame %d root %d: expected %d votes, got %d",
+					frame, i, expected, len(ft))
+			}
+			for j := 0; j < len(ft); j++ {
+				if ft[root(frame-1, j)] != frame*10+int64(j) {
+					t.Fatalf("frame %d root %d: wrong vote for %d", frame, i, j)
+				}
+			}
+		}
+	}
+	for frame := int64(0); frame < frames; frame++ {
+		checkVotes(frame)
+	}
+
+	if _, err := store.GetTimeTable(frames-1, root(frames, 0)); !common.Is(err, common.KeyNotFound) {
+		t.Fatalf("expected a missing time table to be an error, got %v", err)
+	}
+
+	final := int64(30)
+	if err := store.DropTimeTables(final); err != nil {
+		t.Fatal(err)
+	}
+	for frame := int64(0); frame < final; frame++ {
+		if _, err := store.GetTimeTable(frame, root(frame, 0)); !common.Is(err, common.TooLate) {
+			t.Fatalf("frame %d: expected a dropped time table, got %v", frame, err)
+		}
+	}
+	if err := store.AddTimeTable(final-1, root(final-1, 0), root(final-2, 0), 1); !common.Is(err, common.TooLate) {
+		t.Fatalf("expected adding to a dropped frame to fail, got %v", err)
+	}
+	for frame := final; frame < frames; frame++ {
+		checkVotes(frame)
+	}
+	if len(store.timeTables) != int(frames-final) {
+		t.Fatalf("expected %d frames of time tables, got %d",
+			frames-final, len(store.timeTables))
+	}
+}
+
+func TestInmemLeafEventTimeTables(t *testing.T) {
+	f := newFixture(t, fixtureConfig{Participants: 4, Events: 1, Seed: 1})
+	p := f.emptyPoset(t)
+	store := p.Store.(*InmemStore)
+	participants := p.Participants
+	if err := p.SetLeafEvents(participants.ToPeerSlice()); err != nil {
+		t.Fatal(err)
+	}
+	// the leaf events vote for no Clotho, their time tables are empty
+	if l := len(store.timeTables[0]); l != participants.Len() {
+		t.Fatalf("expected %d time tables in frame 0, got %d", participants.Len(), l)
+	}
+	for hash, ft := range store.timeTables[0] {
+		if len(ft) != 0 {
+			t.Fatalf("expected the time table of leaf event %s empty, got %v", hash.String(), ft)
+		}
+	}
+}
+
+func TestInmemTimeTableMissFails(t *testing.T) {
+	f := newFixture(t, fixtureConfig{Participants: 4, Events: 200, Seed: 1})
+	p := f.emptyPoset(t)
+	store := p.Store.(*InmemStore)
+
+	var dropped *Event
+	for i, ev := range f.copyEvents() {
+		err := p.InsertEvent(ev, false)
+		if dropped != nil && err != nil {
+			if !strings.Contains(err.Error(), "GetTimeTable") {
+				t.Fatalf("expected the missing time table in the error, got %v", err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("inserting event %d: %v", i, err)
+		}
+		stored, err := store.GetEventBlock(ev.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dropped == nil && stored.Root && stored.Frame > 0 {
+			// evicted before the frame is final, the votes of the root
+			// would silently disappear
+			delete(store.timeTables[stored.Frame], stored.Hash())
+			dropped = &stored
+		}
+	}
+	if dropped == nil {
+		t.Fatal("expected a root above frame 0")
+	}
+	hash := dropped.Hash()
+	t.Fatalf("expected the missing time table of root %s of frame %d to fail an insertion",
+		hash.String(), dropped.Frame)
+}
@@ -0,0 +1,62 @@
+package poset
+
+import (
+	"fmt"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+// NetworkID derives the ID of a network from its genesis state root and the
+// name its operators chose for it. Networks started from copies of the same
+// peers.json get different IDs from different names.
+func NetworkID(genesis common.Hash, name string) common.Hash {
+	return crypto.Keccak256Hash(genesis.Bytes(), []byte(name))
+}
+
+// NetworkIDError is returned by InsertEvent for an event made for another
+// network, or for no network when events without one are refused
+type NetworkIDError struct {
+	Expected common.Hash
+	Got      common.Hash
+}
+
+func (e *NetworkIDError) Error() string {
+	if e.Got == (common.Hash{}) {
+		return fmt.Sprintf("event without a network ID, expected %s", e.Expected.Hex())
+	}
+	return fmt.Sprintf("event for the network %s, expected %s", e.Got.Hex(), e.Expected.Hex())
+}
+
+// IsNetworkID returns true for a NetworkIDError
+func IsNetworkID(err error) bool {
+	_, ok := err.(*NetworkIDError)
+	return ok
+}
+
+// SetNetworkID sets the network the events are made for. Events made for
+// another one are refused, and so are those without a network ID, from
+// old clients, unless acceptLegacy is set while the network migrates. The
+// zero ID checks nothing.
+func (p *Poset) SetNetworkID(id common.Hash, acceptLegacy bool) {
+	p.networkID = id
+	p.acceptLegacyNetwork = acceptLegacy
+}
+
+// GetNetworkID returns the network the events are made for, the zero hash
+// when not set
+func (p *Poset) GetNetworkID() common.Hash {
+	return p.networkID
+}
+
+// checkNetworkID refuses an event made for another network than ours
+func (p *Poset) checkNetworkID(event Event) error {
+	if p.networkID == (common.Hash{}) {
+		return nil
+	}
+	id := event.NetworkID()
+	if id == p.networkID || id == (common.Hash{}) && p.acceptLegacyNetwork {
+		return nil
+	}
+	return &NetworkIDError{Expected: p.networkID, Got: id}
+}
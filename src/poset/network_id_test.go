@@ -0,0 +1,91 @@
+package poset
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// networkEvent returns a root event of key, signed for the network id
+func networkEvent(participants *peers.Peers, key *ecdsa.PrivateKey, id common.Hash) Event {
+	ev := capEvent(participants, key, nil, EventHash{}, "tx")
+	if id != (common.Hash{}) {
+		ev.SetNetworkID(id)
+		if err := ev.Sign(key); err != nil {
+			panic(err)
+		}
+	}
+	return ev
+}
+
+func TestNetworkIDDerivation(t *testing.T) {
+	genesis := common.BytesToHash([]byte("genesis"))
+	if NetworkID(genesis, "main") != NetworkID(genesis, "main") {
+		t.Fatal("expected the same ID from the same genesis and name")
+	}
+	if NetworkID(genesis, "main") == NetworkID(genesis, "test") {
+		t.Fatal("expected another ID from another name")
+	}
+	if NetworkID(genesis, "main") == NetworkID(common.BytesToHash([]byte("other")), "main") {
+		t.Fatal("expected another ID from another genesis")
+	}
+}
+
+func TestNetworkIDRejectsOtherNetworks(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	genesis := common.BytesToHash([]byte("genesis"))
+	main, test := NetworkID(genesis, "main"), NetworkID(genesis, "test")
+
+	newPoset := func(id common.Hash, compat bool) *Poset {
+		p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.SetNetworkID(id, compat)
+		return p
+	}
+
+	// each network refuses the events of the other one
+	for _, c := range []struct{ ours, theirs common.Hash }{{main, test}, {test, main}} {
+		p := newPoset(c.ours, false)
+		err := p.InsertEvent(networkEvent(participants, keys[0], c.theirs), false)
+		if !IsNetworkID(err) {
+			t.Fatalf("expected an event of %s refused, got %v", c.theirs.Hex(), err)
+		}
+		if err := p.InsertEvent(networkEvent(participants, keys[1], c.ours), false); err != nil {
+			t.Fatalf("expected an event of our network accepted, got %v", err)
+		}
+	}
+
+	// events of old clients pass only in compatibility mode
+	legacy := networkEvent(participants, keys[0], common.Hash{})
+	if err := newPoset(main, false).InsertEvent(legacy, false); !IsNetworkID(err) {
+		t.Fatalf("expected an event without network ID refused, got %v", err)
+	}
+	if err := newPoset(main, true).InsertEvent(legacy, false); err != nil {
+		t.Fatalf("expected an event without network ID accepted in compatibility mode, got %v", err)
+	}
+	// a poset without a network ID takes them all
+	if err := newPoset(common.Hash{}, false).InsertEvent(networkEvent(participants, keys[0], test), false); err != nil {
+		t.Fatalf("expected no check without a network ID, got %v", err)
+	}
+}
+
+func TestNetworkIDSigned(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	genesis := common.BytesToHash([]byte("genesis"))
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetNetworkID(NetworkID(genesis, "main"), false)
+
+	// an event moved to another network no longer verifies
+	ev := networkEvent(participants, keys[0], NetworkID(genesis, "test"))
+	ev.SetNetworkID(NetworkID(genesis, "main"))
+	if err := p.InsertEvent(ev, false); err == nil || IsNetworkID(err) {
+		t.Fatalf("expected the signature to cover the network ID, got %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+// +build !debug
+
+package poset
+
+// strictEventHash makes EventHash.Set panic on bytes of the wrong length
+const strictEventHash = false
@@ -0,0 +1,109 @@
+package poset
+
+import (
+	"sort"
+	"sync"
+)
+
+// maxParticipantGap bounds the indexes recorded for one gap, only the first
+// ones are recorded for an event far ahead of the last known of its creator
+const maxParticipantGap = 1000
+
+// participantGaps records, by public key, the indexes missing before events
+// of the participants known to exist, which the store refused as their index
+// skipped some
+type participantGaps struct {
+	sync.RWMutex
+	gaps map[string][]int64
+}
+
+func newParticipantGaps() *participantGaps {
+	return &participantGaps{gaps: make(map[string][]int64)}
+}
+
+// add records the indexes of a participant in the range, bounds included,
+// as missing
+func (g *participantGaps) add(participant string, from, to int64) {
+	if from < 0 {
+		from = 0
+	}
+	if to-from >= maxParticipantGap {
+		to = from + maxParticipantGap - 1
+	}
+	if to < from {
+		return
+	}
+	g.Lock()
+	defer g.Unlock()
+	missing := make(map[int64]bool, len(g.gaps[participant]))
+	for _, i := range g.gaps[participant] {
+		missing[i] = true
+	}
+	for i := from; i <= to; i++ {
+		missing[i] = true
+	}
+	indexes := make([]int64, 0, len(missing))
+	for i := range missing {
+		indexes = append(indexes, i)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	g.gaps[participant] = indexes
+}
+
+// fill forgets the missing indexes of a participant up to last, included,
+// as the events up to it are known now
+func (g *participantGaps) fill(participant string, last int64) {
+	g.Lock()
+	defer g.Unlock()
+	indexes, ok := g.gaps[participant]
+	if !ok {
+		return
+	}
+	n := sort.Search(len(indexes), func(i int) bool { return indexes[i] > last })
+	if n == len(indexes) {
+		delete(g.gaps, participant)
+		return
+	}
+	g.gaps[participant] = indexes[n:]
+}
+
+// get returns a copy of the missing indexes
+func (g *participantGaps) get() map[string][]int64 {
+	g.RLock()
+	defer g.RUnlock()
+	res := make(map[string][]int64, len(g.gaps))
+	for p, indexes := range g.gaps {
+		res[p] = append([]int64(nil), indexes...)
+	}
+	return res
+}
+
+func (g *participantGaps) reset() {
+	g.Lock()
+	defer g.Unlock()
+	g.gaps = make(map[string][]int64)
+}
+
+// ParticipantGaps returns the indexes missing before the events known to
+// exist of each participant, by public key
+func (s *InmemStore) ParticipantGaps() map[string][]int64 {
+	return s.gaps.get()
+}
+
+// AddParticipantGap records the indexes of a participant in the range,
+// bounds included, as missing
+func (s *InmemStore) AddParticipantGap(participant string, from, to int64) {
+	s.gaps.add(participant, from, to)
+}
+
+// ParticipantGaps returns the indexes missing before the events known to
+// exist of each participant, by public key
+func (s *BadgerStore) ParticipantGaps() map[string][]int64 {
+	return s.inmemStore.ParticipantGaps()
+}
+
+// AddParticipantGap records the indexes of a participant in the range,
+// bounds included, as missing
+func (s *BadgerStore) AddParticipantGap(participant string, from, to int64) {
+	s.inmemStore.AddParticipantGap(participant, from, to)
+}
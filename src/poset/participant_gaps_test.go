@@ -0,0 +1,71 @@
+package poset
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+func TestParticipantGaps(t *testing.T) {
+	store, participants := initInmemStore(100)
+	p := participants[0]
+
+	events := make([]Event, 6)
+	for k := range events {
+		events[k] = NewEvent([][]byte{[]byte(fmt.Sprintf("%s_%d", p.hex[:5], k))},
+			nil, nil, make(EventHashes, 2), p.pubKey, int64(k), nil, nil, 0, false)
+		_ = events[k].Hash() // just to set private variables
+	}
+	set := func(k int) error {
+		return store.SetEvent(events[k])
+	}
+	checkGaps := func(expected map[string][]int64) {
+		t.Helper()
+		if gaps := store.ParticipantGaps(); !reflect.DeepEqual(gaps, expected) {
+			t.Fatalf("expected the gaps %v, got %v", expected, gaps)
+		}
+	}
+
+	for k := 0; k < 2; k++ {
+		if err := set(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkGaps(map[string][]int64{})
+
+	// 4 skips 2 and 3, it is refused and they are recorded
+	if err := set(4); !common.Is(err, common.SkippedIndex) {
+		t.Fatalf("expected a skipped index error, got %v", err)
+	}
+	checkGaps(map[string][]int64{p.hex: {2, 3}})
+	if _, err := store.GetEventBlock(events[4].Hash()); !common.Is(err, common.KeyNotFound) {
+		t.Fatalf("the event skipping indexes should not be stored, got %v", err)
+	}
+
+	// filling them in resolves the gap
+	if err := set(2); err != nil {
+		t.Fatal(err)
+	}
+	checkGaps(map[string][]int64{p.hex: {3}})
+	for k := 3; k < 5; k++ {
+		if err := set(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkGaps(map[string][]int64{})
+
+	// gaps found elsewhere are merged, bounded and dropped by a reset
+	store.AddParticipantGap(p.hex, 7, 8)
+	store.AddParticipantGap(p.hex, 6, 7)
+	checkGaps(map[string][]int64{p.hex: {6, 7, 8}})
+	store.AddParticipantGap(participants[1].hex, 0, 10*maxParticipantGap)
+	if l := len(store.ParticipantGaps()[participants[1].hex]); l != maxParticipantGap {
+		t.Fatalf("expected %d missing indexes, got %d", maxParticipantGap, l)
+	}
+	if err := store.Reset(store.RootsByParticipant()); err != nil {
+		t.Fatal(err)
+	}
+	checkGaps(map[string][]int64{})
+}
@@ -0,0 +1,63 @@
+package poset
+
+import "time"
+
+// PeerReputation is what a node learnt about a peer, kept in the store so
+// that it survives restarts. Records are keyed by the public key of the peer.
+type PeerReputation struct {
+	// Failures counts the failed syncs with the peer
+	Failures int `json:"failures"`
+	// Penalties counts the events of the peer the consensus rules rejected
+	Penalties int `json:"penalties"`
+	// LastSeen is the last successful exchange with the peer
+	LastSeen time.Time `json:"last_seen"`
+	// BannedUntil is when a ban of the peer ends, zero if not banned
+	BannedUntil time.Time `json:"banned_until"`
+}
+
+// Banned returns true while a ban of the peer lasts
+func (r PeerReputation) Banned(now time.Time) bool {
+	return now.Before(r.BannedUntil)
+}
+
+// peerReputationRecord is a PeerReputation as stored by the BadgerStore,
+// whose encoding does not keep the times; they are stored as UnixNano, zero
+// for a zero time.
+type peerReputationRecord struct {
+	Failures    int
+	Penalties   int
+	LastSeen    int64
+	BannedUntil int64
+}
+
+func newPeerReputationRecord(r PeerReputation) peerReputationRecord {
+	return peerReputationRecord{
+		Failures:    r.Failures,
+		Penalties:   r.Penalties,
+		LastSeen:    unixNano(r.LastSeen),
+		BannedUntil: unixNano(r.BannedUntil),
+	}
+}
+
+func (r peerReputationRecord) reputation() PeerReputation {
+	return PeerReputation{
+		Failures:    r.Failures,
+		Penalties:   r.Penalties,
+		LastSeen:    fromUnixNano(r.LastSeen),
+		BannedUntil: fromUnixNano(r.BannedUntil),
+	}
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
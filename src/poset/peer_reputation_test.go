@@ -0,0 +1,128 @@
+package poset
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+func TestPeerReputations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reputation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	participants, _ := iteratorParticipants()
+	badgerStore, err := NewBadgerStore(participants, cacheSize, filepath.Join(dir, "badger"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer badgerStore.Close()
+
+	now := time.Unix(1500000000, 0).UTC()
+	pubKeys := participants.ToPubKeySlice()
+	for name, store := range map[string]Store{
+		"inmem":  NewInmemStore(participants, cacheSize, nil),
+		"badger": badgerStore,
+	} {
+		reps, err := store.PeerReputations()
+		if err != nil || len(reps) != 0 {
+			t.Fatalf("%s: expected no reputation yet, got %v, %v", name, reps, err)
+		}
+
+		expected := map[string]PeerReputation{
+			pubKeys[0]: {Failures: 2, LastSeen: now},
+			pubKeys[1]: {Penalties: 1, BannedUntil: now.Add(time.Hour)},
+		}
+		if err := store.SetPeerReputations(expected); err != nil {
+			t.Fatal(err)
+		}
+		// records are updated by public key
+		update := PeerReputation{Failures: 3, LastSeen: now}
+		if err := store.SetPeerReputations(map[string]PeerReputation{pubKeys[0]: update}); err != nil {
+			t.Fatal(err)
+		}
+		expected[pubKeys[0]] = update
+
+		reps, err = store.PeerReputations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reps) != len(expected) {
+			t.Fatalf("%s: expected %d records, got %d", name, len(expected), len(reps))
+		}
+		for pubKey, rep := range expected {
+			got := reps[pubKey]
+			if got.Failures != rep.Failures || got.Penalties != rep.Penalties ||
+				!got.LastSeen.Equal(rep.LastSeen) || !got.BannedUntil.Equal(rep.BannedUntil) {
+				t.Fatalf("%s: expected %+v, got %+v", name, rep, got)
+			}
+		}
+		if !reps[pubKeys[1]].Banned(now) || reps[pubKeys[1]].Banned(now.Add(2*time.Hour)) {
+			t.Fatalf("%s: expected the ban to last an hour", name)
+		}
+	}
+}
+
+func TestPeerAddresses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "address")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	participants, keys := iteratorParticipants()
+	badgerStore, err := NewBadgerStore(participants, cacheSize, filepath.Join(dir, "badger"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer badgerStore.Close()
+
+	announce := func(i int, addr string, nonce int64) peers.AddrAnnouncement {
+		a, err := peers.NewAddrAnnouncement(keys[i], addr, nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	for name, store := range map[string]Store{
+		"inmem":  NewInmemStore(participants, cacheSize, nil),
+		"badger": badgerStore,
+	} {
+		addrs, err := store.PeerAddresses()
+		if err != nil || len(addrs) != 0 {
+			t.Fatalf("%s: expected no address yet, got %v, %v", name, addrs, err)
+		}
+
+		a0, a1 := announce(0, "10.0.0.1:1337", 1), announce(1, "10.0.0.2:1337", 1)
+		expected := map[string]peers.AddrAnnouncement{a0.PubKeyHex: a0, a1.PubKeyHex: a1}
+		if err := store.SetPeerAddresses(expected); err != nil {
+			t.Fatal(err)
+		}
+		// records are updated by public key
+		moved := announce(0, "10.0.0.3:1337", 2)
+		if err := store.SetPeerAddresses(map[string]peers.AddrAnnouncement{moved.PubKeyHex: moved}); err != nil {
+			t.Fatal(err)
+		}
+		expected[moved.PubKeyHex] = moved
+
+		addrs, err = store.PeerAddresses()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(addrs, expected) {
+			t.Fatalf("%s: expected %+v, got %+v", name, expected, addrs)
+		}
+		for _, a := range addrs {
+			if err := a.Verify(); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+	}
+}
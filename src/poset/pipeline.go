@@ -0,0 +1,136 @@
+package poset
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxPipelineFrames bounds the frames PipelineStatus reports, from the
+// oldest one not final yet
+const maxPipelineFrames = 20
+
+// The stages a frame goes through before it is final. A stalled frame is
+// stuck at the first one it has not passed.
+const (
+	StageNoClothos         = "no clothos"
+	StageClothosUndecided  = "clothos undecided"
+	StageAtroposUnassigned = "atropos time unassigned"
+	StageNotFinal          = "frame not final"
+	StageFinal             = "final"
+)
+
+// FrameStatus is how far a frame got towards being final
+type FrameStatus struct {
+	Frame  int64 `json:"frame"`
+	Events int   `json:"events"`
+	Roots  int   `json:"roots"`
+	// Clothos are the roots seen by a supermajority of the next frame
+	// roots, they are decided once they are known to be Atropos
+	Clothos        int `json:"clothos"`
+	ClothosDecided int `json:"clothos_decided"`
+	// AtroposAssigned is the number of events with an Atropos time
+	AtroposAssigned int  `json:"atropos_assigned"`
+	Final           bool `json:"final"`
+	// OldestUndecidedClotho is the undecided clotho with the lowest
+	// lamport timestamp, empty when there is none
+	OldestUndecidedClotho string `json:"oldest_undecided_clotho,omitempty"`
+	Stage                 string `json:"stage"`
+}
+
+// PipelineStatus is where the frames waiting for consensus are in the
+// pipeline turning them into blocks
+type PipelineStatus struct {
+	LastConsensusRound int64         `json:"last_consensus_round"`
+	NextFinalFrame     int64         `json:"next_final_frame"`
+	LastFrame          int64         `json:"last_frame"`
+	Frames             []FrameStatus `json:"frames"`
+}
+
+// Summary returns the status on one line, for the logs
+func (s PipelineStatus) Summary() string {
+	var frames []string
+	for _, f := range s.Frames {
+		frames = append(frames, fmt.Sprintf("%d:%s(%d events, %d/%d clothos decided, %d timed)",
+			f.Frame, f.Stage, f.Events, f.ClothosDecided, f.Clothos, f.AtroposAssigned))
+	}
+	return fmt.Sprintf("consensus round %d, next final frame %d, last frame %d: %s",
+		s.LastConsensusRound, s.NextFinalFrame, s.LastFrame, strings.Join(frames, "; "))
+}
+
+// PipelineStatus returns the status of the frames from the oldest one not
+// final yet, for debugging stalled consensus
+func (p *Poset) PipelineStatus() (PipelineStatus, error) {
+	p.DecidedLocker.Lock()
+	nextFinalFrame := p.nextFinalFrame
+	p.DecidedLocker.Unlock()
+
+	res := PipelineStatus{
+		LastConsensusRound: p.GetLastConsensusRound(),
+		NextFinalFrame:     nextFinalFrame,
+		LastFrame:          p.Store.LastRound(),
+	}
+	// the next final frame is reported even before any of its rounds is
+	// created, which is where a network without roots is stuck
+	last := res.LastFrame
+	if last < nextFinalFrame {
+		last = nextFinalFrame
+	}
+	for frame := nextFinalFrame; frame <= last &&
+		frame < nextFinalFrame+maxPipelineFrames; frame++ {
+		status, err := p.frameStatus(frame)
+		if err != nil {
+			return PipelineStatus{}, err
+		}
+		res.Frames = append(res.Frames, status)
+	}
+	return res, nil
+}
+
+func (p *Poset) frameStatus(frame int64) (FrameStatus, error) {
+	res := FrameStatus{Frame: frame}
+	hashes, err := p.Store.EventsByRoundRange(frame, frame)
+	if err != nil {
+		return res, err
+	}
+	var oldest *Event
+	for _, hash := range hashes {
+		ev, err := p.Store.GetEventBlock(hash)
+		if err != nil {
+			return res, err
+		}
+		res.Events++
+		if ev.Root {
+			res.Roots++
+		}
+		if ev.Clotho {
+			res.Clothos++
+			if ev.Atropos {
+				res.ClothosDecided++
+			} else if oldest == nil || ev.LamportTimestamp < oldest.LamportTimestamp {
+				oldest = &ev
+			}
+		}
+		if ev.HasAtroposTime() {
+			res.AtroposAssigned++
+		}
+	}
+	if oldest != nil {
+		hash := oldest.Hash()
+		res.OldestUndecidedClotho = hash.String()
+	}
+	res.Final = p.frameFinal(frame)
+
+	switch {
+	case res.Final:
+		res.Stage = StageFinal
+	case res.Clothos == 0:
+		res.Stage = StageNoClothos
+	case res.ClothosDecided < res.Clothos:
+		res.Stage = StageClothosUndecided
+	case res.AtroposAssigned < res.Events:
+		res.Stage = StageAtroposUnassigned
+	default:
+		res.Stage = StageNotFinal
+	}
+	return res, nil
+}
@@ -0,0 +1,76 @@
+package poset
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPipelineStatusNoClothos(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a single creator never gets its roots seen by a supermajority
+	var self *Event
+	for i := 0; i < 3; i++ {
+		ev := capEvent(participants, keys[0], self, EventHash{}, fmt.Sprintf("a%d", i))
+		if err := p.InsertEvent(ev, false); err != nil {
+			t.Fatal(err)
+		}
+		self = &ev
+	}
+
+	status, err := p.PipelineStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Frames) == 0 {
+		t.Fatalf("expected the pending frames, got %+v", status)
+	}
+	events := 0
+	for _, f := range status.Frames {
+		events += f.Events
+		if f.Stage != StageNoClothos {
+			t.Fatalf("expected frame %d at the stage %q, got %q", f.Frame, StageNoClothos, f.Stage)
+		}
+	}
+	if events != 3 {
+		t.Fatalf("expected 3 events, got %d", events)
+	}
+}
+
+func TestPipelineStatusClothosUndecided(t *testing.T) {
+	const frames = 4
+	d := newRootDAG(4, frames, 1, t)
+	p, err := d.checkRoots(true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Store.SetRound(frames-1, *NewRound()); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := p.PipelineStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Frames) != frames {
+		t.Fatalf("expected %d frames, got %+v", frames, status)
+	}
+	first := status.Frames[0]
+	if first.Events != 4 || first.Roots != 4 || first.Clothos == 0 {
+		t.Fatalf("expected the 4 roots of frame 0 with clothos, got %+v", first)
+	}
+	if first.Stage != StageClothosUndecided || first.OldestUndecidedClotho == "" {
+		t.Fatalf("expected frame 0 stuck with undecided clothos, got %+v", first)
+	}
+	if last := status.Frames[frames-1]; last.Stage != StageNoClothos {
+		t.Fatalf("expected the last frame without clothos, got %+v", last)
+	}
+	if summary := status.Summary(); !strings.Contains(summary, "0:"+StageClothosUndecided) {
+		t.Fatalf("expected the summary to name the stuck stage, got %q", summary)
+	}
+}
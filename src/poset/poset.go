@@ -26,6 +26,49 @@ type Core interface {
 	HexID() string
 }
 
+// ErrZeroCreatorID is returned when a wire event has no creator ID
+var ErrZeroCreatorID = errors.New("wire event with zero creator ID")
+
+// ErrInvalidSignature is returned for an event not signed by its creator
+var ErrInvalidSignature = errors.New("invalid Event signature")
+
+// ErrAlreadyKnown is returned by InsertEvent for an event the Store has
+// already, e.g. received from two peers syncing at once. It is no failure,
+// nothing is inserted.
+var ErrAlreadyKnown = errors.New("event already known")
+
+// MissingParentError is returned by InsertEvent and ReadWireInfo for an
+// event whose other-parent is not known, it can be inserted once the parent
+// is. ReadWireInfo knows the parent by creator ID and index only.
+type MissingParentError struct {
+	Parent    EventHash
+	CreatorID uint64
+	Index     int64
+}
+
+func (e *MissingParentError) Error() string {
+	if e.Parent.Zero() {
+		return fmt.Sprintf("other-parent %d of creator %d not known", e.Index, e.CreatorID)
+	}
+	return fmt.Sprintf("other-parent %s not known", e.Parent.String())
+}
+
+// IsMissingParent returns true for a MissingParentError
+func IsMissingParent(err error) bool {
+	_, ok := err.(*MissingParentError)
+	return ok
+}
+
+// UnknownCreatorError is returned when a wire event refers to a creator ID
+// that is neither a participant nor a looked up wire creator
+type UnknownCreatorError struct {
+	ID uint64
+}
+
+func (e *UnknownCreatorError) Error() string {
+	return fmt.Sprintf("unknown wire event creator ID %d", e.ID)
+}
+
 // Poset is a DAG of Events. It also contains methods to extract a consensus
 // order of Events and map them onto a blockchain.
 type Poset struct {
@@ -45,16 +88,64 @@ type Poset struct {
 	topologicalIndex         int64             // counter used to order events in topological order (only local)
 	core                     Core
 	nextFinalFrame           int64
-
-	dominatorCache         *lru.Cache
-	selfDominatorCache     *lru.Cache
-	strictlyDominatedCache *lru.Cache
-	roundCache             *lru.Cache
-	timestampCache         *lru.Cache
-
-	logger *logrus.Entry
+	decidedFrame             int64             // highest frame of a decided Atropos, FrameNIL for none
+	resetFrame               int64             // frame of the block of the last Reset, that of the events the roots stand for
+	consensusListener        func(Event)       // told about each event reaching consensus
+	auditSink                AuditSink         // trail of the consensus decisions, see SetAuditSink
+
+	// decisionListener is told about each clotho decided, see
+	// SetDecisionListener
+	decisionListener func(int64, string)
+
+	dominatorCache         *meteredCache
+	selfDominatorCache     *meteredCache
+	strictlyDominatedCache *meteredCache
+	roundCache             *meteredCache
+	timestampCache         *meteredCache
+	cacheTuner             *cacheTuner // resizes the caches above
+	tableCache             *meteredCache // (event hash, kind) => FlagTable
+	clothoSupportCache     *lru.Cache // root hash => clothoCounts
+	eventTimeCache         *lru.Cache    // event hash => eventTime
+	wireSizeCache          *lru.Cache    // event hash => WireSize
+
+	// atroposVotes are the votes counted by DecideAtropos for the undecided
+	// clothos of the pending rounds, so the next call resumes from them:
+	// [round][x][y] => vote(y, x)
+	atroposVotes map[int64]map[EventHash]map[EventHash]bool
+
+	logger      *logrus.Entry
+	warnLimiter *dag1_log.Limiter
+
+	wireCreators       map[uint64]*peers.PeerMessage // creator ID => peer, for IDs missing from Participants
+	wireCreatorsLocker sync.RWMutex
+
+	maxEventsPerFrame int // events a creator may make per frame, 0 no cap
+
+	maxTimestampDrift time.Duration // bound of the event times after their parents, 0 none
+	clockSkew         clockSkew
+
+	fairness fairness // contributions of the creators, see FairnessReport
+
+	clock common.Clock // dates the blocks
+
+	networkID           common.Hash // network the events are made for, zero unchecked
+	acceptLegacyNetwork bool        // events without a network ID are accepted
+	blockBudget       blockBudget
+	blockCarry        [][]byte // transactions of decided frames left for the next block
+
+	undeterminedWarnAge     int64 // rounds before an undetermined event is warned about
+	undeterminedArchiveAge  int64 // rounds before an undetermined event is archived, 0 never
+	undeterminedPasses      int   // DecideRoundReceived passes, to pace archive scans
+	archivedCount           int
+	archiveOldestRound      int64
+	undeterminedStats       UndeterminedStats
+	undeterminedStatsLocker sync.RWMutex
 
 	undeterminedEventsLocker      sync.RWMutex
+	undeterminedEventsEpoch       uint64      // bumped when UndeterminedEvents is cleared
+	undivided                     EventHashes // inserted events DivideRounds has not processed, in order
+	rootQueue                     *rootQueue  // roots left to ProcessRootQueue, nil to process them on insertion
+	decideRoundReceivedLocker     sync.Mutex
 	pendingLoadedEventsLocker     sync.RWMutex
 	firstLastConsensusRoundLocker sync.RWMutex
 	consensusTransactionsLocker   sync.RWMutex
@@ -62,9 +153,16 @@ type Poset struct {
 	DecidedLocker                 sync.Mutex
 }
 
+// UnknownAddress is the address of a Poset without a core
+const UnknownAddress = "unknown"
+
+// warnRepeatWindow is how long repeats of a warning are collapsed
+const warnRepeatWindow = 10 * time.Second
+
 // NewPoset instantiates a Poset from a list of participants, underlying
-// data store and commit channel
-func NewPoset(participants *peers.Peers, store Store, commitCh chan Block, logger *logrus.Entry) *Poset {
+// data store and commit channel. It fails when the caches of the store size
+// cannot be made.
+func NewPoset(participants *peers.Peers, store Store, commitCh chan Block, logger *logrus.Entry) (*Poset, error) {
 	if logger == nil {
 		log := logrus.New()
 		log.Level = logrus.DebugLevel
@@ -73,25 +171,42 @@ func NewPoset(participants *peers.Peers, store Store, commitCh chan Block, logge
 	}
 
 	cacheSize := store.CacheSize()
-	dominatorCache, err := lru.New(cacheSize)
+	tuner := &cacheTuner{}
+	dominatorCache, err := newMeteredCache("dominator", cacheSize, tuner)
+	if err != nil {
+		return nil, fmt.Errorf("unable to init Poset.dominatorCache: %s", err)
+	}
+	selfDominatorCache, err := newMeteredCache("self_dominator", cacheSize, tuner)
+	if err != nil {
+		return nil, fmt.Errorf("unable to init Poset.selfDominatorCache: %s", err)
+	}
+	strictlyDominatedCache, err := newMeteredCache("strictly_dominated", cacheSize, tuner)
+	if err != nil {
+		return nil, fmt.Errorf("unable to init Poset.strictlyDominatedCache: %s", err)
+	}
+	roundCache, err := newMeteredCache("round", cacheSize, tuner)
+	if err != nil {
+		return nil, fmt.Errorf("unable to init Poset.roundCreatedCache: %s", err)
+	}
+	timestampCache, err := newMeteredCache("timestamp", cacheSize, tuner)
 	if err != nil {
-		logger.Fatal("Unable to init Poset.dominatorCache")
+		return nil, fmt.Errorf("unable to init Poset.timestampCache: %s", err)
 	}
-	selfDominatorCache, err := lru.New(cacheSize)
+	tableCache, err := newMeteredCache("table", cacheSize, tuner)
 	if err != nil {
-		logger.Fatal("Unable to init Poset.selfDominatorCache")
+		return nil, fmt.Errorf("unable to init Poset.tableCache: %s", err)
 	}
-	strictlyDominatedCache, err := lru.New(cacheSize)
+	clothoSupportCache, err := lru.New(cacheSize)
 	if err != nil {
-		logger.Fatal("Unable to init Poset.strictlyDominatedCache")
+		return nil, fmt.Errorf("unable to init Poset.clothoSupportCache: %s", err)
 	}
-	roundCache, err := lru.New(cacheSize)
+	eventTimeCache, err := lru.New(cacheSize)
 	if err != nil {
-		logger.Fatal("Unable to init Poset.roundCreatedCache")
+		return nil, fmt.Errorf("unable to init Poset.eventTimeCache: %s", err)
 	}
-	timestampCache, err := lru.New(cacheSize)
+	wireSizeCache, err := lru.New(cacheSize)
 	if err != nil {
-		logger.Fatal("Unable to init Poset.timestampCache")
+		return nil, fmt.Errorf("unable to init Poset.wireSizeCache: %s", err)
 	}
 	poset := Poset{
 		Participants:           participants,
@@ -104,15 +219,68 @@ func NewPoset(participants *peers.Peers, store Store, commitCh chan Block, logge
 		strictlyDominatedCache: strictlyDominatedCache,
 		roundCache:             roundCache,
 		timestampCache:         timestampCache,
+		cacheTuner:             tuner,
+		tableCache:             tableCache,
+		clothoSupportCache:     clothoSupportCache,
+		eventTimeCache:         eventTimeCache,
+		wireSizeCache:          wireSizeCache,
+		atroposVotes:           make(map[int64]map[EventHash]map[EventHash]bool),
 		logger:                 logger,
+		warnLimiter:            dag1_log.NewLimiter(warnRepeatWindow),
+		undeterminedWarnAge:    DefaultUndeterminedWarnAge,
+		archiveOldestRound:     math.MaxInt64,
+		decidedFrame:           FrameNIL,
+		clock:                  common.RealClock,
+		wireCreators:           make(map[uint64]*peers.PeerMessage),
+		auditSink:              nopAuditSink{},
 	}
 
-	return &poset
+	return &poset, nil
+}
+
+// NewStandalonePoset instantiates a Poset with no core and no commit channel,
+// for offline use such as replaying or analysing a DAG. The events reach
+// consensus in the store and no Blocks are made.
+func NewStandalonePoset(participants *peers.Peers, store Store, logger *logrus.Entry) (*Poset, error) {
+	return NewPoset(participants, store, nil, logger)
+}
+
+// finalityLogger is implemented by the stores logging the final events
+type finalityLogger interface {
+	SetFinalityLog(path string)
 }
 
-// SetCore sets a core for poset.
+// SetCore sets a core for poset. A store logging the final events logs them
+// to a file named after the address of the core.
 func (p *Poset) SetCore(core Core) {
 	p.core = core
+	if logger, ok := p.Store.(finalityLogger); ok {
+		logger.SetFinalityLog(fmt.Sprintf("Node_%v.finality", p.Address()))
+	}
+}
+
+// isSelfHead returns true for the head of the core, false without a core
+func (p *Poset) isSelfHead(ev Event) bool {
+	return p.core != nil && ev.Hash() == p.core.Head() &&
+		ev.GetCreator() == p.core.HexID()
+}
+
+// SetClock sets the clock the blocks are dated with
+func (p *Poset) SetClock(clock common.Clock) {
+	p.clock = clock
+}
+
+// SetConsensusListener sets a function called with each event reaching
+// consensus, once its FrameReceived is known. It must be set before events
+// are inserted and must not block.
+func (p *Poset) SetConsensusListener(listener func(Event)) {
+	p.consensusListener = listener
+}
+
+// SetDecisionListener sets a function called with the round and the creator
+// of each clotho whose Atropos vote gets decided. It must not block.
+func (p *Poset) SetDecisionListener(listener func(round int64, creator string)) {
+	p.decisionListener = listener
 }
 
 /*******************************************************************************
@@ -214,10 +382,10 @@ func (p *Poset) selfDominator2(x, y EventHash) (bool, error) {
 	roots := p.Store.RootsBySelfParent()
 	ex, err := p.Store.GetEventBlock(x)
 	if err != nil {
+		// x is the self-parent of a root, out of the store: no error, it
+		// only self-dominates itself
 		if root, ok := roots[x]; ok {
-			if y.Equal(root.SelfParent.Hash) {
-				return true, nil
-			}
+			return y.Equal(root.SelfParent.Hash), nil
 		}
 		return false, err
 	}
@@ -381,31 +549,44 @@ func (p *Poset) round2(x EventHash) (int64, error) {
 		The Event's parents are "normal" Events.
 		Use the whitepaper formula: parentRound + roundInc
 	*/
-	spRound, err := p.round(ex.SelfParent())
-	if err != nil {
-		p.logger.Debug("p.round2(): return RoundNIL")
-		return FrameNIL, err
-	}
-	var parentRound = spRound
-	opRound, err := p.round(ex.OtherParent())
-	if err != nil {
-		p.logger.Debug("p.round2(): return RoundNIL 2")
-		return FrameNIL, err
+	// a leaf event has no self-parent either
+	var parentRound int64 = RoundNIL
+	if sp := ex.SelfParent(); !sp.Zero() {
+		spRound, err := p.round(sp)
+		if err != nil {
+			p.logger.Debug("p.round2(): return RoundNIL")
+			return RoundNIL, err
+		}
+		parentRound = spRound
 	}
-	if opRound > parentRound {
-		parentRound = opRound
+	// an event made before any of another creator has no other-parent
+	if op := ex.OtherParent(); !op.Zero() {
+		// an other-parent outside of the frame is only known by the Root
+		var opRound int64
+		hash := ex.Hash()
+		if other, ok := root.Others[hash.String()]; ok && op.Equal(other.Hash) {
+			opRound = other.Round
+		} else if opRound, err = p.round(op); err != nil {
+			p.logger.Debug("p.round2(): return RoundNIL 2")
+			return RoundNIL, err
+		}
+		if opRound > parentRound {
+			parentRound = opRound
+		}
 	}
-	p.logger.WithField("parentRound", parentRound).Debug("p.round2()")
+	dag1_log.LazyDebug(p.logger, func() logrus.Fields {
+		return logrus.Fields{"parentRound": parentRound}
+	}, "p.round2()")
 
 	// base of recursion. If both parents are of RoundNIL they are leaf events
-	if parentRound == FrameNIL {
+	if parentRound == RoundNIL {
 		return 0, nil
 	}
 
 	ws := p.Store.RoundClothos(parentRound)
-	p.logger.WithFields(logrus.Fields{
-		"len(ws)": len(ws),
-	}).Debug("p.round2()")
+	dag1_log.LazyDebug(p.logger, func() logrus.Fields {
+		return logrus.Fields{"len(ws)": len(ws)}
+	}, "p.round2()")
 
 //	isDominated := func(poset *Poset, root EventHash, clothos EventHashes) bool {
 //		for _, w := range ws {
@@ -423,10 +604,12 @@ func (p *Poset) round2(x EventHash) (int64, error) {
 //	}
 
 	// check wp
-	p.logger.WithFields(logrus.Fields{
-//		"len(ex.Message.ClothoProof)": len(ex.Message.ClothoProof),
-		"p.superMajority":             p.GetSuperMajority(),
-	}).Debug("p.round2()")
+	dag1_log.LazyDebug(p.logger, func() logrus.Fields {
+		return logrus.Fields{
+//			"len(ex.Message.ClothoProof)": len(ex.Message.ClothoProof),
+			"p.superMajority":             p.GetSuperMajority(),
+		}
+	}, "p.round2()")
 	if false /*len(ex.Message.ClothoProof) >= p.GetSuperMajority()*/ {
 		count := uint64(0)
 //		for _, h := range ex.Message.ClothoProof {
@@ -449,11 +632,13 @@ func (p *Poset) round2(x EventHash) (int64, error) {
 	} else {
 
 		// check ft
-		ft, _ := ex.GetFlagTable()
-		p.logger.WithFields(logrus.Fields{
-			"len(ft)":         len(ft),
-			"p.superMajority": p.GetSuperMajority(),
-		}).Debug("p.round2()")
+		ft, _ := p.flagTableOf(&ex)
+		dag1_log.LazyDebug(p.logger, func() logrus.Fields {
+			return logrus.Fields{
+				"len(ft)":         len(ft),
+				"p.superMajority": p.GetSuperMajority(),
+			}
+		}, "p.round2()")
 		if uint64(len(ft)) >= p.GetSuperMajority() {
 			count := 0
 
@@ -463,11 +648,13 @@ func (p *Poset) round2(x EventHash) (int64, error) {
 				//}
 //			}
 
-			p.logger.WithFields(logrus.Fields{
-				"len(ft)":         len(ft),
-				"count":           count,
-				"p.superMajority": p.GetSuperMajority(),
-			}).Debug("p.round2()")
+			dag1_log.LazyDebug(p.logger, func() logrus.Fields {
+				return logrus.Fields{
+					"len(ft)":         len(ft),
+					"count":           count,
+					"p.superMajority": p.GetSuperMajority(),
+				}
+			}, "p.round2()")
 			if uint64(count) >= p.GetSuperMajority() {
 				p.logger.Debug("p.round2(): return parentRound + 1 (2)")
 				return parentRound + 1, err
@@ -534,6 +721,9 @@ func (p *Poset) lamportTimestamp2(x EventHash) (int64, error) {
 	selfParent := ex.SelfParent()
 	if selfParent.Equal(root.SelfParent.Hash) {
 		plt = root.SelfParent.LamportTimestamp
+	} else if selfParent.Zero() {
+		// a leaf event has no self-parent
+		plt = LamportTimestampNIL
 	} else {
 		t, err := p.lamportTimestamp(selfParent)
 		if err != nil {
@@ -602,12 +792,14 @@ func (p *Poset) checkSelfParent(event Event) error {
 
 	creatorLastKnown, _, err := p.Store.LastEventFrom(creator)
 
-	p.logger.WithFields(logrus.Fields{
-		"selfParent":       selfParent,
-		"creator":          creator,
-		"creatorLastKnown": creatorLastKnown,
-		"event":            event.Hash(),
-	}).Debugf("checkSelfParent")
+	dag1_log.LazyDebug(p.logger, func() logrus.Fields {
+		return logrus.Fields{
+			"selfParent":       selfParent,
+			"creator":          creator,
+			"creatorLastKnown": creatorLastKnown,
+			"event":            event.Hash(),
+		}
+	}, "checkSelfParent")
 
 	if err != nil {
 		return err
@@ -616,19 +808,46 @@ func (p *Poset) checkSelfParent(event Event) error {
 	selfParentLegit := selfParent == creatorLastKnown
 
 	if !selfParentLegit {
+		p.noteGap(creator, event.Index())
 		return fmt.Errorf("self-parent not last known event by creator")
 	}
 
 	return nil
 }
 
+// noteGap records the indexes of a creator its event of the index skipped,
+// those after the last known event of the creator and before it
+func (p *Poset) noteGap(creator string, index int64) {
+	last, isRoot, err := p.Store.LastEventFrom(creator)
+	if err != nil {
+		return
+	}
+	var lastIndex int64
+	if isRoot {
+		root, err := p.Store.GetRoot(creator)
+		if err != nil || root.SelfParent == nil {
+			return
+		}
+		lastIndex = root.SelfParent.Index
+	} else {
+		lastEvent, err := p.Store.GetEventBlock(last)
+		if err != nil {
+			return
+		}
+		lastIndex = lastEvent.Index()
+	}
+	if index > lastIndex+1 {
+		p.Store.AddParticipantGap(creator, lastIndex+1, index-1)
+	}
+}
+
 // Check if we know the OtherParent
 func (p *Poset) checkOtherParent(event Event) error {
 	otherParent := event.OtherParent()
 	if !otherParent.Zero() {
 		// Check if we have it
 		_, err := p.Store.GetEventBlock(otherParent)
-		if err != nil {
+		if err != nil && !p.isParticipantRoot(otherParent) {
 			// it might still be in the Root
 			root, err := p.Store.GetRoot(event.GetCreator())
 			if err != nil {
@@ -640,15 +859,96 @@ func (p *Poset) checkOtherParent(event Event) error {
 			if ok && otherParent.Equal(other.Hash) {
 				return nil
 			}
-			return fmt.Errorf("other-parent not known")
+			return &MissingParentError{Parent: otherParent}
 		}
 	}
 	return nil
 }
 
+// isParticipantRoot returns true when hash is the self-parent of the root of
+// a participant, which an event of another participant which saw none of its
+// events has as other-parent
+func (p *Poset) isParticipantRoot(hash EventHash) bool {
+	_, ok := p.Store.RootsBySelfParent()[hash]
+	return ok
+}
+
+// isRootSelfParent returns true when the self-parent of the event is the
+// self-parent of its creator's root, i.e. it is the first event after it
+func (p *Poset) isRootSelfParent(event Event) bool {
+	root, err := p.Store.GetRoot(event.GetCreator())
+	if err != nil || root.SelfParent == nil {
+		return false
+	}
+	selfParent := event.SelfParent()
+	return selfParent.Equal(root.SelfParent.Hash)
+}
+
+// isRootOtherParent returns true when the event has no other-parent or a
+// root stands for it
+func (p *Poset) isRootOtherParent(event Event) bool {
+	otherParent := event.OtherParent()
+	if otherParent.Zero() || p.isParticipantRoot(otherParent) {
+		return true
+	}
+	root, err := p.Store.GetRoot(event.GetCreator())
+	if err != nil {
+		return false
+	}
+	hash := event.Hash()
+	other, ok := root.Others[hash.String()]
+	return ok && otherParent.Equal(other.Hash)
+}
+
+// parentLamportTimestamp returns the lamport timestamp of a parent of an
+// event being inserted, as looked up in the store. A parent the root stands
+// for has the timestamp the root keeps, no other-parent none.
+func (p *Poset) parentLamportTimestamp(event Event, parent EventHash, parentEvent *Event, lookupErr error) (int64, error) {
+	if lookupErr == nil {
+		if parentEvent.HasLamport() {
+			return parentEvent.GetLamportTimestamp(), nil
+		}
+		return p.lamportTimestamp(parent)
+	}
+	if parent.Zero() {
+		return LamportTimestampNIL, nil
+	}
+	if root, ok := p.Store.RootsBySelfParent()[parent]; ok {
+		return root.SelfParent.LamportTimestamp, nil
+	}
+	root, err := p.Store.GetRoot(event.GetCreator())
+	if err != nil {
+		return LamportTimestampNIL, err
+	}
+	if root.SelfParent != nil && parent.Equal(root.SelfParent.Hash) {
+		return root.SelfParent.LamportTimestamp, nil
+	}
+	hash := event.Hash()
+	if other, ok := root.Others[hash.String()]; ok && parent.Equal(other.Hash) {
+		return other.LamportTimestamp, nil
+	}
+	return LamportTimestampNIL, nil
+}
+
 func (p *Poset) createSelfParentRootEvent(ev Event) (RootEvent, error) {
 	sp := ev.SelfParent()
-	spLT, err := p.lamportTimestamp(sp)
+	if sp.Zero() {
+		// a leaf event has no self-parent, its root is a base one
+		peer, ok := p.Participants.ReadByPubKey(ev.GetCreator())
+		if !ok {
+			return RootEvent{}, fmt.Errorf("creator %v not found", ev.GetCreator())
+		}
+		return RootEvent{
+			Hash:             sp.Bytes(),
+			CreatorID:        peer.ID,
+			Index:            -1,
+			LamportTimestamp: LamportTimestampNIL,
+		}, nil
+	}
+	// the root carries the timestamp the events on top of it were inserted
+	// with, a leaf event's included
+	spEvent, errSp := p.Store.GetEventBlock(sp)
+	spLT, err := p.parentLamportTimestamp(ev, sp, &spEvent, errSp)
 	if err != nil {
 		return RootEvent{}, err
 	}
@@ -689,7 +989,7 @@ func (p *Poset) createOtherParentRootEvent(ev Event) (RootEvent, error) {
 	if err != nil {
 		return RootEvent{}, err
 	}
-	opLT, err := p.lamportTimestamp(op)
+	opLT, err := p.parentLamportTimestamp(ev, op, &otherParent, nil)
 	if err != nil {
 		return RootEvent{}, err
 	}
@@ -759,11 +1059,15 @@ func (p *Poset) SetWireInfo(event *Event) error {
 	return p.setWireInfo(event)
 }
 
-// SetWireInfoAndSign set wire info for the event and sign
+// SetWireInfoAndSign set wire info for the event, the network ID when set,
+// and sign
 func (p *Poset) SetWireInfoAndSign(event *Event, privKey *ecdsa.PrivateKey) error {
 	if err := p.setWireInfo(event); err != nil {
 		return err
 	}
+	if p.networkID != (common.Hash{}) {
+		event.SetNetworkID(p.networkID)
+	}
 	return event.Sign(privKey)
 }
 
@@ -775,23 +1079,34 @@ func (p *Poset) setWireInfo(event *Event) error {
 		return fmt.Errorf("creator %s not found", eventCreator)
 	}
 
+	// a parent the root of its creator stands for has the index -1, as
+	// readWireInfo expects, and no other-parent has the creator ID 0
+	selfParentIndex := int64(-1)
 	selfParent, err := p.Store.GetEventBlock(event.SelfParent())
-	if err != nil {
+	if err == nil {
+		selfParentIndex = selfParent.Index()
+	} else if !p.isRootSelfParent(*event) {
 		return err
 	}
 
-	otherParent, err := p.Store.GetEventBlock(event.OtherParent())
-	if err != nil {
+	var otherParentCreatorID uint64
+	otherParentIndex := int64(-1)
+	otherParentHash := event.OtherParent()
+	if otherParent, err := p.Store.GetEventBlock(otherParentHash); err == nil {
+		otherParentCreator, ok := p.Participants.ReadByPubKey(otherParent.GetCreator())
+		if !ok {
+			return fmt.Errorf("creator %s not found", otherParent.GetCreator())
+		}
+		otherParentCreatorID, otherParentIndex = otherParentCreator.ID, otherParent.Index()
+	} else if root, ok := p.Store.RootsBySelfParent()[otherParentHash]; ok {
+		otherParentCreatorID = root.SelfParent.CreatorID
+	} else if !otherParentHash.Zero() {
 		return err
 	}
-	otherParentCreator, ok := p.Participants.ReadByPubKey(otherParent.GetCreator())
-	if !ok {
-		return fmt.Errorf("creator %s not found", otherParent.GetCreator())
-	}
 
-	event.SetWireInfo(selfParent.Index(),
-		otherParentCreator.ID,
-		otherParent.Index(),
+	event.SetWireInfo(selfParentIndex,
+		otherParentCreatorID,
+		otherParentIndex,
 		creator.ID)
 
 	return nil
@@ -805,6 +1120,41 @@ func (p *Poset) updatePendingRounds(decidedRounds map[int64]int64) {
 	}
 }
 
+// poolSignatures appends the block signatures to SigPool, but those it
+// holds already
+func (p *Poset) poolSignatures(signatures []*BlockSignature) {
+	if len(signatures) == 0 {
+		return
+	}
+	pooled := make(map[string]bool, len(p.SigPool))
+	for _, bs := range p.SigPool {
+		pooled[signatureKey(bs)] = true
+	}
+	for _, bs := range signatures {
+		if key := signatureKey(*bs); !pooled[key] {
+			pooled[key] = true
+			p.SigPool = append(p.SigPool, *bs)
+		}
+	}
+}
+
+// signatureKey identifies a block signature in SigPool
+func signatureKey(bs BlockSignature) string {
+	return fmt.Sprintf("%d/%X/%s", bs.Index, bs.Validator, bs.Signature)
+}
+
+// dropSignatures removes the signatures of the blocks up to index from
+// SigPool
+func (p *Poset) dropSignatures(index int64) {
+	var newSigPool []BlockSignature
+	for _, bs := range p.SigPool {
+		if bs.Index > index {
+			newSigPool = append(newSigPool, bs)
+		}
+	}
+	p.SigPool = newSigPool
+}
+
 // Remove processed Signatures from SigPool
 func (p *Poset) removeProcessedSignatures(processedSignatures map[int64]bool) {
 	var newSigPool []BlockSignature
@@ -820,9 +1170,68 @@ func (p *Poset) removeProcessedSignatures(processedSignatures map[int64]bool) {
 Public Methods
 *******************************************************************************/
 
+// insertion is an event insertion in progress: the batch of its store
+// writes, and the events it made consensus events, whose Poset bookkeeping
+// waits for the batch to be committed
+type insertion struct {
+	batch        *StoreBatch
+	accounted    []Event
+	audits       []AuditEntry // written to the audit sink once committed
+	decidedFrame int64        // highest frame of an Atropos it decided
+}
+
+func (p *Poset) newInsertion() *insertion {
+	return &insertion{batch: NewStoreBatch(p.Store), decidedFrame: FrameNIL}
+}
+
+// commitInsertion commits the writes of an insertion, then does the
+// bookkeeping of its consensus events
+func (p *Poset) commitInsertion(ins *insertion) error {
+	if err := p.Store.CommitBatch(ins.batch); err != nil {
+		return err
+	}
+	for i := range ins.accounted {
+		p.accounted(&ins.accounted[i])
+	}
+	p.setDecidedFrame(ins.decidedFrame)
+	for _, entry := range ins.audits {
+		p.fairness.countDecision(entry)
+		p.audit(entry)
+	}
+	return nil
+}
+
+// checkParentHashes refuses an event whose parents are not two EventHashes,
+// which SelfParent and OtherParent could not return
+func checkParentHashes(event Event) error {
+	parents := event.Message.Body.Parents
+	if len(parents) != 2 {
+		return fmt.Errorf("event has %d parents, expected 2", len(parents))
+	}
+	for _, parent := range parents {
+		if !IsValidEventHash(parent) {
+			return &InvalidEventHashError{Length: len(parent)}
+		}
+	}
+	return nil
+}
+
 // InsertEvent attempts to insert an Event in the DAG. It verifies the signature,
 // checks the dominators are known, and prevents the introduction of forks.
 func (p *Poset) InsertEvent(event Event, setWireInfo bool) error {
+	// a malformed event is refused before its signature is verified
+	if err := checkParentHashes(event); err != nil {
+		return err
+	}
+	if err := checkEventStructure(event); err != nil {
+		return err
+	}
+
+	// a known event is not verified again
+	if p.knownEvent(event) {
+		return ErrAlreadyKnown
+	}
+
 	// verify signature
 	if ok, err := event.Verify(); !ok {
 		if err != nil {
@@ -837,7 +1246,41 @@ func (p *Poset) InsertEvent(event Event, setWireInfo bool) error {
 			"hex":        hash.String(),
 		}).Debugf("Invalid Event signature")
 
-		return fmt.Errorf("invalid Event signature")
+		return ErrInvalidSignature
+	}
+
+	return p.InsertPreverifiedEvent(event, setWireInfo)
+}
+
+// InsertPreverifiedEvent inserts an event whose signature the caller already
+// verified, e.g. with VerifyEvents. The parents are checked as by InsertEvent.
+func (p *Poset) InsertPreverifiedEvent(event Event, setWireInfo bool) error {
+	if err := p.checkNetworkID(event); err != nil {
+		return err
+	}
+
+	if err := CheckEventVersion(event.Version()); err != nil {
+		return err
+	}
+
+	if err := checkParentHashes(event); err != nil {
+		return err
+	}
+
+	if err := checkEventStructure(event); err != nil {
+		return err
+	}
+
+	if p.knownEvent(event) {
+		return ErrAlreadyKnown
+	}
+
+	// the self-parent of another creator, or index, would otherwise only
+	// fail as not the last known event of the creator
+	if selfParent, err := p.Store.GetEventBlock(event.SelfParent()); err == nil {
+		if err := checkSelfParentStructure(event, selfParent); err != nil {
+			return err
+		}
 	}
 
 	if err := p.checkSelfParent(event); err != nil {
@@ -845,6 +1288,9 @@ func (p *Poset) InsertEvent(event Event, setWireInfo bool) error {
 	}
 
 	if err := p.checkOtherParent(event); err != nil {
+		if IsMissingParent(err) {
+			return err
+		}
 		return fmt.Errorf("CheckOtherParent: %s", err)
 	}
 
@@ -856,84 +1302,69 @@ func (p *Poset) InsertEvent(event Event, setWireInfo bool) error {
 		Frame     int64
 	)
 
+	// A parent missing from the store is only legitimate where the root
+	// stands for it, and then the zero Event, of frame 0, stands for the
+	// root. Any other miss, e.g. a parent evicted since the checks, must not
+	// let frames be computed from a zero Event.
 	parentEvent, errSelf := p.Store.GetEventBlock(event.SelfParent())
-	if errSelf != nil {
-		p.logger.Warnf("failed to get self parent: %s", errSelf)
+	if errSelf != nil && !p.isRootSelfParent(event) {
+		selfParent := event.SelfParent()
+		return fmt.Errorf("self-parent %s: %v", selfParent.String(), errSelf)
 	}
 	otherParentEvent, errOther := p.Store.GetEventBlock(event.OtherParent())
-	if errOther != nil {
-		p.logger.Warnf("failed to get other parent: %s", errOther)
+	if errOther != nil && !p.isRootOtherParent(event) {
+		return &MissingParentError{Parent: event.OtherParent()}
+	}
+	// after a Reset the frames go on from the one of its block, an event
+	// without other-parent stays in the frame of its self-parent as before
+	if errSelf != nil {
+		parentEvent.Frame = p.resetFrame
+	}
+	if op := event.OtherParent(); errOther != nil && !op.Zero() {
+		otherParentEvent.Frame = p.resetFrame
 	}
 
-	if parentEvent.Frame == otherParentEvent.Frame {
-		otherFlagTable, err := otherParentEvent.GetFlagTable()
-		if err != nil {
-			return fmt.Errorf("AddSelfEventBlock() otherParentEvent.GetFlagTable(): %v", err)
-		}
-
-//		parentFlagTable, err := parentEvent.GetFlagTable()
-//		if err != nil {
-//			return fmt.Errorf("AddSelfEventBlock() selfParentEvent.GetFlagTable(): %v", err)
-//		}
-
-		flagTable, err = parentEvent.MergeFlagTable(otherFlagTable, parentEvent.Frame)
-		if err != nil {
-			return fmt.Errorf("AddSelfEventBlock() parentEvent.MergeFlagTable(): %v", err)
-		}
-
-//		p.logger.WithFields(logrus.Fields{
-//			"p.GetSuperMajority()": p.GetSuperMajority(),
-//			"len(flagTable)": len(flagTable),
-//			"otherFlagTable": otherFlagTable,
-//			"selfFlagTable": parentFlagTable,
-//		}).Warnf("Majority")
-		
-		if uint64(len(flagTable)) >= p.GetSuperMajority() {
-			Root = true
-			Frame = parentEvent.Frame + 1
-			rootTable = flagTable.Copy()
-			flagTable = NewFlagTable()
-			// flagTable should be set to a new one containing this new root only
-			// new root should be added into poset.CheckClotho
-		} else {
-			Root = false
-			Frame = parentEvent.Frame
-		}
-	} else if parentEvent.Frame > otherParentEvent.Frame {
-		Root = false
-		Frame = parentEvent.Frame
-		flagTable, err = parentEvent.GetFlagTable()
-		if err != nil {
-			return fmt.Errorf("parentEvent.GetFlagTable(): %v", err)
-		}
-	} else {
-		Root = true
-		Frame = otherParentEvent.Frame
-		otherRoot, err := p.Store.GetClothoCreatorCheck(otherParentEvent.Frame, otherParentEvent.CreatorID())
-		if err != nil {
-			hash := otherParentEvent.Hash()
-			return fmt.Errorf("GetClothoCheck(otherParentEvent.Frame=%v, otherHead=%v): %v", otherParentEvent.Frame, hash.String(), err)
-		}
-
-		otherRootEvent, err := p.Store.GetEventBlock(otherRoot)
-		if err != nil {
-			p.logger.Warnf("failed to get other parent: %s", err)
-		}
-
-		otherRootTable, err := otherRootEvent.GetRootTable()
-		if err != nil {
-			return fmt.Errorf("otherRootEvent.GetFlagTable(): %v", err)
-		}
-
-		rootTable, err = parentEvent.MergeFlagTable(otherRootTable, Frame - 1)
-		if err != nil {
-			return fmt.Errorf("AddSelfEventBlock() parentEvent.MergeFlagTable(otherRootTable): %v", err)
-		}
+	selfTable, err := p.flagTableOf(&parentEvent)
+	if err != nil {
+		return fmt.Errorf("parentEvent.GetFlagTable(): %v", err)
+	}
+	otherTable, err := p.flagTableOf(&otherParentEvent)
+	if err != nil {
+		return fmt.Errorf("otherParentEvent.GetFlagTable(): %v", err)
+	}
+	Frame, Root, flagTable, rootTable, err = eventFrame(frameParents{
+		selfFrame:  parentEvent.Frame,
+		selfTable:  selfTable,
+		otherFrame: otherParentEvent.Frame,
+		otherTable: otherTable,
+		otherRootTable: func() (FlagTable, error) {
+			otherRoot, err := p.Store.GetClothoCreatorCheck(otherParentEvent.Frame, otherParentEvent.CreatorID())
+			if err != nil {
+				hash := otherParentEvent.Hash()
+				return nil, fmt.Errorf("GetClothoCheck(otherParentEvent.Frame=%v, otherHead=%v): %v", otherParentEvent.Frame, hash.String(), err)
+			}
+			otherRootEvent, err := p.Store.GetEventBlock(otherRoot)
+			if err != nil {
+				p.warnLimiter.Warnf(p.logger, "failed to get other parent: %s", err)
+			}
+			table, err := p.rootTableOf(&otherRootEvent)
+			if err != nil {
+				return nil, fmt.Errorf("otherRootEvent.GetFlagTable(): %v", err)
+			}
+			return table, nil
+		},
+		first: errSelf != nil,
+	}, p.GetSuperMajority())
+	if err != nil {
+		return err
+	}
 
-		flagTable, err = otherParentEvent.GetFlagTable()
-		if err != nil {
-			return fmt.Errorf("otherParentEvent.GetFlagTable(): %v", err)
-		}
+	var selfParent *Event
+	if errSelf == nil {
+		selfParent = &parentEvent
+	}
+	if err := p.checkEventCap(event, selfParent, Frame); err != nil {
+		return err
 	}
 
 	event.Root = Root
@@ -943,10 +1374,17 @@ func (p *Poset) InsertEvent(event Event, setWireInfo bool) error {
 	event.Frame = Frame
 	event.FlagTableBytes = flagTable.Marshal()
 	event.RootTableBytes = rootTable.Marshal()
-	if event.GetLamportTimestamp() == LamportTimestampNIL {
+	p.forgetTables(event.Hash())
+	if !event.HasLamport() {
 
-		plt := parentEvent.GetLamportTimestamp()
-		opLT := otherParentEvent.GetLamportTimestamp()
+		plt, err := p.parentLamportTimestamp(event, event.SelfParent(), &parentEvent, errSelf)
+		if err != nil {
+			return err
+		}
+		opLT, err := p.parentLamportTimestamp(event, event.OtherParent(), &otherParentEvent, errOther)
+		if err != nil {
+			return err
+		}
 		if opLT > plt {
 			plt = opLT
 		}
@@ -976,7 +1414,9 @@ func (p *Poset) InsertEvent(event Event, setWireInfo bool) error {
 //	}).Warnf("InsertEvent")
 
 	
-	event.Message.TopologicalIndex = p.NextTopologicalIndex()
+	// the index is only taken once the event is in the store
+	topologicalIndex := p.peekTopologicalIndex()
+	event.Message.TopologicalIndex = topologicalIndex
 
 	if setWireInfo {
 		if err := p.setWireInfo(&event); err != nil {
@@ -984,31 +1424,61 @@ func (p *Poset) InsertEvent(event Event, setWireInfo bool) error {
 		}
 	}
 
-	if err := p.Store.SetEvent(event); err != nil {
+	// The writes of the insertion are gathered in a batch and committed
+	// once every record derived from the event is computed, so that the
+	// event is either whole in the store or absent from it. The Poset
+	// bookkeeping follows the commit.
+	ins := p.newInsertion()
+	if err := ins.batch.SetEvent(event); err != nil {
 		return fmt.Errorf("SetEvent: %s", err)
 	}
+	ins.audits = append(ins.audits, auditEvent(AuditEventAccepted, &event))
 
-	err = p.Store.SetRoundCreated(Frame, RoundCreated{}) // FIXME: SetRoundCreated/SetRoundReceived should be abandoned in favour of SetRound.
-	if err != nil {
+	// the round of the frame is known from here on, without clearing what
+	// DivideRounds already put in it
+	if _, err := ins.batch.GetRound(Frame); common.Is(err, common.KeyNotFound) {
+		if err := ins.batch.SetRound(Frame, *NewRound()); err != nil {
+			return err
+		}
+	} else if err != nil {
 		return err
 	}
 
 
+	// a root of a frame final already, e.g. one built on the events of a node
+	// which joined late, is no Clotho of it and votes for none
+	lateRoot := false
 	if Root {
-		if err := p.Store.AddClothoCheck(Frame, event.CreatorID(), event.Hash()); err != nil {
-			// FIXME: add error handling here
-			panic(err)
+		if err := ins.batch.AddClothoCheck(Frame, event.CreatorID(), event.Hash()); err != nil {
+			return fmt.Errorf("AddClothoCheck(newHead): %v", err)
 		}
-		if err := p.ClothoChecking(&event); err != nil {
-			return fmt.Errorf("CheckClotho(newHead):%v", err)
+		if err := ins.batch.NewTimeTable(Frame, event.Hash()); common.Is(err, common.TooLate) {
+			lateRoot = true
+		} else if err != nil {
+			return fmt.Errorf("NewTimeTable(newHead): %v", err)
 		}
-		if err := p.AtroposTimeSelection(&event); err != nil {
-			return fmt.Errorf("AtroposTimeSelection(newHead):%v", err)
+		if p.rootQueue == nil && !lateRoot {
+			if err := p.clothoChecking(ins, &event); err != nil {
+				return fmt.Errorf("CheckClotho(newHead):%v", err)
+			}
+			if err := p.atroposTimeSelection(ins, &event); err != nil {
+				return fmt.Errorf("AtroposTimeSelection(newHead):%v", err)
+			}
 		}
 	}
 
+	if err := p.commitInsertion(ins); err != nil {
+		return fmt.Errorf("CommitBatch: %v", err)
+	}
+	p.setTopologicalIndex(topologicalIndex + 1)
+	p.checkClockSkew(&event)
+	if Root && !lateRoot && p.rootQueue != nil {
+		p.queueRoot(event)
+	}
+
 	p.undeterminedEventsLocker.Lock()
 	p.UndeterminedEvents = append(p.UndeterminedEvents, event.Hash())
+	p.undivided = append(p.undivided, event.Hash())
 	p.undeterminedEventsLocker.Unlock()
 
 	if event.IsLoaded() {
@@ -1017,25 +1487,25 @@ func (p *Poset) InsertEvent(event Event, setWireInfo bool) error {
 		p.pendingLoadedEventsLocker.Unlock()
 	}
 
-	blockSignatures := make([]BlockSignature, len(event.BlockSignatures()))
-	for i, v := range event.BlockSignatures() {
-		blockSignatures[i] = *v
-	}
-	p.SigPool = append(p.SigPool, blockSignatures...)
+	p.poolSignatures(event.BlockSignatures())
 
 	return nil
 }
 
 /*
-DivideRounds assigns a Round and LamportTimestamp to Events, and flags them as
-clothos if necessary. Pushes Rounds in the PendingRounds queue if necessary.
+DivideRounds assigns a Round and LamportTimestamp to the Events inserted since
+the last call, and flags them as clothos if necessary. Pushes Rounds in the
+PendingRounds queue if necessary.
 */
 func (p *Poset) DivideRounds() error {
 
-	p.undeterminedEventsLocker.RLock()
-	defer p.undeterminedEventsLocker.RUnlock()
-
-	for _, hash := range p.UndeterminedEvents {
+	// the events left by an error are divided on the next call
+	undivided, epoch := p.takeUndivided()
+	divided := 0
+	defer func() {
+		p.requeueUndivided(undivided[divided:], epoch)
+	}()
+	for _, hash := range undivided {
 
 		ev, err := p.Store.GetEventBlock(hash)
 		if err != nil {
@@ -1048,7 +1518,7 @@ func (p *Poset) DivideRounds() error {
 		   Compute Event's round, update the corresponding Round object, and
 		   add it to the PendingRounds queue if necessary.
 		*/
-		if ev.GetRound() == FrameNIL {
+		if !ev.HasRound() {
 
 			roundNumber, err := p.round(hash)
 			if err != nil {
@@ -1058,15 +1528,15 @@ func (p *Poset) DivideRounds() error {
 			ev.SetRound(roundNumber)
 			updateEvent = true
 
-			roundCreated, err := p.Store.GetRoundCreated(roundNumber)
+			roundInfo, err := p.Store.GetRound(roundNumber)
 			if err != nil && !common.Is(err, common.KeyNotFound) {
 				return err
 			}
 
 			p.logger.WithFields(logrus.Fields{
-				"hash":         hash,
-				"roundNumber":  roundNumber,
-				"roundCreated": roundCreated,
+				"hash":        hash,
+				"roundNumber": roundNumber,
+				"round":       roundInfo,
 			}).Debug("p.DivideRounds()")
 
 			/*
@@ -1081,49 +1551,54 @@ func (p *Poset) DivideRounds() error {
 				other Events to be added on top, but the base layer must not be
 				reprocessed.
 			*/
-			if !roundCreated.Message.Queued && roundNumber >= p.GetLastConsensusRound() {
+			if !roundInfo.Message.Queued && roundNumber >= p.GetLastConsensusRound() {
 
 				p.PendingRounds = append(p.PendingRounds, &pendingRound{roundNumber, false})
-				roundCreated.Message.Queued = true
+				roundInfo.Message.Queued = true
 			}
 
 			clotho, err := p.clotho(hash)
 			if err != nil {
 				return err
 			}
-			roundCreated.AddEvent(hash, clotho)
+			roundInfo.AddEvent(hash, clotho)
 
-			err = p.Store.SetRoundCreated(roundNumber, roundCreated)
+			err = p.Store.SetRound(roundNumber, roundInfo)
 			if err != nil {
 				return err
 			}
 
 			if clotho {
 				// if event is self head
-				if p.core != nil && ev.Hash() == p.core.Head() &&
-					ev.GetCreator() == p.core.HexID() {
+				if p.isSelfHead(ev) {
 
-					replaceFlagTable := func(event *Event, round int64) {
+					replaceFlagTable := func(event *Event, round int64) error {
 						ft := make(FlagTable)
 //						ws := p.Store.RoundClothos(round)
 //						for _, v := range ws {
 							//ft[v] = 1
 //						}
 						if err := event.ReplaceFlagTable(ft); err != nil {
-							p.logger.Fatal(err)
+							return err
 						}
+						p.forgetTables(event.Hash())
+						return nil
 					}
 
 					// special case
 					if ev.GetRound() == 0 {
-						replaceFlagTable(&ev, 0)
+						if err := replaceFlagTable(&ev, 0); err != nil {
+							return err
+						}
 //						root, err := p.Store.GetRoot(ev.GetCreator())
 //						if err != nil {
 //							return err
 //						}
 //						ev.Message.ClothoProof = [][]byte{root.SelfParent.Hash}
 					} else {
-						replaceFlagTable(&ev, ev.GetRound())
+						if err := replaceFlagTable(&ev, ev.GetRound()); err != nil {
+							return err
+						}
 //						roots := p.Store.RoundClothos(ev.GetRound() - 1)
 //						ev.Message.ClothoProof = roots.Bytes()
 					}
@@ -1134,7 +1609,7 @@ func (p *Poset) DivideRounds() error {
 		/*
 			Compute the Event's LamportTimestamp
 		*/
-		if ev.GetLamportTimestamp() == LamportTimestampNIL {
+		if !ev.HasLamport() {
 
 			lamportTimestamp, err := p.lamportTimestamp(hash)
 			if err != nil {
@@ -1148,28 +1623,34 @@ func (p *Poset) DivideRounds() error {
 		if updateEvent {
 			if ev.CreatorID() == 0 {
 				if err := p.setWireInfo(&ev); err != nil {
-					p.logger.Fatal(err)
+					return err
 				}
 			}
 			if err := p.Store.SetEvent(ev); err != nil {
-				p.logger.Fatal(err)
+				return err
 			}
 		}
+		divided++
 	}
 
 	return nil
 }
 
-// DecideAtropos decides if clothos are atropos
+// DecideAtropos decides if clothos are atropos. The votes of the clothos
+// still undecided are kept for the next call, which only counts the votes of
+// the clothos it has not seen yet. A vote depends on the ancestors of the
+// voter alone, so it does not change once counted.
 func (p *Poset) DecideAtropos() error {
 
-	// Initialize the vote map
-	votes := make(map[EventHash]map[EventHash]bool) // [x][y]=>vote(x,y)
-	setVote := func(votes map[EventHash]map[EventHash]bool, x, y EventHash, vote bool) {
-		if votes[x] == nil {
-			votes[x] = make(map[EventHash]bool)
+	// Forget the votes of the rounds no longer pending
+	pending := make(map[int64]bool, len(p.PendingRounds))
+	for _, r := range p.PendingRounds {
+		pending[r.Index] = true
+	}
+	for r := range p.atroposVotes {
+		if !pending[r] {
+			delete(p.atroposVotes, r)
 		}
-		votes[x][y] = vote
 	}
 
 	decidedRounds := map[int64]int64{} // [round number] => index in p.PendingRounds
@@ -1177,24 +1658,39 @@ func (p *Poset) DecideAtropos() error {
 
 	for pos, r := range p.PendingRounds {
 		roundIndex := r.Index
-		roundInfo, err := p.Store.GetRoundCreated(roundIndex)
+		roundInfo, err := p.Store.GetRound(roundIndex)
 		if err != nil {
 			return err
 		}
+		roundVotes := p.atroposVotes[roundIndex]
+		if roundVotes == nil {
+			roundVotes = make(map[EventHash]map[EventHash]bool)
+			p.atroposVotes[roundIndex] = roundVotes
+		}
 		for _, x := range roundInfo.Clotho() {
 			if roundInfo.IsDecided(x) {
+				delete(roundVotes, x)
 				continue
 			}
+			votes := roundVotes[x] // [y] => vote(y, x)
+			if votes == nil {
+				votes = make(map[EventHash]bool)
+				roundVotes[x] = votes
+			}
 		VoteLoop:
 			for j := roundIndex + 1; j <= p.Store.LastRound(); j++ {
 				for _, y := range p.Store.RoundClothos(j) {
+					if _, ok := votes[y]; ok {
+						// counted by an earlier call, which did not decide
+						continue
+					}
 					diff := j - roundIndex
 					if diff == 1 {
 						ycx, err := p.dominated(y, x)
 						if err != nil {
 							return err
 						}
-						setVote(votes, y, x, ycx)
+						votes[y] = ycx
 					} else {
 						// count votes
 						var ssClotho []EventHash
@@ -1210,7 +1706,7 @@ func (p *Poset) DecideAtropos() error {
 						yays := uint64(0)
 						nays := uint64(0)
 						for _, w := range ssClotho {
-							if votes[w][x] {
+							if votes[w] {
 								yays++
 							} else {
 								nays++
@@ -1227,30 +1723,35 @@ func (p *Poset) DecideAtropos() error {
 						if math.Mod(float64(diff), float64(c)) > 0 {
 							if t >= p.GetSuperMajority() {
 								roundInfo.SetAtropos(x, v)
-								setVote(votes, y, x, v)
+								p.decided(roundIndex, x)
+								votes[y] = v
 								break VoteLoop // break out of j loop
 							} else {
-								setVote(votes, y, x, v)
+								votes[y] = v
 							}
 						} else { // coin round
 							if t >= p.GetSuperMajority() {
-								setVote(votes, y, x, v)
+								votes[y] = v
 							} else {
-								setVote(votes, y, x, randomShift(y)) // middle bit of y's hash
+								votes[y] = CoinFlip(j, x, y)
 							}
 						}
 					}
 				}
 			}
+			if roundInfo.IsDecided(x) {
+				delete(roundVotes, x)
+			}
 		}
 
-		err = p.Store.SetRoundCreated(roundIndex, roundInfo)
+		err = p.Store.SetRound(roundIndex, roundInfo)
 		if err != nil {
 			return err
 		}
 
 		if roundInfo.ClothoDecided() {
 			decidedRounds[roundIndex] = int64(pos)
+			delete(p.atroposVotes, roundIndex)
 		}
 	}
 
@@ -1258,13 +1759,28 @@ func (p *Poset) DecideAtropos() error {
 	return nil
 }
 
-// DecideRoundReceived assigns a RoundReceived to undetermined events when they
-// reach consensus
-func (p *Poset) DecideRoundReceived() error {
+// decided tells the decision listener about a clotho decided
+func (p *Poset) decided(round int64, x EventHash) {
+	if p.decisionListener == nil {
+		return
+	}
+	ev, err := p.Store.GetEventBlock(x)
+	if err != nil {
+		p.logger.WithError(err).Debug("decided(round int64, x EventHash)")
+		return
+	}
+	p.decisionListener(round, ev.GetCreator())
+}
 
-	p.undeterminedEventsLocker.Lock()
-	defer p.undeterminedEventsLocker.Unlock()
+// DecideRoundReceived assigns a RoundReceived to undetermined events when they
+// reach consensus
+func (p *Poset) DecideRoundReceived() error {
+
+	p.decideRoundReceivedLocker.Lock()
+	defer p.decideRoundReceivedLocker.Unlock()
 
+	// scan a snapshot so that InsertEvent can keep appending meanwhile
+	undetermined, epoch := p.undeterminedSnapshot()
 	var newUndeterminedEvents []EventHash
 
 	/* From whitepaper - 18/03/18
@@ -1273,111 +1789,210 @@ func (p *Poset) DecideRoundReceived() error {
 	*/
 
 	pendingRoundReceived := map[int64]bool{}
+	ages := p.newUndeterminedAges()
+	var archived EventHashes
 
-	for _, x := range p.UndeterminedEvents {
-
-		received := false
-		r, err := p.round(x)
+	for _, x := range undetermined {
+		received, u, err := p.decideReceived(x, pendingRoundReceived)
 		if err != nil {
 			return err
 		}
+		if received {
+			continue
+		}
+		if age := ages.observe(x, u); p.undeterminedArchiveAge > 0 &&
+			age > p.undeterminedArchiveAge {
+			archived = append(archived, x)
+			continue
+		}
+		newUndeterminedEvents = append(newUndeterminedEvents, x)
+	}
 
-		for i := r + 1; i <= p.Store.LastRound(); i++ {
+	// a Reset during the scan cleared the queues and the archive, the
+	// results of the scan belong to the poset before it
+	if !p.undeterminedEpochIs(epoch) {
+		return nil
+	}
+	if err := p.updateArchive(archived, pendingRoundReceived, ages); err != nil {
+		return err
+	}
 
-			tr, err := p.Store.GetRoundCreated(i)
-			if err != nil {
-				// Can happen after a Reset/FastSync
-				if r < p.GetLastConsensusRound() {
-					received = true
-					break
-				}
-				return err
-			}
+	p.undeterminedEventsLocker.Lock()
+	if p.undeterminedEventsEpoch != epoch {
+		p.undeterminedEventsLocker.Unlock()
+		return nil
+	}
+	for i := range pendingRoundReceived {
+		p.PendingRoundReceived = append(p.PendingRoundReceived, i)
+	}
+	sort.Sort(p.PendingRoundReceived)
+	// keep the events inserted during the scan, in order
+	p.UndeterminedEvents = append(newUndeterminedEvents,
+		p.UndeterminedEvents[len(undetermined):]...)
+	p.undeterminedEventsLocker.Unlock()
 
-			// We are looping from earlier to later rounds; so if we encounter
-			// one round with undecided clothos, we are sure that this event
-			// is not "received". Break out of i loop
-			if !(tr.ClothoDecided()) {
-				break
-			}
+	p.undeterminedDiagnostics(ages)
 
-			fws := tr.Atropos()
-			// set of atropos that domniates x
-			var s []EventHash
-			for _, w := range fws {
-				domniates, err := p.dominated(w, x)
-				if err != nil {
-					return err
-				}
-				if domniates {
-					s = append(s, w)
-				}
-			}
+	return nil
+}
 
-			if len(s) == len(fws) && len(s) > 0 {
+// decideReceived assigns a RoundReceived to an undetermined event if it
+// reached consensus, adding the round to pendingRoundReceived. Otherwise it
+// returns why the event is still undetermined.
+func (p *Poset) decideReceived(x EventHash, pendingRoundReceived map[int64]bool) (bool, undecided, error) {
+	r, err := p.round(x)
+	if err != nil {
+		return false, undecided{}, err
+	}
+	u := undecided{round: r, blocking: -1}
 
-				received = true
+	for i := r + 1; i <= p.Store.LastRound(); i++ {
 
-				ex, err := p.Store.GetEventBlock(x)
-				if err != nil {
-					return err
-				}
-				ex.SetRoundReceived(i)
+		tr, err := p.Store.GetRound(i)
+		if err != nil {
+			// Can happen after a Reset/FastSync
+			if r < p.GetLastConsensusRound() {
+				return true, u, nil
+			}
+			return false, u, err
+		}
 
-				err = p.Store.SetEvent(ex)
-				if err != nil {
-					return err
-				}
+		// We are looping from earlier to later rounds; so if we encounter
+		// one round with undecided clothos, we are sure that this event
+		// is not "received". Break out of i loop
+		if !(tr.ClothoDecided()) {
+			u.blocking = i
+			break
+		}
 
-				tr.SetConsensusEvent(x)
-				roundReceived, err := p.Store.GetRoundReceived(i)
-				if err != nil {
-					roundReceived = *NewRoundReceived()
-				}
+		fws := tr.Atropos()
+		// set of atropos that domniates x
+		var s []EventHash
+		for _, w := range fws {
+			domniates, err := p.dominated(w, x)
+			if err != nil {
+				return false, u, err
+			}
+			if domniates {
+				s = append(s, w)
+			}
+		}
 
-				roundReceived.Rounds = append(roundReceived.Rounds, x.Bytes())
+		if len(s) == len(fws) && len(s) > 0 {
 
-				err = p.Store.SetRoundReceived(i, roundReceived)
-				if err != nil {
-					return err
-				}
+			ex, err := p.Store.GetEventBlock(x)
+			if err != nil {
+				return false, u, err
+			}
+			ex.SetRoundReceived(i)
 
-				pendingRoundReceived[i] = true
+			err = p.Store.SetEvent(ex)
+			if err != nil {
+				return false, u, err
+			}
 
-				// break out of i loop
-				break
+			// x is received in the round i, which records it along with
+			// the events created in it
+			tr.SetConsensusEvent(x)
+			tr.AddReceived(x)
+			err = p.Store.SetRound(i, tr)
+			if err != nil {
+				return false, u, err
 			}
 
-		}
+			pendingRoundReceived[i] = true
 
-		if !received {
-			newUndeterminedEvents = append(newUndeterminedEvents, x)
+			return true, u, nil
 		}
-	}
 
-	for i := range pendingRoundReceived {
-		p.PendingRoundReceived = append(p.PendingRoundReceived, i)
 	}
 
-	sort.Sort(p.PendingRoundReceived)
+	return false, u, nil
+}
 
-	p.UndeterminedEvents = newUndeterminedEvents
+// undeterminedSnapshot returns the UndeterminedEvents at this point and the
+// epoch they belong to. The queue is only ever appended to in place, so the
+// snapshot stays valid without holding the lock.
+func (p *Poset) undeterminedSnapshot() (EventHashes, uint64) {
+	p.undeterminedEventsLocker.RLock()
+	defer p.undeterminedEventsLocker.RUnlock()
+	n := len(p.UndeterminedEvents)
+	return p.UndeterminedEvents[:n:n], p.undeterminedEventsEpoch
+}
 
-	return nil
+// undeterminedEpochIs returns true when UndeterminedEvents was not cleared
+// since the given epoch
+func (p *Poset) undeterminedEpochIs(epoch uint64) bool {
+	p.undeterminedEventsLocker.RLock()
+	defer p.undeterminedEventsLocker.RUnlock()
+	return p.undeterminedEventsEpoch == epoch
+}
+
+// takeUndivided returns the events inserted since the last DivideRounds and
+// the epoch they belong to, and empties their queue
+func (p *Poset) takeUndivided() (EventHashes, uint64) {
+	p.undeterminedEventsLocker.Lock()
+	defer p.undeterminedEventsLocker.Unlock()
+	undivided := p.undivided
+	p.undivided = nil
+	return undivided, p.undeterminedEventsEpoch
+}
+
+// requeueUndivided puts events back in front of the queue of DivideRounds,
+// unless the queue was cleared since they were taken from it
+func (p *Poset) requeueUndivided(hashes EventHashes, epoch uint64) {
+	if len(hashes) == 0 {
+		return
+	}
+	p.undeterminedEventsLocker.Lock()
+	defer p.undeterminedEventsLocker.Unlock()
+	if p.undeterminedEventsEpoch != epoch {
+		return
+	}
+	p.undivided = append(append(EventHashes{}, hashes...), p.undivided...)
 }
 
 // ProcessDecidedRounds takes Rounds whose clothos are decided, computes the
 // corresponding Frames, maps them into Blocks, and commits the Blocks via the
 // commit channel
 func (p *Poset) ProcessDecidedRounds() error {
+	// the frames are only final once the queued roots are decided
+	if err := p.ProcessRootQueue(); err != nil {
+		return err
+	}
 
 	p.DecidedLocker.Lock()
 	defer p.DecidedLocker.Unlock()
 
-	for p.Store.CheckFrameFinality(p.nextFinalFrame) {
+	for p.frameFinal(p.nextFinalFrame) {
 		if p.commitCh != nil {
 //			p.Store.ProcessOutFrame(p.nextFinalFrame, p.commitCh) // FIXME: to be implemented
-			txs, err := p.Store.ProcessOutFrame(p.nextFinalFrame, p.Address())
+			frameTxs, err := p.Store.ProcessOutFrame(p.nextFinalFrame)
+			if err != nil {
+				return err
+			}
+			creatorTxs, err := creatorTransactions(p.Store, p.nextFinalFrame)
+			if err != nil {
+				return err
+			}
+			for creator, n := range creatorTxs {
+				p.fairness.add(creator, FairnessCounts{Transactions: n})
+			}
+			// the block of a final frame has its index, the transactions
+			// beyond the budget wait for the block of the next one
+			txs, carry := p.blockBudget.take(p.blockCarry, frameTxs)
+			if err := p.setBlockCarry(p.nextFinalFrame, carry); err != nil {
+				return err
+			}
+			createdTime, err := p.finalFrameTime(p.nextFinalFrame)
+			if err != nil {
+				return err
+			}
+			frame, err := p.makeFinalFrame(p.nextFinalFrame)
+			if err != nil {
+				return err
+			}
+			frameHash, err := frame.Hash()
 			if err != nil {
 				return err
 			}
@@ -1386,15 +2001,22 @@ func (p *Poset) ProcessDecidedRounds() error {
 				RoundReceived: p.nextFinalFrame,
 				Transactions:  txs,
 			}
-			p.commitCh <- Block{
+			block := Block{
 				Body:        &body,
-				FrameHash:   []byte{},
+				FrameHash:   frameHash,
 				Signatures:  make(map[string]string),
-				CreatedTime: time.Now().Unix(),
+				CreatedTime: createdTime,
 			}
+			p.audit(auditBlock(block))
+			p.commitCh <- block
 //			p.commitCh <- block
 		}
+		p.archiveFrame(p.nextFinalFrame)
 		p.nextFinalFrame++
+		// the votes of final frames are no longer needed
+		if err := p.Store.DropTimeTables(p.nextFinalFrame); err != nil {
+			return err
+		}
 	}
 
 	// Defer removing processed Rounds from the PendingRounds Queue
@@ -1440,7 +2062,7 @@ func (p *Poset) ProcessDecidedRounds() error {
 			return fmt.Errorf("getting Frame %d: %v", r, err)
 		}
 
-		// round, err := p.Store.GetRoundReceived(r)
+		// round, err := p.Store.GetRound(r)
 		// if err != nil {
 		// 	return err
 		// }
@@ -1450,10 +2072,13 @@ func (p *Poset) ProcessDecidedRounds() error {
 			"roots":          frame.Roots,
 		}).Debugf("Processing Decided Round")
 
+		var events []Event
 		if len(frame.Events) > 0 {
 
+			events = make([]Event, 0, len(frame.Events))
 			for _, e := range frame.Events {
 				ev := e.ToEvent()
+				events = append(events, ev)
 				err := p.Store.AddConsensusEvent(ev)
 				if err != nil {
 					return err
@@ -1461,6 +2086,7 @@ func (p *Poset) ProcessDecidedRounds() error {
 				p.consensusTransactionsLocker.Lock()
 				p.ConsensusTransactions += uint64(len(ev.Transactions()))
 				p.consensusTransactionsLocker.Unlock()
+				p.fairness.add(ev.GetCreator(), FairnessCounts{Transactions: uint64(len(ev.Transactions()))})
 				if ev.IsLoaded() {
 					p.pendingLoadedEventsLocker.Lock()
 					p.pendingLoadedEvents--
@@ -1468,23 +2094,31 @@ func (p *Poset) ProcessDecidedRounds() error {
 				}
 			}
 
-			lastBlockIndex := p.Store.LastBlockIndex()
-			block, err := NewBlockFromFrame(lastBlockIndex+1, frame)
+		} else {
+			p.logger.Debugf("No Events to commit for ConsensusRound %d", r)
+		}
+
+		// as for final frames, the transactions beyond the budget of the
+		// block carry over into the block of the next round
+		txs, carry := p.blockBudget.take(p.blockCarry, frameTransactions(frame))
+		if len(txs) > 0 {
+			frameHash, err := frame.Hash()
 			if err != nil {
 				return err
 			}
-			if len(block.Transactions()) > 0 {
-				if err := p.Store.SetBlock(block); err != nil {
-					return err
-				}
-
-				if p.commitCh != nil {
-					p.commitCh <- block
-				}
+			block := NewBlock(p.Store.LastBlockIndex()+1, frame.Round, frameHash, txs)
+			block.CreatedTime = p.blockTime(events)
+			if err := p.Store.SetBlock(block); err != nil {
+				return err
 			}
+			p.audit(auditBlock(block))
 
-		} else {
-			p.logger.Debugf("No Events to commit for ConsensusRound %d", r)
+			if p.commitCh != nil {
+				p.commitCh <- block
+			}
+		}
+		if err := p.setBlockCarry(frame.Round, carry); err != nil {
+			return err
 		}
 
 		processedIndex++
@@ -1498,6 +2132,37 @@ func (p *Poset) ProcessDecidedRounds() error {
 	return nil
 }
 
+// RunToQuiescence runs the consensus pipeline, DivideRounds, DecideAtropos,
+// DecideRoundReceived then ProcessDecidedRounds, until a pass neither makes a
+// frame final nor adds consensus events. Without a node to drive it, this is
+// how tests and offline tools bring a Poset up to date after inserting events.
+func (p *Poset) RunToQuiescence() error {
+	for {
+		p.DecidedLocker.Lock()
+		frame := p.nextFinalFrame
+		p.DecidedLocker.Unlock()
+		count := p.Store.ConsensusEventsCount()
+
+		for _, step := range []func() error{
+			p.DivideRounds,
+			p.DecideAtropos,
+			p.DecideRoundReceived,
+			p.ProcessDecidedRounds,
+		} {
+			if err := step(); err != nil {
+				return err
+			}
+		}
+
+		p.DecidedLocker.Lock()
+		done := frame == p.nextFinalFrame
+		p.DecidedLocker.Unlock()
+		if done && count == p.Store.ConsensusEventsCount() {
+			return nil
+		}
+	}
+}
+
 // GetFrame returns the Frame corresponding to a RoundReceived.
 func (p *Poset) GetFrame(roundReceived int64) (Frame, error) {
 	// Try to get it from the Store first
@@ -1512,29 +2177,75 @@ func (p *Poset) GetFrame(roundReceived int64) (Frame, error) {
 // MakeFrame computes the Frame corresponding to a RoundReceived.
 func (p *Poset) MakeFrame(roundReceived int64) (Frame, error) {
 	// Get the Round and corresponding consensus Events
-	round, err := p.Store.GetRoundReceived(roundReceived)
+	round, err := p.Store.GetRound(roundReceived)
+	if err != nil {
+		return Frame{}, err
+	}
+
+	events, err := p.receivedEvents(roundReceived, round)
 	if err != nil {
 		return Frame{}, err
 	}
 
+	sort.Stable(ByLamportTimestamp(events))
+
+	stateHash, err := p.ApplyInternalTransactions(roundReceived, events)
+	if err != nil {
+		return Frame{}, err
+	}
+	return p.makeFrame(roundReceived, events, stateHash.Bytes())
+}
+
+// receivedEvents returns the events received in a round. The ones of an
+// archived frame are read from the archive at once.
+func (p *Poset) receivedEvents(roundReceived int64, round Round) ([]Event, error) {
+	if archive, ok := p.Store.(FrameArchive); ok {
+		first, last, err := archive.ArchivedFrames()
+		if err != nil {
+			return nil, err
+		}
+		if first <= roundReceived && roundReceived <= last {
+			var events []Event
+			err := archive.ForEachFrameEvent(roundReceived, roundReceived, func(e Event) bool {
+				events = append(events, e)
+				return true
+			})
+			return events, err
+		}
+	}
 	var events []Event
-	for _, eh := range round.Rounds {
-		var hash EventHash
-		hash.Set(eh)
+	for _, hash := range round.ReceivedEvents() {
 		e, err := p.Store.GetEventBlock(hash)
 		if err != nil {
-			return Frame{}, err
+			return nil, err
 		}
 		events = append(events, e)
 	}
+	return events, nil
+}
 
-	sort.Stable(ByLamportTimestamp(events))
-
-	stateHash, err := p.ApplyInternalTransactions(roundReceived, events)
+// makeFinalFrame computes the Frame of a final frame from the events its
+// block is made of, and stores it
+func (p *Poset) makeFinalFrame(frame int64) (Frame, error) {
+	hashes, err := p.Store.EventsByRoundRange(frame, frame)
 	if err != nil {
 		return Frame{}, err
 	}
+	events := make([]Event, 0, len(hashes))
+	for _, hash := range hashes {
+		e, err := p.Store.GetEventBlock(hash)
+		if err != nil {
+			return Frame{}, err
+		}
+		events = append(events, e)
+	}
+	sort.Sort(ByFinalOrder(events))
+	return p.makeFrame(frame, events, nil)
+}
 
+// makeFrame makes the Frame of a round from its events in topological
+// order, with the Roots a reset Poset needs to insert them, and stores it
+func (p *Poset) makeFrame(roundReceived int64, events []Event, stateHash []byte) (Frame, error) {
 	// Get/Create Roots
 	roots := make(map[string]Root)
 	// The events are in topological order. Each time we run into the first Event
@@ -1606,7 +2317,10 @@ func (p *Poset) MakeFrame(roundReceived int64) (Frame, error) {
 				}
 			}
 		}
-		eventMessages[i] = ev.Message
+		// the topological index is local, the frame is the same on every node
+		msg := *ev.Message
+		msg.TopologicalIndex = 0
+		eventMessages[i] = &msg
 	}
 
 	// order roots
@@ -1621,7 +2335,7 @@ func (p *Poset) MakeFrame(roundReceived int64) (Frame, error) {
 		Round:     roundReceived,
 		Roots:     orderedRoots,
 		Events:    eventMessages,
-		StateHash: stateHash.Bytes(),
+		StateHash: stateHash,
 	}
 
 	if err := p.Store.SetFrame(res); err != nil {
@@ -1735,7 +2449,7 @@ func (p *Poset) ProcessSigPool() error {
 			}
 
 			if err := block.SetSignature(bs); err != nil {
-				p.logger.Fatal(err)
+				return err
 			}
 
 			if err := p.Store.SetBlock(block); err != nil {
@@ -1744,6 +2458,14 @@ func (p *Poset) ProcessSigPool() error {
 					"msg":   err,
 				}).Warning("Saving Block")
 			}
+			index := block.Index()
+			p.audit(AuditEntry{
+				Kind:       AuditBlockSignature,
+				Round:      block.RoundReceived(),
+				Block:      &index,
+				Signatures: len(block.Signatures),
+				Validator:  validatorHex,
+			})
 
 			if uint64(len(block.Signatures)) > p.GetTrustCount() &&
 				(p.AnchorBlock == nil ||
@@ -1786,44 +2508,55 @@ func (p *Poset) GetAnchorBlockWithFrame() (Block, Frame, error) {
 
 // Reset clears the Poset and resets it from a new base.
 func (p *Poset) Reset(block Block, frame Frame) error {
+	// the roots of a frame from a peer are checked before any state is lost
+	for _, root := range frame.Roots {
+		if err := root.CheckHashes(); err != nil {
+			return err
+		}
+	}
 
 	// Clear all state
 	p.firstLastConsensusRoundLocker.Lock()
 	p.LastConsensusRound = nil
 	p.FirstConsensusRound = nil
+	p.decidedFrame = block.RoundReceived()
 	p.firstLastConsensusRoundLocker.Unlock()
 	p.AnchorBlock = nil
 
 	p.undeterminedEventsLocker.Lock()
 	p.UndeterminedEvents = EventHashes{}
+	p.undivided = nil
+	p.PendingRoundReceived = common.Int64Slice{}
+	p.undeterminedEventsEpoch++
 	p.undeterminedEventsLocker.Unlock()
+	if err := p.clearArchive(); err != nil {
+		return err
+	}
+	p.clearRootQueue()
 	p.PendingRounds = []*pendingRound{}
+	// the blocks go on with the transactions carried over past the one
+	// of the reset
+	carry, err := p.Store.BlockCarry(block.RoundReceived())
+	if err != nil {
+		return err
+	}
+	p.blockCarry = carry
 	p.pendingLoadedEventsLocker.Lock()
 	p.pendingLoadedEvents = 0
 	p.pendingLoadedEventsLocker.Unlock()
 	p.topologicalIndex = 0
+	// the frames up to the one of the block are final already, the events of
+	// its frame are numbered on from it
+	p.resetFrame = block.RoundReceived()
+	p.DecidedLocker.Lock()
+	p.nextFinalFrame = block.RoundReceived() + 1
+	p.DecidedLocker.Unlock()
+	// the signatures of the blocks up to the reset one are of no use, those
+	// the frame events carry are pooled again
+	p.dropSignatures(block.Index())
 
-	cacheSize := p.Store.CacheSize()
-	dominatorCache, err := lru.New(cacheSize)
-	if err != nil {
-		p.logger.Fatal("Unable to reset Poset.dominatorCache")
-	}
-	selfDominatorCache, err := lru.New(cacheSize)
-	if err != nil {
-		p.logger.Fatal("Unable to reset Poset.selfDominatorCache")
-	}
-	strictlyDominatedCache, err := lru.New(cacheSize)
-	if err != nil {
-		p.logger.Fatal("Unable to reset Poset.strictlyDominatedCache")
-	}
-	roundCache, err := lru.New(cacheSize)
-	if err != nil {
-		p.logger.Fatal("Unable to reset Poset.roundCache")
-	}
-	p.dominatorCache = dominatorCache
-	p.selfDominatorCache = selfDominatorCache
-	p.strictlyDominatedCache = strictlyDominatedCache
-	p.roundCache = roundCache
+	// the caches keep the sizes they were tuned to
+	p.purgeCaches()
 
 	participants := p.Participants.ToPeerSlice()
 
@@ -1844,9 +2577,31 @@ func (p *Poset) Reset(block Block, frame Frame) error {
 
 	p.setLastConsensusRound(block.RoundReceived())
 
-	// Insert Frame Events
+	// the leaf events of the first frame are set as the node set them, before
+	// the events built on them
+	leafCreators := make(map[uint64]bool)
 	for _, ev := range frame.Events {
-		if err := p.InsertEvent(ev.ToEvent(), false); err != nil {
+		if isLeafEvent(ev.ToEvent()) {
+			leafCreators[ev.CreatorID] = true
+		}
+	}
+	var leafOf []*peers.Peer
+	for _, peer := range participants {
+		if leafCreators[peer.ID] {
+			leafOf = append(leafOf, peer)
+		}
+	}
+	if err := p.SetLeafEvents(leafOf); err != nil {
+		return err
+	}
+
+	// Insert Frame Events, those already inserted are not counted twice
+	for _, ev := range frame.Events {
+		e := ev.ToEvent()
+		if isLeafEvent(e) {
+			continue
+		}
+		if err := p.InsertEvent(e, false); err != nil && err != ErrAlreadyKnown {
 			return err
 		}
 	}
@@ -1854,29 +2609,91 @@ func (p *Poset) Reset(block Block, frame Frame) error {
 	return nil
 }
 
+// knownEvent returns true for an event the Store has already, at its index
+// among the events of its creator. Only the events up to the last one of the
+// creator are looked up, a new event costs no read of the database.
+func (p *Poset) knownEvent(event Event) bool {
+	creator := event.GetCreator()
+	last, isRoot, err := p.Store.LastEventFrom(creator)
+	if err != nil || isRoot {
+		return false
+	}
+	lastEvent, err := p.Store.GetEventBlock(last)
+	if err != nil || event.Index() > lastEvent.Index() {
+		return false
+	}
+	hash, err := p.Store.ParticipantEvent(creator, event.Index())
+	return err == nil && hash == event.Hash()
+}
+
+// preloadingStore is implemented by the stores indexing the last events of
+// the participants as they are loaded, as BadgerStore
+type preloadingStore interface {
+	dropPreloaded() error
+}
+
 // Bootstrap loads all Events from the Store's DB (if there is one) and feeds
 // them to the Poset (in topological order) for consensus ordering. After this
 // method call, the Poset should be in a state coherent with the 'tip' of the
-// Poset
+// Poset. The events are streamed from the Store, not all read at once.
 func (p *Poset) Bootstrap() error {
-	// Retreive the Events from the underlying DB. They come out in topological
-	// order
-	topologicalEvents, err := p.Store.TopologicalEvents()
+	// the events archived by the last run are undetermined again once
+	// inserted
+	if err := p.clearArchive(); err != nil {
+		return err
+	}
+
+	// Nothing cached before the replay is trusted
+	p.purgeCaches()
+
+	// the blocks are made again from the base of the poset, on from the
+	// transactions carried over past it
+	carry, err := p.Store.BlockCarry(p.nextFinalFrame - 1)
 	if err != nil {
 		return err
 	}
+	p.blockCarry = carry
 
-	// Insert the Events in the Poset
-	for _, e := range topologicalEvents {
-		if err := p.InsertEvent(e, true); err != nil {
+	// the events indexed as the store was loaded are inserted again
+	if store, ok := p.Store.(preloadingStore); ok {
+		if err := store.dropPreloaded(); err != nil {
 			return err
 		}
 	}
 
+	// Insert the Events in the Poset. They come out of the underlying DB in
+	// topological order.
+	var insertErr error
+	err = p.Store.ForEachEvent(func(e Event) bool {
+		// the leaf events are set again by the node and not inserted
+		if isLeafEvent(e) {
+			return true
+		}
+		// the consensus of the event is computed again, the stored one
+		// would keep its atropos from making consensus events of its
+		// ancestors
+		e.Clotho, e.Atropos = false, false
+		e.AtroposTimestamp, e.FrameReceived = AtroposTimestampNIL, 0
+		insertErr = p.InsertEvent(e, true)
+		return insertErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	if insertErr != nil {
+		return insertErr
+	}
+
 	// Compute the consensus order of Events
+	if err := p.ProcessRootQueue(); err != nil {
+		return err
+	}
 	if err := p.DivideRounds(); err != nil {
 		return err
 	}
+	if err := p.verifyStoredRoundRecords(); err != nil {
+		return err
+	}
 	if err := p.DecideAtropos(); err != nil {
 		return err
 	}
@@ -1893,9 +2710,242 @@ func (p *Poset) Bootstrap() error {
 	return nil
 }
 
+// isLeafEvent returns true for the unsigned event without parents a node
+// sets for each participant, see node.NewCore
+func isLeafEvent(e Event) bool {
+	sp, op := e.SelfParent(), e.OtherParent()
+	return sp.Zero() && op.Zero()
+}
+
+// SetLeafEvents sets the unsigned event without parents each participant
+// creates its first event on. The hash of a leaf event only depends on its
+// creator, so that all the nodes agree on it.
+func (p *Poset) SetLeafEvents(participants []*peers.Peer) error {
+	for _, peer := range participants {
+		creator, err := peer.PubKeyBytes()
+		if err != nil {
+			return err
+		}
+		body := EventBody{
+			Creator: creator,
+			Index:   0,
+			Parents: EventHashes{EventHash{}, EventHash{}}.Bytes(),
+		}
+		hash, err := body.Hash()
+		if err != nil {
+			return err
+		}
+		ft := NewFlagTable()
+		ft[hash] = 0
+		event := Event{
+			Message: &EventMessage{
+				Hash:             hash.Bytes(),
+				CreatorID:        peer.ID,
+				TopologicalIndex: p.NextTopologicalIndex(),
+				Body:             &body,
+			},
+			FlagTableBytes:   ft.Marshal(),
+			RootTableBytes:   ft.Marshal(),
+			LamportTimestamp: int64(creator[15]),
+			AtroposTimestamp: int64(creator[15]),
+			Frame:            0,
+			Atropos:          true,
+			Clotho:           true,
+			Root:             true,
+		}
+		event.AtTimes = append(event.AtTimes, event.LamportTimestamp)
+		if err := p.Store.SetEvent(event); err != nil {
+			return err
+		}
+		// a leaf event is a root which votes for no Clotho, its time
+		// table is empty unless frame 0 is final already
+		if err := p.Store.NewTimeTable(0, hash); err != nil && !common.Is(err, common.TooLate) {
+			return err
+		}
+		peer.SetHeight(0)
+	}
+	return nil
+}
+
+// verifyStoredRoundRecords verifies the round records of all the events of
+// the Store, see verifyRoundRecords
+func (p *Poset) verifyStoredRoundRecords() error {
+	repaired := 0
+	var verifyErr error
+	err := p.Store.ForEachEvent(func(e Event) bool {
+		// a leaf event is in no round
+		if isLeafEvent(e) {
+			return true
+		}
+		var ok bool
+		if ok, verifyErr = p.verifyRoundRecord(e); verifyErr != nil {
+			return false
+		}
+		if !ok {
+			repaired++
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if verifyErr != nil {
+		return verifyErr
+	}
+	if repaired > 0 {
+		p.logger.WithField("events", repaired).Warn("Bootstrap: repaired round records")
+	}
+	return nil
+}
+
+// verifyRoundRecords checks that every stored event appears in the record of
+// its round, adding back and logging the ones missing
+func (p *Poset) verifyRoundRecords(events []Event) error {
+	repaired := 0
+	for _, e := range events {
+		ok, err := p.verifyRoundRecord(e)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			repaired++
+		}
+	}
+	if repaired > 0 {
+		p.logger.WithField("events", repaired).Warn("Bootstrap: repaired round records")
+	}
+	return nil
+}
+
+// verifyRoundRecord checks that an event appears in the record of its
+// round, it adds it back and returns false when it is missing
+func (p *Poset) verifyRoundRecord(e Event) (bool, error) {
+	hash := e.Hash()
+	r, err := p.round(hash)
+	if err != nil {
+		return false, err
+	}
+	round, err := p.Store.GetRound(r)
+	if err != nil && !common.Is(err, common.KeyNotFound) {
+		return false, err
+	}
+	if round.Message.Events == nil {
+		round = *NewRound()
+	}
+	if _, ok := round.Message.Events[hash.String()]; ok {
+		return true, nil
+	}
+	if !round.Message.Queued && r >= p.GetLastConsensusRound() {
+		p.PendingRounds = append(p.PendingRounds, &pendingRound{r, false})
+		round.Message.Queued = true
+	}
+	clotho, err := p.clotho(hash)
+	if err != nil {
+		return false, err
+	}
+	round.AddEvent(hash, clotho)
+	if err := p.Store.SetRound(r, round); err != nil {
+		return false, err
+	}
+	p.logger.WithFields(logrus.Fields{
+		"hash":   hash,
+		"round":  r,
+		"clotho": clotho,
+	}).Warn("Bootstrap: event missing from its round record, repaired")
+	return false, nil
+}
+
+// wireCreator resolves a wire creator ID, first among the participants and
+// then among the creators set with SetWireCreator
+func (p *Poset) wireCreator(id uint64) (*peers.PeerMessage, error) {
+	if id == 0 {
+		return nil, ErrZeroCreatorID
+	}
+	if peer, ok := p.Participants.ReadByID(id); ok {
+		return peer.Message, nil
+	}
+	p.wireCreatorsLocker.RLock()
+	defer p.wireCreatorsLocker.RUnlock()
+	if pm, ok := p.wireCreators[id]; ok {
+		return pm, nil
+	}
+	return nil, &UnknownCreatorError{ID: id}
+}
+
+// SetWireCreator records the peer behind a creator ID missing from the
+// participants, so that wire events it created can be read. The caller is
+// responsible for checking the peer is legitimate.
+func (p *Poset) SetWireCreator(id uint64, pm *peers.PeerMessage) {
+	p.wireCreatorsLocker.Lock()
+	defer p.wireCreatorsLocker.Unlock()
+	p.wireCreators[id] = pm
+}
+
+// PendingPeerAdd returns true when an undetermined event carries a PEER_ADD
+// internal transaction for pubKey
+func (p *Poset) PendingPeerAdd(pubKey string) bool {
+	undetermined, _ := p.undeterminedSnapshot()
+	for _, hash := range undetermined {
+		ev, err := p.Store.GetEventBlock(hash)
+		if err != nil {
+			continue
+		}
+		for _, tx := range ev.InternalTransactions() {
+			if tx.Type == TransactionType_PEER_ADD && tx.Peer != nil &&
+				tx.Peer.PubKeyHex == pubKey {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // ReadWireInfo converts a WireEvent to an Event by replacing int IDs with the
 // corresponding public keys.
 func (p *Poset) ReadWireInfo(wevent WireEvent) (*Event, error) {
+	return p.readWireInfo(wevent, p.Store.ParticipantEvent)
+}
+
+// wireEventID identifies an event of a batch by creator and index
+type wireEventID struct {
+	creator string
+	index   int64
+}
+
+// ReadWireBatch converts a batch of wire events, in topological order, whose
+// parents may be earlier events of the batch not inserted yet. An event
+// whose parents are neither in the batch nor in the store is nil, with the
+// error ReadWireInfo would return.
+func (p *Poset) ReadWireBatch(wevents []WireEvent) ([]*Event, []error) {
+	events := make([]*Event, len(wevents))
+	errs := make([]error, len(wevents))
+	batch := make(map[wireEventID]EventHash, len(wevents))
+	participantEvent := func(creator string, index int64) (EventHash, error) {
+		if hash, ok := batch[wireEventID{creator, index}]; ok {
+			return hash, nil
+		}
+		return p.Store.ParticipantEvent(creator, index)
+	}
+	for i, we := range wevents {
+		events[i], errs[i] = p.readWireInfo(we, participantEvent)
+		if errs[i] == nil {
+			// resolved by readWireInfo already
+			creator, _ := p.wireCreator(we.Body.CreatorID)
+			batch[wireEventID{creator.PubKeyHex, we.Body.Index}] = events[i].Hash()
+		}
+	}
+	return events, errs
+}
+
+func (p *Poset) readWireInfo(wevent WireEvent,
+	participantEvent func(creator string, index int64) (EventHash, error)) (*Event, error) {
+	if err := CheckEventVersion(wevent.Body.Version); err != nil {
+		return nil, err
+	}
+	if err := checkWireStructure(wevent.Body); err != nil {
+		return nil, err
+	}
+
 	var (
 		selfParent  EventHash = GenRootSelfParent(wevent.Body.CreatorID)
 		otherParent EventHash
@@ -1905,54 +2955,72 @@ func (p *Poset) ReadWireInfo(wevent WireEvent) (*Event, error) {
 		otherParent = GenRootSelfParent(wevent.Body.OtherParentCreatorID)
 	}
 
-	creator, ok := p.Participants.ReadByID(wevent.Body.CreatorID)
-	// FIXIT: creator can be nil when wevent.Body.CreatorID == 0
-	if !ok {
-		return nil, fmt.Errorf("unknown wevent.Body.CreatorID=%v", wevent.Body.CreatorID)
+	creator, err := p.wireCreator(wevent.Body.CreatorID)
+	if err != nil {
+		return nil, err
+	}
+	if len(creator.PubKeyHex) < 2 {
+		return nil, fmt.Errorf("bad public key %q for creator ID %d", creator.PubKeyHex, wevent.Body.CreatorID)
 	}
-	creatorBytes, err := hex.DecodeString(creator.Message.PubKeyHex[2:])
+	creatorBytes, err := hex.DecodeString(creator.PubKeyHex[2:])
 	if err != nil {
 		return nil, fmt.Errorf("hexDecodeString(creator.PubKeyHex[2:]): %v", err)
 	}
 
+	// A wire event carries no consensus field, its frame and lamport
+	// timestamp are unset below, and a negative parent index of any value
+	// stands for the root, as -1 does.
+	if wevent.Body.SelfParentIndex < 0 {
+		wevent.Body.SelfParentIndex = -1
+	}
+	if wevent.Body.OtherParentIndex < 0 {
+		wevent.Body.OtherParentIndex = -1
+	}
+
 	if wevent.Body.SelfParentIndex >= 0 {
-		selfParent, err = p.Store.ParticipantEvent(creator.Message.PubKeyHex, wevent.Body.SelfParentIndex)
+		selfParent, err = participantEvent(creator.PubKeyHex, wevent.Body.SelfParentIndex)
 		if err != nil {
+			p.noteGap(creator.PubKeyHex, wevent.Body.Index)
 			return nil, fmt.Errorf("p.Store.ParticipantEvent(creator.PubKeyHex %v, wevent.Body.SelfParentIndex %v): %v",
-				creator.Message.PubKeyHex, wevent.Body.SelfParentIndex, err)
+				creator.PubKeyHex, wevent.Body.SelfParentIndex, err)
 		}
 	}
 	if wevent.Body.OtherParentIndex >= 0 {
-		otherParentCreator, ok := p.Participants.ReadByID(wevent.Body.OtherParentCreatorID)
-		if ok {
-			otherParent, err = p.Store.ParticipantEvent(otherParentCreator.Message.PubKeyHex, wevent.Body.OtherParentIndex)
+		otherParentCreator, err := p.wireCreator(wevent.Body.OtherParentCreatorID)
+		if err != nil {
+			return nil, err
+		}
+		otherParent, err = participantEvent(otherParentCreator.PubKeyHex, wevent.Body.OtherParentIndex)
+		if err != nil {
+			// PROBLEM Check if other parent can be found in the root
+			// problem, we do not known the WireEvent's EventHash, and
+			// we do not know the creators of the roots RootEvents
+			root, err := p.Store.GetRoot(creator.PubKeyHex)
 			if err != nil {
-				// PROBLEM Check if other parent can be found in the root
-				// problem, we do not known the WireEvent's EventHash, and
-				// we do not know the creators of the roots RootEvents
-				root, err := p.Store.GetRoot(creator.Message.PubKeyHex)
-				if err != nil {
-					return nil, fmt.Errorf("p.Store.GetRoot(creator.PubKeyHex %v): %v", creator.Message.PubKeyHex, err)
-				}
-				// loop through others
-				found := false
-				for _, re := range root.Others {
-					if re.CreatorID == wevent.Body.OtherParentCreatorID &&
-						re.Index == wevent.Body.OtherParentIndex {
-						otherParent.Set(re.Hash)
-						found = true
-						break
+				return nil, fmt.Errorf("p.Store.GetRoot(creator.PubKeyHex %v): %v", creator.PubKeyHex, err)
+			}
+			// loop through others
+			found := false
+			for _, re := range root.Others {
+				if re.CreatorID == wevent.Body.OtherParentCreatorID &&
+					re.Index == wevent.Body.OtherParentIndex {
+					if !IsValidEventHash(re.Hash) {
+						return nil, &InvalidEventHashError{Length: len(re.Hash)}
+					}
+					if err := otherParent.Set(re.Hash); err != nil {
+						return nil, err
 					}
+					found = true
+					break
 				}
+			}
 
-				if !found {
-					return nil, fmt.Errorf("OtherParent not found")
+			if !found {
+				return nil, &MissingParentError{
+					CreatorID: wevent.Body.OtherParentCreatorID,
+					Index:     wevent.Body.OtherParentIndex,
 				}
 			}
-		} else {
-			// unknown participant
-			// TODO: we should handle this nicely
-			return nil, errors.New("unknown participant")
 		}
 	}
 
@@ -1978,6 +3046,10 @@ func (p *Poset) ReadWireInfo(wevent WireEvent) (*Event, error) {
 		Creator:              creatorBytes,
 		Index:                wevent.Body.Index,
 		BlockSignatures:      blockSignatures,
+		TransactionFlags:     wevent.Body.TransactionFlags,
+		Timestamp:            wevent.Body.Timestamp,
+		NetworkID:            wevent.Body.NetworkID,
+		Version:              wevent.Body.Version,
 	}
 
 	ft := NewFlagTable()
@@ -1996,11 +3068,16 @@ func (p *Poset) ReadWireInfo(wevent WireEvent) (*Event, error) {
 //		roundReceived:    RoundNIL,
 //		round:            RoundNIL,
 		LamportTimestamp: LamportTimestampNIL,
+		AtroposTimestamp: AtroposTimestampNIL,
 		Frame:            FrameNIL,
 		FlagTableBytes:   ft.Marshal(),
 		RootTableBytes:   ft.Marshal(),
 	}
 
+	if err := checkEventStructure(*event); err != nil {
+		return nil, err
+	}
+
 	p.logger.WithFields(logrus.Fields{
 		"event.Signature":  event.Message.Signature,
 		"wevent.Signature": wevent.Signature,
@@ -2012,18 +3089,12 @@ func (p *Poset) ReadWireInfo(wevent WireEvent) (*Event, error) {
 // CheckBlock returns an error if the Block does not contain valid signatures
 // from MORE than 1/3 of participants
 func (p *Poset) CheckBlock(block Block) error {
-	validSignatures := uint64(0)
-	for _, s := range block.GetBlockSignatures() {
-		ok, _ := block.Verify(s)
-		if ok {
-			validSignatures++
-		}
-	}
-	if validSignatures <= p.GetTrustCount() {
-		return fmt.Errorf("not enough valid signatures: got %d, need %d", validSignatures, p.GetTrustCount()+1)
+	signed := block.SignedStake(p.Participants)
+	if signed <= p.GetTrustCount() {
+		return fmt.Errorf("not enough valid signatures: got %d, need %d", signed, p.GetTrustCount()+1)
 	}
 
-	p.logger.WithField("valid_signatures", validSignatures).Debug("CheckBlock")
+	p.logger.WithField("valid_signatures", signed).Debug("CheckBlock")
 	return nil
 }
 
@@ -2053,70 +3124,25 @@ func updateCcList(ccList map[int64]map[EventHash]int64, frame int64, hash EventH
 }
 
 func (p *Poset) ClothoChecking(e *Event) error {
+	ins := p.newInsertion()
+	if err := p.clothoChecking(ins, e); err != nil {
+		return err
+	}
+	return p.commitInsertion(ins)
+}
+
+// clothoChecking is ClothoChecking with the writes made to a batch
+func (p *Poset) clothoChecking(ins *insertion, e *Event) error {
+	store := ins.batch
 //	p.logger.WithFields(logrus.Fields{
 //		"Event": e,
 //	}). Warnf("ClothoChecking Start")
 //	defer p.logger.WithFields(logrus.Fields{
 //		"Event": e,
 //	}). Warnf("ClothoChecking End")
-	ccList := make(map[int64]map[EventHash]int64)
-	rootTable, err := e.GetRootTable()
+	ccList, err := p.clothoCounts(e)
 	if err != nil {
-		return fmt.Errorf("ClothoChecking() e.GetRootTable(): %v", err)
-	}
-	for key, val := range rootTable {
-		prevRoot, err := p.Store.GetClothoCheck(val, key)
-		if isDBKeyNotFound(err) {
-			continue
-		}
-		if err != nil {
-			return fmt.Errorf("ClothoChecking(): GetClothoCheck(): %v", err)
-		}
-		prevRootEvent, err := p.Store.GetEventBlock(prevRoot)
-		if err != nil {
-			return fmt.Errorf("GetEventBlock(prevRoot): %v", err)
-		}
-		ccTemp := make(map[int64]map[EventHash]int64)
-
-		prevRootTable, err := prevRootEvent.GetRootTable()
-		if err != nil {
-			return fmt.Errorf("ClothoChecking() prevRootEvent.GetRootTable(): %v", err)
-		}
-
-		for rkey, rval := range prevRootTable {
-			prevPrevRoot, err := p.Store.GetClothoCheck(rval, rkey)
-			if isDBKeyNotFound(err) {
-				continue
-			}
-			if err != nil {
-				return fmt.Errorf("ClothoChecking(): GetClothoCheck(rval, rkey): %v", err)
-			}
-			prevPrevRootEvent, err := p.Store.GetEventBlock(prevPrevRoot)
-			if err != nil {
-				return fmt.Errorf("GetEventBlock(prevPrevRoot): %v", err)
-			}
-
-			prevPrevRootTable, err := prevPrevRootEvent.GetRootTable()
-			if err != nil {
-				return fmt.Errorf("ClothoChecking() prevPrevRootEvent.GetRootTable(): %v", err)
-			}
-
-			for rrkey, rrval := range prevPrevRootTable {
-				_, err := p.Store.GetClothoCheck(rrval, rrkey)
-				if isDBKeyNotFound(err) {
-					continue
-				}
-				if err != nil {
-					return fmt.Errorf("ClothoChecking(): GetClothoCheck(rrval, rrkey): %v", err)
-				}
-				incCcTemp(ccTemp, rrval, rrkey)
-			}
-		}
-		for frame, eventHashMap := range ccTemp {
-			for hash, val := range eventHashMap {
-				updateCcList(ccList, frame, hash, val)
-			}
-		}
+		return err
 	}
 	for frame, eventHashMap := range ccList {
 		for key, val := range eventHashMap {
@@ -2127,31 +3153,36 @@ func (p *Poset) ClothoChecking(e *Event) error {
 //				"val": val,
 //			}). Warnf("ClothoChecking")
 			if uint64(val) >= p.GetSuperMajority() {
-				rootKey, err := p.Store.GetClothoCheck(frame, key)
+				rootKey, err := store.GetClothoCheck(frame, key)
 				if err != nil {
 					return fmt.Errorf("ClothoChecking(): GetClothoCheck(frame, key): %v", err)
 				}
 
-				root, err := p.Store.GetEventBlock(rootKey)
+				root, err := store.GetEventBlock(rootKey)
 				if err != nil {
 					return fmt.Errorf("ClothoChecking() GetEventBlock(key): %v", err)
 				}
 				if !root.Clotho {
 					root.Clotho = true
-					if err := p.Store.SetEvent(root); err != nil {
+					if err := store.SetEvent(root); err != nil {
 						return fmt.Errorf("ClothoChecking() SetEvent(): %v", err)
 					}
-					peer, ok := p.Participants.ReadByPubKey(root.GetCreator())
-					hash := root.Hash()
-					p.logger.WithFields(logrus.Fields{
-						"Frame": frame,
-						"EventCreator": peer.Message.NetAddr,
-						"Hash": hash.String(),
-						"lamport": root.GetLamportTimestamp(),
-						"ok": ok,
-					}).Debugf("Clotho")
+					ins.audits = append(ins.audits, auditEvent(AuditClotho, &root))
+					if dag1_log.DebugEnabled(p.logger) {
+						peer, ok := p.Participants.ReadByPubKey(root.GetCreator())
+						hash := root.Hash()
+						p.logger.WithFields(logrus.Fields{
+							"Frame": frame,
+							"EventCreator": peer.Message.NetAddr,
+							"Hash": hash.String(),
+							"lamport": root.GetLamportTimestamp(),
+							"ok": ok,
+						}).Debugf("Clotho")
+					}
+				}
+				if err := store.AddTimeTable(e.Frame, e.Hash(), root.Hash(), e.LamportTimestamp); err != nil {
+					return fmt.Errorf("ClothoChecking() AddTimeTable(): %v", err)
 				}
-				p.Store.AddTimeTable(e.Hash(), root.Hash(), e.LamportTimestamp)
 			}
 		}
 	}
@@ -2159,35 +3190,60 @@ func (p *Poset) ClothoChecking(e *Event) error {
 }
 
 func (p *Poset) AtroposTimeSelection(e *Event) error {
+	ins := p.newInsertion()
+	if err := p.atroposTimeSelection(ins, e); err != nil {
+		return err
+	}
+	return p.commitInsertion(ins)
+}
+
+// atroposTimeSelection is AtroposTimeSelection with the writes made to the
+// batch of an insertion
+func (p *Poset) atroposTimeSelection(ins *insertion, e *Event) error {
 	countMap := NewCountMap()
 	c := int64(4)
 
-	rootTable, err := e.GetRootTable()
+	rootTable, err := p.rootTableOf(e)
 	if err != nil {
 		return err
 	}
-	for prevKey, _ := range rootTable {
-		timeTable, err := p.Store.GetTimeTable(prevKey)
+	for prevKey, prevFrame := range rootTable {
+		timeTable, err := ins.batch.GetTimeTable(prevFrame, prevKey)
+		if common.Is(err, common.TooLate) {
+			// the frame is final, so are the Clothos it voted for
+			continue
+		}
 		if err != nil {
-			return err
+			return fmt.Errorf("AtroposTimeSelection() GetTimeTable(): %v", err)
 		}
 		for key, val := range timeTable {
 			countMap.Inc(key, val)
 		}
 	}
-	for key, val := range countMap {
-		maxVal := uint64(0)
-		var maxInd int64
-
-		clotho,err := p.Store.GetEventBlock(key)
+	// the clothos are taken in their final order, not in the order of the
+	// map: an ancestor of several Atropos is received with the first one
+	clothos := make([]Event, 0, len(countMap))
+	for key := range countMap {
+		clotho, err := ins.batch.GetEventBlock(key)
 		if err != nil {
-			p.logger.WithFields(logrus.Fields{
-				"Hash": key.String(),
-				"err": err,
-			}). Warnf("Clotho not found in atropos time selection")
+			p.warnLimiter.Warnf(p.logger, "Clotho %s not found in atropos time selection: %v", key.String(), err)
 			continue
 		}
+		clothos = append(clothos, clotho)
+	}
+	sort.Sort(ByFinalOrder(clothos))
+
+	for _, clotho := range clothos {
+		maxVal := uint64(0)
+		var maxInd int64
+		key := clotho.Hash()
+		val := countMap[key]
+
 		if clotho.Atropos { // Clotho is already confirmed as Atropos
+			// by the vote of an earlier root
+			if clotho.Frame > ins.decidedFrame {
+				ins.decidedFrame = clotho.Frame
+			}
 			continue
 		}
 		
@@ -2205,7 +3261,9 @@ func (p *Poset) AtroposTimeSelection(e *Event) error {
 					maxInd = time
 				}
 			}
-			p.Store.AddTimeTable(e.Hash(), key, maxInd)
+			if err := ins.batch.AddTimeTable(e.Frame, e.Hash(), key, maxInd); err != nil {
+				return fmt.Errorf("AtroposTimeSelection() AddTimeTable(): %v", err)
+			}
 		} else {
 			for time, count := range val {
 				if maxVal == uint64(0) || count > maxVal {
@@ -2224,19 +3282,28 @@ func (p *Poset) AtroposTimeSelection(e *Event) error {
 				if !clotho.Atropos {
 					clotho.Atropos = true
 					clotho.FrameReceived = clotho.Frame
+					if clotho.Frame > ins.decidedFrame {
+						ins.decidedFrame = clotho.Frame
+					}
 //					if maxInd < clotho.AtroposTimestamp || 0 == clotho.AtroposTimestamp {
-						if 0 == clotho.AtroposTimestamp {
-							p.accountEvent(&clotho)
+						if !clotho.HasAtroposTime() {
+							if err := p.accountEventIn(ins, &clotho); err != nil {
+								return err
+							}
 						}
 //						clotho.AtroposTimestamp = maxInd
 //						clotho.AtTimes = append(clotho.AtTimes, maxInd)
 //					}
 //					p.AssignAtroposTime(&clotho, clotho.AtroposTimestamp, clotho.Frame)
-					atroposTime := p.AssignAtroposTime2(&clotho, clotho.Frame)
+					atroposTime, err := p.assignAtroposTime2(ins, &clotho, clotho.Frame)
+					if err != nil {
+						return err
+					}
 					clotho.AtroposTimestamp = atroposTime
-					if err := p.Store.SetEvent(clotho); err != nil {
-						p.logger.Fatal(err)
+					if err := ins.batch.SetEvent(clotho); err != nil {
+						return err
 					}
+					ins.audits = append(ins.audits, auditEvent(AuditAtropos, &clotho))
 
 					peer, ok := p.Participants.ReadByPubKey(clotho.GetCreator())
 					hash := clotho.Hash()
@@ -2250,7 +3317,9 @@ func (p *Poset) AtroposTimeSelection(e *Event) error {
 					}). Debugf("Atropos")
 				}
 			} else {
-				p.Store.AddTimeTable(e.Hash(), key, maxInd)
+				if err := ins.batch.AddTimeTable(e.Frame, e.Hash(), key, maxInd); err != nil {
+					return fmt.Errorf("AtroposTimeSelection() AddTimeTable(): %v", err)
+				}
 			}
 			
 		}
@@ -2260,129 +3329,174 @@ func (p *Poset) AtroposTimeSelection(e *Event) error {
 }
 
 // AssignAtroposTime sorts events according Atropos selection rule
-func (p *Poset) AssignAtroposTime2(e *Event, frame int64) int64 {
+func (p *Poset) AssignAtroposTime2(e *Event, frame int64) (int64, error) {
+	ins := p.newInsertion()
+	atroposTime, err := p.assignAtroposTime2(ins, e, frame)
+	if err == nil {
+		err = p.commitInsertion(ins)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return atroposTime, nil
+}
+
+// assignAtroposTime2 is AssignAtroposTime2 with the writes made to the batch
+// of an insertion
+func (p *Poset) assignAtroposTime2(ins *insertion, e *Event, frame int64) (int64, error) {
 	followSelf, followOther := false, false
 	atroposTime := int64(0)
 
-	selfParent, selfErr := p.Store.GetEventBlock(e.SelfParent())
+	selfParent, selfErr := ins.batch.GetEventBlock(e.SelfParent())
 
 	if nil == selfErr {
-		if 0 == selfParent.FrameReceived/* || selfParent.FrameReceived < frame*/ {
+		if !selfParent.HasFrameReceived()/* || selfParent.FrameReceived < frame*/ {
 			selfParent.FrameReceived = frame
 			followSelf = true
 		}
-		if 0 == selfParent.AtroposTimestamp {
-			atroposTime = p.AssignAtroposTime2(&selfParent, frame)
+		if !selfParent.HasAtroposTime() {
+			var err error
+			if atroposTime, err = p.assignAtroposTime2(ins, &selfParent, frame); err != nil {
+				return 0, err
+			}
 			selfParent.AtroposTimestamp = atroposTime
 			followSelf = true
-			p.accountEvent(&selfParent)
+			if err := p.accountEventIn(ins, &selfParent); err != nil {
+				return 0, err
+			}
 		}
 		if followSelf {
-			if err := p.Store.SetEvent(selfParent); err != nil {
-				p.logger.Fatal(err)
+			if err := ins.batch.SetEvent(selfParent); err != nil {
+				return 0, err
 			}
 		}
 	}
 
-	otherParent, otherErr := p.Store.GetEventBlock(e.OtherParent())
+	otherParent, otherErr := ins.batch.GetEventBlock(e.OtherParent())
 
 	if nil == otherErr {
-		if 0 == otherParent.FrameReceived/* || otherParent.FrameReceived < frame*/ {
+		if !otherParent.HasFrameReceived()/* || otherParent.FrameReceived < frame*/ {
 			followOther = true
 			otherParent.FrameReceived = frame
 		}
-		if 0 == otherParent.AtroposTimestamp {
-			atroposTime = p.AssignAtroposTime2(&otherParent, frame)
+		if !otherParent.HasAtroposTime() {
+			var err error
+			if atroposTime, err = p.assignAtroposTime2(ins, &otherParent, frame); err != nil {
+				return 0, err
+			}
 			otherParent.AtroposTimestamp = atroposTime
 			followOther = true
-			p.accountEvent(&otherParent)
+			if err := p.accountEventIn(ins, &otherParent); err != nil {
+				return 0, err
+			}
 		}
 		if followOther {
-			if err := p.Store.SetEvent(otherParent); err != nil {
-				p.logger.Fatal(err)
+			if err := ins.batch.SetEvent(otherParent); err != nil {
+				return 0, err
 			}
 		}
 		atroposTime = otherParent.LamportTimestamp
 	} else { // more likely we are in leaf event here, so it should be equal to LamportTimestamp
 		atroposTime = e.LamportTimestamp
 	}
-	return atroposTime
+	return atroposTime, nil
 }
 
 
 // AssignAtroposTime sorts events according Atropos selection rule
-func (p *Poset) AssignAtroposTime(e *Event, atroposTimestamp int64, frame int64) {
+func (p *Poset) AssignAtroposTime(e *Event, atroposTimestamp int64, frame int64) error {
 	followSelf, followOther := false, false
 	selfParent, selfErr := p.Store.GetEventBlock(e.SelfParent())
 	otherParent, otherErr := p.Store.GetEventBlock(e.OtherParent())
 	if nil == selfErr {
-		if 0 == selfParent.FrameReceived || selfParent.FrameReceived < frame {
+		if !selfParent.HasFrameReceived() || selfParent.FrameReceived < frame {
 			selfParent.FrameReceived = frame
 		}
 		selfParent.RecFrames = append(selfParent.RecFrames, frame)
-		if 0 == selfParent.AtroposTimestamp || selfParent.AtroposTimestamp > atroposTimestamp {
+		if !selfParent.HasAtroposTime() || selfParent.AtroposTimestamp > atroposTimestamp {
 			followSelf = true
-			if 0 == selfParent.AtroposTimestamp {
+			if !selfParent.HasAtroposTime() {
 				p.accountEvent(&selfParent)
 			}
 			selfParent.AtroposTimestamp = atroposTimestamp
 			selfParent.AtTimes = append(selfParent.AtTimes, atroposTimestamp)
 			selfParent.AtVisited++
 			if err := p.Store.SetEvent(selfParent); err != nil {
-				p.logger.Fatal(err)
+				return err
 			}
 		} else {
 			selfParent.AtVisited++
 			if err := p.Store.SetEvent(selfParent); err != nil {
-				p.logger.Fatal(err)
+				return err
 			}
 		}
 	}
 	if nil == otherErr {
-		if 0 == otherParent.FrameReceived || otherParent.FrameReceived < frame {
+		if !otherParent.HasFrameReceived() || otherParent.FrameReceived < frame {
 			otherParent.FrameReceived = frame
 		}
 		otherParent.RecFrames = append(otherParent.RecFrames, frame)
-		if 0 == otherParent.AtroposTimestamp || otherParent.AtroposTimestamp > atroposTimestamp {
+		if !otherParent.HasAtroposTime() || otherParent.AtroposTimestamp > atroposTimestamp {
 			followOther = true
-			if 0 == otherParent.AtroposTimestamp {
+			if !otherParent.HasAtroposTime() {
 				p.accountEvent(&otherParent)
 			}
 			otherParent.AtroposTimestamp = atroposTimestamp
 			otherParent.AtTimes = append(otherParent.AtTimes, atroposTimestamp)
 			otherParent.AtVisited++
 			if err := p.Store.SetEvent(otherParent); err != nil {
-				p.logger.Fatal(err)
+				return err
 			}
 		} else {
 			otherParent.AtVisited++
 			if err := p.Store.SetEvent(otherParent); err != nil {
-				p.logger.Fatal(err)
+				return err
 			}
 		}
 	}
 	if followSelf {
-		p.AssignAtroposTime(&selfParent, atroposTimestamp, frame)
+		if err := p.AssignAtroposTime(&selfParent, atroposTimestamp, frame); err != nil {
+			return err
+		}
 	}
 	if followOther {
-		p.AssignAtroposTime(&otherParent, atroposTimestamp, frame)
+		return p.AssignAtroposTime(&otherParent, atroposTimestamp, frame)
 	}
+	return nil
 }
 
 func (p *Poset) accountEvent(ev *Event) {
+	err := p.Store.AddConsensusEvent(*ev)
+	if err != nil {
+		panic(err)
+	}
+	p.accounted(ev)
+}
+
+// accountEventIn makes an event a consensus event in the batch of an
+// insertion, the bookkeeping waiting for the commit
+func (p *Poset) accountEventIn(ins *insertion, ev *Event) error {
+	if err := ins.batch.AddConsensusEvent(*ev); err != nil {
+		return err
+	}
+	ins.accounted = append(ins.accounted, *ev)
+	return nil
+}
+
+// accounted does the bookkeeping of a new consensus event
+func (p *Poset) accounted(ev *Event) {
 	p.setLastConsensusRound(ev.Frame)
 	if ev.IsLoaded() {
 		p.pendingLoadedEventsLocker.Lock()
 		p.pendingLoadedEvents--
 		p.pendingLoadedEventsLocker.Unlock()
 	}
-	err := p.Store.AddConsensusEvent(*ev)
-	if err != nil {
-		panic(err)
-	}
 	p.consensusTransactionsLocker.Lock()
 	p.ConsensusTransactions += uint64(len(ev.Transactions()))
 	p.consensusTransactionsLocker.Unlock()
+	if p.consensusListener != nil {
+		p.consensusListener(*ev)
+	}
 }
 
 
@@ -2391,6 +3505,25 @@ func (p *Poset) accountEvent(ev *Event) {
 Setters
 *******************************************************************************/
 
+// setDecidedFrame raises the highest frame of a decided Atropos
+func (p *Poset) setDecidedFrame(frame int64) {
+	p.firstLastConsensusRoundLocker.Lock()
+	defer p.firstLastConsensusRoundLocker.Unlock()
+	if frame > p.decidedFrame {
+		p.decidedFrame = frame
+	}
+}
+
+// frameFinal returns true when the blocks of a frame may be made: an Atropos
+// of a later frame is decided, which the frames of the store beyond the last
+// events would otherwise seem final without, and the store finds it final
+func (p *Poset) frameFinal(frame int64) bool {
+	p.firstLastConsensusRoundLocker.RLock()
+	decided := frame < p.decidedFrame
+	p.firstLastConsensusRoundLocker.RUnlock()
+	return decided && p.Store.CheckFrameFinality(frame)
+}
+
 func (p *Poset) setLastConsensusRound(i int64) {
 	p.firstLastConsensusRoundLocker.Lock()
 	defer p.firstLastConsensusRoundLocker.Unlock()
@@ -2418,17 +3551,16 @@ func (p *Poset) setAnchorBlock(i int64) {
 
 // GetPeerFlagTableOfRandomUndeterminedEvent returns the flag table for undermined events
 func (p *Poset) GetPeerFlagTableOfRandomUndeterminedEvent() (map[string]int64, error) {
-	p.undeterminedEventsLocker.RLock()
-	defer p.undeterminedEventsLocker.RUnlock()
+	undetermined, _ := p.undeterminedSnapshot()
 
-	perm := rand.Perm(len(p.UndeterminedEvents))
+	perm := rand.Perm(len(undetermined))
 	for i := 0; i < len(perm); i++ {
-		hash := p.UndeterminedEvents[perm[i]]
+		hash := undetermined[perm[i]]
 		ev, err := p.Store.GetEventBlock(hash)
 		if err != nil {
 			continue
 		}
-		ft, err := ev.GetFlagTable()
+		ft, err := p.flagTableOf(&ev)
 		if err != nil {
 			continue
 		}
@@ -2449,16 +3581,34 @@ func (p *Poset) GetPeerFlagTableOfRandomUndeterminedEvent() (map[string]int64, e
 
 // GetUndeterminedEvents returns all the undetermined events
 func (p *Poset) GetUndeterminedEvents() EventHashes {
-	p.undeterminedEventsLocker.RLock()
-	defer p.undeterminedEventsLocker.RUnlock()
-	return p.UndeterminedEvents
+	undetermined, _ := p.undeterminedSnapshot()
+	return undetermined
+}
+
+// FlushWarnings writes the counts of the repeated warnings not written yet,
+// as the node shuts down
+func (p *Poset) FlushWarnings() {
+	p.warnLimiter.Flush()
 }
 
-// GetPendingLoadedEvents returns all the pending events
+// WireSize returns the size of an event of the store marshalled, see
+// Event.WireSize, cached by event hash
+func (p *Poset) WireSize(ev *Event) int {
+	hash := ev.Hash()
+	if size, ok := p.wireSizeCache.Get(hash); ok {
+		return size.(int)
+	}
+	size := ev.WireSize()
+	p.wireSizeCache.Add(hash, size)
+	return size
+}
+
+// GetPendingLoadedEvents returns all the pending events, counting the root
+// events waiting for ProcessRootQueue
 func (p *Poset) GetPendingLoadedEvents() int64 {
 	p.pendingLoadedEventsLocker.RLock()
 	defer p.pendingLoadedEventsLocker.RUnlock()
-	return p.pendingLoadedEvents
+	return p.pendingLoadedEvents + int64(p.QueuedRoots())
 }
 
 // GetLastConsensusRound returns the last consensus round
@@ -2466,8 +3616,7 @@ func (p *Poset) GetLastConsensusRound() int64 {
 	p.firstLastConsensusRoundLocker.RLock()
 	defer p.firstLastConsensusRoundLocker.RUnlock()
 	if p.LastConsensusRound == nil {
-		// -2 is less that undefined round index, -1
-		return -2
+		return LastConsensusRoundNIL
 	}
 	return *p.LastConsensusRound
 }
@@ -2497,21 +3646,28 @@ func (p *Poset) NextTopologicalIndex() int64 {
 	return result
 }
 
+// peekTopologicalIndex returns the next topological index without taking it
+func (p *Poset) peekTopologicalIndex() int64 {
+	p.topologicalIndexLocker.Lock()
+	defer p.topologicalIndexLocker.Unlock()
+	return p.topologicalIndex
+}
+
+func (p *Poset) setTopologicalIndex(i int64) {
+	p.topologicalIndexLocker.Lock()
+	defer p.topologicalIndexLocker.Unlock()
+	p.topologicalIndex = i
+}
+
+// Address returns the net address of the core, UnknownAddress without a
+// core or when the core is not a participant
 func (p *Poset) Address() string {
+	if p.core == nil {
+		return UnknownAddress
+	}
 	peer, ok := p.Participants.ReadByPubKey(p.core.HexID())
 	if ok {
 		return peer.Message.NetAddr
 	}
-	return "unknown"
-}
-
-/*******************************************************************************
-   Helpers
-*******************************************************************************/
-
-func randomShift(hash EventHash) bool {
-	if !hash.Zero() && hash[len(hash)/2] == 0 {
-		return false
-	}
-	return true
+	return UnknownAddress
 }
@@ -184,7 +184,7 @@ func initPosetNodes(n int) ([]TestNode, map[string]EventHash, *[]Event, *peers.P
 	}
 
 	for _, peer := range participants.ToPeerSlice() {
-		nodes = append(nodes, NewTestNode(keys[peer.PubKeyHex]))
+		nodes = append(nodes, NewTestNode(keys[peer.Message.PubKeyHex]))
 	}
 
 	return nodes, index, orderedEvents, participants
@@ -201,7 +201,7 @@ func playEvents(plays []play, nodes []TestNode,
 		e := NewEvent(p.txPayload, nil,
 			p.sigPayload,
 			EventHashes{index[p.selfParent], index[p.otherParent]},
-			nodes[p.to].Pub, p.index, ft)
+			nodes[p.to].Pub, p.index, ft, nil, 0, false)
 
 		nodes[p.to].signAndAddEvent(e, p.name, index, orderedEvents)
 	}
@@ -223,7 +223,10 @@ func createPoset(t testing.TB,
 		store = NewInmemStore(participants, cacheSize, nil)
 	}
 
-	poset := NewPoset(participants, store, nil, logger)
+	poset, err := NewPoset(participants, store, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	for i, ev := range *orderedEvents {
 		if err := poset.InsertEvent(ev, true); err != nil {
@@ -245,7 +248,7 @@ func initPosetFull(t testing.TB, plays []play, db bool, n int,
 			EventHashes{selfParent, EventHash{}},
 			nodes[i].Pub,
 			0,
-			FlagTable{selfParent: 1})
+			FlagTable{selfParent: 1}, nil, 0, false)
 
 		nodes[i].signAndAddEvent(
 			event,
@@ -260,7 +263,7 @@ func initPosetFull(t testing.TB, plays []play, db bool, n int,
 
 	// Add reference to each participants' root event
 	for i, peer := range participants.ToPeerSlice() {
-		root, err := poset.Store.GetRoot(peer.PubKeyHex)
+		root, err := poset.Store.GetRoot(peer.Message.PubKeyHex)
 		if err != nil {
 			panic(err)
 		}
@@ -406,6 +409,25 @@ func TestSelfDominator(t *testing.T) {
 	}
 }
 
+func TestSelfDominatorOfRootSelfParent(t *testing.T) {
+	p, index := initPoset(t)
+
+	// the self-parent of a root is out of the store, it self-dominates
+	// itself only
+	for i := 0; i < n; i++ {
+		x := fmt.Sprintf("r%d", i)
+		for _, y := range []string{"r0", "r1", "r2", "e0", "e1", "e2", "e12"} {
+			a, err := p.selfDominator(index[x], index[y])
+			if err != nil {
+				t.Fatalf("Error computing selfDominator(%s, %s). Err: %v", x, y, err)
+			}
+			if expected := x == y; a != expected {
+				t.Fatalf("selfDominator(%s, %s) should be %v, not %v", x, y, expected, a)
+			}
+		}
+	}
+}
+
 func TestClotho(t *testing.T) {
 	p, index := initPoset(t)
 
@@ -489,7 +511,10 @@ func TestFork(t *testing.T) {
 	}
 
 	store := NewInmemStore(participants, cacheSize, pos.DefaultConfig())
-	poset := NewPoset(participants, store, nil, testLogger(t))
+	poset, err := NewPoset(participants, store, nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	for i, node := range nodes {
 		parents := make(EventHashes, 2)
@@ -498,7 +523,7 @@ func TestFork(t *testing.T) {
 			t.Fatal(err)
 		}
 		parents[0] = selfParent
-		event := NewEvent(nil, nil, nil, parents, node.Pub, 0, nil)
+		event := NewEvent(nil, nil, nil, parents, node.Pub, 0, nil, nil, 0, false)
 		if err := event.Sign(node.Key); err != nil {
 			t.Fatal(err)
 		}
@@ -509,7 +534,7 @@ func TestFork(t *testing.T) {
 	}
 
 	// a and e2 need to have different hashes
-	eventA := NewEvent([][]byte{[]byte("yo")}, nil, nil, make(EventHashes, 2), nodes[2].Pub, 0, nil)
+	eventA := NewEvent([][]byte{[]byte("yo")}, nil, nil, make(EventHashes, 2), nodes[2].Pub, 0, nil, nil, 0, false)
 	if err := eventA.Sign(nodes[2].Key); err != nil {
 		t.Fatal(err)
 	}
@@ -520,7 +545,7 @@ func TestFork(t *testing.T) {
 
 	event01 := NewEvent(nil, nil, nil,
 		EventHashes{index[e0], index[a]}, // e0 and a
-		nodes[0].Pub, 1, nil)
+		nodes[0].Pub, 1, nil, nil, 0, false)
 	if err := event01.Sign(nodes[0].Key); err != nil {
 		t.Fatal(err)
 	}
@@ -531,7 +556,7 @@ func TestFork(t *testing.T) {
 
 	event20 := NewEvent(nil, nil, nil,
 		EventHashes{index[e2], index[e01]}, // e2 and e01
-		nodes[2].Pub, 1, nil)
+		nodes[2].Pub, 1, nil, nil, 0, false)
 	if err := event20.Sign(nodes[2].Key); err != nil {
 		t.Fatal(err)
 	}
@@ -749,6 +774,181 @@ func TestReadWireInfo(t *testing.T) {
 	}
 }
 
+func TestReadWireInfoUnknownCreator(t *testing.T) {
+	p, _, _ := initRoundPoset(t)
+
+	key, _ := crypto.GenerateECDSAKey()
+	pubHex := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey))
+	outsider := peers.NewPeer(pubHex, "")
+
+	// first event of a creator the poset has never heard of
+	ev := NewEvent([][]byte{[]byte("tx")}, nil, nil,
+		EventHashes{GenRootSelfParent(outsider.ID), EventHash{}},
+		crypto.FromECDSAPub(&key.PublicKey), 0,
+		NewFlagTable(), NewFlagTable(), FrameNIL, false)
+	ev.SetWireInfo(-1, 0, -1, outsider.ID)
+	if err := ev.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	wev := ev.ToWire()
+
+	_, err := p.ReadWireInfo(wev)
+	if e, ok := err.(*UnknownCreatorError); !ok || e.ID != outsider.ID {
+		t.Fatalf("expected an UnknownCreatorError for %d, got %v", outsider.ID, err)
+	}
+
+	zero := wev
+	zero.Body.CreatorID = 0
+	if _, err := p.ReadWireInfo(zero); err != ErrZeroCreatorID {
+		t.Fatalf("expected %v, got %v", ErrZeroCreatorID, err)
+	}
+
+	p.SetWireCreator(outsider.ID, outsider.Message)
+	evFromWire, err := p.ReadWireInfo(wev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := evFromWire.Verify(); !ok {
+		t.Fatalf("Error verifying signature from light wire: %v", err)
+	}
+	if evFromWire.GetCreator() != pubHex {
+		t.Fatalf("expected creator %s, got %s", pubHex, evFromWire.GetCreator())
+	}
+}
+
+// initLeafPoset returns a poset of n unit weight participants knowing only
+// their leaf events, as a new node's core does
+func initLeafPoset(t *testing.T, n int) (*Poset, []TestNode, []Event) {
+	nodes, _, _, participants := initPosetNodes(n)
+	for _, peer := range participants.ToPeerSlice() {
+		participants.SetPeerWeight(peer, 1)
+	}
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaves := make([]Event, n)
+	for i, node := range nodes {
+		body := EventBody{
+			Creator: node.Pub,
+			Parents: EventHashes{EventHash{}, EventHash{}}.Bytes(),
+		}
+		hash, err := body.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ft := FlagTable{hash: 0}
+		leaves[i] = Event{
+			Message: &EventMessage{
+				Hash:             hash.Bytes(),
+				CreatorID:        node.ID,
+				TopologicalIndex: p.NextTopologicalIndex(),
+				Body:             &body,
+			},
+			FlagTableBytes: ft.Marshal(),
+			RootTableBytes: ft.Marshal(),
+			Root:           true,
+		}
+		if err := p.Store.SetEvent(leaves[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p, nodes, leaves
+}
+
+// newChildEvent returns the event of node with the parents, signed
+func newChildEvent(node TestNode, index int64, selfParent, otherParent EventHash) Event {
+	ev := NewEvent(nil, nil, nil,
+		EventHashes{selfParent, otherParent},
+		node.Pub, index,
+		NewFlagTable(), NewFlagTable(), FrameNIL, false)
+	if err := ev.Sign(node.Key); err != nil {
+		panic(err)
+	}
+	return ev
+}
+
+func TestInsertEventMissingOtherParent(t *testing.T) {
+	p, nodes, leaves := initLeafPoset(t, 5)
+
+	unknown := fakeEventHash("unknown")
+	ev := newChildEvent(nodes[0], 1, leaves[0].Hash(), unknown)
+	err := p.InsertEvent(ev, true)
+	if !IsMissingParent(err) {
+		t.Fatalf("expected a MissingParentError, got %v", err)
+	}
+	if e := err.(*MissingParentError); e.Parent != unknown {
+		t.Fatalf("expected the missing parent %s, got %s", unknown.String(), e.Parent.String())
+	}
+	if _, err := p.Store.GetEventBlock(ev.Hash()); err == nil {
+		t.Fatal("expected the event not to be stored")
+	}
+	if len(p.UndeterminedEvents) != 0 {
+		t.Fatalf("expected no undetermined events, got %d", len(p.UndeterminedEvents))
+	}
+
+	// on the wire the other-parent is only known by creator and index
+	ev = newChildEvent(nodes[0], 1, leaves[0].Hash(), leaves[1].Hash())
+	if err := p.InsertEvent(ev, true); err != nil {
+		t.Fatal(err)
+	}
+	wev := ev.ToWire()
+	wev.Body.OtherParentIndex = 5
+	_, err = p.ReadWireInfo(wev)
+	if !IsMissingParent(err) {
+		t.Fatalf("expected a MissingParentError, got %v", err)
+	}
+	if e := err.(*MissingParentError); e.CreatorID != nodes[1].ID || e.Index != 5 {
+		t.Fatalf("expected the missing parent 5 of %d, got %d of %d",
+			nodes[1].ID, e.Index, e.CreatorID)
+	}
+}
+
+// evictingStore loses an event, as a cache can between the parent checks
+// and the frame computation of InsertEvent
+type evictingStore struct {
+	Store
+	evicted EventHash
+}
+
+func (s *evictingStore) GetEventBlock(hash EventHash) (Event, error) {
+	if hash == s.evicted {
+		return Event{}, common.NewStoreErr("EventCache", common.KeyNotFound, hash.String())
+	}
+	return s.Store.GetEventBlock(hash)
+}
+
+func TestInsertEventMissingSelfParent(t *testing.T) {
+	p, nodes, leaves := initLeafPoset(t, 5)
+	ev := newChildEvent(nodes[0], 1, leaves[0].Hash(), leaves[1].Hash())
+
+	store := p.Store
+	p.Store = &evictingStore{Store: store, evicted: leaves[0].Hash()}
+	// as synced events are, which have their wire info already
+	err := p.InsertEvent(ev, false)
+	if err == nil || IsMissingParent(err) {
+		t.Fatalf("expected the event to be rejected, got %v", err)
+	}
+	// no frame was computed from a zero-valued self-parent
+	if _, err := store.GetEventBlock(ev.Hash()); err == nil {
+		t.Fatal("expected the event not to be stored")
+	}
+
+	p.Store = store
+	if err := p.InsertEvent(ev, true); err != nil {
+		t.Fatal(err)
+	}
+	inserted, err := p.Store.GetEventBlock(ev.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inserted.Frame != leaves[0].Frame || inserted.Root {
+		t.Fatalf("expected a non-root event in frame %d, got frame %d, root %v",
+			leaves[0].Frame, inserted.Frame, inserted.Root)
+	}
+}
+
 func TestAtropos(t *testing.T) {
 	p, index, _ := initRoundPoset(t)
 
@@ -793,31 +993,11 @@ func TestAtropos(t *testing.T) {
 	}
 }
 
+// TestClothos checks the roots of the frames, the first events of their
+// creators in a frame, which the Clothos are elected from
 func TestClothos(t *testing.T) {
 	p, index, _ := initRoundPoset(t)
 
-	round0Clotho := make(map[string]*RoundEvent)
-
-	round0Clotho[hashString(index[e0])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	round0Clotho[hashString(index[e1])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	round0Clotho[hashString(index[e2])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	if err := p.Store.SetRoundCreated(0, RoundCreated{Message: RoundCreatedMessage{Events: round0Clotho}}); err != nil {
-		t.Fatalf("Failed to SetRoundCreated(0, ..) Err: %v", err)
-	}
-
-	round1Clotho := make(map[string]*RoundEvent)
-	round1Clotho[hashString(index[f1])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-	if err := p.Store.SetRoundCreated(1, RoundCreated{Message: RoundCreatedMessage{Events: round1Clotho}}); err != nil {
-		t.Fatalf("Failed to SetRoundCreated(1, ..) Err: %v", err)
-	}
-
 	expected := []dominatorItem{
 		{"", e0, true, false},
 		{"", e1, true, false},
@@ -826,54 +1006,26 @@ func TestClothos(t *testing.T) {
 		{"", e10, false, false},
 		{"", e21, true, false},
 		{"", e02, true, false},
+		{"", s11, false, false},
 	}
 
 	for _, exp := range expected {
-		a, err := p.clotho(index[exp.dominator])
+		ev, err := p.Store.GetEventBlock(index[exp.dominator])
 		if err != nil {
-			t.Fatalf("Error computing clotho(%s). Err: %v",
-				exp.dominator, err)
+			t.Fatal(err)
 		}
-		if a != exp.val {
-			t.Fatalf("clotho(%s) should be %v, not %v",
-				exp.dominator, exp.val, a)
+		if ev.Root != exp.val {
+			t.Fatalf("root(%s) should be %v, not %v",
+				exp.dominator, exp.val, ev.Root)
 		}
 	}
 }
 
+// TestRound checks the frames of the events. Unlike a round, the frame of
+// an event without other-parent is the one of its self-parent.
 func TestRound(t *testing.T) {
 	p, index, _ := initRoundPoset(t)
 
-	round0Clotho := make(map[string]*RoundEvent)
-
-	round0Clotho[hashString(index[e0])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	round0Clotho[hashString(index[e1])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	round0Clotho[hashString(index[e2])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	if err := p.Store.SetRoundCreated(0, RoundCreated{Message: RoundCreatedMessage{Events: round0Clotho}}); err != nil {
-		t.Fatalf("Failed to SetRoundCreated(0, ..) Err: %v", err)
-	}
-
-	round1Clotho := make(map[string]*RoundEvent)
-
-	round1Clotho[hashString(index[e21])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	round1Clotho[hashString(index[e02])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	round1Clotho[hashString(index[f1])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	if err := p.Store.SetRoundCreated(1, RoundCreated{Message: RoundCreatedMessage{Events: round1Clotho}}); err != nil {
-		t.Fatalf("Failed to SetRoundCreated(1, ..) Err: %v", err)
-	}
-
 	expected := []roundItem{
 		{e0, 0},
 		{e1, 0},
@@ -885,16 +1037,16 @@ func TestRound(t *testing.T) {
 		{e02, 1},
 		{s10, 0},
 		{f1, 1},
-		{s11, 2},
+		{s11, 1},
 	}
 
 	for _, exp := range expected {
-		r, err := p.round(index[exp.event])
+		ev, err := p.Store.GetEventBlock(index[exp.event])
 		if err != nil {
-			t.Fatalf("Error computing round(%s). Err: %v", exp.event, err)
+			t.Fatal(err)
 		}
-		if r != exp.round {
-			t.Fatalf("round(%s) should be %v, not %v", exp.event, exp.round, r)
+		if ev.Frame != exp.round {
+			t.Fatalf("frame(%s) should be %v, not %v", exp.event, exp.round, ev.Frame)
 		}
 	}
 }
@@ -902,54 +1054,26 @@ func TestRound(t *testing.T) {
 func TestRoundDiff(t *testing.T) {
 	p, index, _ := initRoundPoset(t)
 
-	round0Clotho := make(map[string]*RoundEvent)
-
-	round0Clotho[hashString(index[e0])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	round0Clotho[hashString(index[e1])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	round0Clotho[hashString(index[e2])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	if err := p.Store.SetRoundCreated(0, RoundCreated{Message: RoundCreatedMessage{Events: round0Clotho}}); err != nil {
-		t.Fatalf("Failed to SetRoundCreated(0, ..) Err: %v", err)
-	}
-
-	round1Clotho := make(map[string]*RoundEvent)
-
-	round1Clotho[hashString(index[e21])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	round1Clotho[hashString(index[e02])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	round1Clotho[hashString(index[f1])] = &RoundEvent{
-		Clotho: true, Atropos: Trilean_UNDEFINED}
-
-	if err := p.Store.SetRoundCreated(1, RoundCreated{Message: RoundCreatedMessage{Events: round1Clotho}}); err != nil {
-		t.Fatalf("Failed to SetRoundCreated(1, ..) Err: %v", err)
-	}
-
-	if d, err := p.roundDiff(index[s11], index[e21]); d != 1 {
+	frameDiff := func(x, y string) int64 {
+		ex, err := p.Store.GetEventBlock(index[x])
 		if err != nil {
-			t.Fatalf("RoundDiff(%s, %s) returned an error: %s", s11, e02, err)
+			t.Fatal(err)
 		}
-		t.Fatalf("RoundDiff(%s, %s) should be 1 not %d", s11, e02, d)
-	}
-
-	if d, err := p.roundDiff(index[f1], index[s11]); d != -1 {
+		ey, err := p.Store.GetEventBlock(index[y])
 		if err != nil {
-			t.Fatalf("RoundDiff(%s, %s) returned an error: %s", s11, f1, err)
+			t.Fatal(err)
 		}
-		t.Fatalf("RoundDiff(%s, %s) should be -1 not %d", s11, f1, d)
+		return ex.Frame - ey.Frame
 	}
-	if d, err := p.roundDiff(index[e02], index[e21]); d != 0 {
-		if err != nil {
-			t.Fatalf("RoundDiff(%s, %s) returned an error: %s", e20, e21, err)
-		}
-		t.Fatalf("RoundDiff(%s, %s) should be 0 not %d", e20, e21, d)
+
+	if d := frameDiff(s11, s10); d != 1 {
+		t.Fatalf("FrameDiff(%s, %s) should be 1 not %d", s11, s10, d)
+	}
+	if d := frameDiff(s10, s11); d != -1 {
+		t.Fatalf("FrameDiff(%s, %s) should be -1 not %d", s10, s11, d)
+	}
+	if d := frameDiff(e02, e21); d != 0 {
+		t.Fatalf("FrameDiff(%s, %s) should be 0 not %d", e02, e21, d)
 	}
 }
 
@@ -960,68 +1084,20 @@ func TestDivideRounds(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if l := p.Store.LastRound(); l != 2 {
-		t.Fatalf("last round should be 2 not %d", l)
-	}
-
-	round0, err := p.Store.GetRoundCreated(0)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if l := len(round0.Clotho()); l != 3 {
-		t.Fatalf("round 0 should have 3 clothos, not %d", l)
-	}
-	if !round0.Clotho().Contains(index[e0]) {
-		t.Fatalf("round 0 clothos should contain %s", e0)
-	}
-	if !round0.Clotho().Contains(index[e1]) {
-		t.Fatalf("round 0 clothos should contain %s", e1)
-	}
-	if !round0.Clotho().Contains(index[e2]) {
-		t.Fatalf("round 0 clothos should contain %s", e2)
-	}
-
-	round1, err := p.Store.GetRoundCreated(1)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if l := len(round1.Clotho()); l != 3 {
-		t.Fatalf("round 1 should have 1 clotho, not %d", l)
-	}
-	if !round1.Clotho().Contains(index[f1]) {
-		t.Fatalf("round 1 clothos should contain %s", f1)
-	}
-
-	round2, err := p.Store.GetRoundCreated(2)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if l := len(round2.Clotho()); l != 1 {
-		t.Fatalf("round 1 should have 1 clotho, not %d", l)
-	}
-
-	expectedPendingRounds := []pendingRound{
-		{
-			Index:   0,
-			Decided: false,
-		},
-		{
-			Index:   1,
-			Decided: false,
-		}, {
-			Index:   2,
-			Decided: false,
-		},
+	// there is a round for each frame
+	if l := p.Store.LastRound(); l != 1 {
+		t.Fatalf("last round should be 1 not %d", l)
 	}
-	for i, pd := range p.PendingRounds {
-		if !reflect.DeepEqual(*pd, expectedPendingRounds[i]) {
-			t.Fatalf("pendingRounds[%d] should be %v, not %v",
-				i, expectedPendingRounds[i], *pd)
+	for r := int64(0); r <= 1; r++ {
+		if _, err := p.Store.GetRound(r); err != nil {
+			t.Fatalf("round %d should be stored: %v", r, err)
 		}
 	}
+	if _, err := p.Store.GetRound(2); !common.Is(err, common.KeyNotFound) {
+		t.Fatalf("round 2 should not be stored, got %v", err)
+	}
 
-	// [event] => {lamportTimestamp, round}
+	// [event] => {lamportTimestamp, frame}
 	type tr struct {
 		t, r int64
 	}
@@ -1036,7 +1112,7 @@ func TestDivideRounds(t *testing.T) {
 		e02: {3, 1},
 		s10: {2, 0},
 		f1:  {4, 1},
-		s11: {5, 2},
+		s11: {5, 1},
 	}
 
 	for e, et := range expectedTimestamps {
@@ -1044,12 +1120,8 @@ func TestDivideRounds(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if r := ev.GetRound(); r == RoundNIL || r != et.r {
-			disp := "nil"
-			if r >= 0 {
-				disp = strconv.FormatInt(r, 10)
-			}
-			t.Fatalf("%s round should be %d, not %s", e, et.r, disp)
+		if r := ev.Frame; r != et.r {
+			t.Fatalf("%s frame should be %d, not %d", e, et.r, r)
 		}
 		if ts := ev.GetLamportTimestamp(); ts == LamportTimestampNIL || ts != et.t {
 			disp := "nil"
@@ -1072,10 +1144,13 @@ func TestCreateRoot(t *testing.T) {
 
 	baseRoot := NewBaseRoot(participants[0].ID)
 
+	// the roots carry no rounds, the events of a Reset poset go on from the
+	// frame of its block
+
 	expected := map[string]Root{
 		e0: baseRoot,
 		e02: {
-			NextRound: 1,
+			NextRound: 0,
 			SelfParent: &RootEvent{
 				Hash:             hashBytes(index[s00]),
 				CreatorID:        participants[0].ID,
@@ -1088,7 +1163,7 @@ func TestCreateRoot(t *testing.T) {
 					CreatorID:        participants[2].ID,
 					Index:            2,
 					LamportTimestamp: 2,
-					Round:            1},
+					Round:            0},
 			},
 		},
 		s10: {
@@ -1102,7 +1177,7 @@ func TestCreateRoot(t *testing.T) {
 			Others: map[string]*RootEvent{},
 		},
 		f1: {
-			NextRound: 1,
+			NextRound: 0,
 			SelfParent: &RootEvent{
 				Hash:             hashBytes(index[s10]),
 				CreatorID:        participants[1].ID,
@@ -1115,7 +1190,7 @@ func TestCreateRoot(t *testing.T) {
 					CreatorID:        participants[0].ID,
 					Index:            2,
 					LamportTimestamp: 3,
-					Round:            1},
+					Round:            0},
 			},
 		},
 	}
@@ -1230,18 +1305,21 @@ func initBlockPoset(t *testing.T) (*Poset, []TestNode, map[string]EventHash) {
 			EventHashes{GenRootSelfParent(peer.ID), EventHash{}},
 			nodes[i].Pub,
 			0,
-			nil)
+			nil, nil, 0, false)
 		nodes[i].signAndAddEvent(event, fmt.Sprintf("e%d", i),
 			index, orderedEvents)
 	}
 
-	poset := NewPoset(participants, NewInmemStore(participants, cacheSize, pos.DefaultConfig()),
+	poset, err := NewPoset(participants, NewInmemStore(participants, cacheSize, pos.DefaultConfig()),
 		nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// create a block and signatures manually
 	block := NewBlock(0, 1, []byte("framehash"),
 		[][]byte{[]byte("block tx")})
-	err := poset.Store.SetBlock(block)
+	err = poset.Store.SetBlock(block)
 	if err != nil {
 		t.Fatalf("error setting block. Err: %s", err)
 	}
@@ -1296,7 +1374,7 @@ func TestInsertEventsWithBlockSignatures(t *testing.T) {
 				pl.sigPayload,
 				EventHashes{index[pl.selfParent], index[pl.otherParent]},
 				nodes[pl.to].Pub,
-				pl.index, nil)
+				pl.index, nil, nil, 0, false)
 			if err := e.Sign(nodes[pl.to].Key); err != nil {
 				t.Fatal(err)
 			}
@@ -1352,7 +1430,7 @@ func TestInsertEventsWithBlockSignatures(t *testing.T) {
 				pl.sigPayload,
 				EventHashes{index[pl.selfParent], index[pl.otherParent]},
 				nodes[pl.to].Pub,
-				pl.index, nil)
+				pl.index, nil, nil, 0, false)
 			if err := e.Sign(nodes[pl.to].Key); err != nil {
 				t.Fatal(err)
 			}
@@ -1388,7 +1466,7 @@ func TestInsertEventsWithBlockSignatures(t *testing.T) {
 				pl.sigPayload,
 				EventHashes{index[pl.selfParent], index[pl.otherParent]},
 				nodes[pl.to].Pub,
-				pl.index, nil)
+				pl.index, nil, nil, 0, false)
 			if err := e.Sign(nodes[pl.to].Key); err != nil {
 				t.Fatal(err)
 			}
@@ -1509,7 +1587,7 @@ func TestDivideRoundsBis(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// [event] => {lamportTimestamp, round}
+	// [event] => {lamportTimestamp, frame}
 	type tr struct {
 		t, r int64
 	}
@@ -1522,7 +1600,8 @@ func TestDivideRoundsBis(t *testing.T) {
 		f2b: {3, 1},
 		f0:  {4, 1},
 		f1:  {5, 1},
-		g1:  {6, 2},
+		// without other-parent, g1 stays in the frame of f1
+		g1:  {6, 1},
 		g0:  {7, 2},
 		g2:  {7, 2},
 		g10: {8, 2},
@@ -1552,12 +1631,8 @@ func TestDivideRoundsBis(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if r := ev.GetRound(); r == RoundNIL || r != et.r {
-			disp := "nil"
-			if r >= 0 {
-				disp = strconv.FormatInt(r, 10)
-			}
-			t.Fatalf("%s round should be %d, not %s", e, et.r, disp)
+		if r := ev.Frame; r != et.r {
+			t.Fatalf("%s frame should be %d, not %d", e, et.r, r)
 		}
 		if ts := ev.GetLamportTimestamp(); ts == LamportTimestampNIL || ts != et.t {
 			disp := "nil"
@@ -1580,106 +1655,32 @@ func TestDecideAtropos(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	round0, err := p.Store.GetRoundCreated(0)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if f := round0.Message.Events[hashString(index[e0])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", e0, f)
-	}
-	if f := round0.Message.Events[hashString(index[e1])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", e1, f)
-	}
-	if f := round0.Message.Events[hashString(index[e2])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", e2, f)
-	}
-
-	round1, err := p.Store.GetRoundCreated(1)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if f := round1.Message.Events[hashString(index[f2])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", f2, f)
-	}
-	if f := round1.Message.Events[hashString(index[f0])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", f0, f)
-	}
-	if f := round1.Message.Events[hashString(index[f1])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", f1, f)
-	}
-
-	round2, err := p.Store.GetRoundCreated(2)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if f := round2.Message.Events[hashString(index[g1])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", g1, f)
-	}
-	if f := round2.Message.Events[hashString(index[g0])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", g0, f)
-	}
-	if f := round2.Message.Events[hashString(index[g2])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", g2, f)
+	event := func(name string) Event {
+		ev, err := p.Store.GetEventBlock(index[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ev
 	}
 
-	round3, err := p.Store.GetRoundCreated(3)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if f := round3.Message.Events[hashString(index[h2])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", h2, f)
-	}
-	if f := round3.Message.Events[hashString(index[h0])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", h0, f)
-	}
-	if f := round3.Message.Events[hashString(index[h10])]; !(f.Clotho &&
-		f.Atropos == Trilean_TRUE) {
-		t.Fatalf("%s should be Atropos; got %v", h10, f)
+	// the roots of frames 0 to 3 are decided Atropos
+	for _, name := range []string{e0, e1, e2, f2, f0, f1, g0, g2, g10, h2, h0, h10} {
+		if ev := event(name); !(ev.Clotho && ev.Atropos) {
+			t.Fatalf("%s should be Atropos; got clotho %v atropos %v", name, ev.Clotho, ev.Atropos)
+		}
 	}
 
-	round4, err := p.Store.GetRoundCreated(4)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if f := round4.Message.Events[hashString(index[i0])]; !(f.Clotho &&
-		f.Atropos == Trilean_UNDEFINED) {
-		t.Fatalf("%s should be Atropos; got %v", i0, f)
-	}
-	if f := round4.Message.Events[hashString(index[i2])]; !(f.Clotho &&
-		f.Atropos == Trilean_UNDEFINED) {
-		t.Fatalf("%s should be Atropos; got %v", i2, f)
-	}
-	if f := round4.Message.Events[hashString(index[i1])]; !(f.Clotho &&
-		f.Atropos == Trilean_UNDEFINED) {
-		t.Fatalf("%s should be Atropos; got %v", i1, f)
+	// those of frames 4 and 5 are Clothos not decided yet
+	for _, name := range []string{i0, i2, i1, j2, j0, j1} {
+		if ev := event(name); !ev.Clotho || ev.Atropos {
+			t.Fatalf("%s should be an undecided Clotho; got clotho %v atropos %v", name, ev.Clotho, ev.Atropos)
+		}
 	}
 
-	expectedPendingRounds := []pendingRound{
-		{Index: 0, Decided: true},
-		{Index: 1, Decided: true},
-		{Index: 2, Decided: true},
-		{Index: 3, Decided: true},
-		{Index: 4, Decided: false},
-		{Index: 5, Decided: true},
-		{Index: 6, Decided: false},
-		{Index: 7, Decided: false},
-		{Index: 8, Decided: false},
-	}
-	for i, pd := range p.PendingRounds {
-		if !reflect.DeepEqual(*pd, expectedPendingRounds[i]) {
-			t.Fatalf("pendingRounds[%d] should be %v, not %v",
-				i, expectedPendingRounds[i], *pd)
+	// the other events are not roots
+	for _, name := range []string{e10, f2b, g1, g0x, h0b} {
+		if ev := event(name); ev.Root || ev.Clotho || ev.Atropos {
+			t.Fatalf("%s should be no root; got root %v clotho %v atropos %v", name, ev.Root, ev.Clotho, ev.Atropos)
 		}
 	}
 }
@@ -1697,73 +1698,51 @@ func TestDecideRoundReceived(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	for name, hash := range index {
-		e, _ := p.Store.GetEventBlock(hash)
-
-		switch rune(name[0]) {
-		case rune('e'):
-			if r := e.roundReceived; r != 1 {
-				t.Fatalf("%s round received should be 1 not %d", name, r)
-			}
-		case rune('f'):
-			if r := e.roundReceived; r != 2 {
-				t.Fatalf("%s round received should be 2 not %d", name, r)
-			}
-		}
-	}
-
-	round0, err := p.Store.GetRoundCreated(0)
-	if err != nil {
-		t.Fatalf("could not retrieve Round 0. %s", err)
-	}
-	if ce := len(round0.ConsensusEvents()); ce != 0 {
-		t.Fatalf("round 0 should contain 0 ConsensusEvents, not %d", ce)
+	// no frame is received as 0, see HasFrameReceived: the events of frame 0
+	// are received with those of frame 1. The events above the last decided
+	// Atropos are not received yet.
+	expected := map[string]int64{
+		e0: 1, e1: 1, e2: 1, e10: 1,
+		f2: 1, f2b: 1, f0: 1, f1: 1,
+		g1: 2, g0: 2, g2: 2, g10: 2,
+		g0x: 3, h2: 3, h0: 3, h0b: 3, h10: 3,
+		i0: 0, i2: 0, i1: 0, j2: 0, j0: 0, j1: 0,
+		k0: 0, k2: 0, k10: 0, l2: 0, l0: 0, l1: 0, m0: 0, m2: 0,
 	}
 
-	round1, err := p.Store.GetRoundCreated(1)
-	if err != nil {
-		t.Fatalf("could not retrieve Round 1. %s", err)
-	}
-	if ce := len(round1.ConsensusEvents()); ce != 4 {
-		t.Fatalf("round 1 should contain 4 ConsensusEvents, not %d", ce)
+	received := 0
+	for name, fr := range expected {
+		e, err := p.Store.GetEventBlock(index[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := e.FrameReceived; r != fr {
+			t.Fatalf("%s frame received should be %d not %d", name, fr, r)
+		}
+		if fr != 0 {
+			received++
+		}
 	}
 
-	round2, err := p.Store.GetRoundCreated(2)
-	if err != nil {
-		t.Fatalf("could not retrieve Round 2. %s", err)
+	// the events received are the consensus events
+	consensus := make(map[EventHash]bool)
+	for _, hash := range p.Store.ConsensusEvents() {
+		consensus[hash] = true
 	}
-	if ce := len(round2.ConsensusEvents()); ce != 4 {
-		t.Fatalf("round 2 should contain 9 ConsensusEvents, not %d", ce)
+	if len(consensus) != received {
+		t.Fatalf("there should be %d ConsensusEvents, not %d", received, len(consensus))
 	}
-
-	expectedUndeterminedEvents := []EventHash{
-		index[g0x],
-		index[h2],
-		index[h0],
-		index[h0b],
-		index[h10],
-		index[j2],
-		index[j0],
-		index[j1],
-		index[k0],
-		index[k2],
-		index[k10],
-		index[l2],
-		index[l0],
-		index[l1],
-		index[m0],
-		index[m2],
-	}
-
-	for i, eue := range expectedUndeterminedEvents {
-		if ue := p.UndeterminedEvents[i]; ue != eue {
-			t.Fatalf("undetermined event %d should be %s, not %s", i, eue, ue)
+	for name, fr := range expected {
+		if fr != 0 && !consensus[index[name]] {
+			t.Fatalf("%s should be a ConsensusEvent", name)
 		}
 	}
 }
 
 func TestProcessDecidedRounds(t *testing.T) {
 	p, index := initConsensusPoset(false, t)
+	commitCh := make(chan Block, 10)
+	p.commitCh = commitCh
 
 	if err := p.DivideRounds(); err != nil {
 		t.Fatal(err)
@@ -1784,109 +1763,62 @@ func TestProcessDecidedRounds(t *testing.T) {
 		t.Logf("consensus[%d]: %s\n", i, getName(index, e))
 	}
 
-	if l := len(consensusEvents); l != 12 {
-		t.Fatalf("length of consensus should be 12 not %d", l)
-	}
-
-	if ple := p.GetPendingLoadedEvents(); ple != 3 {
-		t.Fatalf("pending loaded events number should be 3, not %d", ple)
-	}
-
-	block0, err := p.Store.GetBlock(0)
-	if err != nil {
-		t.Fatalf("store should contain a block with Index 0: %v", err)
-	}
-
-	if ind := block0.Index(); ind != 0 {
-		t.Fatalf("block0's index should be 0, not %d", ind)
-	}
-
-	if rr := block0.RoundReceived(); rr != 2 {
-		t.Fatalf("block0's round received should be 2, not %d", rr)
+	if l := len(consensusEvents); l != 17 {
+		t.Fatalf("length of consensus should be 17 not %d", l)
 	}
 
-	if l := len(block0.Transactions()); l != 1 {
-		t.Fatalf("block0 should contain 1 transaction, not %d", l)
-	}
-	if tx := block0.Transactions()[0]; !reflect.DeepEqual(tx, []byte(f2)) {
-		t.Fatalf("transaction 0 from block0 should be '%s', not %s", f2, tx)
+	// i1 and j0 carry transactions and are not received yet
+	if ple := p.GetPendingLoadedEvents(); ple != 2 {
+		t.Fatalf("pending loaded events number should be 2, not %d", ple)
 	}
 
-	frame1, err := p.GetFrame(block0.RoundReceived())
-	if err != nil {
-		t.Fatalf("frame should be returned: %v", err)
-	}
-	frame1Hash, err := frame1.Hash()
-	if err != nil {
-		t.Fatalf("Hash should be generated from frame: %v", err)
+	// frames 0 to 2 are final, each of them makes a block
+	blocks := committedBlocks(commitCh)
+	if l := len(blocks); l != 3 {
+		t.Fatalf("3 blocks should be committed, not %d", l)
 	}
-	if !reflect.DeepEqual(block0.GetFrameHash(), frame1Hash) {
-		t.Fatalf("frame hash from block0 should be %v, not %v",
-			frame1Hash, block0.GetFrameHash())
+	expectedTxs := [][][]byte{
+		nil,
+		{[]byte(f2), []byte(g1)},
+		nil,
 	}
+	for i, block := range blocks {
+		if ind := block.Index(); ind != int64(i) {
+			t.Fatalf("block%d's index should be %d, not %d", i, i, ind)
+		}
+		if rr := block.RoundReceived(); rr != int64(i) {
+			t.Fatalf("block%d's round received should be %d, not %d", i, i, rr)
+		}
+		if txs := block.Transactions(); !reflect.DeepEqual(txs, expectedTxs[i]) {
+			t.Fatalf("block%d's transactions should be %q, not %q", i, expectedTxs[i], txs)
+		}
 
-	block1, err := p.Store.GetBlock(1)
-	if err != nil {
-		t.Fatalf("store should contain a block with Index 1: %v", err)
+		frame, err := p.GetFrame(block.RoundReceived())
+		if err != nil {
+			t.Fatalf("frame should be returned: %v", err)
+		}
+		frameHash, err := frame.Hash()
+		if err != nil {
+			t.Fatalf("Hash should be generated from frame: %v", err)
+		}
+		if !reflect.DeepEqual(block.GetFrameHash(), frameHash) {
+			t.Fatalf("frame hash from block%d should be %v, not %v",
+				i, frameHash, block.GetFrameHash())
+		}
 	}
 
-	if ind := block1.Index(); ind != 1 {
-		t.Fatalf("block1's index should be 1, not %d", ind)
+	if v := p.AnchorBlock; v != nil {
+		t.Fatalf("anchor block should be nil, not %v", v)
 	}
 
-	if rr := block1.RoundReceived(); rr != 3 {
-		t.Fatalf("block1's round received should be 3, not %d", rr)
-	}
+}
 
-	if l := len(block1.Transactions()); l != 1 {
-		t.Fatalf("block1 should contain 1 transactions, not %d", l)
-	}
-
-	if tx := block1.Transactions()[0]; !reflect.DeepEqual(tx, []byte(g1)) {
-		t.Fatalf("transaction 0 from block1 should be '%s', not %s", g1, tx)
-	}
-
-	frame2, err := p.GetFrame(block1.RoundReceived())
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	frame2Hash, err := frame2.Hash()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if !reflect.DeepEqual(block1.GetFrameHash(), frame2Hash) {
-		t.Fatalf("frame hash from block1 should be %v, not %v",
-			frame2Hash, block1.GetFrameHash())
-	}
-
-	expRounds := []pendingRound{
-		{Index: 4, Decided: false},
-		{Index: 5, Decided: true},
-		{Index: 6, Decided: false},
-		{Index: 7, Decided: false},
-		{Index: 8, Decided: false},
-	}
-	for i, pd := range p.PendingRounds {
-		if !reflect.DeepEqual(*pd, expRounds[i]) {
-			t.Fatalf("pending round %d should be %v, not %v", i,
-				expRounds[i], *pd)
-		}
-	}
-
-	if v := p.AnchorBlock; v != nil {
-		t.Fatalf("anchor block should be nil, not %v", v)
-	}
-
-}
-
-func BenchmarkConsensus(b *testing.B) {
-	for n := 0; n < b.N; n++ {
-		// we do not want to benchmark the initialization code
-		b.StopTimer()
-		p, _ := initConsensusPoset(false, b)
-		b.StartTimer()
+func BenchmarkConsensus(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		// we do not want to benchmark the initialization code
+		b.StopTimer()
+		p, _ := initConsensusPoset(false, b)
+		b.StartTimer()
 
 		if err := p.DivideRounds(); err != nil {
 			b.Fatal(err)
@@ -1903,6 +1835,28 @@ func BenchmarkConsensus(b *testing.B) {
 	}
 }
 
+// BenchmarkRound2 measures round2 with debug logging filtered out, where
+// the hot path should not build any log fields
+func BenchmarkRound2(b *testing.B) {
+	p, index := initConsensusPoset(false, b)
+	p.logger.Logger.SetLevel(logrus.InfoLevel)
+
+	hashes := make([]EventHash, 0, len(index))
+	for _, h := range index {
+		hashes = append(hashes, h)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, h := range hashes {
+			if _, err := p.round2(h); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
 func TestKnown(t *testing.T) {
 	p, _ := initConsensusPoset(false, t)
 
@@ -1925,6 +1879,8 @@ func TestKnown(t *testing.T) {
 
 func TestGetFrame(t *testing.T) {
 	p, index := initConsensusPoset(false, t)
+	commitCh := make(chan Block, 10)
+	p.commitCh = commitCh
 
 	participants := p.Participants.ToPeerSlice()
 
@@ -1940,27 +1896,11 @@ func TestGetFrame(t *testing.T) {
 	if err := p.ProcessDecidedRounds(); err != nil {
 		t.Fatal(err)
 	}
+	blocks := committedBlocks(commitCh)
 
-	t.Run("round 1", func(t *testing.T) {
-		expRoots := make([]Root, n)
-		expRoots[0] = NewBaseRoot(participants[0].ID)
-		expRoots[1] = NewBaseRoot(participants[1].ID)
-		expRoots[2] = NewBaseRoot(participants[2].ID)
-
-		frame, err := p.GetFrame(1)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		for p, r := range frame.Roots {
-			expRoot := expRoots[p]
-			compareRootEvents(t, r.SelfParent, expRoot.SelfParent, index)
-			compareOtherParents(t, r.Others, expRoot.Others, index)
-		}
-
+	// the events of a frame are in their final order
+	checkEvents := func(t *testing.T, frame Frame, hashes EventHashes) {
 		var expEvents []Event
-
-		hashes := EventHashes{index[e0], index[e1], index[e2], index[e10]}
 		for _, eh := range hashes {
 			e, err := p.Store.GetEventBlock(eh)
 			if err != nil {
@@ -1969,7 +1909,7 @@ func TestGetFrame(t *testing.T) {
 			expEvents = append(expEvents, e)
 		}
 
-		sort.Sort(ByLamportTimestamp(expEvents))
+		sort.Sort(ByFinalOrder(expEvents))
 		expEventMessages := make([]*EventMessage, len(expEvents))
 		for k := range expEvents {
 			expEventMessages[k] = expEvents[k].Message
@@ -1984,18 +1924,37 @@ func TestGetFrame(t *testing.T) {
 		for k, msg := range expEventMessages {
 			compareEventMessages(t, messages[k], msg, index)
 		}
+	}
+
+	t.Run("frame 0", func(t *testing.T) {
+		expRoots := make([]Root, n)
+		expRoots[0] = NewBaseRoot(participants[0].ID)
+		expRoots[1] = NewBaseRoot(participants[1].ID)
+		expRoots[2] = NewBaseRoot(participants[2].ID)
+
+		frame, err := p.GetFrame(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for p, r := range frame.Roots {
+			expRoot := expRoots[p]
+			compareRootEvents(t, r.SelfParent, expRoot.SelfParent, index)
+			compareOtherParents(t, r.Others, expRoot.Others, index)
+		}
+
+		checkEvents(t, frame, EventHashes{index[e0], index[e1], index[e2], index[e10]})
 	})
 
-	t.Run("round 2", func(t *testing.T) {
+	t.Run("frame 1", func(t *testing.T) {
+		// the roots carry no rounds
 		expRoots := make([]Root, n)
 		expRoots[0] = Root{
-			NextRound: 1,
 			SelfParent: &RootEvent{
 				Hash:             hashBytes(index[e0]),
 				CreatorID:        participants[0].ID,
 				Index:            0,
 				LamportTimestamp: 0,
-				Round:            0,
 			},
 			Others: map[string]*RootEvent{
 				hashString(index[f0]): {
@@ -2003,18 +1962,15 @@ func TestGetFrame(t *testing.T) {
 					CreatorID:        participants[2].ID,
 					Index:            2,
 					LamportTimestamp: 3,
-					Round:            1,
 				},
 			},
 		}
 		expRoots[1] = Root{
-			NextRound: 1,
 			SelfParent: &RootEvent{
 				Hash:             hashBytes(index[e10]),
 				CreatorID:        participants[1].ID,
 				Index:            1,
 				LamportTimestamp: 1,
-				Round:            0,
 			},
 			Others: map[string]*RootEvent{
 				hashString(index[f1]): {
@@ -2022,18 +1978,15 @@ func TestGetFrame(t *testing.T) {
 					CreatorID:        participants[0].ID,
 					Index:            1,
 					LamportTimestamp: 4,
-					Round:            1,
 				},
 			},
 		}
 		expRoots[2] = Root{
-			NextRound: 1,
 			SelfParent: &RootEvent{
 				Hash:             hashBytes(index[e2]),
 				CreatorID:        participants[2].ID,
 				Index:            0,
 				LamportTimestamp: 0,
-				Round:            0,
 			},
 			Others: map[string]*RootEvent{
 				hashString(index[f2]): {
@@ -2041,12 +1994,11 @@ func TestGetFrame(t *testing.T) {
 					CreatorID:        participants[1].ID,
 					Index:            1,
 					LamportTimestamp: 1,
-					Round:            0,
 				},
 			},
 		}
 
-		frame, err := p.GetFrame(2)
+		frame, err := p.GetFrame(1)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -2057,49 +2009,17 @@ func TestGetFrame(t *testing.T) {
 			compareOtherParents(t, r.Others, expRoot.Others, index)
 		}
 
-		expectedEventsHashes := EventHashes{
-			index[f2],
-			index[f2b],
-			index[f0],
-			index[f1],
-		}
-		var expEvents []Event
-		for _, eh := range expectedEventsHashes {
-			e, err := p.Store.GetEventBlock(eh)
-			if err != nil {
-				t.Fatal(err)
-			}
-			expEvents = append(expEvents, e)
-		}
-		sort.Sort(ByLamportTimestamp(expEvents))
-		expEventMessages := make([]*EventMessage, len(expEvents))
-		for k := range expEvents {
-			expEventMessages[k] = expEvents[k].Message
-		}
-
-		messages := frame.GetEvents()
-		if len(expEventMessages) != len(messages) {
-			t.Fatalf("expected number of other parents: %d, got: %d",
-				len(expEventMessages), len(messages))
-		}
-
-		for k, msg := range expEventMessages {
-			compareEventMessages(t, messages[k], msg, index)
-		}
-
-		block0, err := p.Store.GetBlock(0)
-		if err != nil {
-			t.Fatalf("store should contain a block with Index 0: %v", err)
-		}
+		// without other-parent, g1 is in the frame of f1
+		checkEvents(t, frame, EventHashes{index[f2], index[f2b], index[f0], index[f1], index[g1]})
 
 		frameHash, err := frame.Hash()
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		if !reflect.DeepEqual(block0.GetFrameHash(), frameHash) {
-			t.Fatalf("frame hash (0x%X) from block 0 and frame hash"+
-				" (0x%X) differ", block0.GetFrameHash(), frameHash)
+		if !reflect.DeepEqual(blocks[1].GetFrameHash(), frameHash) {
+			t.Fatalf("frame hash (0x%X) from block 1 and frame hash"+
+				" (0x%X) differ", blocks[1].GetFrameHash(), frameHash)
 		}
 	})
 
@@ -2107,6 +2027,8 @@ func TestGetFrame(t *testing.T) {
 
 func TestResetFromFrame(t *testing.T) {
 	p, index := initConsensusPoset(false, t)
+	commitCh := make(chan Block, 10)
+	p.commitCh = commitCh
 
 	participants := p.Participants.ToPeerSlice()
 
@@ -2122,11 +2044,9 @@ func TestResetFromFrame(t *testing.T) {
 	if err := p.ProcessDecidedRounds(); err != nil {
 		t.Fatal(err)
 	}
+	blocks := committedBlocks(commitCh)
 
-	block, err := p.Store.GetBlock(1)
-	if err != nil {
-		t.Fatal(err)
-	}
+	block := blocks[1]
 
 	frame, err := p.GetFrame(block.RoundReceived())
 	if err != nil {
@@ -2134,17 +2054,21 @@ func TestResetFromFrame(t *testing.T) {
 	}
 
 	// This operation clears the private fields which need to be recomputed
-	// in the Events (round, roundReceived,etc)
+	// in the Events (frame, frameReceived,etc)
 	marshaledFrame, _ := frame.ProtoMarshal()
 	unmarshaledFrame := new(Frame)
 	if err := unmarshaledFrame.ProtoUnmarshal(marshaledFrame); err != nil {
 		t.Fatal(err)
 	}
 
-	p2 := NewPoset(p.Participants,
+	commitCh2 := make(chan Block, 10)
+	p2, err := NewPoset(p.Participants,
 		NewInmemStore(p.Participants, cacheSize, nil),
-		nil,
+		commitCh2,
 		testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
 	err = p2.Reset(block, *unmarshaledFrame)
 	if err != nil {
 		t.Fatal(err)
@@ -2153,22 +2077,23 @@ func TestResetFromFrame(t *testing.T) {
 	/*
 		The poset should now look like this:
 
-		   |   |  f10  |
-		   |   | / |   |
-		   |   f0  |   f2
-		   |   | \ | / |
-		   |   |  f1b  |
-		   |   |   |   |
-		   |   |   f1  |
+		   |   g1  |   |
 		   |   |   |   |
+		   |   f1  |   |
+		   | / |   |   |
+		   f0  |   |  f2b
+		   | \ |   |   |
+		   |   |   \   |
+		   |   |   |  f2
+		   |   |   | / |
 		   +-- R0  R1  R2
 	*/
 
 	// Test Known
 	expectedKnown := map[uint64]int64{
-		participants[0].ID: 2,
-		participants[1].ID: 4,
-		participants[2].ID: 3,
+		participants[0].ID: 1,
+		participants[1].ID: 3,
+		participants[2].ID: 2,
 	}
 
 	known := KnownEvents(p2.Store)
@@ -2181,57 +2106,40 @@ func TestResetFromFrame(t *testing.T) {
 	}
 	p2.Participants.RUnlock()
 
-	t.Run("TestDivideRounds", func(t *testing.T) {
-		if err := p2.DivideRounds(); err != nil {
-			t.Fatal(err)
-		}
-
-		pRound1, err := p.Store.GetRoundCreated(2)
-		if err != nil {
-			t.Fatal(err)
-		}
-		p2Round1, err := p2.Store.GetRoundCreated(2)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		// Check round 1 clothos
-		pClotho := pRound1.Clotho()
-		p2Clotho := p2Round1.Clotho()
-		sort.Sort(pClotho)
-		sort.Sort(p2Clotho)
-		if !reflect.DeepEqual(pClotho, p2Clotho) {
-			t.Fatalf("Reset Hg Round 1 clothos should be %v, not %v",
-				pClotho, p2Clotho)
-		}
-
-		// check event rounds and lamport timestamps
-		for _, em := range frame.Events {
-			e := em.ToEvent()
-			ev := &e
-			p2r, err := p2.round(ev.Hash())
+	// the events of the Reset poset are in the frames and have the lamport
+	// timestamps they have in the first one
+	compareEvents := func(t *testing.T, hashes EventHashes) {
+		for _, hash := range hashes {
+			ev, err := p.Store.GetEventBlock(hash)
 			if err != nil {
-				t.Fatalf("Error computing %s Round: %d",
-					getName(index, ev.Hash()), p2r)
-			}
-			hr, _ := p.round(ev.Hash())
-			if p2r != hr {
-
-				t.Fatalf("p2[%v].Round should be %d, not %d",
-					getName(index, ev.Hash()), hr, p2r)
+				t.Fatal(err)
 			}
-
-			p2s, err := p2.lamportTimestamp(ev.Hash())
+			ev2, err := p2.Store.GetEventBlock(hash)
 			if err != nil {
-				t.Fatalf("Error computing %s LamportTimestamp: %d",
-					getName(index, ev.Hash()), p2s)
+				t.Fatalf("Error getting %s: %v", getName(index, hash), err)
 			}
-			hs, _ := p.lamportTimestamp(ev.Hash())
-			if p2s != hs {
+			if ev2.Frame != ev.Frame || ev2.Root != ev.Root {
+				t.Fatalf("p2[%v] should be in frame %d as root %v, not %d as root %v",
+					getName(index, hash), ev.Frame, ev.Root, ev2.Frame, ev2.Root)
+			}
+			if ev2.LamportTimestamp != ev.LamportTimestamp {
 				t.Fatalf("p2[%v].LamportTimestamp should be %d, not %d",
-					getName(index, ev.Hash()), hs, p2s)
+					getName(index, hash), ev.LamportTimestamp, ev2.LamportTimestamp)
 			}
 		}
+	}
+
+	t.Run("TestDivideRounds", func(t *testing.T) {
+		if err := p2.DivideRounds(); err != nil {
+			t.Fatal(err)
+		}
+
+		var hashes EventHashes
+		for _, em := range frame.Events {
+			ev := em.ToEvent()
+			hashes = append(hashes, ev.Hash())
+		}
+		compareEvents(t, hashes)
 	})
 
 	t.Run("TestConsensus", func(t *testing.T) {
@@ -2251,8 +2159,8 @@ func TestResetFromFrame(t *testing.T) {
 		}
 
 		if r := p2.LastConsensusRound; r == nil || *r != block.RoundReceived() {
-			t.Fatalf("LastConsensusRound should be %d, not %d",
-				block.RoundReceived(), *r)
+			t.Fatalf("LastConsensusRound should be %d, not %v",
+				block.RoundReceived(), r)
 		}
 
 		if v := p2.AnchorBlock; v != nil {
@@ -2261,70 +2169,41 @@ func TestResetFromFrame(t *testing.T) {
 	})
 
 	t.Run("TestContinueAfterReset", func(t *testing.T) {
-		// Insert remaining Events into the Reset poset
-		for r := int64(2); r <= int64(2); r++ {
-			round, err := p.Store.GetRoundCreated(r)
+		// Insert the events above the frame of the block into the Reset
+		// poset, as they come from a peer
+		var events []Event
+		for _, hash := range index {
+			ev, err := p.Store.GetEventBlock(hash)
 			if err != nil {
-				t.Fatal(err)
+				continue
 			}
-
-			var events []Event
-			for _, e := range round.RoundEvents() {
-				ev, err := p.Store.GetEventBlock(e)
-				if err != nil {
-					t.Fatal(err)
-				}
+			if ev.Frame > block.RoundReceived() {
 				events = append(events, ev)
 			}
-
-			sort.Stable(ByTopologicalOrder(events))
-
-			for _, ev := range events {
-
-				marshaledEv, _ := ev.ProtoMarshal()
-				unmarshaledEv := new(Event)
-				if err := unmarshaledEv.ProtoUnmarshal(marshaledEv); err != nil {
-					t.Fatal(err)
-				}
-				if err := p2.InsertEvent(*unmarshaledEv, true); err != nil {
-					t.Fatal(err)
-				}
-			}
 		}
 
-		if err := p2.DivideRounds(); err != nil {
-			t.Fatal(err)
-		}
-		if err := p2.DecideAtropos(); err != nil {
-			t.Fatal(err)
-		}
-		if err := p2.DecideRoundReceived(); err != nil {
-			t.Fatal(err)
-		}
-		if err := p2.ProcessDecidedRounds(); err != nil {
-			t.Fatal(err)
-		}
+		sort.Stable(ByTopologicalOrder(events))
 
-		for r := int64(2); r <= 2; r++ {
-			pRound, err := p.Store.GetRoundCreated(r)
-			if err != nil {
+		var hashes EventHashes
+		for _, ev := range events {
+			marshaledEv, _ := ev.ProtoMarshal()
+			unmarshaledEv := new(Event)
+			if err := unmarshaledEv.ProtoUnmarshal(marshaledEv); err != nil {
 				t.Fatal(err)
 			}
-			p2Round, err := p2.Store.GetRoundCreated(r)
-			if err != nil {
+			if err := p2.InsertEvent(unmarshaledEv.Message.ToEvent(), true); err != nil {
 				t.Fatal(err)
 			}
+			hashes = append(hashes, ev.Hash())
+		}
 
-			pClotho := pRound.Clotho()
-			p2Clotho := p2Round.Clotho()
-			sort.Sort(pClotho)
-			sort.Sort(p2Clotho)
-
-			if !reflect.DeepEqual(pClotho, p2Clotho) {
-				t.Fatalf("Reset Hg Round %d clothos should be %v, not %v",
-					r, pClotho, p2Clotho)
-			}
+		if err := p2.RunToQuiescence(); err != nil {
+			t.Fatal(err)
 		}
+		compareEvents(t, hashes)
+
+		// the blocks go on as in the first poset
+		compareBlocks(t, blocks[2:], committedBlocks(commitCh2))
 	})
 }
 
@@ -2364,10 +2243,13 @@ func TestBootstrap(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	np := NewPoset(recycledStore.participants,
+	np, err := NewPoset(recycledStore.participants,
 		recycledStore,
 		nil,
 		logrus.New().WithField("id", "bootstrapped"))
+	if err != nil {
+		t.Fatal(err)
+	}
 	err = np.Bootstrap()
 	if err != nil {
 		t.Fatal(err)
@@ -2477,7 +2359,7 @@ func initFunkyPoset(t *testing.T, logger *logrus.Logger, full bool) (*Poset, map
 			EventHashes{selfParent, EventHash{}},
 			nodes[i].Pub,
 			0,
-			FlagTable{selfParent: 1})
+			FlagTable{selfParent: 1}, nil, 0, false)
 		nodes[i].signAndAddEvent(event, name, index, orderedEvents)
 	}
 
@@ -2561,37 +2443,30 @@ func TestFunkyPosetAtropos(t *testing.T) {
 	}
 
 	l := p.Store.LastRound()
-	if l != 7 {
-		t.Fatalf("last round should be 7 not %d", l)
+	if l != 5 {
+		t.Fatalf("last round should be 5 not %d", l)
 	}
 
-	for r := int64(0); r < l+1; r++ {
-		round, err := p.Store.GetRoundCreated(r)
+	event := func(name string) Event {
+		ev, err := p.Store.GetEventBlock(index[name])
 		if err != nil {
 			t.Fatal(err)
 		}
-		var clothoNames []string
-		for _, w := range round.Clotho() {
-			clothoNames = append(clothoNames, getName(index, w))
-		}
-		t.Logf("round %d clothos: %v", r, clothoNames)
+		return ev
 	}
 
-	expPendingRounds := []pendingRound{
-		{Index: 0, Decided: true},
-		{Index: 1, Decided: true},
-		{Index: 2, Decided: true},
-		{Index: 3, Decided: true},
-		{Index: 4, Decided: true},
-		{Index: 5, Decided: false},
-		{Index: 6, Decided: false},
-		{Index: 7, Decided: false},
+	// w00 has no descendant in frame 1 but a10, it is no Clotho
+	if ev := event(w00); !ev.Root || ev.Clotho {
+		t.Fatalf("%s should be a root but no Clotho", w00)
 	}
-
-	for i, pd := range p.PendingRounds {
-		if !reflect.DeepEqual(*pd, expPendingRounds[i]) {
-			t.Fatalf("pending round %d should be %v, not %v", i,
-				expPendingRounds[i], *pd)
+	for _, name := range []string{w01, w02, w03} {
+		if ev := event(name); !ev.Atropos {
+			t.Fatalf("%s should be Atropos", name)
+		}
+	}
+	for _, name := range []string{a12, a21, w13, w12, w11, w23} {
+		if ev := event(name); !ev.Clotho || ev.Atropos {
+			t.Fatalf("%s should be an undecided Clotho", name)
 		}
 	}
 
@@ -2602,17 +2477,16 @@ func TestFunkyPosetAtropos(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	remainingPendingRounds := expPendingRounds[5:]
-	for i := 0; i < len(p.PendingRounds); i++ {
-		if !reflect.DeepEqual(*p.PendingRounds[i], remainingPendingRounds[i]) {
-			t.Fatalf("remaining pending round %d should be %v, not %v", i,
-				remainingPendingRounds[i], *p.PendingRounds[i])
-		}
+	// without the Clotho of w00 decided, frame 0 is not final
+	if f := p.nextFinalFrame; f != 0 {
+		t.Fatalf("next final frame should be 0, not %d", f)
 	}
 }
 
 func TestFunkyPosetBlocks(t *testing.T) {
 	p, index := initFunkyPoset(t, common.NewTestLogger(t), true)
+	commitCh := make(chan Block, 10)
+	p.commitCh = commitCh
 
 	if err := p.DivideRounds(); err != nil {
 		t.Fatal(err)
@@ -2628,53 +2502,45 @@ func TestFunkyPosetBlocks(t *testing.T) {
 	}
 
 	l := p.Store.LastRound()
-	if l != 7 {
-		t.Fatalf("last round should be 7 not %d", l)
+	if l != 6 {
+		t.Fatalf("last round should be 6 not %d", l)
 	}
 
-	for r := int64(0); r < l+1; r++ {
-		round, err := p.Store.GetRoundCreated(r)
+	for _, name := range []string{w01, w02, w03, a12, a21, w13} {
+		ev, err := p.Store.GetEventBlock(index[name])
 		if err != nil {
 			t.Fatal(err)
 		}
-		var clothoNames []string
-		for _, w := range round.Clotho() {
-			clothoNames = append(clothoNames, getName(index, w))
+		if !ev.Atropos {
+			t.Fatalf("%s should be Atropos", name)
 		}
-		t.Logf("round %d clothos: %v", r, clothoNames)
 	}
 
-	// Rounds 0,1,2,3,4 and 5 should be decided.
-	expPendingRounds := []pendingRound{
-		{Index: 6, Decided: false},
-		{Index: 7, Decided: false},
+	// Frame 0 should be final, its block has the transactions of its events
+	blocks := committedBlocks(commitCh)
+	if len(blocks) != 1 {
+		t.Fatalf("1 block should be committed, not %d", len(blocks))
 	}
-	for i, pd := range p.PendingRounds {
-		if !reflect.DeepEqual(*pd, expPendingRounds[i]) {
-			t.Fatalf("pending round %d should be %v, not %v",
-				i, expPendingRounds[i], *pd)
-		}
-	}
-
-	expBlockTxCounts := map[int64]int64{0: 4, 1: 3, 2: 5, 3: 7, 4: 3}
+	expBlockTxs := map[int64][]string{0: {a00, a23, w00, w01, w02, w03}}
 
-	for bi := int64(0); bi < 5; bi++ {
-		b, err := p.Store.GetBlock(bi)
-		if err != nil {
-			t.Fatal(err)
-		}
+	for bi, b := range blocks {
+		var txs []string
 		for i, tx := range b.Transactions() {
 			t.Logf("block %d, tx %d: %s", bi, i, string(tx))
+			txs = append(txs, string(tx))
 		}
-		if txs := int64(len(b.Transactions())); txs != expBlockTxCounts[bi] {
-			t.Fatalf("Blocks[%d] should contain %d transactions, not %d", bi,
-				expBlockTxCounts[bi], txs)
+		sort.Strings(txs)
+		if exp := expBlockTxs[int64(bi)]; !reflect.DeepEqual(txs, exp) {
+			t.Fatalf("Blocks[%d] should contain the transactions %v, not %v", bi,
+				exp, txs)
 		}
 	}
 }
 
 func TestFunkyPosetFrames(t *testing.T) {
 	p, index := initFunkyPoset(t, common.NewTestLogger(t), true)
+	commitCh := make(chan Block, 10)
+	p.commitCh = commitCh
 
 	participants := p.Participants.ToPeerSlice()
 
@@ -2690,20 +2556,18 @@ func TestFunkyPosetFrames(t *testing.T) {
 	if err := p.ProcessDecidedRounds(); err != nil {
 		t.Fatal(err)
 	}
+	blocks := committedBlocks(commitCh)
 
-	for bi := int64(0); bi < 5; bi++ {
-		block, err := p.Store.GetBlock(bi)
+	for _, block := range blocks {
+		frame, err := p.GetFrame(block.RoundReceived())
 		if err != nil {
 			t.Fatal(err)
 		}
-
-		frame, err := p.GetFrame(block.RoundReceived())
 		for k, em := range frame.Events {
 			e := em.ToEvent()
-			ev := &e
-			r, _ := p.round(ev.Hash())
-			t.Logf("frame %d events %d: %s, round %d",
-				frame.Round, k, getName(index, ev.Hash()), r)
+			ev, _ := p.Store.GetEventBlock(e.Hash())
+			t.Logf("frame %d events %d: %s, frame %d",
+				frame.Round, k, getName(index, e.Hash()), ev.Frame)
 		}
 		for k, r := range frame.Roots {
 			t.Logf("frame %d root %d: next round %d, self parent: %v,"+
@@ -2713,267 +2577,21 @@ func TestFunkyPosetFrames(t *testing.T) {
 	}
 
 	expFrameRoots := map[int64][]Root{
-		1: {
+		0: {
 			NewBaseRoot(participants[0].ID),
 			NewBaseRoot(participants[1].ID),
 			NewBaseRoot(participants[2].ID),
 			NewBaseRoot(participants[3].ID),
 		},
-		2: {
-			NewBaseRoot(participants[0].ID),
-			{
-				NextRound: 1,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w01]),
-					CreatorID:        participants[1].ID,
-					Index:            0,
-					LamportTimestamp: 0,
-					Round:            0},
-				Others: map[string]*RootEvent{
-					hashString(index[a12]): {
-						Hash:             hashBytes(index[a23]),
-						CreatorID:        participants[2].ID,
-						Index:            1,
-						LamportTimestamp: 1,
-						Round:            0},
-				},
-			},
-			{
-				NextRound: 1,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[a23]),
-					CreatorID:        participants[2].ID,
-					Index:            1,
-					LamportTimestamp: 1,
-					Round:            0},
-				Others: map[string]*RootEvent{
-					hashString(index[a21]): {
-						Hash:             hashBytes(index[a12]),
-						CreatorID:        participants[1].ID,
-						Index:            1,
-						LamportTimestamp: 2,
-						Round:            1},
-				},
-			},
-			{
-				NextRound: 1,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w03]),
-					CreatorID:        participants[3].ID,
-					Index:            0,
-					LamportTimestamp: 0,
-					Round:            0},
-				Others: map[string]*RootEvent{
-					hashString(index[w13]): {
-						Hash:             hashBytes(index[a21]),
-						CreatorID:        participants[2].ID,
-						Index:            2,
-						LamportTimestamp: 3,
-						Round:            1},
-				},
-			},
-		},
-		3: {
-			NewBaseRoot(participants[0].ID),
-			{
-				NextRound: 1,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[a12]),
-					CreatorID:        participants[1].ID,
-					Index:            1,
-					LamportTimestamp: 2,
-					Round:            1},
-				Others: map[string]*RootEvent{
-					hashString(index[a10]): {
-						Hash:             hashBytes(index[a00]),
-						CreatorID:        participants[0].ID,
-						Index:            1,
-						LamportTimestamp: 1,
-						Round:            0},
-				},
-			},
-			{
-				NextRound: 2,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[a21]),
-					CreatorID:        participants[2].ID,
-					Index:            2,
-					LamportTimestamp: 3,
-					Round:            1},
-				Others: map[string]*RootEvent{
-					hashString(index[w12]): {
-						Hash:             hashBytes(index[w13]),
-						CreatorID:        participants[3].ID,
-						Index:            1,
-						LamportTimestamp: 4,
-						Round:            1},
-				},
-			},
-			{
-				NextRound: 1,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w03]),
-					CreatorID:        participants[3].ID,
-					Index:            0,
-					LamportTimestamp: 0,
-					Round:            0},
-				Others: map[string]*RootEvent{
-					hashString(index[w13]): {
-						Hash:             hashBytes(index[a21]),
-						CreatorID:        participants[2].ID,
-						Index:            2,
-						LamportTimestamp: 3,
-						Round:            1},
-				},
-			},
-		},
-		4: {
-			{
-				NextRound: 2,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[a00]),
-					CreatorID:        participants[0].ID,
-					Index:            1,
-					LamportTimestamp: 1,
-					Round:            0},
-				Others: map[string]*RootEvent{
-					hashString(index[w10]): {
-						Hash:             hashBytes(index[w11]),
-						CreatorID:        participants[1].ID,
-						Index:            3,
-						LamportTimestamp: 6,
-						Round:            2},
-				},
-			},
-			{
-				NextRound: 3,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w11]),
-					CreatorID:        participants[1].ID,
-					Index:            3,
-					LamportTimestamp: 6,
-					Round:            2},
-				Others: map[string]*RootEvent{
-					hashString(index[w21]): {
-						Hash:             hashBytes(index[w23]),
-						CreatorID:        participants[3].ID,
-						Index:            2,
-						LamportTimestamp: 8,
-						Round:            2},
-				},
-			},
-			{
-				NextRound: 2,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w12]),
-					CreatorID:        participants[2].ID,
-					Index:            3,
-					LamportTimestamp: 5,
-					Round:            2},
-				Others: map[string]*RootEvent{
-					hashString(index[b21]): {
-						Hash:             hashBytes(index[w11]),
-						CreatorID:        participants[1].ID,
-						Index:            3,
-						LamportTimestamp: 6,
-						Round:            2},
-				},
-			},
-			{
-				NextRound: 2,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w13]),
-					CreatorID:        participants[3].ID,
-					Index:            1,
-					LamportTimestamp: 4,
-					Round:            1},
-				Others: map[string]*RootEvent{
-					hashString(index[w23]): {
-						Hash:             hashBytes(index[b21]),
-						CreatorID:        participants[2].ID,
-						Index:            4,
-						LamportTimestamp: 7,
-						Round:            2},
-				},
-			},
-		},
-		5: {
-			{
-				NextRound: 4,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[b00]),
-					CreatorID:        participants[0].ID,
-					Index:            3,
-					LamportTimestamp: 8, Round: 3},
-				Others: map[string]*RootEvent{
-					hashString(index[w20]): {
-						Hash:             hashBytes(index[w22]),
-						CreatorID:        participants[2].ID,
-						Index:            5,
-						LamportTimestamp: 11,
-						Round:            3},
-				},
-			},
-			{
-				NextRound: 4,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[c10]),
-					CreatorID:        participants[1].ID,
-					Index:            5,
-					LamportTimestamp: 10,
-					Round:            3},
-				Others: map[string]*RootEvent{
-					hashString(index[w31]): {
-						Hash:             hashBytes(index[w20]),
-						CreatorID:        participants[0].ID,
-						Index:            4,
-						LamportTimestamp: 12,
-						Round:            4},
-				},
-			},
-			{
-				NextRound: 4,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w22]),
-					CreatorID:        participants[2].ID,
-					Index:            5,
-					LamportTimestamp: 11,
-					Round:            3},
-				Others: map[string]*RootEvent{
-					hashString(index[w32]): {
-						Hash:             hashBytes(index[w31]),
-						CreatorID:        participants[1].ID,
-						Index:            6,
-						LamportTimestamp: 13,
-						Round:            4},
-				},
-			},
-			{
-				NextRound: 2,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w13]),
-					CreatorID:        participants[3].ID,
-					Index:            1,
-					LamportTimestamp: 4,
-					Round:            1},
-				Others: map[string]*RootEvent{
-					hashString(index[w23]): {
-						Hash:             hashBytes(index[b21]),
-						CreatorID:        participants[2].ID,
-						Index:            4,
-						LamportTimestamp: 7,
-						Round:            2},
-				},
-			},
-		},
+	}
+	expFrameEvents := map[int64][]string{
+		0: {a00, a23, w00, w01, w02, w03},
 	}
 
-	for bi := int64(0); bi < 5; bi++ {
-		block, err := p.Store.GetBlock(bi)
-		if err != nil {
-			t.Fatal(err)
-		}
-
+	if len(blocks) != len(expFrameRoots) {
+		t.Fatalf("%d blocks should be committed, not %d", len(expFrameRoots), len(blocks))
+	}
+	for _, block := range blocks {
 		frame, err := p.GetFrame(block.RoundReceived())
 		if err != nil {
 			t.Fatal(err)
@@ -2982,11 +2600,23 @@ func TestFunkyPosetFrames(t *testing.T) {
 		for k, r := range frame.Roots {
 			compareRoots(t, r, &expFrameRoots[frame.Round][k], index)
 		}
+
+		var events []string
+		for _, em := range frame.Events {
+			e := em.ToEvent()
+			events = append(events, getName(index, e.Hash()))
+		}
+		sort.Strings(events)
+		if exp := expFrameEvents[frame.Round]; !reflect.DeepEqual(events, exp) {
+			t.Fatalf("frame %d should contain %v, not %v", frame.Round, exp, events)
+		}
 	}
 }
 
 func TestFunkyPosetReset(t *testing.T) {
 	p, index := initFunkyPoset(t, common.NewTestLogger(t), true)
+	commitCh := make(chan Block, 10)
+	p.commitCh = commitCh
 
 	if err := p.DivideRounds(); err != nil {
 		t.Fatal(err)
@@ -3000,30 +2630,33 @@ func TestFunkyPosetReset(t *testing.T) {
 	if err := p.ProcessDecidedRounds(); err != nil {
 		t.Fatal(err)
 	}
+	blocks := committedBlocks(commitCh)
+	if len(blocks) == 0 {
+		t.Fatal("a block should be committed")
+	}
 
-	for bi := int64(0); bi < 3; bi++ {
-		block, err := p.Store.GetBlock(bi)
-		if err != nil {
-			t.Fatal(err)
-		}
-
+	for bi, block := range blocks {
 		frame, err := p.GetFrame(block.RoundReceived())
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		// This operation clears the private fields which need to be recomputed
-		// in the Events (round, roundReceived,etc)
+		// in the Events (frame, frameReceived,etc)
 		marshaledFrame, _ := frame.ProtoMarshal()
 		unmarshaledFrame := new(Frame)
 		if err := unmarshaledFrame.ProtoUnmarshal(marshaledFrame); err != nil {
 			t.Fatal(err)
 		}
 
-		p2 := NewPoset(p.Participants,
+		commitCh2 := make(chan Block, 10)
+		p2, err := NewPoset(p.Participants,
 			NewInmemStore(p.Participants, cacheSize, nil),
-			nil,
+			commitCh2,
 			testLogger(t))
+		if err != nil {
+			t.Fatal(err)
+		}
 		err = p2.Reset(block, *unmarshaledFrame)
 		if err != nil {
 			t.Fatal(err)
@@ -3065,7 +2698,8 @@ func TestFunkyPosetReset(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		compareRoundClothos(p, p2, index, bi, true, t)
+		compareFrames(p, p2, index, block.RoundReceived(), t)
+		compareBlocks(t, blocks[bi+1:], committedBlocks(commitCh2))
 	}
 
 }
@@ -3141,7 +2775,7 @@ func initSparsePoset(
 			EventHashes{selfParent, EventHash{}},
 			nodes[i].Pub,
 			0,
-			FlagTable{selfParent: 1})
+			FlagTable{selfParent: 1}, nil, 0, false)
 		nodes[i].signAndAddEvent(event, name, index, orderedEvents)
 	}
 
@@ -3200,6 +2834,8 @@ func initSparsePoset(
 
 func TestSparsePosetFrames(t *testing.T) {
 	p, index := initSparsePoset(t, common.NewTestLogger(t))
+	commitCh := make(chan Block, 10)
+	p.commitCh = commitCh
 
 	participants := p.Participants.ToPeerSlice()
 
@@ -3215,337 +2851,67 @@ func TestSparsePosetFrames(t *testing.T) {
 	if err := p.ProcessDecidedRounds(); err != nil {
 		t.Fatal(err)
 	}
+	blocks := committedBlocks(commitCh)
 
-	for bi := int64(0); bi < 5; bi++ {
-		block, err := p.Store.GetBlock(bi)
+	for _, block := range blocks {
+		frame, err := p.GetFrame(block.RoundReceived())
 		if err != nil {
 			t.Fatal(err)
 		}
-
-		frame, err := p.GetFrame(block.RoundReceived())
-		for k, ev := range frame.Events {
-			if _, err := ev.Body.Hash(); err != nil {
-				t.Fatal(err)
-			}
-			hash, err := ev.Body.Hash()
-			if err != nil {
-				t.Fatal(err)
-			}
-			r, err := p.round(hash)
-			if err != nil {
-				t.Fatal(err)
-			}
-			t.Logf("frame %d event %d: %s, round %d",
-				frame.Round, k, getName(index, hash), r)
+		for k, em := range frame.Events {
+			e := em.ToEvent()
+			ev, _ := p.Store.GetEventBlock(e.Hash())
+			t.Logf("frame %d events %d: %s, frame %d",
+				frame.Round, k, getName(index, e.Hash()), ev.Frame)
 		}
 		for k, r := range frame.Roots {
-			var hash EventHash
-			hash.Set(r.SelfParent.Hash)
-			sp := getName(index, hash)
-			var ops []string
-			for k := range r.Others {
-				_ = hash.Parse(k)
-				ops = append(ops, getName(index, hash))
-			}
-
-			t.Logf("frame %d root %d: self parent index %s:"+
-				" %v, others indexes %s: %v", frame.Round, k, sp,
-				r.SelfParent, ops, r.Others)
+			t.Logf("frame %d root %d: next round %d, self parent: %v,"+
+				" others: %v", frame.Round, k, r.NextRound,
+				r.SelfParent, r.Others)
 		}
 	}
 
-	expectedFrameRoots := map[int64][]Root{
-		1: {
+	expFrameRoots := map[int64][]Root{
+		0: {
 			NewBaseRoot(participants[0].ID),
 			NewBaseRoot(participants[1].ID),
 			NewBaseRoot(participants[2].ID),
 			NewBaseRoot(participants[3].ID),
 		},
-		2: {
-			{
-				NextRound: 1,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w00]),
-					CreatorID:        participants[0].ID,
-					Index:            0,
-					LamportTimestamp: 0,
-					Round:            0},
-				Others: map[string]*RootEvent{
-					hashString(index[w10]): {
-						Hash:             hashBytes(index[e32]),
-						CreatorID:        participants[3].ID,
-						Index:            1,
-						LamportTimestamp: 3,
-						Round:            1},
-				},
-			},
-			{
-				NextRound: 0,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w01]),
-					CreatorID:        participants[1].ID,
-					Index:            0,
-					LamportTimestamp: 0,
-					Round:            0},
-				Others: map[string]*RootEvent{
-					hashString(index[e10]): {
-						Hash:             hashBytes(index[w00]),
-						CreatorID:        participants[0].ID,
-						Index:            0,
-						LamportTimestamp: 0,
-						Round:            0},
-				},
-			},
-			{
-				NextRound: 1,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w02]),
-					CreatorID:        participants[2].ID,
-					Index:            0,
-					LamportTimestamp: 0,
-					Round:            0},
-				Others: map[string]*RootEvent{
-					hashString(index[e21]): {
-						Hash:             hashBytes(index[e10]),
-						CreatorID:        participants[1].ID,
-						Index:            1,
-						LamportTimestamp: 1,
-						Round:            0},
-				},
-			},
-			NewBaseRoot(participants[3].ID),
-		},
-		3: {
-			{
-				NextRound: 2,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w10]),
-					CreatorID:        participants[0].ID,
-					Index:            1,
-					LamportTimestamp: 4,
-					Round:            1},
-				Others: map[string]*RootEvent{
-					hashString(index[f01]): {
-						Hash:             hashBytes(index[w11]),
-						CreatorID:        participants[1].ID,
-						Index:            2,
-						LamportTimestamp: 5,
-						Round:            2},
-				},
-			},
-			{
-				NextRound: 2,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[e10]),
-					CreatorID:        participants[1].ID,
-					Index:            1,
-					LamportTimestamp: 1,
-					Round:            0},
-				Others: map[string]*RootEvent{
-					hashString(index[w11]): {
-						Hash:             hashBytes(index[w10]),
-						CreatorID:        participants[0].ID,
-						Index:            1,
-						LamportTimestamp: 4,
-						Round:            1},
-				},
-			},
-			{
-				NextRound: 2,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[e21]),
-					CreatorID:        participants[2].ID,
-					Index:            1,
-					LamportTimestamp: 2,
-					Round:            1},
-				Others: map[string]*RootEvent{
-					hashString(index[w12]): {
-						Hash:             hashBytes(index[f01]),
-						CreatorID:        participants[0].ID,
-						Index:            2,
-						LamportTimestamp: 6,
-						Round:            2},
-				},
-			},
-			{
-				NextRound: 1,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w03]),
-					CreatorID:        participants[3].ID,
-					Index:            0,
-					LamportTimestamp: 0,
-					Round:            0},
-				Others: map[string]*RootEvent{
-					hashString(index[e32]): {
-						Hash:             hashBytes(index[e21]),
-						CreatorID:        participants[2].ID,
-						Index:            1,
-						LamportTimestamp: 2,
-						Round:            1},
-				},
-			},
-		},
-		4: {
-			{
-				NextRound: 2,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w10]),
-					CreatorID:        participants[0].ID,
-					Index:            1,
-					LamportTimestamp: 4,
-					Round:            1},
-				Others: map[string]*RootEvent{
-					hashString(index[f01]): {
-						Hash:             hashBytes(index[w11]),
-						CreatorID:        participants[1].ID,
-						Index:            2,
-						LamportTimestamp: 5,
-						Round:            2},
-				},
-			},
-			{
-				NextRound: 3,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w11]),
-					CreatorID:        participants[1].ID,
-					Index:            2,
-					LamportTimestamp: 5,
-					Round:            2},
-				Others: map[string]*RootEvent{
-					hashString(index[w21]): {
-						Hash:             hashBytes(index[w13]),
-						CreatorID:        participants[3].ID,
-						Index:            2,
-						LamportTimestamp: 8,
-						Round:            3},
-				},
-			},
-			{
-				NextRound: 3,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w12]),
-					CreatorID:        participants[2].ID,
-					Index:            2,
-					LamportTimestamp: 7,
-					Round:            2},
-				Others: map[string]*RootEvent{
-					hashString(index[w22]): {
-						Hash:             hashBytes(index[w21]),
-						CreatorID:        participants[1].ID,
-						Index:            3,
-						LamportTimestamp: 9,
-						Round:            3},
-				},
-			},
-			{
-				NextRound: 3,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[e32]),
-					CreatorID:        participants[3].ID,
-					Index:            1,
-					LamportTimestamp: 3,
-					Round:            1},
-				Others: map[string]*RootEvent{
-					hashString(index[w13]): {
-						Hash:             hashBytes(index[w12]),
-						CreatorID:        participants[2].ID,
-						Index:            2,
-						LamportTimestamp: 7,
-						Round:            2},
-				},
-			},
-		},
-		5: {
-			{
-				NextRound: 2,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w10]),
-					CreatorID:        participants[0].ID,
-					Index:            1,
-					LamportTimestamp: 4,
-					Round:            1},
-				Others: map[string]*RootEvent{
-					hashString(index[f01]): {
-						Hash:             hashBytes(index[w11]),
-						CreatorID:        participants[1].ID,
-						Index:            2,
-						LamportTimestamp: 5,
-						Round:            2},
-				},
-			},
-			{
-				NextRound: 4,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w21]),
-					CreatorID:        participants[1].ID,
-					Index:            3,
-					LamportTimestamp: 9,
-					Round:            3},
-				Others: map[string]*RootEvent{
-					hashString(index[g13]): {
-						Hash:             hashBytes(index[w23]),
-						CreatorID:        participants[3].ID,
-						Index:            3,
-						LamportTimestamp: 11,
-						Round:            4},
-				},
-			},
-			{
-				NextRound: 4,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w22]),
-					CreatorID:        participants[2].ID,
-					Index:            3,
-					LamportTimestamp: 10,
-					Round:            3},
-				Others: map[string]*RootEvent{
-					hashString(index[w32]): {
-						Hash:             hashBytes(index[g13]),
-						CreatorID:        participants[1].ID,
-						Index:            4,
-						LamportTimestamp: 12,
-						Round:            4},
-				},
-			},
-			{
-				NextRound: 4,
-				SelfParent: &RootEvent{
-					Hash:             hashBytes(index[w13]),
-					CreatorID:        participants[3].ID,
-					Index:            2,
-					LamportTimestamp: 8,
-					Round:            3},
-				Others: map[string]*RootEvent{
-					hashString(index[w23]): {
-						Hash:             hashBytes(index[w22]),
-						CreatorID:        participants[2].ID,
-						Index:            3,
-						LamportTimestamp: 10,
-						Round:            3},
-				},
-			},
-		},
+	}
+	expFrameEvents := map[int64][]string{
+		0: {e10, w00, w01, w02, w03},
 	}
 
-	for bi := int64(0); bi < 5; bi++ {
-		block, err := p.Store.GetBlock(bi)
-		if err != nil {
-			t.Fatal(err)
-		}
-
+	if len(blocks) != len(expFrameRoots) {
+		t.Fatalf("%d blocks should be committed, not %d", len(expFrameRoots), len(blocks))
+	}
+	for _, block := range blocks {
 		frame, err := p.GetFrame(block.RoundReceived())
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		for k, r := range frame.Roots {
-			compareRoots(t, r, &expectedFrameRoots[frame.Round][k], index)
+			compareRoots(t, r, &expFrameRoots[frame.Round][k], index)
+		}
+
+		var events []string
+		for _, em := range frame.Events {
+			e := em.ToEvent()
+			events = append(events, getName(index, e.Hash()))
+		}
+		sort.Strings(events)
+		if exp := expFrameEvents[frame.Round]; !reflect.DeepEqual(events, exp) {
+			t.Fatalf("frame %d should contain %v, not %v", frame.Round, exp, events)
 		}
 	}
 }
 
 func TestSparsePosetReset(t *testing.T) {
 	p, index := initSparsePoset(t, common.NewTestLogger(t))
+	commitCh := make(chan Block, 10)
+	p.commitCh = commitCh
 
 	if err := p.DivideRounds(); err != nil {
 		t.Fatal(err)
@@ -3559,44 +2925,43 @@ func TestSparsePosetReset(t *testing.T) {
 	if err := p.ProcessDecidedRounds(); err != nil {
 		t.Fatal(err)
 	}
+	blocks := committedBlocks(commitCh)
+	if len(blocks) == 0 {
+		t.Fatal("a block should be committed")
+	}
 
-	for bi := 0; bi < 5; bi++ {
-		block, err := p.Store.GetBlock(int64(bi))
-		if err != nil {
-			t.Fatal(err)
-		}
-
+	for bi, block := range blocks {
 		frame, err := p.GetFrame(block.RoundReceived())
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		// This operation clears the private fields which need to be recomputed
-		// in the Events (round, roundReceived,etc)
+		// in the Events (frame, frameReceived,etc)
 		marshaledFrame, _ := frame.ProtoMarshal()
 		unmarshaledFrame := new(Frame)
 		if err := unmarshaledFrame.ProtoUnmarshal(marshaledFrame); err != nil {
 			t.Fatal(err)
 		}
 
-		p2 := NewPoset(p.Participants,
+		commitCh2 := make(chan Block, 10)
+		p2, err := NewPoset(p.Participants,
 			NewInmemStore(p.Participants, cacheSize, nil),
-			nil,
+			commitCh2,
 			testLogger(t))
+		if err != nil {
+			t.Fatal(err)
+		}
 		err = p2.Reset(block, *unmarshaledFrame)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		// Test continue after Reset
-
+		// Test continue after reset
 		// Compute diff
 		p2Known := KnownEvents(p2.Store)
 		diff := getDiff(p, p2Known, t)
 
-		t.Logf("p2.Known: %v", p2Known)
-		t.Logf("diff: %v", len(diff))
-
 		wireDiff := make([]WireEvent, len(diff))
 		for i, e := range diff {
 			wireDiff[i] = e.ToWire()
@@ -3604,15 +2969,14 @@ func TestSparsePosetReset(t *testing.T) {
 
 		// Insert remaining Events into the Reset poset
 		for i, wev := range wireDiff {
-			eventName := getName(index, diff[i].Hash())
 			ev, err := p2.ReadWireInfo(wev)
 			if err != nil {
-				t.Fatalf("ReadWireInfo(%s): %s", eventName, err)
+				t.Fatalf("Reading WireInfo for %s: %s",
+					getName(index, diff[i].Hash()), err)
 			}
-			compareEventMessages(t, ev.Message, diff[i].Message, index)
 			err = p2.InsertEvent(*ev, false)
 			if err != nil {
-				t.Fatalf("InsertEvent(%s): %s", eventName, err)
+				t.Fatal(err)
 			}
 		}
 
@@ -3629,41 +2993,48 @@ func TestSparsePosetReset(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		compareRoundClothos(p, p2, index, int64(bi), true, t)
+		compareFrames(p, p2, index, block.RoundReceived(), t)
+		compareBlocks(t, blocks[bi+1:], committedBlocks(commitCh2))
 	}
 
 }
 
-func compareRoundClothos(p, p2 *Poset, index map[string]EventHash, round int64, check bool, t *testing.T) {
-	for i := round; i <= 5; i++ {
-		pRound, err := p.Store.GetRoundCreated(i)
-		if err != nil {
-			t.Fatal(err)
+// compareFrames checks the events of p above a frame are in the same
+// frames of p2, reset from the block of the frame, with the same Clothos
+// and Atropos
+func compareFrames(p, p2 *Poset, index map[string]EventHash, frame int64, t *testing.T) {
+	for name, hash := range index {
+		ev, err := p.Store.GetEventBlock(hash)
+		if err != nil || ev.Frame <= frame {
+			continue
 		}
-		p2Round, err := p2.Store.GetRoundCreated(i)
+		ev2, err := p2.Store.GetEventBlock(hash)
 		if err != nil {
-			t.Fatal(err)
-		}
-
-		// Check Round1 Clotho
-		pClotho := pRound.Clotho()
-		p2Clotho := p2Round.Clotho()
-		sort.Sort(pClotho)
-		sort.Sort(p2Clotho)
-		pwn := make([]string, len(pClotho))
-		p2wn := make([]string, len(p2Clotho))
-		for _, w := range pClotho {
-			pwn = append(pwn, getName(index, w))
+			t.Fatalf("Reset poset should contain %s: %v", name, err)
 		}
-		for _, w := range p2Clotho {
-			p2wn = append(p2wn, getName(index, w))
+		if ev2.Frame != ev.Frame || ev2.Root != ev.Root ||
+			ev2.Clotho != ev.Clotho || ev2.Atropos != ev.Atropos {
+			t.Fatalf("Reset %s should be in frame %d (root %v, clotho %v, atropos %v),"+
+				" not %d (root %v, clotho %v, atropos %v)", name,
+				ev.Frame, ev.Root, ev.Clotho, ev.Atropos,
+				ev2.Frame, ev2.Root, ev2.Clotho, ev2.Atropos)
 		}
+	}
+}
 
-		if check && !reflect.DeepEqual(pwn, p2wn) {
-			t.Fatalf("Reset Hg Round %d clothos should be %v, not %v", i, pwn, p2wn)
+// compareBlocks checks the blocks a reset poset commits are those the
+// other one committed after the block of the reset
+func compareBlocks(t *testing.T, exp, blocks []Block) {
+	if len(blocks) != len(exp) {
+		t.Fatalf("Reset poset should commit %d blocks, not %d", len(exp), len(blocks))
+	}
+	for i, b := range blocks {
+		if b.Index() != exp[i].Index() ||
+			!reflect.DeepEqual(b.GetFrameHash(), exp[i].GetFrameHash()) ||
+			!reflect.DeepEqual(b.Transactions(), exp[i].Transactions()) {
+			t.Fatalf("Reset block %d should be %v, not %v", i, exp[i], b)
 		}
 	}
-
 }
 
 func getDiff(p *Poset, known map[uint64]int64, t *testing.T) []Event {
@@ -3673,7 +3044,7 @@ func getDiff(p *Poset, known map[uint64]int64, t *testing.T) []Event {
 		if !ok {
 			t.Fatal(fmt.Errorf("participant with ID %v not found", id))
 		}
-		pk := peer.PubKeyHex
+		pk := peer.Message.PubKeyHex
 		// get participant Events with index > ct
 		participantEvents, err := p.Store.ParticipantEvents(pk, ct)
 		if err != nil {
@@ -3744,9 +3115,7 @@ func compareRoots(t *testing.T, x, exp *Root, index map[string]EventHash) {
 }
 
 func compareEventMessages(t *testing.T, x, exp *EventMessage, index map[string]EventHash) {
-	if !reflect.DeepEqual(x.ClothoProof, exp.ClothoProof) ||
-		!bytes.Equal(x.FlagTable, exp.FlagTable) ||
-		x.Signature != exp.Signature {
+	if x.Signature != exp.Signature {
 		hash, _ := exp.Body.Hash()
 		t.Fatalf("expcted message to event %s: %v, got: %v",
 			getName(index, hash), exp, x)
@@ -3775,3 +3144,193 @@ func hashBytes(h EventHash) []byte {
 func hashString(h EventHash) string {
 	return h.String()
 }
+
+// initGossipEvents returns count events per participant where each event has
+// the previous event of the next participant as other-parent
+func initGossipEvents(n, count int) ([]Event, *peers.Peers) {
+	participants := peers.NewPeers()
+	keys := make(map[string]*ecdsa.PrivateKey)
+	for i := 0; i < n; i++ {
+		key, _ := crypto.GenerateECDSAKey()
+		pubHex := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey))
+		participants.AddPeer(peers.NewPeer(pubHex, ""))
+		keys[pubHex] = key
+	}
+
+	var (
+		nodes  []TestNode
+		last   []EventHash
+		events []Event
+	)
+	for _, peer := range participants.ToPeerSlice() {
+		nodes = append(nodes, NewTestNode(keys[peer.Message.PubKeyHex]))
+		last = append(last, GenRootSelfParent(peer.ID))
+	}
+	for index := 0; index < count; index++ {
+		for i := range nodes {
+			otherParent := EventHash{}
+			if index > 0 {
+				otherParent = last[(i+1)%n]
+			}
+			ev := NewEvent([][]byte{[]byte(fmt.Sprintf("tx %d %d", i, index))}, nil, nil,
+				EventHashes{last[i], otherParent}, nodes[i].Pub, int64(index),
+				FlagTable{last[i]: 1}, NewFlagTable(), FrameNIL, false)
+			if err := ev.Sign(nodes[i].Key); err != nil {
+				panic(err)
+			}
+			last[i] = ev.Hash()
+			events = append(events, ev)
+		}
+	}
+
+	return events, participants
+}
+
+func TestConcurrentInsertEventAndDecideRoundReceived(t *testing.T) {
+	events, participants := initGossipEvents(4, 50)
+	// big enough a cache for the whole DAG
+	p, err := NewPoset(participants, NewInmemStore(participants, 2*len(events), nil),
+		nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		for _, ev := range events {
+			if err := p.InsertEvent(ev, false); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	var insertErr error
+	for running := true; running; {
+		select {
+		case insertErr = <-done:
+			running = false
+		default:
+		}
+		if err := p.DecideRoundReceived(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if insertErr != nil {
+		t.Fatal(insertErr)
+	}
+
+	// every event is either still undetermined, in insertion order, or has
+	// been received, never both and never lost
+	position := make(map[EventHash]int, len(events))
+	for i, ev := range events {
+		position[ev.Hash()] = i
+	}
+	undetermined := make(map[EventHash]bool)
+	prev := -1
+	for _, hash := range p.GetUndeterminedEvents() {
+		i, ok := position[hash]
+		if !ok || undetermined[hash] {
+			t.Fatalf("unexpected undetermined event %s", hash)
+		}
+		if i < prev {
+			t.Fatalf("undetermined event %s out of order", hash)
+		}
+		prev = i
+		undetermined[hash] = true
+	}
+	received := make(map[EventHash]bool)
+	for r := int64(0); r <= p.Store.LastRound(); r++ {
+		roundReceived, err := p.Store.GetRoundReceived(r)
+		if err != nil {
+			continue
+		}
+		for _, b := range roundReceived.Rounds {
+			var hash EventHash
+			hash.Set(b)
+			if received[hash] {
+				t.Fatalf("event %s received twice", hash)
+			}
+			received[hash] = true
+		}
+	}
+	for _, ev := range events {
+		hash := ev.Hash()
+		if received[hash] == undetermined[hash] {
+			t.Fatalf("event %s: received %v, undetermined %v",
+				hash, received[hash], undetermined[hash])
+		}
+	}
+}
+
+func BenchmarkConcurrentInsertEventAndDecideRoundReceived(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		events, participants := initGossipEvents(8, 30)
+		p, err := NewPoset(participants, NewInmemStore(participants, 2*len(events), nil),
+			nil, testLogger(b))
+		if err != nil {
+			b.Fatal(err)
+		}
+		stop := make(chan struct{})
+		stopped := make(chan struct{})
+		b.StartTimer()
+
+		go func() {
+			defer close(stopped)
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if err := p.DecideRoundReceived(); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}()
+		for _, ev := range events {
+			if err := p.InsertEvent(ev, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		b.StopTimer()
+		close(stop)
+		<-stopped
+		b.StartTimer()
+	}
+}
+
+func TestInsertEventAlreadyKnown(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a0 := capEvent(participants, keys[0], nil, EventHash{}, "a0")
+	a1 := capEvent(participants, keys[0], &a0, EventHash{}, "a1")
+	for _, ev := range []Event{a0, a1} {
+		if err := p.InsertEvent(ev, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// both the last and an older event are known, verified or not
+	for _, ev := range []Event{a0, a1} {
+		if err := p.InsertEvent(ev, false); err != ErrAlreadyKnown {
+			t.Fatalf("event %d: expected ErrAlreadyKnown, got %v", ev.Index(), err)
+		}
+		if err := p.InsertPreverifiedEvent(ev, false); err != ErrAlreadyKnown {
+			t.Fatalf("event %d: expected ErrAlreadyKnown, got %v", ev.Index(), err)
+		}
+	}
+
+	// a fork at a known index is no known event
+	fork := capEvent(participants, keys[0], &a0, EventHash{}, "a1'")
+	if err := p.InsertEvent(fork, false); err == nil || err == ErrAlreadyKnown {
+		t.Fatalf("expected the fork refused, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package poset
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// randomDAG is a random DAG for the tests checking an implementation against
+// the one it replaced. The same seed makes the same DAG, but for the keys.
+type randomDAG struct {
+	rng *rand.Rand
+	// participants have a weight of 1, keys are theirs in order of creation
+	participants *peers.Peers
+	keys         []*ecdsa.PrivateKey
+	// roots are the base roots of the events, or the frames of roots
+	roots []Event
+	// events are in creation order
+	events []Event
+}
+
+// newRandomDAG returns a DAG of n participants without events
+func newRandomDAG(n int, seed int64, t testing.TB) *randomDAG {
+	d := &randomDAG{
+		rng:          rand.New(rand.NewSource(seed)),
+		participants: peers.NewPeers(),
+	}
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateECDSAKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		peer := peers.NewPeer(fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)),
+			fmt.Sprintf("127.0.0.1:%d", 1337+i))
+		d.participants.AddPeer(peer)
+		d.participants.SetPeerWeight(peer, 1)
+		d.keys = append(d.keys, key)
+	}
+	return d
+}
+
+// newGossipDAG returns a DAG of events made by random participants on their
+// last event and the last event of another one, from the first event
+func newGossipDAG(n, events int, seed int64, t testing.TB) *randomDAG {
+	return newRandomDAG(n, seed, t).gossip(events)
+}
+
+// newBatchDAG returns a DAG of gossip events over a base of frame 0 roots,
+// to be inserted with InsertEvent
+func newBatchDAG(n, events int, seed int64, t testing.TB) *randomDAG {
+	return newRandomDAG(n, seed, t).baseRoots().gossip(events)
+}
+
+// newRootDAG returns a DAG of frames of roots: each root of a frame has in
+// its root table a random supermajority of the roots of the frame before
+func newRootDAG(n, frames int, seed int64, t testing.TB) *randomDAG {
+	return newRandomDAG(n, seed, t).rootFrames(frames)
+}
+
+// peer returns the participant of the key i
+func (d *randomDAG) peer(i int) *peers.Peer {
+	return d.participants.ByPubKey[fmt.Sprintf("0x%X", crypto.FromECDSAPub(&d.keys[i].PublicKey))]
+}
+
+// copyParticipants returns fresh participants with the keys of the DAG, of
+// the default weight
+func (d *randomDAG) copyParticipants() *peers.Peers {
+	participants := peers.NewPeers()
+	for i := range d.keys {
+		peer := d.peer(i)
+		participants.AddPeer(peers.NewPeer(peer.Message.PubKeyHex, peer.Message.NetAddr))
+	}
+	return participants
+}
+
+// baseRoots adds a frame 0 root for each participant, the first self parent
+// and other parent of the gossip
+func (d *randomDAG) baseRoots() *randomDAG {
+	for i := range d.keys {
+		ev := capEvent(d.participants, d.keys[i], nil, EventHash{}, fmt.Sprintf("root %d", i))
+		ev.Message.CreatorID = d.peer(i).ID
+		ev.Frame, ev.Root, ev.LamportTimestamp = 0, true, 0
+		ev.FlagTableBytes = FlagTable{ev.Hash(): 0}.Marshal()
+		d.roots = append(d.roots, ev)
+	}
+	return d
+}
+
+// gossip adds events made by random participants on their last event and
+// the last event of another one, when it has one
+func (d *randomDAG) gossip(events int) *randomDAG {
+	n := len(d.keys)
+	heads := make([]*Event, n)
+	for i := range d.roots {
+		heads[i] = &d.roots[i]
+	}
+	for i := 0; i < events; i++ {
+		c := d.rng.Intn(n)
+		other := EventHash{}
+		if head := heads[(c+1+d.rng.Intn(n-1))%n]; head != nil {
+			other = head.Hash()
+		}
+		ev := capEvent(d.participants, d.keys[c], heads[c], other, fmt.Sprintf("event %d", i))
+		ev.Message.CreatorID = d.peer(c).ID
+		d.events = append(d.events, ev)
+		heads[c] = &d.events[len(d.events)-1]
+	}
+	return d
+}
+
+// rootFrames adds frames of roots, unsigned, each with a random
+// supermajority of the roots of the frame before in its root table
+func (d *randomDAG) rootFrames(frames int) *randomDAG {
+	n := len(d.keys)
+	superMajority := 2*n/3 + 1
+	var prev EventHashes
+	for frame := 0; frame < frames; frame++ {
+		var current EventHashes
+		for i := 0; i < n; i++ {
+			rootTable := NewFlagTable()
+			seen := superMajority + d.rng.Intn(n-superMajority+1)
+			for _, j := range d.rng.Perm(len(prev)) {
+				if len(rootTable) == seen {
+					break
+				}
+				rootTable[prev[j]] = int64(frame - 1)
+			}
+			ev := NewEvent([][]byte{[]byte(fmt.Sprintf("root %d %d", frame, i))}, nil, nil,
+				make(EventHashes, 2), crypto.FromECDSAPub(&d.keys[i].PublicKey), int64(frame),
+				NewFlagTable(), rootTable, int64(frame), true)
+			ev.Message.CreatorID = d.peer(i).ID
+			ev.LamportTimestamp = int64(frame)
+			d.roots = append(d.roots, ev)
+			current = append(current, ev.Hash())
+		}
+		prev = current
+	}
+	return d
+}
@@ -0,0 +1,299 @@
+package poset
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// resetState is what a Poset and its Store count and queue, which Reset
+// must leave the same however many times it is done from a frame
+type resetState struct {
+	Undetermined       EventHashes
+	Undivided          EventHashes
+	PendingLoaded      int64
+	QueuedRoots        int
+	SigPool            []BlockSignature
+	PendingRounds      int
+	PendingReceived    common.Int64Slice
+	TopologicalIndex   int64
+	Known              map[uint64]int64
+	LastRound          int64
+	LastBlock          int64
+	LastConsensusRound int64
+}
+
+func resetStateOf(p *Poset) resetState {
+	state := resetState{
+		Undetermined:     p.GetUndeterminedEvents(),
+		Undivided:        append(EventHashes{}, p.undivided...),
+		PendingLoaded:    p.GetPendingLoadedEvents(),
+		QueuedRoots:      p.QueuedRoots(),
+		SigPool:          append([]BlockSignature{}, p.SigPool...),
+		PendingRounds:    len(p.PendingRounds),
+		PendingReceived:  append(common.Int64Slice{}, p.PendingRoundReceived...),
+		TopologicalIndex: p.peekTopologicalIndex(),
+		Known:            KnownEvents(p.Store),
+		LastRound:        p.Store.LastRound(),
+		LastBlock:        p.Store.LastBlockIndex(),
+	}
+	if p.LastConsensusRound != nil {
+		state.LastConsensusRound = *p.LastConsensusRound
+	}
+	return state
+}
+
+// testResetTwice checks two Resets from a frame, as two fast forwards to
+// the same block make them, leave the Poset as a single one
+func testResetTwice(newStore func(*peers.Peers) Store, t *testing.T) {
+	// the events carry transactions, all of them are loaded
+	f := newFixture(t, fixtureConfig{Participants: 4, Events: 200, Seed: 1, Txs: 1, TxSize: 8})
+	p, commitCh := f.committingPoset(t)
+	for i, ev := range f.copyEvents() {
+		if err := p.InsertEvent(ev, false); err != nil {
+			t.Fatalf("inserting event %d: %v", i, err)
+		}
+	}
+	if err := p.RunToQuiescence(); err != nil {
+		t.Fatal(err)
+	}
+	// the first block made of a frame with events
+	var (
+		block Block
+		frame Frame
+	)
+	for _, b := range committedBlocks(commitCh) {
+		fr, err := p.GetFrame(b.RoundReceived())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fr.Events) > 0 {
+			block, frame = b, fr
+			break
+		}
+	}
+	if len(frame.Events) == 0 {
+		t.Fatal("expected a block made of a frame with events")
+	}
+	// each Reset takes the frame as it comes from a peer
+	marshaledFrame, err := frame.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerFrame := func() Frame {
+		var res Frame
+		if err := res.ProtoUnmarshal(marshaledFrame); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	participants2 := f.newParticipants()
+	p2, err := NewPoset(participants2, newStore(participants2), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the signatures of the blocks up to the reset one are dropped
+	stale := BlockSignature{Validator: []byte("validator"), Index: block.Index(), Signature: "stale"}
+	later := BlockSignature{Validator: []byte("validator"), Index: block.Index() + 1, Signature: "later"}
+	p2.SigPool = []BlockSignature{stale, later}
+	if err := p2.Reset(block, peerFrame()); err != nil {
+		t.Fatal(err)
+	}
+	once := resetStateOf(p2)
+	pooled := 0
+	for _, bs := range once.SigPool {
+		if bs.Signature == stale.Signature {
+			t.Fatal("expected the signature of the reset block dropped")
+		}
+		if bs.Signature == later.Signature {
+			pooled++
+		}
+	}
+	if pooled != 1 {
+		t.Fatalf("expected the signature of a later block kept once, got %d", pooled)
+	}
+	if once.PendingLoaded != int64(len(frame.Events)) || len(once.Undetermined) != len(frame.Events) {
+		t.Fatalf("expected the %d frame events pending, got %+v", len(frame.Events), once)
+	}
+
+	if err := p2.Reset(block, peerFrame()); err != nil {
+		t.Fatal(err)
+	}
+	if twice := resetStateOf(p2); !reflect.DeepEqual(once, twice) {
+		t.Fatalf("expected a second Reset to leave\n%+v\ngot\n%+v", once, twice)
+	}
+
+	// an event twice in the frame is counted once
+	doubled := peerFrame()
+	doubled.Events = append(doubled.Events, peerFrame().Events[0])
+	if err := p2.Reset(block, doubled); err != nil {
+		t.Fatal(err)
+	}
+	if state := resetStateOf(p2); !reflect.DeepEqual(once, state) {
+		t.Fatalf("expected a frame with a doubled event to leave\n%+v\ngot\n%+v", once, state)
+	}
+
+	// and consensus goes on as after a single Reset
+	participants3 := f.newParticipants()
+	p3, err := NewPoset(participants3, newStore(participants3), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p3.SigPool = []BlockSignature{stale, later}
+	if err := p3.Reset(block, peerFrame()); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []*Poset{p2, p3} {
+		if err := q.DivideRounds(); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.DecideAtropos(); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.DecideRoundReceived(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if reset, single := resetStateOf(p2), resetStateOf(p3); !reflect.DeepEqual(reset, single) {
+		t.Fatalf("expected the Poset reset thrice to go on as\n%+v\ngot\n%+v", single, reset)
+	}
+}
+
+func TestInmemResetTwice(t *testing.T) {
+	testResetTwice(func(participants *peers.Peers) Store {
+		return NewInmemStore(participants, cacheSize, nil)
+	}, t)
+}
+
+func TestBadgerResetTwice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badger_reset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var stores []*BadgerStore
+	defer func() {
+		for _, store := range stores {
+			store.Close()
+		}
+	}()
+	testResetTwice(func(participants *peers.Peers) Store {
+		path, err := ioutil.TempDir(dir, "store")
+		if err != nil {
+			t.Fatal(err)
+		}
+		store, err := NewBadgerStore(participants, cacheSize, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stores = append(stores, store)
+		return store
+	}, t)
+}
+
+// TestResetGoesOnFromFrame checks a Poset Reset from a block puts the events
+// of its frame, and those inserted after, in the frames they have in the
+// Poset the block comes from, and makes the same blocks on
+func TestResetGoesOnFromFrame(t *testing.T) {
+	p, index := initConsensusPoset(false, t)
+	commitCh := make(chan Block, 10)
+	p.commitCh = commitCh
+	if err := p.RunToQuiescence(); err != nil {
+		t.Fatal(err)
+	}
+	blocks := committedBlocks(commitCh)
+	if len(blocks) < 2 {
+		t.Fatalf("expected at least 2 blocks, got %d", len(blocks))
+	}
+	block := blocks[1]
+	frame, err := p.GetFrame(block.RoundReceived())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the frame as it comes from a peer
+	marshaledFrame, err := frame.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var peerFrame Frame
+	if err := peerFrame.ProtoUnmarshal(marshaledFrame); err != nil {
+		t.Fatal(err)
+	}
+
+	commitCh2 := make(chan Block, 10)
+	p2, err := NewPoset(p.Participants, NewInmemStore(p.Participants, cacheSize, nil), commitCh2, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p2.Reset(block, peerFrame); err != nil {
+		t.Fatal(err)
+	}
+
+	compareEvents := func(hashes EventHashes) {
+		for _, hash := range hashes {
+			ev, err := p.Store.GetEventBlock(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ev2, err := p2.Store.GetEventBlock(hash)
+			if err != nil {
+				t.Fatalf("getting %s: %v", getName(index, hash), err)
+			}
+			if ev2.Frame != ev.Frame || ev2.Root != ev.Root {
+				t.Fatalf("%s should be in frame %d as root %v, not %d as root %v",
+					getName(index, hash), ev.Frame, ev.Root, ev2.Frame, ev2.Root)
+			}
+			if ev2.LamportTimestamp != ev.LamportTimestamp {
+				t.Fatalf("%s lamport timestamp should be %d, not %d",
+					getName(index, hash), ev.LamportTimestamp, ev2.LamportTimestamp)
+			}
+		}
+	}
+	var hashes EventHashes
+	for _, em := range frame.Events {
+		ev := em.ToEvent()
+		hashes = append(hashes, ev.Hash())
+	}
+	compareEvents(hashes)
+
+	// the events above the frame come from a peer
+	var events []Event
+	for _, hash := range index {
+		ev, err := p.Store.GetEventBlock(hash)
+		if err == nil && ev.Frame > block.RoundReceived() {
+			events = append(events, ev)
+		}
+	}
+	sort.Stable(ByTopologicalOrder(events))
+	hashes = nil
+	for _, ev := range events {
+		if err := p2.InsertEvent(ev.Message.ToEvent(), true); err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, ev.Hash())
+	}
+	if err := p2.RunToQuiescence(); err != nil {
+		t.Fatal(err)
+	}
+	compareEvents(hashes)
+
+	resetBlocks := committedBlocks(commitCh2)
+	if len(resetBlocks) != len(blocks)-2 {
+		t.Fatalf("expected %d blocks after the Reset one, got %d", len(blocks)-2, len(resetBlocks))
+	}
+	for i, b := range resetBlocks {
+		exp := blocks[i+2]
+		if b.Index() != exp.Index() ||
+			!reflect.DeepEqual(b.GetFrameHash(), exp.GetFrameHash()) ||
+			!reflect.DeepEqual(b.Transactions(), exp.Transactions()) {
+			t.Fatalf("block %d should be %v, not %v", exp.Index(), exp, b)
+		}
+	}
+}
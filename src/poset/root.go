@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/golang/protobuf/proto"
+
+	"github.com/SamuelMarks/dag1/src/common"
 )
 
 /*
@@ -71,7 +73,7 @@ func NewBaseRootEvent(creatorID uint64) RootEvent {
 		Hash:             hash.Bytes(),
 		CreatorID:        creatorID,
 		Index:            -1,
-		LamportTimestamp: -1,
+		LamportTimestamp: LamportTimestampNIL,
 		Round:            0, //RoundNIL,
 	}
 	return res
@@ -137,12 +139,29 @@ func (root *Root) ProtoMarshal() ([]byte, error) {
 
 // ProtoUnmarshal converts protobuff to a root struct
 func (root *Root) ProtoUnmarshal(data []byte) error {
-	return proto.Unmarshal(data, root)
+	if err := proto.Unmarshal(data, root); err != nil {
+		return err
+	}
+	return root.CheckHashes()
+}
+
+// CheckHashes returns an InvalidEventHashError when the hash of a root
+// event of the root is not an EventHash
+func (root *Root) CheckHashes() error {
+	if root.SelfParent != nil && !IsValidEventHash(root.SelfParent.Hash) {
+		return &InvalidEventHashError{Length: len(root.SelfParent.Hash)}
+	}
+	for _, other := range root.Others {
+		if other != nil && !IsValidEventHash(other.Hash) {
+			return &InvalidEventHashError{Length: len(other.Hash)}
+		}
+	}
+	return nil
 }
 
 // GenRootSelfParent generates Event's parent hash from participant ID.
 // Use it for first Event only.
-func GenRootSelfParent(participantID uint64) (hash EventHash) {
-	hash.Set([]byte(fmt.Sprintf("Root%d", participantID)))
-	return
+// The name is shorter than a hash, and padded as common.BytesToHash does.
+func GenRootSelfParent(participantID uint64) EventHash {
+	return EventHash(common.BytesToHash([]byte(fmt.Sprintf("Root%d", participantID))))
 }
@@ -0,0 +1,95 @@
+package poset
+
+import (
+	"fmt"
+	"sync"
+)
+
+// rootQueue holds the root events whose ClothoChecking and
+// AtroposTimeSelection InsertEvent left to ProcessRootQueue, in the order
+// they were inserted
+type rootQueue struct {
+	lock  sync.Mutex
+	roots []Event
+}
+
+// SetRootQueue makes InsertEvent queue the ClothoChecking and
+// AtroposTimeSelection of root events, which can take long on large
+// participant sets, instead of running them. ProcessDecidedRounds runs the
+// queue first, in insertion order, so the blocks are those of the
+// synchronous insertion. It must be set before events are inserted.
+func (p *Poset) SetRootQueue(enabled bool) {
+	if enabled {
+		p.rootQueue = &rootQueue{}
+	} else {
+		p.rootQueue = nil
+	}
+}
+
+// queueRoot adds an inserted root event to the queue
+func (p *Poset) queueRoot(ev Event) {
+	p.rootQueue.lock.Lock()
+	p.rootQueue.roots = append(p.rootQueue.roots, ev)
+	p.rootQueue.lock.Unlock()
+}
+
+// QueuedRoots returns the number of root events waiting for
+// ProcessRootQueue
+func (p *Poset) QueuedRoots() int {
+	if p.rootQueue == nil {
+		return 0
+	}
+	p.rootQueue.lock.Lock()
+	defer p.rootQueue.lock.Unlock()
+	return len(p.rootQueue.roots)
+}
+
+// ProcessRootQueue runs ClothoChecking and AtroposTimeSelection of the
+// queued root events, in the order they were inserted. A root which fails
+// stays first in the queue, so that the next call resumes from it.
+func (p *Poset) ProcessRootQueue() error {
+	if p.rootQueue == nil {
+		return nil
+	}
+	for {
+		p.rootQueue.lock.Lock()
+		if len(p.rootQueue.roots) == 0 {
+			p.rootQueue.lock.Unlock()
+			return nil
+		}
+		ev := p.rootQueue.roots[0]
+		p.rootQueue.lock.Unlock()
+
+		if err := p.decideRoot(&ev); err != nil {
+			hash := ev.Hash()
+			return fmt.Errorf("root %s: %v", hash.String(), err)
+		}
+
+		p.rootQueue.lock.Lock()
+		p.rootQueue.roots = p.rootQueue.roots[1:]
+		p.rootQueue.lock.Unlock()
+	}
+}
+
+// clearRootQueue drops the queued root events, as Reset does
+func (p *Poset) clearRootQueue() {
+	if p.rootQueue == nil {
+		return
+	}
+	p.rootQueue.lock.Lock()
+	p.rootQueue.roots = nil
+	p.rootQueue.lock.Unlock()
+}
+
+// decideRoot runs ClothoChecking and AtroposTimeSelection of a root event
+// of the store, with their writes committed together
+func (p *Poset) decideRoot(ev *Event) error {
+	ins := p.newInsertion()
+	if err := p.clothoChecking(ins, ev); err != nil {
+		return fmt.Errorf("CheckClotho(newHead):%v", err)
+	}
+	if err := p.atroposTimeSelection(ins, ev); err != nil {
+		return fmt.Errorf("AtroposTimeSelection(newHead):%v", err)
+	}
+	return p.commitInsertion(ins)
+}
@@ -0,0 +1,129 @@
+package poset
+
+import (
+	"reflect"
+	"testing"
+)
+
+// rootQueuePoset inserts the events of the fixture in a fresh Poset, with
+// the roots queued or not, runs the consensus after every batch of them and
+// returns the Poset with the blocks it made
+func rootQueuePoset(t *testing.T, f *fixture, batch int, queued bool) (*Poset, []Block) {
+	p, commitCh := f.committingPoset(t)
+	p.SetRootQueue(queued)
+
+	for i, ev := range f.copyEvents() {
+		if err := p.InsertEvent(ev, false); err != nil {
+			t.Fatalf("inserting event %d: %v", i, err)
+		}
+		if (i+1)%batch == 0 {
+			if err := p.RunToQuiescence(); err != nil {
+				t.Fatalf("consensus after event %d: %v", i, err)
+			}
+		}
+	}
+	if err := p.RunToQuiescence(); err != nil {
+		t.Fatal(err)
+	}
+
+	return p, committedBlocks(commitCh)
+}
+
+// checkSameDecisions fails unless the events of the fixture have the same
+// consensus fields in both Posets
+func checkSameDecisions(t *testing.T, f *fixture, x, y *Poset) {
+	for _, ev := range f.events {
+		a, err := x.Store.GetEventBlock(ev.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := y.Store.GetEventBlock(ev.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Clotho != b.Clotho || a.Atropos != b.Atropos ||
+			a.AtroposTimestamp != b.AtroposTimestamp || a.FrameReceived != b.FrameReceived {
+			hash := ev.Hash()
+			t.Fatalf("event %s: expected clotho %v, atropos %v at %d in frame %d, got %v, %v at %d in frame %d",
+				hash.String(), b.Clotho, b.Atropos, b.AtroposTimestamp, b.FrameReceived,
+				a.Clotho, a.Atropos, a.AtroposTimestamp, a.FrameReceived)
+		}
+	}
+}
+
+func TestRootQueueDeterministic(t *testing.T) {
+	for _, conf := range []fixtureConfig{
+		{Participants: 4, Events: 400, Seed: 1, Txs: 1, TxSize: 8},
+		{Participants: 7, Events: 500, Seed: 2, Txs: 1, TxSize: 8},
+	} {
+		f := newFixture(t, conf)
+		for _, batch := range []int{1, 25} {
+			queued, queuedBlocks := rootQueuePoset(t, f, batch, true)
+			reference, blocks := rootQueuePoset(t, f, batch, false)
+
+			if reference.Store.ConsensusEventsCount() == 0 {
+				t.Fatalf("%+v batch %d: expected consensus events", conf, batch)
+			}
+			if n := queued.QueuedRoots(); n != 0 {
+				t.Fatalf("%+v batch %d: %d roots left in the queue", conf, batch, n)
+			}
+			checkSameDecisions(t, f, queued, reference)
+			if !reflect.DeepEqual(queued.Store.ConsensusEvents(), reference.Store.ConsensusEvents()) {
+				t.Fatalf("%+v batch %d: expected the same consensus events", conf, batch)
+			}
+			if len(queuedBlocks) != len(blocks) {
+				t.Fatalf("%+v batch %d: expected %d blocks, got %d",
+					conf, batch, len(blocks), len(queuedBlocks))
+			}
+			for i := range blocks {
+				if !reflect.DeepEqual(queuedBlocks[i].Body, blocks[i].Body) {
+					t.Fatalf("%+v batch %d: block %d: expected %v, got %v",
+						conf, batch, i, blocks[i].Body, queuedBlocks[i].Body)
+				}
+			}
+		}
+	}
+}
+
+func TestRootQueueDefersRoots(t *testing.T) {
+	f := newFixture(t, fixtureConfig{Participants: 7, Events: 300, Seed: 4})
+	reference := f.newPoset(t, false)
+
+	p := f.emptyPoset(t)
+	p.SetRootQueue(true)
+	for i, ev := range f.copyEvents() {
+		if err := p.InsertEvent(ev, false); err != nil {
+			t.Fatalf("inserting event %d: %v", i, err)
+		}
+	}
+
+	// the insertion leaves every root to the queue, undecided
+	roots := 0
+	for _, ev := range f.events {
+		x, err := p.Store.GetEventBlock(ev.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x.Root {
+			roots++
+		}
+		if x.Clotho || x.Atropos {
+			hash := ev.Hash()
+			t.Fatalf("event %s decided on insertion", hash.String())
+		}
+	}
+	if roots == 0 || p.QueuedRoots() != roots {
+		t.Fatalf("expected %d roots queued, got %d", roots, p.QueuedRoots())
+	}
+	if n := p.GetPendingLoadedEvents(); n < int64(roots) {
+		t.Fatalf("expected the queued roots pending, got %d pending events", n)
+	}
+
+	if err := p.ProcessRootQueue(); err != nil {
+		t.Fatal(err)
+	}
+	if n := p.QueuedRoots(); n != 0 {
+		t.Fatalf("expected the queue drained, %d roots left", n)
+	}
+	checkSameDecisions(t, f, p, reference)
+}
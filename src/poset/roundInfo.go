@@ -1,7 +1,11 @@
 package poset
 
 import (
+	"bytes"
+
 	"github.com/golang/protobuf/proto"
+
+	"github.com/SamuelMarks/dag1/src/common"
 )
 
 type pendingRound struct {
@@ -9,29 +13,23 @@ type pendingRound struct {
 	Decided bool
 }
 
-// RoundCreated wrapper for protobuf created round event messages
-type RoundCreated struct {
-	Message RoundCreatedMessage
+// Round is the record of a round: the events created in it, with their
+// clotho and atropos decisions, and the events received in it
+type Round struct {
+	Message RoundMessage
 }
 
-// NewRoundCreated creates a new round info struct
-func NewRoundCreated() *RoundCreated {
-	return &RoundCreated{
-		Message: RoundCreatedMessage{
+// NewRound creates a new round record
+func NewRound() *Round {
+	return &Round{
+		Message: RoundMessage{
 			Events: make(map[string]*RoundEvent),
 		},
 	}
 }
 
-// NewRoundReceived constructor
-func NewRoundReceived() *RoundReceived {
-	return &RoundReceived{
-		Rounds: [][]byte{},
-	}
-}
-
 // AddEvent add event to round info (optionally set clotho)
-func (r *RoundCreated) AddEvent(x EventHash, clotho bool) {
+func (r *Round) AddEvent(x EventHash, clotho bool) {
 	_, ok := r.Message.Events[x.String()]
 	if !ok {
 		r.Message.Events[x.String()] = &RoundEvent{
@@ -41,7 +39,7 @@ func (r *RoundCreated) AddEvent(x EventHash, clotho bool) {
 }
 
 // SetConsensusEvent set an event as a consensus event
-func (r *RoundCreated) SetConsensusEvent(x EventHash) {
+func (r *Round) SetConsensusEvent(x EventHash) {
 	e, ok := r.Message.Events[x.String()]
 	if !ok {
 		e = &RoundEvent{}
@@ -51,7 +49,7 @@ func (r *RoundCreated) SetConsensusEvent(x EventHash) {
 }
 
 // SetRoundReceived set the received round for the given event
-func (r *RoundCreated) SetRoundReceived(x string, round int64) {
+func (r *Round) SetRoundReceived(x string, round int64) {
 	e, ok := r.Message.Events[x]
 
 	if !ok {
@@ -64,7 +62,7 @@ func (r *RoundCreated) SetRoundReceived(x string, round int64) {
 }
 
 // SetAtropos sets whether the given event is Atropos, otherwise it is Clotho when not found
-func (r *RoundCreated) SetAtropos(x EventHash, f bool) {
+func (r *Round) SetAtropos(x EventHash, f bool) {
 	e, ok := r.Message.Events[x.String()]
 	if !ok {
 		e = &RoundEvent{
@@ -80,7 +78,7 @@ func (r *RoundCreated) SetAtropos(x EventHash, f bool) {
 }
 
 // ClothoDecided return true if no clothos' fame is left undefined
-func (r *RoundCreated) ClothoDecided() bool {
+func (r *Round) ClothoDecided() bool {
 	for _, e := range r.Message.Events {
 		if e.Clotho && e.Atropos == Trilean_UNDEFINED {
 			return false
@@ -90,7 +88,7 @@ func (r *RoundCreated) ClothoDecided() bool {
 }
 
 // Clotho return clothos
-func (r *RoundCreated) Clotho() EventHashes {
+func (r *Round) Clotho() EventHashes {
 	var res EventHashes
 	for x, e := range r.Message.Events {
 		if e.Clotho {
@@ -102,8 +100,21 @@ func (r *RoundCreated) Clotho() EventHashes {
 	return res
 }
 
+// UndecidedClothos returns the clothos whose fame is not decided yet
+func (r *Round) UndecidedClothos() EventHashes {
+	var res EventHashes
+	for x, e := range r.Message.Events {
+		if e.Clotho && e.Atropos == Trilean_UNDEFINED {
+			var hash EventHash
+			_ = hash.Parse(x)
+			res = append(res, hash)
+		}
+	}
+	return res
+}
+
 // RoundEvents returns all non-consensus events for the created round
-func (r *RoundCreated) RoundEvents() (res EventHashes) {
+func (r *Round) RoundEvents() (res EventHashes) {
 	for x, e := range r.Message.Events {
 		if !e.Consensus {
 			var hash EventHash
@@ -115,7 +126,7 @@ func (r *RoundCreated) RoundEvents() (res EventHashes) {
 }
 
 // ConsensusEvents returns all consensus events for the created round
-func (r *RoundCreated) ConsensusEvents() (res EventHashes) {
+func (r *Round) ConsensusEvents() (res EventHashes) {
 	for x, e := range r.Message.Events {
 		if e.Consensus {
 			var hash EventHash
@@ -127,7 +138,7 @@ func (r *RoundCreated) ConsensusEvents() (res EventHashes) {
 }
 
 // Atropos return Atropos
-func (r *RoundCreated) Atropos() (res []EventHash) {
+func (r *Round) Atropos() (res []EventHash) {
 	for x, e := range r.Message.Events {
 		if e.Clotho && e.Atropos == Trilean_TRUE {
 			var hash EventHash
@@ -139,11 +150,178 @@ func (r *RoundCreated) Atropos() (res []EventHash) {
 }
 
 // IsDecided checks if the event is a decided clotho
-func (r *RoundCreated) IsDecided(clotho EventHash) bool {
+func (r *Round) IsDecided(clotho EventHash) bool {
 	w, ok := r.Message.Events[clotho.String()]
 	return ok && w.Clotho && w.Atropos != Trilean_UNDEFINED
 }
 
+// IsQueued returns whether the round is queued for processing in PendingRounds
+func (r *Round) IsQueued() bool {
+	return r.Message.Queued
+}
+
+// AddReceived adds an event to the events received in the round
+func (r *Round) AddReceived(x EventHash) {
+	r.Message.Received = append(r.Message.Received, x.Bytes())
+}
+
+// ReceivedEvents returns the events received in the round, in the order
+// they were added
+func (r *Round) ReceivedEvents() EventHashes {
+	res := make(EventHashes, len(r.Message.Received))
+	for i, x := range r.Message.Received {
+		_ = res[i].Set(x) // added from an EventHash
+	}
+	return res
+}
+
+// Created returns the events created in the round as a RoundCreated, which
+// shares them with the round
+func (r *Round) Created() RoundCreated {
+	return RoundCreated{
+		Message: RoundCreatedMessage{
+			Events: r.Message.Events,
+			Queued: r.Message.Queued,
+		},
+	}
+}
+
+// Received returns the events received in the round as a RoundReceived
+func (r *Round) Received() RoundReceived {
+	return RoundReceived{
+		Rounds: r.Message.Received,
+	}
+}
+
+// ProtoMarshal marshals the round to protobuf
+func (r *Round) ProtoMarshal() ([]byte, error) {
+	var bf proto.Buffer
+	bf.SetDeterministic(true)
+	if err := bf.Marshal(&r.Message); err != nil {
+		return nil, err
+	}
+	return bf.Bytes(), nil
+}
+
+// ProtoUnmarshal de-serialises the round using protobuf
+func (r *Round) ProtoUnmarshal(data []byte) error {
+	if err := proto.Unmarshal(data, &r.Message); err != nil {
+		return err
+	}
+	if r.Message.Events == nil {
+		r.Message.Events = make(map[string]*RoundEvent)
+	}
+	return nil
+}
+
+// Equals compares two rounds for equality
+func (r *Round) Equals(that *Round) bool {
+	if r.Message.Queued != that.Message.Queued ||
+		len(r.Message.Received) != len(that.Message.Received) ||
+		!EqualsMapStringRoundEvent(r.Message.Events, that.Message.Events) {
+		return false
+	}
+	for i, x := range r.Message.Received {
+		if !bytes.Equal(x, that.Message.Received[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// RoundCreated wrapper for protobuf created round event messages
+//
+// Deprecated: RoundCreated is the created part of a Round, use Round. It is
+// kept for one release.
+type RoundCreated struct {
+	Message RoundCreatedMessage
+}
+
+// NewRoundCreated creates a new round info struct
+//
+// Deprecated: use NewRound
+func NewRoundCreated() *RoundCreated {
+	return &RoundCreated{
+		Message: RoundCreatedMessage{
+			Events: make(map[string]*RoundEvent),
+		},
+	}
+}
+
+// NewRoundReceived constructor
+//
+// Deprecated: use NewRound
+func NewRoundReceived() *RoundReceived {
+	return &RoundReceived{
+		Rounds: [][]byte{},
+	}
+}
+
+// round returns the Round sharing the events of r
+func (r *RoundCreated) round() *Round {
+	return &Round{
+		Message: RoundMessage{
+			Events: r.Message.Events,
+			Queued: r.Message.Queued,
+		},
+	}
+}
+
+// AddEvent add event to round info (optionally set clotho)
+func (r *RoundCreated) AddEvent(x EventHash, clotho bool) {
+	r.round().AddEvent(x, clotho)
+}
+
+// SetConsensusEvent set an event as a consensus event
+func (r *RoundCreated) SetConsensusEvent(x EventHash) {
+	r.round().SetConsensusEvent(x)
+}
+
+// SetRoundReceived set the received round for the given event
+func (r *RoundCreated) SetRoundReceived(x string, round int64) {
+	r.round().SetRoundReceived(x, round)
+}
+
+// SetAtropos sets whether the given event is Atropos, otherwise it is Clotho when not found
+func (r *RoundCreated) SetAtropos(x EventHash, f bool) {
+	r.round().SetAtropos(x, f)
+}
+
+// ClothoDecided return true if no clothos' fame is left undefined
+func (r *RoundCreated) ClothoDecided() bool {
+	return r.round().ClothoDecided()
+}
+
+// Clotho return clothos
+func (r *RoundCreated) Clotho() EventHashes {
+	return r.round().Clotho()
+}
+
+// UndecidedClothos returns the clothos whose fame is not decided yet
+func (r *RoundCreated) UndecidedClothos() EventHashes {
+	return r.round().UndecidedClothos()
+}
+
+// RoundEvents returns all non-consensus events for the created round
+func (r *RoundCreated) RoundEvents() (res EventHashes) {
+	return r.round().RoundEvents()
+}
+
+// ConsensusEvents returns all consensus events for the created round
+func (r *RoundCreated) ConsensusEvents() (res EventHashes) {
+	return r.round().ConsensusEvents()
+}
+
+// Atropos return Atropos
+func (r *RoundCreated) Atropos() (res []EventHash) {
+	return r.round().Atropos()
+}
+
+// IsDecided checks if the event is a decided clotho
+func (r *RoundCreated) IsDecided(clotho EventHash) bool {
+	return r.round().IsDecided(clotho)
+}
+
 // ProtoMarshal marshals the created round to protobuf
 func (r *RoundCreated) ProtoMarshal() ([]byte, error) {
 	var bf proto.Buffer
@@ -179,6 +357,41 @@ func (r *RoundCreated) IsQueued() bool {
 	return r.Message.Queued
 }
 
+// getRoundCreated backs the deprecated GetRoundCreated of the stores
+func getRoundCreated(s Store, r int64) (RoundCreated, error) {
+	round, err := s.GetRound(r)
+	return round.Created(), err
+}
+
+// setRoundCreated backs the deprecated SetRoundCreated of the stores: it
+// replaces the created events of the round, keeping the received ones
+func setRoundCreated(s Store, r int64, created RoundCreated) error {
+	round, err := s.GetRound(r)
+	if err != nil && !common.Is(err, common.KeyNotFound) {
+		return err
+	}
+	round.Message.Events = created.Message.Events
+	round.Message.Queued = created.Message.Queued
+	return s.SetRound(r, round)
+}
+
+// getRoundReceived backs the deprecated GetRoundReceived of the stores
+func getRoundReceived(s Store, r int64) (RoundReceived, error) {
+	round, err := s.GetRound(r)
+	return round.Received(), err
+}
+
+// setRoundReceived backs the deprecated SetRoundReceived of the stores: it
+// replaces the received events of the round, keeping the created ones
+func setRoundReceived(s Store, r int64, received RoundReceived) error {
+	round, err := s.GetRound(r)
+	if err != nil && !common.Is(err, common.KeyNotFound) {
+		return err
+	}
+	round.Message.Received = received.Rounds
+	return s.SetRound(r, round)
+}
+
 // Equals compares round events for equality
 func (re *RoundEvent) Equals(that *RoundEvent) bool {
 	return re.Consensus == that.Consensus &&
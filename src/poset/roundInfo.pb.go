@@ -41,7 +41,7 @@ func (x Trilean) String() string {
 	return proto.EnumName(Trilean_name, int32(x))
 }
 func (Trilean) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_roundInfo_e574b400ef2f93db, []int{0}
+	return fileDescriptor_roundInfo_e4d65699f2670355, []int{0}
 }
 
 type RoundEvent struct {
@@ -58,7 +58,7 @@ func (m *RoundEvent) Reset()         { *m = RoundEvent{} }
 func (m *RoundEvent) String() string { return proto.CompactTextString(m) }
 func (*RoundEvent) ProtoMessage()    {}
 func (*RoundEvent) Descriptor() ([]byte, []int) {
-	return fileDescriptor_roundInfo_e574b400ef2f93db, []int{0}
+	return fileDescriptor_roundInfo_e4d65699f2670355, []int{0}
 }
 func (m *RoundEvent) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RoundEvent.Unmarshal(m, b)
@@ -118,7 +118,7 @@ func (m *RoundCreatedMessage) Reset()         { *m = RoundCreatedMessage{} }
 func (m *RoundCreatedMessage) String() string { return proto.CompactTextString(m) }
 func (*RoundCreatedMessage) ProtoMessage()    {}
 func (*RoundCreatedMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_roundInfo_e574b400ef2f93db, []int{1}
+	return fileDescriptor_roundInfo_e4d65699f2670355, []int{1}
 }
 func (m *RoundCreatedMessage) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RoundCreatedMessage.Unmarshal(m, b)
@@ -163,7 +163,7 @@ func (m *RoundReceived) Reset()         { *m = RoundReceived{} }
 func (m *RoundReceived) String() string { return proto.CompactTextString(m) }
 func (*RoundReceived) ProtoMessage()    {}
 func (*RoundReceived) Descriptor() ([]byte, []int) {
-	return fileDescriptor_roundInfo_e574b400ef2f93db, []int{2}
+	return fileDescriptor_roundInfo_e4d65699f2670355, []int{2}
 }
 func (m *RoundReceived) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RoundReceived.Unmarshal(m, b)
@@ -190,36 +190,94 @@ func (m *RoundReceived) GetRounds() [][]byte {
 	return nil
 }
 
+type RoundMessage struct {
+	Events               map[string]*RoundEvent `protobuf:"bytes,1,rep,name=Events,proto3" json:"Events,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Queued               bool                   `protobuf:"varint,2,opt,name=queued,proto3" json:"queued,omitempty"`
+	Received             [][]byte               `protobuf:"bytes,3,rep,name=Received,proto3" json:"Received,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *RoundMessage) Reset()         { *m = RoundMessage{} }
+func (m *RoundMessage) String() string { return proto.CompactTextString(m) }
+func (*RoundMessage) ProtoMessage()    {}
+func (*RoundMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_roundInfo_e4d65699f2670355, []int{3}
+}
+func (m *RoundMessage) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RoundMessage.Unmarshal(m, b)
+}
+func (m *RoundMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RoundMessage.Marshal(b, m, deterministic)
+}
+func (dst *RoundMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RoundMessage.Merge(dst, src)
+}
+func (m *RoundMessage) XXX_Size() int {
+	return xxx_messageInfo_RoundMessage.Size(m)
+}
+func (m *RoundMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_RoundMessage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RoundMessage proto.InternalMessageInfo
+
+func (m *RoundMessage) GetEvents() map[string]*RoundEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *RoundMessage) GetQueued() bool {
+	if m != nil {
+		return m.Queued
+	}
+	return false
+}
+
+func (m *RoundMessage) GetReceived() [][]byte {
+	if m != nil {
+		return m.Received
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*RoundEvent)(nil), "poset.RoundEvent")
 	proto.RegisterType((*RoundCreatedMessage)(nil), "poset.RoundCreatedMessage")
 	proto.RegisterMapType((map[string]*RoundEvent)(nil), "poset.RoundCreatedMessage.EventsEntry")
 	proto.RegisterType((*RoundReceived)(nil), "poset.RoundReceived")
+	proto.RegisterType((*RoundMessage)(nil), "poset.RoundMessage")
+	proto.RegisterMapType((map[string]*RoundEvent)(nil), "poset.RoundMessage.EventsEntry")
 	proto.RegisterEnum("poset.Trilean", Trilean_name, Trilean_value)
 }
 
-func init() { proto.RegisterFile("roundInfo.proto", fileDescriptor_roundInfo_e574b400ef2f93db) }
-
-var fileDescriptor_roundInfo_e574b400ef2f93db = []byte{
-	// 311 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x51, 0xdd, 0x4a, 0xc3, 0x30,
-	0x14, 0x36, 0xeb, 0xfe, 0x7a, 0xe6, 0x66, 0x8d, 0x20, 0x45, 0xbc, 0x18, 0x43, 0x5c, 0x11, 0xec,
-	0xc5, 0xbc, 0x11, 0x2f, 0x84, 0xb1, 0x65, 0x30, 0x98, 0xbb, 0x88, 0xdb, 0x03, 0x54, 0x7b, 0xd4,
-	0x61, 0x49, 0x6a, 0x92, 0x16, 0xf6, 0x1a, 0xbe, 0x8e, 0x2f, 0x27, 0x4d, 0x3b, 0x9c, 0xe2, 0x5d,
-	0xce, 0xf7, 0x93, 0xef, 0x3b, 0x09, 0x1c, 0x29, 0x99, 0x89, 0x78, 0x2e, 0x5e, 0x64, 0x98, 0x2a,
-	0x69, 0x24, 0x6d, 0xa4, 0x52, 0xa3, 0x19, 0x7c, 0x12, 0x00, 0x5e, 0x50, 0x2c, 0x47, 0x61, 0xe8,
-	0x39, 0xb8, 0x13, 0x29, 0x34, 0x0a, 0x9d, 0x69, 0x9f, 0xf4, 0x49, 0xd0, 0xe6, 0x3f, 0x00, 0x3d,
-	0x85, 0xe6, 0x24, 0x91, 0xe6, 0x4d, 0xfa, 0x35, 0x4b, 0x55, 0x13, 0x0d, 0xa0, 0x35, 0x36, 0x4a,
-	0xa6, 0x52, 0xfb, 0x4e, 0x9f, 0x04, 0xbd, 0x51, 0x2f, 0xb4, 0xb7, 0x87, 0x2b, 0xb5, 0x49, 0x30,
-	0x12, 0x7c, 0x47, 0xd3, 0x0b, 0xe8, 0xda, 0x34, 0x8e, 0xcf, 0xb8, 0xc9, 0x31, 0xf6, 0xeb, 0x7d,
-	0x12, 0x38, 0xfc, 0x37, 0x38, 0xf8, 0x22, 0x70, 0x62, 0x91, 0x89, 0xc2, 0xc8, 0x60, 0xfc, 0x80,
-	0x5a, 0x47, 0xaf, 0x48, 0xef, 0xa1, 0x69, 0x6b, 0x16, 0xd5, 0x9c, 0xa0, 0x33, 0xba, 0xac, 0x62,
-	0xfe, 0xd1, 0x86, 0xa5, 0x90, 0x09, 0xa3, 0xb6, 0xbc, 0x72, 0x15, 0xfd, 0x3f, 0x32, 0xcc, 0x30,
-	0xde, 0xf5, 0x2f, 0xa7, 0xb3, 0x05, 0x74, 0xf6, 0xe4, 0xd4, 0x03, 0xe7, 0x1d, 0xb7, 0x76, 0x7d,
-	0x97, 0x17, 0x47, 0x3a, 0x84, 0x46, 0x1e, 0x25, 0x19, 0x5a, 0x5f, 0x67, 0x74, 0xbc, 0x9f, 0x6b,
-	0x9d, 0xbc, 0xe4, 0xef, 0x6a, 0xb7, 0x64, 0x30, 0xfc, 0xb3, 0x63, 0x11, 0x6b, 0x81, 0xb2, 0xf6,
-	0x21, 0xaf, 0xa6, 0xab, 0x6b, 0x68, 0x55, 0x0f, 0x44, 0xbb, 0xe0, 0xae, 0x97, 0x53, 0x36, 0x9b,
-	0x2f, 0xd9, 0xd4, 0x3b, 0xa0, 0x6d, 0xa8, 0xaf, 0xf8, 0x9a, 0x79, 0x84, 0xba, 0xd0, 0x98, 0x8d,
-	0x17, 0x8f, 0xcc, 0xab, 0x3d, 0x35, 0xed, 0xc7, 0xdd, 0x7c, 0x07, 0x00, 0x00, 0xff, 0xff, 0x8f,
-	0x14, 0xf6, 0x49, 0xcb, 0x01, 0x00, 0x00,
+func init() { proto.RegisterFile("roundInfo.proto", fileDescriptor_roundInfo_e4d65699f2670355) }
+
+var fileDescriptor_roundInfo_e4d65699f2670355 = []byte{
+	// 343 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x52, 0xcb, 0x4a, 0xc3, 0x40,
+	0x14, 0x75, 0x9a, 0xbe, 0x72, 0xfb, 0x30, 0x8e, 0x20, 0xa1, 0x08, 0x96, 0x20, 0x36, 0x08, 0x66,
+	0x51, 0x17, 0x8a, 0x0b, 0xa1, 0xb4, 0x29, 0x14, 0x6a, 0x17, 0x63, 0xfb, 0x01, 0xd1, 0x5c, 0xb5,
+	0x58, 0x66, 0x6a, 0x66, 0x52, 0xe8, 0x6f, 0xf8, 0x3b, 0x7e, 0x81, 0x7f, 0x25, 0x99, 0xa4, 0x35,
+	0x16, 0x57, 0xe2, 0xee, 0x9e, 0x73, 0xee, 0xcd, 0x39, 0x27, 0x09, 0xec, 0x47, 0x22, 0xe6, 0xe1,
+	0x88, 0x3f, 0x09, 0x6f, 0x19, 0x09, 0x25, 0x68, 0x69, 0x29, 0x24, 0x2a, 0xe7, 0x9d, 0x00, 0xb0,
+	0x44, 0xf2, 0x57, 0xc8, 0x15, 0x3d, 0x06, 0xb3, 0x2f, 0xb8, 0x44, 0x2e, 0x63, 0x69, 0x93, 0x36,
+	0x71, 0xab, 0xec, 0x9b, 0xa0, 0x47, 0x50, 0xee, 0x2f, 0x84, 0x7a, 0x11, 0x76, 0x41, 0x4b, 0x19,
+	0xa2, 0x2e, 0x54, 0x7a, 0x2a, 0x12, 0x4b, 0x21, 0x6d, 0xa3, 0x4d, 0xdc, 0x66, 0xb7, 0xe9, 0xe9,
+	0xa7, 0x7b, 0xd3, 0x68, 0xbe, 0xc0, 0x80, 0xb3, 0x8d, 0x4c, 0x4f, 0xa1, 0xa1, 0xdd, 0x18, 0x3e,
+	0xe2, 0x7c, 0x85, 0xa1, 0x5d, 0x6c, 0x13, 0xd7, 0x60, 0x3f, 0x49, 0xe7, 0x83, 0xc0, 0xa1, 0x66,
+	0xfa, 0x11, 0x06, 0x0a, 0xc3, 0x3b, 0x94, 0x32, 0x78, 0x46, 0x7a, 0x0b, 0x65, 0x1d, 0x33, 0x89,
+	0x66, 0xb8, 0xb5, 0xee, 0x59, 0x66, 0xf3, 0xcb, 0xae, 0x97, 0x2e, 0xfa, 0x5c, 0x45, 0x6b, 0x96,
+	0x5d, 0x25, 0xf9, 0xdf, 0x62, 0x8c, 0x31, 0xdc, 0xe4, 0x4f, 0x51, 0x6b, 0x0c, 0xb5, 0xdc, 0x3a,
+	0xb5, 0xc0, 0x78, 0xc5, 0xb5, 0xae, 0x6f, 0xb2, 0x64, 0xa4, 0x1d, 0x28, 0xad, 0x82, 0x45, 0x8c,
+	0xfa, 0xae, 0xd6, 0x3d, 0xc8, 0xfb, 0xea, 0x4b, 0x96, 0xea, 0x37, 0x85, 0x6b, 0xe2, 0x74, 0x76,
+	0x3a, 0x26, 0xb6, 0x9a, 0x48, 0x63, 0xd7, 0x59, 0x86, 0x9c, 0x4f, 0x02, 0x75, 0x3d, 0x6e, 0xfa,
+	0x5d, 0xed, 0xf4, 0x3b, 0xc9, 0xfb, 0xfc, 0xa1, 0x18, 0x6d, 0x41, 0x75, 0xfb, 0xa6, 0x0d, 0xed,
+	0xbd, 0xc5, 0xff, 0x5b, 0xfa, 0xfc, 0x02, 0x2a, 0xd9, 0xc7, 0xa6, 0x0d, 0x30, 0x67, 0x93, 0x81,
+	0x3f, 0x1c, 0x4d, 0xfc, 0x81, 0xb5, 0x47, 0xab, 0x50, 0x9c, 0xb2, 0x99, 0x6f, 0x11, 0x6a, 0x42,
+	0x69, 0xd8, 0x1b, 0xdf, 0xfb, 0x56, 0xe1, 0xa1, 0xac, 0x7f, 0xc2, 0xcb, 0xaf, 0x01, 0x00, 0xad,
+	0x1b, 0x43, 0x61, 0x97, 0x02, 0x00, 0x00,
 }
@@ -0,0 +1,193 @@
+package poset
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// decideRoundReceivedSplit decides the received rounds of the undetermined
+// events as DecideRoundReceived did before the Round record: the events
+// created in a round and the events received in it are read and written
+// apart, through the deprecated getters
+func (p *Poset) decideRoundReceivedSplit() error {
+	undetermined, _ := p.undeterminedSnapshot()
+	pendingRoundReceived := map[int64]bool{}
+	var left []EventHash
+	for _, x := range undetermined {
+		received, err := p.decideReceivedSplit(x, pendingRoundReceived)
+		if err != nil {
+			return err
+		}
+		if !received {
+			left = append(left, x)
+		}
+	}
+
+	for i := range pendingRoundReceived {
+		p.PendingRoundReceived = append(p.PendingRoundReceived, i)
+	}
+	sort.Sort(p.PendingRoundReceived)
+
+	p.undeterminedEventsLocker.Lock()
+	p.UndeterminedEvents = append(left, p.UndeterminedEvents[len(undetermined):]...)
+	p.undeterminedEventsLocker.Unlock()
+	return nil
+}
+
+func (p *Poset) decideReceivedSplit(x EventHash, pendingRoundReceived map[int64]bool) (bool, error) {
+	r, err := p.round(x)
+	if err != nil {
+		return false, err
+	}
+
+	for i := r + 1; i <= p.Store.LastRound(); i++ {
+		tr, err := p.Store.GetRoundCreated(i)
+		if err != nil {
+			if r < p.GetLastConsensusRound() {
+				return true, nil
+			}
+			return false, err
+		}
+		if !tr.ClothoDecided() {
+			break
+		}
+
+		fws := tr.Atropos()
+		var s []EventHash
+		for _, w := range fws {
+			dominates, err := p.dominated(w, x)
+			if err != nil {
+				return false, err
+			}
+			if dominates {
+				s = append(s, w)
+			}
+		}
+		if len(s) == len(fws) && len(s) > 0 {
+			ex, err := p.Store.GetEventBlock(x)
+			if err != nil {
+				return false, err
+			}
+			ex.SetRoundReceived(i)
+			if err := p.Store.SetEvent(ex); err != nil {
+				return false, err
+			}
+
+			tr.SetConsensusEvent(x)
+			roundReceived, err := p.Store.GetRoundReceived(i)
+			if err != nil {
+				roundReceived = *NewRoundReceived()
+			}
+			roundReceived.Rounds = append(roundReceived.Rounds, x.Bytes())
+			if err := p.Store.SetRoundReceived(i, roundReceived); err != nil {
+				return false, err
+			}
+
+			pendingRoundReceived[i] = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// consensusTransactions returns the transactions of the blocks of a Poset
+func consensusTransactions(p *Poset) ([][][]byte, error) {
+	var res [][][]byte
+	for i := int64(0); i <= p.Store.LastBlockIndex(); i++ {
+		block, err := p.Store.GetBlock(i)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, block.Transactions())
+	}
+	return res, nil
+}
+
+func TestRoundRecordConsensus(t *testing.T) {
+	for _, conf := range []struct {
+		participants, events int
+		seed                 int64
+	}{
+		{4, 300, 1},
+		{5, 300, 2},
+		{7, 400, 3},
+	} {
+		d := newGossipDAG(conf.participants, conf.events, conf.seed, t)
+		decide := func(decideRoundReceived func(*Poset) error) func(*Poset) error {
+			return func(p *Poset) error {
+				if err := p.DecideAtropos(); err != nil {
+					return err
+				}
+				if err := decideRoundReceived(p); err != nil {
+					return err
+				}
+				return p.ProcessDecidedRounds()
+			}
+		}
+
+		unified, err := d.decide(decide((*Poset).DecideRoundReceived))
+		if err != nil {
+			t.Fatalf("%+v: %v", conf, err)
+		}
+		split, err := d.decide(decide((*Poset).decideRoundReceivedSplit))
+		if err != nil {
+			t.Fatalf("%+v: %v", conf, err)
+		}
+
+		if x, y := unified.Store.ConsensusEvents(), split.Store.ConsensusEvents(); !reflect.DeepEqual(x, y) {
+			t.Fatalf("%+v: expected the consensus events %v, got %v", conf, y, x)
+		}
+		x, err := consensusTransactions(unified)
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, err := consensusTransactions(split)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(y) == 0 {
+			t.Fatalf("%+v: expected blocks", conf)
+		}
+		if !reflect.DeepEqual(x, y) {
+			t.Fatalf("%+v: expected the blocks %q, got %q", conf, y, x)
+		}
+		for r := int64(0); r <= split.Store.LastRound(); r++ {
+			x, err := unified.Store.GetRound(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			y, err := split.Store.GetRound(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !x.Equals(&y) {
+				t.Fatalf("%+v: round %d: expected %+v, got %+v", conf, r, y.Message, x.Message)
+			}
+		}
+	}
+}
+
+func TestRoundDecodesRoundCreated(t *testing.T) {
+	created := NewRoundCreated()
+	for i := 0; i < 3; i++ {
+		created.AddEvent(testHash('a', i), i == 0)
+	}
+	created.SetAtropos(testHash('a', 0), true)
+	created.Message.Queued = true
+	data, err := created.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the fields a Round shares with a RoundCreated keep their numbers
+	var round Round
+	if err := round.ProtoUnmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	res := round.Created()
+	if !created.Equals(&res) || len(round.Message.Received) != 0 {
+		t.Fatalf("expected the round %s, got %+v", fmt.Sprint(created.Message), round.Message)
+	}
+}
@@ -0,0 +1,1223 @@
+// +build sqlite
+
+package poset
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	// the sqlite3 driver of database/sql
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/pos"
+	"github.com/SamuelMarks/dag1/src/state"
+)
+
+// SQLiteAvailable is true in the builds with the sqlite store, made with the
+// sqlite build tag
+const SQLiteAvailable = true
+
+// sqliteEventBatch is the number of events SetEvent queues before they are
+// written, in one transaction
+const sqliteEventBatch = 256
+
+// sqliteSchema creates the tables of the sqlite store. The records are
+// encoded as in the badger store, along with the columns worth querying.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS participants (
+	pub_key  TEXT PRIMARY KEY,
+	id       INTEGER NOT NULL,
+	net_addr TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS roots (
+	pub_key TEXT PRIMARY KEY,
+	data    BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS events (
+	hash              TEXT PRIMARY KEY,
+	creator           TEXT NOT NULL,
+	idx               INTEGER NOT NULL,
+	topological_index INTEGER NOT NULL,
+	frame             INTEGER NOT NULL,
+	frame_received    INTEGER NOT NULL,
+	lamport_timestamp INTEGER NOT NULL,
+	atropos_timestamp INTEGER NOT NULL,
+	root              INTEGER NOT NULL,
+	clotho            INTEGER NOT NULL,
+	atropos           INTEGER NOT NULL,
+	transactions      INTEGER NOT NULL,
+	data              BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_creator ON events (creator, idx);
+CREATE INDEX IF NOT EXISTS events_topological ON events (topological_index);
+CREATE INDEX IF NOT EXISTS events_frame ON events (frame, lamport_timestamp, atropos_timestamp, hash);
+CREATE INDEX IF NOT EXISTS events_frame_received ON events (frame_received, frame);
+CREATE TABLE IF NOT EXISTS rounds (
+	round INTEGER PRIMARY KEY,
+	data  BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS blocks (
+	idx            INTEGER PRIMARY KEY,
+	round_received INTEGER NOT NULL,
+	transactions   INTEGER NOT NULL,
+	created_time   INTEGER NOT NULL,
+	data           BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS frames (
+	round INTEGER PRIMARY KEY,
+	data  BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS checkpoints (
+	frame INTEGER PRIMARY KEY,
+	data  TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS clotho_checks (
+	frame      INTEGER NOT NULL,
+	hash       TEXT NOT NULL,
+	creator_id INTEGER NOT NULL,
+	PRIMARY KEY (frame, hash)
+);
+CREATE INDEX IF NOT EXISTS clotho_checks_creator ON clotho_checks (frame, creator_id);
+CREATE TABLE IF NOT EXISTS time_tables (
+	frame INTEGER NOT NULL,
+	hash  TEXT NOT NULL,
+	data  BLOB NOT NULL,
+	PRIMARY KEY (frame, hash)
+);
+CREATE TABLE IF NOT EXISTS journal (
+	idx  INTEGER PRIMARY KEY,
+	data BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS peer_reputations (
+	pub_key TEXT PRIMARY KEY,
+	data    TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS peer_addresses (
+	pub_key TEXT PRIMARY KEY,
+	data    TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS meta (
+	key  TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+`
+
+// sqlQuerier runs the statements of the sqlite store: the database, or the
+// transaction of the batch being committed
+type sqlQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// SQLiteStore keeps the poset in a sqlite database, for the events and
+// blocks to be queried with SQL. Like the badger store, it serves the recent
+// records from an in-memory store. The events are written behind, in
+// batches: those not written yet are lost by a crash, and fetched again
+// from the peers.
+type SQLiteStore struct {
+	participants  *peers.Peers
+	inmemStore    *InmemStore
+	db            *sql.DB
+	path          string
+	needBootstrap bool
+
+	// tx is the transaction of the batch being committed
+	tx       *sql.Tx
+	txLocker sync.RWMutex
+
+	// pending are the events set and not written yet
+	pending       []Event
+	pendingLocker sync.Mutex
+}
+
+// openSQLite opens the database at path, creating its tables
+func openSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	// sqlite has a single writer, the transaction of a batch holds it
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// NewSQLiteStore creates a store with a new database at path
+func NewSQLiteStore(participants *peers.Peers, cacheSize int, path string, posConf *pos.Config) (*SQLiteStore, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	store := &SQLiteStore{
+		participants: participants,
+		inmemStore:   NewInmemStore(participants, cacheSize, posConf),
+		db:           db,
+		path:         path,
+	}
+	if err := store.dbSetParticipants(participants); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := store.dbSetRoots(store.inmemStore.RootsByParticipant()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// LoadSQLiteStore creates a store from an existing database. It loads the
+// participants and their roots, the events are replayed by the Bootstrap.
+func LoadSQLiteStore(cacheSize int, path string) (*SQLiteStore, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	store := &SQLiteStore{
+		db:            db,
+		path:          path,
+		needBootstrap: true,
+	}
+	participants, err := store.dbGetParticipants()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if participants.Len() == 0 {
+		db.Close()
+		return nil, fmt.Errorf("no participants in %s", path)
+	}
+
+	roots := make(map[string]Root)
+	for p, peer := range participants.ByPubKey {
+		root, err := store.dbGetRoot(p)
+		if err == sql.ErrNoRows {
+			// a root not stored is the base root of the participant
+			root, err = NewBaseRoot(peer.ID), nil
+		}
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		roots[p] = root
+	}
+	inmemStore := NewInmemStore(participants, cacheSize, nil)
+	if err := inmemStore.Reset(roots); err != nil {
+		db.Close()
+		return nil, err
+	}
+	store.participants = participants
+	store.inmemStore = inmemStore
+
+	var lastRound, lastBlock int64
+	if err := db.QueryRow("SELECT IFNULL(MAX(round), -1) FROM rounds").Scan(&lastRound); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := db.QueryRow("SELECT IFNULL(MAX(idx), -1) FROM blocks").Scan(&lastBlock); err != nil {
+		db.Close()
+		return nil, err
+	}
+	inmemStore.setLast(lastRound, lastBlock)
+	return store, nil
+}
+
+// LoadOrCreateSQLiteStore loads the store at path, or creates it when there
+// is none
+func LoadOrCreateSQLiteStore(participants *peers.Peers, cacheSize int, path string, posConf *pos.Config) (*SQLiteStore, error) {
+	if _, err := os.Stat(path); err == nil {
+		return LoadSQLiteStore(cacheSize, path)
+	}
+	return NewSQLiteStore(participants, cacheSize, path, posConf)
+}
+
+// OpenSQLiteStore is LoadOrCreateSQLiteStore, returning a Store for the
+// builds without the sqlite store to have the same signature
+func OpenSQLiteStore(participants *peers.Peers, cacheSize int, path string, posConf *pos.Config) (Store, error) {
+	return LoadOrCreateSQLiteStore(participants, cacheSize, path, posConf)
+}
+
+// q returns what the statements run with: the transaction of the batch
+// being committed, if any
+func (s *SQLiteStore) q() sqlQuerier {
+	s.txLocker.RLock()
+	defer s.txLocker.RUnlock()
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// sqlError maps a missing row to a KeyNotFound error, as mapError does for
+// the badger store
+func sqlError(err error, name, key string) error {
+	if err == sql.ErrNoRows {
+		return common.NewStoreErr(name, common.KeyNotFound, key)
+	}
+	return err
+}
+
+// ==============================================================================
+// Events
+
+// TopologicalEvents returns the events in topological order
+func (s *SQLiteStore) TopologicalEvents() ([]Event, error) {
+	var res []Event
+	err := s.ForEachEvent(func(event Event) bool {
+		res = append(res, event)
+		return true
+	})
+	return res, err
+}
+
+// ForEachEvent calls fn on the events in topological order until it returns
+// false. They are read a page at a time, fn may write to the store.
+func (s *SQLiteStore) ForEachEvent(fn func(Event) bool) error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	from := int64(-1)
+	for {
+		events, err := s.dbEvents(
+			"SELECT data FROM events WHERE topological_index > ? ORDER BY topological_index LIMIT ?",
+			from, sqliteEventBatch)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if !fn(event) {
+				return nil
+			}
+		}
+		if len(events) < sqliteEventBatch {
+			return nil
+		}
+		from = events[len(events)-1].Message.TopologicalIndex
+	}
+}
+
+// EventsByCreator returns the events of a creator with an index from
+// fromIndex to toIndex included
+func (s *SQLiteStore) EventsByCreator(creator string, fromIndex, toIndex int64) (EventHashes, error) {
+	if _, ok := s.participants.ReadByPubKey(creator); !ok {
+		return EventHashes{}, common.NewStoreErr("ParticipantEvents", common.UnknownParticipant, creator)
+	}
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+	return s.dbEventHashes("SELECT hash FROM events WHERE creator = ? AND idx BETWEEN ? AND ? ORDER BY idx",
+		creator, fromIndex, toIndex)
+}
+
+// EventsByRoundRange returns the events created in a round from fromRound to
+// toRound included, ordered by round
+func (s *SQLiteStore) EventsByRoundRange(fromRound, toRound int64) (EventHashes, error) {
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+	return s.dbEventHashes(
+		"SELECT hash FROM events WHERE frame BETWEEN ? AND ? ORDER BY frame, lamport_timestamp, atropos_timestamp, hash",
+		fromRound, toRound)
+}
+
+// CacheSize returns the size of the cache
+func (s *SQLiteStore) CacheSize() int {
+	return s.inmemStore.CacheSize()
+}
+
+// Participants returns the participants
+func (s *SQLiteStore) Participants() (*peers.Peers, error) {
+	return s.participants, nil
+}
+
+// RootsBySelfParent returns the roots by the hash of their self-parent
+func (s *SQLiteStore) RootsBySelfParent() map[EventHash]Root {
+	return s.inmemStore.RootsBySelfParent()
+}
+
+// RootsByParticipant returns the roots by participant
+func (s *SQLiteStore) RootsByParticipant() map[string]Root {
+	return s.inmemStore.RootsByParticipant()
+}
+
+// GetEventBlock returns an event, from the database when it is not in the
+// cache
+func (s *SQLiteStore) GetEventBlock(hash EventHash) (Event, error) {
+	event, err := s.inmemStore.GetEventBlock(hash)
+	if common.Is(err, common.KeyNotFound) {
+		if err := s.flush(); err != nil {
+			return Event{}, err
+		}
+		event, err = s.dbGetEventBlock(hash)
+	}
+	return event, err
+}
+
+// SetEvent caches an event and queues it to be written
+func (s *SQLiteStore) SetEvent(event Event) error {
+	if err := s.inmemStore.SetEvent(event); err != nil {
+		return err
+	}
+	s.pendingLocker.Lock()
+	s.pending = append(s.pending, event)
+	full := len(s.pending) >= sqliteEventBatch
+	s.pendingLocker.Unlock()
+	if full {
+		return s.flush()
+	}
+	return nil
+}
+
+// CommitBatch applies the writes of a batch in a single transaction, with
+// the events queued before
+func (s *SQLiteStore) CommitBatch(batch *StoreBatch) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	s.txLocker.Lock()
+	s.tx = tx
+	s.txLocker.Unlock()
+	defer func() {
+		s.txLocker.Lock()
+		s.tx = nil
+		s.txLocker.Unlock()
+	}()
+
+	if err := applyBatchOps(s, batch.ops); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := s.flush(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// flush writes the queued events, in one transaction
+func (s *SQLiteStore) flush() error {
+	s.pendingLocker.Lock()
+	defer s.pendingLocker.Unlock()
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	s.txLocker.RLock()
+	tx := s.tx
+	s.txLocker.RUnlock()
+	own := tx == nil
+	if own {
+		var err error
+		if tx, err = s.db.Begin(); err != nil {
+			return err
+		}
+	}
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO events (hash, creator, idx, topological_index,
+		frame, frame_received, lamport_timestamp, atropos_timestamp, root, clotho, atropos,
+		transactions, data) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		if own {
+			tx.Rollback()
+		}
+		return err
+	}
+	defer stmt.Close()
+	for _, event := range s.pending {
+		data, err := event.StoreMarshal()
+		if err == nil {
+			hash := event.Hash()
+			_, err = stmt.Exec(hash.String(), event.GetCreator(), event.Index(),
+				event.Message.TopologicalIndex, event.Frame, event.FrameReceived,
+				event.LamportTimestamp, event.AtroposTimestamp, event.Root, event.Clotho,
+				event.Atropos, len(event.Message.Body.Transactions), data)
+		}
+		if err != nil {
+			if own {
+				tx.Rollback()
+			}
+			return err
+		}
+	}
+	if own {
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	s.pending = nil
+	return nil
+}
+
+// ParticipantEvents returns the events of a participant after skip, those
+// older than the cache read from the database
+func (s *SQLiteStore) ParticipantEvents(participant string, skip int64) (EventHashes, error) {
+	res, err := s.inmemStore.ParticipantEvents(participant, skip)
+	if common.Is(err, common.TooLate) {
+		if err := s.flush(); err != nil {
+			return nil, err
+		}
+		res, err = s.dbEventHashes("SELECT hash FROM events WHERE creator = ? AND idx > ? ORDER BY idx",
+			participant, skip)
+	}
+	return res, err
+}
+
+// ParticipantEvent returns the event of a participant at an index, from the
+// database when it is not in the cache. An unknown participant has none.
+func (s *SQLiteStore) ParticipantEvent(participant string, index int64) (EventHash, error) {
+	hash, err := s.inmemStore.ParticipantEvent(participant, index)
+	if common.Is(err, common.TooLate) || common.Is(err, common.KeyNotFound) {
+		if err := s.flush(); err != nil {
+			return EventHash{}, err
+		}
+		var hex string
+		err = s.q().QueryRow("SELECT hash FROM events WHERE creator = ? AND idx = ?",
+			participant, index).Scan(&hex)
+		if err == nil {
+			hash, err = ParseEventHash(hex)
+		}
+		err = sqlError(err, "ParticipantEvent", string(participantEventKey(participant, index)))
+	}
+	return hash, err
+}
+
+// LastEventFrom returns the last event of a participant, its root if it has
+// none
+func (s *SQLiteStore) LastEventFrom(participant string) (EventHash, bool, error) {
+	return s.inmemStore.LastEventFrom(participant)
+}
+
+// ParticipantGaps returns the indexes missing before the events known to
+// exist of each participant, by public key
+func (s *SQLiteStore) ParticipantGaps() map[string][]int64 {
+	return s.inmemStore.ParticipantGaps()
+}
+
+// AddParticipantGap records the indexes of a participant in the range,
+// bounds included, as missing
+func (s *SQLiteStore) AddParticipantGap(participant string, from, to int64) {
+	s.inmemStore.AddParticipantGap(participant, from, to)
+}
+
+// LastConsensusEventFrom returns the last consensus event of a participant
+func (s *SQLiteStore) LastConsensusEventFrom(participant string) (EventHash, bool, error) {
+	return s.inmemStore.LastConsensusEventFrom(participant)
+}
+
+// ConsensusEvents returns the consensus events
+func (s *SQLiteStore) ConsensusEvents() EventHashes {
+	return s.inmemStore.ConsensusEvents()
+}
+
+// ConsensusEventsCount returns the count of the consensus events
+func (s *SQLiteStore) ConsensusEventsCount() int64 {
+	return s.inmemStore.ConsensusEventsCount()
+}
+
+// AddConsensusEvent adds a consensus event, which are not persisted: the
+// Bootstrap finds them again
+func (s *SQLiteStore) AddConsensusEvent(event Event) error {
+	return s.inmemStore.AddConsensusEvent(event)
+}
+
+// ==============================================================================
+// Rounds, blocks and frames
+
+// GetRound returns a round, from the database when it is not in the cache
+func (s *SQLiteStore) GetRound(r int64) (Round, error) {
+	res, err := s.inmemStore.GetRound(r)
+	if common.Is(err, common.KeyNotFound) {
+		var data []byte
+		key := strconv.FormatInt(r, 10)
+		if err = s.q().QueryRow("SELECT data FROM rounds WHERE round = ?", r).Scan(&data); err != nil {
+			return *NewRound(), sqlError(err, "Round", key)
+		}
+		round := NewRound()
+		if err := round.ProtoUnmarshal(data); err != nil {
+			return *NewRound(), err
+		}
+		// the queued flag is recalculated, as in the badger store
+		round.Message.Queued = false
+		res = *round
+	}
+	return res, err
+}
+
+// SetRound sets a round
+func (s *SQLiteStore) SetRound(r int64, round Round) error {
+	if err := s.inmemStore.SetRound(r, round); err != nil {
+		return err
+	}
+	data, err := round.ProtoMarshal()
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		// an empty round marshals to nothing, which the driver writes as NULL
+		data = []byte{}
+	}
+	_, err = s.q().Exec("INSERT OR REPLACE INTO rounds (round, data) VALUES (?, ?)", r, data)
+	return err
+}
+
+// GetRoundCreated returns the created part of a round
+//
+// Deprecated: use GetRound
+func (s *SQLiteStore) GetRoundCreated(r int64) (RoundCreated, error) {
+	return getRoundCreated(s, r)
+}
+
+// SetRoundCreated sets the created part of a round
+//
+// Deprecated: use SetRound
+func (s *SQLiteStore) SetRoundCreated(r int64, round RoundCreated) error {
+	return setRoundCreated(s, r, round)
+}
+
+// GetRoundReceived returns the received part of a round
+//
+// Deprecated: use GetRound
+func (s *SQLiteStore) GetRoundReceived(r int64) (RoundReceived, error) {
+	return getRoundReceived(s, r)
+}
+
+// SetRoundReceived sets the received part of a round
+//
+// Deprecated: use SetRound
+func (s *SQLiteStore) SetRoundReceived(r int64, round RoundReceived) error {
+	return setRoundReceived(s, r, round)
+}
+
+// LastRound returns the last round
+func (s *SQLiteStore) LastRound() int64 {
+	return s.inmemStore.LastRound()
+}
+
+// RoundClothos returns the clothos of a round
+func (s *SQLiteStore) RoundClothos(r int64) EventHashes {
+	round, err := s.GetRound(r)
+	if err != nil {
+		return EventHashes{}
+	}
+	return round.Clotho()
+}
+
+// RoundEvents returns the number of events of a round
+func (s *SQLiteStore) RoundEvents(r int64) int {
+	round, err := s.GetRound(r)
+	if err != nil {
+		return 0
+	}
+	return len(round.Message.Events)
+}
+
+// GetRoot returns the root of a participant
+func (s *SQLiteStore) GetRoot(participant string) (Root, error) {
+	root, err := s.inmemStore.GetRoot(participant)
+	if err != nil {
+		root, err = s.dbGetRoot(participant)
+	}
+	return root, sqlError(err, "Root", participant)
+}
+
+// GetBlock returns a block, from the database when it is not in the cache
+func (s *SQLiteStore) GetBlock(index int64) (Block, error) {
+	res, err := s.inmemStore.GetBlock(index)
+	if err != nil {
+		var data []byte
+		if err = s.q().QueryRow("SELECT data FROM blocks WHERE idx = ?", index).Scan(&data); err != nil {
+			return Block{}, sqlError(err, "Block", strconv.FormatInt(index, 10))
+		}
+		err = res.ProtoUnmarshal(data)
+	}
+	return res, err
+}
+
+// SetBlock sets a block. The events queued are written with it.
+func (s *SQLiteStore) SetBlock(block Block) error {
+	if err := s.inmemStore.SetBlock(block); err != nil {
+		return err
+	}
+	data, err := block.ProtoMarshal()
+	if err != nil {
+		return err
+	}
+	if _, err := s.q().Exec(`INSERT OR REPLACE INTO blocks (idx, round_received, transactions,
+		created_time, data) VALUES (?, ?, ?, ?, ?)`, block.Index(), block.RoundReceived(),
+		len(block.Transactions()), block.CreatedTime, data); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// LastBlockIndex returns the index of the last block
+func (s *SQLiteStore) LastBlockIndex() int64 {
+	return s.inmemStore.LastBlockIndex()
+}
+
+// GetFrame returns a frame, from the database when it is not in the cache
+func (s *SQLiteStore) GetFrame(index int64) (Frame, error) {
+	res, err := s.inmemStore.GetFrame(index)
+	if err != nil {
+		var data []byte
+		if err = s.q().QueryRow("SELECT data FROM frames WHERE round = ?", index).Scan(&data); err != nil {
+			return Frame{}, sqlError(err, "Frame", strconv.FormatInt(index, 10))
+		}
+		err = res.ProtoUnmarshal(data)
+	}
+	return res, err
+}
+
+// SetFrame sets a frame
+func (s *SQLiteStore) SetFrame(frame Frame) error {
+	if err := s.inmemStore.SetFrame(frame); err != nil {
+		return err
+	}
+	data, err := frame.ProtoMarshal()
+	if err != nil {
+		return err
+	}
+	_, err = s.q().Exec("INSERT OR REPLACE INTO frames (round, data) VALUES (?, ?)", frame.Round, data)
+	return err
+}
+
+// GetCheckpoint returns the checkpoint of a frame
+func (s *SQLiteStore) GetCheckpoint(frame int64) (Checkpoint, error) {
+	res, err := s.inmemStore.GetCheckpoint(frame)
+	if common.Is(err, common.KeyNotFound) {
+		var data string
+		if err = s.q().QueryRow("SELECT data FROM checkpoints WHERE frame = ?", frame).Scan(&data); err != nil {
+			return Checkpoint{}, sqlError(err, "Checkpoint", strconv.FormatInt(frame, 10))
+		}
+		res = Checkpoint{}
+		err = json.Unmarshal([]byte(data), &res)
+	}
+	return res, err
+}
+
+// SetCheckpoint adds or updates the checkpoint of a frame
+func (s *SQLiteStore) SetCheckpoint(checkpoint Checkpoint) error {
+	if err := s.inmemStore.SetCheckpoint(checkpoint); err != nil {
+		return err
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	_, err = s.q().Exec("INSERT OR REPLACE INTO checkpoints (frame, data) VALUES (?, ?)",
+		checkpoint.Frame, string(data))
+	return err
+}
+
+// ==============================================================================
+// Metadata
+
+// ArchivedEvents returns the undetermined events archived by the poset
+func (s *SQLiteStore) ArchivedEvents() (EventHashes, error) {
+	var res EventHashes
+	err := s.dbGetMeta(archivedEventsKey, &res)
+	return res, err
+}
+
+// SetArchivedEvents replaces the undetermined events archived by the poset
+func (s *SQLiteStore) SetArchivedEvents(hashes EventHashes) error {
+	return s.dbSetMeta(archivedEventsKey, hashes)
+}
+
+// ConsensusConfigHash returns the hash of the consensus configuration the
+// events were accepted with, the zero hash if none was set
+func (s *SQLiteStore) ConsensusConfigHash() (common.Hash, error) {
+	var res common.Hash
+	err := s.dbGetMeta(consensusConfigKey, &res)
+	return res, err
+}
+
+// SetConsensusConfigHash sets the hash of the consensus configuration
+func (s *SQLiteStore) SetConsensusConfigHash(hash common.Hash) error {
+	return s.dbSetMeta(consensusConfigKey, hash)
+}
+
+// BlockCarry returns the transactions carried over past the block of a
+// frame into the next block
+func (s *SQLiteStore) BlockCarry(frame int64) ([][]byte, error) {
+	var res [][]byte
+	err := s.dbGetMeta(blockCarryKey(frame), &res)
+	return res, err
+}
+
+// SetBlockCarry sets the transactions carried over past the block of a
+// frame into the next block
+func (s *SQLiteStore) SetBlockCarry(frame int64, txs [][]byte) error {
+	return s.dbSetMeta(blockCarryKey(frame), txs)
+}
+
+// PeerReputations returns what the node learnt about its peers
+func (s *SQLiteStore) PeerReputations() (map[string]PeerReputation, error) {
+	res := make(map[string]PeerReputation)
+	err := s.dbForEachJSON("SELECT pub_key, data FROM peer_reputations", func(pubKey string, data []byte) error {
+		var rep PeerReputation
+		if err := json.Unmarshal(data, &rep); err != nil {
+			return err
+		}
+		res[pubKey] = rep
+		return nil
+	})
+	return res, err
+}
+
+// SetPeerReputations adds or updates the reputation of peers
+func (s *SQLiteStore) SetPeerReputations(reps map[string]PeerReputation) error {
+	for pubKey, rep := range reps {
+		if err := s.dbSetJSON("peer_reputations", pubKey, rep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PeerAddresses returns the latest signed network addresses of the
+// participants
+func (s *SQLiteStore) PeerAddresses() (map[string]peers.AddrAnnouncement, error) {
+	res := make(map[string]peers.AddrAnnouncement)
+	err := s.dbForEachJSON("SELECT pub_key, data FROM peer_addresses", func(pubKey string, data []byte) error {
+		var addr peers.AddrAnnouncement
+		if err := json.Unmarshal(data, &addr); err != nil {
+			return err
+		}
+		res[pubKey] = addr
+		return nil
+	})
+	return res, err
+}
+
+// SetPeerAddresses adds or updates the network addresses of participants
+func (s *SQLiteStore) SetPeerAddresses(addrs map[string]peers.AddrAnnouncement) error {
+	for pubKey, addr := range addrs {
+		if err := s.dbSetJSON("peer_addresses", pubKey, addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JournalBlock records a block emitted to the app, until it is acknowledged
+func (s *SQLiteStore) JournalBlock(block Block) error {
+	data, err := block.ProtoMarshal()
+	if err != nil {
+		return err
+	}
+	_, err = s.q().Exec("INSERT OR REPLACE INTO journal (idx, data) VALUES (?, ?)", block.Index(), data)
+	return err
+}
+
+// AcknowledgeBlock records that the app acknowledged the blocks up to index,
+// which leave the journal
+func (s *SQLiteStore) AcknowledgeBlock(index int64) error {
+	last, err := s.LastAcknowledgedBlock()
+	if err != nil {
+		return err
+	}
+	if index > last {
+		if err := s.dbSetMeta(lastAcknowledgedKey, index); err != nil {
+			return err
+		}
+	}
+	_, err = s.q().Exec("DELETE FROM journal WHERE idx <= ?", index)
+	return err
+}
+
+// JournalledBlocks returns the blocks emitted to the app and not
+// acknowledged, in index order
+func (s *SQLiteStore) JournalledBlocks() ([]Block, error) {
+	rows, err := s.q().Query("SELECT data FROM journal ORDER BY idx")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []Block
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var block Block
+		if err := block.ProtoUnmarshal(data); err != nil {
+			return nil, err
+		}
+		res = append(res, block)
+	}
+	return res, rows.Err()
+}
+
+// LastAcknowledgedBlock returns the index of the last block the app
+// acknowledged, -1 for none
+func (s *SQLiteStore) LastAcknowledgedBlock() (int64, error) {
+	res := int64(-1)
+	err := s.dbGetMeta(lastAcknowledgedKey, &res)
+	return res, err
+}
+
+// LookupTx returns ErrNoTxIndex, the sqlite store does not index the
+// transactions: they are queried from the events and blocks tables
+func (s *SQLiteStore) LookupTx(common.Hash) (TxLocation, error) {
+	return TxLocation{}, ErrNoTxIndex
+}
+
+// ==============================================================================
+// Lifecycle
+
+// Reset replaces the roots. The events, rounds, clotho checks and time
+// tables are dropped from the database as they are from memory.
+func (s *SQLiteStore) Reset(roots map[string]Root) error {
+	s.pendingLocker.Lock()
+	s.pending = nil
+	s.pendingLocker.Unlock()
+	if err := s.inmemStore.Reset(roots); err != nil {
+		return err
+	}
+	for _, table := range []string{"events", "rounds", "clotho_checks", "time_tables"} {
+		if _, err := s.q().Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
+	}
+	return s.dbSetRoots(roots)
+}
+
+// Close writes the events queued and closes the database
+func (s *SQLiteStore) Close() error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	if err := s.inmemStore.Close(); err != nil {
+		return err
+	}
+	return s.db.Close()
+}
+
+// NeedBootstrap returns true for a store loaded from an existing database
+func (s *SQLiteStore) NeedBootstrap() bool {
+	return s.needBootstrap
+}
+
+// StorePath returns the path of the database
+func (s *SQLiteStore) StorePath() string {
+	return s.path
+}
+
+// StateDB returns the state database, the in-memory one of the cache
+func (s *SQLiteStore) StateDB() state.Database {
+	return s.inmemStore.StateDB()
+}
+
+// StateRoot returns the genesis state hash
+func (s *SQLiteStore) StateRoot() common.Hash {
+	return s.inmemStore.StateRoot()
+}
+
+// ==============================================================================
+// Clotho checks and time tables
+
+// GetClothoCheck returns the clotho check of an event in a frame
+func (s *SQLiteStore) GetClothoCheck(frame int64, hash EventHash) (EventHash, error) {
+	res, err := s.inmemStore.GetClothoCheck(frame, hash)
+	if err != nil {
+		res, err = s.dbGetClothoCheck(checkClothoKey(frame, hash),
+			"SELECT hash FROM clotho_checks WHERE frame = ? AND hash = ?", frame, hash.String())
+	}
+	return res, err
+}
+
+// GetClothoCreatorCheck returns the last clotho check of a creator in a
+// frame
+func (s *SQLiteStore) GetClothoCreatorCheck(frame int64, creatorID uint64) (EventHash, error) {
+	res, err := s.inmemStore.GetClothoCreatorCheck(frame, creatorID)
+	if err != nil {
+		res, err = s.dbGetClothoCheck(checkClothoCreatorKey(frame, creatorID),
+			"SELECT hash FROM clotho_checks WHERE frame = ? AND creator_id = ? ORDER BY rowid DESC LIMIT 1",
+			frame, int64(creatorID))
+	}
+	return res, err
+}
+
+// AddClothoCheck records the clotho check of an event of a creator in a
+// frame
+func (s *SQLiteStore) AddClothoCheck(frame int64, creatorID uint64, hash EventHash) error {
+	if err := s.inmemStore.AddClothoCheck(frame, creatorID, hash); err != nil {
+		return err
+	}
+	_, err := s.q().Exec("INSERT OR REPLACE INTO clotho_checks (frame, hash, creator_id) VALUES (?, ?, ?)",
+		frame, hash.String(), int64(creatorID))
+	return err
+}
+
+// NewTimeTable creates the empty time table of a root
+func (s *SQLiteStore) NewTimeTable(frame int64, hash EventHash) error {
+	if err := s.inmemStore.NewTimeTable(frame, hash); err != nil {
+		return err
+	}
+	return s.dbSetTimeTable(frame, hash)
+}
+
+// AddTimeTable adds the lamport time of an event to the time table of a
+// root
+func (s *SQLiteStore) AddTimeTable(frame int64, hashTo EventHash, hashFrom EventHash, lamportTime int64) error {
+	if err := s.inmemStore.AddTimeTable(frame, hashTo, hashFrom, lamportTime); err != nil {
+		return err
+	}
+	return s.dbSetTimeTable(frame, hashTo)
+}
+
+// GetTimeTable returns the time table of a root
+func (s *SQLiteStore) GetTimeTable(frame int64, hash EventHash) (FlagTable, error) {
+	res, err := s.inmemStore.GetTimeTable(frame, hash)
+	if common.Is(err, common.KeyNotFound) {
+		// not loaded since the store was opened
+		var data []byte
+		key := timeTableKey(frame, hash)
+		if err = s.q().QueryRow("SELECT data FROM time_tables WHERE frame = ? AND hash = ?",
+			frame, hash.String()).Scan(&data); err != nil {
+			return nil, sqlError(err, "TimeTable", key)
+		}
+		res = NewFlagTable()
+		err = res.Unmarshal(data)
+	}
+	return res, err
+}
+
+// DropTimeTables removes the time tables of the frames before beforeFrame
+func (s *SQLiteStore) DropTimeTables(beforeFrame int64) error {
+	if err := s.inmemStore.DropTimeTables(beforeFrame); err != nil {
+		return err
+	}
+	_, err := s.q().Exec("DELETE FROM time_tables WHERE frame < ?", beforeFrame)
+	return err
+}
+
+// CheckFrameFinality returns true when no event created in the frame waits
+// for a frame received
+func (s *SQLiteStore) CheckFrameFinality(frame int64) bool {
+	if err := s.flush(); err != nil {
+		return false
+	}
+	var one int
+	err := s.q().QueryRow("SELECT 1 FROM events WHERE frame_received = 0 AND frame = ? LIMIT 1",
+		frame).Scan(&one)
+	return err == sql.ErrNoRows
+}
+
+// ProcessOutFrame returns the transactions of the loaded events created in a
+// final frame, in their final order
+func (s *SQLiteStore) ProcessOutFrame(frame int64) ([][]byte, error) {
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+	events, err := s.dbEvents("SELECT data FROM events WHERE frame = ?", frame)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(ByFinalOrder(events))
+	var bodies []*EventBody
+	for _, ev := range events {
+		if ev.IsLoaded() {
+			bodies = append(bodies, ev.Message.Body)
+		}
+	}
+	return orderedTransactions(bodies), nil
+}
+
+// ==============================================================================
+// DB Methods
+
+// dbEvents returns the events of a query of their data
+func (s *SQLiteStore) dbEvents(query string, args ...interface{}) ([]Event, error) {
+	rows, err := s.q().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []Event
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var event Event
+		if err := event.StoreUnmarshal(data); err != nil {
+			return nil, err
+		}
+		res = append(res, event)
+	}
+	return res, rows.Err()
+}
+
+// dbEventHashes returns the hashes of a query of the events
+func (s *SQLiteStore) dbEventHashes(query string, args ...interface{}) (EventHashes, error) {
+	rows, err := s.q().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := EventHashes{}
+	for rows.Next() {
+		var hex string
+		if err := rows.Scan(&hex); err != nil {
+			return nil, err
+		}
+		hash, err := ParseEventHash(hex)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, hash)
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) dbGetEventBlock(hash EventHash) (Event, error) {
+	var data []byte
+	if err := s.q().QueryRow("SELECT data FROM events WHERE hash = ?", hash.String()).Scan(&data); err != nil {
+		return Event{}, sqlError(err, "EventCache", hash.String())
+	}
+	var event Event
+	err := event.StoreUnmarshal(data)
+	return event, err
+}
+
+func (s *SQLiteStore) dbGetParticipants() (*peers.Peers, error) {
+	res := peers.NewPeers()
+	rows, err := s.q().Query("SELECT pub_key, net_addr FROM participants")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pubKey, netAddr string
+		if err := rows.Scan(&pubKey, &netAddr); err != nil {
+			return nil, err
+		}
+		res.AddPeer(peers.NewPeer(pubKey, netAddr))
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) dbSetParticipants(participants *peers.Peers) error {
+	participants.RLock()
+	defer participants.RUnlock()
+	for pubKey, peer := range participants.ByPubKey {
+		if _, err := s.q().Exec("INSERT OR REPLACE INTO participants (pub_key, id, net_addr) VALUES (?, ?, ?)",
+			pubKey, int64(peer.ID), peer.Message.NetAddr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbGetRoot returns the stored root of a participant, sql.ErrNoRows for none
+func (s *SQLiteStore) dbGetRoot(participant string) (Root, error) {
+	var data []byte
+	if err := s.q().QueryRow("SELECT data FROM roots WHERE pub_key = ?", participant).Scan(&data); err != nil {
+		return Root{}, err
+	}
+	root := new(Root)
+	if err := root.ProtoUnmarshal(data); err != nil {
+		return Root{}, err
+	}
+	return *root, nil
+}
+
+func (s *SQLiteStore) dbSetRoots(roots map[string]Root) error {
+	for participant, root := range roots {
+		data, err := root.ProtoMarshal()
+		if err != nil {
+			return err
+		}
+		if _, err := s.q().Exec("INSERT OR REPLACE INTO roots (pub_key, data) VALUES (?, ?)",
+			participant, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) dbGetClothoCheck(key, query string, args ...interface{}) (EventHash, error) {
+	var hex string
+	if err := s.q().QueryRow(query, args...).Scan(&hex); err != nil {
+		return EventHash{}, sqlError(err, "ClothoCheck", key)
+	}
+	return ParseEventHash(hex)
+}
+
+// dbSetTimeTable writes the in-memory time table of a root
+func (s *SQLiteStore) dbSetTimeTable(frame int64, hash EventHash) error {
+	ft, err := s.inmemStore.GetTimeTable(frame, hash)
+	if err != nil {
+		return err
+	}
+	_, err = s.q().Exec("INSERT OR REPLACE INTO time_tables (frame, hash, data) VALUES (?, ?, ?)",
+		frame, hash.String(), ft.Marshal())
+	return err
+}
+
+// dbGetMeta decodes the JSON of a key of the meta table into v, leaving it
+// as is when the key is not set
+func (s *SQLiteStore) dbGetMeta(key string, v interface{}) error {
+	var data string
+	err := s.q().QueryRow("SELECT data FROM meta WHERE key = ?", key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), v)
+}
+
+func (s *SQLiteStore) dbSetMeta(key string, v interface{}) error {
+	return s.dbSetJSON("meta", key, v)
+}
+
+// dbSetJSON sets the JSON of v at a key of a table of keys and data
+func (s *SQLiteStore) dbSetJSON(table, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	keyColumn := "pub_key"
+	if table == "meta" {
+		keyColumn = "key"
+	}
+	_, err = s.q().Exec(fmt.Sprintf("INSERT OR REPLACE INTO %s (%s, data) VALUES (?, ?)", table, keyColumn),
+		key, string(data))
+	return err
+}
+
+// dbForEachJSON calls fn on the key and JSON of the rows of a query
+func (s *SQLiteStore) dbForEachJSON(query string, fn func(key string, data []byte) error) error {
+	rows, err := s.q().Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, data string
+		if err := rows.Scan(&key, &data); err != nil {
+			return err
+		}
+		if err := fn(key, []byte(data)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,23 @@
+// +build !sqlite
+
+package poset
+
+import (
+	"errors"
+
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/pos"
+)
+
+// SQLiteAvailable is false in the builds without the sqlite store, made
+// without the sqlite build tag
+const SQLiteAvailable = false
+
+// ErrNoSQLite is returned opening a sqlite store in a build without it
+var ErrNoSQLite = errors.New("the sqlite store is not in this build, build with the sqlite tag")
+
+// OpenSQLiteStore returns ErrNoSQLite, the sqlite store needs the sqlite
+// build tag
+func OpenSQLiteStore(*peers.Peers, int, string, *pos.Config) (Store, error) {
+	return nil, ErrNoSQLite
+}
@@ -0,0 +1,172 @@
+// +build sqlite
+
+package poset
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+func initSQLiteStore(cacheSize int, t *testing.T) (*SQLiteStore, []pub, string) {
+	var participantPubs []pub
+	participants := peers.NewPeers()
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateECDSAKey()
+		pubKey := crypto.FromECDSAPub(&key.PublicKey)
+		peer := peers.NewPeer(fmt.Sprintf("0x%X", pubKey), "")
+		participants.AddPeer(peer)
+		participantPubs = append(participantPubs,
+			pub{peer.ID, key, pubKey, peer.Message.PubKeyHex})
+	}
+
+	dir, err := ioutil.TempDir("", "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewSQLiteStore(participants, cacheSize, filepath.Join(dir, "dag1.sqlite"), nil)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return store, participantPubs, dir
+}
+
+func TestLoadSQLiteStore(t *testing.T) {
+	// a cache of a single item, for the reads to go to the database
+	store, participants, dir := initSQLiteStore(1, t)
+	defer os.RemoveAll(dir)
+
+	// fewer events than a batch, written by SetBlock and Close
+	var events []Event
+	for i, p := range participants {
+		for k := int64(0); k < 5; k++ {
+			event := NewEvent([][]byte{[]byte(fmt.Sprintf("%d_%d", i, k))}, nil, nil,
+				make(EventHashes, 2), p.pubKey, k, nil, nil, 0, false)
+			if err := event.Sign(p.privKey); err != nil {
+				t.Fatal(err)
+			}
+			event.Message.TopologicalIndex = int64(len(events))
+			if err := store.SetEvent(event); err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, event)
+		}
+	}
+	block := NewBlock(0, 1, []byte("frame"), [][]byte{[]byte("tx")})
+	if err := store.SetBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetRound(1, *NewRound()); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSQLiteStore(1, store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+	if !loaded.NeedBootstrap() {
+		t.Fatal("expected a loaded store to need a bootstrap")
+	}
+	if loaded.participants.Len() != len(participants) {
+		t.Fatalf("expected %d participants, got %d", len(participants), loaded.participants.Len())
+	}
+	if last := loaded.LastBlockIndex(); last != 0 {
+		t.Fatalf("expected the last block 0, got %d", last)
+	}
+	if last := loaded.LastRound(); last != 1 {
+		t.Fatalf("expected the last round 1, got %d", last)
+	}
+
+	topological, err := loaded.TopologicalEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topological) != len(events) {
+		t.Fatalf("expected %d events, got %d", len(events), len(topological))
+	}
+	for i, ev := range topological {
+		if ev.Hash() != events[i].Hash() {
+			t.Fatalf("event %d: expected %v, got %v", i, events[i].Hash(), ev.Hash())
+		}
+	}
+	for _, p := range participants {
+		hash, err := loaded.ParticipantEvent(p.hex, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loaded.GetEventBlock(hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := loaded.GetBlock(0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLiteBootstrap(t *testing.T) {
+	f := newFixture(t, fixtureConfig{Participants: 4, Events: 200, Seed: 1, Txs: 1, TxSize: 8})
+	dir, err := ioutil.TempDir("", "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dag1.sqlite")
+
+	participants := f.newParticipants()
+	store, err := NewSQLiteStore(participants, cacheSize, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPoset(participants, store, nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ev := range f.copyEvents() {
+		if err := p.InsertEvent(ev, false); err != nil {
+			t.Fatalf("inserting event %d: %v", i, err)
+		}
+	}
+	if err := p.RunToQuiescence(); err != nil {
+		t.Fatal(err)
+	}
+	consensusEvents := p.Store.ConsensusEvents()
+	if len(consensusEvents) == 0 {
+		t.Fatal("expected consensus events")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second bootstrap is from the consensus the first one stored
+	for i := 0; i < 2; i++ {
+		loaded, err := LoadSQLiteStore(cacheSize, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		np, err := NewPoset(loaded.participants, loaded, nil, testLogger(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := np.Bootstrap(); err != nil {
+			t.Fatal(err)
+		}
+		if got := np.Store.ConsensusEvents(); !reflect.DeepEqual(got, consensusEvents) {
+			t.Fatalf("bootstrap %d: expected %d consensus events, got %d",
+				i, len(consensusEvents), len(got))
+		}
+		if err := loaded.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
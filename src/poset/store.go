@@ -12,19 +12,40 @@ import (
 // to store key dag1 consensus information on a node.
 type Store interface {
 	TopologicalEvents() ([]Event, error) // returns event in topological order
+	// ForEachEvent calls the function on the events in topological order
+	// until it returns false
+	ForEachEvent(func(Event) bool) error
+	// EventsByCreator returns the events of a creator with an index in the
+	// range, bounds included
+	EventsByCreator(string, int64, int64) (EventHashes, error)
+	// EventsByRoundRange returns the events created in a round of the range,
+	// bounds included
+	EventsByRoundRange(int64, int64) (EventHashes, error)
 	CacheSize() int
 	Participants() (*peers.Peers, error)
 	RootsBySelfParent() map[EventHash]Root
 	RootsByParticipant() map[string]Root
 	GetEventBlock(EventHash) (Event, error)
 	SetEvent(Event) error
+	// CommitBatch applies the writes of a batch together
+	CommitBatch(*StoreBatch) error
 	ParticipantEvents(string, int64) (EventHashes, error)
 	ParticipantEvent(string, int64) (EventHash, error)
 	LastEventFrom(string) (EventHash, bool, error)
+	// the indexes missing before the events known to exist of each
+	// participant, by public key: those an event skipped, until set
+	ParticipantGaps() map[string][]int64
+	AddParticipantGap(string, int64, int64)
 	LastConsensusEventFrom(string) (EventHash, bool, error)
 	ConsensusEvents() EventHashes
 	ConsensusEventsCount() int64
 	AddConsensusEvent(Event) error
+	// GetRound returns the record of a round, with the events created and
+	// received in it
+	GetRound(int64) (Round, error)
+	SetRound(int64, Round) error
+	// Deprecated: the created and received parts of a round, use GetRound
+	// and SetRound
 	GetRoundCreated(int64) (RoundCreated, error)
 	SetRoundCreated(int64, RoundCreated) error
 	GetRoundReceived(int64) (RoundReceived, error)
@@ -38,6 +59,8 @@ type Store interface {
 	LastBlockIndex() int64
 	GetFrame(int64) (Frame, error)
 	SetFrame(Frame) error
+	GetCheckpoint(int64) (Checkpoint, error)
+	SetCheckpoint(Checkpoint) error
 	Reset(map[string]Root) error
 	Close() error
 	NeedBootstrap() bool // Was the store loaded from existing db
@@ -45,11 +68,42 @@ type Store interface {
 	GetClothoCheck(int64, EventHash) (EventHash, error)
 	GetClothoCreatorCheck(int64, uint64) (EventHash, error)
 	AddClothoCheck(int64, uint64, EventHash) error
-	AddTimeTable(EventHash, EventHash, int64) error
-	GetTimeTable(EventHash) (FlagTable, error)
+	// time tables are keyed by the frame of the root they belong to and
+	// kept until DropTimeTables is called for that frame
+	NewTimeTable(int64, EventHash) error
+	AddTimeTable(int64, EventHash, EventHash, int64) error
+	GetTimeTable(int64, EventHash) (FlagTable, error)
+	DropTimeTables(int64) error
 	// StateDB returns state database
 	StateDB() state.Database
 	StateRoot() common.Hash
 	CheckFrameFinality(int64) bool
-	ProcessOutFrame(int64, string) ([][]byte, error)
+	ProcessOutFrame(int64) ([][]byte, error)
+	// the undetermined events the poset moved out of memory, in insertion
+	// order
+	ArchivedEvents() (EventHashes, error)
+	SetArchivedEvents(EventHashes) error
+	// the hash of the consensus configuration the events were accepted with
+	ConsensusConfigHash() (common.Hash, error)
+	SetConsensusConfigHash(common.Hash) error
+	// the transactions of the decided frames left, beyond the budget of
+	// the block of a frame, for the next block, by frame
+	BlockCarry(int64) ([][]byte, error)
+	SetBlockCarry(int64, [][]byte) error
+	// what the node learnt about its peers, by public key
+	PeerReputations() (map[string]PeerReputation, error)
+	SetPeerReputations(map[string]PeerReputation) error
+	// the latest signed network addresses of the participants, by public key
+	PeerAddresses() (map[string]peers.AddrAnnouncement, error)
+	SetPeerAddresses(map[string]peers.AddrAnnouncement) error
+	// the commit journal: the blocks emitted to the app are kept until it
+	// acknowledges them, for a restarted node to deliver them again, and
+	// the index of the last acknowledged one, for it to deliver none twice
+	JournalBlock(Block) error
+	AcknowledgeBlock(int64) error
+	JournalledBlocks() ([]Block, error)
+	LastAcknowledgedBlock() (int64, error)
+	// the location of a transaction by the hash of its content, when the
+	// store indexes the transactions
+	LookupTx(common.Hash) (TxLocation, error)
 }
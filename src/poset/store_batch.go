@@ -0,0 +1,239 @@
+package poset
+
+import (
+	"fmt"
+
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+// batchOpKind is the kind of a write of a StoreBatch
+type batchOpKind int
+
+const (
+	batchSetEvent batchOpKind = iota
+	batchSetRound
+	batchAddClothoCheck
+	batchNewTimeTable
+	batchAddTimeTable
+	batchAddConsensusEvent
+)
+
+// batchOp is one write of a StoreBatch, with the arguments of the Store
+// method it stands for. Its fields are exported for the BadgerStore to
+// journal it.
+type batchOp struct {
+	Kind      batchOpKind
+	Event     Event
+	Frame     int64
+	Round     []byte // the Round, proto encoded
+	CreatorID uint64
+	Hash      EventHash
+	From      EventHash
+	Lamport   int64
+}
+
+// batchTimeTableKey identifies a time table in a StoreBatch
+type batchTimeTableKey struct {
+	frame int64
+	hash  EventHash
+}
+
+// StoreBatch gathers the writes of one event insertion, so that the event
+// and the records derived from it reach the store together, with
+// Store.CommitBatch, or not at all. Until then reads of the events, rounds
+// and time tables written through the batch see its writes; every other
+// read goes to the store.
+type StoreBatch struct {
+	Store
+
+	ops        []batchOp
+	events     map[EventHash]Event
+	rounds     map[int64]Round
+	timeTables map[batchTimeTableKey]FlagTable
+}
+
+// NewStoreBatch returns an empty batch of writes to a store
+func NewStoreBatch(store Store) *StoreBatch {
+	return &StoreBatch{
+		Store:      store,
+		events:     make(map[EventHash]Event),
+		rounds:     make(map[int64]Round),
+		timeTables: make(map[batchTimeTableKey]FlagTable),
+	}
+}
+
+// Len returns the number of writes in the batch
+func (b *StoreBatch) Len() int {
+	return len(b.ops)
+}
+
+// For tests, to simulate a crash at a write of a batch: batchFault fails the
+// write of the index it is given as it is added to the batch, and
+// batchApplyFault as it is applied to the store
+var batchFault, batchApplyFault func(op int) error
+
+func (b *StoreBatch) add(op batchOp) error {
+	if batchFault != nil {
+		if err := batchFault(len(b.ops)); err != nil {
+			return err
+		}
+	}
+	b.ops = append(b.ops, op)
+	return nil
+}
+
+// GetEventBlock returns an event, as written in the batch if it is
+func (b *StoreBatch) GetEventBlock(hash EventHash) (Event, error) {
+	if ev, ok := b.events[hash]; ok {
+		return ev, nil
+	}
+	return b.Store.GetEventBlock(hash)
+}
+
+// SetEvent writes an event in the batch
+func (b *StoreBatch) SetEvent(event Event) error {
+	if err := checkConsensusFields(&event); err != nil {
+		return err
+	}
+	if err := b.add(batchOp{Kind: batchSetEvent, Event: event}); err != nil {
+		return err
+	}
+	b.events[event.Hash()] = event
+	return nil
+}
+
+// GetRound returns a round, as written in the batch if it is. A round read
+// from the store is copied, for the changes made to it before it is written
+// in the batch not to reach the store.
+func (b *StoreBatch) GetRound(r int64) (Round, error) {
+	if round, ok := b.rounds[r]; ok {
+		return round, nil
+	}
+	round, err := b.Store.GetRound(r)
+	if err != nil {
+		return round, err
+	}
+	data, err := round.ProtoMarshal()
+	if err != nil {
+		return Round{}, err
+	}
+	res := *NewRound()
+	if err := res.ProtoUnmarshal(data); err != nil {
+		return Round{}, err
+	}
+	if res.Message.Events == nil {
+		res.Message.Events = make(map[string]*RoundEvent)
+	}
+	return res, nil
+}
+
+// SetRound writes a round in the batch
+func (b *StoreBatch) SetRound(r int64, round Round) error {
+	data, err := round.ProtoMarshal()
+	if err != nil {
+		return err
+	}
+	if err := b.add(batchOp{Kind: batchSetRound, Frame: r, Round: data}); err != nil {
+		return err
+	}
+	b.rounds[r] = round
+	return nil
+}
+
+// AddClothoCheck writes the root of a creator in a frame in the batch
+func (b *StoreBatch) AddClothoCheck(frame int64, creatorID uint64, hash EventHash) error {
+	return b.add(batchOp{Kind: batchAddClothoCheck, Frame: frame, CreatorID: creatorID, Hash: hash})
+}
+
+// timeTable returns the time table of a root as written in the batch,
+// loading it from the store on the first write
+func (b *StoreBatch) timeTable(frame int64, hash EventHash) (FlagTable, error) {
+	key := batchTimeTableKey{frame, hash}
+	if ft, ok := b.timeTables[key]; ok {
+		return ft, nil
+	}
+	ft, err := b.Store.GetTimeTable(frame, hash)
+	if common.Is(err, common.KeyNotFound) || isDBKeyNotFound(err) {
+		ft, err = NewFlagTable(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.timeTables[key] = ft
+	return ft, nil
+}
+
+// NewTimeTable writes a new time table of a root in the batch
+func (b *StoreBatch) NewTimeTable(frame int64, hash EventHash) error {
+	if _, err := b.timeTable(frame, hash); err != nil {
+		return err
+	}
+	return b.add(batchOp{Kind: batchNewTimeTable, Frame: frame, Hash: hash})
+}
+
+// AddTimeTable writes a lamport time vote in a time table in the batch
+func (b *StoreBatch) AddTimeTable(frame int64, hashTo EventHash, hashFrom EventHash, lamportTime int64) error {
+	ft, err := b.timeTable(frame, hashTo)
+	if err != nil {
+		return err
+	}
+	op := batchOp{Kind: batchAddTimeTable, Frame: frame, Hash: hashTo, From: hashFrom, Lamport: lamportTime}
+	if err := b.add(op); err != nil {
+		return err
+	}
+	ft[hashFrom] = lamportTime
+	return nil
+}
+
+// GetTimeTable returns a time table, as written in the batch if it is
+func (b *StoreBatch) GetTimeTable(frame int64, hash EventHash) (FlagTable, error) {
+	if ft, ok := b.timeTables[batchTimeTableKey{frame, hash}]; ok {
+		return ft.Copy(), nil
+	}
+	return b.Store.GetTimeTable(frame, hash)
+}
+
+// AddConsensusEvent writes a consensus event in the batch
+func (b *StoreBatch) AddConsensusEvent(event Event) error {
+	return b.add(batchOp{Kind: batchAddConsensusEvent, Event: event})
+}
+
+// CommitBatch refuses to nest batches
+func (b *StoreBatch) CommitBatch(*StoreBatch) error {
+	return fmt.Errorf("a batch cannot be committed to a batch")
+}
+
+// applyBatchOps applies the writes of a batch to a store, in order
+func applyBatchOps(store Store, ops []batchOp) error {
+	for i, op := range ops {
+		var err error
+		if batchApplyFault != nil {
+			if err := batchApplyFault(i); err != nil {
+				return err
+			}
+		}
+		switch op.Kind {
+		case batchSetEvent:
+			err = store.SetEvent(op.Event)
+		case batchSetRound:
+			var round Round
+			if err = round.ProtoUnmarshal(op.Round); err == nil {
+				err = store.SetRound(op.Frame, round)
+			}
+		case batchAddClothoCheck:
+			err = store.AddClothoCheck(op.Frame, op.CreatorID, op.Hash)
+		case batchNewTimeTable:
+			err = store.NewTimeTable(op.Frame, op.Hash)
+		case batchAddTimeTable:
+			err = store.AddTimeTable(op.Frame, op.Hash, op.From, op.Lamport)
+		case batchAddConsensusEvent:
+			err = store.AddConsensusEvent(op.Event)
+		default:
+			err = fmt.Errorf("unknown batch write %d", op.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("batch write %d: %v", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package poset
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/1lann/cete"
+	"github.com/dgraph-io/badger"
+
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+// batchCacheSize holds the whole of a DAG of newBatchDAG
+const batchCacheSize = 1000
+
+// poset seeds a store with the roots of the DAG and returns a Poset on it
+func (d *randomDAG) poset(store Store, t testing.TB) *Poset {
+	for _, root := range d.roots {
+		if err := store.SetEvent(root); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AddClothoCheck(0, root.CreatorID(), root.Hash()); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.NewTimeTable(0, root.Hash()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := NewPoset(d.participants, store, nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func (d *randomDAG) insert(p *Poset, events []Event, t testing.TB) {
+	for _, ev := range events {
+		if err := p.InsertEvent(ev, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var errCrash = errors.New("crash")
+
+// crashAt returns a fault for the write k of a batch, and whether it struck
+func crashAt(k int) (func(int) error, *bool) {
+	struck := new(bool)
+	return func(op int) error {
+		if op == k {
+			*struck = true
+			return errCrash
+		}
+		return nil
+	}, struck
+}
+
+// firstAtropos returns the index of the first event of the DAG whose
+// insertion decides Atropos, the insertion with the most kinds of writes
+func (d *randomDAG) firstAtropos(t testing.TB) int {
+	p := d.poset(NewInmemStore(d.participants, batchCacheSize, nil), t)
+	for i, ev := range d.events {
+		d.insert(p, []Event{ev}, t)
+		if len(p.Store.ConsensusEvents()) > 0 {
+			return i
+		}
+	}
+	t.Fatal("expected Atropos")
+	return 0
+}
+
+// posetState is the part of a Poset an insertion changes
+type posetState struct {
+	TopologicalIndex int64
+	Undetermined     []EventHash
+	Pending          int64
+	SigPool          int
+	LastRound        int64
+	Rounds           [][]byte
+	ClothoChecks     []EventHash
+}
+
+func getPosetState(p *Poset, d *randomDAG) posetState {
+	s := posetState{
+		TopologicalIndex: p.peekTopologicalIndex(),
+		Pending:          p.GetPendingLoadedEvents(),
+		SigPool:          len(p.SigPool),
+		LastRound:        p.Store.LastRound(),
+	}
+	s.Undetermined, _ = p.undeterminedSnapshot()
+	for r := int64(0); r <= s.LastRound; r++ {
+		round, err := p.Store.GetRound(r)
+		if err != nil {
+			s.Rounds = append(s.Rounds, nil)
+			continue
+		}
+		data, err := round.ProtoMarshal()
+		if err != nil {
+			panic(err)
+		}
+		s.Rounds = append(s.Rounds, data)
+	}
+	for frame := int64(0); frame <= s.LastRound+1; frame++ {
+		for _, peer := range d.participants.ToPeerSlice() {
+			hash, _ := p.Store.GetClothoCreatorCheck(frame, peer.ID)
+			s.ClothoChecks = append(s.ClothoChecks, hash)
+		}
+	}
+	return s
+}
+
+func TestInsertEventCrashInmem(t *testing.T) {
+	d := newBatchDAG(4, 200, 1, t)
+	target := d.firstAtropos(t)
+	defer func() { batchFault = nil }()
+
+	ref := d.poset(NewInmemStore(d.participants, batchCacheSize, nil), t)
+	d.insert(ref, d.events, t)
+
+	p := d.poset(NewInmemStore(d.participants, batchCacheSize, nil), t)
+	d.insert(p, d.events[:target], t)
+	before := getPosetState(p, d)
+	hash := d.events[target].Hash()
+
+	// cut short at any of its writes, the insertion leaves no trace and can
+	// be done again, until it is done whole
+	k := 0
+	for ; ; k++ {
+		fault, struck := crashAt(k)
+		batchFault = fault
+		err := p.InsertEvent(d.events[target], false)
+		if !*struck {
+			if err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+		if err == nil {
+			t.Fatalf("write %d: expected the insertion to fail", k)
+		}
+		if _, err := p.Store.GetEventBlock(hash); !common.Is(err, common.KeyNotFound) {
+			t.Fatalf("write %d: expected the event absent, got %v", k, err)
+		}
+		if after := getPosetState(p, d); !reflect.DeepEqual(before, after) {
+			t.Fatalf("write %d: expected the state %+v, got %+v", k, before, after)
+		}
+	}
+	if k < 10 {
+		t.Fatalf("expected an insertion of many writes, got %d", k)
+	}
+
+	batchFault = nil
+	d.insert(p, d.events[target+1:], t)
+	for _, ev := range d.events {
+		x, err := p.Store.GetEventBlock(ev.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, err := ref.Store.GetEventBlock(ev.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x.Root != y.Root || x.Clotho != y.Clotho || x.Frame != y.Frame {
+			t.Fatalf("expected the event %v as the reference, got %+v and %+v", ev.Hash(), x, y)
+		}
+	}
+}
+
+func TestInsertEventCrashBadger(t *testing.T) {
+	d := newBatchDAG(4, 100, 2, t)
+	target := d.firstAtropos(t)
+	defer func() { batchApplyFault = nil }()
+
+	if err := os.RemoveAll("test_data"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("test_data", os.ModeDir|0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("test_data")
+
+	hash := d.events[target].Hash()
+	for k := 0; ; k++ {
+		dir, err := ioutil.TempDir("test_data", "badger")
+		if err != nil {
+			t.Fatal(err)
+		}
+		store, err := NewBadgerStore(d.participants, batchCacheSize, dir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := d.poset(store, t)
+		d.insert(p, d.events[:target], t)
+
+		// the process dies as the batch is applied to the database
+		fault, struck := crashAt(k)
+		batchApplyFault = fault
+		err = p.InsertEvent(d.events[target], false)
+		batchApplyFault = nil
+		if !*struck {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if k < 10 {
+				t.Fatalf("expected an insertion of many writes, got %d", k)
+			}
+			break
+		}
+		if err == nil {
+			t.Fatalf("write %d: expected the insertion to fail", k)
+		}
+		var ops []batchOp
+		if _, err := store.db.Table(META_TBL).Get(pendingBatchKey, &ops); err != nil {
+			t.Fatalf("write %d: expected the batch journalled: %v", k, err)
+		}
+		if err := store.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// loading the store applies the batch whole. The replay is run as
+		// LoadBadgerStore runs it, on the database opened again: the roots
+		// LoadBadgerStore reads back are not persisted yet.
+		handle, err := cete.Open(dir, badger.DefaultOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store = &BadgerStore{db: handle, path: dir}
+		if err := store.dbReplayBatch(); err != nil {
+			t.Fatalf("write %d: %v", k, err)
+		}
+		var left []batchOp
+		if _, err := store.db.Table(META_TBL).Get(pendingBatchKey, &left); !isDBKeyNotFound(err) {
+			t.Fatalf("write %d: expected the journal gone, got %v", k, err)
+		}
+		if _, err := store.dbGetEventBlock(hash); err != nil {
+			t.Fatalf("write %d: expected the event present: %v", k, err)
+		}
+		rounds := map[int64][]byte{}
+		events := map[EventHash]Event{}
+		timeTables := map[batchTimeTableKey]FlagTable{}
+		for i, op := range ops {
+			switch op.Kind {
+			case batchSetEvent:
+				events[op.Event.Hash()] = op.Event
+			case batchSetRound:
+				rounds[op.Frame] = op.Round
+			case batchAddClothoCheck:
+				if h, err := store.dbGetClothoCreatorCheck(op.Frame, op.CreatorID); err != nil || h != op.Hash {
+					t.Fatalf("write %d: op %d: expected the clotho check %v, got %v (%v)", k, i, op.Hash, h, err)
+				}
+			case batchNewTimeTable, batchAddTimeTable:
+				key := batchTimeTableKey{op.Frame, op.Hash}
+				if timeTables[key] == nil {
+					timeTables[key] = NewFlagTable()
+				}
+				if op.Kind == batchAddTimeTable {
+					timeTables[key][op.From] = op.Lamport
+				}
+			}
+		}
+		for h, x := range events {
+			y, err := store.dbGetEventBlock(h)
+			if err != nil || x.Frame != y.Frame || x.Root != y.Root || x.Clotho != y.Clotho ||
+				x.Atropos != y.Atropos || x.AtroposTimestamp != y.AtroposTimestamp {
+				t.Fatalf("write %d: expected the event %+v, got %+v (%v)", k, x, y, err)
+			}
+		}
+		for frame, data := range rounds {
+			var x Round
+			if err := x.ProtoUnmarshal(data); err != nil {
+				t.Fatal(err)
+			}
+			y, err := store.dbGetRound(frame)
+			if err != nil || !x.Equals(&y) {
+				t.Fatalf("write %d: expected the round %d %+v, got %+v (%v)", k, frame, x.Message, y.Message, err)
+			}
+		}
+		for key, x := range timeTables {
+			y, err := store.dbGetTimeTable(key.frame, key.hash)
+			if err != nil {
+				t.Fatalf("write %d: expected the time table %v: %v", k, key, err)
+			}
+			for from, lamport := range x {
+				if y[from] != lamport {
+					t.Fatalf("write %d: expected the time %d from %v, got %v", k, lamport, from, y)
+				}
+			}
+		}
+		store.db.Close()
+	}
+}
+
+func TestBootstrapRepairsRoundRecords(t *testing.T) {
+	d := newBatchDAG(4, 100, 3, t)
+	p := d.poset(NewInmemStore(d.participants, batchCacheSize, nil), t)
+	d.insert(p, d.events, t)
+	if err := p.DivideRounds(); err != nil {
+		t.Fatal(err)
+	}
+
+	// every third event goes missing from its round record
+	missing := map[EventHash]bool{}
+	for i, ev := range d.events {
+		if i%3 != 0 {
+			continue
+		}
+		hash := ev.Hash()
+		r, err := p.round(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		round, err := p.Store.GetRound(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		re, ok := round.Message.Events[hash.String()]
+		if !ok {
+			t.Fatalf("expected the event %v in the round %d", hash, r)
+		}
+		missing[hash] = re.Clotho
+		delete(round.Message.Events, hash.String())
+		if err := p.Store.SetRound(r, round); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.verifyRoundRecords(d.events); err != nil {
+		t.Fatal(err)
+	}
+	for hash, clotho := range missing {
+		r, err := p.round(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		round, err := p.Store.GetRound(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		re, ok := round.Message.Events[hash.String()]
+		if !ok {
+			t.Fatalf("expected the event %v back in the round %d", hash, r)
+		}
+		if re.Clotho != clotho {
+			t.Fatalf("expected the event %v back with clotho %v", hash, clotho)
+		}
+	}
+}
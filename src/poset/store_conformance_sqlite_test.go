@@ -0,0 +1,35 @@
+// +build sqlite
+
+package poset_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/poset/storetest"
+)
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite_conformance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stores := 0
+	storetest.RunStoreTests(t, func() poset.Store {
+		stores++
+		store, err := poset.NewSQLiteStore(conformanceParticipants(), storetest.MinCacheSize,
+			filepath.Join(dir, fmt.Sprintf("%d.sqlite", stores)), nil)
+		if err != nil {
+			// the factory runs in the subtests
+			t.Error(err)
+			return nil
+		}
+		return store
+	})
+}
@@ -0,0 +1,51 @@
+package poset_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/poset/storetest"
+)
+
+// conformanceParticipants returns 3 participants with fresh keys
+func conformanceParticipants() *peers.Peers {
+	participants := peers.NewPeers()
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateECDSAKey()
+		participants.AddPeer(peers.NewPeer(
+			fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)), ""))
+	}
+	return participants
+}
+
+func TestInmemStoreConformance(t *testing.T) {
+	storetest.RunStoreTests(t, func() poset.Store {
+		return poset.NewInmemStore(conformanceParticipants(), storetest.MinCacheSize, nil)
+	})
+}
+
+func TestBadgerStoreConformance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badger_conformance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stores := 0
+	storetest.RunStoreTests(t, func() poset.Store {
+		stores++
+		store, err := poset.NewBadgerStore(conformanceParticipants(), storetest.MinCacheSize,
+			fmt.Sprintf("%s/%d", dir, stores), nil)
+		if err != nil {
+			// the factory runs in the subtests
+			t.Error(err)
+			return nil
+		}
+		return store
+	})
+}
@@ -0,0 +1,144 @@
+package poset
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+const (
+	iteratorCreators = 3
+	iteratorIndexes  = 10
+)
+
+func iteratorParticipants() (*peers.Peers, []*ecdsa.PrivateKey) {
+	participants := peers.NewPeers()
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < iteratorCreators; i++ {
+		key, _ := crypto.GenerateECDSAKey()
+		participants.AddPeer(peers.NewPeer(fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey)), ""))
+		keys = append(keys, key)
+	}
+	return participants, keys
+}
+
+// populateIteratorStore sets iteratorIndexes events per creator, three
+// indexes per round, in topological order
+func populateIteratorStore(store Store, keys []*ecdsa.PrivateKey, t *testing.T) {
+	topologicalIndex := int64(0)
+	for i := 0; i < iteratorIndexes; i++ {
+		for _, key := range keys {
+			ev := NewEvent([][]byte{[]byte(fmt.Sprintf("tx%d", i))}, nil, nil,
+				make(EventHashes, 2), crypto.FromECDSAPub(&key.PublicKey), int64(i),
+				NewFlagTable(), NewFlagTable(), int64(i/3), false)
+			ev.Message.TopologicalIndex = topologicalIndex
+			topologicalIndex++
+			ev.Hash()
+			if err := store.SetEvent(ev); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func sortedHashes(hashes EventHashes) []string {
+	res := hashes.Strings()
+	sort.Strings(res)
+	return res
+}
+
+func testStoreIterators(store Store, keys []*ecdsa.PrivateKey, t *testing.T) {
+	populateIteratorStore(store, keys, t)
+
+	all, err := store.TopologicalEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != iteratorCreators*iteratorIndexes {
+		t.Fatalf("expected %d events, got %d", iteratorCreators*iteratorIndexes, len(all))
+	}
+	brute := func(keep func(Event) bool) EventHashes {
+		res := EventHashes{}
+		for _, ev := range all {
+			if keep(ev) {
+				res = append(res, ev.Hash())
+			}
+		}
+		return res
+	}
+
+	t.Run("ForEachEvent", func(t *testing.T) {
+		var visited EventHashes
+		err := store.ForEachEvent(func(ev Event) bool {
+			visited = append(visited, ev.Hash())
+			return len(visited) < 5
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := brute(func(Event) bool { return true })[:5]; !reflect.DeepEqual(visited, want) {
+			t.Fatalf("expected the first 5 events %v, got %v", want.Strings(), visited.Strings())
+		}
+	})
+
+	t.Run("EventsByCreator", func(t *testing.T) {
+		creator := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&keys[1].PublicKey))
+		ranges := []struct{ from, to int64 }{{0, 9}, {2, 5}, {8, 100}, {4, 4}, {6, 3}}
+		for _, r := range ranges {
+			got, err := store.EventsByCreator(creator, r.from, r.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := brute(func(ev Event) bool {
+				return ev.GetCreator() == creator && ev.Index() >= r.from && ev.Index() <= r.to
+			})
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("indexes %d to %d: expected %v, got %v", r.from, r.to, want.Strings(), got.Strings())
+			}
+		}
+	})
+
+	t.Run("EventsByRoundRange", func(t *testing.T) {
+		ranges := []struct{ from, to int64 }{{0, 3}, {1, 2}, {3, 10}, {5, 8}}
+		for _, r := range ranges {
+			got, err := store.EventsByRoundRange(r.from, r.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := brute(func(ev Event) bool {
+				return ev.Frame >= r.from && ev.Frame <= r.to
+			})
+			if !reflect.DeepEqual(sortedHashes(got), sortedHashes(want)) {
+				t.Fatalf("rounds %d to %d: expected %v, got %v", r.from, r.to, want.Strings(), got.Strings())
+			}
+		}
+	})
+}
+
+func TestInmemStoreIterators(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	testStoreIterators(NewInmemStore(participants, cacheSize, nil), keys, t)
+}
+
+func TestBadgerStoreIterators(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	dir, err := ioutil.TempDir("", "badger_iterators")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewBadgerStore(participants, cacheSize, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	testStoreIterators(store, keys, t)
+}
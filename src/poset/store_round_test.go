@@ -0,0 +1,147 @@
+package poset
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+func testStoreRounds(store Store, t *testing.T) {
+	hashes := []EventHash{testHash('a', 0), testHash('b', 0), testHash('c', 0)}
+
+	if _, err := store.GetRound(3); !common.Is(err, common.KeyNotFound) {
+		t.Fatalf("expected an unknown round not to be found, got %v", err)
+	}
+
+	round := NewRound()
+	round.AddEvent(hashes[0], true)
+	round.AddEvent(hashes[1], false)
+	round.SetAtropos(hashes[0], true)
+	round.SetConsensusEvent(hashes[1])
+	round.AddReceived(hashes[2])
+	round.AddReceived(hashes[1])
+	if err := store.SetRound(3, *round); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetRound", func(t *testing.T) {
+		res, err := store.GetRound(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Equals(round) {
+			t.Fatalf("expected the round %+v, got %+v", round.Message, res.Message)
+		}
+		if got := res.ReceivedEvents(); !reflect.DeepEqual(got, EventHashes{hashes[2], hashes[1]}) {
+			t.Fatalf("expected the received events in order, got %v", got)
+		}
+		if last := store.LastRound(); last != 3 {
+			t.Fatalf("expected the last round 3, got %d", last)
+		}
+		if clothos := store.RoundClothos(3); !reflect.DeepEqual(clothos, EventHashes{hashes[0]}) {
+			t.Fatalf("expected the clothos %v, got %v", hashes[:1], clothos)
+		}
+		if n := store.RoundEvents(3); n != 2 {
+			t.Fatalf("expected 2 events created in the round, got %d", n)
+		}
+	})
+
+	t.Run("Deprecated", func(t *testing.T) {
+		created, err := store.GetRoundCreated(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !EqualsMapStringRoundEvent(created.Message.Events, round.Message.Events) {
+			t.Fatalf("expected the created events %v, got %v",
+				round.Message.Events, created.Message.Events)
+		}
+		received, err := store.GetRoundReceived(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(received.Rounds, round.Message.Received) {
+			t.Fatalf("expected the received events %v, got %v",
+				round.Message.Received, received.Rounds)
+		}
+
+		// each part is set without clearing the other
+		created = *NewRoundCreated()
+		created.AddEvent(hashes[2], true)
+		if err := store.SetRoundCreated(3, created); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetRoundReceived(4, RoundReceived{Rounds: [][]byte{hashes[0].Bytes()}}); err != nil {
+			t.Fatal(err)
+		}
+		res, err := store.GetRound(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !EqualsMapStringRoundEvent(res.Message.Events, created.Message.Events) ||
+			!reflect.DeepEqual(res.Message.Received, round.Message.Received) {
+			t.Fatalf("expected the new created events and the received ones kept, got %+v", res.Message)
+		}
+		res, err = store.GetRound(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Message.Events) != 0 || !reflect.DeepEqual(res.ReceivedEvents(), EventHashes{hashes[0]}) {
+			t.Fatalf("expected a round with only received events, got %+v", res.Message)
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		if err := store.Reset(store.RootsByParticipant()); err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range []int64{3, 4} {
+			if _, err := store.GetRound(r); !common.Is(err, common.KeyNotFound) {
+				t.Fatalf("expected the round %d dropped by Reset, got %v", r, err)
+			}
+		}
+		if last := store.LastRound(); last != -1 {
+			t.Fatalf("expected no last round after Reset, got %d", last)
+		}
+	})
+}
+
+func TestInmemStoreRounds(t *testing.T) {
+	participants, _ := iteratorParticipants()
+	testStoreRounds(NewInmemStore(participants, cacheSize, nil), t)
+}
+
+func TestBadgerStoreRounds(t *testing.T) {
+	participants, _ := iteratorParticipants()
+	dir, err := ioutil.TempDir("", "badger_rounds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewBadgerStore(participants, cacheSize, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	testStoreRounds(store, t)
+
+	// the rounds are read back from the database, where Queued is dropped
+	round := NewRound()
+	round.AddEvent(testHash('a', 1), true)
+	round.AddReceived(testHash('a', 1))
+	round.Message.Queued = true
+	if err := store.SetRound(5, *round); err != nil {
+		t.Fatal(err)
+	}
+	res, err := store.dbGetRound(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	round.Message.Queued = false
+	if !res.Equals(round) {
+		t.Fatalf("expected the round %+v in the database, got %+v", round.Message, res.Message)
+	}
+}
@@ -0,0 +1,814 @@
+// Package storetest is a conformance suite for the implementations of
+// poset.Store: the poset expects the same behaviour of all of them, down to
+// the kind of the errors they return.
+package storetest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/common/hexutil"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// MinCacheSize is the cache size the stores of the suite need, for none of
+// the events and rounds it sets to be evicted
+const MinCacheSize = 100
+
+// unknownParticipant is the public key of no participant
+const unknownParticipant = "0xDEADBEEF"
+
+// RunStoreTests runs the suite against the stores made by factory. Each test
+// gets a fresh store, with at least two participants, their base roots and a
+// cache of at least MinCacheSize, and closes it. A factory failing to make a
+// store reports it with t.Error, not t.Fatal, and returns nil.
+func RunStoreTests(t *testing.T, factory func() poset.Store) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, *fixture)
+	}{
+		{"Events", testEvents},
+		{"Roots", testRoots},
+		{"ParticipantGaps", testParticipantGaps},
+		{"ConsensusEvents", testConsensusEvents},
+		{"Rounds", testRounds},
+		{"Blocks", testBlocks},
+		{"Frames", testFrames},
+		{"ClothoChecks", testClothoChecks},
+		{"TimeTables", testTimeTables},
+		{"Reset", testReset},
+		{"CommitBatch", testCommitBatch},
+		{"Metadata", testMetadata},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t, factory())
+			defer func() {
+				if err := f.store.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}()
+			test.fn(t, f)
+		})
+	}
+}
+
+// fixture is a store under test and its participants
+type fixture struct {
+	t            *testing.T
+	store        poset.Store
+	participants []*peers.Peer
+	pubKeys      [][]byte
+	// topological is the topological index of the next event
+	topological int64
+}
+
+func newFixture(t *testing.T, store poset.Store) *fixture {
+	if store == nil {
+		t.Fatal("expected a store from the factory")
+	}
+	participants, err := store.Participants()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fixture{t: t, store: store, participants: participants.ToPeerSlice()}
+	if len(f.participants) < 2 {
+		t.Fatalf("expected a store with at least 2 participants, got %d", len(f.participants))
+	}
+	if size := store.CacheSize(); size < MinCacheSize {
+		t.Fatalf("expected a cache of at least %d, got %d", MinCacheSize, size)
+	}
+	for _, p := range f.participants {
+		pubKey, err := hexutil.Decode(p.Message.PubKeyHex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.pubKeys = append(f.pubKeys, pubKey)
+	}
+	return f
+}
+
+// event returns an event of participant p with an index, created in a frame,
+// next in topological order
+func (f *fixture) event(p int, index, frame int64) poset.Event {
+	ev := poset.NewEvent([][]byte{[]byte(fmt.Sprintf("%d_%d", p, index))}, nil, nil,
+		make(poset.EventHashes, 2), f.pubKeys[p], index, nil, nil, frame, false)
+	ev.Message.TopologicalIndex = f.topological
+	f.topological++
+	_ = ev.Hash() // just to set private variables
+	return ev
+}
+
+// set stores events
+func (f *fixture) set(events ...poset.Event) {
+	f.t.Helper()
+	for _, ev := range events {
+		if err := f.store.SetEvent(ev); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+}
+
+// setGrid stores perParticipant events of each participant, in turns, the
+// k-th of each created in frame k, and returns them by participant
+func (f *fixture) setGrid(perParticipant int64) [][]poset.Event {
+	f.t.Helper()
+	res := make([][]poset.Event, len(f.participants))
+	for k := int64(0); k < perParticipant; k++ {
+		for p := range f.participants {
+			ev := f.event(p, k, k)
+			f.set(ev)
+			res[p] = append(res[p], ev)
+		}
+	}
+	return res
+}
+
+func hashes(events []poset.Event) poset.EventHashes {
+	res := make(poset.EventHashes, len(events))
+	for i := range events {
+		res[i] = events[i].Hash()
+	}
+	return res
+}
+
+func sorted(hs poset.EventHashes) []string {
+	res := make([]string, len(hs))
+	for i, h := range hs {
+		res[i] = h.String()
+	}
+	sort.Strings(res)
+	return res
+}
+
+func expectKind(t *testing.T, what string, err error, kind common.StoreErrType) {
+	t.Helper()
+	if !common.Is(err, kind) {
+		t.Fatalf("%s: expected a %v error, got %v", what, kind, err)
+	}
+}
+
+func expectHashes(t *testing.T, what string, got, expected poset.EventHashes) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("%s: expected %d events, got %d", what, len(expected), len(got))
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("%s: expected %v at %d, got %v", what, expected[i], i, got[i])
+		}
+	}
+}
+
+func testEvents(t *testing.T, f *fixture) {
+	s := f.store
+	if _, err := s.GetEventBlock(poset.EventHash{1}); !common.Is(err, common.KeyNotFound) {
+		t.Fatalf("expected an unknown event not found, got %v", err)
+	}
+
+	grid := f.setGrid(3)
+	var all []poset.Event
+	for k := 0; k < 3; k++ {
+		for p := range grid {
+			all = append(all, grid[p][k])
+		}
+	}
+	// setting an event again is no new event
+	f.set(grid[0][1])
+
+	for _, ev := range all {
+		got, err := s.GetEventBlock(ev.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.Message.Body, ev.Message.Body) || got.Frame != ev.Frame {
+			t.Fatalf("expected the event %v, got %v", ev, got)
+		}
+	}
+
+	topological, err := s.TopologicalEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectHashes(t, "TopologicalEvents", hashes(topological), hashes(all))
+	var visited int
+	if err := s.ForEachEvent(func(poset.Event) bool {
+		visited++
+		return visited < 2
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if visited != 2 {
+		t.Fatalf("expected ForEachEvent to stop at the second event, visited %d", visited)
+	}
+
+	p, other := f.participants[0].Message.PubKeyHex, f.participants[1].Message.PubKeyHex
+	byCreator, err := s.EventsByCreator(p, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectHashes(t, "EventsByCreator", byCreator, hashes(grid[0][1:3]))
+	for _, r := range [][2]int64{{2, 1}, {5, 9}} {
+		if res, err := s.EventsByCreator(p, r[0], r[1]); err != nil || len(res) != 0 {
+			t.Fatalf("expected no events from %d to %d, got %v (%v)", r[0], r[1], res, err)
+		}
+	}
+	_, err = s.EventsByCreator(unknownParticipant, 0, 2)
+	expectKind(t, "EventsByCreator", err, common.UnknownParticipant)
+
+	byRound, err := s.EventsByRoundRange(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := sorted(hashes(all[len(grid):])); !reflect.DeepEqual(sorted(byRound), expected) {
+		t.Fatalf("expected the events of rounds 1 and 2 %v, got %v", expected, sorted(byRound))
+	}
+	if res, err := s.EventsByRoundRange(2, 1); err != nil || len(res) != 0 {
+		t.Fatalf("expected no events in a reversed range, got %v (%v)", res, err)
+	}
+
+	participantEvents, err := s.ParticipantEvents(other, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectHashes(t, "ParticipantEvents", participantEvents, hashes(grid[1]))
+	if participantEvents, err = s.ParticipantEvents(other, 0); err != nil {
+		t.Fatal(err)
+	}
+	expectHashes(t, "ParticipantEvents skipping 0", participantEvents, hashes(grid[1][1:]))
+	_, err = s.ParticipantEvents(unknownParticipant, -1)
+	expectKind(t, "ParticipantEvents", err, common.UnknownParticipant)
+
+	if hash, err := s.ParticipantEvent(other, 1); err != nil || hash != grid[1][1].Hash() {
+		t.Fatalf("expected the event %v, got %v (%v)", grid[1][1].Hash(), hash, err)
+	}
+	_, err = s.ParticipantEvent(other, 7)
+	expectKind(t, "ParticipantEvent", err, common.KeyNotFound)
+	if _, err := s.ParticipantEvent(unknownParticipant, 0); err == nil {
+		t.Fatal("expected an error for the event of an unknown participant")
+	}
+
+	last, isRoot, err := s.LastEventFrom(other)
+	if err != nil || isRoot || last != grid[1][2].Hash() {
+		t.Fatalf("expected the last event %v, got %v, root %v (%v)", grid[1][2].Hash(), last, isRoot, err)
+	}
+
+	// consensus fields are refused on an event without a frame
+	ev := f.event(0, 3, poset.FrameNIL)
+	ev.Root = true
+	if err := s.SetEvent(ev); err == nil {
+		t.Fatal("expected a root without a frame to be refused")
+	}
+}
+
+func testRoots(t *testing.T, f *fixture) {
+	s := f.store
+	byParticipant := s.RootsByParticipant()
+	bySelfParent := s.RootsBySelfParent()
+	if len(byParticipant) != len(f.participants) || len(bySelfParent) != len(byParticipant) {
+		t.Fatalf("expected a root for each of the %d participants, got %d by participant and %d by self-parent",
+			len(f.participants), len(byParticipant), len(bySelfParent))
+	}
+	for _, p := range f.participants {
+		root, err := s.GetRoot(p.Message.PubKeyHex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := byParticipant[p.Message.PubKeyHex]
+		if !root.Equals(&expected) {
+			t.Fatalf("expected the root %v, got %v", expected, root)
+		}
+		checkRootEvents(t, f, p.Message.PubKeyHex, root)
+	}
+	checkRootsConsistent(t, s)
+
+	_, err := s.GetRoot(unknownParticipant)
+	expectKind(t, "GetRoot", err, common.KeyNotFound)
+	if _, _, err := s.LastEventFrom(unknownParticipant); err == nil {
+		t.Fatal("expected an error for the last event of an unknown participant")
+	}
+}
+
+// checkRootEvents checks the last events of a participant without events
+// are its root
+func checkRootEvents(t *testing.T, f *fixture, participant string, root poset.Root) {
+	t.Helper()
+	var expected poset.EventHash
+	if err := expected.Set(root.SelfParent.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if last, isRoot, err := f.store.LastEventFrom(participant); err != nil || !isRoot || last != expected {
+		t.Fatalf("expected the last event to be the root %v, got %v, root %v (%v)", expected, last, isRoot, err)
+	}
+	if last, isRoot, err := f.store.LastConsensusEventFrom(participant); err != nil || !isRoot || last != expected {
+		t.Fatalf("expected the last consensus event to be the root %v, got %v, root %v (%v)", expected, last, isRoot, err)
+	}
+	if hash, err := f.store.ParticipantEvent(participant, root.SelfParent.Index); err != nil || hash != expected {
+		t.Fatalf("expected the event at the index of the root to be %v, got %v (%v)", expected, hash, err)
+	}
+}
+
+// checkRootsConsistent checks the roots by self-parent are the roots by
+// participant
+func checkRootsConsistent(t *testing.T, s poset.Store) {
+	t.Helper()
+	bySelfParent := s.RootsBySelfParent()
+	for participant, root := range s.RootsByParticipant() {
+		var hash poset.EventHash
+		if err := hash.Set(root.SelfParent.Hash); err != nil {
+			t.Fatal(err)
+		}
+		other, ok := bySelfParent[hash]
+		if !ok || !other.Equals(&root) {
+			t.Fatalf("expected the root of %s by its self-parent %v, got %v", participant, hash, other)
+		}
+	}
+}
+
+func testParticipantGaps(t *testing.T, f *fixture) {
+	s := f.store
+	p := f.participants[0].Message.PubKeyHex
+	if gaps := s.ParticipantGaps(); len(gaps) != 0 {
+		t.Fatalf("expected no gaps, got %v", gaps)
+	}
+	events := []poset.Event{f.event(0, 0, 0), f.event(0, 1, 0), f.event(0, 2, 0), f.event(0, 3, 0)}
+	f.set(events[0])
+
+	// 3 skips 1 and 2, it is refused and they are recorded
+	err := s.SetEvent(events[3])
+	expectKind(t, "SetEvent", err, common.SkippedIndex)
+	if gaps := s.ParticipantGaps(); !reflect.DeepEqual(gaps, map[string][]int64{p: {1, 2}}) {
+		t.Fatalf("expected the gap of 1 and 2, got %v", gaps)
+	}
+	_, err = s.GetEventBlock(events[3].Hash())
+	expectKind(t, "GetEventBlock", err, common.KeyNotFound)
+
+	f.set(events[1:]...)
+	if gaps := s.ParticipantGaps(); len(gaps) != 0 {
+		t.Fatalf("expected the gap filled, got %v", gaps)
+	}
+
+	s.AddParticipantGap(p, 7, 8)
+	if gaps := s.ParticipantGaps(); !reflect.DeepEqual(gaps, map[string][]int64{p: {7, 8}}) {
+		t.Fatalf("expected the gap of 7 and 8, got %v", gaps)
+	}
+}
+
+func testConsensusEvents(t *testing.T, f *fixture) {
+	s := f.store
+	if n := s.ConsensusEventsCount(); n != 0 || len(s.ConsensusEvents()) != 0 {
+		t.Fatalf("expected no consensus events, got %d", n)
+	}
+	grid := f.setGrid(2)
+	var order []poset.Event
+	for p := range grid {
+		order = append(order, grid[p]...)
+	}
+	for _, ev := range order {
+		if err := s.AddConsensusEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectHashes(t, "ConsensusEvents", s.ConsensusEvents(), hashes(order))
+	if n := s.ConsensusEventsCount(); n != int64(len(order)) {
+		t.Fatalf("expected %d consensus events, got %d", len(order), n)
+	}
+	for p := range grid {
+		last, isRoot, err := s.LastConsensusEventFrom(f.participants[p].Message.PubKeyHex)
+		if err != nil || isRoot || last != grid[p][1].Hash() {
+			t.Fatalf("expected the last consensus event %v, got %v, root %v (%v)", grid[p][1].Hash(), last, isRoot, err)
+		}
+	}
+}
+
+func testRounds(t *testing.T, f *fixture) {
+	s := f.store
+	_, err := s.GetRound(0)
+	expectKind(t, "GetRound", err, common.KeyNotFound)
+	if last := s.LastRound(); last != -1 {
+		t.Fatalf("expected no last round, got %d", last)
+	}
+	if clothos, n := s.RoundClothos(5), s.RoundEvents(5); len(clothos) != 0 || n != 0 {
+		t.Fatalf("expected an unknown round empty, got %v and %d events", clothos, n)
+	}
+
+	grid := f.setGrid(1)
+	round := poset.NewRound()
+	round.AddEvent(grid[0][0].Hash(), true)
+	round.AddEvent(grid[1][0].Hash(), false)
+	if err := s.SetRound(1, *round); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetRound(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equals(round) {
+		t.Fatalf("expected the round %v, got %v", round, got)
+	}
+	expectHashes(t, "RoundClothos", s.RoundClothos(1), poset.EventHashes{grid[0][0].Hash()})
+	if n := s.RoundEvents(1); n != 2 {
+		t.Fatalf("expected 2 events in the round, got %d", n)
+	}
+
+	// the last round does not go back
+	if err := s.SetRound(0, *poset.NewRound()); err != nil {
+		t.Fatal(err)
+	}
+	if last := s.LastRound(); last != 1 {
+		t.Fatalf("expected the last round 1, got %d", last)
+	}
+
+	created := poset.NewRoundCreated()
+	created.AddEvent(grid[0][0].Hash(), true)
+	if err := s.SetRoundCreated(2, *created); err != nil {
+		t.Fatal(err)
+	}
+	gotCreated, err := s.GetRoundCreated(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotCreated.Message.Events, created.Message.Events) {
+		t.Fatalf("expected the created round %v, got %v", created, gotCreated)
+	}
+	if last := s.LastRound(); last != 2 {
+		t.Fatalf("expected the last round 2, got %d", last)
+	}
+}
+
+func testBlocks(t *testing.T, f *fixture) {
+	s := f.store
+	_, err := s.GetBlock(0)
+	expectKind(t, "GetBlock", err, common.KeyNotFound)
+	if last := s.LastBlockIndex(); last != -1 {
+		t.Fatalf("expected no last block, got %d", last)
+	}
+
+	blocks := make([]poset.Block, 2)
+	for i := range blocks {
+		blocks[i] = poset.NewBlock(int64(i), int64(i+1), []byte(fmt.Sprintf("frame %d", i)),
+			[][]byte{[]byte(fmt.Sprintf("tx %d", i))})
+		if err := s.SetBlock(blocks[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range blocks {
+		got, err := s.GetBlock(int64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equals(&blocks[i]) {
+			t.Fatalf("expected the block %v, got %v", blocks[i], got)
+		}
+	}
+
+	// the last block does not go back
+	if err := s.SetBlock(blocks[0]); err != nil {
+		t.Fatal(err)
+	}
+	if last := s.LastBlockIndex(); last != 1 {
+		t.Fatalf("expected the last block 1, got %d", last)
+	}
+}
+
+func testFrames(t *testing.T, f *fixture) {
+	s := f.store
+	_, err := s.GetFrame(3)
+	expectKind(t, "GetFrame", err, common.KeyNotFound)
+
+	root := s.RootsByParticipant()[f.participants[0].Message.PubKeyHex]
+	ev := f.event(0, 0, 3)
+	frame := poset.Frame{Round: 3, Roots: []*poset.Root{&root}, Events: []*poset.EventMessage{ev.Message}}
+	if err := s.SetFrame(frame); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetFrame(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equals(&frame) {
+		t.Fatalf("expected the frame %v, got %v", frame, got)
+	}
+
+	_, err = s.GetCheckpoint(3)
+	expectKind(t, "GetCheckpoint", err, common.KeyNotFound)
+	checkpoint := poset.NewCheckpoint(3, []byte("frame"), []byte("state"))
+	checkpoint.Signatures["validator"] = "signature"
+	if err := s.SetCheckpoint(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	gotCheckpoint, err := s.GetCheckpoint(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotCheckpoint, checkpoint) {
+		t.Fatalf("expected the checkpoint %v, got %v", checkpoint, gotCheckpoint)
+	}
+}
+
+func testClothoChecks(t *testing.T, f *fixture) {
+	s := f.store
+	grid := f.setGrid(1)
+	hash, creator := grid[0][0].Hash(), f.participants[0].ID
+
+	_, err := s.GetClothoCheck(1, hash)
+	expectKind(t, "GetClothoCheck", err, common.KeyNotFound)
+	_, err = s.GetClothoCreatorCheck(1, creator)
+	expectKind(t, "GetClothoCreatorCheck", err, common.KeyNotFound)
+
+	if err := s.AddClothoCheck(1, creator, hash); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetClothoCheck(1, hash); err != nil || got != hash {
+		t.Fatalf("expected the clotho check %v, got %v (%v)", hash, got, err)
+	}
+	if got, err := s.GetClothoCreatorCheck(1, creator); err != nil || got != hash {
+		t.Fatalf("expected the clotho creator check %v, got %v (%v)", hash, got, err)
+	}
+	_, err = s.GetClothoCheck(2, hash)
+	expectKind(t, "GetClothoCheck of another frame", err, common.KeyNotFound)
+}
+
+func testTimeTables(t *testing.T, f *fixture) {
+	s := f.store
+	grid := f.setGrid(1)
+	to, from := grid[0][0].Hash(), grid[1][0].Hash()
+
+	_, err := s.GetTimeTable(1, to)
+	expectKind(t, "GetTimeTable", err, common.KeyNotFound)
+	if err := s.NewTimeTable(1, to); err != nil {
+		t.Fatal(err)
+	}
+	if ft, err := s.GetTimeTable(1, to); err != nil || len(ft) != 0 {
+		t.Fatalf("expected an empty time table, got %v (%v)", ft, err)
+	}
+
+	if err := s.AddTimeTable(1, to, from, 5); err != nil {
+		t.Fatal(err)
+	}
+	ft, err := s.GetTimeTable(1, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (poset.FlagTable{from: 5}); !reflect.DeepEqual(ft, expected) {
+		t.Fatalf("expected the time table %v, got %v", expected, ft)
+	}
+	// the time table returned is a copy
+	ft[from] = 6
+	if ft, err := s.GetTimeTable(1, to); err != nil || ft[from] != 5 {
+		t.Fatalf("expected the stored time table unchanged, got %v (%v)", ft, err)
+	}
+
+	if err := s.AddTimeTable(2, to, from, 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DropTimeTables(2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetTimeTable(1, to); err == nil {
+		t.Fatal("expected the time table of a dropped frame gone")
+	}
+	if ft, err := s.GetTimeTable(2, to); err != nil || ft[from] != 7 {
+		t.Fatalf("expected the time table of a kept frame, got %v (%v)", ft, err)
+	}
+}
+
+func testReset(t *testing.T, f *fixture) {
+	s := f.store
+	grid := f.setGrid(3)
+	hash, creator := grid[0][0].Hash(), f.participants[0].ID
+	if err := s.SetRound(1, *poset.NewRound()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddClothoCheck(1, creator, hash); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.NewTimeTable(1, hash); err != nil {
+		t.Fatal(err)
+	}
+	block := poset.NewBlock(0, 1, []byte("frame"), nil)
+	if err := s.SetBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetFrame(poset.Frame{Round: 1}); err != nil {
+		t.Fatal(err)
+	}
+	s.AddParticipantGap(f.participants[0].Message.PubKeyHex, 7, 8)
+
+	// the roots are the last events of the participants
+	roots := make(map[string]poset.Root)
+	for p, peer := range f.participants {
+		root := poset.NewBaseRoot(peer.ID)
+		last := grid[p][len(grid[p])-1]
+		hash := last.Hash()
+		root.SelfParent.Hash = hash.Bytes()
+		root.SelfParent.Index = last.Index()
+		root.NextRound = 3
+		roots[peer.Message.PubKeyHex] = root
+	}
+	if err := s.Reset(roots); err != nil {
+		t.Fatal(err)
+	}
+
+	for participant, root := range roots {
+		got, err := s.GetRoot(participant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equals(&root) {
+			t.Fatalf("expected the root %v, got %v", root, got)
+		}
+		checkRootEvents(t, f, participant, root)
+	}
+	checkRootsConsistent(t, s)
+
+	if last := s.LastRound(); last != -1 {
+		t.Fatalf("expected no last round, got %d", last)
+	}
+	_, err := s.GetRound(1)
+	expectKind(t, "GetRound", err, common.KeyNotFound)
+	_, err = s.GetClothoCheck(1, hash)
+	expectKind(t, "GetClothoCheck", err, common.KeyNotFound)
+	_, err = s.GetTimeTable(1, hash)
+	expectKind(t, "GetTimeTable", err, common.KeyNotFound)
+	if gaps := s.ParticipantGaps(); len(gaps) != 0 {
+		t.Fatalf("expected no gaps, got %v", gaps)
+	}
+	// the blocks and frames decided are kept
+	if got, err := s.GetBlock(0); err != nil || !got.Equals(&block) {
+		t.Fatalf("expected the block %v, got %v (%v)", block, got, err)
+	}
+	if _, err := s.GetFrame(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// the events are inserted on top of the roots
+	if events, err := s.TopologicalEvents(); err != nil || len(events) != 0 {
+		t.Fatalf("expected no events, got %d (%v)", len(events), err)
+	}
+	ev := f.event(0, 3, 3)
+	f.set(ev)
+	events, err := s.TopologicalEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectHashes(t, "TopologicalEvents", hashes(events), poset.EventHashes{ev.Hash()})
+	if last, isRoot, err := s.LastEventFrom(f.participants[0].Message.PubKeyHex); err != nil || isRoot || last != ev.Hash() {
+		t.Fatalf("expected the last event %v, got %v, root %v (%v)", ev.Hash(), last, isRoot, err)
+	}
+}
+
+func testCommitBatch(t *testing.T, f *fixture) {
+	s := f.store
+	ev := f.event(0, 0, 0)
+	round := poset.NewRound()
+	round.AddEvent(ev.Hash(), true)
+
+	batch := poset.NewStoreBatch(s)
+	if err := batch.SetEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.SetRound(0, *round); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := batch.GetEventBlock(ev.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	// nothing is written before the commit
+	_, err := s.GetEventBlock(ev.Hash())
+	expectKind(t, "GetEventBlock", err, common.KeyNotFound)
+	_, err = s.GetRound(0)
+	expectKind(t, "GetRound", err, common.KeyNotFound)
+
+	if err := s.CommitBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetEventBlock(ev.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetRound(0); err != nil || !got.Equals(round) {
+		t.Fatalf("expected the round %v, got %v (%v)", round, got, err)
+	}
+	if last := s.LastRound(); last != 0 {
+		t.Fatalf("expected the last round 0, got %d", last)
+	}
+}
+
+func testMetadata(t *testing.T, f *fixture) {
+	s := f.store
+	grid := f.setGrid(1)
+
+	if archived, err := s.ArchivedEvents(); err != nil || len(archived) != 0 {
+		t.Fatalf("expected no archived events, got %v (%v)", archived, err)
+	}
+	if err := s.SetArchivedEvents(hashes(grid[0])); err != nil {
+		t.Fatal(err)
+	}
+	archived, err := s.ArchivedEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectHashes(t, "ArchivedEvents", archived, hashes(grid[0]))
+
+	if hash, err := s.ConsensusConfigHash(); err != nil || hash != (common.Hash{}) {
+		t.Fatalf("expected no consensus configuration hash, got %v (%v)", hash, err)
+	}
+	configHash := common.BytesToHash([]byte("config"))
+	if err := s.SetConsensusConfigHash(configHash); err != nil {
+		t.Fatal(err)
+	}
+	if hash, err := s.ConsensusConfigHash(); err != nil || hash != configHash {
+		t.Fatalf("expected the consensus configuration hash %v, got %v (%v)", configHash, hash, err)
+	}
+
+	if carry, err := s.BlockCarry(1); err != nil || len(carry) != 0 {
+		t.Fatalf("expected no block carry, got %v (%v)", carry, err)
+	}
+	carry := [][]byte{[]byte("tx1"), []byte("tx2")}
+	if err := s.SetBlockCarry(1, carry); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.BlockCarry(1); err != nil || !reflect.DeepEqual(got, carry) {
+		t.Fatalf("expected the block carry %q, got %q (%v)", carry, got, err)
+	}
+	if got, err := s.BlockCarry(2); err != nil || len(got) != 0 {
+		t.Fatalf("expected no block carry past frame 2, got %q (%v)", got, err)
+	}
+
+	// the reputations and addresses set are merged with the known ones
+	p, other := f.participants[0].Message.PubKeyHex, f.participants[1].Message.PubKeyHex
+	for _, reps := range []map[string]poset.PeerReputation{{p: {Failures: 1}}, {other: {Penalties: 2}}} {
+		if err := s.SetPeerReputations(reps); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reps, err := s.PeerReputations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reps) != 2 || reps[p].Failures != 1 || reps[other].Penalties != 2 {
+		t.Fatalf("expected the reputations of both peers, got %v", reps)
+	}
+	for _, addr := range []peers.AddrAnnouncement{{PubKeyHex: p, NetAddr: "a:1"}, {PubKeyHex: other, NetAddr: "b:1"}} {
+		if err := s.SetPeerAddresses(map[string]peers.AddrAnnouncement{addr.PubKeyHex: addr}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addrs, err := s.PeerAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 || addrs[p].NetAddr != "a:1" || addrs[other].NetAddr != "b:1" {
+		t.Fatalf("expected the addresses of both peers, got %v", addrs)
+	}
+
+	if last, err := s.LastAcknowledgedBlock(); err != nil || last != -1 {
+		t.Fatalf("expected no acknowledged block, got %d (%v)", last, err)
+	}
+	for _, i := range []int64{2, 0, 1} {
+		if err := s.JournalBlock(poset.NewBlock(i, i+1, nil, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkJournal := func(expected ...int64) {
+		t.Helper()
+		blocks, err := s.JournalledBlocks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		indexes := make([]int64, len(blocks))
+		for i := range blocks {
+			indexes[i] = blocks[i].Index()
+		}
+		if !reflect.DeepEqual(indexes, expected) {
+			t.Fatalf("expected the journalled blocks %v, got %v", expected, indexes)
+		}
+	}
+	checkJournal(0, 1, 2)
+	if err := s.AcknowledgeBlock(1); err != nil {
+		t.Fatal(err)
+	}
+	checkJournal(2)
+	// the last acknowledged block does not go back
+	if err := s.AcknowledgeBlock(0); err != nil {
+		t.Fatal(err)
+	}
+	if last, err := s.LastAcknowledgedBlock(); err != nil || last != 1 {
+		t.Fatalf("expected the acknowledged block 1, got %d (%v)", last, err)
+	}
+
+	if _, err := s.LookupTx(common.BytesToHash([]byte("unknown"))); err != poset.ErrNoTxIndex && !common.Is(err, common.KeyNotFound) {
+		t.Fatalf("expected an unknown transaction not found, got %v", err)
+	}
+	if s.StateDB() == nil {
+		t.Fatal("expected a state database")
+	}
+	if s.NeedBootstrap() {
+		t.Fatal("expected a new store not to need a bootstrap")
+	}
+}
@@ -0,0 +1,81 @@
+package poset
+
+import (
+	"fmt"
+)
+
+// tableKind tells the flag table of an event from its root table
+type tableKind uint8
+
+const (
+	flagTableKind tableKind = iota
+	rootTableKind
+)
+
+// tableKey is the key of a table in the table cache of the Poset
+type tableKey struct {
+	hash EventHash
+	kind tableKind
+}
+
+// tableOf returns a table of an event, unmarshalled once. The table is
+// shared and must not be modified: Copy it first. The zero Event of a
+// missing root parent has no hash, and empty tables which are not cached.
+func (p *Poset) tableOf(ev *Event, kind tableKind) (FlagTable, error) {
+	if ev.Message == nil {
+		return NewFlagTable(), nil
+	}
+	key := tableKey{ev.Hash(), kind}
+	if c, ok := p.tableCache.Get(key); ok {
+		return c.(FlagTable), nil
+	}
+	return p.decodeTable(key, ev)
+}
+
+// flagTableOf returns the flag table of an event, as tableOf
+func (p *Poset) flagTableOf(ev *Event) (FlagTable, error) {
+	return p.tableOf(ev, flagTableKind)
+}
+
+// rootTableOf returns the root table of an event, as tableOf
+func (p *Poset) rootTableOf(ev *Event) (FlagTable, error) {
+	return p.tableOf(ev, rootTableKind)
+}
+
+// decodeTable unmarshals a table of an event into the cache
+func (p *Poset) decodeTable(key tableKey, ev *Event) (FlagTable, error) {
+	data := ev.FlagTableBytes
+	if key.kind == rootTableKind {
+		data = ev.RootTableBytes
+	}
+	table := NewFlagTable()
+	if err := table.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	p.tableCache.Add(key, table)
+	return table, nil
+}
+
+// forgetTables drops the tables of an event from the cache. It must be
+// called whenever the tables of an event are replaced.
+func (p *Poset) forgetTables(hash EventHash) {
+	p.tableCache.Remove(tableKey{hash, flagTableKind})
+	p.tableCache.Remove(tableKey{hash, rootTableKind})
+}
+
+// rootTable returns the root table of an event of the store, as tableOf
+func (p *Poset) rootTable(hash EventHash) (FlagTable, error) {
+	key := tableKey{hash, rootTableKind}
+	if c, ok := p.tableCache.Get(key); ok {
+		return c.(FlagTable), nil
+	}
+	ev, err := p.Store.GetEventBlock(hash)
+	if err != nil {
+		return nil, fmt.Errorf("GetEventBlock(%s): %v", hash.String(), err)
+	}
+	table, err := p.decodeTable(key, &ev)
+	if err != nil {
+		return nil, fmt.Errorf("GetRootTable(%s): %v", hash.String(), err)
+	}
+	return table, nil
+}
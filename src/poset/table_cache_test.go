@@ -0,0 +1,127 @@
+package poset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTableCacheNotCorrupted(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	store := NewInmemStore(participants, cacheSize, nil)
+	p, err := NewPoset(participants, store, nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := func(ev *Event) uint64 {
+		return participants.ByPubKey[ev.GetCreator()].ID
+	}
+
+	// a is the root of the frame 0 and b the root of the frame 1
+	a := capEvent(participants, keys[0], nil, EventHash{}, "a0")
+	a.Message.CreatorID = id(&a)
+	a.Frame, a.Root = 0, true
+	b := capEvent(participants, keys[1], nil, EventHash{}, "b0")
+	b.Message.CreatorID = id(&b)
+	b.Frame, b.Root = 1, true
+	bFlags := FlagTable{b.Hash(): 1}
+	b.FlagTableBytes = bFlags.Marshal()
+	b.RootTableBytes = FlagTable{a.Hash(): 0}.Marshal()
+	for _, ev := range []Event{a, b} {
+		if err := store.SetEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AddClothoCheck(ev.Frame, ev.CreatorID(), ev.Hash()); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.NewTimeTable(ev.Frame, ev.Hash()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := p.flagTableOf(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	// a1 is a root of the frame 1 too, and InsertEvent adds it to its copy
+	// of the flag table of b
+	a1 := capEvent(participants, keys[0], &a, b.Hash(), "a1")
+	if err := p.InsertEvent(a1, false); err != nil {
+		t.Fatal(err)
+	}
+	inserted, err := store.GetEventBlock(a1.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := inserted.GetFlagTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (FlagTable{b.Hash(): 1, a1.Hash(): 1}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the flag table %v, got %v", want, got)
+	}
+	cached, err := p.flagTableOf(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cached, bFlags) {
+		t.Fatalf("expected the cached flag table %v, got %v", bFlags, cached)
+	}
+}
+
+func TestTableCacheForget(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	p, err := NewPoset(participants, NewInmemStore(participants, cacheSize, nil), nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev := capEvent(participants, keys[0], nil, EventHash{}, "a")
+	table := NewFlagTable()
+	table[ev.Hash()] = 1
+	if err := ev.ReplaceFlagTable(table); err != nil {
+		t.Fatal(err)
+	}
+	first, err := p.flagTableOf(&ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := p.flagTableOf(&ev)
+	if reflect.ValueOf(first).Pointer() != reflect.ValueOf(second).Pointer() {
+		t.Fatal("expected the flag table unmarshalled once")
+	}
+
+	if err := ev.ReplaceFlagTable(NewFlagTable()); err != nil {
+		t.Fatal(err)
+	}
+	p.forgetTables(ev.Hash())
+	replaced, err := p.flagTableOf(&ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replaced) != 0 {
+		t.Fatalf("expected the replaced flag table, got %v", replaced)
+	}
+}
+
+func BenchmarkClothoCheckingTables(b *testing.B) {
+	d := newRootDAG(10, 10, 1, b)
+	for _, cached := range []bool{true, false} {
+		name := "uncached"
+		if cached {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := d.checkRoots(true, func(p *Poset, e *Event) error {
+					if !cached {
+						p.tableCache.Purge()
+					}
+					return nil
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package poset
+
+import (
+	"errors"
+
+	"github.com/hashicorp/golang-lru"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+// ErrNoTxIndex is returned by the lookups in a store which does not index
+// the transactions
+var ErrNoTxIndex = errors.New("the store does not index the transactions")
+
+// TxLocation is where a transaction is: the event carrying it and, once
+// committed, the block and its position in the block. Block is -1 before
+// the commit. Transactions with the same content share a location, the last
+// one indexed.
+type TxLocation struct {
+	Tx       common.Hash `json:"tx"`
+	Event    common.Hash `json:"event"`
+	Block    int64       `json:"block"`
+	Position int         `json:"position"`
+}
+
+// TxHash returns the hash a transaction is indexed by
+func TxHash(tx []byte) common.Hash {
+	return crypto.Keccak256Hash(tx)
+}
+
+// eventTxLocation returns the location of a transaction of an event, given
+// the location indexed so far if any. A committed location is kept.
+func eventTxLocation(tx common.Hash, event EventHash, prev *TxLocation) TxLocation {
+	if prev != nil && prev.Block >= 0 {
+		return *prev
+	}
+	return TxLocation{Tx: tx, Event: common.Hash(event), Block: -1}
+}
+
+// blockTxLocation returns the location of the transaction at position in a
+// block, given the location indexed so far if any
+func blockTxLocation(tx common.Hash, block int64, position int, prev *TxLocation) TxLocation {
+	loc := TxLocation{Tx: tx}
+	if prev != nil {
+		loc = *prev
+	}
+	loc.Block = block
+	loc.Position = position
+	return loc
+}
+
+// EnableTxIndex makes the store index the transactions of the events and
+// blocks set from now on. The index keeps as many transactions as the
+// caches, and drops those whose block left the store.
+func (s *InmemStore) EnableTxIndex() {
+	index, err := lru.New(s.cacheSize)
+	if err != nil {
+		panic(err)
+	}
+	s.txIndex = index
+}
+
+// indexEventTxs indexes the transactions of an event, when the index is on
+func (s *InmemStore) indexEventTxs(event *Event) {
+	if s.txIndex == nil {
+		return
+	}
+	hash := event.Hash()
+	for _, tx := range event.Transactions() {
+		txHash := TxHash(tx)
+		s.txIndex.Add(txHash, eventTxLocation(txHash, hash, s.txLocation(txHash)))
+	}
+}
+
+// indexBlockTxs indexes the transactions of a block, when the index is on
+func (s *InmemStore) indexBlockTxs(block *Block) {
+	if s.txIndex == nil {
+		return
+	}
+	for i, tx := range block.Transactions() {
+		txHash := TxHash(tx)
+		s.txIndex.Add(txHash, blockTxLocation(txHash, block.Index(), i, s.txLocation(txHash)))
+	}
+}
+
+func (s *InmemStore) txLocation(txHash common.Hash) *TxLocation {
+	res, ok := s.txIndex.Get(txHash)
+	if !ok {
+		return nil
+	}
+	loc := res.(TxLocation)
+	return &loc
+}
+
+// LookupTx returns the location of a transaction by the hash of its content
+func (s *InmemStore) LookupTx(txHash common.Hash) (TxLocation, error) {
+	if s.txIndex == nil {
+		return TxLocation{}, ErrNoTxIndex
+	}
+	loc := s.txLocation(txHash)
+	if loc != nil && loc.Block >= 0 {
+		if _, err := s.GetBlock(loc.Block); common.Is(err, common.KeyNotFound) {
+			// the block was pruned
+			s.txIndex.Remove(txHash)
+			loc = nil
+		}
+	}
+	if loc == nil {
+		return TxLocation{}, common.NewStoreErr("TxIndex", common.KeyNotFound, txHash.Hex())
+	}
+	return *loc, nil
+}
@@ -0,0 +1,94 @@
+package poset
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+func TestTxIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tx_index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	participants, keys := iteratorParticipants()
+	badgerStore, err := NewBadgerStore(participants, cacheSize, filepath.Join(dir, "badger"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer badgerStore.Close()
+
+	inmemStore := NewInmemStore(participants, cacheSize, nil)
+	for name, store := range map[string]interface {
+		Store
+		EnableTxIndex()
+	}{
+		"inmem":  inmemStore,
+		"badger": badgerStore,
+	} {
+		if _, err := store.LookupTx(TxHash([]byte("a"))); err != ErrNoTxIndex {
+			t.Fatalf("%s: expected %v, got %v", name, ErrNoTxIndex, err)
+		}
+		store.EnableTxIndex()
+
+		event := capEvent(participants, keys[0], nil, EventHash{}, "a")
+		if err := store.SetEvent(event); err != nil {
+			t.Fatal(err)
+		}
+		loc, err := store.LookupTx(TxHash([]byte("a")))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		expected := TxLocation{Tx: TxHash([]byte("a")), Event: common.Hash(event.Hash()), Block: -1}
+		if loc != expected {
+			t.Fatalf("%s: expected %+v, got %+v", name, expected, loc)
+		}
+
+		if err := store.SetBlock(NewBlock(0, 1, []byte("frame"), [][]byte{[]byte("b"), []byte("a")})); err != nil {
+			t.Fatal(err)
+		}
+		// the event is set again once received, it keeps the block
+		if err := store.SetEvent(event); err != nil {
+			t.Fatal(err)
+		}
+		expected.Block, expected.Position = 0, 1
+		if loc, err = store.LookupTx(TxHash([]byte("a"))); err != nil || loc != expected {
+			t.Fatalf("%s: expected %+v, got %+v, %v", name, expected, loc, err)
+		}
+		if loc, err = store.LookupTx(TxHash([]byte("b"))); err != nil || loc.Block != 0 || loc.Position != 0 {
+			t.Fatalf("%s: expected b in block 0, got %+v, %v", name, loc, err)
+		}
+
+		if _, err := store.LookupTx(TxHash([]byte("c"))); !common.Is(err, common.KeyNotFound) {
+			t.Fatalf("%s: expected c not to be found, got %v", name, err)
+		}
+	}
+}
+
+func TestTxIndexPruned(t *testing.T) {
+	participants, _ := iteratorParticipants()
+	store := NewInmemStore(participants, 2, nil)
+	store.EnableTxIndex()
+
+	for i := int64(0); i < 3; i++ {
+		tx := []byte{byte(i)}
+		if err := store.SetBlock(NewBlock(i, i+1, []byte("frame"), [][]byte{tx})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// block 0 left the store
+	if _, err := store.GetBlock(0); !common.Is(err, common.KeyNotFound) {
+		t.Fatalf("expected block 0 to be evicted, got %v", err)
+	}
+	if _, err := store.LookupTx(TxHash([]byte{0})); !common.Is(err, common.KeyNotFound) {
+		t.Fatalf("expected the tx of block 0 to be dropped, got %v", err)
+	}
+	if loc, err := store.LookupTx(TxHash([]byte{2})); err != nil || loc.Block != 2 {
+		t.Fatalf("expected the tx of block 2, got %+v, %v", loc, err)
+	}
+}
@@ -0,0 +1,185 @@
+package poset
+
+import (
+	"math"
+
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+const (
+	// DefaultUndeterminedWarnAge is the number of rounds an event may stay
+	// undetermined before DecideRoundReceived warns about it
+	DefaultUndeterminedWarnAge = 20
+	// archiveScanInterval is the number of DecideRoundReceived passes between
+	// two scans of the archived undetermined events, besides the passes
+	// which receive a round
+	archiveScanInterval = 10
+)
+
+// undeterminedAgeBuckets are the bounds, in rounds, of the histogram of the
+// ages of undetermined events
+var undeterminedAgeBuckets = []float64{0, 1, 2, 3, 5, 8, 13, 21, 34, 55, 89}
+
+// UndeterminedStats describes the events whose consensus order is not
+// determined yet, as of the last DecideRoundReceived. The age of an event is
+// the number of rounds since its own. Ages only covers the archived events
+// on the passes scanning them.
+type UndeterminedStats struct {
+	Events    int                      `json:"events"`
+	Archived  int                      `json:"archived"`
+	OldestAge int64                    `json:"oldest_age"`
+	Ages      common.HistogramSnapshot `json:"ages"`
+}
+
+// undecided is why an event is not received yet: the round it was created in
+// and the first later round whose clothos are not all decided, -1 if none
+type undecided struct {
+	round    int64
+	blocking int64
+}
+
+// undeterminedAges collects the ages of the undetermined events of a pass
+type undeterminedAges struct {
+	lastRound int64
+	ages      *common.Histogram
+	oldest    int64
+	oldestX   EventHash
+	oldestU   undecided
+	seen      bool
+}
+
+func (p *Poset) newUndeterminedAges() *undeterminedAges {
+	return &undeterminedAges{
+		lastRound: p.Store.LastRound(),
+		ages:      common.NewHistogram(undeterminedAgeBuckets),
+		oldest:    -1,
+	}
+}
+
+// observe records the age of an undetermined event and returns it
+func (a *undeterminedAges) observe(x EventHash, u undecided) int64 {
+	age := a.lastRound - u.round
+	if age < 0 {
+		age = 0
+	}
+	a.ages.Observe(float64(age))
+	if age > a.oldest {
+		a.oldest, a.oldestX, a.oldestU, a.seen = age, x, u, true
+	}
+	return age
+}
+
+// SetUndeterminedAges sets the number of rounds after which an undetermined
+// event is warned about, and after which it is archived to the store, out of
+// the UndeterminedEvents. Archived events are still decided, on every pass
+// of DecideRoundReceived which receives a round and every
+// archiveScanInterval passes. 0 never archives.
+func (p *Poset) SetUndeterminedAges(warnAge, archiveAge int64) {
+	p.undeterminedWarnAge = warnAge
+	p.undeterminedArchiveAge = archiveAge
+}
+
+// GetUndeterminedStats returns the undetermined events statistics of the last
+// DecideRoundReceived
+func (p *Poset) GetUndeterminedStats() UndeterminedStats {
+	p.undeterminedStatsLocker.RLock()
+	defer p.undeterminedStatsLocker.RUnlock()
+	return p.undeterminedStats
+}
+
+// updateArchive adds events to the archive, and decides the events archived
+// already on the passes which receive a round and every archiveScanInterval
+// passes. Called by DecideRoundReceived.
+func (p *Poset) updateArchive(archived EventHashes, pendingRoundReceived map[int64]bool,
+	ages *undeterminedAges) error {
+	p.undeterminedPasses++
+	// a round received is processed right after the pass, so an archived
+	// event received in it must be decided in the same pass, or its frame
+	// would be made without it
+	scan := p.archivedCount > 0 && (len(pendingRoundReceived) > 0 ||
+		p.undeterminedPasses%archiveScanInterval == 0)
+	if !scan && len(archived) == 0 {
+		return nil
+	}
+
+	stored, err := p.Store.ArchivedEvents()
+	if err != nil {
+		return err
+	}
+	if scan {
+		var still EventHashes
+		p.archiveOldestRound = math.MaxInt64
+		for _, x := range stored {
+			received, u, err := p.decideReceived(x, pendingRoundReceived)
+			if err != nil {
+				return err
+			}
+			if received {
+				continue
+			}
+			ages.observe(x, u)
+			still = append(still, x)
+			if u.round < p.archiveOldestRound {
+				p.archiveOldestRound = u.round
+			}
+		}
+		stored = still
+	}
+	if len(stored) == 0 {
+		p.archiveOldestRound = math.MaxInt64
+	}
+	for _, x := range archived {
+		// the round is cached by the pass which archived the event
+		if r, err := p.round(x); err == nil && r < p.archiveOldestRound {
+			p.archiveOldestRound = r
+		}
+	}
+
+	res := make(EventHashes, 0, len(stored)+len(archived))
+	res = append(append(res, stored...), archived...)
+	p.archivedCount = len(res)
+	return p.Store.SetArchivedEvents(res)
+}
+
+// clearArchive drops the archived undetermined events, for Reset and
+// Bootstrap which rebuild UndeterminedEvents
+func (p *Poset) clearArchive() error {
+	p.archivedCount = 0
+	p.archiveOldestRound = math.MaxInt64
+	return p.Store.SetArchivedEvents(nil)
+}
+
+// undeterminedDiagnostics updates the undetermined events statistics after a
+// DecideRoundReceived pass, and warns about the oldest event if it is older
+// than the warn age, with the round blocking it
+func (p *Poset) undeterminedDiagnostics(ages *undeterminedAges) {
+	stats := UndeterminedStats{
+		Events:    len(p.GetUndeterminedEvents()),
+		Archived:  p.archivedCount,
+		OldestAge: ages.oldest,
+		Ages:      ages.ages.Snapshot(),
+	}
+	if p.archivedCount > 0 && ages.lastRound-p.archiveOldestRound > stats.OldestAge {
+		stats.OldestAge = ages.lastRound - p.archiveOldestRound
+	}
+	if stats.OldestAge < 0 {
+		stats.OldestAge = 0
+	}
+	p.undeterminedStatsLocker.Lock()
+	p.undeterminedStats = stats
+	p.undeterminedStatsLocker.Unlock()
+
+	if !ages.seen || p.undeterminedWarnAge <= 0 || ages.oldest < p.undeterminedWarnAge {
+		return
+	}
+	var clothos EventHashes
+	if ages.oldestU.blocking >= 0 {
+		if tr, err := p.Store.GetRound(ages.oldestU.blocking); err == nil {
+			clothos = tr.UndecidedClothos()
+		}
+	}
+	p.warnLimiter.Warnf(p.logger,
+		"event %s undetermined for %d rounds (%d undetermined, %d archived), blocked by round %d with undecided clothos %v",
+		ages.oldestX.String(), ages.oldest, stats.Events, stats.Archived,
+		ages.oldestU.blocking, clothos.Strings())
+}
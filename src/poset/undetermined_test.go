@@ -0,0 +1,259 @@
+package poset
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// warnHook records the warnings logged
+type warnHook struct {
+	warnings []string
+}
+
+func (h *warnHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.WarnLevel}
+}
+
+func (h *warnHook) Fire(e *logrus.Entry) error {
+	h.warnings = append(h.warnings, e.Message)
+	return nil
+}
+
+func testHash(kind byte, i int) EventHash {
+	var x EventHash
+	x[0] = kind
+	binary.BigEndian.PutUint64(x[1:], uint64(i))
+	return x
+}
+
+// stalledPoset returns a poset whose rounds never decide their clothos, each
+// round blocking the events of the round before it
+type stalledPoset struct {
+	*Poset
+	rounds int
+	pub    []byte
+	events EventHashes // the undetermined event of each round
+}
+
+func newStalledPoset(warnAge, archiveAge int64, t *testing.T) (*stalledPoset, *warnHook) {
+	participants := peers.NewPeers()
+	key, _ := crypto.GenerateECDSAKey()
+	pub := crypto.FromECDSAPub(&key.PublicKey)
+	participants.AddPeer(peers.NewPeer(fmt.Sprintf("0x%X", pub), ""))
+
+	store := NewInmemStore(participants, cacheSize, nil)
+	p, err := NewPoset(participants, store, nil, testLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetUndeterminedAges(warnAge, archiveAge)
+	hook := &warnHook{}
+	p.logger.Logger.AddHook(hook)
+	return &stalledPoset{Poset: p, pub: pub}, hook
+}
+
+// advance adds a round with an undecided clotho and an undetermined event,
+// then decides the undetermined events
+func (p *stalledPoset) advance(t *testing.T) {
+	r := p.rounds
+	p.rounds++
+
+	round := NewRound()
+	round.AddEvent(testHash('c', r), true)
+	if err := p.Store.SetRound(int64(r), *round); err != nil {
+		t.Fatal(err)
+	}
+	var selfParent EventHash
+	if r > 0 {
+		selfParent = p.events[r-1]
+	}
+	ev := NewEvent(nil, nil, nil, EventHashes{selfParent, EventHash{}}, p.pub,
+		int64(r), nil, nil, int64(r), false)
+	if err := p.Store.SetEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+	x := ev.Hash()
+	p.events = append(p.events, x)
+	p.roundCache.Add(x, int64(r))
+	p.undeterminedEventsLocker.Lock()
+	p.UndeterminedEvents = append(p.UndeterminedEvents, x)
+	p.undeterminedEventsLocker.Unlock()
+
+	if err := p.DecideRoundReceived(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUndeterminedDiagnostics(t *testing.T) {
+	p, hook := newStalledPoset(5, 0, t)
+
+	for i := 0; i < 5; i++ {
+		p.advance(t)
+	}
+	if len(hook.warnings) != 0 {
+		t.Fatalf("expected no warnings before the warn age, got %v", hook.warnings)
+	}
+
+	p.advance(t)
+	stats := p.GetUndeterminedStats()
+	if stats.Events != 6 || stats.Archived != 0 || stats.OldestAge != 5 {
+		t.Fatalf("expected 6 events, 0 archived, oldest 5 rounds, got %+v", stats)
+	}
+	if stats.Ages.Count != 6 {
+		t.Fatalf("expected 6 ages, got %d", stats.Ages.Count)
+	}
+	if len(hook.warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", hook.warnings)
+	}
+	blocker := testHash('c', 1)
+	if w := hook.warnings[0]; !strings.Contains(w, "blocked by round 1") ||
+		!strings.Contains(w, blocker.String()) {
+		t.Fatalf("expected the warning to name round 1 and clotho %s, got %q",
+			blocker.String(), w)
+	}
+}
+
+func TestUndeterminedArchive(t *testing.T) {
+	const archiveAge = 3
+	p, _ := newStalledPoset(0, archiveAge, t)
+
+	const rounds = 50
+	for i := 0; i < rounds; i++ {
+		p.advance(t)
+		if n := len(p.GetUndeterminedEvents()); n > archiveAge+1 {
+			t.Fatalf("expected at most %d events in memory, got %d", archiveAge+1, n)
+		}
+	}
+
+	stats := p.GetUndeterminedStats()
+	if stats.Events+stats.Archived != rounds {
+		t.Fatalf("expected %d undetermined events, got %+v", rounds, stats)
+	}
+	if stats.OldestAge != rounds-1 {
+		t.Fatalf("expected the oldest archived event to be %d rounds old, got %d",
+			rounds-1, stats.OldestAge)
+	}
+	archived, err := p.Store.ArchivedEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != stats.Archived {
+		t.Fatalf("expected %d archived events in the store, got %d",
+			stats.Archived, len(archived))
+	}
+	for i := 0; i < rounds-archiveAge-1; i++ {
+		if archived[i] != p.events[i] {
+			t.Fatalf("expected event %d to be archived, got %s", i, archived[i].String())
+		}
+	}
+}
+
+func TestUndeterminedArchiveReceived(t *testing.T) {
+	const archiveAge = 3
+	p, _ := newStalledPoset(0, archiveAge, t)
+
+	for i := 0; i < archiveAge+2; i++ {
+		p.advance(t)
+	}
+	if stats := p.GetUndeterminedStats(); stats.Archived != 1 {
+		t.Fatalf("expected 1 archived event, got %+v", stats)
+	}
+
+	// the rounds 1 and 2 decide their clotho, which sees the events of the
+	// rounds before it: the archived event is received in the round 1 and
+	// the event of the round 1 in the round 2, on a pass which is not a
+	// scan of the archive
+	for r := 1; r <= 2; r++ {
+		round, err := p.Store.GetRound(int64(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		clotho := testHash('c', r)
+		round.SetAtropos(clotho, true)
+		if err := p.Store.SetRound(int64(r), round); err != nil {
+			t.Fatal(err)
+		}
+		for _, x := range p.events[:r] {
+			p.dominatorCache.Add(Key{clotho, x}, true)
+		}
+	}
+	if err := p.DecideRoundReceived(); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := p.GetUndeterminedStats(); stats.Archived != 0 {
+		t.Fatalf("expected the archived event to be received, got %+v", stats)
+	}
+	round, err := p.Store.GetRound(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received := round.ReceivedEvents(); len(received) != 1 || received[0] != p.events[0] {
+		t.Fatalf("expected the archived event to be received in the round 1, got %v",
+			received.Strings())
+	}
+	if len(p.PendingRoundReceived) != 2 || p.PendingRoundReceived[0] != 1 {
+		t.Fatalf("expected the rounds 1 and 2 to be pending, got %v", p.PendingRoundReceived)
+	}
+}
+
+// resetDuringScan is a store which clears the queues of the poset, as a
+// Reset does, the first time a round is read once armed
+type resetDuringScan struct {
+	Store
+	p     *Poset
+	armed bool
+}
+
+func (s *resetDuringScan) GetRound(r int64) (Round, error) {
+	if s.armed {
+		s.armed = false
+		s.p.undeterminedEventsLocker.Lock()
+		s.p.UndeterminedEvents = EventHashes{}
+		s.p.PendingRoundReceived = common.Int64Slice{}
+		s.p.undeterminedEventsEpoch++
+		s.p.undeterminedEventsLocker.Unlock()
+	}
+	return s.Store.GetRound(r)
+}
+
+func TestDecideRoundReceivedAcrossReset(t *testing.T) {
+	p, _ := newStalledPoset(0, 0, t)
+	for i := 0; i < 3; i++ {
+		p.advance(t)
+	}
+	round, err := p.Store.GetRound(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clotho := testHash('c', 1)
+	round.SetAtropos(clotho, true)
+	if err := p.Store.SetRound(1, round); err != nil {
+		t.Fatal(err)
+	}
+	p.dominatorCache.Add(Key{clotho, p.events[0]}, true)
+
+	store := &resetDuringScan{Store: p.Store, p: p.Poset, armed: true}
+	p.Store = store
+	if err := p.DecideRoundReceived(); err != nil {
+		t.Fatal(err)
+	}
+	if store.armed {
+		t.Fatal("expected the scan to read a round")
+	}
+	if len(p.PendingRoundReceived) != 0 {
+		t.Fatalf("expected no round received from before the reset, got %v",
+			p.PendingRoundReceived)
+	}
+	if n := len(p.GetUndeterminedEvents()); n != 0 {
+		t.Fatalf("expected no undetermined event from before the reset, got %d", n)
+	}
+}
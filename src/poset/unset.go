@@ -0,0 +1,81 @@
+package poset
+
+import (
+	"fmt"
+)
+
+// The unset values of the fields the consensus assigns. Zero is a valid
+// frame, round, lamport timestamp and atropos time, so unset is negative,
+// and the Has
+// accessors of Event are to be used rather than comparisons with them.
+const (
+	// LamportTimestampNIL nil value for lamport
+	LamportTimestampNIL int64 = -1
+	// FrameNIL nil value for event frame number
+	FrameNIL int64 = -1
+	// RoundNIL nil value for event round
+	RoundNIL int64 = -1
+	// AtroposTimestampNIL nil value for the atropos time of an event
+	AtroposTimestampNIL int64 = -1
+	// LastConsensusRoundNIL is the last consensus round of a Poset which has
+	// decided none, below RoundNIL for the undecided rounds of the events to
+	// be after it
+	LastConsensusRoundNIL int64 = -2
+)
+
+// HasFrame returns true once the frame of the event is assigned
+func (e *Event) HasFrame() bool {
+	return e.Frame > FrameNIL
+}
+
+// HasLamport returns true once the lamport timestamp of the event is
+// assigned
+func (e *Event) HasLamport() bool {
+	return e.LamportTimestamp > LamportTimestampNIL
+}
+
+// HasRound returns true once the round of the event is assigned
+func (e *Event) HasRound() bool {
+	return e.GetRound() > RoundNIL
+}
+
+// HasAtroposTime returns true once the atropos time of the event is
+// assigned
+func (e *Event) HasAtroposTime() bool {
+	return e.AtroposTimestamp > AtroposTimestampNIL
+}
+
+// HasFrameReceived returns true once the event is received in a frame, no
+// frame received being zero
+func (e *Event) HasFrameReceived() bool {
+	return e.FrameReceived > 0
+}
+
+// checkConsensusFields returns an error when an event claims fields the
+// consensus cannot have assigned it yet, or values which are neither
+// assigned nor unset
+func checkConsensusFields(e *Event) error {
+	if e.Frame < FrameNIL {
+		return fmt.Errorf("frame %d is neither assigned nor unset", e.Frame)
+	}
+	if e.LamportTimestamp < LamportTimestampNIL {
+		return fmt.Errorf("lamport timestamp %d is neither assigned nor unset", e.LamportTimestamp)
+	}
+	if e.AtroposTimestamp < AtroposTimestampNIL {
+		return fmt.Errorf("atropos time %d is neither assigned nor unset", e.AtroposTimestamp)
+	}
+	if e.HasFrame() {
+		return nil
+	}
+	switch {
+	case e.Root:
+		return fmt.Errorf("root without a frame")
+	case e.Clotho:
+		return fmt.Errorf("clotho without a frame")
+	case e.Atropos:
+		return fmt.Errorf("atropos without a frame")
+	case e.HasFrameReceived():
+		return fmt.Errorf("received in frame %d without a frame", e.FrameReceived)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package poset
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEventHasAccessors(t *testing.T) {
+	cases := []struct {
+		value int64
+		set   bool
+	}{
+		{0, true},
+		{1, true},
+		{math.MaxInt64, true},
+		{-1, false},
+		{math.MinInt64, false},
+	}
+	for _, c := range cases {
+		ev := Event{Frame: c.value, LamportTimestamp: c.value, AtroposTimestamp: c.value}
+		if got := ev.HasFrame(); got != c.set {
+			t.Errorf("frame %d: expected HasFrame %v, got %v", c.value, c.set, got)
+		}
+		if got := ev.HasLamport(); got != c.set {
+			t.Errorf("lamport %d: expected HasLamport %v, got %v", c.value, c.set, got)
+		}
+		if got := ev.HasAtroposTime(); got != c.set {
+			t.Errorf("atropos time %d: expected HasAtroposTime %v, got %v", c.value, c.set, got)
+		}
+	}
+
+	// rounds are not kept on events, they are never assigned
+	ev := Event{Frame: 3, LamportTimestamp: 3}
+	if ev.HasRound() {
+		t.Errorf("expected no round, got %d", ev.GetRound())
+	}
+
+	for _, c := range []struct {
+		value int64
+		set   bool
+	}{{0, false}, {1, true}, {-1, false}, {math.MinInt64, false}} {
+		ev := Event{FrameReceived: c.value}
+		if got := ev.HasFrameReceived(); got != c.set {
+			t.Errorf("frame received %d: expected HasFrameReceived %v, got %v", c.value, c.set, got)
+		}
+	}
+}
+
+func TestCheckConsensusFields(t *testing.T) {
+	cases := []struct {
+		name  string
+		event Event
+		ok    bool
+	}{
+		{"unset", Event{Frame: FrameNIL, LamportTimestamp: LamportTimestampNIL, AtroposTimestamp: AtroposTimestampNIL}, true},
+		{"frame 0", Event{Frame: 0, LamportTimestamp: 0, Root: true}, true},
+		{"received", Event{Frame: 2, LamportTimestamp: 5, Clotho: true, Atropos: true, FrameReceived: 2}, true},
+		{"frame below unset", Event{Frame: math.MinInt64, LamportTimestamp: 0}, false},
+		{"lamport below unset", Event{Frame: 0, LamportTimestamp: math.MinInt64}, false},
+		{"atropos time below unset", Event{Frame: 0, LamportTimestamp: 0, AtroposTimestamp: math.MinInt64}, false},
+		{"root without frame", Event{Frame: FrameNIL, Root: true}, false},
+		{"clotho without frame", Event{Frame: FrameNIL, Clotho: true}, false},
+		{"atropos without frame", Event{Frame: FrameNIL, Atropos: true}, false},
+		{"received without frame", Event{Frame: FrameNIL, FrameReceived: 1}, false},
+	}
+	for _, c := range cases {
+		err := checkConsensusFields(&c.event)
+		if c.ok && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if !c.ok && err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestSetEventRefusesConsensusFields(t *testing.T) {
+	participants, keys := iteratorParticipants()
+	store := NewInmemStore(participants, 10, nil)
+
+	ev := capEvent(participants, keys[0], nil, EventHash{}, "a0")
+	ev.Root = true
+	if err := store.SetEvent(ev); err == nil {
+		t.Fatal("expected a root without a frame to be refused")
+	}
+	if _, err := store.GetEventBlock(ev.Hash()); err == nil {
+		t.Fatal("expected the refused event not to be stored")
+	}
+
+	batch := NewStoreBatch(store)
+	if err := batch.SetEvent(ev); err == nil {
+		t.Fatal("expected the batch to refuse a root without a frame")
+	}
+
+	ev.Frame = 0
+	if err := store.SetEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWireRoundTripKeepsUnsetFields(t *testing.T) {
+	p, index, _ := initRoundPoset(t)
+
+	for k, evh := range index {
+		if k[0] == 'r' {
+			continue
+		}
+		ev, err := p.Store.GetEventBlock(evh)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wev := ev.ToWire()
+		if wev.Body.SelfParentIndex < 0 {
+			// any negative index is the root's
+			wev.Body.SelfParentIndex = math.MinInt64
+		}
+		fromWire, err := p.ReadWireInfo(wev)
+		if err != nil {
+			t.Fatalf("%s: %v", k, err)
+		}
+
+		if fromWire.HasFrame() || fromWire.HasLamport() || fromWire.HasRound() ||
+			fromWire.HasAtroposTime() || fromWire.HasFrameReceived() {
+			t.Fatalf("%s: expected the consensus fields unset, got frame %d, lamport %d, atropos time %d, frame received %d",
+				k, fromWire.Frame, fromWire.LamportTimestamp, fromWire.AtroposTimestamp, fromWire.FrameReceived)
+		}
+		if fromWire.Message.SelfParentIndex != ev.Message.SelfParentIndex {
+			t.Fatalf("%s: expected self-parent index %d, got %d",
+				k, ev.Message.SelfParentIndex, fromWire.Message.SelfParentIndex)
+		}
+		if hash := fromWire.Hash(); hash != evh {
+			t.Fatalf("%s: expected hash %s, got %s", k, evh.String(), hash.String())
+		}
+
+		// the stores take it as it is, unset
+		if err := checkConsensusFields(fromWire); err != nil {
+			t.Fatalf("%s: %v", k, err)
+		}
+	}
+}
+
+func TestReceivedEventsTimed(t *testing.T) {
+	// the first events on the base roots have a zero lamport timestamp, a
+	// valid atropos time of the events they are decided on
+	p, index := initConsensusPoset(false, t)
+	p.commitCh = make(chan Block, 10)
+	if err := p.RunToQuiescence(); err != nil {
+		t.Fatal(err)
+	}
+
+	consensusEvents := p.Store.ConsensusEvents()
+	zeroTimed := 0
+	for _, hash := range consensusEvents {
+		ev, err := p.Store.GetEventBlock(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ev.HasAtroposTime() {
+			t.Fatalf("%s of lamport timestamp %d received in frame %d has the unset atropos time",
+				getName(index, hash), ev.LamportTimestamp, ev.FrameReceived)
+		}
+		if ev.AtroposTimestamp == 0 {
+			zeroTimed++
+		}
+	}
+	if len(consensusEvents) == 0 {
+		t.Fatal("expected events in consensus")
+	}
+	if zeroTimed == 0 {
+		t.Fatal("expected events timed on the first events, at 0")
+	}
+}
@@ -0,0 +1,44 @@
+package poset
+
+import (
+	"runtime"
+	"sync"
+)
+
+// VerifyEvents verifies the signatures of events concurrently across
+// GOMAXPROCS workers, and returns the error InsertEvent would for each
+// event. Nil events are skipped, with a nil error.
+func VerifyEvents(events []*Event) []error {
+	errs := make([]error, len(events))
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(events) {
+		workers = len(events)
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				ok, err := events[i].Verify()
+				switch {
+				case err != nil:
+					errs[i] = err
+				case !ok:
+					errs[i] = ErrInvalidSignature
+				}
+			}
+		}()
+	}
+	for i, ev := range events {
+		if ev != nil {
+			next <- i
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	return errs
+}
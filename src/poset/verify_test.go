@@ -0,0 +1,83 @@
+package poset
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+// signedEvents returns n events signed by their creator, which is one of 4
+func signedEvents(n int, t testing.TB) []*Event {
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateECDSAKey()
+		keys = append(keys, key)
+	}
+	events := make([]*Event, n)
+	for i := range events {
+		key := keys[i%len(keys)]
+		ev := NewEvent([][]byte{[]byte(fmt.Sprintf("tx%d", i))}, nil, nil,
+			make(EventHashes, 2), crypto.FromECDSAPub(&key.PublicKey), int64(i/len(keys)),
+			NewFlagTable(), NewFlagTable(), 0, false)
+		if err := ev.Sign(key); err != nil {
+			t.Fatal(err)
+		}
+		events[i] = &ev
+	}
+	return events
+}
+
+func TestVerifyEvents(t *testing.T) {
+	events := signedEvents(20, t)
+	events[3].Message.Signature = events[4].Message.Signature
+	events[7].Message.Signature = "not a signature"
+	events[9] = nil
+
+	errs := VerifyEvents(events)
+	for i, err := range errs {
+		switch i {
+		case 3:
+			if err != ErrInvalidSignature {
+				t.Fatalf("expected event %d to have an invalid signature, got %v", i, err)
+			}
+		case 7:
+			if err == nil {
+				t.Fatalf("expected event %d to fail to decode", i)
+			}
+		default:
+			if err != nil {
+				t.Fatalf("expected event %d to verify, got %v", i, err)
+			}
+		}
+	}
+
+	if errs := VerifyEvents(nil); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func BenchmarkVerifyEventsSerial(b *testing.B) {
+	events := signedEvents(1000, b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, ev := range events {
+			if ok, err := ev.Verify(); !ok || err != nil {
+				b.Fatal("invalid signature")
+			}
+		}
+	}
+}
+
+func BenchmarkVerifyEvents(b *testing.B) {
+	events := signedEvents(1000, b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, err := range VerifyEvents(events) {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
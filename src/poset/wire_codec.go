@@ -0,0 +1,604 @@
+package poset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// The compact codec encodes the events of the peer wire by hand, without
+// the reflection of gob and protobuf. A batch starts with a magic byte, the
+// codec version and the number of events. An event starts with a fixed
+// width header of its creator IDs, timestamp and body version, followed by
+// varint indexes and length prefixed byte fields. Counts and lengths are
+// checked against the bytes left, truncated input is refused, never read
+// past. The decoded byte fields share a copy of the input, made once.
+
+// ErrCompactTruncated is the error of a compact encoding cut short
+var ErrCompactTruncated = errors.New("truncated compact event encoding")
+
+const (
+	compactWireEvents    byte = 'W'
+	compactEventMessages byte = 'M'
+	compactFlagTable     byte = 'F'
+	compactVersion       byte = 1
+
+	// wireHeaderSize is the fixed width header of a wire event: creator ID,
+	// other parent creator ID, timestamp and body version
+	wireHeaderSize = 8 + 8 + 8 + 4
+)
+
+// CompactError refuses a compact encoding which is not truncated but does
+// not decode either
+type CompactError struct {
+	Reason string
+}
+
+func (e *CompactError) Error() string {
+	return "invalid compact event encoding: " + e.Reason
+}
+
+// IsCompactError returns true for a malformed or truncated compact encoding
+func IsCompactError(err error) bool {
+	_, ok := err.(*CompactError)
+	return ok || err == ErrCompactTruncated
+}
+
+// compactWriter appends the fields of the compact encoding to a buffer
+type compactWriter struct {
+	buf []byte
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (w *compactWriter) uvarint(x uint64) {
+	n := binary.PutUvarint(w.tmp[:], x)
+	w.buf = append(w.buf, w.tmp[:n]...)
+}
+
+func (w *compactWriter) varint(x int64) {
+	n := binary.PutVarint(w.tmp[:], x)
+	w.buf = append(w.buf, w.tmp[:n]...)
+}
+
+func (w *compactWriter) fixed64(x uint64) {
+	binary.BigEndian.PutUint64(w.tmp[:8], x)
+	w.buf = append(w.buf, w.tmp[:8]...)
+}
+
+func (w *compactWriter) fixed32(x uint32) {
+	binary.BigEndian.PutUint32(w.tmp[:4], x)
+	w.buf = append(w.buf, w.tmp[:4]...)
+}
+
+func (w *compactWriter) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *compactWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *compactWriter) byteSlices(bs [][]byte) {
+	w.uvarint(uint64(len(bs)))
+	for _, b := range bs {
+		w.bytes(b)
+	}
+}
+
+func (w *compactWriter) bool(b bool) {
+	if b {
+		w.buf = append(w.buf, 1)
+	} else {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *compactWriter) internalTransaction(tx *InternalTransaction) {
+	w.uvarint(uint64(tx.Type))
+	w.uvarint(tx.Amount)
+	w.bool(tx.Peer != nil)
+	if tx.Peer != nil {
+		w.string(tx.Peer.NetAddr)
+		w.string(tx.Peer.PubKeyHex)
+	}
+}
+
+// compactReader reads the fields of a compact encoding, the first error
+// sticks and the reads after it return zero values
+type compactReader struct {
+	buf []byte
+	err error
+}
+
+// newCompactReader reads a copy of data, which the decoded fields share
+func newCompactReader(data []byte) *compactReader {
+	return &compactReader{buf: append([]byte(nil), data...)}
+}
+
+func (r *compactReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+	r.buf = nil
+}
+
+func (r *compactReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(r.buf)
+	if n == 0 {
+		r.fail(ErrCompactTruncated)
+		return 0
+	}
+	if n < 0 {
+		r.fail(&CompactError{Reason: "varint overflows 64 bits"})
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return x
+}
+
+func (r *compactReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	x, n := binary.Varint(r.buf)
+	if n == 0 {
+		r.fail(ErrCompactTruncated)
+		return 0
+	}
+	if n < 0 {
+		r.fail(&CompactError{Reason: "varint overflows 64 bits"})
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return x
+}
+
+// next returns the next n bytes, sharing the buffer
+func (r *compactReader) next(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.buf)) {
+		r.fail(ErrCompactTruncated)
+		return nil
+	}
+	res := r.buf[:n:n]
+	r.buf = r.buf[n:]
+	return res
+}
+
+func (r *compactReader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *compactReader) fixed64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *compactReader) fixed32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// bytes returns a length prefixed field, nil when empty
+func (r *compactReader) bytes() []byte {
+	b := r.next(r.uvarint())
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
+
+func (r *compactReader) string() string {
+	return string(r.next(r.uvarint()))
+}
+
+// count reads the number of items of a list, each of at least min bytes,
+// refusing more than the bytes left can hold
+func (r *compactReader) count(min int) int {
+	n := r.uvarint()
+	if r.err == nil && n > uint64(len(r.buf)/min) {
+		r.fail(ErrCompactTruncated)
+		return 0
+	}
+	return int(n)
+}
+
+func (r *compactReader) byteSlices() [][]byte {
+	n := r.count(1)
+	if n == 0 {
+		return nil
+	}
+	res := make([][]byte, n)
+	for i := range res {
+		res[i] = r.bytes()
+	}
+	return res
+}
+
+func (r *compactReader) bool() bool {
+	switch r.byte() {
+	case 0:
+		return false
+	case 1:
+		return true
+	}
+	r.fail(&CompactError{Reason: "invalid bool"})
+	return false
+}
+
+func (r *compactReader) internalTransaction() InternalTransaction {
+	tx := InternalTransaction{
+		Type:   TransactionType(r.uvarint()),
+		Amount: r.uvarint(),
+	}
+	if r.bool() {
+		tx.Peer = &peers.PeerMessage{
+			NetAddr:   r.string(),
+			PubKeyHex: r.string(),
+		}
+	}
+	return tx
+}
+
+// header checks the magic byte and the version of an encoding and returns
+// the number of items it holds, each of at least min bytes
+func (r *compactReader) header(magic byte, min int) int {
+	if m := r.byte(); r.err == nil && m != magic {
+		r.fail(&CompactError{Reason: fmt.Sprintf("unexpected magic byte %#x", m)})
+	}
+	if v := r.byte(); r.err == nil && v != compactVersion {
+		r.fail(&CompactError{Reason: fmt.Sprintf("unsupported codec version %d", v)})
+	}
+	return r.count(min)
+}
+
+// end returns the error of the reads, refusing bytes left after the last
+// item
+func (r *compactReader) end() error {
+	if r.err == nil && len(r.buf) > 0 {
+		return &CompactError{Reason: fmt.Sprintf("%d trailing bytes", len(r.buf))}
+	}
+	return r.err
+}
+
+/*******************************************************************************
+ Wire events
+*******************************************************************************/
+
+// MarshalWireEvents returns the compact encoding of a batch of wire events
+func MarshalWireEvents(events []WireEvent) []byte {
+	size := 16
+	for i := range events {
+		size += events[i].compactSize()
+	}
+	w := compactWriter{buf: make([]byte, 0, size)}
+	w.buf = append(w.buf, compactWireEvents, compactVersion)
+	w.uvarint(uint64(len(events)))
+	for i := range events {
+		w.wireEvent(&events[i])
+	}
+	return w.buf
+}
+
+// compactSize returns an upper bound of the size of the compact encoding of
+// the event
+func (ev *WireEvent) compactSize() int {
+	body := &ev.Body
+	size := wireHeaderSize + 9*binary.MaxVarintLen64 + len(ev.Signature) +
+		len(body.TransactionFlags) + len(body.NetworkID) +
+		byteSlicesSize(body.Transactions)
+	for i := range body.InternalTransactions {
+		size += internalTransactionSize(&body.InternalTransactions[i])
+	}
+	for _, bs := range body.BlockSignatures {
+		size += 2*binary.MaxVarintLen64 + len(bs.Signature)
+	}
+	return size
+}
+
+func byteSlicesSize(bs [][]byte) int {
+	size := binary.MaxVarintLen64
+	for _, b := range bs {
+		size += binary.MaxVarintLen64 + len(b)
+	}
+	return size
+}
+
+func internalTransactionSize(tx *InternalTransaction) int {
+	size := 2*binary.MaxVarintLen64 + 1
+	if tx.Peer != nil {
+		size += 2*binary.MaxVarintLen64 + len(tx.Peer.NetAddr) + len(tx.Peer.PubKeyHex)
+	}
+	return size
+}
+
+func (w *compactWriter) wireEvent(ev *WireEvent) {
+	body := &ev.Body
+	w.fixed64(body.CreatorID)
+	w.fixed64(body.OtherParentCreatorID)
+	w.fixed64(uint64(body.Timestamp))
+	w.fixed32(body.Version)
+	w.varint(body.Index)
+	w.varint(body.SelfParentIndex)
+	w.varint(body.OtherParentIndex)
+	w.string(ev.Signature)
+	w.bytes(body.TransactionFlags)
+	w.bytes(body.NetworkID)
+	w.byteSlices(body.Transactions)
+	w.uvarint(uint64(len(body.InternalTransactions)))
+	for i := range body.InternalTransactions {
+		w.internalTransaction(&body.InternalTransactions[i])
+	}
+	w.uvarint(uint64(len(body.BlockSignatures)))
+	for _, bs := range body.BlockSignatures {
+		w.varint(bs.Index)
+		w.string(bs.Signature)
+	}
+}
+
+// UnmarshalWireEvents decodes a batch of wire events from its compact
+// encoding. Empty fields decode to nil.
+func UnmarshalWireEvents(data []byte) ([]WireEvent, error) {
+	r := newCompactReader(data)
+	n := r.header(compactWireEvents, wireHeaderSize)
+	var events []WireEvent
+	if n > 0 {
+		events = make([]WireEvent, n)
+	}
+	for i := 0; i < n && r.err == nil; i++ {
+		r.wireEvent(&events[i])
+	}
+	if err := r.end(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *compactReader) wireEvent(ev *WireEvent) {
+	body := &ev.Body
+	body.CreatorID = r.fixed64()
+	body.OtherParentCreatorID = r.fixed64()
+	body.Timestamp = int64(r.fixed64())
+	body.Version = r.fixed32()
+	body.Index = r.varint()
+	body.SelfParentIndex = r.varint()
+	body.OtherParentIndex = r.varint()
+	ev.Signature = r.string()
+	body.TransactionFlags = r.bytes()
+	body.NetworkID = r.bytes()
+	body.Transactions = r.byteSlices()
+	if n := r.count(3); n > 0 {
+		body.InternalTransactions = make([]InternalTransaction, n)
+		for i := range body.InternalTransactions {
+			body.InternalTransactions[i] = r.internalTransaction()
+		}
+	}
+	if n := r.count(2); n > 0 {
+		body.BlockSignatures = make([]WireBlockSignature, n)
+		for i := range body.BlockSignatures {
+			body.BlockSignatures[i].Index = r.varint()
+			body.BlockSignatures[i].Signature = r.string()
+		}
+	}
+}
+
+/*******************************************************************************
+ Event messages
+*******************************************************************************/
+
+// MarshalEventMessages returns the compact encoding of event messages, those
+// of the frame pages. A nil message is refused.
+func MarshalEventMessages(events []*EventMessage) ([]byte, error) {
+	size := 16
+	for i, ev := range events {
+		if ev == nil {
+			return nil, fmt.Errorf("event message %d is nil", i)
+		}
+		size += ev.compactSize()
+	}
+	w := compactWriter{buf: make([]byte, 0, size)}
+	w.buf = append(w.buf, compactEventMessages, compactVersion)
+	w.uvarint(uint64(len(events)))
+	for _, ev := range events {
+		w.eventMessage(ev)
+	}
+	return w.buf, nil
+}
+
+// compactSize returns an upper bound of the size of the compact encoding of
+// the message
+func (ev *EventMessage) compactSize() int {
+	size := 16 + 6*binary.MaxVarintLen64 + len(ev.Signature) + len(ev.Hash)
+	if ev.Body == nil {
+		return size
+	}
+	body := ev.Body
+	size += 12 + 6*binary.MaxVarintLen64 + len(body.Creator) +
+		len(body.TransactionFlags) + len(body.NetworkID) +
+		byteSlicesSize(body.Parents) + byteSlicesSize(body.Transactions)
+	for _, tx := range body.InternalTransactions {
+		size++
+		if tx != nil {
+			size += internalTransactionSize(tx)
+		}
+	}
+	for _, bs := range body.BlockSignatures {
+		size++
+		if bs != nil {
+			size += 3*binary.MaxVarintLen64 + len(bs.Validator) + len(bs.Signature)
+		}
+	}
+	return size
+}
+
+func (w *compactWriter) eventMessage(ev *EventMessage) {
+	w.fixed64(ev.CreatorID)
+	w.fixed64(ev.OtherParentCreatorID)
+	w.varint(ev.SelfParentIndex)
+	w.varint(ev.OtherParentIndex)
+	w.varint(ev.TopologicalIndex)
+	w.string(ev.Signature)
+	w.bytes(ev.Hash)
+	w.bool(ev.Body != nil)
+	if ev.Body == nil {
+		return
+	}
+	body := ev.Body
+	w.fixed64(uint64(body.Timestamp))
+	w.fixed32(body.Version)
+	w.varint(body.Index)
+	w.bytes(body.Creator)
+	w.bytes(body.TransactionFlags)
+	w.bytes(body.NetworkID)
+	w.byteSlices(body.Parents)
+	w.byteSlices(body.Transactions)
+	w.uvarint(uint64(len(body.InternalTransactions)))
+	for _, tx := range body.InternalTransactions {
+		w.bool(tx != nil)
+		if tx != nil {
+			w.internalTransaction(tx)
+		}
+	}
+	w.uvarint(uint64(len(body.BlockSignatures)))
+	for _, bs := range body.BlockSignatures {
+		w.bool(bs != nil)
+		if bs != nil {
+			w.bytes(bs.Validator)
+			w.varint(bs.Index)
+			w.string(bs.Signature)
+		}
+	}
+}
+
+// UnmarshalEventMessages decodes event messages from their compact
+// encoding. Empty fields decode to nil.
+func UnmarshalEventMessages(data []byte) ([]*EventMessage, error) {
+	r := newCompactReader(data)
+	// the fixed width fields and a byte per other field of a message
+	n := r.header(compactEventMessages, 8+8+6)
+	var events []*EventMessage
+	if n > 0 {
+		events = make([]*EventMessage, n)
+	}
+	for i := 0; i < n && r.err == nil; i++ {
+		events[i] = r.eventMessage()
+	}
+	if err := r.end(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *compactReader) eventMessage() *EventMessage {
+	ev := &EventMessage{
+		CreatorID:            r.fixed64(),
+		OtherParentCreatorID: r.fixed64(),
+		SelfParentIndex:      r.varint(),
+		OtherParentIndex:     r.varint(),
+		TopologicalIndex:     r.varint(),
+		Signature:            r.string(),
+		Hash:                 r.bytes(),
+	}
+	if !r.bool() {
+		return ev
+	}
+	body := &EventBody{
+		Timestamp:        int64(r.fixed64()),
+		Version:          r.fixed32(),
+		Index:            r.varint(),
+		Creator:          r.bytes(),
+		TransactionFlags: r.bytes(),
+		NetworkID:        r.bytes(),
+		Parents:          r.byteSlices(),
+		Transactions:     r.byteSlices(),
+	}
+	if n := r.count(1); n > 0 {
+		body.InternalTransactions = make([]*InternalTransaction, n)
+		for i := range body.InternalTransactions {
+			if r.bool() {
+				tx := r.internalTransaction()
+				body.InternalTransactions[i] = &tx
+			}
+		}
+	}
+	if n := r.count(1); n > 0 {
+		body.BlockSignatures = make([]*BlockSignature, n)
+		for i := range body.BlockSignatures {
+			if r.bool() {
+				body.BlockSignatures[i] = &BlockSignature{
+					Validator: r.bytes(),
+					Index:     r.varint(),
+					Signature: r.string(),
+				}
+			}
+		}
+	}
+	ev.Body = body
+	return ev
+}
+
+/*******************************************************************************
+ Flag tables
+*******************************************************************************/
+
+// MarshalCompact returns the compact encoding of the flag table, its
+// (hash, frame) pairs sorted by hash, so equal tables encode the same
+func (ft FlagTable) MarshalCompact() []byte {
+	hashes := make([]EventHash, 0, len(ft))
+	for hash := range ft {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+
+	w := compactWriter{buf: make([]byte, 0, 4+len(ft)*(len(EventHash{})+4))}
+	w.buf = append(w.buf, compactFlagTable, compactVersion)
+	w.uvarint(uint64(len(hashes)))
+	for _, hash := range hashes {
+		w.buf = append(w.buf, hash[:]...)
+		w.varint(ft[hash])
+	}
+	return w.buf
+}
+
+// UnmarshalCompact reads the compact encoding of a flag table into ft. The
+// pairs must be sorted by hash, without duplicates.
+func (ft FlagTable) UnmarshalCompact(data []byte) error {
+	r := compactReader{buf: data}
+	n := r.header(compactFlagTable, len(EventHash{})+1)
+	var prev []byte
+	for i := 0; i < n && r.err == nil; i++ {
+		b := r.next(uint64(len(EventHash{})))
+		frame := r.varint()
+		if r.err != nil {
+			break
+		}
+		if prev != nil && bytes.Compare(prev, b) >= 0 {
+			return &CompactError{Reason: "flag table pairs not sorted by hash"}
+		}
+		prev = b
+		var hash EventHash
+		copy(hash[:], b)
+		ft[hash] = frame
+	}
+	return r.end()
+}
@@ -0,0 +1,473 @@
+package poset
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/peers"
+)
+
+// randBytes returns n random bytes, nil for none as the codec decodes them
+func randBytes(rng *rand.Rand, n int) []byte {
+	if n == 0 {
+		return nil
+	}
+	b := make([]byte, n)
+	rng.Read(b)
+	return b
+}
+
+func randByteSlices(rng *rand.Rand, max, size int) [][]byte {
+	n := rng.Intn(max + 1)
+	if n == 0 {
+		return nil
+	}
+	res := make([][]byte, n)
+	for i := range res {
+		res[i] = randBytes(rng, rng.Intn(size+1))
+	}
+	return res
+}
+
+// randInt64 returns small and large, negative and positive values
+func randInt64(rng *rand.Rand) int64 {
+	switch rng.Intn(4) {
+	case 0:
+		return 0
+	case 1:
+		return rng.Int63n(128) - 64
+	case 2:
+		return -rng.Int63()
+	}
+	return rng.Int63()
+}
+
+func randInternalTransaction(rng *rand.Rand) InternalTransaction {
+	tx := InternalTransaction{
+		Type:   TransactionType(rng.Intn(3)),
+		Amount: rng.Uint64(),
+	}
+	if rng.Intn(2) == 0 {
+		tx.Peer = &peers.PeerMessage{
+			NetAddr:   fmt.Sprintf("127.0.0.1:%d", rng.Intn(65536)),
+			PubKeyHex: fmt.Sprintf("0x%X", randBytes(rng, rng.Intn(65))),
+		}
+	}
+	return tx
+}
+
+func randWireEvent(rng *rand.Rand) WireEvent {
+	ev := WireEvent{
+		Body: WireBody{
+			Transactions:         randByteSlices(rng, 4, 64),
+			SelfParentIndex:      randInt64(rng),
+			OtherParentCreatorID: rng.Uint64(),
+			OtherParentIndex:     randInt64(rng),
+			CreatorID:            rng.Uint64(),
+			Index:                randInt64(rng),
+			TransactionFlags:     randBytes(rng, rng.Intn(3)),
+			Timestamp:            randInt64(rng),
+			NetworkID:            randBytes(rng, rng.Intn(2)*32),
+			Version:              rng.Uint32(),
+		},
+		Signature: string(randBytes(rng, rng.Intn(150))),
+	}
+	if n := rng.Intn(3); n > 0 {
+		for i := 0; i < n; i++ {
+			ev.Body.InternalTransactions = append(ev.Body.InternalTransactions, randInternalTransaction(rng))
+		}
+	}
+	if n := rng.Intn(3); n > 0 {
+		for i := 0; i < n; i++ {
+			ev.Body.BlockSignatures = append(ev.Body.BlockSignatures, WireBlockSignature{
+				Index:     randInt64(rng),
+				Signature: string(randBytes(rng, rng.Intn(150))),
+			})
+		}
+	}
+	return ev
+}
+
+func randEventMessage(rng *rand.Rand) *EventMessage {
+	ev := &EventMessage{
+		Signature:            string(randBytes(rng, rng.Intn(150))),
+		SelfParentIndex:      randInt64(rng),
+		OtherParentCreatorID: rng.Uint64(),
+		OtherParentIndex:     randInt64(rng),
+		CreatorID:            rng.Uint64(),
+		TopologicalIndex:     randInt64(rng),
+		Hash:                 randBytes(rng, rng.Intn(2)*32),
+	}
+	if rng.Intn(8) == 0 {
+		return ev
+	}
+	ev.Body = &EventBody{
+		Transactions:     randByteSlices(rng, 4, 64),
+		Parents:          randByteSlices(rng, 2, 32),
+		Creator:          randBytes(rng, rng.Intn(2)*65),
+		Index:            randInt64(rng),
+		TransactionFlags: randBytes(rng, rng.Intn(3)),
+		Timestamp:        randInt64(rng),
+		NetworkID:        randBytes(rng, rng.Intn(2)*32),
+		Version:          rng.Uint32(),
+	}
+	if n := rng.Intn(3); n > 0 {
+		for i := 0; i < n; i++ {
+			var tx *InternalTransaction
+			if rng.Intn(4) > 0 {
+				t := randInternalTransaction(rng)
+				tx = &t
+			}
+			ev.Body.InternalTransactions = append(ev.Body.InternalTransactions, tx)
+		}
+	}
+	if n := rng.Intn(3); n > 0 {
+		for i := 0; i < n; i++ {
+			var bs *BlockSignature
+			if rng.Intn(4) > 0 {
+				bs = &BlockSignature{
+					Validator: randBytes(rng, rng.Intn(66)),
+					Index:     randInt64(rng),
+					Signature: string(randBytes(rng, rng.Intn(150))),
+				}
+			}
+			ev.Body.BlockSignatures = append(ev.Body.BlockSignatures, bs)
+		}
+	}
+	return ev
+}
+
+func randWireEvents(rng *rand.Rand, n int) []WireEvent {
+	if n == 0 {
+		return nil
+	}
+	events := make([]WireEvent, n)
+	for i := range events {
+		events[i] = randWireEvent(rng)
+	}
+	return events
+}
+
+func randEventMessages(rng *rand.Rand, n int) []*EventMessage {
+	if n == 0 {
+		return nil
+	}
+	events := make([]*EventMessage, n)
+	for i := range events {
+		events[i] = randEventMessage(rng)
+	}
+	return events
+}
+
+func TestWireEventsCompactRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		events := randWireEvents(rng, rng.Intn(8))
+		data := MarshalWireEvents(events)
+		res, err := UnmarshalWireEvents(data)
+		if err != nil {
+			t.Fatalf("batch %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(events, res) {
+			t.Fatalf("batch %d: expected\n%+v\ngot\n%+v", i, events, res)
+		}
+		if !bytes.Equal(MarshalWireEvents(res), data) {
+			t.Fatalf("batch %d: expected the decoded events to encode the same", i)
+		}
+	}
+}
+
+func TestEventMessagesCompactRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		events := randEventMessages(rng, rng.Intn(8))
+		data, err := MarshalEventMessages(events)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := UnmarshalEventMessages(data)
+		if err != nil {
+			t.Fatalf("batch %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(events, res) {
+			t.Fatalf("batch %d: expected\n%+v\ngot\n%+v", i, events, res)
+		}
+	}
+
+	if _, err := MarshalEventMessages([]*EventMessage{nil}); err == nil {
+		t.Fatal("expected a nil event message refused")
+	}
+}
+
+func TestFlagTableCompact(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		ft := NewFlagTable()
+		for j := rng.Intn(20); j > 0; j-- {
+			var hash EventHash
+			rng.Read(hash[:])
+			ft[hash] = randInt64(rng)
+		}
+		data := ft.MarshalCompact()
+		res := NewFlagTable()
+		if err := res.UnmarshalCompact(data); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ft, res) {
+			t.Fatalf("expected %v, got %v", ft, res)
+		}
+		// the pairs are sorted, a table encodes the same every time
+		if !bytes.Equal(res.MarshalCompact(), data) {
+			t.Fatal("expected the same encoding of equal tables")
+		}
+	}
+
+	var a, b EventHash
+	a[0], b[0] = 1, 2
+	data := FlagTable{a: 1, b: 2}.MarshalCompact()
+	// swap the pairs
+	pair := len(EventHash{}) + 1
+	swapped := append(append(append([]byte{}, data[:3]...), data[3+pair:]...), data[3:3+pair]...)
+	if err := NewFlagTable().UnmarshalCompact(swapped); !IsCompactError(err) {
+		t.Fatalf("expected the unsorted pairs refused, got %v", err)
+	}
+}
+
+// TestCompactTruncated cuts the encodings at every length, each cut is
+// refused as truncated
+func TestCompactTruncated(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	wire := MarshalWireEvents(randWireEvents(rng, 4))
+	messages, err := MarshalEventMessages(randEventMessages(rng, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ft := NewFlagTable()
+	for i := 0; i < 4; i++ {
+		var hash EventHash
+		rng.Read(hash[:])
+		ft[hash] = int64(i)
+	}
+	table := ft.MarshalCompact()
+
+	decoders := []struct {
+		name   string
+		data   []byte
+		decode func([]byte) error
+	}{
+		{"wire events", wire, func(b []byte) error {
+			_, err := UnmarshalWireEvents(b)
+			return err
+		}},
+		{"event messages", messages, func(b []byte) error {
+			_, err := UnmarshalEventMessages(b)
+			return err
+		}},
+		{"flag table", table, func(b []byte) error {
+			return NewFlagTable().UnmarshalCompact(b)
+		}},
+	}
+	for _, d := range decoders {
+		if err := d.decode(d.data); err != nil {
+			t.Fatalf("%s: %v", d.name, err)
+		}
+		for n := 0; n < len(d.data); n++ {
+			if err := d.decode(d.data[:n]); err != ErrCompactTruncated {
+				t.Fatalf("%s cut at %d of %d: expected %v, got %v",
+					d.name, n, len(d.data), ErrCompactTruncated, err)
+			}
+		}
+		if err := d.decode(append(d.data, 0)); !IsCompactError(err) {
+			t.Fatalf("%s: expected the trailing byte refused, got %v", d.name, err)
+		}
+	}
+}
+
+// TestCompactCorrupted decodes randomly corrupted encodings, which must
+// fail or decode without panicking
+func TestCompactCorrupted(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 2000; i++ {
+		data := MarshalWireEvents(randWireEvents(rng, 1+rng.Intn(3)))
+		messages, err := MarshalEventMessages(randEventMessages(rng, 1+rng.Intn(3)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, b := range [][]byte{data, messages} {
+			for j := rng.Intn(4); j >= 0; j-- {
+				b[rng.Intn(len(b))] = byte(rng.Intn(256))
+			}
+		}
+		if events, err := UnmarshalWireEvents(data); err == nil {
+			MarshalWireEvents(events)
+		} else if !IsCompactError(err) {
+			t.Fatalf("expected a compact error, got %v", err)
+		}
+		if _, err := UnmarshalEventMessages(messages); err != nil && !IsCompactError(err) {
+			t.Fatalf("expected a compact error, got %v", err)
+		}
+	}
+}
+
+func FuzzUnmarshalWireEvents(f *testing.F) {
+	rng := rand.New(rand.NewSource(4))
+	f.Add(MarshalWireEvents(nil))
+	f.Add(MarshalWireEvents(randWireEvents(rng, 3)))
+	f.Add([]byte{compactWireEvents, compactVersion, 0xff, 0xff, 0xff, 0xff, 0x0f})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		events, err := UnmarshalWireEvents(data)
+		if err != nil {
+			if !IsCompactError(err) {
+				t.Fatalf("expected a compact error, got %v", err)
+			}
+			return
+		}
+		res, err := UnmarshalWireEvents(MarshalWireEvents(events))
+		if err != nil || !reflect.DeepEqual(events, res) {
+			t.Fatalf("expected the decoded events to round trip, got %v", err)
+		}
+	})
+}
+
+/*******************************************************************************
+ Benchmarks
+*******************************************************************************/
+
+// benchWireEvents are events as a sync carries them: transactions, a
+// signature, the network ID and now and then block signatures
+func benchWireEvents(n int) []WireEvent {
+	rng := rand.New(rand.NewSource(1))
+	now := time.Now().UnixNano()
+	events := make([]WireEvent, n)
+	for i := range events {
+		ev := WireEvent{
+			Body: WireBody{
+				SelfParentIndex:      int64(i),
+				OtherParentCreatorID: rng.Uint64(),
+				OtherParentIndex:     int64(rng.Intn(n + 1)),
+				CreatorID:            rng.Uint64(),
+				Index:                int64(i + 1),
+				TransactionFlags:     []byte{1},
+				Timestamp:            now + int64(i)*int64(time.Millisecond),
+				NetworkID:            randBytes(rng, 32),
+				Version:              1,
+			},
+			Signature: fmt.Sprintf("%x|%x", randBytes(rng, 32), randBytes(rng, 32)),
+		}
+		for j := 0; j < 8; j++ {
+			ev.Body.Transactions = append(ev.Body.Transactions, randBytes(rng, 128))
+		}
+		if i%10 == 0 {
+			ev.Body.BlockSignatures = []WireBlockSignature{{
+				Index:     int64(i / 10),
+				Signature: fmt.Sprintf("%x|%x", randBytes(rng, 32), randBytes(rng, 32)),
+			}}
+		}
+		events[i] = ev
+	}
+	return events
+}
+
+// benchEventMessages are the events of a frame page, with their parents,
+// creator and hash
+func benchEventMessages(n int) []*EventMessage {
+	wire := benchWireEvents(n)
+	rng := rand.New(rand.NewSource(2))
+	events := make([]*EventMessage, n)
+	for i, we := range wire {
+		events[i] = &EventMessage{
+			Body: &EventBody{
+				Transactions:     we.Body.Transactions,
+				Parents:          [][]byte{randBytes(rng, 32), randBytes(rng, 32)},
+				Creator:          randBytes(rng, 65),
+				Index:            we.Body.Index,
+				TransactionFlags: we.Body.TransactionFlags,
+				Timestamp:        we.Body.Timestamp,
+				NetworkID:        we.Body.NetworkID,
+				Version:          we.Body.Version,
+			},
+			Signature:            we.Signature,
+			SelfParentIndex:      we.Body.SelfParentIndex,
+			OtherParentCreatorID: we.Body.OtherParentCreatorID,
+			OtherParentIndex:     we.Body.OtherParentIndex,
+			CreatorID:            we.Body.CreatorID,
+			TopologicalIndex:     int64(i),
+			Hash:                 randBytes(rng, 32),
+		}
+	}
+	return events
+}
+
+// The codecs encode and decode batches of 1000 events. The gob encoder and
+// decoder last as long as the benchmark, as those of a connection do, the
+// types go once.
+
+func BenchmarkWireEventsCodec(b *testing.B) {
+	events := benchWireEvents(1000)
+	b.Run("compact", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			data := MarshalWireEvents(events)
+			if _, err := UnmarshalWireEvents(data); err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(data)))
+		}
+		perEvent(b, len(events))
+	})
+	b.Run("gob", func(b *testing.B) {
+		var buf bytes.Buffer
+		enc, dec := gob.NewEncoder(&buf), gob.NewDecoder(&buf)
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			if err := enc.Encode(events); err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(buf.Len()))
+			var res []WireEvent
+			if err := dec.Decode(&res); err != nil {
+				b.Fatal(err)
+			}
+		}
+		perEvent(b, len(events))
+	})
+}
+
+func BenchmarkEventMessagesCodec(b *testing.B) {
+	events := benchEventMessages(1000)
+	b.Run("compact", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			data, err := MarshalEventMessages(events)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := UnmarshalEventMessages(data); err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(data)))
+		}
+		perEvent(b, len(events))
+	})
+	b.Run("gob", func(b *testing.B) {
+		var buf bytes.Buffer
+		enc, dec := gob.NewEncoder(&buf), gob.NewDecoder(&buf)
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			if err := enc.Encode(events); err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(buf.Len()))
+			var res []*EventMessage
+			if err := dec.Decode(&res); err != nil {
+				b.Fatal(err)
+			}
+		}
+		perEvent(b, len(events))
+	})
+}
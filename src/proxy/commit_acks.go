@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"sync"
+)
+
+// AckHandler is called with a block the app acknowledged and the state hash
+// it answered
+type AckHandler func(blockIndex int64, stateHash []byte)
+
+// commitAcks hands the acknowledgements of the app to the handler the node
+// set, it implements Acknowledger
+type commitAcks struct {
+	ackHandler     AckHandler
+	ackHandlerSync sync.RWMutex
+}
+
+// SetAckHandler implements Acknowledger interface method
+func (a *commitAcks) SetAckHandler(handler AckHandler) {
+	a.ackHandlerSync.Lock()
+	defer a.ackHandlerSync.Unlock()
+	a.ackHandler = handler
+}
+
+// ack reports that the app acknowledged a block
+func (a *commitAcks) ack(blockIndex int64, stateHash []byte) {
+	a.ackHandlerSync.RLock()
+	handler := a.ackHandler
+	a.ackHandlerSync.RUnlock()
+	if handler != nil {
+		handler(blockIndex, stateHash)
+	}
+}
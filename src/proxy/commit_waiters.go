@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy/proto"
+)
+
+// commitWaiter waits for a transaction to be committed
+type commitWaiter struct {
+	tx  []byte
+	ref chan proto.BlockRef
+}
+
+// commitWaiters are the transactions waited for, matched against the blocks
+// as they are committed
+type commitWaiters struct {
+	sync.Mutex
+	waiters map[*commitWaiter]struct{}
+}
+
+// add waits for tx. It is added before tx is submitted so that its block
+// cannot be missed.
+func (c *commitWaiters) add(tx []byte) *commitWaiter {
+	w := &commitWaiter{tx: tx, ref: make(chan proto.BlockRef, 1)}
+	c.Lock()
+	defer c.Unlock()
+	if c.waiters == nil {
+		c.waiters = make(map[*commitWaiter]struct{})
+	}
+	c.waiters[w] = struct{}{}
+	return w
+}
+
+func (c *commitWaiters) remove(w *commitWaiter) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.waiters, w)
+}
+
+// committed hands a block to the waiters of its transactions, each gets the
+// first position of its transaction and stops waiting
+func (c *commitWaiters) committed(block *poset.Block) {
+	c.Lock()
+	defer c.Unlock()
+	if len(c.waiters) == 0 {
+		return
+	}
+	for i, tx := range block.Transactions() {
+		for w := range c.waiters {
+			if bytes.Equal(tx, w.tx) {
+				w.ref <- proto.BlockRef{Index: block.Index(), Position: i}
+				delete(c.waiters, w)
+			}
+		}
+	}
+}
@@ -8,70 +8,143 @@ package proxy
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"math"
 	"net"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/xid"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
 
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/log"
 	"github.com/SamuelMarks/dag1/src/poset"
 	"github.com/SamuelMarks/dag1/src/proxy/internal"
+	"github.com/SamuelMarks/dag1/src/proxy/proto"
+	"github.com/SamuelMarks/dag1/src/version"
 )
 
 var ErrNoAnswers = errors.New("no answers")
 
 type ClientStream internal.DAG1Node_ConnectServer
 
-//GrpcAppProxy implements the AppProxy interface
+// ReadyService is the name to give in grpc.health.v1 checks for the node
+// readiness, the empty name only says the server is up
+const ReadyService = "internal.DAG1Node"
+
+// GrpcAppProxy implements the AppProxy interface
 type GrpcAppProxy struct {
 	logger   *logrus.Logger
 	listener net.Listener
 	server   *grpc.Server
+	health   *health.Server
 
 	timeout     time.Duration
 	newClients  chan ClientStream
-	askings     map[xid.ID]chan *internal.ToServer_Answer
+	askings     map[xid.ID]*asking
 	askingsSync sync.RWMutex
+	// clients are the capabilities of each connected client
+	clients     map[ClientStream]*client
+	clientsSync sync.RWMutex
+	clientSeq   uint64
+	// streams counts the Connect streams being served, Close waits for them
+	streams sync.WaitGroup
+
+	event4server   chan []byte
+	flagged4server chan proto.FlaggedTx
+	event4clients  chan *internal.ToClient
+
+	// allowlist authenticates the clients submitting transactions, when
+	// set, and restricted keeps the blocks from the others
+	allowlist      *Allowlist
+	allowlistWatch time.Duration
+	restricted     bool
+	stopWatch      func()
+	rejected       uint64
+	refused        uint64
+
+	commitAcks
+}
 
-	event4server  chan []byte
-	event4clients chan *internal.ToClient
+// client is what a connected client advertised in its capabilities
+type client struct {
+	// seq is the order of the connection, the first client claiming a topic
+	// is its primary
+	seq     uint64
+	batch   bool
+	topics  []string
+	primary bool
+	filter  bool
+	version string
+	// nonce is the challenge sent to the client, and submitter its
+	// allowlist entry once authenticated
+	nonce     []byte
+	submitter string
+	// sendSync orders the receipts of its transactions with the rest
+	sendSync sync.Mutex
+}
+
+// asking waits for the answers of the clients to a message
+type asking struct {
+	ch chan *internal.ToServer_Answer
+	// primaries are the topics each primary client answers for, the first
+	// answer of any client is kept when there are none
+	primaries map[ClientStream][]string
+	answers   map[ClientStream]*internal.ToServer_Answer
+	done      bool
 }
 
 // NewGrpcAppProxy instantiates a joined AppProxy-interface listen to remote apps
-func NewGrpcAppProxy(bindAddr string, timeout time.Duration, logger *logrus.Logger) (*GrpcAppProxy, error) {
+func NewGrpcAppProxy(bindAddr string, timeout time.Duration, logger *logrus.Logger, opts ...GrpcAppProxyOption) (*GrpcAppProxy, error) {
 	var err error
 
 	if logger == nil {
 		logger = logrus.New()
 		logger.Level = logrus.DebugLevel
 	}
+	logger = dag1_log.ForModule(logger, "proxy")
 
 	p := &GrpcAppProxy{
 		logger:     logger,
 		timeout:    timeout,
 		newClients: make(chan ClientStream, 100),
 		// TODO: make chans buffered?
-		askings:       make(map[xid.ID]chan *internal.ToServer_Answer),
-		event4server:  make(chan []byte),
-		event4clients: make(chan *internal.ToClient),
+		askings:        make(map[xid.ID]*asking),
+		clients:        make(map[ClientStream]*client),
+		event4server:   make(chan []byte),
+		flagged4server: make(chan proto.FlaggedTx),
+		event4clients:  make(chan *internal.ToClient),
+		allowlistWatch: DefaultAllowlistWatch,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
 
 	p.listener, err = net.Listen("tcp", bindAddr)
 	if err != nil {
 		return nil, err
 	}
+	if p.allowlist != nil {
+		p.stopWatch = p.allowlist.Watch(p.allowlistWatch, logger)
+	}
 	p.server = grpc.NewServer(
 		grpc.MaxRecvMsgSize(math.MaxInt32),
 		grpc.MaxSendMsgSize(math.MaxInt32))
 	internal.RegisterDAG1NodeServer(p.server, p)
+	p.health = health.NewServer()
+	p.health.SetServingStatus(ReadyService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(p.server, p.health)
 
 	go func() {
 		if err := p.server.Serve(p.listener); err != nil {
-			logger.Fatal(err)
+			logger.WithError(err).Error("Serving the app")
 		}
 	}()
 
@@ -81,22 +154,48 @@ func NewGrpcAppProxy(bindAddr string, timeout time.Duration, logger *logrus.Logg
 }
 
 func (p *GrpcAppProxy) Close() error {
+	if p.stopWatch != nil {
+		p.stopWatch()
+	}
+	p.health.Shutdown()
 	p.server.Stop()
+	p.streams.Wait()
 	//All listeners are closed by gRPC.Stop() function
 	//err := p.listener.Close()
 	close(p.event4server)
+	close(p.flagged4server)
 	close(p.event4clients)
 	return nil //err
 }
 
+// SetReady implements ReadyReporter interface method
+func (p *GrpcAppProxy) SetReady(ready bool) {
+	status := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	if ready {
+		status = grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	p.health.SetServingStatus(ReadyService, status)
+}
+
 /*
  * network interface:
  */
 
 // Connect implements gRPC-server interface: DAG1NodeServer
 func (p *GrpcAppProxy) Connect(stream internal.DAG1Node_ConnectServer) error {
+	p.streams.Add(1)
+	defer p.streams.Done()
+	// the challenge goes before the blocks
+	nonce, err := p.challenge(stream)
+	if err != nil {
+		p.logger.Debugf("client challenge failed: %s", err)
+		return err
+	}
+	p.setClient(stream, nil)
+	p.setNonce(stream, nonce)
 	// save client's stream for writing
 	p.newClients <- stream
+	defer p.removeClient(stream)
 	p.logger.Debugf("client connected")
 	// read from stream
 	for {
@@ -110,11 +209,43 @@ func (p *GrpcAppProxy) Connect(stream internal.DAG1Node_ConnectServer) error {
 			return err
 		}
 		if tx := req.GetTx(); tx != nil {
-			p.event4server <- tx.GetData()
+			data := tx.GetData()
+			err := p.authorize(stream)
+			if err != nil {
+				atomic.AddUint64(&p.rejected, 1)
+			} else if topic := tx.GetTopic(); topic != "" {
+				data, err = proto.TopicTx(topic, data)
+			}
+			// the receipt goes before the transaction is handed to the node
+			if uid := tx.GetUid(); uid != nil {
+				if err := p.send(stream, newReceipt(uid, err)); err != nil {
+					p.logger.Debugf("client receipt failed: %s", err)
+				}
+			}
+			if err != nil {
+				p.logger.Debugf("client tx refused: %s", err)
+				continue
+			}
+			// clients without flags support send zero and keep the old path
+			if flags := tx.GetFlags(); flags != 0 {
+				p.flagged4server <- proto.FlaggedTx{Tx: data, Flags: byte(flags)}
+				continue
+			}
+			p.event4server <- data
 			continue
 		}
 		if answer := req.GetAnswer(); answer != nil {
-			p.routeAnswer(answer)
+			p.routeAnswer(stream, answer)
+			continue
+		}
+		if caps := req.GetCapabilities(); caps != nil {
+			p.setClient(stream, caps)
+			continue
+		}
+		if auth := req.GetAuth(); auth != nil {
+			if err := p.authenticate(stream, auth); err != nil {
+				p.logger.Warnf("client authentication refused: %s", err)
+			}
 			continue
 		}
 	}
@@ -134,8 +265,14 @@ func (p *GrpcAppProxy) sendEvents4clients() {
 			connected = append(connected, stream)
 		}
 
+		// the clients filtering the same topics get the same blocks
+		filtered := make(map[string]*internal.ToClient)
 		for _, stream = range connected {
-			err = stream.Send(event)
+			if !p.delivers(stream) {
+				alive = append(alive, stream)
+				continue
+			}
+			err = p.send(stream, p.clientEvent(stream, event, filtered))
 			if err == nil {
 				alive = append(alive, stream)
 			}
@@ -146,6 +283,101 @@ func (p *GrpcAppProxy) sendEvents4clients() {
 	}
 }
 
+// clientEvent returns the event as sent to a client, with the transactions
+// of other topics dropped from the blocks when it asked for it
+func (p *GrpcAppProxy) clientEvent(stream ClientStream, event *internal.ToClient, filtered map[string]*internal.ToClient) *internal.ToClient {
+	if event.GetBlock() == nil && event.GetBlocks() == nil {
+		return event
+	}
+	p.clientsSync.RLock()
+	c, ok := p.clients[stream]
+	if !ok || !c.filter || len(c.topics) == 0 {
+		p.clientsSync.RUnlock()
+		return event
+	}
+	topics := make(map[string]bool, len(c.topics))
+	for _, topic := range c.topics {
+		topics[topic] = true
+	}
+	p.clientsSync.RUnlock()
+
+	key := topicsKey(topics)
+	if res, ok := filtered[key]; ok {
+		return res
+	}
+	res, err := filterEvent(event, topics)
+	if err != nil {
+		p.logger.Debugf("filtering blocks err: %s", err)
+		return event
+	}
+	filtered[key] = res
+	return res
+}
+
+// filterEvent returns a copy of a Block or Blocks event with the
+// transactions of the topics only, without their prefix
+func filterEvent(event *internal.ToClient, topics map[string]bool) (*internal.ToClient, error) {
+	if b := event.GetBlock(); b != nil {
+		data, err := filterBlock(b.Data, topics)
+		if err != nil {
+			return nil, err
+		}
+		return &internal.ToClient{
+			Event: &internal.ToClient_Block_{
+				Block: &internal.ToClient_Block{
+					Uid:  b.Uid,
+					Data: data,
+				},
+			},
+		}, nil
+	}
+	b := event.GetBlocks()
+	data := make([][]byte, len(b.Data))
+	for i := range b.Data {
+		var err error
+		if data[i], err = filterBlock(b.Data[i], topics); err != nil {
+			return nil, err
+		}
+	}
+	return &internal.ToClient{
+		Event: &internal.ToClient_Blocks_{
+			Blocks: &internal.ToClient_Blocks{
+				Uid:  b.Uid,
+				Data: data,
+			},
+		},
+	}, nil
+}
+
+// filterBlock returns the marshalled block with the transactions of the
+// topics only, without their prefix
+func filterBlock(data []byte, topics map[string]bool) ([]byte, error) {
+	var block poset.Block
+	if err := block.ProtoUnmarshal(data); err != nil {
+		return nil, err
+	}
+	if block.Body == nil {
+		return data, nil
+	}
+	var txs [][]byte
+	for _, tx := range block.Body.Transactions {
+		if topic, payload, ok := proto.SplitTopicTx(tx); ok && topics[topic] {
+			txs = append(txs, payload)
+		}
+	}
+	block.Body.Transactions = txs
+	return block.ProtoMarshal()
+}
+
+func topicsKey(topics map[string]bool) string {
+	var key []string
+	for topic := range topics {
+		key = append(key, topic)
+	}
+	sort.Strings(key)
+	return fmt.Sprintf("%q", key)
+}
+
 /*
  * inmem interface: AppProxy implementation
  */
@@ -155,13 +387,20 @@ func (p *GrpcAppProxy) SubmitCh() chan []byte {
 	return p.event4server
 }
 
+// SubmitFlaggedCh implements AppProxy interface method
+func (p *GrpcAppProxy) SubmitFlaggedCh() chan proto.FlaggedTx {
+	return p.flagged4server
+}
+
 // SubmitCh implements AppProxy interface method
 // TODO: Incorrect implementation, just adding to the interface so long
 func (p *GrpcAppProxy) SubmitInternalCh() chan poset.InternalTransaction {
 	return nil
 }
 
-// CommitBlock implements AppProxy interface method
+// CommitBlock implements AppProxy interface method. When clients registered
+// topics, the state hash is the one of the primary client of each topic,
+// hashed together in topic order when there are several of them.
 func (p *GrpcAppProxy) CommitBlock(block poset.Block) ([]byte, error) {
 	data, err := block.ProtoMarshal()
 	if err != nil {
@@ -175,9 +414,52 @@ func (p *GrpcAppProxy) CommitBlock(block poset.Block) ([]byte, error) {
 	if errMsg != "" {
 		return nil, errors.New(errMsg)
 	}
+	// the body getter is nil safe, a block without a body acks index 0
+	p.ack(block.Body.GetIndex(), answer.GetData())
 	return answer.GetData(), nil
 }
 
+// CommitBlocks implements BatchCommitter interface method. The blocks go in a
+// single message when every connected client supports batch commits, one
+// by one otherwise.
+func (p *GrpcAppProxy) CommitBlocks(blocks []poset.Block) ([][]byte, error) {
+	if !p.batchSupported() {
+		var stateHashes [][]byte
+		for _, block := range blocks {
+			stateHash, err := p.CommitBlock(block)
+			if err != nil {
+				return stateHashes, err
+			}
+			stateHashes = append(stateHashes, stateHash)
+		}
+		return stateHashes, nil
+	}
+
+	data := make([][]byte, len(blocks))
+	for i, block := range blocks {
+		var err error
+		if data[i], err = block.ProtoMarshal(); err != nil {
+			return nil, err
+		}
+	}
+	answer, ok := <-p.pushBlocks(data)
+	if !ok {
+		return nil, ErrNoAnswers
+	}
+	errMsg := answer.GetError()
+	if errMsg != "" {
+		return nil, errors.New(errMsg)
+	}
+	stateHashes := answer.GetBatch().GetData()
+	if len(stateHashes) != len(blocks) {
+		return nil, fmt.Errorf("expected %d state hashes, got %d", len(blocks), len(stateHashes))
+	}
+	for i, block := range blocks {
+		p.ack(block.Body.GetIndex(), stateHashes[i])
+	}
+	return stateHashes, nil
+}
+
 // GetSnapshot implements AppProxy interface method
 func (p *GrpcAppProxy) GetSnapshot(blockIndex int64) ([]byte, error) {
 	answer, ok := <-p.pushQuery(blockIndex)
@@ -193,32 +475,255 @@ func (p *GrpcAppProxy) GetSnapshot(blockIndex int64) ([]byte, error) {
 
 // Restore implements AppProxy interface method
 func (p *GrpcAppProxy) Restore(snapshot []byte) error {
+	_, err := p.RestoreState(snapshot)
+	return err
+}
+
+// RestoreState implements StateRestorer interface method
+func (p *GrpcAppProxy) RestoreState(snapshot []byte) ([]byte, error) {
 	answer, ok := <-p.pushRestore(snapshot)
 	if !ok {
-		return ErrNoAnswers
+		return nil, ErrNoAnswers
 	}
 	errMsg := answer.GetError()
 	if errMsg != "" {
-		return errors.New(errMsg)
+		return nil, errors.New(errMsg)
 	}
-	return nil
+	return answer.GetData(), nil
 }
 
 /*
  * staff:
  */
 
-func (p *GrpcAppProxy) routeAnswer(hash *internal.ToServer_Answer) {
+// setClient records the capabilities of a client, none on connecting, and
+// warns when it was built with another minor release
+func (p *GrpcAppProxy) setClient(stream ClientStream, caps *internal.ToServer_Capabilities) {
+	p.clientsSync.Lock()
+	c, ok := p.clients[stream]
+	if !ok {
+		p.clientSeq++
+		c = &client{seq: p.clientSeq}
+		p.clients[stream] = c
+	}
+	c.batch = caps.GetBatchCommit()
+	c.topics = caps.GetTopics()
+	c.primary = caps.GetPrimary()
+	c.filter = caps.GetFilter()
+	release, old := caps.GetVersion(), c.version
+	c.version = release
+	p.clientsSync.Unlock()
+
+	if release == old {
+		return
+	}
+	logger := p.logger.WithFields(logrus.Fields{
+		"their_release": release,
+		"our_release":   version.Version,
+	})
+	if !version.SameMinor(release) {
+		logger.Warn("client runs another minor release")
+		return
+	}
+	logger.Debug("client release")
+}
+
+// setNonce records the challenge sent to a client
+func (p *GrpcAppProxy) setNonce(stream ClientStream, nonce []byte) {
+	p.clientsSync.Lock()
+	if c, ok := p.clients[stream]; ok {
+		c.nonce = nonce
+	}
+	p.clientsSync.Unlock()
+}
+
+// send sends an event to the client of a stream, from the goroutine
+// reading the stream or from the one sending the blocks
+func (p *GrpcAppProxy) send(stream ClientStream, event *internal.ToClient) error {
+	p.clientsSync.RLock()
+	c, ok := p.clients[stream]
+	p.clientsSync.RUnlock()
+	if ok {
+		c.sendSync.Lock()
+		defer c.sendSync.Unlock()
+	}
+	return stream.Send(event)
+}
+
+// newReceipt returns the receipt of a transaction, err is why it was
+// refused
+func newReceipt(uid []byte, err error) *internal.ToClient {
+	receipt := &internal.ToClient_Receipt{Uid: uid}
+	if err != nil {
+		receipt.Error = err.Error()
+	}
+	if e, ok := err.(*SubmitterError); ok {
+		receipt.Refused = true
+		receipt.Error = e.Reason
+		receipt.Submitter = e.Submitter
+	}
+	return &internal.ToClient{
+		Event: &internal.ToClient_Receipt_{Receipt: receipt},
+	}
+}
+
+func (p *GrpcAppProxy) removeClient(stream ClientStream) {
+	p.clientsSync.Lock()
+	delete(p.clients, stream)
+	p.clientsSync.Unlock()
+}
+
+// batchSupported returns true when there are clients receiving the blocks
+// and all of them advertised batch commits
+func (p *GrpcAppProxy) batchSupported() bool {
+	p.clientsSync.RLock()
+	defer p.clientsSync.RUnlock()
+	receiving := 0
+	for _, c := range p.clients {
+		if !p.receives(c) {
+			continue
+		}
+		if !c.batch {
+			return false
+		}
+		receiving++
+	}
+	return receiving > 0
+}
+
+// primaries returns the topics each primary client answers for: those it
+// was the first connected client to claim
+func (p *GrpcAppProxy) primaries() map[ClientStream][]string {
+	p.clientsSync.RLock()
+	defer p.clientsSync.RUnlock()
+	first := make(map[string]ClientStream)
+	for stream, c := range p.clients {
+		if !c.primary || !p.receives(c) {
+			continue
+		}
+		for _, topic := range c.topics {
+			if other, ok := first[topic]; !ok || p.clients[other].seq > c.seq {
+				first[topic] = stream
+			}
+		}
+	}
+	if len(first) == 0 {
+		return nil
+	}
+	res := make(map[ClientStream][]string)
+	for topic, stream := range first {
+		res[stream] = append(res[stream], topic)
+	}
+	for _, topics := range res {
+		sort.Strings(topics)
+	}
+	return res
+}
+
+func (p *GrpcAppProxy) routeAnswer(stream ClientStream, hash *internal.ToServer_Answer) {
 	uuid, err := xid.FromBytes(hash.GetUid())
 	if err != nil {
 		// TODO: log invalid uuid
 		return
 	}
-	p.askingsSync.RLock()
-	if ch, ok := p.askings[uuid]; ok {
-		ch <- hash
+	if !p.delivers(stream) {
+		// the client was sent nothing to answer
+		return
+	}
+	p.askingsSync.Lock()
+	defer p.askingsSync.Unlock()
+	a, ok := p.askings[uuid]
+	if !ok || a.done {
+		return
+	}
+	if len(a.primaries) == 0 {
+		// every client answers, the first answer is kept
+		a.done = true
+		a.ch <- hash
+		return
+	}
+	if _, ok := a.primaries[stream]; !ok {
+		// observers answer too, for nothing
+		return
+	}
+	a.answers[stream] = hash
+	if hash.GetError() != "" {
+		a.done = true
+		a.ch <- hash
+		return
+	}
+	if len(a.answers) == len(a.primaries) {
+		a.done = true
+		a.ch <- a.combine()
 	}
-	p.askingsSync.RUnlock()
+}
+
+// combine returns the answer of the primary clients, hashed together in the
+// order of their topics when there are several of them
+func (a *asking) combine() *internal.ToServer_Answer {
+	var (
+		streams []ClientStream
+		firsts  []string
+	)
+	for stream, topics := range a.primaries {
+		streams = append(streams, stream)
+		firsts = append(firsts, topics[0])
+	}
+	if len(streams) == 1 {
+		return a.answers[streams[0]]
+	}
+	sort.Sort(byTopic{streams, firsts})
+
+	answers := make([]*internal.ToServer_Answer, len(streams))
+	for i, stream := range streams {
+		answers[i] = a.answers[stream]
+	}
+	uid := answers[0].GetUid()
+	if answers[0].GetBatch() == nil {
+		var hashes [][]byte
+		for _, answer := range answers {
+			hashes = append(hashes, answer.GetData())
+		}
+		return &internal.ToServer_Answer{
+			Uid:     uid,
+			Payload: &internal.ToServer_Answer_Data{Data: crypto.Keccak256(hashes...)},
+		}
+	}
+	n := len(answers[0].GetBatch().GetData())
+	stateHashes := make([][]byte, n)
+	for i := range stateHashes {
+		var hashes [][]byte
+		for _, answer := range answers {
+			batch := answer.GetBatch().GetData()
+			if len(batch) != n {
+				return &internal.ToServer_Answer{
+					Uid: uid,
+					Payload: &internal.ToServer_Answer_Error{
+						Error: fmt.Sprintf("expected %d state hashes, got %d", n, len(batch)),
+					},
+				}
+			}
+			hashes = append(hashes, batch[i])
+		}
+		stateHashes[i] = crypto.Keccak256(hashes...)
+	}
+	return &internal.ToServer_Answer{
+		Uid:     uid,
+		Payload: &internal.ToServer_Answer_Batch{Batch: &internal.ToServer_Batch{Data: stateHashes}},
+	}
+}
+
+// byTopic sorts the primary clients by the first of their topics
+type byTopic struct {
+	streams []ClientStream
+	topics  []string
+}
+
+func (a byTopic) Len() int           { return len(a.streams) }
+func (a byTopic) Less(i, j int) bool { return a.topics[i] < a.topics[j] }
+func (a byTopic) Swap(i, j int) {
+	a.streams[i], a.streams[j] = a.streams[j], a.streams[i]
+	a.topics[i], a.topics[j] = a.topics[j], a.topics[i]
 }
 
 func (p *GrpcAppProxy) pushBlock(block []byte) chan *internal.ToServer_Answer {
@@ -231,7 +736,22 @@ func (p *GrpcAppProxy) pushBlock(block []byte) chan *internal.ToServer_Answer {
 			},
 		},
 	}
-	answer := p.subscribe4answer(uuid)
+	answer := p.subscribe4answer(uuid, p.primaries())
+	p.event4clients <- event
+	return answer
+}
+
+func (p *GrpcAppProxy) pushBlocks(blocks [][]byte) chan *internal.ToServer_Answer {
+	uuid := xid.New()
+	event := &internal.ToClient{
+		Event: &internal.ToClient_Blocks_{
+			Blocks: &internal.ToClient_Blocks{
+				Uid:  uuid[:],
+				Data: blocks,
+			},
+		},
+	}
+	answer := p.subscribe4answer(uuid, p.primaries())
 	p.event4clients <- event
 	return answer
 }
@@ -246,7 +766,7 @@ func (p *GrpcAppProxy) pushQuery(index int64) chan *internal.ToServer_Answer {
 			},
 		},
 	}
-	answer := p.subscribe4answer(uuid)
+	answer := p.subscribe4answer(uuid, nil)
 	p.event4clients <- event
 	return answer
 }
@@ -261,15 +781,21 @@ func (p *GrpcAppProxy) pushRestore(snapshot []byte) chan *internal.ToServer_Answ
 			},
 		},
 	}
-	answer := p.subscribe4answer(uuid)
+	answer := p.subscribe4answer(uuid, nil)
 	p.event4clients <- event
 	return answer
 }
 
-func (p *GrpcAppProxy) subscribe4answer(uuid xid.ID) chan *internal.ToServer_Answer {
-	ch := make(chan *internal.ToServer_Answer)
+// subscribe4answer returns the channel of the answer to a message, that of
+// the primaries, or the first one when there are none
+func (p *GrpcAppProxy) subscribe4answer(uuid xid.ID, primaries map[ClientStream][]string) chan *internal.ToServer_Answer {
+	ch := make(chan *internal.ToServer_Answer, 1)
 	p.askingsSync.Lock()
-	p.askings[uuid] = ch
+	p.askings[uuid] = &asking{
+		ch:        ch,
+		primaries: primaries,
+		answers:   make(map[ClientStream]*internal.ToServer_Answer),
+	}
 	p.askingsSync.Unlock()
 	// timeout
 	go func() {
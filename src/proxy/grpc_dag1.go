@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"errors"
 	"io"
 	"math"
@@ -12,9 +13,12 @@ import (
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/log"
 	"github.com/SamuelMarks/dag1/src/poset"
 	"github.com/SamuelMarks/dag1/src/proxy/internal"
 	"github.com/SamuelMarks/dag1/src/proxy/proto"
+	"github.com/SamuelMarks/dag1/src/version"
 )
 
 var (
@@ -26,12 +30,40 @@ var (
 type GrpcDAG1Proxy struct {
 	logger    *logrus.Logger
 	commitCh  chan proto.Commit
+	batchCh   chan proto.CommitBatch
 	queryCh   chan proto.SnapshotRequest
 	restoreCh chan proto.RestoreRequest
-
-	reconnTimeout   time.Duration
-	addr            string
-	shutdown        chan struct{}
+	// batchCommit is set once the app takes blocks from CommitBatchCh
+	batchCommit uint32
+	// topics are those given to RegisterTopics
+	topics atomic.Value
+	// waiters are the transactions SubmitTxAndWait waits for
+	waiters commitWaiters
+	// receipts are the transactions waiting for the receipt of the node
+	receipts txReceipts
+
+	// responders is the number of goroutines waiting for the answers of the
+	// app, bounded by maxResponders with responderSlots
+	responders         int64
+	respondersTimedOut uint64
+	responseTimeout    time.Duration
+	maxResponders      int
+	responderSlots     chan struct{}
+	clock              common.Clock
+
+	// submitterToken or submitterKey authenticate the app to the node, and
+	// signed is closed once the authentication of the stream is sent
+	submitterToken []byte
+	submitterKey   *ecdsa.PrivateKey
+	signed         atomic.Value
+
+	reconnTimeout time.Duration
+	addr          string
+	shutdown      chan struct{}
+	// cancel ends the stream, and listened is closed once its events stop
+	ctx             context.Context
+	cancel          context.CancelFunc
+	listened        chan struct{}
 	reconnectTicket chan time.Time
 	conn            *grpc.ClientConn
 	client          internal.DAG1NodeClient
@@ -39,22 +71,36 @@ type GrpcDAG1Proxy struct {
 }
 
 // NewGrpcDAG1Proxy instantiates a DAG1Proxy-interface connected to remote node
-func NewGrpcDAG1Proxy(addr string, logger *logrus.Logger) (p *GrpcDAG1Proxy, err error) {
+func NewGrpcDAG1Proxy(addr string, logger *logrus.Logger, opts ...GrpcDAG1ProxyOption) (p *GrpcDAG1Proxy, err error) {
 	if logger == nil {
 		logger = logrus.New()
 		logger.Level = logrus.DebugLevel
 	}
+	logger = dag1_log.ForModule(logger, "proxy")
 
 	p = &GrpcDAG1Proxy{
 		reconnTimeout:   2 * time.Second,
 		addr:            addr,
 		shutdown:        make(chan struct{}),
+		listened:        make(chan struct{}),
 		reconnectTicket: make(chan time.Time, 1),
 		logger:          logger,
 		commitCh:        make(chan proto.Commit),
+		batchCh:         make(chan proto.CommitBatch),
 		queryCh:         make(chan proto.SnapshotRequest),
 		restoreCh:       make(chan proto.RestoreRequest),
+		responseTimeout: DefaultResponseTimeout,
+		maxResponders:   DefaultMaxOutstandingResponses,
+		clock:           common.RealClock,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.maxResponders > 0 {
+		p.responderSlots = make(chan struct{}, p.maxResponders)
 	}
+	p.resetSigned()
+	p.ctx, p.cancel = context.WithCancel(context.Background())
 
 	p.conn, err = grpc.Dial(p.addr,
 		grpc.WithInsecure(),
@@ -65,7 +111,7 @@ func NewGrpcDAG1Proxy(addr string, logger *logrus.Logger) (p *GrpcDAG1Proxy, err
 
 	p.client = internal.NewDAG1NodeClient(p.conn)
 
-	p.reconnectTicket <- time.Now()
+	p.reconnectTicket <- p.clock.Now()
 
 	go p.listenEvents()
 
@@ -74,6 +120,12 @@ func NewGrpcDAG1Proxy(addr string, logger *logrus.Logger) (p *GrpcDAG1Proxy, err
 
 func (p *GrpcDAG1Proxy) Close() error {
 	close(p.shutdown)
+	p.cancel()
+	// an app no longer reading the channels keeps the events from stopping
+	select {
+	case <-p.listened:
+	case <-time.After(p.reconnTimeout):
+	}
 	return nil
 }
 
@@ -86,6 +138,46 @@ func (p *GrpcDAG1Proxy) CommitCh() chan proto.Commit {
 	return p.commitCh
 }
 
+// CommitBatchCh returns the channel of the blocks committed in batches.
+// They only come once EnableBatchCommit is called, CommitCh still gets the
+// blocks committed one by one.
+func (p *GrpcDAG1Proxy) CommitBatchCh() chan proto.CommitBatch {
+	return p.batchCh
+}
+
+// EnableBatchCommit tells the node the app takes the blocks in batches from
+// CommitBatchCh
+func (p *GrpcDAG1Proxy) EnableBatchCommit() error {
+	atomic.StoreUint32(&p.batchCommit, 1)
+	return p.sendToServer(p.capabilities())
+}
+
+// topicsConfig is what the app gave to RegisterTopics
+type topicsConfig struct {
+	topics  []string
+	primary bool
+	filter  bool
+}
+
+// RegisterTopics tells the node the app shares it with other apps, and is
+// interested in the transactions of the topics only. The state hashes of a
+// topic are those of its first primary app connected, the others observe the
+// blocks. With filter the blocks come without the transactions of other
+// topics, and those of the topics without their prefix.
+func (p *GrpcDAG1Proxy) RegisterTopics(topics []string, primary, filter bool) error {
+	for _, topic := range topics {
+		if len(topic) > proto.MaxTopicLength {
+			return proto.ErrTopicTooLong
+		}
+	}
+	p.topics.Store(topicsConfig{
+		topics:  append([]string(nil), topics...),
+		primary: primary,
+		filter:  filter,
+	})
+	return p.sendToServer(p.capabilities())
+}
+
 // SnapshotRequestCh implements DAG1Proxy interface method
 func (p *GrpcDAG1Proxy) SnapshotRequestCh() chan proto.SnapshotRequest {
 	return p.queryCh
@@ -96,17 +188,82 @@ func (p *GrpcDAG1Proxy) RestoreCh() chan proto.RestoreRequest {
 	return p.restoreCh
 }
 
-// SubmitTx implements DAG1Proxy interface method
+// SubmitTx implements DAG1Proxy interface method. It returns a
+// SubmitterError when the allowlist of the node refuses the app.
 func (p *GrpcDAG1Proxy) SubmitTx(tx []byte) error {
-	r := &internal.ToServer{
+	return p.submit(&internal.ToServer_Tx{
+		Data: tx,
+	})
+}
+
+// SubmitTxWithFlags implements DAG1Proxy interface method
+func (p *GrpcDAG1Proxy) SubmitTxWithFlags(tx []byte, flags byte) error {
+	return p.submit(&internal.ToServer_Tx{
+		Data:  tx,
+		Flags: uint32(flags),
+	})
+}
+
+// SubmitTopicTx submits a transaction of a topic, committed with its prefix
+// as proto.TopicTx makes it
+func (p *GrpcDAG1Proxy) SubmitTopicTx(topic string, tx []byte) error {
+	if len(topic) > proto.MaxTopicLength {
+		return proto.ErrTopicTooLong
+	}
+	return p.submit(&internal.ToServer_Tx{
+		Data:  tx,
+		Topic: topic,
+	})
+}
+
+// submit sends a transaction to the node, after the authentication of the
+// stream, and returns the refusal of its receipt
+func (p *GrpcDAG1Proxy) submit(tx *internal.ToServer_Tx) error {
+	if err := p.waitSigned(); err != nil {
+		return err
+	}
+	uid := xid.New()
+	tx.Uid = uid[:]
+	receipt := p.receipts.add(uid)
+	defer p.receipts.remove(uid)
+	err := p.sendToServer(&internal.ToServer{
 		Event: &internal.ToServer_Tx_{
-			Tx: &internal.ToServer_Tx{
-				Data: tx,
-			},
+			Tx: tx,
 		},
+	})
+	if err != nil {
+		return err
+	}
+	timer := p.clock.NewTimer(receiptTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-receipt:
+		return receiptError(r)
+	case <-timer.C():
+		return ErrNoReceipt
+	case <-p.shutdown:
+		return ErrConnShutdown
+	}
+}
+
+// SubmitTxAndWait implements DAG1Proxy interface method. The commits are
+// watched as they come from the node, before CommitCh or CommitBatchCh, so
+// none is taken from the app, which must still consume them for the node to
+// go on. The first block holding the same bytes after the call is returned.
+func (p *GrpcDAG1Proxy) SubmitTxAndWait(ctx context.Context, tx []byte) (proto.BlockRef, error) {
+	w := p.waiters.add(tx)
+	defer p.waiters.remove(w)
+	if err := p.SubmitTx(tx); err != nil {
+		return proto.BlockRef{}, err
+	}
+	select {
+	case ref := <-w.ref:
+		return ref, nil
+	case <-ctx.Done():
+		return proto.BlockRef{}, ctx.Err()
+	case <-p.shutdown:
+		return proto.BlockRef{}, ErrConnShutdown
 	}
-	err := p.sendToServer(r)
-	return err
 }
 
 /*
@@ -144,7 +301,7 @@ func (p *GrpcDAG1Proxy) recvFromServer() (data *internal.ToClient, err error) {
 }
 
 func (p *GrpcDAG1Proxy) reConnect() (err error) {
-	disconnTime := time.Now()
+	disconnTime := p.clock.Now()
 	connectTime := <-p.reconnectTicket
 
 	if connectTime == ZeroTime {
@@ -162,6 +319,7 @@ func (p *GrpcDAG1Proxy) reConnect() (err error) {
 		p.closeStream()
 		err := p.conn.Close()
 		close(p.commitCh)
+		close(p.batchCh)
 		close(p.queryCh)
 		close(p.restoreCh)
 		p.reconnectTicket <- ZeroTime
@@ -175,7 +333,7 @@ func (p *GrpcDAG1Proxy) reConnect() (err error) {
 
 	var stream internal.DAG1Node_ConnectClient
 	stream, err = p.client.Connect(
-		context.TODO(),
+		p.ctx,
 		grpc.MaxCallRecvMsgSize(math.MaxInt32),
 		grpc.MaxCallSendMsgSize(math.MaxInt32))
 	if err != nil {
@@ -183,13 +341,27 @@ func (p *GrpcDAG1Proxy) reConnect() (err error) {
 		p.reconnectTicket <- connectTime
 		return
 	}
+	p.resetSigned()
+	// the node forgets the capabilities of a closed stream
+	if err := stream.Send(p.capabilities()); err != nil {
+		p.logger.Warnf("send capabilities err: %s", err)
+	}
+	// and the authentication, sent before the stream is shared for the
+	// transactions not to get first
+	if auth := p.tokenAuth(); auth != nil {
+		if err := stream.Send(auth); err != nil {
+			p.logger.Warnf("send token err: %s", err)
+		}
+		p.setSigned()
+	}
 	p.setStream(stream)
 
-	p.reconnectTicket <- time.Now()
+	p.reconnectTicket <- p.clock.Now()
 	return
 }
 
 func (p *GrpcDAG1Proxy) listenEvents() {
+	defer close(p.listened)
 	var (
 		event *internal.ToClient
 		err   error
@@ -205,6 +377,18 @@ func (p *GrpcDAG1Proxy) listenEvents() {
 			}
 			break
 		}
+		// challenge to sign
+		if c := event.GetChallenge(); c != nil {
+			if err = p.answerChallenge(c.Nonce); err != nil {
+				p.logger.Warnf("answer challenge err: %s", err)
+			}
+			continue
+		}
+		// receipt of a transaction
+		if r := event.GetReceipt(); r != nil {
+			p.receipts.received(r)
+			continue
+		}
 		// block commit event
 		if b := event.GetBlock(); b != nil {
 			var pb poset.Block
@@ -212,6 +396,7 @@ func (p *GrpcDAG1Proxy) listenEvents() {
 			if err != nil {
 				continue
 			}
+			p.waiters.committed(&pb)
 			uuid, err = xid.FromBytes(b.Uid)
 			if err == nil {
 				p.commitCh <- proto.Commit{
@@ -221,6 +406,29 @@ func (p *GrpcDAG1Proxy) listenEvents() {
 			}
 			continue
 		}
+		// batch commit event
+		if b := event.GetBlocks(); b != nil {
+			blocks := make([]poset.Block, len(b.Data))
+			for i, data := range b.Data {
+				if err = blocks[i].ProtoUnmarshal(data); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				continue
+			}
+			for i := range blocks {
+				p.waiters.committed(&blocks[i])
+			}
+			uuid, err = xid.FromBytes(b.Uid)
+			if err == nil {
+				p.batchCh <- proto.CommitBatch{
+					Blocks:   blocks,
+					RespChan: p.newCommitBatchResponseCh(uuid),
+				}
+			}
+			continue
+		}
 		// get snapshot query
 		if q := event.GetQuery(); q != nil {
 			uuid, err = xid.FromBytes(q.Uid)
@@ -251,47 +459,67 @@ func (p *GrpcDAG1Proxy) listenEvents() {
  */
 
 func (p *GrpcDAG1Proxy) newCommitResponseCh(uuid xid.ID) chan proto.CommitResponse {
-	respCh := make(chan proto.CommitResponse)
-	go func() {
-		var answer *internal.ToServer
-		resp, ok := <-respCh
-		if ok {
-			answer = newAnswer(uuid[:], resp.StateHash, resp.Error)
+	// buffered, not to block an app answering after the timeout
+	respCh := make(chan proto.CommitResponse, 1)
+	p.respond(uuid, func(timeout <-chan time.Time) (*internal.ToServer, bool) {
+		select {
+		case resp, ok := <-respCh:
+			if !ok {
+				return nil, true
+			}
+			return newAnswer(uuid[:], resp.StateHash, resp.Error), true
+		case <-timeout:
+			return nil, false
 		}
-		if err := p.sendToServer(answer); err != nil {
-			p.logger.Debug(err)
+	})
+	return respCh
+}
+
+func (p *GrpcDAG1Proxy) newCommitBatchResponseCh(uuid xid.ID) chan proto.CommitBatchResponse {
+	respCh := make(chan proto.CommitBatchResponse, 1)
+	p.respond(uuid, func(timeout <-chan time.Time) (*internal.ToServer, bool) {
+		select {
+		case resp, ok := <-respCh:
+			if !ok {
+				return nil, true
+			}
+			return newBatchAnswer(uuid[:], resp.StateHashes, resp.Error), true
+		case <-timeout:
+			return nil, false
 		}
-	}()
+	})
 	return respCh
 }
 
 func (p *GrpcDAG1Proxy) newSnapshotResponseCh(uuid xid.ID) chan proto.SnapshotResponse {
-	respCh := make(chan proto.SnapshotResponse)
-	go func() {
-		var answer *internal.ToServer
-		resp, ok := <-respCh
-		if ok {
-			answer = newAnswer(uuid[:], resp.Snapshot, resp.Error)
-		}
-		if err := p.sendToServer(answer); err != nil {
-			p.logger.Debug(err)
+	respCh := make(chan proto.SnapshotResponse, 1)
+	p.respond(uuid, func(timeout <-chan time.Time) (*internal.ToServer, bool) {
+		select {
+		case resp, ok := <-respCh:
+			if !ok {
+				return nil, true
+			}
+			return newAnswer(uuid[:], resp.Snapshot, resp.Error), true
+		case <-timeout:
+			return nil, false
 		}
-	}()
+	})
 	return respCh
 }
 
 func (p *GrpcDAG1Proxy) newRestoreResponseCh(uuid xid.ID) chan proto.RestoreResponse {
-	respCh := make(chan proto.RestoreResponse)
-	go func() {
-		var answer *internal.ToServer
-		resp, ok := <-respCh
-		if ok {
-			answer = newAnswer(uuid[:], resp.StateHash, resp.Error)
-		}
-		if err := p.sendToServer(answer); err != nil {
-			p.logger.Debug(err)
+	respCh := make(chan proto.RestoreResponse, 1)
+	p.respond(uuid, func(timeout <-chan time.Time) (*internal.ToServer, bool) {
+		select {
+		case resp, ok := <-respCh:
+			if !ok {
+				return nil, true
+			}
+			return newAnswer(uuid[:], resp.StateHash, resp.Error), true
+		case <-timeout:
+			return nil, false
 		}
-	}()
+	})
 	return respCh
 }
 
@@ -320,6 +548,41 @@ func newAnswer(uuid []byte, data []byte, err error) *internal.ToServer {
 	}
 }
 
+func newBatchAnswer(uuid []byte, data [][]byte, err error) *internal.ToServer {
+	if err != nil {
+		return newAnswer(uuid, nil, err)
+	}
+	return &internal.ToServer{
+		Event: &internal.ToServer_Answer_{
+			Answer: &internal.ToServer_Answer{
+				Uid: uuid,
+				Payload: &internal.ToServer_Answer_Batch{
+					Batch: &internal.ToServer_Batch{
+						Data: data,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (p *GrpcDAG1Proxy) capabilities() *internal.ToServer {
+	caps := &internal.ToServer_Capabilities{
+		BatchCommit: atomic.LoadUint32(&p.batchCommit) != 0,
+		Version:     version.Version,
+	}
+	if conf, ok := p.topics.Load().(topicsConfig); ok {
+		caps.Topics = conf.topics
+		caps.Primary = conf.primary
+		caps.Filter = conf.filter
+	}
+	return &internal.ToServer{
+		Event: &internal.ToServer_Capabilities_{
+			Capabilities: caps,
+		},
+	}
+}
+
 func (p *GrpcDAG1Proxy) streamSend(data *internal.ToServer) error {
 	v := p.stream.Load()
 	if v == nil {
@@ -1,12 +1,16 @@
 package proxy
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
 	"github.com/SamuelMarks/dag1/src/poset"
 	"github.com/SamuelMarks/dag1/src/proxy/proto"
 	"github.com/SamuelMarks/dag1/src/utils"
@@ -44,6 +48,22 @@ func TestGrpcCalls(t *testing.T) {
 		}
 	})
 
+	t.Run("#1a Send tx with flags", func(t *testing.T) {
+		assertO := assert.New(t)
+		gold := []byte("654321")
+
+		err = c.SubmitTxWithFlags(gold, 7)
+		assertO.NoError(err)
+
+		select {
+		case tx := <-s.SubmitFlaggedCh():
+			assertO.Equal(gold, tx.Tx)
+			assertO.Equal(byte(7), tx.Flags)
+		case <-time.After(timeout):
+			assertO.Fail(errTimeout)
+		}
+	})
+
 	t.Run("#2 Receive block", func(t *testing.T) {
 		assertO := assert.New(t)
 		block := poset.Block{}
@@ -169,6 +189,121 @@ func TestGrpcReConnection(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestGrpcSubmitTxAndWait(t *testing.T) {
+	const timeout = 1 * time.Second
+	assertO := assert.New(t)
+	addr := utils.GetUnusedNetAddr(1, t)
+	logger := common.NewTestLogger(t)
+
+	s, err := NewGrpcAppProxy(addr[0], timeout, logger)
+	assertO.NoError(err)
+	defer s.Close()
+	c, err := NewGrpcDAG1Proxy(addr[0], logger)
+	assertO.NoError(err)
+	defer c.Close()
+
+	// the app keeps consuming its commits, which SubmitTxAndWait must leave
+	// to it
+	appCommits := make(chan poset.Block, 10)
+	go func() {
+		for commit := range c.CommitCh() {
+			appCommits <- commit.Block
+			commit.Respond([]byte("state"), nil)
+		}
+	}()
+
+	gold := []byte("wait for me")
+	type result struct {
+		ref proto.BlockRef
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*timeout)
+		defer cancel()
+		ref, err := c.SubmitTxAndWait(ctx, gold)
+		done <- result{ref, err}
+	}()
+
+	select {
+	case tx := <-s.SubmitCh():
+		assertO.Equal(gold, tx)
+	case <-time.After(timeout):
+		t.Fatal("time is over")
+	}
+	blocks := []poset.Block{
+		poset.NewBlock(0, 1, []byte("frame"), [][]byte{[]byte("other")}),
+		poset.NewBlock(1, 2, []byte("frame"), [][]byte{[]byte("a"), []byte("b"), gold, gold}),
+	}
+	for _, block := range blocks {
+		_, err := s.CommitBlock(block)
+		assertO.NoError(err)
+	}
+
+	res := <-done
+	if !assertO.NoError(res.err) {
+		return
+	}
+	assertO.Equal(proto.BlockRef{Index: 1, Position: 2}, res.ref)
+	committed := blocks[res.ref.Index]
+	assertO.Equal(gold, committed.Transactions()[res.ref.Position])
+	for i := range blocks {
+		select {
+		case block := <-appCommits:
+			assertO.Equal(int64(i), block.Index())
+		case <-time.After(timeout):
+			t.Fatalf("expected the app to get block %d", i)
+		}
+	}
+
+	// a transaction never committed ends with the context
+	ctx, cancel := context.WithTimeout(context.Background(), timeout/10)
+	defer cancel()
+	_, err = c.SubmitTxAndWait(ctx, []byte("never"))
+	assertO.Equal(context.DeadlineExceeded, err)
+	select {
+	case tx := <-s.SubmitCh():
+		assertO.Equal([]byte("never"), tx)
+	case <-time.After(timeout):
+		assertO.Fail("time is over")
+	}
+}
+
+func TestGrpcHealth(t *testing.T) {
+	assertO := assert.New(t)
+	addr := utils.GetUnusedNetAddr(1, t)
+	logger := common.NewTestLogger(t)
+
+	s, err := NewGrpcAppProxy(addr[0], time.Second, logger)
+	assertO.NoError(err)
+	defer s.Close()
+
+	conn, err := grpc.Dial(addr[0], grpc.WithInsecure())
+	assertO.NoError(err)
+	defer conn.Close()
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	check := func(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		resp, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if !assertO.NoError(err) {
+			return grpc_health_v1.HealthCheckResponse_UNKNOWN
+		}
+		return resp.GetStatus()
+	}
+
+	assertO.Equal(grpc_health_v1.HealthCheckResponse_SERVING, check(""))
+	assertO.Equal(grpc_health_v1.HealthCheckResponse_NOT_SERVING, check(ReadyService))
+
+	s.SetReady(true)
+	assertO.Equal(grpc_health_v1.HealthCheckResponse_SERVING, check(ReadyService))
+
+	s.SetReady(false)
+	assertO.Equal(grpc_health_v1.HealthCheckResponse_NOT_SERVING, check(ReadyService))
+	assertO.Equal(grpc_health_v1.HealthCheckResponse_SERVING, check(""))
+}
+
 /*
 func TestGrpcMaxMsgSize(t *testing.T) {
 	const (
@@ -241,3 +376,218 @@ func TestGrpcMaxMsgSize(t *testing.T) {
 	assert.NoError(t, err)
 }
 */
+
+// batchApp answers the commits of a client with the block indexes as state
+// hashes and counts the batches it gets
+func batchApp(c *GrpcDAG1Proxy, batches chan<- int, done <-chan struct{}) {
+	stateHash := func(block poset.Block) []byte {
+		return []byte{byte(block.Index())}
+	}
+	for {
+		select {
+		case commit, ok := <-c.CommitCh():
+			if !ok {
+				return
+			}
+			commit.Respond(stateHash(commit.Block), nil)
+		case batch, ok := <-c.CommitBatchCh():
+			if !ok {
+				return
+			}
+			var stateHashes [][]byte
+			for _, block := range batch.Blocks {
+				stateHashes = append(stateHashes, stateHash(block))
+			}
+			batch.Respond(stateHashes, nil)
+			batches <- len(batch.Blocks)
+		case <-done:
+			return
+		}
+	}
+}
+
+func waitClients(s *GrpcAppProxy, n int, t *testing.T) {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		s.clientsSync.RLock()
+		connected := len(s.clients)
+		s.clientsSync.RUnlock()
+		if connected == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected %d clients", n)
+}
+
+func TestGrpcBatchCommit(t *testing.T) {
+	assertO := assert.New(t)
+	addr := utils.GetUnusedNetAddr(1, t)
+	logger := common.NewTestLogger(t)
+
+	s, err := NewGrpcAppProxy(addr[0], time.Second, logger)
+	assertO.NoError(err)
+	defer s.Close()
+
+	var blocks []poset.Block
+	for i := int64(0); i < 3; i++ {
+		blocks = append(blocks, poset.NewBlock(i, i+1, []byte("frame"), [][]byte{[]byte("tx")}))
+	}
+	gold := [][]byte{{0}, {1}, {2}}
+
+	done := make(chan struct{})
+	defer close(done)
+	batches := make(chan int, 10)
+
+	// a client supporting batches gets the blocks in one of them
+	c1, err := NewGrpcDAG1Proxy(addr[0], logger)
+	assertO.NoError(err)
+	defer c1.Close()
+	go batchApp(c1, batches, done)
+	assertO.NoError(c1.EnableBatchCommit())
+	waitClients(s, 1, t)
+	for deadline := time.Now().Add(time.Second); !s.batchSupported(); {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the capabilities of the client")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stateHashes, err := s.CommitBlocks(blocks)
+	assertO.NoError(err)
+	assertO.Equal(gold, stateHashes)
+	assertO.Equal(3, <-batches)
+
+	// with an older client too they are committed one by one
+	c2, err := NewGrpcDAG1Proxy(addr[0], logger)
+	assertO.NoError(err)
+	defer c2.Close()
+	go batchApp(c2, batches, done)
+	assertO.NoError(c2.SubmitTx([]byte("tx")))
+	<-s.SubmitCh()
+	waitClients(s, 2, t)
+	assertO.False(s.batchSupported())
+
+	stateHashes, err = s.CommitBlocks(blocks)
+	assertO.NoError(err)
+	assertO.Equal(gold, stateHashes)
+	select {
+	case n := <-batches:
+		t.Fatalf("expected no batch, got one of %d blocks", n)
+	default:
+	}
+}
+
+// topicApp answers the commits of a client with its name as state hash, and
+// passes on the transactions it gets
+func topicApp(c *GrpcDAG1Proxy, name string, txs chan<- [][]byte, done <-chan struct{}) {
+	for {
+		select {
+		case commit, ok := <-c.CommitCh():
+			if !ok {
+				return
+			}
+			txs <- commit.Block.Transactions()
+			commit.Respond([]byte(name), nil)
+		case <-done:
+			return
+		}
+	}
+}
+
+func TestGrpcTopics(t *testing.T) {
+	const timeout = time.Second
+	assertO := assert.New(t)
+	addr := utils.GetUnusedNetAddr(1, t)
+	logger := common.NewTestLogger(t)
+
+	s, err := NewGrpcAppProxy(addr[0], timeout, logger)
+	assertO.NoError(err)
+	defer s.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	names := []string{"ledger", "audit", "observer"}
+	clients := make([]*GrpcDAG1Proxy, len(names))
+	received := make([]chan [][]byte, len(names))
+	for i, name := range names {
+		clients[i], err = NewGrpcDAG1Proxy(addr[0], logger)
+		assertO.NoError(err)
+		defer clients[i].Close()
+		received[i] = make(chan [][]byte, 10)
+		go topicApp(clients[i], name, received[i], done)
+	}
+	// the observer takes every transaction and is not asked for state hashes
+	assertO.NoError(clients[0].RegisterTopics([]string{"ledger"}, true, true))
+	assertO.NoError(clients[1].RegisterTopics([]string{"audit"}, true, true))
+	assertO.NoError(clients[2].SubmitTx([]byte("hello")))
+	<-s.SubmitCh()
+	waitClients(s, 3, t)
+	for deadline := time.Now().Add(timeout); len(s.primaries()) != 2; {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the topics of the clients")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// interleaved transactions of both topics
+	var (
+		committed [][]byte
+		gold      = [][][]byte{nil, nil}
+	)
+	for i := 0; i < 6; i++ {
+		c := i % 2
+		tx := []byte{byte(i)}
+		assertO.NoError(clients[c].SubmitTopicTx(names[c], tx))
+		gold[c] = append(gold[c], tx)
+		select {
+		case data := <-s.SubmitCh():
+			topic, payload, ok := proto.SplitTopicTx(data)
+			assertO.True(ok)
+			assertO.Equal(names[c], topic)
+			assertO.Equal(tx, payload)
+			committed = append(committed, data)
+		case <-time.After(timeout):
+			t.Fatal("time is over")
+		}
+	}
+	committed = append(committed, []byte("no topic"))
+
+	stateHash, err := s.CommitBlock(poset.NewBlock(0, 1, []byte("frame"), committed))
+	assertO.NoError(err)
+	// the primary state hashes in topic order
+	assertO.Equal(crypto.Keccak256([]byte("audit"), []byte("ledger")), stateHash)
+
+	for i := range gold {
+		select {
+		case txs := <-received[i]:
+			assertO.Equal(gold[i], txs)
+		case <-time.After(timeout):
+			t.Fatalf("expected the block at %s", names[i])
+		}
+	}
+	select {
+	case txs := <-received[2]:
+		assertO.Equal(committed, txs)
+	case <-time.After(timeout):
+		t.Fatal("expected the block at the observer")
+	}
+
+	// with the primary of a single topic left, its state hash is the block's
+	assertO.NoError(clients[1].RegisterTopics([]string{"audit"}, false, true))
+	for deadline := time.Now().Add(timeout); len(s.primaries()) != 1; {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a single primary")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stateHash, err = s.CommitBlock(poset.NewBlock(1, 2, []byte("frame"), committed))
+	assertO.NoError(err)
+	assertO.Equal([]byte("ledger"), stateHash)
+	for i := range received {
+		select {
+		case <-received[i]:
+		case <-time.After(timeout):
+			t.Fatalf("expected the block at %s", names[i])
+		}
+	}
+}
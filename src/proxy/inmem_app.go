@@ -3,8 +3,10 @@ package proxy
 import (
 	"github.com/sirupsen/logrus"
 
+	"github.com/SamuelMarks/dag1/src/log"
 	"github.com/SamuelMarks/dag1/src/peers"
 	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy/proto"
 )
 
 // InmemAppProxy implements the AppProxy interface natively
@@ -12,7 +14,10 @@ type InmemAppProxy struct {
 	logger           *logrus.Logger
 	handler          ProxyHandler
 	submitCh         chan []byte
+	submitFlaggedCh  chan proto.FlaggedTx
 	submitInternalCh chan poset.InternalTransaction
+
+	commitAcks
 }
 
 // NewInmemAppProxy instantiates an InmemProxy from a set of handlers
@@ -21,11 +26,13 @@ func NewInmemAppProxy(handler ProxyHandler, logger *logrus.Logger) *InmemAppProx
 		logger = logrus.New()
 		logger.Level = logrus.DebugLevel
 	}
+	logger = dag1_log.ForModule(logger, "proxy")
 
 	return &InmemAppProxy{
 		logger:           logger,
 		handler:          handler,
 		submitCh:         make(chan []byte),
+		submitFlaggedCh:  make(chan proto.FlaggedTx),
 		submitInternalCh: make(chan poset.InternalTransaction),
 	}
 }
@@ -39,6 +46,11 @@ func (p *InmemAppProxy) SubmitCh() chan []byte {
 	return p.submitCh
 }
 
+// SubmitFlaggedCh implements AppProxy interface method
+func (p *InmemAppProxy) SubmitFlaggedCh() chan proto.FlaggedTx {
+	return p.submitFlaggedCh
+}
+
 // ProposePeerAdd propose to add a peer to the rest of the network
 func (p *InmemAppProxy) ProposePeerAdd(peer peers.Peer) {
 	p.submitInternalCh <- poset.NewInternalTransaction(poset.TransactionType_PEER_ADD, peer)
@@ -63,6 +75,9 @@ func (p *InmemAppProxy) CommitBlock(block poset.Block) ([]byte, error) {
 		"state_hash":     stateHash,
 		"err":            err,
 	}).Debug("InmemAppProxy.CommitBlock")
+	if err == nil {
+		p.ack(block.Body.GetIndex(), stateHash)
+	}
 	return stateHash, err
 }
 
@@ -79,12 +94,18 @@ func (p *InmemAppProxy) GetSnapshot(blockIndex int64) ([]byte, error) {
 
 // Restore implements AppProxy interface method, calls handler
 func (p *InmemAppProxy) Restore(snapshot []byte) error {
+	_, err := p.RestoreState(snapshot)
+	return err
+}
+
+// RestoreState implements StateRestorer interface method, calls handler
+func (p *InmemAppProxy) RestoreState(snapshot []byte) ([]byte, error) {
 	stateHash, err := p.handler.RestoreHandler(snapshot)
 	p.logger.WithFields(logrus.Fields{
 		"state_hash": stateHash,
 		"err":        err,
 	}).Debug("InmemAppProxy.Restore")
-	return err
+	return stateHash, err
 }
 
 /*
@@ -99,3 +120,11 @@ func (p *InmemAppProxy) SubmitTx(tx []byte) {
 	copy(t, tx)
 	p.submitCh <- t
 }
+
+// SubmitTxWithFlags is called by the App to submit a transaction with a flags
+// byte to DAG1. Higher flags are ordered first within a block.
+func (p *InmemAppProxy) SubmitTxWithFlags(tx []byte, flags byte) {
+	t := make([]byte, len(tx))
+	copy(t, tx)
+	p.submitFlaggedCh <- proto.FlaggedTx{Tx: t, Flags: flags}
+}
@@ -27,6 +27,8 @@ type ToServer struct {
 	// Types that are valid to be assigned to Event:
 	//	*ToServer_Tx_
 	//	*ToServer_Answer_
+	//	*ToServer_Capabilities_
+	//	*ToServer_Auth_
 	Event                isToServer_Event `protobuf_oneof:"event"`
 	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
 	XXX_unrecognized     []byte           `json:"-"`
@@ -69,10 +71,22 @@ type ToServer_Answer_ struct {
 	Answer *ToServer_Answer `protobuf:"bytes,2,opt,name=answer,proto3,oneof"`
 }
 
+type ToServer_Capabilities_ struct {
+	Capabilities *ToServer_Capabilities `protobuf:"bytes,3,opt,name=capabilities,proto3,oneof"`
+}
+
+type ToServer_Auth_ struct {
+	Auth *ToServer_Auth `protobuf:"bytes,4,opt,name=auth,proto3,oneof"`
+}
+
 func (*ToServer_Tx_) isToServer_Event() {}
 
 func (*ToServer_Answer_) isToServer_Event() {}
 
+func (*ToServer_Capabilities_) isToServer_Event() {}
+
+func (*ToServer_Auth_) isToServer_Event() {}
+
 func (m *ToServer) GetEvent() isToServer_Event {
 	if m != nil {
 		return m.Event
@@ -94,11 +108,27 @@ func (m *ToServer) GetAnswer() *ToServer_Answer {
 	return nil
 }
 
+func (m *ToServer) GetCapabilities() *ToServer_Capabilities {
+	if x, ok := m.GetEvent().(*ToServer_Capabilities_); ok {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (m *ToServer) GetAuth() *ToServer_Auth {
+	if x, ok := m.GetEvent().(*ToServer_Auth_); ok {
+		return x.Auth
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*ToServer) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _ToServer_OneofMarshaller, _ToServer_OneofUnmarshaller, _ToServer_OneofSizer, []interface{}{
 		(*ToServer_Tx_)(nil),
 		(*ToServer_Answer_)(nil),
+		(*ToServer_Capabilities_)(nil),
+		(*ToServer_Auth_)(nil),
 	}
 }
 
@@ -116,6 +146,16 @@ func _ToServer_OneofMarshaller(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Answer); err != nil {
 			return err
 		}
+	case *ToServer_Capabilities_:
+		b.EncodeVarint(3<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Capabilities); err != nil {
+			return err
+		}
+	case *ToServer_Auth_:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Auth); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("ToServer.Event has unexpected type %T", x)
@@ -142,6 +182,22 @@ func _ToServer_OneofUnmarshaller(msg proto.Message, tag, wire int, b *proto.Buff
 		err := b.DecodeMessage(msg)
 		m.Event = &ToServer_Answer_{msg}
 		return true, err
+	case 3: // event.capabilities
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ToServer_Capabilities)
+		err := b.DecodeMessage(msg)
+		m.Event = &ToServer_Capabilities_{msg}
+		return true, err
+	case 4: // event.auth
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ToServer_Auth)
+		err := b.DecodeMessage(msg)
+		m.Event = &ToServer_Auth_{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -161,6 +217,16 @@ func _ToServer_OneofSizer(msg proto.Message) (n int) {
 		n += 1 // tag and wire
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *ToServer_Capabilities_:
+		s := proto.Size(x.Capabilities)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ToServer_Auth_:
+		s := proto.Size(x.Auth)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -169,7 +235,12 @@ func _ToServer_OneofSizer(msg proto.Message) (n int) {
 }
 
 type ToServer_Tx struct {
-	Data                 []byte   `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Data  []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Flags uint32 `protobuf:"varint,2,opt,name=flags,proto3" json:"flags,omitempty"`
+	// topic is set by the clients sharing the node with other applications
+	Topic string `protobuf:"bytes,3,opt,name=topic,proto3" json:"topic,omitempty"`
+	// uid asks the node for the Receipt of the transaction
+	Uid                  []byte   `protobuf:"bytes,4,opt,name=uid,proto3" json:"uid,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -206,11 +277,33 @@ func (m *ToServer_Tx) GetData() []byte {
 	return nil
 }
 
+func (m *ToServer_Tx) GetFlags() uint32 {
+	if m != nil {
+		return m.Flags
+	}
+	return 0
+}
+
+func (m *ToServer_Tx) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *ToServer_Tx) GetUid() []byte {
+	if m != nil {
+		return m.Uid
+	}
+	return nil
+}
+
 type ToServer_Answer struct {
 	Uid []byte `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
 	// Types that are valid to be assigned to Payload:
 	//	*ToServer_Answer_Data
 	//	*ToServer_Answer_Error
+	//	*ToServer_Answer_Batch
 	Payload              isToServer_Answer_Payload `protobuf_oneof:"payload"`
 	XXX_NoUnkeyedLiteral struct{}                  `json:"-"`
 	XXX_unrecognized     []byte                    `json:"-"`
@@ -260,10 +353,16 @@ type ToServer_Answer_Error struct {
 	Error string `protobuf:"bytes,3,opt,name=error,proto3,oneof"`
 }
 
+type ToServer_Answer_Batch struct {
+	Batch *ToServer_Batch `protobuf:"bytes,4,opt,name=batch,proto3,oneof"`
+}
+
 func (*ToServer_Answer_Data) isToServer_Answer_Payload() {}
 
 func (*ToServer_Answer_Error) isToServer_Answer_Payload() {}
 
+func (*ToServer_Answer_Batch) isToServer_Answer_Payload() {}
+
 func (m *ToServer_Answer) GetPayload() isToServer_Answer_Payload {
 	if m != nil {
 		return m.Payload
@@ -285,11 +384,19 @@ func (m *ToServer_Answer) GetError() string {
 	return ""
 }
 
+func (m *ToServer_Answer) GetBatch() *ToServer_Batch {
+	if x, ok := m.GetPayload().(*ToServer_Answer_Batch); ok {
+		return x.Batch
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*ToServer_Answer) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _ToServer_Answer_OneofMarshaller, _ToServer_Answer_OneofUnmarshaller, _ToServer_Answer_OneofSizer, []interface{}{
 		(*ToServer_Answer_Data)(nil),
 		(*ToServer_Answer_Error)(nil),
+		(*ToServer_Answer_Batch)(nil),
 	}
 }
 
@@ -303,6 +410,11 @@ func _ToServer_Answer_OneofMarshaller(msg proto.Message, b *proto.Buffer) error
 	case *ToServer_Answer_Error:
 		b.EncodeVarint(3<<3 | proto.WireBytes)
 		b.EncodeStringBytes(x.Error)
+	case *ToServer_Answer_Batch:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Batch); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("ToServer_Answer.Payload has unexpected type %T", x)
@@ -327,6 +439,14 @@ func _ToServer_Answer_OneofUnmarshaller(msg proto.Message, tag, wire int, b *pro
 		x, err := b.DecodeStringBytes()
 		m.Payload = &ToServer_Answer_Error{x}
 		return true, err
+	case 4: // payload.batch
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ToServer_Batch)
+		err := b.DecodeMessage(msg)
+		m.Payload = &ToServer_Answer_Batch{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -344,6 +464,11 @@ func _ToServer_Answer_OneofSizer(msg proto.Message) (n int) {
 		n += 1 // tag and wire
 		n += proto.SizeVarint(uint64(len(x.Error)))
 		n += len(x.Error)
+	case *ToServer_Answer_Batch:
+		s := proto.Size(x.Batch)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -351,11 +476,181 @@ func _ToServer_Answer_OneofSizer(msg proto.Message) (n int) {
 	return n
 }
 
+// Capabilities are sent by the clients on connecting
+type ToServer_Capabilities struct {
+	BatchCommit          bool     `protobuf:"varint,1,opt,name=batch_commit,json=batchCommit,proto3" json:"batch_commit,omitempty"`
+	Topics               []string `protobuf:"bytes,2,rep,name=topics,proto3" json:"topics,omitempty"`
+	Primary              bool     `protobuf:"varint,3,opt,name=primary,proto3" json:"primary,omitempty"`
+	Filter               bool     `protobuf:"varint,4,opt,name=filter,proto3" json:"filter,omitempty"`
+	Version              string   `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ToServer_Capabilities) Reset()         { *m = ToServer_Capabilities{} }
+func (m *ToServer_Capabilities) String() string { return proto.CompactTextString(m) }
+func (*ToServer_Capabilities) ProtoMessage()    {}
+func (*ToServer_Capabilities) Descriptor() ([]byte, []int) {
+	return fileDescriptor_grpc_6d03d2ce4ea1edae, []int{0, 2}
+}
+func (m *ToServer_Capabilities) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ToServer_Capabilities.Unmarshal(m, b)
+}
+func (m *ToServer_Capabilities) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ToServer_Capabilities.Marshal(b, m, deterministic)
+}
+func (dst *ToServer_Capabilities) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ToServer_Capabilities.Merge(dst, src)
+}
+func (m *ToServer_Capabilities) XXX_Size() int {
+	return xxx_messageInfo_ToServer_Capabilities.Size(m)
+}
+func (m *ToServer_Capabilities) XXX_DiscardUnknown() {
+	xxx_messageInfo_ToServer_Capabilities.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ToServer_Capabilities proto.InternalMessageInfo
+
+func (m *ToServer_Capabilities) GetBatchCommit() bool {
+	if m != nil {
+		return m.BatchCommit
+	}
+	return false
+}
+
+func (m *ToServer_Capabilities) GetTopics() []string {
+	if m != nil {
+		return m.Topics
+	}
+	return nil
+}
+
+func (m *ToServer_Capabilities) GetPrimary() bool {
+	if m != nil {
+		return m.Primary
+	}
+	return false
+}
+
+func (m *ToServer_Capabilities) GetFilter() bool {
+	if m != nil {
+		return m.Filter
+	}
+	return false
+}
+
+func (m *ToServer_Capabilities) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+// Batch answers Blocks with the state hashes in block order
+type ToServer_Batch struct {
+	Data                 [][]byte `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ToServer_Batch) Reset()         { *m = ToServer_Batch{} }
+func (m *ToServer_Batch) String() string { return proto.CompactTextString(m) }
+func (*ToServer_Batch) ProtoMessage()    {}
+func (*ToServer_Batch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_grpc_6d03d2ce4ea1edae, []int{0, 3}
+}
+func (m *ToServer_Batch) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ToServer_Batch.Unmarshal(m, b)
+}
+func (m *ToServer_Batch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ToServer_Batch.Marshal(b, m, deterministic)
+}
+func (dst *ToServer_Batch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ToServer_Batch.Merge(dst, src)
+}
+func (m *ToServer_Batch) XXX_Size() int {
+	return xxx_messageInfo_ToServer_Batch.Size(m)
+}
+func (m *ToServer_Batch) XXX_DiscardUnknown() {
+	xxx_messageInfo_ToServer_Batch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ToServer_Batch proto.InternalMessageInfo
+
+func (m *ToServer_Batch) GetData() [][]byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+// Auth authenticates the client submitting transactions, with a token of
+// the allowlist of the node, or the signature of the challenge of the node
+// by a key of the allowlist
+type ToServer_Auth struct {
+	Token                []byte   `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	PubKey               []byte   `protobuf:"bytes,2,opt,name=pub_key,json=pubKey,proto3" json:"pub_key,omitempty"`
+	Signature            string   `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ToServer_Auth) Reset()         { *m = ToServer_Auth{} }
+func (m *ToServer_Auth) String() string { return proto.CompactTextString(m) }
+func (*ToServer_Auth) ProtoMessage()    {}
+func (*ToServer_Auth) Descriptor() ([]byte, []int) {
+	return fileDescriptor_grpc_6d03d2ce4ea1edae, []int{0, 4}
+}
+func (m *ToServer_Auth) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ToServer_Auth.Unmarshal(m, b)
+}
+func (m *ToServer_Auth) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ToServer_Auth.Marshal(b, m, deterministic)
+}
+func (dst *ToServer_Auth) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ToServer_Auth.Merge(dst, src)
+}
+func (m *ToServer_Auth) XXX_Size() int {
+	return xxx_messageInfo_ToServer_Auth.Size(m)
+}
+func (m *ToServer_Auth) XXX_DiscardUnknown() {
+	xxx_messageInfo_ToServer_Auth.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ToServer_Auth proto.InternalMessageInfo
+
+func (m *ToServer_Auth) GetToken() []byte {
+	if m != nil {
+		return m.Token
+	}
+	return nil
+}
+
+func (m *ToServer_Auth) GetPubKey() []byte {
+	if m != nil {
+		return m.PubKey
+	}
+	return nil
+}
+
+func (m *ToServer_Auth) GetSignature() string {
+	if m != nil {
+		return m.Signature
+	}
+	return ""
+}
+
 type ToClient struct {
 	// Types that are valid to be assigned to Event:
 	//	*ToClient_Block_
 	//	*ToClient_Query_
 	//	*ToClient_Restore_
+	//	*ToClient_Blocks_
+	//	*ToClient_Challenge_
+	//	*ToClient_Receipt_
 	Event                isToClient_Event `protobuf_oneof:"event"`
 	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
 	XXX_unrecognized     []byte           `json:"-"`
@@ -402,12 +697,30 @@ type ToClient_Restore_ struct {
 	Restore *ToClient_Restore `protobuf:"bytes,3,opt,name=restore,proto3,oneof"`
 }
 
+type ToClient_Blocks_ struct {
+	Blocks *ToClient_Blocks `protobuf:"bytes,4,opt,name=blocks,proto3,oneof"`
+}
+
+type ToClient_Challenge_ struct {
+	Challenge *ToClient_Challenge `protobuf:"bytes,5,opt,name=challenge,proto3,oneof"`
+}
+
+type ToClient_Receipt_ struct {
+	Receipt *ToClient_Receipt `protobuf:"bytes,6,opt,name=receipt,proto3,oneof"`
+}
+
 func (*ToClient_Block_) isToClient_Event() {}
 
 func (*ToClient_Query_) isToClient_Event() {}
 
 func (*ToClient_Restore_) isToClient_Event() {}
 
+func (*ToClient_Blocks_) isToClient_Event() {}
+
+func (*ToClient_Challenge_) isToClient_Event() {}
+
+func (*ToClient_Receipt_) isToClient_Event() {}
+
 func (m *ToClient) GetEvent() isToClient_Event {
 	if m != nil {
 		return m.Event
@@ -436,12 +749,36 @@ func (m *ToClient) GetRestore() *ToClient_Restore {
 	return nil
 }
 
+func (m *ToClient) GetBlocks() *ToClient_Blocks {
+	if x, ok := m.GetEvent().(*ToClient_Blocks_); ok {
+		return x.Blocks
+	}
+	return nil
+}
+
+func (m *ToClient) GetChallenge() *ToClient_Challenge {
+	if x, ok := m.GetEvent().(*ToClient_Challenge_); ok {
+		return x.Challenge
+	}
+	return nil
+}
+
+func (m *ToClient) GetReceipt() *ToClient_Receipt {
+	if x, ok := m.GetEvent().(*ToClient_Receipt_); ok {
+		return x.Receipt
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*ToClient) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _ToClient_OneofMarshaller, _ToClient_OneofUnmarshaller, _ToClient_OneofSizer, []interface{}{
 		(*ToClient_Block_)(nil),
 		(*ToClient_Query_)(nil),
 		(*ToClient_Restore_)(nil),
+		(*ToClient_Blocks_)(nil),
+		(*ToClient_Challenge_)(nil),
+		(*ToClient_Receipt_)(nil),
 	}
 }
 
@@ -464,6 +801,21 @@ func _ToClient_OneofMarshaller(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Restore); err != nil {
 			return err
 		}
+	case *ToClient_Blocks_:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Blocks); err != nil {
+			return err
+		}
+	case *ToClient_Challenge_:
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Challenge); err != nil {
+			return err
+		}
+	case *ToClient_Receipt_:
+		b.EncodeVarint(6<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Receipt); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("ToClient.Event has unexpected type %T", x)
@@ -498,6 +850,30 @@ func _ToClient_OneofUnmarshaller(msg proto.Message, tag, wire int, b *proto.Buff
 		err := b.DecodeMessage(msg)
 		m.Event = &ToClient_Restore_{msg}
 		return true, err
+	case 4: // event.blocks
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ToClient_Blocks)
+		err := b.DecodeMessage(msg)
+		m.Event = &ToClient_Blocks_{msg}
+		return true, err
+	case 5: // event.challenge
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ToClient_Challenge)
+		err := b.DecodeMessage(msg)
+		m.Event = &ToClient_Challenge_{msg}
+		return true, err
+	case 6: // event.receipt
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ToClient_Receipt)
+		err := b.DecodeMessage(msg)
+		m.Event = &ToClient_Receipt_{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -522,6 +898,21 @@ func _ToClient_OneofSizer(msg proto.Message) (n int) {
 		n += 1 // tag and wire
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *ToClient_Blocks_:
+		s := proto.Size(x.Blocks)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ToClient_Challenge_:
+		s := proto.Size(x.Challenge)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ToClient_Receipt_:
+		s := proto.Size(x.Receipt)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -667,14 +1058,172 @@ func (m *ToClient_Restore) GetData() []byte {
 	return nil
 }
 
+// Blocks is sent instead of Block to the clients supporting batch commits
+type ToClient_Blocks struct {
+	Uid                  []byte   `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Data                 [][]byte `protobuf:"bytes,2,rep,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ToClient_Blocks) Reset()         { *m = ToClient_Blocks{} }
+func (m *ToClient_Blocks) String() string { return proto.CompactTextString(m) }
+func (*ToClient_Blocks) ProtoMessage()    {}
+func (*ToClient_Blocks) Descriptor() ([]byte, []int) {
+	return fileDescriptor_grpc_6d03d2ce4ea1edae, []int{1, 3}
+}
+func (m *ToClient_Blocks) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ToClient_Blocks.Unmarshal(m, b)
+}
+func (m *ToClient_Blocks) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ToClient_Blocks.Marshal(b, m, deterministic)
+}
+func (dst *ToClient_Blocks) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ToClient_Blocks.Merge(dst, src)
+}
+func (m *ToClient_Blocks) XXX_Size() int {
+	return xxx_messageInfo_ToClient_Blocks.Size(m)
+}
+func (m *ToClient_Blocks) XXX_DiscardUnknown() {
+	xxx_messageInfo_ToClient_Blocks.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ToClient_Blocks proto.InternalMessageInfo
+
+func (m *ToClient_Blocks) GetUid() []byte {
+	if m != nil {
+		return m.Uid
+	}
+	return nil
+}
+
+func (m *ToClient_Blocks) GetData() [][]byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+// Challenge is sent to the clients on connecting, for them to sign it
+// with their key
+type ToClient_Challenge struct {
+	Nonce                []byte   `protobuf:"bytes,1,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ToClient_Challenge) Reset()         { *m = ToClient_Challenge{} }
+func (m *ToClient_Challenge) String() string { return proto.CompactTextString(m) }
+func (*ToClient_Challenge) ProtoMessage()    {}
+func (*ToClient_Challenge) Descriptor() ([]byte, []int) {
+	return fileDescriptor_grpc_6d03d2ce4ea1edae, []int{1, 4}
+}
+func (m *ToClient_Challenge) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ToClient_Challenge.Unmarshal(m, b)
+}
+func (m *ToClient_Challenge) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ToClient_Challenge.Marshal(b, m, deterministic)
+}
+func (dst *ToClient_Challenge) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ToClient_Challenge.Merge(dst, src)
+}
+func (m *ToClient_Challenge) XXX_Size() int {
+	return xxx_messageInfo_ToClient_Challenge.Size(m)
+}
+func (m *ToClient_Challenge) XXX_DiscardUnknown() {
+	xxx_messageInfo_ToClient_Challenge.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ToClient_Challenge proto.InternalMessageInfo
+
+func (m *ToClient_Challenge) GetNonce() []byte {
+	if m != nil {
+		return m.Nonce
+	}
+	return nil
+}
+
+// Receipt answers a transaction sent with a uid: error is why the node
+// refused it, empty when it took it, and refused tells the allowlist of the
+// node refused the submitter
+type ToClient_Receipt struct {
+	Uid                  []byte   `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Refused              bool     `protobuf:"varint,3,opt,name=refused,proto3" json:"refused,omitempty"`
+	Submitter            string   `protobuf:"bytes,4,opt,name=submitter,proto3" json:"submitter,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ToClient_Receipt) Reset()         { *m = ToClient_Receipt{} }
+func (m *ToClient_Receipt) String() string { return proto.CompactTextString(m) }
+func (*ToClient_Receipt) ProtoMessage()    {}
+func (*ToClient_Receipt) Descriptor() ([]byte, []int) {
+	return fileDescriptor_grpc_6d03d2ce4ea1edae, []int{1, 5}
+}
+func (m *ToClient_Receipt) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ToClient_Receipt.Unmarshal(m, b)
+}
+func (m *ToClient_Receipt) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ToClient_Receipt.Marshal(b, m, deterministic)
+}
+func (dst *ToClient_Receipt) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ToClient_Receipt.Merge(dst, src)
+}
+func (m *ToClient_Receipt) XXX_Size() int {
+	return xxx_messageInfo_ToClient_Receipt.Size(m)
+}
+func (m *ToClient_Receipt) XXX_DiscardUnknown() {
+	xxx_messageInfo_ToClient_Receipt.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ToClient_Receipt proto.InternalMessageInfo
+
+func (m *ToClient_Receipt) GetUid() []byte {
+	if m != nil {
+		return m.Uid
+	}
+	return nil
+}
+
+func (m *ToClient_Receipt) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *ToClient_Receipt) GetRefused() bool {
+	if m != nil {
+		return m.Refused
+	}
+	return false
+}
+
+func (m *ToClient_Receipt) GetSubmitter() string {
+	if m != nil {
+		return m.Submitter
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*ToServer)(nil), "internal.ToServer")
 	proto.RegisterType((*ToServer_Tx)(nil), "internal.ToServer.Tx")
 	proto.RegisterType((*ToServer_Answer)(nil), "internal.ToServer.Answer")
+	proto.RegisterType((*ToServer_Capabilities)(nil), "internal.ToServer.Capabilities")
+	proto.RegisterType((*ToServer_Batch)(nil), "internal.ToServer.Batch")
+	proto.RegisterType((*ToServer_Auth)(nil), "internal.ToServer.Auth")
 	proto.RegisterType((*ToClient)(nil), "internal.ToClient")
 	proto.RegisterType((*ToClient_Block)(nil), "internal.ToClient.Block")
 	proto.RegisterType((*ToClient_Query)(nil), "internal.ToClient.Query")
 	proto.RegisterType((*ToClient_Restore)(nil), "internal.ToClient.Restore")
+	proto.RegisterType((*ToClient_Blocks)(nil), "internal.ToClient.Blocks")
+	proto.RegisterType((*ToClient_Challenge)(nil), "internal.ToClient.Challenge")
+	proto.RegisterType((*ToClient_Receipt)(nil), "internal.ToClient.Receipt")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -692,20 +1241,20 @@ type DAG1NodeClient interface {
 	Connect(ctx context.Context, opts ...grpc.CallOption) (DAG1Node_ConnectClient, error)
 }
 
-type dag1NodeClient struct {
+type dAG1NodeClient struct {
 	cc *grpc.ClientConn
 }
 
 func NewDAG1NodeClient(cc *grpc.ClientConn) DAG1NodeClient {
-	return &dag1NodeClient{cc}
+	return &dAG1NodeClient{cc}
 }
 
-func (c *dag1NodeClient) Connect(ctx context.Context, opts ...grpc.CallOption) (DAG1Node_ConnectClient, error) {
+func (c *dAG1NodeClient) Connect(ctx context.Context, opts ...grpc.CallOption) (DAG1Node_ConnectClient, error) {
 	stream, err := c.cc.NewStream(ctx, &_DAG1Node_serviceDesc.Streams[0], "/internal.DAG1Node/Connect", opts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &dag1NodeConnectClient{stream}
+	x := &dAG1NodeConnectClient{stream}
 	return x, nil
 }
 
@@ -715,15 +1264,15 @@ type DAG1Node_ConnectClient interface {
 	grpc.ClientStream
 }
 
-type dag1NodeConnectClient struct {
+type dAG1NodeConnectClient struct {
 	grpc.ClientStream
 }
 
-func (x *dag1NodeConnectClient) Send(m *ToServer) error {
+func (x *dAG1NodeConnectClient) Send(m *ToServer) error {
 	return x.ClientStream.SendMsg(m)
 }
 
-func (x *dag1NodeConnectClient) Recv() (*ToClient, error) {
+func (x *dAG1NodeConnectClient) Recv() (*ToClient, error) {
 	m := new(ToClient)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
@@ -741,7 +1290,7 @@ func RegisterDAG1NodeServer(s *grpc.Server, srv DAG1NodeServer) {
 }
 
 func _DAG1Node_Connect_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(DAG1NodeServer).Connect(&dag1NodeConnectServer{stream})
+	return srv.(DAG1NodeServer).Connect(&dAG1NodeConnectServer{stream})
 }
 
 type DAG1Node_ConnectServer interface {
@@ -750,15 +1299,15 @@ type DAG1Node_ConnectServer interface {
 	grpc.ServerStream
 }
 
-type dag1NodeConnectServer struct {
+type dAG1NodeConnectServer struct {
 	grpc.ServerStream
 }
 
-func (x *dag1NodeConnectServer) Send(m *ToClient) error {
+func (x *dAG1NodeConnectServer) Send(m *ToClient) error {
 	return x.ServerStream.SendMsg(m)
 }
 
-func (x *dag1NodeConnectServer) Recv() (*ToServer, error) {
+func (x *dAG1NodeConnectServer) Recv() (*ToServer, error) {
 	m := new(ToServer)
 	if err := x.ServerStream.RecvMsg(m); err != nil {
 		return nil, err
@@ -784,27 +1333,48 @@ var _DAG1Node_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("grpc.proto", fileDescriptor_grpc_6d03d2ce4ea1edae) }
 
 var fileDescriptor_grpc_6d03d2ce4ea1edae = []byte{
-	// 351 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0xc1, 0x6e, 0xe2, 0x30,
-	0x10, 0x86, 0x93, 0xb0, 0x21, 0x30, 0x70, 0x58, 0x8d, 0x76, 0x57, 0xd9, 0x9c, 0x10, 0x97, 0x72,
-	0x69, 0x40, 0x20, 0xb5, 0xe7, 0xc2, 0xa1, 0x39, 0x54, 0x95, 0x1a, 0x78, 0x01, 0x93, 0x8c, 0xda,
-	0xa8, 0x91, 0x4d, 0x1d, 0x43, 0xc3, 0xdb, 0xf5, 0x51, 0xfa, 0x28, 0x95, 0x1d, 0x53, 0x21, 0x91,
-	0x43, 0x6f, 0xb6, 0xe6, 0xfb, 0xf3, 0xcf, 0xff, 0xc7, 0x00, 0xcf, 0x72, 0x97, 0xc5, 0x3b, 0x29,
-	0x94, 0xc0, 0x5e, 0xc1, 0x15, 0x49, 0xce, 0xca, 0xf1, 0xa7, 0x0b, 0xbd, 0x8d, 0x58, 0x93, 0x3c,
-	0x90, 0xc4, 0x2b, 0xf0, 0x54, 0x1d, 0xba, 0x23, 0x77, 0x32, 0x98, 0xff, 0x8d, 0x4f, 0x4c, 0x7c,
-	0x9a, 0xc7, 0x9b, 0x3a, 0x71, 0x52, 0x4f, 0xd5, 0xb8, 0x80, 0x2e, 0xe3, 0xd5, 0x3b, 0xc9, 0xd0,
-	0x33, 0xf0, 0xff, 0x16, 0xf8, 0xce, 0x00, 0x89, 0x93, 0x5a, 0x34, 0x0a, 0xc1, 0xdb, 0xd4, 0x88,
-	0xf0, 0x2b, 0x67, 0x8a, 0x19, 0x97, 0x61, 0x6a, 0xce, 0xd1, 0x1a, 0xba, 0x0d, 0x8d, 0xbf, 0xa1,
-	0xb3, 0x2f, 0x72, 0x3b, 0xd4, 0x47, 0xfc, 0x63, 0x79, 0x6d, 0x34, 0x4c, 0x9c, 0x46, 0x81, 0xff,
-	0xc0, 0x27, 0x29, 0x85, 0x0c, 0x3b, 0x23, 0x77, 0xd2, 0x4f, 0x9c, 0xb4, 0xb9, 0x2e, 0xfb, 0x10,
-	0xec, 0xd8, 0xb1, 0x14, 0x2c, 0x5f, 0x06, 0xe0, 0xd3, 0x81, 0xb8, 0x1a, 0x7f, 0x78, 0x3a, 0xe2,
-	0xaa, 0x2c, 0x88, 0x2b, 0x9c, 0x81, 0xbf, 0x2d, 0x45, 0xf6, 0x6a, 0x53, 0x86, 0xe7, 0x8b, 0x37,
-	0x48, 0xbc, 0xd4, 0x73, 0xfd, 0x49, 0x03, 0x6a, 0xc5, 0xdb, 0x9e, 0xe4, 0xd1, 0x46, 0x6d, 0x53,
-	0x3c, 0xe9, 0xb9, 0x56, 0x18, 0x10, 0x6f, 0x20, 0x90, 0x54, 0x29, 0x21, 0xc9, 0xac, 0x37, 0x98,
-	0x47, 0x2d, 0x9a, 0xb4, 0x21, 0x12, 0x27, 0x3d, 0xc1, 0xd1, 0x35, 0xf8, 0xc6, 0xbb, 0xa5, 0x05,
-	0x3c, 0x6f, 0xc1, 0xb6, 0x36, 0x05, 0xdf, 0x18, 0xb7, 0x96, 0xe6, 0x17, 0x3c, 0xa7, 0xda, 0xf0,
-	0x9d, 0xb4, 0xb9, 0x44, 0x53, 0x08, 0xac, 0xeb, 0xcf, 0x1c, 0xbe, 0x2b, 0x9c, 0xdf, 0xc3, 0xf0,
-	0x81, 0x65, 0x2f, 0x54, 0x15, 0xd5, 0xa3, 0xc8, 0x09, 0x6f, 0x21, 0x58, 0x09, 0xce, 0x29, 0x53,
-	0x88, 0x97, 0xbf, 0x3e, 0xc2, 0xcb, 0xbc, 0x63, 0x67, 0xe2, 0xce, 0xdc, 0x6d, 0xd7, 0xbc, 0xbf,
-	0xc5, 0x57, 0x00, 0x00, 0x00, 0xff, 0xff, 0x54, 0x51, 0x85, 0x10, 0x8d, 0x02, 0x00, 0x00,
+	// 677 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0x41, 0x6e, 0xdb, 0x38,
+	0x14, 0x95, 0x6c, 0x4b, 0xb2, 0xbe, 0x3d, 0xc0, 0x80, 0xc8, 0x24, 0x1a, 0x4d, 0x80, 0x69, 0xb2,
+	0x69, 0x36, 0x71, 0x52, 0x07, 0x68, 0x37, 0xdd, 0xc4, 0x6e, 0x51, 0x01, 0x05, 0x0a, 0x94, 0x09,
+	0xba, 0x0d, 0x68, 0x99, 0x71, 0x88, 0xc8, 0x94, 0x4a, 0x51, 0xa9, 0x7d, 0x80, 0x1e, 0xa1, 0x87,
+	0xe8, 0x71, 0x7a, 0xa3, 0x82, 0x9f, 0x52, 0xec, 0xc2, 0x6a, 0xd0, 0x1d, 0x1f, 0xf9, 0x9e, 0xf8,
+	0xfe, 0xe3, 0xff, 0x02, 0x58, 0xa8, 0x22, 0x1d, 0x15, 0x2a, 0xd7, 0x39, 0xe9, 0x0b, 0xa9, 0xb9,
+	0x92, 0x2c, 0x3b, 0xfe, 0xe1, 0x41, 0xff, 0x3a, 0xbf, 0xe2, 0xea, 0x81, 0x2b, 0xf2, 0x1c, 0x3a,
+	0x7a, 0x15, 0xb9, 0xcf, 0xdc, 0x93, 0xc1, 0xf8, 0x9f, 0x51, 0xc3, 0x19, 0x35, 0xe7, 0xa3, 0xeb,
+	0x55, 0xe2, 0xd0, 0x8e, 0x5e, 0x91, 0x0b, 0xf0, 0x99, 0x2c, 0xbf, 0x70, 0x15, 0x75, 0x90, 0xfc,
+	0x6f, 0x0b, 0xf9, 0x12, 0x09, 0x89, 0x43, 0x6b, 0x2a, 0x79, 0x0b, 0xc3, 0x94, 0x15, 0x6c, 0x26,
+	0x32, 0xa1, 0x05, 0x2f, 0xa3, 0x2e, 0x4a, 0xff, 0x6f, 0x91, 0x4e, 0xb7, 0x68, 0x89, 0x43, 0x7f,
+	0x91, 0x91, 0x53, 0xe8, 0xb1, 0x4a, 0xdf, 0x45, 0x3d, 0x94, 0x1f, 0xb4, 0xdd, 0x5c, 0xe9, 0xbb,
+	0xc4, 0xa1, 0x48, 0x8b, 0x3f, 0x41, 0xe7, 0x7a, 0x45, 0x08, 0xf4, 0xe6, 0x4c, 0x33, 0xac, 0x6d,
+	0x48, 0x71, 0x4d, 0xf6, 0xc0, 0xbb, 0xcd, 0xd8, 0xa2, 0xc4, 0x1a, 0xfe, 0xa2, 0x16, 0x98, 0x5d,
+	0x9d, 0x17, 0x22, 0x45, 0x7b, 0x21, 0xb5, 0x80, 0xfc, 0x0d, 0xdd, 0x4a, 0xcc, 0xf1, 0xce, 0x21,
+	0x35, 0xcb, 0xf8, 0xab, 0x0b, 0xbe, 0x2d, 0xb1, 0x39, 0x74, 0x1f, 0x0f, 0xc9, 0x5e, 0x7d, 0x9d,
+	0xf9, 0xf2, 0xd0, 0x58, 0xc1, 0x0b, 0xf7, 0xc1, 0xe3, 0x4a, 0xe5, 0xca, 0x7e, 0x3a, 0x71, 0xa8,
+	0x85, 0xe4, 0x1c, 0xbc, 0x19, 0xd3, 0x69, 0x53, 0x52, 0xd4, 0x52, 0xd2, 0xc4, 0x9c, 0x1b, 0x05,
+	0x12, 0x27, 0x21, 0x04, 0x05, 0x5b, 0x67, 0x39, 0x9b, 0xc7, 0xdf, 0x5c, 0x18, 0x6e, 0xe7, 0x45,
+	0x8e, 0x60, 0x88, 0xa4, 0x9b, 0x34, 0x5f, 0x2e, 0x85, 0x46, 0x5b, 0x7d, 0x3a, 0xc0, 0xbd, 0x29,
+	0x6e, 0x91, 0x7d, 0xf0, 0xb1, 0x2c, 0x53, 0x7a, 0xf7, 0x24, 0xa4, 0x35, 0x22, 0x11, 0x04, 0x85,
+	0x12, 0x4b, 0xa6, 0xd6, 0x68, 0xb1, 0x4f, 0x1b, 0x68, 0x14, 0xb7, 0x22, 0xd3, 0x5c, 0xa1, 0xc7,
+	0x3e, 0xad, 0x91, 0x51, 0x3c, 0x70, 0x55, 0x8a, 0x5c, 0x46, 0x1e, 0xe6, 0xd5, 0xc0, 0xf8, 0x3f,
+	0xf0, 0xd0, 0xf4, 0x56, 0xf4, 0xdd, 0x26, 0xfa, 0xf8, 0x0a, 0x7a, 0xe6, 0x91, 0x6c, 0xd8, 0xf7,
+	0x5c, 0xd6, 0xd9, 0x59, 0x40, 0x0e, 0x20, 0x28, 0xaa, 0xd9, 0xcd, 0x3d, 0x5f, 0xdb, 0x00, 0xa9,
+	0x5f, 0x54, 0xb3, 0xf7, 0x7c, 0x4d, 0x0e, 0x21, 0x2c, 0xc5, 0x42, 0x32, 0x5d, 0x29, 0x5e, 0xbf,
+	0xcf, 0x66, 0x63, 0x12, 0x80, 0xc7, 0x1f, 0xb8, 0xd4, 0xc7, 0xdf, 0xb1, 0xa7, 0xa7, 0x99, 0xe0,
+	0x52, 0x63, 0xb8, 0x59, 0x9e, 0xde, 0x47, 0xee, 0x6e, 0xb8, 0x96, 0x32, 0x9a, 0x98, 0x73, 0x0c,
+	0xd7, 0x2c, 0x8c, 0xe2, 0x73, 0xc5, 0xd5, 0x3a, 0xea, 0xfc, 0x56, 0xf1, 0xd1, 0x9c, 0x1b, 0x05,
+	0x12, 0xc9, 0x4b, 0x08, 0x14, 0x2f, 0x75, 0x5e, 0xbb, 0x1a, 0x8c, 0xe3, 0x16, 0x0d, 0xb5, 0x8c,
+	0xc4, 0xa1, 0x0d, 0xd9, 0x8c, 0x11, 0x5e, 0x59, 0x46, 0xbd, 0xdd, 0x31, 0xda, 0x36, 0x67, 0xa6,
+	0xa0, 0xa6, 0x92, 0xd7, 0x10, 0xa6, 0x77, 0x2c, 0xcb, 0xb8, 0x5c, 0x70, 0x0c, 0x7d, 0x30, 0x3e,
+	0x6c, 0xd1, 0x4d, 0x1b, 0x4e, 0xe2, 0xd0, 0x8d, 0xc0, 0x5a, 0x4d, 0xb9, 0x28, 0x74, 0xe4, 0x3f,
+	0x61, 0x15, 0x19, 0xd6, 0x2a, 0x2e, 0xe3, 0x53, 0xf0, 0xd0, 0x49, 0x4b, 0xb3, 0x93, 0xed, 0x66,
+	0xaf, 0x1f, 0xf8, 0x0c, 0x3c, 0xcc, 0xa8, 0x75, 0x36, 0x3c, 0x21, 0xe7, 0x7c, 0x85, 0xfc, 0x2e,
+	0xb5, 0x20, 0x3e, 0x83, 0xa0, 0x0e, 0xe8, 0x0f, 0x6f, 0x18, 0x81, 0x6f, 0xa3, 0x79, 0x92, 0xbf,
+	0x69, 0xb9, 0x23, 0x08, 0x1f, 0x23, 0x31, 0x1e, 0x64, 0x2e, 0x53, 0xde, 0xf4, 0x1d, 0x82, 0x58,
+	0x18, 0x0f, 0x58, 0x6e, 0xbb, 0x6d, 0x3b, 0xbc, 0x1d, 0xfb, 0x5f, 0x40, 0x60, 0xfa, 0x5f, 0xf1,
+	0xdb, 0xaa, 0xe4, 0xf3, 0x66, 0x62, 0x6a, 0x88, 0xbd, 0x5a, 0xcd, 0x96, 0x42, 0x37, 0x43, 0x13,
+	0xd2, 0xcd, 0xc6, 0x63, 0xaf, 0x8e, 0xa7, 0xd0, 0x7f, 0x73, 0xf9, 0xee, 0xc5, 0x87, 0x7c, 0xce,
+	0xc9, 0x2b, 0x08, 0xa6, 0xb9, 0x94, 0x3c, 0xd5, 0x84, 0xec, 0xfe, 0x03, 0x62, 0xb2, 0xfb, 0x52,
+	0xc7, 0xce, 0x89, 0x7b, 0xee, 0xce, 0x7c, 0xfc, 0xab, 0x5f, 0xfc, 0x1c, 0x00, 0xe7, 0xab, 0x7b,
+	0xa5, 0xe3, 0x05, 0x00, 0x00,
 }
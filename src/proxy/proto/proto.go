@@ -23,6 +23,40 @@ func (r *Commit) Respond(stateHash []byte, err error) {
 	r.RespChan <- CommitResponse{stateHash, err}
 }
 
+// CommitBatchResponse captures the state hashes of a batch of blocks, in block
+// order, or an error.
+type CommitBatchResponse struct {
+	StateHashes [][]byte
+	Error       error
+}
+
+// CommitBatch provides a response mechanism for several blocks at once.
+type CommitBatch struct {
+	Blocks   []poset.Block
+	RespChan chan<- CommitBatchResponse
+}
+
+// Respond is used to respond with the state hashes of the blocks in order,
+// an error or both
+func (r *CommitBatch) Respond(stateHashes [][]byte, err error) {
+	r.RespChan <- CommitBatchResponse{stateHashes, err}
+}
+
+// BlockRef is where a transaction was committed: the index of its block and
+// its position among the transactions of the block
+type BlockRef struct {
+	Index    int64
+	Position int
+}
+
+//------------------------------------------------------------------------------
+// FlaggedTx is a transaction submitted together with its flags byte. The
+// flags travel inside the event, so within a block higher values sort first.
+type FlaggedTx struct {
+	Tx    []byte
+	Flags byte
+}
+
 //------------------------------------------------------------------------------
 type Snapshot struct {
 	Bytes []byte
@@ -0,0 +1,43 @@
+package proto
+
+import (
+	"bytes"
+	"errors"
+)
+
+// MaxTopicLength is the length limit of the topics, in bytes
+const MaxTopicLength = 255
+
+// ErrTopicTooLong is returned for a topic over MaxTopicLength bytes
+var ErrTopicTooLong = errors.New("topic too long")
+
+// topicTxPrefix starts the transactions submitted with a topic. It is
+// followed by the length of the topic in one byte, the topic, then the
+// transaction as submitted.
+var topicTxPrefix = []byte{0xd1, 0x70}
+
+// TopicTx returns the transaction of a topic as committed in the blocks
+func TopicTx(topic string, tx []byte) ([]byte, error) {
+	if len(topic) > MaxTopicLength {
+		return nil, ErrTopicTooLong
+	}
+	res := make([]byte, 0, len(topicTxPrefix)+1+len(topic)+len(tx))
+	res = append(res, topicTxPrefix...)
+	res = append(res, byte(len(topic)))
+	res = append(res, topic...)
+	return append(res, tx...), nil
+}
+
+// SplitTopicTx returns the topic and the submitted transaction of a
+// committed one, ok is false for the transactions submitted without a topic
+func SplitTopicTx(tx []byte) (topic string, data []byte, ok bool) {
+	if !bytes.HasPrefix(tx, topicTxPrefix) || len(tx) == len(topicTxPrefix) {
+		return "", tx, false
+	}
+	n := int(tx[len(topicTxPrefix)])
+	start := len(topicTxPrefix) + 1
+	if len(tx) < start+n {
+		return "", tx, false
+	}
+	return string(tx[start : start+n]), tx[start+n:], true
+}
@@ -1,6 +1,8 @@
 package proxy
 
 import (
+	"context"
+
 	"github.com/SamuelMarks/dag1/src/poset"
 	"github.com/SamuelMarks/dag1/src/proxy/proto"
 )
@@ -9,12 +11,39 @@ import (
 // with the application.
 type AppProxy interface {
 	SubmitCh() chan []byte
+	SubmitFlaggedCh() chan proto.FlaggedTx
 	SubmitInternalCh() chan poset.InternalTransaction
 	CommitBlock(block poset.Block) ([]byte, error)
 	GetSnapshot(blockIndex int64) ([]byte, error)
 	Restore(snapshot []byte) error
 }
 
+// ReadyReporter is implemented by the app proxies which tell their clients
+// whether the node is ready
+type ReadyReporter interface {
+	SetReady(ready bool)
+}
+
+// StateRestorer is implemented by the app proxies which tell the state hash
+// the app ended with after restoring a snapshot, to check the snapshot
+type StateRestorer interface {
+	RestoreState(snapshot []byte) ([]byte, error)
+}
+
+// BatchCommitter is implemented by the app proxies which can commit several
+// blocks in a single round trip. The state hashes are returned in block
+// order; on an error, they are those of the blocks committed before it.
+type BatchCommitter interface {
+	CommitBlocks(blocks []poset.Block) ([][]byte, error)
+}
+
+// Acknowledger is implemented by the app proxies which report the blocks
+// the app acknowledged. The handler is called before CommitBlock or
+// CommitBlocks return, once for each block the app answered without error.
+type Acknowledger interface {
+	SetAckHandler(handler AckHandler)
+}
+
 // DAG1Proxy provides an interface for the application to
 // submit transactions to the dag1 node.
 type DAG1Proxy interface {
@@ -22,4 +51,8 @@ type DAG1Proxy interface {
 	SnapshotRequestCh() chan proto.SnapshotRequest
 	RestoreCh() chan proto.RestoreRequest
 	SubmitTx(tx []byte) error
+	SubmitTxWithFlags(tx []byte, flags byte) error
+	// SubmitTxAndWait submits a transaction and returns where it was
+	// committed, or the error of ctx when it is done first
+	SubmitTxAndWait(ctx context.Context, tx []byte) (proto.BlockRef, error)
 }
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/proxy/internal"
+)
+
+// ErrResponseTimeout is answered to the node for the commits, snapshot
+// requests and restores the app did not answer in the response timeout
+var ErrResponseTimeout = errors.New("the app did not answer in time")
+
+const (
+	// DefaultResponseTimeout is how long an answer of the app is waited for.
+	// The node gives up on an answer after its own timeout, its heartbeat
+	// timeout, and asks again, so a later answer is of no use to it: this
+	// only bounds the life of the waiters of the apps which never answer.
+	DefaultResponseTimeout = time.Minute
+	// DefaultMaxOutstandingResponses is the number of answers of the app
+	// waited for at once, the node sends one message at a time
+	DefaultMaxOutstandingResponses = 64
+)
+
+// GrpcDAG1ProxyOption configures a GrpcDAG1Proxy
+type GrpcDAG1ProxyOption func(*GrpcDAG1Proxy)
+
+// WithResponseTimeout sets how long an answer of the app is waited for,
+// forever when 0
+func WithResponseTimeout(timeout time.Duration) GrpcDAG1ProxyOption {
+	return func(p *GrpcDAG1Proxy) {
+		p.responseTimeout = timeout
+	}
+}
+
+// WithMaxOutstandingResponses sets the number of answers of the app waited
+// for at once, no more commits, snapshot requests or restores are taken from
+// the node meanwhile. There is no bound when 0.
+func WithMaxOutstandingResponses(max int) GrpcDAG1ProxyOption {
+	return func(p *GrpcDAG1Proxy) {
+		p.maxResponders = max
+	}
+}
+
+// WithClock sets the clock timing the answers of the app, and the
+// reconnections
+func WithClock(clock common.Clock) GrpcDAG1ProxyOption {
+	return func(p *GrpcDAG1Proxy) {
+		p.clock = clock
+	}
+}
+
+// ResponderStats are the gauges of the goroutines waiting for the answers of
+// the app
+type ResponderStats struct {
+	Outstanding int64  `json:"outstanding"`
+	Max         int    `json:"max"`
+	TimedOut    uint64 `json:"timed_out"`
+}
+
+// ResponderStats returns the number of answers of the app waited for, and
+// of those it did not give in time
+func (p *GrpcDAG1Proxy) ResponderStats() ResponderStats {
+	return ResponderStats{
+		Outstanding: atomic.LoadInt64(&p.responders),
+		Max:         p.maxResponders,
+		TimedOut:    atomic.LoadUint64(&p.respondersTimedOut),
+	}
+}
+
+// respond waits for the answer of the app to the message uuid in a
+// goroutine and sends it to the node. wait returns the answer, or false when
+// timeout fires first, in which case the node gets ErrResponseTimeout. It
+// blocks while the max number of answers are outstanding.
+func (p *GrpcDAG1Proxy) respond(uuid xid.ID, wait func(timeout <-chan time.Time) (*internal.ToServer, bool)) {
+	if p.responderSlots != nil {
+		select {
+		case p.responderSlots <- struct{}{}:
+		case <-p.shutdown:
+			return
+		}
+	}
+	atomic.AddInt64(&p.responders, 1)
+
+	go func() {
+		defer func() {
+			atomic.AddInt64(&p.responders, -1)
+			if p.responderSlots != nil {
+				<-p.responderSlots
+			}
+		}()
+
+		var timeout <-chan time.Time
+		if p.responseTimeout > 0 {
+			timer := p.clock.NewTimer(p.responseTimeout)
+			defer timer.Stop()
+			timeout = timer.C()
+		}
+		answer, ok := wait(timeout)
+		if !ok {
+			atomic.AddUint64(&p.respondersTimedOut, 1)
+			p.logger.Warnf("no answer of the app to %s in %v", uuid, p.responseTimeout)
+			answer = newAnswer(uuid[:], nil, ErrResponseTimeout)
+		}
+		if err := p.sendToServer(answer); err != nil {
+			p.logger.Debug(err)
+		}
+	}()
+}
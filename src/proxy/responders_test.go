@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/utils"
+)
+
+func TestGrpcResponseTimeout(t *testing.T) {
+	const (
+		timeout         = 200 * time.Millisecond
+		responseTimeout = time.Minute
+		commits         = 20
+	)
+	assertO := assert.New(t)
+	addr := utils.GetUnusedNetAddr(1, t)
+	logger := common.NewTestLogger(t)
+	clock := common.NewManualClock(time.Unix(1500000000, 0))
+
+	s, err := NewGrpcAppProxy(addr[0], timeout, logger)
+	assertO.NoError(err)
+	defer s.Close()
+	c, err := NewGrpcDAG1Proxy(addr[0], logger, WithClock(clock),
+		WithResponseTimeout(responseTimeout), WithMaxOutstandingResponses(4))
+	assertO.NoError(err)
+	defer c.Close()
+
+	// the app drops every other commit
+	go func() {
+		i := 0
+		for commit := range c.CommitCh() {
+			if i%2 == 0 {
+				commit.Respond([]byte("state"), nil)
+			}
+			i++
+		}
+	}()
+	waitClients(s, 1, t)
+	goroutines := runtime.NumGoroutine()
+
+	// outstanding waits for the answers of the app being waited for
+	outstanding := func(n int64) {
+		for deadline := time.Now().Add(time.Second); c.ResponderStats().Outstanding != n && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for i := 0; i < commits; i++ {
+		if i%2 == 0 {
+			stateHash, err := s.CommitBlock(poset.Block{})
+			assertO.NoError(err)
+			assertO.Equal([]byte("state"), stateHash)
+			continue
+		}
+		// the timer of the previous answer is stopped
+		outstanding(0)
+		done := make(chan error, 1)
+		go func() {
+			_, err := s.CommitBlock(poset.Block{})
+			done <- err
+		}()
+		clock.BlockUntil(1)
+		clock.Advance(responseTimeout)
+		if err := <-done; assertO.Error(err, "commit %d", i) {
+			assertO.Equal(ErrResponseTimeout.Error(), err.Error())
+		}
+	}
+
+	outstanding(0)
+	stats := c.ResponderStats()
+	assertO.Equal(int64(0), stats.Outstanding)
+	assertO.Equal(uint64(commits/2), stats.TimedOut)
+	assertO.Equal(4, stats.Max)
+	// the app proxy drops the askings it sent after its own timeout
+	time.Sleep(2 * timeout)
+	if n := runtime.NumGoroutine(); n > goroutines+2 {
+		t.Fatalf("expected the goroutines to stay about %d, got %d", goroutines, n)
+	}
+}
+
+func TestGrpcMaxOutstandingResponses(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	assertO := assert.New(t)
+	addr := utils.GetUnusedNetAddr(1, t)
+	logger := common.NewTestLogger(t)
+
+	s, err := NewGrpcAppProxy(addr[0], timeout, logger)
+	assertO.NoError(err)
+	defer s.Close()
+	c, err := NewGrpcDAG1Proxy(addr[0], logger,
+		WithResponseTimeout(0), WithMaxOutstandingResponses(1))
+	assertO.NoError(err)
+	defer c.Close()
+	waitClients(s, 1, t)
+
+	// the node gives up on the first commit, the app holds it
+	go s.CommitBlock(poset.NewBlock(0, 1, nil, nil))
+	first := <-c.CommitCh()
+	assertO.Equal(int64(0), first.Block.Index())
+	assertO.Equal(int64(1), c.ResponderStats().Outstanding)
+
+	// the second commit waits for the first answer
+	go s.CommitBlock(poset.NewBlock(1, 2, nil, nil))
+	select {
+	case commit := <-c.CommitCh():
+		t.Fatalf("expected no commit before the answer, got block %d", commit.Block.Index())
+	case <-time.After(3 * timeout):
+	}
+	first.Respond([]byte("state"), nil)
+	select {
+	case commit := <-c.CommitCh():
+		assertO.Equal(int64(1), commit.Block.Index())
+		commit.Respond([]byte("state"), nil)
+	case <-time.After(time.Second):
+		t.Fatal("expected the second commit after the first answer")
+	}
+}
@@ -0,0 +1,188 @@
+package sealed
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+const (
+	// PublicKeyFile is the file of the key dir holding the current public
+	// key, hex as in the peers file, which the submitting apps seal to
+	PublicKeyFile = "seal_key.pub"
+
+	// currentFile holds the ID of the current key
+	currentFile = "seal_current"
+	// keyPrefix and keySuffix make the names of the private key files,
+	// after their IDs
+	keyPrefix = "seal_"
+	keySuffix = ".pem"
+)
+
+// ErrNoKeys is returned loading a key dir holding no key
+var ErrNoKeys = errors.New("no sealing key")
+
+// Keyring holds the private keys an app opens the transactions with: the
+// current one, the submitting apps seal to, and those it replaced, for the
+// transactions sealed before a rotation
+type Keyring struct {
+	sync.RWMutex
+	keys    map[KeyID]*ecdsa.PrivateKey
+	current KeyID
+}
+
+// NewKeyring constructor, the last key being the current one
+func NewKeyring(keys ...*ecdsa.PrivateKey) *Keyring {
+	k := &Keyring{keys: make(map[KeyID]*ecdsa.PrivateKey)}
+	for _, key := range keys {
+		k.Add(key)
+	}
+	return k
+}
+
+// Add adds a key and makes it the current one
+func (k *Keyring) Add(key *ecdsa.PrivateKey) {
+	k.Lock()
+	defer k.Unlock()
+	id := KeyIDOf(&key.PublicKey)
+	k.keys[id] = key
+	k.current = id
+}
+
+// Current returns the current public key, nil for an empty keyring
+func (k *Keyring) Current() *ecdsa.PublicKey {
+	k.RLock()
+	defer k.RUnlock()
+	key, ok := k.keys[k.current]
+	if !ok {
+		return nil
+	}
+	return &key.PublicKey
+}
+
+// Len returns the number of keys
+func (k *Keyring) Len() int {
+	k.RLock()
+	defer k.RUnlock()
+	return len(k.keys)
+}
+
+// Open decrypts a sealed transaction with the key it is sealed to
+func (k *Keyring) Open(tx []byte) ([]byte, error) {
+	if !IsSealed(tx) {
+		return nil, ErrNotSealed
+	}
+	id := sealedKeyID(tx)
+	k.RLock()
+	key, ok := k.keys[id]
+	k.RUnlock()
+	if !ok {
+		return nil, &UnknownKeyError{KeyID: id}
+	}
+	return open(key, tx)
+}
+
+// ParsePublicKey parses a public key, hex as in the peers file
+func ParsePublicKey(s string) (*ecdsa.PublicKey, error) {
+	s = strings.TrimSpace(s)
+	data, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil {
+		return nil, fmt.Errorf("public key %q: %s", s, err)
+	}
+	pub := crypto.ToECDSAPub(data)
+	if pub == nil || pub.X == nil {
+		return nil, fmt.Errorf("public key %q: not a P256 point", s)
+	}
+	return pub, nil
+}
+
+// ReadPublicKey reads a public key file, as the PublicKeyFile of a key dir
+func ReadPublicKey(path string) (*ecdsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKey(string(data))
+}
+
+// NewKey generates a key in the key dir and makes it the current one. The
+// keys it replaces stay in the dir, to open the transactions sealed to them;
+// the submitting apps seal to the new one once they read the PublicKeyFile
+// again.
+func NewKey(dir string) (*ecdsa.PrivateKey, error) {
+	key, err := crypto.GenerateECDSAKey()
+	if err != nil {
+		return nil, err
+	}
+	dump, err := crypto.ToPemKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	id := KeyIDOf(&key.PublicKey)
+	files := []struct {
+		name string
+		data string
+	}{
+		{keyPrefix + id.String() + keySuffix, dump.PrivateKey},
+		{currentFile, id.String()},
+		{PublicKeyFile, dump.PublicKey},
+	}
+	for _, f := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, f.name), []byte(f.data), 0600); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// LoadKeyring reads the keys of a key dir
+func LoadKeyring(dir string) (*Keyring, error) {
+	current, err := ioutil.ReadFile(filepath.Join(dir, currentFile))
+	if os.IsNotExist(err) {
+		return nil, ErrNoKeys
+	}
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, keyPrefix+"*"+keySuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	k := NewKeyring()
+	var currentKey *ecdsa.PrivateKey
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := new(crypto.PemKey).ReadKeyFromBuf(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		if key == nil {
+			return nil, fmt.Errorf("%s: empty key", path)
+		}
+		if KeyIDOf(&key.PublicKey).String() == strings.TrimSpace(string(current)) {
+			currentKey = key
+			continue
+		}
+		k.Add(key)
+	}
+	if currentKey == nil {
+		return nil, fmt.Errorf("%s: current key %s not found", dir, current)
+	}
+	k.Add(currentKey)
+	return k, nil
+}
@@ -0,0 +1,94 @@
+package sealed
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+func TestKeyRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sealed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := LoadKeyring(dir); err != ErrNoKeys {
+		t.Fatalf("expected ErrNoKeys, got %v", err)
+	}
+
+	first, err := NewKey(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ReadPublicKey(filepath.Join(dir, PublicKeyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if KeyIDOf(pub) != KeyIDOf(&first.PublicKey) {
+		t.Fatal("expected the public key file to hold the key")
+	}
+	old, err := Seal(pub, []byte("before"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewKey(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub, err = ReadPublicKey(filepath.Join(dir, PublicKeyFile)); err != nil {
+		t.Fatal(err)
+	}
+	if KeyIDOf(pub) != KeyIDOf(&second.PublicKey) {
+		t.Fatal("expected the public key file to hold the new key")
+	}
+	recent, err := Seal(pub, []byte("after"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the keyring opens what was sealed to either key, and the new one is
+	// current
+	keys, err := LoadKeyring(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.Len() != 2 || KeyIDOf(keys.Current()) != KeyIDOf(&second.PublicKey) {
+		t.Fatalf("expected 2 keys, the second current, got %d", keys.Len())
+	}
+	for tx, payload := range map[*[]byte]string{&old: "before", &recent: "after"} {
+		opened, err := keys.Open(*tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(opened, []byte(payload)) {
+			t.Fatalf("expected %q, got %q", payload, opened)
+		}
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	key := newKey(t)
+	dump, err := crypto.ToPemKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ParsePublicKey(dump.PublicKey + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if KeyIDOf(pub) != KeyIDOf(&key.PublicKey) {
+		t.Fatal("expected the key parsed")
+	}
+
+	for _, s := range []string{"", "0xZZ", "0x0102"} {
+		if _, err := ParsePublicKey(s); err == nil {
+			t.Fatalf("expected %q refused", s)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package sealed
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/dag1"
+	"github.com/SamuelMarks/dag1/src/node"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/proxy"
+	"github.com/SamuelMarks/dag1/src/utils"
+)
+
+// TestSealedNetwork runs two nodes committing to apps out of process, over
+// the gRPC proxies. The apps submit sealed transactions and read them
+// opened, the nodes only carry them sealed.
+func TestSealedNetwork(t *testing.T) {
+	const n = 2
+	logger := common.NewTestLogger(t)
+	addrs := utils.GetUnusedNetAddr(2*n, t)
+	keys := make([]*ecdsa.PrivateKey, n)
+	var participants []*peers.Peer
+	for i := range keys {
+		keys[i] = newKey(t)
+		pub := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&keys[i].PublicKey))
+		participants = append(participants, peers.NewPeer(pub, addrs[i]))
+	}
+	sealKey := newKey(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		engines    []*dag1.Engine
+		apps       []*recordingHandler
+		submitters []*SealingDAG1Proxy
+	)
+	for i := range keys {
+		appProxy, err := proxy.NewGrpcAppProxy(addrs[n+i], 5*time.Second, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer appProxy.Close()
+		dag1Proxy, err := proxy.NewGrpcDAG1Proxy(addrs[n+i], logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dag1Proxy.Close()
+
+		app := &recordingHandler{}
+		NewUnsealingClient(dag1Proxy, NewKeyring(sealKey), app, logger)
+		apps = append(apps, app)
+		submitter := NewSealingDAG1Proxy(dag1Proxy, &sealKey.PublicKey)
+		submitter.SetMaxTxSize(node.MaxEventsPayloadSize)
+		submitters = append(submitters, submitter)
+
+		engine, err := dag1.NewEngine(
+			dag1.WithLogger(logger),
+			dag1.WithKey(keys[i]),
+			dag1.WithPeers(participants),
+			dag1.WithInmemStore(),
+			dag1.WithProxy(appProxy),
+			dag1.WithListen(addrs[i]),
+			dag1.WithService(""),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := engine.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		engines = append(engines, engine)
+	}
+	defer func() {
+		cancel()
+		for i, engine := range engines {
+			select {
+			case <-engine.Done():
+			case <-time.After(10 * time.Second):
+				t.Errorf("timed out stopping engine %d", i)
+			}
+		}
+	}()
+
+	deadline := time.Now().Add(30 * time.Second)
+	wait := func(what string, done func() bool) {
+		for !done() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	for _, engine := range engines {
+		wait("the quorum", engine.Node().HasQuorum)
+	}
+
+	payloads := []string{"alpha", "beta", "gamma"}
+	for i, payload := range payloads {
+		if err := submitters[i%n].SubmitTx([]byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := submitters[0].SubmitTx(make([]byte, MaxPayloadSize(node.MaxEventsPayloadSize)+1)); !IsTxSize(err) {
+		t.Fatalf("expected a payload beyond the max size of the nodes refused, got %v", err)
+	}
+
+	// every app reads every payload
+	for i, app := range apps {
+		wait(fmt.Sprintf("the payloads of app %d", i), func() bool {
+			return len(app.committed()) >= len(payloads)
+		})
+		found := make(map[string]bool)
+		for _, tx := range app.committed() {
+			found[string(tx)] = true
+		}
+		for _, payload := range payloads {
+			if !found[payload] {
+				t.Fatalf("app %d: expected %q committed, got %q", i, payload, app.committed())
+			}
+		}
+	}
+
+	// the blocks of the nodes hold them sealed
+	for i, engine := range engines {
+		wait(fmt.Sprintf("the blocks of node %d", i), func() bool {
+			return sealedCount(t, engine.Node(), payloads) >= len(payloads)
+		})
+	}
+}
+
+// sealedCount returns the number of transactions in the blocks of a node,
+// failing when one is not sealed or shows a payload
+func sealedCount(t *testing.T, n *node.Node, payloads []string) int {
+	sealed := 0
+	for b := int64(0); b <= n.GetLastBlockIndex(); b++ {
+		block, err := n.GetBlock(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tx := range block.Transactions() {
+			if !IsSealed(tx) {
+				t.Fatalf("expected sealed transactions, got %q", tx)
+			}
+			for _, payload := range payloads {
+				if bytes.Contains(tx, []byte(payload)) {
+					t.Fatalf("expected %q unreadable", payload)
+				}
+			}
+			sealed++
+		}
+	}
+	return sealed
+}
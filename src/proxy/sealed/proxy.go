@@ -0,0 +1,227 @@
+package sealed
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy"
+	"github.com/SamuelMarks/dag1/src/proxy/proto"
+)
+
+// TxSizeError refuses a payload whose sealed transaction is longer than the
+// max transaction size
+type TxSizeError struct {
+	Size int
+	Max  int
+}
+
+func (e *TxSizeError) Error() string {
+	return fmt.Sprintf("sealed transaction of %d bytes beyond the max of %d, payloads are limited to %d",
+		e.Size, e.Max, MaxPayloadSize(e.Max))
+}
+
+// IsTxSize returns true for a TxSizeError
+func IsTxSize(err error) bool {
+	_, ok := err.(*TxSizeError)
+	return ok
+}
+
+// SealingDAG1Proxy is a DAG1Proxy sealing the transactions it submits to
+// the recipient key. The commits and the other requests pass through.
+type SealingDAG1Proxy struct {
+	proxy.DAG1Proxy
+
+	locker    sync.RWMutex
+	recipient *ecdsa.PublicKey
+	maxTxSize int
+}
+
+// NewSealingDAG1Proxy constructor of a proxy sealing the transactions it
+// submits through p to recipient
+func NewSealingDAG1Proxy(p proxy.DAG1Proxy, recipient *ecdsa.PublicKey) *SealingDAG1Proxy {
+	return &SealingDAG1Proxy{
+		DAG1Proxy: p,
+		recipient: recipient,
+	}
+}
+
+// SetRecipient sets the key the next transactions are sealed to, after the
+// consuming apps rotated their key
+func (p *SealingDAG1Proxy) SetRecipient(recipient *ecdsa.PublicKey) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+	p.recipient = recipient
+}
+
+// SetMaxTxSize refuses the payloads whose sealed transaction is longer than
+// n bytes, as node.MaxEventsPayloadSize or the transaction pool bytes of the
+// nodes, 0 for no limit. The nodes would refuse them.
+func (p *SealingDAG1Proxy) SetMaxTxSize(n int) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+	p.maxTxSize = n
+}
+
+func (p *SealingDAG1Proxy) seal(payload []byte) ([]byte, error) {
+	p.locker.RLock()
+	recipient, maxTxSize := p.recipient, p.maxTxSize
+	p.locker.RUnlock()
+	if size := SealedSize(len(payload)); maxTxSize > 0 && size > maxTxSize {
+		return nil, &TxSizeError{Size: size, Max: maxTxSize}
+	}
+	return Seal(recipient, payload)
+}
+
+// SubmitTx seals a transaction and submits it
+func (p *SealingDAG1Proxy) SubmitTx(tx []byte) error {
+	sealed, err := p.seal(tx)
+	if err != nil {
+		return err
+	}
+	return p.DAG1Proxy.SubmitTx(sealed)
+}
+
+// SubmitTxWithFlags seals a transaction and submits it with its flags,
+// which stay in clear
+func (p *SealingDAG1Proxy) SubmitTxWithFlags(tx []byte, flags byte) error {
+	sealed, err := p.seal(tx)
+	if err != nil {
+		return err
+	}
+	return p.DAG1Proxy.SubmitTxWithFlags(sealed, flags)
+}
+
+// SubmitTxAndWait seals a transaction, submits it and returns where it was
+// committed
+func (p *SealingDAG1Proxy) SubmitTxAndWait(ctx context.Context, tx []byte) (proto.BlockRef, error) {
+	sealed, err := p.seal(tx)
+	if err != nil {
+		return proto.BlockRef{}, err
+	}
+	return p.DAG1Proxy.SubmitTxAndWait(ctx, sealed)
+}
+
+// UnsealingHandler is a ProxyHandler opening the transactions of the
+// committed blocks before handing them to the handler of the app. The
+// transactions which do not open, sealed to another key, altered or not
+// sealed, are left out of the block and counted, so that no submitter can
+// stop the app with one. Every app must hold the same keys to reach the
+// same state.
+type UnsealingHandler struct {
+	handler proxy.ProxyHandler
+	keys    *Keyring
+	logger  *logrus.Logger
+	dropped uint64
+}
+
+// NewUnsealingHandler constructor
+func NewUnsealingHandler(handler proxy.ProxyHandler, keys *Keyring, logger *logrus.Logger) *UnsealingHandler {
+	if logger == nil {
+		logger = logrus.New()
+		logger.Level = logrus.DebugLevel
+	}
+	return &UnsealingHandler{
+		handler: handler,
+		keys:    keys,
+		logger:  logger,
+	}
+}
+
+// CommitHandler opens the transactions of the block and commits them to
+// the handler of the app
+func (h *UnsealingHandler) CommitHandler(block poset.Block) ([]byte, error) {
+	return h.handler.CommitHandler(h.open(block))
+}
+
+// SnapshotHandler returns the snapshot of the handler of the app
+func (h *UnsealingHandler) SnapshotHandler(blockIndex int64) ([]byte, error) {
+	return h.handler.SnapshotHandler(blockIndex)
+}
+
+// RestoreHandler restores the handler of the app
+func (h *UnsealingHandler) RestoreHandler(snapshot []byte) ([]byte, error) {
+	return h.handler.RestoreHandler(snapshot)
+}
+
+// Dropped returns the number of transactions left out as they did not open
+func (h *UnsealingHandler) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+// open returns the block with its transactions opened. The body is copied,
+// the block of the node is left as it is.
+func (h *UnsealingHandler) open(block poset.Block) poset.Block {
+	if block.Body == nil {
+		return block
+	}
+	txs := block.Transactions()
+	opened := make([][]byte, 0, len(txs))
+	for i, tx := range txs {
+		payload, err := h.keys.Open(tx)
+		if err != nil {
+			atomic.AddUint64(&h.dropped, 1)
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"block":       block.Index(),
+				"transaction": i,
+			}).Warn("Leaving out a transaction which does not open")
+			continue
+		}
+		opened = append(opened, payload)
+	}
+	body := *block.Body
+	body.Transactions = opened
+	block.Body = &body
+	return block
+}
+
+// UnsealingClient serves the commits, snapshot requests and restores of a
+// DAG1Proxy with an UnsealingHandler, as dummy.DummyClient does with the
+// handler of the app
+type UnsealingClient struct {
+	*UnsealingHandler
+}
+
+// NewUnsealingClient constructor of a client committing the blocks of
+// dag1Proxy, opened with keys, to handler, until the channels of the proxy
+// are closed
+func NewUnsealingClient(dag1Proxy proxy.DAG1Proxy, keys *Keyring, handler proxy.ProxyHandler,
+	logger *logrus.Logger) *UnsealingClient {
+	c := &UnsealingClient{
+		UnsealingHandler: NewUnsealingHandler(handler, keys, logger),
+	}
+	go c.serve(dag1Proxy)
+	return c
+}
+
+func (c *UnsealingClient) serve(dag1Proxy proxy.DAG1Proxy) {
+	for {
+		select {
+		case b, ok := <-dag1Proxy.CommitCh():
+			if !ok {
+				return
+			}
+			hash, err := c.CommitHandler(b.Block)
+			b.Respond(hash, err)
+
+		case r, ok := <-dag1Proxy.RestoreCh():
+			if !ok {
+				return
+			}
+			hash, err := c.RestoreHandler(r.Snapshot)
+			r.Respond(hash, err)
+
+		case s, ok := <-dag1Proxy.SnapshotRequestCh():
+			if !ok {
+				return
+			}
+			snapshot, err := c.SnapshotHandler(s.BlockIndex)
+			s.Respond(snapshot, err)
+		}
+	}
+}
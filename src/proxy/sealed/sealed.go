@@ -0,0 +1,187 @@
+// Package sealed encrypts the transactions of an application to the public
+// key of the applications consuming the blocks, so that the nodes order
+// transactions they cannot read. The payloads are sealed with ECIES over the
+// P256 keys dag1 uses: an ephemeral key agrees on a secret with the
+// recipient key, which keys AES-256-GCM.
+//
+// The nodes carry the sealed transactions as any other. SealingDAG1Proxy
+// seals the transactions an app submits, UnsealingClient opens those of the
+// committed blocks before handing them to the app.
+package sealed
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+)
+
+const (
+	// KeyIDSize is the size of the ID of the key a payload is sealed to
+	KeyIDSize = 8
+
+	pubSize    = 65 // uncompressed P256 point
+	tagSize    = 16 // GCM
+	headerSize = len(magic) + KeyIDSize + pubSize
+
+	// Overhead is the number of bytes a sealed transaction is longer than
+	// its payload
+	Overhead = headerSize + tagSize
+)
+
+// magic starts the sealed transactions, its last byte is the version of
+// the format
+const magic = "\xd5\xea\x1e\x01"
+
+var (
+	// ErrNotSealed is returned opening a transaction which is not sealed
+	ErrNotSealed = errors.New("not a sealed transaction")
+	// ErrCorrupt is returned opening a sealed transaction which does not
+	// authenticate, as it was altered
+	ErrCorrupt = errors.New("sealed transaction does not authenticate")
+)
+
+// KeyID identifies the key a payload is sealed to, so that the app picks
+// the key among those it rotated through
+type KeyID [KeyIDSize]byte
+
+// KeyIDOf returns the ID of a public key
+func KeyIDOf(pub *ecdsa.PublicKey) KeyID {
+	var id KeyID
+	sum := sha256.Sum256(crypto.FromECDSAPub(pub))
+	copy(id[:], sum[:])
+	return id
+}
+
+func (id KeyID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// UnknownKeyError is returned opening a transaction sealed to a key the
+// app does not hold
+type UnknownKeyError struct {
+	KeyID KeyID
+}
+
+func (e *UnknownKeyError) Error() string {
+	return fmt.Sprintf("transaction sealed to unknown key %s", e.KeyID)
+}
+
+// IsUnknownKey returns true for an UnknownKeyError
+func IsUnknownKey(err error) bool {
+	_, ok := err.(*UnknownKeyError)
+	return ok
+}
+
+// SealedSize returns the size of the sealed transaction of a payload of n
+// bytes
+func SealedSize(n int) int {
+	return n + Overhead
+}
+
+// MaxPayloadSize returns the size of the largest payload whose sealed
+// transaction is at most maxTxSize bytes, 0 when none fits
+func MaxPayloadSize(maxTxSize int) int {
+	if maxTxSize < Overhead {
+		return 0
+	}
+	return maxTxSize - Overhead
+}
+
+// IsSealed returns true for a transaction in the sealed format. It does not
+// tell whether it opens.
+func IsSealed(tx []byte) bool {
+	return len(tx) >= Overhead && bytes.HasPrefix(tx, []byte(magic))
+}
+
+// Seal encrypts a payload to the recipient key. The sealed transaction is
+// SealedSize(len(payload)) bytes: the magic, the ID of the recipient key,
+// the ephemeral public key and the payload encrypted and authenticated
+// together with them.
+func Seal(recipient *ecdsa.PublicKey, payload []byte) ([]byte, error) {
+	if recipient == nil || recipient.X == nil {
+		return nil, errors.New("no recipient key")
+	}
+	ephemeral, err := crypto.GenerateECDSAKey()
+	if err != nil {
+		return nil, err
+	}
+	ephemeralPub := crypto.FromECDSAPub(&ephemeral.PublicKey)
+	id := KeyIDOf(recipient)
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = append(header, id[:]...)
+	header = append(header, ephemeralPub...)
+
+	aead, err := newAEAD(ephemeral.D.Bytes(), recipient, ephemeralPub)
+	if err != nil {
+		return nil, err
+	}
+	// the key is used once, a zero nonce is enough
+	nonce := make([]byte, aead.NonceSize())
+	return aead.Seal(header, nonce, payload, header), nil
+}
+
+// Open decrypts a transaction sealed to the public key of priv
+func Open(priv *ecdsa.PrivateKey, tx []byte) ([]byte, error) {
+	if !IsSealed(tx) {
+		return nil, ErrNotSealed
+	}
+	if id := sealedKeyID(tx); id != KeyIDOf(&priv.PublicKey) {
+		return nil, &UnknownKeyError{KeyID: id}
+	}
+	return open(priv, tx)
+}
+
+// sealedKeyID returns the ID of the key a sealed transaction is sealed to
+func sealedKeyID(tx []byte) KeyID {
+	var id KeyID
+	copy(id[:], tx[len(magic):])
+	return id
+}
+
+// open decrypts a sealed transaction with priv, the key of its ID
+func open(priv *ecdsa.PrivateKey, tx []byte) ([]byte, error) {
+	header := tx[:headerSize]
+	ephemeralPub := header[len(magic)+KeyIDSize:]
+	x, y := elliptic.Unmarshal(elliptic.P256(), ephemeralPub)
+	if x == nil {
+		return nil, ErrCorrupt
+	}
+	aead, err := newAEAD(priv.D.Bytes(), &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, ephemeralPub)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	payload, err := aead.Open(nil, nonce, tx[headerSize:], header)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return payload, nil
+}
+
+// newAEAD returns the cipher keyed by the secret the scalar d agrees on
+// with the public key pub, the ephemeral public key bound to it
+func newAEAD(d []byte, pub *ecdsa.PublicKey, ephemeralPub []byte) (cipher.AEAD, error) {
+	x, _ := elliptic.P256().ScalarMult(pub.X, pub.Y, d)
+	shared := make([]byte, 32)
+	xBytes := x.Bytes()
+	copy(shared[len(shared)-len(xBytes):], xBytes)
+
+	kdf := sha256.New()
+	kdf.Write(shared)
+	kdf.Write(ephemeralPub)
+	block, err := aes.NewCipher(kdf.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,232 @@
+package sealed
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"sync"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy/proto"
+)
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateECDSAKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSealOpen(t *testing.T) {
+	key := newKey(t)
+	payload := []byte("transfer 10 from alice to bob")
+
+	tx, err := Seal(&key.PublicKey, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx) != SealedSize(len(payload)) {
+		t.Fatalf("expected %d bytes, got %d", SealedSize(len(payload)), len(tx))
+	}
+	if !IsSealed(tx) || IsSealed(payload) {
+		t.Fatal("expected the sealed transaction only to be sealed")
+	}
+	if bytes.Contains(tx, payload) {
+		t.Fatal("expected the payload not to be readable")
+	}
+	again, err := Seal(&key.PublicKey, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(tx, again) {
+		t.Fatal("expected the same payload to seal differently")
+	}
+
+	opened, err := Open(key, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, payload) {
+		t.Fatalf("expected %q, got %q", payload, opened)
+	}
+
+	// an empty payload seals too
+	tx, err = Seal(&key.PublicKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := Open(key, tx); err != nil || len(opened) != 0 {
+		t.Fatalf("expected an empty payload, got %q, %v", opened, err)
+	}
+}
+
+func TestOpenWrongKey(t *testing.T) {
+	key, other := newKey(t), newKey(t)
+	tx, err := Seal(&key.PublicKey, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(other, tx); !IsUnknownKey(err) {
+		t.Fatalf("expected an UnknownKeyError, got %v", err)
+	}
+	if _, err := NewKeyring(other).Open(tx); !IsUnknownKey(err) {
+		t.Fatalf("expected an UnknownKeyError, got %v", err)
+	}
+
+	// sealed to the key, but claiming to be sealed to the other one
+	forged := append([]byte{}, tx...)
+	id := KeyIDOf(&other.PublicKey)
+	copy(forged[len(magic):], id[:])
+	if _, err := Open(other, forged); err != ErrCorrupt {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+
+	for _, i := range []int{len(magic) + KeyIDSize + 1, headerSize, len(tx) - 1} {
+		altered := append([]byte{}, tx...)
+		altered[i] ^= 1
+		if _, err := Open(key, altered); err != ErrCorrupt {
+			t.Fatalf("byte %d altered: expected ErrCorrupt, got %v", i, err)
+		}
+	}
+
+	for _, plain := range [][]byte{nil, []byte("secret"), tx[:Overhead-1]} {
+		if _, err := Open(key, plain); err != ErrNotSealed {
+			t.Fatalf("expected ErrNotSealed, got %v", err)
+		}
+	}
+}
+
+// submitProxy is a DAG1Proxy keeping the transactions submitted
+type submitProxy struct {
+	sync.Mutex
+	txs [][]byte
+}
+
+func (p *submitProxy) CommitCh() chan proto.Commit                   { return nil }
+func (p *submitProxy) SnapshotRequestCh() chan proto.SnapshotRequest { return nil }
+func (p *submitProxy) RestoreCh() chan proto.RestoreRequest          { return nil }
+
+func (p *submitProxy) SubmitTx(tx []byte) error {
+	p.Lock()
+	defer p.Unlock()
+	p.txs = append(p.txs, tx)
+	return nil
+}
+
+func (p *submitProxy) SubmitTxWithFlags(tx []byte, flags byte) error {
+	return p.SubmitTx(tx)
+}
+
+func (p *submitProxy) SubmitTxAndWait(ctx context.Context, tx []byte) (proto.BlockRef, error) {
+	return proto.BlockRef{}, p.SubmitTx(tx)
+}
+
+func TestSealedSize(t *testing.T) {
+	key := newKey(t)
+	for _, max := range []int{Overhead, 1024, 1 << 20} {
+		payload := make([]byte, MaxPayloadSize(max))
+		tx, err := Seal(&key.PublicKey, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tx) != max {
+			t.Fatalf("expected the largest payload to seal to %d bytes, got %d", max, len(tx))
+		}
+	}
+	if n := MaxPayloadSize(Overhead - 1); n != 0 {
+		t.Fatalf("expected no payload to fit, got %d", n)
+	}
+
+	const max = 1024
+	submitted := &submitProxy{}
+	p := NewSealingDAG1Proxy(submitted, &key.PublicKey)
+	p.SetMaxTxSize(max)
+	if err := p.SubmitTx(make([]byte, MaxPayloadSize(max))); err != nil {
+		t.Fatal(err)
+	}
+	err := p.SubmitTxWithFlags(make([]byte, MaxPayloadSize(max)+1), 1)
+	if !IsTxSize(err) {
+		t.Fatalf("expected a TxSizeError, got %v", err)
+	}
+	if e := err.(*TxSizeError); e.Size != max+1 || e.Max != max {
+		t.Fatalf("expected a sealed size of %d beyond %d, got %+v", max+1, max, e)
+	}
+	if len(submitted.txs) != 1 || len(submitted.txs[0]) != max || !IsSealed(submitted.txs[0]) {
+		t.Fatalf("expected a single sealed transaction of %d bytes submitted", max)
+	}
+}
+
+// recordingHandler is a ProxyHandler keeping the transactions committed
+type recordingHandler struct {
+	sync.Mutex
+	txs [][]byte
+}
+
+func (h *recordingHandler) CommitHandler(block poset.Block) ([]byte, error) {
+	h.Lock()
+	defer h.Unlock()
+	h.txs = append(h.txs, block.Transactions()...)
+	return []byte{}, nil
+}
+
+func (h *recordingHandler) SnapshotHandler(blockIndex int64) ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (h *recordingHandler) RestoreHandler(snapshot []byte) ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (h *recordingHandler) committed() [][]byte {
+	h.Lock()
+	defer h.Unlock()
+	return append([][]byte{}, h.txs...)
+}
+
+func TestUnsealingHandler(t *testing.T) {
+	key, other := newKey(t), newKey(t)
+	var txs [][]byte
+	for _, tx := range []struct {
+		key     *ecdsa.PrivateKey
+		payload string
+	}{
+		{key, "a"},
+		{other, "b"},
+		{nil, "c"},
+		{key, "d"},
+	} {
+		if tx.key == nil {
+			txs = append(txs, []byte(tx.payload))
+			continue
+		}
+		sealed, err := Seal(&tx.key.PublicKey, []byte(tx.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, sealed)
+	}
+	block := poset.NewBlock(0, 1, []byte{}, txs)
+
+	app := &recordingHandler{}
+	h := NewUnsealingHandler(app, NewKeyring(key), nil)
+	if _, err := h.CommitHandler(block); err != nil {
+		t.Fatal(err)
+	}
+
+	// the transactions sealed to another key or not sealed are left out
+	committed := app.committed()
+	if len(committed) != 2 || string(committed[0]) != "a" || string(committed[1]) != "d" {
+		t.Fatalf("expected a and d committed, got %q", committed)
+	}
+	if h.Dropped() != 2 {
+		t.Fatalf("expected 2 transactions left out, got %d", h.Dropped())
+	}
+	// the block of the node keeps its sealed transactions
+	if !bytes.Equal(block.Transactions()[0], txs[0]) {
+		t.Fatal("expected the block to be left as it is")
+	}
+}
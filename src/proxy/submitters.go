@@ -0,0 +1,522 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/proxy/internal"
+)
+
+const (
+	// CommitsOpen sends the blocks to every client
+	CommitsOpen = "open"
+	// CommitsRestricted sends the blocks, the snapshot requests and the
+	// restores to the authenticated clients only, the others only submit
+	CommitsRestricted = "restricted"
+
+	// DefaultAllowlistWatch is how often the allowlist file is checked for
+	// changes, it is reloaded on SIGHUP too
+	DefaultAllowlistWatch = 5 * time.Second
+
+	// tokenPrefix marks the token hashes of the allowlist
+	tokenPrefix = "sha256:"
+	// nonceSize is the size of the challenges the clients sign
+	nonceSize = 32
+	// receiptTimeout is how long the receipt of a transaction is waited for
+	receiptTimeout = 10 * time.Second
+)
+
+// ErrNoReceipt is returned for the transactions the node did not answer in
+// time, they may still be taken
+var ErrNoReceipt = errors.New("no receipt of the transaction")
+
+// CommitDeliveries are the clients the blocks can be sent to
+var CommitDeliveries = []string{CommitsOpen, CommitsRestricted}
+
+// SubmitterError rejects the transactions, or the authentication, of a
+// client the allowlist of the node does not hold
+type SubmitterError struct {
+	Submitter string
+	Reason    string
+}
+
+func (e *SubmitterError) Error() string {
+	if e.Submitter == "" {
+		return fmt.Sprintf("submitter refused: %s", e.Reason)
+	}
+	return fmt.Sprintf("submitter %s refused: %s", e.Submitter, e.Reason)
+}
+
+// IsSubmitterError returns true for a SubmitterError
+func IsSubmitterError(err error) bool {
+	_, ok := err.(*SubmitterError)
+	return ok
+}
+
+// Allowlist is the file of the clients allowed to submit transactions, one
+// a line: the public keys, hex as in the peers file, and the SHA-256 of the
+// tokens, hex after "sha256:". Empty lines and those starting with # are
+// skipped.
+type Allowlist struct {
+	path string
+
+	sync.RWMutex
+	entries map[string]bool
+	// size and modTime are those of the file read, to watch it
+	size    int64
+	modTime time.Time
+}
+
+// LoadAllowlist reads the allowlist file at path
+func LoadAllowlist(path string) (*Allowlist, error) {
+	a := &Allowlist{path: path}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload reads the allowlist file again. The clients it no longer holds are
+// refused from their next transaction on. The list is kept as it was when
+// the file cannot be read.
+func (a *Allowlist) Reload() error {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(a.path)
+	if err != nil {
+		return err
+	}
+	entries, err := parseAllowlist(data)
+	if err != nil {
+		return fmt.Errorf("%s: %s", a.path, err)
+	}
+	a.Lock()
+	a.entries = entries
+	a.size, a.modTime = info.Size(), info.ModTime()
+	a.Unlock()
+	return nil
+}
+
+// Len returns the number of entries of the allowlist
+func (a *Allowlist) Len() int {
+	a.RLock()
+	defer a.RUnlock()
+	return len(a.entries)
+}
+
+// Watch reloads the allowlist on SIGHUP, and when the file changed,
+// checked every interval unless it is 0. The returned func stops it.
+func (a *Allowlist) Watch(interval time.Duration, logger logrus.FieldLogger) func() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		defer signal.Stop(hup)
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+			case <-tick:
+				if !a.changed() {
+					continue
+				}
+			}
+			if err := a.Reload(); err != nil {
+				logger.WithError(err).Error("Reloading the submitter allowlist")
+				continue
+			}
+			logger.WithField("entries", a.Len()).Info("Reloaded the submitter allowlist")
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// changed returns true when the file is not the one read last
+func (a *Allowlist) changed() bool {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return false
+	}
+	a.RLock()
+	defer a.RUnlock()
+	return info.Size() != a.size || !info.ModTime().Equal(a.modTime)
+}
+
+// allows returns true when the allowlist holds the entry
+func (a *Allowlist) allows(entry string) bool {
+	a.RLock()
+	defer a.RUnlock()
+	return a.entries[entry]
+}
+
+// authenticate returns the allowlist entry of a client from its token, or
+// from its key and its signature of the challenge nonce
+func (a *Allowlist) authenticate(auth *internal.ToServer_Auth, nonce []byte) (string, error) {
+	if token := auth.GetToken(); len(token) > 0 {
+		entry := tokenEntry(token)
+		if !a.allows(entry) {
+			return "", &SubmitterError{Reason: "unknown token"}
+		}
+		return entry, nil
+	}
+	pubKey := auth.GetPubKey()
+	if len(pubKey) == 0 {
+		return "", &SubmitterError{Reason: "neither token nor key"}
+	}
+	entry := keyEntry(pubKey)
+	pub := crypto.ToECDSAPub(pubKey)
+	if pub.X == nil {
+		return "", &SubmitterError{Submitter: entry, Reason: "malformed key"}
+	}
+	r, s, err := crypto.DecodeSignature(auth.GetSignature())
+	if err != nil {
+		return "", &SubmitterError{Submitter: entry, Reason: err.Error()}
+	}
+	if nonce == nil || !crypto.Verify(pub, challengeHash(nonce), r, s) {
+		return "", &SubmitterError{Submitter: entry, Reason: "bad signature of the challenge"}
+	}
+	if !a.allows(entry) {
+		return "", &SubmitterError{Submitter: entry, Reason: "unknown key"}
+	}
+	return entry, nil
+}
+
+// parseAllowlist returns the normalized entries of an allowlist file
+func parseAllowlist(data []byte) (map[string]bool, error) {
+	entries := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lower := strings.ToLower(line)
+		if strings.HasPrefix(lower, tokenPrefix) {
+			hexa := lower[len(tokenPrefix):]
+			if _, err := hex.DecodeString(hexa); err != nil || len(hexa) != 2*sha256.Size {
+				return nil, fmt.Errorf("line %d: a token hash is %d hex digits", n, 2*sha256.Size)
+			}
+			entries[tokenPrefix+hexa] = true
+			continue
+		}
+		if !strings.HasPrefix(lower, "0x") {
+			return nil, fmt.Errorf("line %d: neither 0x<public key> nor %s<token hash>", n, tokenPrefix)
+		}
+		pubKey, err := hex.DecodeString(lower[2:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", n, err)
+		}
+		entries[keyEntry(pubKey)] = true
+	}
+	return entries, scanner.Err()
+}
+
+// keyEntry returns the allowlist entry of a public key
+func keyEntry(pubKey []byte) string {
+	return fmt.Sprintf("0x%X", pubKey)
+}
+
+// tokenEntry returns the allowlist entry of a token
+func tokenEntry(token []byte) string {
+	hash := sha256.Sum256(token)
+	return tokenPrefix + hex.EncodeToString(hash[:])
+}
+
+// challengeHash returns what the clients sign of a challenge
+func challengeHash(nonce []byte) []byte {
+	return crypto.Keccak256(nonce)
+}
+
+/*
+ * node side:
+ */
+
+// GrpcAppProxyOption configures a GrpcAppProxy
+type GrpcAppProxyOption func(*GrpcAppProxy)
+
+// WithSubmitterAllowlist refuses the transactions of the clients the
+// allowlist does not authenticate, which are counted in SubmitterStats.
+// The blocks go to every client with CommitsOpen, to the authenticated ones
+// only with CommitsRestricted.
+func WithSubmitterAllowlist(allowlist *Allowlist, delivery string) GrpcAppProxyOption {
+	return func(p *GrpcAppProxy) {
+		p.allowlist = allowlist
+		p.restricted = delivery == CommitsRestricted
+	}
+}
+
+// SubmitterStats are the counters of the submitter authentication
+type SubmitterStats struct {
+	Allowlist bool   `json:"allowlist"`
+	Rejected  uint64 `json:"rejected"`
+	Refused   uint64 `json:"refused_authentications"`
+}
+
+// SubmitterStats returns the number of transactions refused to the clients
+// the allowlist does not authenticate, and of authentications refused
+func (p *GrpcAppProxy) SubmitterStats() SubmitterStats {
+	return SubmitterStats{
+		Allowlist: p.allowlist != nil,
+		Rejected:  atomic.LoadUint64(&p.rejected),
+		Refused:   atomic.LoadUint64(&p.refused),
+	}
+}
+
+// challenge sends a client the nonce to sign, before anything else, when
+// there is an allowlist
+func (p *GrpcAppProxy) challenge(stream ClientStream) ([]byte, error) {
+	if p.allowlist == nil {
+		return nil, nil
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	err := stream.Send(&internal.ToClient{
+		Event: &internal.ToClient_Challenge_{
+			Challenge: &internal.ToClient_Challenge{Nonce: nonce},
+		},
+	})
+	return nonce, err
+}
+
+// authenticate records the allowlist entry of a client, a refused
+// authentication drops the one before
+func (p *GrpcAppProxy) authenticate(stream ClientStream, auth *internal.ToServer_Auth) error {
+	if p.allowlist == nil {
+		return nil
+	}
+	p.clientsSync.Lock()
+	defer p.clientsSync.Unlock()
+	c, ok := p.clients[stream]
+	if !ok {
+		return nil
+	}
+	submitter, err := p.allowlist.authenticate(auth, c.nonce)
+	if err != nil {
+		atomic.AddUint64(&p.refused, 1)
+	}
+	c.submitter = submitter
+	return err
+}
+
+// authorize returns a SubmitterError for a client the allowlist does not
+// authenticate, nil for all of them when there is no allowlist
+func (p *GrpcAppProxy) authorize(stream ClientStream) error {
+	if p.allowlist == nil {
+		return nil
+	}
+	p.clientsSync.RLock()
+	var submitter string
+	if c, ok := p.clients[stream]; ok {
+		submitter = c.submitter
+	}
+	p.clientsSync.RUnlock()
+	if submitter == "" {
+		return &SubmitterError{Reason: "not authenticated"}
+	}
+	if !p.allowlist.allows(submitter) {
+		return &SubmitterError{Submitter: submitter, Reason: "no longer in the allowlist"}
+	}
+	return nil
+}
+
+// receives returns true when the blocks go to a client
+func (p *GrpcAppProxy) receives(c *client) bool {
+	if !p.restricted || p.allowlist == nil {
+		return true
+	}
+	return c.submitter != "" && p.allowlist.allows(c.submitter)
+}
+
+// delivers returns true when the blocks go to the client of a stream, or
+// it is gone, for its stream to be dropped on sending
+func (p *GrpcAppProxy) delivers(stream ClientStream) bool {
+	p.clientsSync.RLock()
+	defer p.clientsSync.RUnlock()
+	c, ok := p.clients[stream]
+	return !ok || p.receives(c)
+}
+
+/*
+ * app side:
+ */
+
+// WithSubmitterToken authenticates the app to the node with a token of its
+// allowlist
+func WithSubmitterToken(token []byte) GrpcDAG1ProxyOption {
+	return func(p *GrpcDAG1Proxy) {
+		p.submitterToken = token
+	}
+}
+
+// WithSubmitterKey authenticates the app to the node by signing its
+// challenge with a key of its allowlist. The transactions wait for the
+// challenge of the node, which must have an allowlist.
+func WithSubmitterKey(key *ecdsa.PrivateKey) GrpcDAG1ProxyOption {
+	return func(p *GrpcDAG1Proxy) {
+		p.submitterKey = key
+	}
+}
+
+// tokenAuth returns the authentication by token, nil without a token
+func (p *GrpcDAG1Proxy) tokenAuth() *internal.ToServer {
+	if len(p.submitterToken) == 0 {
+		return nil
+	}
+	return &internal.ToServer{
+		Event: &internal.ToServer_Auth_{
+			Auth: &internal.ToServer_Auth{Token: p.submitterToken},
+		},
+	}
+}
+
+// answerChallenge sends the node the signature of its challenge, and lets
+// the transactions waiting for it go
+func (p *GrpcDAG1Proxy) answerChallenge(nonce []byte) error {
+	if p.submitterKey == nil {
+		return nil
+	}
+	r, s, err := crypto.Sign(p.submitterKey, challengeHash(nonce))
+	if err != nil {
+		return err
+	}
+	err = p.sendToServer(&internal.ToServer{
+		Event: &internal.ToServer_Auth_{
+			Auth: &internal.ToServer_Auth{
+				PubKey:    crypto.FromECDSAPub(&p.submitterKey.PublicKey),
+				Signature: crypto.EncodeSignature(r, s),
+			},
+		},
+	})
+	p.setSigned()
+	return err
+}
+
+// setSigned lets the transactions waiting for the authentication of the
+// stream go
+func (p *GrpcDAG1Proxy) setSigned() {
+	if signed, ok := p.signed.Load().(chan struct{}); ok {
+		select {
+		case <-signed:
+		default:
+			close(signed)
+		}
+	}
+}
+
+// resetSigned makes the transactions wait for the authentication of a new
+// stream, unless they still wait for the one before
+func (p *GrpcDAG1Proxy) resetSigned() {
+	if p.submitterKey == nil && len(p.submitterToken) == 0 {
+		return
+	}
+	signed, ok := p.signed.Load().(chan struct{})
+	if !ok {
+		p.signed.Store(make(chan struct{}))
+		return
+	}
+	select {
+	case <-signed:
+		p.signed.Store(make(chan struct{}))
+	default:
+	}
+}
+
+// waitSigned waits for the token, or the signature of the challenge of the
+// node, to be sent, so that the transactions do not get before the
+// authentication
+func (p *GrpcDAG1Proxy) waitSigned() error {
+	signed, ok := p.signed.Load().(chan struct{})
+	if !ok {
+		return nil
+	}
+	select {
+	case <-signed:
+		return nil
+	case <-p.shutdown:
+		return ErrConnShutdown
+	}
+}
+
+// txReceipts are the transactions waiting for the receipt of the node
+type txReceipts struct {
+	sync.Mutex
+	waiting map[xid.ID]chan *internal.ToClient_Receipt
+}
+
+func (r *txReceipts) add(uid xid.ID) chan *internal.ToClient_Receipt {
+	ch := make(chan *internal.ToClient_Receipt, 1)
+	r.Lock()
+	defer r.Unlock()
+	if r.waiting == nil {
+		r.waiting = make(map[xid.ID]chan *internal.ToClient_Receipt)
+	}
+	r.waiting[uid] = ch
+	return ch
+}
+
+func (r *txReceipts) remove(uid xid.ID) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.waiting, uid)
+}
+
+// received hands a receipt to the transaction waiting for it
+func (r *txReceipts) received(receipt *internal.ToClient_Receipt) {
+	uid, err := xid.FromBytes(receipt.GetUid())
+	if err != nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	if ch, ok := r.waiting[uid]; ok {
+		ch <- receipt
+		delete(r.waiting, uid)
+	}
+}
+
+// receiptError returns the refusal of a receipt, a SubmitterError when the
+// allowlist of the node refused the app
+func receiptError(receipt *internal.ToClient_Receipt) error {
+	switch {
+	case receipt.GetRefused():
+		return &SubmitterError{
+			Submitter: receipt.GetSubmitter(),
+			Reason:    receipt.GetError(),
+		}
+	case receipt.GetError() != "":
+		return errors.New(receipt.GetError())
+	}
+	return nil
+}
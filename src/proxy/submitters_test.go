@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy/internal"
+	"github.com/SamuelMarks/dag1/src/utils"
+)
+
+func TestParseAllowlist(t *testing.T) {
+	hash := sha256.Sum256([]byte("secret"))
+	entries, err := parseAllowlist([]byte(fmt.Sprintf(`
+# the apps of the node
+0xab01
+SHA256:%X
+`, hash)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || !entries["0xAB01"] || !entries[tokenEntry([]byte("secret"))] {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	for _, data := range []string{"ab01", "0xzz", "sha256:ab01"} {
+		if _, err := parseAllowlist([]byte(data)); err == nil {
+			t.Fatalf("expected %q refused", data)
+		}
+	}
+}
+
+// writeAllowlist writes the allowlist entries to path
+func writeAllowlist(path string, entries ...string) error {
+	return ioutil.WriteFile(path, []byte(strings.Join(entries, "\n")+"\n"), 0600)
+}
+
+// submit returns the error of a transaction of a client, the node gets it
+// when there is none
+func submit(c *GrpcDAG1Proxy, s *GrpcAppProxy, tx []byte, t *testing.T) error {
+	if err := c.SubmitTx(tx); err != nil {
+		return err
+	}
+	select {
+	case got := <-s.SubmitCh():
+		if string(got) != string(tx) {
+			t.Fatalf("expected %s, got %s", tx, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected %s at the node", tx)
+	}
+	return nil
+}
+
+func TestGrpcSubmitterAllowlist(t *testing.T) {
+	const timeout = time.Second
+	assertO := assert.New(t)
+	addr := utils.GetUnusedNetAddr(1, t)
+	logger := common.NewTestLogger(t)
+
+	dir, err := ioutil.TempDir("", "allowlist")
+	assertO.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "allowlist")
+
+	key, err := crypto.GenerateECDSAKey()
+	assertO.NoError(err)
+	keyHex := keyEntry(crypto.FromECDSAPub(&key.PublicKey))
+	token := []byte("secret")
+	tokenHash := sha256.Sum256(token)
+	tokenHex := tokenPrefix + hex.EncodeToString(tokenHash[:])
+	assertO.NoError(writeAllowlist(path, keyHex, tokenHex))
+	allowlist, err := LoadAllowlist(path)
+	assertO.NoError(err)
+
+	s, err := NewGrpcAppProxy(addr[0], timeout, logger,
+		WithSubmitterAllowlist(allowlist, CommitsRestricted),
+		func(p *GrpcAppProxy) { p.allowlistWatch = 10 * time.Millisecond })
+	assertO.NoError(err)
+	defer s.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	names := []string{"key", "token", "none", "wrong"}
+	opts := [][]GrpcDAG1ProxyOption{
+		{WithSubmitterKey(key)},
+		{WithSubmitterToken(token)},
+		nil,
+		{WithSubmitterToken([]byte("wrong"))},
+	}
+	clients := make([]*GrpcDAG1Proxy, len(names))
+	received := make([]chan [][]byte, len(names))
+	for i, name := range names {
+		clients[i], err = NewGrpcDAG1Proxy(addr[0], logger, opts[i]...)
+		assertO.NoError(err)
+		defer clients[i].Close()
+		received[i] = make(chan [][]byte, 10)
+		go topicApp(clients[i], name, received[i], done)
+	}
+
+	// allowed
+	assertO.NoError(submit(clients[0], s, []byte("by key"), t))
+	assertO.NoError(submit(clients[1], s, []byte("by token"), t))
+	// denied
+	err = submit(clients[2], s, []byte("anonymous"), t)
+	assertO.True(IsSubmitterError(err), "expected a SubmitterError, got %v", err)
+	err = submit(clients[3], s, []byte("forged"), t)
+	assertO.True(IsSubmitterError(err), "expected a SubmitterError, got %v", err)
+	stats := s.SubmitterStats()
+	assertO.True(stats.Allowlist)
+	assertO.Equal(uint64(2), stats.Rejected)
+	assertO.Equal(uint64(1), stats.Refused)
+	_, err = allowlist.authenticate(&internal.ToServer_Auth{Token: []byte("wrong")}, nil)
+	assertO.True(IsSubmitterError(err))
+
+	// the blocks only go to the authenticated clients
+	waitClients(s, len(names), t)
+	block := poset.NewBlock(0, 1, []byte("frame"), [][]byte{[]byte("by key")})
+	_, err = s.CommitBlock(block)
+	assertO.NoError(err)
+	for i, name := range names {
+		select {
+		case <-received[i]:
+			assertO.True(i < 2, "expected no block at %s", name)
+		case <-time.After(200 * time.Millisecond):
+			assertO.True(i >= 2, "expected the block at %s", name)
+		}
+	}
+
+	// the token taken out of the allowlist is refused without a restart
+	assertO.NoError(writeAllowlist(path, keyHex))
+	for deadline := time.Now().Add(timeout); allowlist.Len() != 1; {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the allowlist reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assertO.NoError(submit(clients[0], s, []byte("by key again"), t))
+	err = submit(clients[1], s, []byte("by token again"), t)
+	if assertO.True(IsSubmitterError(err), "expected a SubmitterError, got %v", err) {
+		assertO.Equal(tokenHex, err.(*SubmitterError).Submitter)
+	}
+	assertO.Equal(uint64(3), s.SubmitterStats().Rejected)
+
+	// and allowed again once put back
+	assertO.NoError(writeAllowlist(path, keyHex, tokenHex))
+	for deadline := time.Now().Add(timeout); allowlist.Len() != 2; {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the allowlist reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assertO.NoError(submit(clients[1], s, []byte("by token at last"), t))
+}
@@ -0,0 +1,211 @@
+// Package replay re-runs consensus offline from an exported event log so
+// that the blocks of two nodes can be reproduced and diffed.
+package replay
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/dummy"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// cacheMargin is added to the number of events to size the store caches,
+// which must not evict anything during a replay
+const cacheMargin = 1000
+
+// Block is the replayed summary of a committed block
+type Block struct {
+	Index         int64
+	RoundReceived int64
+	FrameHash     []byte
+	TxHashes      [][]byte
+	StateHash     []byte
+	// Events are the hashes of the frame events, when the frame is known
+	Events []string
+}
+
+// String renders the block as one line of replay output
+func (b *Block) String() string {
+	txs := make([]string, len(b.TxHashes))
+	for i, h := range b.TxHashes {
+		txs[i] = fmt.Sprintf("%X", h)
+	}
+	return fmt.Sprintf("block=%d round=%d frame=%X state=%X txs=[%s]",
+		b.Index, b.RoundReceived, b.FrameHash, b.StateHash, strings.Join(txs, ","))
+}
+
+// Replay inserts events, in the given topological order, into a fresh
+// Poset backed by an InmemStore and runs the whole consensus pipeline after
+// each insertion. The committed blocks are applied to a dummy
+// state, whose cumulative hash serves as the state hash.
+func Replay(participants *peers.Peers, events []poset.Event, logger *logrus.Logger) ([]Block, error) {
+	if logger == nil {
+		logger = logrus.New()
+		logger.Level = logrus.WarnLevel
+	}
+
+	store := poset.NewInmemStore(participants, len(events)+cacheMargin, nil)
+	commitCh := make(chan poset.Block, 400)
+	p, err := poset.NewPoset(participants, store, commitCh, logrus.NewEntry(logger))
+	if err != nil {
+		return nil, err
+	}
+
+	// consume the commit channel here, there is no node to do it
+	state := dummy.NewState(logger)
+	var blocks []Block
+	var commitErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for block := range commitCh {
+			if commitErr != nil {
+				continue
+			}
+			stateHash, err := state.CommitHandler(block)
+			if err != nil {
+				commitErr = err
+				continue
+			}
+			blocks = append(blocks, newBlock(p, block, stateHash))
+		}
+	}()
+
+	err = run(p, events)
+	close(commitCh)
+	<-done
+	if err != nil {
+		return nil, err
+	}
+	return blocks, commitErr
+}
+
+func run(p *poset.Poset, events []poset.Event) error {
+	for i, e := range events {
+		// drop what the exporting node computed locally, the wire info is
+		// computed again as for an event read from the wire
+		ev := e.Message.ToEvent()
+		if err := p.InsertEvent(ev, true); err != nil {
+			hash := ev.Hash()
+			return fmt.Errorf("inserting event %d (%s): %v", i, hash.String(), err)
+		}
+		for _, step := range []struct {
+			name string
+			run  func() error
+		}{
+			{"dividing rounds", p.DivideRounds},
+			{"deciding atropos", p.DecideAtropos},
+			{"deciding round received", p.DecideRoundReceived},
+			{"processing decided rounds", p.ProcessDecidedRounds},
+		} {
+			if err := step.run(); err != nil {
+				return fmt.Errorf("%s after event %d: %v", step.name, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func newBlock(p *poset.Poset, block poset.Block, stateHash []byte) Block {
+	b := Block{
+		Index:         block.Index(),
+		RoundReceived: block.RoundReceived(),
+		FrameHash:     block.FrameHash,
+		StateHash:     stateHash,
+	}
+	for _, tx := range block.Transactions() {
+		b.TxHashes = append(b.TxHashes, crypto.Keccak256(tx))
+	}
+	if frame, err := p.Store.GetFrame(block.RoundReceived()); err == nil {
+		for _, m := range frame.Events {
+			ev := m.ToEvent()
+			hash := ev.Hash()
+			b.Events = append(b.Events, hash.String())
+		}
+	}
+	return b
+}
+
+// Write writes one line per block
+func Write(w io.Writer, blocks []Block) error {
+	for i := range blocks {
+		if _, err := fmt.Fprintln(w, blocks[i].String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Divergence describes the first block on which two replays disagree
+type Divergence struct {
+	Position int
+	A, B     *Block
+	// OnlyA and OnlyB are the frame events found in one block only
+	OnlyA, OnlyB []string
+}
+
+// Compare returns the first divergent block of two replays, or nil when
+// they produced the same blocks
+func Compare(a, b []Block) *Divergence {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		d := &Divergence{Position: i}
+		if i < len(a) {
+			d.A = &a[i]
+		}
+		if i < len(b) {
+			d.B = &b[i]
+		}
+		if d.A != nil && d.B != nil && d.A.String() == d.B.String() {
+			continue
+		}
+		if d.A != nil && d.B != nil {
+			d.OnlyA = difference(d.A.Events, d.B.Events)
+			d.OnlyB = difference(d.B.Events, d.A.Events)
+		}
+		return d
+	}
+	return nil
+}
+
+// Write prints the divergent blocks and the events they disagree on
+func (d *Divergence) Write(w io.Writer) error {
+	lines := []string{fmt.Sprintf("first divergent block at position %d", d.Position)}
+	for _, side := range []struct {
+		name  string
+		block *Block
+		only  []string
+	}{{"a", d.A, d.OnlyA}, {"b", d.B, d.OnlyB}} {
+		if side.block == nil {
+			lines = append(lines, fmt.Sprintf("%s: no block", side.name))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", side.name, side.block.String()))
+		for _, e := range side.only {
+			lines = append(lines, fmt.Sprintf("%s: only event %s", side.name, e))
+		}
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func difference(x, y []string) []string {
+	seen := make(map[string]bool, len(y))
+	for _, h := range y {
+		seen[h] = true
+	}
+	var res []string
+	for _, h := range x {
+		if !seen[h] {
+			res = append(res, h)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
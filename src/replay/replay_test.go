@@ -0,0 +1,167 @@
+package replay
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// fixture builds a stream of events created round-robin by n participants,
+// each event having the previous creator's head as other-parent
+func fixture(t *testing.T, n, count int) []byte {
+	participants := peers.NewPeers()
+	keys := make(map[string]*ecdsa.PrivateKey)
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateECDSAKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubHex := fmt.Sprintf("0x%X", crypto.FromECDSAPub(&key.PublicKey))
+		participants.AddPeer(peers.NewPeer(pubHex, fmt.Sprintf("127.0.0.1:%d", 1337+i)))
+		keys[pubHex] = key
+	}
+
+	store := poset.NewInmemStore(participants, count+cacheMargin, nil)
+	p, err := poset.NewPoset(participants, store, nil, common.NewTestLogger(t).WithField("id", "fixture"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	heads := make(map[string]poset.EventHash)
+	for _, peer := range participants.ToPeerSlice() {
+		heads[peer.Message.PubKeyHex] = poset.GenRootSelfParent(peer.ID)
+	}
+
+	var events []poset.Event
+	var prev string
+	slice := participants.ToPeerSlice()
+	for i := 0; i < count; i++ {
+		creator := slice[i%n].Message.PubKeyHex
+		key := keys[creator]
+		var otherHead poset.EventHash
+		if prev != "" {
+			otherHead = heads[prev]
+		}
+		event := poset.NewEvent(
+			[][]byte{[]byte(fmt.Sprintf("tx %d", i))}, nil, nil,
+			poset.EventHashes{heads[creator], otherHead},
+			crypto.FromECDSAPub(&key.PublicKey), int64(i/n),
+			poset.NewFlagTable(), poset.NewFlagTable(), poset.FrameNIL, false)
+		if err := event.Sign(key); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.InsertEvent(event, true); err != nil {
+			t.Fatalf("inserting fixture event %d: %v", i, err)
+		}
+		heads[creator] = event.Hash()
+		prev = creator
+		events = append(events, event)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEvents(&buf, participants, events); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func replayStream(t *testing.T, stream []byte) ([]byte, int) {
+	participants, events, err := ReadEvents(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := Replay(participants, events, common.NewTestLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := Write(&out, blocks); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes(), len(blocks)
+}
+
+func TestReadWriteEvents(t *testing.T) {
+	stream := fixture(t, 3, 12)
+
+	participants, events, err := ReadEvents(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if participants.Len() != 3 {
+		t.Fatalf("expected 3 participants, got %d", participants.Len())
+	}
+	if len(events) != 12 {
+		t.Fatalf("expected 12 events, got %d", len(events))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEvents(&buf, participants, events); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), stream) {
+		t.Fatal("rewritten stream differs from the original")
+	}
+
+	if _, _, err := ReadEvents(bytes.NewReader(stream[:len(stream)-1])); err == nil {
+		t.Fatal("expected an error reading a truncated stream")
+	}
+
+	var huge bytes.Buffer
+	if err := writeUvarint(&huge, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeUvarint(&huge, maxRecordSize+1); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadEvents(&huge); err == nil {
+		t.Fatal("expected an error reading a record over the limit")
+	}
+}
+
+func TestReplayDeterministic(t *testing.T) {
+	stream := fixture(t, 3, 30)
+
+	first, blocks := replayStream(t, stream)
+	if blocks == 0 {
+		t.Fatal("expected the replay to commit blocks")
+	}
+	second, _ := replayStream(t, stream)
+	if !bytes.Equal(first, second) {
+		t.Fatalf("replays differ:\n%s\n%s", first, second)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a := []Block{
+		{Index: 0, RoundReceived: 1, Events: []string{"x", "y"}},
+		{Index: 1, RoundReceived: 2, Events: []string{"x", "y"}},
+	}
+	b := []Block{
+		{Index: 0, RoundReceived: 1, Events: []string{"x", "y"}},
+		{Index: 1, RoundReceived: 2, TxHashes: [][]byte{{1}}, Events: []string{"y", "z"}},
+	}
+
+	if d := Compare(a, a); d != nil {
+		t.Fatalf("expected no divergence, got %+v", d)
+	}
+
+	d := Compare(a, b)
+	if d == nil || d.Position != 1 {
+		t.Fatalf("expected a divergence at 1, got %+v", d)
+	}
+	if len(d.OnlyA) != 1 || d.OnlyA[0] != "x" || len(d.OnlyB) != 1 || d.OnlyB[0] != "z" {
+		t.Fatalf("unexpected differing events %v, %v", d.OnlyA, d.OnlyB)
+	}
+
+	d = Compare(a, a[:1])
+	if d == nil || d.Position != 1 || d.B != nil {
+		t.Fatalf("expected a missing block at 1, got %+v", d)
+	}
+}
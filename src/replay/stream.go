@@ -0,0 +1,173 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/SamuelMarks/dag1/src/peers"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+/*
+ * An event stream is a uvarint count of participants followed by that many
+ * length-prefixed PeerMessages, then length-prefixed EventMessages in
+ * topological order until EOF. Lengths are uvarints.
+ */
+
+// maxRecordSize bounds a record of an event stream, above the largest event
+// a node creates, whose transactions take up to node.MaxEventsPayloadSize
+const maxRecordSize = 128 << 20
+
+// WriteEvents writes the participants and events as an event stream
+func WriteEvents(w io.Writer, participants *peers.Peers, events []poset.Event) error {
+	bw := bufio.NewWriter(w)
+
+	peerSlice := participants.ToPeerSlice()
+	if err := writeUvarint(bw, uint64(len(peerSlice))); err != nil {
+		return err
+	}
+	for _, p := range peerSlice {
+		data, err := proto.Marshal(p.Message)
+		if err != nil {
+			return err
+		}
+		if err := writeRecord(bw, data); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range events {
+		data, err := e.ProtoMarshal()
+		if err != nil {
+			return err
+		}
+		if err := writeRecord(bw, data); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ReadEvents reads an event stream written by WriteEvents
+func ReadEvents(r io.Reader) (*peers.Peers, []poset.Event, error) {
+	br := bufio.NewReader(r)
+
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading participant count: %v", err)
+	}
+	messages := make([]*peers.PeerMessage, 0, count)
+	for i := uint64(0); i < count; i++ {
+		data, err := readRecord(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading participant %d: %v", i, err)
+		}
+		pm := &peers.PeerMessage{}
+		if err := proto.Unmarshal(data, pm); err != nil {
+			return nil, nil, fmt.Errorf("decoding participant %d: %v", i, err)
+		}
+		messages = append(messages, pm)
+	}
+
+	var events []poset.Event
+	for {
+		data, err := readRecord(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading event %d: %v", len(events), err)
+		}
+		var e poset.Event
+		if err := e.ProtoUnmarshal(data); err != nil {
+			return nil, nil, fmt.Errorf("decoding event %d: %v", len(events), err)
+		}
+		events = append(events, e)
+	}
+
+	return peers.NewPeersFromMessageSlice(messages), events, nil
+}
+
+// LoadBadger reads the participants and events of a badger database in
+// topological order
+func LoadBadger(dir string, cacheSize int) (*peers.Peers, []poset.Event, error) {
+	store, err := poset.LoadBadgerStore(cacheSize, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer store.Close()
+
+	participants, err := store.Participants()
+	if err != nil {
+		return nil, nil, err
+	}
+	var events []poset.Event
+	err = store.ForEachEvent(func(e poset.Event) bool {
+		events = append(events, e)
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return participants, events, nil
+}
+
+// Load reads the participants and events from path, which is either a
+// badger directory or an event stream file
+func Load(path string, cacheSize int) (*peers.Peers, []poset.Event, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return LoadBadger(path, cacheSize)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return ReadEvents(f)
+}
+
+func writeUvarint(w io.Writer, x uint64) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], x)
+	_, err := w.Write(buf[:n])
+	return err
+}
+
+func writeRecord(w io.Writer, data []byte) error {
+	if err := writeUvarint(w, uint64(len(data))); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func readRecord(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxRecordSize {
+		return nil, fmt.Errorf("record of %d bytes, the limit is %d", size, maxRecordSize)
+	}
+	// the buffer grows with what is read, not with what the size claims
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/SamuelMarks/dag1/src/node"
+)
+
+// maxTxSize bounds the body of a POST /tx request
+const maxTxSize = 1 << 20
+
+// KVQuerier reads the state of a key/value app run in the node process, such
+// as dummy.KVState
+type KVQuerier interface {
+	Query(key string) (string, bool)
+}
+
+// EnableKV serves GET /kv/{key} from the state of an app embedded in the node,
+// and POST /tx to write to it
+func (s *Service) EnableKV(kv KVQuerier) {
+	s.kv = kv
+}
+
+// GetKV returns the value of a key in the state of the embedded app
+func (s *Service) GetKV(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Path[len("/kv/"):]
+	value, ok := s.kv.Query(key)
+	if !ok {
+		http.Error(w, "key not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"key": key, "value": value}); err != nil {
+		s.logger.WithError(err).Errorf("Failed to encode value of key %s", key)
+	}
+}
+
+// SubmitTx submits the body of the request as a transaction, e.g.
+// "set:key=value" or "del:key" for the key/value app
+func (s *Service) SubmitTx(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tx, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxTxSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(tx) == 0 {
+		http.Error(w, "empty transaction", http.StatusBadRequest)
+		return
+	}
+
+	switch err := s.node.SubmitTxWithResult(tx); err {
+	case nil:
+	case node.ErrTxPoolFull, node.ErrNoQuorum:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case node.ErrTooBigTx:
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	default:
+		s.logger.WithError(err).Error("Submitting transaction")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
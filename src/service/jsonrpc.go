@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/SamuelMarks/dag1/src/node"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// maxRPCSize bounds the body of a POST /rpc request, room for a batch of
+// transactions in hex
+const maxRPCSize = 4 * maxTxSize
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	// rpcServerError is returned when the node refuses a call, e.g. the
+	// transaction pool is full or the block is not known
+	rpcServerError = -32000
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// rpcMethod answers a call with its positional params
+type rpcMethod func(s *Service, params []json.RawMessage) (interface{}, error)
+
+var rpcMethods = map[string]rpcMethod{
+	"dag1_submitTx":      (*Service).rpcSubmitTx,
+	"dag1_getBlock":      (*Service).rpcGetBlock,
+	"dag1_getBlockCount": (*Service).rpcGetBlockCount,
+	"dag1_getStats":      (*Service).rpcGetStats,
+}
+
+// EnableRPCCors lets web pages of the origins, or of any origin with "*",
+// call POST /rpc
+func (s *Service) EnableRPCCors(origins []string) {
+	s.rpcOrigins = origins
+}
+
+// JSONRPC serves JSON-RPC 2.0 calls, single or batched, to submit
+// transactions and read blocks and stats
+func (s *Service) JSONRPC(w http.ResponseWriter, r *http.Request) {
+	if origin := s.rpcOrigin(r); origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Vary", "Origin")
+	}
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRPCSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var res interface{}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			res = rpcFailure(nil, rpcParseError, err.Error())
+		} else if len(batch) == 0 {
+			res = rpcFailure(nil, rpcInvalidRequest, "empty batch")
+		} else {
+			var responses []*rpcResponse
+			for _, call := range batch {
+				if resp := s.rpcCall(call); resp != nil {
+					responses = append(responses, resp)
+				}
+			}
+			if len(responses) > 0 {
+				res = responses
+			}
+		}
+	} else if resp := s.rpcCall(body); resp != nil {
+		res = resp
+	}
+
+	// only notifications
+	if res == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// rpcOrigin returns the Access-Control-Allow-Origin of a request, empty if
+// its origin is not allowed
+func (s *Service) rpcOrigin(r *http.Request) string {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return ""
+	}
+	for _, o := range s.rpcOrigins {
+		if strings.TrimSpace(o) == "*" {
+			return "*"
+		}
+		if strings.EqualFold(strings.TrimSpace(o), origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// rpcCall answers a single call, nil for a notification
+func (s *Service) rpcCall(data json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return rpcFailure(nil, rpcParseError, err.Error())
+		}
+		return rpcFailure(nil, rpcInvalidRequest, err.Error())
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcFailure(req.ID, rpcInvalidRequest, "not a JSON-RPC 2.0 request")
+	}
+
+	method, ok := rpcMethods[req.Method]
+	if !ok {
+		err := &rpcError{rpcMethodNotFound, fmt.Sprintf("method %s not found", req.Method)}
+		return rpcResult(req.ID, nil, err)
+	}
+	var params []json.RawMessage
+	if len(req.Params) > 0 && string(req.Params) != "null" {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return rpcResult(req.ID, nil, &rpcError{rpcInvalidParams, "params must be an array"})
+		}
+	}
+	res, err := method(s, params)
+	if err != nil {
+		s.logger.WithError(err).WithField("method", req.Method).Debug("JSON-RPC call failed")
+	}
+	return rpcResult(req.ID, res, err)
+}
+
+// rpcResult makes the response to a call, nil for a notification
+func rpcResult(id json.RawMessage, res interface{}, err error) *rpcResponse {
+	if id == nil {
+		return nil
+	}
+	if err == nil {
+		var data []byte
+		if data, err = json.Marshal(res); err == nil {
+			return &rpcResponse{JSONRPC: "2.0", Result: data, ID: id}
+		}
+	}
+	rerr, ok := err.(*rpcError)
+	if !ok {
+		rerr = &rpcError{rpcInternalError, err.Error()}
+	}
+	return &rpcResponse{JSONRPC: "2.0", Error: rerr, ID: id}
+}
+
+// rpcFailure makes the response to a request which is not a valid call. Its
+// id is null when not known.
+func rpcFailure(id json.RawMessage, code int, message string) *rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &rpcResponse{JSONRPC: "2.0", Error: &rpcError{code, message}, ID: id}
+}
+
+// rpcParams decodes the positional params of a call into args
+func rpcParams(params []json.RawMessage, args ...interface{}) error {
+	if len(params) != len(args) {
+		return &rpcError{rpcInvalidParams, fmt.Sprintf("expected %d params, got %d", len(args), len(params))}
+	}
+	for i, p := range params {
+		if err := json.Unmarshal(p, args[i]); err != nil {
+			return &rpcError{rpcInvalidParams, fmt.Sprintf("param %d: %v", i, err)}
+		}
+	}
+	return nil
+}
+
+// rpcSubmitTx submits a transaction given in hex, with or without 0x, and
+// returns its hash, see /txlookup
+func (s *Service) rpcSubmitTx(params []json.RawMessage) (interface{}, error) {
+	var payload string
+	if err := rpcParams(params, &payload); err != nil {
+		return nil, err
+	}
+	tx, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(payload, "0x"), "0X"))
+	if err != nil || len(tx) == 0 {
+		return nil, &rpcError{rpcInvalidParams, "the transaction must be non-empty hex"}
+	}
+	switch err := s.node.SubmitTxWithResult(tx); err {
+	case nil:
+	case node.ErrTxPoolFull, node.ErrTooBigTx, node.ErrNoQuorum:
+		return nil, &rpcError{rpcServerError, err.Error()}
+	default:
+		return nil, err
+	}
+	return poset.TxHash(tx).Hex(), nil
+}
+
+// rpcGetBlock returns the block of an index
+func (s *Service) rpcGetBlock(params []json.RawMessage) (interface{}, error) {
+	var index int64
+	if err := rpcParams(params, &index); err != nil {
+		return nil, err
+	}
+	block, err := s.node.GetBlock(index)
+	if err != nil {
+		return nil, &rpcError{rpcServerError, err.Error()}
+	}
+	return block, nil
+}
+
+// rpcGetBlockCount returns the number of blocks committed
+func (s *Service) rpcGetBlockCount(params []json.RawMessage) (interface{}, error) {
+	if err := rpcParams(params); err != nil {
+		return nil, err
+	}
+	return s.node.GetLastBlockIndex() + 1, nil
+}
+
+// rpcGetStats returns the stats of the node, as /stats does
+func (s *Service) rpcGetStats(params []json.RawMessage) (interface{}, error) {
+	if err := rpcParams(params); err != nil {
+		return nil, err
+	}
+	return s.node.GetStats(), nil
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/node"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+type testRPCResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// rpcPost posts a body to /rpc and returns the status and the body of the
+// answer
+func rpcPost(h http.Handler, body string) (int, string) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body)))
+	return w.Code, w.Body.String()
+}
+
+func rpcSingle(h http.Handler, body string, t *testing.T) testRPCResponse {
+	code, answer := rpcPost(h, body)
+	if code != http.StatusOK {
+		t.Fatalf("%s: expected %d, got %d", body, http.StatusOK, code)
+	}
+	var resp testRPCResponse
+	if err := json.Unmarshal([]byte(answer), &resp); err != nil {
+		t.Fatalf("%s: %v in %s", body, err, answer)
+	}
+	return resp
+}
+
+func TestServiceJSONRPCErrors(t *testing.T) {
+	s := &Service{logger: common.NewTestLogger(t)}
+	h := s.handler()
+
+	checks := []struct {
+		body string
+		code int
+		id   string
+	}{
+		{`{"jsonrpc":"2.0","method":`, rpcParseError, "null"},
+		{`{"jsonrpc":"2.0","method":"dag1_mine","id":1}`, rpcMethodNotFound, "1"},
+		{`{"method":"dag1_getStats","id":2}`, rpcInvalidRequest, "2"},
+		{`[]`, rpcInvalidRequest, "null"},
+		{`{"jsonrpc":"2.0","method":"dag1_getBlock","params":{"index":1},"id":"a"}`, rpcInvalidParams, `"a"`},
+		{`{"jsonrpc":"2.0","method":"dag1_getBlock","params":["x"],"id":3}`, rpcInvalidParams, "3"},
+		{`{"jsonrpc":"2.0","method":"dag1_getBlockCount","params":[1],"id":4}`, rpcInvalidParams, "4"},
+		{`{"jsonrpc":"2.0","method":"dag1_submitTx","params":["0xzz"],"id":5}`, rpcInvalidParams, "5"},
+	}
+	for _, c := range checks {
+		resp := rpcSingle(h, c.body, t)
+		if resp.Error == nil || resp.Error.Code != c.code || string(resp.ID) != c.id {
+			t.Fatalf("%s: expected error %d with id %s, got %+v", c.body, c.code, c.id, resp)
+		}
+	}
+
+	// a notification is not answered, even to an unknown method
+	if code, _ := rpcPost(h, `{"jsonrpc":"2.0","method":"dag1_mine"}`); code != http.StatusNoContent {
+		t.Fatalf("expected a notification not to be answered, got %d", code)
+	}
+
+	// each call of a malformed batch is answered
+	code, answer := rpcPost(h, `[1,{"jsonrpc":"2.0","method":"dag1_mine","id":1}]`)
+	var batch []testRPCResponse
+	if err := json.Unmarshal([]byte(answer), &batch); err != nil || code != http.StatusOK {
+		t.Fatalf("expected a batch answer, got %d %s", code, answer)
+	}
+	if len(batch) != 2 || batch[0].Error.Code != rpcInvalidRequest || batch[1].Error.Code != rpcMethodNotFound {
+		t.Fatalf("expected an invalid request and an unknown method, got %s", answer)
+	}
+
+	if code, _ := rpcPost(h, `[{"jsonrpc":"2.0",`); code != http.StatusOK {
+		t.Fatalf("expected a malformed batch to be answered, got %d", code)
+	}
+}
+
+func TestServiceJSONRPCCors(t *testing.T) {
+	s := &Service{logger: common.NewTestLogger(t)}
+	h := s.handler()
+
+	preflight := func(origin string) string {
+		req := httptest.NewRequest(http.MethodOptions, "/rpc", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("expected the preflight to be answered, got %d", w.Code)
+		}
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	if allowed := preflight("http://localhost:3000"); allowed != "" {
+		t.Fatalf("expected no origin allowed by default, got %q", allowed)
+	}
+	s.EnableRPCCors([]string{"http://localhost:3000", " http://127.0.0.1:3000"})
+	if allowed := preflight("http://127.0.0.1:3000"); allowed != "http://127.0.0.1:3000" {
+		t.Fatalf("expected the origin to be allowed, got %q", allowed)
+	}
+	if allowed := preflight("http://example.com"); allowed != "" {
+		t.Fatalf("expected another origin not to be allowed, got %q", allowed)
+	}
+	s.EnableRPCCors([]string{"*"})
+	if allowed := preflight("http://example.com"); allowed != "*" {
+		t.Fatalf("expected any origin to be allowed, got %q", allowed)
+	}
+}
+
+func TestServiceJSONRPC(t *testing.T) {
+	nodeLogger := logrus.New()
+	nodeLogger.Level = logrus.FatalLevel
+	nodes, err := node.NewNodeList(3, nodeLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, n := range nodes {
+			n.Shutdown()
+		}
+	}()
+	n := nodes.Values()[0]
+	// the transactions are refused until the node synced with a quorum
+	for deadline := time.Now().Add(10 * time.Second); !n.HasQuorum(); {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the node to sync with a quorum")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s := &Service{node: n, logger: common.NewTestLogger(t)}
+	h := s.handler()
+
+	resp := rpcSingle(h, `{"jsonrpc":"2.0","method":"dag1_getBlockCount","id":1}`, t)
+	var count int64
+	if err := json.Unmarshal(resp.Result, &count); err != nil || resp.Error != nil || count < 0 {
+		t.Fatalf("expected a block count, got %+v", resp)
+	}
+
+	code, answer := rpcPost(h, `[
+		{"jsonrpc":"2.0","method":"dag1_submitTx","params":["0x72706320747831"],"id":1},
+		{"jsonrpc":"2.0","method":"dag1_submitTx","params":["72706320747832"]},
+		{"jsonrpc":"2.0","method":"dag1_getStats","params":[],"id":2},
+		{"jsonrpc":"2.0","method":"dag1_getBlock","params":[1000000],"id":3}
+	]`)
+	if code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, code)
+	}
+	var batch []testRPCResponse
+	if err := json.Unmarshal([]byte(answer), &batch); err != nil {
+		t.Fatal(err)
+	}
+	// the notification is not answered
+	if len(batch) != 3 {
+		t.Fatalf("expected 3 answers, got %s", answer)
+	}
+
+	var txHash string
+	if err := json.Unmarshal(batch[0].Result, &txHash); err != nil || string(batch[0].ID) != "1" {
+		t.Fatalf("expected the hash of the transaction, got %+v", batch[0])
+	}
+	if txHash != poset.TxHash([]byte("rpc tx1")).Hex() {
+		t.Fatalf("expected the hash of %q, got %s", "rpc tx1", txHash)
+	}
+
+	var stats map[string]string
+	if err := json.Unmarshal(batch[1].Result, &stats); err != nil || string(batch[1].ID) != "2" {
+		t.Fatalf("expected the stats, got %+v", batch[1])
+	}
+	if _, ok := stats["last_block_index"]; !ok {
+		t.Fatalf("expected the stats of the node, got %v", stats)
+	}
+
+	if batch[2].Error == nil || batch[2].Error.Code != rpcServerError || string(batch[2].ID) != "3" {
+		t.Fatalf("expected an unknown block to be an error, got %+v", batch[2])
+	}
+}
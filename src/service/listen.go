@@ -0,0 +1,111 @@
+package service
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	// unixScheme prefixes the service addresses of unix domain sockets, e.g.
+	// unix:///var/run/dag1.sock
+	unixScheme = "unix://"
+	// socketMode lets the owner and the group of the socket use the API
+	socketMode = 0660
+)
+
+// SocketPath returns the path of the unix socket of a service address, and
+// false for TCP addresses
+func SocketPath(bindAddress string) (string, bool) {
+	if !strings.HasPrefix(bindAddress, unixScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(bindAddress, unixScheme), true
+}
+
+// EnableAuth requires the bearer token on the requests which change the
+// node, e.g. POST /tx or /admin/pause, and on every request if reads is true
+func (s *Service) EnableAuth(token string, reads bool) {
+	s.token = token
+	s.authReads = reads
+}
+
+// listen opens the listener of the bind address. A unix socket left over by
+// an earlier run is replaced.
+func (s *Service) listen() (net.Listener, error) {
+	path, ok := SocketPath(s.bindAddress)
+	if !ok {
+		return net.Listen("tcp", s.bindAddress)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("no socket path in %q", s.bindAddress)
+	}
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, socketMode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// Close stops serving, removing the unix socket
+func (s *Service) Close() error {
+	s.listenerLock.Lock()
+	defer s.listenerLock.Unlock()
+	s.closed = true
+	if s.listener == nil {
+		return nil
+	}
+	err := s.listener.Close()
+	if path, ok := SocketPath(s.bindAddress); ok {
+		if rmErr := os.Remove(path); rmErr != nil && !os.IsNotExist(rmErr) && err == nil {
+			err = rmErr
+		}
+	}
+	return err
+}
+
+// authHandler lets through the requests bearing the token, and the reads
+// unless they require it too
+func (s *Service) authHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read := r.Method == http.MethodGet || r.Method == http.MethodHead ||
+			r.Method == http.MethodOptions
+		if s.token == "" || (read && !s.authReads) || s.authorized(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="dag1"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+func (s *Service) authorized(r *http.Request) bool {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), []byte(s.token)) == 1
+}
+
+// unixConn returns true if the request came in on a unix socket, which the
+// file mode of the socket already restricts
+func unixConn(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && addr.Network() == "unix"
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/node"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// GetLatencyStats returns the per-creator histograms of the time and the
+// rounds events took to reach consensus
+func (s *Service) GetLatencyStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.node.GetLatencyStats()); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// GetUndeterminedStats returns the number and the ages, in rounds, of the
+// events whose consensus order is not determined yet
+func (s *Service) GetUndeterminedStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.node.GetUndeterminedStats()); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// GetPipeline returns, per frame waiting for consensus, the stage it is
+// stuck at and the counts of its events, clothos and Atropos
+func (s *Service) GetPipeline(w http.ResponseWriter, r *http.Request) {
+	status, err := s.node.GetPipelineStatus()
+	if err != nil {
+		s.logger.WithError(err).Error("Retrieving the pipeline status")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// GetParticipantGaps returns, by public key, the indexes missing before
+// the events known to exist of each participant
+func (s *Service) GetParticipantGaps(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.node.GetParticipantGaps()); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// GetMetrics serves the latency histograms in the Prometheus text format,
+// unless the metrics of the node are off
+func (s *Service) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	if !s.node.MetricsEnabled() {
+		http.Error(w, "metrics are off", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	stats := s.node.GetLatencyStats()
+	writeHistograms(w, "dag1_event_finality_seconds",
+		"Time from event creation, by the creator clock, to consensus.",
+		stats, func(c node.CreatorLatency) common.HistogramSnapshot { return c.Finality })
+	writeHistograms(w, "dag1_event_rounds_to_finality",
+		"Frames from event creation to consensus.",
+		stats, func(c node.CreatorLatency) common.HistogramSnapshot { return c.RoundsToFinality })
+	writeCacheMetrics(w, s.node.GetCacheStats())
+	if stats, ok := s.node.GetStoreGCStats(); ok {
+		writeStoreGCMetrics(w, stats)
+	}
+	if stats, ok := s.node.GetStoreLoadStats(); ok {
+		writeStoreLoadMetrics(w, stats)
+	}
+}
+
+// writeStoreLoadMetrics writes what loading the store from its database took
+func writeStoreLoadMetrics(w io.Writer, stats poset.StoreLoadStats) {
+	metrics := []struct {
+		name, help string
+		value      float64
+	}{
+		{"dag1_store_load_duration_seconds", "Time loading the store from its database took.", stats.Duration.Seconds()},
+		{"dag1_store_load_roots", "Roots loaded from the database.", float64(stats.Roots)},
+		{"dag1_store_load_events", "Events of the participants indexed in memory by the load.", float64(stats.Events)},
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", m.name, m.help, m.name, m.name, m.value)
+	}
+}
+
+// writeStoreGCMetrics writes the totals of the value log GCs of the store
+func writeStoreGCMetrics(w io.Writer, stats poset.ValueLogGCStats) {
+	metrics := []struct {
+		name, help string
+		value      float64
+	}{
+		{"dag1_store_gc_runs_total", "Value log GCs run.", float64(stats.Runs)},
+		{"dag1_store_gc_skipped_total", "Value log GCs skipped for a store maintenance.", float64(stats.Skipped)},
+		{"dag1_store_gc_rewritten_files_total", "Value log files rewritten.", float64(stats.Rewritten)},
+		{"dag1_store_gc_reclaimed_bytes_total", "Bytes the store shrank by on disk.", float64(stats.Reclaimed)},
+		{"dag1_store_gc_duration_seconds_total", "Time spent in value log GCs.", stats.Duration.Seconds()},
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %g\n", m.name, m.help, m.name, m.name, m.value)
+	}
+}
+
+// writeCacheMetrics writes the counters and sizes of the poset caches,
+// labelled by cache
+func writeCacheMetrics(w io.Writer, caches []poset.CacheStats) {
+	metrics := []struct {
+		name, kind, help string
+		value            func(poset.CacheStats) uint64
+	}{
+		{"dag1_poset_cache_hits_total", "counter", "Lookups found in the cache.",
+			func(c poset.CacheStats) uint64 { return c.Hits }},
+		{"dag1_poset_cache_misses_total", "counter", "Lookups not found in the cache.",
+			func(c poset.CacheStats) uint64 { return c.Misses }},
+		{"dag1_poset_cache_evictions_total", "counter", "Items evicted to make room.",
+			func(c poset.CacheStats) uint64 { return c.Evictions }},
+		{"dag1_poset_cache_resizes_total", "counter", "Times the cache was resized.",
+			func(c poset.CacheStats) uint64 { return c.Resizes }},
+		{"dag1_poset_cache_size", "gauge", "Max number of items of the cache.",
+			func(c poset.CacheStats) uint64 { return uint64(c.Size) }},
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, c := range caches {
+			fmt.Fprintf(w, "%s{cache=%q} %d\n", m.name, c.Name, m.value(c))
+		}
+	}
+}
+
+// writeHistograms writes one histogram per creator, labelled by creator
+func writeHistograms(w io.Writer, name, help string, stats map[string]node.CreatorLatency,
+	histogram func(node.CreatorLatency) common.HistogramSnapshot) {
+	creators := make([]string, 0, len(stats))
+	for creator := range stats {
+		creators = append(creators, creator)
+	}
+	sort.Strings(creators)
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, creator := range creators {
+		h := histogram(stats[creator])
+		cumulative := h.Cumulative()
+		for i, bound := range h.Bounds {
+			fmt.Fprintf(w, "%s_bucket{creator=%q,le=%q} %d\n",
+				name, creator, strconv.FormatFloat(bound, 'g', -1, 64), cumulative[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{creator=%q,le=\"+Inf\"} %d\n", name, creator, h.Count)
+		fmt.Fprintf(w, "%s_sum{creator=%q} %s\n",
+			name, creator, strconv.FormatFloat(h.Sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{creator=%q} %d\n", name, creator, h.Count)
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/node"
+)
+
+// roundReportHeader is the header of the CSV round report
+var roundReportHeader = []string{"round", "started", "committed", "latency_seconds",
+	"events", "transactions", "last_decider"}
+
+// GetRoundReport returns a row per committed consensus round, with the time
+// from its first event inserted to its block committed. It is JSON unless
+// format=csv is asked.
+func (s *Service) GetRoundReport(w http.ResponseWriter, r *http.Request) {
+	rounds := s.node.GetRoundReport()
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rounds); err != nil {
+			s.logger.Debug(err)
+		}
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		if err := writeRoundReport(w, rounds); err != nil {
+			s.logger.Debug(err)
+		}
+	default:
+		http.Error(w, "unknown format "+format, http.StatusBadRequest)
+	}
+}
+
+// writeRoundReport writes a round report as CSV, with a header
+func writeRoundReport(w io.Writer, rounds []node.RoundReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(roundReportHeader); err != nil {
+		return err
+	}
+	for _, round := range rounds {
+		record := []string{
+			strconv.FormatInt(round.Round, 10),
+			formatReportTime(round.Started),
+			formatReportTime(round.Committed),
+			strconv.FormatFloat(round.Latency, 'f', -1, 64),
+			strconv.Itoa(round.Events),
+			strconv.Itoa(round.Transactions),
+			round.LastDecider,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatReportTime formats the times of the round report, empty when unknown
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// GetFairnessReport returns the contributions of the participants to the
+// consensus, the events they created and those which became clotho and
+// Atropos, and the transactions of their events in blocks, with their shares
+// and the Gini coefficients of each count
+func (s *Service) GetFairnessReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.node.GetFairnessReport()); err != nil {
+		s.logger.Debug(err)
+	}
+}
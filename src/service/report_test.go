@@ -0,0 +1,43 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/node"
+)
+
+func TestWriteRoundReport(t *testing.T) {
+	started := time.Unix(1500000000, 0)
+	rounds := []node.RoundReport{
+		{Round: 1, Started: started, Committed: started.Add(1500 * time.Millisecond),
+			Latency: 1.5, Events: 4, Transactions: 10, LastDecider: "0xAB"},
+		// a round whose events were inserted before a restart
+		{Round: 2, Committed: started.Add(2 * time.Second), Transactions: 1},
+	}
+	var buf bytes.Buffer
+	if err := writeRoundReport(&buf, rounds); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %d records", len(records))
+	}
+	expected := [][]string{
+		roundReportHeader,
+		{"1", "2017-07-14T02:40:00Z", "2017-07-14T02:40:01.5Z", "1.5", "4", "10", "0xAB"},
+		{"2", "", "2017-07-14T02:40:02Z", "0", "0", "1", ""},
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if records[i][j] != expected[i][j] {
+				t.Fatalf("row %d: expected %v, got %v", i, expected[i], records[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Group is a set of endpoints mounted together
+type Group string
+
+const (
+	// GroupPublic are the reads of the node and the submissions every
+	// client may make, always mounted
+	GroupPublic Group = "public"
+	// GroupDebug are the /debug/ endpoints, costly on a large poset
+	GroupDebug Group = "debug"
+	// GroupApp are the /kv/ and /tx endpoints of an app embedded in the
+	// node, mounted with EnableKV
+	GroupApp Group = "app"
+	// GroupAdmin are the /admin/ endpoints changing the node, to local
+	// clients or bearers of the token only
+	GroupAdmin Group = "admin"
+)
+
+// Options controls the endpoint groups mounted and the timeouts of the
+// service. The zero value mounts the public endpoints only, with no
+// timeouts.
+type Options struct {
+	Debug bool
+	Admin bool
+	// RequestTimeout bounds the time a handler takes to answer, past which
+	// the client gets a 503 and the context of the request is canceled
+	RequestTimeout time.Duration
+	// ReadTimeout bounds the read of a request, headers and body, and
+	// WriteTimeout the time from the end of the read to the end of the
+	// response, which must leave room for RequestTimeout
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// IdleTimeout bounds the wait for the next request on a keep-alive
+	// connection
+	IdleTimeout time.Duration
+}
+
+// writeMargin is the time WriteTimeout leaves past RequestTimeout to write
+// the response
+const writeMargin = 5 * time.Second
+
+// DefaultOptions returns the options of NewService: the public and debug
+// endpoints, and timeouts loose enough for the largest blocks and rounds
+func DefaultOptions() Options {
+	opts := Options{
+		Debug:       true,
+		ReadTimeout: 10 * time.Second,
+		IdleTimeout: 2 * time.Minute,
+	}
+	opts.SetRequestTimeout(30 * time.Second)
+	return opts
+}
+
+// SetRequestTimeout sets RequestTimeout, and WriteTimeout to outlast it, or
+// neither when d is 0
+func (o *Options) SetRequestTimeout(d time.Duration) {
+	o.RequestTimeout, o.WriteTimeout = d, 0
+	if d > 0 {
+		o.WriteTimeout = d + writeMargin
+	}
+}
+
+// Route is an endpoint of the service as listed by GET /. Path ends with a
+// slash for the endpoints taking parameters in the path.
+type Route struct {
+	Path    string   `json:"path"`
+	Methods []string `json:"methods"`
+	Group   Group    `json:"group"`
+}
+
+type route struct {
+	Route
+	handler http.Handler
+}
+
+// routes returns the endpoints of every group, mounted or not
+func (s *Service) routes() []route {
+	get := []string{http.MethodGet}
+	post := []string{http.MethodPost}
+	getPost := []string{http.MethodGet, http.MethodPost}
+	r := func(path string, methods []string, group Group, h http.Handler) route {
+		return route{Route{path, methods, group}, h}
+	}
+	return []route{
+		r("/", get, GroupPublic, corsHandler(s.GetRoutes)),
+		r("/stats", get, GroupPublic, corsHandler(s.GetStats)),
+		r("/version", get, GroupPublic, corsHandler(s.GetVersion)),
+		r("/stats/latency", get, GroupPublic, corsHandler(s.GetLatencyStats)),
+		r("/stats/undetermined", get, GroupPublic, corsHandler(s.GetUndeterminedStats)),
+		r("/report/rounds", get, GroupPublic, corsHandler(s.GetRoundReport)),
+		r("/report/fairness", get, GroupPublic, corsHandler(s.GetFairnessReport)),
+		r("/metrics", get, GroupPublic, corsHandler(s.GetMetrics)),
+		r("/participants", get, GroupPublic, corsHandler(s.GetParticipantKeys)),
+		r("/participants/", get, GroupPublic, corsHandler(s.GetParticipants)),
+		r("/peers", get, GroupPublic, corsHandler(s.GetPeers)),
+		r("/event/", get, GroupPublic, corsHandler(s.GetEventBlock)),
+		r("/lasteventfrom/", get, GroupPublic, corsHandler(s.GetLastEventFrom)),
+		r("/events/", get, GroupPublic, corsHandler(s.GetKnownEvents)),
+		r("/heads", get, GroupPublic, corsHandler(s.GetHeads)),
+		r("/consensusevents/", get, GroupPublic, corsHandler(s.GetConsensusEvents)),
+		r("/round/", get, GroupPublic, corsHandler(s.GetRound)),
+		r("/lastround/", get, GroupPublic, corsHandler(s.GetLastRound)),
+		r("/roundclothos/", get, GroupPublic, corsHandler(s.GetRoundClothos)),
+		r("/roundevents/", get, GroupPublic, corsHandler(s.GetRoundEvents)),
+		r("/root/", get, GroupPublic, corsHandler(s.GetRoot)),
+		r("/block/", get, GroupPublic, corsHandler(s.GetBlock)),
+		r("/checkpoint/", get, GroupPublic, corsHandler(s.GetCheckpoint)),
+		r("/frame/", get, GroupPublic, corsHandler(s.GetFrameOrdering)),
+		r("/account/", get, GroupPublic, corsHandler(s.GetAccount)),
+		r("/txlookup/", get, GroupPublic, corsHandler(s.LookupTx)),
+		r("/rpc", post, GroupPublic, http.HandlerFunc(s.JSONRPC)),
+		r("/verify", post, GroupPublic, http.HandlerFunc(s.Verify)),
+		r("/healthz", get, GroupPublic, corsHandler(s.GetHealth)),
+		r("/readyz", get, GroupPublic, corsHandler(s.GetReady)),
+
+		r("/debug/pipeline", get, GroupDebug, corsHandler(s.GetPipeline)),
+		r("/debug/gaps", get, GroupDebug, corsHandler(s.GetParticipantGaps)),
+
+		r("/kv/", get, GroupApp, corsHandler(s.GetKV)),
+		r("/tx", post, GroupApp, http.HandlerFunc(s.SubmitTx)),
+
+		r("/admin/pause", post, GroupAdmin, s.adminHandler(http.MethodPost, s.PauseNode)),
+		r("/admin/resume", post, GroupAdmin, s.adminHandler(http.MethodPost, s.ResumeNode)),
+		r("/admin/peers", get, GroupAdmin, s.adminHandler(http.MethodGet, s.GetPeerReputations)),
+		r("/admin/peers/", post, GroupAdmin, s.adminHandler(http.MethodPost, s.AdminPeer)),
+		r("/admin/verify", post, GroupAdmin, s.adminHandler(http.MethodPost, s.VerifyNode)),
+		r("/admin/tick", post, GroupAdmin, s.adminHandler(http.MethodPost, s.TickNode)),
+		r("/admin/fastforward", post, GroupAdmin, s.adminHandler(http.MethodPost, s.FastForward)),
+		r("/admin/fastforward/", get, GroupAdmin, s.adminHandler(http.MethodGet, s.GetFastForward)),
+		r("/admin/store/gc", post, GroupAdmin, s.adminHandler(http.MethodPost, s.StoreGC)),
+		r("/admin/config", getPost, GroupAdmin, byMethod(map[string]http.Handler{
+			http.MethodGet:  s.adminHandler(http.MethodGet, s.GetConfig),
+			http.MethodPost: s.adminHandler(http.MethodPost, s.SetConfig),
+		})),
+	}
+}
+
+// byMethod routes the requests to the handler of their method
+func byMethod(handlers map[string]http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := handlers[r.Method]
+		if !ok {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// mounted returns true if the endpoints of the group are served
+func (s *Service) mounted(group Group) bool {
+	switch group {
+	case GroupDebug:
+		return s.opts.Debug
+	case GroupApp:
+		return s.kv != nil
+	case GroupAdmin:
+		return s.opts.Admin
+	}
+	return true
+}
+
+// Routes returns the endpoints served, sorted by path
+func (s *Service) Routes() []Route {
+	var res []Route
+	for _, r := range s.routes() {
+		if s.mounted(r.Group) {
+			res = append(res, r.Route)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res
+}
+
+// GetRoutes lists the endpoints served at GET /, see Route for the fields
+func (s *Service) GetRoutes(w http.ResponseWriter, r *http.Request) {
+	// the pattern "/" catches the paths of no other endpoint
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.Routes()); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// handler routes the API requests to the endpoints mounted, behind the token
+// check if any and the middlewares
+func (s *Service) handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, r := range s.routes() {
+		if s.mounted(r.Group) {
+			mux.Handle(r.Path, r.handler)
+		}
+	}
+	return s.middlewares(s.authHandler(mux))
+}
+
+// middlewares wraps h in the logging of the requests, the request timeout
+// and the recovery from the panics of the handlers, outermost first. The
+// recovery runs in the goroutine of the handler, for its stack.
+func (s *Service) middlewares(h http.Handler) http.Handler {
+	h = s.recoverHandler(h)
+	if s.opts.RequestTimeout > 0 {
+		h = http.TimeoutHandler(h, s.opts.RequestTimeout, "request timed out")
+	}
+	return s.logHandler(h)
+}
+
+// statusWriter records the status and the size of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// logHandler logs every request with its status and latency
+func (s *Service) logHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		s.logger.WithFields(logrus.Fields{
+			"method":  r.Method,
+			"path":    r.URL.Path,
+			"remote":  r.RemoteAddr,
+			"status":  sw.status,
+			"size":    sw.size,
+			"latency": time.Since(start),
+		}).Debug("Request served")
+	})
+}
+
+// recoverHandler answers a 500 to the requests whose handler panicked, with
+// the panic logged, instead of dropping the connection. The panics of
+// http.ErrAbortHandler still abort the response.
+func (s *Service) recoverHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			s.logger.WithFields(logrus.Fields{
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"panic":  p,
+			}).Errorf("Handler panicked\n%s", debug.Stack())
+			if sw.status == 0 {
+				http.Error(sw, "internal error", http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(sw, r)
+	})
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+)
+
+type kvStub map[string]string
+
+func (kv kvStub) Query(key string) (string, bool) {
+	v, ok := kv[key]
+	return v, ok
+}
+
+func TestServiceRecover(t *testing.T) {
+	s := &Service{logger: common.NewTestLogger(t), opts: DefaultOptions()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]int
+		m["boom"]++
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(s.middlewares(mux))
+	defer server.Close()
+
+	// the panic is answered, and the next requests are served
+	for _, c := range []struct {
+		path   string
+		status int
+	}{
+		{"/panic", http.StatusInternalServerError},
+		{"/ok", http.StatusNoContent},
+		{"/panic", http.StatusInternalServerError},
+	} {
+		resp, err := http.Get(server.URL + c.path)
+		if err != nil {
+			t.Fatalf("%s: %v", c.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Fatalf("%s: expected %d, got %d", c.path, c.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestServiceTimeout(t *testing.T) {
+	s := &Service{logger: common.NewTestLogger(t)}
+	s.opts.SetRequestTimeout(50 * time.Millisecond)
+	canceled := make(chan error, 1)
+	slow := s.middlewares(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			canceled <- r.Context().Err()
+		case <-time.After(5 * time.Second):
+			canceled <- nil
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	slow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the request cut at the timeout, took %s", elapsed)
+	}
+	if err := <-canceled; err != context.DeadlineExceeded {
+		t.Fatalf("expected the context of the handler canceled, got %v", err)
+	}
+
+	// the writes outlast the handlers
+	if s.opts.WriteTimeout <= s.opts.RequestTimeout {
+		t.Fatalf("expected a write timeout beyond %s, got %s", s.opts.RequestTimeout, s.opts.WriteTimeout)
+	}
+	s.opts.SetRequestTimeout(0)
+	if s.opts.WriteTimeout != 0 {
+		t.Fatalf("expected no write timeout, got %s", s.opts.WriteTimeout)
+	}
+}
+
+func TestServiceRouteGating(t *testing.T) {
+	serve := func(s *Service, path string) int {
+		w := httptest.NewRecorder()
+		s.handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+	listed := func(s *Service) map[string]Group {
+		w := httptest.NewRecorder()
+		s.handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /: expected %d, got %d", http.StatusOK, w.Code)
+		}
+		var routes []Route
+		if err := json.NewDecoder(w.Body).Decode(&routes); err != nil {
+			t.Fatal(err)
+		}
+		res := make(map[string]Group)
+		for _, r := range routes {
+			res[r.Path] = r.Group
+		}
+		return res
+	}
+
+	// the zero options mount the public endpoints only
+	s := &Service{logger: common.NewTestLogger(t)}
+	routes := listed(s)
+	for _, path := range []string{"/", "/stats", "/block/", "/healthz", "/rpc"} {
+		if routes[path] != GroupPublic {
+			t.Fatalf("expected %s listed as public, got %v", path, routes)
+		}
+	}
+	for _, path := range []string{"/debug/pipeline", "/kv/", "/admin/pause"} {
+		if _, ok := routes[path]; ok {
+			t.Fatalf("expected %s not listed", path)
+		}
+	}
+	for _, path := range []string{"/debug/gaps", "/kv/a", "/admin/peers", "/nowhere"} {
+		if status := serve(s, path); status != http.StatusNotFound {
+			t.Fatalf("%s: expected %d, got %d", path, http.StatusNotFound, status)
+		}
+	}
+	if status := serve(s, "/healthz"); status != http.StatusOK {
+		t.Fatalf("expected /healthz served, got %d", status)
+	}
+
+	// each group is mounted by its option
+	s = &Service{logger: common.NewTestLogger(t), opts: Options{Debug: true}}
+	s.EnableAdmin()
+	s.EnableKV(kvStub{"a": "1"})
+	routes = listed(s)
+	for path, group := range map[string]Group{
+		"/debug/pipeline":     GroupDebug,
+		"/debug/gaps":         GroupDebug,
+		"/kv/":                GroupApp,
+		"/tx":                 GroupApp,
+		"/admin/pause":        GroupAdmin,
+		"/admin/fastforward/": GroupAdmin,
+		"/admin/config":       GroupAdmin,
+	} {
+		if routes[path] != group {
+			t.Fatalf("expected %s listed in %s, got %q", path, group, routes[path])
+		}
+	}
+	if status := serve(s, "/kv/a"); status != http.StatusOK {
+		t.Fatalf("expected /kv/a served, got %d", status)
+	}
+	if status := serve(s, "/admin/pause"); status != http.StatusMethodNotAllowed {
+		t.Fatalf("expected /admin/pause mounted, got %d", status)
+	}
+}
@@ -2,11 +2,20 @@ package service
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/log"
 	"github.com/SamuelMarks/dag1/src/node"
+	"github.com/SamuelMarks/dag1/src/peers"
 	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/version"
 	"github.com/sirupsen/logrus"
 )
 
@@ -16,39 +25,70 @@ type Service struct {
 	node        *node.Node
 	graph       *node.Graph
 	logger      *logrus.Logger
+	kv          KVQuerier
+	token       string
+	authReads   bool
+	rpcOrigins  []string
+	opts        Options
+
+	listenerLock sync.Mutex
+	listener     net.Listener
+	closed       bool
 }
 
-// NewService creates a new http API service
+// NewService creates a new http API service with the DefaultOptions
 func NewService(bindAddress string, n *node.Node, logger *logrus.Logger) *Service {
+	return NewServiceWithOptions(bindAddress, n, DefaultOptions(), logger)
+}
+
+// NewServiceWithOptions creates a new http API service mounting the endpoint
+// groups of opts
+func NewServiceWithOptions(bindAddress string, n *node.Node, opts Options, logger *logrus.Logger) *Service {
 	service := Service{
 		bindAddress: bindAddress,
 		node:        n,
 		graph:       node.NewGraph(n),
-		logger:      logger,
+		logger:      dag1_log.ForModule(logger, "service"),
+		opts:        opts,
 	}
 
 	return &service
 }
 
-// Serve serves the API
+// EnableAdmin serves the /admin/ endpoints, to local clients only
+func (s *Service) EnableAdmin() {
+	s.opts.Admin = true
+}
+
+// Serve serves the API until Close
 func (s *Service) Serve() {
 	s.logger.WithField("bind_address", s.bindAddress).Debug("Service serving")
-	mux := http.NewServeMux()
-	mux.Handle("/stats", corsHandler(s.GetStats))
-	mux.Handle("/participants/", corsHandler(s.GetParticipants))
-	mux.Handle("/event/", corsHandler(s.GetEventBlock))
-	mux.Handle("/lasteventfrom/", corsHandler(s.GetLastEventFrom))
-	mux.Handle("/events/", corsHandler(s.GetKnownEvents))
-	mux.Handle("/consensusevents/", corsHandler(s.GetConsensusEvents))
-	mux.Handle("/round/", corsHandler(s.GetRound))
-	mux.Handle("/lastround/", corsHandler(s.GetLastRound))
-	mux.Handle("/roundclothos/", corsHandler(s.GetRoundClothos))
-	mux.Handle("/roundevents/", corsHandler(s.GetRoundEvents))
-	mux.Handle("/root/", corsHandler(s.GetRoot))
-	mux.Handle("/block/", corsHandler(s.GetBlock))
-	err := http.ListenAndServe(s.bindAddress, mux)
+	ln, err := s.listen()
 	if err != nil {
 		s.logger.WithField("error", err).Error("Service failed")
+		return
+	}
+	s.listenerLock.Lock()
+	if s.closed {
+		s.listenerLock.Unlock()
+		ln.Close()
+		return
+	}
+	s.listener = ln
+	s.listenerLock.Unlock()
+
+	server := &http.Server{
+		Handler:      s.handler(),
+		ReadTimeout:  s.opts.ReadTimeout,
+		WriteTimeout: s.opts.WriteTimeout,
+		IdleTimeout:  s.opts.IdleTimeout,
+	}
+	err = server.Serve(ln)
+	s.listenerLock.Lock()
+	closed := s.closed
+	s.listenerLock.Unlock()
+	if !closed {
+		s.logger.WithField("error", err).Error("Service failed")
 	}
 }
 
@@ -73,6 +113,286 @@ func corsHandler(h http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// adminHandler only lets requests of the method through from the loopback
+// interface or the unix socket, or from anywhere bearing the token. Reads
+// need the token too when there is one.
+func (s *Service) adminHandler(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if s.token != "" {
+			if !s.authorized(r) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="dag1"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+		if unixConn(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+			s.logger.WithField("remote", r.RemoteAddr).Warn("Refused admin request")
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}
+}
+
+// PauseNode stops the node from gossiping until ResumeNode is called
+func (s *Service) PauseNode(w http.ResponseWriter, r *http.Request) {
+	if err := s.node.Pause(); err != nil {
+		s.logger.WithError(err).Errorf("Pausing node")
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResumeNode resumes a paused node
+func (s *Service) ResumeNode(w http.ResponseWriter, r *http.Request) {
+	s.node.Resume()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyNode checks the cached rounds and lamport timestamps of the node
+// against its store, repairs them, and returns the inconsistencies found
+func (s *Service) VerifyNode(w http.ResponseWriter, r *http.Request) {
+	found, err := s.node.VerifyCaches()
+	if err != nil {
+		s.logger.WithError(err).Errorf("Verifying node")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if found == nil {
+		found = []poset.Inconsistency{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(found); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// TickNode creates an empty event of the node, for a consensus stalled for
+// lack of transactions, and returns its hash
+func (s *Service) TickNode(w http.ResponseWriter, r *http.Request) {
+	hash, err := s.node.CreateEmptyEvent()
+	switch {
+	case poset.IsEventCap(err) || err == node.ErrNodePaused || err == node.ErrObserverEvent:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		s.logger.WithError(err).Errorf("Creating an empty event")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	res := struct {
+		Event string `json:"event"`
+	}{hash.String()}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// FastForward starts fast forwarding the node from the peer given as
+// {"peer":"host:port"} and returns the job, to follow with GetFastForward
+func (s *Service) FastForward(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Peer string `json:"peer"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Peer == "" {
+		if err == nil {
+			err = fmt.Errorf("no peer")
+		}
+		s.logger.WithError(err).Errorf("Parsing fast forward request")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job, err := s.node.FastForwardFrom(req.Peer)
+	switch {
+	case err == node.ErrResetInProgress:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		s.logger.WithError(err).Errorf("Fast forwarding from %s", req.Peer)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// StoreGC runs the value log GC of the store and returns what it reclaimed
+func (s *Service) StoreGC(w http.ResponseWriter, r *http.Request) {
+	res, err := s.node.RunStoreGC()
+	switch {
+	case err == poset.ErrValueLogGCUnsupported:
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case err != nil:
+		s.logger.WithError(err).Errorf("Running the store GC")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// GetFastForward returns a job of FastForward by id, with its stage
+func (s *Service) GetFastForward(w http.ResponseWriter, r *http.Request) {
+	param := r.URL.Path[len("/admin/fastforward/"):]
+	job, ok := s.node.GetFastForward(param)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// GetPeerReputations returns the reputation of the participants, as saved in
+// the store, with whether they are banned
+func (s *Service) GetPeerReputations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.node.GetPeerReputations()); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// AdminPeer bans a peer with POST /admin/peers/{id}/ban?duration=1h, or lifts
+// its ban with POST /admin/peers/{id}/unban
+func (s *Service) AdminPeer(w http.ResponseWriter, r *http.Request) {
+	param := strings.Split(r.URL.Path[len("/admin/peers/"):], "/")
+	if len(param) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.ParseUint(param[0], 10, 64)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Parsing peer id %s", param[0])
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch param[1] {
+	case "ban":
+		duration, err := time.ParseDuration(r.FormValue("duration"))
+		if err != nil {
+			s.logger.WithError(err).Errorf("Parsing ban duration %s", r.FormValue("duration"))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = s.node.BanPeer(id, duration)
+	case "unban":
+		err = s.node.UnbanPeer(id)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Errorf("Changing the ban of peer %d", id)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetConfig returns the settings of the node which SetConfig changes
+func (s *Service) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.node.GetRuntimeConfig().Settings()); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// SetConfig changes the settings of the running node given as a JSON object
+// such as {"log":"info,node=debug","heartbeat":"200ms"}, all or none, see
+// node.Reconfigure. It returns the changes and the settings.
+func (s *Service) SetConfig(w http.ResponseWriter, r *http.Request) {
+	var req map[string]interface{}
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		s.logger.WithError(err).Errorf("Parsing config request")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	settings := make(map[string]string, len(req))
+	for key, value := range req {
+		switch v := value.(type) {
+		case string:
+			settings[key] = v
+		case json.Number:
+			settings[key] = v.String()
+		case bool:
+			settings[key] = strconv.FormatBool(v)
+		default:
+			http.Error(w, fmt.Sprintf("%s: expected a string, number or boolean", key), http.StatusBadRequest)
+			return
+		}
+	}
+
+	changes, err := s.node.Reconfigure(settings)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Changing the config")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := struct {
+		Changes []node.RuntimeChange `json:"changes"`
+		Config  map[string]string    `json:"config"`
+	}{changes, s.node.GetRuntimeConfig().Settings()}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// GetHealth answers as long as the service is serving
+func (s *Service) GetHealth(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetReady answers 200 when the node is ready and 503 with the reason
+// otherwise
+func (s *Service) GetReady(w http.ResponseWriter, r *http.Request) {
+	resp := struct {
+		Ready  bool   `json:"ready"`
+		Reason string `json:"reason,omitempty"`
+	}{Ready: true}
+	status := http.StatusOK
+	if err := s.node.Ready(); err != nil {
+		resp.Ready, resp.Reason = false, err.Error()
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
 // GetStats returns all the node processing stats
 func (s *Service) GetStats(w http.ResponseWriter, r *http.Request) {
 	s.logger.Debug("Stats")
@@ -85,6 +405,15 @@ func (s *Service) GetStats(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetVersion returns the release and the build of the node, see version.Info
+// for the fields
+func (s *Service) GetVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(version.Get()); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
 // GetParticipants returns all the known participants
 func (s *Service) GetParticipants(w http.ResponseWriter, r *http.Request) {
 	participants, err := s.node.GetParticipants()
@@ -99,15 +428,23 @@ func (s *Service) GetParticipants(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetPeers returns the snapshot of the participants the peer selectors use,
+// see peers.PeerSnapshot for the fields
+func (s *Service) GetPeers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.node.GetPeersSnapshot()); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
 // GetEventBlock returns a specific event block by id
 func (s *Service) GetEventBlock(w http.ResponseWriter, r *http.Request) {
 	param := r.URL.Path[len("/event/"):]
 
-	var hash poset.EventHash
-	err := hash.Parse(param)
+	hash, err := poset.ParseEventHash(param)
 	if err != nil {
-		s.logger.WithError(err).Errorf("Parsing event hash %s", param)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.logger.WithError(err).Debugf("Parsing event hash %s", param)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -150,6 +487,15 @@ func (s *Service) GetKnownEvents(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetHeads returns the head of the node, and the last known event of each
+// other participant with the head it advertised last
+func (s *Service) GetHeads(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.node.Heads()); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
 // GetConsensusEvents returns all the events that have reached consensus
 func (s *Service) GetConsensusEvents(w http.ResponseWriter, r *http.Request) {
 	consensusEvents := s.node.GetConsensusEvents()
@@ -267,3 +613,211 @@ func (s *Service) GetBlock(w http.ResponseWriter, r *http.Request) {
 		s.logger.WithError(err).Errorf("Failed to encode block: %v", block)
 	}
 }
+
+// GetAccount returns the PoS balance of an address, given in hex or as the
+// public key of a peer, in the state of the last block, or with the proof of
+// it at /account/{addr}/proof?frame=F
+func (s *Service) GetAccount(w http.ResponseWriter, r *http.Request) {
+	param := r.URL.Path[len("/account/"):]
+	if strings.HasSuffix(param, "/proof") {
+		s.GetAccountProof(w, r)
+		return
+	}
+	address, err := peers.ParseAddress(param)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Parsing address parameter %s", param)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err := s.node.GetAccount(address)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Retrieving account %s", address.Hex())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(account); err != nil {
+		s.logger.WithError(err).Errorf("Failed to encode account: %v", account)
+	}
+}
+
+// GetAccountProof returns the PoS balance of an address in the state of a
+// frame with all a light client needs to check it: the trie nodes proving it
+// against the state hash of the frame, the frame in protobuf, whose Keccak256
+// hash is the frame hash of the block, and the block with its signatures
+func (s *Service) GetAccountProof(w http.ResponseWriter, r *http.Request) {
+	param := strings.TrimSuffix(r.URL.Path[len("/account/"):], "/proof")
+	address, err := peers.ParseAddress(param)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Parsing address parameter %s", param)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	frame, err := strconv.ParseInt(r.URL.Query().Get("frame"), 10, 64)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Parsing frame parameter %s", r.URL.Query().Get("frame"))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	proof, err := s.node.GetAccountProof(address, frame)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Proving account %s at frame %d", address.Hex(), frame)
+		status := http.StatusInternalServerError
+		if common.Is(err, common.KeyNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	frameBytes, err := proof.Frame.ProtoMarshal()
+	if err != nil {
+		s.logger.WithError(err).Errorf("Marshalling frame %d", frame)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	nodes := make([]string, len(proof.Proof))
+	for i, node := range proof.Proof {
+		nodes[i] = fmt.Sprintf("0x%X", node)
+	}
+	type blockRef struct {
+		Index      int64                  `json:"index"`
+		FrameHash  string                 `json:"frame_hash"`
+		Body       *poset.BlockBody       `json:"body"`
+		Signatures []poset.BlockSignature `json:"signatures"`
+	}
+	resp := struct {
+		poset.Account
+		Proof []string `json:"proof"`
+		Frame string   `json:"frame"`
+		Block blockRef `json:"block"`
+	}{
+		Account: proof.Account,
+		Proof:   nodes,
+		Frame:   fmt.Sprintf("0x%X", frameBytes),
+		Block: blockRef{
+			Index:      proof.Block.Index(),
+			FrameHash:  fmt.Sprintf("0x%X", proof.Block.FrameHash),
+			Body:       proof.Block.Body,
+			Signatures: proof.Block.GetBlockSignatures(),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.WithError(err).Errorf("Failed to encode account proof: %v", resp)
+	}
+}
+
+// LookupTx returns the event and block of a transaction by the hash of its
+// content, when the store indexes the transactions
+func (s *Service) LookupTx(w http.ResponseWriter, r *http.Request) {
+	param := r.URL.Path[len("/txlookup/"):]
+	var txHash common.Hash
+	if err := txHash.UnmarshalText([]byte(param)); err != nil {
+		s.logger.WithError(err).Errorf("Parsing tx hash parameter %s", param)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	loc, err := s.node.LookupTx(txHash)
+	switch {
+	case err == poset.ErrNoTxIndex:
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case common.Is(err, common.KeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		s.logger.WithError(err).Errorf("Looking up tx %s", txHash.Hex())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(loc); err != nil {
+		s.logger.WithError(err).Errorf("Failed to encode tx location: %v", loc)
+	}
+}
+
+// GetCheckpoint returns the signed checkpoint of a frame, with whether it has
+// enough signatures to prove the frame final
+func (s *Service) GetCheckpoint(w http.ResponseWriter, r *http.Request) {
+	param := r.URL.Path[len("/checkpoint/"):]
+	frame, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Parsing frame parameter %s", param)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	checkpoint, provable, err := s.node.GetCheckpoint(frame)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Retrieving checkpoint %d", frame)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resp := struct {
+		Frame      int64                  `json:"frame"`
+		FrameHash  string                 `json:"frame_hash"`
+		StateHash  string                 `json:"state_hash"`
+		Provable   bool                   `json:"provable"`
+		Signatures []poset.BlockSignature `json:"signatures"`
+	}{
+		Frame:      checkpoint.Frame,
+		FrameHash:  fmt.Sprintf("0x%X", checkpoint.FrameHash),
+		StateHash:  fmt.Sprintf("0x%X", checkpoint.StateHash),
+		Provable:   provable,
+		Signatures: checkpoint.GetSignatures(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.WithError(err).Errorf("Failed to encode checkpoint: %v", checkpoint)
+	}
+}
+
+// GetFrameOrdering serves the events of a final frame in their final order at
+// /frame/{round}/events, or the pipeline status of the frame with a 409 when
+// it is not final yet
+func (s *Service) GetFrameOrdering(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/frame/"), "/")
+	if len(parts) != 2 || parts[1] != "events" {
+		http.NotFound(w, r)
+		return
+	}
+	frame, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Parsing frame parameter %s", parts[0])
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	refs, err := s.node.GetFrameOrdering(frame)
+	if notFinal, ok := err.(*poset.FrameNotFinalError); ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		res := struct {
+			Error  string            `json:"error"`
+			Status poset.FrameStatus `json:"status"`
+		}{notFinal.Error(), notFinal.Status}
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			s.logger.Debug(err)
+		}
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Errorf("Retrieving the order of frame %d", frame)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(refs); err != nil {
+		s.logger.Debug(err)
+	}
+}
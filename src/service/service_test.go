@@ -0,0 +1,448 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/node"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/version"
+	"github.com/sirupsen/logrus"
+)
+
+const testToken = "s3cr3t"
+
+// serveTest serves the API of a service without a node on bindAddress and
+// returns the base URL and the client of the service
+func serveTest(bindAddress string, t *testing.T) (*Service, string, *http.Client) {
+	s := &Service{
+		bindAddress: bindAddress,
+		logger:      common.NewTestLogger(t),
+	}
+	s.EnableAuth(testToken, false)
+
+	ln, err := s.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.listener = ln
+	go http.Serve(ln, s.handler())
+
+	path, ok := SocketPath(bindAddress)
+	if !ok {
+		return s, "http://" + ln.Addr().String(), http.DefaultClient
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	return s, "http://unix", client
+}
+
+func request(client *http.Client, method, url, token string, t *testing.T) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func testAuth(bindAddress string, t *testing.T) {
+	s, url, client := serveTest(bindAddress, t)
+	defer s.Close()
+
+	checks := []struct {
+		method string
+		token  string
+		status int
+	}{
+		{http.MethodGet, "", http.StatusOK},
+		{http.MethodPost, "", http.StatusUnauthorized},
+		{http.MethodPost, "wrong", http.StatusUnauthorized},
+		{http.MethodPost, testToken, http.StatusOK},
+	}
+	for _, c := range checks {
+		if status := request(client, c.method, url+"/healthz", c.token, t); status != c.status {
+			t.Fatalf("%s with token %q: expected %d, got %d", c.method, c.token, c.status, status)
+		}
+	}
+
+	s.authReads = true
+	if status := request(client, http.MethodGet, url+"/healthz", "", t); status != http.StatusUnauthorized {
+		t.Fatalf("expected GET without the token to be refused, got %d", status)
+	}
+	if status := request(client, http.MethodGet, url+"/healthz", testToken, t); status != http.StatusOK {
+		t.Fatalf("expected GET with the token to be served, got %d", status)
+	}
+}
+
+func TestServiceAuthTCP(t *testing.T) {
+	testAuth("127.0.0.1:0", t)
+}
+
+func TestServiceAuthUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dag1.sock")
+
+	testAuth(unixScheme+path, t)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the socket to be removed on Close, got %v", err)
+	}
+}
+
+func TestServiceUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dag1.sock")
+
+	// a socket left over by a crashed run is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	s, _, _ := serveTest(unixScheme+path, t)
+	defer s.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != socketMode {
+		t.Fatalf("expected the socket mode %o, got %o", socketMode, mode)
+	}
+
+	// other files are not
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	other := &Service{bindAddress: unixScheme + file}
+	if _, err := other.listen(); err == nil {
+		t.Fatal("expected a regular file not to be replaced by the socket")
+	}
+}
+
+func TestServiceAdminPeersAuth(t *testing.T) {
+	s := &Service{logger: common.NewTestLogger(t)}
+	s.EnableAdmin()
+	s.EnableAuth(testToken, false)
+	h := s.handler()
+
+	checks := []struct {
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		// the reputation of the peers is not a public read
+		{http.MethodGet, "/admin/peers", "", http.StatusUnauthorized},
+		{http.MethodPost, "/admin/peers", testToken, http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/peers/1/ban?duration=1h", "", http.StatusUnauthorized},
+		{http.MethodPost, "/admin/peers/x/ban?duration=1h", testToken, http.StatusBadRequest},
+		{http.MethodPost, "/admin/peers/1/ban?duration=x", testToken, http.StatusBadRequest},
+		{http.MethodPost, "/admin/peers/1/kick", testToken, http.StatusNotFound},
+		{http.MethodGet, "/admin/verify", testToken, http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/verify", "", http.StatusUnauthorized},
+		{http.MethodGet, "/admin/tick", testToken, http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/tick", "", http.StatusUnauthorized},
+		{http.MethodGet, "/admin/fastforward", testToken, http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/fastforward", "", http.StatusUnauthorized},
+		{http.MethodPost, "/admin/fastforward", testToken, http.StatusBadRequest},
+		{http.MethodGet, "/admin/fastforward/1", "", http.StatusUnauthorized},
+		{http.MethodPost, "/admin/fastforward/1", testToken, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/admin/store/gc", testToken, http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/store/gc", "", http.StatusUnauthorized},
+	}
+	for _, c := range checks {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != c.status {
+			t.Fatalf("%s %s with token %q: expected %d, got %d",
+				c.method, c.path, c.token, c.status, w.Code)
+		}
+	}
+}
+
+func TestServiceLookupTx(t *testing.T) {
+	nodeLogger := logrus.New()
+	nodeLogger.Level = logrus.FatalLevel
+	nodes, err := node.NewNodeList(3, nodeLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, n := range nodes {
+			n.Shutdown()
+		}
+	}()
+	n := nodes.Values()[0]
+	s := &Service{node: n, logger: common.NewTestLogger(t)}
+	h := s.handler()
+
+	lookup := func(path string) (int, poset.TxLocation) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var loc poset.TxLocation
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&loc); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, loc
+	}
+
+	txs := [][]byte{[]byte("lookup tx 1"), []byte("lookup tx 2")}
+	for _, tx := range txs {
+		if err := n.PushTx(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tx := range txs {
+		txHash := poset.TxHash(tx)
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			code, loc := lookup("/txlookup/" + txHash.Hex())
+			if code == http.StatusOK {
+				if loc.Tx != txHash {
+					t.Fatalf("expected the location of %s, got %+v", txHash.Hex(), loc)
+				}
+				event, err := n.GetEventBlock(poset.EventHash(loc.Event))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !containsTx(event.Transactions(), tx) {
+					t.Fatalf("expected event %s to carry %q", loc.Event.Hex(), tx)
+				}
+				break
+			}
+			if code != http.StatusNotFound || time.Now().After(deadline) {
+				t.Fatalf("looking up %q: got %d", tx, code)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	if code, _ := lookup("/txlookup/" + poset.TxHash([]byte("unknown")).Hex()); code != http.StatusNotFound {
+		t.Fatalf("expected an unknown tx not to be found, got %d", code)
+	}
+	if code, _ := lookup("/txlookup/0x12"); code != http.StatusBadRequest {
+		t.Fatalf("expected a bad hash to be refused, got %d", code)
+	}
+}
+
+func TestServiceFrameOrdering(t *testing.T) {
+	nodeLogger := logrus.New()
+	nodeLogger.Level = logrus.FatalLevel
+	nodes, err := node.NewNodeList(3, nodeLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, n := range nodes {
+			n.Shutdown()
+		}
+	}()
+	s := &Service{node: nodes.Values()[0], logger: common.NewTestLogger(t)}
+	h := s.handler()
+
+	checks := []struct {
+		path   string
+		status int
+	}{
+		{"/frame/x/events", http.StatusBadRequest},
+		{"/frame/5", http.StatusNotFound},
+		{"/frame/5/roots", http.StatusNotFound},
+		// no frame is final far ahead of the consensus
+		{"/frame/1000/events", http.StatusConflict},
+	}
+	for _, c := range checks {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if w.Code != c.status {
+			t.Fatalf("%s: expected %d, got %d", c.path, c.status, w.Code)
+		}
+		if w.Code != http.StatusConflict {
+			continue
+		}
+		var res struct {
+			Status poset.FrameStatus `json:"status"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Status.Frame != 1000 || res.Status.Final {
+			t.Fatalf("expected the status of the frame 1000, got %+v", res.Status)
+		}
+	}
+}
+
+func containsTx(txs [][]byte, tx []byte) bool {
+	for _, t := range txs {
+		if bytes.Equal(t, tx) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestServiceVersion(t *testing.T) {
+	gitCommit, buildDate := version.GitCommit, version.BuildDate
+	defer func() {
+		version.GitCommit, version.BuildDate = gitCommit, buildDate
+	}()
+	// as set by the ldflags
+	version.GitCommit = "0123456789abcdef0123456789abcdef01234567"
+	version.BuildDate = "2018-11-05T10:00:00Z"
+
+	s, url, client := serveTest("127.0.0.1:0", t)
+	defer s.Close()
+	resp, err := client.Get(url + "/version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var info version.Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info != version.Get() {
+		t.Fatalf("expected %+v, got %+v", version.Get(), info)
+	}
+	if info.GitCommit != version.GitCommit || info.BuildDate != version.BuildDate {
+		t.Fatalf("expected the injected build, got %+v", info)
+	}
+}
+
+func TestServiceConfig(t *testing.T) {
+	nodeLogger := logrus.New()
+	nodeLogger.Level = logrus.FatalLevel
+	nodes, err := node.NewNodeList(1, nodeLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, n := range nodes {
+			n.Shutdown()
+		}
+	}()
+	s := &Service{node: nodes.Values()[0], logger: common.NewTestLogger(t)}
+	s.EnableAdmin()
+	s.EnableAuth(testToken, false)
+	h := s.handler()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+testToken)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	get := func() map[string]string {
+		w := do(http.MethodGet, "/admin/config", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /admin/config: expected %d, got %d", http.StatusOK, w.Code)
+		}
+		var settings map[string]string
+		if err := json.NewDecoder(w.Body).Decode(&settings); err != nil {
+			t.Fatal(err)
+		}
+		return settings
+	}
+
+	before := get()
+	if before[node.SettingLog] != "fatal" || before[node.SettingMetrics] != "true" {
+		t.Fatalf("expected the settings the node started with, got %v", before)
+	}
+
+	// refused settings change nothing
+	for _, c := range []struct {
+		body   string
+		reason string
+	}{
+		{`{"heartbeat": "50ms", "max-block-bytes": 1024}`, "consensus-params-file"},
+		{`{"heartbeat": "50ms", "datadir": "/tmp"}`, "not changeable"},
+		{`{"heartbeat": "50ms", "sync-limit": 0}`, "sync-limit"},
+		{`{"heartbeat": ["50ms"]}`, "heartbeat"},
+		{`heartbeat`, "invalid"},
+	} {
+		w := do(http.MethodPost, "/admin/config", c.body)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), c.reason) {
+			t.Fatalf("%s: expected %d mentioning %q, got %d %q", c.body, http.StatusBadRequest, c.reason, w.Code, w.Body.String())
+		}
+	}
+	if after := get(); after[node.SettingHeartbeat] != before[node.SettingHeartbeat] {
+		t.Fatalf("expected the heartbeat unchanged, got %v", after)
+	}
+
+	w := do(http.MethodPost, "/admin/config", `{"log": "error", "heartbeat": "50ms", "sync-limit": 500, "metrics": false}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d %q", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp struct {
+		Changes []node.RuntimeChange `json:"changes"`
+		Config  map[string]string    `json:"config"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Changes) != 4 || resp.Changes[0] != (node.RuntimeChange{Key: node.SettingLog, Before: "fatal", After: "error"}) {
+		t.Fatalf("expected 4 changes, the log level first, got %+v", resp.Changes)
+	}
+	after := get()
+	for key, value := range map[string]string{
+		node.SettingLog:       "error",
+		node.SettingHeartbeat: "50ms",
+		node.SettingSyncLimit: "500",
+		node.SettingMetrics:   "false",
+	} {
+		if after[key] != value || resp.Config[key] != value {
+			t.Fatalf("expected %s %s, got %v", key, value, after)
+		}
+	}
+	if nodeLogger.GetLevel() != logrus.ErrorLevel {
+		t.Fatalf("expected the logger at error, got %v", nodeLogger.GetLevel())
+	}
+	if w := do(http.MethodGet, "/metrics", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected the metrics off, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/admin/config", "{}"); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected PUT not allowed, got %d", w.Code)
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/SamuelMarks/dag1/src/common/hexutil"
+	"github.com/SamuelMarks/dag1/src/crypto/verify"
+	"github.com/SamuelMarks/dag1/src/poset"
+)
+
+// maxVerifySize bounds the body of a POST /verify request
+const maxVerifySize = 1 << 20
+
+// ParticipantKey is a participant as listed by GET /participants
+type ParticipantKey struct {
+	ID      uint64 `json:"id"`
+	PubKey  string `json:"pubkey"`
+	Address string `json:"address"`
+}
+
+// VerifyRequest is the body of a POST /verify request. Payload is the body
+// of the event or block in protobuf, in base64 as encoding/json encodes
+// []byte, and Signature is as in the event or block signature.
+type VerifyRequest struct {
+	Type      string `json:"type"` // "event" or "block"
+	Payload   []byte `json:"payload"`
+	Signature string `json:"signature"`
+	PubKey    string `json:"pubkey"`
+}
+
+// VerifyResponse is the result of a POST /verify request. Hash is the hash
+// signed, of the canonical encoding of the payload, in hex.
+type VerifyResponse struct {
+	Valid bool   `json:"valid"`
+	Hash  string `json:"hash"`
+}
+
+// GetParticipantKeys returns the ID, public key and address of each
+// participant
+func (s *Service) GetParticipantKeys(w http.ResponseWriter, r *http.Request) {
+	participants, err := s.node.GetParticipants()
+	if err != nil {
+		s.logger.WithError(err).Error("Getting participants")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	keys := []ParticipantKey{}
+	for _, p := range participants.ToPeerSlice() {
+		keys = append(keys, ParticipantKey{
+			ID:      p.ID,
+			PubKey:  p.Message.PubKeyHex,
+			Address: p.Address().Hex(),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(keys); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// Verify checks the signature of an event or block body by a public key
+func (s *Service) Verify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req VerifyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxVerifySize)).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pubKey, err := verify.ParsePubKey(req.PubKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var res VerifyResponse
+	switch req.Type {
+	case "event":
+		res, err = verifyEvent(pubKey, req.Payload, req.Signature)
+	case "block":
+		res, err = verifyBlock(pubKey, req.Payload, req.Signature)
+	default:
+		http.Error(w, "type must be event or block", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		s.logger.Debug(err)
+	}
+}
+
+// verifyEvent checks the signature of an event body, which is only valid
+// for the creator of the event. The signature is checked with pubKey, which
+// was validated, the creator of a tampered body may not even be a key.
+func verifyEvent(pubKey, payload []byte, sig string) (VerifyResponse, error) {
+	var body poset.EventBody
+	if err := body.ProtoUnmarshal(payload); err != nil {
+		return VerifyResponse{}, err
+	}
+	hash, err := body.Hash()
+	if err != nil {
+		return VerifyResponse{}, err
+	}
+	res := VerifyResponse{Hash: hexutil.Encode(hash.Bytes())}
+	if !bytes.Equal(body.Creator, pubKey) {
+		return res, nil
+	}
+	if res.Valid, err = verify.Signature(pubKey, hash.Bytes(), sig); err != nil {
+		return VerifyResponse{}, err
+	}
+	return res, nil
+}
+
+// verifyBlock checks the signature of a block body by a validator
+func verifyBlock(pubKey, payload []byte, sig string) (VerifyResponse, error) {
+	var body poset.BlockBody
+	if err := body.ProtoUnmarshal(payload); err != nil {
+		return VerifyResponse{}, err
+	}
+	hash, err := body.Hash()
+	if err != nil {
+		return VerifyResponse{}, err
+	}
+	block := poset.Block{Body: &body}
+	ok, err := block.Verify(poset.BlockSignature{
+		Validator: pubKey,
+		Index:     body.Index,
+		Signature: sig,
+	})
+	if err != nil {
+		return VerifyResponse{}, err
+	}
+	return VerifyResponse{Valid: ok, Hash: hexutil.Encode(hash)}, nil
+}
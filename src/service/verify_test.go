@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/common/hexutil"
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/node"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/sirupsen/logrus"
+)
+
+func TestServiceVerify(t *testing.T) {
+	s, url, client := serveTest("127.0.0.1:0", t)
+	defer s.Close()
+
+	post := func(req VerifyRequest, token string) (int, VerifyResponse) {
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := http.NewRequest(http.MethodPost, url+"/verify", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var res VerifyResponse
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode, res
+	}
+
+	key, _ := crypto.GenerateECDSAKey()
+	pubKey := crypto.FromECDSAPub(&key.PublicKey)
+	pubKeyHex := "0x" + common.Bytes2Hex(pubKey)
+
+	event := poset.NewEvent([][]byte{[]byte("tx")}, nil, nil,
+		poset.EventHashes{}, pubKey, 0, nil, nil, 0, false)
+	if err := event.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	eventBody, err := event.Message.Body.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventHash, err := event.Message.Body.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := poset.NewBlock(1, 2, []byte("frame"), [][]byte{[]byte("tx")})
+	blockSig, err := block.Sign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockBody, err := block.Body.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockHash, err := block.Body.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventReq := VerifyRequest{Type: "event", Payload: eventBody,
+		Signature: event.Message.Signature, PubKey: pubKeyHex}
+	if code, _ := post(eventReq, ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected the token required, got %d", code)
+	}
+	code, res := post(eventReq, testToken)
+	if code != http.StatusOK || !res.Valid || res.Hash != hexutil.Encode(eventHash.Bytes()) {
+		t.Fatalf("expected the event valid with hash %s, got %d %+v", eventHash.String(), code, res)
+	}
+
+	blockReq := VerifyRequest{Type: "block", Payload: blockBody,
+		Signature: blockSig.Signature, PubKey: pubKeyHex}
+	code, res = post(blockReq, testToken)
+	if code != http.StatusOK || !res.Valid || res.Hash != hexutil.Encode(blockHash) {
+		t.Fatalf("expected the block valid with hash %X, got %d %+v", blockHash, code, res)
+	}
+
+	// tampered payloads, or the key of another participant, do not verify
+	for _, req := range []VerifyRequest{eventReq, blockReq} {
+		req.Payload = append([]byte{}, req.Payload...)
+		req.Payload[len(req.Payload)-1] ^= 1
+		if code, res := post(req, testToken); code != http.StatusOK || res.Valid {
+			t.Fatalf("expected a tampered %s refused, got %d %+v", req.Type, code, res)
+		}
+	}
+	other, _ := crypto.GenerateECDSAKey()
+	otherReq := eventReq
+	otherReq.PubKey = "0x" + common.Bytes2Hex(crypto.FromECDSAPub(&other.PublicKey))
+	if code, res := post(otherReq, testToken); code != http.StatusOK || res.Valid {
+		t.Fatalf("expected the event refused for another key, got %d %+v", code, res)
+	}
+
+	// malformed requests
+	for _, req := range []VerifyRequest{
+		{Type: "round", Payload: eventBody, Signature: event.Message.Signature, PubKey: pubKeyHex},
+		{Type: "event", Payload: eventBody, Signature: "not a signature", PubKey: pubKeyHex},
+		{Type: "event", Payload: eventBody, Signature: event.Message.Signature, PubKey: "0x0401"},
+		{Type: "block", Payload: []byte{0xff}, Signature: blockSig.Signature, PubKey: pubKeyHex},
+	} {
+		if code, _ := post(req, testToken); code != http.StatusBadRequest {
+			t.Fatalf("expected %+v refused, got %d", req, code)
+		}
+	}
+	if code := request(client, http.MethodGet, url+"/verify", "", t); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected GET /verify refused, got %d", code)
+	}
+}
+
+func TestServiceParticipantKeys(t *testing.T) {
+	nodeLogger := logrus.New()
+	nodeLogger.Level = logrus.FatalLevel
+	nodes, err := node.NewNodeList(3, nodeLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, n := range nodes {
+			n.Shutdown()
+		}
+	}()
+	n := nodes.Values()[0]
+	s := &Service{node: n, logger: common.NewTestLogger(t)}
+
+	w := httptest.NewRecorder()
+	s.handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/participants", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the participants, got %d", w.Code)
+	}
+	var keys []ParticipantKey
+	if err := json.NewDecoder(w.Body).Decode(&keys); err != nil {
+		t.Fatal(err)
+	}
+
+	participants, err := n.GetParticipants()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != participants.Len() {
+		t.Fatalf("expected %d participants, got %d", participants.Len(), len(keys))
+	}
+	for _, k := range keys {
+		p, ok := participants.ReadByID(k.ID)
+		if !ok {
+			t.Fatalf("unknown participant %+v", k)
+		}
+		if k.PubKey != p.Message.PubKeyHex || k.Address != p.Address().Hex() {
+			t.Fatalf("expected %s %s, got %+v", p.Message.PubKeyHex, p.Address().Hex(), k)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/crypto"
+	"github.com/SamuelMarks/dag1/src/kvdb"
+	"github.com/SamuelMarks/dag1/src/rlp"
+	"github.com/SamuelMarks/dag1/src/trie"
+)
+
+// ProofNodes are the RLP encoded trie nodes on the path from a state root to
+// an account, root first
+type ProofNodes [][]byte
+
+// GetProof returns the nodes proving the account of an address, or its
+// absence, in the state of a root
+func GetProof(db Database, root common.Hash, addr common.Address) (ProofNodes, error) {
+	statedb, err := New(root, db)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := statedb.GetProof(addr)
+	if err != nil {
+		return nil, err
+	}
+	return ProofNodes(proof), nil
+}
+
+// VerifyProof checks that a proof shows the balance of an address in the
+// state of a root, an address without an account having none. It needs no
+// database, so that light clients can check the proofs of untrusted nodes.
+func VerifyProof(root common.Hash, addr common.Address, balance uint64, proof ProofNodes) error {
+	nodes := kvdb.NewMemDatabase()
+	for _, node := range proof {
+		if err := nodes.Put(crypto.Keccak256(node), node); err != nil {
+			return err
+		}
+	}
+	value, _, err := trie.VerifyProof(root, crypto.Keccak256(addr.Bytes()), nodes)
+	if err != nil {
+		return err
+	}
+
+	var proven uint64
+	if value != nil {
+		var account Account
+		if err := rlp.DecodeBytes(value, &account); err != nil {
+			return fmt.Errorf("bad account in proof: %v", err)
+		}
+		proven = account.Balance
+	}
+	if proven != balance {
+		return fmt.Errorf("proof shows a balance of %d, not %d", proven, balance)
+	}
+	return nil
+}
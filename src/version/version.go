@@ -1,6 +1,9 @@
 package version
 
-import "strings"
+import (
+	"runtime"
+	"strings"
+)
 
 // Maj major semver version
 const Maj = "0"
@@ -19,9 +22,43 @@ func dashPrependAndSliceOn(condition bool, s string) string {
 }
 
 var (
-	// GitCommit is set with: -ldflags "-X main.gitCommit=$(git rev-parse HEAD)"
+	// GitCommit is set with:
+	// -ldflags "-X github.com/SamuelMarks/dag1/src/version.GitCommit=$(git rev-parse HEAD)"
 	GitCommit string
 
+	// BuildDate is set with:
+	// -ldflags "-X github.com/SamuelMarks/dag1/src/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+	BuildDate string
+
 	// Version the full version string
 	Version = strings.Join([]string{Maj, Min, Fix}, ".") + dashPrependAndSliceOn(GitCommit != "", GitCommit)
 )
+
+// Info is the version and the build of the binary
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the version and the build of the binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// SameMinor returns true when another version has the major and minor
+// versions of ours. An empty one, of a node which does not tell, is taken as
+// the same.
+func SameMinor(other string) bool {
+	if other == "" {
+		return true
+	}
+	parts := strings.SplitN(other, ".", 3)
+	return len(parts) >= 2 && parts[0] == Maj && parts[1] == Min
+}
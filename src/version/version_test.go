@@ -0,0 +1,21 @@
+package version
+
+import "testing"
+
+func TestSameMinor(t *testing.T) {
+	for _, c := range []struct {
+		other string
+		same  bool
+	}{
+		{"", true},
+		{Maj + "." + Min + ".0", true},
+		{Maj + "." + Min + "." + Fix + "-0123abcd", true},
+		{Maj + "." + Min + "9.0", false},
+		{"9" + Maj + "." + Min + ".0", false},
+		{"garbage", false},
+	} {
+		if same := SameMinor(c.other); same != c.same {
+			t.Errorf("%q: expected %v, got %v", c.other, c.same, same)
+		}
+	}
+}
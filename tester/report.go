@@ -0,0 +1,52 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/SamuelMarks/dag1/src/node"
+	"github.com/SamuelMarks/dag1/src/service"
+)
+
+// reportTimeout bounds the request of the round report
+const reportTimeout = 10 * time.Second
+
+// FetchRoundReport gets the round report of a node from its HTTP service,
+// at a host:port or unix:///path address, with the bearer token if any
+func FetchRoundReport(serviceAddr, token string) ([]node.RoundReport, error) {
+	client := &http.Client{Timeout: reportTimeout}
+	url := "http://" + serviceAddr + "/report/rounds"
+	if path, ok := service.SocketPath(serviceAddr); ok {
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+		url = "http://unix/report/rounds"
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("round report: %s", resp.Status)
+	}
+	var rounds []node.RoundReport
+	if err := json.NewDecoder(resp.Body).Decode(&rounds); err != nil {
+		return nil, err
+	}
+	return rounds, nil
+}
@@ -1,85 +1,361 @@
 package tester
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
 	"fmt"
-	"math/rand"
-	_ "os" // required for TODO
+	mrand "math/rand"
+	"sort"
 	"strconv"
 	"strings"
-	_ "sync" // required for TODO
+	"sync"
 	"time"
 
+	"github.com/SamuelMarks/dag1/src/common"
 	"github.com/SamuelMarks/dag1/src/peers"
 	"github.com/SamuelMarks/dag1/src/proxy"
+	"github.com/SamuelMarks/dag1/src/proxy/proto"
 	"github.com/sirupsen/logrus"
 )
 
-// PingNodesN ping the nodes to make sure they are communicating
-func PingNodesN(participants []*peers.Peer, p peers.PubKeyPeers, n uint64, delay uint64, logger *logrus.Logger, ProxyAddr string) {
-	// pause before shooting test transactions
-	time.Sleep(time.Duration(delay) * time.Second)
+// Distributions of the transactions across the nodes
+const (
+	// RoundRobin sends to the nodes in turn
+	RoundRobin = "round-robin"
+	// Random sends to a node picked at random
+	Random = "random"
+	// Single sends to the first node only
+	Single = "single"
+)
 
-	proxies := make(map[uint64]*proxy.GrpcDAG1Proxy)
-	for _, participant := range participants {
-		node := p[participant.Message.PubKeyHex]
-		if node.Message.NetAddr == "" {
-			fmt.Printf("node missing NetAddr [%v]", node)
-			continue
+// Distributions lists the distributions of a load test
+var Distributions = []string{RoundRobin, Random, Single}
+
+// minFinalityPayload is the least payload size telling the transactions
+// apart when waiting for their blocks: a sequence number and random bytes
+const minFinalityPayload = 16
+
+// Config of a load test
+type Config struct {
+	// PayloadSize is the size of a transaction in bytes
+	PayloadSize int
+	// TPS is the target number of transactions per second, 0 to send as fast
+	// as the nodes take them
+	TPS float64
+	// Count is the number of transactions to send, 0 for no limit
+	Count uint64
+	// Duration is how long to send for, 0 for no limit
+	Duration time.Duration
+	// Distribution is how the transactions are spread across the nodes
+	Distribution string
+	// WaitFinality waits for the block of every transaction, to report the
+	// end-to-end latency. The payloads must be of at least 16 bytes.
+	// PingNodesN then acknowledges the blocks of the nodes as their app
+	// would, so it is for nodes with no other app connected.
+	WaitFinality bool
+	// FinalityTimeout is how long to wait for the block of a transaction
+	FinalityTimeout time.Duration
+	// Delay is the pause before the first transaction, for the nodes to
+	// come up
+	Delay time.Duration
+	// Clock paces the transactions and times their latency, the real clock
+	// when nil
+	Clock common.Clock
+}
+
+// clock returns the clock of the load test
+func (c *Config) clock() common.Clock {
+	if c.Clock == nil {
+		return common.RealClock
+	}
+	return c.Clock
+}
+
+// DefaultConfig returns the config of a load test of 10 transactions of 120
+// bytes per second for a minute
+func DefaultConfig() *Config {
+	return &Config{
+		// Ethereum txns are ~108 bytes. Bitcoin txns are ~250 bytes.
+		PayloadSize:     120,
+		TPS:             10,
+		Duration:        time.Minute,
+		Distribution:    Random,
+		FinalityTimeout: time.Minute,
+		Delay:           time.Second,
+	}
+}
+
+// Validate checks the config can run a load test
+func (c *Config) Validate() error {
+	switch {
+	case c.PayloadSize < 1:
+		return fmt.Errorf("payload size %d is less than 1", c.PayloadSize)
+	case c.TPS < 0:
+		return fmt.Errorf("target tps %v is negative", c.TPS)
+	case c.Count == 0 && c.Duration <= 0:
+		return errors.New("a load test needs a count or a duration")
+	case c.WaitFinality && c.PayloadSize < minFinalityPayload:
+		return fmt.Errorf("waiting for finality needs payloads of at least %d bytes", minFinalityPayload)
+	case c.WaitFinality && c.FinalityTimeout <= 0:
+		return fmt.Errorf("finality timeout %v is not a positive duration", c.FinalityTimeout)
+	}
+	for _, d := range Distributions {
+		if c.Distribution == d {
+			return nil
 		}
-		hostPort := strings.Split(node.Message.NetAddr, ":")
-		port, err := strconv.Atoi(hostPort[1])
-		if err != nil {
-			fmt.Printf("error:\t\t\t%s\n", err.Error())
-			fmt.Printf("Unable to create port:\t\t\t%s (id=%d)\n", participant.Message.NetAddr, node.ID)
+	}
+	return fmt.Errorf("unknown distribution %q, available: %s",
+		c.Distribution, strings.Join(Distributions, ","))
+}
+
+// Submitter is the part of a proxy.DAG1Proxy a load test sends through
+type Submitter interface {
+	SubmitTx(tx []byte) error
+	SubmitTxAndWait(ctx context.Context, tx []byte) (proto.BlockRef, error)
+}
+
+// Latency sums up the end-to-end latencies of the transactions, in
+// milliseconds
+type Latency struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// Result of a load test
+type Result struct {
+	Submitted uint64 `json:"submitted"`
+	Failed    uint64 `json:"failed"`
+	// ElapsedSeconds is the time spent sending
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	// TPS is the achieved number of transactions submitted per second
+	TPS float64 `json:"tps"`
+	// Finalized is the number of transactions found in a block, when
+	// waiting for finality
+	Finalized uint64   `json:"finalized,omitempty"`
+	Latency   *Latency `json:"latency_ms,omitempty"`
+}
+
+// tokenBucket paces the transactions at a rate, letting through bursts of
+// up to a twentieth of a second of them
+type tokenBucket struct {
+	clock  common.Clock
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, clock common.Clock) *tokenBucket {
+	burst := rate / 20
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{clock: clock, rate: rate, burst: burst, tokens: burst, last: clock.Now()}
+}
+
+func (b *tokenBucket) refill() {
+	t := b.clock.Now()
+	b.tokens += t.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = t
+}
+
+// take waits for a token
+func (b *tokenBucket) take() {
+	b.refill()
+	if b.tokens < 1 {
+		b.clock.Sleep(time.Duration((1 - b.tokens) / b.rate * float64(time.Second)))
+		b.refill()
+	}
+	b.tokens--
+}
+
+// payload returns the transaction of a sequence number: the number, then
+// random bytes
+func payload(seq uint64, size int) []byte {
+	tx := make([]byte, size)
+	if size < 8 {
+		tx[0] = byte(seq)
+		return tx
+	}
+	binary.BigEndian.PutUint64(tx, seq)
+	if _, err := rand.Read(tx[8:]); err != nil {
+		panic(err)
+	}
+	return tx
+}
+
+// percentile returns the p-th percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) float64 {
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return float64(sorted[i]) / float64(time.Millisecond)
+}
+
+// Run sends transactions to the nodes as the config says and returns what it
+// achieved
+func Run(submitters []Submitter, conf Config) (Result, error) {
+	if len(submitters) == 0 {
+		return Result{}, errors.New("no node to send transactions to")
+	}
+	if err := conf.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	clock := conf.clock()
+	var bucket *tokenBucket
+	if conf.TPS > 0 {
+		bucket = newTokenBucket(conf.TPS, clock)
+	}
+	rng := mrand.New(mrand.NewSource(clock.Now().UnixNano()))
+
+	var (
+		lock      sync.Mutex
+		res       Result
+		latencies []time.Duration
+		wg        sync.WaitGroup
+	)
+	start := clock.Now()
+	for seq := uint64(0); conf.Count == 0 || seq < conf.Count; seq++ {
+		if conf.Duration > 0 && clock.Now().Sub(start) >= conf.Duration {
+			break
 		}
-		addr := fmt.Sprintf("%s:%d", hostPort[0], port-3000 /*9000*/)
-		dag1Proxy, err := proxy.NewGrpcDAG1Proxy(addr, logger)
-		if err != nil {
-			fmt.Printf("error:\t\t\t%s\n", err.Error())
-			fmt.Printf("Failed to create WebsocketDAG1Proxy:\t\t\t%s (id=%d)\n", participant.Message.NetAddr, node.ID)
+		if bucket != nil {
+			bucket.take()
+		}
+
+		var s Submitter
+		switch conf.Distribution {
+		case RoundRobin:
+			s = submitters[seq%uint64(len(submitters))]
+		case Random:
+			s = submitters[rng.Intn(len(submitters))]
+		default:
+			s = submitters[0]
 		}
-		proxies[node.ID] = dag1Proxy
+		tx := payload(seq, conf.PayloadSize)
+
+		if !conf.WaitFinality {
+			if err := s.SubmitTx(tx); err != nil {
+				res.Failed++
+			} else {
+				res.Submitted++
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(s Submitter, tx []byte) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), conf.FinalityTimeout)
+			defer cancel()
+			sent := clock.Now()
+			_, err := s.SubmitTxAndWait(ctx, tx)
+			latency := clock.Now().Sub(sent)
+
+			lock.Lock()
+			defer lock.Unlock()
+			switch {
+			case err == nil:
+				res.Submitted++
+				res.Finalized++
+				latencies = append(latencies, latency)
+			case err == context.DeadlineExceeded:
+				res.Submitted++
+			default:
+				res.Failed++
+			}
+		}(s, tx)
 	}
-	for iteration := uint64(0); iteration < n; iteration++ {
-		participant := participants[rand.Intn(len(participants))]
-		node := p[participant.Message.PubKeyHex]
+	elapsed := clock.Now().Sub(start)
+	wg.Wait()
 
-		_, err := transact(proxies[node.ID], ProxyAddr, iteration)
+	res.ElapsedSeconds = elapsed.Seconds()
+	if elapsed > 0 {
+		res.TPS = float64(res.Submitted) / elapsed.Seconds()
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		res.Latency = &Latency{
+			P50: percentile(latencies, 0.5),
+			P90: percentile(latencies, 0.9),
+			P99: percentile(latencies, 0.99),
+			Max: percentile(latencies, 1),
+		}
+	}
+	return res, nil
+}
 
-		if err != nil {
-			fmt.Printf("error:\t\t\t%s\n", err.Error())
-			fmt.Printf("Failed to ping:\t\t\t%s (id=%d)\n", participant.Message.NetAddr, node.ID)
-			fmt.Printf("Failed to send transaction:\t%d\n\n", iteration)
-		} /*else {
-			fmt.Printf("Pinged:\t\t\t%s (id=%d)\n", participant.NetAddr, node)
-			fmt.Printf("Last transaction sent:\t%d\n\n", iteration)
-		}*/
-	}
-
-	for _, dag1Proxy := range proxies {
-		if err := dag1Proxy.Close(); err != nil {
-			logger.Fatal(err)
+// answerCommits stands for the app of a node, acknowledging its blocks until
+// done is closed, so that the node goes on committing
+func answerCommits(p *proxy.GrpcDAG1Proxy, done chan struct{}) {
+	for {
+		select {
+		case commit := <-p.CommitCh():
+			commit.Respond(nil, nil)
+		case batch := <-p.CommitBatchCh():
+			batch.Respond(make([][]byte, len(batch.Blocks)), nil)
+		case <-done:
+			return
 		}
 	}
-	fmt.Println("Pinging stopped after ", n, " iterations")
 }
 
-func transact(proxy *proxy.GrpcDAG1Proxy, proxyAddr string, iteration uint64) (string, error) {
+// PingNodesN runs a load test against the app proxies of the nodes, sending
+// to each through the port 3000 below its gossip port
+func PingNodesN(participants []*peers.Peer, p peers.PubKeyPeers, conf Config, logger *logrus.Logger) (Result, error) {
+	if err := conf.Validate(); err != nil {
+		return Result{}, err
+	}
+	// pause before shooting test transactions
+	conf.clock().Sleep(conf.Delay)
 
-	// Ethereum txns are ~108 bytes. Bitcoin txns are ~250 bytes.
-	// A good assumption is to make txns 120 bytes in size.
-	// However, for speed, we're using 1 byte here. Modify accordingly.
-	msg := []byte{ 0 }
-	for i := 0; i < 10; i++ {
-		// Send 10 txns to the server.
-		//msg := fmt.Sprintf("%s.%d.%d", proxyAddr, iteration, i)
-		//err := proxy.SubmitTx([]byte(msg))
-		err := proxy.SubmitTx(msg)
+	var proxies []*proxy.GrpcDAG1Proxy
+	var submitters []Submitter
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		for _, dag1Proxy := range proxies {
+			if err := dag1Proxy.Close(); err != nil {
+				logger.Error(err)
+			}
+		}
+	}()
+	for _, participant := range participants {
+		node := p[participant.Message.PubKeyHex]
+		if node.Message.NetAddr == "" {
+			logger.WithField("id", node.ID).Warn("node missing NetAddr")
+			continue
+		}
+		hostPort := strings.Split(node.Message.NetAddr, ":")
+		port, err := strconv.Atoi(hostPort[len(hostPort)-1])
 		if err != nil {
-			return "", err
+			logger.WithField("id", node.ID).Warnf("Unable to read the port of %s: %v", node.Message.NetAddr, err)
+			continue
+		}
+		addr := fmt.Sprintf("%s:%d", strings.Join(hostPort[:len(hostPort)-1], ":"), port-3000 /*9000*/)
+		dag1Proxy, err := proxy.NewGrpcDAG1Proxy(addr, logger, proxy.WithClock(conf.clock()))
+		if err != nil {
+			logger.WithField("id", node.ID).Warnf("Failed to create GrpcDAG1Proxy to %s: %v", addr, err)
+			continue
+		}
+		proxies = append(proxies, dag1Proxy)
+		submitters = append(submitters, dag1Proxy)
+		if conf.WaitFinality {
+			go answerCommits(dag1Proxy, done)
 		}
 	}
-	// fmt.Println("Submitted tx, ack=", ack)  # `ack` is now `_`
 
-	return "", nil
+	return Run(submitters, conf)
 }
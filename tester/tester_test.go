@@ -0,0 +1,333 @@
+package tester
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/SamuelMarks/dag1/src/common"
+	"github.com/SamuelMarks/dag1/src/poset"
+	"github.com/SamuelMarks/dag1/src/proxy"
+	"github.com/SamuelMarks/dag1/src/proxy/proto"
+	"github.com/SamuelMarks/dag1/src/utils"
+)
+
+// fakeClock stands for the clock of the load generator: sleeping moves it
+// forward at once
+type fakeClock struct {
+	*common.ManualClock
+}
+
+func newFakeClock() fakeClock {
+	return fakeClock{common.NewManualClock(time.Unix(1e9, 0))}
+}
+
+func (c fakeClock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+type submission struct {
+	seq uint64
+	at  time.Time
+}
+
+// fakeProxy captures the transactions submitted to it, at the time of clock
+// when set. Its transactions are committed after latency, or never when it is
+// negative.
+type fakeProxy struct {
+	clock   common.Clock
+	lock    sync.Mutex
+	txs     []submission
+	fail    bool
+	latency time.Duration
+}
+
+func (p *fakeProxy) SubmitTx(tx []byte) error {
+	if p.fail {
+		return errors.New("refused")
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var seq uint64
+	if len(tx) >= 8 {
+		seq = binary.BigEndian.Uint64(tx)
+	}
+	at := time.Now()
+	if p.clock != nil {
+		at = p.clock.Now()
+	}
+	p.txs = append(p.txs, submission{seq, at})
+	return nil
+}
+
+func (p *fakeProxy) SubmitTxAndWait(ctx context.Context, tx []byte) (proto.BlockRef, error) {
+	if err := p.SubmitTx(tx); err != nil {
+		return proto.BlockRef{}, err
+	}
+	if p.latency < 0 {
+		<-ctx.Done()
+		return proto.BlockRef{}, ctx.Err()
+	}
+	time.Sleep(p.latency)
+	return proto.BlockRef{}, nil
+}
+
+func (p *fakeProxy) submitted() []submission {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]submission{}, p.txs...)
+}
+
+func TestRunPacing(t *testing.T) {
+	clock := newFakeClock()
+	p := &fakeProxy{clock: clock}
+	res, err := Run([]Submitter{p}, Config{
+		PayloadSize:  8,
+		TPS:          100,
+		Count:        50,
+		Distribution: Single,
+		Clock:        clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a burst of a twentieth of a second of transactions, then one every
+	// hundredth of a second
+	txs := p.submitted()
+	if res.Submitted != 50 || len(txs) != 50 {
+		t.Fatalf("expected 50 transactions, got %d (%+v)", len(txs), res)
+	}
+	for i := 1; i < len(txs); i++ {
+		gap := txs[i].at.Sub(txs[i-1].at)
+		expected := 10 * time.Millisecond
+		if i < 5 {
+			expected = 0
+		}
+		if gap < expected-time.Microsecond || gap > expected+time.Microsecond {
+			t.Fatalf("transaction %d: expected a gap of %v, got %v", i, expected, gap)
+		}
+	}
+	if res.ElapsedSeconds < 0.449 || res.ElapsedSeconds > 0.451 {
+		t.Fatalf("expected 0.45s of sending, got %v", res.ElapsedSeconds)
+	}
+	if res.TPS < 110 || res.TPS > 112 {
+		t.Fatalf("expected about 111 tps with the burst, got %v", res.TPS)
+	}
+}
+
+func TestRunDuration(t *testing.T) {
+	clock := newFakeClock()
+	p := &fakeProxy{clock: clock}
+	res, err := Run([]Submitter{p}, Config{
+		PayloadSize:  8,
+		TPS:          100,
+		Duration:     time.Second,
+		Distribution: Single,
+		Clock:        clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Submitted < 104 || res.Submitted > 106 {
+		t.Fatalf("expected a second of 100 tps after a burst of 5, got %d", res.Submitted)
+	}
+}
+
+func TestRunDistribution(t *testing.T) {
+	newProxies := func() ([]*fakeProxy, []Submitter) {
+		proxies := []*fakeProxy{{}, {}, {}}
+		return proxies, []Submitter{proxies[0], proxies[1], proxies[2]}
+	}
+
+	proxies, submitters := newProxies()
+	if _, err := Run(submitters, Config{PayloadSize: 8, Count: 9, Distribution: RoundRobin}); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range proxies {
+		txs := p.submitted()
+		if len(txs) != 3 {
+			t.Fatalf("round-robin: expected 3 transactions to node %d, got %d", i, len(txs))
+		}
+		for j, tx := range txs {
+			if tx.seq != uint64(3*j+i) {
+				t.Fatalf("round-robin: expected the transaction %d to node %d, got %d", 3*j+i, i, tx.seq)
+			}
+		}
+	}
+
+	proxies, submitters = newProxies()
+	if _, err := Run(submitters, Config{PayloadSize: 8, Count: 9, Distribution: Single}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(proxies[0].submitted()); n != 9 {
+		t.Fatalf("single: expected 9 transactions to the first node, got %d", n)
+	}
+
+	proxies, submitters = newProxies()
+	if _, err := Run(submitters, Config{PayloadSize: 8, Count: 300, Distribution: Random}); err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for i, p := range proxies {
+		n := len(p.submitted())
+		if n == 0 {
+			t.Fatalf("random: expected transactions to node %d", i)
+		}
+		total += n
+	}
+	if total != 300 {
+		t.Fatalf("random: expected 300 transactions, got %d", total)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	res, err := Run([]Submitter{&fakeProxy{}, &fakeProxy{fail: true}}, Config{
+		PayloadSize:  1,
+		Count:        10,
+		Distribution: RoundRobin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Submitted != 5 || res.Failed != 5 {
+		t.Fatalf("expected 5 transactions submitted and 5 failed, got %+v", res)
+	}
+}
+
+func TestRunFinality(t *testing.T) {
+	res, err := Run([]Submitter{&fakeProxy{latency: 20 * time.Millisecond}, &fakeProxy{latency: -1}}, Config{
+		PayloadSize:     minFinalityPayload,
+		Count:           10,
+		Distribution:    RoundRobin,
+		WaitFinality:    true,
+		FinalityTimeout: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Submitted != 10 || res.Finalized != 5 || res.Failed != 0 {
+		t.Fatalf("expected 10 transactions submitted and 5 finalized, got %+v", res)
+	}
+	if res.Latency == nil || res.Latency.P50 < 20 || res.Latency.Max < res.Latency.P99 ||
+		res.Latency.P99 < res.Latency.P90 || res.Latency.P90 < res.Latency.P50 {
+		t.Fatalf("expected latencies of at least 20ms, got %+v", res.Latency)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{PayloadSize: 1, Count: 1, Distribution: Random}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range []func(c *Config){
+		func(c *Config) { c.PayloadSize = 0 },
+		func(c *Config) { c.TPS = -1 },
+		func(c *Config) { c.Count = 0 },
+		func(c *Config) { c.Distribution = "all" },
+		func(c *Config) { c.WaitFinality, c.FinalityTimeout = true, time.Second },
+	} {
+		c := valid
+		change(&c)
+		if err := c.Validate(); err == nil {
+			t.Fatalf("expected %+v invalid", c)
+		}
+	}
+}
+
+// TestThroughputSmoke sends a paced load through gRPC to an app proxy
+// standing for a node, which commits what it gets every few milliseconds
+func TestThroughputSmoke(t *testing.T) {
+	addr := utils.GetUnusedNetAddr(1, t)
+	// the proxies log as they close, after the test
+	logger := logrus.New()
+	logger.Level = logrus.ErrorLevel
+	node, err := proxy.NewGrpcAppProxy(addr[0], time.Second, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+	client, err := proxy.NewGrpcDAG1Proxy(addr[0], logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go answerCommits(client, done)
+
+	// the transactions are taken as they come, as by a node, and committed
+	// apart
+	var (
+		lock     sync.Mutex
+		pending  [][]byte
+		received int
+	)
+	go func() {
+		for {
+			select {
+			case tx := <-node.SubmitCh():
+				lock.Lock()
+				pending = append(pending, tx)
+				received++
+				lock.Unlock()
+			case <-done:
+				return
+			}
+		}
+	}()
+	go func() {
+		tick := time.NewTicker(5 * time.Millisecond)
+		defer tick.Stop()
+		for index := int64(0); ; {
+			select {
+			case <-tick.C:
+				lock.Lock()
+				txs := pending
+				pending = nil
+				lock.Unlock()
+				if len(txs) == 0 {
+					continue
+				}
+				if _, err := node.CommitBlock(poset.NewBlock(index, index+1, []byte{}, txs)); err != nil {
+					t.Error(err)
+					return
+				}
+				index++
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	res, err := Run([]Submitter{client}, Config{
+		PayloadSize:     120,
+		TPS:             500,
+		Count:           200,
+		Distribution:    Single,
+		WaitFinality:    true,
+		FinalityTimeout: 10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Submitted != 200 || res.Finalized != 200 || res.Latency == nil {
+		t.Fatalf("expected 200 transactions finalized, got %+v", res)
+	}
+	if res.TPS > 600 {
+		t.Fatalf("expected at most about 500 tps, got %v", res.TPS)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if received != 200 {
+		t.Fatalf("expected the node to get 200 transactions, got %d", received)
+	}
+}